@@ -47,6 +47,9 @@ type EvaluationRequest struct {
 	EmbeddingModelID string `json:"embedding_id"` // Embedding model ID
 	ChatModelID      string `json:"chat_id"`      // Chat model ID
 	RerankModelID    string `json:"rerank_id"`    // Reranking model ID
+
+	MaxConcurrency    int `json:"max_concurrency,omitempty"`     // QA pairs evaluated in parallel, capped by the server
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"` // QA pairs started per minute, capped by the server
 }
 
 // EvaluationTaskResponse represents an evaluation task response
@@ -111,3 +114,34 @@ func (c *Client) GetEvaluationResult(ctx context.Context, taskID string) (*Evalu
 
 	return &response.Data, nil
 }
+
+// PauseEvaluation pauses an evaluation task
+// A paused task starts no new queries; queries already in progress still finish
+// Parameters:
+//   - ctx: Context, used for passing request context information
+//   - taskID: Evaluation task ID
+//
+// Returns:
+//   - error: Error information if the request fails
+func (c *Client) PauseEvaluation(ctx context.Context, taskID string) error {
+	resp, err := c.doRequest(ctx, http.MethodPost, "/api/v1/evaluation/"+url.PathEscape(taskID)+"/pause", nil, nil)
+	if err != nil {
+		return err
+	}
+	return parseResponse(resp, nil)
+}
+
+// ResumeEvaluation resumes a paused evaluation task
+// Parameters:
+//   - ctx: Context, used for passing request context information
+//   - taskID: Evaluation task ID
+//
+// Returns:
+//   - error: Error information if the request fails
+func (c *Client) ResumeEvaluation(ctx context.Context, taskID string) error {
+	resp, err := c.doRequest(ctx, http.MethodPost, "/api/v1/evaluation/"+url.PathEscape(taskID)+"/resume", nil, nil)
+	if err != nil {
+		return err
+	}
+	return parseResponse(resp, nil)
+}
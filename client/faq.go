@@ -376,6 +376,51 @@ func (c *Client) ExportFAQEntries(ctx context.Context, knowledgeBaseID string) (
 	return data, nil
 }
 
+// FAQExportOptions selects the format and the entries of a streamed FAQ export.
+type FAQExportOptions struct {
+	// Format is "csv" (default) or "jsonl"
+	Format string
+	// TagID limits the export to a tag (seq_id)
+	TagID int64
+	// Enabled limits the export to enabled or disabled entries
+	Enabled *bool
+}
+
+// StreamFAQEntries streams an FAQ export to w without buffering it, for large sets.
+// CSV exports join similar questions with ##; JSON Lines exports write one entry per line.
+// Returns the number of bytes written.
+func (c *Client) StreamFAQEntries(ctx context.Context,
+	knowledgeBaseID string, opts *FAQExportOptions, w io.Writer,
+) (int64, error) {
+	path := fmt.Sprintf("/api/v1/knowledge-bases/%s/faq/entries/export", knowledgeBaseID)
+	query := url.Values{}
+	if opts != nil {
+		if opts.Format != "" {
+			query.Set("format", opts.Format)
+		}
+		if opts.TagID > 0 {
+			query.Set("tag_id", strconv.FormatInt(opts.TagID, 10))
+		}
+		if opts.Enabled != nil {
+			query.Set("enabled", strconv.FormatBool(*opts.Enabled))
+		}
+	}
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil, query)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, parseResponse(resp, nil)
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to read export response: %w", err)
+	}
+	return n, nil
+}
+
 // FAQFailedEntry represents a failed entry during FAQ import/validation.
 type FAQFailedEntry struct {
 	Index             int      `json:"index"`
@@ -44,6 +44,9 @@ type Knowledge struct {
 	UpdatedAt        time.Time       `json:"updated_at"`
 	ProcessedAt      *time.Time      `json:"processed_at"`
 	ErrorMessage     string          `json:"error_message"`
+	ProcessingMode   string          `json:"processing_mode,omitempty"`    // "sync" or "async", set on file upload
+	ProcessingTaskID string          `json:"processing_task_id,omitempty"` // Background task processing an async upload
+	Chunks           []Chunk         `json:"chunks,omitempty"`             // Chunks of a file processed within the upload request
 }
 
 // KnowledgeResponse represents the API response containing a single knowledge entry
@@ -54,8 +54,10 @@ type Message struct {
 
 // MessageListResponse message list response
 type MessageListResponse struct {
-	Success bool      `json:"success"`
-	Data    []Message `json:"data"`
+	Success    bool      `json:"success"`
+	Data       []Message `json:"data"`
+	NextCursor string    `json:"next_cursor"` // Cursor of the next older page
+	HasMore    bool      `json:"has_more"`    // False once the beginning of the conversation is reached
 }
 
 // LoadMessages loads session messages, supports pagination and time filtering
@@ -102,6 +104,35 @@ func (c *Client) GetMessagesBefore(
 	return c.LoadMessages(ctx, sessionID, limit, &beforeTime)
 }
 
+// LoadMessagesBefore loads the messages strictly before a cursor, newest first
+// An empty cursor loads the most recent messages, oldest first; pass the returned NextCursor
+// to load the previous page until HasMore is false
+func (c *Client) LoadMessagesBefore(
+	ctx context.Context,
+	sessionID string,
+	cursor string,
+	limit int,
+) (*MessageListResponse, error) {
+	path := fmt.Sprintf("/api/v1/messages/%s/load", sessionID)
+
+	queryParams := url.Values{}
+	queryParams.Add("limit", strconv.Itoa(limit))
+	if cursor != "" {
+		queryParams.Add("before", cursor)
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var response MessageListResponse
+	if err := parseResponse(resp, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
 // DeleteMessage deletes a message
 func (c *Client) DeleteMessage(ctx context.Context, sessionID string, messageID string) error {
 	path := fmt.Sprintf("/api/v1/messages/%s/%s", sessionID, messageID)
@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -108,20 +109,60 @@ func (c *Client) UpdateTenant(ctx context.Context, tenant *Tenant) (*Tenant, err
 	return &response.Data, nil
 }
 
-// DeleteTenant removes a tenant by ID
-func (c *Client) DeleteTenant(ctx context.Context, tenantID uint64) error {
+// TenantDeleteConfirmation is the confirmation token that deleting a tenant requires
+type TenantDeleteConfirmation struct {
+	TenantID  uint64 `json:"tenant_id"`
+	Token     string `json:"confirmation_token"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// TenantPurgeTask is the background task deleting a tenant and all of its data
+type TenantPurgeTask struct {
+	TaskID   string `json:"task_id"`
+	TenantID uint64 `json:"tenant_id"`
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+}
+
+// ConfirmTenantDeletion requests the confirmation token for deleting a tenant, valid for 10 minutes
+func (c *Client) ConfirmTenantDeletion(ctx context.Context, tenantID uint64) (*TenantDeleteConfirmation, error) {
+	path := fmt.Sprintf("/api/v1/tenants/%d/purge/confirmation", tenantID)
+	resp, err := c.doRequest(ctx, http.MethodPost, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Success bool                     `json:"success"`
+		Data    TenantDeleteConfirmation `json:"data"`
+	}
+	if err := parseResponse(resp, &response); err != nil {
+		return nil, err
+	}
+
+	return &response.Data, nil
+}
+
+// DeleteTenant starts the permanent deletion of a tenant and all of its data.
+// The token comes from ConfirmTenantDeletion.
+func (c *Client) DeleteTenant(ctx context.Context, tenantID uint64, confirmationToken string) (*TenantPurgeTask, error) {
 	path := fmt.Sprintf("/api/v1/tenants/%d", tenantID)
-	resp, err := c.doRequest(ctx, http.MethodDelete, path, nil, nil)
+	query := url.Values{}
+	query.Set("confirmation_token", confirmationToken)
+	resp, err := c.doRequest(ctx, http.MethodDelete, path, nil, query)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var response struct {
-		Success bool   `json:"success"`
-		Message string `json:"message,omitempty"`
+		Success bool            `json:"success"`
+		Data    TenantPurgeTask `json:"data"`
+	}
+	if err := parseResponse(resp, &response); err != nil {
+		return nil, err
 	}
 
-	return parseResponse(resp, &response)
+	return &response.Data, nil
 }
 
 // ListTenants retrieves all tenants
@@ -24,6 +24,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -63,24 +64,25 @@ func main() {
 		tracer *tracing.Tracer,
 		resourceCleaner interfaces.ResourceCleaner,
 	) error {
-		// Create context for resource cleanup
 		shutdownTimeout := cfg.Server.ShutdownTimeout
 		if shutdownTimeout == 0 {
 			shutdownTimeout = 30 * time.Second
 		}
-		cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), shutdownTimeout)
-		defer cleanupCancel()
 
-		// Register tracer cleanup function to resource cleaner
-		resourceCleaner.RegisterWithName("Tracer", func() error {
-			return tracer.Cleanup(cleanupCtx)
+		// The services constructed above registered their shutdown hooks first, so these run before them
+		runtime.OnShutdown("Resources", func(ctx context.Context) error {
+			return errors.Join(resourceCleaner.Cleanup(ctx)...)
 		})
+		runtime.OnShutdown("Tracer", tracer.Cleanup)
 
 		// Create HTTP server
 		server := &http.Server{
 			Addr:    fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
 			Handler: router,
 		}
+		// Registered last so that it runs first: the server stops accepting requests and waits for the
+		// active requests, including KnowledgeQA/AgentQA streams, before the services are closed
+		runtime.OnShutdown("HTTP server", server.Shutdown)
 
 		ctx, done := context.WithCancel(context.Background())
 		signals := make(chan os.Signal, 1)
@@ -89,19 +91,12 @@ func main() {
 			sig := <-signals
 			log.Printf("Received signal: %v, starting server shutdown...", sig)
 
-			// Create a context with timeout for server shutdown
-			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+			// Run the shutdown hooks within the shutdown timeout
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
 			defer shutdownCancel()
 
-			if err := server.Shutdown(shutdownCtx); err != nil {
-				log.Fatalf("Server forced to shutdown: %v", err)
-			}
-
-			// Clean up all registered resources
-			log.Println("Cleaning up resources...")
-			errs := resourceCleaner.Cleanup(cleanupCtx)
-			if len(errs) > 0 {
-				log.Printf("Errors occurred during resource cleanup: %v", errs)
+			if err := runtime.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Errors occurred during shutdown: %v", err)
 			}
 
 			log.Println("Server has exited")
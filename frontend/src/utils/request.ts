@@ -42,6 +42,8 @@ instance.interceptors.request.use(
     }
     
     config.headers["X-Request-ID"] = `${generateRandomString(12)}`;
+    // Ask the server to localize error and system messages in the UI language
+    config.headers["Accept-Language"] = localStorage.getItem("locale") || "en-US";
     return config;
   },
   (error) => {
@@ -3,13 +3,16 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Tencent/WeKnora/internal/agent/tools"
 	"github.com/Tencent/WeKnora/internal/common"
 	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/i18n"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/chat"
 	"github.com/Tencent/WeKnora/internal/types"
@@ -169,7 +172,22 @@ func (e *AgentEngine) executeLoop(
 	common.PipelineInfo(ctx, "Agent", "loop_start", map[string]interface{}{
 		"max_iterations": e.config.MaxIterations,
 	})
+	// Model and tool calls run under the wall-clock budget so a slow call is interrupted rather
+	// than noticed after it returns; the final answer is generated with the caller's context
+	loopCtx := ctx
+	if e.config.MaxExecutionSeconds > 0 {
+		var cancel context.CancelFunc
+		loopCtx, cancel = context.WithTimeout(ctx, time.Duration(e.config.MaxExecutionSeconds)*time.Second)
+		defer cancel()
+	}
 	for state.CurrentRound < e.config.MaxIterations {
+		// Enforce token and wall-clock budgets before starting another round
+		if reason := e.checkBudget(ctx, loopCtx, state); reason != "" {
+			logger.Warnf(ctx, "[Agent] Budget exhausted before round %d: %s (tokens=%d, elapsed=%dms)",
+				state.CurrentRound+1, reason, state.TokensUsed, time.Since(startTime).Milliseconds())
+			state.StopReason = reason
+			break
+		}
 		roundStart := time.Now()
 		logger.Infof(ctx, "========== Round %d/%d Started ==========", state.CurrentRound+1, e.config.MaxIterations)
 		logger.Infof(ctx, "[Agent][Round-%d] Message history size: %d messages", state.CurrentRound+1, len(messages))
@@ -188,8 +206,18 @@ func (e *AgentEngine) executeLoop(
 			"round":     state.CurrentRound + 1,
 			"tool_cnt":  len(tools),
 		})
-		response, err := e.streamThinkingToEventBus(ctx, messages, tools, state.CurrentRound, sessionID)
+		response, err := e.streamThinkingToEventBus(loopCtx, messages, tools, state.CurrentRound, sessionID)
+		if err == nil && loopCtx.Err() != nil && ctx.Err() == nil {
+			// The stream was cut off by the time budget; its partial output is not a usable round
+			err = loopCtx.Err()
+		}
 		if err != nil {
+			if reason := e.checkBudget(ctx, loopCtx, state); reason == types.AgentStopReasonTimeBudget {
+				logger.Warnf(ctx, "[Agent][Round-%d] Time budget exhausted during LLM call (elapsed=%dms)",
+					state.CurrentRound+1, time.Since(startTime).Milliseconds())
+				state.StopReason = reason
+				break
+			}
 			logger.Errorf(ctx, "[Agent][Round-%d] LLM call failed: %v", state.CurrentRound+1, err)
 			common.PipelineError(ctx, "Agent", "think_failed", map[string]interface{}{
 				"iteration": state.CurrentRound,
@@ -198,6 +226,8 @@ func (e *AgentEngine) executeLoop(
 			return state, fmt.Errorf("LLM call failed: %w", err)
 		}
 
+		state.TokensUsed += roundTokens(messages, response)
+
 		common.PipelineInfo(ctx, "Agent", "think_result", map[string]interface{}{
 			"iteration":     state.CurrentRound,
 			"finish_reason": response.FinishReason,
@@ -237,6 +267,7 @@ func (e *AgentEngine) executeLoop(
 			})
 			state.FinalAnswer = response.Content
 			state.IsComplete = true
+			state.StopReason = types.AgentStopReasonCompleted
 			state.RoundSteps = append(state.RoundSteps, step)
 
 			// Emit final answer done marker
@@ -268,6 +299,16 @@ func (e *AgentEngine) executeLoop(
 			)
 
 			for i, tc := range response.ToolCalls {
+				// Stop dispatching further tools once the session has been stopped
+				if ctx.Err() != nil {
+					return state, fmt.Errorf("agent stopped: %w", ctx.Err())
+				}
+				// Skip the remaining tools once the time budget is exhausted
+				if loopCtx.Err() != nil {
+					logger.Warnf(ctx, "[Agent][Round-%d] Time budget exhausted, skipping %d remaining tool calls",
+						state.CurrentRound+1, len(response.ToolCalls)-i)
+					break
+				}
 				logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool: %s, ID: %s",
 					state.CurrentRound+1, i+1, len(response.ToolCalls), tc.Function.Name, tc.ID)
 
@@ -307,7 +348,7 @@ func (e *AgentEngine) executeLoop(
 					"tool_call_id": tc.ID,
 					"tool_index":   fmt.Sprintf("%d/%d", i+1, len(response.ToolCalls)),
 				})
-				result, err := e.toolRegistry.ExecuteTool(ctx, tc.Function.Name, json.RawMessage(tc.Function.Arguments))
+				result, err := e.executeToolWithStatus(loopCtx, tc, state.CurrentRound, sessionID)
 				duration := time.Since(toolCallStartTime).Milliseconds()
 				logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool execution completed in %dms",
 					state.CurrentRound+1, i+1, len(response.ToolCalls), duration)
@@ -380,6 +421,14 @@ func (e *AgentEngine) executeLoop(
 					}
 				}
 
+				// Collect citable chunks returned by retrieval tools so they become message references
+				if toolCall.Result != nil && toolCall.Result.Data != nil {
+					if refs, ok := toolCall.Result.Data["knowledge_refs"].([]*types.SearchResult); ok {
+						state.KnowledgeRefs = append(state.KnowledgeRefs, refs...)
+						delete(toolCall.Result.Data, "knowledge_refs")
+					}
+				}
+
 				// Store tool call (Observations are now derived from ToolCall.Result.Output)
 				step.ToolCalls = append(step.ToolCalls, toolCall)
 
@@ -419,7 +468,7 @@ func (e *AgentEngine) executeLoop(
 				// Optional: Reflection after each tool call (streaming)
 				if e.config.ReflectionEnabled && result != nil {
 					reflection, err := e.streamReflectionToEventBus(
-						ctx, tc.ID, tc.Function.Name, result.Output,
+						loopCtx, tc.ID, tc.Function.Name, result.Output,
 						state.CurrentRound, sessionID,
 					)
 					if err != nil {
@@ -451,10 +500,16 @@ func (e *AgentEngine) executeLoop(
 
 	// If loop finished without final answer, generate one
 	if !state.IsComplete {
-		logger.Info(ctx, "Reached max iterations, generating final answer")
-		common.PipelineWarn(ctx, "Agent", "max_iterations_reached", map[string]interface{}{
-			"iterations": state.CurrentRound,
-			"max":        e.config.MaxIterations,
+		if state.StopReason == "" {
+			state.StopReason = types.AgentStopReasonMaxIterations
+		}
+		logger.Infof(ctx, "Agent loop cut short (%s), generating final answer", state.StopReason)
+		common.PipelineWarn(ctx, "Agent", "loop_limit_reached", map[string]interface{}{
+			"reason":      state.StopReason,
+			"iterations":  state.CurrentRound,
+			"max":         e.config.MaxIterations,
+			"tokens_used": state.TokensUsed,
+			"elapsed_ms":  time.Since(startTime).Milliseconds(),
 		})
 
 		// Stream final answer generation through EventBus
@@ -463,7 +518,7 @@ func (e *AgentEngine) executeLoop(
 			common.PipelineError(ctx, "Agent", "final_answer_failed", map[string]interface{}{
 				"error": err.Error(),
 			})
-			state.FinalAnswer = "抱歉，我无法生成完整的答案。"
+			state.FinalAnswer = i18n.Localize(ctx, "chat.answer_failed")
 		}
 		state.IsComplete = true
 	}
@@ -486,6 +541,9 @@ func (e *AgentEngine) executeLoop(
 			TotalSteps:      len(state.RoundSteps),
 			TotalDurationMs: time.Since(startTime).Milliseconds(),
 			MessageID:       messageID, // Include message ID for proper message update
+			StopReason:      state.StopReason,
+			Truncated:       state.IsTruncated(),
+			TokensUsed:      state.TokensUsed,
 		},
 	})
 
@@ -493,6 +551,110 @@ func (e *AgentEngine) executeLoop(
 	return state, nil
 }
 
+// executeToolWithStatus runs a tool call while streaming its status and partial output.
+// The call is abandoned as soon as ctx is cancelled (e.g. by StopSession) so a tool that
+// ignores its context cannot hold the turn open.
+func (e *AgentEngine) executeToolWithStatus(
+	ctx context.Context,
+	tc types.LLMToolCall,
+	iteration int,
+	sessionID string,
+) (*types.ToolResult, error) {
+	var mu sync.Mutex
+	seq := 0
+	emitStatus := func(status, partial string) {
+		mu.Lock()
+		seq++
+		current := seq
+		mu.Unlock()
+		e.eventBus.Emit(ctx, event.Event{
+			ID:        fmt.Sprintf("%s-tool-status-%d", tc.ID, current),
+			Type:      event.EventAgentToolStatus,
+			SessionID: sessionID,
+			Data: event.AgentToolStatusData{
+				ToolCallID: tc.ID,
+				ToolName:   tc.Function.Name,
+				Status:     status,
+				Partial:    partial,
+				Seq:        current,
+				Iteration:  iteration,
+			},
+		})
+	}
+	emitStatus(fmt.Sprintf("Running %s...", tc.Function.Name), "")
+
+	type toolOutcome struct {
+		result *types.ToolResult
+		err    error
+	}
+	done := make(chan toolOutcome, 1)
+	toolCtx := tools.WithProgressReporter(ctx, emitStatus)
+	go func() {
+		result, err := e.toolRegistry.ExecuteTool(toolCtx, tc.Function.Name, json.RawMessage(tc.Function.Arguments))
+		done <- toolOutcome{result: result, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		if out.result == nil {
+			out.result = &types.ToolResult{Success: false}
+			if out.err != nil {
+				out.result.Error = out.err.Error()
+			}
+		}
+		return out.result, out.err
+	case <-ctx.Done():
+		logger.Warnf(ctx, "[Agent] Tool %s (%s) cancelled: %v", tc.Function.Name, tc.ID, ctx.Err())
+		err := fmt.Errorf("tool call cancelled: %w", ctx.Err())
+		return &types.ToolResult{Success: false, Error: err.Error()}, err
+	}
+}
+
+// checkBudget returns the stop reason if the token or wall-clock budget is exhausted, or "" otherwise.
+// loopCtx is ctx limited by the wall-clock budget; it only counts as exhausted when ctx itself is still live.
+func (e *AgentEngine) checkBudget(ctx, loopCtx context.Context, state *types.AgentState) string {
+	if e.config.MaxTotalTokens > 0 && state.TokensUsed >= e.config.MaxTotalTokens {
+		return types.AgentStopReasonMaxTokens
+	}
+	if ctx.Err() == nil && errors.Is(loopCtx.Err(), context.DeadlineExceeded) {
+		return types.AgentStopReasonTimeBudget
+	}
+	return ""
+}
+
+// roundTokens returns the tokens spent on a round's LLM call: the provider's reported usage when
+// available, otherwise an estimate from the prompt and response sizes
+func roundTokens(messages []chat.Message, response *types.ChatResponse) int {
+	if response.Usage.TotalTokens > 0 {
+		return response.Usage.TotalTokens
+	}
+	return estimateTokens(messages) + estimateResponseTokens(response)
+}
+
+// estimateTokens estimates token count for messages (rough approximation: 4 characters ≈ 1 token)
+func estimateTokens(messages []chat.Message) int {
+	totalChars := 0
+	for _, msg := range messages {
+		totalChars += len(msg.Role) + len(msg.Content)
+		for _, tc := range msg.ToolCalls {
+			totalChars += len(tc.Function.Name) + len(tc.Function.Arguments)
+		}
+	}
+	return totalChars / 4
+}
+
+// estimateResponseTokens estimates completion token count for an LLM response
+func estimateResponseTokens(response *types.ChatResponse) int {
+	if response.Usage.CompletionTokens > 0 {
+		return response.Usage.CompletionTokens
+	}
+	totalChars := len(response.Content)
+	for _, tc := range response.ToolCalls {
+		totalChars += len(tc.Function.Name) + len(tc.Function.Arguments)
+	}
+	return totalChars / 4
+}
+
 // buildToolsForLLM builds the tools list for LLM function calling
 func (e *AgentEngine) buildToolsForLLM() []chat.Tool {
 	functionDefs := e.toolRegistry.GetFunctionDefinitions()
@@ -592,21 +754,25 @@ func (e *AgentEngine) streamLLMToEventBus(
 	messages []chat.Message,
 	opts *chat.ChatOptions,
 	emitFunc func(chunk *types.StreamResponse, fullContent string),
-) (string, []types.LLMToolCall, error) {
+) (string, []types.LLMToolCall, *types.TokenUsage, error) {
 	logger.Debugf(ctx, "[Agent][Stream] Starting LLM stream with %d messages", len(messages))
 
 	stream, err := e.chatModel.ChatStream(ctx, messages, opts)
 	if err != nil {
 		logger.Errorf(ctx, "[Agent][Stream] Failed to start LLM stream: %v", err)
-		return "", nil, err
+		return "", nil, nil, err
 	}
 
 	fullContent := ""
 	var toolCalls []types.LLMToolCall
+	var usage *types.TokenUsage
 	chunkCount := 0
 
 	for chunk := range stream {
 		chunkCount++
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
 
 		if chunk.Content != "" {
 			fullContent += chunk.Content
@@ -623,7 +789,7 @@ func (e *AgentEngine) streamLLMToEventBus(
 		}
 	}
 
-	return fullContent, toolCalls, nil
+	return fullContent, toolCalls, usage, nil
 }
 
 // streamReflectionToEventBus streams reflection process through EventBus
@@ -652,7 +818,7 @@ func (e *AgentEngine) streamReflectionToEventBus(
 	// Generate a single ID for this entire reflection stream
 	reflectionID := generateEventID("reflection")
 
-	fullReflection, _, err := e.streamLLMToEventBus(
+	fullReflection, _, _, err := e.streamLLMToEventBus(
 		ctx,
 		messages,
 		&chat.ChatOptions{Temperature: 0.5},
@@ -695,6 +861,11 @@ func (e *AgentEngine) streamThinkingToEventBus(
 		Temperature: e.config.Temperature,
 		Tools:       tools,
 		Thinking:    e.config.Thinking,
+		ToolChoice:  e.config.ToolChoiceForRound(iteration, listToolNames(tools)),
+	}
+	if opts.ToolChoice != "" {
+		logger.Infof(ctx, "[Agent][Thinking][Iteration-%d] Retrieval mode %s, forcing tool_choice=%s",
+			iteration+1, e.config.RetrievalMode, opts.ToolChoice)
 	}
 	logger.Debug(context.Background(), "[Agent] streamLLM opts tool_choice=auto temperature=", e.config.Temperature)
 
@@ -704,7 +875,7 @@ func (e *AgentEngine) streamThinkingToEventBus(
 	thinkingID := generateEventID("thinking")
 	logger.Debugf(ctx, "[Agent][Thinking][Iteration-%d] ThinkingID: %s", iteration+1, thinkingID)
 
-	fullContent, toolCalls, err := e.streamLLMToEventBus(
+	fullContent, toolCalls, usage, err := e.streamLLMToEventBus(
 		ctx,
 		messages,
 		opts,
@@ -753,11 +924,17 @@ func (e *AgentEngine) streamThinkingToEventBus(
 		iteration+1, len(fullContent), len(toolCalls))
 
 	// Build response
-	return &types.ChatResponse{
+	response := &types.ChatResponse{
 		Content:      fullContent,
 		ToolCalls:    toolCalls,
 		FinishReason: "stop",
-	}, nil
+	}
+	if usage != nil {
+		response.Usage.PromptTokens = usage.PromptTokens
+		response.Usage.CompletionTokens = usage.CompletionTokens
+		response.Usage.TotalTokens = usage.TotalTokens
+	}
+	return response, nil
 }
 
 // streamFinalAnswerToEventBus streams the final answer generation through EventBus
@@ -830,7 +1007,7 @@ func (e *AgentEngine) streamFinalAnswerToEventBus(
 	answerID := generateEventID("answer")
 	logger.Debugf(ctx, "[Agent][FinalAnswer] AnswerID: %s", answerID)
 
-	fullAnswer, _, err := e.streamLLMToEventBus(
+	fullAnswer, _, _, err := e.streamLLMToEventBus(
 		ctx,
 		messages,
 		&chat.ChatOptions{Temperature: e.config.Temperature, Thinking: e.config.Thinking},
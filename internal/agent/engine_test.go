@@ -0,0 +1,358 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// loopingChat keeps calling the lookup tool until it is asked for the final answer (a call without tools)
+type loopingChat struct {
+	usage *types.TokenUsage
+}
+
+func (c *loopingChat) Chat(context.Context, []chat.Message, *chat.ChatOptions) (*types.ChatResponse, error) {
+	return &types.ChatResponse{Content: "final answer", FinishReason: "stop"}, nil
+}
+
+func (c *loopingChat) ChatStream(
+	_ context.Context, _ []chat.Message, opts *chat.ChatOptions,
+) (<-chan types.StreamResponse, error) {
+	stream := make(chan types.StreamResponse, 2)
+	if len(opts.Tools) == 0 {
+		stream <- types.StreamResponse{ResponseType: types.ResponseTypeAnswer, Content: "final answer"}
+	} else {
+		stream <- types.StreamResponse{
+			ResponseType: types.ResponseTypeToolCall,
+			ToolCalls: []types.LLMToolCall{{
+				ID:       "call-1",
+				Type:     "function",
+				Function: types.FunctionCall{Name: "lookup", Arguments: `{}`},
+			}},
+		}
+	}
+	stream <- types.StreamResponse{ResponseType: types.ResponseTypeAnswer, Done: true, Usage: c.usage}
+	close(stream)
+	return stream, nil
+}
+
+func (c *loopingChat) GetModelName() string { return "fake" }
+func (c *loopingChat) GetModelID() string   { return "fake" }
+
+// lookupTool answers immediately, or blocks until its context is cancelled when slow is set
+type lookupTool struct {
+	slow bool
+}
+
+func (t *lookupTool) Name() string                { return "lookup" }
+func (t *lookupTool) Description() string         { return "Looks things up" }
+func (t *lookupTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+
+func (t *lookupTool) Execute(ctx context.Context, _ json.RawMessage) (*types.ToolResult, error) {
+	if t.slow {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &types.ToolResult{Success: true, Output: "found it"}, nil
+}
+
+func TestExecuteLoopStopReasons(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     types.AgentConfig
+		usage      *types.TokenUsage
+		slowTool   bool
+		wantReason string
+		wantRounds int
+		wantTokens int
+	}{
+		{
+			name:       "iteration limit",
+			config:     types.AgentConfig{MaxIterations: 2},
+			wantReason: types.AgentStopReasonMaxIterations,
+			wantRounds: 2,
+		},
+		{
+			name:       "token budget uses provider usage",
+			config:     types.AgentConfig{MaxIterations: 5, MaxTotalTokens: 100},
+			usage:      &types.TokenUsage{PromptTokens: 120, CompletionTokens: 30, TotalTokens: 150},
+			wantReason: types.AgentStopReasonMaxTokens,
+			wantRounds: 1,
+			wantTokens: 150,
+		},
+		{
+			name:       "time budget interrupts a running tool",
+			config:     types.AgentConfig{MaxIterations: 5, MaxExecutionSeconds: 1},
+			slowTool:   true,
+			wantReason: types.AgentStopReasonTimeBudget,
+			wantRounds: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := tools.NewToolRegistry()
+			registry.RegisterTool(&lookupTool{slow: tt.slowTool})
+			config := tt.config
+			engine := NewAgentEngine(&config, &loopingChat{usage: tt.usage}, registry, nil, nil, nil, nil, "session", "")
+
+			start := time.Now()
+			state, err := engine.Execute(context.Background(), "session", "message", "question", nil)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("loop took %s, budget not enforced", elapsed)
+			}
+			if state.StopReason != tt.wantReason {
+				t.Errorf("StopReason = %q, want %q", state.StopReason, tt.wantReason)
+			}
+			if state.CurrentRound != tt.wantRounds {
+				t.Errorf("CurrentRound = %d, want %d", state.CurrentRound, tt.wantRounds)
+			}
+			if tt.wantTokens > 0 && state.TokensUsed != tt.wantTokens {
+				t.Errorf("TokensUsed = %d, want %d", state.TokensUsed, tt.wantTokens)
+			}
+			if !state.IsTruncated() || state.FinalAnswer != "final answer" {
+				t.Errorf("expected a forced final answer, got truncated=%v answer=%q",
+					state.IsTruncated(), state.FinalAnswer)
+			}
+		})
+	}
+}
+
+func TestRoundTokens(t *testing.T) {
+	messages := []chat.Message{{Role: "user", Content: "0123456789012345"}}
+	tests := []struct {
+		name     string
+		response *types.ChatResponse
+		want     int
+	}{
+		{
+			name:     "estimated without usage",
+			response: &types.ChatResponse{Content: "01234567"},
+			want:     (len("user")+16)/4 + 8/4,
+		},
+		{
+			name: "provider usage",
+			response: func() *types.ChatResponse {
+				r := &types.ChatResponse{Content: "01234567"}
+				r.Usage.TotalTokens = 42
+				return r
+			}(),
+			want: 42,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := roundTokens(messages, tt.response); got != tt.want {
+				t.Errorf("roundTokens = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// retrievalChat answers with a citation of the retrieved chunk, unless it is forced to call a tool
+type retrievalChat struct {
+	toolChoices []string
+}
+
+func (c *retrievalChat) Chat(context.Context, []chat.Message, *chat.ChatOptions) (*types.ChatResponse, error) {
+	return &types.ChatResponse{Content: "unused", FinishReason: "stop"}, nil
+}
+
+func (c *retrievalChat) ChatStream(
+	_ context.Context, _ []chat.Message, opts *chat.ChatOptions,
+) (<-chan types.StreamResponse, error) {
+	c.toolChoices = append(c.toolChoices, opts.ToolChoice)
+	stream := make(chan types.StreamResponse, 2)
+	if opts.ToolChoice != "" {
+		stream <- types.StreamResponse{
+			ResponseType: types.ResponseTypeToolCall,
+			ToolCalls: []types.LLMToolCall{{
+				ID:       "call-1",
+				Type:     "function",
+				Function: types.FunctionCall{Name: opts.ToolChoice, Arguments: `{"queries":["refund policy"]}`},
+			}},
+		}
+	} else {
+		stream <- types.StreamResponse{
+			ResponseType: types.ResponseTypeAnswer,
+			Content:      `Refunds take 30 days <kb doc="policy.pdf" chunk_id="chunk-1" />`,
+		}
+	}
+	stream <- types.StreamResponse{ResponseType: types.ResponseTypeAnswer, Done: true}
+	close(stream)
+	return stream, nil
+}
+
+func (c *retrievalChat) GetModelName() string { return "fake" }
+func (c *retrievalChat) GetModelID() string   { return "fake" }
+
+// searchTool stands in for knowledge_search and returns one citable chunk
+type searchTool struct {
+	calls int
+}
+
+func (t *searchTool) Name() string                { return "knowledge_search" }
+func (t *searchTool) Description() string         { return "Searches the knowledge bases" }
+func (t *searchTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+
+func (t *searchTool) Execute(context.Context, json.RawMessage) (*types.ToolResult, error) {
+	t.calls++
+	return &types.ToolResult{
+		Success: true,
+		Output:  "chunk-1: Refunds are issued within 30 days.",
+		Data: map[string]interface{}{
+			"knowledge_refs": []*types.SearchResult{{ID: "chunk-1", KnowledgeFilename: "policy.pdf"}},
+		},
+	}, nil
+}
+
+func TestExecuteForcedRetrieval(t *testing.T) {
+	tests := []struct {
+		name            string
+		retrievalMode   string
+		wantToolChoices []string
+		wantSearches    int
+		wantRefs        []string
+	}{
+		{
+			name:            "always forces a search in the first round",
+			retrievalMode:   types.RetrievalModeAlways,
+			wantToolChoices: []string{"knowledge_search", ""},
+			wantSearches:    1,
+			wantRefs:        []string{"chunk-1"},
+		},
+		{
+			name:            "tool only lets the model choose",
+			retrievalMode:   types.RetrievalModeToolOnly,
+			wantToolChoices: []string{""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search := &searchTool{}
+			registry := tools.NewToolRegistry()
+			registry.RegisterTool(search)
+			model := &retrievalChat{}
+			bus := event.NewEventBus()
+			var complete event.AgentCompleteData
+			bus.On(event.EventAgentComplete, func(_ context.Context, e event.Event) error {
+				complete, _ = e.Data.(event.AgentCompleteData)
+				return nil
+			})
+			config := types.AgentConfig{MaxIterations: 3, RetrievalMode: tt.retrievalMode}
+			engine := NewAgentEngine(&config, model, registry, bus, nil, nil, nil, "session", "")
+
+			state, err := engine.Execute(context.Background(), "session", "message", "refund policy", nil)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if !slices.Equal(model.toolChoices, tt.wantToolChoices) {
+				t.Errorf("tool choices = %q, want %q", model.toolChoices, tt.wantToolChoices)
+			}
+			if search.calls != tt.wantSearches {
+				t.Errorf("searched %d times, want %d", search.calls, tt.wantSearches)
+			}
+
+			var refs []string
+			for _, ref := range state.KnowledgeRefs {
+				refs = append(refs, ref.ID)
+			}
+			if !slices.Equal(refs, tt.wantRefs) {
+				t.Errorf("references = %v, want %v", refs, tt.wantRefs)
+			}
+			if len(complete.KnowledgeRefs) != len(tt.wantRefs) {
+				t.Errorf("completion event carries %d references, want %d", len(complete.KnowledgeRefs), len(tt.wantRefs))
+			}
+			// The cited chunk comes back as the answer's reference once retrieval was forced
+			if !strings.Contains(state.FinalAnswer, `chunk_id="chunk-1"`) {
+				t.Errorf("answer lost its citation: %q", state.FinalAnswer)
+			}
+		})
+	}
+}
+
+// progressTool reports two updates before returning
+type progressTool struct{}
+
+func (t *progressTool) Name() string                { return "query_db" }
+func (t *progressTool) Description() string         { return "Queries the database" }
+func (t *progressTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+
+func (t *progressTool) Execute(ctx context.Context, _ json.RawMessage) (*types.ToolResult, error) {
+	tools.ReportProgress(ctx, "Querying database...", "")
+	tools.ReportProgress(ctx, "Fetched 10 rows", "id,name")
+	return &types.ToolResult{Success: true, Output: "done"}, nil
+}
+
+func TestExecuteToolWithStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		tool         types.Tool
+		cancel       bool
+		wantStatuses []string
+		wantPartials []string
+		wantErr      bool
+	}{
+		{
+			name:         "progress is streamed in order",
+			tool:         &progressTool{},
+			wantStatuses: []string{"Running query_db...", "Querying database...", "Fetched 10 rows"},
+			wantPartials: []string{"", "", "id,name"},
+		},
+		{
+			name:         "cancelled tool is abandoned",
+			tool:         &lookupTool{slow: true},
+			cancel:       true,
+			wantStatuses: []string{"Running lookup..."},
+			wantPartials: []string{""},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := tools.NewToolRegistry()
+			registry.RegisterTool(tt.tool)
+			bus := event.NewEventBus()
+			var updates []event.AgentToolStatusData
+			bus.On(event.EventAgentToolStatus, func(_ context.Context, e event.Event) error {
+				updates = append(updates, e.Data.(event.AgentToolStatusData))
+				return nil
+			})
+			engine := NewAgentEngine(&types.AgentConfig{}, &loopingChat{}, registry, bus, nil, nil, nil, "session", "")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(50*time.Millisecond, cancel)
+			}
+			call := types.LLMToolCall{ID: "call-1", Function: types.FunctionCall{Name: tt.tool.Name(), Arguments: `{}`}}
+			result, err := engine.executeToolWithStatus(ctx, call, 0, "session")
+			if (err != nil) != tt.wantErr || result.Success == tt.wantErr {
+				t.Fatalf("result = %+v, err = %v, wantErr %v", result, err, tt.wantErr)
+			}
+
+			if len(updates) != len(tt.wantStatuses) {
+				t.Fatalf("got %d updates, want %d: %+v", len(updates), len(tt.wantStatuses), updates)
+			}
+			for i, update := range updates {
+				if update.Seq != i+1 || update.Status != tt.wantStatuses[i] || update.Partial != tt.wantPartials[i] {
+					t.Errorf("update %d = %+v, want seq %d status %q partial %q",
+						i, update, i+1, tt.wantStatuses[i], tt.wantPartials[i])
+				}
+				if update.ToolCallID != "call-1" {
+					t.Errorf("update %d belongs to %s", i, update.ToolCallID)
+				}
+			}
+		})
+	}
+}
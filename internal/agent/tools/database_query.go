@@ -99,6 +99,9 @@ type DatabaseQueryInput struct {
 	SQL string `json:"sql" jsonschema:"The SELECT SQL query to execute. DO NOT include tenant_id condition - it will be automatically added for security."`
 }
 
+// dbQueryProgressBatch is the number of rows reported to the client per partial result
+const dbQueryProgressBatch = 100
+
 // DatabaseQueryTool allows AI to query the database with auto-injected tenant_id for security
 type DatabaseQueryTool struct {
 	BaseTool
@@ -161,6 +164,7 @@ func (t *DatabaseQueryTool) Execute(ctx context.Context, args json.RawMessage) (
 
 	// Execute the query
 	logger.Infof(ctx, "[Tool][DatabaseQuery] Executing query against database...")
+	ReportProgress(ctx, "Querying database...", "")
 	rows, err := t.db.WithContext(ctx).Raw(securedSQL).Rows()
 	if err != nil {
 		logger.Errorf(ctx, "[Tool][DatabaseQuery] Query execution failed: %v", err)
@@ -212,6 +216,12 @@ func (t *DatabaseQueryTool) Execute(ctx context.Context, args json.RawMessage) (
 			}
 		}
 		results = append(results, rowMap)
+
+		// Stream each batch of rows as it arrives so the client can render partial results
+		if len(results)%dbQueryProgressBatch == 0 {
+			batch, _ := json.Marshal(results[len(results)-dbQueryProgressBatch:])
+			ReportProgress(ctx, fmt.Sprintf("Fetched %d rows...", len(results)), string(batch))
+		}
 	}
 
 	if err := rows.Err(); err != nil {
@@ -6,6 +6,7 @@ const (
 	ToolTodoWrite           = "todo_write"
 	ToolGrepChunks          = "grep_chunks"
 	ToolKnowledgeSearch     = "knowledge_search"
+	ToolRetrieval           = "retrieval"
 	ToolListKnowledgeChunks = "list_knowledge_chunks"
 	ToolQueryKnowledgeGraph = "query_knowledge_graph"
 	ToolGetDocumentInfo     = "get_document_info"
@@ -31,6 +32,7 @@ func AvailableToolDefinitions() []AvailableTool {
 		{Name: ToolTodoWrite, Label: "Create Plan", Description: "Create structured research plans"},
 		{Name: ToolGrepChunks, Label: "Keyword Search", Description: "Quickly locate documents and chunks containing specific keywords"},
 		{Name: ToolKnowledgeSearch, Label: "Semantic Search", Description: "Understand questions and find semantically relevant content"},
+		{Name: ToolRetrieval, Label: "Retrieval", Description: "Hybrid search over the agent's knowledge bases with top-k and filters, returning citable chunks"},
 		{Name: ToolListKnowledgeChunks, Label: "View Document Chunks", Description: "Get complete chunk content of documents"},
 		{Name: ToolQueryKnowledgeGraph, Label: "Query Knowledge Graph", Description: "Query relationships from knowledge graph"},
 		{Name: ToolGetDocumentInfo, Label: "Get Document Info", Description: "View document metadata"},
@@ -11,6 +11,7 @@ import (
 	"sync"
 
 	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/database"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/chat"
 	"github.com/Tencent/WeKnora/internal/models/rerank"
@@ -154,6 +155,8 @@ func NewKnowledgeSearchTool(
 // Execute executes the knowledge search tool
 func (t *KnowledgeSearchTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
 	logger.Infof(ctx, "[Tool][KnowledgeSearch] Execute started")
+	// Retrieval reads tolerate replication lag
+	ctx = database.WithReplicaReads(ctx)
 
 	// Parse args from json.RawMessage
 	var input KnowledgeSearchInput
@@ -284,9 +287,9 @@ func (t *KnowledgeSearchTool) Execute(ctx context.Context, args json.RawMessage)
 		topK, vectorThreshold, keywordThreshold, kbTypeMap)
 	logger.Infof(ctx, "[Tool][KnowledgeSearch] Concurrent search completed: %d raw results", len(allResults))
 
-	// Note: HybridSearch now uses RRF (Reciprocal Rank Fusion) which produces normalized scores
+	// Note: HybridSearch fuses vector and keyword scores (RRF by default, configurable per knowledge base)
 	// RRF scores are in range [0, ~0.033] (max when rank=1 on both sides: 2/(60+1))
-	// Threshold filtering is already done inside HybridSearch before RRF, so we skip it here
+	// Threshold filtering is already done inside HybridSearch before fusion, so we skip it here
 
 	// Deduplicate before reranking to reduce processing overhead
 	deduplicatedBeforeRerank := t.deduplicateResults(allResults)
@@ -367,8 +370,8 @@ func (t *KnowledgeSearchTool) Execute(ctx context.Context, args json.RawMessage)
 		}
 	}
 
-	// Note: minScore filter is skipped because HybridSearch now uses RRF scores
-	// RRF scores are in range [0, ~0.033], not [0, 1], so old thresholds don't apply
+	// Note: minScore filter is skipped because HybridSearch returns fused scores
+	// e.g. RRF scores are in range [0, ~0.033], not [0, 1], so old thresholds don't apply
 	// Threshold filtering is already done inside HybridSearch before RRF fusion
 
 	// Final deduplication after rerank (in case rerank changed scores/order but duplicates remain)
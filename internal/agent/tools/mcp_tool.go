@@ -75,30 +75,13 @@ func (t *MCPTool) Execute(ctx context.Context, args json.RawMessage) (*types.Too
 		}, err
 	}
 
-	// Get or create MCP client
-	client, err := t.mcpManager.GetOrCreateClient(t.service)
-	if err != nil {
-		logger.GetLogger(ctx).Errorf("Failed to get MCP client: %v", err)
-		return &types.ToolResult{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to connect to MCP service: %v", err),
-		}, nil
-	}
-
-	// For stdio transport, ensure connection is released after use
-	isStdio := t.service.TransportType == types.MCPTransportStdio
-	if isStdio {
-		defer func() {
-			if err := client.Disconnect(); err != nil {
-				logger.GetLogger(ctx).Warnf("Failed to disconnect stdio MCP client: %v", err)
-			} else {
-				logger.GetLogger(ctx).Infof("Stdio MCP client disconnected after tool execution")
-			}
-		}()
-	}
-
-	// Call the tool via MCP
-	result, err := client.CallTool(ctx, t.mcpTool.Name, input)
+	// Call the tool via MCP; tool calls may have side effects, so they go through the circuit breaker
+	// of the service without being retried
+	var result *mcp.CallToolResult
+	err := t.mcpManager.CallOnce(ctx, t.service, func(ctx context.Context, client mcp.MCPClient) (err error) {
+		result, err = client.CallTool(ctx, t.mcpTool.Name, input)
+		return err
+	})
 	if err != nil {
 		logger.GetLogger(ctx).Errorf("MCP tool call failed: %v", err)
 		return &types.ToolResult{
@@ -200,7 +183,7 @@ func RegisterMCPTools(
 	}
 
 	// Use provided context, but don't add timeout here
-	// The GetOrCreateClient has its own timeout for connection/init
+	// Connection/init has its own timeout
 	// For ListTools, we use a reasonable timeout to prevent hanging
 	// but longer than before since ListTools may need time for SSE communication
 	listToolsTimeout := 30 * time.Second
@@ -216,27 +199,14 @@ func RegisterMCPTools(
 			continue
 		}
 
-		// Get or create client (this may take time, but has its own timeout)
-		client, err := mcpManager.GetOrCreateClient(service)
-		if err != nil {
-			logger.GetLogger(ctx).Errorf("Failed to create MCP client for service %s: %v", service.Name, err)
-			continue
-		}
-
-		// For stdio transport, ensure connection is released after listing tools
-		isStdio := service.TransportType == types.MCPTransportStdio
-		if isStdio {
-			defer func() {
-				if err := client.Disconnect(); err != nil {
-					logger.GetLogger(ctx).Warnf("Failed to disconnect stdio MCP client after listing tools: %v", err)
-				}
-			}()
-		}
-
-		// List tools from the service with timeout
+		// List tools from the service with timeout, retrying transient failures
 		// Create a new context with timeout for this specific operation
 		listCtx, cancel := context.WithTimeout(ctx, listToolsTimeout)
-		tools, err := client.ListTools(listCtx)
+		var tools []*types.MCPTool
+		err := mcpManager.Call(listCtx, service, func(ctx context.Context, client mcp.MCPClient) (err error) {
+			tools, err = client.ListTools(ctx)
+			return err
+		})
 		cancel() // Cancel after ListTools completes
 
 		if err != nil {
@@ -272,12 +242,11 @@ func GetMCPToolsInfo(
 			continue
 		}
 
-		client, err := mcpManager.GetOrCreateClient(service)
-		if err != nil {
-			continue
-		}
-
-		tools, err := client.ListTools(infoCtx)
+		var tools []*types.MCPTool
+		err := mcpManager.Call(infoCtx, service, func(ctx context.Context, client mcp.MCPClient) (err error) {
+			tools, err = client.ListTools(ctx)
+			return err
+		})
 		if err != nil {
 			continue
 		}
@@ -0,0 +1,21 @@
+package tools
+
+import "context"
+
+// ProgressReporter receives intermediate status updates from a running tool
+type ProgressReporter func(status, partial string)
+
+type progressReporterKey struct{}
+
+// WithProgressReporter returns a context through which tools can report progress
+func WithProgressReporter(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressReporterKey{}, reporter)
+}
+
+// ReportProgress sends a status message and optional partial output to the client.
+// It is a no-op when the tool is not running inside an agent turn.
+func ReportProgress(ctx context.Context, status, partial string) {
+	if reporter, ok := ctx.Value(progressReporterKey{}).(ProgressReporter); ok && reporter != nil {
+		reporter(status, partial)
+	}
+}
@@ -0,0 +1,319 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/database"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+const (
+	// defaultRetrievalTopK is the number of chunks returned when top_k is not given
+	defaultRetrievalTopK = 5
+	// maxRetrievalTopK caps top_k to keep tool output within the context window
+	maxRetrievalTopK = 20
+)
+
+var retrievalTool = BaseTool{
+	name: ToolRetrieval,
+	description: `Hybrid (vector + keyword) retrieval over the knowledge bases this agent can access.
+
+## When to Use
+- Need passages from the knowledge base to answer or cite
+- Want precise control over how many chunks are returned
+- Need to narrow the search to specific knowledge bases, documents or tags
+
+## Parameters
+- query (required): what you are looking for, as a short question or statement
+- top_k (optional): number of chunks to return (default 5, max 20)
+- knowledge_base_ids (optional): restrict to these knowledge bases
+- knowledge_ids (optional): restrict to these documents
+- tag_ids (optional): restrict to chunks with these tags
+
+Filters can only narrow the agent's knowledge scope, never widen it.
+
+## Output
+Numbered chunks with chunk_id, document title and content.
+Cite a chunk in the answer with its number, e.g. [1].`,
+	schema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "query": {
+      "type": "string",
+      "description": "REQUIRED: Search query"
+    },
+    "top_k": {
+      "type": "integer",
+      "description": "Number of chunks to return (default 5, max 20)",
+      "minimum": 1,
+      "maximum": 20
+    },
+    "knowledge_base_ids": {
+      "type": "array",
+      "description": "Optional: knowledge base IDs to search",
+      "items": {"type": "string"}
+    },
+    "knowledge_ids": {
+      "type": "array",
+      "description": "Optional: document IDs to search",
+      "items": {"type": "string"}
+    },
+    "tag_ids": {
+      "type": "array",
+      "description": "Optional: tag IDs to filter by",
+      "items": {"type": "string"}
+    }
+  },
+  "required": ["query"]
+}`),
+}
+
+// RetrievalInput defines the input parameters for the retrieval tool
+type RetrievalInput struct {
+	Query            string   `json:"query"`
+	TopK             int      `json:"top_k,omitempty"`
+	KnowledgeBaseIDs []string `json:"knowledge_base_ids,omitempty"`
+	KnowledgeIDs     []string `json:"knowledge_ids,omitempty"`
+	TagIDs           []string `json:"tag_ids,omitempty"`
+}
+
+// RetrievalTool performs hybrid search over the agent's knowledge scope and returns citable chunks
+type RetrievalTool struct {
+	BaseTool
+	knowledgeBaseService interfaces.KnowledgeBaseService
+	searchTargets        types.SearchTargets // Agent's knowledge scope, same as direct chat
+	config               *config.Config
+}
+
+// NewRetrievalTool creates a new retrieval tool
+func NewRetrievalTool(
+	knowledgeBaseService interfaces.KnowledgeBaseService,
+	searchTargets types.SearchTargets,
+	cfg *config.Config,
+) *RetrievalTool {
+	return &RetrievalTool{
+		BaseTool:             retrievalTool,
+		knowledgeBaseService: knowledgeBaseService,
+		searchTargets:        searchTargets,
+		config:               cfg,
+	}
+}
+
+// Execute runs the hybrid search
+func (t *RetrievalTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	// Retrieval reads tolerate replication lag
+	ctx = database.WithReplicaReads(ctx)
+	var input RetrievalInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		logger.Errorf(ctx, "[Tool][Retrieval] Failed to parse args: %v", err)
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to parse args: %v", err),
+		}, err
+	}
+
+	query := strings.TrimSpace(input.Query)
+	if query == "" {
+		return &types.ToolResult{
+			Success: false,
+			Error:   "query parameter is required",
+		}, fmt.Errorf("no query provided")
+	}
+
+	topK := input.TopK
+	if topK <= 0 {
+		topK = defaultRetrievalTopK
+	}
+	if topK > maxRetrievalTopK {
+		topK = maxRetrievalTopK
+	}
+
+	targets := t.scopeTargets(input.KnowledgeBaseIDs, input.KnowledgeIDs)
+	if len(targets) == 0 {
+		return &types.ToolResult{
+			Success: false,
+			Error:   "no accessible knowledge base matches the requested filters",
+		}, fmt.Errorf("no search targets available")
+	}
+
+	vectorThreshold, keywordThreshold := t.thresholds(ctx)
+	logger.Infof(ctx, "[Tool][Retrieval] query=%q, top_k=%d, targets=%d, tags=%d",
+		query, topK, len(targets), len(input.TagIDs))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	results := make([]*types.SearchResult, 0)
+	for _, target := range targets {
+		st := target
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			params := types.SearchParams{
+				QueryText:        query,
+				MatchCount:       topK,
+				VectorThreshold:  vectorThreshold,
+				KeywordThreshold: keywordThreshold,
+				KnowledgeIDs:     st.KnowledgeIDs,
+				TagIDs:           input.TagIDs,
+			}
+			kbResults, err := t.knowledgeBaseService.HybridSearch(ctx, st.KnowledgeBaseID, params)
+			if err != nil {
+				logger.Warnf(ctx, "[Tool][Retrieval] Failed to search KB %s: %v", st.KnowledgeBaseID, err)
+				return
+			}
+			mu.Lock()
+			results = append(results, kbResults...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	results = topUniqueResults(results, topK)
+	return t.formatOutput(query, results), nil
+}
+
+// scopeTargets narrows the agent's search targets to the requested knowledge bases and documents
+// Requested IDs outside the agent's scope are ignored
+func (t *RetrievalTool) scopeTargets(kbIDs, knowledgeIDs []string) types.SearchTargets {
+	kbFilter := toSet(kbIDs)
+	docFilter := toSet(knowledgeIDs)
+
+	scoped := make(types.SearchTargets, 0, len(t.searchTargets))
+	for _, target := range t.searchTargets {
+		if len(kbFilter) > 0 && !kbFilter[target.KnowledgeBaseID] {
+			continue
+		}
+		st := *target
+		if len(docFilter) > 0 {
+			if st.Type == types.SearchTargetTypeKnowledge {
+				// Keep only documents that are both allowed and requested
+				allowed := make([]string, 0, len(st.KnowledgeIDs))
+				for _, id := range st.KnowledgeIDs {
+					if docFilter[id] {
+						allowed = append(allowed, id)
+					}
+				}
+				if len(allowed) == 0 {
+					continue
+				}
+				st.KnowledgeIDs = allowed
+			} else {
+				// Whole-KB targets: the search is still bounded by the KB, so requested documents are safe
+				st.Type = types.SearchTargetTypeKnowledge
+				st.KnowledgeIDs = knowledgeIDs
+			}
+		}
+		scoped = append(scoped, &st)
+	}
+	return scoped
+}
+
+// thresholds resolves search thresholds from the tenant conversation config, falling back to global config
+func (t *RetrievalTool) thresholds(ctx context.Context) (float64, float64) {
+	var vectorThreshold, keywordThreshold float64
+	if tenant, ok := ctx.Value(types.TenantInfoContextKey).(*types.Tenant); ok && tenant != nil &&
+		tenant.ConversationConfig != nil {
+		vectorThreshold = tenant.ConversationConfig.VectorThreshold
+		keywordThreshold = tenant.ConversationConfig.KeywordThreshold
+	}
+	if vectorThreshold == 0 && t.config != nil {
+		vectorThreshold = t.config.Conversation.VectorThreshold
+	}
+	if keywordThreshold == 0 && t.config != nil {
+		keywordThreshold = t.config.Conversation.KeywordThreshold
+	}
+	if vectorThreshold == 0 {
+		vectorThreshold = 0.6
+	}
+	if keywordThreshold == 0 {
+		keywordThreshold = 0.5
+	}
+	return vectorThreshold, keywordThreshold
+}
+
+// formatOutput renders numbered, citable chunks
+func (t *RetrievalTool) formatOutput(query string, results []*types.SearchResult) *types.ToolResult {
+	if len(results) == 0 {
+		return &types.ToolResult{
+			Success: true,
+			Output: "No relevant content found in the knowledge base.\n" +
+				"Do NOT answer from general knowledge; say that the knowledge base has no relevant information.",
+			Data: map[string]interface{}{
+				"query":   query,
+				"results": []interface{}{},
+				"count":   0,
+			},
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== Retrieved %d chunks ===\n\n", len(results))
+	formatted := make([]map[string]interface{}, 0, len(results))
+	for i, r := range results {
+		fmt.Fprintf(&b, "[%d] %s (chunk_id: %s, knowledge_id: %s)\n%s\n\n",
+			i+1, r.KnowledgeTitle, r.ID, r.KnowledgeID, r.Content)
+		formatted = append(formatted, map[string]interface{}{
+			"result_index":    i + 1,
+			"chunk_id":        r.ID,
+			"content":         r.Content,
+			"knowledge_id":    r.KnowledgeID,
+			"knowledge_title": r.KnowledgeTitle,
+			"match_type":      r.MatchType,
+			"score":           r.Score,
+		})
+	}
+	b.WriteString("Cite chunks by their number, e.g. [1].")
+
+	return &types.ToolResult{
+		Success: true,
+		Output:  b.String(),
+		Data: map[string]interface{}{
+			"query":          query,
+			"results":        formatted,
+			"count":          len(formatted),
+			"display_type":   "search_results",
+			"knowledge_refs": results,
+		},
+	}
+}
+
+// topUniqueResults deduplicates results by chunk ID (keeping the best score) and returns the top k
+func topUniqueResults(results []*types.SearchResult, k int) []*types.SearchResult {
+	best := make(map[string]*types.SearchResult, len(results))
+	for _, r := range results {
+		if existing, ok := best[r.ID]; !ok || r.Score > existing.Score {
+			best[r.ID] = r
+		}
+	}
+	unique := make([]*types.SearchResult, 0, len(best))
+	for _, r := range best {
+		unique = append(unique, r)
+	}
+	sort.Slice(unique, func(i, j int) bool {
+		if unique[i].Score != unique[j].Score {
+			return unique[i].Score > unique[j].Score
+		}
+		return unique[i].ID < unique[j].ID
+	})
+	if len(unique) > k {
+		unique = unique[:k]
+	}
+	return unique
+}
+
+// toSet converts a string slice into a lookup set
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
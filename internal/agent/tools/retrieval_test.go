@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeSearchService returns the given results per knowledge base and records the search params
+type fakeSearchService struct {
+	interfaces.KnowledgeBaseService
+	results map[string][]*types.SearchResult
+
+	mu       sync.Mutex
+	searched map[string]types.SearchParams
+}
+
+func (s *fakeSearchService) HybridSearch(
+	_ context.Context, id string, params types.SearchParams,
+) ([]*types.SearchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.searched == nil {
+		s.searched = make(map[string]types.SearchParams)
+	}
+	s.searched[id] = params
+	return s.results[id], nil
+}
+
+func TestRetrievalScopeTargets(t *testing.T) {
+	tool := NewRetrievalTool(nil, types.SearchTargets{
+		{Type: types.SearchTargetTypeKnowledgeBase, KnowledgeBaseID: "kb-1"},
+		{Type: types.SearchTargetTypeKnowledge, KnowledgeBaseID: "kb-2", KnowledgeIDs: []string{"doc-a", "doc-b"}},
+	}, nil)
+
+	tests := []struct {
+		name         string
+		kbIDs        []string
+		knowledgeIDs []string
+		want         map[string][]string
+	}{
+		{
+			name: "no filters keep the agent scope",
+			want: map[string][]string{"kb-1": nil, "kb-2": {"doc-a", "doc-b"}},
+		},
+		{
+			name:  "knowledge base filter",
+			kbIDs: []string{"kb-2"},
+			want:  map[string][]string{"kb-2": {"doc-a", "doc-b"}},
+		},
+		{
+			name:  "unknown knowledge base is ignored",
+			kbIDs: []string{"kb-other"},
+			want:  map[string][]string{},
+		},
+		{
+			name:         "document filter narrows scoped documents",
+			knowledgeIDs: []string{"doc-b", "doc-outside"},
+			want:         map[string][]string{"kb-1": {"doc-b", "doc-outside"}, "kb-2": {"doc-b"}},
+		},
+		{
+			name:         "document filter outside the scope drops the target",
+			kbIDs:        []string{"kb-2"},
+			knowledgeIDs: []string{"doc-outside"},
+			want:         map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string][]string)
+			for _, target := range tool.scopeTargets(tt.kbIDs, tt.knowledgeIDs) {
+				got[target.KnowledgeBaseID] = target.KnowledgeIDs
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("targets = %v, want %v", got, tt.want)
+			}
+			for kbID, ids := range tt.want {
+				if !slices.Equal(got[kbID], ids) {
+					t.Errorf("%s documents = %v, want %v", kbID, got[kbID], ids)
+				}
+			}
+		})
+	}
+	// Narrowing must not change the agent's own targets
+	if len(tool.searchTargets[1].KnowledgeIDs) != 2 || tool.searchTargets[0].Type != types.SearchTargetTypeKnowledgeBase {
+		t.Errorf("agent targets were modified: %+v %+v", tool.searchTargets[0], tool.searchTargets[1])
+	}
+}
+
+func TestTopUniqueResults(t *testing.T) {
+	results := []*types.SearchResult{
+		{ID: "c1", Score: 0.5},
+		{ID: "c2", Score: 0.9},
+		{ID: "c1", Score: 0.8},
+		{ID: "c3", Score: 0.8},
+	}
+	tests := []struct {
+		name string
+		k    int
+		want []string
+	}{
+		{name: "all unique by best score", k: 10, want: []string{"c2", "c1", "c3"}},
+		{name: "top k", k: 2, want: []string{"c2", "c1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range topUniqueResults(results, tt.k) {
+				got = append(got, r.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetrievalExecute(t *testing.T) {
+	manyResults := func(kbID string, n int) []*types.SearchResult {
+		results := make([]*types.SearchResult, n)
+		for i := range results {
+			results[i] = &types.SearchResult{ID: fmt.Sprintf("%s-%02d", kbID, i), Score: 1 - float64(i)/100}
+		}
+		return results
+	}
+	service := &fakeSearchService{results: map[string][]*types.SearchResult{
+		"kb-1": manyResults("kb-1", 30),
+		"kb-2": manyResults("kb-2", 3),
+	}}
+	tool := NewRetrievalTool(service, types.SearchTargets{
+		{Type: types.SearchTargetTypeKnowledgeBase, KnowledgeBaseID: "kb-1"},
+		{Type: types.SearchTargetTypeKnowledgeBase, KnowledgeBaseID: "kb-2"},
+	}, nil)
+
+	tests := []struct {
+		name     string
+		args     string
+		wantRefs int
+		wantErr  bool
+	}{
+		{name: "default top k", args: `{"query":"refunds"}`, wantRefs: defaultRetrievalTopK},
+		{name: "top k is capped", args: `{"query":"refunds","top_k":100}`, wantRefs: maxRetrievalTopK},
+		{name: "single knowledge base", args: `{"query":"refunds","top_k":10,"knowledge_base_ids":["kb-2"]}`, wantRefs: 3},
+		{name: "empty query", args: `{"query":"  "}`, wantErr: true},
+		{name: "filters outside the scope", args: `{"query":"refunds","knowledge_base_ids":["kb-9"]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tool.Execute(context.Background(), json.RawMessage(tt.args))
+			if tt.wantErr {
+				if err == nil || result.Success {
+					t.Fatalf("expected a failed result, got %+v, %v", result, err)
+				}
+				return
+			}
+			if err != nil || !result.Success {
+				t.Fatalf("Execute failed: %+v, %v", result, err)
+			}
+			refs, _ := result.Data["knowledge_refs"].([]*types.SearchResult)
+			if len(refs) != tt.wantRefs || result.Data["count"] != tt.wantRefs {
+				t.Errorf("returned %d refs (count %v), want %d", len(refs), result.Data["count"], tt.wantRefs)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// Defaults used when the options leave them unset
+const (
+	DefaultBufferSize    = 10000
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = 2 * time.Second
+)
+
+// Options configures the emitter
+type Options struct {
+	Enabled bool
+	// Sink is stdout, webhook or kafka (default stdout)
+	Sink string
+	// Events lists the event types to emit, all of them when empty
+	Events []string
+	// QueryMode is none, redacted or full (default none)
+	QueryMode string
+	// BufferSize is the number of events queued before new events are dropped
+	BufferSize int
+	// BatchSize is the largest number of events published at once
+	BatchSize int
+	// FlushInterval is the longest time an event waits for its batch to fill
+	FlushInterval time.Duration
+	// Timeout bounds each publish call of the webhook and kafka sinks
+	Timeout time.Duration
+
+	WebhookURL        string
+	WebhookSecret     string
+	KafkaRESTProxyURL string
+	KafkaTopic        string
+
+	// Stdout receives the events of the stdout sink (default os.Stdout)
+	Stdout io.Writer
+}
+
+// Emitter queues events and publishes them to the sink in the background
+type Emitter struct {
+	sink          Sink
+	events        map[EventType]bool
+	queryMode     string
+	batchSize     int
+	flushInterval time.Duration
+	timeout       time.Duration
+
+	queue     chan *Event
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closed    atomic.Bool
+	dropped   atomic.Int64
+}
+
+// New creates an emitter and starts its worker. It returns nil when analytics are disabled.
+func New(opts Options) (*Emitter, error) {
+	if !opts.Enabled {
+		return nil, nil
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	sink, err := newSink(opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithSink(opts, sink)
+}
+
+// NewWithSink creates an emitter publishing to the given sink and starts its worker
+func NewWithSink(opts Options, sink Sink) (*Emitter, error) {
+	e := &Emitter{
+		sink:          sink,
+		queryMode:     opts.QueryMode,
+		batchSize:     opts.BatchSize,
+		flushInterval: opts.FlushInterval,
+		timeout:       opts.Timeout,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	switch e.queryMode {
+	case "":
+		e.queryMode = QueryModeNone
+	case QueryModeNone, QueryModeRedacted, QueryModeFull:
+	default:
+		return nil, fmt.Errorf("unknown analytics query mode %q", opts.QueryMode)
+	}
+	if len(opts.Events) > 0 {
+		e.events = make(map[EventType]bool, len(opts.Events))
+		for _, name := range opts.Events {
+			eventType := EventType(name)
+			if !isKnownEventType(eventType) {
+				return nil, fmt.Errorf("unknown analytics event %q", name)
+			}
+			e.events[eventType] = true
+		}
+	}
+	bufferSize := opts.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	if e.batchSize <= 0 {
+		e.batchSize = DefaultBatchSize
+	}
+	if e.flushInterval <= 0 {
+		e.flushInterval = DefaultFlushInterval
+	}
+	if e.timeout <= 0 {
+		e.timeout = defaultSinkTimeout
+	}
+	e.queue = make(chan *Event, bufferSize)
+
+	go e.run()
+	return e, nil
+}
+
+func isKnownEventType(eventType EventType) bool {
+	for _, known := range AllEventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled reports whether events of the type are emitted
+func (e *Emitter) Enabled(eventType EventType) bool {
+	if e == nil {
+		return false
+	}
+	return e.events == nil || e.events[eventType]
+}
+
+// QueryMode returns how much of the query text is sent with the events
+func (e *Emitter) QueryMode() string {
+	if e == nil {
+		return QueryModeNone
+	}
+	return e.queryMode
+}
+
+// Emit queues the event without blocking. The timestamp, request ID and tenant ID are taken from the
+// context when not set. The event is dropped when its type is not selected or the queue is full.
+func (e *Emitter) Emit(ctx context.Context, event *Event) {
+	if event == nil || !e.Enabled(event.Type) || e.closed.Load() {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	if event.RequestID == "" {
+		event.RequestID, _ = ctx.Value(types.RequestIDContextKey).(string)
+	}
+	if event.TenantID == 0 {
+		event.TenantID, _ = ctx.Value(types.TenantIDContextKey).(uint64)
+	}
+	select {
+	case e.queue <- event:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of events dropped because the queue was full or the sink failed
+func (e *Emitter) Dropped() int64 {
+	if e == nil {
+		return 0
+	}
+	return e.dropped.Load()
+}
+
+// Close publishes the queued events and stops the worker
+func (e *Emitter) Close(ctx context.Context) error {
+	if e == nil {
+		return nil
+	}
+	e.closeOnce.Do(func() {
+		e.closed.Store(true)
+		close(e.stop)
+	})
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run batches the queued events until the emitter is closed
+func (e *Emitter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Event, 0, e.batchSize)
+	var reported int64
+	flush := func() {
+		if len(batch) > 0 {
+			e.publish(batch)
+			batch = make([]*Event, 0, e.batchSize)
+		}
+		if dropped := e.dropped.Load(); dropped > reported {
+			logger.Warnf(context.Background(), "[Analytics] %d events dropped so far", dropped)
+			reported = dropped
+		}
+	}
+
+	for {
+		select {
+		case event := <-e.queue:
+			batch = append(batch, event)
+			if len(batch) >= e.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.stop:
+			for {
+				select {
+				case event := <-e.queue:
+					batch = append(batch, event)
+					if len(batch) >= e.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// publish sends a batch to the sink; a failed batch is dropped
+func (e *Emitter) publish(batch []*Event) {
+	// Each attempt of the sink is bounded by the timeout, leave room for the webhook retries
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout*webhookAttempts)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			e.dropped.Add(int64(len(batch)))
+			logger.Errorf(ctx, "[Analytics] Sink panicked: %v", r)
+		}
+	}()
+	if err := e.sink.Publish(ctx, batch); err != nil {
+		e.dropped.Add(int64(len(batch)))
+		logger.Warnf(ctx, "[Analytics] Failed to publish %d events: %v", len(batch), err)
+	}
+}
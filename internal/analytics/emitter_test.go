@@ -0,0 +1,229 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+type recordingSink struct {
+	mu      sync.Mutex
+	batches [][]*Event
+	err     error
+	block   chan struct{}
+}
+
+func (s *recordingSink) Publish(_ context.Context, events []*Event) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, events)
+	return s.err
+}
+
+func (s *recordingSink) events() []*Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []*Event
+	for _, batch := range s.batches {
+		events = append(events, batch...)
+	}
+	return events
+}
+
+func TestEmitterPublishesSelectedEvents(t *testing.T) {
+	sink := &recordingSink{}
+	emitter, err := NewWithSink(Options{
+		Events:        []string{string(EventQueryReceived), string(EventAnswerGenerated)},
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	}, sink)
+	if err != nil {
+		t.Fatalf("NewWithSink() error = %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(10002))
+	ctx = context.WithValue(ctx, types.RequestIDContextKey, "req-1")
+	emitter.Emit(ctx, &Event{Type: EventQueryReceived})
+	emitter.Emit(ctx, &Event{Type: EventRetrievalDone})
+	emitter.Emit(ctx, &Event{Type: EventAnswerGenerated})
+	emitter.Emit(ctx, &Event{Type: EventAnswerGenerated, TenantID: 7})
+	if err := emitter.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	events := sink.events()
+	if len(events) != 3 {
+		t.Fatalf("published %d events, want 3", len(events))
+	}
+	if len(sink.batches) != 2 {
+		t.Errorf("published %d batches, want 2", len(sink.batches))
+	}
+	for _, event := range events {
+		if event.Type == EventRetrievalDone {
+			t.Error("retrieval_done published although it is not selected")
+		}
+		if event.Timestamp.IsZero() || event.RequestID != "req-1" {
+			t.Errorf("event = %+v, want the timestamp and request ID set", event)
+		}
+	}
+	if events[0].TenantID != 10002 || events[2].TenantID != 7 {
+		t.Errorf("tenant IDs = %d, %d, want 10002, 7", events[0].TenantID, events[2].TenantID)
+	}
+
+	// Events emitted after closing are ignored
+	emitter.Emit(ctx, &Event{Type: EventQueryReceived})
+}
+
+func TestEmitterNeverBlocks(t *testing.T) {
+	sink := &recordingSink{err: errors.New("sink down"), block: make(chan struct{})}
+	emitter, err := NewWithSink(Options{BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour}, sink)
+	if err != nil {
+		t.Fatalf("NewWithSink() error = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			emitter.Emit(context.Background(), &Event{Type: EventQueryReceived})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Emit() blocked on a stalled sink")
+	}
+
+	close(sink.block)
+	if err := emitter.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	// Every event was either dropped from the queue or failed in the sink
+	if got := emitter.Dropped(); got != 10 {
+		t.Errorf("Dropped() = %d, want 10", got)
+	}
+}
+
+func TestNilEmitter(t *testing.T) {
+	var emitter *Emitter
+	emitter.Emit(context.Background(), &Event{Type: EventQueryReceived})
+	if emitter.Enabled(EventQueryReceived) || emitter.QueryMode() != QueryModeNone {
+		t.Error("nil emitter should be disabled")
+	}
+	if err := emitter.Close(context.Background()); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	emitter, err := New(Options{Sink: SinkKafka})
+	if emitter != nil || err != nil {
+		t.Errorf("New() of disabled analytics = %v, %v, want nil, nil", emitter, err)
+	}
+}
+
+func TestNewValidatesOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "unknown sink", opts: Options{Enabled: true, Sink: "s3"}},
+		{name: "unknown event", opts: Options{Enabled: true, Events: []string{"page_viewed"}}},
+		{name: "unknown query mode", opts: Options{Enabled: true, QueryMode: "hashed"}},
+		{name: "webhook without secret", opts: Options{Enabled: true, Sink: SinkWebhook, WebhookURL: "https://example.com"}},
+		{name: "kafka without topic", opts: Options{Enabled: true, Sink: SinkKafka, KafkaRESTProxyURL: "http://kafka:8082"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opts); err == nil {
+				t.Error("New() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestEventWithQuery(t *testing.T) {
+	query := "Why was card 4111 1111 1111 1111 of jane.doe@example.com charged? Call +1 (555) 010-9999"
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{mode: QueryModeNone, want: ""},
+		{mode: QueryModeFull, want: query},
+		{mode: QueryModeRedacted, want: "Why was card [number] of [email] charged? Call [number]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			event := (&Event{}).WithQuery(query, tt.mode)
+			if event.Query != tt.want {
+				t.Errorf("Query = %q, want %q", event.Query, tt.want)
+			}
+			if event.QueryLength != len([]rune(query)) {
+				t.Errorf("QueryLength = %d, want %d", event.QueryLength, len([]rune(query)))
+			}
+		})
+	}
+
+	for query, want := range map[string]string{
+		"order 20261016 shipped":       "order [number] shipped",
+		"top 10 tips for 2026":         "top 10 tips for 2026",
+		"is 12-34 a valid (99) range?": "is 12-34 a valid (99) range?",
+	} {
+		if got := RedactQuery(query); got != want {
+			t.Errorf("RedactQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestStdoutSink(t *testing.T) {
+	var out bytes.Buffer
+	sink := &stdoutSink{out: &out}
+	if err := sink.Publish(context.Background(), []*Event{
+		{Type: EventQueryReceived, TenantID: 1},
+		{Type: EventAnswerGenerated, TenantID: 1, Usage: &types.TokenUsage{TotalTokens: 42}},
+	}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("wrote %d lines, want 2", len(lines))
+	}
+	var event Event
+	if err := json.Unmarshal(lines[1], &event); err != nil || event.Usage == nil || event.Usage.TotalTokens != 42 {
+		t.Errorf("second line = %s, err = %v", lines[1], err)
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	var got kafkaRecords
+	var path, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sink, err := newSink(Options{Sink: SinkKafka, KafkaRESTProxyURL: server.URL + "/", KafkaTopic: "weknora.analytics"})
+	if err != nil {
+		t.Fatalf("newSink() error = %v", err)
+	}
+	if err := sink.Publish(context.Background(), []*Event{{Type: EventQueryReceived, TenantID: 10002}}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if path != "/topics/weknora.analytics" || contentType != "application/vnd.kafka.json.v2+json" {
+		t.Errorf("request = %s %s", path, contentType)
+	}
+	if len(got.Records) != 1 || got.Records[0].Key != "10002" || got.Records[0].Value.Type != EventQueryReceived {
+		t.Errorf("records = %+v", got.Records)
+	}
+}
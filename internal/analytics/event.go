@@ -0,0 +1,140 @@
+// Package analytics streams structured events about question answering to an analytics sink.
+//
+// Events are queued in memory and published in batches by a background worker, so emitting never
+// blocks a request: when the queue is full or the sink fails, events are dropped and counted.
+// A nil *Emitter is valid and emits nothing.
+package analytics
+
+import (
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// EventType identifies an analytics event
+type EventType string
+
+// Analytics event types
+const (
+	// EventQueryReceived is emitted when a question is accepted for answering
+	EventQueryReceived EventType = "query_received"
+	// EventRetrievalDone is emitted when the retrieved chunks are merged, or when retrieval found nothing
+	EventRetrievalDone EventType = "retrieval_done"
+	// EventAnswerGenerated is emitted when the answer is complete
+	EventAnswerGenerated EventType = "answer_generated"
+	// EventFeedbackGiven is emitted when a user rates an answer
+	EventFeedbackGiven EventType = "feedback_given"
+)
+
+// AllEventTypes lists the event types in the order they occur
+var AllEventTypes = []EventType{EventQueryReceived, EventRetrievalDone, EventAnswerGenerated, EventFeedbackGiven}
+
+// Query modes deciding how much of the query text is sent with the events
+const (
+	// QueryModeNone sends only the length of the query
+	QueryModeNone = "none"
+	// QueryModeRedacted sends the query with e-mail addresses and long numbers masked, see RedactQuery
+	QueryModeRedacted = "redacted"
+	// QueryModeFull sends the query as is
+	QueryModeFull = "full"
+)
+
+// Event is a single analytics event. Fields that do not apply to the event type are left out.
+type Event struct {
+	Type      EventType `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	RequestID string    `json:"request_id,omitempty"`
+	TenantID  uint64    `json:"tenant_id"`
+	// KnowledgeBaseIDs are the knowledge bases searched for the answer
+	KnowledgeBaseIDs []string `json:"knowledge_base_ids,omitempty"`
+	SessionID        string   `json:"session_id,omitempty"`
+	MessageID        string   `json:"message_id,omitempty"`
+	// Query is the question, sent according to the query mode; QueryLength is always set with a query
+	Query       string `json:"query,omitempty"`
+	QueryLength int    `json:"query_length,omitempty"`
+	// LatencyMs is the time from receiving the query to the event
+	LatencyMs int64 `json:"latency_ms,omitempty"`
+	// TimingsMs is the time spent in each pipeline stage so far, e.g. chunk_search or chunk_rerank
+	TimingsMs map[string]int64 `json:"timings_ms,omitempty"`
+	// Usage is the token usage of the answer, when reported by the model
+	Usage *types.TokenUsage `json:"usage,omitempty"`
+	// Attributes holds the details of the event type, such as the number of retrieved chunks
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// NewChatEvent returns an event of the question answered with chatManage
+func NewChatEvent(eventType EventType, chatManage *types.ChatManage) *Event {
+	event := &Event{
+		Type:             eventType,
+		TenantID:         chatManage.TenantID,
+		KnowledgeBaseIDs: chatManage.SearchTargets.GetAllKnowledgeBaseIDs(),
+		SessionID:        chatManage.SessionID,
+		MessageID:        chatManage.MessageID,
+	}
+	if len(event.KnowledgeBaseIDs) == 0 {
+		event.KnowledgeBaseIDs = chatManage.KnowledgeBaseIDs
+	}
+	if !chatManage.ReceivedAt.IsZero() {
+		event.LatencyMs = time.Since(chatManage.ReceivedAt).Milliseconds()
+	}
+	return event
+}
+
+// WithQuery sets the length of the query and the query text the mode allows
+func (e *Event) WithQuery(query, mode string) *Event {
+	e.QueryLength = utf8.RuneCountInString(query)
+	switch mode {
+	case QueryModeFull:
+		e.Query = query
+	case QueryModeRedacted:
+		e.Query = RedactQuery(query)
+	default:
+		e.Query = ""
+	}
+	return e
+}
+
+// WithStageTimings copies the stage timings collected in the request
+func (e *Event) WithStageTimings(timings *types.StageTimings) *Event {
+	if timings == nil {
+		return e
+	}
+	stages := timings.Stages()
+	if len(stages) == 0 {
+		return e
+	}
+	e.TimingsMs = make(map[string]int64, len(stages))
+	for _, stage := range stages {
+		e.TimingsMs[stage.Stage] = stage.Duration.Milliseconds()
+	}
+	return e
+}
+
+// minRedactedDigits is the number of digits from which a number, such as a phone, card or account
+// number, is masked in redacted queries
+const minRedactedDigits = 6
+
+var (
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	numberPattern = regexp.MustCompile(`\+?\(?\d[\d\s\-().]*\d`)
+)
+
+// RedactQuery masks e-mail addresses and numbers of six digits or more, which may be separated by
+// spaces, dashes or parentheses, in a query
+func RedactQuery(query string) string {
+	query = emailPattern.ReplaceAllString(query, "[email]")
+	return numberPattern.ReplaceAllStringFunc(query, func(number string) string {
+		digits := 0
+		for _, r := range number {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits < minRedactedDigits {
+			return number
+		}
+		return "[number]"
+	})
+}
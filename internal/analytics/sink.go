@@ -0,0 +1,149 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+)
+
+// Sink names accepted in the configuration
+const (
+	SinkStdout  = "stdout"
+	SinkWebhook = "webhook"
+	SinkKafka   = "kafka"
+)
+
+// defaultSinkTimeout bounds each publish call when no timeout is configured
+const defaultSinkTimeout = 5 * time.Second
+
+// webhookAttempts is the number of deliveries tried for each batch posted to the webhook
+const webhookAttempts = 3
+
+// Sink publishes batches of events
+type Sink interface {
+	Publish(ctx context.Context, events []*Event) error
+}
+
+// stdoutSink writes each event as a line of JSON
+type stdoutSink struct {
+	out io.Writer
+}
+
+// Publish writes the events, one JSON object per line
+func (s *stdoutSink) Publish(_ context.Context, events []*Event) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	_, err := s.out.Write(buf.Bytes())
+	return err
+}
+
+// webhookSink posts each batch as a JSON array, signed like the task webhooks
+type webhookSink struct {
+	client *http.Client
+	url    string
+	secret string
+}
+
+// Publish posts the events, retrying failed deliveries
+func (s *webhookSink) Publish(ctx context.Context, events []*Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	return secutils.DeliverWebhook(ctx, s.client, s.url, s.secret, body, webhookAttempts)
+}
+
+// kafkaSink produces the events to a Kafka topic through a Kafka REST Proxy (v2 API)
+type kafkaSink struct {
+	client   *http.Client
+	endpoint string
+}
+
+// kafkaRecords is the produce request of the Kafka REST Proxy
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key,omitempty"`
+	Value *Event `json:"value"`
+}
+
+// Publish produces one record per event, keyed by tenant so that the events of a tenant stay ordered
+func (s *kafkaSink) Publish(ctx context.Context, events []*Event) error {
+	records := kafkaRecords{Records: make([]kafkaRecord, 0, len(events))}
+	for _, event := range events {
+		records.Records = append(records.Records, kafkaRecord{
+			Key:   strconv.FormatUint(event.TenantID, 10),
+			Value: event,
+		})
+	}
+	body, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("kafka rest proxy returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newSink creates the sink named in the options
+func newSink(opts Options) (Sink, error) {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultSinkTimeout
+	}
+	switch opts.Sink {
+	case "", SinkStdout:
+		return &stdoutSink{out: opts.Stdout}, nil
+	case SinkWebhook:
+		if opts.WebhookURL == "" {
+			return nil, fmt.Errorf("analytics webhook sink requires a url")
+		}
+		if opts.WebhookSecret == "" {
+			return nil, fmt.Errorf("analytics webhook sink requires a secret to sign the events")
+		}
+		return &webhookSink{
+			client: &http.Client{Timeout: timeout},
+			url:    opts.WebhookURL,
+			secret: opts.WebhookSecret,
+		}, nil
+	case SinkKafka:
+		if opts.KafkaRESTProxyURL == "" || opts.KafkaTopic == "" {
+			return nil, fmt.Errorf("analytics kafka sink requires rest_proxy_url and topic")
+		}
+		return &kafkaSink{
+			client:   &http.Client{Timeout: timeout},
+			endpoint: strings.TrimRight(opts.KafkaRESTProxyURL, "/") + "/topics/" + url.PathEscape(opts.KafkaTopic),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown analytics sink %q", opts.Sink)
+	}
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// agentRunRepository implements the AgentRunRepository interface
+type agentRunRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentRunRepository creates a new agent run repository
+func NewAgentRunRepository(db *gorm.DB) interfaces.AgentRunRepository {
+	return &agentRunRepository{db: db}
+}
+
+// CreateRun stores an agent execution trace
+func (r *agentRunRepository) CreateRun(ctx context.Context, run *types.AgentRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// ListRunsByMessageID lists the original run and all replays of a message, oldest first
+func (r *agentRunRepository) ListRunsByMessageID(
+	ctx context.Context, tenantID uint64, messageID string,
+) ([]*types.AgentRun, error) {
+	var runs []*types.AgentRun
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND message_id = ?", tenantID, messageID).
+		Order("created_at ASC").
+		Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// apiKeyRepository implements the APIKeyRepository interface
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *gorm.DB) interfaces.APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// CreateKey stores an API key
+func (r *apiKeyRepository) CreateKey(ctx context.Context, key *types.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+// GetKeyByHash gets the API key with the hash, nil when there is none
+func (r *apiKeyRepository) GetKeyByHash(ctx context.Context, keyHash string) (*types.APIKey, error) {
+	var key types.APIKey
+	if err := r.db.WithContext(ctx).Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetKeyByID gets an API key of a tenant, nil when there is none
+func (r *apiKeyRepository) GetKeyByID(ctx context.Context, tenantID uint64, id string) (*types.APIKey, error) {
+	var key types.APIKey
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND id = ?", tenantID, id).
+		First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListKeys lists the API keys of a tenant, newest first
+func (r *apiKeyRepository) ListKeys(ctx context.Context, tenantID uint64) ([]*types.APIKey, error) {
+	var keys []*types.APIKey
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("created_at DESC").
+		Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CountActiveKeys counts the API keys of a tenant that are neither revoked nor expired
+func (r *apiKeyRepository) CountActiveKeys(ctx context.Context, tenantID uint64, now time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&types.APIKey{}).
+		Where("tenant_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", tenantID, now).
+		Count(&count).Error
+	return count, err
+}
+
+// RevokeKey marks an API key of a tenant as revoked
+func (r *apiKeyRepository) RevokeKey(ctx context.Context, tenantID uint64, id string, revokedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&types.APIKey{}).
+		Where("tenant_id = ? AND id = ? AND revoked_at IS NULL", tenantID, id).
+		Updates(map[string]interface{}{"revoked_at": revokedAt, "updated_at": revokedAt}).Error
+}
+
+// TouchKey records the last use of an API key
+func (r *apiKeyRepository) TouchKey(ctx context.Context, id string, usedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&types.APIKey{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", usedAt).Error
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// auditLogRepository implements the AuditLogRepository interface
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository creates a new audit log repository
+func NewAuditLogRepository(db *gorm.DB) interfaces.AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// CreateLogs stores a batch of audit log entries
+func (r *auditLogRepository) CreateLogs(ctx context.Context, logs []*types.AuditLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(logs, 100).Error
+}
+
+// ListLogs lists the entries of a tenant matching the filter, newest first, with their total count
+func (r *auditLogRepository) ListLogs(
+	ctx context.Context, tenantID uint64, filter *types.AuditLogFilter, page *types.Pagination,
+) ([]*types.AuditLog, int64, error) {
+	query := r.db.WithContext(ctx).Model(&types.AuditLog{}).Where("tenant_id = ?", tenantID)
+	if filter != nil {
+		if filter.ResourceType != "" {
+			query = query.Where("resource_type = ?", filter.ResourceType)
+		}
+		if filter.UserID != "" {
+			query = query.Where("user_id = ?", filter.UserID)
+		}
+		if filter.StartTime != nil {
+			query = query.Where("created_at >= ?", *filter.StartTime)
+		}
+		if filter.EndTime != nil {
+			query = query.Where("created_at < ?", *filter.EndTime)
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []*types.AuditLog
+	if err := query.Order("created_at DESC").
+		Offset(page.Offset()).
+		Limit(page.Limit()).
+		Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+// GetLog gets an entry of a tenant, nil when there is none
+func (r *auditLogRepository) GetLog(ctx context.Context, tenantID uint64, id string) (*types.AuditLog, error) {
+	var log types.AuditLog
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND id = ?", tenantID, id).
+		First(&log).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &log, nil
+}
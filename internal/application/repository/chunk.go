@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/common"
 	"github.com/Tencent/WeKnora/internal/types"
@@ -24,8 +25,20 @@ func NewChunkRepository(db *gorm.DB) interfaces.ChunkRepository {
 
 // CreateChunks creates multiple chunks in batches
 func (r *chunkRepository) CreateChunks(ctx context.Context, chunks []*types.Chunk) error {
+	ids := make([]string, 0, len(chunks))
 	for _, chunk := range chunks {
 		chunk.Content = common.CleanInvalidUTF8(chunk.Content)
+		ids = append(ids, chunk.ID)
+	}
+	// Chunk IDs are deterministic, so re-processed content reuses the IDs of soft-deleted chunks;
+	// purge those rows first to free the primary keys
+	for start := 0; start < len(ids); start += 500 {
+		end := min(start+500, len(ids))
+		if err := r.db.WithContext(ctx).Unscoped().
+			Where("id IN ? AND deleted_at IS NOT NULL", ids[start:end]).
+			Delete(&types.Chunk{}).Error; err != nil {
+			return err
+		}
 	}
 	// Use Select("*") to ensure all fields including zero values (IsEnabled=false, Flags=0)
 	// are inserted, bypassing GORM's default value behavior for zero values
@@ -416,6 +429,23 @@ func (r *chunkRepository) CountChunksByKnowledgeBaseID(
 	return count, err
 }
 
+// CountEnabledChunks counts the enabled chunks of a knowledge base, optionally limited to some knowledge
+func (r *chunkRepository) CountEnabledChunks(
+	ctx context.Context,
+	tenantID uint64,
+	kbID string,
+	knowledgeIDs []string,
+) (int64, error) {
+	var count int64
+	query := r.db.WithContext(ctx).Model(&types.Chunk{}).
+		Where("tenant_id = ? AND knowledge_base_id = ? AND is_enabled = ?", tenantID, kbID, true)
+	if len(knowledgeIDs) > 0 {
+		query = query.Where("knowledge_id IN ?", knowledgeIDs)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
+
 // DeleteUnindexedChunks by knowledge id and chunk index range
 func (r *chunkRepository) DeleteUnindexedChunks(
 	ctx context.Context,
@@ -534,7 +564,7 @@ func (r *chunkRepository) ListAllFAQChunksForExport(
 	for {
 		var batchChunks []*types.Chunk
 		if err := r.db.WithContext(ctx).
-			Select("id, metadata, tag_id, is_enabled, flags").
+			Select("id, seq_id, metadata, tag_id, is_enabled, flags").
 			Where("tenant_id = ? AND knowledge_id = ? AND chunk_type = ? AND status = ?",
 				tenantID, knowledgeID, types.ChunkTypeFAQ, types.ChunkStatusIndexed).
 			Order("created_at ASC").
@@ -562,6 +592,37 @@ func (r *chunkRepository) ListAllFAQChunksForExport(
 	return allChunks, nil
 }
 
+// ListFAQChunksForExportAfter lists up to limit FAQ chunks for export whose seq_id is greater than afterSeqID,
+// ordered by seq_id, so that exports can page through large sets without offsets.
+func (r *chunkRepository) ListFAQChunksForExportAfter(
+	ctx context.Context,
+	tenantID uint64,
+	knowledgeID string,
+	filter *types.FAQExportFilter,
+	afterSeqID int64,
+	limit int,
+) ([]*types.Chunk, error) {
+	query := r.db.WithContext(ctx).
+		Select("id, seq_id, knowledge_id, knowledge_base_id, chunk_type, metadata, tag_id, is_enabled, flags, "+
+			"created_at, updated_at").
+		Where("tenant_id = ? AND knowledge_id = ? AND chunk_type = ? AND status = ? AND seq_id > ?",
+			tenantID, knowledgeID, types.ChunkTypeFAQ, types.ChunkStatusIndexed, afterSeqID)
+	if filter != nil {
+		if filter.TagID != "" {
+			query = query.Where("tag_id = ?", filter.TagID)
+		}
+		if filter.Enabled != nil {
+			query = query.Where("is_enabled = ?", *filter.Enabled)
+		}
+	}
+
+	var chunks []*types.Chunk
+	if err := query.Order("seq_id ASC").Limit(limit).Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
 // UpdateChunkFlagsBatch updates flags for multiple chunks in batch using SQL CASE expressions.
 // This is more efficient than updating chunks one by one.
 // setFlags: map of chunk ID to flags to set (OR operation)
@@ -728,6 +789,32 @@ func (r *chunkRepository) UpdateChunkFieldsByTagID(
 	return affectedIDs, nil
 }
 
+// UpdateChunkEnabledByKnowledgeIDs sets is_enabled on all chunks of the given knowledge items.
+// Returns the IDs of chunks whose state changed for syncing with retriever engines.
+func (r *chunkRepository) UpdateChunkEnabledByKnowledgeIDs(
+	ctx context.Context,
+	tenantID uint64,
+	knowledgeIDs []string,
+	isEnabled bool,
+) ([]string, error) {
+	var affectedIDs []string
+	if err := r.db.WithContext(ctx).Model(&types.Chunk{}).
+		Where("tenant_id = ? AND knowledge_id IN ? AND is_enabled != ?", tenantID, knowledgeIDs, isEnabled).
+		Pluck("id", &affectedIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(affectedIDs) == 0 {
+		return nil, nil
+	}
+
+	if err := r.db.WithContext(ctx).Model(&types.Chunk{}).
+		Where("tenant_id = ? AND id IN ?", tenantID, affectedIDs).
+		Updates(map[string]interface{}{"is_enabled": isEnabled, "updated_at": time.Now()}).Error; err != nil {
+		return nil, err
+	}
+	return affectedIDs, nil
+}
+
 // FAQChunkDiff compares FAQ chunks between two knowledge bases and returns the differences.
 // Returns: chunksToAdd (IDs of chunks in src whose content_hash is not in dst),
 //
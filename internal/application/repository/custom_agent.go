@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
@@ -60,3 +61,101 @@ func (r *customAgentRepository) UpdateAgent(ctx context.Context, agent *types.Cu
 func (r *customAgentRepository) DeleteAgent(ctx context.Context, id string, tenantID uint64) error {
 	return r.db.WithContext(ctx).Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&types.CustomAgent{}).Error
 }
+
+// ErrCustomAgentVersionNotFound is returned when an agent version is not found
+var ErrCustomAgentVersionNotFound = errors.New("custom agent version not found")
+
+// CreateVersion creates a new agent version record
+func (r *customAgentRepository) CreateVersion(ctx context.Context, version *types.CustomAgentVersion) error {
+	return r.db.WithContext(ctx).Create(version).Error
+}
+
+// GetVersion gets a specific version of an agent
+func (r *customAgentRepository) GetVersion(
+	ctx context.Context, agentID string, tenantID uint64, version int,
+) (*types.CustomAgentVersion, error) {
+	var v types.CustomAgentVersion
+	if err := r.db.WithContext(ctx).
+		Where("agent_id = ? AND tenant_id = ? AND version = ?", agentID, tenantID, version).
+		First(&v).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomAgentVersionNotFound
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListVersions lists all versions of an agent, newest first
+func (r *customAgentRepository) ListVersions(
+	ctx context.Context, agentID string, tenantID uint64,
+) ([]*types.CustomAgentVersion, error) {
+	var versions []*types.CustomAgentVersion
+	if err := r.db.WithContext(ctx).
+		Where("agent_id = ? AND tenant_id = ?", agentID, tenantID).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// GetLatestVersionNumber returns the highest version number of an agent (0 if it has no versions)
+func (r *customAgentRepository) GetLatestVersionNumber(ctx context.Context, agentID string, tenantID uint64) (int, error) {
+	var latest int
+	if err := r.db.WithContext(ctx).Model(&types.CustomAgentVersion{}).
+		Where("agent_id = ? AND tenant_id = ?", agentID, tenantID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&latest).Error; err != nil {
+		return 0, err
+	}
+	return latest, nil
+}
+
+// PublishVersion promotes a version to live in a single transaction:
+// the previously published version is retired and the agent record takes the new version's snapshot
+func (r *customAgentRepository) PublishVersion(
+	ctx context.Context, agentID string, tenantID uint64, version int,
+) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var target types.CustomAgentVersion
+		if err := tx.Where("agent_id = ? AND tenant_id = ? AND version = ?", agentID, tenantID, version).
+			First(&target).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCustomAgentVersionNotFound
+			}
+			return err
+		}
+
+		if err := tx.Model(&types.CustomAgentVersion{}).
+			Where("agent_id = ? AND tenant_id = ? AND status = ?", agentID, tenantID, types.AgentVersionStatusPublished).
+			Update("status", types.AgentVersionStatusRetired).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		result := tx.Model(&types.CustomAgentVersion{}).
+			Where("agent_id = ? AND tenant_id = ? AND version = ?", agentID, tenantID, version).
+			Updates(map[string]interface{}{
+				"status":       types.AgentVersionStatusPublished,
+				"published_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCustomAgentVersionNotFound
+		}
+
+		return tx.Model(&types.CustomAgent{}).
+			Where("id = ? AND tenant_id = ?", agentID, tenantID).
+			Updates(map[string]interface{}{
+				"name":              target.Name,
+				"description":       target.Description,
+				"avatar":            target.Avatar,
+				"config":            target.Config,
+				"published_version": version,
+				"updated_at":        now,
+			}).Error
+	})
+}
@@ -3,17 +3,21 @@ package repository
 import (
 	"context"
 	"errors"
+	"net/http"
 	"strings"
+	"time"
 
+	werrors "github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	"gorm.io/gorm"
 )
 
-var ErrKnowledgeNotFound = errors.New("knowledge not found")
+var ErrKnowledgeNotFound = werrors.NewSentinel(werrors.CodeKnowledgeNotFound, http.StatusNotFound, "knowledge not found")
 
-// omitFieldsOnUpdate defines fields to omit when updating knowledge
-var omitFieldsOnUpdate = []string{"DeletedAt"}
+// omitFieldsOnUpdate defines fields to omit when updating knowledge.
+// IsEnabled is only changed through UpdateKnowledgeEnabled so that processing does not overwrite it.
+var omitFieldsOnUpdate = []string{"DeletedAt", "IsEnabled"}
 
 // knowledgeRepository implements knowledge base and knowledge repository interface
 type knowledgeRepository struct {
@@ -59,6 +63,18 @@ func (r *knowledgeRepository) ListKnowledgeByKnowledgeBaseID(
 	return knowledges, nil
 }
 
+// ListAllKnowledgeByKnowledgeBaseID lists all knowledge in a knowledge base, including soft-deleted knowledge
+func (r *knowledgeRepository) ListAllKnowledgeByKnowledgeBaseID(
+	ctx context.Context, tenantID uint64, kbID string,
+) ([]*types.Knowledge, error) {
+	var knowledges []*types.Knowledge
+	if err := r.db.WithContext(ctx).Unscoped().Where("tenant_id = ? AND knowledge_base_id = ?", tenantID, kbID).
+		Order("created_at DESC").Find(&knowledges).Error; err != nil {
+		return nil, err
+	}
+	return knowledges, nil
+}
+
 // ListPagedKnowledgeByKnowledgeBaseID lists all knowledge in a knowledge base with pagination
 func (r *knowledgeRepository) ListPagedKnowledgeByKnowledgeBaseID(
 	ctx context.Context,
@@ -68,6 +84,7 @@ func (r *knowledgeRepository) ListPagedKnowledgeByKnowledgeBaseID(
 	tagID string,
 	keyword string,
 	fileType string,
+	isEnabled *bool,
 ) ([]*types.Knowledge, int64, error) {
 	var knowledges []*types.Knowledge
 	var total int64
@@ -80,6 +97,9 @@ func (r *knowledgeRepository) ListPagedKnowledgeByKnowledgeBaseID(
 	if keyword != "" {
 		query = query.Where("file_name LIKE ?", "%"+keyword+"%")
 	}
+	if isEnabled != nil {
+		query = query.Where("is_enabled = ?", *isEnabled)
+	}
 	if fileType != "" {
 		if fileType == "manual" {
 			query = query.Where("type = ?", "manual")
@@ -104,6 +124,9 @@ func (r *knowledgeRepository) ListPagedKnowledgeByKnowledgeBaseID(
 	if keyword != "" {
 		dataQuery = dataQuery.Where("file_name LIKE ?", "%"+keyword+"%")
 	}
+	if isEnabled != nil {
+		dataQuery = dataQuery.Where("is_enabled = ?", *isEnabled)
+	}
 	if fileType != "" {
 		if fileType == "manual" {
 			dataQuery = dataQuery.Where("type = ?", "manual")
@@ -261,6 +284,18 @@ func (r *knowledgeRepository) UpdateKnowledgeColumn(
 	return err
 }
 
+// UpdateKnowledgeEnabled sets the retrieval enable flag of knowledge items
+func (r *knowledgeRepository) UpdateKnowledgeEnabled(
+	ctx context.Context,
+	tenantID uint64,
+	ids []string,
+	isEnabled bool,
+) error {
+	return r.db.WithContext(ctx).Model(&types.Knowledge{}).
+		Where("tenant_id = ? AND id IN ?", tenantID, ids).
+		Updates(map[string]interface{}{"is_enabled": isEnabled, "updated_at": time.Now()}).Error
+}
+
 // CountKnowledgeByKnowledgeBaseID counts the number of knowledge items in a knowledge base
 func (r *knowledgeRepository) CountKnowledgeByKnowledgeBaseID(
 	ctx context.Context,
@@ -297,6 +332,34 @@ func (r *knowledgeRepository) CountKnowledgeByStatus(
 	return count, nil
 }
 
+// ListFailedKnowledge lists knowledge in a knowledge base whose processing failed, oldest first
+func (r *knowledgeRepository) ListFailedKnowledge(
+	ctx context.Context,
+	tenantID uint64,
+	kbID string,
+	filter *types.KnowledgeFailureFilter,
+) ([]*types.Knowledge, error) {
+	query := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND knowledge_base_id = ? AND parse_status = ?", tenantID, kbID, types.ParseStatusFailed)
+	if filter != nil {
+		if filter.ErrorContains != "" {
+			query = query.Where("LOWER(error_message) LIKE ?", "%"+strings.ToLower(filter.ErrorContains)+"%")
+		}
+		if filter.FailedAfter != nil {
+			query = query.Where("updated_at >= ?", *filter.FailedAfter)
+		}
+		if filter.FailedBefore != nil {
+			query = query.Where("updated_at < ?", *filter.FailedBefore)
+		}
+	}
+
+	var knowledges []*types.Knowledge
+	if err := query.Order("updated_at ASC").Find(&knowledges).Error; err != nil {
+		return nil, err
+	}
+	return knowledges, nil
+}
+
 // SearchKnowledge searches knowledge items by keyword across the tenant
 // If keyword is empty, returns recent files
 // Only returns documents from document-type knowledge bases (excludes FAQ)
@@ -414,3 +477,25 @@ func (r *knowledgeRepository) ListIDsByTagID(
 		Pluck("id", &ids).Error
 	return ids, err
 }
+
+// ListIDsByMetadata returns all knowledge IDs whose metadata has all the given values
+func (r *knowledgeRepository) ListIDsByMetadata(
+	ctx context.Context,
+	tenantID uint64,
+	kbID string,
+	filter map[string]string,
+) ([]string, error) {
+	var ids []string
+	db := r.db.WithContext(ctx).Model(&types.Knowledge{}).
+		Where("tenant_id = ? AND knowledge_base_id = ?", tenantID, kbID)
+	isPostgres := db.Dialector.Name() == "postgres"
+	for key, value := range filter {
+		if isPostgres {
+			db = db.Where("metadata->>? = ?", key, value)
+		} else {
+			db = db.Where("JSON_UNQUOTE(JSON_EXTRACT(metadata, ?)) = ?", "$."+key, value)
+		}
+	}
+	err := db.Pluck("id", &ids).Error
+	return ids, err
+}
@@ -3,13 +3,16 @@ package repository
 import (
 	"context"
 	"errors"
+	"net/http"
 
+	werrors "github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	"gorm.io/gorm"
 )
 
-var ErrKnowledgeBaseNotFound = errors.New("knowledge base not found")
+var ErrKnowledgeBaseNotFound = werrors.NewSentinel(werrors.CodeKnowledgeBaseNotFound,
+	http.StatusNotFound, "knowledge base not found")
 
 // knowledgeBaseRepository implements the KnowledgeBaseRepository interface
 type knowledgeBaseRepository struct {
@@ -71,6 +74,18 @@ func (r *knowledgeBaseRepository) ListKnowledgeBasesByTenantID(
 	return kbs, nil
 }
 
+// ListAllKnowledgeBasesByTenantID lists all knowledge bases of a tenant, including temporary and soft-deleted ones
+func (r *knowledgeBaseRepository) ListAllKnowledgeBasesByTenantID(
+	ctx context.Context, tenantID uint64,
+) ([]*types.KnowledgeBase, error) {
+	var kbs []*types.KnowledgeBase
+	if err := r.db.WithContext(ctx).Unscoped().Where("tenant_id = ?", tenantID).
+		Order("created_at DESC").Find(&kbs).Error; err != nil {
+		return nil, err
+	}
+	return kbs, nil
+}
+
 // UpdateKnowledgeBase updates a knowledge base
 func (r *knowledgeBaseRepository) UpdateKnowledgeBase(ctx context.Context, kb *types.KnowledgeBase) error {
 	return r.db.WithContext(ctx).Save(kb).Error
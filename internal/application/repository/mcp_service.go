@@ -68,6 +68,19 @@ func (r *mcpServiceRepository) ListEnabled(ctx context.Context, tenantID uint64)
 	return services, nil
 }
 
+// ListAllEnabled retrieves the enabled MCP services of every tenant
+func (r *mcpServiceRepository) ListAllEnabled(ctx context.Context) ([]*types.MCPService, error) {
+	var services []*types.MCPService
+	err := r.db.WithContext(ctx).
+		Where("enabled = ?", true).
+		Find(&services).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return services, nil
+}
+
 // ListByIDs retrieves MCP services by multiple IDs for a tenant
 func (r *mcpServiceRepository) ListByIDs(
 	ctx context.Context,
@@ -133,6 +146,19 @@ func (r *mcpServiceRepository) Update(ctx context.Context, service *types.MCPSer
 		Updates(updateMap).Error
 }
 
+// UpdateHealth stores the result of the last validation of the tools of an MCP service
+func (r *mcpServiceRepository) UpdateHealth(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+	health *types.MCPServiceHealth,
+) error {
+	return r.db.WithContext(ctx).
+		Model(&types.MCPService{}).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		UpdateColumn("health", health).Error
+}
+
 // Delete deletes an MCP service (soft delete)
 func (r *mcpServiceRepository) Delete(ctx context.Context, tenantID uint64, id string) error {
 	return r.db.WithContext(ctx).
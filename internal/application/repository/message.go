@@ -65,7 +65,7 @@ func (r *messageRepository) GetRecentMessagesBySession(
 	var messages []*types.Message
 	if err := r.db.WithContext(ctx).Where(
 		"session_id = ?", sessionID,
-	).Order("created_at DESC").Limit(limit).Find(&messages).Error; err != nil {
+	).Order("created_at DESC, id DESC").Limit(limit).Find(&messages).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -107,6 +107,36 @@ func (r *messageRepository) GetMessagesBySessionBeforeTime(
 	return messages, nil
 }
 
+// GetMessagesBySessionBeforeCursor retrieves messages of a session strictly before the cursor, newest first.
+// Messages created at the same time are ordered by ID, so that pages never overlap or skip a message.
+func (r *messageRepository) GetMessagesBySessionBeforeCursor(
+	ctx context.Context, sessionID string, cursor *types.MessageCursor, limit int,
+) ([]*types.Message, error) {
+	var messages []*types.Message
+	query := r.db.WithContext(ctx).Where("session_id = ?", sessionID)
+	if cursor != nil {
+		query = query.Where("(created_at < ? OR (created_at = ? AND id < ?))",
+			cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+	}
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetUserMessageByRequestID retrieves the user message of a conversation turn
+func (r *messageRepository) GetUserMessageByRequestID(
+	ctx context.Context, sessionID string, requestID string,
+) (*types.Message, error) {
+	var message types.Message
+	if err := r.db.WithContext(ctx).Where(
+		"session_id = ? AND request_id = ? AND role = ?", sessionID, requestID, "user",
+	).Order("created_at ASC").First(&message).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
 // UpdateMessage updates an existing message
 func (r *messageRepository) UpdateMessage(ctx context.Context, message *types.Message) error {
 	return r.db.WithContext(ctx).Model(&types.Message{}).Where(
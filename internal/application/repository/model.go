@@ -97,3 +97,18 @@ func (r *modelRepository) ClearDefaultByType(
 	// Batch update: set is_default to false for all matching records
 	return query.Update("is_default", false).Error
 }
+
+// ListActiveByType lists the most recently updated active models of a type across all tenants
+func (r *modelRepository) ListActiveByType(
+	ctx context.Context, modelType types.ModelType, limit int,
+) ([]*types.Model, error) {
+	var models []*types.Model
+	if err := r.db.WithContext(ctx).
+		Where("type = ? AND (status = ? OR status = '')", modelType, types.ModelStatusActive).
+		Order("updated_at DESC").
+		Limit(limit).
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+	return models, nil
+}
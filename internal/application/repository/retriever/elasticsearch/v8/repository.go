@@ -349,6 +349,7 @@ func (e *elasticsearchRepository) VectorRetrieve(ctx context.Context,
 	filter := e.getBaseConds(params)
 
 	// Build script scoring query with cosine similarity
+	// Script scoring is always exact, so approximate index parameters (params.VectorSearch) do not apply
 	queryVectorJSON, err := json.Marshal(params.Embedding)
 	if err != nil {
 		log.Errorf("[Elasticsearch] Failed to marshal query vector: %v", err)
@@ -253,8 +253,8 @@ func (g *pgRepository) KeywordsRetrieve(ctx context.Context,
 func (g *pgRepository) VectorRetrieve(ctx context.Context,
 	params types.RetrieveParams,
 ) ([]*types.RetrieveResult, error) {
-	logger.GetLogger(ctx).Infof("[Postgres] Vector retrieval: dim=%d, topK=%d, threshold=%.4f",
-		len(params.Embedding), params.TopK, params.Threshold)
+	logger.GetLogger(ctx).Infof("[Postgres] Vector retrieval: dim=%d, topK=%d, threshold=%.4f, mode=%s",
+		len(params.Embedding), params.TopK, params.Threshold, params.VectorSearch.Mode())
 
 	dimension := len(params.Embedding)
 	queryVector := pgvector.NewHalfVector(params.Embedding)
@@ -369,7 +369,19 @@ func (g *pgRepository) VectorRetrieve(ctx context.Context,
 
 	var embeddingDBList []pgVectorWithScore
 
-	err := g.db.WithContext(ctx).Raw(querySQL, allVars...).Scan(&embeddingDBList).Error
+	var err error
+	if params.VectorSearch.IsZero() {
+		err = g.db.WithContext(ctx).Raw(querySQL, allVars...).Scan(&embeddingDBList).Error
+	} else {
+		// Search-time index parameters are session settings; SET LOCAL scopes them to this transaction
+		// so they never leak to other queries sharing the pooled connection
+		err = g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := applyVectorSearchParams(tx, params.VectorSearch); err != nil {
+				return err
+			}
+			return tx.Raw(querySQL, allVars...).Scan(&embeddingDBList).Error
+		})
+	}
 
 	if err == gorm.ErrRecordNotFound {
 		logger.GetLogger(ctx).Warnf("[Postgres] No vector matches found that meet threshold %.4f", params.Threshold)
@@ -402,6 +414,32 @@ func (g *pgRepository) VectorRetrieve(ctx context.Context,
 	}, nil
 }
 
+// applyVectorSearchParams sets pgvector search-time parameters for the current transaction
+func applyVectorSearchParams(tx *gorm.DB, cfg *types.VectorSearchConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	// Exact search: disable index scans so the planner scores every row with a sequential scan
+	if cfg.Exact {
+		if err := tx.Exec("SET LOCAL enable_indexscan = off").Error; err != nil {
+			return fmt.Errorf("failed to disable index scan for exact search: %w", err)
+		}
+		return nil
+	}
+	// SET does not accept bind parameters; values are range-checked integers
+	if cfg.EfSearch > 0 {
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", cfg.EfSearch)).Error; err != nil {
+			return fmt.Errorf("failed to set hnsw.ef_search: %w", err)
+		}
+	}
+	if cfg.Probes > 0 {
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL ivfflat.probes = %d", cfg.Probes)).Error; err != nil {
+			return fmt.Errorf("failed to set ivfflat.probes: %w", err)
+		}
+	}
+	return nil
+}
+
 // CopyIndices copies index data
 func (g *pgRepository) CopyIndices(ctx context.Context,
 	sourceKnowledgeBaseID string,
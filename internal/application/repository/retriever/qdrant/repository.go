@@ -571,6 +571,7 @@ func (q *qdrantRepository) VectorRetrieve(ctx context.Context,
 		Limit:          &limit,
 		ScoreThreshold: &scoreThreshold,
 		WithPayload:    qdrant.NewWithPayload(true),
+		Params:         buildSearchParams(params.VectorSearch),
 	})
 	if err != nil {
 		log.Errorf("[Qdrant] Vector search failed: %v", err)
@@ -870,6 +871,23 @@ func createPayload(embedding *QdrantVectorEmbedding) map[string]*qdrant.Value {
 	return qdrant.NewValueMap(payload)
 }
 
+// buildSearchParams maps vector search parameters to Qdrant search params; nil keeps collection defaults.
+// Qdrant collections use HNSW only, so IVF probes do not apply.
+func buildSearchParams(cfg *types.VectorSearchConfig) *qdrant.SearchParams {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Exact {
+		exact := true
+		return &qdrant.SearchParams{Exact: &exact}
+	}
+	if cfg.EfSearch <= 0 {
+		return nil
+	}
+	ef := uint64(cfg.EfSearch)
+	return &qdrant.SearchParams{HnswEf: &ef}
+}
+
 func buildRetrieveResult(results []*types.IndexWithScore, retrieverType types.RetrieverType) []*types.RetrieveResult {
 	return []*types.RetrieveResult{
 		{
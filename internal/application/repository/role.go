@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// roleRepository implements the RoleRepository interface
+type roleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository creates a new role repository
+func NewRoleRepository(db *gorm.DB) interfaces.RoleRepository {
+	return &roleRepository{db: db}
+}
+
+// GetAssignment gets the role assignment of a user in a tenant, nil when the user has none
+func (r *roleRepository) GetAssignment(
+	ctx context.Context, tenantID uint64, userID string,
+) (*types.UserRole, error) {
+	var assignment types.UserRole
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND user_id = ?", tenantID, userID).
+		First(&assignment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// ListAssignments lists the role assignments of a tenant
+func (r *roleRepository) ListAssignments(ctx context.Context, tenantID uint64) ([]*types.UserRole, error) {
+	var assignments []*types.UserRole
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("created_at ASC").
+		Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// UpsertAssignment creates or replaces the role assignment of a user in a tenant
+func (r *roleRepository) UpsertAssignment(ctx context.Context, assignment *types.UserRole) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(assignment).Error
+}
+
+// DeleteAssignment deletes the role assignment of a user in a tenant
+func (r *roleRepository) DeleteAssignment(ctx context.Context, tenantID uint64, userID string) error {
+	return r.db.WithContext(ctx).
+		Where("tenant_id = ? AND user_id = ?", tenantID, userID).
+		Delete(&types.UserRole{}).Error
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
@@ -103,6 +104,52 @@ func (r *tenantRepository) DeleteTenant(ctx context.Context, id uint64) error {
 	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&types.Tenant{}).Error
 }
 
+// PurgeTenant permanently deletes the tenant and every record that belongs to it, including soft-deleted
+// records, in one transaction. Returns the number of deleted records by table.
+func (r *tenantRepository) PurgeTenant(ctx context.Context, id uint64) (map[string]int64, error) {
+	deleted := make(map[string]int64)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		sessions := tx.Unscoped().Model(&types.Session{}).Select("id").Where("tenant_id = ?", id)
+		users := tx.Unscoped().Model(&types.User{}).Select("id").Where("tenant_id = ?", id)
+		// Records without a tenant column are deleted through their owner, so they go first
+		steps := []struct {
+			table string
+			query *gorm.DB
+			model interface{}
+		}{
+			{"messages", tx.Where("session_id IN (?)", sessions), &types.Message{}},
+			{"auth_tokens", tx.Where("user_id IN (?)", users), &types.AuthToken{}},
+			{"user_identities", tx.Where("user_id IN (?)", users), &types.UserIdentity{}},
+			{"user_roles", tx.Where("tenant_id = ?", id), &types.UserRole{}},
+			{"api_keys", tx.Where("tenant_id = ?", id), &types.APIKey{}},
+			{"agent_runs", tx.Where("tenant_id = ?", id), &types.AgentRun{}},
+			{"sessions", tx.Where("tenant_id = ?", id), &types.Session{}},
+			{"custom_agent_versions", tx.Where("tenant_id = ?", id), &types.CustomAgentVersion{}},
+			{"custom_agents", tx.Where("tenant_id = ?", id), &types.CustomAgent{}},
+			{"chunks", tx.Where("tenant_id = ?", id), &types.Chunk{}},
+			{"knowledges", tx.Where("tenant_id = ?", id), &types.Knowledge{}},
+			{"knowledge_tags", tx.Where("tenant_id = ?", id), &types.KnowledgeTag{}},
+			{"knowledge_bases", tx.Where("tenant_id = ?", id), &types.KnowledgeBase{}},
+			{"mcp_services", tx.Where("tenant_id = ?", id), &types.MCPService{}},
+			{"models", tx.Where("tenant_id = ?", id), &types.Model{}},
+			{"users", tx.Where("tenant_id = ?", id), &types.User{}},
+			{"tenants", tx.Where("id = ?", id), &types.Tenant{}},
+		}
+		for _, step := range steps {
+			result := step.query.Unscoped().Delete(step.model)
+			if result.Error != nil {
+				return fmt.Errorf("purge %s: %w", step.table, result.Error)
+			}
+			deleted[step.table] = result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
 func (r *tenantRepository) AdjustStorageUsed(ctx context.Context, tenantID uint64, delta int64) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var tenant types.Tenant
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
@@ -95,6 +96,57 @@ func (r *userRepository) ListUsers(ctx context.Context, offset, limit int) ([]*t
 	return users, nil
 }
 
+// UseTOTPStep records the time step of a TOTP code accepted for a user and clears its failed two-factor
+// attempts, unless a code of the same or a later step was accepted meanwhile
+func (r *userRepository) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&types.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Updates(map[string]interface{}{
+			"totp_last_step":             step,
+			"two_factor_failed_attempts": 0,
+			"two_factor_locked_until":    nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// UseRecoveryCode replaces the recovery codes of a user with the remaining ones and clears its failed
+// two-factor attempts, unless the codes changed meanwhile
+func (r *userRepository) UseRecoveryCode(
+	ctx context.Context, id string, codes, remaining types.StringArray,
+) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&types.User{}).
+		Where("id = ? AND recovery_codes = ?", id, codes).
+		Updates(map[string]interface{}{
+			"recovery_codes":             remaining,
+			"two_factor_failed_attempts": 0,
+			"two_factor_locked_until":    nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RecordTwoFactorFailure counts a wrong two-factor code of a user, locking its two-factor authentication
+// until lockedUntil on the attempt reaching maxAttempts. The count is incremented in the database, so that
+// concurrent attempts are all counted.
+func (r *userRepository) RecordTwoFactorFailure(
+	ctx context.Context, id string, maxAttempts int, lockedUntil time.Time,
+) error {
+	return r.db.WithContext(ctx).Model(&types.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"two_factor_failed_attempts": gorm.Expr(
+				"CASE WHEN two_factor_failed_attempts + 1 >= ? THEN 0 ELSE two_factor_failed_attempts + 1 END",
+				maxAttempts),
+			"two_factor_locked_until": gorm.Expr(
+				"CASE WHEN two_factor_failed_attempts + 1 >= ? THEN ? ELSE two_factor_locked_until END",
+				maxAttempts, lockedUntil),
+		}).Error
+}
+
 // authTokenRepository implements auth token repository interface
 type authTokenRepository struct {
 	db *gorm.DB
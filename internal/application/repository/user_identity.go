@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// userIdentityRepository implements the UserIdentityRepository interface
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewUserIdentityRepository creates a new user identity repository
+func NewUserIdentityRepository(db *gorm.DB) interfaces.UserIdentityRepository {
+	return &userIdentityRepository{db: db}
+}
+
+// GetIdentity gets the link of an account of a provider, nil when the account is not linked
+func (r *userIdentityRepository) GetIdentity(
+	ctx context.Context, provider, subject string,
+) (*types.UserIdentity, error) {
+	var identity types.UserIdentity
+	if err := r.db.WithContext(ctx).
+		Where("provider = ? AND subject = ?", provider, subject).
+		First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// CreateIdentity links an account of a provider to a user
+func (r *userIdentityRepository) CreateIdentity(ctx context.Context, identity *types.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+// DeleteIdentity unlinks an account of a provider
+func (r *userIdentityRepository) DeleteIdentity(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&types.UserIdentity{}).Error
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// ErrAgentRunNotFound is returned when no execution trace exists for a message
+var ErrAgentRunNotFound = errors.New("agent run not found")
+
+// agentRunService implements the AgentRunService interface
+type agentRunService struct {
+	repo               interfaces.AgentRunRepository
+	sessionService     interfaces.SessionService
+	customAgentService interfaces.CustomAgentService
+}
+
+// NewAgentRunService creates a new agent run service
+func NewAgentRunService(
+	repo interfaces.AgentRunRepository,
+	sessionService interfaces.SessionService,
+	customAgentService interfaces.CustomAgentService,
+) interfaces.AgentRunService {
+	return &agentRunService{
+		repo:               repo,
+		sessionService:     sessionService,
+		customAgentService: customAgentService,
+	}
+}
+
+// ListRuns returns the original run of a message followed by its replays
+func (s *agentRunService) ListRuns(ctx context.Context, messageID string) ([]*types.AgentRun, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+
+	runs, err := s.repo.ListRunsByMessageID(ctx, tenantID, messageID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"message_id": messageID,
+		})
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, ErrAgentRunNotFound
+	}
+	return runs, nil
+}
+
+// ReplayRun re-executes the inputs of a message's original run
+func (s *agentRunService) ReplayRun(ctx context.Context, messageID string, agentVersion int) (*types.AgentRun, error) {
+	runs, err := s.ListRuns(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	var original *types.AgentRun
+	for _, run := range runs {
+		if run.ReplayOf == "" {
+			original = run
+			break
+		}
+	}
+	if original == nil {
+		return nil, ErrAgentRunNotFound
+	}
+
+	var customAgent *types.CustomAgent
+	if agentVersion > 0 {
+		customAgent, err = s.customAgentService.GetAgentAtVersion(ctx, original.AgentID, agentVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.sessionService.ReplayAgentRun(ctx, original, customAgent)
+}
@@ -226,6 +226,7 @@ func (s *agentService) registerTools(
 		filteredTools := make([]string, 0)
 		kbTools := map[string]bool{
 			tools.ToolKnowledgeSearch:     true,
+			tools.ToolRetrieval:           true,
 			tools.ToolGrepChunks:          true,
 			tools.ToolListKnowledgeChunks: true,
 			tools.ToolQueryKnowledgeGraph: true,
@@ -275,6 +276,8 @@ func (s *agentService) registerTools(
 				chatModel,
 				s.cfg,
 			)
+		case tools.ToolRetrieval:
+			toolToRegister = tools.NewRetrievalTool(s.knowledgeBaseService, config.SearchTargets, s.cfg)
 		case tools.ToolGrepChunks:
 			toolToRegister = tools.NewGrepChunksTool(s.db, config.KnowledgeBases, config.KnowledgeIDs)
 			logger.Infof(ctx, "Registered grep_chunks tool, KBs: %d, KnowledgeIDs: %d", len(config.KnowledgeBases), len(config.KnowledgeIDs))
@@ -339,6 +342,14 @@ func (s *agentService) ValidateConfig(config *types.AgentConfig) error {
 		return fmt.Errorf("max iterations too high: %d (max %d)", config.MaxIterations, MAX_ITERATIONS)
 	}
 
+	if config.MaxTotalTokens < 0 {
+		return fmt.Errorf("max total tokens cannot be negative: %d", config.MaxTotalTokens)
+	}
+
+	if config.MaxExecutionSeconds < 0 {
+		return fmt.Errorf("max execution seconds cannot be negative: %d", config.MaxExecutionSeconds)
+	}
+
 	return nil
 }
 
@@ -406,7 +417,7 @@ func (s *agentService) getKnowledgeBaseInfos(ctx context.Context, kbIDs []string
 			pageResult, err := s.knowledgeService.ListPagedKnowledgeByKnowledgeBaseID(ctx, kbID, &types.Pagination{
 				Page:     1,
 				PageSize: 10,
-			}, "", "", "")
+			}, "", "", "", nil)
 
 			if err == nil && pageResult != nil {
 				docCount = int(pageResult.Total)
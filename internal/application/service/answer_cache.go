@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	answerCacheKeyPrefix        = "answer_cache:"
+	answerCacheGenerationPrefix = "answer_cache:gen:"
+)
+
+// answerCacheService implements the AnswerCacheService interface.
+// Every knowledge base has a generation counter that is part of the cache keys, so invalidating a knowledge
+// base only increments its counter; the orphaned entries expire with their TTL.
+type answerCacheService struct {
+	redisClient *redis.Client
+	kbRepo      interfaces.KnowledgeBaseRepository
+}
+
+// NewAnswerCacheService creates a new answer cache service instance
+func NewAnswerCacheService(
+	redisClient *redis.Client,
+	kbRepo interfaces.KnowledgeBaseRepository,
+) interfaces.AnswerCacheService {
+	return &answerCacheService{
+		redisClient: redisClient,
+		kbRepo:      kbRepo,
+	}
+}
+
+// TTL returns the shortest cache TTL of the knowledge bases, 0 when any of them disables caching
+func (s *answerCacheService) TTL(ctx context.Context, knowledgeBaseIDs []string) time.Duration {
+	ids := slices.Compact(slices.Sorted(slices.Values(knowledgeBaseIDs)))
+	if len(ids) == 0 {
+		return 0
+	}
+	kbs, err := s.kbRepo.GetKnowledgeBaseByIDs(ctx, ids)
+	if err != nil || len(kbs) != len(ids) {
+		return 0
+	}
+	var ttl time.Duration
+	for _, kb := range kbs {
+		kbTTL := kb.AnswerCacheConfig.TTL()
+		if kbTTL <= 0 {
+			return 0
+		}
+		if ttl == 0 || kbTTL < ttl {
+			ttl = kbTTL
+		}
+	}
+	return ttl
+}
+
+// Get returns the cached answer for the key, nil on a miss
+func (s *answerCacheService) Get(ctx context.Context, key types.AnswerCacheKey) (*types.CachedAnswer, error) {
+	entryKey, err := s.entryKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := s.redisClient.Get(ctx, entryKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached answer: %w", err)
+	}
+	var answer types.CachedAnswer
+	if err := json.Unmarshal(raw, &answer); err != nil {
+		return nil, fmt.Errorf("failed to decode cached answer: %w", err)
+	}
+	return &answer, nil
+}
+
+// Set caches an answer for the key
+func (s *answerCacheService) Set(ctx context.Context,
+	key types.AnswerCacheKey, answer *types.CachedAnswer, ttl time.Duration,
+) error {
+	if ttl <= 0 {
+		return nil
+	}
+	entryKey, err := s.entryKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(answer)
+	if err != nil {
+		return fmt.Errorf("failed to encode answer: %w", err)
+	}
+	return s.redisClient.Set(ctx, entryKey, payload, ttl).Err()
+}
+
+// Invalidate drops every cached answer that used the knowledge base
+func (s *answerCacheService) Invalidate(ctx context.Context, knowledgeBaseID string) error {
+	if err := s.redisClient.Incr(ctx, answerCacheGenerationPrefix+knowledgeBaseID).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate answer cache: %w", err)
+	}
+	logger.Infof(ctx, "Answer cache invalidated, knowledge base ID: %s", knowledgeBaseID)
+	return nil
+}
+
+// entryKey combines the key hash with the current generations of its knowledge bases
+func (s *answerCacheService) entryKey(ctx context.Context, key types.AnswerCacheKey) (string, error) {
+	kbIDs := slices.Sorted(slices.Values(key.KnowledgeBaseIDs))
+	generationKeys := make([]string, 0, len(kbIDs))
+	for _, id := range kbIDs {
+		generationKeys = append(generationKeys, answerCacheGenerationPrefix+id)
+	}
+	generations := make([]string, 0, len(kbIDs))
+	if len(generationKeys) > 0 {
+		values, err := s.redisClient.MGet(ctx, generationKeys...).Result()
+		if err != nil {
+			return "", fmt.Errorf("failed to get answer cache generations: %w", err)
+		}
+		for _, value := range values {
+			generation, _ := value.(string)
+			if generation == "" {
+				generation = "0"
+			}
+			generations = append(generations, generation)
+		}
+	}
+	return answerCacheKeyPrefix + key.Hash() + ":" + strings.Join(generations, "."), nil
+}
+
+// invalidateAnswerCache invalidates the cached answers of a knowledge base after its content changed.
+// Failures are logged only; the cached answers then expire with their TTL.
+func invalidateAnswerCache(ctx context.Context, answerCache interfaces.AnswerCacheService, knowledgeBaseID string) {
+	if answerCache == nil || knowledgeBaseID == "" {
+		return
+	}
+	if err := answerCache.Invalidate(ctx, knowledgeBaseID); err != nil {
+		logger.Warnf(ctx, "Failed to invalidate answer cache of knowledge base %s: %v", knowledgeBaseID, err)
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/Tencent/WeKnora/internal/clock"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+const (
+	// maxAPIKeysPerTenant bounds the active API keys of a tenant
+	maxAPIKeysPerTenant = 50
+	// maxAPIKeyNameLength bounds the names of the API keys, in characters
+	maxAPIKeyNameLength = 100
+	// apiKeyPrefixLength is how many characters of a key are kept to recognize it in listings
+	apiKeyPrefixLength = 11
+	// apiKeyTouchInterval is how often the last use of a key is written at most
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	// ErrAPIKeyNotFound is returned for an API key that is not a key of the tenant
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInvalidAPIKeyRequest is returned for an API key with no name, unknown scopes or a past expiration
+	ErrInvalidAPIKeyRequest = errors.New("invalid API key request")
+	// ErrTooManyAPIKeys is returned when a tenant already has the maximum number of active API keys
+	ErrTooManyAPIKeys = errors.New("too many API keys")
+	// ErrInvalidAPIKey is returned when authenticating with an unknown, expired or revoked API key
+	ErrInvalidAPIKey = errors.New("invalid, expired or revoked API key")
+)
+
+// apiKeyService implements the APIKeyService interface
+type apiKeyService struct {
+	repo  interfaces.APIKeyRepository
+	clock clock.Clock
+}
+
+// NewAPIKeyService creates the service of the API keys minted for tenants
+func NewAPIKeyService(repo interfaces.APIKeyRepository, clk clock.Clock) interfaces.APIKeyService {
+	return &apiKeyService{repo: repo, clock: clk}
+}
+
+// CreateKey mints an API key for the current tenant; the key is only returned here
+func (s *apiKeyService) CreateKey(ctx context.Context, req *types.CreateAPIKeyRequest) (*types.CreatedAPIKey, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+	now := s.clock.Now()
+	name := strings.TrimSpace(req.Name)
+	switch {
+	case name == "" || utf8.RuneCountInString(name) > maxAPIKeyNameLength:
+		return nil, fmt.Errorf("%w: the name must have 1 to %d characters", ErrInvalidAPIKeyRequest,
+			maxAPIKeyNameLength)
+	case len(req.Scopes) == 0:
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyRequest)
+	case req.ExpiresAt != nil && !req.ExpiresAt.After(now):
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKeyRequest)
+	}
+	if err := types.ValidateAPIKeyScopes(req.Scopes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAPIKeyRequest, err)
+	}
+
+	count, err := s.repo.CountActiveKeys(ctx, tenantID, now)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"tenant_id": tenantID,
+		})
+		return nil, err
+	}
+	if count >= maxAPIKeysPerTenant {
+		return nil, fmt.Errorf("%w: a tenant can have at most %d active keys", ErrTooManyAPIKeys, maxAPIKeysPerTenant)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := types.ManagedAPIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	apiKey := &types.APIKey{
+		ID:        uuid.New().String(),
+		TenantID:  tenantID,
+		Name:      name,
+		Prefix:    key[:apiKeyPrefixLength],
+		KeyHash:   hashAPIKey(key),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if user, ok := ctx.Value(types.UserContextKey).(*types.User); ok {
+		apiKey.CreatedBy = user.ID
+	}
+	if err := s.repo.CreateKey(ctx, apiKey); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"tenant_id": tenantID,
+		})
+		return nil, err
+	}
+
+	logger.Infof(ctx, "Created API key %s with scopes %v for tenant %d", apiKey.ID, apiKey.Scopes, tenantID)
+	return &types.CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// ListKeys lists the API keys of the current tenant, newest first
+func (s *apiKeyService) ListKeys(ctx context.Context) ([]*types.APIKey, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+	keys, err := s.repo.ListKeys(ctx, tenantID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"tenant_id": tenantID,
+		})
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeKey revokes an API key of the current tenant; revoking a revoked key does nothing
+func (s *apiKeyService) RevokeKey(ctx context.Context, id string) error {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return ErrInvalidTenantID
+	}
+	key, err := s.repo.GetKeyByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return ErrAPIKeyNotFound
+	}
+	if err := s.repo.RevokeKey(ctx, tenantID, id, s.clock.Now()); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"tenant_id":  tenantID,
+			"api_key_id": id,
+		})
+		return err
+	}
+	logger.Infof(ctx, "Revoked API key %s of tenant %d", id, tenantID)
+	return nil
+}
+
+// Authenticate returns the active API key matching a key sent with a request, and records its use
+func (s *apiKeyService) Authenticate(ctx context.Context, key string) (*types.APIKey, error) {
+	apiKey, err := s.repo.GetKeyByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	if apiKey == nil || !apiKey.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.repo.TouchKey(ctx, apiKey.ID, now); err != nil {
+			logger.Warnf(ctx, "Failed to record the use of API key %s: %v", apiKey.ID, err)
+		}
+	}
+	return apiKey, nil
+}
+
+// hashAPIKey returns the hash an API key is stored as. The keys are random, so a plain SHA-256 suffices.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
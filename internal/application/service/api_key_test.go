@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/clock"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// apiKeyTestRepo keeps the API keys in memory
+type apiKeyTestRepo struct {
+	keys    []*types.APIKey
+	touched int
+}
+
+func (r *apiKeyTestRepo) CreateKey(ctx context.Context, key *types.APIKey) error {
+	r.keys = append(r.keys, key)
+	return nil
+}
+
+func (r *apiKeyTestRepo) GetKeyByHash(ctx context.Context, keyHash string) (*types.APIKey, error) {
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *apiKeyTestRepo) GetKeyByID(ctx context.Context, tenantID uint64, id string) (*types.APIKey, error) {
+	for _, key := range r.keys {
+		if key.TenantID == tenantID && key.ID == id {
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *apiKeyTestRepo) ListKeys(ctx context.Context, tenantID uint64) ([]*types.APIKey, error) {
+	var keys []*types.APIKey
+	for _, key := range r.keys {
+		if key.TenantID == tenantID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *apiKeyTestRepo) CountActiveKeys(ctx context.Context, tenantID uint64, now time.Time) (int64, error) {
+	var count int64
+	for _, key := range r.keys {
+		if key.TenantID == tenantID && key.Active(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *apiKeyTestRepo) RevokeKey(ctx context.Context, tenantID uint64, id string, revokedAt time.Time) error {
+	if key, _ := r.GetKeyByID(ctx, tenantID, id); key != nil && key.RevokedAt == nil {
+		key.RevokedAt = &revokedAt
+	}
+	return nil
+}
+
+func (r *apiKeyTestRepo) TouchKey(ctx context.Context, id string, usedAt time.Time) error {
+	r.touched++
+	for _, key := range r.keys {
+		if key.ID == id {
+			key.LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+func TestAPIKeyService(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	repo := &apiKeyTestRepo{}
+	svc := NewAPIKeyService(repo, clk)
+	ctx := context.WithValue(context.WithValue(context.Background(), types.TenantIDContextKey, uint64(7)),
+		types.UserContextKey, &types.User{ID: "alice"})
+
+	expiresAt := clk.Now().Add(24 * time.Hour)
+	created, err := svc.CreateKey(ctx, &types.CreateAPIKeyRequest{
+		Name: " search bot ", Scopes: types.StringArray{types.APIKeyScopeKnowledgeSearch}, ExpiresAt: &expiresAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.Key, types.ManagedAPIKeyPrefix) || !strings.HasPrefix(created.Key, created.Prefix) ||
+		created.Name != "search bot" || created.CreatedBy != "alice" || created.TenantID != 7 {
+		t.Errorf("created key = %+v", created)
+	}
+	if strings.Contains(repo.keys[0].KeyHash, created.Key) {
+		t.Error("the key is stored in clear")
+	}
+
+	key, err := svc.Authenticate(ctx, created.Key)
+	if err != nil || key.ID != created.ID || key.Scopes[0] != types.APIKeyScopeKnowledgeSearch {
+		t.Fatalf("Authenticate = %+v, err = %v", key, err)
+	}
+	if _, err := svc.Authenticate(ctx, created.Key); err != nil || repo.touched != 1 {
+		t.Errorf("touched %d times within a minute, err = %v, want 1", repo.touched, err)
+	}
+	if _, err := svc.Authenticate(ctx, created.Key+"x"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("unknown key err = %v, want %v", err, ErrInvalidAPIKey)
+	}
+
+	clk.Advance(25 * time.Hour)
+	if _, err := svc.Authenticate(ctx, created.Key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expired key err = %v, want %v", err, ErrInvalidAPIKey)
+	}
+
+	created, err = svc.CreateKey(ctx, &types.CreateAPIKeyRequest{
+		Name: "ingest", Scopes: types.StringArray{types.APIKeyScopeKnowledgeIngest},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.RevokeKey(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Authenticate(ctx, created.Key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("revoked key err = %v, want %v", err, ErrInvalidAPIKey)
+	}
+	otherTenant := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(8))
+	if err := svc.RevokeKey(otherTenant, created.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("revoking the key of another tenant err = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+	if keys, err := svc.ListKeys(ctx); err != nil || len(keys) != 2 {
+		t.Errorf("ListKeys = %v, err = %v, want both keys", keys, err)
+	}
+
+	past := clk.Now().Add(-time.Minute)
+	invalid := []*types.CreateAPIKeyRequest{
+		{Name: " ", Scopes: types.StringArray{types.APIKeyScopeChat}},
+		{Name: "no scopes", Scopes: types.StringArray{}},
+		{Name: "unknown scope", Scopes: types.StringArray{"knowledge:delete"}},
+		{Name: "expired", Scopes: types.StringArray{types.APIKeyScopeChat}, ExpiresAt: &past},
+	}
+	for _, req := range invalid {
+		if _, err := svc.CreateKey(ctx, req); !errors.Is(err, ErrInvalidAPIKeyRequest) {
+			t.Errorf("CreateKey(%q) err = %v, want %v", req.Name, err, ErrInvalidAPIKeyRequest)
+		}
+	}
+}
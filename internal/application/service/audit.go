@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// ErrAuditLogNotFound is returned for an entry that is not in the audit log of the tenant
+var ErrAuditLogNotFound = errors.New("audit log entry not found")
+
+// auditLogService implements the AuditLogService interface
+type auditLogService struct {
+	repo interfaces.AuditLogRepository
+}
+
+// NewAuditLogService creates a new audit log service
+func NewAuditLogService(repo interfaces.AuditLogRepository) interfaces.AuditLogService {
+	return &auditLogService{repo: repo}
+}
+
+// ListLogs lists the audit log entries of the current tenant matching the filter, newest first
+func (s *auditLogService) ListLogs(
+	ctx context.Context, filter *types.AuditLogFilter, page *types.Pagination,
+) (*types.PageResult, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+
+	logs, total, err := s.repo.ListLogs(ctx, tenantID, filter, page)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"tenant_id": tenantID,
+		})
+		return nil, err
+	}
+	return types.NewPageResult(total, page, logs), nil
+}
+
+// GetLog gets an audit log entry of the current tenant
+func (s *auditLogService) GetLog(ctx context.Context, id string) (*types.AuditLog, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+
+	log, err := s.repo.GetLog(ctx, tenantID, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"tenant_id": tenantID,
+			"id":        id,
+		})
+		return nil, err
+	}
+	if log == nil {
+		return nil, ErrAuditLogNotFound
+	}
+	return log, nil
+}
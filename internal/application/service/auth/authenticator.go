@@ -0,0 +1,206 @@
+// Package auth provides the authenticators validating the bearer tokens of API requests: the tokens
+// WeKnora issues at login, and tokens of an external auth service mapped to WeKnora users.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+const (
+	// defaultCacheTTL is used when auth.cache_ttl is not set
+	defaultCacheTTL = time.Minute
+	// defaultUserClaim is used when auth.user_claim is not set
+	defaultUserClaim = "email"
+	// cacheSweepInterval is how often expired validation results are dropped
+	cacheSweepInterval = time.Minute
+)
+
+// NewAuthenticator creates the authenticator selected by the auth section of the configuration
+func NewAuthenticator(cfg *config.Config, userService interfaces.UserService) (interfaces.Authenticator, error) {
+	authCfg := cfg.Auth
+	if authCfg == nil {
+		authCfg = &config.AuthConfig{}
+	}
+	if err := authCfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var validator claimsValidator
+	switch authCfg.Provider {
+	case config.AuthProviderIntrospection:
+		validator = newIntrospectionValidator(authCfg.Introspection)
+	case config.AuthProviderJWKS:
+		validator = newJWKSValidator(authCfg.JWKS)
+	default:
+		return NewJWTAuthenticator(userService), nil
+	}
+
+	ttl := authCfg.CacheTTL
+	if ttl == 0 {
+		ttl = defaultCacheTTL
+	}
+	claim := authCfg.UserClaim
+	if claim == "" {
+		claim = defaultUserClaim
+	}
+	return &externalAuthenticator{
+		validator: validator,
+		users:     userService,
+		userClaim: claim,
+		userField: authCfg.UserField,
+		cacheTTL:  ttl,
+		cache:     make(map[string]*cachedUser),
+		now:       time.Now,
+	}, nil
+}
+
+// jwtAuthenticator validates the tokens WeKnora issues at login
+type jwtAuthenticator struct {
+	users interfaces.UserService
+}
+
+// NewJWTAuthenticator creates the authenticator of the built-in JWTs
+func NewJWTAuthenticator(userService interfaces.UserService) interfaces.Authenticator {
+	return &jwtAuthenticator{users: userService}
+}
+
+// Name identifies the provider in logs
+func (a *jwtAuthenticator) Name() string {
+	return config.AuthProviderJWT
+}
+
+// Authenticate validates the token with the user service, which also checks that it was not revoked
+func (a *jwtAuthenticator) Authenticate(ctx context.Context, token string) (*types.User, error) {
+	return a.users.ValidateToken(ctx, token)
+}
+
+// claimsValidator validates a token with an external auth service and returns its claims
+type claimsValidator interface {
+	name() string
+	validate(ctx context.Context, token string) (map[string]interface{}, error)
+}
+
+// externalAuthenticator maps the tokens validated by an external auth service to the WeKnora user named
+// by one of their claims. Results are cached until the TTL or the expiry of the token, whichever is first.
+type externalAuthenticator struct {
+	validator claimsValidator
+	users     interfaces.UserService
+	userClaim string
+	userField string
+	cacheTTL  time.Duration // Negative disables the cache
+
+	mu        sync.Mutex
+	cache     map[string]*cachedUser // Keyed on the hash of the token
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// cachedUser is the user a token was mapped to
+type cachedUser struct {
+	user    types.User
+	expires time.Time
+}
+
+// Name identifies the provider in logs
+func (a *externalAuthenticator) Name() string {
+	return a.validator.name()
+}
+
+// Authenticate validates the token, or reuses the result of an earlier validation
+func (a *externalAuthenticator) Authenticate(ctx context.Context, token string) (*types.User, error) {
+	sum := sha256.Sum256([]byte(token))
+	cacheKey := hex.EncodeToString(sum[:])
+	if user := a.cached(cacheKey); user != nil {
+		return user, nil
+	}
+
+	claims, err := a.validator.validate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.lookupUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	a.store(cacheKey, user, claims)
+	return user, nil
+}
+
+// lookupUser returns the user named by the user claim
+func (a *externalAuthenticator) lookupUser(ctx context.Context, claims map[string]interface{}) (*types.User, error) {
+	value, _ := claims[a.userClaim].(string)
+	if value == "" {
+		return nil, fmt.Errorf("token has no %s claim", a.userClaim)
+	}
+	var (
+		user *types.User
+		err  error
+	)
+	switch a.userField {
+	case "username":
+		user, err = a.users.GetUserByUsername(ctx, value)
+	case "id":
+		user, err = a.users.GetUserByID(ctx, value)
+	default:
+		user, err = a.users.GetUserByEmail(ctx, value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the user of the token: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("no user matches the token")
+	}
+	if !user.IsActive {
+		return nil, errors.New("user of the token is inactive")
+	}
+	return user, nil
+}
+
+// cached returns a copy of the user a token was mapped to, nil when the result expired
+func (a *externalAuthenticator) cached(cacheKey string) *types.User {
+	if a.cacheTTL < 0 {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry, ok := a.cache[cacheKey]
+	if !ok || !a.now().Before(entry.expires) {
+		return nil
+	}
+	user := entry.user
+	return &user
+}
+
+// store caches the user a token was mapped to, no longer than the token is valid
+func (a *externalAuthenticator) store(cacheKey string, user *types.User, claims map[string]interface{}) {
+	if a.cacheTTL < 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	if now.Sub(a.lastSweep) >= cacheSweepInterval {
+		for k, entry := range a.cache {
+			if !now.Before(entry.expires) {
+				delete(a.cache, k)
+			}
+		}
+		a.lastSweep = now
+	}
+	expires := now.Add(a.cacheTTL)
+	if exp, ok := claims["exp"].(float64); ok && time.Unix(int64(exp), 0).Before(expires) {
+		expires = time.Unix(int64(exp), 0)
+	}
+	a.cache[cacheKey] = &cachedUser{user: *user, expires: expires}
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeUserService knows the user alice@example.com of tenant 7
+type fakeUserService struct {
+	interfaces.UserService
+}
+
+func (fakeUserService) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	if email != "alice@example.com" {
+		return nil, errors.New("user not found")
+	}
+	return &types.User{ID: "user-1", Email: email, TenantID: 7, IsActive: true}, nil
+}
+
+func TestIntrospectionAuthenticator(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if user, pass, _ := r.BasicAuth(); user != "weknora" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.FormValue("token") {
+		case "alice-token":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"active": true, "email": "alice@example.com"})
+		case "bob-token":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"active": true, "email": "bob@example.com"})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"active": false})
+		}
+	}))
+	defer server.Close()
+
+	authenticator, err := NewAuthenticator(&config.Config{Auth: &config.AuthConfig{
+		Provider:      config.AuthProviderIntrospection,
+		Introspection: &config.AuthIntrospectionConfig{URL: server.URL, ClientID: "weknora", ClientSecret: "secret"},
+	}}, fakeUserService{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		user, err := authenticator.Authenticate(ctx, "alice-token")
+		if err != nil || user.ID != "user-1" || user.TenantID != 7 {
+			t.Fatalf("user = %+v, err = %v, want user-1 of tenant 7", user, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("introspection calls = %d, want the second validation to be cached", calls)
+	}
+	if _, err := authenticator.Authenticate(ctx, "revoked-token"); err == nil {
+		t.Error("inactive token accepted")
+	}
+	if _, err := authenticator.Authenticate(ctx, "bob-token"); err == nil {
+		t.Error("token of an unknown user accepted")
+	}
+}
+
+func TestJWKSAuthenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer server.Close()
+
+	authenticator, err := NewAuthenticator(&config.Config{Auth: &config.AuthConfig{
+		Provider: config.AuthProviderJWKS,
+		CacheTTL: -1,
+		JWKS:     &config.AuthJWKSConfig{URL: server.URL, Issuer: "https://idp.example.com", Audience: "weknora"},
+	}}, fakeUserService{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(kid string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	valid := jwt.MapClaims{
+		"iss":   "https://idp.example.com",
+		"aud":   "weknora",
+		"email": "alice@example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	ctx := context.Background()
+
+	user, err := authenticator.Authenticate(ctx, sign("key-1", valid))
+	if err != nil || user.ID != "user-1" {
+		t.Fatalf("user = %+v, err = %v, want user-1", user, err)
+	}
+
+	expired := jwt.MapClaims{}
+	otherAudience := jwt.MapClaims{}
+	for k, v := range valid {
+		expired[k], otherAudience[k] = v, v
+	}
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	otherAudience["aud"] = "other"
+	for name, token := range map[string]string{
+		"expired":        sign("key-1", expired),
+		"other audience": sign("key-1", otherAudience),
+		"unknown key":    sign("key-2", valid),
+	} {
+		if _, err := authenticator.Authenticate(ctx, token); err == nil {
+			t.Errorf("%s token accepted", name)
+		}
+	}
+}
+
+func TestExternalAuthenticatorCacheExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	authenticator := &externalAuthenticator{cacheTTL: time.Hour, cache: make(map[string]*cachedUser),
+		now: func() time.Time { return now }}
+	user := &types.User{ID: "user-1"}
+
+	// A token expiring before the TTL is not trusted past its expiry
+	authenticator.store("token", user, map[string]interface{}{"exp": float64(now.Add(time.Minute).Unix())})
+	if authenticator.cached("token") == nil {
+		t.Fatal("validation result not cached")
+	}
+	now = now.Add(2 * time.Minute)
+	if authenticator.cached("token") != nil {
+		t.Error("cached past the expiry of the token")
+	}
+}
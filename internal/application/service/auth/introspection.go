@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/config"
+)
+
+const (
+	// defaultIntrospectionTimeout is used when auth.introspection.timeout is not set
+	defaultIntrospectionTimeout = 5 * time.Second
+	// maxIntrospectionResponseBytes bounds the responses read from the introspection endpoint
+	maxIntrospectionResponseBytes = 1 << 20
+)
+
+// introspectionValidator validates tokens with an OAuth2 token introspection endpoint (RFC 7662)
+type introspectionValidator struct {
+	cfg    *config.AuthIntrospectionConfig
+	client *http.Client
+}
+
+// newIntrospectionValidator creates a validator calling the configured endpoint
+func newIntrospectionValidator(cfg *config.AuthIntrospectionConfig) *introspectionValidator {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultIntrospectionTimeout
+	}
+	return &introspectionValidator{cfg: cfg, client: &http.Client{Timeout: timeout}}
+}
+
+// name identifies the provider in logs
+func (v *introspectionValidator) name() string {
+	return config.AuthProviderIntrospection
+}
+
+// validate asks the endpoint whether the token is active, and returns the claims it answers with
+func (v *introspectionValidator) validate(ctx context.Context, token string) (map[string]interface{}, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.cfg.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if v.cfg.ClientID != "" {
+		req.SetBasicAuth(v.cfg.ClientID, v.cfg.ClientSecret)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIntrospectionResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read introspection response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection endpoint returned status %d", resp.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, fmt.Errorf("failed to decode introspection response: %w", err)
+	}
+	if active, _ := claims["active"].(bool); !active {
+		return nil, errors.New("token is not active")
+	}
+	return claims, nil
+}
@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/logger"
+)
+
+const (
+	// defaultJWKSRefreshInterval is used when auth.jwks.refresh_interval is not set
+	defaultJWKSRefreshInterval = time.Hour
+	// minJWKSFetchInterval bounds how often unknown key IDs make the keys be fetched again
+	minJWKSFetchInterval = time.Minute
+	// jwksFetchTimeout bounds a fetch of the keys
+	jwksFetchTimeout = 10 * time.Second
+	// maxJWKSResponseBytes bounds the key sets read
+	maxJWKSResponseBytes = 1 << 20
+)
+
+// jwksSigningMethods are the asymmetric algorithms accepted; HMAC is refused so that a public key
+// can never be used as a shared secret
+var jwksSigningMethods = []string{
+	"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA",
+}
+
+// jwksValidator validates JWTs signed by an external issuer with the keys it publishes as a JWK Set
+type jwksValidator struct {
+	cfg      *config.AuthJWKSConfig
+	client   *http.Client
+	refresh  time.Duration
+	options  []jwt.ParserOption
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey // Keyed on the key ID
+	fetched  time.Time                   // Last successful fetch
+	attempt  time.Time                   // Last fetch
+	fetching sync.Mutex
+}
+
+// newJWKSValidator creates a validator of the tokens of the configured issuer
+func newJWKSValidator(cfg *config.AuthJWKSConfig) *jwksValidator {
+	refresh := cfg.RefreshInterval
+	if refresh <= 0 {
+		refresh = defaultJWKSRefreshInterval
+	}
+	options := []jwt.ParserOption{jwt.WithValidMethods(jwksSigningMethods), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	return &jwksValidator{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: jwksFetchTimeout},
+		refresh: refresh,
+		options: options,
+	}
+}
+
+// name identifies the provider in logs
+func (v *jwksValidator) name() string {
+	return config.AuthProviderJWKS
+}
+
+// validate checks the signature and claims of the token and returns its claims
+func (v *jwksValidator) validate(ctx context.Context, token string) (map[string]interface{}, error) {
+	claims := jwt.MapClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid)
+	}, v.options...)
+	if err != nil || !parsed.Valid {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	return claims, nil
+}
+
+// key returns the public key of a key ID, fetching the keys when they are stale or the ID is unknown.
+// A token without key ID may be signed by the only key of the set.
+func (v *jwksValidator) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, found, stale := v.lookup(kid)
+	if found && !stale {
+		return key, nil
+	}
+	if err := v.fetch(ctx, found); err != nil {
+		if found {
+			// Keep using the stale keys while the issuer is unreachable
+			logger.Warnf(ctx, "Failed to refresh JWKS keys, using the cached ones: %v", err)
+			return key, nil
+		}
+		return nil, err
+	}
+	if key, found, _ = v.lookup(kid); !found {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// lookup returns the cached key of a key ID, and whether the keys are due for a refresh
+func (v *jwksValidator) lookup(kid string) (crypto.PublicKey, bool, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	stale := time.Since(v.fetched) >= v.refresh
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true, stale
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok, stale
+}
+
+// fetch downloads the keys when they are due for a refresh or a key ID is unknown. Concurrent callers wait
+// for a single fetch, and fetches are at least a minute apart so that bogus tokens cannot flood the issuer.
+func (v *jwksValidator) fetch(ctx context.Context, refresh bool) error {
+	v.fetching.Lock()
+	defer v.fetching.Unlock()
+
+	v.mu.Lock()
+	fresh := time.Since(v.fetched) < v.refresh
+	recent := time.Since(v.attempt) < minJWKSFetchInterval
+	v.mu.Unlock()
+	if (refresh && fresh) || recent {
+		return nil
+	}
+
+	keys, err := v.download(ctx)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.attempt = time.Now()
+	if err != nil {
+		return err
+	}
+	v.keys = keys
+	v.fetched = v.attempt
+	return nil
+}
+
+// jsonWebKey is a key of a JWK Set (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// download fetches and decodes the signing keys of the set, skipping the keys it cannot use
+func (v *jwksValidator) download(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("JWKS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSResponseBytes)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			logger.Warnf(ctx, "Skipping JWKS key %q: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no usable signing key")
+	}
+	return keys, nil
+}
+
+// publicKey decodes an RSA, EC or Ed25519 public key
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// decodeBigInt decodes a base64url encoded unsigned integer
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/logger"
+)
+
+const (
+	// oidcDiscoveryRefresh is how often the discovery document of a provider is fetched again
+	oidcDiscoveryRefresh = 24 * time.Hour
+	// oidcRequestTimeout bounds the calls to a provider
+	oidcRequestTimeout = 10 * time.Second
+	// maxOIDCResponseBytes bounds the responses read from a provider
+	maxOIDCResponseBytes = 1 << 20
+)
+
+// defaultOIDCScopes are requested in addition to openid when a provider sets no scopes
+var defaultOIDCScopes = []string{"email", "profile"}
+
+// OIDCIdentity is a user of an identity provider, as asserted by an ID token
+type OIDCIdentity struct {
+	// Subject identifies the user at the provider and never changes
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// OIDCProvider logs users in with an OpenID Connect identity provider through the authorization code flow
+// with PKCE. The endpoints and signing keys of the provider are discovered from its issuer URL.
+type OIDCProvider struct {
+	cfg    *config.AuthOIDCProviderConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	idTokens  *jwksValidator
+	fetched   time.Time
+}
+
+// oidcDiscovery is the part of the discovery document of a provider used for the login
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewOIDCProviders creates the identity providers of the auth.oidc section of the configuration, in order
+func NewOIDCProviders(cfg *config.Config) ([]*OIDCProvider, error) {
+	if cfg.Auth == nil || cfg.Auth.OIDC == nil {
+		return nil, nil
+	}
+	if err := cfg.Auth.Validate(); err != nil {
+		return nil, err
+	}
+	providers := make([]*OIDCProvider, 0, len(cfg.Auth.OIDC.Providers))
+	for _, p := range cfg.Auth.OIDC.Providers {
+		if p == nil {
+			continue
+		}
+		providers = append(providers, &OIDCProvider{
+			cfg:    p,
+			client: &http.Client{Timeout: oidcRequestTimeout},
+		})
+	}
+	return providers, nil
+}
+
+// Name identifies the provider in the login routes
+func (p *OIDCProvider) Name() string {
+	return p.cfg.Name
+}
+
+// DisplayName is the name of the provider shown to users
+func (p *OIDCProvider) DisplayName() string {
+	if p.cfg.DisplayName != "" {
+		return p.cfg.DisplayName
+	}
+	return p.cfg.Name
+}
+
+// Type is the type of the provider: oidc, google or azure
+func (p *OIDCProvider) Type() string {
+	if p.cfg.Type == "" {
+		return config.AuthOIDCTypeGeneric
+	}
+	return p.cfg.Type
+}
+
+// TrustEmail reports whether the emails the provider does not mark as verified are accepted
+func (p *OIDCProvider) TrustEmail() bool {
+	return p.cfg.TrustEmail
+}
+
+// AllowsEmail reports whether users with the email may log in with the provider
+func (p *OIDCProvider) AllowsEmail(email string) bool {
+	if len(p.cfg.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range p.cfg.AllowedDomains {
+		if strings.EqualFold(domain, strings.TrimPrefix(allowed, "@")) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthCodeURL returns the login page of the provider, which sends the user back to the redirect URL with
+// an authorization code and the state
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	discovery, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultOIDCScopes
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, scopes...), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code at the token endpoint of the provider and returns the identity
+// asserted by the ID token, after checking its signature, issuer, audience, expiry and nonce
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*OIDCIdentity, error) {
+	discovery, idTokens, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOIDCResponseBytes)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned status %d: %s %s", resp.StatusCode,
+			body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return nil, errors.New("token response has no ID token")
+	}
+
+	claims, err := idTokens.validate(ctx, body.IDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, errors.New("ID token nonce does not match the login")
+	}
+	identity := &OIDCIdentity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	identity.PreferredUsername, _ = claims["preferred_username"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+	return identity, nil
+}
+
+// discover returns the discovery document of the provider and the validator of its ID tokens, fetching
+// the document when it is stale. A stale document keeps being used while the provider is unreachable.
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, *jwksValidator, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil && time.Since(p.fetched) < oidcDiscoveryRefresh {
+		return p.discovery, p.idTokens, nil
+	}
+
+	discovery, err := p.fetchDiscovery(ctx)
+	if err != nil {
+		if p.discovery != nil {
+			logger.Warnf(ctx, "Failed to refresh the discovery document of OIDC provider %s, using the cached one: %v",
+				p.cfg.Name, err)
+			return p.discovery, p.idTokens, nil
+		}
+		return nil, nil, err
+	}
+	if p.idTokens == nil || p.discovery.JWKSURI != discovery.JWKSURI || p.discovery.Issuer != discovery.Issuer {
+		p.idTokens = newJWKSValidator(&config.AuthJWKSConfig{
+			URL:      discovery.JWKSURI,
+			Issuer:   discovery.Issuer,
+			Audience: p.cfg.ClientID,
+		})
+	}
+	p.discovery = discovery
+	p.fetched = time.Now()
+	return p.discovery, p.idTokens, nil
+}
+
+// fetchDiscovery downloads the discovery document published under the issuer URL
+func (p *OIDCProvider) fetchDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		p.cfg.IssuerURL()+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OIDC discovery request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC discovery request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OIDC discovery endpoint returned status %d", resp.StatusCode)
+	}
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOIDCResponseBytes)).Decode(&discovery); err != nil {
+		return nil, fmt.Errorf("failed to decode OIDC discovery document: %w", err)
+	}
+	if discovery.Issuer == "" || discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" ||
+		discovery.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document lacks the issuer, endpoints or keys")
+	}
+	return &discovery, nil
+}
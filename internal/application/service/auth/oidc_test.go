@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Tencent/WeKnora/internal/config"
+)
+
+func TestOIDCProvider(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server *httptest.Server
+	challenges := map[string]string{} // Code challenge of each authorization code
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize?prompt=login",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		challenge, ok := challenges[r.FormValue("code")]
+		if !ok || challenge != base64.RawURLEncoding.EncodeToString(sum[:]) ||
+			r.FormValue("client_secret") != "secret" || r.FormValue("redirect_uri") != "https://weknora.example.com/cb" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            server.URL,
+			"aud":            "weknora",
+			"sub":            "alice-sub",
+			"email":          "alice@example.com",
+			"email_verified": true,
+			"nonce":          "nonce-1",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Error(err)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "access_token": "at"})
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	providers, err := NewOIDCProviders(&config.Config{Auth: &config.AuthConfig{OIDC: &config.AuthOIDCConfig{
+		Providers: []*config.AuthOIDCProviderConfig{{
+			Name: "sso", DisplayName: "Company SSO", Issuer: server.URL + "/", ClientID: "weknora",
+			ClientSecret: "secret", RedirectURL: "https://weknora.example.com/cb", AllowedDomains: []string{"example.com"},
+		}},
+	}}})
+	if err != nil || len(providers) != 1 {
+		t.Fatalf("providers = %v, err = %v", providers, err)
+	}
+	p := providers[0]
+	if p.Name() != "sso" || p.DisplayName() != "Company SSO" || p.Type() != config.AuthOIDCTypeGeneric {
+		t.Errorf("unexpected provider %s %s %s", p.Name(), p.DisplayName(), p.Type())
+	}
+	ctx := context.Background()
+
+	verifier := "verifier-with-enough-entropy-for-the-test-0123456789"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if parsed.Path != "/authorize" || query.Get("prompt") != "login" || query.Get("state") != "state-1" ||
+		query.Get("nonce") != "nonce-1" || query.Get("code_challenge") != challenge ||
+		query.Get("code_challenge_method") != "S256" || query.Get("scope") != "openid email profile" ||
+		query.Get("client_id") != "weknora" {
+		t.Errorf("unexpected authorization URL %s", authURL)
+	}
+	challenges["code-1"] = challenge
+
+	identity, err := p.Exchange(ctx, "code-1", verifier, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Subject != "alice-sub" || identity.Email != "alice@example.com" || !identity.EmailVerified {
+		t.Errorf("identity = %+v", identity)
+	}
+	if _, err := p.Exchange(ctx, "code-1", "other-verifier", "nonce-1"); err == nil {
+		t.Error("code redeemed with another verifier")
+	}
+	if _, err := p.Exchange(ctx, "code-1", verifier, "nonce-2"); err == nil {
+		t.Error("ID token of another login accepted")
+	}
+
+	if !p.AllowsEmail("bob@EXAMPLE.com") || p.AllowsEmail("eve@example.org") || p.AllowsEmail("no-domain") {
+		t.Error("AllowsEmail does not follow the allowed domains")
+	}
+}
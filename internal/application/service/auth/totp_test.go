@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// Test vectors of RFC 6238 for SHA-1, truncated to 6 digits
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/30); got != tt.want {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+
+	step, ok := VerifyTOTP(secret, "081804", now, 0)
+	if !ok || step != 1111111109/30 {
+		t.Fatalf("VerifyTOTP = %d, %v, want the current step", step, ok)
+	}
+	if _, ok := VerifyTOTP(secret, "081804", now.Add(30*time.Second), 0); !ok {
+		t.Error("code of the previous step rejected")
+	}
+	if _, ok := VerifyTOTP(secret, "081804", now.Add(90*time.Second), 0); ok {
+		t.Error("code three steps old accepted")
+	}
+	if _, ok := VerifyTOTP(secret, "081804", now, step); ok {
+		t.Error("code accepted twice")
+	}
+	if _, ok := VerifyTOTP(secret, "081805", now, 0); ok {
+		t.Error("wrong code accepted")
+	}
+
+	generated, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(TOTPURL("WeKnora", "alice@example.com", generated))
+	if err != nil || parsed.Scheme != "otpauth" || parsed.Host != "totp" ||
+		parsed.Path != "/WeKnora:alice@example.com" || parsed.Query().Get("secret") != generated {
+		t.Errorf("TOTPURL = %v, err = %v", parsed, err)
+	}
+}
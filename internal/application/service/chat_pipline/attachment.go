@@ -0,0 +1,44 @@
+package chatpipline
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// PluginAttachment adds the chunks extracted from the message attachments to the retrieved context.
+// Attachments are always included, ahead of the retrieved chunks, and are not reranked or filtered.
+type PluginAttachment struct{}
+
+// NewPluginAttachment creates and registers a new PluginAttachment instance
+func NewPluginAttachment(eventManager *EventManager) *PluginAttachment {
+	res := &PluginAttachment{}
+	eventManager.Register(res)
+	return res
+}
+
+// ActivationEvents returns the event types this plugin handles
+func (p *PluginAttachment) ActivationEvents() []types.EventType {
+	return []types.EventType{types.ATTACHMENT_MERGE}
+}
+
+// OnEvent prepends the attachment chunks to the merge result
+func (p *PluginAttachment) OnEvent(ctx context.Context,
+	eventType types.EventType, chatManage *types.ChatManage, next func() *PluginError,
+) *PluginError {
+	if len(chatManage.Attachments) == 0 {
+		return next()
+	}
+
+	merged := make([]*types.SearchResult, 0, len(chatManage.Attachments)+len(chatManage.MergeResult))
+	merged = append(merged, chatManage.Attachments...)
+	merged = append(merged, chatManage.MergeResult...)
+	chatManage.MergeResult = merged
+
+	pipelineInfo(ctx, "Attachment", "output", map[string]interface{}{
+		"session_id":     chatManage.SessionID,
+		"attachment_cnt": len(chatManage.Attachments),
+		"merge_cnt":      len(chatManage.MergeResult),
+	})
+	return next()
+}
@@ -0,0 +1,56 @@
+package chatpipline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestPluginAttachment(t *testing.T) {
+	tests := []struct {
+		name        string
+		attachments []*types.SearchResult
+		merged      []*types.SearchResult
+		want        []string
+	}{
+		{
+			name:   "no attachments keep the merge result",
+			merged: []*types.SearchResult{{ID: "chunk-1"}},
+			want:   []string{"chunk-1"},
+		},
+		{
+			name:        "attachments come first",
+			attachments: []*types.SearchResult{{ID: "attachment-1-0"}, {ID: "attachment-1-1"}},
+			merged:      []*types.SearchResult{{ID: "chunk-1"}, {ID: "chunk-2"}},
+			want:        []string{"attachment-1-0", "attachment-1-1", "chunk-1", "chunk-2"},
+		},
+		{
+			name:        "attachments without retrieval",
+			attachments: []*types.SearchResult{{ID: "attachment-1-0"}},
+			want:        []string{"attachment-1-0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatManage := &types.ChatManage{Attachments: tt.attachments, MergeResult: tt.merged}
+			nextCalled := false
+			err := (&PluginAttachment{}).OnEvent(context.Background(), types.ATTACHMENT_MERGE, chatManage,
+				func() *PluginError {
+					nextCalled = true
+					return nil
+				})
+			if err != nil || !nextCalled {
+				t.Fatalf("OnEvent = %v, next called %v", err, nextCalled)
+			}
+			if len(chatManage.MergeResult) != len(tt.want) {
+				t.Fatalf("merge result has %d chunks, want %d", len(chatManage.MergeResult), len(tt.want))
+			}
+			for i, id := range tt.want {
+				if chatManage.MergeResult[i].ID != id {
+					t.Errorf("merge result[%d] = %s, want %s", i, chatManage.MergeResult[i].ID, id)
+				}
+			}
+		})
+	}
+}
@@ -73,6 +73,7 @@ func (p *PluginChatCompletionStream) OnEvent(ctx context.Context,
 	pipelineInfo(ctx, "Stream", "eventbus_ready", map[string]interface{}{
 		"session_id": chatManage.SessionID,
 	})
+	reportContextBudget(ctx, chatManage)
 
 	// Initiate streaming chat model call with independent context
 	pipelineInfo(ctx, "Stream", "model_call", map[string]interface{}{
@@ -181,6 +182,7 @@ func (p *PluginChatCompletionStream) OnEvent(ctx context.Context,
 					Data: event.AgentFinalAnswerData{
 						Content: response.Content,
 						Done:    response.Done,
+						Usage:   response.Usage,
 					},
 				}); err != nil {
 					logger.Errorf(ctx, "Failed to emit answer event: %v", err)
@@ -2,6 +2,7 @@ package chatpipline
 
 import (
 	"context"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/types"
 )
@@ -72,6 +73,9 @@ func (e *EventManager) Trigger(ctx context.Context,
 	eventType types.EventType, chatManage *types.ChatManage,
 ) *PluginError {
 	if handler, ok := e.handlers[eventType]; ok {
+		types.RecordQuery(ctx, chatManage.Query)
+		start := time.Now()
+		defer func() { types.RecordStage(ctx, string(eventType), time.Since(start)) }()
 		return handler(ctx, eventType, chatManage)
 	}
 	return nil
@@ -122,6 +126,10 @@ var (
 		Description: "Failed to get conversation history",
 		ErrorType:   "get_history_failed",
 	}
+	ErrLowConfidence = &PluginError{
+		Description: "Retrieved content is not confident enough to answer",
+		ErrorType:   "low_confidence",
+	}
 )
 
 // clone creates a copy of the PluginError
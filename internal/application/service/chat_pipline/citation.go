@@ -0,0 +1,211 @@
+package chatpipline
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+)
+
+const citationInstruction = `Cite the passage supporting each sentence of your answer right after the sentence, with the
+label of the passage in square brackets as given in the context, e.g. [1]. Leave out statements that no passage
+supports: sentences without a citation are removed from the answer.`
+
+// PluginCitation enforces inline citations for knowledge bases that require them. Before generation it asks
+// the model to cite the passages; after generation it drops or flags the sentences without a valid citation
+// marker, or replaces the answer with the fallback response when too many sentences lack one.
+//
+// In streaming pipelines the check runs before generation and holds back the answer until the last chunk;
+// without streaming it checks the response.
+type PluginCitation struct {
+	knowledgeBaseService interfaces.KnowledgeBaseService
+}
+
+// NewPluginCitation creates a new PluginCitation and registers it with the event manager
+func NewPluginCitation(eventManager *EventManager,
+	knowledgeBaseService interfaces.KnowledgeBaseService,
+) *PluginCitation {
+	res := &PluginCitation{knowledgeBaseService: knowledgeBaseService}
+	eventManager.Register(res)
+	return res
+}
+
+// ActivationEvents returns the event types this plugin handles
+func (p *PluginCitation) ActivationEvents() []types.EventType {
+	return []types.EventType{types.INTO_CHAT_MESSAGE, types.CITATION_CHECK}
+}
+
+// OnEvent adds the citation instruction to the prompt, then checks the generated answer or intercepts the
+// answer stream to check it once complete
+func (p *PluginCitation) OnEvent(ctx context.Context,
+	eventType types.EventType, chatManage *types.ChatManage, next func() *PluginError,
+) *PluginError {
+	policy := p.resolvePolicy(ctx, chatManage)
+	if policy == nil || !policy.Required {
+		return next()
+	}
+
+	if eventType == types.INTO_CHAT_MESSAGE {
+		// Runs after the prompt is built by PluginIntoChatMessage, which is registered first
+		chatManage.SummaryConfig.Prompt = strings.TrimRight(chatManage.SummaryConfig.Prompt, "\n") +
+			"\n\n" + citationInstruction
+		return next()
+	}
+
+	if chatManage.ChatResponse != nil {
+		content, result := p.check(ctx, chatManage, policy, chatManage.ChatResponse.Content)
+		chatManage.ChatResponse.Content = content
+		emitCitation(ctx, chatManage.EventBus, chatManage.SessionID, result)
+		return next()
+	}
+	if chatManage.EventBus == nil {
+		return next()
+	}
+
+	pipelineInfo(ctx, "Citation", "hold_stream", map[string]interface{}{
+		"session_id": chatManage.SessionID,
+		"strictness": policy.EffectiveStrictness(),
+		"action":     policy.EffectiveAction(),
+	})
+	chatManage.EventBus = &citationEventBus{
+		EventBusInterface: chatManage.EventBus,
+		sessionID:         chatManage.SessionID,
+		check: func(ctx context.Context, answer string) (string, *types.CitationResult) {
+			return p.check(ctx, chatManage, policy, answer)
+		},
+	}
+	return next()
+}
+
+// resolvePolicy combines the citation policies of the searched knowledge bases that require citations
+func (p *PluginCitation) resolvePolicy(ctx context.Context, chatManage *types.ChatManage) *types.CitationConfig {
+	seen := make(map[string]bool)
+	var policy *types.CitationConfig
+	for _, target := range chatManage.SearchTargets {
+		if target == nil || seen[target.KnowledgeBaseID] {
+			continue
+		}
+		seen[target.KnowledgeBaseID] = true
+		kb, err := p.knowledgeBaseService.GetKnowledgeBaseByID(ctx, target.KnowledgeBaseID)
+		if err != nil {
+			pipelineWarn(ctx, "Citation", "get_kb", map[string]interface{}{
+				"knowledge_base_id": target.KnowledgeBaseID,
+				"error":             err.Error(),
+			})
+			continue
+		}
+		policy = policy.Stricter(kb.CitationConfig)
+	}
+	return policy
+}
+
+// check applies the policy to the answer. Returns the answer to deliver and the verdict.
+func (p *PluginCitation) check(ctx context.Context,
+	chatManage *types.ChatManage, policy *types.CitationConfig, answer string,
+) (string, *types.CitationResult) {
+	labels := citationLabels(chatManage)
+	content, result := policy.ApplyCitations(answer, func(label string) bool { return labels[label] })
+	chatManage.Citation = result
+
+	fields := map[string]interface{}{
+		"session_id":     chatManage.SessionID,
+		"message_id":     chatManage.MessageID,
+		"sentences":      result.Sentences,
+		"uncited":        len(result.Uncited),
+		"uncited_ratio":  fmt.Sprintf("%.4f", result.UncitedRatio),
+		"drop_threshold": result.DropThreshold,
+	}
+	if len(result.Uncited) == 0 {
+		pipelineInfo(ctx, "Citation", "result", fields)
+		return content, result
+	}
+	if result.Refused {
+		fields["action"] = "refuse"
+		pipelineWarn(ctx, "Citation", "result", fields)
+		if policy.FallbackResponse != "" {
+			return policy.FallbackResponse, result
+		}
+		return chatManage.FallbackResponse, result
+	}
+	fields["action"] = result.Action
+	pipelineWarn(ctx, "Citation", "result", fields)
+	return content, result
+}
+
+// citationLabels returns the labels of the passages in the context, as numbered by PluginIntoChatMessage
+func citationLabels(chatManage *types.ChatManage) map[string]bool {
+	labels := make(map[string]bool, len(chatManage.MergeResult))
+	if chatManage.FAQPriorityEnabled {
+		faqs, docs := 0, 0
+		for _, result := range chatManage.MergeResult {
+			if result.ChunkType == string(types.ChunkTypeFAQ) {
+				faqs++
+				labels[fmt.Sprintf("FAQ-%d", faqs)] = true
+			} else {
+				docs++
+				labels[fmt.Sprintf("DOC-%d", docs)] = true
+			}
+		}
+		if faqs > 0 {
+			return labels
+		}
+		clear(labels)
+	}
+	for i := range chatManage.MergeResult {
+		labels[strconv.Itoa(i+1)] = true
+	}
+	return labels
+}
+
+// emitCitation reports the citation verdict to the client
+func emitCitation(ctx context.Context, eventBus types.EventBusInterface, sessionID string,
+	result *types.CitationResult,
+) {
+	if eventBus == nil || result == nil {
+		return
+	}
+	if err := eventBus.Emit(ctx, types.Event{
+		ID:        fmt.Sprintf("%s-citation", uuid.New().String()[:8]),
+		Type:      types.EventType(event.EventCitation),
+		SessionID: sessionID,
+		Data:      result,
+	}); err != nil {
+		logger.Errorf(ctx, "Failed to emit citation event: %v", err)
+	}
+}
+
+// citationEventBus collects the streamed answer instead of forwarding it. When the last chunk arrives,
+// the answer is checked, the verdict is emitted and the checked answer is forwarded as a single chunk.
+// Other events pass through.
+type citationEventBus struct {
+	types.EventBusInterface
+	sessionID string
+	check     func(ctx context.Context, answer string) (string, *types.CitationResult)
+
+	answer strings.Builder
+	done   bool
+}
+
+// Emit holds back answer chunks until the answer is complete
+func (b *citationEventBus) Emit(ctx context.Context, evt types.Event) error {
+	data, ok := evt.Data.(event.AgentFinalAnswerData)
+	if b.done || !ok || evt.Type != types.EventType(event.EventAgentFinalAnswer) {
+		return b.EventBusInterface.Emit(ctx, evt)
+	}
+	b.answer.WriteString(data.Content)
+	if !data.Done {
+		return nil
+	}
+	b.done = true
+
+	content, result := b.check(ctx, b.answer.String())
+	emitCitation(ctx, b.EventBusInterface, b.sessionID, result)
+	evt.Data = event.AgentFinalAnswerData{Content: content, Done: true, Usage: data.Usage}
+	return b.EventBusInterface.Emit(ctx, evt)
+}
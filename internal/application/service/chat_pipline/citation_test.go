@@ -0,0 +1,99 @@
+package chatpipline
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestPluginCitationStream(t *testing.T) {
+	plugin := &PluginCitation{knowledgeBaseService: &fakeRerankKnowledgeBaseService{
+		kb: &types.KnowledgeBase{ID: "kb-1", CitationConfig: &types.CitationConfig{Required: true}},
+	}}
+	bus := &recordingEventBus{}
+	chatManage := &types.ChatManage{
+		SearchTargets: types.SearchTargets{{KnowledgeBaseID: "kb-1"}},
+		MergeResult: []*types.SearchResult{
+			{Content: "Passwords expire after 90 days."},
+			{Content: "Passwords are stored hashed."},
+		},
+		SummaryConfig: types.SummaryConfig{Prompt: "Answer from the context."},
+		EventBus:      bus,
+	}
+	noop := func() *PluginError { return nil }
+
+	if err := plugin.OnEvent(context.Background(), types.INTO_CHAT_MESSAGE, chatManage, noop); err != nil {
+		t.Fatalf("OnEvent(INTO_CHAT_MESSAGE) = %v", err)
+	}
+	if !strings.HasSuffix(chatManage.SummaryConfig.Prompt, citationInstruction) {
+		t.Errorf("prompt = %q, want the citation instruction", chatManage.SummaryConfig.Prompt)
+	}
+	if err := plugin.OnEvent(context.Background(), types.CITATION_CHECK, chatManage, noop); err != nil {
+		t.Fatalf("OnEvent(CITATION_CHECK) = %v", err)
+	}
+
+	// The second sentence cites no passage, the third one a passage missing from the context
+	chunks := []string{"Passwords expire ", "after 90 days [1]. ", "Reset links last one hour. ", "They are hashed [3]. ",
+		"Hashes use bcrypt [2]."}
+	for i, chunk := range chunks {
+		_ = chatManage.EventBus.Emit(context.Background(), types.Event{
+			Type: types.EventType(event.EventAgentFinalAnswer),
+			Data: event.AgentFinalAnswerData{Content: chunk, Done: i == len(chunks)-1},
+		})
+	}
+
+	var answer string
+	var verdict *types.CitationResult
+	for _, evt := range bus.events {
+		switch data := evt.Data.(type) {
+		case event.AgentFinalAnswerData:
+			if verdict == nil {
+				t.Error("answer emitted before the citation verdict")
+			}
+			answer += data.Content
+		case *types.CitationResult:
+			verdict = data
+		}
+	}
+	if want := "Passwords expire after 90 days [1]. Hashes use bcrypt [2]."; answer != want {
+		t.Errorf("answer = %q, want %q", answer, want)
+	}
+	if verdict == nil || !verdict.Dropped || len(verdict.Uncited) != 2 {
+		t.Errorf("verdict = %+v, want the two uncited sentences dropped", verdict)
+	}
+}
+
+func TestPluginCitationCompletion(t *testing.T) {
+	plugin := &PluginCitation{knowledgeBaseService: &fakeRerankKnowledgeBaseService{
+		kb: &types.KnowledgeBase{ID: "kb-1", CitationConfig: &types.CitationConfig{
+			Required:      true,
+			Action:        types.CitationActionFlag,
+			DropThreshold: 0.4,
+		}},
+	}}
+	chatManage := &types.ChatManage{
+		SearchTargets:      types.SearchTargets{{KnowledgeBaseID: "kb-1"}},
+		FAQPriorityEnabled: true,
+		MergeResult: []*types.SearchResult{
+			{Content: "How long are passwords valid? 90 days.", ChunkType: string(types.ChunkTypeFAQ)},
+			{Content: "Passwords are stored hashed."},
+		},
+		FallbackResponse: "I cannot answer this from the knowledge base.",
+		ChatResponse:     &types.ChatResponse{Content: "Passwords expire after 90 days [FAQ-1]. They are hashed [1]."},
+	}
+	if err := plugin.OnEvent(context.Background(), types.CITATION_CHECK, chatManage, func() *PluginError {
+		return nil
+	}); err != nil {
+		t.Fatalf("OnEvent() = %v", err)
+	}
+	// With FAQ priority the documents are labeled [DOC-n], so [1] is not a valid citation
+	if chatManage.ChatResponse.Content != "I cannot answer this from the knowledge base." {
+		t.Errorf("answer = %q, want the fallback response", chatManage.ChatResponse.Content)
+	}
+	if chatManage.Citation == nil || !chatManage.Citation.Refused {
+		t.Errorf("citation = %+v, want a refusal", chatManage.Citation)
+	}
+}
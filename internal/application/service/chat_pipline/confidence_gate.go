@@ -0,0 +1,299 @@
+package chatpipline
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+)
+
+// selfAssessmentPassages is the number of top passages shown to the model for self-assessment
+const selfAssessmentPassages = 5
+
+const selfAssessmentPrompt = `You judge whether reference passages contain the answer to a question.
+Reply with a single number between 0 and 1: 1 means the passages fully answer the question,
+0 means they are unrelated or do not contain the answer. Reply with the number only.`
+
+var confidenceNumberPattern = regexp.MustCompile(`[01](?:\.\d+)?`)
+
+// PluginConfidenceGate decides whether retrieved content is good enough to answer the question.
+// The confidence is always computed, logged and reported; questions are refused only when
+// a knowledge base enables its gate. Once the answer is generated, the plugin scores it again
+// with the coverage of the answer by the sources, which is reported and stored with the message.
+type PluginConfidenceGate struct {
+	knowledgeBaseService interfaces.KnowledgeBaseService
+	modelService         interfaces.ModelService
+}
+
+// NewPluginConfidenceGate creates a new PluginConfidenceGate and registers it with the event manager
+func NewPluginConfidenceGate(eventManager *EventManager,
+	knowledgeBaseService interfaces.KnowledgeBaseService,
+	modelService interfaces.ModelService,
+) *PluginConfidenceGate {
+	res := &PluginConfidenceGate{
+		knowledgeBaseService: knowledgeBaseService,
+		modelService:         modelService,
+	}
+	eventManager.Register(res)
+	return res
+}
+
+// ActivationEvents returns the event types this plugin handles
+func (p *PluginConfidenceGate) ActivationEvents() []types.EventType {
+	return []types.EventType{types.CONFIDENCE_GATE, types.ANSWER_CONFIDENCE}
+}
+
+// OnEvent computes the answer confidence and refuses low-confidence questions, or scores the answer
+func (p *PluginConfidenceGate) OnEvent(ctx context.Context,
+	eventType types.EventType, chatManage *types.ChatManage, next func() *PluginError,
+) *PluginError {
+	if eventType == types.ANSWER_CONFIDENCE {
+		return p.scoreAnswer(ctx, chatManage, next)
+	}
+
+	results := confidenceResults(chatManage)
+	if len(results) == 0 {
+		return next()
+	}
+
+	policy := p.resolvePolicy(ctx, chatManage)
+	signals := types.ConfidenceSignalsFromResults(results)
+	if policy != nil && policy.SelfAssessment {
+		score, err := AssessAnswerability(ctx, p.modelService, chatManage.ChatModelID, chatManage.Query, results)
+		if err != nil {
+			pipelineWarn(ctx, "ConfidenceGate", "self_assessment", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			signals.SelfAssessment = &score
+		}
+	}
+
+	result := policy.Evaluate(signals)
+	chatManage.Confidence = result
+	logConfidence(ctx, chatManage, result)
+	emitConfidence(ctx, chatManage.EventBus, chatManage.SessionID, result)
+
+	if result.Refused() {
+		pipelineWarn(ctx, "ConfidenceGate", "refuse", map[string]interface{}{
+			"confidence": fmt.Sprintf("%.4f", result.Confidence),
+			"threshold":  result.Threshold,
+		})
+		return ErrLowConfidence
+	}
+	return next()
+}
+
+// scoreAnswer computes the confidence of the generated answer, or intercepts the answer stream to
+// compute it once the answer is complete. Without a confidence from the gate, i.e. without retrieved
+// content, the answer is not scored.
+func (p *PluginConfidenceGate) scoreAnswer(ctx context.Context,
+	chatManage *types.ChatManage, next func() *PluginError,
+) *PluginError {
+	if chatManage.Confidence == nil || chatManage.Confidence.Final {
+		return next()
+	}
+	policy := p.resolvePolicy(ctx, chatManage)
+
+	if chatManage.ChatResponse != nil {
+		result := answerConfidence(ctx, chatManage, policy, chatManage.ChatResponse.Content)
+		emitConfidence(ctx, chatManage.EventBus, chatManage.SessionID, result)
+		return next()
+	}
+	if chatManage.EventBus == nil {
+		return next()
+	}
+	chatManage.EventBus = &confidenceEventBus{
+		EventBusInterface: chatManage.EventBus,
+		sessionID:         chatManage.SessionID,
+		score: func(ctx context.Context, answer string) *types.ConfidenceResult {
+			return answerConfidence(ctx, chatManage, policy, answer)
+		},
+	}
+	return next()
+}
+
+// answerConfidence scores the answer with the gate signals and its coverage by the sources. The
+// grounding verdict, when the answer was checked, is more accurate than the lexical coverage.
+func answerConfidence(ctx context.Context, chatManage *types.ChatManage,
+	policy *types.ConfidenceGateConfig, answer string,
+) *types.ConfidenceResult {
+	var coverage *float64
+	if grounding := chatManage.Grounding; grounding != nil && grounding.Error == "" {
+		v := grounding.SupportedRatio
+		coverage = &v
+	} else if v, ok := types.AnswerCoverage(answer, confidenceResults(chatManage)); ok {
+		coverage = &v
+	}
+	result := policy.EvaluateAnswer(chatManage.Confidence.Signals(), coverage)
+	chatManage.Confidence = result
+	logConfidence(ctx, chatManage, result)
+	return result
+}
+
+// confidenceResults returns the retrieved content the question is answered from
+func confidenceResults(chatManage *types.ChatManage) []*types.SearchResult {
+	results := chatManage.MergeResult
+	if len(results) == 0 {
+		results = chatManage.RerankResult
+	}
+	if len(results) == 0 {
+		results = chatManage.SearchResult
+	}
+	return results
+}
+
+// resolvePolicy returns the strictest enabled policy among the searched knowledge bases,
+// or the first configured one when none is enabled
+func (p *PluginConfidenceGate) resolvePolicy(ctx context.Context, chatManage *types.ChatManage) *types.ConfidenceGateConfig {
+	seen := make(map[string]bool)
+	var policy, fallback *types.ConfidenceGateConfig
+	for _, target := range chatManage.SearchTargets {
+		if target == nil || seen[target.KnowledgeBaseID] {
+			continue
+		}
+		seen[target.KnowledgeBaseID] = true
+		kb, err := p.knowledgeBaseService.GetKnowledgeBaseByID(ctx, target.KnowledgeBaseID)
+		if err != nil {
+			pipelineWarn(ctx, "ConfidenceGate", "get_kb", map[string]interface{}{
+				"knowledge_base_id": target.KnowledgeBaseID,
+				"error":             err.Error(),
+			})
+			continue
+		}
+		if kb.ConfidenceGateConfig == nil {
+			continue
+		}
+		if fallback == nil {
+			fallback = kb.ConfidenceGateConfig
+		}
+		policy = policy.Stricter(kb.ConfidenceGateConfig)
+	}
+	if policy == nil || !policy.Enabled {
+		return fallback
+	}
+	return policy
+}
+
+// logConfidence records the gate outcome with enough context to calibrate thresholds later
+func logConfidence(ctx context.Context, chatManage *types.ChatManage, result *types.ConfidenceResult) {
+	fields := map[string]interface{}{
+		"session_id": chatManage.SessionID,
+		"message_id": chatManage.MessageID,
+		"query":      chatManage.Query,
+		"confidence": fmt.Sprintf("%.4f", result.Confidence),
+		"threshold":  result.Threshold,
+		"answer":     result.Answer,
+		"enforced":   result.Enforced,
+	}
+	if result.RetrievalScore != nil {
+		fields["retrieval_score"] = fmt.Sprintf("%.4f", *result.RetrievalScore)
+	}
+	if result.RerankScore != nil {
+		fields["rerank_score"] = fmt.Sprintf("%.4f", *result.RerankScore)
+	}
+	if result.SelfAssessmentScore != nil {
+		fields["self_assessment_score"] = fmt.Sprintf("%.4f", *result.SelfAssessmentScore)
+	}
+	if result.CoverageScore != nil {
+		fields["coverage_score"] = fmt.Sprintf("%.4f", *result.CoverageScore)
+	}
+	action := "result"
+	if result.Final {
+		action = "answer_result"
+	}
+	pipelineInfo(ctx, "ConfidenceGate", action, fields)
+}
+
+// emitConfidence reports the confidence to the client
+func emitConfidence(ctx context.Context, eventBus types.EventBusInterface, sessionID string,
+	result *types.ConfidenceResult,
+) {
+	if eventBus == nil || result == nil {
+		return
+	}
+	if err := eventBus.Emit(ctx, types.Event{
+		ID:        fmt.Sprintf("%s-confidence", uuid.New().String()[:8]),
+		Type:      types.EventType(event.EventConfidence),
+		SessionID: sessionID,
+		Data:      result,
+	}); err != nil {
+		logger.Errorf(ctx, "Failed to emit confidence event: %v", err)
+	}
+}
+
+// confidenceEventBus forwards the streamed answer while collecting it. When the last chunk arrives,
+// the answer is scored and the confidence is emitted just before the last chunk is forwarded, so
+// that it is stored with the completed message.
+type confidenceEventBus struct {
+	types.EventBusInterface
+	sessionID string
+	score     func(ctx context.Context, answer string) *types.ConfidenceResult
+
+	answer strings.Builder
+	done   bool
+}
+
+// Emit collects answer chunks and scores the answer before its last chunk
+func (b *confidenceEventBus) Emit(ctx context.Context, evt types.Event) error {
+	data, ok := evt.Data.(event.AgentFinalAnswerData)
+	if b.done || !ok || evt.Type != types.EventType(event.EventAgentFinalAnswer) {
+		return b.EventBusInterface.Emit(ctx, evt)
+	}
+	b.answer.WriteString(data.Content)
+	if data.Done {
+		b.done = true
+		emitConfidence(ctx, b.EventBusInterface, b.sessionID, b.score(ctx, b.answer.String()))
+	}
+	return b.EventBusInterface.Emit(ctx, evt)
+}
+
+// AssessAnswerability asks the chat model how well the top passages answer the query (0-1)
+func AssessAnswerability(ctx context.Context,
+	modelService interfaces.ModelService,
+	chatModelID, query string,
+	results []*types.SearchResult,
+) (float64, error) {
+	chatModel, err := modelService.GetChatModel(ctx, chatModelID)
+	if err != nil {
+		return 0, err
+	}
+
+	var passages strings.Builder
+	for i, result := range results {
+		if i >= selfAssessmentPassages {
+			break
+		}
+		fmt.Fprintf(&passages, "[%d] %s\n\n", i+1, result.Content)
+	}
+	messages := []chat.Message{
+		{Role: "system", Content: selfAssessmentPrompt},
+		{Role: "user", Content: fmt.Sprintf("Question: %s\n\nPassages:\n%s", query, passages.String())},
+	}
+	resp, err := chatModel.Chat(ctx, messages, &chat.ChatOptions{Temperature: 0, MaxTokens: 16})
+	if err != nil {
+		return 0, err
+	}
+	return parseSelfAssessment(resp.Content)
+}
+
+// parseSelfAssessment extracts the first score in [0, 1] from the model reply
+func parseSelfAssessment(content string) (float64, error) {
+	match := confidenceNumberPattern.FindString(content)
+	if match == "" {
+		return 0, fmt.Errorf("no score in self-assessment reply: %q", content)
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil || score < 0 || score > 1 {
+		return 0, fmt.Errorf("invalid self-assessment score: %q", match)
+	}
+	return score, nil
+}
@@ -0,0 +1,60 @@
+package chatpipline
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestPluginConfidenceGateAnswerStream(t *testing.T) {
+	retrieval := 0.8
+	plugin := &PluginConfidenceGate{
+		knowledgeBaseService: &fakeRerankKnowledgeBaseService{
+			kb: &types.KnowledgeBase{ID: "kb-1", ConfidenceGateConfig: &types.ConfidenceGateConfig{
+				RetrievalWeight: 1,
+				CoverageWeight:  1,
+			}},
+		},
+	}
+	bus := &recordingEventBus{}
+	chatManage := &types.ChatManage{
+		SearchTargets: types.SearchTargets{{KnowledgeBaseID: "kb-1"}},
+		MergeResult:   []*types.SearchResult{{Content: "Passwords expire after 90 days."}},
+		Confidence:    &types.ConfidenceResult{RetrievalScore: &retrieval},
+		EventBus:      bus,
+	}
+	if err := plugin.OnEvent(context.Background(), types.ANSWER_CONFIDENCE, chatManage, func() *PluginError {
+		return nil
+	}); err != nil {
+		t.Fatalf("OnEvent() = %v", err)
+	}
+
+	chunks := []string{"Passwords expire ", "after 90 days. ", "Accounts are locked on Sundays."}
+	for i, chunk := range chunks {
+		_ = chatManage.EventBus.Emit(context.Background(), types.Event{
+			Type: types.EventType(event.EventAgentFinalAnswer),
+			Data: event.AgentFinalAnswerData{Content: chunk, Done: i == len(chunks)-1},
+		})
+	}
+
+	// Chunks are forwarded as they arrive and the confidence precedes the last one
+	if len(bus.events) != len(chunks)+1 {
+		t.Fatalf("events = %d, want %d", len(bus.events), len(chunks)+1)
+	}
+	result, ok := bus.events[len(chunks)-1].Data.(*types.ConfidenceResult)
+	if !ok {
+		t.Fatalf("event before the last chunk = %+v, want the answer confidence", bus.events[len(chunks)-1])
+	}
+	if !result.Final || result.CoverageScore == nil || *result.CoverageScore != 0.5 {
+		t.Errorf("result = %+v, want a final result with coverage 0.5", result)
+	}
+	if math.Abs(result.Confidence-0.65) > 1e-9 {
+		t.Errorf("confidence = %v, want 0.65", result.Confidence)
+	}
+	if chatManage.Confidence != result {
+		t.Errorf("chat confidence = %+v, want the answer confidence", chatManage.Confidence)
+	}
+}
@@ -0,0 +1,64 @@
+package chatpipline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/google/uuid"
+)
+
+// newContextBudgetReport estimates how the prompt built from chatManage spends the context window;
+// contexts is the part of the user content made of retrieved passages
+func newContextBudgetReport(chatManage *types.ChatManage, contexts string) *types.ContextBudgetReport {
+	return types.NewContextBudgetReport(
+		chatManage.SummaryConfig.ContextWindow,
+		chatManage.SummaryConfig.MaxCompletionTokens,
+		renderSystemPromptPlaceholders(chatManage.SummaryConfig.Prompt),
+		chatManage.History,
+		chatManage.UserContent,
+		contexts,
+		len(chatManage.MergeResult),
+	)
+}
+
+// reportContextBudget logs the context budget of the final prompt and streams it to the client,
+// so truncation can be traced back to history or retrieved chunks.
+// Prompts that did not go through INTO_CHAT_MESSAGE are reported without retrieved context.
+func reportContextBudget(ctx context.Context, chatManage *types.ChatManage) {
+	if chatManage.ContextBudget == nil {
+		chatManage.ContextBudget = newContextBudgetReport(chatManage, "")
+	}
+	report := chatManage.ContextBudget
+
+	fields := map[string]interface{}{
+		"session_id":       chatManage.SessionID,
+		"context_window":   report.ContextWindow,
+		"system_tokens":    report.SystemPromptTokens,
+		"history_tokens":   report.HistoryTokens,
+		"retrieved_tokens": report.RetrievedContextTokens,
+		"prompt_tokens":    report.PromptTokens,
+		"remaining_tokens": report.RemainingTokens,
+		"oversized_chunks": len(report.OversizedChunks),
+	}
+	if report.NearLimit() {
+		fields["warning"] = report.Warning
+		pipelineWarn(ctx, "ContextBudget", "near_limit", fields)
+	} else {
+		pipelineInfo(ctx, "ContextBudget", "report", fields)
+	}
+
+	if chatManage.EventBus == nil {
+		return
+	}
+	if err := chatManage.EventBus.Emit(ctx, types.Event{
+		ID:        fmt.Sprintf("%s-context-budget", uuid.New().String()[:8]),
+		Type:      types.EventType(event.EventContextBudget),
+		SessionID: chatManage.SessionID,
+		Data:      report,
+	}); err != nil {
+		logger.Errorf(ctx, "Failed to emit context budget event: %v", err)
+	}
+}
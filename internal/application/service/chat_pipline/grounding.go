@@ -0,0 +1,263 @@
+package chatpipline
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+)
+
+const groundingPrompt = `You check whether the statements of an answer are supported by reference passages.
+For each numbered statement reply with one line "<number>: yes" when the passages state or directly imply it,
+or "<number>: no" when they do not. Statements that only introduce the answer, or say that the passages do not
+contain the information, count as supported. Reply with the lines only.`
+
+var groundingVerdictPattern = regexp.MustCompile(`(?im)^\D*?(\d+)\s*[:.)\-]\s*\**\s*(yes|no)\b`)
+
+// PluginGrounding verifies after generation that the answer is supported by the retrieved chunks, for
+// knowledge bases that enable grounding. Unsupported sentences are annotated, or the answer is replaced
+// with the fallback response, when the share of supported sentences is below the configured strictness.
+//
+// In streaming pipelines the plugin runs before generation and holds back the answer until the last
+// chunk, since a refused answer must never reach the client; without streaming it checks the response.
+type PluginGrounding struct {
+	knowledgeBaseService interfaces.KnowledgeBaseService
+	modelService         interfaces.ModelService
+}
+
+// NewPluginGrounding creates a new PluginGrounding and registers it with the event manager
+func NewPluginGrounding(eventManager *EventManager,
+	knowledgeBaseService interfaces.KnowledgeBaseService,
+	modelService interfaces.ModelService,
+) *PluginGrounding {
+	res := &PluginGrounding{
+		knowledgeBaseService: knowledgeBaseService,
+		modelService:         modelService,
+	}
+	eventManager.Register(res)
+	return res
+}
+
+// ActivationEvents returns the event types this plugin handles
+func (p *PluginGrounding) ActivationEvents() []types.EventType {
+	return []types.EventType{types.GROUNDING_CHECK}
+}
+
+// OnEvent checks the generated answer, or intercepts the answer stream to check it once complete
+func (p *PluginGrounding) OnEvent(ctx context.Context,
+	eventType types.EventType, chatManage *types.ChatManage, next func() *PluginError,
+) *PluginError {
+	policy := p.resolvePolicy(ctx, chatManage)
+	if policy == nil || !policy.Enabled {
+		return next()
+	}
+
+	if chatManage.ChatResponse != nil {
+		content, result := p.check(ctx, chatManage, policy, chatManage.ChatResponse.Content)
+		chatManage.ChatResponse.Content = content
+		emitGrounding(ctx, chatManage.EventBus, chatManage.SessionID, result)
+		return next()
+	}
+	if chatManage.EventBus == nil {
+		return next()
+	}
+
+	pipelineInfo(ctx, "Grounding", "hold_stream", map[string]interface{}{
+		"session_id": chatManage.SessionID,
+		"strictness": policy.EffectiveStrictness(),
+		"action":     policy.EffectiveAction(),
+	})
+	chatManage.EventBus = &groundingEventBus{
+		EventBusInterface: chatManage.EventBus,
+		sessionID:         chatManage.SessionID,
+		check: func(ctx context.Context, answer string) (string, *types.GroundingResult) {
+			return p.check(ctx, chatManage, policy, answer)
+		},
+	}
+	return next()
+}
+
+// resolvePolicy combines the enabled grounding policies of the searched knowledge bases
+func (p *PluginGrounding) resolvePolicy(ctx context.Context, chatManage *types.ChatManage) *types.GroundingConfig {
+	seen := make(map[string]bool)
+	var policy *types.GroundingConfig
+	for _, target := range chatManage.SearchTargets {
+		if target == nil || seen[target.KnowledgeBaseID] {
+			continue
+		}
+		seen[target.KnowledgeBaseID] = true
+		kb, err := p.knowledgeBaseService.GetKnowledgeBaseByID(ctx, target.KnowledgeBaseID)
+		if err != nil {
+			pipelineWarn(ctx, "Grounding", "get_kb", map[string]interface{}{
+				"knowledge_base_id": target.KnowledgeBaseID,
+				"error":             err.Error(),
+			})
+			continue
+		}
+		policy = policy.Stricter(kb.GroundingConfig)
+	}
+	return policy
+}
+
+// check verifies the answer and applies the policy. Returns the answer to deliver and the verdict.
+func (p *PluginGrounding) check(ctx context.Context,
+	chatManage *types.ChatManage, policy *types.GroundingConfig, answer string,
+) (string, *types.GroundingResult) {
+	results := chatManage.MergeResult
+	if len(results) == 0 {
+		results = chatManage.RerankResult
+	}
+	if len(results) == 0 {
+		results = chatManage.SearchResult
+	}
+
+	sentences := types.SplitGroundingSentences(answer)
+	var err error
+	if len(sentences) > 0 {
+		err = VerifyGrounding(ctx, p.modelService, chatManage.ChatModelID, sentences, results)
+	}
+	result := policy.Evaluate(sentences, err)
+	chatManage.Grounding = result
+
+	fields := map[string]interface{}{
+		"session_id":      chatManage.SessionID,
+		"message_id":      chatManage.MessageID,
+		"sentences":       len(sentences),
+		"supported_ratio": fmt.Sprintf("%.4f", result.SupportedRatio),
+		"threshold":       result.Threshold,
+		"grounded":        result.Grounded,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		pipelineWarn(ctx, "Grounding", "check_failed", fields)
+	}
+	if result.Grounded {
+		pipelineInfo(ctx, "Grounding", "result", fields)
+		return answer, result
+	}
+
+	fields["unsupported"] = result.Unsupported
+	if result.Action == types.GroundingActionRefuse {
+		result.Refused = true
+		fields["action"] = "refuse"
+		pipelineWarn(ctx, "Grounding", "result", fields)
+		if policy.FallbackResponse != "" {
+			return policy.FallbackResponse, result
+		}
+		return chatManage.FallbackResponse, result
+	}
+	if err == nil {
+		result.Annotated = true
+		answer = types.AnnotateUnsupported(answer, sentences)
+	}
+	fields["action"] = "annotate"
+	pipelineWarn(ctx, "Grounding", "result", fields)
+	return answer, result
+}
+
+// VerifyGrounding asks the chat model which sentences the passages support and records the
+// verdicts on the sentences. Sentences without a verdict in the reply count as unsupported.
+func VerifyGrounding(ctx context.Context,
+	modelService interfaces.ModelService,
+	chatModelID string,
+	sentences []*types.GroundingSentence,
+	results []*types.SearchResult,
+) error {
+	chatModel, err := modelService.GetChatModel(ctx, chatModelID)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	content.WriteString("Passages:\n")
+	for i, result := range results {
+		fmt.Fprintf(&content, "[%d] %s\n\n", i+1, result.Content)
+	}
+	content.WriteString("Statements:\n")
+	for i, sentence := range sentences {
+		fmt.Fprintf(&content, "%d. %s\n", i+1, sentence.Text)
+	}
+	messages := []chat.Message{
+		{Role: "system", Content: groundingPrompt},
+		{Role: "user", Content: content.String()},
+	}
+	resp, err := chatModel.Chat(ctx, messages, &chat.ChatOptions{
+		Temperature: 0,
+		MaxTokens:   16*len(sentences) + 32,
+	})
+	if err != nil {
+		return err
+	}
+	return parseGroundingVerdicts(resp.Content, sentences)
+}
+
+// parseGroundingVerdicts reads the "<number>: yes|no" lines of the model reply
+func parseGroundingVerdicts(reply string, sentences []*types.GroundingSentence) error {
+	matches := groundingVerdictPattern.FindAllStringSubmatch(reply, -1)
+	if len(matches) == 0 {
+		return fmt.Errorf("no verdict in grounding reply: %q", reply)
+	}
+	for _, match := range matches {
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n < 1 || n > len(sentences) {
+			continue
+		}
+		sentences[n-1].Supported = strings.EqualFold(match[2], "yes")
+	}
+	return nil
+}
+
+// emitGrounding reports the grounding verdict to the client
+func emitGrounding(ctx context.Context, eventBus types.EventBusInterface, sessionID string,
+	result *types.GroundingResult,
+) {
+	if eventBus == nil || result == nil {
+		return
+	}
+	if err := eventBus.Emit(ctx, types.Event{
+		ID:        fmt.Sprintf("%s-grounding", uuid.New().String()[:8]),
+		Type:      types.EventType(event.EventGrounding),
+		SessionID: sessionID,
+		Data:      result,
+	}); err != nil {
+		logger.Errorf(ctx, "Failed to emit grounding event: %v", err)
+	}
+}
+
+// groundingEventBus collects the streamed answer instead of forwarding it. When the last chunk
+// arrives, the answer is checked, the verdict is emitted and the checked answer is forwarded as a
+// single chunk. Other events pass through.
+type groundingEventBus struct {
+	types.EventBusInterface
+	sessionID string
+	check     func(ctx context.Context, answer string) (string, *types.GroundingResult)
+
+	answer strings.Builder
+	done   bool
+}
+
+// Emit holds back answer chunks until the answer is complete
+func (b *groundingEventBus) Emit(ctx context.Context, evt types.Event) error {
+	data, ok := evt.Data.(event.AgentFinalAnswerData)
+	if b.done || !ok || evt.Type != types.EventType(event.EventAgentFinalAnswer) {
+		return b.EventBusInterface.Emit(ctx, evt)
+	}
+	b.answer.WriteString(data.Content)
+	if !data.Done {
+		return nil
+	}
+	b.done = true
+
+	content, result := b.check(ctx, b.answer.String())
+	emitGrounding(ctx, b.EventBusInterface, b.sessionID, result)
+	evt.Data = event.AgentFinalAnswerData{Content: content, Done: true, Usage: data.Usage}
+	return b.EventBusInterface.Emit(ctx, evt)
+}
@@ -0,0 +1,175 @@
+package chatpipline
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeJudge replies to every chat with the same content
+type fakeJudge struct {
+	reply string
+}
+
+func (j *fakeJudge) Chat(context.Context, []chat.Message, *chat.ChatOptions) (*types.ChatResponse, error) {
+	return &types.ChatResponse{Content: j.reply}, nil
+}
+
+func (j *fakeJudge) ChatStream(context.Context, []chat.Message, *chat.ChatOptions) (<-chan types.StreamResponse, error) {
+	return nil, errors.New("streaming is not supported")
+}
+
+func (j *fakeJudge) GetModelName() string { return "judge" }
+
+func (j *fakeJudge) GetModelID() string { return "judge" }
+
+// fakeGroundingModelService serves the judge as chat model
+type fakeGroundingModelService struct {
+	interfaces.ModelService
+	judge *fakeJudge
+}
+
+func (s *fakeGroundingModelService) GetChatModel(context.Context, string) (chat.Chat, error) {
+	return s.judge, nil
+}
+
+// recordingEventBus records the emitted events
+type recordingEventBus struct {
+	events []types.Event
+}
+
+func (b *recordingEventBus) On(types.EventType, types.EventHandler) {}
+
+func (b *recordingEventBus) Emit(_ context.Context, evt types.Event) error {
+	b.events = append(b.events, evt)
+	return nil
+}
+
+func TestPluginGroundingStream(t *testing.T) {
+	chunks := []string{
+		"<think>Check the passages.</think>", "Passwords expire after 90 days. ", "Expired passwords ", "are deleted.",
+	}
+	tests := []struct {
+		name        string
+		policy      *types.GroundingConfig
+		reply       string
+		wantAnswer  string
+		wantVerdict bool
+	}{
+		{
+			name:       "disabled",
+			policy:     &types.GroundingConfig{Strictness: types.GroundingStrictnessStrict},
+			wantAnswer: "<think>Check the passages.</think>Passwords expire after 90 days. Expired passwords are deleted.",
+		},
+		{
+			name:        "grounded",
+			policy:      &types.GroundingConfig{Enabled: true, Strictness: types.GroundingStrictnessLenient},
+			reply:       "1: yes\n2: no",
+			wantAnswer:  "<think>Check the passages.</think>Passwords expire after 90 days. Expired passwords are deleted.",
+			wantVerdict: true,
+		},
+		{
+			name:   "annotate",
+			policy: &types.GroundingConfig{Enabled: true},
+			reply:  "1: yes\n2: no",
+			wantAnswer: "<think>Check the passages.</think>Passwords expire after 90 days. Expired passwords are deleted." +
+				types.GroundingUnsupportedMarker,
+			wantVerdict: true,
+		},
+		{
+			name:        "refuse",
+			policy:      &types.GroundingConfig{Enabled: true, Action: types.GroundingActionRefuse},
+			reply:       "1. **Yes**\n2. No",
+			wantAnswer:  "I cannot answer this from the knowledge base.",
+			wantVerdict: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &PluginGrounding{
+				modelService: &fakeGroundingModelService{judge: &fakeJudge{reply: tt.reply}},
+				knowledgeBaseService: &fakeRerankKnowledgeBaseService{
+					kb: &types.KnowledgeBase{ID: "kb-1", GroundingConfig: tt.policy},
+				},
+			}
+			bus := &recordingEventBus{}
+			chatManage := &types.ChatManage{
+				SearchTargets:    types.SearchTargets{{KnowledgeBaseID: "kb-1"}},
+				MergeResult:      []*types.SearchResult{{Content: "Passwords expire after 90 days."}},
+				FallbackResponse: "I cannot answer this from the knowledge base.",
+				EventBus:         bus,
+			}
+			if err := plugin.OnEvent(context.Background(), types.GROUNDING_CHECK, chatManage, func() *PluginError {
+				return nil
+			}); err != nil {
+				t.Fatalf("OnEvent() = %v", err)
+			}
+
+			// The generation stage streams the answer to the bus installed by the plugin
+			for i, chunk := range chunks {
+				_ = chatManage.EventBus.Emit(context.Background(), types.Event{
+					Type: types.EventType(event.EventAgentFinalAnswer),
+					Data: event.AgentFinalAnswerData{Content: chunk, Done: i == len(chunks)-1},
+				})
+			}
+
+			var answer string
+			var verdict *types.GroundingResult
+			for _, evt := range bus.events {
+				switch data := evt.Data.(type) {
+				case event.AgentFinalAnswerData:
+					if verdict == nil && tt.wantVerdict {
+						t.Error("answer emitted before the grounding verdict")
+					}
+					answer += data.Content
+				case *types.GroundingResult:
+					verdict = data
+				}
+			}
+			if answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", answer, tt.wantAnswer)
+			}
+			if (verdict != nil) != tt.wantVerdict {
+				t.Fatalf("verdict = %+v, want verdict %v", verdict, tt.wantVerdict)
+			}
+			if verdict != nil && len(verdict.Unsupported) != 1 {
+				t.Errorf("unsupported = %q, want the second sentence", verdict.Unsupported)
+			}
+		})
+	}
+}
+
+func TestPluginGroundingCompletion(t *testing.T) {
+	plugin := &PluginGrounding{
+		modelService: &fakeGroundingModelService{judge: &fakeJudge{reply: "I cannot tell."}},
+		knowledgeBaseService: &fakeRerankKnowledgeBaseService{
+			kb: &types.KnowledgeBase{ID: "kb-1", GroundingConfig: &types.GroundingConfig{
+				Enabled:          true,
+				Strictness:       types.GroundingStrictnessStrict,
+				Action:           types.GroundingActionRefuse,
+				FallbackResponse: "Not in the documentation.",
+			}},
+		},
+	}
+	chatManage := &types.ChatManage{
+		SearchTargets: types.SearchTargets{{KnowledgeBaseID: "kb-1"}},
+		MergeResult:   []*types.SearchResult{{Content: "Passwords expire after 90 days."}},
+		ChatResponse:  &types.ChatResponse{Content: "Passwords never expire."},
+	}
+	if err := plugin.OnEvent(context.Background(), types.GROUNDING_CHECK, chatManage, func() *PluginError {
+		return nil
+	}); err != nil {
+		t.Fatalf("OnEvent() = %v", err)
+	}
+	if chatManage.ChatResponse.Content != "Not in the documentation." {
+		t.Errorf("answer = %q, want the fallback response", chatManage.ChatResponse.Content)
+	}
+	if chatManage.Grounding == nil || !chatManage.Grounding.Refused || chatManage.Grounding.Error == "" {
+		t.Errorf("grounding = %+v, want a refusal after the failed check", chatManage.Grounding)
+	}
+}
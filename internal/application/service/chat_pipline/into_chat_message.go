@@ -37,6 +37,14 @@ func (p *PluginIntoChatMessage) OnEvent(ctx context.Context,
 		"template_len":     len(chatManage.SummaryConfig.ContextTemplate),
 	})
 
+	// Prompt injection defense configured for the tenant, nil when disabled
+	defense := newRetrievedContentDefense(ctx)
+	// Single chunks larger than the context budget are reduced before the defense scans them
+	fitter := newPassageFitter(chatManage)
+	passageOf := func(source string, result *types.SearchResult) string {
+		return defense.passage(ctx, source, result, fitter.fit(ctx, result, getEnrichedPassageForChat(ctx, result)))
+	}
+
 	// Separate FAQ and document results when FAQ priority is enabled
 	var faqResults, docResults []*types.SearchResult
 	var hasHighConfidenceFAQ bool
@@ -85,7 +93,7 @@ func (p *PluginIntoChatMessage) OnEvent(ctx context.Context,
 		contextsBuilder.WriteString("### 资料来源 1：标准问答库 (FAQ)\n")
 		contextsBuilder.WriteString("【高置信度 - 请优先参考】\n")
 		for i, result := range faqResults {
-			passage := getEnrichedPassageForChat(ctx, result)
+			passage := passageOf(fmt.Sprintf("FAQ-%d", i+1), result)
 			if hasHighConfidenceFAQ && i == 0 {
 				contextsBuilder.WriteString(fmt.Sprintf("[FAQ-%d] ⭐ 精准匹配: %s\n", i+1, passage))
 			} else {
@@ -97,7 +105,7 @@ func (p *PluginIntoChatMessage) OnEvent(ctx context.Context,
 			contextsBuilder.WriteString("\n### 资料来源 2：参考文档\n")
 			contextsBuilder.WriteString("【补充资料 - 仅在FAQ无法解答时参考】\n")
 			for i, result := range docResults {
-				passage := getEnrichedPassageForChat(ctx, result)
+				passage := passageOf(fmt.Sprintf("DOC-%d", i+1), result)
 				contextsBuilder.WriteString(fmt.Sprintf("[DOC-%d] %s\n", i+1, passage))
 			}
 		}
@@ -105,7 +113,7 @@ func (p *PluginIntoChatMessage) OnEvent(ctx context.Context,
 		// Original behavior: simple numbered list
 		passages := make([]string, len(chatManage.MergeResult))
 		for i, result := range chatManage.MergeResult {
-			passages[i] = getEnrichedPassageForChat(ctx, result)
+			passages[i] = passageOf(fmt.Sprintf("%d", i+1), result)
 		}
 		for i, passage := range passages {
 			if i > 0 {
@@ -124,11 +132,21 @@ func (p *PluginIntoChatMessage) OnEvent(ctx context.Context,
 
 	// Set formatted content back to chat management
 	chatManage.UserContent = userContent
+	chatManage.SummaryConfig.Prompt = defense.systemPrompt(chatManage.SummaryConfig.Prompt)
+	if defense != nil && defense.flagged > 0 {
+		pipelineWarn(ctx, "IntoChatMessage", "prompt_injection_summary", map[string]interface{}{
+			"session_id": chatManage.SessionID,
+			"flagged":    defense.flagged,
+			"action":     defense.config.GetAction(),
+		})
+	}
 	pipelineInfo(ctx, "IntoChatMessage", "output", map[string]interface{}{
 		"session_id":       chatManage.SessionID,
 		"user_content_len": len(chatManage.UserContent),
 		"faq_priority":     chatManage.FAQPriorityEnabled,
 	})
+	chatManage.ContextBudget = newContextBudgetReport(chatManage, contextsBuilder.String())
+	chatManage.ContextBudget.OversizedChunks = fitter.actions
 	return next()
 }
 
@@ -47,10 +47,15 @@ func (p *PluginLoadHistory) ActivationEvents() []types.EventType {
 func (p *PluginLoadHistory) OnEvent(ctx context.Context,
 	eventType types.EventType, chatManage *types.ChatManage, next func() *PluginError,
 ) *PluginError {
-	// Determine max rounds from config or request
-	maxRounds := p.config.Conversation.MaxRounds
-	if chatManage.MaxRounds > 0 {
-		maxRounds = chatManage.MaxRounds
+	// Determine max rounds from history depth, request or config
+	maxRounds := chatManage.HistoryRounds(p.config.Conversation.MaxRounds)
+	if maxRounds <= 0 {
+		pipelineInfo(ctx, "LoadHistory", "skip", map[string]interface{}{
+			"session_id": chatManage.SessionID,
+			"reason":     "history_depth_zero",
+		})
+		chatManage.History = nil
+		return next()
 	}
 
 	pipelineInfo(ctx, "LoadHistory", "input", map[string]interface{}{
@@ -59,7 +64,15 @@ func (p *PluginLoadHistory) OnEvent(ctx context.Context,
 	})
 
 	// Get conversation history (fetch more to account for incomplete pairs)
-	history, err := p.messageService.GetRecentMessagesBySession(ctx, chatManage.SessionID, maxRounds*2+10)
+	var history []*types.Message
+	var err error
+	if chatManage.HistoryBefore.IsZero() {
+		history, err = p.messageService.GetRecentMessagesBySession(ctx, chatManage.SessionID, maxRounds*2+10)
+	} else {
+		history, err = p.messageService.GetMessagesBySessionBeforeTime(
+			ctx, chatManage.SessionID, chatManage.HistoryBefore, maxRounds*2+10,
+		)
+	}
 	if err != nil {
 		pipelineWarn(ctx, "LoadHistory", "history_fetch", map[string]interface{}{
 			"session_id": chatManage.SessionID,
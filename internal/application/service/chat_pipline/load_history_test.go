@@ -0,0 +1,100 @@
+package chatpipline
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeHistoryMessageService serves the messages of one session, newest first
+type fakeHistoryMessageService struct {
+	interfaces.MessageService
+	messages []*types.Message
+}
+
+func (s *fakeHistoryMessageService) GetRecentMessagesBySession(
+	_ context.Context, _ string, limit int,
+) ([]*types.Message, error) {
+	return s.messages[:min(limit, len(s.messages))], nil
+}
+
+func (s *fakeHistoryMessageService) GetMessagesBySessionBeforeTime(
+	_ context.Context, _ string, beforeTime time.Time, limit int,
+) ([]*types.Message, error) {
+	var messages []*types.Message
+	for _, message := range s.messages {
+		if message.CreatedAt.Before(beforeTime) && len(messages) < limit {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func TestPluginLoadHistoryBefore(t *testing.T) {
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	turnTime := func(turn int) time.Time { return start.Add(time.Duration(turn) * time.Minute) }
+	service := &fakeHistoryMessageService{}
+	for turn := 4; turn >= 1; turn-- {
+		requestID := fmt.Sprintf("req-%d", turn)
+		service.messages = append(service.messages,
+			&types.Message{RequestID: requestID, Role: "assistant", Content: fmt.Sprintf("answer %d", turn),
+				CreatedAt: turnTime(turn).Add(time.Second)},
+			&types.Message{RequestID: requestID, Role: "user", Content: fmt.Sprintf("question %d", turn),
+				CreatedAt: turnTime(turn)},
+		)
+	}
+	plugin := &PluginLoadHistory{
+		messageService: service,
+		config:         &config.Config{Conversation: &config.ConversationConfig{MaxRounds: 5}},
+	}
+
+	tests := []struct {
+		name          string
+		historyBefore time.Time
+		want          []string
+	}{
+		{name: "most recent turns", want: []string{"question 1", "question 2", "question 3", "question 4"}},
+		{
+			name:          "turns before a regenerated answer",
+			historyBefore: turnTime(3),
+			want:          []string{"question 1", "question 2"},
+		},
+		{name: "regenerating the first turn", historyBefore: turnTime(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatManage := &types.ChatManage{SessionID: "session-1", HistoryBefore: tt.historyBefore}
+			err := plugin.OnEvent(context.Background(), types.LOAD_HISTORY, chatManage, func() *PluginError { return nil })
+			if err != nil {
+				t.Fatalf("OnEvent() = %v", err)
+			}
+			var got []string
+			for _, h := range chatManage.History {
+				got = append(got, h.Query)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("history = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegeneratePipelineSkipsRetrieval(t *testing.T) {
+	pipeline := types.Pipline["regenerate_stream"]
+	for _, event := range []types.EventType{
+		types.REWRITE_QUERY, types.CHUNK_SEARCH, types.CHUNK_SEARCH_PARALLEL, types.CHUNK_RERANK,
+	} {
+		if slices.Contains(pipeline, event) {
+			t.Errorf("regenerate_stream runs %s", event)
+		}
+	}
+	if !slices.Contains(pipeline, types.LOAD_HISTORY) || !slices.Contains(pipeline, types.CHAT_COMPLETION_STREAM) {
+		t.Errorf("regenerate_stream = %v, want history loading and streamed completion", pipeline)
+	}
+}
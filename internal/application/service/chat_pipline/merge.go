@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/Tencent/WeKnora/internal/database"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 )
@@ -33,6 +34,8 @@ func (p *PluginMerge) ActivationEvents() []types.EventType {
 func (p *PluginMerge) OnEvent(ctx context.Context,
 	eventType types.EventType, chatManage *types.ChatManage, next func() *PluginError,
 ) *PluginError {
+	// Chunk lookups of retrieval tolerate replication lag
+	ctx = database.WithReplicaReads(ctx)
 	pipelineInfo(ctx, "Merge", "input", map[string]interface{}{
 		"session_id":    chatManage.SessionID,
 		"candidate_cnt": len(chatManage.RerankResult),
@@ -118,6 +121,9 @@ func (p *PluginMerge) OnEvent(ctx context.Context,
 				if chunks[i].Score > lastChunk.Score {
 					lastChunk.Score = chunks[i].Score
 				}
+				if lastChunk.Pinned == nil {
+					lastChunk.Pinned = chunks[i].Pinned
+				}
 			}
 
 			pipelineInfo(ctx, "Merge", "group_output", map[string]interface{}{
@@ -134,6 +140,11 @@ func (p *PluginMerge) OnEvent(ctx context.Context,
 		}
 	}
 
+	// Results pinned for the query stay ahead of the organic ones
+	sort.SliceStable(mergedChunks, func(i, j int) bool {
+		return pinnedRank(mergedChunks[i]) < pinnedRank(mergedChunks[j])
+	})
+
 	pipelineInfo(ctx, "Merge", "output", map[string]interface{}{
 		"merged_total": len(mergedChunks),
 	})
@@ -145,6 +156,14 @@ func (p *PluginMerge) OnEvent(ctx context.Context,
 	return next()
 }
 
+// pinnedRank orders pinned results before organic ones
+func pinnedRank(result *types.SearchResult) int {
+	if result.Pinned != nil {
+		return 0
+	}
+	return 1
+}
+
 // mergeImageInfo 合并两个chunk的ImageInfo
 func mergeImageInfo(ctx context.Context, target *types.SearchResult, source *types.SearchResult) error {
 	// 如果source没有ImageInfo，不需要合并
@@ -0,0 +1,165 @@
+package chatpipline
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// minOversizedPassageTokens is the smallest size an oversized passage is reduced to, even when the rest of
+// the prompt already fills the context window
+const minOversizedPassageTokens = 256
+
+// truncatedPassageMarker ends a passage cut to fit the context budget
+const truncatedPassageMarker = "\n[...]"
+
+// sentenceRegex matches a sentence with its closing punctuation, or a line without one
+var sentenceRegex = regexp.MustCompile(`[^.!?。！？\n]+[.!?。！？]*`)
+
+// passageFitter reduces retrieved passages that alone exceed the prompt's context budget, so that a single
+// huge chunk degrades the answer instead of failing the model call
+type passageFitter struct {
+	strategy string
+	query    string
+	// budget is the estimated number of tokens the prompt leaves for retrieved passages
+	budget int
+	// share is the number of tokens an oversized passage is reduced to
+	share   int
+	actions []*types.OversizedChunkAction
+}
+
+// newPassageFitter estimates the context budget left for the retrieved passages of chatManage
+func newPassageFitter(chatManage *types.ChatManage) *passageFitter {
+	contextWindow := chatManage.SummaryConfig.ContextWindow
+	if contextWindow <= 0 {
+		contextWindow = types.DefaultContextWindow
+	}
+	// Everything but the passages: system prompt, history, the context template and the query
+	used := types.EstimateTextTokens(renderSystemPromptPlaceholders(chatManage.SummaryConfig.Prompt)) +
+		types.EstimateTextTokens(chatManage.SummaryConfig.ContextTemplate) +
+		types.EstimateTextTokens(chatManage.Query)
+	for _, h := range chatManage.History {
+		used += types.EstimateTextTokens(h.Query) + types.EstimateTextTokens(h.Answer)
+	}
+	budget := contextWindow - max(chatManage.SummaryConfig.MaxCompletionTokens, 0) - used
+	share := budget / max(len(chatManage.MergeResult), 1)
+	return &passageFitter{
+		strategy: types.ResolveOversizedChunkStrategy(chatManage.SummaryConfig.OversizedChunkStrategy),
+		query:    chatManage.Query,
+		budget:   max(budget, minOversizedPassageTokens),
+		share:    max(share, minOversizedPassageTokens),
+	}
+}
+
+// fit returns the passage of a search result, reduced to its share of the budget when it alone exceeds
+// the whole budget
+func (f *passageFitter) fit(ctx context.Context, result *types.SearchResult, passage string) string {
+	tokens := types.EstimateTextTokens(passage)
+	if tokens <= f.budget {
+		return passage
+	}
+	var fitted string
+	if f.strategy == types.OversizedChunkSummarize {
+		fitted = summarizePassage(passage, f.query, f.share)
+	} else {
+		fitted = truncatePassage(passage, f.share)
+	}
+	action := &types.OversizedChunkAction{
+		ChunkID:        result.ID,
+		Action:         f.strategy,
+		OriginalTokens: tokens,
+		Tokens:         types.EstimateTextTokens(fitted),
+	}
+	f.actions = append(f.actions, action)
+	pipelineWarn(ctx, "IntoChatMessage", "oversized_chunk", map[string]interface{}{
+		"chunk_id":        action.ChunkID,
+		"action":          action.Action,
+		"original_tokens": action.OriginalTokens,
+		"tokens":          action.Tokens,
+		"budget":          f.budget,
+	})
+	return fitted
+}
+
+// truncatePassage keeps the beginning of a passage within maxTokens, cut on a character boundary
+func truncatePassage(passage string, maxTokens int) string {
+	maxBytes := maxTokens*4 - len(truncatedPassageMarker)
+	if maxBytes >= len(passage) {
+		return passage
+	}
+	for maxBytes > 0 && !utf8.RuneStart(passage[maxBytes]) {
+		maxBytes--
+	}
+	return passage[:maxBytes] + truncatedPassageMarker
+}
+
+// summarizePassage keeps the sentences of a passage sharing the most character pairs with the query,
+// in their original order, within maxTokens. Without any sentence matching the query it truncates.
+func summarizePassage(passage, query string, maxTokens int) string {
+	queryPairs := characterPairs(query)
+	sentences := sentenceRegex.FindAllString(passage, -1)
+	scores := make([]int, len(sentences))
+	order := make([]int, len(sentences))
+	for i, sentence := range sentences {
+		order[i] = i
+		for pair := range characterPairs(sentence) {
+			if _, ok := queryPairs[pair]; ok {
+				scores[i]++
+			}
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	if len(order) == 0 || scores[order[0]] == 0 {
+		return truncatePassage(passage, maxTokens)
+	}
+
+	maxBytes := maxTokens*4 - len(truncatedPassageMarker)
+	kept := make([]bool, len(sentences))
+	size := 0
+	for _, i := range order {
+		sentence := strings.TrimSpace(sentences[i])
+		if scores[i] == 0 || sentence == "" || size+len(sentence)+1 > maxBytes {
+			continue
+		}
+		kept[i] = true
+		size += len(sentence) + 1
+	}
+	var builder strings.Builder
+	for i, sentence := range sentences {
+		if !kept[i] {
+			continue
+		}
+		if builder.Len() > 0 {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(strings.TrimSpace(sentence))
+	}
+	if builder.Len() == 0 {
+		return truncatePassage(passage, maxTokens)
+	}
+	builder.WriteString(truncatedPassageMarker)
+	return builder.String()
+}
+
+// characterPairs returns the set of adjacent letter or digit pairs of a text, ignoring case. Pairs rather
+// than words keep the matching meaningful for languages written without spaces.
+func characterPairs(text string) map[string]struct{} {
+	pairs := make(map[string]struct{})
+	var prev rune
+	for _, r := range strings.ToLower(text) {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			prev = 0
+			continue
+		}
+		if prev != 0 {
+			pairs[string([]rune{prev, r})] = struct{}{}
+		}
+		prev = r
+	}
+	return pairs
+}
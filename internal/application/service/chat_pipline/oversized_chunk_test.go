@@ -0,0 +1,103 @@
+package chatpipline
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestPluginIntoChatMessageOversizedChunk(t *testing.T) {
+	const refundSentence = "Refunds are issued within 14 days."
+	// Far larger than the context window, with the only relevant sentence at the end
+	oversized := strings.Repeat("Sky is grey. ", 8000) + refundSentence
+
+	tests := []struct {
+		name        string
+		strategy    string
+		content     string
+		wantAction  string
+		wantRefund  bool
+		wantContent bool
+	}{
+		{name: "small chunk is kept", content: refundSentence, wantRefund: true, wantContent: true},
+		{name: "truncated by default", content: oversized, wantAction: types.OversizedChunkTruncate},
+		{
+			name:       "summarized keeps the relevant sentence",
+			strategy:   types.OversizedChunkSummarize,
+			content:    oversized,
+			wantAction: types.OversizedChunkSummarize,
+			wantRefund: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatManage := &types.ChatManage{
+				Query: "How long do refunds take?",
+				SummaryConfig: types.SummaryConfig{
+					Prompt:                 "Answer from the passages.",
+					ContextTemplate:        "Passages:\n{{contexts}}\n\nQuestion: {{query}}",
+					ContextWindow:          4096,
+					MaxCompletionTokens:    1024,
+					OversizedChunkStrategy: tt.strategy,
+				},
+				MergeResult: []*types.SearchResult{{ID: "chunk-1", Content: tt.content}},
+			}
+			nextCalled := false
+			err := (&PluginIntoChatMessage{}).OnEvent(context.Background(), types.INTO_CHAT_MESSAGE, chatManage,
+				func() *PluginError {
+					nextCalled = true
+					return nil
+				})
+			if err != nil || !nextCalled {
+				t.Fatalf("OnEvent = %v, next called %v", err, nextCalled)
+			}
+
+			report := chatManage.ContextBudget
+			if report.PromptTokens+chatManage.SummaryConfig.MaxCompletionTokens > report.ContextWindow {
+				t.Errorf("prompt of %d tokens leaves no room for the answer in %d", report.PromptTokens, report.ContextWindow)
+			}
+			if got := strings.Contains(chatManage.UserContent, refundSentence); got != tt.wantRefund {
+				t.Errorf("prompt contains the relevant sentence = %v, want %v", got, tt.wantRefund)
+			}
+			if got := strings.Contains(chatManage.UserContent, tt.content); got != tt.wantContent {
+				t.Errorf("prompt contains the whole chunk = %v, want %v", got, tt.wantContent)
+			}
+			if tt.wantAction == "" {
+				if len(report.OversizedChunks) != 0 {
+					t.Errorf("unexpected oversized chunks: %+v", report.OversizedChunks[0])
+				}
+				return
+			}
+			if len(report.OversizedChunks) != 1 {
+				t.Fatalf("oversized chunks = %d, want 1", len(report.OversizedChunks))
+			}
+			action := report.OversizedChunks[0]
+			if action.ChunkID != "chunk-1" || action.Action != tt.wantAction || action.Tokens >= action.OriginalTokens {
+				t.Errorf("oversized chunk action = %+v, want %s", action, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestTruncatePassage(t *testing.T) {
+	tests := []struct {
+		name      string
+		passage   string
+		maxTokens int
+		want      string
+	}{
+		{name: "fits", passage: "short", maxTokens: 10, want: "short"},
+		{name: "cut", passage: strings.Repeat("a", 100), maxTokens: 10, want: strings.Repeat("a", 34) + truncatedPassageMarker},
+		// Each character takes 3 bytes, the cut must not split one
+		{name: "multibyte", passage: strings.Repeat("退", 40), maxTokens: 10, want: strings.Repeat("退", 11) + truncatedPassageMarker},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncatePassage(tt.passage, tt.maxTokens); got != tt.want {
+				t.Errorf("truncatePassage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
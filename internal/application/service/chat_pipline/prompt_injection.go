@@ -0,0 +1,87 @@
+package chatpipline
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// Metadata keys set on search results whose content matched a prompt injection pattern;
+// they are returned with the message references
+const (
+	promptInjectionMetadataAction   = "prompt_injection"
+	promptInjectionMetadataPatterns = "prompt_injection_patterns"
+)
+
+// retrievedContentDefense applies the tenant's prompt injection defense to retrieved passages
+type retrievedContentDefense struct {
+	config  *types.PromptInjectionConfig
+	scanner *types.PromptInjectionScanner
+	flagged int
+}
+
+// newRetrievedContentDefense returns the defense configured for the tenant in ctx, nil when it is disabled
+func newRetrievedContentDefense(ctx context.Context) *retrievedContentDefense {
+	tenant, _ := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil || tenant.PromptInjectionConfig == nil {
+		return nil
+	}
+	config := tenant.PromptInjectionConfig
+	if !config.Enabled && !config.ScanEnabled {
+		return nil
+	}
+	return &retrievedContentDefense{config: config, scanner: config.NewScanner()}
+}
+
+// passage scans and wraps the passage of a search result. Results with injection patterns are marked in
+// their metadata and, depending on the action, neutralized or replaced.
+func (d *retrievedContentDefense) passage(ctx context.Context, source string,
+	result *types.SearchResult, passage string,
+) string {
+	if d == nil {
+		return passage
+	}
+	if patterns := d.scanner.Detect(passage); len(patterns) > 0 {
+		d.flagged++
+		action := d.config.GetAction()
+		pipelineWarn(ctx, "IntoChatMessage", "prompt_injection_detected", map[string]interface{}{
+			"chunk_id": result.ID,
+			"patterns": strings.Join(patterns, ","),
+			"action":   action,
+		})
+		// Copy the metadata, results may be shared with coalesced searches
+		metadata := maps.Clone(result.Metadata)
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[promptInjectionMetadataAction] = action
+		metadata[promptInjectionMetadataPatterns] = strings.Join(patterns, ",")
+		result.Metadata = metadata
+
+		switch action {
+		case types.PromptInjectionActionNeutralize:
+			passage = d.scanner.Neutralize(passage)
+		case types.PromptInjectionActionDrop:
+			// Keep a placeholder so that citation numbers still match the references
+			passage = types.PromptInjectionNeutralized
+		}
+	}
+	if d.config.Enabled {
+		return types.WrapRetrievedContent(source, passage)
+	}
+	return passage
+}
+
+// systemPrompt adds the guidance on delimited content to the system prompt
+func (d *retrievedContentDefense) systemPrompt(prompt string) string {
+	if d == nil || !d.config.Enabled || strings.Contains(prompt, types.PromptInjectionGuidance) {
+		return prompt
+	}
+	if prompt == "" {
+		return types.PromptInjectionGuidance
+	}
+	return fmt.Sprintf("%s\n\n%s", prompt, types.PromptInjectionGuidance)
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/models/rerank"
 	"github.com/Tencent/WeKnora/internal/searchutil"
@@ -15,13 +17,18 @@ import (
 
 // PluginRerank implements reranking functionality for chat pipeline
 type PluginRerank struct {
-	modelService interfaces.ModelService // Service to access rerank models
+	modelService         interfaces.ModelService         // Service to access rerank models
+	knowledgeBaseService interfaces.KnowledgeBaseService // Service to read the rerank fallback policies
 }
 
 // NewPluginRerank creates a new rerank plugin instance
-func NewPluginRerank(eventManager *EventManager, modelService interfaces.ModelService) *PluginRerank {
+func NewPluginRerank(eventManager *EventManager,
+	modelService interfaces.ModelService,
+	knowledgeBaseService interfaces.KnowledgeBaseService,
+) *PluginRerank {
 	res := &PluginRerank{
-		modelService: modelService,
+		modelService:         modelService,
+		knowledgeBaseService: knowledgeBaseService,
 	}
 	eventManager.Register(res)
 	return res
@@ -57,21 +64,31 @@ func (p *PluginRerank) OnEvent(ctx context.Context,
 	}
 
 	// Get rerank model from service
+	policy := p.resolvePolicy(ctx, chatManage)
 	rerankModel, err := p.modelService.GetRerankModel(ctx, chatManage.RerankModelID)
 	if err != nil {
 		pipelineError(ctx, "Rerank", "get_model", map[string]interface{}{
 			"model_id": chatManage.RerankModelID,
 			"error":    err.Error(),
 		})
-		return ErrGetRerankModel.WithError(err)
+		if policy.FailOnError() {
+			return ErrGetRerankModel.WithError(err)
+		}
+		return p.fallbackToRetrievalOrder(ctx, chatManage, err, next)
 	}
 
 	// Prepare passages for reranking (excluding DirectLoad results)
 	var passages []string
 	var candidatesToRerank []*types.SearchResult
 	var directLoadResults []*types.SearchResult
+	var pinnedResults []*types.SearchResult
 
 	for _, result := range chatManage.SearchResult {
+		// Pinned results are curated for the query and stay on top regardless of the rerank score
+		if result.Pinned != nil {
+			pinnedResults = append(pinnedResults, result)
+			continue
+		}
 		if result.MatchType == types.MatchTypeDirectLoad {
 			directLoadResults = append(directLoadResults, result)
 			pipelineInfo(ctx, "Rerank", "direct_load_skip", map[string]interface{}{
@@ -89,6 +106,7 @@ func (p *PluginRerank) OnEvent(ctx context.Context,
 		"total_cnt":     len(chatManage.SearchResult),
 		"candidate_cnt": len(candidatesToRerank),
 		"direct_cnt":    len(directLoadResults),
+		"pinned_cnt":    len(pinnedResults),
 	})
 
 	var rerankResp []rerank.RankResult
@@ -97,10 +115,11 @@ func (p *PluginRerank) OnEvent(ctx context.Context,
 	if len(candidatesToRerank) > 0 {
 		// Single rerank call with RewriteQuery, use threshold degradation if no results
 		originalThreshold := chatManage.RerankThreshold
-		rerankResp = p.rerank(ctx, chatManage, rerankModel, chatManage.RewriteQuery, passages, candidatesToRerank)
+		rerankResp, err = p.rerank(ctx, chatManage, rerankModel, chatManage.RewriteQuery, passages,
+			candidatesToRerank, policy.Timeout())
 
 		// If no results and threshold is high enough, try with lower threshold
-		if len(rerankResp) == 0 && originalThreshold > 0.3 {
+		if err == nil && len(rerankResp) == 0 && originalThreshold > 0.3 {
 			degradedThreshold := originalThreshold * 0.7
 			if degradedThreshold < 0.3 {
 				degradedThreshold = 0.3
@@ -110,10 +129,17 @@ func (p *PluginRerank) OnEvent(ctx context.Context,
 				"degraded": degradedThreshold,
 			})
 			chatManage.RerankThreshold = degradedThreshold
-			rerankResp = p.rerank(ctx, chatManage, rerankModel, chatManage.RewriteQuery, passages, candidatesToRerank)
+			rerankResp, err = p.rerank(ctx, chatManage, rerankModel, chatManage.RewriteQuery, passages,
+				candidatesToRerank, policy.Timeout())
 			// Restore original threshold
 			chatManage.RerankThreshold = originalThreshold
 		}
+		if err != nil {
+			if policy.FailOnError() {
+				return ErrRerank.WithError(err)
+			}
+			return p.fallbackToRetrievalOrder(ctx, chatManage, err, next)
+		}
 	}
 
 	pipelineInfo(ctx, "Rerank", "model_response", map[string]interface{}{
@@ -144,6 +170,7 @@ func (p *PluginRerank) OnEvent(ctx context.Context,
 		base := sr.Score
 		sr.Metadata["base_score"] = fmt.Sprintf("%.4f", base)
 		modelScore := rr.RelevanceScore
+		sr.Metadata["rerank_score"] = fmt.Sprintf("%.4f", modelScore)
 		sr.Score = compositeScore(sr, modelScore, base)
 
 		// Apply FAQ score boost if enabled
@@ -188,7 +215,7 @@ func (p *PluginRerank) OnEvent(ctx context.Context,
 		reranked = append(reranked, sr)
 	}
 	final := applyMMR(ctx, reranked, chatManage, min(len(reranked), max(1, chatManage.RerankTopK)), 0.7)
-	chatManage.RerankResult = final
+	chatManage.RerankResult = append(pinnedResults, final...)
 
 	// Log composite top scores and MMR selection summary
 	topN := min(3, len(reranked))
@@ -214,22 +241,89 @@ func (p *PluginRerank) OnEvent(ctx context.Context,
 	return next()
 }
 
-// rerank performs the actual reranking operation with given query and passages
+// fallbackToRetrievalOrder ranks the results by their retrieval score when the rerank model is unavailable.
+// The results are marked with the rerank_fallback metadata so that clients can tell.
+func (p *PluginRerank) fallbackToRetrievalOrder(ctx context.Context,
+	chatManage *types.ChatManage, cause error, next func() *PluginError,
+) *PluginError {
+	pipelineWarn(ctx, "Rerank", "fallback", map[string]interface{}{
+		"session_id":    chatManage.SessionID,
+		"rerank_model":  chatManage.RerankModelID,
+		"candidate_cnt": len(chatManage.SearchResult),
+		"reason":        cause.Error(),
+	})
+
+	var pinnedResults, ranked []*types.SearchResult
+	for _, sr := range chatManage.SearchResult {
+		sr.Metadata = ensureMetadata(sr.Metadata)
+		sr.Metadata["rerank_fallback"] = "true"
+		if sr.Pinned != nil {
+			pinnedResults = append(pinnedResults, sr)
+			continue
+		}
+		sr.Metadata["base_score"] = fmt.Sprintf("%.4f", sr.Score)
+		ranked = append(ranked, sr)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	final := applyMMR(ctx, ranked, chatManage, min(len(ranked), max(1, chatManage.RerankTopK)), 0.7)
+	chatManage.RerankResult = append(pinnedResults, final...)
+
+	if len(chatManage.RerankResult) == 0 {
+		return ErrSearchNothing
+	}
+	pipelineInfo(ctx, "Rerank", "output", map[string]interface{}{
+		"filtered_cnt": len(chatManage.RerankResult),
+		"fallback":     true,
+	})
+	return next()
+}
+
+// resolvePolicy combines the rerank fallback policies of the searched knowledge bases
+func (p *PluginRerank) resolvePolicy(ctx context.Context, chatManage *types.ChatManage) *types.RerankFallbackConfig {
+	seen := make(map[string]bool)
+	var policy *types.RerankFallbackConfig
+	for _, target := range chatManage.SearchTargets {
+		if target == nil || seen[target.KnowledgeBaseID] {
+			continue
+		}
+		seen[target.KnowledgeBaseID] = true
+		kb, err := p.knowledgeBaseService.GetKnowledgeBaseByID(ctx, target.KnowledgeBaseID)
+		if err != nil {
+			pipelineWarn(ctx, "Rerank", "get_kb", map[string]interface{}{
+				"knowledge_base_id": target.KnowledgeBaseID,
+				"error":             err.Error(),
+			})
+			continue
+		}
+		policy = policy.Stricter(kb.RerankFallbackConfig)
+	}
+	return policy
+}
+
+// rerank performs the actual reranking operation with given query and passages.
+// A timeout greater than 0 bounds the model call.
 func (p *PluginRerank) rerank(ctx context.Context,
 	chatManage *types.ChatManage, rerankModel rerank.Reranker, query string, passages []string,
-	candidates []*types.SearchResult,
-) []rerank.RankResult {
+	candidates []*types.SearchResult, timeout time.Duration,
+) ([]rerank.RankResult, error) {
 	pipelineInfo(ctx, "Rerank", "model_call", map[string]interface{}{
 		"query_variant": query,
 		"passages":      len(passages),
+		"timeout":       timeout.String(),
 	})
-	rerankResp, err := rerankModel.Rerank(ctx, query, passages)
+	callCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	rerankResp, err := rerankModel.Rerank(callCtx, query, passages)
 	if err != nil {
 		pipelineError(ctx, "Rerank", "model_call", map[string]interface{}{
 			"query_variant": query,
 			"error":         err.Error(),
 		})
-		return nil
+		return nil, err
 	}
 
 	// Log top scores for debugging
@@ -264,7 +358,7 @@ func (p *PluginRerank) rerank(ctx context.Context,
 			rankFilter = append(rankFilter, result)
 		}
 	}
-	return rankFilter
+	return rankFilter, nil
 }
 
 // ensureMetadata ensures the metadata is not nil
@@ -0,0 +1,124 @@
+package chatpipline
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/models/rerank"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// unavailableReranker fails every rerank call
+type unavailableReranker struct {
+	rerank.Reranker
+}
+
+func (r *unavailableReranker) Rerank(context.Context, string, []string) ([]rerank.RankResult, error) {
+	return nil, errors.New("rerank service unavailable")
+}
+
+// fakeRerankModelService serves the reranker, or fails to load it when reranker is nil
+type fakeRerankModelService struct {
+	interfaces.ModelService
+	reranker rerank.Reranker
+}
+
+func (s *fakeRerankModelService) GetRerankModel(context.Context, string) (rerank.Reranker, error) {
+	if s.reranker == nil {
+		return nil, errors.New("rerank model not found")
+	}
+	return s.reranker, nil
+}
+
+// fakeRerankKnowledgeBaseService serves one knowledge base
+type fakeRerankKnowledgeBaseService struct {
+	interfaces.KnowledgeBaseService
+	kb *types.KnowledgeBase
+}
+
+func (s *fakeRerankKnowledgeBaseService) GetKnowledgeBaseByID(context.Context, string) (*types.KnowledgeBase, error) {
+	return s.kb, nil
+}
+
+func TestPluginRerankUnavailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		reranker rerank.Reranker
+		policy   *types.RerankFallbackConfig
+		wantErr  string
+	}{
+		{name: "rerank call fails", reranker: &unavailableReranker{}},
+		{name: "rerank model cannot be loaded"},
+		{
+			name:     "explicit degrade",
+			reranker: &unavailableReranker{},
+			policy:   &types.RerankFallbackConfig{OnFailure: types.RerankFailureDegrade},
+		},
+		{
+			name:     "fail policy",
+			reranker: &unavailableReranker{},
+			policy:   &types.RerankFallbackConfig{OnFailure: types.RerankFailureFail},
+			wantErr:  ErrRerank.ErrorType,
+		},
+		{
+			name:    "fail policy without model",
+			policy:  &types.RerankFallbackConfig{OnFailure: types.RerankFailureFail},
+			wantErr: ErrGetRerankModel.ErrorType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &PluginRerank{
+				modelService: &fakeRerankModelService{reranker: tt.reranker},
+				knowledgeBaseService: &fakeRerankKnowledgeBaseService{
+					kb: &types.KnowledgeBase{ID: "kb-1", RerankFallbackConfig: tt.policy},
+				},
+			}
+			chatManage := &types.ChatManage{
+				RerankModelID: "rerank-1",
+				RerankTopK:    2,
+				RewriteQuery:  "how do I reset my password",
+				SearchTargets: types.SearchTargets{{KnowledgeBaseID: "kb-1"}},
+				SearchResult: []*types.SearchResult{
+					{ID: "low", Content: "office opening hours", Score: 0.41},
+					{ID: "high", Content: "reset the password from the login page", Score: 0.92},
+					{ID: "mid", Content: "password rules and expiry", Score: 0.63},
+				},
+			}
+
+			answered := false
+			err := plugin.OnEvent(context.Background(), types.CHUNK_RERANK, chatManage, func() *PluginError {
+				answered = true
+				return nil
+			})
+			if tt.wantErr != "" {
+				if err == nil || err.ErrorType != tt.wantErr {
+					t.Fatalf("OnEvent() = %v, want %s", err, tt.wantErr)
+				}
+				if answered {
+					t.Error("answered although the policy fails the request")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OnEvent() = %v", err)
+			}
+			if !answered {
+				t.Fatal("the pipeline did not continue to answer")
+			}
+			var got []string
+			for _, result := range chatManage.RerankResult {
+				got = append(got, result.ID)
+				if result.Metadata["rerank_fallback"] != "true" {
+					t.Errorf("result %s is not marked as a rerank fallback", result.ID)
+				}
+			}
+			if want := []string{"high", "mid"}; !slices.Equal(got, want) {
+				t.Errorf("rerank result = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package chatpipline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/google/uuid"
+)
+
+// retrievalCollector gathers the results of concurrent searches. Once closed, results of searches still
+// running are dropped, so that the results handed to the rest of the pipeline are no longer modified.
+type retrievalCollector struct {
+	mu      sync.Mutex
+	results []*types.SearchResult
+	closed  bool
+	added   chan struct{} // Signaled when results are added
+}
+
+func newRetrievalCollector() *retrievalCollector {
+	return &retrievalCollector{added: make(chan struct{}, 1)}
+}
+
+// add adds search results, unless the collector is closed
+func (c *retrievalCollector) add(results []*types.SearchResult) {
+	if len(results) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.results = append(c.results, results...)
+	select {
+	case c.added <- struct{}{}:
+	default:
+	}
+}
+
+// count returns the number of results gathered so far
+func (c *retrievalCollector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.results)
+}
+
+// close stops gathering results and returns those gathered so far
+func (c *retrievalCollector) close() []*types.SearchResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return c.results
+}
+
+// awaitRetrieval waits until the searches are done. Once the soft deadline has passed, it stops waiting as
+// soon as minChunks results are gathered, cancels the searches still running and returns the degradation
+// to note on the answer; with fewer results it keeps waiting for the searches. A soft deadline of 0 waits
+// for every search.
+func awaitRetrieval(collector *retrievalCollector, done <-chan struct{}, cancel context.CancelFunc,
+	softDeadline time.Duration, minChunks int,
+) ([]*types.SearchResult, *types.RetrievalDegradation) {
+	if softDeadline <= 0 {
+		<-done
+		return collector.close(), nil
+	}
+	if minChunks <= 0 {
+		minChunks = types.DefaultRetrievalMinChunks
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(softDeadline)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return collector.close(), nil
+	case <-timer.C:
+	}
+
+	for {
+		select {
+		case <-done:
+			return collector.close(), nil
+		default:
+		}
+		if collector.count() >= minChunks {
+			results := collector.close()
+			cancel()
+			return results, &types.RetrievalDegradation{
+				Reason:          types.RetrievalDegradationSoftDeadline,
+				SoftDeadlineMs:  softDeadline.Milliseconds(),
+				ElapsedMs:       time.Since(start).Milliseconds(),
+				RetrievedChunks: len(results),
+			}
+		}
+		select {
+		case <-done:
+			return collector.close(), nil
+		case <-collector.added:
+		}
+	}
+}
+
+// retrievalSoftDeadline returns the soft deadline and minimum chunks of retrieval from the conversation config
+func (p *PluginSearch) retrievalSoftDeadline() (time.Duration, int) {
+	if p.config == nil || p.config.Conversation == nil {
+		return 0, 0
+	}
+	return p.config.Conversation.RetrievalSoftDeadline, p.config.Conversation.RetrievalMinChunks
+}
+
+// reportRetrievalDegradation logs that retrieval was cut short at its soft deadline and streams the
+// degradation to the client, which stores it on the answer
+func reportRetrievalDegradation(ctx context.Context, chatManage *types.ChatManage) {
+	degradation := chatManage.RetrievalDegradation
+	pipelineWarn(ctx, "Search", "soft_deadline", map[string]interface{}{
+		"session_id":       chatManage.SessionID,
+		"soft_deadline_ms": degradation.SoftDeadlineMs,
+		"elapsed_ms":       degradation.ElapsedMs,
+		"retrieved_chunks": degradation.RetrievedChunks,
+	})
+
+	if chatManage.EventBus == nil {
+		return
+	}
+	if err := chatManage.EventBus.Emit(ctx, types.Event{
+		ID:        fmt.Sprintf("%s-retrieval-degraded", uuid.New().String()[:8]),
+		Type:      types.EventType(event.EventRetrievalDegraded),
+		SessionID: chatManage.SessionID,
+		Data:      degradation,
+	}); err != nil {
+		logger.Errorf(ctx, "Failed to emit retrieval degradation event: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package chatpipline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// slowSearchKnowledgeBaseService answers the search of "fast" at once, and of "slow" after delay
+// or once the search is cancelled
+type slowSearchKnowledgeBaseService struct {
+	interfaces.KnowledgeBaseService
+	delay     time.Duration
+	cancelled chan struct{}
+}
+
+func (s *slowSearchKnowledgeBaseService) HybridSearch(ctx context.Context,
+	id string, params types.SearchParams,
+) ([]*types.SearchResult, error) {
+	if id == "fast" {
+		return []*types.SearchResult{{ID: "fast-1", Content: "fast chunk"}}, nil
+	}
+	select {
+	case <-time.After(s.delay):
+		return []*types.SearchResult{{ID: "slow-1", Content: "slow chunk"}}, nil
+	case <-ctx.Done():
+		close(s.cancelled)
+		return nil, ctx.Err()
+	}
+}
+
+func TestPluginSearchSoftDeadline(t *testing.T) {
+	tests := []struct {
+		name         string
+		minChunks    int
+		wantResults  int
+		wantDegraded bool
+	}{
+		{name: "proceeds with the chunks retrieved so far", minChunks: 1, wantResults: 1, wantDegraded: true},
+		{name: "waits for the minimum chunks", minChunks: 2, wantResults: 2, wantDegraded: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kbService := &slowSearchKnowledgeBaseService{delay: 300 * time.Millisecond, cancelled: make(chan struct{})}
+			plugin := &PluginSearch{
+				knowledgeBaseService: kbService,
+				config: &config.Config{Conversation: &config.ConversationConfig{
+					RetrievalSoftDeadline: 50 * time.Millisecond,
+					RetrievalMinChunks:    tt.minChunks,
+				}},
+			}
+			chatManage := &types.ChatManage{
+				RewriteQuery: "comet",
+				SearchTargets: types.SearchTargets{
+					{Type: types.SearchTargetTypeKnowledgeBase, KnowledgeBaseID: "fast"},
+					{Type: types.SearchTargetTypeKnowledgeBase, KnowledgeBaseID: "slow"},
+				},
+			}
+
+			start := time.Now()
+			nextCalled := false
+			err := plugin.OnEvent(context.Background(), types.CHUNK_SEARCH, chatManage, func() *PluginError {
+				nextCalled = true
+				return nil
+			})
+			if err != nil || !nextCalled {
+				t.Fatalf("OnEvent() = %v, next called = %v", err, nextCalled)
+			}
+			if len(chatManage.SearchResult) != tt.wantResults {
+				t.Errorf("results = %d, want %d", len(chatManage.SearchResult), tt.wantResults)
+			}
+			if got := chatManage.RetrievalDegradation != nil; got != tt.wantDegraded {
+				t.Fatalf("degraded = %v, want %v", got, tt.wantDegraded)
+			}
+			if !tt.wantDegraded {
+				return
+			}
+			if elapsed := time.Since(start); elapsed >= kbService.delay {
+				t.Errorf("search took %v, want less than the slow search %v", elapsed, kbService.delay)
+			}
+			if chatManage.RetrievalDegradation.RetrievedChunks != 1 ||
+				chatManage.RetrievalDegradation.Reason != types.RetrievalDegradationSoftDeadline {
+				t.Errorf("degradation = %+v", chatManage.RetrievalDegradation)
+			}
+			select {
+			case <-kbService.cancelled:
+			case <-time.After(time.Second):
+				t.Error("slow search was not cancelled")
+			}
+		})
+	}
+}
@@ -71,8 +71,18 @@ func (p *PluginRewrite) OnEvent(ctx context.Context,
 		"enable_rewrite": chatManage.EnableRewrite,
 	})
 
+	// Determine max rounds from history depth, request or config
+	maxRounds := chatManage.HistoryRounds(p.config.Conversation.MaxRounds)
+	if maxRounds <= 0 {
+		pipelineInfo(ctx, "Rewrite", "skip", map[string]interface{}{
+			"session_id": chatManage.SessionID,
+			"reason":     "history_depth_zero",
+		})
+		return next()
+	}
+
 	// Get conversation history
-	history, err := p.messageService.GetRecentMessagesBySession(ctx, chatManage.SessionID, 20)
+	history, err := p.messageService.GetRecentMessagesBySession(ctx, chatManage.SessionID, max(20, maxRounds*2+10))
 	if err != nil {
 		pipelineWarn(ctx, "Rewrite", "history_fetch", map[string]interface{}{
 			"session_id": chatManage.SessionID,
@@ -115,10 +125,6 @@ func (p *PluginRewrite) OnEvent(ctx context.Context,
 	})
 
 	// Limit the number of historical records
-	maxRounds := p.config.Conversation.MaxRounds
-	if chatManage.MaxRounds > 0 {
-		maxRounds = chatManage.MaxRounds
-	}
 	if len(historyList) > maxRounds {
 		historyList = historyList[:maxRounds]
 	}
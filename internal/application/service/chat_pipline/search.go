@@ -9,6 +9,7 @@ import (
 	"unicode"
 
 	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/database"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/searchutil"
 	"github.com/Tencent/WeKnora/internal/types"
@@ -60,6 +61,8 @@ func (p *PluginSearch) ActivationEvents() []types.EventType {
 func (p *PluginSearch) OnEvent(ctx context.Context,
 	eventType types.EventType, chatManage *types.ChatManage, next func() *PluginError,
 ) *PluginError {
+	// Retrieval reads tolerate replication lag
+	ctx = database.WithReplicaReads(ctx)
 	// Check if we have search targets or web search enabled
 	hasKBTargets := len(chatManage.SearchTargets) > 0 || len(chatManage.KnowledgeBaseIDs) > 0 || len(chatManage.KnowledgeIDs) > 0
 	if !hasKBTargets && !chatManage.WebSearchEnabled {
@@ -84,36 +87,36 @@ func (p *PluginSearch) OnEvent(ctx context.Context,
 		"vector_threshold":  chatManage.VectorThreshold,
 		"keyword_threshold": chatManage.KeywordThreshold,
 	})
+	// Searches are cancelled when retrieval is cut short at its soft deadline
+	searchCtx, cancelSearch := context.WithCancel(ctx)
+	defer cancelSearch()
+	collector := newRetrievalCollector()
 	var wg sync.WaitGroup
-	var mu sync.Mutex
-	allResults := make([]*types.SearchResult, 0)
 
 	wg.Add(2)
 	// Goroutine 1: Knowledge base search using SearchTargets
 	go func() {
 		defer wg.Done()
-		kbResults := p.searchByTargets(ctx, chatManage)
-		if len(kbResults) > 0 {
-			mu.Lock()
-			allResults = append(allResults, kbResults...)
-			mu.Unlock()
-		}
+		p.searchByTargets(searchCtx, chatManage, collector)
 	}()
 
 	// Goroutine 2: Web search (if enabled)
 	go func() {
 		defer wg.Done()
-		webResults := p.searchWebIfEnabled(ctx, chatManage)
-		if len(webResults) > 0 {
-			mu.Lock()
-			allResults = append(allResults, webResults...)
-			mu.Unlock()
-		}
+		collector.add(p.searchWebIfEnabled(searchCtx, chatManage))
 	}()
 
-	wg.Wait()
-
-	chatManage.SearchResult = allResults
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	softDeadline, minChunks := p.retrievalSoftDeadline()
+	chatManage.SearchResult, chatManage.RetrievalDegradation = awaitRetrieval(
+		collector, done, cancelSearch, softDeadline, minChunks)
+	if chatManage.RetrievalDegradation != nil {
+		reportRetrievalDegradation(ctx, chatManage)
+	}
 
 	// Log all search results with scores before any processing
 	for i, r := range chatManage.SearchResult {
@@ -126,7 +129,9 @@ func (p *PluginSearch) OnEvent(ctx context.Context,
 	}
 
 	// If recall is low, attempt query expansion with keyword-focused search
-	if chatManage.EnableQueryExpansion && len(chatManage.SearchResult) < max(1, chatManage.EmbeddingTopK/2) {
+	// Skipped when retrieval is already past its soft deadline
+	if chatManage.RetrievalDegradation == nil && chatManage.EnableQueryExpansion &&
+		len(chatManage.SearchResult) < max(1, chatManage.EmbeddingTopK/2) {
 		pipelineInfo(ctx, "Search", "recall_low", map[string]interface{}{
 			"current":   len(chatManage.SearchResult),
 			"threshold": chatManage.EmbeddingTopK / 2,
@@ -311,12 +316,14 @@ func buildContentSignature(content string) string {
 
 // searchByTargets performs KB searches using pre-computed SearchTargets
 // This is the main search method that uses the unified search targets
+// Results are added to the collector as each target returns, so they are available at the soft deadline
 func (p *PluginSearch) searchByTargets(
 	ctx context.Context,
 	chatManage *types.ChatManage,
-) []*types.SearchResult {
+	collector *retrievalCollector,
+) {
 	if len(chatManage.SearchTargets) == 0 {
-		return nil
+		return
 	}
 
 	var wg sync.WaitGroup
@@ -346,6 +353,7 @@ func (p *PluginSearch) searchByTargets(
 					mu.Lock()
 					results = append(results, directResults...)
 					mu.Unlock()
+					collector.add(directResults)
 				}
 
 				// If all files were loaded directly, we don't need to search anything
@@ -368,6 +376,9 @@ func (p *PluginSearch) searchByTargets(
 				VectorThreshold:  chatManage.VectorThreshold,
 				KeywordThreshold: chatManage.KeywordThreshold,
 				MatchCount:       chatManage.EmbeddingTopK,
+				SummaryFirst:     chatManage.SummaryFirst,
+				RetrievalMode:    chatManage.RetrievalMode,
+				SourceQuota:      chatManage.SourceQuota,
 			}
 			// Apply knowledge ID filter if this is a partial KB search
 			if t.Type == types.SearchTargetTypeKnowledge {
@@ -391,6 +402,7 @@ func (p *PluginSearch) searchByTargets(
 			mu.Lock()
 			results = append(results, res...)
 			mu.Unlock()
+			collector.add(res)
 		}(target)
 	}
 
@@ -399,7 +411,6 @@ func (p *PluginSearch) searchByTargets(
 	pipelineInfo(ctx, "Search", "kb_result_summary", map[string]interface{}{
 		"total_hits": len(results),
 	})
-	return results
 }
 
 // tryDirectChunkLoading attempts to load chunks for given knowledge IDs directly
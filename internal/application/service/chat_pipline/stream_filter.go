@@ -104,6 +104,7 @@ func (p *PluginStreamFilter) filterEventsWithPrefix(
 				Data: event.AgentFinalAnswerData{
 					Content: responseBuilder.String(),
 					Done:    data.Done,
+					Usage:   data.Usage,
 				},
 			})
 			matchFound = true
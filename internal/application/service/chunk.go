@@ -21,6 +21,7 @@ type chunkService struct {
 	kbRepository    interfaces.KnowledgeBaseRepository
 	modelService    interfaces.ModelService
 	retrieveEngine  interfaces.RetrieveEngineRegistry
+	answerCache     interfaces.AnswerCacheService
 }
 
 // NewChunkService creates a new chunk service
@@ -35,12 +36,14 @@ func NewChunkService(
 	kbRepository interfaces.KnowledgeBaseRepository,
 	modelService interfaces.ModelService,
 	retrieveEngine interfaces.RetrieveEngineRegistry,
+	answerCache interfaces.AnswerCacheService,
 ) interfaces.ChunkService {
 	return &chunkService{
 		chunkRepository: chunkRepository,
 		kbRepository:    kbRepository,
 		modelService:    modelService,
 		retrieveEngine:  retrieveEngine,
+		answerCache:     answerCache,
 	}
 }
 
@@ -135,12 +138,14 @@ func (s *chunkService) ListChunksByKnowledgeID(ctx context.Context, knowledgeID
 //   - ctx: Context with authentication and request information
 //   - knowledgeID: ID of the knowledge document
 //   - page: Pagination parameters including page number and page size
+//   - chunkType: Types of the listed chunks
+//   - keyword: Substring the chunk content must contain, empty lists all chunks
 //
 // Returns:
 //   - *types.PageResult: Paginated result containing chunks and pagination metadata
 //   - error: Any error encountered during retrieval
 func (s *chunkService) ListPagedChunksByKnowledgeID(ctx context.Context,
-	knowledgeID string, page *types.Pagination, chunkType []types.ChunkType,
+	knowledgeID string, page *types.Pagination, chunkType []types.ChunkType, keyword string,
 ) (*types.PageResult, error) {
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 	chunks, total, err := s.chunkRepository.ListPagedChunksByKnowledgeID(
@@ -150,7 +155,7 @@ func (s *chunkService) ListPagedChunksByKnowledgeID(ctx context.Context,
 		page,
 		chunkType,
 		"",
-		"",
+		keyword,
 		"",
 		"",
 		"",
@@ -189,6 +194,7 @@ func (s *chunkService) UpdateChunk(ctx context.Context, chunk *types.Chunk) erro
 		})
 		return err
 	}
+	invalidateAnswerCache(ctx, s.answerCache, chunk.KnowledgeBaseID)
 
 	logger.Info(ctx, "Chunk updated successfully")
 	return nil
@@ -224,6 +230,7 @@ func (s *chunkService) UpdateChunks(ctx context.Context, chunks []*types.Chunk)
 //   - error: Any error encountered during deletion
 func (s *chunkService) DeleteChunk(ctx context.Context, id string) error {
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	chunk, _ := s.chunkRepository.GetChunkByID(ctx, tenantID, id)
 	err := s.chunkRepository.DeleteChunk(ctx, tenantID, id)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{
@@ -231,6 +238,9 @@ func (s *chunkService) DeleteChunk(ctx context.Context, id string) error {
 		})
 		return err
 	}
+	if chunk != nil {
+		invalidateAnswerCache(ctx, s.answerCache, chunk.KnowledgeBaseID)
+	}
 	logger.Info(ctx, "Chunk deleted successfully")
 	return nil
 }
@@ -434,6 +444,7 @@ func (s *chunkService) DeleteGeneratedQuestion(ctx context.Context, chunkID stri
 		})
 		return fmt.Errorf("failed to update chunk: %w", err)
 	}
+	invalidateAnswerCache(ctx, s.answerCache, chunk.KnowledgeBaseID)
 
 	logger.Infof(ctx, "Successfully deleted generated question %s from chunk %s", questionID, chunkID)
 	return nil
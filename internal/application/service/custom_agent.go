@@ -19,6 +19,11 @@ var (
 	ErrCannotModifyBuiltin = errors.New("cannot modify built-in agent basic info")
 	ErrCannotDeleteBuiltin = errors.New("cannot delete built-in agent")
 	ErrAgentNameRequired   = errors.New("agent name is required")
+
+	ErrAgentVersionNotFound     = errors.New("agent version not found")
+	ErrBuiltinAgentNotVersioned = errors.New("built-in agents do not support versioning")
+	ErrAgentVersionNotPublished = errors.New("can only roll back to a previously published version")
+	ErrNoRollbackTarget         = errors.New("no earlier published version to roll back to")
 )
 
 // customAgentService implements the CustomAgentService interface
@@ -67,9 +72,19 @@ func (s *customAgentService) CreateAgent(ctx context.Context, agent *types.Custo
 	// Set defaults
 	agent.EnsureDefaults()
 
+	// Reject unknown or malformed placeholders before they break live chats
+	if err := agent.Config.ValidatePromptTemplates(); err != nil {
+		return nil, err
+	}
+	if err := agent.Config.ValidateRetrievalMode(); err != nil {
+		return nil, err
+	}
+
 	logger.Infof(ctx, "Creating custom agent, ID: %s, tenant ID: %d, name: %s, agent_mode: %s",
 		agent.ID, agent.TenantID, agent.Name, agent.Config.AgentMode)
 
+	// A new agent goes live immediately as version 1
+	agent.PublishedVersion = 1
 	if err := s.repo.CreateAgent(ctx, agent); err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{
 			"agent_id":  agent.ID,
@@ -77,6 +92,9 @@ func (s *customAgentService) CreateAgent(ctx context.Context, agent *types.Custo
 		})
 		return nil, err
 	}
+	if _, err := s.recordVersion(ctx, agent, 1, types.AgentVersionStatusPublished); err != nil {
+		return nil, err
+	}
 
 	logger.Infof(ctx, "Custom agent created successfully, ID: %s, name: %s", agent.ID, agent.Name)
 	return agent, nil
@@ -217,27 +235,52 @@ func (s *customAgentService) UpdateAgent(ctx context.Context, agent *types.Custo
 		return nil, ErrAgentNameRequired
 	}
 
-	// Update fields
-	existingAgent.Name = agent.Name
-	existingAgent.Description = agent.Description
-	existingAgent.Avatar = agent.Avatar
-	existingAgent.Config = agent.Config
-	existingAgent.UpdatedAt = time.Now()
+	latestVersion, err := s.repo.GetLatestVersionNumber(ctx, agent.ID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Agents created before versioning existed have no published version yet;
+	// snapshot the current live config first so there is a version to roll back to
+	if existingAgent.PublishedVersion == 0 {
+		latestVersion++
+		if _, err := s.recordVersion(ctx, existingAgent, latestVersion, types.AgentVersionStatusPublished); err != nil {
+			return nil, err
+		}
+		if err := s.repo.PublishVersion(ctx, existingAgent.ID, tenantID, latestVersion); err != nil {
+			return nil, err
+		}
+		existingAgent.PublishedVersion = latestVersion
+	}
+
+	// Build the draft on a copy: the stored agent keeps serving live traffic
+	// and is only overwritten when a version is published or rolled back to
+	draft := *existingAgent
+	draft.Name = agent.Name
+	draft.Description = agent.Description
+	draft.Avatar = agent.Avatar
+	draft.Config = agent.Config
+	draft.UpdatedAt = time.Now()
 
 	// Ensure defaults
-	existingAgent.EnsureDefaults()
+	draft.EnsureDefaults()
+
+	if err := draft.Config.ValidatePromptTemplates(); err != nil {
+		return nil, err
+	}
+	if err := draft.Config.ValidateRetrievalMode(); err != nil {
+		return nil, err
+	}
 
 	logger.Infof(ctx, "Updating custom agent, ID: %s, name: %s", agent.ID, agent.Name)
 
-	if err := s.repo.UpdateAgent(ctx, existingAgent); err != nil {
-		logger.ErrorWithFields(ctx, err, map[string]interface{}{
-			"agent_id": agent.ID,
-		})
+	if _, err := s.recordVersion(ctx, &draft, latestVersion+1, types.AgentVersionStatusDraft); err != nil {
 		return nil, err
 	}
 
-	logger.Infof(ctx, "Custom agent updated successfully, ID: %s", agent.ID)
-	return existingAgent, nil
+	logger.Infof(ctx, "Custom agent draft saved, ID: %s, draft version: %d, published version: %d",
+		agent.ID, latestVersion+1, draft.PublishedVersion)
+	return &draft, nil
 }
 
 // updateBuiltinAgent updates a built-in agent's configuration (but not basic info)
@@ -260,6 +303,13 @@ func (s *customAgentService) updateBuiltinAgent(ctx context.Context, agent *type
 		existingAgent.UpdatedAt = time.Now()
 		existingAgent.EnsureDefaults()
 
+		if err := existingAgent.Config.ValidatePromptTemplates(); err != nil {
+			return nil, err
+		}
+		if err := existingAgent.Config.ValidateRetrievalMode(); err != nil {
+			return nil, err
+		}
+
 		logger.Infof(ctx, "Updating built-in agent config, ID: %s", agent.ID)
 
 		if err := s.repo.UpdateAgent(ctx, existingAgent); err != nil {
@@ -287,6 +337,13 @@ func (s *customAgentService) updateBuiltinAgent(ctx context.Context, agent *type
 	}
 	newAgent.EnsureDefaults()
 
+	if err := newAgent.Config.ValidatePromptTemplates(); err != nil {
+		return nil, err
+	}
+	if err := newAgent.Config.ValidateRetrievalMode(); err != nil {
+		return nil, err
+	}
+
 	logger.Infof(ctx, "Creating built-in agent config record, ID: %s, tenant ID: %d", agent.ID, tenantID)
 
 	if err := s.repo.CreateAgent(ctx, newAgent); err != nil {
@@ -383,6 +440,7 @@ func (s *customAgentService) CopyAgent(ctx context.Context, id string) (*types.C
 
 	logger.Infof(ctx, "Copying agent, source ID: %s, new ID: %s", id, newAgent.ID)
 
+	newAgent.PublishedVersion = 1
 	if err := s.repo.CreateAgent(ctx, newAgent); err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{
 			"source_agent_id": id,
@@ -390,7 +448,217 @@ func (s *customAgentService) CopyAgent(ctx context.Context, id string) (*types.C
 		})
 		return nil, err
 	}
+	if _, err := s.recordVersion(ctx, newAgent, 1, types.AgentVersionStatusPublished); err != nil {
+		return nil, err
+	}
 
 	logger.Infof(ctx, "Agent copied successfully, source ID: %s, new ID: %s", id, newAgent.ID)
 	return newAgent, nil
 }
+
+// GetPublishedAgent retrieves an agent with its published version applied
+func (s *customAgentService) GetPublishedAgent(ctx context.Context, id string) (*types.CustomAgent, error) {
+	agent, err := s.GetAgentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if agent.IsBuiltin || agent.PublishedVersion == 0 {
+		return agent, nil
+	}
+
+	version, err := s.repo.GetVersion(ctx, agent.ID, agent.TenantID, agent.PublishedVersion)
+	if err != nil {
+		if errors.Is(err, repository.ErrCustomAgentVersionNotFound) {
+			logger.Warnf(ctx, "Published version %d of agent %s not found, using stored config",
+				agent.PublishedVersion, agent.ID)
+			return agent, nil
+		}
+		return nil, err
+	}
+
+	published := *agent
+	version.ApplyTo(&published)
+	return &published, nil
+}
+
+// GetAgentAtVersion retrieves a custom agent as it looks in the given version
+func (s *customAgentService) GetAgentAtVersion(ctx context.Context, id string, version int) (*types.CustomAgent, error) {
+	agent, err := s.getVersionedAgent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.getVersion(ctx, agent, version)
+	if err != nil {
+		return nil, err
+	}
+	v.ApplyTo(agent)
+	// The returned copy is not persisted; PublishedVersion reports which version it reflects
+	agent.PublishedVersion = v.Version
+	return agent, nil
+}
+
+// ListVersions lists all versions of a custom agent, newest first
+func (s *customAgentService) ListVersions(ctx context.Context, id string) ([]*types.CustomAgentVersion, error) {
+	agent, err := s.getVersionedAgent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListVersions(ctx, agent.ID, agent.TenantID)
+}
+
+// DiffVersions compares two versions of a custom agent
+func (s *customAgentService) DiffVersions(
+	ctx context.Context, id string, fromVersion, toVersion int,
+) (*types.CustomAgentVersionDiff, error) {
+	agent, err := s.getVersionedAgent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := s.getVersion(ctx, agent, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.getVersion(ctx, agent, toVersion)
+	if err != nil {
+		return nil, err
+	}
+	return types.DiffAgentVersions(from, to), nil
+}
+
+// PublishVersion promotes a version of a custom agent to serve live traffic
+func (s *customAgentService) PublishVersion(ctx context.Context, id string, version int) (*types.CustomAgent, error) {
+	agent, err := s.getVersionedAgent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.getVersion(ctx, agent, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.publish(ctx, agent, target)
+}
+
+// RollbackAgent reverts a custom agent to a previously published version
+func (s *customAgentService) RollbackAgent(ctx context.Context, id string, version int) (*types.CustomAgent, error) {
+	agent, err := s.getVersionedAgent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *types.CustomAgentVersion
+	if version > 0 {
+		target, err = s.getVersion(ctx, agent, version)
+		if err != nil {
+			return nil, err
+		}
+		if target.PublishedAt == nil {
+			return nil, ErrAgentVersionNotPublished
+		}
+	} else {
+		// Default to the most recent version published before the current one
+		versions, err := s.repo.ListVersions(ctx, agent.ID, agent.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range versions {
+			if v.Version < agent.PublishedVersion && v.PublishedAt != nil {
+				target = v
+				break
+			}
+		}
+		if target == nil {
+			return nil, ErrNoRollbackTarget
+		}
+	}
+
+	logger.Infof(ctx, "Rolling back agent %s from version %d to version %d",
+		agent.ID, agent.PublishedVersion, target.Version)
+	return s.publish(ctx, agent, target)
+}
+
+// publish marks the target version as live and returns the agent with it applied
+func (s *customAgentService) publish(
+	ctx context.Context, agent *types.CustomAgent, target *types.CustomAgentVersion,
+) (*types.CustomAgent, error) {
+	if err := s.repo.PublishVersion(ctx, agent.ID, agent.TenantID, target.Version); err != nil {
+		if errors.Is(err, repository.ErrCustomAgentVersionNotFound) {
+			return nil, ErrAgentVersionNotFound
+		}
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"agent_id": agent.ID,
+			"version":  target.Version,
+		})
+		return nil, err
+	}
+
+	logger.Infof(ctx, "Agent version published, ID: %s, version: %d", agent.ID, target.Version)
+
+	agent.PublishedVersion = target.Version
+	target.ApplyTo(agent)
+	return agent, nil
+}
+
+// getVersionedAgent loads a custom agent that supports versioning
+func (s *customAgentService) getVersionedAgent(ctx context.Context, id string) (*types.CustomAgent, error) {
+	if id == "" {
+		return nil, errors.New("agent ID cannot be empty")
+	}
+	if types.IsBuiltinAgentID(id) {
+		return nil, ErrBuiltinAgentNotVersioned
+	}
+
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+
+	agent, err := s.repo.GetAgentByID(ctx, id, tenantID)
+	if err != nil {
+		if errors.Is(err, repository.ErrCustomAgentNotFound) {
+			return nil, ErrAgentNotFound
+		}
+		return nil, err
+	}
+	if agent.IsBuiltin {
+		return nil, ErrBuiltinAgentNotVersioned
+	}
+	return agent, nil
+}
+
+// getVersion loads a single version of an agent
+func (s *customAgentService) getVersion(
+	ctx context.Context, agent *types.CustomAgent, version int,
+) (*types.CustomAgentVersion, error) {
+	v, err := s.repo.GetVersion(ctx, agent.ID, agent.TenantID, version)
+	if err != nil {
+		if errors.Is(err, repository.ErrCustomAgentVersionNotFound) {
+			return nil, ErrAgentVersionNotFound
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// recordVersion stores a snapshot of the agent as a new version
+func (s *customAgentService) recordVersion(
+	ctx context.Context, agent *types.CustomAgent, version int, status string,
+) (*types.CustomAgentVersion, error) {
+	v := types.NewCustomAgentVersion(agent, version, status)
+	v.ID = uuid.New().String()
+	if status == types.AgentVersionStatusPublished {
+		publishedAt := v.CreatedAt
+		v.PublishedAt = &publishedAt
+	}
+
+	if err := s.repo.CreateVersion(ctx, v); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"agent_id": agent.ID,
+			"version":  version,
+		})
+		return nil, err
+	}
+	return v, nil
+}
@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/Tencent/WeKnora/internal/config"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/scheduler"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	"github.com/Tencent/WeKnora/internal/utils"
@@ -58,14 +61,22 @@ func NewEvaluationService(
 
 // evaluationMemoryStorage stores evaluation tasks in memory with thread-safe access
 type evaluationMemoryStorage struct {
-	store map[string]*types.EvaluationDetail // Map of taskID to evaluation details
-	mu    *sync.RWMutex                      // Read-write lock for concurrent access
+	store  map[string]*types.EvaluationDetail  // Map of taskID to evaluation details
+	events map[string][]*types.EvaluationEvent // Map of taskID to stream events in delivery order
+	notify map[string]chan struct{}            // Map of taskID to the channel closed on the next event
+	// Map of taskID to the pause and rate limit control of its workers
+	controls map[string]*evaluationControl
+	mu       *sync.RWMutex // Read-write lock for concurrent access
 }
 
 func newEvaluationMemoryStorage() *evaluationMemoryStorage {
 	res := &evaluationMemoryStorage{
-		store: make(map[string]*types.EvaluationDetail),
-		mu:    &sync.RWMutex{},
+		store:  make(map[string]*types.EvaluationDetail),
+		events: make(map[string][]*types.EvaluationEvent),
+		notify: make(map[string]chan struct{}),
+		mu:     &sync.RWMutex{},
+
+		controls: make(map[string]*evaluationControl),
 	}
 	return res
 }
@@ -75,6 +86,18 @@ func (e *evaluationMemoryStorage) register(params *types.EvaluationDetail) {
 	defer e.mu.Unlock()
 	logger.Infof(context.Background(), "Registering evaluation task: %s", params.Task.ID)
 	e.store[params.Task.ID] = params
+	e.notify[params.Task.ID] = make(chan struct{})
+	e.controls[params.Task.ID] = newEvaluationControl(params.Task.Limits.RequestsPerMinute)
+}
+
+func (e *evaluationMemoryStorage) control(taskID string) (*evaluationControl, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	control, ok := e.controls[taskID]
+	if !ok {
+		return nil, errors.New("task not found")
+	}
+	return control, nil
 }
 
 func (e *evaluationMemoryStorage) get(taskID string) (*types.EvaluationDetail, error) {
@@ -99,6 +122,49 @@ func (e *evaluationMemoryStorage) update(taskID string, fn func(params *types.Ev
 	return nil
 }
 
+// publish updates a task and appends the event built from the updated task to its stream,
+// waking up the readers waiting for it. fn may return nil to leave the stream unchanged.
+func (e *evaluationMemoryStorage) publish(taskID string,
+	fn func(params *types.EvaluationDetail) *types.EvaluationEvent,
+) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	params, ok := e.store[taskID]
+	if !ok {
+		return errors.New("task not found")
+	}
+	event := fn(params)
+	if event == nil {
+		return nil
+	}
+	event.Seq = len(e.events[taskID]) + 1
+	e.events[taskID] = append(e.events[taskID], event)
+
+	if ch := e.notify[taskID]; ch != nil {
+		close(ch)
+	}
+	if event.Type == types.EvaluationEventSummary {
+		e.notify[taskID] = nil
+	} else {
+		e.notify[taskID] = make(chan struct{})
+	}
+	return nil
+}
+
+// eventsAfter returns the events of a task with a sequence number greater than afterSeq,
+// and the channel closed on the next event (nil once the summary was published)
+func (e *evaluationMemoryStorage) eventsAfter(taskID string, afterSeq int) ([]*types.EvaluationEvent, <-chan struct{}) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	events := e.events[taskID]
+	afterSeq = min(max(afterSeq, 0), len(events))
+	var wait <-chan struct{}
+	if ch := e.notify[taskID]; ch != nil {
+		wait = ch
+	}
+	return slices.Clone(events[afterSeq:]), wait
+}
+
 func (e *EvaluationService) EvaluationResult(ctx context.Context, taskID string) (*types.EvaluationDetail, error) {
 	logger.Info(ctx, "Start getting evaluation result")
 	logger.Infof(ctx, "Task ID: %s", taskID)
@@ -125,13 +191,81 @@ func (e *EvaluationService) EvaluationResult(ctx context.Context, taskID string)
 	return detail, nil
 }
 
+// EvaluationEvents returns the stream events of a task after afterSeq, so that a client
+// reconnecting mid-run catches up from the last event it received
+func (e *EvaluationService) EvaluationEvents(ctx context.Context,
+	taskID string, afterSeq int,
+) ([]*types.EvaluationEvent, <-chan struct{}, error) {
+	// Reuse the task lookup for the tenant check
+	if _, err := e.EvaluationResult(ctx, taskID); err != nil {
+		return nil, nil, err
+	}
+	events, wait := e.evaluationMemoryStorage.eventsAfter(taskID, afterSeq)
+	return events, wait, nil
+}
+
+// PauseEvaluation stops a running task from starting new QA pairs; QA pairs in flight still finish
+func (e *EvaluationService) PauseEvaluation(ctx context.Context, taskID string) (*types.EvaluationDetail, error) {
+	return e.setEvaluationPaused(ctx, taskID, true)
+}
+
+// ResumeEvaluation lets a paused task start QA pairs again
+func (e *EvaluationService) ResumeEvaluation(ctx context.Context, taskID string) (*types.EvaluationDetail, error) {
+	return e.setEvaluationPaused(ctx, taskID, false)
+}
+
+// setEvaluationPaused pauses or resumes a task and emits the matching stream event
+func (e *EvaluationService) setEvaluationPaused(ctx context.Context,
+	taskID string, paused bool,
+) (*types.EvaluationDetail, error) {
+	detail, err := e.EvaluationResult(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	control, err := e.evaluationMemoryStorage.control(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	var publishErr error
+	err = e.evaluationMemoryStorage.publish(taskID, func(params *types.EvaluationDetail) *types.EvaluationEvent {
+		if paused {
+			if params.Task.Status != types.EvaluationStatueRunning && params.Task.Status != types.EvaluationStatuePending {
+				publishErr = werrors.NewBadRequestError("Only a pending or running evaluation can be paused")
+				return nil
+			}
+			control.pause()
+			params.Task.Status = types.EvaluationStatuePaused
+			logger.Infof(ctx, "Evaluation task paused, task ID: %s", taskID)
+			return &types.EvaluationEvent{Type: types.EvaluationEventPaused, Task: *params.Task, Metric: params.Metric}
+		}
+		if params.Task.Status != types.EvaluationStatuePaused {
+			publishErr = werrors.NewBadRequestError("Only a paused evaluation can be resumed")
+			return nil
+		}
+		control.resume()
+		params.Task.Status = types.EvaluationStatueRunning
+		logger.Infof(ctx, "Evaluation task resumed, task ID: %s", taskID)
+		return &types.EvaluationEvent{Type: types.EvaluationEventResumed, Task: *params.Task, Metric: params.Metric}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if publishErr != nil {
+		return nil, publishErr
+	}
+	return detail, nil
+}
+
 // Evaluation starts a new evaluation task with given parameters
 // datasetID: ID of the dataset to evaluate against
 // knowledgeBaseID: ID of the knowledge base to use (empty to create new)
 // chatModelID: ID of the chat model to evaluate
 // rerankModelID: ID of the rerank model to evaluate
+// limits: concurrency and rate limits, lowered to the configured maximums
 func (e *EvaluationService) Evaluation(ctx context.Context,
 	datasetID string, knowledgeBaseID string, chatModelID string, rerankModelID string,
+	limits types.EvaluationLimits,
 ) (*types.EvaluationDetail, error) {
 	logger.Info(ctx, "Start evaluation")
 	logger.Infof(ctx, "Dataset ID: %s, Knowledge Base ID: %s, Chat Model ID: %s, Rerank Model ID: %s",
@@ -256,6 +390,18 @@ func (e *EvaluationService) Evaluation(ctx context.Context,
 	taskID := utils.GenerateTaskID("evaluation", tenantID, datasetID)
 	logger.Infof(ctx, "Generated task ID: %s", taskID)
 
+	// Apply the configured limits so an evaluation can't overwhelm the shared model providers
+	var maxLimits types.EvaluationLimits
+	if e.config.Evaluation != nil {
+		maxLimits = types.EvaluationLimits{
+			MaxConcurrency:    e.config.Evaluation.MaxConcurrency,
+			RequestsPerMinute: e.config.Evaluation.RequestsPerMinute,
+		}
+	}
+	limits = limits.Clamp(maxLimits, max(runtime.GOMAXPROCS(0)-1, 1))
+	logger.Infof(ctx, "Evaluation limits: concurrency %d, requests per minute %d",
+		limits.MaxConcurrency, limits.RequestsPerMinute)
+
 	// Prepare evaluation detail with all parameters
 	detail := &types.EvaluationDetail{
 		Task: &types.EvaluationTask{
@@ -264,6 +410,7 @@ func (e *EvaluationService) Evaluation(ctx context.Context,
 			DatasetID: datasetID,
 			Status:    types.EvaluationStatuePending,
 			StartTime: time.Now(),
+			Limits:    limits,
 		},
 		Params: &types.ChatManage{
 			VectorThreshold:  e.config.Conversation.VectorThreshold,
@@ -301,25 +448,41 @@ func (e *EvaluationService) Evaluation(ctx context.Context,
 	// Start evaluation in background goroutine
 	logger.Info(ctx, "Starting evaluation in background")
 	go func() {
-		// Create new context with logger for background task
-		newCtx := logger.CloneContext(ctx)
+		// Create new context with logger for background task; its model calls are queued
+		// behind interactive traffic so that the evaluation doesn't degrade live chat
+		newCtx := scheduler.WithPriority(logger.CloneContext(ctx), scheduler.PriorityBulk)
 		logger.Infof(newCtx, "Background evaluation started for task ID: %s", taskID)
 
-		// Update task status to running
-		detail.Task.Status = types.EvaluationStatueRunning
+		// Update task status to running, unless it was paused before starting
+		e.evaluationMemoryStorage.update(taskID, func(params *types.EvaluationDetail) {
+			if params.Task.Status == types.EvaluationStatuePending {
+				params.Task.Status = types.EvaluationStatueRunning
+			}
+		})
 		logger.Info(newCtx, "Evaluation task status set to running")
 
 		// Execute actual evaluation
-		if err := e.EvalDataset(newCtx, detail, knowledgeBaseID); err != nil {
-			detail.Task.Status = types.EvaluationStatueFailed
-			detail.Task.ErrMsg = err.Error()
-			logger.Errorf(newCtx, "Evaluation task failed: %v, task ID: %s", err, taskID)
-			return
+		evalErr := e.EvalDataset(newCtx, detail, knowledgeBaseID)
+		if evalErr != nil {
+			logger.Errorf(newCtx, "Evaluation task failed: %v, task ID: %s", evalErr, taskID)
+		} else {
+			logger.Infof(newCtx, "Evaluation task completed successfully, task ID: %s", taskID)
 		}
 
-		// Mark task as completed successfully
-		logger.Infof(newCtx, "Evaluation task completed successfully, task ID: %s", taskID)
-		detail.Task.Status = types.EvaluationStatueSuccess
+		// Mark task as finished and emit the terminal summary event
+		e.evaluationMemoryStorage.publish(taskID, func(params *types.EvaluationDetail) *types.EvaluationEvent {
+			if evalErr != nil {
+				params.Task.Status = types.EvaluationStatueFailed
+				params.Task.ErrMsg = evalErr.Error()
+			} else {
+				params.Task.Status = types.EvaluationStatueSuccess
+			}
+			return &types.EvaluationEvent{
+				Type:   types.EvaluationEventSummary,
+				Task:   *params.Task,
+				Metric: params.Metric,
+			}
+		})
 	}()
 
 	logger.Infof(ctx, "Evaluation task created successfully, task ID: %s", taskID)
@@ -366,7 +529,7 @@ func (e *EvaluationService) EvalDataset(ctx context.Context, detail *types.Evalu
 		}
 
 		logger.Infof(ctx, "Cleaning up resources - deleting knowledge base: %s", knowledgeBaseID)
-		if err := e.knowledgeBaseService.DeleteKnowledgeBase(ctx, knowledgeBaseID); err != nil {
+		if err := e.knowledgeBaseService.DeleteKnowledgeBase(ctx, knowledgeBaseID, true); err != nil {
 			logger.Errorf(
 				ctx,
 				"Failed to delete knowledge base: %v, knowledge base ID: %s",
@@ -376,20 +539,35 @@ func (e *EvaluationService) EvalDataset(ctx context.Context, detail *types.Evalu
 	}()
 
 	// Initialize parallel evaluation metrics
-	var finished int
-	var mu sync.Mutex
 	var g errgroup.Group
 	metricHook := NewHookMetric(len(dataset))
+	control, err := e.evaluationMemoryStorage.control(detail.Task.ID)
+	if err != nil {
+		return err
+	}
 
-	// Set worker limit based on available CPUs
-	g.SetLimit(max(runtime.GOMAXPROCS(0)-1, 1))
-	logger.Infof(ctx, "Starting evaluation with %d parallel workers", max(runtime.GOMAXPROCS(0)-1, 1))
+	// Set worker limit from the task limits
+	g.SetLimit(detail.Task.Limits.MaxConcurrency)
+	logger.Infof(ctx, "Starting evaluation with %d parallel workers", detail.Task.Limits.MaxConcurrency)
 
 	// Process each QA pair in parallel
 	for i, qaPair := range dataset {
 		qaPair := qaPair
 		i := i
 		g.Go(func() error {
+			// Wait while the task is paused and for the rate limit
+			delay, err := control.acquire(ctx)
+			if err != nil {
+				return err
+			}
+			if delay > 0 {
+				logger.Infof(ctx, "QA pair %d throttled for %s by the rate limit", i, delay)
+				e.evaluationMemoryStorage.update(detail.Task.ID, func(params *types.EvaluationDetail) {
+					params.Task.Throttled++
+					params.Task.ThrottledSeconds += delay.Seconds()
+				})
+			}
+
 			logger.Infof(ctx, "Processing QA pair %d, question: %s", i, qaPair.Question)
 
 			// Prepare chat management parameters for this QA pair
@@ -420,17 +598,29 @@ func (e *EvaluationService) EvalDataset(ctx context.Context, detail *types.Evalu
 			metricHook.recordSearchResult(i, chatManage.SearchResult)
 			metricHook.recordRerankResult(i, chatManage.RerankResult)
 			metricHook.recordChatResponse(i, chatManage.ChatResponse)
-			metricHook.recordFinish(i)
-
-			// Update progress metrics
-			mu.Lock()
-			finished += 1
-			metricResult := metricHook.MetricResult()
-			mu.Unlock()
-			e.evaluationMemoryStorage.update(detail.Task.ID, func(params *types.EvaluationDetail) {
-				params.Metric = metricResult
-				params.Task.Finished = finished
-				logger.Infof(ctx, "Updated task progress: %d/%d completed", finished, params.Task.Total)
+			caseMetric := metricHook.recordFinish(i)
+
+			// Update progress metrics and stream the result of this QA pair
+			var answer string
+			if chatManage.ChatResponse != nil {
+				answer = chatManage.ChatResponse.Content
+			}
+			e.evaluationMemoryStorage.publish(detail.Task.ID, func(params *types.EvaluationDetail) *types.EvaluationEvent {
+				params.Metric = metricHook.MetricResult()
+				params.Task.Finished++
+				logger.Infof(ctx, "Updated task progress: %d/%d completed", params.Task.Finished, params.Task.Total)
+				return &types.EvaluationEvent{
+					Type: types.EvaluationEventCase,
+					Case: &types.EvaluationCaseResult{
+						Index:    i,
+						Question: qaPair.Question,
+						Expected: qaPair.Answer,
+						Answer:   answer,
+						Metric:   caseMetric,
+					},
+					Task:   *params.Task,
+					Metric: params.Metric,
+				}
 			})
 			return nil
 		})
@@ -446,7 +636,6 @@ func (e *EvaluationService) EvalDataset(ctx context.Context, detail *types.Evalu
 	// Final update of evaluation metrics
 	e.evaluationMemoryStorage.update(detail.Task.ID, func(params *types.EvaluationDetail) {
 		params.Metric = metricHook.MetricResult()
-		params.Task.Finished = finished
 	})
 
 	logger.Infof(ctx, "Dataset evaluation completed successfully, task ID: %s", detail.Task.ID)
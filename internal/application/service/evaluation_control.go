@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// evaluationControl gates the start of each QA pair of an evaluation task:
+// it blocks workers while the task is paused and spaces QA pairs out to the rate limit
+type evaluationControl struct {
+	mu       sync.Mutex
+	resumed  chan struct{} // Closed when a paused task is resumed, nil while running
+	interval time.Duration // Minimum time between two QA pair starts, 0 for no rate limit
+	next     time.Time     // Earliest start time of the next QA pair
+}
+
+// newEvaluationControl creates a control starting at most requestsPerMinute QA pairs per minute (0 = unlimited)
+func newEvaluationControl(requestsPerMinute int) *evaluationControl {
+	c := &evaluationControl{}
+	if requestsPerMinute > 0 {
+		c.interval = time.Minute / time.Duration(requestsPerMinute)
+	}
+	return c
+}
+
+// pause stops workers from starting new QA pairs, returning false if the task was already paused
+func (c *evaluationControl) pause() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resumed != nil {
+		return false
+	}
+	c.resumed = make(chan struct{})
+	return true
+}
+
+// resume lets workers start QA pairs again, returning false if the task was not paused
+func (c *evaluationControl) resume() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resumed == nil {
+		return false
+	}
+	close(c.resumed)
+	c.resumed = nil
+	return true
+}
+
+// acquire blocks until a QA pair may start, and returns how long it was delayed by the rate limit
+func (c *evaluationControl) acquire(ctx context.Context) (time.Duration, error) {
+	for {
+		c.mu.Lock()
+		resumed := c.resumed
+		if resumed == nil {
+			break
+		}
+		c.mu.Unlock()
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	// Reserve the next start slot while holding the lock
+	now := time.Now()
+	start := now
+	if c.next.After(now) {
+		start = c.next
+	}
+	c.next = start.Add(c.interval)
+	c.mu.Unlock()
+
+	delay := start.Sub(now)
+	if delay <= 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
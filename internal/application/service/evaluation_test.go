@@ -0,0 +1,78 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestEvaluationEventStream(t *testing.T) {
+	storage := newEvaluationMemoryStorage()
+	storage.register(&types.EvaluationDetail{Task: &types.EvaluationTask{ID: "task-1", Total: 2}})
+
+	publishCase := func(index int) {
+		t.Helper()
+		err := storage.publish("task-1", func(params *types.EvaluationDetail) *types.EvaluationEvent {
+			params.Task.Finished++
+			return &types.EvaluationEvent{
+				Type: types.EvaluationEventCase,
+				Case: &types.EvaluationCaseResult{Index: index},
+				Task: *params.Task,
+			}
+		})
+		if err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	_, wait := storage.eventsAfter("task-1", 0)
+	publishCase(0)
+	select {
+	case <-wait:
+	default:
+		t.Fatal("readers were not woken up by the first event")
+	}
+	publishCase(1)
+	// Events built with a nil result leave the stream unchanged
+	storage.publish("task-1", func(*types.EvaluationDetail) *types.EvaluationEvent { return nil })
+	storage.publish("task-1", func(params *types.EvaluationDetail) *types.EvaluationEvent {
+		params.Task.Status = types.EvaluationStatueSuccess
+		return &types.EvaluationEvent{Type: types.EvaluationEventSummary, Task: *params.Task}
+	})
+
+	tests := []struct {
+		name     string
+		afterSeq int
+		wantSeqs []int
+	}{
+		{name: "from the start", afterSeq: 0, wantSeqs: []int{1, 2, 3}},
+		{name: "resume after the first case", afterSeq: 1, wantSeqs: []int{2, 3}},
+		{name: "resume after the summary", afterSeq: 3},
+		{name: "negative sequence", afterSeq: -5, wantSeqs: []int{1, 2, 3}},
+		{name: "sequence beyond the stream", afterSeq: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, wait := storage.eventsAfter("task-1", tt.afterSeq)
+			if wait != nil {
+				t.Error("a finished stream must not wait for more events")
+			}
+			if len(events) != len(tt.wantSeqs) {
+				t.Fatalf("got %d events, want seqs %v", len(events), tt.wantSeqs)
+			}
+			for i, event := range events {
+				if event.Seq != tt.wantSeqs[i] {
+					t.Errorf("event %d seq = %d, want %d", i, event.Seq, tt.wantSeqs[i])
+				}
+			}
+		})
+	}
+
+	events, _ := storage.eventsAfter("task-1", 0)
+	if events[1].Task.Finished != 2 || events[2].Type != types.EvaluationEventSummary {
+		t.Errorf("events do not carry the task state at publish time: %+v", events)
+	}
+	if err := storage.publish("task-unknown", nil); err == nil {
+		t.Error("publishing to an unknown task must fail")
+	}
+}
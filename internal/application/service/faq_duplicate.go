@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// faqDuplicateMatch is an existing FAQ entry that duplicates a new one
+type faqDuplicateMatch struct {
+	chunkID   string
+	matchType string
+	score     float64
+}
+
+// buildFAQQuestionIndex maps every standard and similar question of the existing FAQ chunks to its chunk ID
+func buildFAQQuestionIndex(chunks []*types.Chunk) map[string]string {
+	index := make(map[string]string)
+	for _, chunk := range chunks {
+		meta, err := chunk.FAQMetadata()
+		if err != nil || meta == nil {
+			continue
+		}
+		if meta.StandardQuestion != "" {
+			index[meta.StandardQuestion] = chunk.ID
+		}
+		for _, q := range meta.SimilarQuestions {
+			if q != "" {
+				index[q] = chunk.ID
+			}
+		}
+	}
+	return index
+}
+
+// faqQuestions returns the standard question followed by the similar questions
+func faqQuestions(meta *types.FAQChunkMetadata) []string {
+	return append([]string{meta.StandardQuestion}, meta.SimilarQuestions...)
+}
+
+// findFAQDuplicate finds the existing entry duplicated by meta. Identical questions are checked first;
+// with a positive threshold the entry closest to the standard question by vector similarity is checked next.
+func (s *knowledgeService) findFAQDuplicate(ctx context.Context, kbID string,
+	questionIndex map[string]string, meta *types.FAQChunkMetadata, threshold float64,
+) (*faqDuplicateMatch, error) {
+	for _, q := range faqQuestions(meta) {
+		if chunkID, ok := questionIndex[q]; ok {
+			return &faqDuplicateMatch{chunkID: chunkID, matchType: types.FAQDuplicateMatchExact, score: 1}, nil
+		}
+	}
+	if threshold <= 0 {
+		return nil, nil
+	}
+
+	results, err := s.kbService.HybridSearch(ctx, kbID, types.SearchParams{
+		QueryText:            meta.StandardQuestion,
+		VectorThreshold:      threshold,
+		MatchCount:           1,
+		DisableKeywordsMatch: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search near-duplicate FAQ entries: %w", err)
+	}
+	for _, result := range results {
+		if result.ChunkType == string(types.ChunkTypeFAQ) && result.Score >= threshold {
+			return &faqDuplicateMatch{chunkID: result.ID, matchType: types.FAQDuplicateMatchNear, score: result.Score}, nil
+		}
+	}
+	return nil, nil
+}
+
+// newFAQDuplicateDecision describes a duplicate match, resolving the seq_id of the existing entry
+func (s *knowledgeService) newFAQDuplicateDecision(ctx context.Context, tenantID uint64,
+	index int, meta *types.FAQChunkMetadata, match *faqDuplicateMatch, decision string,
+) types.FAQDuplicateDecision {
+	result := types.FAQDuplicateDecision{
+		Index:            index,
+		StandardQuestion: meta.StandardQuestion,
+		Decision:         decision,
+		MatchType:        match.matchType,
+		DuplicateChunkID: match.chunkID,
+	}
+	if match.matchType == types.FAQDuplicateMatchNear {
+		result.Score = match.score
+	}
+	if chunk, err := s.chunkRepo.GetChunkByID(ctx, tenantID, match.chunkID); err == nil && chunk != nil {
+		result.DuplicateOf = chunk.SeqID
+	} else {
+		logger.Warnf(ctx, "Failed to get duplicated FAQ chunk %s: %v", match.chunkID, err)
+	}
+	return result
+}
+
+// resolveFAQCreateDuplicate applies the duplicate policy to a single new entry.
+// A non-nil entry is returned when the new entry was merged into an existing one and must not be created.
+func (s *knowledgeService) resolveFAQCreateDuplicate(ctx context.Context, tenantID uint64, kbID string,
+	meta *types.FAQChunkMetadata, options types.FAQDuplicateOptions,
+) (*types.FAQEntry, *types.FAQDuplicateDecision, error) {
+	decision := &types.FAQDuplicateDecision{
+		StandardQuestion: meta.StandardQuestion,
+		Decision:         types.FAQDuplicateDecisionCreated,
+	}
+
+	// error 策略沿用原有的精确重复检查，其余策略仅检查条目自身
+	if options.Policy() == types.FAQDuplicatePolicyError {
+		if err := s.checkFAQQuestionDuplicate(ctx, tenantID, kbID, "", meta); err != nil {
+			return nil, nil, err
+		}
+		if options.DuplicateThreshold <= 0 {
+			return nil, decision, nil
+		}
+	} else if err := checkFAQQuestionSelfDuplicate(meta); err != nil {
+		return nil, nil, err
+	}
+
+	existingChunks, err := s.chunkRepo.ListAllFAQChunksWithMetadataByKnowledgeBaseID(ctx, tenantID, kbID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list existing FAQ chunks: %w", err)
+	}
+	match, err := s.findFAQDuplicate(ctx, kbID, buildFAQQuestionIndex(existingChunks), meta, options.DuplicateThreshold)
+	if err != nil {
+		return nil, nil, err
+	}
+	if match == nil {
+		return nil, decision, nil
+	}
+
+	*decision = s.newFAQDuplicateDecision(ctx, tenantID, 0, meta, match, types.FAQDuplicateDecisionCreated)
+	switch options.Policy() {
+	case types.FAQDuplicatePolicyAllow:
+		return nil, decision, nil
+	case types.FAQDuplicatePolicyMerge:
+		if decision.DuplicateOf == 0 {
+			return nil, nil, fmt.Errorf("failed to resolve duplicated FAQ entry %s", match.chunkID)
+		}
+		entry, err := s.AddSimilarQuestions(ctx, kbID, decision.DuplicateOf, faqQuestions(meta))
+		if err != nil {
+			return nil, nil, err
+		}
+		decision.Decision = types.FAQDuplicateDecisionMerged
+		logger.Infof(ctx, "Merged new FAQ entry into existing entry %d", decision.DuplicateOf)
+		return entry, decision, nil
+	default:
+		return nil, nil, werrors.NewBadRequestError(
+			fmt.Sprintf("标准问「%s」与已有条目 %d 相似（相似度 %.2f）", meta.StandardQuestion, decision.DuplicateOf, match.score))
+	}
+}
+
+// resolveFAQBatchDuplicate applies the duplicate policy to an entry of an append-mode batch and records the
+// decision in progress. It returns false when the entry must not be imported as a new entry.
+func (s *knowledgeService) resolveFAQBatchDuplicate(ctx context.Context, tenantID uint64, kbID string,
+	index int, entry *types.FAQEntryPayload, questionIndex map[string]string,
+	options types.FAQDuplicateOptions, progress *types.FAQImportProgress,
+) bool {
+	meta, err := sanitizeFAQEntryPayload(entry)
+	if err != nil {
+		return true
+	}
+	match, err := s.findFAQDuplicate(ctx, kbID, questionIndex, meta, options.DuplicateThreshold)
+	if err != nil {
+		// 相似检测失败不阻塞导入
+		logger.Warnf(ctx, "Failed to check duplicate of FAQ entry %d: %v", index, err)
+		return true
+	}
+	if match == nil {
+		return true
+	}
+
+	switch options.Policy() {
+	case types.FAQDuplicatePolicyAllow:
+		progress.DuplicateDecisions = append(progress.DuplicateDecisions,
+			s.newFAQDuplicateDecision(ctx, tenantID, index, meta, match, types.FAQDuplicateDecisionCreated))
+		return true
+	case types.FAQDuplicatePolicyMerge:
+		progress.DuplicateDecisions = append(progress.DuplicateDecisions,
+			s.newFAQDuplicateDecision(ctx, tenantID, index, meta, match, types.FAQDuplicateDecisionMerged))
+		return false
+	default:
+		decision := s.newFAQDuplicateDecision(ctx, tenantID, index, meta, match, types.FAQDuplicateDecisionRejected)
+		progress.DuplicateDecisions = append(progress.DuplicateDecisions, decision)
+		progress.FailedCount++
+		progress.FailedEntries = append(progress.FailedEntries, buildFAQFailedEntry(index,
+			fmt.Sprintf("标准问与知识库中已有条目 %d 相似（相似度 %.2f）", decision.DuplicateOf, match.score), entry))
+		return false
+	}
+}
+
+// mergeFAQDuplicateEntries folds the entries marked as merged during validation into their existing entries.
+// Entries that cannot be merged are reported as failed.
+func (s *knowledgeService) mergeFAQDuplicateEntries(ctx context.Context,
+	payload *types.FAQImportPayload, progress *types.FAQImportProgress,
+) {
+	for i := range progress.DuplicateDecisions {
+		decision := &progress.DuplicateDecisions[i]
+		if decision.Decision != types.FAQDuplicateDecisionMerged || decision.Index >= len(payload.Entries) {
+			continue
+		}
+		entry := &payload.Entries[decision.Index]
+		meta, err := sanitizeFAQEntryPayload(entry)
+		if err == nil && decision.DuplicateOf == 0 {
+			err = fmt.Errorf("failed to resolve duplicated FAQ entry %s", decision.DuplicateChunkID)
+		}
+		if err == nil {
+			_, err = s.AddSimilarQuestions(ctx, payload.KBID, decision.DuplicateOf, faqQuestions(meta))
+		}
+		if err != nil {
+			logger.Warnf(ctx, "Failed to merge FAQ entry %d into entry %d: %v", decision.Index, decision.DuplicateOf, err)
+			decision.Decision = types.FAQDuplicateDecisionRejected
+			progress.FailedCount++
+			progress.FailedEntries = append(progress.FailedEntries,
+				buildFAQFailedEntry(decision.Index, fmt.Sprintf("合并到已有条目失败: %v", err), entry))
+		}
+	}
+}
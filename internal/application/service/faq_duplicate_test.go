@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeFAQSearchService returns fixed near-duplicate search results
+type fakeFAQSearchService struct {
+	interfaces.KnowledgeBaseService
+	results  []*types.SearchResult
+	searches int
+}
+
+func (s *fakeFAQSearchService) HybridSearch(context.Context, string, types.SearchParams) ([]*types.SearchResult, error) {
+	s.searches++
+	return s.results, nil
+}
+
+// fakeFAQChunkRepo serves FAQ chunks by ID
+type fakeFAQChunkRepo struct {
+	interfaces.ChunkRepository
+	chunks map[string]*types.Chunk
+}
+
+func (r *fakeFAQChunkRepo) GetChunkByID(_ context.Context, _ uint64, id string) (*types.Chunk, error) {
+	return r.chunks[id], nil
+}
+
+func newFAQChunk(t *testing.T, id string, seqID int64, standard string, similar ...string) *types.Chunk {
+	t.Helper()
+	chunk := &types.Chunk{ID: id, SeqID: seqID, ChunkType: types.ChunkTypeFAQ}
+	if err := chunk.SetFAQMetadata(&types.FAQChunkMetadata{
+		StandardQuestion: standard,
+		SimilarQuestions: similar,
+		Answers:          []string{"answer"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return chunk
+}
+
+func TestResolveFAQBatchDuplicate(t *testing.T) {
+	existing := []*types.Chunk{
+		newFAQChunk(t, "chunk-refund", 7, "How do I get a refund?", "Refund policy"),
+		newFAQChunk(t, "chunk-login", 8, "How do I log in?"),
+	}
+	nearLogin := []*types.SearchResult{{ID: "chunk-login", ChunkType: types.ChunkTypeFAQ, Score: 0.93}}
+
+	tests := []struct {
+		name         string
+		question     string
+		options      types.FAQDuplicateOptions
+		results      []*types.SearchResult
+		wantImport   bool
+		wantDecision *types.FAQDuplicateDecision
+		wantFailed   int
+	}{
+		{name: "no duplicate", question: "Where is my invoice?", wantImport: true},
+		{
+			name:         "exact duplicate rejected by default",
+			question:     "Refund policy",
+			wantDecision: &types.FAQDuplicateDecision{Decision: types.FAQDuplicateDecisionRejected, MatchType: types.FAQDuplicateMatchExact, DuplicateOf: 7},
+			wantFailed:   1,
+		},
+		{
+			name:         "exact duplicate allowed",
+			question:     "How do I get a refund?",
+			options:      types.FAQDuplicateOptions{DuplicatePolicy: types.FAQDuplicatePolicyAllow},
+			wantImport:   true,
+			wantDecision: &types.FAQDuplicateDecision{Decision: types.FAQDuplicateDecisionCreated, MatchType: types.FAQDuplicateMatchExact, DuplicateOf: 7},
+		},
+		{
+			name:         "near duplicate merged",
+			question:     "I cannot sign in",
+			options:      types.FAQDuplicateOptions{DuplicatePolicy: types.FAQDuplicatePolicyMerge, DuplicateThreshold: 0.9},
+			results:      nearLogin,
+			wantDecision: &types.FAQDuplicateDecision{Decision: types.FAQDuplicateDecisionMerged, MatchType: types.FAQDuplicateMatchNear, DuplicateOf: 8, Score: 0.93},
+		},
+		{
+			name:       "near duplicate below the threshold",
+			question:   "I cannot sign in",
+			options:    types.FAQDuplicateOptions{DuplicateThreshold: 0.95},
+			results:    nearLogin,
+			wantImport: true,
+		},
+		{
+			name:       "near duplicates ignored without a threshold",
+			question:   "I cannot sign in",
+			results:    nearLogin,
+			wantImport: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searchService := &fakeFAQSearchService{results: tt.results}
+			svc := &knowledgeService{
+				kbService: searchService,
+				chunkRepo: &fakeFAQChunkRepo{chunks: map[string]*types.Chunk{
+					"chunk-refund": existing[0],
+					"chunk-login":  existing[1],
+				}},
+			}
+			entry := &types.FAQEntryPayload{StandardQuestion: tt.question, Answers: []string{"answer"}}
+			progress := &types.FAQImportProgress{}
+
+			imported := svc.resolveFAQBatchDuplicate(context.Background(), 1, "kb-1", 3, entry,
+				buildFAQQuestionIndex(existing), tt.options, progress)
+			if imported != tt.wantImport {
+				t.Errorf("imported = %v, want %v", imported, tt.wantImport)
+			}
+			if progress.FailedCount != tt.wantFailed || len(progress.FailedEntries) != tt.wantFailed {
+				t.Errorf("failed = %d (%d entries), want %d", progress.FailedCount, len(progress.FailedEntries), tt.wantFailed)
+			}
+			if tt.options.DuplicateThreshold == 0 && searchService.searches > 0 {
+				t.Error("searched near duplicates without a threshold")
+			}
+			if tt.wantDecision == nil {
+				if len(progress.DuplicateDecisions) != 0 {
+					t.Errorf("decisions = %+v, want none", progress.DuplicateDecisions)
+				}
+				return
+			}
+			if len(progress.DuplicateDecisions) != 1 {
+				t.Fatalf("decisions = %+v, want one", progress.DuplicateDecisions)
+			}
+			got := progress.DuplicateDecisions[0]
+			if got.Index != 3 || got.Decision != tt.wantDecision.Decision || got.MatchType != tt.wantDecision.MatchType ||
+				got.DuplicateOf != tt.wantDecision.DuplicateOf || got.Score != tt.wantDecision.Score {
+				t.Errorf("decision = %+v, want %+v", got, tt.wantDecision)
+			}
+		})
+	}
+}
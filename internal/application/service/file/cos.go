@@ -96,6 +96,21 @@ func (s *cosFileService) SaveFile(ctx context.Context,
 	return fmt.Sprintf("%s%s", s.bucketURL, objectName), nil
 }
 
+// SaveStream uploads a file read from content to COS storage as it arrives
+func (s *cosFileService) SaveStream(ctx context.Context,
+	content io.Reader, tenantID uint64, knowledgeID string, fileName string,
+) (string, error) {
+	objectName := fmt.Sprintf("%s/%d/%s/%s%s",
+		s.cosPathPrefix, tenantID, knowledgeID, uuid.New().String(), filepath.Ext(fileName))
+	_, err := s.client.Object.Put(ctx, objectName, content, nil)
+	if err != nil {
+		// An aborted upload must not leave a partial object behind, even if the request was cancelled
+		s.client.Object.Delete(context.WithoutCancel(ctx), objectName)
+		return "", fmt.Errorf("failed to upload file to COS: %w", err)
+	}
+	return fmt.Sprintf("%s%s", s.bucketURL, objectName), nil
+}
+
 // GetFile retrieves a file from COS storage by its path URL
 func (s *cosFileService) GetFile(ctx context.Context, filePathUrl string) (io.ReadCloser, error) {
 	objectName := strings.TrimPrefix(filePathUrl, s.bucketURL)
@@ -116,6 +131,43 @@ func (s *cosFileService) DeleteFile(ctx context.Context, filePath string) error
 	return nil
 }
 
+// DeleteTenantFiles deletes all objects stored under the prefix of the tenant, including its
+// files in the temp bucket
+func (s *cosFileService) DeleteTenantFiles(ctx context.Context, tenantID uint64) (int, error) {
+	count, err := deleteCosPrefix(ctx, s.client, fmt.Sprintf("%s/%d/", s.cosPathPrefix, tenantID))
+	if err != nil || s.tempClient == nil {
+		return count, err
+	}
+	tempCount, err := deleteCosPrefix(ctx, s.tempClient, fmt.Sprintf("exports/%d/", tenantID))
+	return count + tempCount, err
+}
+
+// deleteCosPrefix deletes all objects of the bucket whose key starts with prefix
+func deleteCosPrefix(ctx context.Context, client *cos.Client, prefix string) (int, error) {
+	count := 0
+	marker := ""
+	for {
+		result, _, err := client.Bucket.Get(ctx, &cos.BucketGetOptions{
+			Prefix:  prefix,
+			Marker:  marker,
+			MaxKeys: 1000,
+		})
+		if err != nil {
+			return count, fmt.Errorf("failed to list files with prefix %s: %w", prefix, err)
+		}
+		for _, object := range result.Contents {
+			if _, err := client.Object.Delete(ctx, object.Key); err != nil {
+				return count, fmt.Errorf("failed to delete file %s: %w", object.Key, err)
+			}
+			count++
+		}
+		if !result.IsTruncated {
+			return count, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
 // SaveBytes saves bytes data to COS
 // If temp is true and temp bucket is configured, saves to temp bucket (with lifecycle auto-expiration)
 // Otherwise saves to main bucket
@@ -27,6 +27,16 @@ func (s *DummyFileService) SaveFile(ctx context.Context,
 	return uuid.New().String(), nil
 }
 
+// SaveStream drains content so that callers hashing it see the whole file, then returns a random UUID
+func (s *DummyFileService) SaveStream(ctx context.Context,
+	content io.Reader, tenantID uint64, knowledgeID string, fileName string,
+) (string, error) {
+	if _, err := io.Copy(io.Discard, content); err != nil {
+		return "", err
+	}
+	return uuid.New().String(), nil
+}
+
 // GetFile always returns an error as dummy service doesn't store files
 func (s *DummyFileService) GetFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
 	return nil, errors.New("not implemented")
@@ -37,6 +47,11 @@ func (s *DummyFileService) DeleteFile(ctx context.Context, filePath string) erro
 	return nil
 }
 
+// DeleteTenantFiles is a no-op operation that always succeeds
+func (s *DummyFileService) DeleteTenantFiles(ctx context.Context, tenantID uint64) (int, error) {
+	return 0, nil
+}
+
 // SaveBytes pretends to save bytes but just returns a random UUID
 func (s *DummyFileService) SaveBytes(ctx context.Context, data []byte, tenantID uint64, fileName string, temp bool) (string, error) {
 	return uuid.New().String(), nil
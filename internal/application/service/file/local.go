@@ -2,8 +2,10 @@ package file
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"mime/multipart"
 	"os"
 	"path/filepath"
@@ -78,6 +80,37 @@ func (s *localFileService) SaveFile(ctx context.Context,
 	return filePath, nil
 }
 
+// SaveStream stores a file read from content to the local file system as it arrives
+// The file is stored in the same directory structure as SaveFile; a partially written file is removed on failure
+func (s *localFileService) SaveStream(ctx context.Context,
+	content io.Reader, tenantID uint64, knowledgeID string, fileName string,
+) (string, error) {
+	dir := filepath.Join(s.baseDir, fmt.Sprintf("%d", tenantID), knowledgeID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		logger.Errorf(ctx, "Failed to create directory: %v", err)
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	filePath := filepath.Join(dir, fmt.Sprintf("%d%s", time.Now().UnixNano(), filepath.Ext(fileName)))
+
+	dst, err := os.Create(filePath)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to create destination file: %v", err)
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	_, err = io.Copy(dst, content)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logger.Errorf(ctx, "Failed to write file content: %v", err)
+		os.Remove(filePath)
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
+	logger.Infof(ctx, "File streamed successfully: %s", filePath)
+	return filePath, nil
+}
+
 // GetFile retrieves a file from the local file system by its path
 // Returns a ReadCloser for reading the file content
 func (s *localFileService) GetFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
@@ -110,6 +143,36 @@ func (s *localFileService) DeleteFile(ctx context.Context, filePath string) erro
 	return nil
 }
 
+// DeleteTenantFiles removes the storage directory of the tenant with all of its files
+func (s *localFileService) DeleteTenantFiles(ctx context.Context, tenantID uint64) (int, error) {
+	dir := filepath.Join(s.baseDir, fmt.Sprintf("%d", tenantID))
+	logger.Infof(ctx, "Deleting tenant files: %s", dir)
+
+	count := 0
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			count++
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tenant files: %w", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		logger.Errorf(ctx, "Failed to delete tenant files: %v", err)
+		return 0, fmt.Errorf("failed to delete tenant files: %w", err)
+	}
+
+	logger.Infof(ctx, "Deleted %d tenant files", count)
+	return count, nil
+}
+
 // SaveBytes saves bytes data to a file and returns the file path
 // temp parameter is ignored for local storage (no auto-expiration support)
 func (s *localFileService) SaveBytes(ctx context.Context, data []byte, tenantID uint64, fileName string, temp bool) (string, error) {
@@ -80,6 +80,25 @@ func (s *minioFileService) SaveFile(ctx context.Context,
 	return fmt.Sprintf("minio://%s/%s", s.bucketName, objectName), nil
 }
 
+// SaveStream uploads a file read from content to MinIO as it arrives
+// The size is unknown, so the upload is sent as a multipart upload that buffers one part at a time
+func (s *minioFileService) SaveStream(ctx context.Context,
+	content io.Reader, tenantID uint64, knowledgeID string, fileName string,
+) (string, error) {
+	objectName := fmt.Sprintf("%d/%s/%s%s", tenantID, knowledgeID, uuid.New().String(), filepath.Ext(fileName))
+
+	_, err := s.client.PutObject(ctx, s.bucketName, objectName, content, -1, minio.PutObjectOptions{})
+	if err != nil {
+		// An aborted upload must not leave a partial object or multipart upload behind,
+		// even if the request was cancelled
+		cleanupCtx := context.WithoutCancel(ctx)
+		s.client.RemoveIncompleteUpload(cleanupCtx, s.bucketName, objectName)
+		s.client.RemoveObject(cleanupCtx, s.bucketName, objectName, minio.RemoveObjectOptions{})
+		return "", fmt.Errorf("failed to upload file to MinIO: %w", err)
+	}
+	return fmt.Sprintf("minio://%s/%s", s.bucketName, objectName), nil
+}
+
 // GetFile gets a file from MinIO
 func (s *minioFileService) GetFile(ctx context.Context, filePath string) (io.ReadCloser, error) {
 	// Parse MinIO path
@@ -128,6 +147,26 @@ func (s *minioFileService) DeleteFile(ctx context.Context, filePath string) erro
 	return nil
 }
 
+// DeleteTenantFiles deletes all objects stored under the prefix of the tenant
+func (s *minioFileService) DeleteTenantFiles(ctx context.Context, tenantID uint64) (int, error) {
+	count := 0
+	for object := range s.client.ListObjects(ctx, s.bucketName, minio.ListObjectsOptions{
+		Prefix:    fmt.Sprintf("%d/", tenantID),
+		Recursive: true,
+	}) {
+		if object.Err != nil {
+			return count, fmt.Errorf("failed to list tenant files: %w", object.Err)
+		}
+		if err := s.client.RemoveObject(ctx, s.bucketName, object.Key, minio.RemoveObjectOptions{
+			GovernanceBypass: true,
+		}); err != nil {
+			return count, fmt.Errorf("failed to delete file %s: %w", object.Key, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
 // SaveBytes saves bytes data to MinIO and returns the file path
 // temp parameter is ignored for MinIO (no auto-expiration support in this implementation)
 func (s *minioFileService) SaveBytes(ctx context.Context, data []byte, tenantID uint64, fileName string, temp bool) (string, error) {
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"bufio"
 	"context"
 	"crypto/md5"
 	"encoding/hex"
@@ -9,6 +10,8 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
+	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"slices"
@@ -38,8 +41,6 @@ import (
 
 // Error definitions for knowledge service operations
 var (
-	// ErrInvalidFileType is returned when an unsupported file type is provided
-	ErrInvalidFileType = errors.New("unsupported file type")
 	// ErrInvalidURL is returned when an invalid URL is provided
 	ErrInvalidURL = errors.New("invalid URL")
 	// ErrChunkNotFound is returned when a requested chunk cannot be found
@@ -70,6 +71,8 @@ type knowledgeService struct {
 	task            *asynq.Client
 	graphEngine     interfaces.RetrieveGraphRepository
 	redisClient     *redis.Client
+	answerCache     interfaces.AnswerCacheService
+	taskWebhooks    interfaces.TaskWebhookService
 }
 
 const (
@@ -95,6 +98,8 @@ func NewKnowledgeService(
 	graphEngine interfaces.RetrieveGraphRepository,
 	retrieveEngine interfaces.RetrieveEngineRegistry,
 	redisClient *redis.Client,
+	answerCache interfaces.AnswerCacheService,
+	taskWebhooks interfaces.TaskWebhookService,
 ) (interfaces.KnowledgeService, error) {
 	return &knowledgeService{
 		config:          config,
@@ -112,6 +117,8 @@ func NewKnowledgeService(
 		graphEngine:     graphEngine,
 		retrieveEngine:  retrieveEngine,
 		redisClient:     redisClient,
+		answerCache:     answerCache,
+		taskWebhooks:    taskWebhooks,
 	}, nil
 }
 
@@ -153,69 +160,207 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 		logger.Infof(ctx, "Using custom filename: %s (original: %s)", customFileName, file.Filename)
 	}
 
-	logger.Infof(ctx, "Knowledge base ID: %s, file: %s", kbID, fileName)
+	src, err := file.Open()
+	if err != nil {
+		logger.Errorf(ctx, "Failed to open uploaded file: %v", err)
+		return nil, err
+	}
+	defer src.Close()
 
-	// Get knowledge base configuration
-	logger.Info(ctx, "Getting knowledge base configuration")
-	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	staged, err := s.StageKnowledgeFile(ctx, kbID, fileName, src)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
 		return nil, err
 	}
+	return s.CreateKnowledgeFromStagedFile(ctx, kbID, staged, metadata, enableMultimodel, customFileName, tagID)
+}
+
+// checkKnowledgeFile rejects files the knowledge base can't process before anything is stored
+func (s *knowledgeService) checkKnowledgeFile(ctx context.Context, kb *types.KnowledgeBase, fileName string) error {
+	// Reject unsupported file types before any processing
+	fileType := getFileType(fileName)
+	logger.Infof(ctx, "Checking file type: %s", fileType)
+	formats := types.SupportedFileFormats(kb.VLMConfig.IsEnabled())
+	if format, ok := types.LookupFileFormat(formats, fileType); !ok || !format.Available {
+		logger.Errorf(ctx, "Unsupported file type: %s", fileType)
+		return types.NewUnsupportedFileTypeError(formats, fileType)
+	}
 
 	// 检查多模态配置完整性 - 只在图片文件时校验
 	// 检查是否为图片文件
-	if !IsImageType(getFileType(fileName)) {
+	if !IsImageType(strings.ToLower(fileType)) {
 		logger.Info(ctx, "Non-image file with multimodal enabled, skipping COS/VLM validation")
-	} else {
-		// 检查COS配置
-		switch kb.StorageConfig.Provider {
-		case "cos":
-			if kb.StorageConfig.SecretID == "" || kb.StorageConfig.SecretKey == "" ||
-				kb.StorageConfig.Region == "" || kb.StorageConfig.BucketName == "" ||
-				kb.StorageConfig.AppID == "" {
-				logger.Error(ctx, "COS configuration incomplete for image multimodal processing")
-				return nil, werrors.NewBadRequestError("上传图片文件需要完整的对象存储配置信息, 请前往系统设置页面进行补全")
-			}
-		case "minio":
-			if kb.StorageConfig.BucketName == "" {
-				logger.Error(ctx, "MinIO configuration incomplete for image multimodal processing")
-				return nil, werrors.NewBadRequestError("上传图片文件需要完整的对象存储配置信息, 请前往系统设置页面进行补全")
-			}
+		return nil
+	}
+	// 检查COS配置
+	switch kb.StorageConfig.Provider {
+	case "cos":
+		if kb.StorageConfig.SecretID == "" || kb.StorageConfig.SecretKey == "" ||
+			kb.StorageConfig.Region == "" || kb.StorageConfig.BucketName == "" ||
+			kb.StorageConfig.AppID == "" {
+			logger.Error(ctx, "COS configuration incomplete for image multimodal processing")
+			return werrors.NewBadRequestError("上传图片文件需要完整的对象存储配置信息, 请前往系统设置页面进行补全")
+		}
+	case "minio":
+		if kb.StorageConfig.BucketName == "" {
+			logger.Error(ctx, "MinIO configuration incomplete for image multimodal processing")
+			return werrors.NewBadRequestError("上传图片文件需要完整的对象存储配置信息, 请前往系统设置页面进行补全")
 		}
+	}
+
+	logger.Info(ctx, "Image multimodal configuration validation passed")
+	return nil
+}
+
+// byteCounter counts the bytes written to it
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// StageKnowledgeFile spools an uploaded file to a local temporary file as it arrives, computing its hash
+// on the fly, so that large files are never buffered in memory and nothing reaches storage before the
+// duplicate check
+func (s *knowledgeService) StageKnowledgeFile(ctx context.Context,
+	kbID string, fileName string, content io.Reader,
+) (*types.StagedKnowledgeFile, error) {
+	logger.Infof(ctx, "Staging uploaded file, knowledge base ID: %s, file: %s", kbID, fileName)
 
-		// 检查VLM配置
-		if !kb.VLMConfig.Enabled || kb.VLMConfig.ModelID == "" {
-			logger.Error(ctx, "VLM model is not configured")
-			return nil, werrors.NewBadRequestError("上传图片文件需要设置VLM模型")
+	// Get knowledge base configuration
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+		return nil, err
+	}
+	if err := s.checkKnowledgeFile(ctx, kb, fileName); err != nil {
+		return nil, err
+	}
+
+	// Check storage quota
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenantInfo.StorageQuota > 0 && tenantInfo.StorageUsed >= tenantInfo.StorageQuota {
+		logger.Error(ctx, "Storage quota exceeded")
+		return nil, types.NewStorageQuotaExceededError()
+	}
+
+	tmp, err := os.CreateTemp("", "knowledge-upload-*"+filepath.Ext(fileName))
+	if err != nil {
+		logger.Errorf(ctx, "Failed to create temporary file: %v", err)
+		return nil, err
+	}
+	hash := md5.New()
+	var size byteCounter
+	_, err = io.Copy(io.MultiWriter(tmp, hash, &size), content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		logger.Errorf(ctx, "Failed to stage uploaded file: %v", err)
+		return nil, err
+	}
+
+	staged := &types.StagedKnowledgeFile{
+		// The knowledge ID is reserved now because it is part of the storage path
+		KnowledgeID: uuid.New().String(),
+		FileName:    fileName,
+		TempPath:    tmp.Name(),
+		FileSize:    int64(size),
+		FileHash:    hex.EncodeToString(hash.Sum(nil)),
+	}
+	logger.Infof(ctx, "File staged: %s, size: %d bytes, hash: %s", staged.TempPath, staged.FileSize, staged.FileHash)
+	return staged, nil
+}
+
+// DiscardStagedKnowledgeFile removes a staged file, and its stored copy if it was already saved,
+// when its knowledge will not be created
+func (s *knowledgeService) DiscardStagedKnowledgeFile(ctx context.Context, file *types.StagedKnowledgeFile) {
+	if file == nil {
+		return
+	}
+	if file.TempPath != "" {
+		if err := os.Remove(file.TempPath); err != nil && !os.IsNotExist(err) {
+			logger.Errorf(ctx, "Failed to remove staged file %s: %v", file.TempPath, err)
+		}
+	}
+	if file.FilePath != "" {
+		logger.Infof(ctx, "Deleting stored file of knowledge that was not created: %s", file.FilePath)
+		if err := s.fileSvc.DeleteFile(context.WithoutCancel(ctx), file.FilePath); err != nil {
+			logger.Errorf(ctx, "Failed to delete stored file %s: %v", file.FilePath, err)
 		}
+	}
+}
 
-		logger.Info(ctx, "Image multimodal configuration validation passed")
+// saveStagedKnowledgeFile copies a staged file to storage and records its storage path
+func (s *knowledgeService) saveStagedKnowledgeFile(ctx context.Context,
+	tenantID uint64, file *types.StagedKnowledgeFile,
+) error {
+	src, err := os.Open(file.TempPath)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to open staged file: %v", err)
+		return err
 	}
+	defer src.Close()
+	filePath, err := s.fileSvc.SaveStream(ctx, src, tenantID, file.KnowledgeID, file.FileName)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to save file, knowledge ID: %s, error: %v", file.KnowledgeID, err)
+		return err
+	}
+	file.FilePath = filePath
+	logger.Infof(ctx, "File stored: %s", filePath)
+	return nil
+}
+
+// CreateKnowledgeFromStagedFile creates the knowledge of a file staged by StageKnowledgeFile, saves the file
+// to storage and enqueues its processing. The file is only saved once the duplicate check has passed, and
+// the staged file is always removed; the stored copy is deleted unless the knowledge is created and enqueued.
+func (s *knowledgeService) CreateKnowledgeFromStagedFile(ctx context.Context,
+	kbID string, file *types.StagedKnowledgeFile, metadata map[string]string, enableMultimodel *bool,
+	customFileName string, tagID string,
+) (knowledge *types.Knowledge, err error) {
+	created := false
+	defer func() {
+		if created {
+			// Only the staged copy is left to clean up
+			file.FilePath = ""
+		}
+		s.DiscardStagedKnowledgeFile(ctx, file)
+	}()
 
-	// Validate file type
-	logger.Infof(ctx, "Checking file type: %s", fileName)
-	if !isValidFileType(fileName) {
-		logger.Error(ctx, "Invalid file type")
-		return nil, ErrInvalidFileType
+	// Use custom filename if provided, otherwise use original filename
+	fileName := file.FileName
+	if customFileName != "" {
+		fileName = customFileName
 	}
+	logger.Infof(ctx, "Creating knowledge from staged file, knowledge base ID: %s, file: %s", kbID, fileName)
 
-	// Calculate file hash for deduplication
-	logger.Info(ctx, "Calculating file hash")
-	hash, err := calculateFileHash(file)
+	// Get knowledge base configuration
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to calculate file hash: %v", err)
+		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+		return nil, err
+	}
+	// The custom filename may arrive after the file, so its type is checked again
+	if err := s.checkKnowledgeFile(ctx, kb, fileName); err != nil {
 		return nil, err
 	}
 
+	// 按知识库的元数据规范校验元数据
+	metadata, err = kb.MetadataSchemaConfig.Apply(metadata)
+	if err != nil {
+		logger.Errorf(ctx, "Metadata does not match the schema: %v", err)
+		return nil, werrors.NewBadRequestError(fmt.Sprintf("元数据不符合知识库的元数据规范: %v", err))
+	}
+
 	// Check if file already exists
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 	logger.Infof(ctx, "Checking if file exists, tenant ID: %d", tenantID)
 	exists, existingKnowledge, err := s.repo.CheckKnowledgeExists(ctx, tenantID, kbID, &types.KnowledgeCheckParams{
 		Type:     "file",
 		FileName: fileName,
-		FileSize: file.Size,
-		FileHash: hash,
+		FileSize: file.FileSize,
+		FileHash: file.FileHash,
 	})
 	if err != nil {
 		logger.Errorf(ctx, "Failed to check knowledge existence: %v", err)
@@ -231,13 +376,6 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 		return existingKnowledge, types.NewDuplicateFileError(existingKnowledge)
 	}
 
-	// Check storage quota
-	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
-	if tenantInfo.StorageQuota > 0 && tenantInfo.StorageUsed >= tenantInfo.StorageQuota {
-		logger.Error(ctx, "Storage quota exceeded")
-		return nil, types.NewStorageQuotaExceededError()
-	}
-
 	// Convert metadata to JSON format if provided
 	var metadataJSON types.JSON
 	if metadata != nil {
@@ -256,9 +394,15 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 		return nil, werrors.NewValidationError("文件名包含非法字符")
 	}
 
+	// The file is new, so it can be saved to storage now
+	if err := s.saveStagedKnowledgeFile(ctx, tenantID, file); err != nil {
+		return nil, err
+	}
+
 	// Create knowledge record
 	logger.Info(ctx, "Creating knowledge record")
-	knowledge := &types.Knowledge{
+	knowledge = &types.Knowledge{
+		ID:               file.KnowledgeID,
 		TenantID:         tenantID,
 		KnowledgeBaseID:  kbID,
 		TagID:            tagID, // 设置分类ID，用于知识分类管理
@@ -266,8 +410,9 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 		Title:            safeFilename,
 		FileName:         safeFilename,
 		FileType:         getFileType(safeFilename),
-		FileSize:         file.Size,
-		FileHash:         hash,
+		FileSize:         file.FileSize,
+		FileHash:         file.FileHash,
+		FilePath:         file.FilePath,
 		ParseStatus:      "pending",
 		EnableStatus:     "disabled",
 		CreatedAt:        time.Now(),
@@ -281,24 +426,7 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 		logger.Errorf(ctx, "Failed to create knowledge record, ID: %s, error: %v", knowledge.ID, err)
 		return nil, err
 	}
-	// Save the file to storage
-	logger.Infof(ctx, "Saving file, knowledge ID: %s", knowledge.ID)
-	filePath, err := s.fileSvc.SaveFile(ctx, file, knowledge.TenantID, knowledge.ID)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to save file, knowledge ID: %s, error: %v", knowledge.ID, err)
-		return nil, err
-	}
-	knowledge.FilePath = filePath
-
-	// Update knowledge record with file path
-	logger.Info(ctx, "Updating knowledge record with file path")
-	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
-		logger.Errorf(ctx, "Failed to update knowledge with file path, ID: %s, error: %v", knowledge.ID, err)
-		return nil, err
-	}
 
-	// Enqueue document processing task to Asynq
-	logger.Info(ctx, "Enqueuing document processing task to Asynq")
 	enableMultimodelValue := false
 	if enableMultimodel != nil {
 		enableMultimodelValue = *enableMultimodel
@@ -320,35 +448,45 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 		TenantID:                 tenantID,
 		KnowledgeID:              knowledge.ID,
 		KnowledgeBaseID:          kbID,
-		FilePath:                 filePath,
+		FilePath:                 file.FilePath,
 		FileName:                 safeFilename,
 		FileType:                 getFileType(safeFilename),
 		EnableMultimodel:         enableMultimodelValue,
 		EnableQuestionGeneration: enableQuestionGeneration,
 		QuestionCount:            questionCount,
 	}
+	taskPayload.RequestId, _ = ctx.Value(types.RequestIDContextKey).(string)
 
-	payloadBytes, err := json.Marshal(taskPayload)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to marshal document process task payload: %v", err)
-		// 即使入队失败，也返回knowledge，因为文件已保存
-		return knowledge, nil
-	}
-
-	task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
-	info, err := s.task.Enqueue(task)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to enqueue document process task: %v", err)
-		// 即使入队失败，也返回knowledge，因为文件已保存
-		return knowledge, nil
+	if s.processesSynchronously(file.FileSize) {
+		// Small files are processed within the request and returned ready
+		created = true
+		knowledge, err = s.processDocumentSync(ctx, taskPayload)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Enqueue document processing task to Asynq
+		logger.Info(ctx, "Enqueuing document processing task to Asynq")
+		info, err := s.enqueueDocumentProcess(taskPayload)
+		if err != nil {
+			// Without a processing task the knowledge would stay pending forever, so it is removed with its file
+			logger.Errorf(ctx, "Failed to enqueue document process task: %v", err)
+			if delErr := s.repo.DeleteKnowledge(ctx, tenantID, knowledge.ID); delErr != nil {
+				logger.Errorf(ctx, "Failed to delete knowledge %s after enqueue failure: %v", knowledge.ID, delErr)
+			}
+			return nil, werrors.NewInternalServerError("Failed to schedule document processing").WithDetails(err.Error())
+		}
+		created = true
+		logger.Infof(
+			ctx,
+			"Enqueued document process task: id=%s queue=%s knowledge_id=%s",
+			info.ID,
+			info.Queue,
+			knowledge.ID,
+		)
+		knowledge.ProcessingMode = types.KnowledgeProcessingAsync
+		knowledge.ProcessingTaskID = info.ID
 	}
-	logger.Infof(
-		ctx,
-		"Enqueued document process task: id=%s queue=%s knowledge_id=%s",
-		info.ID,
-		info.Queue,
-		knowledge.ID,
-	)
 
 	if slices.Contains([]string{"csv", "xlsx", "xls"}, getFileType(safeFilename)) {
 		NewDataTableSummaryTask(ctx, s.task, tenantID, knowledge.ID, kb.SummaryModelID, kb.EmbeddingModelID)
@@ -358,6 +496,17 @@ func (s *knowledgeService) CreateKnowledgeFromFile(ctx context.Context,
 	return knowledge, nil
 }
 
+// enqueueDocumentProcess enqueues the processing task of an uploaded document
+func (s *knowledgeService) enqueueDocumentProcess(payload types.DocumentProcessPayload) (*asynq.TaskInfo, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal document process task payload: %w", err)
+	}
+	task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"),
+		asynq.MaxRetry(s.ingestionMaxRetry()))
+	return s.task.Enqueue(task)
+}
+
 // CreateKnowledgeFromURL creates a knowledge entry from a URL source
 // tagID is optional - when provided, the knowledge will be assigned to the specified tag/category.
 func (s *knowledgeService) CreateKnowledgeFromURL(ctx context.Context,
@@ -479,7 +628,8 @@ func (s *knowledgeService) CreateKnowledgeFromURL(ctx context.Context,
 		return knowledge, nil
 	}
 
-	task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
+	task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"),
+		asynq.MaxRetry(s.ingestionMaxRetry()))
 	info, err := s.task.Enqueue(task)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to enqueue URL process task: %v", err)
@@ -585,7 +735,7 @@ func (s *knowledgeService) CreateKnowledgeFromManual(ctx context.Context,
 
 	if status == types.ManualKnowledgeStatusPublish {
 		logger.Infof(ctx, "Manual knowledge created, scheduling indexing, ID: %s", knowledge.ID)
-		s.triggerManualProcessing(ctx, kb, knowledge, cleanContent, false)
+		s.triggerManualProcessing(ctx, kb, knowledge, cleanContent, false, false)
 	}
 
 	return knowledge, nil
@@ -684,7 +834,8 @@ func (s *knowledgeService) createKnowledgeFromPassageInternal(ctx context.Contex
 			return knowledge, nil
 		}
 
-		task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"))
+		task := asynq.NewTask(types.TypeDocumentProcess, payloadBytes, asynq.Queue("default"),
+			asynq.MaxRetry(s.ingestionMaxRetry()))
 		info, err := s.task.Enqueue(task)
 		if err != nil {
 			logger.Errorf(ctx, "Failed to enqueue passage process task: %v", err)
@@ -722,10 +873,10 @@ func (s *knowledgeService) ListKnowledgeByKnowledgeBaseID(ctx context.Context,
 
 // ListPagedKnowledgeByKnowledgeBaseID returns paginated knowledge entries in a knowledge base
 func (s *knowledgeService) ListPagedKnowledgeByKnowledgeBaseID(ctx context.Context,
-	kbID string, page *types.Pagination, tagID string, keyword string, fileType string,
+	kbID string, page *types.Pagination, tagID string, keyword string, fileType string, isEnabled *bool,
 ) (*types.PageResult, error) {
 	knowledges, total, err := s.repo.ListPagedKnowledgeByKnowledgeBaseID(ctx,
-		ctx.Value(types.TenantIDContextKey).(uint64), kbID, page, tagID, keyword, fileType)
+		ctx.Value(types.TenantIDContextKey).(uint64), kbID, page, tagID, keyword, fileType, isEnabled)
 	if err != nil {
 		return nil, err
 	}
@@ -774,6 +925,7 @@ func (s *knowledgeService) DeleteKnowledge(ctx context.Context, id string) error
 			logger.GetLogger(ctx).WithField("error", err).Errorf("DeleteKnowledge delete knowledge embedding failed")
 			return err
 		}
+		s.deleteKnowledgeVectorSpaces(ctx, retrieveEngine, []*types.Knowledge{knowledge})
 		return nil
 	})
 
@@ -814,6 +966,7 @@ func (s *knowledgeService) DeleteKnowledge(ctx context.Context, id string) error
 	if err = wg.Wait(); err != nil {
 		return err
 	}
+	invalidateAnswerCache(ctx, s.answerCache, knowledge.KnowledgeBaseID)
 	// Delete the knowledge entry itself from the database
 	return s.repo.DeleteKnowledge(ctx, ctx.Value(types.TenantIDContextKey).(uint64), id)
 }
@@ -877,6 +1030,7 @@ func (s *knowledgeService) DeleteKnowledgeList(ctx context.Context, ids []string
 				return err
 			}
 		}
+		s.deleteKnowledgeVectorSpaces(ctx, retrieveEngine, knowledgeList)
 		return nil
 	})
 
@@ -926,14 +1080,37 @@ func (s *knowledgeService) DeleteKnowledgeList(ctx context.Context, ids []string
 	if err = wg.Wait(); err != nil {
 		return err
 	}
+	s.invalidateKnowledgeAnswerCache(ctx, knowledgeList)
 	// 5. Delete the knowledge entry itself from the database
 	return s.repo.DeleteKnowledgeList(ctx, tenantInfo.ID, ids)
 }
 
+// invalidateKnowledgeAnswerCache invalidates the cached answers of the knowledge bases of the knowledge
+func (s *knowledgeService) invalidateKnowledgeAnswerCache(ctx context.Context, knowledgeList []*types.Knowledge) {
+	seen := make(map[string]bool)
+	for _, knowledge := range knowledgeList {
+		if knowledge == nil || seen[knowledge.KnowledgeBaseID] {
+			continue
+		}
+		seen[knowledge.KnowledgeBaseID] = true
+		invalidateAnswerCache(ctx, s.answerCache, knowledge.KnowledgeBaseID)
+	}
+}
+
+// cloneOptions changes how knowledge and chunks are cloned into another knowledge base
+type cloneOptions struct {
+	// tagIDMapping maps source tag IDs to target tag IDs resolved beforehand; when set, tags missing from it
+	// are left empty instead of being looked up or created in the target
+	tagIDMapping map[string]string
+	// reembed indexes the cloned chunks with the target embedding model instead of copying the source vectors
+	reembed bool
+}
+
 func (s *knowledgeService) cloneKnowledge(
 	ctx context.Context,
 	src *types.Knowledge,
 	targetKB *types.KnowledgeBase,
+	opts ...cloneOptions,
 ) (err error) {
 	if src.ParseStatus != "completed" {
 		logger.GetLogger(ctx).WithField("knowledge_id", src.ID).Errorf("MoveKnowledge parse status is not completed")
@@ -958,6 +1135,10 @@ func (s *knowledgeService) cloneKnowledge(
 		FilePath:         src.FilePath,
 		StorageSize:      src.StorageSize,
 		Metadata:         src.Metadata,
+		ChunkSizeStats:   src.ChunkSizeStats,
+	}
+	if len(opts) > 0 && opts[0].tagIDMapping != nil {
+		dst.TagID = opts[0].tagIDMapping[src.TagID]
 	}
 	defer func() {
 		if err != nil {
@@ -982,7 +1163,7 @@ func (s *knowledgeService) cloneKnowledge(
 		logger.GetLogger(ctx).WithField("error", err).Errorf("MoveKnowledge update tenant storage used failed")
 		return
 	}
-	if err = s.CloneChunk(ctx, src, dst); err != nil {
+	if err = s.cloneChunks(ctx, src, dst, opts...); err != nil {
 		logger.GetLogger(ctx).WithField("knowledge_id", dst.ID).
 			WithField("error", err).Errorf("MoveKnowledge move chunks failed")
 		return
@@ -1026,18 +1207,27 @@ func (s *knowledgeService) processDocumentFromPassage(ctx context.Context,
 type ProcessChunksOptions struct {
 	EnableQuestionGeneration bool
 	QuestionCount            int
+	// Stages resolved by the caller; nil resolves them from the knowledge base
+	Stages *types.PipelineStages
+	// Incremental keeps the unchanged chunks of the previous processing, which the caller left in place
+	// along with its storage usage, and only embeds new and changed chunks
+	Incremental bool
 }
 
 // processChunks processes chunks and creates embeddings for knowledge content
 func (s *knowledgeService) processChunks(ctx context.Context,
 	kb *types.KnowledgeBase, knowledge *types.Knowledge, chunks []*proto.Chunk,
 	opts ...ProcessChunksOptions,
-) {
+) error {
 	// Get options
 	var options ProcessChunksOptions
 	if len(opts) > 0 {
 		options = opts[0]
 	}
+	stages := pipelineStages(kb, kb.IsMultimodalEnabled(), options.EnableQuestionGeneration)
+	if options.Stages != nil {
+		stages = *options.Stages
+	}
 
 	ctx, span := tracing.ContextWithSpan(ctx, "knowledgeService.processChunks")
 	defer span.End()
@@ -1053,46 +1243,84 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 	if s.isKnowledgeDeleting(ctx, knowledge.TenantID, knowledge.ID) {
 		logger.Infof(ctx, "Knowledge is being deleted, aborting chunk processing: %s", knowledge.ID)
 		span.AddEvent("aborted: knowledge is being deleted")
-		return
+		return nil
 	}
 
 	// Get embedding model for vectorization
 	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
 	if err != nil {
 		logger.GetLogger(ctx).WithField("error", err).Errorf("processChunks get embedding model failed")
+		knowledge.MarkProcessFailed(err, false)
+		s.repo.UpdateKnowledge(ctx, knowledge)
 		span.RecordError(err)
-		return
+		return err
 	}
 
-	// 幂等性处理：清理旧的chunks和索引数据，避免重复数据
-	logger.Infof(ctx, "Cleaning up existing chunks and index data for knowledge: %s", knowledge.ID)
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
 
-	// 删除旧的chunks
-	if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
-		logger.Warnf(ctx, "Failed to delete existing chunks (may not exist): %v", err)
-		// 不返回错误，继续处理（可能没有旧数据）
+	// 增量更新时保留上次处理的chunks与索引，仅替换变化的部分；调用方保留了其存储占用
+	var previousStorageSize int64
+	var previousChunks []*types.Chunk
+	incremental := false
+	if options.Incremental {
+		previousStorageSize = knowledge.StorageSize
+		if err == nil {
+			previousChunks, incremental = s.reusableChunks(ctx, kb, knowledge, stages)
+		}
 	}
+	knowledge.ChunkReuseStats = nil
 
-	// 删除旧的索引数据
-	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
-	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
-	if err == nil {
-		if err := retrieveEngine.DeleteByKnowledgeIDList(ctx, []string{knowledge.ID}, embeddingModel.GetDimensions(), knowledge.Type); err != nil {
-			logger.Warnf(ctx, "Failed to delete existing index data (may not exist): %v", err)
+	if !incremental {
+		// 幂等性处理：清理旧的chunks和索引数据，避免重复数据
+		logger.Infof(ctx, "Cleaning up existing chunks and index data for knowledge: %s", knowledge.ID)
+
+		// 删除旧的chunks
+		if err := s.chunkService.DeleteChunksByKnowledgeID(ctx, knowledge.ID); err != nil {
+			logger.Warnf(ctx, "Failed to delete existing chunks (may not exist): %v", err)
 			// 不返回错误，继续处理（可能没有旧数据）
-		} else {
-			logger.Infof(ctx, "Successfully deleted existing index data for knowledge: %s", knowledge.ID)
 		}
+
+		// 删除旧的索引数据
+		if err == nil {
+			if err := retrieveEngine.DeleteByKnowledgeIDList(ctx, []string{knowledge.ID}, embeddingModel.GetDimensions(), knowledge.Type); err != nil {
+				logger.Warnf(ctx, "Failed to delete existing index data (may not exist): %v", err)
+				// 不返回错误，继续处理（可能没有旧数据）
+			} else {
+				logger.Infof(ctx, "Successfully deleted existing index data for knowledge: %s", knowledge.ID)
+			}
+			deleteVectorSpaceIndices(ctx, s.modelService, retrieveEngine, kb, []string{knowledge.ID}, knowledge.Type)
+		}
+
+		// 删除知识图谱数据（如果存在）
+		namespace := types.NameSpace{KnowledgeBase: knowledge.KnowledgeBaseID, Knowledge: knowledge.ID}
+		if err := s.graphEngine.DelGraph(ctx, []types.NameSpace{namespace}); err != nil {
+			logger.Warnf(ctx, "Failed to delete existing graph data (may not exist): %v", err)
+			// 不返回错误，继续处理
+		}
+
+		logger.Infof(ctx, "Cleanup completed, starting to process new chunks")
 	}
 
-	// 删除知识图谱数据（如果存在）
-	namespace := types.NameSpace{KnowledgeBase: knowledge.KnowledgeBaseID, Knowledge: knowledge.ID}
-	if err := s.graphEngine.DelGraph(ctx, []types.NameSpace{namespace}); err != nil {
-		logger.Warnf(ctx, "Failed to delete existing graph data (may not exist): %v", err)
-		// 不返回错误，继续处理
+	// Without the extract stage the file is only stored
+	if !stages.Extract {
+		chunks = nil
 	}
 
-	logger.Infof(ctx, "Cleanup completed, starting to process new chunks")
+	// 合并过短的片段、拆分过长的块，并记录块大小分布；按章节分块时以标题划分并记录章节路径
+	var sectionPaths map[int32][]string
+	knowledge.ChunkSizeStats = nil
+	if stages.Chunk {
+		if kb.ChunkingConfig.UsesSections() {
+			chunks, sectionPaths, knowledge.ChunkSizeStats = splitSections(chunks, &kb.ChunkingConfig)
+		} else {
+			chunks, knowledge.ChunkSizeStats = normalizeChunkSizes(chunks, &kb.ChunkingConfig)
+		}
+		logger.Infof(ctx, "Chunk sizes normalized: %d chunks, %d merged, %d split, size min/avg/max %d/%d/%d",
+			knowledge.ChunkSizeStats.Count, knowledge.ChunkSizeStats.Merged, knowledge.ChunkSizeStats.Split,
+			knowledge.ChunkSizeStats.MinSize, knowledge.ChunkSizeStats.AvgSize, knowledge.ChunkSizeStats.MaxSize)
+	}
+	logger.Infof(ctx, "Pipeline stages of knowledge %s, skipped: %v", knowledge.ID, stages.Skipped())
 
 	// ========== DocReader 解析结果日志 ==========
 	logger.Infof(ctx, "[DocReader] ========== 解析结果概览 ==========")
@@ -1160,6 +1388,8 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 
 	// 重新分配容量，考虑图片相关的Chunk
 	insertChunks := make([]*types.Chunk, 0, len(chunks)+imageChunkCount)
+	// Chunk ID 由知识ID、位置与内容确定，重新解析相同内容时保持不变
+	chunkIDs := types.NewChunkIDGenerator(knowledge.ID)
 
 	for _, chunkData := range chunks {
 		if strings.TrimSpace(chunkData.Content) == "" {
@@ -1168,19 +1398,24 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 
 		// 创建主文本Chunk
 		textChunk := &types.Chunk{
-			ID:              uuid.New().String(),
 			TenantID:        knowledge.TenantID,
 			KnowledgeID:     knowledge.ID,
 			KnowledgeBaseID: knowledge.KnowledgeBaseID,
 			Content:         chunkData.Content,
 			ChunkIndex:      int(chunkData.Seq),
-			IsEnabled:       true,
+			IsEnabled:       knowledge.IsEnabled,
 			CreatedAt:       time.Now(),
 			UpdatedAt:       time.Now(),
 			StartAt:         int(chunkData.Start),
 			EndAt:           int(chunkData.End),
 			ChunkType:       types.ChunkTypeText,
 		}
+		if path := sectionPaths[chunkData.Seq]; len(path) > 0 {
+			if err := textChunk.SetDocumentMetadata(&types.DocumentChunkMetadata{SectionPath: path}); err != nil {
+				logger.Warnf(ctx, "Failed to set section path of chunk #%d: %v", chunkData.Seq, err)
+			}
+		}
+		textChunk.ID = chunkIDs.ID(textChunk)
 		var chunkImages []types.ImageInfo
 		insertChunks = append(insertChunks, textChunk)
 
@@ -1210,13 +1445,12 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 				// 如果有OCR文本，创建OCR Chunk
 				if img.OcrText != "" {
 					ocrChunk := &types.Chunk{
-						ID:              uuid.New().String(),
 						TenantID:        knowledge.TenantID,
 						KnowledgeID:     knowledge.ID,
 						KnowledgeBaseID: knowledge.KnowledgeBaseID,
 						Content:         img.OcrText,
 						ChunkIndex:      maxSeq + i*100 + 1, // 使用不冲突的索引方式
-						IsEnabled:       true,
+						IsEnabled:       knowledge.IsEnabled,
 						CreatedAt:       time.Now(),
 						UpdatedAt:       time.Now(),
 						StartAt:         int(img.Start),
@@ -1225,6 +1459,7 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 						ParentChunkID:   textChunk.ID,
 						ImageInfo:       string(imageInfoJSON),
 					}
+					ocrChunk.ID = chunkIDs.ID(ocrChunk)
 					insertChunks = append(insertChunks, ocrChunk)
 					logger.GetLogger(ctx).Infof("Created OCR chunk for image %d in chunk #%d", i, chunkData.Seq)
 				}
@@ -1232,13 +1467,12 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 				// 如果有图片描述，创建Caption Chunk
 				if img.Caption != "" {
 					captionChunk := &types.Chunk{
-						ID:              uuid.New().String(),
 						TenantID:        knowledge.TenantID,
 						KnowledgeID:     knowledge.ID,
 						KnowledgeBaseID: knowledge.KnowledgeBaseID,
 						Content:         img.Caption,
 						ChunkIndex:      maxSeq + i*100 + 2, // 使用不冲突的索引方式
-						IsEnabled:       true,
+						IsEnabled:       knowledge.IsEnabled,
 						CreatedAt:       time.Now(),
 						UpdatedAt:       time.Now(),
 						StartAt:         int(img.Start),
@@ -1247,6 +1481,7 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 						ParentChunkID:   textChunk.ID,
 						ImageInfo:       string(imageInfoJSON),
 					}
+					captionChunk.ID = chunkIDs.ID(captionChunk)
 					insertChunks = append(insertChunks, captionChunk)
 					logger.GetLogger(ctx).Infof("Created caption chunk for image %d in chunk #%d", i, chunkData.Seq)
 				}
@@ -1261,6 +1496,38 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 		}
 	}
 
+	// Group text chunks into parent chunks, which are stored for generation but not indexed
+	if stages.Chunk && kb.ParentChildConfig.IsEnabled() {
+		var childChunks []*types.Chunk
+		for _, chunk := range insertChunks {
+			if chunk.ChunkType == types.ChunkTypeText {
+				childChunks = append(childChunks, chunk)
+			}
+		}
+		parentChunks := buildParentChunks(childChunks, kb.ParentChildConfig.ParentChunkSize, chunkIDs)
+		insertChunks = append(insertChunks, parentChunks...)
+		logger.Infof(ctx, "Grouped %d chunks into %d parent chunks", len(childChunks), len(parentChunks))
+	}
+
+	// 增量更新时按内容哈希匹配上次处理的Chunk，未变化的Chunk沿用其ID、索引与生成的问题
+	var reuse *types.ChunkReuse
+	var reused map[string]bool
+	if incremental {
+		reuse = types.ReuseChunks(previousChunks, insertChunks)
+		reused = reuse.Reused
+		knowledge.ChunkReuseStats = &reuse.Stats
+		logger.Infof(ctx, "Incremental update of knowledge %s: %d chunks reused, %d recomputed, %d removed",
+			knowledge.ID, reuse.Stats.Reused, reuse.Stats.Recomputed, reuse.Stats.Removed)
+	}
+
+	// 确定性 ID 与其他知识的现有 Chunk 冲突时重新生成，需在建立前后关系和索引之前完成
+	if err := s.resolveChunkIDCollisions(ctx, knowledge.TenantID, insertChunks, chunkIDs, reused); err != nil {
+		knowledge.MarkProcessFailed(err, isTransientIngestionError(err))
+		s.repo.UpdateKnowledge(ctx, knowledge)
+		span.RecordError(err)
+		return err
+	}
+
 	// Sort chunks by index for proper ordering
 	sort.Slice(insertChunks, func(i, j int) bool {
 		return insertChunks[i].ChunkIndex < insertChunks[j].ChunkIndex
@@ -1284,44 +1551,49 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 		}
 	}
 
-	// Create index information for each chunk (without generated questions for now)
-	indexInfoList := make([]*types.IndexInfo, 0, len(insertChunks))
+	// Create index information for each chunk (without generated questions for now); parent chunks are
+	// only stored, their children are indexed, and reused chunks keep their index entries
+	indexChunks := make([]*types.Chunk, 0, len(insertChunks))
 	for _, chunk := range insertChunks {
-		// Add original chunk content to index
-		indexInfoList = append(indexInfoList, &types.IndexInfo{
-			Content:         chunk.Content,
-			SourceID:        chunk.ID,
-			SourceType:      types.ChunkSourceType,
-			ChunkID:         chunk.ID,
-			KnowledgeID:     knowledge.ID,
-			KnowledgeBaseID: knowledge.KnowledgeBaseID,
-		})
+		if !reused[chunk.ID] {
+			indexChunks = append(indexChunks, chunk)
+		}
 	}
+	indexInfoList := chunkIndexInfo(indexChunks)
 
-	// Initialize retrieval engine
+	// Initialize retrieval engine; without the embed stage the chunks are indexed for keyword retrieval only
+	indexEngine, err := indexingEngine(s.retrieveEngine, tenantInfo, stages)
+	if err != nil {
+		knowledge.MarkProcessFailed(err, false)
+		s.repo.UpdateKnowledge(ctx, knowledge)
+		span.RecordError(err)
+		return err
+	}
 
 	// Calculate storage size required for embeddings
 	span.AddEvent("estimate storage size")
-	totalStorageSize := retrieveEngine.EstimateStorageSize(ctx, embeddingModel, indexInfoList)
+	totalStorageSize := indexEngine.EstimateStorageSize(ctx, embeddingModel, indexInfoList)
+	if reuse != nil {
+		// Reused chunks keep the storage of the previous processing, less that of the removed chunks
+		staleStorageSize := indexEngine.EstimateStorageSize(ctx, embeddingModel, chunkIndexInfo(reuse.Stale))
+		totalStorageSize += max(previousStorageSize-staleStorageSize, 0)
+	}
 	if tenantInfo.StorageQuota > 0 {
 		// Re-fetch tenant storage information
 		tenantInfo, err = s.tenantRepo.GetTenantByID(ctx, tenantInfo.ID)
 		if err != nil {
-			knowledge.ParseStatus = types.ParseStatusFailed
-			knowledge.ErrorMessage = err.Error()
-			knowledge.UpdatedAt = time.Now()
+			knowledge.MarkProcessFailed(err, isTransientIngestionError(err))
 			s.repo.UpdateKnowledge(ctx, knowledge)
 			span.RecordError(err)
-			return
+			return err
 		}
 		// Check if there's enough storage quota available
-		if tenantInfo.StorageUsed+totalStorageSize > tenantInfo.StorageQuota {
-			knowledge.ParseStatus = types.ParseStatusFailed
-			knowledge.ErrorMessage = "存储空间不足"
-			knowledge.UpdatedAt = time.Now()
+		if tenantInfo.StorageUsed+totalStorageSize-previousStorageSize > tenantInfo.StorageQuota {
+			err := errors.New("存储空间不足")
+			knowledge.MarkProcessFailed(err, false)
 			s.repo.UpdateKnowledge(ctx, knowledge)
 			span.RecordError(errors.New("storage quota exceeded"))
-			return
+			return err
 		}
 	}
 
@@ -1329,18 +1601,29 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 	if s.isKnowledgeDeleting(ctx, knowledge.TenantID, knowledge.ID) {
 		logger.Infof(ctx, "Knowledge is being deleted, aborting before saving chunks: %s", knowledge.ID)
 		span.AddEvent("aborted: knowledge is being deleted before saving")
-		return
+		return nil
+	}
+
+	// An incremental update replaces the chunks of the previous processing, the reused ones keeping their IDs
+	if reuse != nil {
+		if err := s.removePreviousChunks(ctx, kb, knowledge, retrieveEngine,
+			embeddingModel.GetDimensions(), previousChunks, reuse.Stale); err != nil {
+			knowledge.MarkProcessFailed(err, isTransientIngestionError(err))
+			s.repo.UpdateKnowledge(ctx, knowledge)
+			span.RecordError(err)
+			return err
+		}
 	}
 
-	// Save chunks to database
+	// Save chunks to database; a file stored without the extract stage has none
 	span.AddEvent("create chunks")
-	if err := s.chunkService.CreateChunks(ctx, insertChunks); err != nil {
-		knowledge.ParseStatus = types.ParseStatusFailed
-		knowledge.ErrorMessage = err.Error()
-		knowledge.UpdatedAt = time.Now()
-		s.repo.UpdateKnowledge(ctx, knowledge)
-		span.RecordError(err)
-		return
+	if stages.Extract {
+		if err := s.chunkService.CreateChunks(ctx, insertChunks); err != nil {
+			knowledge.MarkProcessFailed(err, isTransientIngestionError(err))
+			s.repo.UpdateKnowledge(ctx, knowledge)
+			span.RecordError(err)
+			return err
+		}
 	}
 
 	// Check again before batch indexing (this is a heavy operation)
@@ -1351,15 +1634,19 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 			logger.Warnf(ctx, "Failed to cleanup chunks after deletion detected: %v", err)
 		}
 		span.AddEvent("aborted: knowledge is being deleted before indexing")
-		return
+		return nil
 	}
 
 	span.AddEvent("batch index")
-	err = retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfoList)
+	if len(indexInfoList) > 0 {
+		err = indexEngine.BatchIndex(ctx, embeddingModel, indexInfoList)
+	}
+	if err == nil && stages.Embed {
+		// Embed into the additional vector spaces of the knowledge base as well
+		err = indexVectorSpaces(ctx, s.modelService, retrieveEngine, kb, indexInfoList)
+	}
 	if err != nil {
-		knowledge.ParseStatus = types.ParseStatusFailed
-		knowledge.ErrorMessage = err.Error()
-		knowledge.UpdatedAt = time.Now()
+		knowledge.MarkProcessFailed(err, isTransientIngestionError(err))
 		s.repo.UpdateKnowledge(ctx, knowledge)
 
 		// delete failed chunks
@@ -1373,11 +1660,23 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 		); err != nil {
 			logger.Errorf(ctx, "Delete index failed: %v", err)
 		}
+		deleteVectorSpaceIndices(ctx, s.modelService, retrieveEngine, kb, []string{knowledge.ID}, kb.Type)
 		span.RecordError(err)
-		return
+		return err
 	}
 	logger.GetLogger(ctx).Infof("processChunks batch index successfully, with %d index", len(indexInfoList))
 
+	// Knowledge disabled for retrieval keeps its new index entries disabled as well
+	if !knowledge.IsEnabled {
+		disabled := make(map[string]bool, len(indexInfoList))
+		for _, indexInfo := range indexInfoList {
+			disabled[indexInfo.ChunkID] = false
+		}
+		if err := retrieveEngine.BatchUpdateChunkEnabledStatus(ctx, disabled); err != nil {
+			logger.Errorf(ctx, "Failed to disable index of disabled knowledge %s: %v", knowledge.ID, err)
+		}
+	}
+
 	logger.Infof(ctx, "processChunks create relationship rag task")
 	if kb.ExtractConfig != nil && kb.ExtractConfig.Enabled {
 		for _, chunk := range textChunks {
@@ -1400,7 +1699,7 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 			logger.Warnf(ctx, "Failed to cleanup index after deletion detected: %v", err)
 		}
 		span.AddEvent("aborted: knowledge was deleted during processing")
-		return
+		return nil
 	}
 
 	// Update knowledge status to completed
@@ -1410,9 +1709,11 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 	now := time.Now()
 	knowledge.ProcessedAt = &now
 	knowledge.UpdatedAt = now
+	knowledge.SkippedStages = stages.Skipped()
 
 	// Set summary status based on whether summary generation will be triggered
-	if len(textChunks) > 0 {
+	generateSummary := len(textChunks) > 0 && stages.Summarize
+	if generateSummary {
 		knowledge.SummaryStatus = types.SummaryStatusPending
 	} else {
 		knowledge.SummaryStatus = types.SummaryStatusNone
@@ -1421,9 +1722,10 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
 		logger.GetLogger(ctx).WithField("error", err).Errorf("processChunks update knowledge failed")
 	}
+	invalidateAnswerCache(ctx, s.answerCache, knowledge.KnowledgeBaseID)
 
 	// Enqueue question generation task if enabled (async, non-blocking)
-	if options.EnableQuestionGeneration && len(textChunks) > 0 {
+	if stages.GenerateQuestions && len(textChunks) > 0 {
 		questionCount := options.QuestionCount
 		if questionCount <= 0 {
 			questionCount = 3
@@ -1435,16 +1737,17 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 	}
 
 	// Enqueue summary generation task (async, non-blocking)
-	if len(textChunks) > 0 {
+	if generateSummary {
 		s.enqueueSummaryGenerationTask(ctx, knowledge.KnowledgeBaseID, knowledge.ID)
 	}
 
 	// Update tenant's storage usage
-	tenantInfo.StorageUsed += totalStorageSize
-	if err := s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, totalStorageSize); err != nil {
+	tenantInfo.StorageUsed += totalStorageSize - previousStorageSize
+	if err := s.tenantRepo.AdjustStorageUsed(ctx, tenantInfo.ID, totalStorageSize-previousStorageSize); err != nil {
 		logger.GetLogger(ctx).WithField("error", err).Errorf("processChunks update tenant storage used failed")
 	}
 	logger.GetLogger(ctx).Infof("processChunks successfully")
+	return nil
 }
 
 // GetSummary generates a summary for knowledge content using an AI model
@@ -1631,6 +1934,17 @@ func (s *knowledgeService) ProcessSummaryGeneration(ctx context.Context, t *asyn
 		return nil
 	}
 
+	// Summaries may have been disabled since the task was enqueued
+	if !kb.DocumentSummaryConfig.GenerationEnabled() {
+		logger.Infof(ctx, "Document summaries are disabled for knowledge base %s, skipping", kb.ID)
+		knowledge.SummaryStatus = types.SummaryStatusNone
+		knowledge.UpdatedAt = time.Now()
+		if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+			logger.Warnf(ctx, "Failed to update summary status: %v", err)
+		}
+		return nil
+	}
+
 	// Update summary status to processing
 	knowledge.SummaryStatus = types.SummaryStatusProcessing
 	knowledge.UpdatedAt = time.Now()
@@ -1709,22 +2023,59 @@ func (s *knowledgeService) ProcessSummaryGeneration(ctx context.Context, t *asyn
 
 	// Create summary chunk and index it
 	if strings.TrimSpace(summary) != "" {
-		// Get max chunk index
+		tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to get tenant info: %v", err)
+			return fmt.Errorf("failed to get tenant info: %w", err)
+		}
+		ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
+
+		retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+		if err != nil {
+			logger.Errorf(ctx, "Failed to init retrieve engine: %v", err)
+			return fmt.Errorf("failed to init retrieve engine: %w", err)
+		}
+
+		embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to get embedding model: %v", err)
+			return fmt.Errorf("failed to get embedding model: %w", err)
+		}
+
+		// Get max chunk index, and replace the summary of a previous run (e.g. a retried task)
 		maxChunkIndex := 0
+		var staleSummaryIDs []string
 		for _, chunk := range chunks {
+			if chunk.ChunkType == types.ChunkTypeSummary {
+				staleSummaryIDs = append(staleSummaryIDs, chunk.ID)
+				continue
+			}
 			if chunk.ChunkIndex > maxChunkIndex {
 				maxChunkIndex = chunk.ChunkIndex
 			}
 		}
+		if len(staleSummaryIDs) > 0 {
+			if err := retrieveEngine.DeleteByChunkIDList(
+				ctx, staleSummaryIDs, embeddingModel.GetDimensions(), knowledge.Type,
+			); err != nil {
+				logger.Warnf(ctx, "Failed to delete index of previous summary chunks: %v", err)
+			}
+			deleteVectorSpaceChunkIndices(ctx, s.modelService, retrieveEngine, kb, staleSummaryIDs, knowledge.Type)
+			if err := s.chunkService.DeleteChunks(ctx, staleSummaryIDs); err != nil {
+				logger.Errorf(ctx, "Failed to delete previous summary chunks: %v", err)
+				return fmt.Errorf("failed to delete previous summary chunks: %w", err)
+			}
+			logger.Infof(ctx, "Replacing %d previous summary chunks of knowledge: %s",
+				len(staleSummaryIDs), payload.KnowledgeID)
+		}
 
 		summaryChunk := &types.Chunk{
-			ID:              uuid.New().String(),
 			TenantID:        knowledge.TenantID,
 			KnowledgeID:     knowledge.ID,
 			KnowledgeBaseID: knowledge.KnowledgeBaseID,
 			Content:         fmt.Sprintf("# 文档名称\n%s\n\n# 摘要\n%s", knowledge.FileName, summary),
 			ChunkIndex:      maxChunkIndex + 1,
-			IsEnabled:       true,
+			IsEnabled:       knowledge.IsEnabled,
 			CreatedAt:       time.Now(),
 			UpdatedAt:       time.Now(),
 			StartAt:         0,
@@ -1732,6 +2083,11 @@ func (s *knowledgeService) ProcessSummaryGeneration(ctx context.Context, t *asyn
 			ChunkType:       types.ChunkTypeSummary,
 			ParentChunkID:   textChunks[0].ID,
 		}
+		summaryIDs := types.NewChunkIDGenerator(knowledge.ID)
+		summaryChunk.ID = summaryIDs.ID(summaryChunk)
+		if err := s.resolveChunkIDCollisions(ctx, knowledge.TenantID, []*types.Chunk{summaryChunk}, summaryIDs, nil); err != nil {
+			return fmt.Errorf("failed to check summary chunk ID: %w", err)
+		}
 
 		// Save summary chunk
 		if err := s.chunkService.CreateChunks(ctx, []*types.Chunk{summaryChunk}); err != nil {
@@ -1740,25 +2096,6 @@ func (s *knowledgeService) ProcessSummaryGeneration(ctx context.Context, t *asyn
 		}
 
 		// Index summary chunk
-		tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to get tenant info: %v", err)
-			return fmt.Errorf("failed to get tenant info: %w", err)
-		}
-		ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
-
-		retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
-		if err != nil {
-			logger.Errorf(ctx, "Failed to init retrieve engine: %v", err)
-			return fmt.Errorf("failed to init retrieve engine: %w", err)
-		}
-
-		embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to get embedding model: %v", err)
-			return fmt.Errorf("failed to get embedding model: %w", err)
-		}
-
 		indexInfo := []*types.IndexInfo{{
 			Content:         summaryChunk.Content,
 			SourceID:        summaryChunk.ID,
@@ -1768,10 +2105,28 @@ func (s *knowledgeService) ProcessSummaryGeneration(ctx context.Context, t *asyn
 			KnowledgeBaseID: knowledge.KnowledgeBaseID,
 		}}
 
-		if err := retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfo); err != nil {
+		// Without the embed stage of the pipeline the summary is indexed for keyword retrieval only
+		stages := pipelineStages(kb, false, false)
+		indexEngine, err := indexingEngine(s.retrieveEngine, tenantInfo, stages)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to get summary index engine: %v", err)
+			return fmt.Errorf("failed to index summary chunk: %w", err)
+		}
+		if err := indexEngine.BatchIndex(ctx, embeddingModel, indexInfo); err != nil {
 			logger.Errorf(ctx, "Failed to index summary chunk: %v", err)
 			return fmt.Errorf("failed to index summary chunk: %w", err)
 		}
+		if stages.Embed {
+			if err := indexVectorSpaces(ctx, s.modelService, retrieveEngine, kb, indexInfo); err != nil {
+				logger.Errorf(ctx, "Failed to index summary chunk into vector spaces: %v", err)
+				return fmt.Errorf("failed to index summary chunk: %w", err)
+			}
+		}
+		if !knowledge.IsEnabled {
+			if err := retrieveEngine.BatchUpdateChunkEnabledStatus(ctx, map[string]bool{summaryChunk.ID: false}); err != nil {
+				logger.Errorf(ctx, "Failed to disable summary chunk index: %v", err)
+			}
+		}
 
 		logger.Infof(ctx, "Successfully created and indexed summary chunk for knowledge: %s", payload.KnowledgeID)
 	}
@@ -1873,6 +2228,11 @@ func (s *knowledgeService) ProcessQuestionGeneration(ctx context.Context, t *asy
 	// Generate questions for each chunk with context
 	var indexInfoList []*types.IndexInfo
 	for i, chunk := range textChunks {
+		// Chunks reused by an incremental update keep their questions
+		if meta, err := chunk.DocumentMetadata(); err == nil && meta != nil && len(meta.GeneratedQuestions) > 0 {
+			continue
+		}
+
 		// Build context from adjacent chunks
 		var prevContent, nextContent string
 		if i > 0 {
@@ -1909,9 +2269,12 @@ func (s *knowledgeService) ProcessQuestionGeneration(ctx context.Context, t *asy
 				Question: question,
 			}
 		}
-		meta := &types.DocumentChunkMetadata{
-			GeneratedQuestions: generatedQuestions,
+		// Keep the rest of the metadata, such as the section path
+		meta, err := chunk.DocumentMetadata()
+		if err != nil || meta == nil {
+			meta = &types.DocumentChunkMetadata{}
 		}
+		meta.GeneratedQuestions = generatedQuestions
 		if err := chunk.SetDocumentMetadata(meta); err != nil {
 			logger.Warnf(ctx, "Failed to set document metadata for chunk %s: %v", chunk.ID, err)
 			continue
@@ -2075,6 +2438,12 @@ func (s *knowledgeService) UpdateKnowledge(ctx context.Context, knowledge *types
 	if knowledge.Title != "" {
 		record.Title = knowledge.Title
 	}
+	// 手工知识和 FAQ 的 metadata 存储内部数据，不支持更新
+	if knowledge.Metadata != nil && !record.IsManual() && record.Type != types.KnowledgeTypeFAQ {
+		if err := s.updateKnowledgeMetadata(ctx, record, knowledge.Metadata); err != nil {
+			return err
+		}
+	}
 
 	// Update knowledge record in the repository
 	if err := s.repo.UpdateKnowledge(ctx, record); err != nil {
@@ -2085,6 +2454,29 @@ func (s *knowledgeService) UpdateKnowledge(ctx context.Context, knowledge *types
 	return nil
 }
 
+// updateKnowledgeMetadata replaces the metadata of knowledge after validating it against the knowledge base schema
+func (s *knowledgeService) updateKnowledgeMetadata(ctx context.Context, record *types.Knowledge, raw types.JSON) error {
+	var metadata map[string]string
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return werrors.NewBadRequestError("元数据必须是字符串键值对").WithDetails(err.Error())
+	}
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, record.KnowledgeBaseID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+		return err
+	}
+	metadata, err = kb.MetadataSchemaConfig.Apply(metadata)
+	if err != nil {
+		return werrors.NewBadRequestError(fmt.Sprintf("元数据不符合知识库的元数据规范: %v", err))
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	record.Metadata = types.JSON(metadataJSON)
+	return nil
+}
+
 // UpdateManualKnowledge updates manual Markdown knowledge content.
 func (s *knowledgeService) UpdateManualKnowledge(ctx context.Context,
 	knowledgeID string, payload *types.ManualKnowledgePayload,
@@ -2156,11 +2548,17 @@ func (s *knowledgeService) UpdateManualKnowledge(ctx context.Context,
 	existing.EnableStatus = "disabled"
 	existing.UpdatedAt = time.Now()
 
-	if err := s.cleanupKnowledgeResources(ctx, existing); err != nil {
-		logger.ErrorWithFields(ctx, err, map[string]interface{}{
-			"knowledge_id": knowledgeID,
-		})
-		return nil, err
+	// Publishing an edit of processed content with the same embedding model only recomputes the changed
+	// chunks; the previous chunks and their storage usage are kept for processing to reuse
+	incremental := status == types.ManualKnowledgeStatusPublish &&
+		existing.ParseStatus == types.ParseStatusCompleted && existing.EmbeddingModelID == kb.EmbeddingModelID
+	if !incremental {
+		if err := s.cleanupKnowledgeResources(ctx, existing); err != nil {
+			logger.ErrorWithFields(ctx, err, map[string]interface{}{
+				"knowledge_id": knowledgeID,
+			})
+			return nil, err
+		}
 	}
 
 	existing.EmbeddingModelID = kb.EmbeddingModelID
@@ -2187,18 +2585,36 @@ func (s *knowledgeService) UpdateManualKnowledge(ctx context.Context,
 	}
 
 	logger.Infof(ctx, "Manual knowledge updated, scheduling indexing, ID: %s", existing.ID)
-	s.triggerManualProcessing(ctx, kb, existing, cleanContent, false)
+	s.triggerManualProcessing(ctx, kb, existing, cleanContent, false, incremental)
 	return existing, nil
 }
 
-// isValidFileType checks if a file type is supported
-func isValidFileType(filename string) bool {
-	switch strings.ToLower(getFileType(filename)) {
-	case "pdf", "txt", "docx", "doc", "md", "markdown", "png", "jpg", "jpeg", "gif", "csv", "xlsx", "xls":
-		return true
-	default:
-		return false
+// GetSupportedFileFormats lists the uploadable file formats, with VLM-dependent formats available
+// only when a VLM model is configured for the knowledge base (or, without one, for the tenant)
+func (s *knowledgeService) GetSupportedFileFormats(ctx context.Context, kbID string) ([]types.FileFormat, error) {
+	if kbID != "" {
+		kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+		if err != nil {
+			return nil, err
+		}
+		if kb.TenantID != ctx.Value(types.TenantIDContextKey).(uint64) {
+			return nil, werrors.NewNotFoundError("Knowledge base not found").WithCode(werrors.CodeKnowledgeBaseNotFound)
+		}
+		return types.SupportedFileFormats(kb.VLMConfig.IsEnabled()), nil
+	}
+
+	models, err := s.modelService.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vlmAvailable := false
+	for _, model := range models {
+		if model.Type == types.ModelTypeVLLM {
+			vlmAvailable = true
+			break
+		}
 	}
+	return types.SupportedFileFormats(vlmAvailable), nil
 }
 
 // getFileType extracts the file extension from a filename
@@ -2229,27 +2645,6 @@ func (s *knowledgeService) GetKnowledgeBatch(ctx context.Context,
 	return s.repo.GetKnowledgeBatch(ctx, tenantID, ids)
 }
 
-// calculateFileHash calculates MD5 hash of a file
-func calculateFileHash(file *multipart.FileHeader) (string, error) {
-	f, err := file.Open()
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
-
-	h := md5.New()
-	if _, err := io.Copy(h, f); err != nil {
-		return "", err
-	}
-
-	// Reset file pointer for subsequent operations
-	if _, err := f.Seek(0, 0); err != nil {
-		return "", err
-	}
-
-	return hex.EncodeToString(h.Sum(nil)), nil
-}
-
 func calculateStr(strList ...string) string {
 	h := md5.New()
 	input := strings.Join(strList, "")
@@ -2368,7 +2763,14 @@ func (s *knowledgeService) updateChunkVector(ctx context.Context, kbID string, c
 	if err != nil {
 		return err
 	}
-	return nil
+
+	// Re-embed the chunks in the additional vector spaces as well
+	for _, space := range loadVectorSpaceEmbedders(ctx, s.modelService, sourceKB) {
+		if err := retrieveEngine.DeleteByChunkIDList(ctx, ids, space.embedder.GetDimensions(), sourceKB.Type); err != nil {
+			return err
+		}
+	}
+	return indexVectorSpaces(ctx, s.modelService, retrieveEngine, sourceKB, indexInfo)
 }
 
 func (s *knowledgeService) UpdateImageInfo(
@@ -2550,14 +2952,27 @@ func (s *knowledgeService) UpdateImageInfo(
 // It also ensures that the chunk's relationships (like pre and next chunk IDs) are maintained
 // by mapping the source chunk IDs to the new target chunk IDs.
 func (s *knowledgeService) CloneChunk(ctx context.Context, src, dst *types.Knowledge) error {
+	return s.cloneChunks(ctx, src, dst)
+}
+
+// cloneChunks clones the chunks of src into dst, see CloneChunk
+func (s *knowledgeService) cloneChunks(ctx context.Context, src, dst *types.Knowledge, opts ...cloneOptions) error {
+	var options cloneOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
 	chunkPage := 1
 	chunkPageSize := 100
 	srcTodst := map[string]string{}
 	tagIDMapping := map[string]string{} // srcTagID -> dstTagID
+	if options.tagIDMapping != nil {
+		tagIDMapping = options.tagIDMapping
+	}
 	targetChunks := make([]*types.Chunk, 0, 10)
 	chunkType := []types.ChunkType{
 		types.ChunkTypeText, types.ChunkTypeSummary,
 		types.ChunkTypeImageCaption, types.ChunkTypeImageOCR,
+		types.ChunkTypeParent,
 	}
 	for {
 		sourceChunks, _, err := s.chunkRepo.ListPagedChunksByKnowledgeID(ctx,
@@ -2586,7 +3001,7 @@ func (s *knowledgeService) CloneChunk(ctx context.Context, src, dst *types.Knowl
 			// Map TagID to target knowledge base
 			targetTagID := ""
 			if sourceChunk.TagID != "" {
-				if mappedTagID, ok := tagIDMapping[sourceChunk.TagID]; ok {
+				if mappedTagID, ok := tagIDMapping[sourceChunk.TagID]; ok || options.tagIDMapping != nil {
 					targetTagID = mappedTagID
 				} else {
 					// Try to find or create the tag in target knowledge base
@@ -2654,6 +3069,9 @@ func (s *knowledgeService) CloneChunk(ctx context.Context, src, dst *types.Knowl
 	if err != nil {
 		return err
 	}
+	if options.reembed {
+		return s.reembedClonedChunks(ctx, retrieveEngine, embeddingModel, dst, targetChunks)
+	}
 	if err := retrieveEngine.CopyIndices(ctx, src.KnowledgeBaseID, dst.KnowledgeBaseID,
 		map[string]string{src.ID: dst.ID},
 		srcTodst,
@@ -2662,6 +3080,22 @@ func (s *knowledgeService) CloneChunk(ctx context.Context, src, dst *types.Knowl
 	); err != nil {
 		return err
 	}
+
+	// Copy the additional vector spaces shared by both knowledge bases
+	dstKB, err := s.kbService.GetKnowledgeBaseByID(ctx, dst.KnowledgeBaseID)
+	if err != nil {
+		return err
+	}
+	for _, space := range loadVectorSpaceEmbedders(ctx, s.modelService, dstKB) {
+		if err := retrieveEngine.CopyIndices(ctx, src.KnowledgeBaseID, dst.KnowledgeBaseID,
+			map[string]string{src.ID: dst.ID},
+			srcTodst,
+			space.embedder.GetDimensions(),
+			dst.Type,
+		); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -2691,7 +3125,7 @@ func (s *knowledgeService) ListFAQEntries(ctx context.Context,
 	if tagSeqID > 0 {
 		tag, err := s.tagRepo.GetBySeqID(ctx, tenantID, tagSeqID)
 		if err != nil {
-			return nil, werrors.NewNotFoundError("标签不存在")
+			return nil, werrors.NewNotFoundError("标签不存在").WithCode(werrors.CodeTagNotFound)
 		}
 		tagID = tag.ID
 	}
@@ -2757,6 +3191,9 @@ func (s *knowledgeService) UpsertFAQEntries(ctx context.Context,
 	if payload.Mode != types.FAQBatchModeAppend && payload.Mode != types.FAQBatchModeReplace {
 		return "", werrors.NewBadRequestError("模式仅支持 append 或 replace")
 	}
+	if err := payload.FAQDuplicateOptions.Validate(); err != nil {
+		return "", werrors.NewBadRequestError(err.Error())
+	}
 
 	// 验证知识库是否存在且有效
 	kb, err := s.validateFAQKnowledgeBase(ctx, kbID)
@@ -2784,6 +3221,13 @@ func (s *knowledgeService) UpsertFAQEntries(ctx context.Context,
 		return "", werrors.NewBadRequestError(fmt.Sprintf("该知识库已有导入任务正在进行中（任务ID: %s），请等待完成后再试", runningTaskID))
 	}
 
+	// 注册任务完成时通知的 webhook
+	if payload.WebhookURL != "" {
+		if err := s.taskWebhooks.RegisterTaskWebhook(ctx, taskID, payload.WebhookURL); err != nil {
+			return "", err
+		}
+	}
+
 	// 确保 FAQ knowledge 存在
 	faqKnowledge, err := s.ensureFAQKnowledge(ctx, tenantID, kb)
 	if err != nil {
@@ -2840,6 +3284,8 @@ func (s *knowledgeService) UpsertFAQEntries(ctx context.Context,
 		Mode:        payload.Mode,
 		DryRun:      payload.DryRun,
 		EnqueuedAt:  enqueuedAt,
+		// 重复处理仅在 Append 模式下生效
+		FAQDuplicateOptions: payload.FAQDuplicateOptions,
 	}
 
 	// 阈值：超过 200 条或序列化后超过 50KB 时使用对象存储
@@ -3083,7 +3529,8 @@ func (s *knowledgeService) executeFAQDryRunValidation(ctx context.Context,
 
 	// 根据模式选择不同的验证逻辑
 	if payload.Mode == types.FAQBatchModeAppend {
-		validEntryIndices = s.validateEntriesForAppendModeWithProgress(ctx, payload.TenantID, payload.KBID, entries, progress)
+		validEntryIndices = s.validateEntriesForAppendModeWithProgress(ctx, payload.TenantID, payload.KBID, entries,
+			payload.FAQDuplicateOptions, progress)
 	} else {
 		validEntryIndices = s.validateEntriesForReplaceModeWithProgress(ctx, entries, progress)
 	}
@@ -3093,8 +3540,10 @@ func (s *knowledgeService) executeFAQDryRunValidation(ctx context.Context,
 
 // validateEntriesForAppendModeWithProgress 验证 Append 模式下的条目（带进度更新）
 // 注意：验证阶段不更新 Processed，只有实际导入时才更新
+// 与已有条目重复的条目按 options 中的重复策略处理，判定结果记录在 progress.DuplicateDecisions 中
 func (s *knowledgeService) validateEntriesForAppendModeWithProgress(ctx context.Context,
-	tenantID uint64, kbID string, entries []types.FAQEntryPayload, progress *types.FAQImportProgress,
+	tenantID uint64, kbID string, entries []types.FAQEntryPayload, options types.FAQDuplicateOptions,
+	progress *types.FAQImportProgress,
 ) []int {
 	validIndices := make([]int, 0, len(entries))
 
@@ -3105,22 +3554,10 @@ func (s *knowledgeService) validateEntriesForAppendModeWithProgress(ctx context.
 		// 无法获取已有数据时，仅做批次内验证
 	}
 
-	// 构建已存在的标准问和相似问集合
-	existingQuestions := make(map[string]bool)
-	for _, chunk := range existingChunks {
-		meta, err := chunk.FAQMetadata()
-		if err != nil || meta == nil {
-			continue
-		}
-		if meta.StandardQuestion != "" {
-			existingQuestions[meta.StandardQuestion] = true
-		}
-		for _, q := range meta.SimilarQuestions {
-			if q != "" {
-				existingQuestions[q] = true
-			}
-		}
-	}
+	// 构建已存在的标准问和相似问到 chunk ID 的映射
+	existingQuestions := buildFAQQuestionIndex(existingChunks)
+	// 仅 error 策略直接拒绝与已有条目精确重复的条目，其余策略在下方按策略处理
+	rejectExisting := options.Policy() == types.FAQDuplicatePolicyError
 
 	// 构建当前批次的标准问和相似问集合（用于批次内去重）
 	batchQuestions := make(map[string]int) // value 为首次出现的索引
@@ -3136,7 +3573,7 @@ func (s *knowledgeService) validateEntriesForAppendModeWithProgress(ctx context.
 		standardQ := strings.TrimSpace(entry.StandardQuestion)
 
 		// 检查标准问是否与已有知识库重复
-		if existingQuestions[standardQ] {
+		if _, exists := existingQuestions[standardQ]; exists && rejectExisting {
 			progress.FailedCount++
 			progress.FailedEntries = append(progress.FailedEntries, buildFAQFailedEntry(i, "标准问与知识库中已有问题重复", &entry))
 			continue
@@ -3156,7 +3593,7 @@ func (s *knowledgeService) validateEntriesForAppendModeWithProgress(ctx context.
 			if q == "" {
 				continue
 			}
-			if existingQuestions[q] {
+			if _, exists := existingQuestions[q]; exists && rejectExisting {
 				progress.FailedCount++
 				progress.FailedEntries = append(progress.FailedEntries, buildFAQFailedEntry(i, fmt.Sprintf("相似问 \"%s\" 与知识库中已有问题重复", q), &entry))
 				hasDuplicate = true
@@ -3173,6 +3610,11 @@ func (s *knowledgeService) validateEntriesForAppendModeWithProgress(ctx context.
 			continue
 		}
 
+		// 按重复策略处理与已有条目重复（含相似）的条目
+		if !s.resolveFAQBatchDuplicate(ctx, tenantID, kbID, i, &entry, existingQuestions, options, progress) {
+			continue
+		}
+
 		// 将当前条目的标准问和相似问加入批次集合
 		batchQuestions[standardQ] = i
 		for _, q := range entry.SimilarQuestions {
@@ -3294,35 +3736,37 @@ func validateFAQEntryPayloadBasic(entry *types.FAQEntryPayload) error {
 }
 
 // calculateAppendOperations 计算Append模式下需要处理的条目，跳过已存在且内容相同的条目
-// 同时过滤掉标准问或相似问与同批次或已有知识库中重复的条目
+// 同时过滤掉标准问或相似问与同批次或已有知识库中重复的条目（allowExisting 为 true 时不过滤与已有条目重复的条目）
 func (s *knowledgeService) calculateAppendOperations(ctx context.Context,
-	tenantID uint64, kbID string, entries []types.FAQEntryPayload,
+	tenantID uint64, kbID string, entries []types.FAQEntryPayload, allowExisting bool,
 ) ([]types.FAQEntryPayload, int, error) {
 	if len(entries) == 0 {
 		return []types.FAQEntryPayload{}, 0, nil
 	}
 
 	// 1. 查询知识库中已有的所有FAQ chunks的metadata
-	existingChunks, err := s.chunkRepo.ListAllFAQChunksWithMetadataByKnowledgeBaseID(ctx, tenantID, kbID)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to list existing FAQ chunks: %w", err)
-	}
-
-	// 2. 构建已存在的标准问和相似问集合
 	existingQuestions := make(map[string]bool)
-	for _, chunk := range existingChunks {
-		meta, err := chunk.FAQMetadata()
-		if err != nil || meta == nil {
-			continue
-		}
-		// 添加标准问
-		if meta.StandardQuestion != "" {
-			existingQuestions[meta.StandardQuestion] = true
+	if !allowExisting {
+		existingChunks, err := s.chunkRepo.ListAllFAQChunksWithMetadataByKnowledgeBaseID(ctx, tenantID, kbID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to list existing FAQ chunks: %w", err)
 		}
-		// 添加相似问
-		for _, q := range meta.SimilarQuestions {
-			if q != "" {
-				existingQuestions[q] = true
+
+		// 2. 构建已存在的标准问和相似问集合
+		for _, chunk := range existingChunks {
+			meta, err := chunk.FAQMetadata()
+			if err != nil || meta == nil {
+				continue
+			}
+			// 添加标准问
+			if meta.StandardQuestion != "" {
+				existingQuestions[meta.StandardQuestion] = true
+			}
+			// 添加相似问
+			for _, q := range meta.SimilarQuestions {
+				if q != "" {
+					existingQuestions[q] = true
+				}
 			}
 		}
 	}
@@ -3585,7 +4029,8 @@ func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string,
 		}
 	} else {
 		// Append模式：查询已存在的条目，跳过未变化的
-		entriesToProcess, skippedCount, err = s.calculateAppendOperations(ctx, tenantID, kb.ID, payload.Entries)
+		allowExisting := payload.Policy() == types.FAQDuplicatePolicyAllow
+		entriesToProcess, skippedCount, err = s.calculateAppendOperations(ctx, tenantID, kb.ID, payload.Entries, allowExisting)
 		if err != nil {
 			return fmt.Errorf("failed to calculate append operations: %w", err)
 		}
@@ -3792,16 +4237,20 @@ func (s *knowledgeService) executeFAQImport(ctx context.Context, taskID string,
 }
 
 // CreateFAQEntry creates a single FAQ entry synchronously.
+// Duplicates of existing entries are handled according to options; the decision is returned with the entry.
 func (s *knowledgeService) CreateFAQEntry(ctx context.Context,
-	kbID string, payload *types.FAQEntryPayload,
-) (*types.FAQEntry, error) {
+	kbID string, payload *types.FAQEntryPayload, options types.FAQDuplicateOptions,
+) (*types.FAQEntry, *types.FAQDuplicateDecision, error) {
 	if payload == nil {
-		return nil, werrors.NewBadRequestError("请求体不能为空")
+		return nil, nil, werrors.NewBadRequestError("请求体不能为空")
+	}
+	if err := options.Validate(); err != nil {
+		return nil, nil, werrors.NewBadRequestError(err.Error())
 	}
 
 	kb, err := s.validateFAQKnowledgeBase(ctx, kbID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	kb.EnsureDefaults()
 
@@ -3810,24 +4259,28 @@ func (s *knowledgeService) CreateFAQEntry(ctx context.Context,
 	// 验证并清理输入
 	meta, err := sanitizeFAQEntryPayload(payload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// 解析 TagID
 	tagID, err := s.resolveTagID(ctx, kbID, payload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// 检查标准问和相似问是否与其他条目重复
-	if err := s.checkFAQQuestionDuplicate(ctx, tenantID, kb.ID, "", meta); err != nil {
-		return nil, err
+	// 检查标准问和相似问是否与其他条目重复，按重复策略处理
+	mergedEntry, decision, err := s.resolveFAQCreateDuplicate(ctx, tenantID, kb.ID, meta, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mergedEntry != nil {
+		return mergedEntry, decision, nil
 	}
 
 	// 确保FAQ Knowledge存在
 	faqKnowledge, err := s.ensureFAQKnowledge(ctx, tenantID, kb)
 	if err != nil {
-		return nil, fmt.Errorf("failed to ensure FAQ knowledge: %w", err)
+		return nil, nil, fmt.Errorf("failed to ensure FAQ knowledge: %w", err)
 	}
 
 	// 获取索引模式
@@ -3839,7 +4292,7 @@ func (s *knowledgeService) CreateFAQEntry(ctx context.Context,
 	// 获取embedding模型
 	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get embedding model: %w", err)
+		return nil, nil, fmt.Errorf("failed to get embedding model: %w", err)
 	}
 
 	// 创建chunk
@@ -3871,25 +4324,25 @@ func (s *knowledgeService) CreateFAQEntry(ctx context.Context,
 	}
 
 	if err := chunk.SetFAQMetadata(meta); err != nil {
-		return nil, fmt.Errorf("failed to set FAQ metadata: %w", err)
+		return nil, nil, fmt.Errorf("failed to set FAQ metadata: %w", err)
 	}
 
 	// 保存chunk
 	if err := s.chunkService.CreateChunks(ctx, []*types.Chunk{chunk}); err != nil {
-		return nil, fmt.Errorf("failed to create chunk: %w", err)
+		return nil, nil, fmt.Errorf("failed to create chunk: %w", err)
 	}
 
 	// 索引chunk
 	if err := s.indexFAQChunks(ctx, kb, faqKnowledge, []*types.Chunk{chunk}, embeddingModel, true, false); err != nil {
 		// 如果索引失败，删除已创建的chunk
 		_ = s.chunkService.DeleteChunk(ctx, chunk.ID)
-		return nil, fmt.Errorf("failed to index chunk: %w", err)
+		return nil, nil, fmt.Errorf("failed to index chunk: %w", err)
 	}
 
 	// 更新chunk状态为已索引
 	chunk.Status = int(types.ChunkStatusIndexed)
 	if err := s.chunkService.UpdateChunk(ctx, chunk); err != nil {
-		return nil, fmt.Errorf("failed to update chunk status: %w", err)
+		return nil, nil, fmt.Errorf("failed to update chunk status: %w", err)
 	}
 
 	// Build tag seq_id map for conversion
@@ -3904,7 +4357,7 @@ func (s *knowledgeService) CreateFAQEntry(ctx context.Context,
 	// 转换为FAQEntry返回
 	entry, err := s.chunkToFAQEntry(chunk, kb, tagSeqIDMap)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// 查询TagName
@@ -3915,7 +4368,7 @@ func (s *knowledgeService) CreateFAQEntry(ctx context.Context,
 		}
 	}
 
-	return entry, nil
+	return entry, decision, nil
 }
 
 // GetFAQEntry retrieves a single FAQ entry by seq_id.
@@ -3937,17 +4390,17 @@ func (s *knowledgeService) GetFAQEntry(ctx context.Context,
 	// 获取chunk by seq_id
 	chunk, err := s.chunkRepo.GetChunkBySeqID(ctx, tenantID, entrySeqID)
 	if err != nil {
-		return nil, werrors.NewNotFoundError("FAQ条目不存在")
+		return nil, werrors.NewNotFoundError("FAQ条目不存在").WithCode(werrors.CodeFAQEntryNotFound)
 	}
 
 	// 验证chunk属于当前知识库
 	if chunk.KnowledgeBaseID != kb.ID || chunk.TenantID != tenantID {
-		return nil, werrors.NewNotFoundError("FAQ条目不存在")
+		return nil, werrors.NewNotFoundError("FAQ条目不存在").WithCode(werrors.CodeFAQEntryNotFound)
 	}
 
 	// 验证是FAQ类型
 	if chunk.ChunkType != types.ChunkTypeFAQ {
-		return nil, werrors.NewNotFoundError("FAQ条目不存在")
+		return nil, werrors.NewNotFoundError("FAQ条目不存在").WithCode(werrors.CodeFAQEntryNotFound)
 	}
 
 	// Build tag seq_id map for conversion
@@ -3992,7 +4445,7 @@ func (s *knowledgeService) UpdateFAQEntry(ctx context.Context,
 
 	chunk, err := s.chunkRepo.GetChunkBySeqID(ctx, tenantID, entrySeqID)
 	if err != nil {
-		return nil, werrors.NewNotFoundError("FAQ条目不存在")
+		return nil, werrors.NewNotFoundError("FAQ条目不存在").WithCode(werrors.CodeFAQEntryNotFound)
 	}
 	if chunk.KnowledgeBaseID != kb.ID {
 		return nil, werrors.NewForbiddenError("无权操作该 FAQ 条目")
@@ -4041,7 +4494,7 @@ func (s *knowledgeService) UpdateFAQEntry(ctx context.Context,
 	if payload.TagID > 0 {
 		tag, tagErr := s.tagRepo.GetBySeqID(ctx, tenantID, payload.TagID)
 		if tagErr != nil {
-			return nil, werrors.NewNotFoundError("标签不存在")
+			return nil, werrors.NewNotFoundError("标签不存在").WithCode(werrors.CodeTagNotFound)
 		}
 		chunk.TagID = tag.ID
 	} else {
@@ -4136,6 +4589,8 @@ func (s *knowledgeService) UpdateFAQEntry(ctx context.Context,
 		}
 	}
 
+	invalidateAnswerCache(ctx, s.answerCache, kb.ID)
+
 	return entry, nil
 }
 
@@ -4158,7 +4613,7 @@ func (s *knowledgeService) AddSimilarQuestions(ctx context.Context,
 	// Get existing FAQ entry
 	chunk, err := s.chunkRepo.GetChunkBySeqID(ctx, tenantID, entrySeqID)
 	if err != nil {
-		return nil, werrors.NewNotFoundError("FAQ条目不存在")
+		return nil, werrors.NewNotFoundError("FAQ条目不存在").WithCode(werrors.CodeFAQEntryNotFound)
 	}
 	if chunk.KnowledgeBaseID != kb.ID {
 		return nil, werrors.NewForbiddenError("无权操作该 FAQ 条目")
@@ -4286,6 +4741,8 @@ func (s *knowledgeService) AddSimilarQuestions(ctx context.Context,
 		}
 	}
 
+	invalidateAnswerCache(ctx, s.answerCache, kb.ID)
+
 	return entry, nil
 }
 
@@ -4530,6 +4987,8 @@ func (s *knowledgeService) UpdateFAQEntryFieldsBatch(ctx context.Context,
 		}
 	}
 
+	invalidateAnswerCache(ctx, s.answerCache, kb.ID)
+
 	return nil
 }
 
@@ -4629,6 +5088,70 @@ func (s *knowledgeService) UpdateKnowledgeTagBatch(ctx context.Context, updates
 	return nil
 }
 
+// UpdateKnowledgeEnabledBatch enables or disables document knowledge items for retrieval in batch.
+// The flag is applied to the knowledge rows and to all of their chunks in the database and the
+// retriever engines; disabled knowledge stays listed and downloadable.
+func (s *knowledgeService) UpdateKnowledgeEnabledBatch(ctx context.Context, updates map[string]bool) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	knowledgeIDs := make([]string, 0, len(updates))
+	for knowledgeID := range updates {
+		knowledgeIDs = append(knowledgeIDs, knowledgeID)
+	}
+	knowledgeList, err := s.repo.GetKnowledgeBatch(ctx, tenantID, knowledgeIDs)
+	if err != nil {
+		return err
+	}
+
+	// Group knowledge by target state; FAQ entries are toggled individually through the FAQ API
+	grouped := map[bool][]string{}
+	for _, knowledge := range knowledgeList {
+		if knowledge.Type == types.KnowledgeTypeFAQ {
+			return werrors.NewBadRequestError(fmt.Sprintf("知识 %s 为FAQ类型，请使用FAQ条目接口启用或停用", knowledge.ID))
+		}
+		isEnabled := updates[knowledge.ID]
+		grouped[isEnabled] = append(grouped[isEnabled], knowledge.ID)
+	}
+
+	enabledUpdates := make(map[string]bool)
+	for isEnabled, ids := range grouped {
+		if err := s.repo.UpdateKnowledgeEnabled(ctx, tenantID, ids, isEnabled); err != nil {
+			return err
+		}
+		affectedIDs, err := s.chunkRepo.UpdateChunkEnabledByKnowledgeIDs(ctx, tenantID, ids, isEnabled)
+		if err != nil {
+			return err
+		}
+		for _, id := range affectedIDs {
+			enabledUpdates[id] = isEnabled
+		}
+	}
+
+	// Sync to retriever engines
+	if len(enabledUpdates) > 0 {
+		tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+		retrieveEngine, err := retriever.NewCompositeRetrieveEngine(
+			s.retrieveEngine,
+			tenantInfo.GetEffectiveEngines(),
+		)
+		if err != nil {
+			return err
+		}
+		if err := retrieveEngine.BatchUpdateChunkEnabledStatus(ctx, enabledUpdates); err != nil {
+			return err
+		}
+	}
+
+	s.invalidateKnowledgeAnswerCache(ctx, knowledgeList)
+
+	logger.Infof(ctx, "Updated retrieval enable flag for %d knowledge items, %d chunks synced",
+		len(knowledgeList), len(enabledUpdates))
+	return nil
+}
+
 // UpdateFAQEntryTag updates the tag assigned to an FAQ entry.
 func (s *knowledgeService) UpdateFAQEntryTag(ctx context.Context, kbID string, entryID string, tagID *string) error {
 	kb, err := s.validateFAQKnowledgeBase(ctx, kbID)
@@ -5140,7 +5663,7 @@ func (s *knowledgeService) DeleteFAQEntries(ctx context.Context,
 		}
 		chunk, err := s.chunkRepo.GetChunkBySeqID(ctx, tenantID, seqID)
 		if err != nil {
-			return werrors.NewNotFoundError("FAQ条目不存在")
+			return werrors.NewNotFoundError("FAQ条目不存在").WithCode(werrors.CodeFAQEntryNotFound)
 		}
 		if chunk.KnowledgeBaseID != kb.ID || chunk.ChunkType != types.ChunkTypeFAQ {
 			return werrors.NewBadRequestError("包含无效的 FAQ 条目")
@@ -5161,47 +5684,128 @@ func (s *knowledgeService) DeleteFAQEntries(ctx context.Context,
 			return err
 		}
 	}
+	invalidateAnswerCache(ctx, s.answerCache, kb.ID)
 	return nil
 }
 
-// ExportFAQEntries exports all FAQ entries for a knowledge base as CSV data.
+// faqExportBatchSize is the number of FAQ chunks loaded at a time during an export
+const faqExportBatchSize = 1000
+
+// faqExportCSVHeaders are the columns of a CSV FAQ export, matching the import example format
+var faqExportCSVHeaders = []string{
+	"分类(必填)",
+	"问题(必填)",
+	"相似问题(选填-多个用##分隔)",
+	"反例问题(选填-多个用##分隔)",
+	"机器人回答(必填-多个用##分隔)",
+	"是否全部回复(选填-默认FALSE)",
+	"是否停用(选填-默认FALSE)",
+	"是否禁止被推荐(选填-默认False 可被推荐)",
+}
+
+// ExportFAQEntries streams the FAQ entries of a knowledge base to w, optionally filtered by tag and
+// enabled status. Entries are loaded in batches, so large sets are exported without holding them in memory.
 // The CSV format matches the import example format with 8 columns:
 // 分类(必填), 问题(必填), 相似问题(选填-多个用##分隔), 反例问题(选填-多个用##分隔),
 // 机器人回答(必填-多个用##分隔), 是否全部回复(选填-默认FALSE), 是否停用(选填-默认FALSE),
 // 是否禁止被推荐(选填-默认False 可被推荐)
-func (s *knowledgeService) ExportFAQEntries(ctx context.Context, kbID string) ([]byte, error) {
+// The JSON Lines format writes one FAQ entry per line, with the question lists as arrays.
+// Nothing is written when the request is invalid.
+func (s *knowledgeService) ExportFAQEntries(ctx context.Context,
+	kbID string, req *types.FAQExportRequest, w io.Writer,
+) error {
+	if req == nil {
+		req = &types.FAQExportRequest{}
+	}
+	if err := req.Validate(); err != nil {
+		return werrors.NewBadRequestError(err.Error())
+	}
 	kb, err := s.validateFAQKnowledgeBase(ctx, kbID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	filter := &types.FAQExportFilter{Enabled: req.Enabled}
+	if req.TagID > 0 {
+		tag, err := s.tagRepo.GetBySeqID(ctx, tenantID, req.TagID)
+		if err != nil {
+			return werrors.NewNotFoundError("标签不存在").WithCode(werrors.CodeTagNotFound)
+		}
+		filter.TagID = tag.ID
+	}
 	faqKnowledge, err := s.findFAQKnowledge(ctx, tenantID, kb.ID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if faqKnowledge == nil {
-		// Return empty CSV with headers only
-		return s.buildFAQCSV(nil, nil), nil
+	tags, err := s.buildTagMap(ctx, tenantID, kb.ID)
+	if err != nil {
+		return fmt.Errorf("failed to build tag map: %w", err)
 	}
 
-	// Get all FAQ chunks
-	chunks, err := s.chunkRepo.ListAllFAQChunksForExport(ctx, tenantID, faqKnowledge.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list FAQ chunks: %w", err)
+	format := req.EffectiveFormat()
+	out := bufio.NewWriterSize(w, 64*1024)
+	if format == types.FAQExportFormatCSV {
+		// BOM for Excel compatibility with UTF-8
+		out.WriteString("\uFEFF")
+		out.WriteString(strings.Join(faqExportCSVHeaders, ","))
+		out.WriteString("\n")
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+
+	exported := 0
+	if faqKnowledge != nil {
+		var afterSeqID int64
+		for {
+			chunks, err := s.chunkRepo.ListFAQChunksForExportAfter(
+				ctx, tenantID, faqKnowledge.ID, filter, afterSeqID, faqExportBatchSize,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to list FAQ chunks: %w", err)
+			}
+			for _, chunk := range chunks {
+				afterSeqID = chunk.SeqID
+				meta, err := chunk.FAQMetadata()
+				if err != nil || meta == nil {
+					continue
+				}
+				tag := tags[chunk.TagID]
+				if format == types.FAQExportFormatJSONL {
+					entry, _ := s.chunkToFAQEntry(chunk, kb, nil)
+					if tag != nil {
+						entry.TagID = tag.SeqID
+						entry.TagName = tag.Name
+					}
+					err = encoder.Encode(entry)
+				} else {
+					_, err = out.WriteString(faqExportCSVRow(chunk, meta, tag))
+				}
+				if err != nil {
+					return fmt.Errorf("failed to write FAQ export: %w", err)
+				}
+				exported++
+			}
+			// Hand each batch to the client before loading the next
+			if err := out.Flush(); err != nil {
+				return fmt.Errorf("failed to write FAQ export: %w", err)
+			}
+			if len(chunks) < faqExportBatchSize {
+				break
+			}
+		}
 	}
-
-	// Build tag map for tag_id -> tag_name conversion
-	tagMap, err := s.buildTagMap(ctx, tenantID, kbID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build tag map: %w", err)
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write FAQ export: %w", err)
 	}
-
-	return s.buildFAQCSV(chunks, tagMap), nil
+	logger.Infof(ctx, "FAQ export completed, kb: %s, format: %s, entries: %d", kb.ID, format, exported)
+	return nil
 }
 
-// buildTagMap builds a map from tag_id to tag_name for the given knowledge base.
-func (s *knowledgeService) buildTagMap(ctx context.Context, tenantID uint64, kbID string) (map[string]string, error) {
+// buildTagMap builds a map from tag_id to the tags of the given knowledge base.
+func (s *knowledgeService) buildTagMap(ctx context.Context,
+	tenantID uint64, kbID string,
+) (map[string]*types.KnowledgeTag, error) {
 	// Get all tags for this knowledge base (no pagination limit)
 	page := &types.Pagination{Page: 1, PageSize: 10000}
 	tags, _, err := s.tagRepo.ListByKB(ctx, tenantID, kbID, page, "")
@@ -5209,64 +5813,32 @@ func (s *knowledgeService) buildTagMap(ctx context.Context, tenantID uint64, kbI
 		return nil, err
 	}
 
-	tagMap := make(map[string]string, len(tags))
+	tagMap := make(map[string]*types.KnowledgeTag, len(tags))
 	for _, tag := range tags {
 		if tag != nil {
-			tagMap[tag.ID] = tag.Name
+			tagMap[tag.ID] = tag
 		}
 	}
 	return tagMap, nil
 }
 
-// buildFAQCSV builds CSV content from FAQ chunks.
-func (s *knowledgeService) buildFAQCSV(chunks []*types.Chunk, tagMap map[string]string) []byte {
-	var buf strings.Builder
-
-	// Write CSV header (matching import example format)
-	headers := []string{
-		"分类(必填)",
-		"问题(必填)",
-		"相似问题(选填-多个用##分隔)",
-		"反例问题(选填-多个用##分隔)",
-		"机器人回答(必填-多个用##分隔)",
-		"是否全部回复(选填-默认FALSE)",
-		"是否停用(选填-默认FALSE)",
-		"是否禁止被推荐(选填-默认False 可被推荐)",
-	}
-	buf.WriteString(strings.Join(headers, ","))
-	buf.WriteString("\n")
-
-	// Write data rows
-	for _, chunk := range chunks {
-		meta, err := chunk.FAQMetadata()
-		if err != nil || meta == nil {
-			continue
-		}
-
-		// Get tag name
-		tagName := ""
-		if chunk.TagID != "" && tagMap != nil {
-			if name, ok := tagMap[chunk.TagID]; ok {
-				tagName = name
-			}
-		}
-
-		// Build row
-		row := []string{
-			escapeCSVField(tagName),
-			escapeCSVField(meta.StandardQuestion),
-			escapeCSVField(strings.Join(meta.SimilarQuestions, "##")),
-			escapeCSVField(strings.Join(meta.NegativeQuestions, "##")),
-			escapeCSVField(strings.Join(meta.Answers, "##")),
-			boolToCSV(meta.AnswerStrategy == types.AnswerStrategyAll),
-			boolToCSV(!chunk.IsEnabled),                                 // 是否停用：取反
-			boolToCSV(!chunk.Flags.HasFlag(types.ChunkFlagRecommended)), // 是否禁止被推荐：取反
-		}
-		buf.WriteString(strings.Join(row, ","))
-		buf.WriteString("\n")
-	}
-
-	return []byte(buf.String())
+// faqExportCSVRow builds the CSV row of an FAQ entry, list fields joined with ##.
+func faqExportCSVRow(chunk *types.Chunk, meta *types.FAQChunkMetadata, tag *types.KnowledgeTag) string {
+	tagName := ""
+	if tag != nil {
+		tagName = tag.Name
+	}
+	row := []string{
+		escapeCSVField(tagName),
+		escapeCSVField(meta.StandardQuestion),
+		escapeCSVField(strings.Join(meta.SimilarQuestions, "##")),
+		escapeCSVField(strings.Join(meta.NegativeQuestions, "##")),
+		escapeCSVField(strings.Join(meta.Answers, "##")),
+		boolToCSV(meta.AnswerStrategy == types.AnswerStrategyAll),
+		boolToCSV(!chunk.IsEnabled),                                 // 是否停用：取反
+		boolToCSV(!chunk.Flags.HasFlag(types.ChunkFlagRecommended)), // 是否禁止被推荐：取反
+	}
+	return strings.Join(row, ",") + "\n"
 }
 
 // escapeCSVField escapes a field for CSV format.
@@ -5531,20 +6103,8 @@ func (s *knowledgeService) checkFAQQuestionDuplicate(
 	excludeChunkID string,
 	meta *types.FAQChunkMetadata,
 ) error {
-	// 首先检查当前条目自身的相似问是否与标准问重复
-	for _, q := range meta.SimilarQuestions {
-		if q == meta.StandardQuestion {
-			return werrors.NewBadRequestError(fmt.Sprintf("相似问「%s」不能与标准问相同", q))
-		}
-	}
-
-	// 检查当前条目自身的相似问之间是否有重复
-	seen := make(map[string]struct{})
-	for _, q := range meta.SimilarQuestions {
-		if _, exists := seen[q]; exists {
-			return werrors.NewBadRequestError(fmt.Sprintf("相似问「%s」重复", q))
-		}
-		seen[q] = struct{}{}
+	if err := checkFAQQuestionSelfDuplicate(meta); err != nil {
+		return err
 	}
 
 	// 查询知识库中已有的所有FAQ chunks的metadata
@@ -5597,6 +6157,26 @@ func (s *knowledgeService) checkFAQQuestionDuplicate(
 	return nil
 }
 
+// checkFAQQuestionSelfDuplicate 检查条目自身的标准问和相似问之间是否重复
+func checkFAQQuestionSelfDuplicate(meta *types.FAQChunkMetadata) error {
+	// 检查相似问是否与标准问重复
+	for _, q := range meta.SimilarQuestions {
+		if q == meta.StandardQuestion {
+			return werrors.NewBadRequestError(fmt.Sprintf("相似问「%s」不能与标准问相同", q))
+		}
+	}
+
+	// 检查相似问之间是否有重复
+	seen := make(map[string]struct{})
+	for _, q := range meta.SimilarQuestions {
+		if _, exists := seen[q]; exists {
+			return werrors.NewBadRequestError(fmt.Sprintf("相似问「%s」重复", q))
+		}
+		seen[q] = struct{}{}
+	}
+	return nil
+}
+
 // resolveTagID resolves tag ID (UUID) from payload, prioritizing tag_id (seq_id) over tag_name
 // If no tag is specified, creates or finds the "未分类" tag
 // Returns the internal UUID of the tag
@@ -6091,7 +6671,7 @@ func ensureManualFileName(title string) string {
 }
 
 func (s *knowledgeService) triggerManualProcessing(ctx context.Context,
-	kb *types.KnowledgeBase, knowledge *types.Knowledge, content string, sync bool,
+	kb *types.KnowledgeBase, knowledge *types.Knowledge, content string, sync bool, incremental bool,
 ) {
 	clean := strings.TrimSpace(content)
 	if clean == "" {
@@ -6103,8 +6683,25 @@ func (s *knowledgeService) triggerManualProcessing(ctx context.Context,
 	fileName := ensureManualFileName(knowledge.Title)
 	fileType := "md"
 
+	// 按知识库的处理流水线确定执行的阶段；手动内容默认不生成问题，除非流水线显式启用
+	stages := pipelineStages(kb, kb.IsMultimodalEnabled(), false)
 	// 检查是否需要启用多模态（对于手动内容通常不需要，但保持一致性）
-	enableMultimodel := kb.IsMultimodalEnabled() && kb.StorageConfig.Provider != ""
+	enableMultimodel := stages.ExtractImages && kb.StorageConfig.Provider != ""
+	options := ProcessChunksOptions{Stages: &stages, Incremental: incremental}
+	if kb.QuestionGenerationConfig != nil {
+		options.QuestionCount = kb.QuestionGenerationConfig.QuestionCount
+	}
+
+	// Without the extract stage the content is stored as is, without calling the document parser
+	if !stages.Extract {
+		if sync {
+			s.processChunks(ctx, kb, knowledge, nil, options)
+			return
+		}
+		go s.processChunks(logger.CloneContext(ctx), kb, knowledge, nil, options)
+		return
+	}
+	chunkSize, chunkOverlap := parserChunking(kb, stages)
 
 	var vlmConfig *proto.VLMConfig
 	if enableMultimodel {
@@ -6131,8 +6728,8 @@ func (s *knowledgeService) triggerManualProcessing(ctx context.Context,
 		FileName:    fileName,
 		FileType:    fileType,
 		ReadConfig: &proto.ReadConfig{
-			ChunkSize:        int32(kb.ChunkingConfig.ChunkSize),
-			ChunkOverlap:     int32(kb.ChunkingConfig.ChunkOverlap),
+			ChunkSize:        chunkSize,
+			ChunkOverlap:     chunkOverlap,
 			Separators:       kb.ChunkingConfig.Separators,
 			EnableMultimodal: enableMultimodel,
 			StorageConfig: &proto.StorageConfig{
@@ -6161,12 +6758,12 @@ func (s *knowledgeService) triggerManualProcessing(ctx context.Context,
 	}
 
 	if sync {
-		s.processChunks(ctx, kb, knowledge, resp.Chunks)
+		s.processChunks(ctx, kb, knowledge, resp.Chunks, options)
 		return
 	}
 
 	newCtx := logger.CloneContext(ctx)
-	go s.processChunks(newCtx, kb, knowledge, resp.Chunks)
+	go s.processChunks(newCtx, kb, knowledge, resp.Chunks, options)
 }
 
 func (s *knowledgeService) cleanupKnowledgeResources(ctx context.Context, knowledge *types.Knowledge) error {
@@ -6198,6 +6795,7 @@ func (s *knowledgeService) cleanupKnowledgeResources(ctx context.Context, knowle
 					logger.GetLogger(ctx).WithField("error", err).Error("Failed to delete manual knowledge index")
 					cleanupErr = errors.Join(cleanupErr, err)
 				}
+				s.deleteKnowledgeVectorSpaces(ctx, retrieveEngine, []*types.Knowledge{knowledge})
 			}
 		}
 	}
@@ -6286,10 +6884,8 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 	ctx = logger.WithField(ctx, "document_process", payload.KnowledgeID)
 	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
 
-	// 获取任务重试信息，用于判断是否是最后一次重试
-	retryCount, _ := asynq.GetRetryCount(ctx)
-	maxRetry, _ := asynq.GetMaxRetry(ctx)
-	isLastRetry := retryCount >= maxRetry
+	// 获取当前尝试次数，用于判断是否是最后一次重试
+	attempt, isLastRetry := processAttempt(ctx)
 
 	tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
 	if err != nil {
@@ -6298,8 +6894,8 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 	}
 	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
 
-	logger.Infof(ctx, "Processing document task: knowledge_id=%s, file_path=%s, retry=%d/%d",
-		payload.KnowledgeID, payload.FilePath, retryCount, maxRetry)
+	logger.Infof(ctx, "Processing document task: knowledge_id=%s, file_path=%s, attempt=%d, last=%v",
+		payload.KnowledgeID, payload.FilePath, attempt, isLastRetry)
 
 	// 幂等性检查：获取knowledge记录
 	knowledge, err := s.repo.GetKnowledgeByID(ctx, payload.TenantID, payload.KnowledgeID)
@@ -6347,23 +6943,37 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KnowledgeBaseID)
 	if err != nil {
 		logger.Errorf(ctx, "failed to get knowledge base: %v", err)
-		knowledge.ParseStatus = "failed"
-		knowledge.ErrorMessage = fmt.Sprintf("failed to get knowledge base: %v", err)
-		knowledge.UpdatedAt = time.Now()
+		knowledge.MarkProcessFailed(fmt.Errorf("failed to get knowledge base: %w", err), false)
 		s.repo.UpdateKnowledge(ctx, knowledge)
 		return nil
 	}
 
-	knowledge.ParseStatus = "processing"
-	knowledge.UpdatedAt = time.Now()
+	knowledge.StartProcessAttempt(attempt)
 	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
 		logger.Errorf(ctx, "failed to update knowledge status to processing: %v", err)
 		return nil
 	}
 
+	// 按知识库的处理流水线确定执行的阶段
+	stages := pipelineStages(kb, payload.EnableMultimodel, payload.EnableQuestionGeneration)
+	options := ProcessChunksOptions{
+		EnableQuestionGeneration: stages.GenerateQuestions,
+		QuestionCount:            payload.QuestionCount,
+		Stages:                   &stages,
+	}
+
+	// Without the extract stage the file is stored as is, without calling the document parser
+	if !stages.Extract {
+		if err := s.processChunks(ctx, kb, knowledge, nil, options); err != nil {
+			return s.handleProcessError(ctx, knowledge, err, isLastRetry)
+		}
+		return nil
+	}
+	chunkSize, chunkOverlap := parserChunking(kb, stages)
+
 	// 构建VLM配置（如果需要）
 	var vlmConfig *proto.VLMConfig
-	if payload.EnableMultimodel {
+	if stages.ExtractImages {
 		vlmConfig, err = s.getVLMProtoConfig(ctx, kb)
 		if err != nil {
 			logger.GetLogger(ctx).WithField("knowledge_id", knowledge.ID).
@@ -6376,12 +6986,10 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 	}
 
 	// 检查多模态配置（仅对文件导入）
-	if payload.FilePath != "" && !payload.EnableMultimodel && IsImageType(payload.FileType) {
+	if payload.FilePath != "" && !stages.ExtractImages && IsImageType(payload.FileType) {
 		logger.GetLogger(ctx).WithField("knowledge_id", knowledge.ID).
 			WithField("error", ErrImageNotParse).Errorf("processDocument image without enable multimodel")
-		knowledge.ParseStatus = "failed"
-		knowledge.ErrorMessage = ErrImageNotParse.Error()
-		knowledge.UpdatedAt = time.Now()
+		knowledge.MarkProcessFailed(ErrImageNotParse, false)
 		s.repo.UpdateKnowledge(ctx, knowledge)
 		return nil
 	}
@@ -6392,9 +7000,7 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 		// URL导入 - 再次进行 SSRF 验证（防止 DNS 重绑定攻击）
 		if safe, reason := secutils.IsSSRFSafeURL(payload.URL); !safe {
 			logger.Errorf(ctx, "URL rejected for SSRF protection in ProcessDocument: %s, reason: %s", payload.URL, reason)
-			knowledge.ParseStatus = "failed"
-			knowledge.ErrorMessage = "URL is not allowed for security reasons"
-			knowledge.UpdatedAt = time.Now()
+			knowledge.MarkProcessFailed(errors.New("URL is not allowed for security reasons"), false)
 			s.repo.UpdateKnowledge(ctx, knowledge)
 			return nil
 		}
@@ -6403,10 +7009,10 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 			Url:   payload.URL,
 			Title: knowledge.Title,
 			ReadConfig: &proto.ReadConfig{
-				ChunkSize:        int32(kb.ChunkingConfig.ChunkSize),
-				ChunkOverlap:     int32(kb.ChunkingConfig.ChunkOverlap),
+				ChunkSize:        chunkSize,
+				ChunkOverlap:     chunkOverlap,
 				Separators:       kb.ChunkingConfig.Separators,
-				EnableMultimodal: payload.EnableMultimodel,
+				EnableMultimodal: stages.ExtractImages,
 				StorageConfig: &proto.StorageConfig{
 					Provider: proto.StorageProvider(
 						proto.StorageProvider_value[strings.ToUpper(kb.StorageConfig.Provider)],
@@ -6423,14 +7029,8 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 			RequestId: payload.RequestId,
 		})
 		if err != nil {
-			// 如果是最后一次重试，更新状态为失败
-			if isLastRetry {
-				knowledge.ParseStatus = "failed"
-				knowledge.ErrorMessage = err.Error()
-				knowledge.UpdatedAt = time.Now()
-				s.repo.UpdateKnowledge(ctx, knowledge)
-			}
-			return fmt.Errorf("failed to read from URL: %w", err)
+			// 临时错误在最后一次重试前交给任务重试，其他错误直接标记为失败
+			return s.handleProcessError(ctx, knowledge, fmt.Errorf("failed to read from URL: %w", err), isLastRetry)
 		}
 		chunks = urlResp.Chunks
 	} else if len(payload.Passages) > 0 {
@@ -6452,7 +7052,9 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 			chunks = append(chunks, chunk)
 		}
 		// 直接处理chunks，不需要调用docReader
-		s.processChunks(ctx, kb, knowledge, chunks)
+		if err := s.processChunks(ctx, kb, knowledge, chunks, options); err != nil {
+			return s.handleProcessError(ctx, knowledge, err, isLastRetry)
+		}
 		return nil
 	} else {
 		// 文件导入
@@ -6460,28 +7062,14 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 		if err != nil {
 			logger.GetLogger(ctx).WithField("knowledge_id", knowledge.ID).
 				WithField("error", err).Errorf("processDocument get file failed")
-			// 如果是最后一次重试，更新状态为失败
-			if isLastRetry {
-				knowledge.ParseStatus = "failed"
-				knowledge.ErrorMessage = err.Error()
-				knowledge.UpdatedAt = time.Now()
-				s.repo.UpdateKnowledge(ctx, knowledge)
-			}
-			return fmt.Errorf("failed to get file: %w", err)
+			return s.handleProcessError(ctx, knowledge, fmt.Errorf("failed to get file: %w", err), isLastRetry)
 		}
 		defer fileReader.Close()
 
 		// 读取文件内容
 		contentBytes, err := io.ReadAll(fileReader)
 		if err != nil {
-			// 如果是最后一次重试，更新状态为失败
-			if isLastRetry {
-				knowledge.ParseStatus = "failed"
-				knowledge.ErrorMessage = err.Error()
-				knowledge.UpdatedAt = time.Now()
-				s.repo.UpdateKnowledge(ctx, knowledge)
-			}
-			return fmt.Errorf("failed to read file: %w", err)
+			return s.handleProcessError(ctx, knowledge, fmt.Errorf("failed to read file: %w", err), isLastRetry)
 		}
 
 		// 调用docReader处理文件
@@ -6490,10 +7078,10 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 			FileName:    payload.FileName,
 			FileType:    payload.FileType,
 			ReadConfig: &proto.ReadConfig{
-				ChunkSize:        int32(kb.ChunkingConfig.ChunkSize),
-				ChunkOverlap:     int32(kb.ChunkingConfig.ChunkOverlap),
+				ChunkSize:        chunkSize,
+				ChunkOverlap:     chunkOverlap,
 				Separators:       kb.ChunkingConfig.Separators,
-				EnableMultimodal: payload.EnableMultimodel,
+				EnableMultimodal: stages.ExtractImages,
 				StorageConfig: &proto.StorageConfig{
 					Provider:        proto.StorageProvider(proto.StorageProvider_value[strings.ToUpper(kb.StorageConfig.Provider)]),
 					Region:          kb.StorageConfig.Region,
@@ -6510,23 +7098,16 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 		if err != nil {
 			logger.GetLogger(ctx).WithField("knowledge_id", knowledge.ID).
 				WithField("error", err).Errorf("processDocument read file failed")
-			// 如果是最后一次重试，更新状态为失败
-			if isLastRetry {
-				knowledge.ParseStatus = "failed"
-				knowledge.ErrorMessage = err.Error()
-				knowledge.UpdatedAt = time.Now()
-				s.repo.UpdateKnowledge(ctx, knowledge)
-			}
-			return fmt.Errorf("failed to read file from docreader: %w", err)
+			return s.handleProcessError(ctx, knowledge,
+				fmt.Errorf("failed to read file from docreader: %w", err), isLastRetry)
 		}
 		chunks = fileResp.Chunks
 	}
 
 	// 处理chunks（这会更新状态为completed）
-	s.processChunks(ctx, kb, knowledge, chunks, ProcessChunksOptions{
-		EnableQuestionGeneration: payload.EnableQuestionGeneration,
-		QuestionCount:            payload.QuestionCount,
-	})
+	if err := s.processChunks(ctx, kb, knowledge, chunks, options); err != nil {
+		return s.handleProcessError(ctx, knowledge, err, isLastRetry)
+	}
 
 	return nil
 }
@@ -6619,6 +7200,7 @@ func (s *knowledgeService) ProcessFAQImport(ctx context.Context, t *asynq.Task)
 		validEntryIndices = existingProgress.ValidEntryIndices
 		progress.FailedCount = existingProgress.FailedCount
 		progress.FailedEntries = existingProgress.FailedEntries
+		progress.DuplicateDecisions = existingProgress.DuplicateDecisions
 		logger.Infof(ctx, "Reusing previous validation result: valid=%d, failed=%d",
 			len(validEntryIndices), progress.FailedCount)
 	} else {
@@ -6638,6 +7220,14 @@ func (s *knowledgeService) ProcessFAQImport(ctx context.Context, t *asynq.Task)
 		return s.finalizeFAQValidation(ctx, &payload, progress, originalTotalEntries)
 	}
 
+	// 将按 merge_into_existing 策略判定的重复条目合并到已有条目（重试时重复合并不会产生重复的相似问）
+	if payload.Policy() == types.FAQDuplicatePolicyMerge && len(progress.DuplicateDecisions) > 0 {
+		s.mergeFAQDuplicateEntries(ctx, &payload, progress)
+		if err := s.saveFAQImportProgress(ctx, progress); err != nil {
+			logger.Warnf(ctx, "Failed to save FAQ merge result: %v", err)
+		}
+	}
+
 	// Import 模式：检查是否有有效条目需要导入
 	if len(validEntryIndices) == 0 {
 		// 没有有效条目，直接完成
@@ -6751,8 +7341,9 @@ func (s *knowledgeService) ProcessFAQImport(ctx context.Context, t *asynq.Task)
 
 	// 构建FAQBatchUpsertPayload（使用验证通过的有效条目）
 	faqPayload := &types.FAQBatchUpsertPayload{
-		Entries: entriesToImport,
-		Mode:    importMode,
+		Entries:             entriesToImport,
+		Mode:                importMode,
+		FAQDuplicateOptions: payload.FAQDuplicateOptions,
 	}
 
 	// 执行FAQ导入（传入已处理的偏移量，用于进度计算）
@@ -6830,6 +7421,9 @@ func (s *knowledgeService) finalizeFAQValidation(ctx context.Context, payload *t
 		100, originalTotalEntries, originalTotalEntries, progress.Message, ""); err != nil {
 		logger.Warnf(ctx, "Failed to update final FAQ import status: %v", err)
 	}
+	if !payload.DryRun {
+		invalidateAnswerCache(ctx, s.answerCache, payload.KBID)
+	}
 
 	logger.Infof(ctx, "FAQ task completed: %s, dry_run=%v, success: %d, failed: %d",
 		payload.TaskID, payload.DryRun, progress.SuccessCount, progress.FailedCount)
@@ -6871,7 +7465,13 @@ func (s *knowledgeService) saveFAQImportProgress(ctx context.Context, progress *
 	if err != nil {
 		return fmt.Errorf("failed to marshal FAQ import progress: %w", err)
 	}
-	return s.redisClient.Set(ctx, key, data, faqImportProgressTTL).Err()
+	if err := s.redisClient.Set(ctx, key, data, faqImportProgressTTL).Err(); err != nil {
+		return err
+	}
+	if tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64); ok && s.taskWebhooks != nil {
+		s.taskWebhooks.NotifyTaskFinished(ctx, types.NewFAQImportWebhookEvent(tenantID, progress))
+	}
+	return nil
 }
 
 // GetFAQImportProgress retrieves the progress of an FAQ import task
@@ -7377,7 +7977,13 @@ func (s *knowledgeService) saveKBCloneProgress(ctx context.Context, progress *ty
 	if err != nil {
 		return fmt.Errorf("failed to marshal progress: %w", err)
 	}
-	return s.redisClient.Set(ctx, key, data, kbCloneProgressTTL).Err()
+	if err := s.redisClient.Set(ctx, key, data, kbCloneProgressTTL).Err(); err != nil {
+		return err
+	}
+	if tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64); ok && s.taskWebhooks != nil {
+		s.taskWebhooks.NotifyTaskFinished(ctx, types.NewKBCloneWebhookEvent(tenantID, progress))
+	}
+	return nil
 }
 
 // SaveKBCloneProgress saves the KB clone progress to Redis (public method for handler use)
@@ -7496,3 +8102,357 @@ func (s *knowledgeService) ProcessKnowledgeListDelete(ctx context.Context, t *as
 	logger.Infof(ctx, "Successfully deleted %d knowledge items", len(payload.KnowledgeIDs))
 	return nil
 }
+
+const (
+	knowledgeReprocessProgressKeyPrefix = "knowledge_reprocess_progress:"
+	knowledgeReprocessProgressTTL       = 24 * time.Hour
+)
+
+// getKnowledgeReprocessProgressKey returns the Redis key for storing batch reprocess progress
+func getKnowledgeReprocessProgressKey(taskID string) string {
+	return knowledgeReprocessProgressKeyPrefix + taskID
+}
+
+// saveKnowledgeReprocessProgress saves the batch reprocess progress to Redis
+func (s *knowledgeService) saveKnowledgeReprocessProgress(ctx context.Context,
+	progress *types.KnowledgeReprocessProgress,
+) error {
+	progress.UpdatedAt = time.Now().Unix()
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reprocess progress: %w", err)
+	}
+	return s.redisClient.Set(ctx, getKnowledgeReprocessProgressKey(progress.TaskID), data,
+		knowledgeReprocessProgressTTL).Err()
+}
+
+// GetKnowledgeReprocessProgress retrieves the progress of a batch reprocess task.
+// Items still in progress are resolved against the current status of their knowledge.
+func (s *knowledgeService) GetKnowledgeReprocessProgress(ctx context.Context,
+	taskID string,
+) (*types.KnowledgeReprocessProgress, error) {
+	progress, err := s.loadKnowledgeReprocessProgress(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if progress.InProgress > 0 && s.refreshReprocessItems(ctx, progress) {
+		if err := s.saveKnowledgeReprocessProgress(ctx, progress); err != nil {
+			logger.Warnf(ctx, "Failed to save reprocess progress: %v", err)
+		}
+	}
+	return progress, nil
+}
+
+// refreshReprocessItems updates the items whose processing was still running and reports whether any changed
+func (s *knowledgeService) refreshReprocessItems(ctx context.Context, progress *types.KnowledgeReprocessProgress) bool {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	changed := false
+	for _, item := range progress.Items {
+		if item.Status != types.ReprocessItemInProgress {
+			continue
+		}
+		knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, item.KnowledgeID)
+		if err != nil {
+			continue
+		}
+		item.Status, item.Error = reprocessItemOutcome(knowledge)
+		if item.Status == types.ReprocessItemInProgress {
+			continue
+		}
+		progress.InProgress--
+		progress.CountItem(item.Status)
+		changed = true
+	}
+	if changed && progress.InProgress == 0 && progress.Status == types.KnowledgeReprocessStatusCompleted {
+		progress.Message = progress.Summary()
+	}
+	return changed
+}
+
+// loadKnowledgeReprocessProgress reads the stored progress of a batch reprocess task
+func (s *knowledgeService) loadKnowledgeReprocessProgress(ctx context.Context,
+	taskID string,
+) (*types.KnowledgeReprocessProgress, error) {
+	data, err := s.redisClient.Get(ctx, getKnowledgeReprocessProgressKey(taskID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, werrors.NewNotFoundError("Reprocess task not found")
+		}
+		return nil, fmt.Errorf("failed to get reprocess progress from Redis: %w", err)
+	}
+
+	var progress types.KnowledgeReprocessProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reprocess progress: %w", err)
+	}
+	return &progress, nil
+}
+
+// ReprocessFailedKnowledge selects the failed knowledge of a knowledge base and re-queues it
+// as a single tracked task. Knowledge whose source cannot be re-read is reported as skipped.
+func (s *knowledgeService) ReprocessFailedKnowledge(ctx context.Context,
+	kbID string,
+	filter *types.KnowledgeFailureFilter,
+) (*types.KnowledgeReprocessProgress, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	failed, err := s.repo.ListFailedKnowledge(ctx, tenantID, kbID, filter)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_base_id": kbID,
+		})
+		return nil, err
+	}
+
+	progress := &types.KnowledgeReprocessProgress{
+		TaskID:          secutils.GenerateTaskID("knowledge_reprocess", tenantID, kbID),
+		KnowledgeBaseID: kbID,
+		Status:          types.KnowledgeReprocessStatusPending,
+		Total:           len(failed),
+		Items:           make([]*types.ReprocessItemResult, 0, len(failed)),
+		Message:         "Task queued, waiting to start...",
+		CreatedAt:       time.Now().Unix(),
+	}
+	for _, knowledge := range failed {
+		item := &types.ReprocessItemResult{
+			KnowledgeID:   knowledge.ID,
+			Title:         knowledge.Title,
+			Status:        types.ReprocessItemPending,
+			PreviousError: knowledge.ErrorMessage,
+		}
+		if reason := reprocessUnsupportedReason(knowledge); reason != "" {
+			item.Status = types.ReprocessItemSkipped
+			item.Error = reason
+			progress.Skipped++
+			progress.Processed++
+		}
+		progress.Items = append(progress.Items, item)
+	}
+
+	if progress.Processed == progress.Total {
+		progress.Status = types.KnowledgeReprocessStatusCompleted
+		progress.Message = "No failed knowledge to reprocess"
+		if err := s.saveKnowledgeReprocessProgress(ctx, progress); err != nil {
+			logger.Warnf(ctx, "Failed to save reprocess progress: %v", err)
+		}
+		return progress, nil
+	}
+
+	if err := s.saveKnowledgeReprocessProgress(ctx, progress); err != nil {
+		logger.Errorf(ctx, "Failed to save reprocess progress: %v", err)
+		return nil, err
+	}
+
+	requestID, _ := ctx.Value(types.RequestIDContextKey).(string)
+	payloadBytes, err := json.Marshal(types.KnowledgeReprocessPayload{
+		TenantID:        tenantID,
+		TaskID:          progress.TaskID,
+		KnowledgeBaseID: kbID,
+		RequestID:       requestID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reprocess payload: %w", err)
+	}
+	// Items carry their own outcome, so the batch itself is not retried
+	task := asynq.NewTask(types.TypeKnowledgeReprocess, payloadBytes,
+		asynq.TaskID(progress.TaskID), asynq.Queue("default"), asynq.MaxRetry(0), asynq.Timeout(6*time.Hour))
+	info, err := s.task.Enqueue(task)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to enqueue reprocess task: %v", err)
+		return nil, err
+	}
+	logger.Infof(ctx, "Enqueued reprocess task: id=%s queue=%s knowledge_base_id=%s items=%d",
+		info.ID, info.Queue, kbID, progress.Total)
+	return progress, nil
+}
+
+// ProcessKnowledgeReprocess handles Asynq batch reprocess tasks.
+// Items are processed one by one; knowledge that is no longer failed is skipped and
+// knowledge that fails again keeps its failed status with the new error.
+func (s *knowledgeService) ProcessKnowledgeReprocess(ctx context.Context, t *asynq.Task) error {
+	var payload types.KnowledgeReprocessPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "failed to unmarshal reprocess task payload: %v", err)
+		return nil
+	}
+
+	ctx = logger.WithRequestID(ctx, payload.RequestID)
+	ctx = context.WithValue(ctx, types.RequestIDContextKey, payload.RequestID)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get tenant: %v", err)
+		return nil
+	}
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
+
+	progress, err := s.loadKnowledgeReprocessProgress(ctx, payload.TaskID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to load reprocess progress: %v", err)
+		return nil
+	}
+
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KnowledgeBaseID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get knowledge base: %v", err)
+		progress.Status = types.KnowledgeReprocessStatusCompleted
+		progress.Message = fmt.Sprintf("Failed to get knowledge base: %v", err)
+		_ = s.saveKnowledgeReprocessProgress(ctx, progress)
+		return nil
+	}
+
+	progress.Status = types.KnowledgeReprocessStatusProcessing
+	progress.Message = "Reprocessing failed knowledge..."
+	_ = s.saveKnowledgeReprocessProgress(ctx, progress)
+
+	for _, item := range progress.Items {
+		if item.Status != types.ReprocessItemPending {
+			continue
+		}
+		s.reprocessKnowledgeItem(ctx, kb, item)
+		if item.Status == types.ReprocessItemInProgress {
+			progress.InProgress++
+		} else {
+			progress.CountItem(item.Status)
+		}
+		progress.Processed++
+		_ = s.saveKnowledgeReprocessProgress(ctx, progress)
+	}
+
+	progress.Status = types.KnowledgeReprocessStatusCompleted
+	progress.Message = progress.Summary()
+	_ = s.saveKnowledgeReprocessProgress(ctx, progress)
+	logger.Infof(ctx, "Reprocess task %s completed: %s", payload.TaskID, progress.Message)
+	return nil
+}
+
+// reprocessKnowledgeItem clears partial results of a failed knowledge and processes it again
+func (s *knowledgeService) reprocessKnowledgeItem(ctx context.Context,
+	kb *types.KnowledgeBase,
+	item *types.ReprocessItemResult,
+) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, tenantID, item.KnowledgeID)
+	if err != nil || knowledge == nil {
+		item.Status = types.ReprocessItemSkipped
+		item.Error = "knowledge no longer exists"
+		return
+	}
+	// Someone may have fixed or re-uploaded it since the task was created
+	if knowledge.ParseStatus != types.ParseStatusFailed {
+		item.Status = types.ReprocessItemSkipped
+		item.Error = fmt.Sprintf("knowledge is no longer failed (status: %s)", knowledge.ParseStatus)
+		return
+	}
+
+	if err := s.cleanupKnowledgeResources(ctx, knowledge); err != nil {
+		logger.Warnf(ctx, "Failed to clean partial resources of knowledge %s: %v", knowledge.ID, err)
+	}
+	knowledge.ParseStatus = types.ParseStatusPending
+	knowledge.ErrorMessage = ""
+	knowledge.FailureKind = ""
+	knowledge.ProcessAttempts = 0
+	knowledge.EmbeddingModelID = kb.EmbeddingModelID
+	knowledge.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledge(ctx, knowledge); err != nil {
+		item.Status = types.ReprocessItemFailed
+		item.Error = fmt.Sprintf("failed to reset knowledge status: %v", err)
+		return
+	}
+
+	if knowledge.IsManual() {
+		meta, err := knowledge.ManualMetadata()
+		if err != nil || meta == nil {
+			item.Status = types.ReprocessItemFailed
+			item.Error = "manual knowledge content is missing"
+			return
+		}
+		s.triggerManualProcessing(ctx, kb, knowledge, meta.Content, true, false)
+	} else {
+		payloadBytes, err := json.Marshal(s.buildReprocessPayload(ctx, kb, knowledge))
+		if err != nil {
+			item.Status = types.ReprocessItemFailed
+			item.Error = err.Error()
+			return
+		}
+		if err := s.ProcessDocument(ctx, asynq.NewTask(types.TypeDocumentProcess, payloadBytes)); err != nil {
+			logger.Warnf(ctx, "Reprocessing knowledge %s returned error: %v", knowledge.ID, err)
+		}
+	}
+
+	updated, err := s.repo.GetKnowledgeByID(ctx, tenantID, knowledge.ID)
+	if err != nil {
+		updated = nil
+	}
+	item.Status, item.Error = reprocessItemOutcome(updated)
+}
+
+// reprocessItemOutcome returns the item status and error that the current state of a reprocessed knowledge implies
+func reprocessItemOutcome(knowledge *types.Knowledge) (string, string) {
+	if knowledge == nil {
+		return types.ReprocessItemFailed, "knowledge disappeared during reprocessing"
+	}
+	switch knowledge.ParseStatus {
+	case types.ParseStatusCompleted:
+		return types.ReprocessItemSucceeded, ""
+	case types.ParseStatusFailed:
+		return types.ReprocessItemFailed, knowledge.ErrorMessage
+	case types.ParseStatusDeleting:
+		return types.ReprocessItemSkipped, "knowledge is being deleted"
+	default:
+		// Processing continues asynchronously (e.g. chunks still being indexed or a manual task queued)
+		return types.ReprocessItemInProgress, ""
+	}
+}
+
+// buildReprocessPayload rebuilds the document process payload of a file or URL knowledge
+func (s *knowledgeService) buildReprocessPayload(ctx context.Context,
+	kb *types.KnowledgeBase,
+	knowledge *types.Knowledge,
+) types.DocumentProcessPayload {
+	requestID, _ := ctx.Value(types.RequestIDContextKey).(string)
+	payload := types.DocumentProcessPayload{
+		RequestId:        requestID,
+		TenantID:         knowledge.TenantID,
+		KnowledgeID:      knowledge.ID,
+		KnowledgeBaseID:  kb.ID,
+		EnableMultimodel: kb.IsMultimodalEnabled(),
+		QuestionCount:    3,
+	}
+	if kb.QuestionGenerationConfig != nil && kb.QuestionGenerationConfig.Enabled {
+		payload.EnableQuestionGeneration = true
+		if kb.QuestionGenerationConfig.QuestionCount > 0 {
+			payload.QuestionCount = kb.QuestionGenerationConfig.QuestionCount
+		}
+	}
+	if knowledge.Type == "url" {
+		payload.URL = knowledge.Source
+	} else {
+		payload.FilePath = knowledge.FilePath
+		payload.FileName = knowledge.FileName
+		payload.FileType = knowledge.FileType
+	}
+	return payload
+}
+
+// reprocessUnsupportedReason explains why a failed knowledge cannot be reprocessed, or returns ""
+func reprocessUnsupportedReason(knowledge *types.Knowledge) string {
+	switch {
+	case knowledge.IsManual():
+		return ""
+	case knowledge.Type == "url":
+		if knowledge.Source == "" {
+			return "source URL is missing"
+		}
+		return ""
+	case knowledge.Type == "file":
+		if knowledge.FilePath == "" {
+			return "source file is missing"
+		}
+		return ""
+	case knowledge.Type == "passage":
+		return "passage content is not retained; re-submit the passages"
+	default:
+		return fmt.Sprintf("knowledge type %q cannot be reprocessed", knowledge.Type)
+	}
+}
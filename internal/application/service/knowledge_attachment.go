@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/docreader/proto"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// ExtractChatAttachments parses the attachments of a single chat message into in-memory chunks.
+// Files are parsed and chunked by docreader like uploaded knowledge, images are described by the
+// multimodal model. Nothing is written to a knowledge base or the vector store; the returned chunks
+// only live for the current turn. The chunking, storage and multimodal settings of the first
+// resolvable knowledge base in knowledgeBaseIDs are used when available.
+func (s *knowledgeService) ExtractChatAttachments(ctx context.Context,
+	knowledgeBaseIDs []string, attachments []*types.ChatAttachment,
+) ([]*types.SearchResult, error) {
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+	if err := types.ValidateChatAttachments(attachments); err != nil {
+		return nil, err
+	}
+	if s.docReaderClient == nil {
+		return nil, fmt.Errorf("document reader service not configured")
+	}
+
+	var kb *types.KnowledgeBase
+	for _, kbID := range knowledgeBaseIDs {
+		if found, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID); err == nil {
+			kb = found
+			break
+		}
+	}
+
+	budget := types.MaxChatAttachmentContextChars
+	results := make([]*types.SearchResult, 0)
+	for i, attachment := range attachments {
+		if budget <= 0 {
+			logger.Warnf(ctx, "Attachment context budget exhausted, skipping attachment %q", attachment.Name)
+			break
+		}
+		chunks, err := s.readChatAttachment(ctx, kb, attachment)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %q: %w", attachment.Name, err)
+		}
+
+		knowledgeID := fmt.Sprintf("attachment-%d", i+1)
+		for _, chunk := range chunks {
+			content := attachmentChunkContent(chunk)
+			if strings.TrimSpace(content) == "" {
+				continue
+			}
+			if n := utf8.RuneCountInString(content); n > budget {
+				content = string([]rune(content)[:budget])
+			}
+			budget -= utf8.RuneCountInString(content)
+
+			results = append(results, &types.SearchResult{
+				ID:                fmt.Sprintf("%s-%d", knowledgeID, chunk.Seq),
+				Content:           content,
+				KnowledgeID:       knowledgeID,
+				ChunkIndex:        int(chunk.Seq),
+				KnowledgeTitle:    attachment.Name,
+				StartAt:           int(chunk.Start),
+				EndAt:             int(chunk.End),
+				Seq:               int(chunk.Seq),
+				Score:             1.0,
+				MatchType:         types.MatchTypeDirectLoad,
+				SubChunkID:        []string{},
+				Metadata:          map[string]string{"transient": "true"},
+				ChunkType:         string(types.ChunkTypeAttachment),
+				KnowledgeFilename: attachment.Name,
+				KnowledgeSource:   "attachment",
+			})
+			if budget <= 0 {
+				break
+			}
+		}
+	}
+
+	logger.Infof(ctx, "Extracted %d chunks from %d chat attachments", len(results), len(attachments))
+	return results, nil
+}
+
+// readChatAttachment sends one attachment to docreader and returns its chunks
+func (s *knowledgeService) readChatAttachment(ctx context.Context,
+	kb *types.KnowledgeBase, attachment *types.ChatAttachment,
+) ([]*proto.Chunk, error) {
+	content, err := attachment.Decode()
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > types.MaxChatAttachmentSize {
+		return nil, fmt.Errorf("exceeds %d MB", types.MaxChatAttachmentSize>>20)
+	}
+
+	fileName, fileType := attachment.Name, attachment.FileType()
+	if attachment.Type == types.ChatAttachmentTypeText {
+		fileName, fileType = ensureManualFileName(attachment.Name), "md"
+	}
+
+	readConfig := &proto.ReadConfig{}
+	if s.config.KnowledgeBase != nil {
+		readConfig.ChunkSize = int32(s.config.KnowledgeBase.ChunkSize)
+		readConfig.ChunkOverlap = int32(s.config.KnowledgeBase.ChunkOverlap)
+		readConfig.Separators = s.config.KnowledgeBase.SplitMarkers
+	}
+	if kb != nil {
+		if kb.ChunkingConfig.ChunkSize > 0 {
+			readConfig.ChunkSize = int32(kb.ChunkingConfig.ChunkSize)
+			readConfig.ChunkOverlap = int32(kb.ChunkingConfig.ChunkOverlap)
+			readConfig.Separators = kb.ChunkingConfig.Separators
+		}
+		if kb.StorageConfig.Provider != "" {
+			readConfig.StorageConfig = &proto.StorageConfig{
+				Provider: proto.StorageProvider(
+					proto.StorageProvider_value[strings.ToUpper(kb.StorageConfig.Provider)],
+				),
+				Region:          kb.StorageConfig.Region,
+				BucketName:      kb.StorageConfig.BucketName,
+				AccessKeyId:     kb.StorageConfig.SecretID,
+				SecretAccessKey: kb.StorageConfig.SecretKey,
+				AppId:           kb.StorageConfig.AppID,
+				PathPrefix:      kb.StorageConfig.PathPrefix,
+			}
+		}
+	}
+
+	if attachment.Type == types.ChatAttachmentTypeImage {
+		if !IsImageType(fileType) {
+			return nil, fmt.Errorf("unsupported image type %q", fileType)
+		}
+		vlmConfig, err := s.chatAttachmentVLMConfig(ctx, kb)
+		if err != nil {
+			return nil, err
+		}
+		readConfig.EnableMultimodal = true
+		readConfig.VlmConfig = vlmConfig
+	}
+
+	resp, err := s.docReaderClient.ReadFromFile(ctx, &proto.ReadFromFileRequest{
+		FileContent: content,
+		FileName:    fileName,
+		FileType:    fileType,
+		ReadConfig:  readConfig,
+		RequestId:   ctx.Value(types.RequestIDContextKey).(string),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%s", resp.Error)
+	}
+	return resp.Chunks, nil
+}
+
+// chatAttachmentVLMConfig returns the multimodal model used to describe image attachments:
+// the knowledge base's VLM when configured, otherwise the tenant's first VLLM model
+func (s *knowledgeService) chatAttachmentVLMConfig(ctx context.Context, kb *types.KnowledgeBase) (*proto.VLMConfig, error) {
+	if cfg, err := s.getVLMProtoConfig(ctx, kb); err == nil && cfg != nil {
+		return cfg, nil
+	}
+
+	models, err := s.modelService.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, model := range models {
+		if model == nil || model.Type != types.ModelTypeVLLM {
+			continue
+		}
+		interfaceType := model.Parameters.InterfaceType
+		if interfaceType == "" {
+			interfaceType = "openai"
+		}
+		return &proto.VLMConfig{
+			ModelName:     model.Name,
+			BaseUrl:       model.Parameters.BaseURL,
+			ApiKey:        model.Parameters.APIKey,
+			InterfaceType: interfaceType,
+		}, nil
+	}
+	return nil, fmt.Errorf("no multimodal model configured for image attachments")
+}
+
+// attachmentChunkContent merges chunk text with the captions and OCR text of its images
+func attachmentChunkContent(chunk *proto.Chunk) string {
+	var builder strings.Builder
+	builder.WriteString(chunk.Content)
+	for _, image := range chunk.Images {
+		if image.Caption != "" {
+			builder.WriteString("\n图片描述: " + image.Caption)
+		}
+		if image.OcrText != "" {
+			builder.WriteString("\n图片文本: " + image.OcrText)
+		}
+	}
+	return strings.TrimSpace(builder.String())
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// chunkIDLookupBatchSize limits the number of IDs per collision lookup query
+const chunkIDLookupBatchSize = 500
+
+// resolveChunkIDCollisions gives a new ID to chunks whose deterministic ID is already taken by a live
+// chunk (a hash collision, since chunks of the knowledge being processed were removed beforehand) and
+// updates parent references within the batch. Must run before other references to chunk IDs are built.
+// Chunks in reused keep the IDs of the previous processing of an incremental update and are not checked.
+func (s *knowledgeService) resolveChunkIDCollisions(ctx context.Context,
+	tenantID uint64, chunks []*types.Chunk, generator *types.ChunkIDGenerator, reused map[string]bool,
+) error {
+	taken := make(map[string]bool)
+	for start := 0; start < len(chunks); start += chunkIDLookupBatchSize {
+		end := min(start+chunkIDLookupBatchSize, len(chunks))
+		ids := make([]string, 0, end-start)
+		for _, chunk := range chunks[start:end] {
+			if !reused[chunk.ID] {
+				ids = append(ids, chunk.ID)
+			}
+		}
+		existing, err := s.chunkRepo.ListChunksByID(ctx, tenantID, ids)
+		if err != nil {
+			return err
+		}
+		for _, chunk := range existing {
+			taken[chunk.ID] = true
+			generator.Reserve(chunk.ID)
+		}
+	}
+	if len(taken) == 0 {
+		return nil
+	}
+
+	renamed := make(map[string]string, len(taken))
+	for _, chunk := range chunks {
+		if taken[chunk.ID] {
+			newID := generator.ID(chunk)
+			logger.Warnf(ctx, "Chunk ID %s collides with an existing chunk, using %s", chunk.ID, newID)
+			renamed[chunk.ID] = newID
+			chunk.ID = newID
+		}
+	}
+	for _, chunk := range chunks {
+		if newID, ok := renamed[chunk.ParentChunkID]; ok {
+			chunk.ParentChunkID = newID
+		}
+	}
+	return nil
+}
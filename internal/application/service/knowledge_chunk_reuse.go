@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Tencent/WeKnora/internal/application/service/retriever"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// reusableChunkPageSize is the page size when listing the chunks of the previous processing
+const reusableChunkPageSize = 100
+
+// reusableChunks lists the chunks of the previous processing of knowledge for an incremental update. It
+// reports false when all chunks have to be rebuilt: the knowledge graph is extracted from the chunks, or
+// the previous processing embedded the chunks while this one does not, or the other way round
+func (s *knowledgeService) reusableChunks(ctx context.Context,
+	kb *types.KnowledgeBase, knowledge *types.Knowledge, stages types.PipelineStages,
+) ([]*types.Chunk, bool) {
+	if kb.ExtractConfig != nil && kb.ExtractConfig.Enabled {
+		logger.Infof(ctx, "Knowledge graph extraction is enabled, reprocessing all chunks of knowledge %s", knowledge.ID)
+		return nil, false
+	}
+	if slices.Contains(knowledge.SkippedStages, types.PipelineStageEmbed) == stages.Embed {
+		logger.Infof(ctx, "Embed stage changed, reprocessing all chunks of knowledge %s", knowledge.ID)
+		return nil, false
+	}
+
+	chunkTypes := []types.ChunkType{
+		types.ChunkTypeText, types.ChunkTypeSummary,
+		types.ChunkTypeImageCaption, types.ChunkTypeImageOCR,
+		types.ChunkTypeParent,
+	}
+	var chunks []*types.Chunk
+	for page := 1; ; page++ {
+		pageChunks, _, err := s.chunkRepo.ListPagedChunksByKnowledgeID(ctx,
+			knowledge.TenantID,
+			knowledge.ID,
+			&types.Pagination{Page: page, PageSize: reusableChunkPageSize},
+			chunkTypes,
+			"",
+			"",
+			"",
+			"",
+			"",
+		)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to list chunks of knowledge %s, reprocessing all chunks: %v", knowledge.ID, err)
+			return nil, false
+		}
+		if len(pageChunks) == 0 {
+			return chunks, true
+		}
+		chunks = append(chunks, pageChunks...)
+	}
+}
+
+// removePreviousChunks deletes the rows of the chunks of the previous processing, which are created again
+// with the reused IDs, and the index entries of the stale chunks, including their generated questions
+func (s *knowledgeService) removePreviousChunks(ctx context.Context,
+	kb *types.KnowledgeBase, knowledge *types.Knowledge, retrieveEngine *retriever.CompositeRetrieveEngine,
+	dimension int, previous []*types.Chunk, stale []*types.Chunk,
+) error {
+	staleIDs := make([]string, 0, len(stale))
+	for _, chunk := range stale {
+		staleIDs = append(staleIDs, chunk.ID)
+	}
+	if len(staleIDs) > 0 {
+		if err := retrieveEngine.DeleteByChunkIDList(ctx, staleIDs, dimension, knowledge.Type); err != nil {
+			return err
+		}
+		deleteVectorSpaceChunkIndices(ctx, s.modelService, retrieveEngine, kb, staleIDs, knowledge.Type)
+	}
+
+	previousIDs := make([]string, 0, len(previous))
+	for _, chunk := range previous {
+		previousIDs = append(previousIDs, chunk.ID)
+	}
+	return s.chunkService.DeleteChunks(ctx, previousIDs)
+}
+
+// chunkIndexInfo builds the index entries of the content of chunks; parent chunks are not indexed
+func chunkIndexInfo(chunks []*types.Chunk) []*types.IndexInfo {
+	indexInfoList := make([]*types.IndexInfo, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.ChunkType == types.ChunkTypeParent {
+			continue
+		}
+		indexInfoList = append(indexInfoList, &types.IndexInfo{
+			Content:         chunk.Content,
+			SourceID:        chunk.ID,
+			SourceType:      types.ChunkSourceType,
+			ChunkID:         chunk.ID,
+			KnowledgeID:     chunk.KnowledgeID,
+			KnowledgeBaseID: chunk.KnowledgeBaseID,
+		})
+	}
+	return indexInfoList
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/docreader/proto"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// chunkSplitBoundaries are the characters a split chunk is preferably cut after, from the strongest boundary
+var chunkSplitBoundaries = []string{"\n", "。！？.!?", "；;，,", " \t"}
+
+// normalizeChunkSizes merges parsed fragments below the minimum chunk size into a neighbor and splits chunks
+// above the maximum size, so that lines of a few words don't end up as chunks of their own. Returns the chunks
+// renumbered in document order along with their size distribution. Sizes are counted in characters.
+func normalizeChunkSizes(chunks []*proto.Chunk, cfg *types.ChunkingConfig) ([]*proto.Chunk, *types.ChunkSizeStats) {
+	maxSize := cfg.EffectiveMaxChunkSize()
+	// A configuration saved before validation existed may not leave room for merging
+	minSize := min(cfg.EffectiveMinChunkSize(), maxSize/2)
+
+	merged, split := 0, 0
+	pieces := make([]*proto.Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if utf8.RuneCountInString(chunk.Content) > maxSize {
+			pieces = append(pieces, splitChunk(chunk, maxSize)...)
+			split++
+			continue
+		}
+		pieces = append(pieces, &proto.Chunk{
+			Content: chunk.Content,
+			Seq:     chunk.Seq,
+			Start:   chunk.Start,
+			End:     chunk.End,
+			Images:  chunk.Images,
+		})
+	}
+
+	normalized := make([]*proto.Chunk, 0, len(pieces))
+	for _, chunk := range pieces {
+		if n := len(normalized); n > 0 {
+			last := normalized[n-1]
+			lastSize := utf8.RuneCountInString(last.Content)
+			size := utf8.RuneCountInString(chunk.Content)
+			if (lastSize < minSize || size < minSize) && lastSize+size <= maxSize {
+				mergeChunk(last, chunk)
+				merged++
+				continue
+			}
+		}
+		normalized = append(normalized, chunk)
+	}
+
+	sizes := make([]int, len(normalized))
+	for i, chunk := range normalized {
+		chunk.Seq = chunks[0].Seq + int32(i)
+		sizes[i] = utf8.RuneCountInString(chunk.Content)
+	}
+	return normalized, types.NewChunkSizeStats(sizes, merged, split)
+}
+
+// mergeChunk appends next to chunk, dropping the text the two chunks overlap on
+func mergeChunk(chunk, next *proto.Chunk) {
+	nextRunes := []rune(next.Content)
+	overlap := int(chunk.End - next.Start)
+	switch {
+	case overlap > 0 && overlap <= len(nextRunes) && strings.HasSuffix(chunk.Content, string(nextRunes[:overlap])):
+		chunk.Content += string(nextRunes[overlap:])
+	case endsWithSpace(chunk.Content) || startsWithSpace(next.Content):
+		chunk.Content += next.Content
+	default:
+		chunk.Content += "\n" + next.Content
+	}
+	chunk.End = max(chunk.End, next.End)
+	chunk.Images = append(chunk.Images, next.Images...)
+}
+
+// splitChunk cuts a chunk into pieces of similar size no larger than maxSize, preferably after a line or
+// sentence boundary. Images go to the piece their position falls in.
+func splitChunk(chunk *proto.Chunk, maxSize int) []*proto.Chunk {
+	runes := []rune(chunk.Content)
+	count := (len(runes) + maxSize - 1) / maxSize
+	target := (len(runes) + count - 1) / count
+
+	var pieces []*proto.Chunk
+	for pos := 0; pos < len(runes); {
+		end := len(runes)
+		if end-pos > maxSize {
+			end = splitPoint(runes, pos+target/2, pos+target)
+		}
+		pieces = append(pieces, &proto.Chunk{
+			Content: string(runes[pos:end]),
+			Start:   chunk.Start + int32(pos),
+			End:     chunk.Start + int32(end),
+		})
+		pos = end
+	}
+	for _, image := range chunk.Images {
+		piece := pieces[0]
+		for _, p := range pieces {
+			if image.Start >= p.Start {
+				piece = p
+			}
+		}
+		piece.Images = append(piece.Images, image)
+	}
+	return pieces
+}
+
+// splitPoint returns the position in (from, to] to cut runes at, right after the strongest boundary found
+// closest to to, or to itself without any boundary
+func splitPoint(runes []rune, from, to int) int {
+	for _, boundary := range chunkSplitBoundaries {
+		for i := to - 1; i >= from; i-- {
+			if strings.ContainsRune(boundary, runes[i]) {
+				return i + 1
+			}
+		}
+	}
+	return to
+}
+
+func startsWithSpace(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsSpace(r)
+}
+
+func endsWithSpace(s string) bool {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return unicode.IsSpace(r)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/docreader/proto"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestNormalizeChunkSizes(t *testing.T) {
+	chunk := func(start int, content string) *proto.Chunk {
+		return &proto.Chunk{
+			Content: content,
+			Start:   int32(start),
+			End:     int32(start + utf8.RuneCountInString(content)),
+		}
+	}
+	paragraph := strings.Repeat("word ", 20)        // 100 characters
+	long := strings.Repeat("A sentence here. ", 30) // 510 characters
+
+	tests := []struct {
+		name        string
+		cfg         types.ChunkingConfig
+		chunks      []*proto.Chunk
+		want        []string
+		wantMerged  int
+		wantSplit   int
+		wantMaxSize int
+	}{
+		{
+			name:        "sizes within limits are kept",
+			cfg:         types.ChunkingConfig{MinChunkSize: 10, MaxChunkSize: 200},
+			chunks:      []*proto.Chunk{chunk(0, paragraph), chunk(100, paragraph)},
+			want:        []string{paragraph, paragraph},
+			wantMaxSize: 100,
+		},
+		{
+			name:        "fragments merge into the previous chunk",
+			cfg:         types.ChunkingConfig{MinChunkSize: 10, MaxChunkSize: 200},
+			chunks:      []*proto.Chunk{chunk(0, paragraph), chunk(100, "Note"), chunk(104, "See")},
+			want:        []string{paragraph + "Note\nSee"},
+			wantMerged:  2,
+			wantMaxSize: 108,
+		},
+		{
+			name:        "leading fragment merges into the next chunk",
+			cfg:         types.ChunkingConfig{MinChunkSize: 10, MaxChunkSize: 200},
+			chunks:      []*proto.Chunk{chunk(0, "Title"), chunk(5, paragraph)},
+			want:        []string{"Title\n" + paragraph},
+			wantMerged:  1,
+			wantMaxSize: 106,
+		},
+		{
+			name:        "overlap is not repeated",
+			cfg:         types.ChunkingConfig{MinChunkSize: 10, MaxChunkSize: 200},
+			chunks:      []*proto.Chunk{chunk(0, "abc def"), chunk(4, "def ghi")},
+			want:        []string{"abc def ghi"},
+			wantMerged:  1,
+			wantMaxSize: 11,
+		},
+		{
+			name:        "merge never exceeds the maximum",
+			cfg:         types.ChunkingConfig{MinChunkSize: 10, MaxChunkSize: 103},
+			chunks:      []*proto.Chunk{chunk(0, paragraph), chunk(100, "Note")},
+			want:        []string{paragraph, "Note"},
+			wantMaxSize: 100,
+		},
+		{
+			name:        "oversized chunk splits at sentence ends",
+			cfg:         types.ChunkingConfig{MinChunkSize: 10, MaxChunkSize: 200},
+			chunks:      []*proto.Chunk{chunk(0, long)},
+			want:        []string{long[:169], long[169:339], long[339:]},
+			wantSplit:   1,
+			wantMaxSize: 171,
+		},
+		{
+			name:        "maximum defaults to twice the chunk size",
+			cfg:         types.ChunkingConfig{ChunkSize: 150},
+			chunks:      []*proto.Chunk{chunk(0, long)},
+			want:        []string{long[:254], long[254:]},
+			wantSplit:   1,
+			wantMaxSize: 256,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stats := normalizeChunkSizes(tt.chunks, &tt.cfg)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d chunks, want %d", len(got), len(tt.want))
+			}
+			for i, c := range got {
+				if c.Content != tt.want[i] {
+					t.Errorf("chunk %d = %q, want %q", i, c.Content, tt.want[i])
+				}
+				if c.Seq != int32(i) {
+					t.Errorf("chunk %d seq = %d", i, c.Seq)
+				}
+			}
+			if stats.Count != len(tt.want) || stats.Merged != tt.wantMerged || stats.Split != tt.wantSplit ||
+				stats.MaxSize != tt.wantMaxSize {
+				t.Errorf("stats = %+v", stats)
+			}
+		})
+	}
+}
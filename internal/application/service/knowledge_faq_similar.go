@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	faqSimilarProgressKeyPrefix = "faq_similar_progress:"
+	faqSimilarProgressTTL       = 24 * time.Hour
+
+	// faqSimilarMaxAttempts is the number of model calls per entry while the model is rate limited or unavailable
+	faqSimilarMaxAttempts = 3
+	// faqSimilarRetryDelay is the delay before retrying a model call, doubled at each further attempt
+	faqSimilarRetryDelay = 2 * time.Second
+)
+
+const faqSimilarQuestionPrompt = `You write alternative phrasings of a FAQ question, the way users would ask it.
+
+Standard question: {{question}}
+Answer: {{answer}}
+Existing similar questions:
+{{existing}}
+
+Write {{count}} new questions that ask the same thing in different words, in the language of the standard question.
+Do not repeat the standard question or the existing similar questions.
+Output one question per line, without numbering or any other text.`
+
+// getFAQSimilarProgressKey returns the Redis key for storing similar question generation progress
+func getFAQSimilarProgressKey(taskID string) string {
+	return faqSimilarProgressKeyPrefix + taskID
+}
+
+// saveFAQSimilarProgress saves the similar question generation progress to Redis
+func (s *knowledgeService) saveFAQSimilarProgress(ctx context.Context,
+	progress *types.FAQSimilarGenerationProgress,
+) error {
+	progress.UpdatedAt = time.Now().Unix()
+	if progress.Total > 0 {
+		progress.Progress = progress.Processed * 100 / progress.Total
+	}
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal similar question progress: %w", err)
+	}
+	return s.redisClient.Set(ctx, getFAQSimilarProgressKey(progress.TaskID), data, faqSimilarProgressTTL).Err()
+}
+
+// loadFAQSimilarProgress reads the stored progress of a similar question generation task
+func (s *knowledgeService) loadFAQSimilarProgress(ctx context.Context,
+	taskID string,
+) (*types.FAQSimilarGenerationProgress, error) {
+	data, err := s.redisClient.Get(ctx, getFAQSimilarProgressKey(taskID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, werrors.NewNotFoundError("Similar question generation task not found")
+		}
+		return nil, fmt.Errorf("failed to get similar question progress from Redis: %w", err)
+	}
+
+	var progress types.FAQSimilarGenerationProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal similar question progress: %w", err)
+	}
+	return &progress, nil
+}
+
+// GetFAQSimilarGenerationProgress retrieves the progress and the suggestions of a similar question generation task
+func (s *knowledgeService) GetFAQSimilarGenerationProgress(ctx context.Context,
+	kbID, taskID string,
+) (*types.FAQSimilarGenerationProgress, error) {
+	if _, err := s.validateFAQKnowledgeBase(ctx, kbID); err != nil {
+		return nil, err
+	}
+	progress, err := s.loadFAQSimilarProgress(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if progress.KBID != kbID {
+		return nil, werrors.NewNotFoundError("Similar question generation task not found")
+	}
+	return progress, nil
+}
+
+// GenerateFAQSimilarQuestions queues the generation of similar questions for the selected FAQ entries, or all
+// entries of the knowledge base, as a tracked task. The knowledge base's summary model writes the questions.
+func (s *knowledgeService) GenerateFAQSimilarQuestions(ctx context.Context,
+	kbID string, req *types.FAQSimilarGenerationRequest,
+) (*types.FAQSimilarGenerationProgress, error) {
+	kb, err := s.validateFAQKnowledgeBase(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	if kb.SummaryModelID == "" {
+		return nil, werrors.NewBadRequestError("The knowledge base has no summary model to generate similar questions")
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	entryIDs := req.EntryIDs
+	if len(entryIDs) == 0 {
+		faqKnowledge, err := s.findFAQKnowledge(ctx, tenantID, kb.ID)
+		if err != nil {
+			return nil, err
+		}
+		if faqKnowledge != nil {
+			chunks, err := s.chunkRepo.ListAllFAQChunksForExport(ctx, tenantID, faqKnowledge.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list FAQ chunks: %w", err)
+			}
+			for _, chunk := range chunks {
+				entryIDs = append(entryIDs, chunk.SeqID)
+			}
+		}
+	}
+	if len(entryIDs) == 0 {
+		return nil, werrors.NewBadRequestError("No FAQ entries to generate similar questions for")
+	}
+
+	progress := &types.FAQSimilarGenerationProgress{
+		TaskID:    secutils.GenerateTaskID("faq_similar", tenantID, kb.ID),
+		KBID:      kb.ID,
+		Status:    types.FAQSimilarGenerationPending,
+		Count:     req.EffectiveCount(),
+		Apply:     req.Apply,
+		Message:   "Task queued, waiting to start...",
+		CreatedAt: time.Now().Unix(),
+	}
+	seen := make(map[int64]bool, len(entryIDs))
+	for _, id := range entryIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		progress.Suggestions = append(progress.Suggestions, &types.FAQSimilarSuggestion{EntryID: id, Questions: []string{}})
+	}
+	progress.Total = len(progress.Suggestions)
+	if err := s.saveFAQSimilarProgress(ctx, progress); err != nil {
+		logger.Errorf(ctx, "Failed to save similar question progress: %v", err)
+		return nil, err
+	}
+
+	requestID, _ := ctx.Value(types.RequestIDContextKey).(string)
+	payloadBytes, err := json.Marshal(types.FAQSimilarGenerationPayload{
+		TenantID:  tenantID,
+		TaskID:    progress.TaskID,
+		KBID:      kb.ID,
+		RequestID: requestID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal similar question payload: %w", err)
+	}
+	// Entries carry their own outcome, so the task itself is not retried
+	task := asynq.NewTask(types.TypeFAQSimilarQuestion, payloadBytes,
+		asynq.TaskID(progress.TaskID), asynq.Queue("low"), asynq.MaxRetry(0), asynq.Timeout(6*time.Hour))
+	info, err := s.task.Enqueue(task)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to enqueue similar question task: %v", err)
+		return nil, err
+	}
+	logger.Infof(ctx, "Enqueued similar question task: id=%s queue=%s kb_id=%s entries=%d apply=%v",
+		info.ID, info.Queue, kb.ID, progress.Total, progress.Apply)
+	return progress, nil
+}
+
+// ProcessFAQSimilarGeneration handles Asynq similar question generation tasks.
+// Entries are processed one at a time so that the model is not flooded; the questions of each entry are
+// stored as a suggestion, and added to the entry when the task applies them directly.
+func (s *knowledgeService) ProcessFAQSimilarGeneration(ctx context.Context, t *asynq.Task) error {
+	var payload types.FAQSimilarGenerationPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "failed to unmarshal similar question payload: %v", err)
+		return nil
+	}
+
+	ctx = logger.WithRequestID(ctx, payload.RequestID)
+	ctx = context.WithValue(ctx, types.RequestIDContextKey, payload.RequestID)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get tenant: %v", err)
+		return nil
+	}
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
+
+	progress, err := s.loadFAQSimilarProgress(ctx, payload.TaskID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to load similar question progress: %v", err)
+		return nil
+	}
+	fail := func(message string) error {
+		logger.Errorf(ctx, "similar question generation failed: %s", message)
+		progress.Status = types.FAQSimilarGenerationFailed
+		progress.Message = message
+		_ = s.saveFAQSimilarProgress(ctx, progress)
+		return nil
+	}
+
+	kb, err := s.validateFAQKnowledgeBase(ctx, payload.KBID)
+	if err != nil {
+		return fail(fmt.Sprintf("Failed to get knowledge base: %v", err))
+	}
+	chatModel, err := s.modelService.GetChatModel(ctx, kb.SummaryModelID)
+	if err != nil {
+		return fail(fmt.Sprintf("Failed to get summary model: %v", err))
+	}
+
+	progress.Status = types.FAQSimilarGenerationProcessing
+	progress.Message = "Generating similar questions..."
+	_ = s.saveFAQSimilarProgress(ctx, progress)
+
+	for _, suggestion := range progress.Suggestions {
+		if err := s.generateFAQSimilarSuggestion(ctx, chatModel, kb.ID, suggestion, progress.Count); err != nil {
+			suggestion.Error = err.Error()
+		} else if progress.Apply && len(suggestion.Questions) > 0 {
+			if _, err := s.AddSimilarQuestions(ctx, kb.ID, suggestion.EntryID, suggestion.Questions); err != nil {
+				suggestion.Error = err.Error()
+			} else {
+				suggestion.Applied = true
+			}
+		}
+		if suggestion.Error != "" {
+			progress.Failed++
+			logger.Warnf(ctx, "Failed to generate similar questions for FAQ entry %d: %s",
+				suggestion.EntryID, suggestion.Error)
+		}
+		progress.Generated += len(suggestion.Questions)
+		progress.Processed++
+		if err := s.saveFAQSimilarProgress(ctx, progress); err != nil {
+			logger.Warnf(ctx, "Failed to save similar question progress: %v", err)
+		}
+	}
+
+	progress.Status = types.FAQSimilarGenerationCompleted
+	progress.Message = fmt.Sprintf("Generated %d similar questions for %d entries, %d failed",
+		progress.Generated, progress.Total-progress.Failed, progress.Failed)
+	if err := s.saveFAQSimilarProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save similar question progress: %v", err)
+	}
+	logger.Infof(ctx, "Similar question task %s completed: %s", progress.TaskID, progress.Message)
+	return nil
+}
+
+// generateFAQSimilarSuggestion asks the model for similar questions of an FAQ entry and keeps those that
+// are not already questions of the entry
+func (s *knowledgeService) generateFAQSimilarSuggestion(ctx context.Context,
+	chatModel chat.Chat, kbID string, suggestion *types.FAQSimilarSuggestion, count int,
+) error {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	chunk, err := s.chunkRepo.GetChunkBySeqID(ctx, tenantID, suggestion.EntryID)
+	if err != nil || chunk.KnowledgeBaseID != kbID || chunk.ChunkType != types.ChunkTypeFAQ {
+		return fmt.Errorf("FAQ entry not found")
+	}
+	meta, err := chunk.FAQMetadata()
+	if err != nil || meta == nil {
+		return fmt.Errorf("failed to read FAQ metadata")
+	}
+	suggestion.StandardQuestion = meta.StandardQuestion
+
+	existing := "(none)"
+	if len(meta.SimilarQuestions) > 0 {
+		existing = "- " + strings.Join(meta.SimilarQuestions, "\n- ")
+	}
+	prompt := strings.NewReplacer(
+		"{{question}}", meta.StandardQuestion,
+		"{{answer}}", strings.Join(meta.Answers, "\n"),
+		"{{existing}}", existing,
+		"{{count}}", fmt.Sprintf("%d", count),
+	).Replace(faqSimilarQuestionPrompt)
+
+	content, err := s.chatWithRetry(ctx, chatModel, prompt)
+	if err != nil {
+		return err
+	}
+	suggestion.Questions = newSimilarQuestions(meta, content, count)
+	return nil
+}
+
+// chatWithRetry calls the model, waiting and retrying while it is rate limited or momentarily unavailable
+func (s *knowledgeService) chatWithRetry(ctx context.Context, chatModel chat.Chat, prompt string) (string, error) {
+	thinking := false
+	delay := faqSimilarRetryDelay
+	for attempt := 1; ; attempt++ {
+		response, err := chatModel.Chat(ctx, []chat.Message{{Role: "user", Content: prompt}}, &chat.ChatOptions{
+			Temperature: 0.8,
+			MaxTokens:   512,
+			Thinking:    &thinking,
+		})
+		if err == nil {
+			return response.Content, nil
+		}
+		if attempt >= faqSimilarMaxAttempts || !isTransientIngestionError(err) {
+			return "", fmt.Errorf("failed to generate similar questions: %w", err)
+		}
+		logger.Warnf(ctx, "Model call failed in attempt %d, retrying in %s: %v", attempt, delay, err)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// newSimilarQuestions parses the questions written by the model, one per line, and returns up to limit of
+// them that differ from the entry's questions and from each other, ignoring case, spaces and punctuation
+func newSimilarQuestions(meta *types.FAQChunkMetadata, content string, limit int) []string {
+	seen := make(map[string]bool)
+	seen[questionKey(meta.StandardQuestion)] = true
+	for _, q := range meta.SimilarQuestions {
+		seen[questionKey(q)] = true
+	}
+
+	questions := []string{}
+	for _, line := range strings.Split(content, "\n") {
+		q := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "0123456789.-*)、 "))
+		q = strings.Trim(q, "\"'“”")
+		key := questionKey(q)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		questions = append(questions, q)
+		if len(questions) >= limit {
+			break
+		}
+	}
+	return questions
+}
+
+// questionKey normalizes a question for duplicate detection
+func questionKey(q string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, q)
+}
+
+// ApplyFAQSimilarQuestions adds the previewed suggestions of a completed task to their entries. Questions
+// edited in the request replace the generated ones; suggestions already applied are left alone.
+func (s *knowledgeService) ApplyFAQSimilarQuestions(ctx context.Context,
+	kbID, taskID string, req *types.FAQSimilarApplyRequest,
+) (*types.FAQSimilarGenerationProgress, error) {
+	progress, err := s.GetFAQSimilarGenerationProgress(ctx, kbID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if progress.Status != types.FAQSimilarGenerationCompleted {
+		return nil, werrors.NewBadRequestError("Similar question generation has not completed yet")
+	}
+
+	suggestions := make(map[int64]*types.FAQSimilarSuggestion, len(progress.Suggestions))
+	for _, suggestion := range progress.Suggestions {
+		suggestions[suggestion.EntryID] = suggestion
+	}
+	selected := make(map[int64][]string)
+	for _, entry := range req.Entries {
+		if suggestions[entry.EntryID] == nil {
+			return nil, werrors.NewBadRequestError(fmt.Sprintf("Entry %d is not part of the task", entry.EntryID))
+		}
+		selected[entry.EntryID] = entry.Questions
+	}
+
+	for _, suggestion := range progress.Suggestions {
+		questions, ok := selected[suggestion.EntryID]
+		if len(selected) > 0 && !ok {
+			continue
+		}
+		if suggestion.Applied {
+			continue
+		}
+		if len(questions) == 0 {
+			questions = suggestion.Questions
+		}
+		if len(questions) == 0 {
+			continue
+		}
+		if _, err := s.AddSimilarQuestions(ctx, kbID, suggestion.EntryID, questions); err != nil {
+			logger.Warnf(ctx, "Failed to apply similar questions to FAQ entry %d: %v", suggestion.EntryID, err)
+			suggestion.Error = err.Error()
+			continue
+		}
+		suggestion.Questions = questions
+		suggestion.Applied = true
+		suggestion.Error = ""
+	}
+
+	if err := s.saveFAQSimilarProgress(ctx, progress); err != nil {
+		logger.Warnf(ctx, "Failed to save similar question progress: %v", err)
+	}
+	return progress, nil
+}
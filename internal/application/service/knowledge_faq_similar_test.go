@@ -0,0 +1,36 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestNewSimilarQuestions(t *testing.T) {
+	meta := &types.FAQChunkMetadata{
+		StandardQuestion: "How do I reset my password?",
+		SimilarQuestions: []string{"I forgot my password"},
+	}
+	content := "1. How can I change a forgotten password?\n" +
+		"2) how do i reset my password\n" +
+		"- I forgot my password!\n" +
+		"\n" +
+		"\"Where is the password reset option?\"\n" +
+		"How can I change a forgotten password\n" +
+		"What should I do when my password no longer works?\n"
+
+	got := newSimilarQuestions(meta, content, 3)
+	want := []string{
+		"How can I change a forgotten password?",
+		"Where is the password reset option?",
+		"What should I do when my password no longer works?",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("newSimilarQuestions() = %q, want %q", got, want)
+	}
+
+	if got := newSimilarQuestions(meta, content, 1); len(got) != 1 {
+		t.Errorf("newSimilarQuestions() returned %d questions over the limit of 1", len(got))
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"syscall"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/hibiken/asynq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultIngestionMaxAttempts is the number of processing attempts when not configured
+const defaultIngestionMaxAttempts = 3
+
+// transientHTTPStatus matches the HTTP status of model and storage API errors that are worth retrying
+var transientHTTPStatus = regexp.MustCompile(`(?i)status:?\s*(429|5\d\d)\b`)
+
+// ingestionMaxRetry returns the number of retries of a document processing task
+func (s *knowledgeService) ingestionMaxRetry() int {
+	attempts := defaultIngestionMaxAttempts
+	if s.config != nil && s.config.KnowledgeBase != nil && s.config.KnowledgeBase.IngestionRetry != nil &&
+		s.config.KnowledgeBase.IngestionRetry.MaxAttempts > 0 {
+		attempts = s.config.KnowledgeBase.IngestionRetry.MaxAttempts
+	}
+	return attempts - 1
+}
+
+// processAttempt returns the attempt of the running document processing task counted from 1, and
+// whether it is the last one. Processing outside of a task has a single attempt.
+func processAttempt(ctx context.Context) (int, bool) {
+	retryCount, ok := asynq.GetRetryCount(ctx)
+	if !ok {
+		return 1, true
+	}
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	return retryCount + 1, retryCount >= maxRetry
+}
+
+// isTransientIngestionError reports whether a processing error is caused by a momentary failure of the
+// docreader, a model or the storage, so that processing the document again may succeed
+func isTransientIngestionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if st, ok := status.FromError(err); ok {
+		switch st.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+			return true
+		}
+		return false
+	}
+	return transientHTTPStatus.MatchString(err.Error())
+}
+
+// handleProcessError records a document processing error on the knowledge. Transient errors are left to
+// the task retry until the last attempt; the returned error makes the task retry, nil ends it.
+func (s *knowledgeService) handleProcessError(ctx context.Context,
+	knowledge *types.Knowledge, err error, lastAttempt bool,
+) error {
+	transient := isTransientIngestionError(err)
+	if transient && !lastAttempt {
+		logger.Warnf(ctx, "Processing knowledge %s failed in attempt %d, retrying: %v",
+			knowledge.ID, knowledge.ProcessAttempts, err)
+		knowledge.MarkProcessRetrying(err)
+		if updateErr := s.repo.UpdateKnowledge(ctx, knowledge); updateErr != nil {
+			logger.Errorf(ctx, "Failed to update knowledge %s: %v", knowledge.ID, updateErr)
+		}
+		return err
+	}
+
+	logger.Errorf(ctx, "Processing knowledge %s failed after %d attempts (transient: %v): %v",
+		knowledge.ID, knowledge.ProcessAttempts, transient, err)
+	knowledge.MarkProcessFailed(err, transient)
+	if updateErr := s.repo.UpdateKnowledge(ctx, knowledge); updateErr != nil {
+		logger.Errorf(ctx, "Failed to update knowledge %s: %v", knowledge.ID, updateErr)
+	}
+	return nil
+}
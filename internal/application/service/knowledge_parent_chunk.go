@@ -0,0 +1,82 @@
+package service
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// buildParentChunks groups consecutive text chunks, in document order, into parent chunks of at most
+// parentSize characters and links each grouped chunk to its parent. A chunk that does not fit with its
+// neighbors keeps no parent, since it already is the largest context available. Sizes are counted in
+// characters.
+func buildParentChunks(textChunks []*types.Chunk, parentSize int, ids *types.ChunkIDGenerator) []*types.Chunk {
+	var parents []*types.Chunk
+	var group []*types.Chunk
+	groupSize := 0
+
+	flush := func() {
+		if len(group) > 1 {
+			parent := newParentChunk(group)
+			parent.ID = ids.ID(parent)
+			for _, chunk := range group {
+				chunk.ParentChunkID = parent.ID
+			}
+			parents = append(parents, parent)
+		}
+		group, groupSize = nil, 0
+	}
+
+	for _, chunk := range textChunks {
+		size := utf8.RuneCountInString(chunk.Content)
+		if len(group) > 0 {
+			// The text a chunk overlaps its predecessor on appears once in the parent
+			overlap := min(max(group[len(group)-1].EndAt-chunk.StartAt, 0), size)
+			if groupSize+size-overlap > parentSize {
+				flush()
+			} else {
+				size -= overlap
+			}
+		}
+		group = append(group, chunk)
+		groupSize += size
+	}
+	flush()
+	return parents
+}
+
+// newParentChunk builds the parent chunk of a group of consecutive chunks, dropping the text neighboring
+// chunks overlap on
+func newParentChunk(group []*types.Chunk) *types.Chunk {
+	first := group[0]
+	var content strings.Builder
+	content.WriteString(first.Content)
+	end := first.EndAt
+	for _, chunk := range group[1:] {
+		runes := []rune(chunk.Content)
+		overlap := end - chunk.StartAt
+		if overlap > 0 && overlap <= len(runes) && strings.HasSuffix(content.String(), string(runes[:overlap])) {
+			content.WriteString(string(runes[overlap:]))
+		} else {
+			content.WriteString("\n")
+			content.WriteString(chunk.Content)
+		}
+		end = max(end, chunk.EndAt)
+	}
+	now := time.Now()
+	return &types.Chunk{
+		TenantID:        first.TenantID,
+		KnowledgeID:     first.KnowledgeID,
+		KnowledgeBaseID: first.KnowledgeBaseID,
+		Content:         content.String(),
+		ChunkIndex:      first.ChunkIndex,
+		IsEnabled:       first.IsEnabled,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		StartAt:         first.StartAt,
+		EndAt:           end,
+		ChunkType:       types.ChunkTypeParent,
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestBuildParentChunks(t *testing.T) {
+	chunk := func(index, start int, content string) *types.Chunk {
+		return &types.Chunk{
+			ID:         content,
+			Content:    content,
+			ChunkIndex: index,
+			StartAt:    start,
+			EndAt:      start + utf8.RuneCountInString(content),
+			ChunkType:  types.ChunkTypeText,
+		}
+	}
+	// "cdef" overlaps "abcd" on "cd"
+	chunks := []*types.Chunk{
+		chunk(0, 0, "abcd"),
+		chunk(1, 2, "cdef"),
+		chunk(2, 6, "ghij"),
+		chunk(3, 10, "klmnopqrst"),
+		chunk(4, 20, "uv"),
+	}
+
+	parents := buildParentChunks(chunks, 10, types.NewChunkIDGenerator("knowledge"))
+	if len(parents) != 1 {
+		t.Fatalf("got %d parents, want 1", len(parents))
+	}
+	parent := parents[0]
+	if parent.Content != "abcdef\nghij" {
+		t.Errorf("parent content = %q, want the overlap dropped", parent.Content)
+	}
+	if parent.ChunkType != types.ChunkTypeParent || parent.StartAt != 0 || parent.EndAt != 10 || parent.ChunkIndex != 0 {
+		t.Errorf("unexpected parent %+v", parent)
+	}
+	for i, c := range chunks {
+		wantParent := ""
+		if i < 3 {
+			wantParent = parent.ID
+		}
+		if c.ParentChunkID != wantParent {
+			t.Errorf("chunk %d parent = %q, want %q", i, c.ParentChunkID, wantParent)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"math"
+
+	"github.com/Tencent/WeKnora/internal/application/service/retriever"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// errNoKeywordEngine fails documents indexed without the embed stage when no keyword retrieval is configured
+var errNoKeywordEngine = errors.New("the embed stage is disabled but no keyword retrieval engine is configured")
+
+// pipelineStages resolves the ingestion stages of a document: the knowledge base pipeline applied over
+// the stages selected by the one-off options of the document
+func pipelineStages(kb *types.KnowledgeBase, enableMultimodal, enableQuestionGeneration bool) types.PipelineStages {
+	return kb.PipelineConfig.Resolve(types.PipelineStages{
+		Extract:           true,
+		Chunk:             true,
+		Embed:             true,
+		GenerateQuestions: enableQuestionGeneration,
+		ExtractImages:     enableMultimodal,
+		Summarize:         kb.DocumentSummaryConfig.GenerationEnabled(),
+	})
+}
+
+// parserChunking returns the chunk size and overlap the document parser splits with. Without the
+// chunk stage the parser is asked for a single chunk per document.
+func parserChunking(kb *types.KnowledgeBase, stages types.PipelineStages) (int32, int32) {
+	if !stages.Chunk {
+		return math.MaxInt32, 0
+	}
+	return int32(kb.ChunkingConfig.ChunkSize), int32(kb.ChunkingConfig.ChunkOverlap)
+}
+
+// indexingEngine returns the engine the chunks are indexed into: every engine of the tenant, or only
+// its keyword engines without the embed stage
+func indexingEngine(registry interfaces.RetrieveEngineRegistry, tenant *types.Tenant,
+	stages types.PipelineStages,
+) (*retriever.CompositeRetrieveEngine, error) {
+	engines := tenant.GetEffectiveEngines()
+	if !stages.Embed {
+		keywordEngines := make([]types.RetrieverEngineParams, 0, len(engines))
+		for _, engine := range engines {
+			if engine.RetrieverType == types.KeywordsRetrieverType {
+				keywordEngines = append(keywordEngines, engine)
+			}
+		}
+		if len(keywordEngines) == 0 {
+			return nil, errNoKeywordEngine
+		}
+		engines = keywordEngines
+	}
+	return retriever.NewCompositeRetrieveEngine(registry, engines)
+}
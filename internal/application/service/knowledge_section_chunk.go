@@ -0,0 +1,331 @@
+package service
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/docreader/proto"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+var (
+	sectionHeadingPattern        = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)[ \t#]*$`)
+	sectionTableSeparatorPattern = regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+)
+
+// sectionBlockKind is the kind of a Markdown block
+type sectionBlockKind int
+
+const (
+	sectionBlockText sectionBlockKind = iota
+	sectionBlockHeading
+	sectionBlockTable
+	sectionBlockHTMLTable
+	sectionBlockCode
+)
+
+// sectionBlock is a run of document lines that section chunking keeps together when it fits in a chunk
+type sectionBlock struct {
+	kind  sectionBlockKind
+	level int    // Heading level, for headings
+	title string // Heading text, for headings
+	text  string
+	start int // Position in the document, in characters
+}
+
+func (b sectionBlock) size() int {
+	return utf8.RuneCountInString(b.text)
+}
+
+// section is a heading with its content up to the next heading
+type section struct {
+	path   []string
+	blocks []sectionBlock
+}
+
+// splitSections re-chunks a parsed document by its Markdown sections: every heading starts a new chunk, a
+// section larger than the maximum chunk size is cut between blocks, and tables and code blocks stay whole
+// unless they alone exceed the maximum size. Returns the chunks renumbered in document order, the heading path
+// of each chunk by sequence number and the chunk size distribution.
+func splitSections(chunks []*proto.Chunk, cfg *types.ChunkingConfig) ([]*proto.Chunk, map[int32][]string,
+	*types.ChunkSizeStats,
+) {
+	if len(chunks) == 0 {
+		return chunks, nil, types.NewChunkSizeStats(nil, 0, 0)
+	}
+	maxSize := cfg.EffectiveMaxChunkSize()
+
+	doc := &proto.Chunk{
+		Content: chunks[0].Content,
+		Start:   chunks[0].Start,
+		End:     chunks[0].End,
+		Images:  slices.Clone(chunks[0].Images),
+	}
+	for _, chunk := range chunks[1:] {
+		mergeChunk(doc, chunk)
+	}
+
+	split := 0
+	var sectioned []*proto.Chunk
+	var paths [][]string
+	for _, sec := range buildSections(parseSectionBlocks(doc.Content, int(doc.Start))) {
+		var blocks []sectionBlock
+		for _, block := range sec.blocks {
+			pieces := splitSectionBlock(block, maxSize)
+			if len(pieces) > 1 {
+				split++
+			}
+			blocks = append(blocks, pieces...)
+		}
+		for _, group := range packSectionBlocks(blocks, maxSize) {
+			chunk := sectionChunk(group)
+			if chunk == nil {
+				continue
+			}
+			sectioned = append(sectioned, chunk)
+			paths = append(paths, sec.path)
+		}
+	}
+	if len(sectioned) == 0 {
+		normalized, stats := normalizeChunkSizes(chunks, cfg)
+		return normalized, nil, stats
+	}
+
+	for _, image := range doc.Images {
+		target := sectioned[0]
+		for _, chunk := range sectioned {
+			if image.Start >= chunk.Start {
+				target = chunk
+			}
+		}
+		target.Images = append(target.Images, image)
+	}
+
+	sectionPaths := make(map[int32][]string, len(sectioned))
+	sizes := make([]int, len(sectioned))
+	for i, chunk := range sectioned {
+		chunk.Seq = chunks[0].Seq + int32(i)
+		sizes[i] = utf8.RuneCountInString(chunk.Content)
+		if len(paths[i]) > 0 {
+			sectionPaths[chunk.Seq] = paths[i]
+		}
+	}
+	return sectioned, sectionPaths, types.NewChunkSizeStats(sizes, 0, split)
+}
+
+// parseSectionBlocks splits Markdown text into headings, tables, fenced code blocks and paragraphs.
+// Blank lines stay with the block they follow.
+func parseSectionBlocks(text string, offset int) []sectionBlock {
+	lines := strings.SplitAfter(text, "\n")
+	var blocks []sectionBlock
+	pos := offset
+	add := func(kind sectionBlockKind, from, to int) {
+		block := sectionBlock{kind: kind, text: strings.Join(lines[from:to], ""), start: pos}
+		pos += block.size()
+		blocks = append(blocks, block)
+	}
+	appendBlank := func(i int) {
+		if n := len(blocks); n > 0 {
+			blocks[n-1].text += lines[i]
+		} else {
+			blocks = append(blocks, sectionBlock{kind: sectionBlockText, text: lines[i], start: pos})
+		}
+		pos += utf8.RuneCountInString(lines[i])
+	}
+
+	for i := 0; i < len(lines); {
+		line := strings.TrimRight(lines[i], "\r\n")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			appendBlank(i)
+			i++
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence := trimmed[:3]
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), fence) {
+				end++
+			}
+			end = min(end+1, len(lines))
+			add(sectionBlockCode, i, end)
+			i = end
+		case strings.HasPrefix(strings.ToLower(trimmed), "<table"):
+			end := i
+			for end < len(lines) && !strings.Contains(strings.ToLower(lines[end]), "</table>") {
+				end++
+			}
+			end = min(end+1, len(lines))
+			add(sectionBlockHTMLTable, i, end)
+			i = end
+		case strings.HasPrefix(trimmed, "|"):
+			end := i + 1
+			for end < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end]), "|") {
+				end++
+			}
+			add(sectionBlockTable, i, end)
+			i = end
+		default:
+			if m := sectionHeadingPattern.FindStringSubmatch(line); m != nil {
+				add(sectionBlockHeading, i, i+1)
+				blocks[len(blocks)-1].level = len(m[1])
+				blocks[len(blocks)-1].title = strings.TrimSpace(m[2])
+				i++
+				continue
+			}
+			end := i + 1
+			for end < len(lines) && isParagraphLine(lines[end]) {
+				end++
+			}
+			add(sectionBlockText, i, end)
+			i = end
+		}
+	}
+	return blocks
+}
+
+// isParagraphLine reports whether a line continues a paragraph rather than starting another block
+func isParagraphLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" && !strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~") &&
+		!strings.HasPrefix(trimmed, "|") && !strings.HasPrefix(strings.ToLower(trimmed), "<table") &&
+		!sectionHeadingPattern.MatchString(strings.TrimRight(line, "\r\n"))
+}
+
+// buildSections groups blocks under the heading they follow. Headings directly followed by another
+// heading are kept with the next section, so that no chunk holds a heading alone.
+func buildSections(blocks []sectionBlock) []section {
+	var sections []section
+	var headings []sectionBlock
+	current := section{}
+	var pending []sectionBlock
+	hasBody := false
+	flush := func() {
+		if hasBody {
+			sections = append(sections, current)
+			pending = nil
+		} else {
+			pending = current.blocks
+		}
+	}
+
+	for _, block := range blocks {
+		if block.kind != sectionBlockHeading {
+			current.blocks = append(current.blocks, block)
+			if strings.TrimSpace(block.text) != "" {
+				hasBody = true
+			}
+			continue
+		}
+		flush()
+		for len(headings) > 0 && headings[len(headings)-1].level >= block.level {
+			headings = headings[:len(headings)-1]
+		}
+		headings = append(headings, block)
+		path := make([]string, len(headings))
+		for i, heading := range headings {
+			path[i] = heading.title
+		}
+		current = section{path: path, blocks: append(slices.Clone(pending), block)}
+		hasBody = false
+	}
+	if hasBody || len(current.blocks) > 0 {
+		sections = append(sections, current)
+	}
+	return sections
+}
+
+// splitSectionBlock cuts a block larger than maxSize: tables between rows with the header repeated, code
+// blocks between lines within their fences, and other blocks like oversized chunks
+func splitSectionBlock(block sectionBlock, maxSize int) []sectionBlock {
+	if block.size() <= maxSize {
+		return []sectionBlock{block}
+	}
+	// Trailing blank lines are dropped so that the closing fence is the last line
+	lines := strings.SplitAfter(strings.TrimRight(block.text, " \t\r\n")+"\n", "\n")
+	lines = lines[:len(lines)-1]
+	var head, body, tail []string
+	switch block.kind {
+	case sectionBlockTable:
+		head, body = lines[:1], lines[1:]
+		if len(body) > 0 && sectionTableSeparatorPattern.MatchString(strings.TrimRight(body[0], "\r\n")) {
+			head, body = lines[:2], lines[2:]
+		}
+	case sectionBlockCode:
+		fence := strings.TrimSpace(lines[0])[:3]
+		head, body = lines[:1], lines[1:]
+		if n := len(body); n > 0 && strings.HasPrefix(strings.TrimSpace(body[n-1]), fence) {
+			body, tail = body[:n-1], body[n-1:]
+		} else {
+			tail = []string{fence + "\n"}
+		}
+	default:
+		var pieces []sectionBlock
+		for _, piece := range splitChunk(&proto.Chunk{Content: block.text, Start: int32(block.start)}, maxSize) {
+			pieces = append(pieces, sectionBlock{kind: block.kind, text: piece.Content, start: int(piece.Start)})
+		}
+		return pieces
+	}
+
+	frame := utf8.RuneCountInString(strings.Join(head, "") + strings.Join(tail, ""))
+	var pieces []sectionBlock
+	start := block.start
+	for i := 0; i < len(body); {
+		size := frame
+		end := i
+		for end < len(body) && (end == i || size+utf8.RuneCountInString(body[end]) <= maxSize) {
+			size += utf8.RuneCountInString(body[end])
+			end++
+		}
+		rows := strings.Join(body[i:end], "")
+		if len(tail) > 0 && !strings.HasSuffix(rows, "\n") {
+			rows += "\n"
+		}
+		text := strings.Join(head, "") + rows + strings.Join(tail, "")
+		pieces = append(pieces, sectionBlock{kind: block.kind, text: text, start: start})
+		start += utf8.RuneCountInString(rows)
+		i = end
+	}
+	if len(pieces) == 0 {
+		return []sectionBlock{block}
+	}
+	return pieces
+}
+
+// packSectionBlocks groups consecutive blocks of a section into chunks of up to maxSize characters
+func packSectionBlocks(blocks []sectionBlock, maxSize int) [][]sectionBlock {
+	var groups [][]sectionBlock
+	var group []sectionBlock
+	size := 0
+	for _, block := range blocks {
+		if len(group) > 0 && size+block.size() > maxSize {
+			groups = append(groups, group)
+			group, size = nil, 0
+		}
+		group = append(group, block)
+		size += block.size()
+	}
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// sectionChunk joins a group of blocks into a chunk, nil when the blocks hold only whitespace
+func sectionChunk(blocks []sectionBlock) *proto.Chunk {
+	var sb strings.Builder
+	for _, block := range blocks {
+		sb.WriteString(block.text)
+	}
+	content := strings.TrimSpace(sb.String())
+	if content == "" {
+		return nil
+	}
+	last := blocks[len(blocks)-1]
+	return &proto.Chunk{
+		Content: content,
+		Start:   int32(blocks[0].start),
+		End:     int32(last.start + last.size()),
+	}
+}
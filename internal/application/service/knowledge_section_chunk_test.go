@@ -0,0 +1,97 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/docreader/proto"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestSplitSections(t *testing.T) {
+	// The parser cut the manual in the middle of the table and repeats a few characters as overlap
+	manual := "# Manual\n\n## Install\n\nRun the installer.\n\n| Option | Default |\n| --- | --- |\n" +
+		"| port | 8080 |\n| host | localhost |\n\n## Usage\n\n### CLI\n\n```sh\n# not a heading\nweknora serve\n```\n"
+	cut := strings.Index(manual, "| host")
+	chunks := []*proto.Chunk{
+		{Content: manual[:cut], Start: 0, End: int32(cut)},
+		{Content: manual[cut-5:], Start: int32(cut - 5), End: int32(len(manual))},
+	}
+
+	got, paths, stats := splitSections(chunks, &types.ChunkingConfig{MaxChunkSize: 200})
+	want := []struct {
+		content string
+		path    []string
+	}{
+		{
+			content: "# Manual\n\n## Install\n\nRun the installer.\n\n| Option | Default |\n| --- | --- |\n" +
+				"| port | 8080 |\n| host | localhost |",
+			path: []string{"Manual", "Install"},
+		},
+		{
+			content: "## Usage\n\n### CLI\n\n```sh\n# not a heading\nweknora serve\n```",
+			path:    []string{"Manual", "Usage", "CLI"},
+		},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d chunks, want %d: %q", len(got), len(want), chunkContents(got))
+	}
+	for i, w := range want {
+		if got[i].Content != w.content {
+			t.Errorf("chunk %d = %q, want %q", i, got[i].Content, w.content)
+		}
+		if got[i].Seq != int32(i) {
+			t.Errorf("chunk %d has seq %d", i, got[i].Seq)
+		}
+		if !slices.Equal(paths[got[i].Seq], w.path) {
+			t.Errorf("chunk %d path = %v, want %v", i, paths[got[i].Seq], w.path)
+		}
+	}
+	if stats.Count != 2 || stats.Split != 0 {
+		t.Errorf("stats = %+v, want 2 chunks and no split", stats)
+	}
+}
+
+func TestSplitSectionsOversizedBlocks(t *testing.T) {
+	var table strings.Builder
+	table.WriteString("| Key | Value |\n| --- | --- |\n")
+	for i := 0; i < 20; i++ {
+		table.WriteString("| key | value of the row |\n")
+	}
+	code := "```go\n" + strings.Repeat("fmt.Println(\"hello\")\n", 12) + "```\n"
+	doc := "# Reference\n\n" + table.String() + "\n" + code
+	chunks := []*proto.Chunk{{Content: doc, End: int32(utf8.RuneCountInString(doc))}}
+
+	got, paths, stats := splitSections(chunks, &types.ChunkingConfig{MaxChunkSize: 150})
+	if stats.Split != 2 {
+		t.Errorf("split = %d, want the table and the code block", stats.Split)
+	}
+	for i, chunk := range got {
+		if size := utf8.RuneCountInString(chunk.Content); size > 150 {
+			t.Errorf("chunk %d has %d characters", i, size)
+		}
+		if !slices.Equal(paths[chunk.Seq], []string{"Reference"}) {
+			t.Errorf("chunk %d path = %v", i, paths[chunk.Seq])
+		}
+		switch {
+		case strings.Contains(chunk.Content, "| key"):
+			if !strings.Contains(chunk.Content, "| Key | Value |\n| --- | --- |\n") {
+				t.Errorf("table piece %d lost its header: %q", i, chunk.Content)
+			}
+		case strings.Contains(chunk.Content, "Println"):
+			if !strings.HasPrefix(chunk.Content, "```go\n") || !strings.HasSuffix(chunk.Content, "\n```") {
+				t.Errorf("code piece %d is not fenced: %q", i, chunk.Content)
+			}
+		}
+	}
+}
+
+func chunkContents(chunks []*proto.Chunk) []string {
+	contents := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		contents[i] = chunk.Content
+	}
+	return contents
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/hibiken/asynq"
+)
+
+// defaultSyncProcessingTimeout bounds the processing of a file within the upload request when not configured
+const defaultSyncProcessingTimeout = 60 * time.Second
+
+// processesSynchronously reports whether an uploaded file is small enough to be processed within the request
+func (s *knowledgeService) processesSynchronously(fileSize int64) bool {
+	if s.config == nil || s.config.KnowledgeBase == nil || s.config.KnowledgeBase.SyncProcessing == nil {
+		return false
+	}
+	maxSizeKB := s.config.KnowledgeBase.SyncProcessing.MaxFileSizeKB
+	return maxSizeKB > 0 && fileSize <= maxSizeKB*1024
+}
+
+// syncProcessingTimeout returns how long a file may be processed within the upload request
+func (s *knowledgeService) syncProcessingTimeout() time.Duration {
+	if s.config != nil && s.config.KnowledgeBase != nil && s.config.KnowledgeBase.SyncProcessing != nil &&
+		s.config.KnowledgeBase.SyncProcessing.Timeout > 0 {
+		return s.config.KnowledgeBase.SyncProcessing.Timeout
+	}
+	return defaultSyncProcessingTimeout
+}
+
+// processDocumentSync processes an uploaded file within the request the way the processing task would, in a
+// single attempt, and returns the knowledge with its chunks once completed. Processing is detached from the
+// request so that a client disconnect doesn't leave the knowledge half processed.
+func (s *knowledgeService) processDocumentSync(ctx context.Context,
+	payload types.DocumentProcessPayload,
+) (*types.Knowledge, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal document process payload: %w", err)
+	}
+
+	processCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.syncProcessingTimeout())
+	defer cancel()
+	start := time.Now()
+	// Failures are recorded on the knowledge, which is returned with the failed status
+	if err := s.ProcessDocument(processCtx, asynq.NewTask(types.TypeDocumentProcess, payloadBytes)); err != nil {
+		logger.Warnf(ctx, "Synchronous processing of knowledge %s failed: %v", payload.KnowledgeID, err)
+	}
+	logger.Infof(ctx, "Processed knowledge %s synchronously in %v", payload.KnowledgeID, time.Since(start))
+
+	knowledge, err := s.repo.GetKnowledgeByID(ctx, payload.TenantID, payload.KnowledgeID)
+	if err != nil {
+		return nil, err
+	}
+	knowledge.ProcessingMode = types.KnowledgeProcessingSync
+	if knowledge.ParseStatus == types.ParseStatusCompleted {
+		chunks, err := s.chunkService.ListChunksByKnowledgeID(ctx, knowledge.ID)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to list chunks of knowledge %s: %v", knowledge.ID, err)
+			return nil, err
+		}
+		knowledge.Chunks = chunks
+	}
+	return knowledge, nil
+}
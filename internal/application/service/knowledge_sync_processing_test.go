@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/config"
+)
+
+func TestSyncProcessingThreshold(t *testing.T) {
+	withSyncProcessing := func(sync *config.SyncProcessingConfig) *config.Config {
+		return &config.Config{KnowledgeBase: &config.KnowledgeBaseConfig{SyncProcessing: sync}}
+	}
+
+	tests := []struct {
+		name        string
+		cfg         *config.Config
+		fileSize    int64
+		wantSync    bool
+		wantTimeout time.Duration
+	}{
+		{name: "no config", fileSize: 1, wantTimeout: defaultSyncProcessingTimeout},
+		{name: "not configured", cfg: withSyncProcessing(nil), fileSize: 1, wantTimeout: defaultSyncProcessingTimeout},
+		{
+			name:        "disabled with zero size",
+			cfg:         withSyncProcessing(&config.SyncProcessingConfig{}),
+			fileSize:    1,
+			wantTimeout: defaultSyncProcessingTimeout,
+		},
+		{
+			name:        "small file",
+			cfg:         withSyncProcessing(&config.SyncProcessingConfig{MaxFileSizeKB: 64, Timeout: 20 * time.Second}),
+			fileSize:    10 * 1024,
+			wantSync:    true,
+			wantTimeout: 20 * time.Second,
+		},
+		{
+			name:        "file at the threshold",
+			cfg:         withSyncProcessing(&config.SyncProcessingConfig{MaxFileSizeKB: 64}),
+			fileSize:    64 * 1024,
+			wantSync:    true,
+			wantTimeout: defaultSyncProcessingTimeout,
+		},
+		{
+			name:        "file above the threshold",
+			cfg:         withSyncProcessing(&config.SyncProcessingConfig{MaxFileSizeKB: 64}),
+			fileSize:    64*1024 + 1,
+			wantTimeout: defaultSyncProcessingTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &knowledgeService{config: tt.cfg}
+			if got := svc.processesSynchronously(tt.fileSize); got != tt.wantSync {
+				t.Errorf("processesSynchronously(%d) = %v, want %v", tt.fileSize, got, tt.wantSync)
+			}
+			if got := svc.syncProcessingTimeout(); got != tt.wantTimeout {
+				t.Errorf("syncProcessingTimeout() = %v, want %v", got, tt.wantTimeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeKnowledgeRepo serves knowledge by ID
+type fakeKnowledgeRepo struct {
+	interfaces.KnowledgeRepository
+	knowledge map[string]*types.Knowledge
+}
+
+func (r *fakeKnowledgeRepo) GetKnowledgeByID(_ context.Context, _ uint64, id string) (*types.Knowledge, error) {
+	return r.knowledge[id], nil
+}
+
+func (r *fakeKnowledgeRepo) GetKnowledgeBatch(_ context.Context, _ uint64, ids []string) ([]*types.Knowledge, error) {
+	var list []*types.Knowledge
+	for _, id := range ids {
+		if knowledge, ok := r.knowledge[id]; ok {
+			list = append(list, knowledge)
+		}
+	}
+	return list, nil
+}
+
+func (r *fakeKnowledgeRepo) UpdateKnowledgeEnabled(_ context.Context, _ uint64, ids []string, isEnabled bool) error {
+	for _, id := range ids {
+		r.knowledge[id].IsEnabled = isEnabled
+	}
+	return nil
+}
+
+// fakeChunkEnabledRepo records the knowledge whose chunks were toggled
+type fakeChunkEnabledRepo struct {
+	interfaces.ChunkRepository
+	toggled map[string]bool
+}
+
+func (r *fakeChunkEnabledRepo) UpdateChunkEnabledByKnowledgeIDs(
+	_ context.Context, _ uint64, knowledgeIDs []string, isEnabled bool,
+) ([]string, error) {
+	for _, id := range knowledgeIDs {
+		r.toggled[id] = isEnabled
+	}
+	return nil, nil
+}
+
+func TestReprocessItemOutcome(t *testing.T) {
+	tests := []struct {
+		name       string
+		knowledge  *types.Knowledge
+		wantStatus string
+		wantError  string
+	}{
+		{name: "completed", knowledge: &types.Knowledge{ParseStatus: types.ParseStatusCompleted}, wantStatus: types.ReprocessItemSucceeded},
+		{
+			name:       "failed again",
+			knowledge:  &types.Knowledge{ParseStatus: types.ParseStatusFailed, ErrorMessage: "docreader unavailable"},
+			wantStatus: types.ReprocessItemFailed,
+			wantError:  "docreader unavailable",
+		},
+		{name: "still pending", knowledge: &types.Knowledge{ParseStatus: types.ParseStatusPending}, wantStatus: types.ReprocessItemInProgress},
+		{name: "still processing", knowledge: &types.Knowledge{ParseStatus: types.ParseStatusProcessing}, wantStatus: types.ReprocessItemInProgress},
+		{
+			name:       "being deleted",
+			knowledge:  &types.Knowledge{ParseStatus: types.ParseStatusDeleting},
+			wantStatus: types.ReprocessItemSkipped,
+			wantError:  "knowledge is being deleted",
+		},
+		{name: "deleted", wantStatus: types.ReprocessItemFailed, wantError: "knowledge disappeared during reprocessing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, errMsg := reprocessItemOutcome(tt.knowledge)
+			if status != tt.wantStatus || errMsg != tt.wantError {
+				t.Errorf("outcome = (%s, %q), want (%s, %q)", status, errMsg, tt.wantStatus, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestRefreshReprocessItems(t *testing.T) {
+	repo := &fakeKnowledgeRepo{knowledge: map[string]*types.Knowledge{
+		"done":    {ID: "done", ParseStatus: types.ParseStatusCompleted},
+		"running": {ID: "running", ParseStatus: types.ParseStatusPending},
+	}}
+	svc := &knowledgeService{repo: repo}
+	progress := &types.KnowledgeReprocessProgress{
+		Status:     types.KnowledgeReprocessStatusCompleted,
+		Total:      3,
+		Processed:  3,
+		Failed:     1,
+		InProgress: 2,
+		Items: []*types.ReprocessItemResult{
+			{KnowledgeID: "done", Status: types.ReprocessItemInProgress},
+			{KnowledgeID: "running", Status: types.ReprocessItemInProgress},
+			{KnowledgeID: "broken", Status: types.ReprocessItemFailed},
+		},
+	}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	if !svc.refreshReprocessItems(ctx, progress) {
+		t.Fatal("expected the finished item to be refreshed")
+	}
+	if progress.Items[0].Status != types.ReprocessItemSucceeded || progress.Items[1].Status != types.ReprocessItemInProgress {
+		t.Errorf("item statuses = %s, %s", progress.Items[0].Status, progress.Items[1].Status)
+	}
+	if progress.Succeeded != 1 || progress.InProgress != 1 || progress.Failed != 1 {
+		t.Errorf("counters succeeded=%d in_progress=%d failed=%d, want 1, 1, 1",
+			progress.Succeeded, progress.InProgress, progress.Failed)
+	}
+
+	repo.knowledge["running"].ParseStatus = types.ParseStatusFailed
+	repo.knowledge["running"].ErrorMessage = "timeout"
+	svc.refreshReprocessItems(ctx, progress)
+	if progress.InProgress != 0 || progress.Failed != 2 || progress.Items[1].Error != "timeout" {
+		t.Errorf("after the last item failed: in_progress=%d failed=%d error=%q",
+			progress.InProgress, progress.Failed, progress.Items[1].Error)
+	}
+	if want := "Reprocessed 3 knowledge: 1 succeeded, 2 failed, 0 skipped"; progress.Message != want {
+		t.Errorf("message = %q, want %q", progress.Message, want)
+	}
+}
+
+func TestUpdateKnowledgeEnabledBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		updates     map[string]bool
+		wantErr     bool
+		wantToggled []string
+		wantEnabled map[string]bool
+	}{
+		{name: "no updates", wantEnabled: map[string]bool{"doc-1": true, "doc-2": true, "faq": true}},
+		{
+			name:        "disable and enable",
+			updates:     map[string]bool{"doc-1": false, "doc-2": true},
+			wantToggled: []string{"doc-1", "doc-2"},
+			wantEnabled: map[string]bool{"doc-1": false, "doc-2": true, "faq": true},
+		},
+		{
+			name:        "unknown knowledge is ignored",
+			updates:     map[string]bool{"doc-1": false, "missing": false},
+			wantToggled: []string{"doc-1"},
+			wantEnabled: map[string]bool{"doc-1": false, "doc-2": true, "faq": true},
+		},
+		{
+			name:        "faq knowledge is rejected before any change",
+			updates:     map[string]bool{"doc-1": false, "faq": false},
+			wantErr:     true,
+			wantEnabled: map[string]bool{"doc-1": true, "doc-2": true, "faq": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeKnowledgeRepo{knowledge: map[string]*types.Knowledge{
+				"doc-1": {ID: "doc-1", Type: "file", IsEnabled: true},
+				"doc-2": {ID: "doc-2", Type: "file", IsEnabled: true},
+				"faq":   {ID: "faq", Type: types.KnowledgeTypeFAQ, IsEnabled: true},
+			}}
+			chunkRepo := &fakeChunkEnabledRepo{toggled: map[string]bool{}}
+			svc := &knowledgeService{repo: repo, chunkRepo: chunkRepo}
+			ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+			err := svc.UpdateKnowledgeEnabledBatch(ctx, tt.updates)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateKnowledgeEnabledBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var toggled []string
+			for id, isEnabled := range chunkRepo.toggled {
+				toggled = append(toggled, id)
+				if isEnabled != tt.updates[id] {
+					t.Errorf("chunks of %s set to %v, want %v", id, isEnabled, tt.updates[id])
+				}
+			}
+			slices.Sort(toggled)
+			if !slices.Equal(toggled, tt.wantToggled) {
+				t.Errorf("toggled chunks of %v, want %v", toggled, tt.wantToggled)
+			}
+			for id, want := range tt.wantEnabled {
+				if repo.knowledge[id].IsEnabled != want {
+					t.Errorf("%s is_enabled = %v, want %v", id, repo.knowledge[id].IsEnabled, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFAQExportCSVRow(t *testing.T) {
+	chunk := &types.Chunk{IsEnabled: false, Flags: types.ChunkFlagRecommended}
+	meta := &types.FAQChunkMetadata{
+		StandardQuestion: `How do I reset my "password"?`,
+		SimilarQuestions: []string{"I forgot my password", "Reset password, please"},
+		Answers:          []string{"Use the reset link."},
+		AnswerStrategy:   types.AnswerStrategyAll,
+	}
+	tag := &types.KnowledgeTag{Name: "Account"}
+
+	want := `Account,"How do I reset my ""password""?","I forgot my password##Reset password, please",,` +
+		"Use the reset link.,TRUE,TRUE,FALSE\n"
+	if got := faqExportCSVRow(chunk, meta, tag); got != want {
+		t.Errorf("faqExportCSVRow() = %q, want %q", got, want)
+	}
+	if got := faqExportCSVRow(chunk, meta, nil); got[0] != ',' {
+		t.Errorf("untagged row = %q, want an empty category", got)
+	}
+}
@@ -2,19 +2,26 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"maps"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Tencent/WeKnora/internal/application/service/retriever"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/embedding"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
+	"golang.org/x/sync/singleflight"
 )
 
 // ErrInvalidTenantID represents an error for invalid tenant ID
@@ -31,6 +38,12 @@ type knowledgeBaseService struct {
 	fileSvc        interfaces.FileService
 	graphEngine    interfaces.RetrieveGraphRepository
 	asynqClient    *asynq.Client
+	answerCache    interfaces.AnswerCacheService
+	agentRepo      interfaces.CustomAgentRepository
+	// searchFlight coalesces identical concurrent hybrid searches
+	searchFlight singleflight.Group
+	// chunkCounts caches the chunk count of knowledge bases with an exact search threshold
+	chunkCounts sync.Map
 }
 
 // NewKnowledgeBaseService creates a new knowledge base service
@@ -43,6 +56,8 @@ func NewKnowledgeBaseService(repo interfaces.KnowledgeBaseRepository,
 	fileSvc interfaces.FileService,
 	graphEngine interfaces.RetrieveGraphRepository,
 	asynqClient *asynq.Client,
+	answerCache interfaces.AnswerCacheService,
+	agentRepo interfaces.CustomAgentRepository,
 ) interfaces.KnowledgeBaseService {
 	return &knowledgeBaseService{
 		repo:           repo,
@@ -54,6 +69,8 @@ func NewKnowledgeBaseService(repo interfaces.KnowledgeBaseRepository,
 		fileSvc:        fileSvc,
 		graphEngine:    graphEngine,
 		asynqClient:    asynqClient,
+		answerCache:    answerCache,
+		agentRepo:      agentRepo,
 	}
 }
 
@@ -79,6 +96,9 @@ func (s *knowledgeBaseService) CreateKnowledgeBase(ctx context.Context,
 	kb.TenantID = ctx.Value(types.TenantIDContextKey).(uint64)
 	kb.UpdatedAt = time.Now()
 	kb.EnsureDefaults()
+	if err := validateVectorSpaceDimensions(ctx, s.modelService, kb.EmbeddingModelID, kb.VectorSpaceConfig); err != nil {
+		return nil, err
+	}
 
 	logger.Infof(ctx, "Creating knowledge base, ID: %s, tenant ID: %d, name: %s", kb.ID, kb.TenantID, kb.Name)
 
@@ -201,6 +221,93 @@ func (s *knowledgeBaseService) UpdateKnowledgeBase(ctx context.Context,
 	if config.FAQConfig != nil {
 		kb.FAQConfig = config.FAQConfig
 	}
+	// Update vector search config if provided; an empty config restores index defaults
+	if config.VectorSearchConfig != nil {
+		kb.VectorSearchConfig = config.VectorSearchConfig
+		if kb.VectorSearchConfig.IsZero() {
+			kb.VectorSearchConfig = nil
+		}
+	}
+	// Update near-duplicate collapsing config if provided
+	if config.DedupConfig != nil {
+		kb.DedupConfig = config.DedupConfig
+	}
+	// Update source quota if provided; a cap of 0 disables it
+	if config.SourceQuotaConfig != nil {
+		kb.SourceQuotaConfig = config.SourceQuotaConfig
+		if !kb.SourceQuotaConfig.IsEnabled() {
+			kb.SourceQuotaConfig = nil
+		}
+	}
+	// Update confidence gate config if provided
+	if config.ConfidenceGateConfig != nil {
+		kb.ConfidenceGateConfig = config.ConfidenceGateConfig
+	}
+	// Update hybrid search fusion config if provided
+	if config.FusionConfig != nil {
+		kb.FusionConfig = config.FusionConfig
+	}
+	// Update history depth if provided; HistoryDepthUnset restores the system default
+	if config.HistoryDepth != nil {
+		kb.HistoryDepth = config.HistoryDepth
+		if *kb.HistoryDepth == types.HistoryDepthUnset {
+			kb.HistoryDepth = nil
+		}
+	}
+	// Update empty knowledge base message if provided
+	if config.EmptyMessage != nil {
+		kb.EmptyMessage = *config.EmptyMessage
+	}
+	// Update vector spaces if provided; an empty config keeps only the primary space
+	var removedSpaces []vectorSpaceEmbedder
+	if config.VectorSpaceConfig != nil {
+		if err := validateVectorSpaceDimensions(ctx, s.modelService, kb.EmbeddingModelID, config.VectorSpaceConfig); err != nil {
+			return nil, err
+		}
+		removedSpaces = s.removedVectorSpaces(ctx, kb, config.VectorSpaceConfig)
+		kb.VectorSpaceConfig = config.VectorSpaceConfig
+		if len(kb.VectorSpaceConfig.Spaces) == 0 && kb.VectorSpaceConfig.Query == "" {
+			kb.VectorSpaceConfig = nil
+		}
+	}
+	// Update metadata schema if provided; existing metadata is validated on its next update only
+	if config.MetadataSchemaConfig != nil {
+		kb.MetadataSchemaConfig = config.MetadataSchemaConfig
+		if kb.MetadataSchemaConfig.IsZero() {
+			kb.MetadataSchemaConfig = nil
+		}
+	}
+	// Update document summaries if provided; summaries already generated are kept
+	if config.DocumentSummaryConfig != nil {
+		kb.DocumentSummaryConfig = config.DocumentSummaryConfig
+	}
+	// Update parent-child chunking if provided; it applies to documents chunked from now on
+	if config.ParentChildConfig != nil {
+		kb.ParentChildConfig = config.ParentChildConfig
+	}
+	// Update rerank fallback if provided
+	if config.RerankFallbackConfig != nil {
+		kb.RerankFallbackConfig = config.RerankFallbackConfig
+	}
+	// Update answer grounding if provided
+	if config.GroundingConfig != nil {
+		kb.GroundingConfig = config.GroundingConfig
+	}
+	// Update citation requirement if provided
+	if config.CitationConfig != nil {
+		kb.CitationConfig = config.CitationConfig
+	}
+	// Update pipeline stages if provided; they apply to documents processed from now on
+	if config.PipelineConfig != nil {
+		kb.PipelineConfig = config.PipelineConfig
+	}
+	// Update answer cache if provided; a TTL of 0 disables it
+	if config.AnswerCacheConfig != nil {
+		kb.AnswerCacheConfig = config.AnswerCacheConfig
+		if kb.AnswerCacheConfig.TTLSeconds == 0 {
+			kb.AnswerCacheConfig = nil
+		}
+	}
 	kb.UpdatedAt = time.Now()
 	kb.EnsureDefaults()
 
@@ -212,6 +319,11 @@ func (s *knowledgeBaseService) UpdateKnowledgeBase(ctx context.Context,
 		return nil, err
 	}
 
+	s.deleteRemovedVectorSpaces(ctx, kb, removedSpaces)
+
+	// Answers depend on the retrieval and generation settings changed above
+	invalidateAnswerCache(ctx, s.answerCache, kb.ID)
+
 	logger.Infof(ctx, "Knowledge base updated successfully, ID: %s, name: %s", kb.ID, kb.Name)
 	return kb, nil
 }
@@ -219,18 +331,29 @@ func (s *knowledgeBaseService) UpdateKnowledgeBase(ctx context.Context,
 // DeleteKnowledgeBase deletes a knowledge base by its ID
 // This method marks the knowledge base as deleted and enqueues an async task
 // to handle the heavy cleanup operations (embeddings, chunks, files, graph data)
-func (s *knowledgeBaseService) DeleteKnowledgeBase(ctx context.Context, id string) error {
+func (s *knowledgeBaseService) DeleteKnowledgeBase(ctx context.Context, id string, force bool) error {
 	if id == "" {
 		logger.Error(ctx, "Knowledge base ID is empty")
 		return errors.New("knowledge base ID cannot be empty")
 	}
 
-	logger.Infof(ctx, "Deleting knowledge base, ID: %s", id)
+	logger.Infof(ctx, "Deleting knowledge base, ID: %s, force: %v", id, force)
 
 	// Get tenant ID from context
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
 
+	// Agents configured with the knowledge base block the deletion, force removes it from them
+	if err := s.releaseAgentReferences(ctx, tenantID, id, force); err != nil {
+		return err
+	}
+
+	// Keep the vector spaces so that the cleanup task can remove their indices
+	var vectorSpaceConfig *types.VectorSpaceConfig
+	if kb, err := s.repo.GetKnowledgeBaseByID(ctx, id); err == nil {
+		vectorSpaceConfig = kb.VectorSpaceConfig
+	}
+
 	// Step 1: Delete the knowledge base record first (mark as deleted)
 	logger.Infof(ctx, "Deleting knowledge base from database")
 	err := s.repo.DeleteKnowledgeBase(ctx, id)
@@ -240,12 +363,14 @@ func (s *knowledgeBaseService) DeleteKnowledgeBase(ctx context.Context, id strin
 		})
 		return err
 	}
+	invalidateAnswerCache(ctx, s.answerCache, id)
 
 	// Step 2: Enqueue async task for heavy cleanup operations
 	payload := types.KBDeletePayload{
-		TenantID:         tenantID,
-		KnowledgeBaseID:  id,
-		EffectiveEngines: tenantInfo.GetEffectiveEngines(),
+		TenantID:          tenantID,
+		KnowledgeBaseID:   id,
+		EffectiveEngines:  tenantInfo.GetEffectiveEngines(),
+		VectorSpaceConfig: vectorSpaceConfig,
 	}
 
 	payloadBytes, err := json.Marshal(payload)
@@ -268,6 +393,39 @@ func (s *knowledgeBaseService) DeleteKnowledgeBase(ctx context.Context, id strin
 	return nil
 }
 
+// releaseAgentReferences checks the agents configured with the knowledge base. Without force they fail the
+// deletion with the list of agents, with force the knowledge base is removed from their configuration.
+func (s *knowledgeBaseService) releaseAgentReferences(ctx context.Context, tenantID uint64, id string, force bool) error {
+	agents, err := s.agentRepo.ListAgentsByTenantID(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	var refs []types.ResourceReference
+	for _, agent := range agents {
+		if types.AgentUsesKnowledgeBase(agent, id) {
+			refs = append(refs, types.ResourceReference{
+				Type: types.ReferenceTypeAgent, ID: agent.ID, Name: agent.Name, Fields: []string{"knowledge_bases"},
+			})
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	if !force {
+		logger.Warnf(ctx, "Knowledge base %s is used by %d agents", id, len(refs))
+		return types.NewResourceInUseError(werrors.CodeKnowledgeBaseInUse, "knowledge base", refs, int64(len(refs)))
+	}
+	for _, agent := range agents {
+		if types.RemoveAgentKnowledgeBase(agent, id) {
+			if err := s.agentRepo.UpdateAgent(ctx, agent); err != nil {
+				return err
+			}
+			logger.Infof(ctx, "Removed knowledge base %s from agent %s", id, agent.ID)
+		}
+	}
+	return nil
+}
+
 // ProcessKBDelete handles async knowledge base deletion task
 // This method performs heavy cleanup operations: deleting embeddings, chunks, files, and graph data
 func (s *knowledgeBaseService) ProcessKBDelete(ctx context.Context, t *asynq.Task) error {
@@ -335,6 +493,12 @@ func (s *knowledgeBaseService) ProcessKBDelete(ctx context.Context, t *asynq.Tas
 					logger.Warnf(ctx, "Failed to delete embeddings for model %s: %v", key.EmbeddingModelID, err)
 				}
 			}
+
+			// Delete embeddings of the additional vector spaces
+			deletedKB := &types.KnowledgeBase{ID: kbID, VectorSpaceConfig: payload.VectorSpaceConfig}
+			for key, knowledgeGroup := range embeddingGroups {
+				deleteVectorSpaceIndices(ctx, s.modelService, retrieveEngine, deletedKB, knowledgeGroup, key.Type)
+			}
 		}
 
 		// Delete all chunks
@@ -471,9 +635,26 @@ func (s *knowledgeBaseService) CopyKnowledgeBase(ctx context.Context,
 			ImageProcessingConfig: sourceKB.ImageProcessingConfig,
 			EmbeddingModelID:      sourceKB.EmbeddingModelID,
 			SummaryModelID:        sourceKB.SummaryModelID,
+			RerankModelID:         sourceKB.RerankModelID,
 			VLMConfig:             sourceKB.VLMConfig,
 			StorageConfig:         sourceKB.StorageConfig,
 			FAQConfig:             faqConfig,
+			VectorSearchConfig:    sourceKB.VectorSearchConfig,
+			DedupConfig:           sourceKB.DedupConfig,
+			SourceQuotaConfig:     sourceKB.SourceQuotaConfig,
+			ConfidenceGateConfig:  sourceKB.ConfidenceGateConfig,
+			FusionConfig:          sourceKB.FusionConfig,
+			HistoryDepth:          sourceKB.HistoryDepth,
+			EmptyMessage:          sourceKB.EmptyMessage,
+			VectorSpaceConfig:     sourceKB.VectorSpaceConfig,
+			MetadataSchemaConfig:  sourceKB.MetadataSchemaConfig,
+			AnswerCacheConfig:     sourceKB.AnswerCacheConfig,
+			DocumentSummaryConfig: sourceKB.DocumentSummaryConfig,
+			ParentChildConfig:     sourceKB.ParentChildConfig,
+			RerankFallbackConfig:  sourceKB.RerankFallbackConfig,
+			GroundingConfig:       sourceKB.GroundingConfig,
+			CitationConfig:        sourceKB.CitationConfig,
+			PipelineConfig:        sourceKB.PipelineConfig,
 		}
 		targetKB.EnsureDefaults()
 		if err := s.repo.CreateKnowledgeBase(ctx, targetKB); err != nil {
@@ -483,10 +664,83 @@ func (s *knowledgeBaseService) CopyKnowledgeBase(ctx context.Context,
 	return sourceKB, targetKB, nil
 }
 
-// HybridSearch performs hybrid search, including vector retrieval and keyword retrieval
+// HybridSearch performs hybrid search, including vector retrieval and keyword retrieval.
+// Identical concurrent searches of the same tenant share one computation; each caller
+// receives its own copy of the results.
 func (s *knowledgeBaseService) HybridSearch(ctx context.Context,
 	id string,
 	params types.SearchParams,
+) ([]*types.SearchResult, error) {
+	payload, err := json.Marshal(params)
+	if err != nil {
+		return s.hybridSearch(ctx, id, params)
+	}
+	tenantID, _ := ctx.Value(types.TenantIDContextKey).(uint64)
+	sum := sha256.Sum256(payload)
+	key := fmt.Sprintf("%d:%s:%s", tenantID, id, hex.EncodeToString(sum[:]))
+
+	// The shared search must not fail for every caller when the first one cancels
+	sharedCtx := context.WithoutCancel(ctx)
+	ch := s.searchFlight.DoChan(key, func() (interface{}, error) {
+		return s.hybridSearch(sharedCtx, id, params)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		results := res.Val.([]*types.SearchResult)
+		if !res.Shared {
+			return results, nil
+		}
+		logger.Infof(ctx, "Hybrid search coalesced with an identical concurrent request, knowledge base ID: %s", id)
+		return copySearchResults(results), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// filterKnowledgeByMetadata returns the IDs of the knowledge matching the metadata filter of params,
+// intersected with params.KnowledgeIDs when set
+func (s *knowledgeBaseService) filterKnowledgeByMetadata(ctx context.Context,
+	kb *types.KnowledgeBase, params types.SearchParams,
+) ([]string, error) {
+	filter, err := kb.MetadataSchemaConfig.NormalizeFilter(params.MetadataFilter)
+	if err != nil {
+		return nil, werrors.NewBadRequestError("Invalid metadata filter").WithDetails(err.Error())
+	}
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledgeIDs, err := s.kgRepo.ListIDsByMetadata(ctx, tenantID, kb.ID, filter)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to filter knowledge by metadata: %v", err)
+		return nil, err
+	}
+	if len(params.KnowledgeIDs) > 0 {
+		knowledgeIDs = slices.DeleteFunc(knowledgeIDs, func(id string) bool {
+			return !slices.Contains(params.KnowledgeIDs, id)
+		})
+	}
+	logger.Infof(ctx, "Metadata filter matched %d knowledge", len(knowledgeIDs))
+	return knowledgeIDs, nil
+}
+
+// copySearchResults copies results shared between coalesced callers, which may modify them downstream
+func copySearchResults(results []*types.SearchResult) []*types.SearchResult {
+	copied := make([]*types.SearchResult, len(results))
+	for i, result := range results {
+		c := *result
+		c.Metadata = maps.Clone(result.Metadata)
+		c.SubChunkID = slices.Clone(result.SubChunkID)
+		c.Alternates = slices.Clone(result.Alternates)
+		copied[i] = &c
+	}
+	return copied
+}
+
+// hybridSearch runs the vector and keyword retrieval and fuses the results
+func (s *knowledgeBaseService) hybridSearch(ctx context.Context,
+	id string,
+	params types.SearchParams,
 ) ([]*types.SearchResult, error) {
 	logger.Infof(ctx, "Hybrid search parameters, knowledge base ID: %s, query text: %s", id, params.QueryText)
 
@@ -511,46 +765,68 @@ func (s *knowledgeBaseService) HybridSearch(ctx context.Context,
 		return nil, err
 	}
 
+	if kb.Type == types.KnowledgeBaseTypeDocument && kb.DocumentSummaryConfig.UseSummaryFirst(params.SummaryFirst) {
+		return s.summaryFirstSearch(ctx, kb, params)
+	}
+
+	// Restrict the search to knowledge whose metadata matches the filter
+	if len(params.MetadataFilter) > 0 {
+		knowledgeIDs, err := s.filterKnowledgeByMetadata(ctx, kb, params)
+		if err != nil {
+			return nil, err
+		}
+		if len(knowledgeIDs) == 0 {
+			logger.Info(ctx, "No knowledge matches the metadata filter")
+			return []*types.SearchResult{}, nil
+		}
+		params.KnowledgeIDs = knowledgeIDs
+	}
+
 	matchCount := params.MatchCount * 3
 
 	// Add vector retrieval params if supported
 	if retrieveEngine.SupportRetriever(types.VectorRetrieverType) && !params.DisableVectorMatch {
 		logger.Info(ctx, "Vector retrieval supported, preparing vector retrieval parameters")
 
-		logger.Infof(ctx, "Getting embedding model, model ID: %s", kb.EmbeddingModelID)
-		embeddingModel, err = s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to get embedding model, model ID: %s, error: %v", kb.EmbeddingModelID, err)
-			return nil, err
-		}
-		logger.Infof(ctx, "Embedding model retrieved: %v", embeddingModel)
+		vectorSearch := s.resolveVectorSearch(ctx, kb, params)
+		for _, space := range kb.VectorSpaceConfig.QuerySpaces() {
+			modelID := kb.VectorSpaceConfig.SpaceModelID(space, kb.EmbeddingModelID)
+			logger.Infof(ctx, "Getting embedding model of vector space %s, model ID: %s", space, modelID)
+			embeddingModel, err = s.modelService.GetEmbeddingModel(ctx, modelID)
+			if err != nil {
+				logger.Errorf(ctx, "Failed to get embedding model, model ID: %s, error: %v", modelID, err)
+				return nil, err
+			}
+			logger.Infof(ctx, "Embedding model retrieved: %v", embeddingModel)
 
-		// Generate embedding vector for the query text
-		logger.Info(ctx, "Starting to generate query embedding")
-		queryEmbedding, err := embeddingModel.Embed(ctx, params.QueryText)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to embed query text, query text: %s, error: %v", params.QueryText, err)
-			return nil, err
-		}
-		logger.Infof(ctx, "Query embedding generated successfully, embedding vector length: %d", len(queryEmbedding))
+			// Generate embedding vector for the query text
+			logger.Info(ctx, "Starting to generate query embedding")
+			queryEmbedding, err := embeddingModel.Embed(ctx, params.QueryText)
+			if err != nil {
+				logger.Errorf(ctx, "Failed to embed query text, query text: %s, error: %v", params.QueryText, err)
+				return nil, err
+			}
+			logger.Infof(ctx, "Query embedding generated successfully, embedding vector length: %d", len(queryEmbedding))
+
+			vectorParams := types.RetrieveParams{
+				Query:            params.QueryText,
+				Embedding:        queryEmbedding,
+				KnowledgeBaseIDs: []string{id},
+				TopK:             matchCount,
+				Threshold:        params.VectorThreshold,
+				RetrieverType:    types.VectorRetrieverType,
+				KnowledgeIDs:     params.KnowledgeIDs,
+				TagIDs:           params.TagIDs,
+				VectorSearch:     vectorSearch,
+			}
 
-		vectorParams := types.RetrieveParams{
-			Query:            params.QueryText,
-			Embedding:        queryEmbedding,
-			KnowledgeBaseIDs: []string{id},
-			TopK:             matchCount,
-			Threshold:        params.VectorThreshold,
-			RetrieverType:    types.VectorRetrieverType,
-			KnowledgeIDs:     params.KnowledgeIDs,
-			TagIDs:           params.TagIDs,
-		}
+			// For FAQ knowledge base, use FAQ index
+			if kb.Type == types.KnowledgeBaseTypeFAQ {
+				vectorParams.KnowledgeType = types.KnowledgeTypeFAQ
+			}
 
-		// For FAQ knowledge base, use FAQ index
-		if kb.Type == types.KnowledgeBaseTypeFAQ {
-			vectorParams.KnowledgeType = types.KnowledgeTypeFAQ
+			retrieveParams = append(retrieveParams, vectorParams)
 		}
-
-		retrieveParams = append(retrieveParams, vectorParams)
 		logger.Info(ctx, "Vector retrieval parameters setup completed")
 	}
 
@@ -589,9 +865,10 @@ func (s *knowledgeBaseService) HybridSearch(ctx context.Context,
 	// Collect all results from different retrievers and deduplicate by chunk ID
 	logger.Infof(ctx, "Processing retrieval results")
 
-	// Separate results by retriever type for RRF fusion
+	// Separate results by retriever type for fusion
 	var vectorResults []*types.IndexWithScore
 	var keywordResults []*types.IndexWithScore
+	var vectorSpaceResults [][]*types.IndexWithScore
 	for _, retrieveResult := range retrieveResults {
 		logger.Infof(ctx, "Retrieval results, engine: %v, retriever: %v, count: %v",
 			retrieveResult.RetrieverEngineType,
@@ -600,19 +877,26 @@ func (s *knowledgeBaseService) HybridSearch(ctx context.Context,
 		)
 		if retrieveResult.RetrieverType == types.VectorRetrieverType {
 			vectorResults = append(vectorResults, retrieveResult.Results...)
+			vectorSpaceResults = append(vectorSpaceResults, retrieveResult.Results)
 		} else {
 			keywordResults = append(keywordResults, retrieveResult.Results...)
 		}
 	}
+	// Scores of different embedding models are not comparable, so spaces are merged by rank
+	if len(vectorSpaceResults) > 1 {
+		vectorResults = types.FuseVectorSpaces(vectorSpaceResults, 0)
+		logger.Infof(ctx, "Fused vector results of %d vector spaces: %d", len(vectorSpaceResults), len(vectorResults))
+	}
 
 	// Early return if no results
 	if len(vectorResults) == 0 && len(keywordResults) == 0 {
 		logger.Info(ctx, "No search results found")
-		return nil, nil
+		return s.applyPinnedSources(ctx, kb, params, nil), nil
 	}
 	logger.Infof(ctx, "Result count before fusion: vector=%d, keyword=%d", len(vectorResults), len(keywordResults))
 
 	var deduplicatedChunks []*types.IndexWithScore
+	var fusionTraces map[string]*types.FusionTrace
 
 	// If only vector results (no keyword results), keep original embedding scores
 	// This is important for FAQ search which only uses vector retrieval
@@ -639,80 +923,23 @@ func (s *knowledgeBaseService) HybridSearch(ctx context.Context,
 		})
 		logger.Infof(ctx, "Result count after deduplication: %d", len(deduplicatedChunks))
 	} else {
-		// Use RRF (Reciprocal Rank Fusion) to merge results from multiple retrievers
-		// RRF score = sum(1 / (k + rank)) for each retriever where the chunk appears
-		// k=60 is a common choice that works well in practice
-		const rrfK = 60
-
-		// Build rank maps for each retriever (already sorted by score from retriever)
-		vectorRanks := make(map[string]int)
-		for i, r := range vectorResults {
-			if _, exists := vectorRanks[r.ChunkID]; !exists {
-				vectorRanks[r.ChunkID] = i + 1 // 1-indexed rank
-			}
-		}
-		keywordRanks := make(map[string]int)
-		for i, r := range keywordResults {
-			if _, exists := keywordRanks[r.ChunkID]; !exists {
-				keywordRanks[r.ChunkID] = i + 1 // 1-indexed rank
-			}
-		}
-
-		// Collect all unique chunks and compute RRF scores
-		// Keep the highest score for each chunk from each retriever
-		chunkInfoMap := make(map[string]*types.IndexWithScore)
-		rrfScores := make(map[string]float64)
-
-		// Process vector results - keep highest score per chunk
-		for _, r := range vectorResults {
-			if existing, exists := chunkInfoMap[r.ChunkID]; !exists || r.Score > existing.Score {
-				chunkInfoMap[r.ChunkID] = r
-			}
-		}
-		// Process keyword results - only add if not already from vector
-		for _, r := range keywordResults {
-			if _, exists := chunkInfoMap[r.ChunkID]; !exists {
-				chunkInfoMap[r.ChunkID] = r
-			}
-		}
-
-		// Compute RRF scores
-		for chunkID := range chunkInfoMap {
-			rrfScore := 0.0
-			if rank, ok := vectorRanks[chunkID]; ok {
-				rrfScore += 1.0 / float64(rrfK+rank)
-			}
-			if rank, ok := keywordRanks[chunkID]; ok {
-				rrfScore += 1.0 / float64(rrfK+rank)
-			}
-			rrfScores[chunkID] = rrfScore
+		// Merge results from multiple retrievers with the configured fusion algorithm
+		fusionConfig := kb.FusionConfig
+		if params.Fusion != nil {
+			fusionConfig = params.Fusion
 		}
+		resolved := fusionConfig.Resolved()
+		deduplicatedChunks, fusionTraces = fusionConfig.Fuse(vectorResults, keywordResults)
 
-		// Convert to slice and sort by RRF score
-		deduplicatedChunks = make([]*types.IndexWithScore, 0, len(chunkInfoMap))
-		for chunkID, info := range chunkInfoMap {
-			// Store RRF score in the Score field for downstream processing
-			info.Score = rrfScores[chunkID]
-			deduplicatedChunks = append(deduplicatedChunks, info)
-		}
-		slices.SortFunc(deduplicatedChunks, func(a, b *types.IndexWithScore) int {
-			if a.Score > b.Score {
-				return -1
-			} else if a.Score < b.Score {
-				return 1
-			}
-			return 0
-		})
-
-		logger.Infof(ctx, "Result count after RRF fusion: %d", len(deduplicatedChunks))
+		logger.Infof(ctx, "Result count after %s fusion: %d (rrf_k=%d, vector_weight=%.2f, keyword_weight=%.2f)",
+			resolved.Algorithm, len(deduplicatedChunks), resolved.RRFK, resolved.VectorWeight, resolved.KeywordWeight)
 
-		// Log top results after RRF fusion for debugging
+		// Log top results after fusion for debugging
 		for i, chunk := range deduplicatedChunks {
 			if i < 15 {
-				vRank, vOk := vectorRanks[chunk.ChunkID]
-				kRank, kOk := keywordRanks[chunk.ChunkID]
-				logger.Debugf(ctx, "RRF rank %d: chunk_id=%s, rrf_score=%.6f, vector_rank=%v(%v), keyword_rank=%v(%v)",
-					i, chunk.ChunkID, chunk.Score, vRank, vOk, kRank, kOk)
+				trace := fusionTraces[chunk.ChunkID]
+				logger.Debugf(ctx, "Fusion rank %d: chunk_id=%s, fused_score=%.6f, vector_rank=%d(%.4f), keyword_rank=%d(%.4f)",
+					i, chunk.ChunkID, chunk.Score, trace.VectorRank, trace.VectorScore, trace.KeywordRank, trace.KeywordScore)
 			}
 		}
 	}
@@ -739,12 +966,139 @@ func (s *knowledgeBaseService) HybridSearch(ctx context.Context,
 		logger.Infof(ctx, "Result count after negative question filtering: %d", len(deduplicatedChunks))
 	}
 
+	// Cap the chunks of each knowledge item so that the results draw from more documents; FAQ entries
+	// share their knowledge item and are not capped
+	sourceQuota := kb.SourceQuotaConfig
+	if params.SourceQuota != nil {
+		sourceQuota = params.SourceQuota
+	}
+	var quotaDropped map[string][]*types.QuotaDroppedChunk
+	if sourceQuota.IsEnabled() && kb.Type != types.KnowledgeBaseTypeFAQ {
+		deduplicatedChunks, quotaDropped = sourceQuota.Apply(deduplicatedChunks, params.MatchCount)
+		logger.Infof(ctx, "Result count after source quota of %d chunks per knowledge: %d, dropped from %d knowledge",
+			sourceQuota.MaxChunksPerKnowledge, len(deduplicatedChunks), len(quotaDropped))
+	}
+
 	// Limit to MatchCount
 	if len(deduplicatedChunks) > params.MatchCount {
 		deduplicatedChunks = deduplicatedChunks[:params.MatchCount]
 	}
 
-	return s.processSearchResults(ctx, deduplicatedChunks)
+	results, err := s.processSearchResults(ctx, deduplicatedChunks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collapse near-duplicate chunks coming from different knowledge items
+	dedupConfig := kb.DedupConfig
+	if params.Dedup != nil {
+		dedupConfig = params.Dedup
+	}
+	if dedupConfig.IsEnabled() {
+		results = s.collapseNearDuplicates(ctx, results, dedupConfig.Threshold())
+	}
+	// Pass parent chunks on instead of their matched children, or leave them out
+	if kb.ParentChildConfig.ResolveRetrievalMode(params.RetrievalMode) == types.RetrievalModeParent {
+		results = types.ExpandToParents(results)
+	} else {
+		results = types.DropParents(results)
+	}
+	// In debug mode report which fusion algorithm and parameters produced the ranking
+	if params.Debug {
+		for _, result := range results {
+			if trace, ok := fusionTraces[result.ID]; ok {
+				result.Fusion = trace
+			} else if fusionTraces == nil {
+				result.Fusion = &types.FusionTrace{Algorithm: types.FusionAlgorithmNone, FusedScore: result.Score}
+			}
+		}
+		markSourceQuotaDropped(results, sourceQuota, quotaDropped)
+	}
+	// Report which vector search mode produced the results of vector retrieval
+	for _, rp := range retrieveParams {
+		if rp.RetrieverType == types.VectorRetrieverType {
+			markVectorSearchMode(results, rp.VectorSearch.Mode())
+			break
+		}
+	}
+	return s.applyPinnedSources(ctx, kb, params, results), nil
+}
+
+// markSourceQuotaDropped reports the chunks dropped by the source quota on the highest-ranked result of
+// their knowledge item
+func markSourceQuotaDropped(results []*types.SearchResult,
+	sourceQuota *types.SourceQuotaConfig, dropped map[string][]*types.QuotaDroppedChunk,
+) {
+	for _, result := range results {
+		if chunks, ok := dropped[result.KnowledgeID]; ok {
+			result.SourceQuota = &types.SourceQuotaTrace{
+				MaxChunksPerKnowledge: sourceQuota.MaxChunksPerKnowledge,
+				Dropped:               chunks,
+			}
+			delete(dropped, result.KnowledgeID)
+		}
+	}
+}
+
+// markVectorSearchMode sets the vector search mode on the results matched by vector retrieval;
+// keyword matches and chunks added around them were not produced by a vector search
+func markVectorSearchMode(results []*types.SearchResult, mode string) {
+	for _, result := range results {
+		if result.MatchType == types.MatchTypeEmbedding {
+			result.VectorSearchMode = mode
+		}
+	}
+}
+
+// resolveVectorSearch merges the knowledge base and request vector search parameters and decides
+// between ANN and exact search. Exact search is used when requested, configured on the knowledge
+// base, or when the knowledge base is smaller than its exact_threshold.
+func (s *knowledgeBaseService) resolveVectorSearch(ctx context.Context,
+	kb *types.KnowledgeBase,
+	params types.SearchParams,
+) *types.VectorSearchConfig {
+	vectorSearch := kb.VectorSearchConfig.Merge(params.VectorSearch)
+	exact := params.Exact || vectorSearch.Mode() == types.VectorSearchModeExact
+	if !exact && kb.VectorSearchConfig != nil && kb.VectorSearchConfig.ExactThreshold > 0 {
+		chunkCount, err := s.countChunksForThreshold(ctx, kb)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to count chunks for exact search threshold, using ANN index: %v", err)
+		} else if chunkCount < int64(kb.VectorSearchConfig.ExactThreshold) {
+			logger.Infof(ctx, "Knowledge base %s has %d chunks (threshold %d), using exact vector search",
+				kb.ID, chunkCount, kb.VectorSearchConfig.ExactThreshold)
+			exact = true
+		}
+	}
+	if exact {
+		return vectorSearch.Merge(&types.VectorSearchConfig{Exact: true})
+	}
+	return vectorSearch
+}
+
+// chunkCountCacheTTL is how long the chunk count of a knowledge base is reused for the exact search threshold.
+// The threshold only needs the approximate size, so a count that lags behind ingestion is fine.
+const chunkCountCacheTTL = time.Minute
+
+// chunkCountEntry is a cached chunk count of a knowledge base
+type chunkCountEntry struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// countChunksForThreshold returns the chunk count of the knowledge base, counting it at most once
+// per chunkCountCacheTTL so that searches do not run a COUNT query each time
+func (s *knowledgeBaseService) countChunksForThreshold(ctx context.Context, kb *types.KnowledgeBase) (int64, error) {
+	if cached, ok := s.chunkCounts.Load(kb.ID); ok {
+		if entry := cached.(chunkCountEntry); time.Now().Before(entry.expiresAt) {
+			return entry.count, nil
+		}
+	}
+	count, err := s.chunkRepo.CountChunksByKnowledgeBaseID(ctx, kb.TenantID, kb.ID)
+	if err != nil {
+		return 0, err
+	}
+	s.chunkCounts.Store(kb.ID, chunkCountEntry{count: count, expiresAt: time.Now().Add(chunkCountCacheTTL)})
+	return count, nil
 }
 
 // iterativeRetrieveWithDeduplication performs iterative retrieval until enough unique chunks are found
@@ -1190,6 +1544,7 @@ func (s *knowledgeBaseService) buildSearchResult(chunk *types.Chunk,
 		KnowledgeFilename: knowledge.FileName,
 		KnowledgeSource:   knowledge.Source,
 		ChunkMetadata:     chunk.Metadata,
+		SectionPath:       chunk.SectionPath(),
 		MatchedContent:    matchedContent,
 	}
 }
@@ -1199,7 +1554,7 @@ func (s *knowledgeBaseService) isValidTextChunk(chunk *types.Chunk) bool {
 	return slices.Contains([]types.ChunkType{
 		types.ChunkTypeText, types.ChunkTypeSummary,
 		types.ChunkTypeTableColumn, types.ChunkTypeTableSummary,
-		types.ChunkTypeFAQ,
+		types.ChunkTypeFAQ, types.ChunkTypeParent,
 	}, chunk.ChunkType)
 }
 
@@ -0,0 +1,721 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/application/service/retriever"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/hibiken/asynq"
+)
+
+const (
+	kbImportChunkBatch = 100
+	// kbArchiveHeaderLimit caps the manifest and knowledge base entries read when an import is requested
+	kbArchiveHeaderLimit = 1 << 20
+)
+
+// faqExportEntry is an FAQ entry in the faq.jsonl file of a knowledge base
+type faqExportEntry struct {
+	ID          string `json:"id"`
+	SeqID       int64  `json:"seq_id"`
+	KnowledgeID string `json:"knowledge_id"`
+	TagID       string `json:"tag_id"`
+	IsEnabled   bool   `json:"is_enabled"`
+	*types.FAQChunkMetadata
+}
+
+// writeKnowledgeEntries exports the knowledge of a knowledge base with its chunks, FAQ entries and original
+// files. It is shared by tenant exports and knowledge base archives.
+func writeKnowledgeEntries(ctx context.Context,
+	knowledgeRepo interfaces.KnowledgeRepository,
+	chunkRepo interfaces.ChunkRepository,
+	fileSvc interfaces.FileService,
+	kb *types.KnowledgeBase,
+	archive *exportArchive,
+) error {
+	knowledgeList, err := knowledgeRepo.ListKnowledgeByKnowledgeBaseID(ctx, kb.TenantID, kb.ID)
+	if err != nil {
+		return err
+	}
+	spool, err := newExportSpool()
+	if err != nil {
+		return err
+	}
+	defer spool.close()
+	for _, knowledge := range knowledgeList {
+		if err := spool.encode(knowledge); err != nil {
+			return err
+		}
+	}
+	if err := archive.addSpool(types.KBArchiveEntryKnowledge, spool, "knowledge"); err != nil {
+		return err
+	}
+
+	faq, err := newExportSpool()
+	if err != nil {
+		return err
+	}
+	defer faq.close()
+	for _, knowledge := range knowledgeList {
+		chunks, err := chunkRepo.ListChunksByKnowledgeID(ctx, kb.TenantID, knowledge.ID)
+		if err != nil {
+			return err
+		}
+		spool, err := newExportSpool()
+		if err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			if err = spool.encode(chunk); err != nil {
+				break
+			}
+			if chunk.ChunkType != types.ChunkTypeFAQ {
+				continue
+			}
+			meta, metaErr := chunk.FAQMetadata()
+			if metaErr != nil || meta == nil {
+				continue
+			}
+			if err = faq.encode(faqExportEntry{
+				ID:               chunk.ID,
+				SeqID:            chunk.SeqID,
+				KnowledgeID:      chunk.KnowledgeID,
+				TagID:            chunk.TagID,
+				IsEnabled:        chunk.IsEnabled,
+				FAQChunkMetadata: meta,
+			}); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = archive.addSpool(path.Join(types.KBArchiveDirChunks, knowledge.ID+".jsonl"), spool, "chunks")
+		}
+		spool.close()
+		if err != nil {
+			return err
+		}
+
+		if knowledge.FilePath != "" {
+			if err := exportKnowledgeFile(ctx, fileSvc, knowledge, archive); err != nil {
+				return err
+			}
+		}
+	}
+	if faq.count > 0 {
+		return archive.addSpool(types.KBArchiveEntryFAQ, faq, "faq_entries")
+	}
+	return nil
+}
+
+// exportKnowledgeFile adds the original file of the knowledge. Files missing from storage are counted
+// as missing_files instead of failing the export.
+func exportKnowledgeFile(ctx context.Context,
+	fileSvc interfaces.FileService, knowledge *types.Knowledge, archive *exportArchive,
+) error {
+	reader, err := fileSvc.GetFile(ctx, knowledge.FilePath)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to open file of knowledge %s: %v", knowledge.ID, err)
+		archive.records["missing_files"]++
+		return nil
+	}
+	defer reader.Close()
+
+	spool, err := newExportSpool()
+	if err != nil {
+		return err
+	}
+	defer spool.close()
+	if _, err := io.Copy(spool.file, reader); err != nil {
+		return fmt.Errorf("read file of knowledge %s: %w", knowledge.ID, err)
+	}
+	return archive.addSpool(path.Join(types.KBArchiveDirFiles, knowledge.ID, archiveFileName(knowledge)), spool, "files")
+}
+
+// archiveFileName returns the base name of the original file of the knowledge
+func archiveFileName(knowledge *types.Knowledge) string {
+	name := path.Base(strings.ReplaceAll(knowledge.FileName, "\\", "/"))
+	if name == "" || name == "." || name == "/" {
+		name = knowledge.ID
+	}
+	return name
+}
+
+// ExportKnowledgeBase streams an archive of the knowledge base to w: a gzip-compressed tar archive with the
+// manifest, the knowledge base, its tags, knowledge, chunks, FAQ entries and original files. Entries are
+// spooled to temporary files one at a time, so the archive is never held in memory.
+func (s *knowledgeService) ExportKnowledgeBase(ctx context.Context, kbID string, w io.Writer) error {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil || kb.TenantID != tenantID {
+		return werrors.NewNotFoundError("Knowledge base not found")
+	}
+
+	manifest := types.NewKBArchiveManifest(kb, time.Now().Unix())
+	knowledgeCount, err := s.repo.CountKnowledgeByKnowledgeBaseID(ctx, tenantID, kbID)
+	if err != nil {
+		return err
+	}
+	chunkCount, err := s.chunkRepo.CountChunksByKnowledgeBaseID(ctx, tenantID, kbID)
+	if err != nil {
+		return err
+	}
+	manifest.Records["knowledge"] = int(knowledgeCount)
+	manifest.Records["chunks"] = int(chunkCount)
+
+	gz := gzip.NewWriter(w)
+	archive := &exportArchive{tw: tar.NewWriter(gz), records: make(map[string]int)}
+	tags, err := newExportSpool()
+	if err != nil {
+		return err
+	}
+	defer tags.close()
+	for page := 1; ; page++ {
+		batch, _, err := s.tagRepo.ListByKB(ctx, tenantID, kbID, &types.Pagination{Page: page, PageSize: 100}, "")
+		if err != nil {
+			return err
+		}
+		for _, tag := range batch {
+			if err := tags.encode(tag); err != nil {
+				return err
+			}
+		}
+		if len(batch) < 100 {
+			break
+		}
+	}
+	manifest.Records["tags"] = tags.count
+
+	redacted := *kb
+	redacted.RedactSecrets()
+	if err := archive.addJSON(types.KBArchiveEntryManifest, manifest); err != nil {
+		return err
+	}
+	if err := archive.addJSON(types.KBArchiveEntryKnowledgeBase, &redacted); err != nil {
+		return err
+	}
+	if err := archive.addSpool(types.KBArchiveEntryTags, tags, "tags"); err != nil {
+		return err
+	}
+	if err := writeKnowledgeEntries(ctx, s.repo, s.chunkRepo, s.fileSvc, kb, archive); err != nil {
+		return err
+	}
+	if err := archive.tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	logger.Infof(ctx, "Exported knowledge base %s: %v", kbID, archive.records)
+	return nil
+}
+
+// readKBArchiveHeader reads the manifest and the knowledge base at the start of a knowledge base archive
+func readKBArchiveHeader(r io.Reader) (*types.KBArchiveManifest, *types.KnowledgeBase, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a gzip-compressed archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	var manifest types.KBArchiveManifest
+	if err := readKBArchiveEntry(tr, types.KBArchiveEntryManifest, &manifest); err != nil {
+		return nil, nil, err
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, nil, err
+	}
+	var kb types.KnowledgeBase
+	if err := readKBArchiveEntry(tr, types.KBArchiveEntryKnowledgeBase, &kb); err != nil {
+		return nil, nil, err
+	}
+	return &manifest, &kb, nil
+}
+
+// readKBArchiveEntry decodes the next entry of the archive, which must have the name
+func readKBArchiveEntry(tr *tar.Reader, name string, v interface{}) error {
+	header, err := tr.Next()
+	if err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
+	}
+	if header.Name != name {
+		return fmt.Errorf("expected %s, found %s", name, header.Name)
+	}
+	if err := json.NewDecoder(io.LimitReader(tr, kbArchiveHeaderLimit)).Decode(v); err != nil {
+		return fmt.Errorf("decode %s: %w", name, err)
+	}
+	return nil
+}
+
+// ImportKnowledgeBase checks a knowledge base archive, stores it and queues its import into a new knowledge base
+// of the tenant. The import is tracked like a knowledge base copy.
+func (s *knowledgeService) ImportKnowledgeBase(ctx context.Context,
+	archive io.ReadSeeker, req *types.KBImportRequest,
+) (*types.KBCloneProgress, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	manifest, kb, err := readKBArchiveHeader(archive)
+	if err != nil {
+		return nil, werrors.NewBadRequestError("Invalid knowledge base archive").WithDetails(err.Error())
+	}
+	if req.Name != "" {
+		kb.Name = req.Name
+	}
+	if err := s.resolveImportModels(ctx, kb, req); err != nil {
+		return nil, err
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	taskID := secutils.GenerateTaskID("kb_import", tenantID, manifest.KnowledgeBaseID)
+	archivePath, err := s.fileSvc.SaveStream(ctx, archive, tenantID, "kb_import_"+taskID, "archive.tar.gz")
+	if err != nil {
+		logger.Errorf(ctx, "Failed to save knowledge base archive: %v", err)
+		return nil, err
+	}
+	// The new knowledge base gets its ID up front, so that the caller can follow it
+	kb.ID = types.KBImportID(taskID, manifest.KnowledgeBaseID)
+
+	now := time.Now().Unix()
+	progress := &types.KBCloneProgress{
+		TaskID:    taskID,
+		SourceID:  manifest.KnowledgeBaseID,
+		TargetID:  kb.ID,
+		Status:    types.KBCloneStatusPending,
+		Total:     manifest.Records["chunks"],
+		Message:   "Task queued, waiting to start...",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	requestID, _ := ctx.Value(types.RequestIDContextKey).(string)
+	payloadBytes, err := json.Marshal(types.KBImportPayload{
+		TenantID:      tenantID,
+		TaskID:        taskID,
+		RequestID:     requestID,
+		ArchivePath:   archivePath,
+		KnowledgeBase: kb,
+	})
+	if err == nil {
+		err = s.saveKBCloneProgress(ctx, progress)
+	}
+	if err == nil {
+		// A failed import is rolled back, so the task itself is not retried
+		_, err = s.task.Enqueue(asynq.NewTask(types.TypeKBImport, payloadBytes,
+			asynq.TaskID(taskID), asynq.Queue("default"), asynq.MaxRetry(0), asynq.Timeout(6*time.Hour)))
+	}
+	if err != nil {
+		logger.Errorf(ctx, "Failed to queue knowledge base import: %v", err)
+		_ = s.fileSvc.DeleteFile(ctx, archivePath)
+		return nil, err
+	}
+	logger.Infof(ctx, "Enqueued knowledge base import task %s, archived knowledge base %s",
+		taskID, manifest.KnowledgeBaseID)
+	return progress, nil
+}
+
+// resolveImportModels points the models of an archived knowledge base to models of the tenant. The embedding
+// and summary models are required; other models missing from the tenant are left unset.
+func (s *knowledgeService) resolveImportModels(ctx context.Context,
+	kb *types.KnowledgeBase, req *types.KBImportRequest,
+) error {
+	if req.EmbeddingModelID != "" {
+		kb.EmbeddingModelID = req.EmbeddingModelID
+	}
+	if req.SummaryModelID != "" {
+		kb.SummaryModelID = req.SummaryModelID
+	}
+	exists := func(modelID string) bool {
+		if modelID == "" {
+			return false
+		}
+		_, err := s.modelService.GetModelByID(ctx, modelID)
+		return err == nil
+	}
+	if !exists(kb.EmbeddingModelID) {
+		return werrors.NewBadRequestError(fmt.Sprintf(
+			"Embedding model %q of the archive does not exist, set embedding_model_id", kb.EmbeddingModelID))
+	}
+	if kb.SummaryModelID != "" && !exists(kb.SummaryModelID) {
+		return werrors.NewBadRequestError(fmt.Sprintf(
+			"Summary model %q of the archive does not exist, set summary_model_id", kb.SummaryModelID))
+	}
+	if !exists(kb.RerankModelID) {
+		kb.RerankModelID = ""
+	}
+	if kb.VLMConfig.ModelID != "" && !exists(kb.VLMConfig.ModelID) {
+		kb.VLMConfig.Enabled = false
+		kb.VLMConfig.ModelID = ""
+	}
+	if kb.VectorSpaceConfig != nil {
+		config := kb.VectorSpaceConfig
+		spaces := make([]types.VectorSpace, 0, len(config.Spaces))
+		queried := config.Query == "" || config.Query == types.PrimaryVectorSpace ||
+			config.Query == types.VectorSpaceQueryFuse
+		for _, space := range config.Spaces {
+			if exists(space.EmbeddingModelID) && space.EmbeddingModelID != kb.EmbeddingModelID {
+				spaces = append(spaces, space)
+				queried = queried || space.Name == config.Query
+			}
+		}
+		config.Spaces = spaces
+		if !queried {
+			config.Query = ""
+		}
+		if len(spaces) == 0 {
+			kb.VectorSpaceConfig = nil
+		}
+	}
+	kb.ClearRedactedSecrets()
+	kb.IsTemporary = false
+	return nil
+}
+
+// ProcessKBImport handles Asynq knowledge base import tasks.
+// The archive is read in one pass: the knowledge base, tags and knowledge are created first, then the chunks of
+// every knowledge are stored and embedded with the models of the tenant and its original file is saved. When
+// anything fails, the new knowledge base is deleted with everything imported so far.
+func (s *knowledgeService) ProcessKBImport(ctx context.Context, t *asynq.Task) error {
+	var payload types.KBImportPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "failed to unmarshal KB import payload: %v", err)
+		return nil
+	}
+
+	ctx = logger.WithRequestID(ctx, payload.RequestID)
+	ctx = context.WithValue(ctx, types.RequestIDContextKey, payload.RequestID)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get tenant: %v", err)
+		return nil
+	}
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
+	defer func() {
+		if err := s.fileSvc.DeleteFile(ctx, payload.ArchivePath); err != nil {
+			logger.Warnf(ctx, "Failed to delete knowledge base archive %s: %v", payload.ArchivePath, err)
+		}
+	}()
+
+	progress, err := s.GetKBCloneProgress(ctx, payload.TaskID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to load KB import progress: %v", err)
+		return nil
+	}
+	save := func(message string) {
+		progress.Message = message
+		progress.UpdatedAt = time.Now().Unix()
+		if progress.Total > 0 {
+			progress.Progress = min(progress.Processed*100/progress.Total, 99)
+		}
+		_ = s.saveKBCloneProgress(ctx, progress)
+	}
+	progress.Status = types.KBCloneStatusProcessing
+	save("Importing knowledge base...")
+
+	imp := &kbArchiveImport{
+		s:         s,
+		taskID:    payload.TaskID,
+		progress:  progress,
+		save:      save,
+		knowledge: make(map[string]*importedKnowledge),
+	}
+	if err := imp.run(ctx, payload); err != nil {
+		logger.Errorf(ctx, "KB import task %s failed: %v", payload.TaskID, err)
+		if imp.kb != nil {
+			if delErr := s.kbService.DeleteKnowledgeBase(ctx, imp.kb.ID, true); delErr != nil {
+				logger.Errorf(ctx, "Failed to roll back imported knowledge base %s: %v", imp.kb.ID, delErr)
+			}
+		}
+		progress.Status = types.KBCloneStatusFailed
+		progress.Error = err.Error()
+		save("Failed to import knowledge base, the partial import was removed")
+		return nil
+	}
+
+	progress.Status = types.KBCloneStatusCompleted
+	progress.Processed = progress.Total
+	progress.Progress = 100
+	save(fmt.Sprintf("Imported %d knowledge into knowledge base %s", len(imp.knowledge), imp.kb.Name))
+	logger.Infof(ctx, "KB import task %s completed: %s", payload.TaskID, progress.Message)
+	return nil
+}
+
+// importedKnowledge is a knowledge of the archive and its copy
+type importedKnowledge struct {
+	archived *types.Knowledge
+	created  *types.Knowledge
+}
+
+// kbArchiveImport is the state of an import, read from the archive entry by entry
+type kbArchiveImport struct {
+	s        *knowledgeService
+	taskID   string
+	progress *types.KBCloneProgress
+	save     func(message string)
+
+	kb             *types.KnowledgeBase
+	embeddingModel embedding.Embedder
+	retrieveEngine *retriever.CompositeRetrieveEngine
+	// knowledge maps the archived knowledge IDs to the imported knowledge
+	knowledge map[string]*importedKnowledge
+}
+
+// run imports the archive. imp.kb is set as soon as the knowledge base is created.
+func (imp *kbArchiveImport) run(ctx context.Context, payload types.KBImportPayload) error {
+	s := imp.s
+	reader, err := s.fileSvc.GetFile(ctx, payload.ArchivePath)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	defer reader.Close()
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	// The archive was checked when the import was requested, the payload carries the resolved knowledge base
+	var manifest types.KBArchiveManifest
+	if err := readKBArchiveEntry(tr, types.KBArchiveEntryManifest, &manifest); err != nil {
+		return err
+	}
+	if err := manifest.Validate(); err != nil {
+		return err
+	}
+
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	imp.retrieveEngine, err = retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+	if err != nil {
+		return err
+	}
+	imp.embeddingModel, err = s.modelService.GetEmbeddingModel(ctx, payload.KnowledgeBase.EmbeddingModelID)
+	if err != nil {
+		return fmt.Errorf("load embedding model: %w", err)
+	}
+	kb, err := s.kbService.CreateKnowledgeBase(ctx, payload.KnowledgeBase)
+	if err != nil {
+		return fmt.Errorf("create knowledge base: %w", err)
+	}
+	imp.kb = kb
+	imp.progress.TargetID = kb.ID
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		dir, rest, _ := strings.Cut(header.Name, "/")
+		switch {
+		case header.Name == types.KBArchiveEntryKnowledgeBase, header.Name == types.KBArchiveEntryFAQ:
+			// FAQ entries are imported with the chunks of the FAQ knowledge
+		case header.Name == types.KBArchiveEntryTags:
+			err = imp.importTags(ctx, tr)
+		case header.Name == types.KBArchiveEntryKnowledge:
+			err = imp.importKnowledge(ctx, tr)
+		case dir == types.KBArchiveDirChunks && strings.HasSuffix(rest, ".jsonl"):
+			err = imp.importChunks(ctx, strings.TrimSuffix(rest, ".jsonl"), tr)
+		case dir == types.KBArchiveDirFiles && strings.Contains(rest, "/"):
+			knowledgeID, name, _ := strings.Cut(rest, "/")
+			err = imp.importFile(ctx, knowledgeID, name, tr)
+		default:
+			logger.Warnf(ctx, "Skipping unknown archive entry %s", header.Name)
+		}
+		if err != nil {
+			return fmt.Errorf("import %s: %w", header.Name, err)
+		}
+	}
+	return imp.finish(ctx)
+}
+
+// importTags creates the tags of the archive in the new knowledge base
+func (imp *kbArchiveImport) importTags(ctx context.Context, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for dec.More() {
+		var tag types.KnowledgeTag
+		if err := dec.Decode(&tag); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := imp.s.tagRepo.Create(ctx, &types.KnowledgeTag{
+			ID:              types.KBImportID(imp.taskID, tag.ID),
+			TenantID:        imp.kb.TenantID,
+			KnowledgeBaseID: imp.kb.ID,
+			Name:            tag.Name,
+			Color:           tag.Color,
+			SortOrder:       tag.SortOrder,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}); err != nil {
+			return fmt.Errorf("create tag %s: %w", tag.Name, err)
+		}
+	}
+	return nil
+}
+
+// importKnowledge creates the knowledge of the archive. It stays disabled until its chunks are imported.
+func (imp *kbArchiveImport) importKnowledge(ctx context.Context, r io.Reader) error {
+	s := imp.s
+	dec := json.NewDecoder(r)
+	for dec.More() {
+		var archived types.Knowledge
+		if err := dec.Decode(&archived); err != nil {
+			return err
+		}
+		now := time.Now()
+		created := archived
+		created.ID = types.KBImportID(imp.taskID, archived.ID)
+		created.TenantID = imp.kb.TenantID
+		created.KnowledgeBaseID = imp.kb.ID
+		created.TagID = types.KBImportID(imp.taskID, archived.TagID)
+		created.EmbeddingModelID = imp.kb.EmbeddingModelID
+		created.FilePath = ""
+		created.ParseStatus = types.ParseStatusProcessing
+		created.EnableStatus = "disabled"
+		created.CreatedAt = now
+		created.UpdatedAt = now
+		created.Chunks = nil
+		if err := s.repo.CreateKnowledge(ctx, &created); err != nil {
+			return fmt.Errorf("create knowledge %s: %w", archived.ID, err)
+		}
+		if created.StorageSize > 0 {
+			if err := s.tenantRepo.AdjustStorageUsed(ctx, created.TenantID, created.StorageSize); err != nil {
+				return err
+			}
+		}
+		imp.knowledge[archived.ID] = &importedKnowledge{archived: &archived, created: &created}
+	}
+	return nil
+}
+
+// importChunks stores the chunks of a knowledge and embeds them in batches. Chunk references are mapped
+// to the new IDs; chunks only stored in the source are not embedded.
+func (imp *kbArchiveImport) importChunks(ctx context.Context, archivedKnowledgeID string, r io.Reader) error {
+	knowledge, ok := imp.knowledge[archivedKnowledgeID]
+	if !ok {
+		return fmt.Errorf("unknown knowledge %s", archivedKnowledgeID)
+	}
+	dec := json.NewDecoder(r)
+	batch := make([]*types.Chunk, 0, kbImportChunkBatch)
+	for {
+		more := dec.More()
+		if more {
+			var chunk types.Chunk
+			if err := dec.Decode(&chunk); err != nil {
+				return err
+			}
+			batch = append(batch, imp.newChunk(&chunk, knowledge.created))
+		}
+		if len(batch) == kbImportChunkBatch || (!more && len(batch) > 0) {
+			if err := imp.storeChunks(ctx, knowledge.created, batch); err != nil {
+				return err
+			}
+			imp.progress.Processed += len(batch)
+			imp.save(fmt.Sprintf("Imported %d/%d chunks", imp.progress.Processed, imp.progress.Total))
+			batch = batch[:0]
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+// newChunk returns the copy of an archived chunk in the imported knowledge
+func (imp *kbArchiveImport) newChunk(chunk *types.Chunk, knowledge *types.Knowledge) *types.Chunk {
+	now := time.Now()
+	copied := *chunk
+	copied.ID = types.KBImportID(imp.taskID, chunk.ID)
+	copied.SeqID = 0
+	copied.TenantID = knowledge.TenantID
+	copied.KnowledgeID = knowledge.ID
+	copied.KnowledgeBaseID = knowledge.KnowledgeBaseID
+	copied.TagID = types.KBImportID(imp.taskID, chunk.TagID)
+	copied.PreChunkID = types.KBImportID(imp.taskID, chunk.PreChunkID)
+	copied.NextChunkID = types.KBImportID(imp.taskID, chunk.NextChunkID)
+	copied.ParentChunkID = types.KBImportID(imp.taskID, chunk.ParentChunkID)
+	copied.CreatedAt = now
+	copied.UpdatedAt = now
+	return &copied
+}
+
+// storeChunks creates a batch of chunks and indexes them with the embedding model of the knowledge base
+func (imp *kbArchiveImport) storeChunks(ctx context.Context, knowledge *types.Knowledge, chunks []*types.Chunk) error {
+	s := imp.s
+	if err := s.chunkRepo.CreateChunks(ctx, chunks); err != nil {
+		return err
+	}
+	if imp.kb.Type == types.KnowledgeBaseTypeFAQ {
+		if err := s.indexFAQChunks(ctx, imp.kb, knowledge, chunks, imp.embeddingModel, false, false); err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			chunk.Status = int(types.ChunkStatusIndexed)
+		}
+		return s.chunkService.UpdateChunks(ctx, chunks)
+	}
+	indexed := make([]*types.Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.Status != int(types.ChunkStatusStored) {
+			indexed = append(indexed, chunk)
+		}
+	}
+	return s.reembedClonedChunks(ctx, imp.retrieveEngine, imp.embeddingModel, knowledge, indexed)
+}
+
+// importFile saves the original file of a knowledge to storage
+func (imp *kbArchiveImport) importFile(ctx context.Context, archivedKnowledgeID, name string, r io.Reader) error {
+	knowledge, ok := imp.knowledge[archivedKnowledgeID]
+	if !ok {
+		return fmt.Errorf("unknown knowledge %s", archivedKnowledgeID)
+	}
+	created := knowledge.created
+	filePath, err := imp.s.fileSvc.SaveStream(ctx, r, created.TenantID, created.ID, name)
+	if err != nil {
+		return err
+	}
+	// Saved right away, so that a rollback deletes the file with the knowledge
+	created.FilePath = filePath
+	return imp.s.repo.UpdateKnowledge(ctx, created)
+}
+
+// finish restores the status of the imported knowledge. Knowledge still processing when it was exported has
+// no complete chunks and is marked failed, so that it can be reprocessed from its file.
+func (imp *kbArchiveImport) finish(ctx context.Context) error {
+	for _, knowledge := range imp.knowledge {
+		created := knowledge.created
+		switch knowledge.archived.ParseStatus {
+		case types.ParseStatusCompleted, types.ParseStatusFailed:
+			created.ParseStatus = knowledge.archived.ParseStatus
+			created.EnableStatus = knowledge.archived.EnableStatus
+		default:
+			created.ParseStatus = types.ParseStatusFailed
+			created.ErrorMessage = "Processing had not finished when the knowledge base was exported"
+		}
+		created.UpdatedAt = time.Now()
+		if err := imp.s.repo.UpdateKnowledge(ctx, created); err != nil {
+			return fmt.Errorf("update knowledge %s: %w", created.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"math"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// collapseNearDuplicates merges search results whose contents are at least threshold similar but come
+// from different knowledge items. The highest-scored result of each group is kept and the others are
+// attached to it as alternates. Contents are compared directly, so collapsing needs no model calls.
+func (s *knowledgeBaseService) collapseNearDuplicates(ctx context.Context,
+	results []*types.SearchResult,
+	threshold float64,
+) []*types.SearchResult {
+	collapsed, collapsedCount := types.CollapseNearDuplicates(results, threshold)
+	if collapsedCount > 0 {
+		logger.Infof(ctx, "Near-duplicate collapsing (threshold %.2f): %d results, %d collapsed, %d kept",
+			threshold, len(results), collapsedCount, len(collapsed))
+	}
+	return collapsed
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, or 0 when undefined
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
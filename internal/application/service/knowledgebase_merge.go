@@ -0,0 +1,487 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/application/service/retriever"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/types"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	kbMergeProgressKeyPrefix = "kb_merge_progress:"
+	kbMergeProgressTTL       = 24 * time.Hour
+)
+
+// getKBMergeProgressKey returns the Redis key for storing KB merge progress
+func getKBMergeProgressKey(taskID string) string {
+	return kbMergeProgressKeyPrefix + taskID
+}
+
+// saveKBMergeProgress saves the KB merge progress to Redis
+func (s *knowledgeService) saveKBMergeProgress(ctx context.Context, progress *types.KBMergeProgress) error {
+	progress.UpdatedAt = time.Now().Unix()
+	if progress.Total > 0 {
+		progress.Progress = progress.Processed * 100 / progress.Total
+	}
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal KB merge progress: %w", err)
+	}
+	return s.redisClient.Set(ctx, getKBMergeProgressKey(progress.TaskID), data, kbMergeProgressTTL).Err()
+}
+
+// GetKBMergeProgress retrieves the progress of a knowledge base merge task
+func (s *knowledgeService) GetKBMergeProgress(ctx context.Context, taskID string) (*types.KBMergeProgress, error) {
+	data, err := s.redisClient.Get(ctx, getKBMergeProgressKey(taskID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, werrors.NewNotFoundError("KB merge task not found")
+		}
+		return nil, fmt.Errorf("failed to get KB merge progress from Redis: %w", err)
+	}
+
+	var progress types.KBMergeProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal KB merge progress: %w", err)
+	}
+	return &progress, nil
+}
+
+// MergeKnowledgeBases validates a merge of the source knowledge base into the target and queues it as a
+// tracked task. Both knowledge bases must belong to the tenant and be of the same type.
+func (s *knowledgeService) MergeKnowledgeBases(ctx context.Context,
+	req *types.KBMergeRequest,
+) (*types.KBMergeProgress, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	if req.SourceID == req.TargetID {
+		return nil, werrors.NewBadRequestError("source and target knowledge bases must differ")
+	}
+	switch req.TagConflict {
+	case "":
+		req.TagConflict = types.KBMergeTagConflictMerge
+	case types.KBMergeTagConflictMerge, types.KBMergeTagConflictRename:
+	default:
+		return nil, werrors.NewBadRequestError("tag_conflict must be merge or rename")
+	}
+	srcKB, err := s.kbService.GetKnowledgeBaseByID(ctx, req.SourceID)
+	if err != nil || srcKB.TenantID != tenantID {
+		return nil, werrors.NewNotFoundError("Source knowledge base not found")
+	}
+	dstKB, err := s.kbService.GetKnowledgeBaseByID(ctx, req.TargetID)
+	if err != nil || dstKB.TenantID != tenantID {
+		return nil, werrors.NewNotFoundError("Target knowledge base not found")
+	}
+	if srcKB.Type != dstKB.Type {
+		return nil, werrors.NewBadRequestError(
+			fmt.Sprintf("cannot merge a %s knowledge base into a %s knowledge base", srcKB.Type, dstKB.Type))
+	}
+
+	progress := &types.KBMergeProgress{
+		TaskID:    secutils.GenerateTaskID("kb_merge", tenantID, req.SourceID),
+		SourceID:  req.SourceID,
+		TargetID:  req.TargetID,
+		Status:    types.KBCloneStatusPending,
+		Message:   "Task queued, waiting to start...",
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := s.saveKBMergeProgress(ctx, progress); err != nil {
+		logger.Errorf(ctx, "Failed to save KB merge progress: %v", err)
+		return nil, err
+	}
+
+	requestID, _ := ctx.Value(types.RequestIDContextKey).(string)
+	payloadBytes, err := json.Marshal(types.KBMergePayload{
+		TenantID:     tenantID,
+		TaskID:       progress.TaskID,
+		SourceID:     req.SourceID,
+		TargetID:     req.TargetID,
+		DeleteSource: req.DeleteSource,
+		TagConflict:  req.TagConflict,
+		RequestID:    requestID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal KB merge payload: %w", err)
+	}
+	// Items carry their own outcome and duplicates are skipped, so the task itself is not retried
+	task := asynq.NewTask(types.TypeKBMerge, payloadBytes,
+		asynq.TaskID(progress.TaskID), asynq.Queue("default"), asynq.MaxRetry(0), asynq.Timeout(6*time.Hour))
+	info, err := s.task.Enqueue(task)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to enqueue KB merge task: %v", err)
+		return nil, err
+	}
+	logger.Infof(ctx, "Enqueued KB merge task: id=%s queue=%s source=%s target=%s",
+		info.ID, info.Queue, req.SourceID, req.TargetID)
+	return progress, nil
+}
+
+// ProcessKBMerge handles Asynq knowledge base merge tasks.
+// Knowledge (or FAQ entries) of the source whose content hash already exists in the target is skipped,
+// everything else is copied into the target and re-embedded when the embedding models differ.
+// The source is deleted afterwards if requested and nothing failed.
+func (s *knowledgeService) ProcessKBMerge(ctx context.Context, t *asynq.Task) error {
+	var payload types.KBMergePayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "failed to unmarshal KB merge payload: %v", err)
+		return nil
+	}
+
+	ctx = logger.WithRequestID(ctx, payload.RequestID)
+	ctx = context.WithValue(ctx, types.RequestIDContextKey, payload.RequestID)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get tenant: %v", err)
+		return nil
+	}
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
+
+	progress, err := s.GetKBMergeProgress(ctx, payload.TaskID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to load KB merge progress: %v", err)
+		return nil
+	}
+	fail := func(err error, message string) error {
+		logger.Errorf(ctx, "KB merge task %s failed: %s: %v", payload.TaskID, message, err)
+		progress.Status = types.KBCloneStatusFailed
+		progress.Error = err.Error()
+		progress.Message = message
+		_ = s.saveKBMergeProgress(ctx, progress)
+		return nil
+	}
+
+	srcKB, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.SourceID)
+	if err != nil {
+		return fail(err, "Failed to get source knowledge base")
+	}
+	dstKB, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.TargetID)
+	if err != nil {
+		return fail(err, "Failed to get target knowledge base")
+	}
+
+	progress.Status = types.KBCloneStatusProcessing
+	progress.Reembedded = srcKB.EmbeddingModelID != dstKB.EmbeddingModelID
+	progress.Message = "Merging tags..."
+	_ = s.saveKBMergeProgress(ctx, progress)
+
+	tagIDMapping, err := s.resolveMergeTags(ctx, srcKB, dstKB, payload.TagConflict, progress)
+	if err != nil {
+		return fail(err, "Failed to merge tags")
+	}
+
+	if srcKB.Type == types.KnowledgeBaseTypeFAQ {
+		err = s.mergeFAQKnowledgeBase(ctx, srcKB, dstKB, tagIDMapping, progress)
+	} else {
+		err = s.mergeDocumentKnowledgeBase(ctx, srcKB, dstKB, tagIDMapping, progress)
+	}
+	if err != nil {
+		return fail(err, "Failed to merge knowledge")
+	}
+	invalidateAnswerCache(ctx, s.answerCache, dstKB.ID)
+
+	progress.Message = fmt.Sprintf("Merged %d, skipped %d duplicates, %d failed",
+		progress.Merged, progress.Skipped, progress.Failed)
+	if payload.DeleteSource {
+		if progress.Failed > 0 {
+			progress.Message += "; source knowledge base kept because of failures"
+		} else if err := s.kbService.DeleteKnowledgeBase(ctx, srcKB.ID, true); err != nil {
+			logger.Errorf(ctx, "Failed to delete merged source knowledge base %s: %v", srcKB.ID, err)
+			progress.Error = err.Error()
+			progress.Message += "; failed to delete source knowledge base"
+		} else {
+			progress.SourceDeleted = true
+			progress.Message += "; source knowledge base deleted"
+		}
+	}
+
+	progress.Status = types.KBCloneStatusCompleted
+	progress.Processed = progress.Total
+	progress.Progress = 100
+	_ = s.saveKBMergeProgress(ctx, progress)
+	logger.Infof(ctx, "KB merge task %s completed: %s", payload.TaskID, progress.Message)
+	return nil
+}
+
+// resolveMergeTags maps every tag of the source knowledge base to a tag of the target. A source tag whose name
+// exists in the target is merged into it, or with the rename strategy created under a name suffixed with the
+// source knowledge base name. The untagged tag is always merged.
+func (s *knowledgeService) resolveMergeTags(ctx context.Context,
+	srcKB, dstKB *types.KnowledgeBase,
+	tagConflict string,
+	progress *types.KBMergeProgress,
+) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for page := 1; ; page++ {
+		tags, _, err := s.tagRepo.ListByKB(ctx, srcKB.TenantID, srcKB.ID,
+			&types.Pagination{Page: page, PageSize: 100}, "")
+		if err != nil {
+			return nil, err
+		}
+		if len(tags) == 0 {
+			return mapping, nil
+		}
+		for _, srcTag := range tags {
+			name := srcTag.Name
+			if existing, err := s.tagRepo.GetByName(ctx, dstKB.TenantID, dstKB.ID, name); err == nil && existing != nil {
+				if tagConflict != types.KBMergeTagConflictRename || name == types.UntaggedTagName {
+					mapping[srcTag.ID] = existing.ID
+					progress.TagsMerged++
+					continue
+				}
+				name, err = s.uniqueMergeTagName(ctx, dstKB, fmt.Sprintf("%s (%s)", srcTag.Name, srcKB.Name))
+				if err != nil {
+					return nil, err
+				}
+				progress.TagsRenamed++
+			} else {
+				progress.TagsCreated++
+			}
+
+			sortOrder := srcTag.SortOrder
+			if name == types.UntaggedTagName {
+				sortOrder = -1
+			}
+			newTag := &types.KnowledgeTag{
+				ID:              uuid.New().String(),
+				TenantID:        dstKB.TenantID,
+				KnowledgeBaseID: dstKB.ID,
+				Name:            name,
+				Color:           srcTag.Color,
+				SortOrder:       sortOrder,
+				CreatedAt:       time.Now(),
+				UpdatedAt:       time.Now(),
+			}
+			if err := s.tagRepo.Create(ctx, newTag); err != nil {
+				return nil, fmt.Errorf("create tag %s in target: %w", name, err)
+			}
+			mapping[srcTag.ID] = newTag.ID
+		}
+	}
+}
+
+// uniqueMergeTagName returns name, or name with a numeric suffix, that is not used by a tag of the knowledge base
+func (s *knowledgeService) uniqueMergeTagName(ctx context.Context, kb *types.KnowledgeBase, name string) (string, error) {
+	candidate := name
+	for i := 2; i <= 100; i++ {
+		existing, err := s.tagRepo.GetByName(ctx, kb.TenantID, kb.ID, candidate)
+		if err != nil || existing == nil {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s %d", name, i)
+	}
+	return "", fmt.Errorf("no free tag name for %s", name)
+}
+
+// mergeDocumentKnowledgeBase copies the source knowledge whose file hash is not in the target
+func (s *knowledgeService) mergeDocumentKnowledgeBase(ctx context.Context,
+	srcKB, dstKB *types.KnowledgeBase,
+	tagIDMapping map[string]string,
+	progress *types.KBMergeProgress,
+) error {
+	total, err := s.repo.CountKnowledgeByKnowledgeBaseID(ctx, srcKB.TenantID, srcKB.ID)
+	if err != nil {
+		return err
+	}
+	addKnowledge, err := s.repo.AminusB(ctx, srcKB.TenantID, srcKB.ID, dstKB.TenantID, dstKB.ID)
+	if err != nil {
+		return err
+	}
+	progress.Total = int(total)
+	progress.Skipped = progress.Total - len(addKnowledge)
+	progress.Processed = progress.Skipped
+	progress.Message = fmt.Sprintf("Merging %d knowledge, %d already in target", len(addKnowledge), progress.Skipped)
+	_ = s.saveKBMergeProgress(ctx, progress)
+
+	opts := cloneOptions{tagIDMapping: tagIDMapping, reembed: progress.Reembedded}
+	for _, knowledgeID := range addKnowledge {
+		srcKn, err := s.repo.GetKnowledgeByID(ctx, srcKB.TenantID, knowledgeID)
+		switch {
+		case err != nil:
+			logger.Errorf(ctx, "get knowledge %s: %v", knowledgeID, err)
+			progress.Failed++
+		case srcKn.ParseStatus != types.ParseStatusCompleted:
+			// Knowledge that is still processing or failed has nothing to merge yet
+			progress.Skipped++
+		default:
+			if err := s.cloneKnowledge(ctx, srcKn, dstKB, opts); err != nil {
+				logger.Errorf(ctx, "merge knowledge %s: %v", knowledgeID, err)
+				progress.Failed++
+			} else {
+				progress.Merged++
+			}
+		}
+		progress.Processed++
+		progress.Message = fmt.Sprintf("Merged %d/%d knowledge", progress.Processed, progress.Total)
+		_ = s.saveKBMergeProgress(ctx, progress)
+	}
+	return nil
+}
+
+// mergeFAQKnowledgeBase copies the FAQ entries of the source whose content hash is not in the target
+func (s *knowledgeService) mergeFAQKnowledgeBase(ctx context.Context,
+	srcKB, dstKB *types.KnowledgeBase,
+	tagIDMapping map[string]string,
+	progress *types.KBMergeProgress,
+) error {
+	srcKnowledgeList, err := s.repo.ListKnowledgeByKnowledgeBaseID(ctx, srcKB.TenantID, srcKB.ID)
+	if err != nil {
+		return err
+	}
+	if len(srcKnowledgeList) == 0 {
+		return nil
+	}
+	total, err := s.chunkRepo.CountChunksByKnowledgeBaseID(ctx, srcKB.TenantID, srcKB.ID)
+	if err != nil {
+		return err
+	}
+	chunksToAdd, _, err := s.chunkRepo.FAQChunkDiff(ctx, srcKB.TenantID, srcKB.ID, dstKB.TenantID, dstKB.ID)
+	if err != nil {
+		return err
+	}
+	progress.Total = int(total)
+	progress.Skipped = max(progress.Total-len(chunksToAdd), 0)
+	progress.Processed = progress.Skipped
+	progress.Message = fmt.Sprintf("Merging %d FAQ entries, %d already in target", len(chunksToAdd), progress.Skipped)
+	_ = s.saveKBMergeProgress(ctx, progress)
+	if len(chunksToAdd) == 0 {
+		return nil
+	}
+
+	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, dstKB.EmbeddingModelID)
+	if err != nil {
+		return err
+	}
+	dstKnowledge, err := s.getOrCreateFAQKnowledge(ctx, dstKB, srcKnowledgeList[0])
+	if err != nil {
+		return err
+	}
+
+	batch := 50
+	for i := 0; i < len(chunksToAdd); i += batch {
+		batchIDs := chunksToAdd[i:min(i+batch, len(chunksToAdd))]
+		srcChunks, err := s.chunkRepo.ListChunksByID(ctx, srcKB.TenantID, batchIDs)
+		if err == nil {
+			err = s.copyFAQChunks(ctx, dstKB, dstKnowledge, srcChunks, tagIDMapping, embeddingModel)
+		}
+		if err != nil {
+			logger.Errorf(ctx, "merge FAQ entries %v: %v", batchIDs, err)
+			progress.Failed += len(batchIDs)
+		} else {
+			progress.Merged += len(batchIDs)
+		}
+		progress.Processed += len(batchIDs)
+		progress.Message = fmt.Sprintf("Merged %d/%d FAQ entries", progress.Processed, progress.Total)
+		_ = s.saveKBMergeProgress(ctx, progress)
+	}
+	return nil
+}
+
+// copyFAQChunks creates copies of FAQ chunks in the FAQ knowledge of the target and indexes them with its
+// embedding model
+func (s *knowledgeService) copyFAQChunks(ctx context.Context,
+	dstKB *types.KnowledgeBase,
+	dstKnowledge *types.Knowledge,
+	srcChunks []*types.Chunk,
+	tagIDMapping map[string]string,
+	embeddingModel embedding.Embedder,
+) error {
+	now := time.Now()
+	newChunks := make([]*types.Chunk, 0, len(srcChunks))
+	for _, srcChunk := range srcChunks {
+		newChunks = append(newChunks, &types.Chunk{
+			ID:              uuid.New().String(),
+			TenantID:        dstKB.TenantID,
+			KnowledgeID:     dstKnowledge.ID,
+			KnowledgeBaseID: dstKB.ID,
+			TagID:           tagIDMapping[srcChunk.TagID],
+			Content:         srcChunk.Content,
+			ChunkIndex:      srcChunk.ChunkIndex,
+			IsEnabled:       srcChunk.IsEnabled,
+			Flags:           srcChunk.Flags,
+			ChunkType:       types.ChunkTypeFAQ,
+			Metadata:        srcChunk.Metadata,
+			ContentHash:     srcChunk.ContentHash,
+			ImageInfo:       srcChunk.ImageInfo,
+			Status:          int(types.ChunkStatusStored),
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		})
+	}
+	if err := s.chunkRepo.CreateChunks(ctx, newChunks); err != nil {
+		return err
+	}
+	if err := s.indexFAQChunks(ctx, dstKB, dstKnowledge, newChunks, embeddingModel, false, false); err != nil {
+		return err
+	}
+	for _, chunk := range newChunks {
+		chunk.Status = int(types.ChunkStatusIndexed)
+	}
+	if err := s.chunkService.UpdateChunks(ctx, newChunks); err != nil {
+		logger.Warnf(ctx, "Failed to update merged FAQ chunks status: %v", err)
+	}
+	return nil
+}
+
+// reembedClonedChunks indexes cloned chunks, including their generated questions, with the embedding model
+// of the target knowledge base and its additional vector spaces
+func (s *knowledgeService) reembedClonedChunks(ctx context.Context,
+	retrieveEngine *retriever.CompositeRetrieveEngine,
+	embeddingModel embedding.Embedder,
+	dst *types.Knowledge,
+	chunks []*types.Chunk,
+) error {
+	indexInfoList := make([]*types.IndexInfo, 0, len(chunks))
+	for _, chunk := range chunks {
+		// Parent chunks are only stored, their children are indexed
+		if chunk.ChunkType == types.ChunkTypeParent {
+			continue
+		}
+		indexInfoList = append(indexInfoList, &types.IndexInfo{
+			Content:         chunk.Content,
+			SourceID:        chunk.ID,
+			SourceType:      types.ChunkSourceType,
+			ChunkID:         chunk.ID,
+			KnowledgeID:     dst.ID,
+			KnowledgeBaseID: dst.KnowledgeBaseID,
+			IsEnabled:       chunk.IsEnabled,
+		})
+		meta, err := chunk.DocumentMetadata()
+		if err != nil || meta == nil {
+			continue
+		}
+		for _, question := range meta.GeneratedQuestions {
+			indexInfoList = append(indexInfoList, &types.IndexInfo{
+				Content:         question.Question,
+				SourceID:        fmt.Sprintf("%s-%s", chunk.ID, question.ID),
+				SourceType:      types.ChunkSourceType,
+				ChunkID:         chunk.ID,
+				KnowledgeID:     dst.ID,
+				KnowledgeBaseID: dst.KnowledgeBaseID,
+				IsEnabled:       chunk.IsEnabled,
+			})
+		}
+	}
+	if len(indexInfoList) == 0 {
+		return nil
+	}
+	if err := retrieveEngine.BatchIndex(ctx, embeddingModel, indexInfoList); err != nil {
+		return err
+	}
+	dstKB, err := s.kbService.GetKnowledgeBaseByID(ctx, dst.KnowledgeBaseID)
+	if err != nil {
+		return err
+	}
+	return indexVectorSpaces(ctx, s.modelService, retrieveEngine, dstKB, indexInfoList)
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeMergeTagRepo stores tags per knowledge base in memory
+type fakeMergeTagRepo struct {
+	interfaces.KnowledgeTagRepository
+	tags map[string][]*types.KnowledgeTag
+}
+
+func (r *fakeMergeTagRepo) ListByKB(
+	_ context.Context, _ uint64, kbID string, page *types.Pagination, _ string,
+) ([]*types.KnowledgeTag, int64, error) {
+	if page.Page > 1 {
+		return nil, 0, nil
+	}
+	return r.tags[kbID], int64(len(r.tags[kbID])), nil
+}
+
+func (r *fakeMergeTagRepo) GetByName(_ context.Context, _ uint64, kbID string, name string) (*types.KnowledgeTag, error) {
+	for _, tag := range r.tags[kbID] {
+		if tag.Name == name {
+			return tag, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeMergeTagRepo) Create(_ context.Context, tag *types.KnowledgeTag) error {
+	r.tags[tag.KnowledgeBaseID] = append(r.tags[tag.KnowledgeBaseID], tag)
+	return nil
+}
+
+func TestResolveMergeTags(t *testing.T) {
+	srcKB := &types.KnowledgeBase{ID: "kb-src", TenantID: 1, Name: "Support"}
+	dstKB := &types.KnowledgeBase{ID: "kb-dst", TenantID: 1, Name: "Docs"}
+
+	tests := []struct {
+		name        string
+		tagConflict string
+		want        map[string]string
+		wantCreated int
+		wantMerged  int
+		wantRenamed int
+	}{
+		{
+			name:        "merge into tags of the same name",
+			tagConflict: types.KBMergeTagConflictMerge,
+			want:        map[string]string{"Untagged": "Untagged", "Billing": "Billing", "Shipping": "Shipping"},
+			wantCreated: 1,
+			wantMerged:  2,
+		},
+		{
+			name:        "rename colliding tags",
+			tagConflict: types.KBMergeTagConflictRename,
+			want:        map[string]string{"Untagged": "Untagged", "Billing": "Billing (Support) 2", "Shipping": "Shipping"},
+			wantCreated: 1,
+			wantMerged:  1,
+			wantRenamed: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeMergeTagRepo{tags: map[string][]*types.KnowledgeTag{
+				"kb-src": {
+					{ID: "src-untagged", KnowledgeBaseID: "kb-src", Name: types.UntaggedTagName},
+					{ID: "src-billing", KnowledgeBaseID: "kb-src", Name: "Billing"},
+					{ID: "src-shipping", KnowledgeBaseID: "kb-src", Name: "Shipping"},
+				},
+				"kb-dst": {
+					{ID: "dst-untagged", KnowledgeBaseID: "kb-dst", Name: types.UntaggedTagName},
+					{ID: "dst-billing", KnowledgeBaseID: "kb-dst", Name: "Billing"},
+					{ID: "dst-billing-support", KnowledgeBaseID: "kb-dst", Name: "Billing (Support)"},
+				},
+			}}
+			svc := &knowledgeService{tagRepo: repo}
+			progress := &types.KBMergeProgress{}
+
+			mapping, err := svc.resolveMergeTags(context.Background(), srcKB, dstKB, tt.tagConflict, progress)
+			if err != nil {
+				t.Fatalf("resolveMergeTags() error = %v", err)
+			}
+			names := make(map[string]string)
+			for _, tag := range repo.tags["kb-dst"] {
+				names[tag.ID] = tag.Name
+			}
+			for _, srcTag := range repo.tags["kb-src"] {
+				if got := names[mapping[srcTag.ID]]; got != tt.want[srcTag.Name] {
+					t.Errorf("%s mapped to target tag %q, want %q", srcTag.Name, got, tt.want[srcTag.Name])
+				}
+			}
+			if progress.TagsCreated != tt.wantCreated || progress.TagsMerged != tt.wantMerged ||
+				progress.TagsRenamed != tt.wantRenamed {
+				t.Errorf("tags created=%d merged=%d renamed=%d, want %d, %d, %d", progress.TagsCreated,
+					progress.TagsMerged, progress.TagsRenamed, tt.wantCreated, tt.wantMerged, tt.wantRenamed)
+			}
+		})
+	}
+}
+
+// fakeMergeKBService serves knowledge bases by ID
+type fakeMergeKBService struct {
+	interfaces.KnowledgeBaseService
+	kbs map[string]*types.KnowledgeBase
+}
+
+func (s *fakeMergeKBService) GetKnowledgeBaseByID(_ context.Context, id string) (*types.KnowledgeBase, error) {
+	if kb, ok := s.kbs[id]; ok {
+		return kb, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func TestMergeKnowledgeBasesValidation(t *testing.T) {
+	svc := &knowledgeService{kbService: &fakeMergeKBService{kbs: map[string]*types.KnowledgeBase{
+		"kb-doc":   {ID: "kb-doc", TenantID: 1, Type: types.KnowledgeBaseTypeDocument},
+		"kb-faq":   {ID: "kb-faq", TenantID: 1, Type: types.KnowledgeBaseTypeFAQ},
+		"kb-other": {ID: "kb-other", TenantID: 2, Type: types.KnowledgeBaseTypeDocument},
+	}}}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	tests := []struct {
+		name       string
+		req        types.KBMergeRequest
+		wantStatus int
+	}{
+		{name: "same knowledge base", req: types.KBMergeRequest{SourceID: "kb-doc", TargetID: "kb-doc"}, wantStatus: http.StatusBadRequest},
+		{
+			name:       "unknown tag conflict strategy",
+			req:        types.KBMergeRequest{SourceID: "kb-doc", TargetID: "kb-faq", TagConflict: "overwrite"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{name: "source of another tenant", req: types.KBMergeRequest{SourceID: "kb-other", TargetID: "kb-doc"}, wantStatus: http.StatusNotFound},
+		{name: "missing target", req: types.KBMergeRequest{SourceID: "kb-doc", TargetID: "kb-gone"}, wantStatus: http.StatusNotFound},
+		{name: "different types", req: types.KBMergeRequest{SourceID: "kb-faq", TargetID: "kb-doc"}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.MergeKnowledgeBases(ctx, &tt.req)
+			appErr, ok := werrors.IsAppError(err)
+			if !ok || appErr.HTTPCode != tt.wantStatus {
+				t.Errorf("MergeKnowledgeBases() error = %v, want status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"golang.org/x/sync/errgroup"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// MultiHybridSearch runs the hybrid search of every knowledge base, at most concurrency at once, and merges
+// the results. The knowledge bases must all belong to the tenant, otherwise nothing is searched.
+func (s *knowledgeBaseService) MultiHybridSearch(ctx context.Context,
+	ids []string, params types.SearchParams, concurrency int,
+) ([]*types.SearchResult, error) {
+	tenantID, _ := ctx.Value(types.TenantIDContextKey).(uint64)
+	kbs, err := s.repo.GetKnowledgeBaseByIDs(ctx, ids)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge bases: %v", err)
+		return nil, err
+	}
+	owned := make(map[string]*types.KnowledgeBase, len(kbs))
+	for _, kb := range kbs {
+		if kb.TenantID == tenantID {
+			owned[kb.ID] = kb
+		}
+	}
+	var missing []string
+	for _, id := range ids {
+		if owned[id] == nil {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		// Knowledge bases of other tenants are reported as missing, not to reveal that they exist
+		return nil, werrors.NewNotFoundError("Knowledge base not found").
+			WithCode(werrors.CodeKnowledgeBaseNotFound).
+			WithDetails(map[string]interface{}{"knowledge_base_ids": missing})
+	}
+
+	perKB := make([][]*types.SearchResult, len(ids))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(concurrency, 1))
+	for i, id := range ids {
+		g.Go(func() error {
+			results, err := s.HybridSearch(gctx, id, params)
+			if err != nil {
+				logger.Errorf(gctx, "Hybrid search failed, knowledge base ID: %s, error: %v", id, err)
+				return err
+			}
+			for _, result := range results {
+				result.KnowledgeBaseID = id
+				result.KnowledgeBaseName = owned[id].Name
+			}
+			perKB[i] = results
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	merged := mergeSearchResults(perKB, params.MatchCount)
+	logger.Infof(ctx, "Hybrid search across %d knowledge bases completed, result count: %d", len(ids), len(merged))
+	return merged, nil
+}
+
+// mergeSearchResults merges the ranked results of several knowledge bases into one ranking, keeping the
+// score each knowledge base gave. Pinned results come first, then the others by score; ties keep the rank
+// within their knowledge base, then the order of the knowledge bases. limit bounds the merged results
+// when positive.
+func mergeSearchResults(perKB [][]*types.SearchResult, limit int) []*types.SearchResult {
+	type rankedResult struct {
+		result *types.SearchResult
+		kb     int
+		rank   int
+	}
+	var ranked []rankedResult
+	for kb, results := range perKB {
+		for rank, result := range results {
+			ranked = append(ranked, rankedResult{result: result, kb: kb, rank: rank})
+		}
+	}
+	slices.SortStableFunc(ranked, func(a, b rankedResult) int {
+		if pinnedA, pinnedB := a.result.Pinned != nil, b.result.Pinned != nil; pinnedA != pinnedB {
+			if pinnedA {
+				return -1
+			}
+			return 1
+		}
+		if a.result.Pinned == nil {
+			if c := cmp.Compare(b.result.Score, a.result.Score); c != 0 {
+				return c
+			}
+		}
+		if c := cmp.Compare(a.rank, b.rank); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.kb, b.kb)
+	})
+
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	merged := make([]*types.SearchResult, len(ranked))
+	for i, r := range ranked {
+		merged[i] = r.result
+	}
+	return merged
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestMergeSearchResults(t *testing.T) {
+	perKB := [][]*types.SearchResult{
+		{
+			{ID: "a1", Score: 0.9},
+			{ID: "a2", Score: 0.5},
+		},
+		{
+			{ID: "b-pinned", Score: 0.1, Pinned: &types.PinnedTrace{}},
+			{ID: "b1", Score: 0.7},
+			{ID: "b2", Score: 0.5},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{name: "all results", want: []string{"b-pinned", "a1", "b1", "a2", "b2"}},
+		{name: "limited", limit: 3, want: []string{"b-pinned", "a1", "b1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := mergeSearchResults(perKB, tt.limit)
+			if len(merged) != len(tt.want) {
+				t.Fatalf("merged %d results, want %d", len(merged), len(tt.want))
+			}
+			for i, result := range merged {
+				if result.ID != tt.want[i] {
+					t.Errorf("result %d = %s, want %s", i, result.ID, tt.want[i])
+				}
+			}
+		})
+	}
+
+	// Scores are not normalized across knowledge bases
+	if merged := mergeSearchResults(perKB, 0); merged[1].Score != 0.9 || merged[2].Score != 0.7 {
+		t.Errorf("scores = %v, %v, want the scores of their knowledge base", merged[1].Score, merged[2].Score)
+	}
+}
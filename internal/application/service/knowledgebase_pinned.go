@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/google/uuid"
+)
+
+// ListPinnedSources lists the pinned source rules of a knowledge base
+func (s *knowledgeBaseService) ListPinnedSources(ctx context.Context, kbID string) ([]*types.PinnedSourceRule, error) {
+	kb, err := s.repo.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	if kb.PinnedSources == nil {
+		return []*types.PinnedSourceRule{}, nil
+	}
+	return kb.PinnedSources, nil
+}
+
+// CreatePinnedSource adds a pinned source rule to a knowledge base
+func (s *knowledgeBaseService) CreatePinnedSource(ctx context.Context,
+	kbID string, rule *types.PinnedSourceRule,
+) (*types.PinnedSourceRule, error) {
+	kb, err := s.repo.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	if len(kb.PinnedSources) >= types.MaxPinnedSourceRules {
+		return nil, werrors.NewBadRequestError(
+			fmt.Sprintf("A knowledge base can have at most %d pinned source rules", types.MaxPinnedSourceRules))
+	}
+	if err := s.validatePinnedSource(ctx, kb, rule); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rule.ID = uuid.New().String()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	kb.PinnedSources = append(kb.PinnedSources, rule)
+	if err := s.savePinnedSources(ctx, kb); err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "Pinned source rule %s created in knowledge base %s", rule.ID, kb.ID)
+	return rule, nil
+}
+
+// UpdatePinnedSource replaces a pinned source rule of a knowledge base
+func (s *knowledgeBaseService) UpdatePinnedSource(ctx context.Context,
+	kbID string, ruleID string, rule *types.PinnedSourceRule,
+) (*types.PinnedSourceRule, error) {
+	kb, err := s.repo.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	existing := kb.PinnedSources.Find(ruleID)
+	if existing == nil {
+		return nil, werrors.NewNotFoundError("Pinned source rule not found")
+	}
+	if err := s.validatePinnedSource(ctx, kb, rule); err != nil {
+		return nil, err
+	}
+
+	rule.ID = existing.ID
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now()
+	*existing = *rule
+	if err := s.savePinnedSources(ctx, kb); err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "Pinned source rule %s updated in knowledge base %s", ruleID, kb.ID)
+	return existing, nil
+}
+
+// DeletePinnedSource removes a pinned source rule from a knowledge base
+func (s *knowledgeBaseService) DeletePinnedSource(ctx context.Context, kbID string, ruleID string) error {
+	kb, err := s.repo.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return err
+	}
+	if kb.PinnedSources.Find(ruleID) == nil {
+		return werrors.NewNotFoundError("Pinned source rule not found")
+	}
+	kb.PinnedSources = slices.DeleteFunc(kb.PinnedSources, func(rule *types.PinnedSourceRule) bool {
+		return rule.ID == ruleID
+	})
+	if err := s.savePinnedSources(ctx, kb); err != nil {
+		return err
+	}
+	logger.Infof(ctx, "Pinned source rule %s deleted from knowledge base %s", ruleID, kb.ID)
+	return nil
+}
+
+// validatePinnedSource normalizes and validates a rule and checks that its knowledge belongs to the knowledge base
+func (s *knowledgeBaseService) validatePinnedSource(ctx context.Context,
+	kb *types.KnowledgeBase, rule *types.PinnedSourceRule,
+) error {
+	rule.Normalize()
+	if err := rule.Validate(); err != nil {
+		return werrors.NewBadRequestError("Invalid pinned source rule").WithDetails(err.Error())
+	}
+	knowledges, err := s.kgRepo.GetKnowledgeBatch(ctx, kb.TenantID, rule.KnowledgeIDs)
+	if err != nil {
+		return err
+	}
+	for _, id := range rule.KnowledgeIDs {
+		found := slices.ContainsFunc(knowledges, func(k *types.Knowledge) bool {
+			return k.ID == id && k.KnowledgeBaseID == kb.ID
+		})
+		if !found {
+			return werrors.NewBadRequestError("Invalid pinned source rule").
+				WithDetails(fmt.Sprintf("knowledge %s does not belong to the knowledge base", id))
+		}
+	}
+	return nil
+}
+
+// savePinnedSources stores the rules of a knowledge base and drops its cached answers, which may have been
+// generated without the pinned knowledge
+func (s *knowledgeBaseService) savePinnedSources(ctx context.Context, kb *types.KnowledgeBase) error {
+	kb.UpdatedAt = time.Now()
+	if err := s.repo.UpdateKnowledgeBase(ctx, kb); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_base_id": kb.ID,
+		})
+		return err
+	}
+	invalidateAnswerCache(ctx, s.answerCache, kb.ID)
+	return nil
+}
+
+// matchPinnedSources returns the knowledge pinned by the enabled rules matching the query, each knowledge
+// once and in rule order. Semantic rules are skipped when the query or the patterns can't be embedded.
+func (s *knowledgeBaseService) matchPinnedSources(ctx context.Context,
+	kb *types.KnowledgeBase, params types.SearchParams,
+) []*types.PinnedKnowledge {
+	var pinned []*types.PinnedKnowledge
+	pin := func(rule *types.PinnedSourceRule, trace types.PinnedTrace) {
+		for _, id := range rule.KnowledgeIDs {
+			// Searches restricted to some knowledge only pin within it
+			if len(params.KnowledgeIDs) > 0 && !slices.Contains(params.KnowledgeIDs, id) {
+				continue
+			}
+			if slices.ContainsFunc(pinned, func(p *types.PinnedKnowledge) bool { return p.KnowledgeID == id }) {
+				continue
+			}
+			pinned = append(pinned, &types.PinnedKnowledge{KnowledgeID: id, Chunks: rule.InjectedChunks(), Trace: trace})
+		}
+	}
+
+	for _, rule := range kb.PinnedSources.Enabled(types.PinnedMatchKeyword) {
+		if pattern, ok := rule.MatchKeyword(params.QueryText); ok {
+			pin(rule, types.PinnedTrace{
+				RuleID: rule.ID, RuleName: rule.Name, MatchType: rule.MatchType, Pattern: pattern,
+			})
+		}
+	}
+
+	semanticRules := kb.PinnedSources.Enabled(types.PinnedMatchSemantic)
+	if len(semanticRules) == 0 {
+		return pinned
+	}
+	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+	if err != nil {
+		logger.Warnf(ctx, "Skipping semantic pinned sources, failed to get embedding model: %v", err)
+		return pinned
+	}
+	texts := []string{params.QueryText}
+	for _, rule := range semanticRules {
+		texts = append(texts, rule.Patterns...)
+	}
+	vectors, err := embeddingModel.BatchEmbed(ctx, texts)
+	if err != nil || len(vectors) != len(texts) {
+		logger.Warnf(ctx, "Skipping semantic pinned sources, failed to embed query and patterns: %v", err)
+		return pinned
+	}
+	offset := 1
+	for _, rule := range semanticRules {
+		best, bestPattern := -1.0, ""
+		for i, pattern := range rule.Patterns {
+			if similarity := cosineSimilarity(vectors[0], vectors[offset+i]); similarity > best {
+				best, bestPattern = similarity, pattern
+			}
+		}
+		offset += len(rule.Patterns)
+		if best >= rule.SemanticThreshold() {
+			pin(rule, types.PinnedTrace{
+				RuleID: rule.ID, RuleName: rule.Name, MatchType: rule.MatchType, Pattern: bestPattern, Similarity: best,
+			})
+		}
+	}
+	return pinned
+}
+
+// applyPinnedSources moves the results of knowledge pinned for the query to the top and injects the leading
+// chunks of pinned knowledge the search didn't find. Pinned results are kept even when they exceed the
+// match count; organic results make room for them.
+func (s *knowledgeBaseService) applyPinnedSources(ctx context.Context,
+	kb *types.KnowledgeBase, params types.SearchParams, results []*types.SearchResult,
+) []*types.SearchResult {
+	pinned := s.matchPinnedSources(ctx, kb, params)
+	if len(pinned) > 0 {
+		var missing []*types.PinnedKnowledge
+		results, missing = types.PinSearchResults(results, pinned)
+		if len(missing) > 0 {
+			injected := s.loadPinnedChunks(ctx, kb, missing)
+			results, _ = types.PinSearchResults(append(results, injected...), pinned)
+		}
+		pinnedCount := 0
+		for _, result := range results {
+			if result.Pinned != nil {
+				pinnedCount++
+			}
+		}
+		if limit := max(params.MatchCount, pinnedCount); params.MatchCount > 0 && len(results) > limit {
+			results = results[:limit]
+		}
+		logger.Infof(ctx, "Pinned sources matched %d knowledge, %d pinned results", len(pinned), pinnedCount)
+	}
+
+	// In debug mode report whether each result was pinned or organically retrieved
+	if params.Debug {
+		for _, result := range results {
+			result.Origin = types.RetrievalOriginOrganic
+			if result.Pinned != nil {
+				result.Origin = types.RetrievalOriginPinned
+			}
+		}
+	}
+	return results
+}
+
+// loadPinnedChunks builds results from the leading enabled chunks of pinned knowledge the search didn't find.
+// Knowledge that is disabled, not processed or no longer in the knowledge base is skipped.
+func (s *knowledgeBaseService) loadPinnedChunks(ctx context.Context,
+	kb *types.KnowledgeBase, missing []*types.PinnedKnowledge,
+) []*types.SearchResult {
+	knowledgeIDs := make([]string, len(missing))
+	for i, pin := range missing {
+		knowledgeIDs[i] = pin.KnowledgeID
+	}
+	knowledgeMap, err := s.fetchKnowledgeData(ctx, kb.TenantID, knowledgeIDs)
+	if err != nil {
+		logger.Warnf(ctx, "Skipping pinned chunk injection, failed to fetch knowledge: %v", err)
+		return nil
+	}
+
+	var injected []*types.SearchResult
+	for _, pin := range missing {
+		knowledge, ok := knowledgeMap[pin.KnowledgeID]
+		if !ok || knowledge.KnowledgeBaseID != kb.ID || !knowledge.IsEnabled ||
+			knowledge.ParseStatus != types.ParseStatusCompleted {
+			logger.Warnf(ctx, "Pinned knowledge %s is not available for retrieval, skipping", pin.KnowledgeID)
+			continue
+		}
+		chunks, err := s.chunkRepo.ListChunksByKnowledgeID(ctx, kb.TenantID, pin.KnowledgeID)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to list chunks of pinned knowledge %s: %v", pin.KnowledgeID, err)
+			continue
+		}
+		chunks = slices.DeleteFunc(chunks, func(chunk *types.Chunk) bool {
+			return !chunk.IsEnabled || !s.isValidTextChunk(chunk)
+		})
+		slices.SortFunc(chunks, func(a, b *types.Chunk) int { return a.ChunkIndex - b.ChunkIndex })
+		trace := pin.Trace
+		trace.Injected = true
+		for _, chunk := range chunks[:min(pin.Chunks, len(chunks))] {
+			result := s.buildSearchResult(chunk, knowledge, 1.0, types.MatchTypePinned, "")
+			pinnedTrace := trace
+			result.Pinned = &pinnedTrace
+			injected = append(injected, result)
+		}
+	}
+	return injected
+}
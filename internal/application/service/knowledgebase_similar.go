@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/application/service/retriever"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// SimilarChunks returns the chunks of the source chunk's knowledge base whose embedding is closest to the
+// source chunk's, excluding the source chunk itself. Only the primary vector space is searched.
+func (s *knowledgeBaseService) SimilarChunks(ctx context.Context,
+	source *types.Chunk, params types.SimilarChunkParams,
+) ([]*types.SearchResult, error) {
+	kb, err := s.repo.GetKnowledgeBaseByID(ctx, source.KnowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+	if err != nil {
+		logger.Errorf(ctx, "Failed to create retrieval engine: %v", err)
+		return nil, err
+	}
+	if !retrieveEngine.SupportRetriever(types.VectorRetrieverType) {
+		return nil, werrors.NewBadRequestError("Vector retrieval is not enabled for this tenant")
+	}
+
+	embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, kb.EmbeddingModelID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get embedding model, model ID: %s, error: %v", kb.EmbeddingModelID, err)
+		return nil, err
+	}
+	// Embedding the content again gives the vector the chunk was indexed with
+	embedding, err := embeddingModel.Embed(ctx, source.Content)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to embed chunk %s: %v", source.ID, err)
+		return nil, err
+	}
+
+	count := params.EffectiveCount()
+	scope := params.EffectiveScope()
+	retrieveParams := types.RetrieveParams{
+		Query:            source.Content,
+		Embedding:        embedding,
+		KnowledgeBaseIDs: []string{kb.ID},
+		ExcludeChunkIDs:  []string{source.ID},
+		// Some engines ignore the exclusions, leave room to drop excluded results afterwards
+		TopK:          (count + 1) * 3,
+		Threshold:     params.Threshold,
+		RetrieverType: types.VectorRetrieverType,
+		VectorSearch:  s.resolveVectorSearch(ctx, kb, types.SearchParams{}),
+	}
+	switch scope {
+	case types.SimilarChunkScopeDocument:
+		retrieveParams.KnowledgeIDs = []string{source.KnowledgeID}
+	case types.SimilarChunkScopeOtherDocuments:
+		retrieveParams.ExcludeKnowledgeIDs = []string{source.KnowledgeID}
+	}
+	if kb.Type == types.KnowledgeBaseTypeFAQ {
+		retrieveParams.KnowledgeType = types.KnowledgeTypeFAQ
+	}
+
+	retrieveResults, err := retrieveEngine.Retrieve(ctx, []types.RetrieveParams{retrieveParams})
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_base_id": kb.ID,
+			"chunk_id":          source.ID,
+		})
+		return nil, err
+	}
+	var candidates []*types.IndexWithScore
+	for _, retrieveResult := range retrieveResults {
+		candidates = append(candidates, retrieveResult.Results...)
+	}
+
+	similar := selectSimilarChunks(candidates, source, scope, count)
+	logger.Infof(ctx, "Similar chunks of chunk %s: %d candidates, %d returned, scope: %s",
+		source.ID, len(candidates), len(similar), scope)
+	results, err := s.processSearchResults(ctx, similar)
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = []*types.SearchResult{}
+	}
+	return results, nil
+}
+
+// selectSimilarChunks keeps the count best scored chunks within scope, once each and without the source chunk
+func selectSimilarChunks(candidates []*types.IndexWithScore,
+	source *types.Chunk, scope string, count int,
+) []*types.IndexWithScore {
+	best := make(map[string]*types.IndexWithScore)
+	for _, candidate := range candidates {
+		if candidate.ChunkID == source.ID {
+			continue
+		}
+		sameDocument := candidate.KnowledgeID == source.KnowledgeID
+		if (scope == types.SimilarChunkScopeDocument && !sameDocument) ||
+			(scope == types.SimilarChunkScopeOtherDocuments && sameDocument) {
+			continue
+		}
+		// A chunk can be indexed several times, e.g. with its generated questions
+		if existing, ok := best[candidate.ChunkID]; !ok || candidate.Score > existing.Score {
+			best[candidate.ChunkID] = candidate
+		}
+	}
+	selected := make([]*types.IndexWithScore, 0, len(best))
+	for _, candidate := range best {
+		selected = append(selected, candidate)
+	}
+	slices.SortFunc(selected, func(a, b *types.IndexWithScore) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return strings.Compare(a.ChunkID, b.ChunkID)
+		}
+	})
+	if len(selected) > count {
+		selected = selected[:count]
+	}
+	return selected
+}
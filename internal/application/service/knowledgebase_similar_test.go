@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestSelectSimilarChunks(t *testing.T) {
+	source := &types.Chunk{ID: "source", KnowledgeID: "doc-1"}
+	candidates := []*types.IndexWithScore{
+		{ChunkID: "source", KnowledgeID: "doc-1", Score: 1},
+		{ChunkID: "same-doc", KnowledgeID: "doc-1", Score: 0.9},
+		{ChunkID: "other-doc", KnowledgeID: "doc-2", Score: 0.7},
+		// The same chunk indexed again through a generated question
+		{ChunkID: "other-doc", KnowledgeID: "doc-2", Score: 0.8},
+		{ChunkID: "far", KnowledgeID: "doc-3", Score: 0.2},
+	}
+
+	tests := []struct {
+		name  string
+		scope string
+		count int
+		want  []string
+	}{
+		{name: "whole knowledge base", scope: types.SimilarChunkScopeKnowledgeBase, count: 10,
+			want: []string{"same-doc", "other-doc", "far"}},
+		{name: "limited count", scope: types.SimilarChunkScopeKnowledgeBase, count: 2,
+			want: []string{"same-doc", "other-doc"}},
+		{name: "same document", scope: types.SimilarChunkScopeDocument, count: 10, want: []string{"same-doc"}},
+		{name: "other documents", scope: types.SimilarChunkScopeOtherDocuments, count: 10,
+			want: []string{"other-doc", "far"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectSimilarChunks(candidates, source, tt.scope, tt.count)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d chunks, want %v", len(got), tt.want)
+			}
+			for i, chunk := range got {
+				if chunk.ChunkID != tt.want[i] {
+					t.Errorf("chunk %d = %s, want %s", i, chunk.ChunkID, tt.want[i])
+				}
+			}
+			if tt.scope == types.SimilarChunkScopeKnowledgeBase && got[1].Score != 0.8 {
+				t.Errorf("duplicate chunk kept score %v, want the best one", got[1].Score)
+			}
+		})
+	}
+}
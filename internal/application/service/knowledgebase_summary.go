@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// summaryFirstSearch matches the query against the document summaries of the knowledge base first, then
+// searches the chunks of the best matching documents only. Broad questions match the summary of a document
+// better than any of its chunks, which keeps whole documents from being missed by chunk-level retrieval.
+func (s *knowledgeBaseService) summaryFirstSearch(ctx context.Context,
+	kb *types.KnowledgeBase,
+	params types.SearchParams,
+) ([]*types.SearchResult, error) {
+	summaryFirst := false
+	params.SummaryFirst = &summaryFirst
+
+	// Summaries compete with the chunks of their own documents, so the summary pass looks further down
+	summaryParams := params
+	summaryParams.MatchCount = max(params.MatchCount, kb.DocumentSummaryConfig.SummaryCandidateCount())
+	summaryParams.Debug = false
+	candidates, err := s.hybridSearch(ctx, kb.ID, summaryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	knowledgeIDs := types.SummaryDocumentIDs(candidates, kb.DocumentSummaryConfig.ExpandCount())
+	if len(knowledgeIDs) == 0 {
+		// Without a matching summary, the best candidates are the results of a regular search
+		logger.Infof(ctx, "No document summary matched in knowledge base %s, using chunk results", kb.ID)
+		if len(candidates) > params.MatchCount {
+			candidates = candidates[:params.MatchCount]
+		}
+		return candidates, nil
+	}
+	logger.Infof(ctx, "Document summaries matched %d documents in knowledge base %s, searching their chunks",
+		len(knowledgeIDs), kb.ID)
+
+	params.KnowledgeIDs = knowledgeIDs
+	return s.hybridSearch(ctx, kb.ID, params)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeChunkCountRepo reports a fixed chunk count and counts the queries
+type fakeChunkCountRepo struct {
+	interfaces.ChunkRepository
+	count   int64
+	queries int
+}
+
+func (r *fakeChunkCountRepo) CountChunksByKnowledgeBaseID(context.Context, uint64, string) (int64, error) {
+	r.queries++
+	return r.count, nil
+}
+
+func TestResolveVectorSearch(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *types.VectorSearchConfig
+		params      types.SearchParams
+		chunkCount  int64
+		wantMode    string
+		wantQueries int
+	}{
+		{name: "no config", wantMode: types.VectorSearchModeApproximate},
+		{
+			name:     "requested exact",
+			params:   types.SearchParams{Exact: true},
+			wantMode: types.VectorSearchModeExact,
+		},
+		{
+			name:        "small knowledge base below threshold",
+			config:      &types.VectorSearchConfig{ExactThreshold: 1000},
+			chunkCount:  10,
+			wantMode:    types.VectorSearchModeExact,
+			wantQueries: 1,
+		},
+		{
+			name:        "large knowledge base above threshold",
+			config:      &types.VectorSearchConfig{ExactThreshold: 1000},
+			chunkCount:  5000,
+			wantMode:    types.VectorSearchModeApproximate,
+			wantQueries: 1,
+		},
+		{
+			name:     "exact config skips counting",
+			config:   &types.VectorSearchConfig{Exact: true, ExactThreshold: 1000},
+			wantMode: types.VectorSearchModeExact,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunkRepo := &fakeChunkCountRepo{count: tt.chunkCount}
+			svc := &knowledgeBaseService{chunkRepo: chunkRepo}
+			kb := &types.KnowledgeBase{ID: "kb-1", TenantID: 1, VectorSearchConfig: tt.config}
+
+			// Repeated searches reuse the cached count
+			for i := 0; i < 3; i++ {
+				if mode := svc.resolveVectorSearch(context.Background(), kb, tt.params).Mode(); mode != tt.wantMode {
+					t.Fatalf("mode = %s, want %s", mode, tt.wantMode)
+				}
+			}
+			if chunkRepo.queries != tt.wantQueries {
+				t.Errorf("counted chunks %d times, want %d", chunkRepo.queries, tt.wantQueries)
+			}
+		})
+	}
+}
+
+func TestMarkVectorSearchMode(t *testing.T) {
+	results := []*types.SearchResult{
+		{ID: "vector", MatchType: types.MatchTypeEmbedding},
+		{ID: "keyword", MatchType: types.MatchTypeKeywords},
+		{ID: "nearby", MatchType: types.MatchTypeNearByChunk},
+		{ID: "parent", MatchType: types.MatchTypeParentChunk},
+	}
+	markVectorSearchMode(results, types.VectorSearchModeExact)
+
+	want := map[string]string{"vector": types.VectorSearchModeExact}
+	for _, result := range results {
+		if result.VectorSearchMode != want[result.ID] {
+			t.Errorf("%s: vector_search_mode = %q, want %q", result.ID, result.VectorSearchMode, want[result.ID])
+		}
+	}
+}
+
+func TestCopySearchResults(t *testing.T) {
+	shared := []*types.SearchResult{
+		{
+			ID:         "chunk-1",
+			Score:      0.9,
+			Metadata:   map[string]string{"source": "handbook"},
+			SubChunkID: []string{"sub-1"},
+			Alternates: []*types.ChunkAlternate{{ChunkID: "chunk-9"}},
+		},
+		{ID: "chunk-2", Score: 0.5},
+	}
+	copied := copySearchResults(shared)
+
+	// A caller modifying its results downstream must not affect the other callers
+	copied[0].Score = 0.1
+	copied[0].Metadata["source"] = "changed"
+	copied[0].SubChunkID[0] = "changed"
+	copied[0].Alternates = append(copied[0].Alternates[:0], &types.ChunkAlternate{ChunkID: "changed"})
+	copied[1].Content = "changed"
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "score", got: fmt.Sprint(shared[0].Score), want: "0.9"},
+		{name: "metadata", got: shared[0].Metadata["source"], want: "handbook"},
+		{name: "sub chunks", got: shared[0].SubChunkID[0], want: "sub-1"},
+		{name: "alternates", got: shared[0].Alternates[0].ChunkID, want: "chunk-9"},
+		{name: "content", got: shared[1].Content, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("shared %s = %q, want %q", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+	if len(copySearchResults(nil)) != 0 {
+		t.Error("copying no results must return no results")
+	}
+}
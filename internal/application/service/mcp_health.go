@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/clock"
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/runtime"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+)
+
+// defaultMCPCheckTimeout bounds the tool listing of a health check when the service sets no timeout
+const defaultMCPCheckTimeout = 30 * time.Second
+
+// CheckMCPServiceHealth validates the tools advertised by an MCP service and stores the result
+func (s *mcpServiceService) CheckMCPServiceHealth(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) (*types.MCPServiceHealth, error) {
+	service, err := s.mcpServiceRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP service: %w", err)
+	}
+	if service == nil {
+		return nil, fmt.Errorf("MCP service not found")
+	}
+	return s.checkMCPService(ctx, service)
+}
+
+// CheckAllMCPServices validates the tools of the enabled MCP services of every tenant
+func (s *mcpServiceService) CheckAllMCPServices(ctx context.Context) {
+	services, err := s.mcpServiceRepo.ListAllEnabled(ctx)
+	if err != nil {
+		logger.GetLogger(ctx).Errorf("Failed to list MCP services for health check: %v", err)
+		return
+	}
+	for _, service := range services {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := s.checkMCPService(ctx, service); err != nil {
+			logger.GetLogger(ctx).Errorf("Failed to check MCP service %s: %v", service.ID, err)
+		}
+	}
+}
+
+// GetAgentMCPStatus reports the problems of the MCP services used by an agent
+func (s *mcpServiceService) GetAgentMCPStatus(
+	ctx context.Context,
+	tenantID uint64,
+	agent *types.CustomAgent,
+) (*types.AgentMCPStatus, error) {
+	services, err := s.mcpServiceRepo.List(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list MCP services: %w", err)
+	}
+	return types.NewAgentMCPStatus(agent, services), nil
+}
+
+// checkMCPService lists the tools of a service, validates them against the previous check and stores the result
+// A service that cannot be reached is recorded as unreachable rather than returned as an error
+func (s *mcpServiceService) checkMCPService(
+	ctx context.Context,
+	service *types.MCPService,
+) (*types.MCPServiceHealth, error) {
+	health := s.listToolsForHealth(ctx, service)
+	if err := s.mcpServiceRepo.UpdateHealth(ctx, service.TenantID, service.ID, health); err != nil {
+		return nil, fmt.Errorf("failed to store MCP service health: %w", err)
+	}
+
+	name := secutils.SanitizeForLog(service.Name)
+	if !health.Reachable {
+		logger.GetLogger(ctx).Warnf("MCP service unreachable: %s (ID: %s): %s", name, service.ID, health.Error)
+	}
+	for _, tool := range health.Tools {
+		if tool.Status != types.MCPToolStatusOK {
+			logger.GetLogger(ctx).Warnf("MCP tool %s of service %s (ID: %s) is %s %s",
+				secutils.SanitizeForLog(tool.Name), name, service.ID, tool.Status, tool.Error)
+		}
+	}
+	return health, nil
+}
+
+// listToolsForHealth lists the tools of a service within its timeout and validates them
+func (s *mcpServiceService) listToolsForHealth(ctx context.Context, service *types.MCPService) *types.MCPServiceHealth {
+	client, err := s.mcpManager.GetOrCreateClient(service)
+	if err != nil {
+		return types.UnreachableMCPServiceHealth(service.Health, err, time.Now())
+	}
+
+	timeout := defaultMCPCheckTimeout
+	if service.AdvancedConfig != nil && service.AdvancedConfig.Timeout > 0 {
+		timeout = time.Duration(service.AdvancedConfig.Timeout) * time.Second
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tools, err := client.ListTools(checkCtx)
+	if err != nil {
+		// Drop the connection so that the next check or tool call reconnects
+		s.mcpManager.CloseClient(service.ID)
+		return types.UnreachableMCPServiceHealth(service.Health, err, time.Now())
+	}
+	return types.NewMCPServiceHealth(service.Health, tools, time.Now())
+}
+
+// StartMCPHealthChecks validates the tools of every enabled MCP service periodically,
+// until the application shuts down. A zero interval disables the checks.
+func StartMCPHealthChecks(cfg *config.Config, mcpServiceService interfaces.MCPServiceService, clk clock.Clock) {
+	if cfg.MCP == nil || cfg.MCP.HealthCheckInterval <= 0 {
+		return
+	}
+	interval := cfg.MCP.HealthCheckInterval
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := clk.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				mcpServiceService.CheckAllMCPServices(ctx)
+			}
+		}
+	}()
+
+	runtime.OnShutdown("MCP health checks", func(shutdownCtx context.Context) error {
+		cancel()
+		select {
+		case <-stopped:
+			return nil
+		case <-shutdownCtx.Done():
+			return shutdownCtx.Err()
+		}
+	})
+	logger.Infof(context.Background(), "MCP health checks every %s", interval)
+}
@@ -201,19 +201,22 @@ func (s *mcpServiceService) UpdateMCPService(ctx context.Context, service *types
 		configChanged = true
 	}
 	name := secutils.SanitizeForLog(existing.Name)
-	// Close existing client connection if:
+	// Close existing client connection and forget past failures if:
 	// 1. Service is now disabled (need to close connection)
 	// 2. Critical configuration changed (need to reconnect with new config)
 	if !existing.Enabled {
 		s.mcpManager.CloseClient(service.ID)
+		s.mcpManager.ResetBreaker(service.ID)
 		logger.GetLogger(ctx).Infof("MCP service disabled, connection closed: %s (ID: %s)", name, service.ID)
 	} else if configChanged {
 		s.mcpManager.CloseClient(service.ID)
+		s.mcpManager.ResetBreaker(service.ID)
 		logger.GetLogger(ctx).Infof("MCP service config changed, connection closed: %s (ID: %s)", name, service.ID)
 	} else if oldEnabled != existing.Enabled && existing.Enabled {
 		// Service was just enabled (was disabled, now enabled)
 		// Close any existing connection to ensure clean state
 		s.mcpManager.CloseClient(service.ID)
+		s.mcpManager.ResetBreaker(service.ID)
 		logger.GetLogger(ctx).Infof("MCP service enabled, existing connection closed: %s (ID: %s)", name, service.ID)
 	}
 
@@ -234,6 +237,7 @@ func (s *mcpServiceService) DeleteMCPService(ctx context.Context, tenantID uint6
 
 	// Close client connection
 	s.mcpManager.CloseClient(id)
+	s.mcpManager.ResetBreaker(id)
 
 	if err := s.mcpServiceRepo.Delete(ctx, tenantID, id); err != nil {
 		logger.GetLogger(ctx).Errorf("Failed to delete MCP service: %v", err)
@@ -314,8 +318,9 @@ func (s *mcpServiceService) TestMCPService(
 			initResult.ServerInfo.Name,
 			initResult.ServerInfo.Version,
 		),
-		Tools:     tools,
-		Resources: resources,
+		Tools:        tools,
+		Resources:    resources,
+		BreakerState: s.mcpManager.BreakerState(service),
 	}, nil
 }
 
@@ -334,14 +339,12 @@ func (s *mcpServiceService) GetMCPServiceTools(
 		return nil, fmt.Errorf("MCP service not found")
 	}
 
-	// Get or create client
-	client, err := s.mcpManager.GetOrCreateClient(service)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get MCP client: %w", err)
-	}
-
-	// List tools
-	tools, err := client.ListTools(ctx)
+	// List tools, retrying transient failures
+	var tools []*types.MCPTool
+	err = s.mcpManager.Call(ctx, service, func(ctx context.Context, client mcp.MCPClient) (err error) {
+		tools, err = client.ListTools(ctx)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tools: %w", err)
 	}
@@ -364,14 +367,12 @@ func (s *mcpServiceService) GetMCPServiceResources(
 		return nil, fmt.Errorf("MCP service not found")
 	}
 
-	// Get or create client
-	client, err := s.mcpManager.GetOrCreateClient(service)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get MCP client: %w", err)
-	}
-
-	// List resources
-	resources, err := client.ListResources(ctx)
+	// List resources, retrying transient failures
+	var resources []*types.MCPResource
+	err = s.mcpManager.Call(ctx, service, func(ctx context.Context, client mcp.MCPClient) (err error) {
+		resources, err = client.ListResources(ctx)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list resources: %w", err)
 	}
@@ -217,6 +217,72 @@ func (s *messageService) GetMessagesBySessionBeforeTime(ctx context.Context,
 	return messages, nil
 }
 
+// GetMessagesBySessionBeforeCursor retrieves messages sent strictly before a cursor, newest first
+// Unlike a time filter, the cursor also orders messages created at the same time, so pages
+// loaded while new messages arrive neither repeat nor skip messages
+// Parameters:
+//   - ctx: Context containing tenant information
+//   - sessionID: The ID of the session to get messages from
+//   - cursor: Position to retrieve messages before, nil for the most recent messages
+//   - limit: Maximum number of messages to retrieve
+//
+// Returns a slice of messages or an error if retrieval fails
+func (s *messageService) GetMessagesBySessionBeforeCursor(ctx context.Context,
+	sessionID string, cursor *types.MessageCursor, limit int,
+) ([]*types.Message, error) {
+	logger.Infof(ctx, "Getting messages before cursor for session ID: %s, limit: %d", sessionID, limit)
+
+	// Verify the session exists before retrieving messages
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	_, err := s.sessionRepo.Get(ctx, tenantID, sessionID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get session: %v", err)
+		return nil, err
+	}
+
+	messages, err := s.messageRepo.GetMessagesBySessionBeforeCursor(ctx, sessionID, cursor, limit)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"session_id": sessionID,
+			"limit":      limit,
+		})
+		return nil, err
+	}
+
+	logger.Infof(ctx, "Retrieved %d messages before cursor successfully", len(messages))
+	return messages, nil
+}
+
+// GetUserMessageByRequestID retrieves the user message of a conversation turn
+// Parameters:
+//   - ctx: Context containing tenant information
+//   - sessionID: The ID of the session containing the message
+//   - requestID: The request ID shared by the messages of the turn
+//
+// Returns the user message or an error if retrieval fails
+func (s *messageService) GetUserMessageByRequestID(ctx context.Context,
+	sessionID string, requestID string,
+) (*types.Message, error) {
+	logger.Infof(ctx, "Getting user message, session ID: %s, request ID: %s", sessionID, requestID)
+
+	// Verify the session exists before attempting to retrieve the message
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	if _, err := s.sessionRepo.Get(ctx, tenantID, sessionID); err != nil {
+		logger.Errorf(ctx, "Failed to get session: %v", err)
+		return nil, err
+	}
+
+	message, err := s.messageRepo.GetUserMessageByRequestID(ctx, sessionID, requestID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"session_id": sessionID,
+			"request_id": requestID,
+		})
+		return nil, err
+	}
+	return message, nil
+}
+
 // UpdateMessage updates an existing message's content or metadata
 // Parameters:
 //   - ctx: Context containing tenant information
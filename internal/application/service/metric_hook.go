@@ -49,8 +49,8 @@ var metricCalculators = []struct {
 	}},
 }
 
-// Append calculates and stores metrics for given input
-func (m *MetricList) Append(metricInput *types.MetricInput) {
+// Append calculates and stores metrics for given input, returning the metrics of this input
+func (m *MetricList) Append(metricInput *types.MetricInput) *types.MetricResult {
 	result := &types.MetricResult{}
 	// Calculate all configured metrics
 	for _, c := range metricCalculators {
@@ -59,6 +59,7 @@ func (m *MetricList) Append(metricInput *types.MetricInput) {
 	}
 	logger.Infof(context.Background(), "metric: %v", result)
 	m.results = append(m.results, result)
+	return result
 }
 
 // Avg calculates average of all stored metric results
@@ -130,8 +131,8 @@ func (h *HookMetric) recordChatResponse(index int, chatResponse *types.ChatRespo
 	h.qaPairMetricList[index].chatResponse = chatResponse
 }
 
-// recordFinish finalizes metrics for a QA pair
-func (h *HookMetric) recordFinish(index int) {
+// recordFinish finalizes metrics for a QA pair and returns them
+func (h *HookMetric) recordFinish(index int) *types.MetricResult {
 	// Prepare retrieval IDs from rerank results
 	retrievalIDs := make([]int, len(h.qaPairMetricList[index].rerankResult))
 	for i, r := range h.qaPairMetricList[index].rerankResult {
@@ -155,7 +156,7 @@ func (h *HookMetric) recordFinish(index int) {
 	// Thread-safe append of metrics
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.metricResults.Append(metricInput)
+	return h.metricResults.Append(metricInput)
 }
 
 // MetricResult returns the averaged metric results
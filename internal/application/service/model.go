@@ -3,7 +3,10 @@ package service
 import (
 	"context"
 	"errors"
+	"net/http"
+	"slices"
 
+	werrors "github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/chat"
 	"github.com/Tencent/WeKnora/internal/models/embedding"
@@ -14,19 +17,29 @@ import (
 )
 
 // ErrModelNotFound is returned when a model cannot be found in the repository
-var ErrModelNotFound = errors.New("model not found")
+var ErrModelNotFound = werrors.NewSentinel(werrors.CodeModelNotFound, http.StatusNotFound, "model not found")
 
 // modelService implements the model service interface
 type modelService struct {
 	repo          interfaces.ModelRepository
+	kbRepo        interfaces.KnowledgeBaseRepository
+	agentRepo     interfaces.CustomAgentRepository
 	ollamaService *ollama.OllamaService
 	pooler        embedding.EmbedderPooler
 }
 
 // NewModelService creates a new model service instance
-func NewModelService(repo interfaces.ModelRepository, ollamaService *ollama.OllamaService, pooler embedding.EmbedderPooler) interfaces.ModelService {
+func NewModelService(
+	repo interfaces.ModelRepository,
+	kbRepo interfaces.KnowledgeBaseRepository,
+	agentRepo interfaces.CustomAgentRepository,
+	ollamaService *ollama.OllamaService,
+	pooler embedding.EmbedderPooler,
+) interfaces.ModelService {
 	return &modelService{
 		repo:          repo,
+		kbRepo:        kbRepo,
+		agentRepo:     agentRepo,
 		ollamaService: ollamaService,
 		pooler:        pooler,
 	}
@@ -181,6 +194,12 @@ func (s *modelService) UpdateModel(ctx context.Context, model *types.Model) erro
 		logger.Warnf(ctx, "Attempted to update builtin model: %s", model.ID)
 		return errors.New("builtin models cannot be updated")
 	}
+	if existingModel != nil && existingModel.Type == types.ModelTypeEmbedding &&
+		existingModel.Parameters.EmbeddingParameters.Normalize != model.Parameters.EmbeddingParameters.Normalize {
+		if err := s.checkNormalizationChange(ctx, model.ID); err != nil {
+			return err
+		}
+	}
 
 	// Update model in repository
 	err = s.repo.Update(ctx, model)
@@ -196,13 +215,54 @@ func (s *modelService) UpdateModel(ctx context.Context, model *types.Model) erro
 	return nil
 }
 
-// DeleteModel removes a model from the repository
-func (s *modelService) DeleteModel(ctx context.Context, id string) error {
-	logger.Info(ctx, "Start deleting model")
-	logger.Infof(ctx, "Deleting model ID: %s", id)
+// checkNormalizationChange rejects toggling the normalization of an embedding model that knowledge bases
+// have already embedded with, since their stored vectors would no longer match the query vectors
+func (s *modelService) checkNormalizationChange(ctx context.Context, id string) error {
+	references, err := s.GetModelReferences(ctx, []string{id})
+	if err != nil {
+		return err
+	}
+	var embedded []types.ResourceReference
+	for _, ref := range references[id] {
+		if ref.Type == types.ReferenceTypeKnowledgeBase {
+			embedded = append(embedded, ref)
+		}
+	}
+	if len(embedded) == 0 {
+		return nil
+	}
+	logger.Warnf(ctx, "Rejected normalization change of embedding model %s used by %d knowledge bases", id, len(embedded))
+	inUse := types.NewResourceInUseError(werrors.CodeModelInUse, "model", embedded, int64(len(embedded)))
+	inUse.Message = "normalization of an embedding model cannot change while knowledge bases use it, " +
+		"their stored vectors would no longer match"
+	return inUse
+}
+
+// DeleteModel removes a model from the repository. A model used by knowledge bases or agents is only
+// deleted with force, which first removes the references; embedding models of knowledge bases are never
+// removed since the indexed chunks depend on them.
+func (s *modelService) DeleteModel(ctx context.Context, id string, force bool) error {
+	logger.Infof(ctx, "Deleting model ID: %s, force: %v", id, force)
+
+	references, err := s.GetModelReferences(ctx, []string{id})
+	if err != nil {
+		return err
+	}
+	if refs := references[id]; len(refs) > 0 {
+		if !force {
+			logger.Warnf(ctx, "Model %s is used by %d resources", id, len(refs))
+			return types.NewResourceInUseError(werrors.CodeModelInUse, "model", refs, int64(len(refs)))
+		}
+		if err := s.clearModelReferences(ctx, id, refs); err != nil {
+			return err
+		}
+	}
+	return s.deleteModel(ctx, id)
+}
 
+// deleteModel removes a model from the repository without checking its references
+func (s *modelService) deleteModel(ctx context.Context, id string) error {
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
-	logger.Infof(ctx, "Tenant ID: %d", tenantID)
 
 	// Check if the model is builtin - builtin models cannot be deleted
 	existingModel, err := s.repo.GetByID(ctx, tenantID, id)
@@ -231,6 +291,124 @@ func (s *modelService) DeleteModel(ctx context.Context, id string) error {
 	return nil
 }
 
+// clearModelReferences removes the references to a model from knowledge bases and agents. Knowledge bases
+// that embed with the model keep it and fail the deletion, nothing is changed in that case.
+func (s *modelService) clearModelReferences(ctx context.Context, id string, refs []types.ResourceReference) error {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	var kbs []*types.KnowledgeBase
+	var embedded []types.ResourceReference
+	for _, ref := range refs {
+		if ref.Type != types.ReferenceTypeKnowledgeBase {
+			continue
+		}
+		kb, err := s.kbRepo.GetKnowledgeBaseByID(ctx, ref.ID)
+		if err != nil {
+			return err
+		}
+		if types.KnowledgeBaseEmbedsWithModel(kb, id) {
+			embedded = append(embedded, ref)
+		}
+		kbs = append(kbs, kb)
+	}
+	if len(embedded) > 0 {
+		logger.Warnf(ctx, "Model %s is the embedding model of %d knowledge bases", id, len(embedded))
+		inUse := types.NewResourceInUseError(werrors.CodeModelInUse, "model", embedded, int64(len(embedded)))
+		inUse.Message = "model is the embedding model of knowledge bases and cannot be removed from them"
+		return inUse
+	}
+
+	for _, kb := range kbs {
+		if types.ClearKnowledgeBaseModel(kb, id) {
+			if err := s.kbRepo.UpdateKnowledgeBase(ctx, kb); err != nil {
+				return err
+			}
+			logger.Infof(ctx, "Removed model %s from knowledge base %s", id, kb.ID)
+		}
+	}
+	for _, ref := range refs {
+		if ref.Type != types.ReferenceTypeAgent {
+			continue
+		}
+		agent, err := s.agentRepo.GetAgentByID(ctx, ref.ID, tenantID)
+		if err != nil {
+			return err
+		}
+		if types.ClearAgentModel(agent, id) {
+			if err := s.agentRepo.UpdateAgent(ctx, agent); err != nil {
+				return err
+			}
+			logger.Infof(ctx, "Removed model %s from agent %s", id, agent.ID)
+		}
+	}
+	return nil
+}
+
+// GetModelReferences returns the knowledge bases and agents of the current tenant that use each of the models.
+// Models without references are left out of the result.
+func (s *modelService) GetModelReferences(ctx context.Context, ids []string) (map[string][]types.ResourceReference, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+
+	kbs, err := s.kbRepo.ListKnowledgeBasesByTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	agents, err := s.agentRepo.ListAgentsByTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	references := make(map[string][]types.ResourceReference)
+	for _, id := range ids {
+		for _, kb := range kbs {
+			if fields := types.KnowledgeBaseModelFields(kb, id); len(fields) > 0 {
+				references[id] = append(references[id], types.ResourceReference{
+					Type: types.ReferenceTypeKnowledgeBase, ID: kb.ID, Name: kb.Name, Fields: fields,
+				})
+			}
+		}
+		for _, agent := range agents {
+			if fields := types.AgentModelFields(agent, id); len(fields) > 0 {
+				references[id] = append(references[id], types.ResourceReference{
+					Type: types.ReferenceTypeAgent, ID: agent.ID, Name: agent.Name, Fields: fields,
+				})
+			}
+		}
+	}
+	return references, nil
+}
+
+// DeleteModels deletes models in batch. Models still used by knowledge bases or agents are kept and
+// reported with their references; every model gets its own result.
+func (s *modelService) DeleteModels(ctx context.Context, ids []string) ([]*types.ModelBatchResult, error) {
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	logger.Infof(ctx, "Deleting %d models in batch", len(ids))
+
+	references, err := s.GetModelReferences(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*types.ModelBatchResult, 0, len(ids))
+	for _, id := range ids {
+		result := &types.ModelBatchResult{ID: id}
+		results = append(results, result)
+
+		if refs := references[id]; len(refs) > 0 {
+			logger.Warnf(ctx, "Model %s is used by %d resources, skipping deletion", id, len(refs))
+			result.Error = "model is in use"
+			result.References = refs
+			continue
+		}
+		if err := s.deleteModel(ctx, id); err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		result.Success = true
+	}
+	return results, nil
+}
+
 // GetEmbeddingModel retrieves and initializes an embedding model instance
 // Takes a model ID and returns an Embedder interface implementation
 func (s *modelService) GetEmbeddingModel(ctx context.Context, modelId string) (embedding.Embedder, error) {
@@ -255,6 +433,7 @@ func (s *modelService) GetEmbeddingModel(ctx context.Context, modelId string) (e
 		Dimensions:           model.Parameters.EmbeddingParameters.Dimension,
 		TruncatePromptTokens: model.Parameters.EmbeddingParameters.TruncatePromptTokens,
 		Provider:             model.Parameters.Provider,
+		Normalize:            model.Parameters.EmbeddingParameters.Normalize,
 	}, s.pooler, s.ollamaService)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{
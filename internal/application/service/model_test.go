@@ -0,0 +1,48 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestDeleteModels(t *testing.T) {
+	kb := &types.KnowledgeBase{ID: "kb-1", Name: "Handbook", EmbeddingModelID: "embed", SummaryModelID: "chat"}
+	agent := &types.CustomAgent{ID: "agent-1", Name: "Helper"}
+	agent.Config.ModelID = "chat"
+	modelRepo := &fakeModelRepo{}
+	svc := NewModelService(modelRepo,
+		&fakeKnowledgeBaseRepo{kbs: []*types.KnowledgeBase{kb}},
+		&fakeAgentRepo{agents: []*types.CustomAgent{agent}}, nil, nil)
+
+	results, err := svc.DeleteModels(referenceTestContext(), []string{"old-rerank", "chat", "embed", "old-rerank"})
+	if err != nil {
+		t.Fatalf("DeleteModels failed: %v", err)
+	}
+
+	tests := []struct {
+		id          string
+		wantSuccess bool
+		wantRefs    int
+	}{
+		{id: "chat", wantRefs: 2},
+		{id: "embed", wantRefs: 1},
+		{id: "old-rerank", wantSuccess: true},
+	}
+	if len(results) != len(tests) {
+		t.Fatalf("got %d results, want one per distinct model", len(results))
+	}
+	for i, tt := range tests {
+		result := results[i]
+		if result.ID != tt.id || result.Success != tt.wantSuccess || len(result.References) != tt.wantRefs {
+			t.Errorf("result %d = %+v, want %s success=%v with %d references", i, result, tt.id, tt.wantSuccess, tt.wantRefs)
+		}
+		if !tt.wantSuccess && result.Error == "" {
+			t.Errorf("%s: kept model without an error", tt.id)
+		}
+	}
+	if !slices.Equal(modelRepo.deleted, []string{"old-rerank"}) {
+		t.Errorf("deleted %v, want only the unused model", modelRepo.deleted)
+	}
+}
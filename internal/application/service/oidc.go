@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/Tencent/WeKnora/internal/application/repository"
+	"github.com/Tencent/WeKnora/internal/application/service/auth"
+	"github.com/Tencent/WeKnora/internal/clock"
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+)
+
+const (
+	// oidcStateTTL is how long a user has to log in at the identity provider
+	oidcStateTTL = 10 * time.Minute
+	// oidcStateAudience keeps the state tokens from being mistaken for other tokens signed with the secret
+	oidcStateAudience = "weknora-oidc-state"
+	// oidcTwoFactorTTL is how long a user has to enter its two-factor code after logging in at the provider
+	oidcTwoFactorTTL = 5 * time.Minute
+	// oidcTwoFactorAudience keeps the two-factor tokens from being mistaken for other tokens
+	oidcTwoFactorAudience = "weknora-oidc-two-factor"
+	// maxOIDCUsernameLength bounds the usernames derived from identities
+	maxOIDCUsernameLength = 40
+)
+
+var (
+	// ErrOIDCProviderNotFound is returned for a provider that is not configured
+	ErrOIDCProviderNotFound = errors.New("identity provider not found")
+	// ErrOIDCLoginFailed is returned when a login with an identity provider is refused
+	ErrOIDCLoginFailed = errors.New("identity provider login failed")
+	// ErrOIDCRegistrationDisabled is returned when an identity has no user and registration is disabled
+	ErrOIDCRegistrationDisabled = errors.New("registration is disabled")
+)
+
+// oidcStateClaims is the state of a login, signed so that it needs no storage between its two steps
+type oidcStateClaims struct {
+	Provider string `json:"prv"`
+	Nonce    string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// oidcTwoFactorClaims identify a user who logged in with a provider and still has to enter its two-factor code
+type oidcTwoFactorClaims struct {
+	Provider string `json:"prv"`
+	jwt.RegisteredClaims
+}
+
+// oidcService implements the OIDCService interface
+type oidcService struct {
+	providers     map[string]*auth.OIDCProvider
+	order         []*auth.OIDCProvider
+	userService   interfaces.UserService
+	tenantService interfaces.TenantService
+	identityRepo  interfaces.UserIdentityRepository
+	clock         clock.Clock
+}
+
+// NewOIDCService creates the login with the identity providers of the configuration
+func NewOIDCService(
+	cfg *config.Config,
+	userService interfaces.UserService,
+	tenantService interfaces.TenantService,
+	identityRepo interfaces.UserIdentityRepository,
+	clk clock.Clock,
+) (interfaces.OIDCService, error) {
+	providers, err := auth.NewOIDCProviders(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &oidcService{
+		providers:     make(map[string]*auth.OIDCProvider, len(providers)),
+		order:         providers,
+		userService:   userService,
+		tenantService: tenantService,
+		identityRepo:  identityRepo,
+		clock:         clk,
+	}
+	for _, p := range providers {
+		s.providers[p.Name()] = p
+	}
+	return s, nil
+}
+
+// Providers lists the identity providers users can log in with
+func (s *oidcService) Providers() []*types.OIDCProviderInfo {
+	infos := make([]*types.OIDCProviderInfo, 0, len(s.order))
+	for _, p := range s.order {
+		infos = append(infos, &types.OIDCProviderInfo{Name: p.Name(), DisplayName: p.DisplayName(), Type: p.Type()})
+	}
+	return infos
+}
+
+// Authorize starts a login with a provider. The state carries a nonce bound into the ID token, from
+// which the PKCE code verifier is derived, so that nothing is stored until the user comes back.
+func (s *oidcService) Authorize(ctx context.Context, provider string) (*types.OIDCAuthorization, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	nonceBytes := make([]byte, 32)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(nonceBytes)
+	now := s.clock.Now()
+	state, err := jwt.NewWithClaims(jwt.SigningMethodHS256, oidcStateClaims{
+		Provider: provider,
+		Nonce:    nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oidcStateAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(oidcStateTTL)),
+		},
+	}).SignedString(oidcKey("state"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign state: %w", err)
+	}
+
+	challenge := sha256.Sum256([]byte(oidcCodeVerifier(nonce)))
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		logger.Errorf(ctx, "Failed to start login with OIDC provider %s: %v", provider, err)
+		return nil, err
+	}
+	return &types.OIDCAuthorization{AuthorizationURL: authURL, State: state}, nil
+}
+
+// Login completes a login with a provider and returns tokens for the user of the identity. An identity
+// logging in for the first time is linked to the user with its verified email, or else to a new user with
+// its own tenant, as with a registration.
+func (s *oidcService) Login(
+	ctx context.Context, provider string, req *types.OIDCCallbackRequest,
+) (*types.LoginResponse, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	var claims oidcStateClaims
+	if _, err := jwt.ParseWithClaims(req.State, &claims, func(t *jwt.Token) (interface{}, error) {
+		return oidcKey("state"), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithAudience(oidcStateAudience),
+		jwt.WithExpirationRequired(), jwt.WithTimeFunc(s.clock.Now)); err != nil || claims.Provider != provider {
+		logger.Warnf(ctx, "Rejected OIDC login with an invalid state for provider %s", provider)
+		return nil, fmt.Errorf("%w: invalid or expired state", ErrOIDCLoginFailed)
+	}
+
+	identity, err := p.Exchange(ctx, req.Code, oidcCodeVerifier(claims.Nonce), claims.Nonce)
+	if err != nil {
+		logger.Warnf(ctx, "OIDC login with provider %s failed: %v", provider, err)
+		return nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+	if !p.AllowsEmail(identity.Email) {
+		logger.Warnf(ctx, "Rejected OIDC login of %s with provider %s: email domain not allowed",
+			secutils.SanitizeForLog(identity.Email), provider)
+		return nil, fmt.Errorf("%w: email domain not allowed", ErrOIDCLoginFailed)
+	}
+
+	user, err := s.identityUser(ctx, p, identity)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		logger.Warnf(ctx, "Rejected OIDC login of disabled user %s", user.ID)
+		return &types.LoginResponse{Success: false, Message: "Account is disabled"}, nil
+	}
+	// Providers do not tell whether they asked for a second factor, so users with two-factor authentication
+	// enter their code as with a password login, sending it back with a token of the login
+	if user.TwoFactorEnabled {
+		return s.twoFactorChallenge(ctx, provider, user)
+	}
+	return s.loginResponse(ctx, provider, user)
+}
+
+// CompleteTwoFactor completes a login with a provider of a user with two-factor authentication, checking
+// the TOTP or recovery code sent with the token of the login
+func (s *oidcService) CompleteTwoFactor(
+	ctx context.Context, req *types.OIDCTwoFactorRequest,
+) (*types.LoginResponse, error) {
+	var claims oidcTwoFactorClaims
+	if _, err := jwt.ParseWithClaims(req.TwoFactorToken, &claims, func(t *jwt.Token) (interface{}, error) {
+		return oidcKey("two_factor"), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithAudience(oidcTwoFactorAudience),
+		jwt.WithExpirationRequired(), jwt.WithTimeFunc(s.clock.Now)); err != nil {
+		logger.Warn(ctx, "Rejected OIDC two-factor login with an invalid token")
+		return nil, fmt.Errorf("%w: invalid or expired two-factor token", ErrOIDCLoginFailed)
+	}
+
+	user, err := s.userService.GetUserByID(ctx, claims.Subject)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, fmt.Errorf("%w: the user no longer exists", ErrOIDCLoginFailed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		logger.Warnf(ctx, "Rejected OIDC login of disabled user %s", user.ID)
+		return &types.LoginResponse{Success: false, Message: "Account is disabled"}, nil
+	}
+	if user.TwoFactorEnabled {
+		if resp := s.userService.CheckTwoFactorLogin(ctx, user, req.TOTPCode, req.RecoveryCode); resp != nil {
+			return resp, nil
+		}
+	}
+	return s.loginResponse(ctx, claims.Provider, user)
+}
+
+// twoFactorChallenge answers a login with a provider of a user with two-factor authentication with a
+// token of the login, to send back with the code
+func (s *oidcService) twoFactorChallenge(
+	ctx context.Context, provider string, user *types.User,
+) (*types.LoginResponse, error) {
+	now := s.clock.Now()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, oidcTwoFactorClaims{
+		Provider: provider,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID,
+			Audience:  jwt.ClaimStrings{oidcTwoFactorAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(oidcTwoFactorTTL)),
+		},
+	}).SignedString(oidcKey("two_factor"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign two-factor token: %w", err)
+	}
+
+	logger.Infof(ctx, "OIDC login of user %s with provider %s needs a two-factor code", user.ID, provider)
+	return &types.LoginResponse{
+		Success:           false,
+		Message:           "Two-factor authentication code required",
+		TwoFactorRequired: true,
+		TwoFactorToken:    token,
+	}, nil
+}
+
+// loginResponse issues the tokens of a user who logged in with a provider
+func (s *oidcService) loginResponse(
+	ctx context.Context, provider string, user *types.User,
+) (*types.LoginResponse, error) {
+	accessToken, refreshToken, err := s.userService.GenerateTokens(ctx, user)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to generate tokens: %v", err)
+		return nil, err
+	}
+	tenant, err := s.tenantService.GetTenantByID(ctx, user.TenantID)
+	if err != nil {
+		logger.Warn(ctx, "Failed to get tenant info")
+	}
+
+	logger.Infof(ctx, "User %s logged in with OIDC provider %s", user.ID, provider)
+	return &types.LoginResponse{
+		Success:      true,
+		Message:      "Login successful",
+		User:         user,
+		Tenant:       tenant,
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// identityUser returns the user linked to the identity, linking or creating it on the first login
+func (s *oidcService) identityUser(
+	ctx context.Context, p *auth.OIDCProvider, identity *auth.OIDCIdentity,
+) (*types.User, error) {
+	link, err := s.identityRepo.GetIdentity(ctx, p.Name(), identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if link != nil {
+		user, err := s.userService.GetUserByID(ctx, link.UserID)
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			return user, err
+		}
+		// The linked user was deleted: drop the dangling link and handle the login as a first login
+		if err := s.identityRepo.DeleteIdentity(ctx, link.ID); err != nil {
+			logger.Errorf(ctx, "Failed to delete the OIDC identity of deleted user %s: %v", link.UserID, err)
+			return nil, err
+		}
+		logger.Infof(ctx, "Deleted the link of OIDC provider %s to deleted user %s", p.Name(), link.UserID)
+	}
+
+	if identity.Email == "" {
+		logger.Warnf(ctx, "Rejected first OIDC login with provider %s: no email", p.Name())
+		return nil, fmt.Errorf("%w: the identity has no email", ErrOIDCLoginFailed)
+	}
+	user, err := s.userService.GetUserByEmail(ctx, identity.Email)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		logger.Errorf(ctx, "Failed to look up the user of an OIDC identity: %v", err)
+		return nil, err
+	}
+	// An existing account is only linked to an email the provider verified: trust_email would let
+	// anyone registering the email at the provider take the account over
+	if user != nil && !identity.EmailVerified {
+		logger.Warnf(ctx, "Rejected first OIDC login with provider %s: unverified email of an existing user",
+			p.Name())
+		return nil, fmt.Errorf("%w: the email of the identity is not verified", ErrOIDCLoginFailed)
+	}
+	if user == nil && !identity.EmailVerified && !p.TrustEmail() {
+		logger.Warnf(ctx, "Rejected first OIDC login with provider %s: no verified email", p.Name())
+		return nil, fmt.Errorf("%w: the identity has no verified email", ErrOIDCLoginFailed)
+	}
+	if user == nil {
+		if os.Getenv("DISABLE_REGISTRATION") == "true" {
+			return nil, ErrOIDCRegistrationDisabled
+		}
+		if user, err = s.registerIdentity(ctx, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.identityRepo.CreateIdentity(ctx, &types.UserIdentity{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Provider:  p.Name(),
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: s.clock.Now(),
+	}); err != nil {
+		logger.Errorf(ctx, "Failed to link OIDC identity to user %s: %v", user.ID, err)
+		return nil, err
+	}
+	logger.Infof(ctx, "Linked the account of OIDC provider %s to user %s", p.Name(), user.ID)
+	return user, nil
+}
+
+// registerIdentity creates a user with its own tenant for the identity. The user gets a random password,
+// which it can only change by logging in with the provider.
+func (s *oidcService) registerIdentity(ctx context.Context, identity *auth.OIDCIdentity) (*types.User, error) {
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return nil, fmt.Errorf("failed to generate password: %w", err)
+	}
+	base := oidcUsername(identity)
+	username := base
+	for attempt := 0; ; attempt++ {
+		if existing, _ := s.userService.GetUserByUsername(ctx, username); existing == nil {
+			break
+		}
+		if attempt == 5 {
+			return nil, errors.New("failed to find a free username")
+		}
+		suffix := make([]byte, 3)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, fmt.Errorf("failed to generate username: %w", err)
+		}
+		username = base + "-" + hex.EncodeToString(suffix)
+	}
+	return s.userService.Register(ctx, &types.RegisterRequest{
+		Username: username,
+		Email:    identity.Email,
+		Password: base64.RawURLEncoding.EncodeToString(password),
+	})
+}
+
+// oidcUsername derives a username from the preferred username, the name or the email of the identity
+func oidcUsername(identity *auth.OIDCIdentity) string {
+	var b strings.Builder
+	for _, candidate := range []string{identity.PreferredUsername, identity.Name, identity.Email} {
+		if at := strings.IndexByte(candidate, '@'); at >= 0 {
+			candidate = candidate[:at]
+		}
+		b.Reset()
+		for _, r := range candidate {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+				b.WriteRune(r)
+			case r == ' ':
+				b.WriteByte('.')
+			}
+			if b.Len() == maxOIDCUsernameLength {
+				break
+			}
+		}
+		if b.Len() >= 3 {
+			return b.String()
+		}
+	}
+	return "user"
+}
+
+// oidcKey derives the key of a purpose of the OIDC login from the JWT secret
+func oidcKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(getJwtSecret()))
+	mac.Write([]byte("oidc:" + purpose))
+	return mac.Sum(nil)
+}
+
+// oidcCodeVerifier derives the PKCE code verifier of a login from its nonce
+func oidcCodeVerifier(nonce string) string {
+	mac := hmac.New(sha256.New, oidcKey("pkce"))
+	mac.Write([]byte(nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
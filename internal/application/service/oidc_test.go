@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Tencent/WeKnora/internal/application/repository"
+	"github.com/Tencent/WeKnora/internal/clock"
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// oidcTestUsers keeps users in memory; alice@example.com exists as user-1 and frank@example.com, with
+// two-factor authentication accepting the code 123456, as user-2
+type oidcTestUsers struct {
+	interfaces.UserService
+	users map[string]*types.User
+}
+
+func (s *oidcTestUsers) GetUserByID(ctx context.Context, id string) (*types.User, error) {
+	if user, ok := s.users[id]; ok {
+		return user, nil
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (s *oidcTestUsers) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (s *oidcTestUsers) GetUserByUsername(ctx context.Context, username string) (*types.User, error) {
+	for _, user := range s.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (s *oidcTestUsers) Register(ctx context.Context, req *types.RegisterRequest) (*types.User, error) {
+	if req.Password == "" {
+		return nil, errors.New("password is required")
+	}
+	user := &types.User{ID: "user-new", Username: req.Username, Email: req.Email, TenantID: 9, IsActive: true}
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (s *oidcTestUsers) CheckTwoFactorLogin(
+	ctx context.Context, user *types.User, totpCode, recoveryCode string,
+) *types.LoginResponse {
+	if totpCode != "123456" {
+		return &types.LoginResponse{Success: false, TwoFactorRequired: true}
+	}
+	return nil
+}
+
+func (s *oidcTestUsers) GenerateTokens(ctx context.Context, user *types.User) (string, string, error) {
+	return "access-" + user.ID, "refresh-" + user.ID, nil
+}
+
+type oidcTestTenants struct {
+	interfaces.TenantService
+}
+
+func (oidcTestTenants) GetTenantByID(ctx context.Context, id uint64) (*types.Tenant, error) {
+	return &types.Tenant{ID: id}, nil
+}
+
+type oidcTestIdentities struct {
+	identities map[string]*types.UserIdentity
+}
+
+func (r *oidcTestIdentities) GetIdentity(ctx context.Context, provider, subject string) (*types.UserIdentity, error) {
+	return r.identities[provider+"/"+subject], nil
+}
+
+func (r *oidcTestIdentities) CreateIdentity(ctx context.Context, identity *types.UserIdentity) error {
+	r.identities[identity.Provider+"/"+identity.Subject] = identity
+	return nil
+}
+
+func (r *oidcTestIdentities) DeleteIdentity(ctx context.Context, id string) error {
+	for key, identity := range r.identities {
+		if identity.ID == id {
+			delete(r.identities, key)
+		}
+	}
+	return nil
+}
+
+// authorizationState starts a login and returns its state, a token signed with the same secret
+func authorizationState(t *testing.T, svc interfaces.OIDCService) string {
+	t.Helper()
+	authorization, err := svc.Authorize(context.Background(), "sso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authorization.State
+}
+
+func TestOIDCServiceLogin(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server *httptest.Server
+	codes := map[string]jwt.MapClaims{} // Claims of the ID token of each authorization code
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := codes[r.FormValue("code")]
+		if !ok || r.FormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+		if err != nil {
+			t.Error(err)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	users := &oidcTestUsers{users: map[string]*types.User{
+		"user-1": {ID: "user-1", Username: "alice", Email: "alice@example.com", TenantID: 7, IsActive: true},
+		"user-2": {ID: "user-2", Username: "frank", Email: "frank@example.com", TenantID: 7, IsActive: true,
+			TwoFactorEnabled: true},
+	}}
+	identities := &oidcTestIdentities{identities: map[string]*types.UserIdentity{}}
+	svc, err := NewOIDCService(&config.Config{Auth: &config.AuthConfig{OIDC: &config.AuthOIDCConfig{
+		Providers: []*config.AuthOIDCProviderConfig{{
+			Name: "sso", Issuer: server.URL, ClientID: "weknora", RedirectURL: "https://weknora.example.com/cb",
+		}, {
+			Name: "azure", Issuer: server.URL, ClientID: "weknora", RedirectURL: "https://weknora.example.com/cb",
+			TrustEmail: true,
+		}},
+	}}}, users, oidcTestTenants{}, identities, clock.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// loginWith runs the whole flow for an account of a provider with the given claims
+	loginWith := func(provider string, claims jwt.MapClaims) (*types.LoginResponse, error) {
+		authorization, err := svc.Authorize(ctx, provider)
+		if err != nil {
+			t.Fatal(err)
+		}
+		authURL, err := url.Parse(authorization.AuthorizationURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims["iss"] = server.URL
+		claims["aud"] = "weknora"
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+		claims["nonce"] = authURL.Query().Get("nonce")
+		code := "code-" + authURL.Query().Get("nonce")
+		codes[code] = claims
+		return svc.Login(ctx, provider, &types.OIDCCallbackRequest{Code: code, State: authorization.State})
+	}
+	login := func(claims jwt.MapClaims) (*types.LoginResponse, error) {
+		return loginWith("sso", claims)
+	}
+
+	resp, err := login(jwt.MapClaims{"sub": "alice-sub", "email": "alice@example.com", "email_verified": true})
+	if err != nil || resp.User.ID != "user-1" || resp.Token != "access-user-1" || resp.Tenant.ID != 7 {
+		t.Fatalf("first login = %+v, err = %v, want user-1 linked by email", resp, err)
+	}
+	if identities.identities["sso/alice-sub"] == nil {
+		t.Error("identity not linked")
+	}
+	resp, err = login(jwt.MapClaims{"sub": "alice-sub", "email": "alice@other.example.com"})
+	if err != nil || resp.User.ID != "user-1" {
+		t.Errorf("linked login = %+v, err = %v, want user-1", resp, err)
+	}
+
+	resp, err = login(jwt.MapClaims{"sub": "bob-sub", "email": "bob@example.com", "email_verified": true,
+		"preferred_username": "bob@example.com"})
+	if err != nil || resp.User.ID != "user-new" || resp.User.Username != "bob" || resp.Tenant.ID != 9 {
+		t.Errorf("new identity login = %+v, err = %v, want a registered user", resp, err)
+	}
+
+	if _, err := login(jwt.MapClaims{"sub": "eve-sub", "email": "alice@example.com"}); !errors.Is(err, ErrOIDCLoginFailed) {
+		t.Errorf("unverified email login err = %v, want %v", err, ErrOIDCLoginFailed)
+	}
+
+	// trust_email only admits unverified emails for new users, never to take over an existing one
+	if _, err := loginWith("azure", jwt.MapClaims{"sub": "mallory-sub", "email": "alice@example.com"}); !errors.Is(err, ErrOIDCLoginFailed) {
+		t.Errorf("trusted unverified email of an existing user err = %v, want %v", err, ErrOIDCLoginFailed)
+	}
+	if resp, err := loginWith("azure", jwt.MapClaims{"sub": "carol-sub", "email": "carol@example.com"}); err != nil || resp.User.Username != "carol" {
+		t.Errorf("trusted unverified email of a new user = %+v, err = %v, want a registered user", resp, err)
+	}
+
+	// A link to a deleted user is dropped and the account is handled as a first login
+	identities.identities["sso/dave-sub"] = &types.UserIdentity{ID: "link-dave", UserID: "user-deleted",
+		Provider: "sso", Subject: "dave-sub"}
+	resp, err = login(jwt.MapClaims{"sub": "dave-sub", "email": "dave@example.com", "email_verified": true})
+	if err != nil || resp.User.Username != "dave" {
+		t.Errorf("login with a dangling link = %+v, err = %v, want a registered user", resp, err)
+	}
+	if link := identities.identities["sso/dave-sub"]; link == nil || link.UserID != resp.User.ID {
+		t.Errorf("dangling link = %+v, want a link to %s", link, resp.User.ID)
+	}
+
+	// Users with two-factor authentication get a token of the login to send back with their code
+	resp, err = login(jwt.MapClaims{"sub": "frank-sub", "email": "frank@example.com", "email_verified": true})
+	if err != nil || resp.Success || !resp.TwoFactorRequired || resp.TwoFactorToken == "" || resp.Token != "" {
+		t.Fatalf("login with two-factor authentication = %+v, err = %v, want a two-factor challenge", resp, err)
+	}
+	twoFactorToken := resp.TwoFactorToken
+	resp, err = svc.CompleteTwoFactor(ctx, &types.OIDCTwoFactorRequest{
+		TwoFactorToken: twoFactorToken, TOTPCode: "000000",
+	})
+	if err != nil || resp.Success {
+		t.Errorf("two-factor login with a wrong code = %+v, err = %v, want a failed login", resp, err)
+	}
+	resp, err = svc.CompleteTwoFactor(ctx, &types.OIDCTwoFactorRequest{
+		TwoFactorToken: twoFactorToken, TOTPCode: "123456",
+	})
+	if err != nil || !resp.Success || resp.User.ID != "user-2" || resp.Token != "access-user-2" {
+		t.Errorf("two-factor login = %+v, err = %v, want user-2 logged in", resp, err)
+	}
+	if _, err := svc.CompleteTwoFactor(ctx, &types.OIDCTwoFactorRequest{
+		TwoFactorToken: authorizationState(t, svc), TOTPCode: "123456",
+	}); !errors.Is(err, ErrOIDCLoginFailed) {
+		t.Errorf("two-factor login with a state token err = %v, want %v", err, ErrOIDCLoginFailed)
+	}
+
+	authorization, err := svc.Authorize(ctx, "sso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Login(ctx, "sso", &types.OIDCCallbackRequest{Code: "code", State: authorization.State + "x"}); !errors.Is(err, ErrOIDCLoginFailed) {
+		t.Errorf("tampered state err = %v, want %v", err, ErrOIDCLoginFailed)
+	}
+	if _, err := svc.Authorize(ctx, "unknown"); !errors.Is(err, ErrOIDCProviderNotFound) {
+		t.Errorf("unknown provider err = %v, want %v", err, ErrOIDCProviderNotFound)
+	}
+	if providers := svc.Providers(); len(providers) != 2 || providers[0].Name != "sso" || providers[0].Type != "oidc" {
+		t.Errorf("Providers() = %+v", providers)
+	}
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeKnowledgeBaseRepo keeps knowledge bases in memory and records updates
+type fakeKnowledgeBaseRepo struct {
+	interfaces.KnowledgeBaseRepository
+	kbs     []*types.KnowledgeBase
+	updated []string
+}
+
+func (r *fakeKnowledgeBaseRepo) ListKnowledgeBasesByTenantID(context.Context, uint64) ([]*types.KnowledgeBase, error) {
+	return r.kbs, nil
+}
+
+func (r *fakeKnowledgeBaseRepo) GetKnowledgeBaseByID(_ context.Context, id string) (*types.KnowledgeBase, error) {
+	for _, kb := range r.kbs {
+		if kb.ID == id {
+			return kb, nil
+		}
+	}
+	return nil, errors.New("knowledge base not found")
+}
+
+func (r *fakeKnowledgeBaseRepo) UpdateKnowledgeBase(_ context.Context, kb *types.KnowledgeBase) error {
+	r.updated = append(r.updated, kb.ID)
+	return nil
+}
+
+// fakeAgentRepo keeps agents in memory and records updates
+type fakeAgentRepo struct {
+	interfaces.CustomAgentRepository
+	agents  []*types.CustomAgent
+	updated []string
+}
+
+func (r *fakeAgentRepo) ListAgentsByTenantID(context.Context, uint64) ([]*types.CustomAgent, error) {
+	return r.agents, nil
+}
+
+func (r *fakeAgentRepo) GetAgentByID(_ context.Context, id string, _ uint64) (*types.CustomAgent, error) {
+	for _, agent := range r.agents {
+		if agent.ID == id {
+			return agent, nil
+		}
+	}
+	return nil, errors.New("agent not found")
+}
+
+func (r *fakeAgentRepo) UpdateAgent(_ context.Context, agent *types.CustomAgent) error {
+	r.updated = append(r.updated, agent.ID)
+	return nil
+}
+
+// fakeModelRepo records deleted models
+type fakeModelRepo struct {
+	interfaces.ModelRepository
+	deleted []string
+}
+
+func (r *fakeModelRepo) GetByID(_ context.Context, _ uint64, id string) (*types.Model, error) {
+	return &types.Model{ID: id}, nil
+}
+
+func (r *fakeModelRepo) Delete(_ context.Context, _ uint64, id string) error {
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+// fakeTagRepo serves a single tag with fixed reference counts
+type fakeTagRepo struct {
+	interfaces.KnowledgeTagRepository
+	tag            *types.KnowledgeTag
+	knowledgeCount int64
+	chunkCount     int64
+	deleted        []string
+}
+
+func (r *fakeTagRepo) GetByID(context.Context, uint64, string) (*types.KnowledgeTag, error) {
+	return r.tag, nil
+}
+
+func (r *fakeTagRepo) CountReferences(context.Context, uint64, string, string) (int64, int64, error) {
+	return r.knowledgeCount, r.chunkCount, nil
+}
+
+func (r *fakeTagRepo) Delete(_ context.Context, _ uint64, id string) error {
+	r.deleted = append(r.deleted, id)
+	return nil
+}
+
+// fakeTagKnowledgeRepo lists the knowledge of a tag
+type fakeTagKnowledgeRepo struct {
+	interfaces.KnowledgeRepository
+	knowledge []*types.Knowledge
+}
+
+func (r *fakeTagKnowledgeRepo) ListIDsByTagID(context.Context, uint64, string, string) ([]string, error) {
+	ids := make([]string, 0, len(r.knowledge))
+	for _, k := range r.knowledge {
+		ids = append(ids, k.ID)
+	}
+	return ids, nil
+}
+
+func (r *fakeTagKnowledgeRepo) GetKnowledgeBatch(context.Context, uint64, []string) ([]*types.Knowledge, error) {
+	return r.knowledge, nil
+}
+
+// fakeTagChunkRepo records the tags whose chunks were deleted
+type fakeTagChunkRepo struct {
+	interfaces.ChunkRepository
+	deletedTags []string
+}
+
+func (r *fakeTagChunkRepo) DeleteChunksByTagID(_ context.Context, _ uint64, _ string, tagID string, _ []string) ([]string, error) {
+	r.deletedTags = append(r.deletedTags, tagID)
+	return nil, nil
+}
+
+// fakeTagKnowledgeBaseService serves the knowledge base of the tag
+type fakeTagKnowledgeBaseService struct {
+	interfaces.KnowledgeBaseService
+	kb *types.KnowledgeBase
+}
+
+func (s *fakeTagKnowledgeBaseService) GetKnowledgeBaseByID(context.Context, string) (*types.KnowledgeBase, error) {
+	return s.kb, nil
+}
+
+func referenceTestContext() context.Context {
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	return context.WithValue(ctx, types.TenantInfoContextKey, &types.Tenant{ID: 1})
+}
+
+// assertResourceInUse checks that err is a 409 in use error with the given code listing wantRefs references
+func assertResourceInUse(t *testing.T, err error, code string, wantRefs int) {
+	t.Helper()
+	var inUse *types.ResourceInUseError
+	if !errors.As(err, &inUse) {
+		t.Fatalf("expected ResourceInUseError, got %v", err)
+	}
+	if appErr := inUse.AppError(); appErr.HTTPCode != http.StatusConflict || inUse.Code != code {
+		t.Errorf("status %d code %s, want %d %s", appErr.HTTPCode, inUse.Code, http.StatusConflict, code)
+	}
+	if len(inUse.References) != wantRefs {
+		t.Errorf("listed %d references, want %d: %+v", len(inUse.References), wantRefs, inUse.References)
+	}
+}
+
+func TestDeleteModelReferences(t *testing.T) {
+	tests := []struct {
+		name         string
+		force        bool
+		embedding    bool
+		wantInUse    int
+		wantDeleted  bool
+		wantKBUpdate bool
+	}{
+		{name: "in use without force", wantInUse: 2},
+		{name: "force clears references", force: true, wantDeleted: true, wantKBUpdate: true},
+		{name: "force keeps embedding models", force: true, embedding: true, wantInUse: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb := &types.KnowledgeBase{ID: "kb-1", Name: "Handbook", EmbeddingModelID: "embed", RerankModelID: "m-1"}
+			if tt.embedding {
+				kb.EmbeddingModelID = "m-1"
+			}
+			agent := &types.CustomAgent{ID: "agent-1", Name: "Helper"}
+			agent.Config.ModelID = "m-1"
+			kbRepo := &fakeKnowledgeBaseRepo{kbs: []*types.KnowledgeBase{kb}}
+			agentRepo := &fakeAgentRepo{agents: []*types.CustomAgent{agent}}
+			modelRepo := &fakeModelRepo{}
+			svc := NewModelService(modelRepo, kbRepo, agentRepo, nil, nil)
+
+			err := svc.DeleteModel(referenceTestContext(), "m-1", tt.force)
+			if tt.wantInUse > 0 {
+				assertResourceInUse(t, err, werrors.CodeModelInUse, tt.wantInUse)
+				if len(kbRepo.updated) > 0 || len(agentRepo.updated) > 0 {
+					t.Errorf("references changed on a rejected deletion: kbs %v agents %v", kbRepo.updated, agentRepo.updated)
+				}
+			} else if err != nil {
+				t.Fatalf("DeleteModel failed: %v", err)
+			}
+			if deleted := len(modelRepo.deleted) == 1; deleted != tt.wantDeleted {
+				t.Errorf("deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if tt.wantKBUpdate {
+				if kb.RerankModelID != "" || agent.Config.ModelID != "" {
+					t.Errorf("references kept: kb rerank %q, agent model %q", kb.RerankModelID, agent.Config.ModelID)
+				}
+				if len(kbRepo.updated) != 1 || len(agentRepo.updated) != 1 {
+					t.Errorf("updated kbs %v agents %v, want one each", kbRepo.updated, agentRepo.updated)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteTagReferences(t *testing.T) {
+	tests := []struct {
+		name           string
+		kbType         string
+		force          bool
+		knowledgeCount int64
+		chunkCount     int64
+		wantInUse      int
+		wantTotal      int64
+		wantDeleted    bool
+	}{
+		{
+			name: "document tag lists knowledge", kbType: types.KnowledgeBaseTypeDocument,
+			knowledgeCount: 2, chunkCount: 5, wantInUse: 2, wantTotal: 2,
+		},
+		{name: "faq tag only counts entries", kbType: types.KnowledgeBaseTypeFAQ, chunkCount: 3, wantTotal: 3},
+		{name: "force deletes faq entries and tag", kbType: types.KnowledgeBaseTypeFAQ, chunkCount: 3, force: true, wantDeleted: true},
+		{name: "unused tag", kbType: types.KnowledgeBaseTypeDocument, wantDeleted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb := &types.KnowledgeBase{ID: "kb-1", Type: tt.kbType}
+			tagRepo := &fakeTagRepo{
+				tag:            &types.KnowledgeTag{ID: "tag-1", KnowledgeBaseID: kb.ID},
+				knowledgeCount: tt.knowledgeCount,
+				chunkCount:     tt.chunkCount,
+			}
+			knowledgeRepo := &fakeTagKnowledgeRepo{knowledge: []*types.Knowledge{
+				{ID: "k-1", Title: "Policy"}, {ID: "k-2", Title: "Guide"},
+			}}
+			chunkRepo := &fakeTagChunkRepo{}
+			svc, _ := NewKnowledgeTagService(&fakeTagKnowledgeBaseService{kb: kb}, tagRepo, knowledgeRepo, chunkRepo, nil, nil, nil)
+
+			err := svc.DeleteTag(referenceTestContext(), "tag-1", tt.force, false, nil)
+			switch {
+			case tt.wantDeleted:
+				if err != nil {
+					t.Fatalf("DeleteTag failed: %v", err)
+				}
+			default:
+				assertResourceInUse(t, err, werrors.CodeTagInUse, tt.wantInUse)
+				var inUse *types.ResourceInUseError
+				errors.As(err, &inUse)
+				if inUse.Total != tt.wantTotal {
+					t.Errorf("total = %d, want %d", inUse.Total, tt.wantTotal)
+				}
+			}
+			if deleted := len(tagRepo.deleted) == 1; deleted != tt.wantDeleted {
+				t.Errorf("tag deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if tt.force && len(chunkRepo.deletedTags) != 1 {
+				t.Errorf("forced deletion removed chunks of %v, want the tag's", chunkRepo.deletedTags)
+			}
+		})
+	}
+}
+
+func TestReleaseAgentReferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		force       bool
+		wantInUse   int
+		wantUpdated []string
+	}{
+		{name: "in use without force", wantInUse: 1},
+		{name: "force removes the knowledge base", force: true, wantUpdated: []string{"agent-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			using := &types.CustomAgent{ID: "agent-1", Name: "Helper"}
+			using.Config.KnowledgeBases = []string{"kb-1", "kb-2"}
+			other := &types.CustomAgent{ID: "agent-2", Name: "Other"}
+			other.Config.KnowledgeBases = []string{"kb-2"}
+			agentRepo := &fakeAgentRepo{agents: []*types.CustomAgent{using, other}}
+			svc := &knowledgeBaseService{agentRepo: agentRepo}
+
+			err := svc.releaseAgentReferences(referenceTestContext(), 1, "kb-1", tt.force)
+			if tt.wantInUse > 0 {
+				assertResourceInUse(t, err, werrors.CodeKnowledgeBaseInUse, tt.wantInUse)
+			} else if err != nil {
+				t.Fatalf("releaseAgentReferences failed: %v", err)
+			}
+			if len(agentRepo.updated) != len(tt.wantUpdated) ||
+				(len(tt.wantUpdated) > 0 && agentRepo.updated[0] != tt.wantUpdated[0]) {
+				t.Errorf("updated agents %v, want %v", agentRepo.updated, tt.wantUpdated)
+			}
+			if tt.force && types.AgentUsesKnowledgeBase(using, "kb-1") {
+				t.Errorf("agent still uses the knowledge base: %v", using.Config.KnowledgeBases)
+			}
+		})
+	}
+}
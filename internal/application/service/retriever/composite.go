@@ -245,6 +245,37 @@ func (c *CompositeRetrieveEngine) BatchIndex(ctx context.Context,
 	return err
 }
 
+// BatchIndexVectors batch saves vector embeddings to the repositories serving vector retrieval only,
+// leaving keyword indices untouched. Used for additional vector spaces of a knowledge base.
+func (c *CompositeRetrieveEngine) BatchIndexVectors(ctx context.Context,
+	embedder embedding.Embedder, indexInfoList []*types.IndexInfo,
+) error {
+	ctx, span := tracing.ContextWithSpan(ctx, "CompositeRetrieveEngine.BatchIndexVectors")
+	defer span.End()
+	indexInfoList = common.Deduplicate(func(info *types.IndexInfo) string { return info.SourceID }, indexInfoList...)
+	err := c.concurrentExecWithError(ctx, func(ctx context.Context, engineInfo *engineInfo) error {
+		if !slices.Contains(engineInfo.retrieverType, types.VectorRetrieverType) {
+			return nil
+		}
+		if err := engineInfo.retrieveEngine.BatchIndex(
+			ctx,
+			embedder,
+			indexInfoList,
+			[]types.RetrieverType{types.VectorRetrieverType},
+		); err != nil {
+			logger.Errorf(ctx, "Repository %s failed to batch save vectors: %v", engineInfo.retrieveEngine.EngineType(), err)
+			return err
+		}
+		return nil
+	})
+	span.RecordError(err)
+	span.SetAttributes(
+		attribute.String("embedder", embedder.GetModelName()),
+		attribute.Int("index_info_count", len(indexInfoList)),
+	)
+	return err
+}
+
 // DeleteByChunkIDList deletes vector embeddings by chunk ID list from all registered repositories
 func (c *CompositeRetrieveEngine) DeleteByChunkIDList(ctx context.Context,
 	chunkIDList []string, dimension int, knowledgeType string,
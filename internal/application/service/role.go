@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Tencent/WeKnora/internal/application/repository"
+	"github.com/Tencent/WeKnora/internal/clock"
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+var (
+	// ErrInvalidRole is returned for a role other than admin, editor and viewer
+	ErrInvalidRole = errors.New("invalid role")
+	// ErrRoleUserNotFound is returned when assigning a role to a user who is not a user of the tenant
+	ErrRoleUserNotFound = errors.New("user not found in tenant")
+	// ErrOwnRoleChange is returned when users try to change or remove their own role
+	ErrOwnRoleChange = errors.New("users cannot change their own role")
+)
+
+// roleService implements the RoleService interface
+type roleService struct {
+	cfg         *config.Config
+	defaultRole string
+	repo        interfaces.RoleRepository
+	userService interfaces.UserService
+	clock       clock.Clock
+}
+
+// NewRoleService creates the service of the user roles, failing on an unknown default role
+func NewRoleService(
+	cfg *config.Config,
+	repo interfaces.RoleRepository,
+	userService interfaces.UserService,
+	clk clock.Clock,
+) (interfaces.RoleService, error) {
+	defaultRole := types.RoleViewer
+	if cfg.Tenant != nil && cfg.Tenant.DefaultRole != "" {
+		defaultRole = cfg.Tenant.DefaultRole
+	}
+	if err := types.ValidateRole(defaultRole); err != nil {
+		return nil, fmt.Errorf("tenant.default_role: %w", err)
+	}
+	return &roleService{
+		cfg:         cfg,
+		defaultRole: defaultRole,
+		repo:        repo,
+		userService: userService,
+		clock:       clk,
+	}, nil
+}
+
+// ResolveRole returns the role of a logged-in user in a tenant: users with cross-tenant access are admins,
+// other users have their assigned role or else the default role
+func (s *roleService) ResolveRole(ctx context.Context, tenantID uint64, user *types.User) (string, error) {
+	if user.CanAccessAllTenants {
+		return types.RoleAdmin, nil
+	}
+	assignment, err := s.repo.GetAssignment(ctx, tenantID, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if assignment == nil || types.ValidateRole(assignment.Role) != nil {
+		return s.defaultRole, nil
+	}
+	return assignment.Role, nil
+}
+
+// ListAssignments lists the role assignments of the current tenant
+func (s *roleService) ListAssignments(ctx context.Context) ([]*types.UserRole, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+	assignments, err := s.repo.ListAssignments(ctx, tenantID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"tenant_id": tenantID,
+		})
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// AssignRole assigns a role to a user of the current tenant. Users cannot change their own role, so that
+// the last admin of a tenant cannot be demoted by mistake.
+func (s *roleService) AssignRole(ctx context.Context, userID string, role string) (*types.UserRole, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+	if err := types.ValidateRole(role); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRole, err)
+	}
+	if err := s.checkTarget(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	assignment := &types.UserRole{
+		TenantID:  tenantID,
+		UserID:    userID,
+		Role:      role,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.UpsertAssignment(ctx, assignment); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"tenant_id": tenantID,
+			"user_id":   userID,
+		})
+		return nil, err
+	}
+	logger.Infof(ctx, "Assigned role %s to user %s in tenant %d", role, userID, tenantID)
+	return assignment, nil
+}
+
+// RemoveRole removes the role assignment of a user of the current tenant, who gets the default role again
+func (s *roleService) RemoveRole(ctx context.Context, userID string) error {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return ErrInvalidTenantID
+	}
+	if err := s.checkTarget(ctx, tenantID, userID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteAssignment(ctx, tenantID, userID); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"tenant_id": tenantID,
+			"user_id":   userID,
+		})
+		return err
+	}
+	logger.Infof(ctx, "Removed the role of user %s in tenant %d", userID, tenantID)
+	return nil
+}
+
+// checkTarget checks that the role of a user of the tenant may be changed by the current user. In
+// single-tenant mode every user acts on the default tenant, whatever tenant the user belongs to.
+func (s *roleService) checkTarget(ctx context.Context, tenantID uint64, userID string) error {
+	if current, ok := ctx.Value(types.UserContextKey).(*types.User); ok && current.ID == userID {
+		return ErrOwnRoleChange
+	}
+	user, err := s.userService.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrRoleUserNotFound
+		}
+		return err
+	}
+	if user.TenantID != tenantID && s.cfg.SingleTenantMode() == nil {
+		return ErrRoleUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/application/repository"
+	"github.com/Tencent/WeKnora/internal/clock"
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// roleTestRepo keeps the role assignments in memory
+type roleTestRepo struct {
+	assignments map[uint64]map[string]*types.UserRole
+}
+
+func (r *roleTestRepo) GetAssignment(ctx context.Context, tenantID uint64, userID string) (*types.UserRole, error) {
+	return r.assignments[tenantID][userID], nil
+}
+
+func (r *roleTestRepo) ListAssignments(ctx context.Context, tenantID uint64) ([]*types.UserRole, error) {
+	var assignments []*types.UserRole
+	for _, assignment := range r.assignments[tenantID] {
+		assignments = append(assignments, assignment)
+	}
+	return assignments, nil
+}
+
+func (r *roleTestRepo) UpsertAssignment(ctx context.Context, assignment *types.UserRole) error {
+	if r.assignments[assignment.TenantID] == nil {
+		r.assignments[assignment.TenantID] = map[string]*types.UserRole{}
+	}
+	r.assignments[assignment.TenantID][assignment.UserID] = assignment
+	return nil
+}
+
+func (r *roleTestRepo) DeleteAssignment(ctx context.Context, tenantID uint64, userID string) error {
+	delete(r.assignments[tenantID], userID)
+	return nil
+}
+
+// roleTestUsers knows users alice and bob of tenant 7 and eve of tenant 8
+type roleTestUsers struct {
+	interfaces.UserService
+}
+
+func (roleTestUsers) GetUserByID(ctx context.Context, id string) (*types.User, error) {
+	tenants := map[string]uint64{"alice": 7, "bob": 7, "eve": 8}
+	tenantID, ok := tenants[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	return &types.User{ID: id, TenantID: tenantID}, nil
+}
+
+func TestRoleService(t *testing.T) {
+	repo := &roleTestRepo{assignments: map[uint64]map[string]*types.UserRole{}}
+	svc, err := NewRoleService(&config.Config{}, repo, roleTestUsers{}, clock.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := &types.User{ID: "alice", TenantID: 7}
+	ctx := context.WithValue(context.WithValue(context.Background(), types.TenantIDContextKey, uint64(7)),
+		types.UserContextKey, alice)
+
+	resolve := func(user *types.User) string {
+		role, err := svc.ResolveRole(ctx, 7, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return role
+	}
+	if role := resolve(&types.User{ID: "bob", TenantID: 7}); role != types.RoleViewer {
+		t.Errorf("role without assignment = %s, want the default %s", role, types.RoleViewer)
+	}
+	if role := resolve(&types.User{ID: "root", CanAccessAllTenants: true}); role != types.RoleAdmin {
+		t.Errorf("role of a cross-tenant user = %s, want %s", role, types.RoleAdmin)
+	}
+
+	if _, err := svc.AssignRole(ctx, "bob", types.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	if role := resolve(&types.User{ID: "bob", TenantID: 7}); role != types.RoleEditor {
+		t.Errorf("assigned role = %s, want %s", role, types.RoleEditor)
+	}
+	if assignments, err := svc.ListAssignments(ctx); err != nil || len(assignments) != 1 {
+		t.Errorf("ListAssignments = %v, err = %v, want the assignment of bob", assignments, err)
+	}
+
+	if _, err := svc.AssignRole(ctx, "bob", "owner"); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("unknown role err = %v, want %v", err, ErrInvalidRole)
+	}
+	if _, err := svc.AssignRole(ctx, "alice", types.RoleAdmin); !errors.Is(err, ErrOwnRoleChange) {
+		t.Errorf("own role err = %v, want %v", err, ErrOwnRoleChange)
+	}
+	if _, err := svc.AssignRole(ctx, "eve", types.RoleAdmin); !errors.Is(err, ErrRoleUserNotFound) {
+		t.Errorf("user of another tenant err = %v, want %v", err, ErrRoleUserNotFound)
+	}
+	if _, err := svc.AssignRole(ctx, "mallory", types.RoleAdmin); !errors.Is(err, ErrRoleUserNotFound) {
+		t.Errorf("unknown user err = %v, want %v", err, ErrRoleUserNotFound)
+	}
+
+	if err := svc.RemoveRole(ctx, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if role := resolve(&types.User{ID: "bob", TenantID: 7}); role != types.RoleViewer {
+		t.Errorf("role after removal = %s, want the default %s", role, types.RoleViewer)
+	}
+
+	if _, err := NewRoleService(&config.Config{Tenant: &config.TenantConfig{DefaultRole: "owner"}},
+		repo, roleTestUsers{}, clock.New()); err == nil {
+		t.Error("unknown default role accepted")
+	}
+}
+
+// roleTestRegistry stores the registered users in memory
+type roleTestRegistry struct {
+	interfaces.UserRepository
+	users []*types.User
+}
+
+func (r *roleTestRegistry) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *roleTestRegistry) GetUserByUsername(ctx context.Context, username string) (*types.User, error) {
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *roleTestRegistry) CreateUser(ctx context.Context, user *types.User) error {
+	r.users = append(r.users, user)
+	return nil
+}
+
+type roleTestTenants struct {
+	interfaces.TenantService
+}
+
+func (roleTestTenants) CreateTenant(ctx context.Context, tenant *types.Tenant) (*types.Tenant, error) {
+	tenant.ID = 9
+	return tenant, nil
+}
+
+func TestRegisterAssignsAdminRole(t *testing.T) {
+	repo := &roleTestRepo{assignments: map[uint64]map[string]*types.UserRole{}}
+	users := &roleTestRegistry{}
+	svc := NewUserService(users, nil, roleTestTenants{}, repo, clock.New())
+
+	user, err := svc.Register(context.Background(), &types.RegisterRequest{
+		Username: "alice", Email: "alice@example.com", Password: "hunter22",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assignment := repo.assignments[9][user.ID]; assignment == nil || assignment.Role != types.RoleAdmin {
+		t.Errorf("assignment of the registering user = %+v, want %s of the new tenant", assignment, types.RoleAdmin)
+	}
+}
@@ -5,19 +5,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/analytics"
 	chatpipline "github.com/Tencent/WeKnora/internal/application/service/chat_pipline"
 	llmcontext "github.com/Tencent/WeKnora/internal/application/service/llmcontext"
 	"github.com/Tencent/WeKnora/internal/config"
 	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/i18n"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/chat"
 	"github.com/Tencent/WeKnora/internal/models/rerank"
 	"github.com/Tencent/WeKnora/internal/tracing"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -42,6 +47,8 @@ type sessionService struct {
 	knowledgeService     interfaces.KnowledgeService      // Service for knowledge operations
 	chunkService         interfaces.ChunkService          // Service for chunk operations
 	webSearchStateRepo   interfaces.WebSearchStateService // Service for web search state
+	agentRunRepo         interfaces.AgentRunRepository    // Repository for agent execution traces
+	analytics            *analytics.Emitter               // Analytics events, nil when disabled
 }
 
 // NewSessionService creates a new session service instance with all required dependencies
@@ -57,6 +64,8 @@ func NewSessionService(cfg *config.Config,
 	agentService interfaces.AgentService,
 	sessionStorage llmcontext.ContextStorage,
 	webSearchStateRepo interfaces.WebSearchStateService,
+	agentRunRepo interfaces.AgentRunRepository,
+	analyticsEmitter *analytics.Emitter,
 ) interfaces.SessionService {
 	return &sessionService{
 		cfg:                  cfg,
@@ -71,6 +80,8 @@ func NewSessionService(cfg *config.Config,
 		agentService:         agentService,
 		sessionStorage:       sessionStorage,
 		webSearchStateRepo:   webSearchStateRepo,
+		agentRunRepo:         agentRunRepo,
+		analytics:            analyticsEmitter,
 	}
 }
 
@@ -407,13 +418,17 @@ func (s *sessionService) KnowledgeQA(
 	webSearchEnabled bool,
 	eventBus *event.EventBus,
 	customAgent *types.CustomAgent,
+	attachments []*types.ChatAttachment,
+	historyDepth *int,
+	retrieval *types.RetrievalParams,
 ) error {
 	logger.Infof(
 		ctx,
-		"Knowledge base question answering parameters, session ID: %s, query: %s, webSearchEnabled: %v",
+		"Knowledge base question answering parameters, session ID: %s, query: %s, webSearchEnabled: %v, attachments: %d",
 		session.ID,
 		query,
 		webSearchEnabled,
+		len(attachments),
 	)
 
 	// Use custom agent's knowledge bases only if request didn't specify any
@@ -424,7 +439,11 @@ func (s *sessionService) KnowledgeQA(
 		logger.Infof(ctx, "KB resolution (quick-answer): hasExplicitMention=%v, RetrieveKBOnlyWhenMentioned=%v, KBSelectionMode=%s",
 			hasExplicitMention, customAgent.Config.RetrieveKBOnlyWhenMentioned, customAgent.Config.KBSelectionMode)
 	}
-	if hasExplicitMention {
+	if customAgent != nil && customAgent.Config.EffectiveRetrievalMode() == types.RetrievalModeNever {
+		knowledgeBaseIDs = nil
+		knowledgeIDs = nil
+		logger.Infof(ctx, "Retrieval mode is never, KB retrieval disabled for this agent")
+	} else if hasExplicitMention {
 		logger.Infof(ctx, "Using request-specified targets (ignoring agent config): kbs=%v, docs=%v", knowledgeBaseIDs, knowledgeIDs)
 	} else if customAgent != nil && customAgent.Config.RetrieveKBOnlyWhenMentioned {
 		// User didn't mention any KB/file, and the setting requires explicit mention
@@ -451,7 +470,7 @@ func (s *sessionService) KnowledgeQA(
 	rerankThreshold := s.cfg.Conversation.RerankThreshold
 	maxRounds := s.cfg.Conversation.MaxRounds
 	fallbackStrategy := types.FallbackStrategy(s.cfg.Conversation.FallbackStrategy)
-	fallbackResponse := s.cfg.Conversation.FallbackResponse
+	fallbackResponse := i18n.LocalizeDefault(ctx, "chat.fallback_response", s.cfg.Conversation.FallbackResponse)
 	fallbackPrompt := s.cfg.Conversation.FallbackPrompt
 	enableRewrite := s.cfg.Conversation.EnableRewrite
 	enableQueryExpansion := s.cfg.Conversation.EnableQueryExpansion
@@ -463,7 +482,10 @@ func (s *sessionService) KnowledgeQA(
 		Temperature:         s.cfg.Conversation.Summary.Temperature,
 		NoMatchPrefix:       s.cfg.Conversation.Summary.NoMatchPrefix,
 		MaxCompletionTokens: s.cfg.Conversation.Summary.MaxCompletionTokens,
+		ContextWindow:       s.cfg.Conversation.Summary.ContextWindow,
 		Thinking:            s.cfg.Conversation.Summary.Thinking,
+
+		OversizedChunkStrategy: s.cfg.Conversation.Summary.OversizedChunkStrategy,
 	}
 
 	// Set default fallback strategy if not set
@@ -579,6 +601,25 @@ func (s *sessionService) KnowledgeQA(
 		logger.Warnf(ctx, "Failed to build search targets: %v", err)
 	}
 
+	// Rerank with the knowledge bases' own rerank model unless the agent selects one
+	if rerankModelID == "" {
+		rerankModelID = s.selectRerankModelID(ctx, searchTargetKnowledgeBaseIDs(searchTargets))
+	}
+
+	// Resolve how many prior turns to include: request override, then the knowledge bases' setting
+	historyDepth = s.resolveHistoryDepth(ctx, historyDepth, searchTargetKnowledgeBaseIDs(searchTargets))
+	if customAgent != nil && !customAgent.Config.MultiTurnEnabled {
+		noHistory := 0
+		historyDepth = &noHistory
+	}
+
+	// Extract message attachments in memory, they are only used as context for this turn
+	attachmentChunks, err := s.knowledgeService.ExtractChatAttachments(ctx, knowledgeBaseIDs, attachments)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to extract message attachments: %v", err)
+		return fmt.Errorf("failed to extract attachments: %w", err)
+	}
+
 	// Create chat management object with session settings
 	logger.Infof(
 		ctx,
@@ -603,6 +644,7 @@ func (s *sessionService) KnowledgeQA(
 		RerankTopK:           rerankTopK,
 		RerankThreshold:      rerankThreshold,
 		MaxRounds:            maxRounds,
+		HistoryDepth:         historyDepth,
 		ChatModelID:          chatModelID,
 		SummaryConfig:        summaryConfig,
 		FallbackStrategy:     fallbackStrategy,
@@ -619,20 +661,40 @@ func (s *sessionService) KnowledgeQA(
 		FAQPriorityEnabled:       faqPriorityEnabled,
 		FAQDirectAnswerThreshold: faqDirectAnswerThreshold,
 		FAQScoreBoost:            faqScoreBoost,
+		Attachments:              attachmentChunks,
+	}
+	// Session defaults and request overrides take precedence over the agent's retrieval settings
+	if retrieval != nil {
+		retrieval.Apply(chatManage)
+		logger.Infof(ctx, "Using retrieval params: embedding_top_k=%d, rerank_top_k=%d, rerank_model_id=%s",
+			chatManage.EmbeddingTopK, chatManage.RerankTopK, chatManage.RerankModelID)
+	}
+
+	// Answer without calling the model when the only sources are knowledge bases without content
+	if !webSearchEnabled && len(attachmentChunks) == 0 {
+		if message, empty := s.emptySearchTargetsMessage(ctx, session.TenantID, searchTargets); empty {
+			logger.Info(ctx, "Knowledge bases have no enabled chunks, answering with empty knowledge base message")
+			chatManage.ChatResponse = &types.ChatResponse{Content: message}
+			s.emitFallbackAnswer(ctx, chatManage, message)
+			return nil
+		}
 	}
 
 	// Determine pipeline based on knowledge bases availability and web search setting
 	// If no knowledge bases are selected AND web search is disabled, use pure chat pipeline
 	// Otherwise use rag_stream pipeline (which handles both KB search and web search)
 	var pipeline []types.EventType
-	if len(knowledgeBaseIDs) == 0 && len(knowledgeIDs) == 0 && !webSearchEnabled {
+	if len(knowledgeBaseIDs) == 0 && len(knowledgeIDs) == 0 && !webSearchEnabled && len(attachmentChunks) > 0 {
+		logger.Info(ctx, "No knowledge bases selected and web search disabled, using attachment_stream pipeline")
+		pipeline = types.Pipline["attachment_stream"]
+	} else if len(knowledgeBaseIDs) == 0 && len(knowledgeIDs) == 0 && !webSearchEnabled {
 		logger.Info(ctx, "No knowledge bases selected and web search disabled, using chat pipeline")
 		// For pure chat, UserContent is the Query (since INTO_CHAT_MESSAGE is skipped)
 		chatManage.UserContent = query
 
 		// Use chat_history_stream if multi-turn is enabled, otherwise use chat_stream
-		if maxRounds > 0 {
-			logger.Infof(ctx, "Multi-turn enabled with maxRounds=%d, using chat_history_stream pipeline", maxRounds)
+		if rounds := chatManage.HistoryRounds(0); rounds > 0 {
+			logger.Infof(ctx, "Multi-turn enabled with maxRounds=%d, using chat_history_stream pipeline", rounds)
 			pipeline = types.Pipline["chat_history_stream"]
 		} else {
 			logger.Info(ctx, "Multi-turn disabled, using chat_stream pipeline")
@@ -658,14 +720,21 @@ func (s *sessionService) KnowledgeQA(
 	}
 
 	// Emit references event if we have search results
-	if len(chatManage.MergeResult) > 0 {
-		logger.Infof(ctx, "Emitting references event with %d results", len(chatManage.MergeResult))
+	// Attachment chunks are left out so that their content is not stored with the message
+	references := make([]*types.SearchResult, 0, len(chatManage.MergeResult))
+	for _, result := range chatManage.MergeResult {
+		if result.ChunkType != string(types.ChunkTypeAttachment) {
+			references = append(references, result)
+		}
+	}
+	if len(references) > 0 {
+		logger.Infof(ctx, "Emitting references event with %d results", len(references))
 		if err := eventBus.Emit(ctx, event.Event{
 			ID:        generateEventID("references"),
 			Type:      event.EventAgentReferences,
 			SessionID: session.ID,
 			Data: event.AgentReferencesData{
-				References: chatManage.MergeResult,
+				References: references,
 			},
 		}); err != nil {
 			logger.Errorf(ctx, "Failed to emit references event: %v", err)
@@ -801,6 +870,28 @@ func (s *sessionService) selectChatModelID(
 	return "", errors.New("no chat model ID available: no knowledge bases configured and no available models")
 }
 
+// selectRerankModelID returns the rerank model of the first knowledge base that configures one,
+// or an empty ID when none does and reranking is skipped
+func (s *sessionService) selectRerankModelID(ctx context.Context, knowledgeBaseIDs []string) string {
+	seen := make(map[string]bool, len(knowledgeBaseIDs))
+	for _, kbID := range knowledgeBaseIDs {
+		if kbID == "" || seen[kbID] {
+			continue
+		}
+		seen[kbID] = true
+		kb, err := s.knowledgeBaseService.GetKnowledgeBaseByID(ctx, kbID)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to get knowledge base %s for rerank model: %v", kbID, err)
+			continue
+		}
+		if kb.RerankModelID != "" {
+			logger.Infof(ctx, "Using rerank model from knowledge base %s: %s", kbID, kb.RerankModelID)
+			return kb.RerankModelID
+		}
+	}
+	return ""
+}
+
 // resolveKnowledgeBasesFromAgent resolves knowledge base IDs based on agent's KBSelectionMode
 // Returns the resolved knowledge base IDs based on the selection mode:
 //   - "all": fetches all knowledge bases for the tenant
@@ -912,6 +1003,12 @@ func (s *sessionService) KnowledgeQAByEvent(ctx context.Context,
 	defer span.End()
 
 	logger.Info(ctx, "Start processing knowledge base question answering through events")
+	ctx = s.startQueryAnalytics(ctx, chatManage)
+	if chatManage.EventBus != nil {
+		s.watchAnswerAnalytics(ctx, chatManage)
+	} else {
+		defer s.emitCompletionAnalytics(ctx, chatManage)
+	}
 	logger.Infof(ctx, "Knowledge base question answering parameters, session ID: %s,  query: %s",
 		chatManage.SessionID, chatManage.Query)
 
@@ -930,9 +1027,22 @@ func (s *sessionService) KnowledgeQAByEvent(ctx context.Context,
 	)
 
 	// Process each event in sequence
-	for _, eventType := range eventList {
+	for i := 0; i < len(eventList); i++ {
+		eventType := eventList[i]
 		logger.Infof(ctx, "Starting to trigger event: %v", eventType)
 		err := s.eventManager.Trigger(ctx, eventType, chatManage)
+		if err == chatpipline.ErrSearchNothing || (err == nil && eventType == types.CHUNK_MERGE) {
+			s.emitRetrievalAnalytics(ctx, chatManage)
+		}
+
+		// Without retrieved content, answer from the message attachments if there are any
+		if err == chatpipline.ErrSearchNothing && len(chatManage.Attachments) > 0 {
+			if resume := slices.Index(eventList, types.ATTACHMENT_MERGE); resume > i {
+				logger.Warnf(ctx, "Event %v triggered, search result is empty, answering from attachments", eventType)
+				i = resume - 1
+				continue
+			}
+		}
 
 		// Handle case where search returns no results
 		if err == chatpipline.ErrSearchNothing {
@@ -946,6 +1056,18 @@ func (s *sessionService) KnowledgeQAByEvent(ctx context.Context,
 			return nil
 		}
 
+		// Handle case where the confidence gate refuses to answer
+		if err == chatpipline.ErrLowConfidence {
+			logger.Warnf(
+				ctx,
+				"Event %v triggered, confidence below threshold, using fallback response, strategy: %v",
+				eventType,
+				chatManage.FallbackStrategy,
+			)
+			s.handleFallbackResponse(ctx, chatManage)
+			return nil
+		}
+
 		// Handle other errors
 		if err != nil {
 			logger.Errorf(ctx, "Event triggering failed, event: %v, error type: %s, description: %s, error: %v",
@@ -1003,23 +1125,24 @@ func (s *sessionService) SearchKnowledge(ctx context.Context,
 		RerankTopK:       s.cfg.Conversation.RerankTopK,       // Use default configuration
 		RerankThreshold:  s.cfg.Conversation.RerankThreshold,  // Use default configuration
 		MaxRounds:        s.cfg.Conversation.MaxRounds,
+		RerankModelID:    s.selectRerankModelID(ctx, searchTargetKnowledgeBaseIDs(searchTargets)),
 	}
 
-	// Get default models
-	models, err := s.modelService.ListModels(ctx)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to get models: %v", err)
-		return nil, err
-	}
-
-	// Find the first available rerank model
-	for _, model := range models {
-		if model == nil {
-			continue
+	// Fall back to the first available rerank model when no knowledge base configures one
+	if chatManage.RerankModelID == "" {
+		models, err := s.modelService.ListModels(ctx)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to get models: %v", err)
+			return nil, err
 		}
-		if model.Type == types.ModelTypeRerank {
-			chatManage.RerankModelID = model.ID
-			break
+		for _, model := range models {
+			if model == nil {
+				continue
+			}
+			if model.Type == types.ModelTypeRerank {
+				chatManage.RerankModelID = model.ID
+				break
+			}
 		}
 	}
 
@@ -1076,6 +1199,65 @@ func (s *sessionService) SearchKnowledge(ctx context.Context,
 	return chatManage.MergeResult, nil
 }
 
+// EvaluateConfidenceGate runs the confidence gate over labeled queries against a knowledge base.
+// override replaces the knowledge base policy when provided. Besides the outcome at the configured
+// threshold, the report sweeps thresholds so a better one can be chosen before enabling the gate.
+func (s *sessionService) EvaluateConfidenceGate(ctx context.Context,
+	knowledgeBaseID string, cases []types.ConfidenceEvalCase, override *types.ConfidenceGateConfig,
+) (*types.ConfidenceEvalReport, error) {
+	kb, err := s.knowledgeBaseService.GetKnowledgeBaseByID(ctx, knowledgeBaseID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+		return nil, err
+	}
+
+	policy := kb.ConfidenceGateConfig
+	if override != nil {
+		policy = override
+	}
+	report := &types.ConfidenceEvalReport{Config: policy}
+	logger.Infof(ctx, "Evaluating confidence gate, knowledge base ID: %s, cases: %d", knowledgeBaseID, len(cases))
+
+	for _, c := range cases {
+		item := &types.ConfidenceEvalItem{Query: c.Query, Answerable: c.Answerable}
+		report.Items = append(report.Items, item)
+
+		results, err := s.SearchKnowledge(ctx, []string{knowledgeBaseID}, nil, c.Query)
+		if err != nil {
+			logger.Warnf(ctx, "Confidence evaluation search failed, query: %s, error: %v", c.Query, err)
+			item.Error = err.Error()
+			continue
+		}
+
+		signals := types.ConfidenceSignalsFromResults(results)
+		if policy != nil && policy.SelfAssessment && len(results) > 0 && kb.SummaryModelID != "" {
+			score, err := chatpipline.AssessAnswerability(ctx, s.modelService, kb.SummaryModelID, c.Query, results)
+			if err != nil {
+				logger.Warnf(ctx, "Confidence self-assessment failed, query: %s, error: %v", c.Query, err)
+			} else {
+				signals.SelfAssessment = &score
+			}
+		}
+		item.Result = policy.Evaluate(signals)
+		item.Correct = item.Result.Answer == c.Answerable
+	}
+
+	report.Summary = report.ThresholdStat(policy.EffectiveThreshold())
+	best := report.Summary
+	for step := 1; step < 20; step++ {
+		stat := report.ThresholdStat(float64(step) * 0.05)
+		report.Sweep = append(report.Sweep, stat)
+		if stat.Accuracy > best.Accuracy {
+			best = stat
+		}
+	}
+	report.BestThreshold = best.Threshold
+
+	logger.Infof(ctx, "Confidence gate evaluation completed, accuracy: %.4f, best threshold: %.2f (accuracy %.4f)",
+		report.Summary.Accuracy, best.Threshold, best.Accuracy)
+	return report, nil
+}
+
 // AgentQA performs agent-based question answering with conversation history and streaming support
 // customAgent is optional - if provided, uses custom agent configuration instead of tenant defaults
 // summaryModelID is optional - if provided, overrides the model from customAgent config
@@ -1089,6 +1271,7 @@ func (s *sessionService) AgentQA(
 	customAgent *types.CustomAgent,
 	knowledgeBaseIDs []string,
 	knowledgeIDs []string,
+	historyDepth *int,
 ) error {
 	sessionID := session.ID
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
@@ -1114,90 +1297,8 @@ func (s *sessionService) AgentQA(
 	// Ensure defaults are set
 	customAgent.EnsureDefaults()
 
-	// Create runtime AgentConfig from customAgent
-	// Note: tenantInfo.AgentConfig is deprecated, all config comes from customAgent now
-	agentConfig := &types.AgentConfig{
-		MaxIterations:               customAgent.Config.MaxIterations,
-		ReflectionEnabled:           customAgent.Config.ReflectionEnabled,
-		Temperature:                 customAgent.Config.Temperature,
-		WebSearchEnabled:            customAgent.Config.WebSearchEnabled,
-		WebSearchMaxResults:         customAgent.Config.WebSearchMaxResults,
-		MultiTurnEnabled:            customAgent.Config.MultiTurnEnabled,
-		HistoryTurns:                customAgent.Config.HistoryTurns,
-		MCPSelectionMode:            customAgent.Config.MCPSelectionMode,
-		MCPServices:                 customAgent.Config.MCPServices,
-		Thinking:                    customAgent.Config.Thinking,
-		RetrieveKBOnlyWhenMentioned: customAgent.Config.RetrieveKBOnlyWhenMentioned,
-	}
-
-	// Resolve knowledge bases: request-level @ mentions take priority over agent config
-	// If RetrieveKBOnlyWhenMentioned is enabled and no @ mentions, don't use KB at all
-	hasExplicitMention := len(knowledgeBaseIDs) > 0 || len(knowledgeIDs) > 0
-	logger.Infof(ctx, "KB resolution: hasExplicitMention=%v, RetrieveKBOnlyWhenMentioned=%v, KBSelectionMode=%s",
-		hasExplicitMention, agentConfig.RetrieveKBOnlyWhenMentioned, customAgent.Config.KBSelectionMode)
-	if hasExplicitMention {
-		// User explicitly specified via @ mention
-		if len(knowledgeBaseIDs) > 0 {
-			agentConfig.KnowledgeBases = knowledgeBaseIDs
-			logger.Infof(ctx, "Using request-specified knowledge bases: %v", knowledgeBaseIDs)
-		}
-		if len(knowledgeIDs) > 0 {
-			agentConfig.KnowledgeIDs = knowledgeIDs
-			logger.Infof(ctx, "Using request-specified knowledge IDs: %v", knowledgeIDs)
-		}
-	} else if agentConfig.RetrieveKBOnlyWhenMentioned {
-		// User didn't mention any KB/file, and the setting requires explicit mention
-		agentConfig.KnowledgeBases = nil
-		agentConfig.KnowledgeIDs = nil
-		logger.Infof(ctx, "RetrieveKBOnlyWhenMentioned is enabled and no @ mention found, KB retrieval disabled for this request")
-	} else {
-		// Use agent's configured knowledge bases based on KBSelectionMode
-		agentConfig.KnowledgeBases = s.resolveKnowledgeBasesFromAgent(ctx, customAgent)
-	}
-
-	// Use custom agent's allowed tools if specified, otherwise use defaults
-	if len(customAgent.Config.AllowedTools) > 0 {
-		agentConfig.AllowedTools = customAgent.Config.AllowedTools
-	} else {
-		agentConfig.AllowedTools = tools.DefaultAllowedTools()
-	}
-
-	// Use custom agent's system prompt if specified
-	if customAgent.Config.SystemPrompt != "" {
-		agentConfig.UseCustomSystemPrompt = true
-		agentConfig.SystemPrompt = customAgent.Config.SystemPrompt
-	}
-
-	logger.Infof(ctx, "Custom agent config applied: MaxIterations=%d, Temperature=%.2f, AllowedTools=%v, WebSearchEnabled=%v",
-		agentConfig.MaxIterations, agentConfig.Temperature, agentConfig.AllowedTools, agentConfig.WebSearchEnabled)
-
-	// Set web search max results from tenant config if not set (default: 5)
-	if agentConfig.WebSearchMaxResults == 0 {
-		agentConfig.WebSearchMaxResults = 5
-		if tenantInfo.WebSearchConfig != nil && tenantInfo.WebSearchConfig.MaxResults > 0 {
-			agentConfig.WebSearchMaxResults = tenantInfo.WebSearchConfig.MaxResults
-		}
-	}
-
-	logger.Infof(ctx, "Merged agent config from tenant %d and session %s", tenantInfo.ID, sessionID)
-
-	// Log knowledge bases if present
-	if len(agentConfig.KnowledgeBases) > 0 {
-		logger.Infof(ctx, "Agent configured with %d knowledge base(s): %v",
-			len(agentConfig.KnowledgeBases), agentConfig.KnowledgeBases)
-	} else {
-		// Allow running without knowledge bases (Pure Agent mode)
-		logger.Infof(ctx, "No knowledge bases specified for agent, running in pure agent mode")
-	}
-
-	// Build search targets for agent (pre-compute once to avoid repeated queries)
-	searchTargets, err := s.buildSearchTargets(ctx, tenantInfo.ID, agentConfig.KnowledgeBases, agentConfig.KnowledgeIDs)
-	if err != nil {
-		logger.Warnf(ctx, "Failed to build search targets for agent: %v", err)
-		// Continue without search targets, the tool will handle empty targets
-	}
-	agentConfig.SearchTargets = searchTargets
-	logger.Infof(ctx, "Agent search targets built: %d targets", len(searchTargets))
+	agentConfig := s.buildAgentConfig(ctx, customAgent, tenantInfo, sessionID, knowledgeBaseIDs, knowledgeIDs)
+	s.emitAgentQueryAnalytics(ctx, sessionID, assistantMessageID, query, agentConfig.KnowledgeBases)
 
 	// Get summary model: prioritize request's summaryModelID, then custom agent config
 	// Note: tenantInfo.ConversationConfig is deprecated, all config comes from customAgent now
@@ -1223,9 +1324,10 @@ func (s *sessionService) AgentQA(
 
 	// Get rerank model from custom agent config (only required when knowledge bases are configured)
 	var rerankModel rerank.Reranker
+	var rerankModelID string
 	hasKnowledge := len(agentConfig.KnowledgeBases) > 0 || len(agentConfig.KnowledgeIDs) > 0
 	if hasKnowledge {
-		rerankModelID := customAgent.Config.RerankModelID
+		rerankModelID = customAgent.Config.RerankModelID
 		if rerankModelID == "" {
 			logger.Warnf(ctx, "No rerank model configured for custom agent %s, but knowledge bases are specified", customAgent.ID)
 			return errors.New("rerank model (rerank_model_id) is not configured in custom agent settings")
@@ -1269,6 +1371,10 @@ func (s *sessionService) AgentQA(
 		// Multi-turn disabled, clear history
 		logger.Infof(ctx, "Multi-turn disabled for this agent, clearing history context")
 		llmContext = []chat.Message{}
+	} else if depth := s.resolveHistoryDepth(ctx, historyDepth, agentConfig.KnowledgeBases); depth != nil && *depth == 0 {
+		// A history depth of 0 answers without history; positive depths are left to context compression
+		logger.Infof(ctx, "History depth is 0, clearing history context")
+		llmContext = []chat.Message{}
 	}
 
 	// Create agent engine with EventBus and ContextManager
@@ -1290,7 +1396,26 @@ func (s *sessionService) AgentQA(
 	// Execute agent with streaming (asynchronously)
 	// Events will be emitted to EventBus and handled by the Handler layer
 	logger.Info(ctx, "Executing agent with streaming")
-	if _, err := engine.Execute(ctx, sessionID, assistantMessageID, query, llmContext); err != nil {
+	run := &types.AgentRun{
+		TenantID:     tenantID,
+		SessionID:    sessionID,
+		MessageID:    assistantMessageID,
+		AgentID:      customAgent.ID,
+		AgentVersion: customAgent.PublishedVersion,
+		Input: types.AgentRunInput{
+			Query:                     query,
+			ModelID:                   effectiveModelID,
+			RerankModelID:             rerankModelID,
+			Config:                    *agentConfig,
+			History:                   toAgentRunMessages(llmContext),
+			MentionedKnowledgeBaseIDs: knowledgeBaseIDs,
+			MentionedKnowledgeIDs:     knowledgeIDs,
+		},
+	}
+	startTime := time.Now()
+	state, err := engine.Execute(ctx, sessionID, assistantMessageID, query, llmContext)
+	s.recordAgentRun(ctx, run, state, err, startTime)
+	if err != nil {
 		logger.Errorf(ctx, "Agent execution failed: %v", err)
 		// Emit error event to the EventBus used by this agent
 		eventBus.Emit(ctx, event.Event{
@@ -1302,11 +1427,258 @@ func (s *sessionService) AgentQA(
 				SessionID: sessionID,
 			},
 		})
+	} else {
+		s.emitAgentAnswerAnalytics(ctx, run, state, startTime)
 	}
 	// Return empty - events will be handled by Handler via EventBus subscription
 	return nil
 }
 
+// ReplayAgentRun re-executes the inputs of a recorded agent run without touching the session history
+// If customAgent is nil the recorded configuration is reused, otherwise the config is rebuilt from customAgent
+func (s *sessionService) ReplayAgentRun(
+	ctx context.Context,
+	original *types.AgentRun,
+	customAgent *types.CustomAgent,
+) (*types.AgentRun, error) {
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+
+	input := original.Input
+	run := &types.AgentRun{
+		TenantID:     original.TenantID,
+		SessionID:    original.SessionID,
+		MessageID:    original.MessageID,
+		AgentID:      original.AgentID,
+		AgentVersion: original.AgentVersion,
+		ReplayOf:     original.ID,
+	}
+
+	agentConfig := &input.Config
+	if customAgent != nil {
+		customAgent.EnsureDefaults()
+		agentConfig = s.buildAgentConfig(ctx, customAgent, tenantInfo, original.SessionID,
+			input.MentionedKnowledgeBaseIDs, input.MentionedKnowledgeIDs)
+		input.RerankModelID = customAgent.Config.RerankModelID
+		run.AgentVersion = customAgent.PublishedVersion
+	} else {
+		// Search targets are runtime-only and not part of the recorded config
+		searchTargets, err := s.buildSearchTargets(ctx, tenantInfo.ID, agentConfig.KnowledgeBases, agentConfig.KnowledgeIDs)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to build search targets for replay: %v", err)
+		}
+		agentConfig.SearchTargets = searchTargets
+	}
+	input.Config = *agentConfig
+	run.Input = input
+
+	logger.Infof(ctx, "Replaying agent run %s for message %s, agent version: %d",
+		original.ID, original.MessageID, run.AgentVersion)
+
+	chatModel, err := s.modelService.GetChatModel(ctx, input.ModelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat model: %w", err)
+	}
+	var rerankModel rerank.Reranker
+	if len(agentConfig.KnowledgeBases) > 0 || len(agentConfig.KnowledgeIDs) > 0 {
+		if input.RerankModelID == "" {
+			return nil, errors.New("rerank model is required when knowledge bases are configured")
+		}
+		rerankModel, err = s.modelService.GetRerankModel(ctx, input.RerankModelID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rerank model: %w", err)
+		}
+	}
+
+	history := make([]chat.Message, 0, len(input.History))
+	for _, m := range input.History {
+		history = append(history, chat.Message{
+			Role: m.Role, Content: m.Content, Name: m.Name, ToolCallID: m.ToolCallID,
+		})
+	}
+
+	// A private event bus with no subscribers and no context manager keeps the replay
+	// invisible to the live session: nothing is streamed and nothing is written to history
+	engine, err := s.agentService.CreateAgentEngine(
+		ctx, agentConfig, chatModel, rerankModel, event.NewEventBus(), nil, original.SessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	state, err := engine.Execute(ctx, original.SessionID, original.MessageID, input.Query, history)
+	s.recordAgentRun(ctx, run, state, err, startTime)
+	return run, nil
+}
+
+// recordAgentRun completes the run with the execution result and stores it
+// Failures to store the trace are logged and never affect the user-facing answer
+func (s *sessionService) recordAgentRun(
+	ctx context.Context,
+	run *types.AgentRun,
+	state *types.AgentState,
+	execErr error,
+	startTime time.Time,
+) {
+	run.ID = uuid.New().String()
+	run.CreatedAt = startTime
+	run.DurationMs = time.Since(startTime).Milliseconds()
+	run.Status = types.AgentRunStatusSucceeded
+	if execErr != nil {
+		run.Status = types.AgentRunStatusFailed
+		run.Error = execErr.Error()
+	}
+	if state != nil {
+		run.Steps = redactAgentSteps(state.RoundSteps)
+		run.FinalAnswer = state.FinalAnswer
+		run.StopReason = state.StopReason
+		run.TokensUsed = state.TokensUsed
+	}
+	if run.Steps == nil {
+		run.Steps = types.AgentSteps{}
+	}
+
+	if err := s.agentRunRepo.CreateRun(ctx, run); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"message_id": run.MessageID,
+			"session_id": run.SessionID,
+		})
+	}
+}
+
+// redactAgentSteps copies the steps with secrets removed from tool inputs
+func redactAgentSteps(steps []types.AgentStep) types.AgentSteps {
+	redacted := make(types.AgentSteps, 0, len(steps))
+	for _, step := range steps {
+		toolCalls := make([]types.ToolCall, 0, len(step.ToolCalls))
+		for _, tc := range step.ToolCalls {
+			tc.Args = secutils.RedactSensitiveFields(tc.Args)
+			toolCalls = append(toolCalls, tc)
+		}
+		step.ToolCalls = toolCalls
+		redacted = append(redacted, step)
+	}
+	return redacted
+}
+
+// toAgentRunMessages converts LLM context messages into their recorded form
+func toAgentRunMessages(messages []chat.Message) []types.AgentRunMessage {
+	result := make([]types.AgentRunMessage, 0, len(messages))
+	for _, m := range messages {
+		result = append(result, types.AgentRunMessage{
+			Role: m.Role, Content: m.Content, Name: m.Name, ToolCallID: m.ToolCallID,
+		})
+	}
+	return result
+}
+
+// buildAgentConfig creates the runtime agent configuration from a custom agent
+// Request-level @ mentions (knowledgeBaseIDs / knowledgeIDs) take priority over the agent's configured knowledge bases
+func (s *sessionService) buildAgentConfig(
+	ctx context.Context,
+	customAgent *types.CustomAgent,
+	tenantInfo *types.Tenant,
+	sessionID string,
+	knowledgeBaseIDs []string,
+	knowledgeIDs []string,
+) *types.AgentConfig {
+	// Create runtime AgentConfig from customAgent
+	// Note: tenantInfo.AgentConfig is deprecated, all config comes from customAgent now
+	agentConfig := &types.AgentConfig{
+		MaxIterations:               customAgent.Config.MaxIterations,
+		MaxTotalTokens:              customAgent.Config.MaxTotalTokens,
+		MaxExecutionSeconds:         customAgent.Config.MaxExecutionSeconds,
+		ReflectionEnabled:           customAgent.Config.ReflectionEnabled,
+		Temperature:                 customAgent.Config.Temperature,
+		WebSearchEnabled:            customAgent.Config.WebSearchEnabled,
+		WebSearchMaxResults:         customAgent.Config.WebSearchMaxResults,
+		MultiTurnEnabled:            customAgent.Config.MultiTurnEnabled,
+		HistoryTurns:                customAgent.Config.HistoryTurns,
+		MCPSelectionMode:            customAgent.Config.MCPSelectionMode,
+		MCPServices:                 customAgent.Config.MCPServices,
+		Thinking:                    customAgent.Config.Thinking,
+		RetrieveKBOnlyWhenMentioned: customAgent.Config.RetrieveKBOnlyWhenMentioned,
+		RetrievalMode:               customAgent.Config.EffectiveRetrievalMode(),
+	}
+
+	// Resolve knowledge bases: request-level @ mentions take priority over agent config
+	// If RetrieveKBOnlyWhenMentioned is enabled and no @ mentions, don't use KB at all
+	// Retrieval mode "never" overrides both; without knowledge the knowledge tools are not registered
+	hasExplicitMention := len(knowledgeBaseIDs) > 0 || len(knowledgeIDs) > 0
+	logger.Infof(ctx, "KB resolution: hasExplicitMention=%v, RetrieveKBOnlyWhenMentioned=%v, KBSelectionMode=%s, RetrievalMode=%s",
+		hasExplicitMention, agentConfig.RetrieveKBOnlyWhenMentioned, customAgent.Config.KBSelectionMode, agentConfig.RetrievalMode)
+	if agentConfig.RetrievalMode == types.RetrievalModeNever {
+		agentConfig.KnowledgeBases = nil
+		agentConfig.KnowledgeIDs = nil
+		logger.Infof(ctx, "Retrieval mode is never, KB retrieval disabled for this agent")
+	} else if hasExplicitMention {
+		// User explicitly specified via @ mention
+		if len(knowledgeBaseIDs) > 0 {
+			agentConfig.KnowledgeBases = knowledgeBaseIDs
+			logger.Infof(ctx, "Using request-specified knowledge bases: %v", knowledgeBaseIDs)
+		}
+		if len(knowledgeIDs) > 0 {
+			agentConfig.KnowledgeIDs = knowledgeIDs
+			logger.Infof(ctx, "Using request-specified knowledge IDs: %v", knowledgeIDs)
+		}
+	} else if agentConfig.RetrieveKBOnlyWhenMentioned {
+		// User didn't mention any KB/file, and the setting requires explicit mention
+		agentConfig.KnowledgeBases = nil
+		agentConfig.KnowledgeIDs = nil
+		logger.Infof(ctx, "RetrieveKBOnlyWhenMentioned is enabled and no @ mention found, KB retrieval disabled for this request")
+	} else {
+		// Use agent's configured knowledge bases based on KBSelectionMode
+		agentConfig.KnowledgeBases = s.resolveKnowledgeBasesFromAgent(ctx, customAgent)
+	}
+
+	// Use custom agent's allowed tools if specified, otherwise use defaults
+	if len(customAgent.Config.AllowedTools) > 0 {
+		agentConfig.AllowedTools = customAgent.Config.AllowedTools
+	} else {
+		agentConfig.AllowedTools = tools.DefaultAllowedTools()
+	}
+
+	// Use custom agent's system prompt if specified
+	if customAgent.Config.SystemPrompt != "" {
+		agentConfig.UseCustomSystemPrompt = true
+		agentConfig.SystemPrompt = customAgent.Config.SystemPrompt
+	}
+
+	logger.Infof(ctx, "Custom agent config applied: MaxIterations=%d, MaxTotalTokens=%d, MaxExecutionSeconds=%d, Temperature=%.2f, AllowedTools=%v, WebSearchEnabled=%v",
+		agentConfig.MaxIterations, agentConfig.MaxTotalTokens, agentConfig.MaxExecutionSeconds,
+		agentConfig.Temperature, agentConfig.AllowedTools, agentConfig.WebSearchEnabled)
+
+	// Set web search max results from tenant config if not set (default: 5)
+	if agentConfig.WebSearchMaxResults == 0 {
+		agentConfig.WebSearchMaxResults = 5
+		if tenantInfo.WebSearchConfig != nil && tenantInfo.WebSearchConfig.MaxResults > 0 {
+			agentConfig.WebSearchMaxResults = tenantInfo.WebSearchConfig.MaxResults
+		}
+	}
+
+	logger.Infof(ctx, "Merged agent config from tenant %d and session %s", tenantInfo.ID, sessionID)
+
+	// Log knowledge bases if present
+	if len(agentConfig.KnowledgeBases) > 0 {
+		logger.Infof(ctx, "Agent configured with %d knowledge base(s): %v",
+			len(agentConfig.KnowledgeBases), agentConfig.KnowledgeBases)
+	} else {
+		// Allow running without knowledge bases (Pure Agent mode)
+		logger.Infof(ctx, "No knowledge bases specified for agent, running in pure agent mode")
+	}
+
+	// Build search targets for agent (pre-compute once to avoid repeated queries)
+	searchTargets, err := s.buildSearchTargets(ctx, tenantInfo.ID, agentConfig.KnowledgeBases, agentConfig.KnowledgeIDs)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to build search targets for agent: %v", err)
+		// Continue without search targets, the tool will handle empty targets
+	}
+	agentConfig.SearchTargets = searchTargets
+	logger.Infof(ctx, "Agent search targets built: %d targets", len(searchTargets))
+
+	return agentConfig
+}
+
 // getContextManagerForSession creates a context manager for the session based on configuration
 // Returns the configured context manager (tenant-level or session-level) or default
 func (s *sessionService) getContextManagerForSession(
@@ -1468,6 +1840,7 @@ func (s *sessionService) consumeFallbackStream(
 				Data: event.AgentFinalAnswerData{
 					Content: response.Content,
 					Done:    response.Done,
+					Usage:   response.Usage,
 				},
 			}); err != nil {
 				logger.Errorf(ctx, "Failed to emit fallback answer chunk event: %v", err)
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/internal/analytics"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// Modes of question answering reported with the analytics events
+const (
+	analyticsModeKnowledge = "knowledge"
+	analyticsModeAgent     = "agent"
+)
+
+// startQueryAnalytics marks the time the question was accepted and emits query_received. When analytics
+// are enabled, the returned context collects the stage timings reported by the later events.
+func (s *sessionService) startQueryAnalytics(ctx context.Context, chatManage *types.ChatManage) context.Context {
+	if chatManage.ReceivedAt.IsZero() {
+		chatManage.ReceivedAt = time.Now()
+	}
+	if s.analytics == nil {
+		return ctx
+	}
+	if _, ok := ctx.Value(types.StageTimingsContextKey).(*types.StageTimings); !ok {
+		ctx, _ = types.WithStageTimings(ctx)
+	}
+	evt := analytics.NewChatEvent(analytics.EventQueryReceived, chatManage).
+		WithQuery(chatManage.Query, s.analytics.QueryMode())
+	evt.Attributes = map[string]interface{}{"mode": analyticsModeKnowledge}
+	s.analytics.Emit(ctx, evt)
+	return ctx
+}
+
+// emitRetrievalAnalytics emits retrieval_done with the number of chunks found at each step
+func (s *sessionService) emitRetrievalAnalytics(ctx context.Context, chatManage *types.ChatManage) {
+	if !s.analytics.Enabled(analytics.EventRetrievalDone) {
+		return
+	}
+	evt := analytics.NewChatEvent(analytics.EventRetrievalDone, chatManage).WithStageTimings(stageTimings(ctx))
+	evt.Attributes = map[string]interface{}{
+		"search_count": len(chatManage.SearchResult),
+		"rerank_count": len(chatManage.RerankResult),
+		"result_count": len(chatManage.MergeResult),
+		"degraded":     chatManage.RetrievalDegradation != nil,
+	}
+	s.analytics.Emit(ctx, evt)
+}
+
+// watchAnswerAnalytics emits answer_generated when the last chunk of the streamed answer is emitted
+func (s *sessionService) watchAnswerAnalytics(ctx context.Context, chatManage *types.ChatManage) {
+	if !s.analytics.Enabled(analytics.EventAnswerGenerated) || chatManage.EventBus == nil {
+		return
+	}
+	var mu sync.Mutex
+	answerLength := 0
+	done := false
+	chatManage.EventBus.On(types.EventType(event.EventAgentFinalAnswer), func(_ context.Context, evt types.Event) error {
+		data, ok := evt.Data.(event.AgentFinalAnswerData)
+		if !ok {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if done {
+			return nil
+		}
+		answerLength += utf8.RuneCountInString(data.Content)
+		if data.Done {
+			done = true
+			s.emitAnswerAnalytics(ctx, chatManage, answerLength, data.Usage)
+		}
+		return nil
+	})
+}
+
+// emitCompletionAnalytics emits answer_generated for the answer of a pipeline without streaming
+func (s *sessionService) emitCompletionAnalytics(ctx context.Context, chatManage *types.ChatManage) {
+	if chatManage.ChatResponse == nil {
+		return
+	}
+	var usage *types.TokenUsage
+	if u := chatManage.ChatResponse.Usage; u.TotalTokens > 0 {
+		usage = &types.TokenUsage{
+			PromptTokens:     u.PromptTokens,
+			CompletionTokens: u.CompletionTokens,
+			TotalTokens:      u.TotalTokens,
+		}
+	}
+	s.emitAnswerAnalytics(ctx, chatManage, utf8.RuneCountInString(chatManage.ChatResponse.Content), usage)
+}
+
+// emitAnswerAnalytics emits answer_generated with the stage timings of the request
+func (s *sessionService) emitAnswerAnalytics(ctx context.Context, chatManage *types.ChatManage,
+	answerLength int, usage *types.TokenUsage,
+) {
+	if !s.analytics.Enabled(analytics.EventAnswerGenerated) {
+		return
+	}
+	evt := analytics.NewChatEvent(analytics.EventAnswerGenerated, chatManage).WithStageTimings(stageTimings(ctx))
+	evt.Usage = usage
+	evt.Attributes = map[string]interface{}{
+		"mode":          analyticsModeKnowledge,
+		"chat_model_id": chatManage.ChatModelID,
+		"answer_length": answerLength,
+		"result_count":  len(chatManage.MergeResult),
+	}
+	s.analytics.Emit(ctx, evt)
+}
+
+// emitAgentQueryAnalytics emits query_received for a question answered by an agent
+func (s *sessionService) emitAgentQueryAnalytics(ctx context.Context, sessionID, messageID, query string,
+	knowledgeBaseIDs []string,
+) {
+	if !s.analytics.Enabled(analytics.EventQueryReceived) {
+		return
+	}
+	evt := (&analytics.Event{
+		Type:             analytics.EventQueryReceived,
+		KnowledgeBaseIDs: knowledgeBaseIDs,
+		SessionID:        sessionID,
+		MessageID:        messageID,
+		Attributes:       map[string]interface{}{"mode": analyticsModeAgent},
+	}).WithQuery(query, s.analytics.QueryMode())
+	s.analytics.Emit(ctx, evt)
+}
+
+// emitAgentAnswerAnalytics emits answer_generated for the final answer of an agent. Agents only estimate
+// their token usage, it is reported as the total.
+func (s *sessionService) emitAgentAnswerAnalytics(ctx context.Context, run *types.AgentRun,
+	state *types.AgentState, startTime time.Time,
+) {
+	if !s.analytics.Enabled(analytics.EventAnswerGenerated) || state == nil {
+		return
+	}
+	s.analytics.Emit(ctx, &analytics.Event{
+		Type:             analytics.EventAnswerGenerated,
+		KnowledgeBaseIDs: run.Input.Config.KnowledgeBases,
+		SessionID:        run.SessionID,
+		MessageID:        run.MessageID,
+		LatencyMs:        time.Since(startTime).Milliseconds(),
+		Usage:            &types.TokenUsage{TotalTokens: state.TokensUsed},
+		Attributes: map[string]interface{}{
+			"mode":          analyticsModeAgent,
+			"agent_id":      run.AgentID,
+			"chat_model_id": run.Input.ModelID,
+			"answer_length": utf8.RuneCountInString(state.FinalAnswer),
+			"rounds":        state.CurrentRound,
+			"stop_reason":   state.StopReason,
+		},
+	})
+}
+
+// stageTimings returns the stage timings collected in the context, if any
+func stageTimings(ctx context.Context) *types.StageTimings {
+	timings, _ := ctx.Value(types.StageTimingsContextKey).(*types.StageTimings)
+	return timings
+}
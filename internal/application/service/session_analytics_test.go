@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/analytics"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+type collectingAnalyticsSink struct {
+	mu     sync.Mutex
+	events []*analytics.Event
+}
+
+func (s *collectingAnalyticsSink) Publish(_ context.Context, events []*analytics.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func TestKnowledgeQAAnalytics(t *testing.T) {
+	sink := &collectingAnalyticsSink{}
+	emitter, err := analytics.NewWithSink(analytics.Options{QueryMode: analytics.QueryModeRedacted}, sink)
+	if err != nil {
+		t.Fatalf("NewWithSink() error = %v", err)
+	}
+	s := &sessionService{analytics: emitter}
+
+	bus := event.NewEventBus()
+	chatManage := &types.ChatManage{
+		SessionID:        "session-1",
+		MessageID:        "message-1",
+		Query:            "Why was card 4111 1111 1111 1111 declined?",
+		KnowledgeBaseIDs: []string{"kb-1"},
+		ChatModelID:      "model-1",
+		MergeResult:      []*types.SearchResult{{ID: "chunk-1"}, {ID: "chunk-2"}},
+		EventBus:         bus.AsEventBusInterface(),
+	}
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(10002))
+	ctx = s.startQueryAnalytics(ctx, chatManage)
+	types.RecordStage(ctx, string(types.CHUNK_SEARCH), 120*time.Millisecond)
+	s.emitRetrievalAnalytics(ctx, chatManage)
+	s.watchAnswerAnalytics(ctx, chatManage)
+
+	chunks := []string{"The card ", "was reported ", "stolen."}
+	for i, chunk := range chunks {
+		data := event.AgentFinalAnswerData{Content: chunk, Done: i == len(chunks)-1}
+		if data.Done {
+			data.Usage = &types.TokenUsage{PromptTokens: 900, CompletionTokens: 12, TotalTokens: 912}
+		}
+		_ = chatManage.EventBus.Emit(ctx, types.Event{Type: types.EventType(event.EventAgentFinalAnswer), Data: data})
+	}
+	// A later chunk of another answer on the same bus is not reported again
+	_ = chatManage.EventBus.Emit(ctx, types.Event{
+		Type: types.EventType(event.EventAgentFinalAnswer),
+		Data: event.AgentFinalAnswerData{Content: "again", Done: true},
+	})
+	if err := emitter.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if len(sink.events) != 3 {
+		t.Fatalf("published %d events, want 3", len(sink.events))
+	}
+	query, retrieval, answer := sink.events[0], sink.events[1], sink.events[2]
+	if query.Type != analytics.EventQueryReceived || query.Query != "Why was card [number] declined?" ||
+		query.TenantID != 10002 || query.KnowledgeBaseIDs[0] != "kb-1" {
+		t.Errorf("query_received = %+v", query)
+	}
+	if retrieval.Type != analytics.EventRetrievalDone || retrieval.Attributes["result_count"] != 2 ||
+		retrieval.TimingsMs[string(types.CHUNK_SEARCH)] != 120 {
+		t.Errorf("retrieval_done = %+v", retrieval)
+	}
+	if answer.Type != analytics.EventAnswerGenerated || answer.Usage == nil || answer.Usage.TotalTokens != 912 ||
+		answer.Attributes["answer_length"] != len("The card was reported stolen.") || answer.MessageID != "message-1" {
+		t.Errorf("answer_generated = %+v", answer)
+	}
+	if answer.Query != "" {
+		t.Errorf("answer_generated carries the query %q", answer.Query)
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/i18n"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// EmptyKnowledgeBaseMessage reports whether none of the given knowledge bases or knowledge has
+// enabled chunks, and if so returns the message to answer with instead of calling the model
+func (s *sessionService) EmptyKnowledgeBaseMessage(ctx context.Context,
+	knowledgeBaseIDs []string, knowledgeIDs []string,
+) (string, bool) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return "", false
+	}
+	searchTargets, err := s.buildSearchTargets(ctx, tenantID, knowledgeBaseIDs, knowledgeIDs)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to build search targets for empty check: %v", err)
+		return "", false
+	}
+	return s.emptySearchTargetsMessage(ctx, tenantID, searchTargets)
+}
+
+// emptySearchTargetsMessage reports whether no search target has enabled chunks. The first custom
+// empty message of the targeted knowledge bases is returned, otherwise the localized default.
+// Any lookup failure counts as non-empty so that the normal pipeline runs.
+func (s *sessionService) emptySearchTargetsMessage(ctx context.Context,
+	tenantID uint64, searchTargets types.SearchTargets,
+) (string, bool) {
+	if len(searchTargets) == 0 {
+		return "", false
+	}
+	for _, target := range searchTargets {
+		var knowledgeIDs []string
+		if target.Type == types.SearchTargetTypeKnowledge {
+			knowledgeIDs = target.KnowledgeIDs
+		}
+		count, err := s.chunkService.GetRepository().CountEnabledChunks(ctx, tenantID, target.KnowledgeBaseID, knowledgeIDs)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to count chunks of knowledge base %s: %v", target.KnowledgeBaseID, err)
+			return "", false
+		}
+		if count > 0 {
+			return "", false
+		}
+	}
+
+	for _, kbID := range searchTargets.GetAllKnowledgeBaseIDs() {
+		kb, err := s.knowledgeBaseService.GetKnowledgeBaseByID(ctx, kbID)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to get knowledge base %s for empty message: %v", kbID, err)
+			continue
+		}
+		if kb.EmptyMessage != "" {
+			return kb.EmptyMessage, true
+		}
+	}
+	return i18n.Localize(ctx, "chat.knowledge_base_empty"), true
+}
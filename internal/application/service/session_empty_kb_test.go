@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/i18n"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeEnabledChunkRepo reports the enabled chunk count per knowledge base
+type fakeEnabledChunkRepo struct {
+	interfaces.ChunkRepository
+	counts map[string]int64
+	err    error
+}
+
+func (r *fakeEnabledChunkRepo) CountEnabledChunks(_ context.Context, _ uint64, kbID string, _ []string) (int64, error) {
+	return r.counts[kbID], r.err
+}
+
+// fakeChunkRepoService exposes a chunk repository
+type fakeChunkRepoService struct {
+	interfaces.ChunkService
+	repo interfaces.ChunkRepository
+}
+
+func (s *fakeChunkRepoService) GetRepository() interfaces.ChunkRepository {
+	return s.repo
+}
+
+// fakeEmptyMessageKBService serves knowledge bases with their empty message
+type fakeEmptyMessageKBService struct {
+	interfaces.KnowledgeBaseService
+	kbs map[string]*types.KnowledgeBase
+}
+
+func (s *fakeEmptyMessageKBService) GetKnowledgeBaseByID(_ context.Context, id string) (*types.KnowledgeBase, error) {
+	if kb, ok := s.kbs[id]; ok {
+		return kb, nil
+	}
+	return nil, errors.New("knowledge base not found")
+}
+
+func TestEmptySearchTargetsMessage(t *testing.T) {
+	ctx := context.Background()
+	defaultMessage := i18n.Localize(ctx, "chat.knowledge_base_empty")
+	kbs := map[string]*types.KnowledgeBase{
+		"kb-empty":  {ID: "kb-empty"},
+		"kb-custom": {ID: "kb-custom", EmptyMessage: "Nothing has been uploaded yet."},
+		"kb-full":   {ID: "kb-full"},
+	}
+	target := func(kbID string) *types.SearchTarget {
+		return &types.SearchTarget{Type: types.SearchTargetTypeKnowledgeBase, KnowledgeBaseID: kbID}
+	}
+
+	tests := []struct {
+		name      string
+		targets   types.SearchTargets
+		countErr  error
+		wantEmpty bool
+		want      string
+	}{
+		{name: "no targets", targets: nil},
+		{name: "knowledge base with content", targets: types.SearchTargets{target("kb-empty"), target("kb-full")}},
+		{name: "empty knowledge base", targets: types.SearchTargets{target("kb-empty")}, wantEmpty: true, want: defaultMessage},
+		{
+			name:      "custom empty message",
+			targets:   types.SearchTargets{target("kb-empty"), target("kb-custom")},
+			wantEmpty: true,
+			want:      "Nothing has been uploaded yet.",
+		},
+		{name: "missing knowledge base uses the default", targets: types.SearchTargets{target("kb-gone")}, wantEmpty: true, want: defaultMessage},
+		{name: "count failure runs the pipeline", targets: types.SearchTargets{target("kb-empty")}, countErr: errors.New("db down")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &sessionService{
+				chunkService: &fakeChunkRepoService{repo: &fakeEnabledChunkRepo{
+					counts: map[string]int64{"kb-full": 12},
+					err:    tt.countErr,
+				}},
+				knowledgeBaseService: &fakeEmptyMessageKBService{kbs: kbs},
+			}
+			message, empty := svc.emptySearchTargetsMessage(ctx, 1, tt.targets)
+			if empty != tt.wantEmpty || message != tt.want {
+				t.Errorf("emptySearchTargetsMessage() = (%q, %v), want (%q, %v)", message, empty, tt.want, tt.wantEmpty)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+const (
+	// explainMaxSources is the number of top search results covered by a retrieval explanation
+	explainMaxSources = 5
+	// explainMaxSourceRunes caps the content of each source passed to the model
+	explainMaxSourceRunes = 600
+)
+
+// defaultExplainRetrievalPrompt is used when no explain_retrieval_prompt is configured
+const defaultExplainRetrievalPrompt = `You help non-technical users understand search results. Given a search query and the top sources
+returned for it, explain briefly why these sources are relevant to the query.
+
+## Query
+{{query}}
+
+## Sources
+{{sources}}
+
+## Requirements
+- Refer to sources by their number, e.g. [1]
+- Base the explanation only on the sources above; do not answer the query itself
+- Point out sources that are only loosely related
+- Keep it under 100 words, in plain language, in the same language as the query`
+
+// ExplainSearchResults generates a short natural-language explanation of why the top search
+// results are relevant to the query. It only uses the already retrieved results, no retrieval is run.
+func (s *sessionService) ExplainSearchResults(ctx context.Context,
+	knowledgeBaseIDs []string, knowledgeIDs []string, query string, results []*types.SearchResult,
+) (string, error) {
+	if len(results) == 0 {
+		return "", nil
+	}
+
+	modelID, err := s.selectChatModelID(ctx, nil, knowledgeBaseIDs, knowledgeIDs)
+	if err != nil {
+		return "", err
+	}
+	chatModel, err := s.modelService.GetChatModel(ctx, modelID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"model_id": modelID,
+		})
+		return "", err
+	}
+
+	prompt := s.cfg.Conversation.ExplainRetrievalPrompt
+	if prompt == "" {
+		prompt = defaultExplainRetrievalPrompt
+	}
+	prompt = strings.ReplaceAll(prompt, "{{query}}", query)
+	prompt = strings.ReplaceAll(prompt, "{{sources}}", formatExplainSources(results))
+
+	thinking := false
+	response, err := chatModel.Chat(ctx, []chat.Message{
+		{Role: "user", Content: prompt + " /no_think"},
+	}, &chat.ChatOptions{
+		Temperature: 0.3,
+		Thinking:    &thinking,
+	})
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		return "", err
+	}
+
+	explanation := strings.TrimSpace(strings.TrimPrefix(response.Content, "<think>\n\n</think>"))
+	logger.Infof(ctx, "Generated retrieval explanation with model %s, length: %d", modelID, len(explanation))
+	return explanation, nil
+}
+
+// formatExplainSources renders the top results as a numbered list with truncated content
+func formatExplainSources(results []*types.SearchResult) string {
+	var builder strings.Builder
+	for i, result := range results {
+		if i >= explainMaxSources {
+			break
+		}
+		content := []rune(result.Content)
+		if len(content) > explainMaxSourceRunes {
+			content = append(content[:explainMaxSourceRunes], []rune("...")...)
+		}
+		fmt.Fprintf(&builder, "[%d] %s (score %.2f)\n%s\n\n", i+1, result.KnowledgeTitle, result.Score, string(content))
+	}
+	return strings.TrimSpace(builder.String())
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestFormatExplainSources(t *testing.T) {
+	many := make([]*types.SearchResult, explainMaxSources+2)
+	for i := range many {
+		many[i] = &types.SearchResult{KnowledgeTitle: "doc", Content: "text"}
+	}
+
+	tests := []struct {
+		name        string
+		results     []*types.SearchResult
+		want        []string
+		wantSources int
+	}{
+		{
+			name: "numbered with title and score",
+			results: []*types.SearchResult{
+				{KnowledgeTitle: "Refund policy", Score: 0.876, Content: "Refunds take 5 days."},
+				{KnowledgeTitle: "FAQ", Score: 0.5, Content: "Contact support."},
+			},
+			want:        []string{"[1] Refund policy (score 0.88)\nRefunds take 5 days.", "[2] FAQ (score 0.50)\nContact support."},
+			wantSources: 2,
+		},
+		{
+			name:        "long content is truncated by runes",
+			results:     []*types.SearchResult{{KnowledgeTitle: "Long", Content: strings.Repeat("退", explainMaxSourceRunes+10)}},
+			want:        []string{strings.Repeat("退", explainMaxSourceRunes) + "..."},
+			wantSources: 1,
+		},
+		{name: "only the top sources", results: many, wantSources: explainMaxSources},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatExplainSources(tt.results)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("sources %q do not contain %q", got, want)
+				}
+			}
+			if sources := strings.Count(got, " (score "); sources != tt.wantSources {
+				t.Errorf("rendered %d sources, want %d", sources, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestExplainSearchResultsWithoutResults(t *testing.T) {
+	// Nothing was found, so no model is selected or called
+	explanation, err := (&sessionService{}).ExplainSearchResults(context.Background(), []string{"kb-1"}, nil, "refunds", nil)
+	if err != nil || explanation != "" {
+		t.Errorf("ExplainSearchResults() = %q, %v, want no explanation", explanation, err)
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// resolveHistoryDepth returns the history depth of a request: the request override when set,
+// otherwise the smallest depth configured on the given knowledge bases, otherwise nil (system default)
+func (s *sessionService) resolveHistoryDepth(ctx context.Context,
+	requestDepth *int, knowledgeBaseIDs []string,
+) *int {
+	if requestDepth != nil {
+		logger.Infof(ctx, "Using request's history_depth: %d", *requestDepth)
+		return requestDepth
+	}
+	var depth *int
+	seen := make(map[string]bool, len(knowledgeBaseIDs))
+	for _, kbID := range knowledgeBaseIDs {
+		if kbID == "" || seen[kbID] {
+			continue
+		}
+		seen[kbID] = true
+		kb, err := s.knowledgeBaseService.GetKnowledgeBaseByID(ctx, kbID)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to get knowledge base %s for history depth: %v", kbID, err)
+			continue
+		}
+		if kb.HistoryDepth != nil && (depth == nil || *kb.HistoryDepth < *depth) {
+			depth = kb.HistoryDepth
+		}
+	}
+	if depth != nil {
+		logger.Infof(ctx, "Using knowledge base history_depth: %d", *depth)
+	}
+	return depth
+}
+
+// searchTargetKnowledgeBaseIDs returns the knowledge base IDs covered by the search targets
+func searchTargetKnowledgeBaseIDs(targets types.SearchTargets) []string {
+	ids := make([]string, 0, len(targets))
+	for _, target := range targets {
+		ids = append(ids, target.KnowledgeBaseID)
+	}
+	return ids
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/i18n"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// RegenerateAnswer generates a new answer for an earlier turn from the references stored with its answer.
+// Retrieval is not run again, so only generation varies. History is limited to the turns before the
+// regenerated one. Answers without references are regenerated as plain chat.
+// Events are emitted through eventBus (references, answer chunks, completion)
+func (s *sessionService) RegenerateAnswer(
+	ctx context.Context,
+	session *types.Session,
+	userMessage *types.Message,
+	sourceMessage *types.Message,
+	assistantMessageID string,
+	summaryModelID string,
+	temperature *float64,
+	eventBus *event.EventBus,
+) error {
+	logger.Infof(ctx, "Regenerating answer, session ID: %s, source message ID: %s, references: %d",
+		session.ID, sourceMessage.ID, len(sourceMessage.KnowledgeReferences))
+
+	chatModelID, err := s.selectChatModelIDWithOverride(ctx, session, nil, nil, summaryModelID)
+	if err != nil {
+		return err
+	}
+
+	summaryConfig := types.SummaryConfig{
+		Prompt:              s.cfg.Conversation.Summary.Prompt,
+		ContextTemplate:     s.cfg.Conversation.Summary.ContextTemplate,
+		Temperature:         s.cfg.Conversation.Summary.Temperature,
+		NoMatchPrefix:       s.cfg.Conversation.Summary.NoMatchPrefix,
+		MaxCompletionTokens: s.cfg.Conversation.Summary.MaxCompletionTokens,
+		ContextWindow:       s.cfg.Conversation.Summary.ContextWindow,
+		Thinking:            s.cfg.Conversation.Summary.Thinking,
+
+		OversizedChunkStrategy: s.cfg.Conversation.Summary.OversizedChunkStrategy,
+	}
+	if temperature != nil {
+		summaryConfig.Temperature = *temperature
+	}
+
+	chatManage := &types.ChatManage{
+		Query:            userMessage.Content,
+		RewriteQuery:     userMessage.Content,
+		SessionID:        session.ID,
+		MessageID:        assistantMessageID,
+		MaxRounds:        s.cfg.Conversation.MaxRounds,
+		HistoryBefore:    userMessage.CreatedAt,
+		ChatModelID:      chatModelID,
+		SummaryConfig:    summaryConfig,
+		FallbackStrategy: types.FallbackStrategyFixed,
+		FallbackResponse: i18n.LocalizeDefault(ctx, "chat.fallback_response", s.cfg.Conversation.FallbackResponse),
+		EventBus:         eventBus.AsEventBusInterface(),
+		TenantID:         session.TenantID,
+		MergeResult:      sourceMessage.KnowledgeReferences,
+	}
+
+	pipeline := types.Pipline["regenerate_stream"]
+	if len(sourceMessage.KnowledgeReferences) == 0 {
+		logger.Info(ctx, "Source answer has no references, regenerating as chat")
+		chatManage.UserContent = userMessage.Content
+		pipeline = types.Pipline["chat_history_stream"]
+	}
+
+	if err := s.KnowledgeQAByEvent(ctx, chatManage, pipeline); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"session_id": session.ID,
+		})
+		return err
+	}
+
+	// Emit the reused references so that they are stored with the new answer
+	if len(sourceMessage.KnowledgeReferences) > 0 {
+		if err := eventBus.Emit(ctx, event.Event{
+			ID:        generateEventID("references"),
+			Type:      event.EventAgentReferences,
+			SessionID: session.ID,
+			Data: event.AgentReferencesData{
+				References: []*types.SearchResult(sourceMessage.KnowledgeReferences),
+			},
+		}); err != nil {
+			logger.Errorf(ctx, "Failed to emit references event: %v", err)
+		}
+	}
+
+	logger.Info(ctx, "Answer regeneration initiated")
+	return nil
+}
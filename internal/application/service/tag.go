@@ -132,7 +132,7 @@ func (s *knowledgeTagService) CreateTag(
 	// Check if tag with same name already exists
 	existingTag, err := s.repo.GetByName(ctx, kb.TenantID, kbID, name)
 	if err == nil && existingTag != nil {
-		return nil, werrors.NewConflictError("标签名称已存在")
+		return nil, werrors.NewConflictError("标签名称已存在").WithCode(werrors.CodeTagDuplicate)
 	}
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
@@ -292,7 +292,7 @@ func (s *knowledgeTagService) DeleteTag(ctx context.Context, id string, force bo
 	}
 
 	if !force && (kCount > 0 || cCount > 0) {
-		return werrors.NewBadRequestError("标签仍有知识或FAQ条目引用，无法删除")
+		return s.tagInUseError(ctx, tenantID, kb, tag.ID, kCount, cCount)
 	}
 
 	// When force=true, delete all content under this tag first
@@ -317,6 +317,33 @@ func (s *knowledgeTagService) DeleteTag(ctx context.Context, id string, force bo
 	return s.repo.Delete(ctx, tenantID, id)
 }
 
+// tagInUseError lists the knowledge that uses a tag of a document knowledge base; FAQ entries are only counted
+func (s *knowledgeTagService) tagInUseError(ctx context.Context,
+	tenantID uint64, kb *types.KnowledgeBase, tagID string, kCount, cCount int64,
+) error {
+	if kb.Type != types.KnowledgeBaseTypeDocument || kCount == 0 {
+		return types.NewResourceInUseError(werrors.CodeTagInUse, "tag", nil, cCount)
+	}
+	knowledgeIDs, err := s.knowledgeRepo.ListIDsByTagID(ctx, tenantID, kb.ID, tagID)
+	if err != nil {
+		return err
+	}
+	if len(knowledgeIDs) > types.MaxListedReferences {
+		knowledgeIDs = knowledgeIDs[:types.MaxListedReferences]
+	}
+	knowledgeList, err := s.knowledgeRepo.GetKnowledgeBatch(ctx, tenantID, knowledgeIDs)
+	if err != nil {
+		return err
+	}
+	refs := make([]types.ResourceReference, 0, len(knowledgeList))
+	for _, knowledge := range knowledgeList {
+		refs = append(refs, types.ResourceReference{
+			Type: types.ReferenceTypeKnowledge, ID: knowledge.ID, Name: knowledge.Title, Fields: []string{"tag_id"},
+		})
+	}
+	return types.NewResourceInUseError(werrors.CodeTagInUse, "tag", refs, kCount)
+}
+
 // enqueueIndexDeleteTask enqueues an async task for index deletion (low priority)
 func (s *knowledgeTagService) enqueueIndexDeleteTask(ctx context.Context,
 	tenantID uint64, kbID, embeddingModelID, kbType string, chunkIDs []string, effectiveEngines []types.RetrieverEngineParams,
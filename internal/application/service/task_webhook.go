@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// Task webhook defaults used when task_webhook is not configured
+const (
+	defaultTaskWebhookTimeout  = 10 * time.Second
+	defaultTaskWebhookAttempts = 5
+)
+
+const (
+	taskWebhookURLKeyPrefix      = "task_webhook_url:"
+	taskWebhookDeliveryKeyPrefix = "task_webhook_delivery:"
+	// taskWebhookTTL outlives the progress of the tasks, kept for up to 24 hours
+	taskWebhookTTL = 48 * time.Hour
+)
+
+// taskWebhookService implements the TaskWebhookService interface. Per-task webhook URLs and the last
+// delivery of each task are kept in Redis.
+type taskWebhookService struct {
+	redisClient *redis.Client
+	tenantRepo  interfaces.TenantRepository
+	timeout     time.Duration
+	attempts    int
+}
+
+// NewTaskWebhookService creates a new task webhook service
+func NewTaskWebhookService(
+	cfg *config.Config,
+	redisClient *redis.Client,
+	tenantRepo interfaces.TenantRepository,
+) interfaces.TaskWebhookService {
+	s := &taskWebhookService{
+		redisClient: redisClient,
+		tenantRepo:  tenantRepo,
+		timeout:     defaultTaskWebhookTimeout,
+		attempts:    defaultTaskWebhookAttempts,
+	}
+	if cfg != nil && cfg.TaskWebhook != nil {
+		if cfg.TaskWebhook.Timeout > 0 {
+			s.timeout = cfg.TaskWebhook.Timeout
+		}
+		if cfg.TaskWebhook.Attempts > 0 {
+			s.attempts = cfg.TaskWebhook.Attempts
+		}
+	}
+	return s
+}
+
+// RegisterTaskWebhook stores the webhook URL of a task
+func (s *taskWebhookService) RegisterTaskWebhook(ctx context.Context, taskID, url string) error {
+	tenant, _ := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil || tenant.WebhookConfig == nil || tenant.WebhookConfig.Secret == "" {
+		return werrors.NewBadRequestError("Task webhooks are not configured").
+			WithDetails("set a secret with the webhook-config tenant KV key to sign webhooks")
+	}
+	if safe, reason := secutils.IsSSRFSafeURL(url); !safe {
+		return werrors.NewBadRequestError("Invalid webhook URL").WithDetails(reason)
+	}
+	return s.redisClient.Set(ctx, taskWebhookURLKeyPrefix+taskID, url, taskWebhookTTL).Err()
+}
+
+// NotifyTaskFinished claims the delivery of the task, then delivers it in the background
+func (s *taskWebhookService) NotifyTaskFinished(ctx context.Context, event *types.TaskWebhookEvent) {
+	if event == nil {
+		return
+	}
+	// The task may finish before the delivery does
+	ctx = context.WithoutCancel(ctx)
+
+	url, secret, err := s.resolveWebhook(ctx, event)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to resolve the webhook of task %s: %v", event.TaskID, err)
+		return
+	}
+	if url == "" {
+		return
+	}
+
+	delivery := &types.TaskWebhookDelivery{URL: url, Status: types.WebhookDeliveryPending}
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to marshal webhook delivery: %v", err)
+		return
+	}
+	// Tasks save their final status more than once, only the first save notifies
+	claimed, err := s.redisClient.SetNX(ctx, taskWebhookDeliveryKeyPrefix+event.TaskID, data, taskWebhookTTL).Result()
+	if err != nil {
+		logger.Warnf(ctx, "Failed to claim the webhook delivery of task %s: %v", event.TaskID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to marshal webhook event: %v", err)
+		return
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Errorf(ctx, "Webhook delivery of task %s panicked: %v", event.TaskID, r)
+			}
+		}()
+		s.deliver(ctx, event, delivery, secret, body)
+	}()
+}
+
+// resolveWebhook returns the URL notified for the task and the secret of its tenant; no URL when neither
+// the task nor the tenant has a webhook
+func (s *taskWebhookService) resolveWebhook(ctx context.Context,
+	event *types.TaskWebhookEvent,
+) (string, string, error) {
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, event.TenantID)
+	if err != nil {
+		return "", "", fmt.Errorf("get tenant: %w", err)
+	}
+	if tenant.WebhookConfig == nil || tenant.WebhookConfig.Secret == "" {
+		return "", "", nil
+	}
+	url, err := s.redisClient.Get(ctx, taskWebhookURLKeyPrefix+event.TaskID).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", "", fmt.Errorf("get task webhook: %w", err)
+	}
+	if url == "" {
+		url = tenant.WebhookConfig.URL
+	}
+	return url, tenant.WebhookConfig.Secret, nil
+}
+
+// deliver posts the event and records the outcome of the delivery
+func (s *taskWebhookService) deliver(ctx context.Context, event *types.TaskWebhookEvent,
+	delivery *types.TaskWebhookDelivery, secret string, body []byte,
+) {
+	client := secutils.NewSSRFSafeHTTPClient(secutils.SSRFSafeHTTPClientConfig{
+		Timeout:      s.timeout,
+		MaxRedirects: 3,
+	})
+	attempts, err := secutils.DeliverWebhookCounted(ctx, client, delivery.URL, secret, body, s.attempts)
+	now := time.Now()
+	delivery.Attempts = attempts
+	delivery.LastAttemptAt = &now
+	if err != nil {
+		delivery.Status = types.WebhookDeliveryFailed
+		delivery.Error = err.Error()
+		logger.Warnf(ctx, "Failed to deliver the webhook of task %s after %d attempts: %v",
+			event.TaskID, attempts, err)
+	} else {
+		delivery.Status = types.WebhookDeliveryDelivered
+		logger.Infof(ctx, "Delivered the webhook of task %s", event.TaskID)
+	}
+
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to marshal webhook delivery: %v", err)
+		return
+	}
+	if err := s.redisClient.Set(ctx, taskWebhookDeliveryKeyPrefix+event.TaskID, data, taskWebhookTTL).Err(); err != nil {
+		logger.Warnf(ctx, "Failed to record the webhook delivery of task %s: %v", event.TaskID, err)
+	}
+}
+
+// GetTaskWebhookDelivery returns the last webhook delivery of the task
+func (s *taskWebhookService) GetTaskWebhookDelivery(ctx context.Context,
+	taskID string,
+) (*types.TaskWebhookDelivery, error) {
+	data, err := s.redisClient.Get(ctx, taskWebhookDeliveryKeyPrefix+taskID).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery from Redis: %w", err)
+	}
+	var delivery types.TaskWebhookDelivery
+	if err := json.Unmarshal(data, &delivery); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
@@ -0,0 +1,983 @@
+package service
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/application/service/retriever"
+	"github.com/Tencent/WeKnora/internal/clock"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	tenantExportProgressKeyPrefix = "tenant_export_progress:"
+	tenantPurgeProgressKeyPrefix  = "tenant_purge_progress:"
+	// Exports stay downloadable for a week
+	tenantExportProgressTTL = 7 * 24 * time.Hour
+	// Purge reports are kept as long, so that failed purges can be retried
+	tenantPurgeProgressTTL = 7 * 24 * time.Hour
+	// An export still processing without progress for this long is considered interrupted and can be resumed
+	tenantExportStaleAfter = 10 * time.Minute
+	// Purges save progress per knowledge base, whose cleanup takes longer than an export heartbeat
+	tenantPurgeStaleAfter   = 30 * time.Minute
+	tenantExportMessagePage = 200
+)
+
+// tenantDataService exports and purges all data of a tenant
+type tenantDataService struct {
+	tenantRepo     interfaces.TenantRepository
+	kbRepo         interfaces.KnowledgeBaseRepository
+	knowledgeRepo  interfaces.KnowledgeRepository
+	chunkRepo      interfaces.ChunkRepository
+	sessionRepo    interfaces.SessionRepository
+	messageRepo    interfaces.MessageRepository
+	modelRepo      interfaces.ModelRepository
+	agentRepo      interfaces.CustomAgentRepository
+	fileSvc        interfaces.FileService
+	modelService   interfaces.ModelService
+	retrieveEngine interfaces.RetrieveEngineRegistry
+	graphEngine    interfaces.RetrieveGraphRepository
+	redisClient    *redis.Client
+	task           *asynq.Client
+	clock          clock.Clock
+}
+
+// NewTenantDataService creates a new tenant data service
+func NewTenantDataService(tenantRepo interfaces.TenantRepository,
+	kbRepo interfaces.KnowledgeBaseRepository,
+	knowledgeRepo interfaces.KnowledgeRepository,
+	chunkRepo interfaces.ChunkRepository,
+	sessionRepo interfaces.SessionRepository,
+	messageRepo interfaces.MessageRepository,
+	modelRepo interfaces.ModelRepository,
+	agentRepo interfaces.CustomAgentRepository,
+	fileSvc interfaces.FileService,
+	modelService interfaces.ModelService,
+	retrieveEngine interfaces.RetrieveEngineRegistry,
+	graphEngine interfaces.RetrieveGraphRepository,
+	redisClient *redis.Client,
+	task *asynq.Client,
+	clk clock.Clock,
+) interfaces.TenantDataService {
+	return &tenantDataService{
+		tenantRepo:     tenantRepo,
+		kbRepo:         kbRepo,
+		knowledgeRepo:  knowledgeRepo,
+		chunkRepo:      chunkRepo,
+		sessionRepo:    sessionRepo,
+		messageRepo:    messageRepo,
+		modelRepo:      modelRepo,
+		agentRepo:      agentRepo,
+		fileSvc:        fileSvc,
+		modelService:   modelService,
+		retrieveEngine: retrieveEngine,
+		graphEngine:    graphEngine,
+		redisClient:    redisClient,
+		task:           task,
+		clock:          clk,
+	}
+}
+
+// saveTenantExportProgress saves the tenant export progress to Redis
+func (s *tenantDataService) saveTenantExportProgress(ctx context.Context, progress *types.TenantExportProgress) error {
+	progress.UpdatedAt = s.clock.Now().Unix()
+	progress.Total = len(progress.Parts)
+	progress.Processed = 0
+	for _, part := range progress.Parts {
+		if part.Status == types.TenantExportPartCompleted {
+			progress.Processed++
+		}
+	}
+	if progress.Total > 0 {
+		progress.Progress = progress.Processed * 100 / progress.Total
+	}
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tenant export progress: %w", err)
+	}
+	return s.redisClient.Set(ctx, tenantExportProgressKeyPrefix+progress.TaskID, data, tenantExportProgressTTL).Err()
+}
+
+// GetTenantExportProgress retrieves the progress of an export of the tenant
+func (s *tenantDataService) GetTenantExportProgress(ctx context.Context,
+	tenantID uint64, taskID string,
+) (*types.TenantExportProgress, error) {
+	data, err := s.redisClient.Get(ctx, tenantExportProgressKeyPrefix+taskID).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, werrors.NewNotFoundError("Tenant export task not found")
+		}
+		return nil, fmt.Errorf("failed to get tenant export progress from Redis: %w", err)
+	}
+
+	var progress types.TenantExportProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tenant export progress: %w", err)
+	}
+	if progress.TenantID != tenantID {
+		return nil, werrors.NewNotFoundError("Tenant export task not found")
+	}
+	return &progress, nil
+}
+
+// ExportTenantData queues an export of all data of the tenant as a tracked task
+func (s *tenantDataService) ExportTenantData(ctx context.Context, tenantID uint64) (*types.TenantExportProgress, error) {
+	if _, err := s.tenantRepo.GetTenantByID(ctx, tenantID); err != nil {
+		return nil, werrors.NewNotFoundError("Tenant not found")
+	}
+
+	progress := &types.TenantExportProgress{
+		TaskID:    secutils.GenerateTaskID("tenant_export", tenantID),
+		TenantID:  tenantID,
+		Status:    types.KBCloneStatusPending,
+		Message:   "Task queued, waiting to start...",
+		CreatedAt: s.clock.Now().Unix(),
+	}
+	if err := s.saveTenantExportProgress(ctx, progress); err != nil {
+		logger.Errorf(ctx, "Failed to save tenant export progress: %v", err)
+		return nil, err
+	}
+	if err := s.enqueueTenantExport(ctx, progress, progress.TaskID); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// ResumeTenantExport queues a failed or interrupted export again. Completed parts are kept and
+// the export continues with the first incomplete part.
+func (s *tenantDataService) ResumeTenantExport(ctx context.Context,
+	tenantID uint64, taskID string,
+) (*types.TenantExportProgress, error) {
+	progress, err := s.GetTenantExportProgress(ctx, tenantID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	stale := s.clock.Since(time.Unix(progress.UpdatedAt, 0)) > tenantExportStaleAfter
+	switch {
+	case progress.Status == types.KBCloneStatusCompleted:
+		return nil, werrors.NewBadRequestError("Tenant export is already completed")
+	case progress.Status != types.KBCloneStatusFailed && !stale:
+		return nil, werrors.NewConflictError("Tenant export is still running")
+	}
+
+	progress.Status = types.KBCloneStatusPending
+	progress.Error = ""
+	progress.Message = "Task queued, resuming..."
+	if err := s.saveTenantExportProgress(ctx, progress); err != nil {
+		logger.Errorf(ctx, "Failed to save tenant export progress: %v", err)
+		return nil, err
+	}
+	// The queue may still hold the interrupted task under the export ID, so every run has its own task ID
+	runID := fmt.Sprintf("%s_resume_%d", progress.TaskID, s.clock.Now().UnixMilli())
+	if err := s.enqueueTenantExport(ctx, progress, runID); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// enqueueTenantExport queues a run of the export
+func (s *tenantDataService) enqueueTenantExport(ctx context.Context,
+	progress *types.TenantExportProgress, runID string,
+) error {
+	requestID, _ := ctx.Value(types.RequestIDContextKey).(string)
+	payloadBytes, err := json.Marshal(types.TenantExportPayload{
+		TenantID:  progress.TenantID,
+		TaskID:    progress.TaskID,
+		RequestID: requestID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal tenant export payload: %w", err)
+	}
+	// Failures are recorded in the progress and resumed on request, so the task itself is not retried
+	task := asynq.NewTask(types.TypeTenantExport, payloadBytes,
+		asynq.TaskID(runID), asynq.Queue("low"), asynq.MaxRetry(0), asynq.Timeout(24*time.Hour))
+	info, err := s.task.Enqueue(task)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to enqueue tenant export task: %v", err)
+		return err
+	}
+	logger.Infof(ctx, "Enqueued tenant export task: id=%s queue=%s tenant=%d", info.ID, info.Queue, progress.TenantID)
+	return nil
+}
+
+// GetTenantExportPart opens the archive of a completed part of an export
+func (s *tenantDataService) GetTenantExportPart(ctx context.Context,
+	tenantID uint64, taskID string, name string,
+) (*types.TenantExportPart, io.ReadCloser, error) {
+	progress, err := s.GetTenantExportProgress(ctx, tenantID, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	part := progress.Part(name)
+	if part == nil {
+		return nil, nil, werrors.NewNotFoundError("Tenant export part not found")
+	}
+	if part.Status != types.TenantExportPartCompleted {
+		return nil, nil, werrors.NewBadRequestError("Tenant export part is not completed yet")
+	}
+	reader, err := s.fileSvc.GetFile(ctx, part.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open tenant export part: %w", err)
+	}
+	return part, reader, nil
+}
+
+// ProcessTenantExport handles Asynq tenant export tasks.
+// Every part is streamed into storage as a gzip-compressed tar archive and marked completed, so that a
+// resumed export skips it. The manifest is written once all parts are completed.
+func (s *tenantDataService) ProcessTenantExport(ctx context.Context, t *asynq.Task) error {
+	var payload types.TenantExportPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "failed to unmarshal tenant export payload: %v", err)
+		return nil
+	}
+
+	ctx = logger.WithRequestID(ctx, payload.RequestID)
+	ctx = context.WithValue(ctx, types.RequestIDContextKey, payload.RequestID)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get tenant: %v", err)
+		return nil
+	}
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
+
+	progress, err := s.GetTenantExportProgress(ctx, payload.TenantID, payload.TaskID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to load tenant export progress: %v", err)
+		return nil
+	}
+	fail := func(err error, message string) error {
+		logger.Errorf(ctx, "Tenant export task %s failed: %s: %v", payload.TaskID, message, err)
+		progress.Status = types.KBCloneStatusFailed
+		progress.Error = err.Error()
+		progress.Message = message
+		_ = s.saveTenantExportProgress(ctx, progress)
+		return nil
+	}
+
+	// The parts are planned on the first run; resumed runs keep the plan
+	if len(progress.Parts) == 0 {
+		kbs, err := s.kbRepo.ListKnowledgeBasesByTenantID(ctx, payload.TenantID)
+		if err != nil {
+			return fail(err, "Failed to list knowledge bases")
+		}
+		progress.Parts = append(progress.Parts,
+			&types.TenantExportPart{Name: types.TenantExportPartTenant, Status: types.TenantExportPartPending})
+		for _, kb := range kbs {
+			progress.Parts = append(progress.Parts, &types.TenantExportPart{
+				Name: types.TenantExportKnowledgeBasePart(kb.ID), Status: types.TenantExportPartPending,
+			})
+		}
+		progress.Parts = append(progress.Parts,
+			&types.TenantExportPart{Name: types.TenantExportPartSessions, Status: types.TenantExportPartPending})
+	}
+	progress.Status = types.KBCloneStatusProcessing
+	_ = s.saveTenantExportProgress(ctx, progress)
+
+	for _, part := range progress.Parts {
+		if part.Status == types.TenantExportPartCompleted {
+			continue
+		}
+		progress.Message = fmt.Sprintf("Exporting %s (%d/%d)", part.Name, progress.Processed+1, progress.Total)
+		_ = s.saveTenantExportProgress(ctx, progress)
+
+		if err := s.exportPart(ctx, progress, part); err != nil {
+			return fail(err, fmt.Sprintf("Failed to export %s", part.Name))
+		}
+		part.Status = types.TenantExportPartCompleted
+		_ = s.saveTenantExportProgress(ctx, progress)
+		logger.Infof(ctx, "Tenant export task %s: exported %s, %d bytes", payload.TaskID, part.Name, part.Size)
+	}
+
+	manifest, err := progress.BuildManifest(s.clock.Now().Unix())
+	if err != nil {
+		return fail(err, "Failed to build manifest")
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fail(err, "Failed to build manifest")
+	}
+	manifestPath, err := s.fileSvc.SaveBytes(ctx, data, payload.TenantID, "tenant_export_manifest.json", false)
+	if err != nil {
+		return fail(err, "Failed to save manifest")
+	}
+
+	progress.Manifest = manifest
+	progress.ManifestPath = manifestPath
+	progress.Status = types.KBCloneStatusCompleted
+	progress.Message = fmt.Sprintf("Exported %d parts, %d bytes", len(progress.Parts), manifest.Size)
+	_ = s.saveTenantExportProgress(ctx, progress)
+	logger.Infof(ctx, "Tenant export task %s completed: %s", payload.TaskID, progress.Message)
+	return nil
+}
+
+// exportPart streams the archive of a part into storage while computing its size and checksum
+func (s *tenantDataService) exportPart(ctx context.Context,
+	progress *types.TenantExportProgress, part *types.TenantExportPart,
+) error {
+	tenantID := progress.TenantID
+	var write func(archive *exportArchive) error
+	switch {
+	case part.Name == types.TenantExportPartTenant:
+		write = func(archive *exportArchive) error { return s.writeTenantPart(ctx, tenantID, archive) }
+	case part.Name == types.TenantExportPartSessions:
+		write = func(archive *exportArchive) error { return s.writeSessionsPart(ctx, tenantID, archive) }
+	case strings.HasPrefix(part.Name, types.TenantExportPartKnowledgeBasePrefix):
+		kbID := strings.TrimPrefix(part.Name, types.TenantExportPartKnowledgeBasePrefix)
+		write = func(archive *exportArchive) error {
+			return s.writeKnowledgeBasePart(ctx, tenantID, kbID, archive)
+		}
+	default:
+		return fmt.Errorf("unknown export part %s", part.Name)
+	}
+
+	reader, writer := io.Pipe()
+	hash := sha256.New()
+	counter := &countingWriter{}
+	archive := &exportArchive{records: make(map[string]int)}
+	// Keeps the export from looking interrupted while a large part is written
+	lastSaved := s.clock.Now()
+	archive.heartbeat = func() {
+		if s.clock.Since(lastSaved) > time.Minute {
+			lastSaved = s.clock.Now()
+			_ = s.saveTenantExportProgress(ctx, progress)
+		}
+	}
+	done := make(chan error, 1)
+	go func() {
+		gz := gzip.NewWriter(io.MultiWriter(writer, hash, counter))
+		archive.tw = tar.NewWriter(gz)
+		err := write(archive)
+		if err == nil {
+			err = archive.tw.Close()
+		}
+		if err == nil {
+			err = gz.Close()
+		}
+		writer.CloseWithError(err)
+		done <- err
+	}()
+
+	filePath, err := s.fileSvc.SaveStream(ctx, reader, tenantID, "export_"+progress.TaskID, part.Name+".tar.gz")
+	// Unblocks the archive writer when storage stopped reading
+	reader.CloseWithError(err)
+	if writeErr := <-done; writeErr != nil {
+		if err == nil {
+			_ = s.fileSvc.DeleteFile(ctx, filePath)
+		}
+		return writeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	part.Path = filePath
+	part.Size = counter.n
+	part.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	part.Records = archive.records
+	return nil
+}
+
+// writeTenantPart exports the tenant settings, the models of the tenant and its agents. Credentials are redacted.
+func (s *tenantDataService) writeTenantPart(ctx context.Context, tenantID uint64, archive *exportArchive) error {
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	redacted := *tenant
+	redacted.RedactSecrets()
+	if err := archive.addJSON("tenant.json", &redacted); err != nil {
+		return err
+	}
+
+	models, err := s.modelRepo.List(ctx, tenantID, "", "")
+	if err != nil {
+		return err
+	}
+	spool, err := newExportSpool()
+	if err != nil {
+		return err
+	}
+	defer spool.close()
+	for _, model := range models {
+		// Builtin models are shared by all tenants and not part of the tenant data
+		if model.TenantID != tenantID || model.IsBuiltin {
+			continue
+		}
+		model.RedactSecrets()
+		if err := spool.encode(model); err != nil {
+			return err
+		}
+	}
+	if err := archive.addSpool("models.jsonl", spool, "models"); err != nil {
+		return err
+	}
+
+	agents, err := s.agentRepo.ListAgentsByTenantID(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	spool, err = newExportSpool()
+	if err != nil {
+		return err
+	}
+	defer spool.close()
+	for _, agent := range agents {
+		if err := spool.encode(agent); err != nil {
+			return err
+		}
+	}
+	return archive.addSpool("agents.jsonl", spool, "agents")
+}
+
+// writeKnowledgeBasePart exports a knowledge base with its knowledge, chunks, FAQ entries and original files
+func (s *tenantDataService) writeKnowledgeBasePart(ctx context.Context,
+	tenantID uint64, kbID string, archive *exportArchive,
+) error {
+	kb, err := s.kbRepo.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil || kb.TenantID != tenantID {
+		return fmt.Errorf("knowledge base %s not found: %v", kbID, err)
+	}
+	if err := archive.addJSON("knowledge_base.json", kb); err != nil {
+		return err
+	}
+	archive.records["knowledge_bases"]++
+	return writeKnowledgeEntries(ctx, s.knowledgeRepo, s.chunkRepo, s.fileSvc, kb, archive)
+}
+
+// writeSessionsPart exports the sessions of the tenant and their messages
+func (s *tenantDataService) writeSessionsPart(ctx context.Context, tenantID uint64, archive *exportArchive) error {
+	sessions, err := s.sessionRepo.GetByTenantID(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	spool, err := newExportSpool()
+	if err != nil {
+		return err
+	}
+	defer spool.close()
+	messages, err := newExportSpool()
+	if err != nil {
+		return err
+	}
+	defer messages.close()
+
+	for _, session := range sessions {
+		if err := spool.encode(session); err != nil {
+			return err
+		}
+		for page := 1; ; page++ {
+			batch, err := s.messageRepo.GetMessagesBySession(ctx, session.ID, page, tenantExportMessagePage)
+			if err != nil {
+				return err
+			}
+			for _, message := range batch {
+				if err := messages.encode(message); err != nil {
+					return err
+				}
+			}
+			if len(batch) < tenantExportMessagePage {
+				break
+			}
+		}
+	}
+	if err := archive.addSpool("sessions.jsonl", spool, "sessions"); err != nil {
+		return err
+	}
+	return archive.addSpool("messages.jsonl", messages, "messages")
+}
+
+// exportArchive writes the entries of an export part and counts the exported records
+type exportArchive struct {
+	tw        *tar.Writer
+	records   map[string]int
+	heartbeat func()
+}
+
+// addJSON adds an indented JSON document
+func (a *exportArchive) addJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := a.tw.WriteHeader(&tar.Header{
+		Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err = a.tw.Write(data)
+	return err
+}
+
+// addSpool adds the content of a spool and counts its records under kind. Files count as one record.
+func (a *exportArchive) addSpool(name string, spool *exportSpool, kind string) error {
+	size, err := spool.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := spool.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := a.tw.WriteHeader(&tar.Header{
+		Name: name, Mode: 0o644, Size: size, ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(a.tw, spool.file, size); err != nil {
+		return err
+	}
+	if a.heartbeat != nil {
+		a.heartbeat()
+	}
+	if spool.count > 0 {
+		a.records[kind] += spool.count
+	} else if kind == "files" {
+		a.records[kind]++
+	}
+	return nil
+}
+
+// exportSpool buffers an archive entry in a temporary file, since tar headers need the size of the
+// entry up front and large tenants do not fit in memory
+type exportSpool struct {
+	file  *os.File
+	enc   *json.Encoder
+	count int
+}
+
+func newExportSpool() (*exportSpool, error) {
+	file, err := os.CreateTemp("", "tenant-export-*")
+	if err != nil {
+		return nil, fmt.Errorf("create export spool: %w", err)
+	}
+	return &exportSpool{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// encode appends a JSON lines record
+func (s *exportSpool) encode(v interface{}) error {
+	if err := s.enc.Encode(v); err != nil {
+		return err
+	}
+	s.count++
+	return nil
+}
+
+func (s *exportSpool) close() {
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// saveTenantPurgeProgress saves the tenant purge progress to Redis
+func (s *tenantDataService) saveTenantPurgeProgress(ctx context.Context, progress *types.TenantPurgeProgress) error {
+	progress.UpdatedAt = s.clock.Now().Unix()
+	if progress.Total > 0 {
+		progress.Progress = progress.Processed * 100 / progress.Total
+	}
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tenant purge progress: %w", err)
+	}
+	return s.redisClient.Set(ctx, tenantPurgeProgressKeyPrefix+progress.TaskID, data, tenantPurgeProgressTTL).Err()
+}
+
+// GetTenantPurgeProgress retrieves the progress of a purge of the tenant
+func (s *tenantDataService) GetTenantPurgeProgress(ctx context.Context,
+	tenantID uint64, taskID string,
+) (*types.TenantPurgeProgress, error) {
+	data, err := s.redisClient.Get(ctx, tenantPurgeProgressKeyPrefix+taskID).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, werrors.NewNotFoundError("Tenant purge task not found")
+		}
+		return nil, fmt.Errorf("failed to get tenant purge progress from Redis: %w", err)
+	}
+
+	var progress types.TenantPurgeProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tenant purge progress: %w", err)
+	}
+	if progress.TenantID != tenantID {
+		return nil, werrors.NewNotFoundError("Tenant purge task not found")
+	}
+	return &progress, nil
+}
+
+// ConfirmTenantPurge issues the confirmation token that a purge of the tenant requires
+func (s *tenantDataService) ConfirmTenantPurge(ctx context.Context,
+	tenantID uint64,
+) (*types.TenantPurgeConfirmation, error) {
+	if _, err := s.tenantRepo.GetTenantByID(ctx, tenantID); err != nil {
+		return nil, werrors.NewNotFoundError("Tenant not found")
+	}
+	expiresAt := s.clock.Now().Add(types.TenantPurgeConfirmationTTL).Unix()
+	token, err := tenantPurgeToken(tenantID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof(ctx, "Issued tenant purge confirmation, ID: %d", tenantID)
+	return &types.TenantPurgeConfirmation{TenantID: tenantID, Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// tenantPurgeToken signs the tenant ID and the expiry time of a purge confirmation
+func tenantPurgeToken(tenantID uint64, expiresAt int64) (string, error) {
+	secret := apiKeySecret()
+	if len(secret) == 0 {
+		return "", werrors.NewInternalServerError("Tenant purge confirmation is not configured").
+			WithDetails("TENANT_AES_KEY is not set")
+	}
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "tenant_purge:%d:%d", tenantID, expiresAt)
+	return fmt.Sprintf("%d.%s", expiresAt, hex.EncodeToString(mac.Sum(nil))), nil
+}
+
+// verifyTenantPurgeToken checks that the token confirms the purge of the tenant and has not expired
+func verifyTenantPurgeToken(tenantID uint64, token string, now time.Time) error {
+	if token == "" {
+		return werrors.NewBadRequestError("Confirmation token is required")
+	}
+	expiryText, _, _ := strings.Cut(token, ".")
+	expiresAt, err := strconv.ParseInt(expiryText, 10, 64)
+	if err != nil {
+		return werrors.NewBadRequestError("Invalid confirmation token")
+	}
+	expected, err := tenantPurgeToken(tenantID, expiresAt)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(token), []byte(expected)) {
+		return werrors.NewBadRequestError("Invalid confirmation token")
+	}
+	if now.Unix() > expiresAt {
+		return werrors.NewBadRequestError("Confirmation token has expired")
+	}
+	return nil
+}
+
+// PurgeTenantData queues the permanent deletion of the tenant and all of its data as a tracked task.
+// The token must be a valid confirmation of ConfirmTenantPurge.
+func (s *tenantDataService) PurgeTenantData(ctx context.Context,
+	tenantID uint64, confirmationToken string,
+) (*types.TenantPurgeProgress, error) {
+	if err := verifyTenantPurgeToken(tenantID, confirmationToken, s.clock.Now()); err != nil {
+		return nil, err
+	}
+	if _, err := s.tenantRepo.GetTenantByID(ctx, tenantID); err != nil {
+		return nil, werrors.NewNotFoundError("Tenant not found")
+	}
+
+	progress := &types.TenantPurgeProgress{
+		TaskID:    secutils.GenerateTaskID("tenant_purge", tenantID),
+		TenantID:  tenantID,
+		Status:    types.KBCloneStatusPending,
+		Deleted:   make(map[string]int64),
+		Message:   "Task queued, waiting to start...",
+		CreatedAt: s.clock.Now().Unix(),
+	}
+	if err := s.saveTenantPurgeProgress(ctx, progress); err != nil {
+		logger.Errorf(ctx, "Failed to save tenant purge progress: %v", err)
+		return nil, err
+	}
+	if err := s.enqueueTenantPurge(ctx, progress, progress.TaskID, false); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// RetryTenantPurge queues a failed or interrupted purge again. Knowledge bases purged by earlier runs
+// are skipped. With force the records are removed even if data still cannot be deleted.
+func (s *tenantDataService) RetryTenantPurge(ctx context.Context,
+	tenantID uint64, taskID string, force bool,
+) (*types.TenantPurgeProgress, error) {
+	progress, err := s.GetTenantPurgeProgress(ctx, tenantID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	stale := s.clock.Since(time.Unix(progress.UpdatedAt, 0)) > tenantPurgeStaleAfter
+	switch {
+	case progress.Status == types.KBCloneStatusCompleted:
+		return nil, werrors.NewBadRequestError("Tenant purge is already completed")
+	case progress.Status != types.KBCloneStatusFailed && !stale:
+		return nil, werrors.NewConflictError("Tenant purge is still running")
+	}
+
+	progress.Status = types.KBCloneStatusPending
+	progress.Error = ""
+	progress.Message = "Task queued, retrying..."
+	if err := s.saveTenantPurgeProgress(ctx, progress); err != nil {
+		logger.Errorf(ctx, "Failed to save tenant purge progress: %v", err)
+		return nil, err
+	}
+	runID := fmt.Sprintf("%s_retry_%d", progress.TaskID, s.clock.Now().UnixMilli())
+	if err := s.enqueueTenantPurge(ctx, progress, runID, force); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// enqueueTenantPurge queues a run of the purge
+func (s *tenantDataService) enqueueTenantPurge(ctx context.Context,
+	progress *types.TenantPurgeProgress, runID string, force bool,
+) error {
+	requestID, _ := ctx.Value(types.RequestIDContextKey).(string)
+	payloadBytes, err := json.Marshal(types.TenantPurgePayload{
+		TenantID:  progress.TenantID,
+		TaskID:    progress.TaskID,
+		RequestID: requestID,
+		Force:     force,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal tenant purge payload: %w", err)
+	}
+	// Failures are recorded in the progress and retried on request, so the task itself is not retried
+	task := asynq.NewTask(types.TypeTenantPurge, payloadBytes,
+		asynq.TaskID(runID), asynq.Queue("low"), asynq.MaxRetry(0), asynq.Timeout(24*time.Hour))
+	info, err := s.task.Enqueue(task)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to enqueue tenant purge task: %v", err)
+		return err
+	}
+	logger.Infof(ctx, "Enqueued tenant purge task: id=%s queue=%s tenant=%d", info.ID, info.Queue, progress.TenantID)
+	return nil
+}
+
+// ProcessTenantPurge handles Asynq tenant purge tasks.
+// The index entries and graph data of every knowledge base of the tenant, deleted and temporary ones
+// included, are deleted first, then all files of the tenant in storage. Anything that fails is recorded
+// and the records stay in place, so that a retry finds the data again. Only when everything is deleted,
+// or the retry is forced, are the records of the tenant removed permanently together with the tenant.
+func (s *tenantDataService) ProcessTenantPurge(ctx context.Context, t *asynq.Task) error {
+	var payload types.TenantPurgePayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "failed to unmarshal tenant purge payload: %v", err)
+		return nil
+	}
+
+	ctx = logger.WithRequestID(ctx, payload.RequestID)
+	ctx = context.WithValue(ctx, types.RequestIDContextKey, payload.RequestID)
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+
+	progress, err := s.GetTenantPurgeProgress(ctx, payload.TenantID, payload.TaskID)
+	if err != nil {
+		logger.Errorf(ctx, "failed to load tenant purge progress: %v", err)
+		return nil
+	}
+	progress.Attempts++
+	progress.Failures = nil
+	fail := func(message string) error {
+		logger.Errorf(ctx, "Tenant purge task %s failed: %s", payload.TaskID, message)
+		progress.Status = types.KBCloneStatusFailed
+		progress.Message = message
+		if len(progress.Failures) > 0 {
+			last := progress.Failures[len(progress.Failures)-1]
+			progress.Error = fmt.Sprintf("%s %s: %s", last.Resource, last.ID, last.Error)
+		}
+		_ = s.saveTenantPurgeProgress(ctx, progress)
+		return nil
+	}
+
+	tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		progress.AddFailure(types.TenantPurgeResourceRecords, strconv.FormatUint(payload.TenantID, 10), err)
+		return fail("Failed to get tenant")
+	}
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenantInfo)
+
+	kbs, err := s.kbRepo.ListAllKnowledgeBasesByTenantID(ctx, payload.TenantID)
+	if err != nil {
+		progress.AddFailure(types.TenantPurgeResourceKnowledgeBase, "", err)
+		return fail("Failed to list knowledge bases")
+	}
+	progress.Status = types.KBCloneStatusProcessing
+	progress.Total = len(kbs) + 2
+	progress.Processed = 0
+	_ = s.saveTenantPurgeProgress(ctx, progress)
+
+	for _, kb := range kbs {
+		if progress.KnowledgeBasePurged(kb.ID) {
+			progress.Processed++
+			continue
+		}
+		progress.Message = fmt.Sprintf("Purging knowledge base %s (%d/%d)", kb.Name, progress.Processed+1, len(kbs))
+		_ = s.saveTenantPurgeProgress(ctx, progress)
+
+		if retained, ok := s.purgeKnowledgeBase(ctx, tenantInfo, kb, progress); ok {
+			progress.PurgedKnowledgeBases = append(progress.PurgedKnowledgeBases, kb.ID)
+			for _, filePath := range retained {
+				progress.AddRetainedFile(filePath)
+			}
+		}
+		progress.Processed++
+	}
+
+	// All backends store the files of a tenant under its own prefix, export archives included
+	progress.Message = "Deleting files..."
+	_ = s.saveTenantPurgeProgress(ctx, progress)
+	deletedFiles, err := s.fileSvc.DeleteTenantFiles(ctx, payload.TenantID)
+	progress.Deleted["files"] += int64(deletedFiles)
+	if err != nil {
+		progress.AddFailure(types.TenantPurgeResourceStorage, "", err)
+	}
+	progress.Processed++
+
+	if len(progress.Failures) > 0 && !payload.Force {
+		return fail(fmt.Sprintf("%d deletions failed, the records are kept so that the purge can be retried",
+			len(progress.Failures)))
+	}
+
+	progress.Message = "Deleting records..."
+	_ = s.saveTenantPurgeProgress(ctx, progress)
+	deleted, err := s.tenantRepo.PurgeTenant(ctx, payload.TenantID)
+	if err != nil {
+		progress.AddFailure(types.TenantPurgeResourceRecords, "", err)
+		return fail("Failed to delete records")
+	}
+	for table, n := range deleted {
+		progress.Deleted[table] = n
+	}
+
+	progress.Status = types.KBCloneStatusCompleted
+	progress.Processed = progress.Total
+	progress.CompletedAt = s.clock.Now().Unix()
+	progress.Message = fmt.Sprintf("Tenant %d purged", payload.TenantID)
+	if len(progress.Failures) > 0 {
+		progress.Message = fmt.Sprintf("Tenant %d purged, %d deletions failed and were skipped",
+			payload.TenantID, len(progress.Failures))
+	}
+	_ = s.saveTenantPurgeProgress(ctx, progress)
+	logger.Infof(ctx, "Tenant purge task %s completed: deleted=%v failures=%d retained_files=%d",
+		payload.TaskID, progress.Deleted, len(progress.Failures), progress.RetainedFileCount)
+	return nil
+}
+
+// purgeKnowledgeBase deletes the index entries, vector space entries and graph data of a knowledge
+// base and records the failures. Chunk images are deleted where the storage backend manages them; the
+// others are returned as retained. Returns false if anything failed.
+func (s *tenantDataService) purgeKnowledgeBase(ctx context.Context,
+	tenant *types.Tenant, kb *types.KnowledgeBase, progress *types.TenantPurgeProgress,
+) ([]string, bool) {
+	failures := len(progress.Failures)
+	knowledgeList, err := s.knowledgeRepo.ListAllKnowledgeByKnowledgeBaseID(ctx, tenant.ID, kb.ID)
+	if err != nil {
+		progress.AddFailure(types.TenantPurgeResourceKnowledgeBase, kb.ID, err)
+		return nil, false
+	}
+	if len(knowledgeList) == 0 {
+		return nil, true
+	}
+
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenant.GetEffectiveEngines())
+	if err != nil {
+		progress.AddFailure(types.TenantPurgeResourceVectors, kb.ID, err)
+		return nil, false
+	}
+	type groupKey struct {
+		EmbeddingModelID string
+		Type             string
+	}
+	embeddingGroups := make(map[groupKey][]string)
+	for _, knowledge := range knowledgeList {
+		key := groupKey{EmbeddingModelID: knowledge.EmbeddingModelID, Type: knowledge.Type}
+		embeddingGroups[key] = append(embeddingGroups[key], knowledge.ID)
+	}
+	for key, knowledgeIDs := range embeddingGroups {
+		embeddingModel, err := s.modelService.GetEmbeddingModel(ctx, key.EmbeddingModelID)
+		if err != nil {
+			progress.AddFailure(types.TenantPurgeResourceVectors, key.EmbeddingModelID, err)
+			continue
+		}
+		if err := retrieveEngine.DeleteByKnowledgeIDList(
+			ctx, knowledgeIDs, embeddingModel.GetDimensions(), key.Type,
+		); err != nil {
+			progress.AddFailure(types.TenantPurgeResourceVectors, key.EmbeddingModelID, err)
+		}
+		if kb.VectorSpaceConfig == nil {
+			continue
+		}
+		for _, space := range kb.VectorSpaceConfig.Spaces {
+			embedder, err := s.modelService.GetEmbeddingModel(ctx, space.EmbeddingModelID)
+			if err != nil {
+				progress.AddFailure(types.TenantPurgeResourceVectors, space.EmbeddingModelID, err)
+				continue
+			}
+			if err := retrieveEngine.DeleteByKnowledgeIDList(
+				ctx, knowledgeIDs, embedder.GetDimensions(), key.Type,
+			); err != nil {
+				progress.AddFailure(types.TenantPurgeResourceVectors, space.EmbeddingModelID, err)
+			}
+		}
+	}
+
+	if s.graphEngine != nil {
+		namespaces := make([]types.NameSpace, 0, len(knowledgeList))
+		for _, knowledge := range knowledgeList {
+			namespaces = append(namespaces, types.NameSpace{KnowledgeBase: kb.ID, Knowledge: knowledge.ID})
+		}
+		if err := s.graphEngine.DelGraph(ctx, namespaces); err != nil {
+			progress.AddFailure(types.TenantPurgeResourceGraph, kb.ID, err)
+		}
+	}
+
+	// Images are only deleted once the indices are gone, so that a retry does not count them twice
+	if len(progress.Failures) > failures {
+		return nil, false
+	}
+	var retained []string
+	for _, knowledge := range knowledgeList {
+		chunks, err := s.chunkRepo.ListChunksByKnowledgeID(ctx, tenant.ID, knowledge.ID)
+		if err != nil {
+			progress.AddFailure(types.TenantPurgeResourceStorage, knowledge.ID, err)
+			continue
+		}
+		for _, chunk := range chunks {
+			if chunk.ImageInfo == "" {
+				continue
+			}
+			var images []types.ImageInfo
+			if err := json.Unmarshal([]byte(chunk.ImageInfo), &images); err != nil {
+				continue
+			}
+			for _, image := range images {
+				if image.URL == "" {
+					continue
+				}
+				// Images are stored by the document parser outside of the tenant storage
+				if err := s.fileSvc.DeleteFile(ctx, image.URL); err != nil {
+					logger.Warnf(ctx, "Failed to delete image %s of chunk %s: %v", image.URL, chunk.ID, err)
+					retained = append(retained, image.URL)
+					continue
+				}
+				progress.Deleted["images"]++
+			}
+		}
+	}
+	return retained, len(progress.Failures) == failures
+}
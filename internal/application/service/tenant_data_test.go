@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/clock"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+type tenantDataTestTenants struct {
+	interfaces.TenantRepository
+}
+
+func (tenantDataTestTenants) GetTenantByID(ctx context.Context, id uint64) (*types.Tenant, error) {
+	return &types.Tenant{ID: id}, nil
+}
+
+func TestVerifyTenantPurgeToken(t *testing.T) {
+	secret := apiKeySecret
+	apiKeySecret = func() []byte { return []byte("0123456789abcdef0123456789abcdef") }
+	defer func() { apiKeySecret = secret }()
+
+	now := time.Unix(1760600000, 0)
+	token, err := tenantPurgeToken(10003, now.Add(10*time.Minute).Unix())
+	if err != nil {
+		t.Fatalf("tenantPurgeToken() = %v", err)
+	}
+	tests := []struct {
+		name     string
+		tenantID uint64
+		token    string
+		now      time.Time
+		wantErr  bool
+	}{
+		{name: "valid", tenantID: 10003, token: token, now: now},
+		{name: "missing", tenantID: 10003, token: "", now: now, wantErr: true},
+		{name: "other tenant", tenantID: 10004, token: token, now: now, wantErr: true},
+		{name: "expired", tenantID: 10003, token: token, now: now.Add(11 * time.Minute), wantErr: true},
+		{name: "extended expiry", tenantID: 10003, token: "9999999999" + token[10:], now: now, wantErr: true},
+		{name: "malformed", tenantID: 10003, token: "not-a-token", now: now, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyTenantPurgeToken(tt.tenantID, tt.token, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyTenantPurgeToken() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfirmTenantPurgeExpiry(t *testing.T) {
+	secret := apiKeySecret
+	apiKeySecret = func() []byte { return []byte("0123456789abcdef0123456789abcdef") }
+	defer func() { apiKeySecret = secret }()
+
+	clk := clock.NewFake(time.Unix(1760600000, 0))
+	svc := &tenantDataService{tenantRepo: tenantDataTestTenants{}, clock: clk}
+	ctx := context.Background()
+
+	confirmation, err := svc.ConfirmTenantPurge(ctx, 10003)
+	if err != nil {
+		t.Fatalf("ConfirmTenantPurge() = %v", err)
+	}
+	if want := clk.Now().Add(types.TenantPurgeConfirmationTTL).Unix(); confirmation.ExpiresAt != want {
+		t.Errorf("ExpiresAt = %d, want %d", confirmation.ExpiresAt, want)
+	}
+
+	clk.Advance(types.TenantPurgeConfirmationTTL + time.Second)
+	_, err = svc.PurgeTenantData(ctx, 10003, confirmation.Token)
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("PurgeTenantData() after the TTL = %v, want an expired token", err)
+	}
+}
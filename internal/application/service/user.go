@@ -15,6 +15,7 @@ import (
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/Tencent/WeKnora/internal/clock"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
@@ -49,6 +50,8 @@ type userService struct {
 	userRepo      interfaces.UserRepository
 	tokenRepo     interfaces.AuthTokenRepository
 	tenantService interfaces.TenantService
+	roleRepo      interfaces.RoleRepository
+	clock         clock.Clock
 }
 
 // NewUserService creates a new user service instance
@@ -56,11 +59,15 @@ func NewUserService(
 	userRepo interfaces.UserRepository,
 	tokenRepo interfaces.AuthTokenRepository,
 	tenantService interfaces.TenantService,
+	roleRepo interfaces.RoleRepository,
+	clk clock.Clock,
 ) interfaces.UserService {
 	return &userService{
 		userRepo:      userRepo,
 		tokenRepo:     tokenRepo,
 		tenantService: tenantService,
+		roleRepo:      roleRepo,
+		clock:         clk,
 	}
 }
 
@@ -113,8 +120,8 @@ func (s *userService) Register(ctx context.Context, req *types.RegisterRequest)
 		PasswordHash: string(hashedPassword),
 		TenantID:     createdTenant.ID,
 		IsActive:     true,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		CreatedAt:    s.clock.Now(),
+		UpdatedAt:    s.clock.Now(),
 	}
 
 	err = s.userRepo.CreateUser(ctx, user)
@@ -123,6 +130,19 @@ func (s *userService) Register(ctx context.Context, req *types.RegisterRequest)
 		return nil, errors.New("failed to create user")
 	}
 
+	// The user administers the tenant it registered; other users of the tenant get the default role
+	err = s.roleRepo.UpsertAssignment(ctx, &types.UserRole{
+		TenantID:  createdTenant.ID,
+		UserID:    user.ID,
+		Role:      types.RoleAdmin,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.CreatedAt,
+	})
+	if err != nil {
+		logger.Errorf(ctx, "Failed to assign the admin role to user %s: %v", user.ID, err)
+		return nil, errors.New("failed to assign the admin role")
+	}
+
 	logger.Info(ctx, "User registered successfully")
 	return user, nil
 }
@@ -167,6 +187,14 @@ func (s *userService) Login(ctx context.Context, req *types.LoginRequest) (*type
 	}
 	logger.Info(ctx, "Password verification successful")
 
+	// Verify the second factor of users with two-factor authentication
+	if user.TwoFactorEnabled {
+		if resp := s.CheckTwoFactorLogin(ctx, user, req.TOTPCode, req.RecoveryCode); resp != nil {
+			return resp, nil
+		}
+		logger.Info(ctx, "Two-factor authentication successful")
+	}
+
 	// Generate tokens
 	logger.Info(ctx, "Generating tokens")
 	accessToken, refreshToken, err := s.GenerateTokens(ctx, user)
@@ -215,7 +243,7 @@ func (s *userService) GetUserByUsername(ctx context.Context, username string) (*
 
 // UpdateUser updates user information
 func (s *userService) UpdateUser(ctx context.Context, user *types.User) error {
-	user.UpdatedAt = time.Now()
+	user.UpdatedAt = s.clock.Now()
 	return s.userRepo.UpdateUser(ctx, user)
 }
 
@@ -244,7 +272,7 @@ func (s *userService) ChangePassword(ctx context.Context, userID string, oldPass
 	}
 
 	user.PasswordHash = string(hashedPassword)
-	user.UpdatedAt = time.Now()
+	user.UpdatedAt = s.clock.Now()
 
 	return s.userRepo.UpdateUser(ctx, user)
 }
@@ -264,13 +292,15 @@ func (s *userService) GenerateTokens(
 	ctx context.Context,
 	user *types.User,
 ) (accessToken, refreshToken string, err error) {
+	now := s.clock.Now()
+
 	// Generate access token (expires in 24 hours)
 	accessClaims := jwt.MapClaims{
 		"user_id":   user.ID,
 		"email":     user.Email,
 		"tenant_id": user.TenantID,
-		"exp":       time.Now().Add(24 * time.Hour).Unix(),
-		"iat":       time.Now().Unix(),
+		"exp":       now.Add(24 * time.Hour).Unix(),
+		"iat":       now.Unix(),
 		"type":      "access",
 	}
 
@@ -283,8 +313,8 @@ func (s *userService) GenerateTokens(
 	// Generate refresh token (expires in 7 days)
 	refreshClaims := jwt.MapClaims{
 		"user_id": user.ID,
-		"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(),
-		"iat":     time.Now().Unix(),
+		"exp":     now.Add(7 * 24 * time.Hour).Unix(),
+		"iat":     now.Unix(),
 		"type":    "refresh",
 	}
 
@@ -300,9 +330,9 @@ func (s *userService) GenerateTokens(
 		UserID:    user.ID,
 		Token:     accessToken,
 		TokenType: "access_token",
-		ExpiresAt: time.Now().Add(24 * time.Hour),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ExpiresAt: now.Add(24 * time.Hour),
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 
 	refreshTokenRecord := &types.AuthToken{
@@ -310,9 +340,9 @@ func (s *userService) GenerateTokens(
 		UserID:    user.ID,
 		Token:     refreshToken,
 		TokenType: "refresh_token",
-		ExpiresAt: time.Now().Add(7 * 24 * time.Hour),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ExpiresAt: now.Add(7 * 24 * time.Hour),
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 
 	_ = s.tokenRepo.CreateToken(ctx, accessTokenRecord)
@@ -328,7 +358,7 @@ func (s *userService) ValidateToken(ctx context.Context, tokenString string) (*t
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(getJwtSecret()), nil
-	})
+	}, jwt.WithTimeFunc(s.clock.Now))
 
 	if err != nil || !token.Valid {
 		return nil, errors.New("invalid token")
@@ -363,7 +393,7 @@ func (s *userService) RefreshToken(
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(getJwtSecret()), nil
-	})
+	}, jwt.WithTimeFunc(s.clock.Now))
 
 	if err != nil || !token.Valid {
 		return "", "", errors.New("invalid refresh token")
@@ -412,7 +442,7 @@ func (s *userService) RevokeToken(ctx context.Context, tokenString string) error
 	}
 
 	tokenRecord.IsRevoked = true
-	tokenRecord.UpdatedAt = time.Now()
+	tokenRecord.UpdatedAt = s.clock.Now()
 
 	return s.tokenRepo.UpdateToken(ctx, tokenRecord)
 }
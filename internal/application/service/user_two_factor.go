@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Tencent/WeKnora/internal/application/service/auth"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+const (
+	// twoFactorIssuer names the accounts in authenticator apps
+	twoFactorIssuer = "WeKnora"
+	// recoveryCodeCount is how many recovery codes a user gets
+	recoveryCodeCount = 10
+	// maxTwoFactorAttempts is how many wrong codes in a row lock the two-factor authentication of a user
+	maxTwoFactorAttempts = 5
+	// twoFactorLockout is how long codes are refused once the two-factor authentication is locked
+	twoFactorLockout = 15 * time.Minute
+)
+
+var (
+	// ErrTwoFactorAlreadyEnabled is returned when setting up two-factor authentication a second time
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned when changing the two-factor authentication of a user without it
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorNotSetUp is returned when verifying a code before the setup
+	ErrTwoFactorNotSetUp = errors.New("two-factor authentication has not been set up")
+	// ErrInvalidTwoFactorCode is returned for a wrong, expired or reused TOTP or recovery code
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor authentication code")
+	// ErrTwoFactorLocked is returned while codes are refused after too many wrong ones
+	ErrTwoFactorLocked = errors.New("too many failed two-factor authentication attempts")
+	// ErrInvalidPassword is returned when the password confirming a change is wrong
+	ErrInvalidPassword = errors.New("invalid password")
+	// ErrTwoFactorNotConfigured is returned when there is no key to encrypt the TOTP secrets with
+	ErrTwoFactorNotConfigured = errors.New("two-factor authentication is not configured: TENANT_AES_KEY is not set")
+)
+
+// SetupTwoFactor generates a TOTP secret for the user, to add to an authenticator app. Two-factor
+// authentication is only enabled once a code of the app is verified with EnableTwoFactor.
+func (s *userService) SetupTwoFactor(ctx context.Context, userID string) (*types.TwoFactorSetup, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := encryptTOTPSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = encrypted
+	user.TOTPLastStep = 0
+	user.UpdatedAt = s.clock.Now()
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	logger.Infof(ctx, "Two-factor authentication set up for user %s", user.ID)
+	return &types.TwoFactorSetup{
+		Secret:     secret,
+		OTPAuthURL: auth.TOTPURL(twoFactorIssuer, user.Email, secret),
+	}, nil
+}
+
+// EnableTwoFactor enables two-factor authentication once the user proves the authenticator app has the
+// secret of the setup, and returns the recovery codes
+func (s *userService) EnableTwoFactor(
+	ctx context.Context, userID string, totpCode string,
+) (*types.TwoFactorRecoveryCodes, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetUp
+	}
+	if err := s.verifyTOTPCode(ctx, user, totpCode); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	user.TwoFactorEnabled = true
+	user.RecoveryCodes = hashes
+	user.UpdatedAt = s.clock.Now()
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	logger.Infof(ctx, "Two-factor authentication enabled for user %s", user.ID)
+	return &types.TwoFactorRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor disables two-factor authentication after checking the password and a TOTP or recovery code
+func (s *userService) DisableTwoFactor(ctx context.Context, userID string, req *types.TwoFactorDisableRequest) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return ErrInvalidPassword
+	}
+	if err := s.verifySecondFactor(ctx, user, req.TOTPCode, req.RecoveryCode); err != nil {
+		return err
+	}
+
+	user.TwoFactorEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = nil
+	user.UpdatedAt = s.clock.Now()
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	logger.Infof(ctx, "Two-factor authentication disabled for user %s", user.ID)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user after checking a TOTP code
+func (s *userService) RegenerateRecoveryCodes(
+	ctx context.Context, userID string, totpCode string,
+) (*types.TwoFactorRecoveryCodes, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.verifyTOTPCode(ctx, user, totpCode); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	user.RecoveryCodes = hashes
+	user.UpdatedAt = s.clock.Now()
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	logger.Infof(ctx, "Recovery codes regenerated for user %s", user.ID)
+	return &types.TwoFactorRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// CheckTwoFactorLogin checks the second factor of a login of a user with two-factor authentication. It
+// returns the failed login response, or nil when the code was accepted and marked as used.
+func (s *userService) CheckTwoFactorLogin(
+	ctx context.Context, user *types.User, totpCode, recoveryCode string,
+) *types.LoginResponse {
+	if totpCode == "" && recoveryCode == "" {
+		logger.Info(ctx, "Two-factor authentication code required")
+		return &types.LoginResponse{
+			Success:           false,
+			Message:           "Two-factor authentication code required",
+			TwoFactorRequired: true,
+		}
+	}
+	if err := s.verifySecondFactor(ctx, user, totpCode, recoveryCode); err != nil {
+		logger.Warnf(ctx, "Two-factor authentication failed for user %s: %v", user.ID, err)
+		message := "Login failed"
+		switch {
+		case errors.Is(err, ErrInvalidTwoFactorCode):
+			message = "Invalid two-factor authentication code"
+		case errors.Is(err, ErrTwoFactorLocked):
+			message = "Too many failed two-factor authentication attempts, try again later"
+		}
+		return &types.LoginResponse{Success: false, Message: message, TwoFactorRequired: true}
+	}
+	return nil
+}
+
+// verifySecondFactor checks a TOTP code, or else a recovery code, and marks it as used
+func (s *userService) verifySecondFactor(ctx context.Context, user *types.User, totpCode, recoveryCode string) error {
+	if totpCode != "" {
+		return s.verifyTOTPCode(ctx, user, totpCode)
+	}
+	if err := s.checkTwoFactorLock(user); err != nil {
+		return err
+	}
+	hash := hashRecoveryCode(recoveryCode)
+	index := slices.IndexFunc(user.RecoveryCodes, func(h string) bool {
+		return hmac.Equal([]byte(h), []byte(hash))
+	})
+	if recoveryCode == "" || index < 0 {
+		return s.twoFactorFailed(ctx, user)
+	}
+	// The codes are only replaced when they did not change since they were read, so that a code used by
+	// concurrent requests is only accepted once
+	remaining := slices.Delete(slices.Clone(user.RecoveryCodes), index, index+1)
+	used, err := s.userRepo.UseRecoveryCode(ctx, user.ID, user.RecoveryCodes, remaining)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return s.twoFactorFailed(ctx, user)
+	}
+	user.RecoveryCodes = remaining
+	user.TwoFactorFailedAttempts = 0
+	user.TwoFactorLockedUntil = nil
+	return nil
+}
+
+// verifyTOTPCode checks a TOTP code against the secret of the user and marks its time step as used
+func (s *userService) verifyTOTPCode(ctx context.Context, user *types.User, code string) error {
+	if err := s.checkTwoFactorLock(user); err != nil {
+		return err
+	}
+	secret, err := decryptTOTPSecret(user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+	step, ok := auth.VerifyTOTP(secret, strings.ReplaceAll(strings.TrimSpace(code), " ", ""), s.clock.Now(),
+		user.TOTPLastStep)
+	if !ok {
+		return s.twoFactorFailed(ctx, user)
+	}
+	// The step is only recorded when no code of the step was accepted since the user was read, so that a
+	// code sent by concurrent requests is only accepted once
+	used, err := s.userRepo.UseTOTPStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return s.twoFactorFailed(ctx, user)
+	}
+	user.TOTPLastStep = step
+	user.TwoFactorFailedAttempts = 0
+	user.TwoFactorLockedUntil = nil
+	return nil
+}
+
+// checkTwoFactorLock refuses the codes of a user whose two-factor authentication is locked
+func (s *userService) checkTwoFactorLock(user *types.User) error {
+	if user.TwoFactorLockedUntil != nil && s.clock.Now().Before(*user.TwoFactorLockedUntil) {
+		return ErrTwoFactorLocked
+	}
+	return nil
+}
+
+// twoFactorFailed counts a wrong code of the user, which locks its two-factor authentication for
+// twoFactorLockout once maxTwoFactorAttempts codes in a row were wrong, and returns ErrInvalidTwoFactorCode
+func (s *userService) twoFactorFailed(ctx context.Context, user *types.User) error {
+	err := s.userRepo.RecordTwoFactorFailure(ctx, user.ID, maxTwoFactorAttempts, s.clock.Now().Add(twoFactorLockout))
+	if err != nil {
+		logger.Errorf(ctx, "Failed to record the failed two-factor attempt of user %s: %v", user.ID, err)
+		return err
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+// newRecoveryCodes generates the recovery codes of a user and the hashes they are stored as
+func newRecoveryCodes() (codes []string, hashes types.StringArray, err error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < recoveryCodeCount; i++ {
+		random := make([]byte, 7)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		code := strings.ToLower(encoding.EncodeToString(random)[:10])
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode returns the hash a recovery code is stored as, ignoring case, spaces and dashes.
+// The codes are random, so a plain SHA-256 suffices.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// totpSecretKey derives the key the TOTP secrets are encrypted with from TENANT_AES_KEY
+func totpSecretKey() ([]byte, error) {
+	secret := apiKeySecret()
+	if len(secret) == 0 {
+		return nil, ErrTwoFactorNotConfigured
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("totp_secret"))
+	return mac.Sum(nil), nil
+}
+
+// encryptTOTPSecret encrypts a TOTP secret with AES-GCM for storage on the user
+func encryptTOTPSecret(secret string) (string, error) {
+	key, err := totpSecretKey()
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aesgcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aesgcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+// decryptTOTPSecret decrypts a TOTP secret stored on a user
+func decryptTOTPSecret(encrypted string) (string, error) {
+	key, err := totpSecretKey()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted TOTP secret: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aesgcm.NonceSize() {
+		return "", errors.New("invalid encrypted TOTP secret")
+	}
+	plaintext, err := aesgcm.Open(nil, data[:aesgcm.NonceSize()], data[aesgcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+	}
+	return string(plaintext), nil
+}
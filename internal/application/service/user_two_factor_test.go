@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Tencent/WeKnora/internal/clock"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// twoFactorTestUsers keeps copies of the users, so that changes only show once they are saved
+type twoFactorTestUsers struct {
+	interfaces.UserRepository
+	users map[string]types.User
+}
+
+func (r *twoFactorTestUsers) GetUserByID(ctx context.Context, id string) (*types.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return &user, nil
+}
+
+func (r *twoFactorTestUsers) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *twoFactorTestUsers) UpdateUser(ctx context.Context, user *types.User) error {
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *twoFactorTestUsers) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	user := r.users[id]
+	if user.TOTPLastStep >= step {
+		return false, nil
+	}
+	user.TOTPLastStep, user.TwoFactorFailedAttempts, user.TwoFactorLockedUntil = step, 0, nil
+	r.users[id] = user
+	return true, nil
+}
+
+func (r *twoFactorTestUsers) UseRecoveryCode(
+	ctx context.Context, id string, codes, remaining types.StringArray,
+) (bool, error) {
+	user := r.users[id]
+	if !slices.Equal(user.RecoveryCodes, codes) {
+		return false, nil
+	}
+	user.RecoveryCodes, user.TwoFactorFailedAttempts, user.TwoFactorLockedUntil = remaining, 0, nil
+	r.users[id] = user
+	return true, nil
+}
+
+func (r *twoFactorTestUsers) RecordTwoFactorFailure(
+	ctx context.Context, id string, maxAttempts int, lockedUntil time.Time,
+) error {
+	user := r.users[id]
+	if user.TwoFactorFailedAttempts++; user.TwoFactorFailedAttempts >= maxAttempts {
+		user.TwoFactorFailedAttempts, user.TwoFactorLockedUntil = 0, &lockedUntil
+	}
+	r.users[id] = user
+	return nil
+}
+
+type twoFactorTestTokens struct {
+	interfaces.AuthTokenRepository
+}
+
+func (twoFactorTestTokens) CreateToken(ctx context.Context, token *types.AuthToken) error {
+	return nil
+}
+
+func TestUserServiceTwoFactor(t *testing.T) {
+	secret := apiKeySecret
+	apiKeySecret = func() []byte { return []byte("0123456789abcdef0123456789abcdef") }
+	defer func() { apiKeySecret = secret }()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("hunter22"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &twoFactorTestUsers{users: map[string]types.User{
+		"user-1": {ID: "user-1", Email: "alice@example.com", PasswordHash: string(hash), TenantID: 7, IsActive: true},
+	}}
+	clk := clock.NewFake(time.Unix(1111111109, 0))
+	svc := NewUserService(users, twoFactorTestTokens{}, oidcTestTenants{}, nil, clk)
+	ctx := context.Background()
+
+	setup, err := svc.SetupTwoFactor(ctx, "user-1")
+	if err != nil || setup.Secret == "" || setup.OTPAuthURL == "" {
+		t.Fatalf("SetupTwoFactor = %+v, err = %v", setup, err)
+	}
+	stored := users.users["user-1"]
+	if stored.TOTPSecret == "" || stored.TOTPSecret == setup.Secret || stored.TwoFactorEnabled {
+		t.Fatalf("stored secret %q, enabled %v, want an encrypted secret of a disabled setup",
+			stored.TOTPSecret, stored.TwoFactorEnabled)
+	}
+	// Swap in the secret of the RFC 6238 test vectors, whose codes are known
+	stored.TOTPSecret, err = encryptTOTPSecret(
+		base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	users.users["user-1"] = stored
+
+	if _, err := svc.EnableTwoFactor(ctx, "user-1", "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("EnableTwoFactor with a wrong code err = %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+	codes, err := svc.EnableTwoFactor(ctx, "user-1", "081804")
+	if err != nil || len(codes.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("EnableTwoFactor = %+v, err = %v", codes, err)
+	}
+	if _, err := svc.SetupTwoFactor(ctx, "user-1"); !errors.Is(err, ErrTwoFactorAlreadyEnabled) {
+		t.Errorf("second SetupTwoFactor err = %v, want %v", err, ErrTwoFactorAlreadyEnabled)
+	}
+
+	login := func(totpCode, recoveryCode string) *types.LoginResponse {
+		resp, err := svc.Login(ctx, &types.LoginRequest{
+			Email: "alice@example.com", Password: "hunter22", TOTPCode: totpCode, RecoveryCode: recoveryCode,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	if resp := login("", ""); resp.Success || !resp.TwoFactorRequired {
+		t.Errorf("login without a code = %+v, want a two-factor challenge", resp)
+	}
+	if resp := login("081804", ""); resp.Success {
+		t.Error("login with the code used to enable two-factor authentication succeeded")
+	}
+	clk.Set(time.Unix(1234567890, 0))
+	if resp := login("005924", ""); !resp.Success || resp.Token == "" {
+		t.Errorf("login with a TOTP code = %+v, want success", resp)
+	}
+	if resp := login("005924", ""); resp.Success {
+		t.Error("TOTP code accepted twice")
+	}
+	if resp := login("", codes.RecoveryCodes[0]); !resp.Success {
+		t.Errorf("login with a recovery code = %+v, want success", resp)
+	}
+	if resp := login("", codes.RecoveryCodes[0]); resp.Success {
+		t.Error("recovery code accepted twice")
+	}
+	if got := len(users.users["user-1"].RecoveryCodes); got != recoveryCodeCount-1 {
+		t.Errorf("%d recovery codes left, want %d", got, recoveryCodeCount-1)
+	}
+
+	err = svc.DisableTwoFactor(ctx, "user-1", &types.TwoFactorDisableRequest{
+		Password: "wrong", RecoveryCode: codes.RecoveryCodes[1],
+	})
+	if !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("DisableTwoFactor with a wrong password err = %v, want %v", err, ErrInvalidPassword)
+	}
+	err = svc.DisableTwoFactor(ctx, "user-1", &types.TwoFactorDisableRequest{
+		Password: "hunter22", RecoveryCode: codes.RecoveryCodes[1],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user := users.users["user-1"]; user.TwoFactorEnabled || user.TOTPSecret != "" || len(user.RecoveryCodes) != 0 {
+		t.Errorf("user after disabling = %+v, want no two-factor authentication", user)
+	}
+	if resp := login("", ""); !resp.Success {
+		t.Errorf("login after disabling = %+v, want success without a code", resp)
+	}
+}
+
+// enabledTwoFactorUser returns a user with two-factor authentication enabled with the secret of the RFC 6238
+// test vectors, whose codes are known
+func enabledTwoFactorUser(t *testing.T, recoveryCodes ...string) types.User {
+	t.Helper()
+	secret, err := encryptTOTPSecret(
+		base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := types.StringArray{}
+	for _, code := range recoveryCodes {
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return types.User{ID: "user-1", Email: "alice@example.com", TenantID: 7, IsActive: true,
+		TwoFactorEnabled: true, TOTPSecret: secret, RecoveryCodes: hashes}
+}
+
+func TestUserServiceTwoFactorLockout(t *testing.T) {
+	secret := apiKeySecret
+	apiKeySecret = func() []byte { return []byte("0123456789abcdef0123456789abcdef") }
+	defer func() { apiKeySecret = secret }()
+
+	users := &twoFactorTestUsers{users: map[string]types.User{"user-1": enabledTwoFactorUser(t)}}
+	clk := clock.NewFake(time.Unix(1234567890, 0))
+	svc := NewUserService(users, twoFactorTestTokens{}, oidcTestTenants{}, nil, clk)
+	ctx := context.Background()
+
+	for i := 0; i < maxTwoFactorAttempts; i++ {
+		if _, err := svc.RegenerateRecoveryCodes(ctx, "user-1", "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("wrong code %d err = %v, want %v", i+1, err, ErrInvalidTwoFactorCode)
+		}
+	}
+	if _, err := svc.RegenerateRecoveryCodes(ctx, "user-1", "005924"); !errors.Is(err, ErrTwoFactorLocked) {
+		t.Errorf("right code while locked err = %v, want %v", err, ErrTwoFactorLocked)
+	}
+	clk.Advance(twoFactorLockout)
+	if _, err := svc.RegenerateRecoveryCodes(ctx, "user-1", "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("wrong code after the lockout err = %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+	if user := users.users["user-1"]; user.TwoFactorFailedAttempts != 1 {
+		t.Errorf("%d failed attempts after the lockout, want a new count", user.TwoFactorFailedAttempts)
+	}
+}
+
+func TestUserServiceTwoFactorConcurrentUse(t *testing.T) {
+	secret := apiKeySecret
+	apiKeySecret = func() []byte { return []byte("0123456789abcdef0123456789abcdef") }
+	defer func() { apiKeySecret = secret }()
+
+	users := &twoFactorTestUsers{users: map[string]types.User{
+		"user-1": enabledTwoFactorUser(t, "aaaaa-bbbbb", "ccccc-ddddd"),
+	}}
+	clk := clock.NewFake(time.Unix(1234567890, 0))
+	svc := NewUserService(users, twoFactorTestTokens{}, oidcTestTenants{}, nil, clk).(*userService)
+	ctx := context.Background()
+
+	// Two requests read the user before either of them uses the code
+	first, second := users.users["user-1"], users.users["user-1"]
+	if err := svc.verifyTOTPCode(ctx, &first, "005924"); err != nil {
+		t.Fatalf("first use of the TOTP code err = %v", err)
+	}
+	if err := svc.verifyTOTPCode(ctx, &second, "005924"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("concurrent use of the TOTP code err = %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	first, second = users.users["user-1"], users.users["user-1"]
+	if err := svc.verifySecondFactor(ctx, &first, "", "aaaaa-bbbbb"); err != nil {
+		t.Fatalf("first use of the recovery code err = %v", err)
+	}
+	if err := svc.verifySecondFactor(ctx, &second, "", "aaaaa-bbbbb"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("concurrent use of the recovery code err = %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+	if got := users.users["user-1"].RecoveryCodes; len(got) != 1 || got[0] != hashRecoveryCode("ccccc-ddddd") {
+		t.Errorf("recovery codes left = %v, want the unused one", got)
+	}
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Tencent/WeKnora/internal/application/service/retriever"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// vectorSpaceEmbedder is an additional vector space of a knowledge base with its embedding model
+type vectorSpaceEmbedder struct {
+	space    types.VectorSpace
+	embedder embedding.Embedder
+}
+
+// loadVectorSpaceEmbedders loads the embedding models of the additional vector spaces of a knowledge
+// base. Spaces whose model cannot be loaded are skipped with a warning.
+func loadVectorSpaceEmbedders(ctx context.Context,
+	modelService interfaces.ModelService, kb *types.KnowledgeBase,
+) []vectorSpaceEmbedder {
+	if kb == nil || kb.VectorSpaceConfig == nil {
+		return nil
+	}
+	spaces := make([]vectorSpaceEmbedder, 0, len(kb.VectorSpaceConfig.Spaces))
+	for _, space := range kb.VectorSpaceConfig.Spaces {
+		embedder, err := modelService.GetEmbeddingModel(ctx, space.EmbeddingModelID)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to get embedding model %s of vector space %s: %v",
+				space.EmbeddingModelID, space.Name, err)
+			continue
+		}
+		spaces = append(spaces, vectorSpaceEmbedder{space: space, embedder: embedder})
+	}
+	return spaces
+}
+
+// validateVectorSpaceDimensions checks that the embedding models of all vector spaces, the primary one
+// included, exist and have distinct dimensions, since index stores keep vector spaces apart by dimension
+func validateVectorSpaceDimensions(ctx context.Context,
+	modelService interfaces.ModelService, primaryModelID string, config *types.VectorSpaceConfig,
+) error {
+	if config == nil || len(config.Spaces) == 0 {
+		return nil
+	}
+	dimensions := make(map[int]string)
+	if primaryModelID != "" {
+		primary, err := modelService.GetEmbeddingModel(ctx, primaryModelID)
+		if err != nil {
+			return err
+		}
+		dimensions[primary.GetDimensions()] = types.PrimaryVectorSpace
+	}
+	for _, space := range config.Spaces {
+		embedder, err := modelService.GetEmbeddingModel(ctx, space.EmbeddingModelID)
+		if err != nil {
+			return werrors.NewBadRequestError("Invalid vector space configuration").
+				WithDetails(fmt.Sprintf("embedding model of vector space %q is not available: %v", space.Name, err))
+		}
+		if other, exists := dimensions[embedder.GetDimensions()]; exists {
+			return werrors.NewBadRequestError("Invalid vector space configuration").
+				WithDetails(fmt.Sprintf("vector spaces %q and %q have the same dimension %d",
+					other, space.Name, embedder.GetDimensions()))
+		}
+		dimensions[embedder.GetDimensions()] = space.Name
+	}
+	return nil
+}
+
+// indexVectorSpaces embeds index entries into the additional vector spaces of a knowledge base.
+// Only vector indices are written; each space stores the entries under source IDs of its own.
+func indexVectorSpaces(ctx context.Context,
+	modelService interfaces.ModelService,
+	retrieveEngine *retriever.CompositeRetrieveEngine,
+	kb *types.KnowledgeBase,
+	indexInfoList []*types.IndexInfo,
+) error {
+	if len(indexInfoList) == 0 {
+		return nil
+	}
+	for _, space := range loadVectorSpaceEmbedders(ctx, modelService, kb) {
+		spaceIndexInfo := make([]*types.IndexInfo, 0, len(indexInfoList))
+		for _, indexInfo := range indexInfoList {
+			info := *indexInfo
+			info.SourceID = types.VectorSpaceSourceID(indexInfo.SourceID, space.space.EmbeddingModelID)
+			spaceIndexInfo = append(spaceIndexInfo, &info)
+		}
+		if err := retrieveEngine.BatchIndexVectors(ctx, space.embedder, spaceIndexInfo); err != nil {
+			return fmt.Errorf("index vector space %s: %w", space.space.Name, err)
+		}
+		logger.Infof(ctx, "Indexed %d entries into vector space %s", len(spaceIndexInfo), space.space.Name)
+	}
+	return nil
+}
+
+// deleteVectorSpaceIndices removes the index entries of knowledge from the additional vector spaces
+// of a knowledge base; failures are logged
+func deleteVectorSpaceIndices(ctx context.Context,
+	modelService interfaces.ModelService,
+	retrieveEngine *retriever.CompositeRetrieveEngine,
+	kb *types.KnowledgeBase,
+	knowledgeIDs []string,
+	knowledgeType string,
+) {
+	if len(knowledgeIDs) == 0 {
+		return
+	}
+	for _, space := range loadVectorSpaceEmbedders(ctx, modelService, kb) {
+		if err := retrieveEngine.DeleteByKnowledgeIDList(
+			ctx, knowledgeIDs, space.embedder.GetDimensions(), knowledgeType,
+		); err != nil {
+			logger.Warnf(ctx, "Failed to delete index of vector space %s: %v", space.space.Name, err)
+		}
+	}
+}
+
+// deleteVectorSpaceChunkIndices removes the index entries of chunks from the additional vector spaces
+// of a knowledge base; failures are logged
+func deleteVectorSpaceChunkIndices(ctx context.Context,
+	modelService interfaces.ModelService,
+	retrieveEngine *retriever.CompositeRetrieveEngine,
+	kb *types.KnowledgeBase,
+	chunkIDs []string,
+	knowledgeType string,
+) {
+	if len(chunkIDs) == 0 {
+		return
+	}
+	for _, space := range loadVectorSpaceEmbedders(ctx, modelService, kb) {
+		if err := retrieveEngine.DeleteByChunkIDList(
+			ctx, chunkIDs, space.embedder.GetDimensions(), knowledgeType,
+		); err != nil {
+			logger.Warnf(ctx, "Failed to delete chunk index of vector space %s: %v", space.space.Name, err)
+		}
+	}
+}
+
+// removedVectorSpaces returns the additional vector spaces of a knowledge base whose embedding model
+// is no longer used by the new configuration
+func (s *knowledgeBaseService) removedVectorSpaces(ctx context.Context,
+	kb *types.KnowledgeBase, config *types.VectorSpaceConfig,
+) []vectorSpaceEmbedder {
+	kept := make(map[string]bool, len(config.Spaces))
+	for _, space := range config.Spaces {
+		kept[space.EmbeddingModelID] = true
+	}
+	var removed []vectorSpaceEmbedder
+	for _, space := range loadVectorSpaceEmbedders(ctx, s.modelService, kb) {
+		if !kept[space.space.EmbeddingModelID] {
+			removed = append(removed, space)
+		}
+	}
+	return removed
+}
+
+// deleteRemovedVectorSpaces deletes the indices of removed vector spaces for all knowledge of a
+// knowledge base; failures are logged
+func (s *knowledgeBaseService) deleteRemovedVectorSpaces(ctx context.Context,
+	kb *types.KnowledgeBase, removed []vectorSpaceEmbedder,
+) {
+	if len(removed) == 0 {
+		return
+	}
+	tenantInfo, ok := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if !ok {
+		return
+	}
+	retrieveEngine, err := retriever.NewCompositeRetrieveEngine(s.retrieveEngine, tenantInfo.GetEffectiveEngines())
+	if err != nil {
+		logger.Warnf(ctx, "Failed to create retrieval engine for vector space cleanup: %v", err)
+		return
+	}
+	knowledgeList, err := s.kgRepo.ListKnowledgeByKnowledgeBaseID(ctx, kb.TenantID, kb.ID)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to list knowledge for vector space cleanup: %v", err)
+		return
+	}
+	knowledgeByType := make(map[string][]string)
+	for _, knowledge := range knowledgeList {
+		knowledgeByType[knowledge.Type] = append(knowledgeByType[knowledge.Type], knowledge.ID)
+	}
+	for _, space := range removed {
+		for knowledgeType, knowledgeIDs := range knowledgeByType {
+			if err := retrieveEngine.DeleteByKnowledgeIDList(
+				ctx, knowledgeIDs, space.embedder.GetDimensions(), knowledgeType,
+			); err != nil {
+				logger.Warnf(ctx, "Failed to delete index of removed vector space %s: %v", space.space.Name, err)
+			}
+		}
+		logger.Infof(ctx, "Deleted index of removed vector space %s", space.space.Name)
+	}
+}
+
+// deleteKnowledgeVectorSpaces removes knowledge from the additional vector spaces of their knowledge
+// bases; failures are logged
+func (s *knowledgeService) deleteKnowledgeVectorSpaces(ctx context.Context,
+	retrieveEngine *retriever.CompositeRetrieveEngine, knowledgeList []*types.Knowledge,
+) {
+	type groupKey struct {
+		KnowledgeBaseID string
+		Type            string
+	}
+	group := make(map[groupKey][]string)
+	for _, knowledge := range knowledgeList {
+		key := groupKey{KnowledgeBaseID: knowledge.KnowledgeBaseID, Type: knowledge.Type}
+		group[key] = append(group[key], knowledge.ID)
+	}
+	knowledgeBases := make(map[string]*types.KnowledgeBase)
+	for key, knowledgeIDs := range group {
+		kb, loaded := knowledgeBases[key.KnowledgeBaseID]
+		if !loaded {
+			var err error
+			kb, err = s.kbService.GetKnowledgeBaseByID(ctx, key.KnowledgeBaseID)
+			if err != nil {
+				logger.Warnf(ctx, "Failed to get knowledge base %s for vector space cleanup: %v", key.KnowledgeBaseID, err)
+			}
+			knowledgeBases[key.KnowledgeBaseID] = kb
+		}
+		deleteVectorSpaceIndices(ctx, s.modelService, retrieveEngine, kb, knowledgeIDs, key.Type)
+	}
+}
@@ -121,7 +121,7 @@ func (s *webSearchStateService) DeleteWebSearchTempKBState(ctx context.Context,
 	}
 
 	// Delete the knowledge base
-	if delErr := s.knowledgeBaseService.DeleteKnowledgeBase(ctx, state.KBID); delErr != nil {
+	if delErr := s.knowledgeBaseService.DeleteKnowledgeBase(ctx, state.KBID, true); delErr != nil {
 		logger.Warnf(ctx, "Failed to delete temp knowledge base %s: %v", state.KBID, delErr)
 	}
 
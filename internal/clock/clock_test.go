@@ -0,0 +1,50 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeAdvance(t *testing.T) {
+	start := time.Date(2024, 3, 10, 1, 30, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	c.Advance(90 * time.Minute)
+	if got := c.Since(start); got != 90*time.Minute {
+		t.Errorf("Since = %v, want 90m", got)
+	}
+	if !c.Now().Equal(start.Add(90 * time.Minute)) {
+		t.Errorf("Now = %v", c.Now())
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	start := time.Unix(0, 0)
+	c := NewFake(start)
+	ticker := c.NewTicker(time.Minute)
+
+	c.Advance(30 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker fired early")
+	default:
+	}
+
+	c.Advance(30 * time.Second)
+	select {
+	case at := <-ticker.C():
+		if !at.Equal(start.Add(time.Minute)) {
+			t.Errorf("tick at %v, want %v", at, start.Add(time.Minute))
+		}
+	default:
+		t.Fatal("ticker did not fire")
+	}
+
+	ticker.Stop()
+	c.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
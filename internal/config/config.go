@@ -20,6 +20,7 @@ type Config struct {
 	Server          *ServerConfig          `yaml:"server"           json:"server"`
 	KnowledgeBase   *KnowledgeBaseConfig   `yaml:"knowledge_base"   json:"knowledge_base"`
 	Tenant          *TenantConfig          `yaml:"tenant"           json:"tenant"`
+	Auth            *AuthConfig            `yaml:"auth"             json:"auth"`
 	Models          []ModelConfig          `yaml:"models"           json:"models"`
 	VectorDatabase  *VectorDatabaseConfig  `yaml:"vector_database"  json:"vector_database"`
 	DocReader       *DocReaderConfig       `yaml:"docreader"        json:"docreader"`
@@ -27,6 +28,26 @@ type Config struct {
 	ExtractManager  *ExtractManagerConfig  `yaml:"extract"          json:"extract"`
 	WebSearch       *WebSearchConfig       `yaml:"web_search"       json:"web_search"`
 	PromptTemplates *PromptTemplatesConfig `yaml:"prompt_templates" json:"prompt_templates"`
+	ProviderLog     *ProviderLogConfig     `yaml:"provider_log"     json:"provider_log"`
+	ModelQueue      *ModelQueueConfig      `yaml:"model_queue"      json:"model_queue"`
+	Evaluation      *EvaluationConfig      `yaml:"evaluation"       json:"evaluation"`
+	OpenAICompat    *OpenAICompatConfig    `yaml:"openai_compat"    json:"openai_compat"`
+	AsyncChat       *AsyncChatConfig       `yaml:"async_chat"       json:"async_chat"`
+	TaskWebhook     *TaskWebhookConfig     `yaml:"task_webhook"     json:"task_webhook"`
+	SlowRequestLog  *SlowRequestLogConfig  `yaml:"slow_request_log" json:"slow_request_log"`
+	Analytics       *AnalyticsConfig       `yaml:"analytics"        json:"analytics"`
+	RateLimit       *RateLimitConfig       `yaml:"rate_limit"       json:"rate_limit"`
+	PayloadLimit    *PayloadLimitConfig    `yaml:"payload_limit"    json:"payload_limit"`
+	BodyLimit       *BodyLimitConfig       `yaml:"body_limit"       json:"body_limit"`
+	Idempotency     *IdempotencyConfig     `yaml:"idempotency"      json:"idempotency"`
+	Audit           *AuditConfig           `yaml:"audit"            json:"audit"`
+	CORS            *CORSConfig            `yaml:"cors"             json:"cors"`
+	Health          *HealthConfig          `yaml:"health"           json:"health"`
+	MCP             *MCPConfig             `yaml:"mcp"              json:"mcp"`
+	Features        types.FeatureFlags     `yaml:"features"         json:"features"`
+
+	configFile string // Path of the loaded configuration file
+	rawContent []byte // Configuration file content before environment substitution
 }
 
 type DocReaderConfig struct {
@@ -62,6 +83,13 @@ type ConversationConfig struct {
 	ExtractRelationshipsPrompt string         `yaml:"extract_relationships_prompt"  json:"extract_relationships_prompt"`
 	// GenerateQuestionsPrompt is used to generate questions for document chunks to improve recall
 	GenerateQuestionsPrompt string `yaml:"generate_questions_prompt" json:"generate_questions_prompt"`
+	// ExplainRetrievalPrompt is used to explain in natural language why search results match a query
+	ExplainRetrievalPrompt string `yaml:"explain_retrieval_prompt" json:"explain_retrieval_prompt"`
+	// RetrievalSoftDeadline is the time after which retrieval stops waiting for slow searches and the answer
+	// is generated from the chunks retrieved so far; 0 waits for every search
+	RetrievalSoftDeadline time.Duration `yaml:"retrieval_soft_deadline" json:"retrieval_soft_deadline"`
+	// RetrievalMinChunks is the number of chunks needed to stop at the soft deadline (default 1)
+	RetrievalMinChunks int `yaml:"retrieval_min_chunks" json:"retrieval_min_chunks"`
 }
 
 // SummaryConfig 摘要配置
@@ -77,8 +105,11 @@ type SummaryConfig struct {
 	Temperature         float64 `yaml:"temperature"           json:"temperature"`
 	Seed                int     `yaml:"seed"                  json:"seed"`
 	MaxCompletionTokens int     `yaml:"max_completion_tokens" json:"max_completion_tokens"`
+	ContextWindow       int     `yaml:"context_window"        json:"context_window"`
 	NoMatchPrefix       string  `yaml:"no_match_prefix"       json:"no_match_prefix"`
 	Thinking            *bool   `yaml:"thinking"              json:"thinking"`
+	// OversizedChunkStrategy fits retrieved chunks larger than the context budget: truncate or summarize
+	OversizedChunkStrategy string `yaml:"oversized_chunk_strategy" json:"oversized_chunk_strategy"`
 }
 
 // ServerConfig 服务器配置
@@ -87,6 +118,22 @@ type ServerConfig struct {
 	Host            string        `yaml:"host"             json:"host"`
 	LogPath         string        `yaml:"log_path"         json:"log_path"`
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" json:"shutdown_timeout" default:"30s"`
+	// RequestIDFormat is the format of generated request IDs: "uuid" (default) or "short"
+	RequestIDFormat string `yaml:"request_id_format" json:"request_id_format"`
+	// SSE controls how streamed answers are written
+	SSE *SSEConfig `yaml:"sse" json:"sse"`
+}
+
+// SSEConfig controls the writing of Server-Sent Events streams
+type SSEConfig struct {
+	// PaddingInterval sends a padding comment when no frame was written for this long, forcing proxies that
+	// buffer until a size threshold to flush; 0 disables padding
+	PaddingInterval time.Duration `yaml:"padding_interval" json:"padding_interval"`
+	// PaddingBytes is the size of each padding comment (default: 2048)
+	PaddingBytes int `yaml:"padding_bytes"    json:"padding_bytes"`
+	// HeartbeatInterval sends a heartbeat comment when no frame was written for this long, keeping proxies
+	// from closing idle connections while a model is thinking; 0 disables heartbeats
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" json:"heartbeat_interval"`
 }
 
 // KnowledgeBaseConfig 知识库配置
@@ -96,6 +143,38 @@ type KnowledgeBaseConfig struct {
 	SplitMarkers    []string               `yaml:"split_markers"    json:"split_markers"`
 	KeepSeparator   bool                   `yaml:"keep_separator"   json:"keep_separator"`
 	ImageProcessing *ImageProcessingConfig `yaml:"image_processing" json:"image_processing"`
+	// IngestionRetry controls the retry of documents whose processing failed with a transient error
+	IngestionRetry *IngestionRetryConfig `yaml:"ingestion_retry" json:"ingestion_retry"`
+	// SyncProcessing processes small uploaded files within the upload request
+	SyncProcessing *SyncProcessingConfig `yaml:"sync_processing" json:"sync_processing"`
+	// MultiSearch bounds the hybrid searches across several knowledge bases
+	MultiSearch *MultiSearchConfig `yaml:"multi_search" json:"multi_search"`
+}
+
+// MultiSearchConfig bounds a hybrid search across several knowledge bases
+type MultiSearchConfig struct {
+	// MaxKnowledgeBases is the largest number of knowledge bases searched by a request (default: 20)
+	MaxKnowledgeBases int `yaml:"max_knowledge_bases" json:"max_knowledge_bases"`
+	// MaxConcurrency is the number of knowledge bases of a request searched at once (default: 4)
+	MaxConcurrency int `yaml:"max_concurrency"     json:"max_concurrency"`
+}
+
+// SyncProcessingConfig 小文件同步处理配置
+type SyncProcessingConfig struct {
+	// MaxFileSizeKB processes uploaded files up to this size within the request, larger ones by a task (0 = always a task)
+	MaxFileSizeKB int64 `yaml:"max_file_size_kb" json:"max_file_size_kb"`
+	// Timeout bounds processing within the request, the knowledge is marked failed when it expires (default: 60s)
+	Timeout time.Duration `yaml:"timeout"          json:"timeout"`
+}
+
+// IngestionRetryConfig 文档处理重试配置
+type IngestionRetryConfig struct {
+	// MaxAttempts is the number of processing attempts before a document is marked failed (default: 3)
+	MaxAttempts int `yaml:"max_attempts" json:"max_attempts"`
+	// BaseDelay is the delay before the first retry, doubled for every further retry (default: 10s)
+	BaseDelay time.Duration `yaml:"base_delay"   json:"base_delay"`
+	// MaxDelay caps the delay between retries (default: 5m)
+	MaxDelay time.Duration `yaml:"max_delay"    json:"max_delay"`
 }
 
 // ImageProcessingConfig 图像处理配置
@@ -110,6 +189,427 @@ type TenantConfig struct {
 	DefaultSessionDescription string `yaml:"default_session_description" json:"default_session_description"`
 	// EnableCrossTenantAccess enables cross-tenant access for users with permission
 	EnableCrossTenantAccess bool `yaml:"enable_cross_tenant_access" json:"enable_cross_tenant_access"`
+	// SingleTenant resolves every request to one default tenant (off by default)
+	SingleTenant *SingleTenantConfig `yaml:"single_tenant"              json:"single_tenant"`
+	// DefaultRole is the role of the users without a role assignment in a tenant: admin, editor or viewer (default)
+	DefaultRole string `yaml:"default_role"               json:"default_role"`
+}
+
+// DefaultSingleTenantID is the default tenant of single-tenant mode, the first tenant created
+const DefaultSingleTenantID uint64 = 1
+
+// SingleTenantConfig configures single-tenant mode for deployments with a single tenant. Every authenticated
+// request acts on the default tenant, whatever tenant its credentials belong to, and the endpoints creating,
+// listing, deleting or switching tenants are hidden.
+type SingleTenantConfig struct {
+	// Enabled turns single-tenant mode on
+	Enabled bool `yaml:"enabled"        json:"enabled"`
+	// TenantID is the default tenant (0 = 1)
+	TenantID uint64 `yaml:"tenant_id"      json:"tenant_id"`
+	// SharedAPIKey, when set, is accepted as X-API-Key or Bearer token in addition to user logins and
+	// tenant API keys; anyone holding it has full access to the default tenant
+	SharedAPIKey string `yaml:"shared_api_key" json:"shared_api_key"`
+}
+
+// MinSharedAPIKeyLength is the shortest shared API key accepted in single-tenant mode
+const MinSharedAPIKeyLength = 32
+
+// Validate checks that a shared API key is long enough and not an unresolved environment variable reference
+func (c *SingleTenantConfig) Validate() error {
+	if c == nil || !c.Enabled || c.SharedAPIKey == "" {
+		return nil
+	}
+	if strings.HasPrefix(c.SharedAPIKey, "${") {
+		return fmt.Errorf("tenant.single_tenant.shared_api_key references an unset environment variable")
+	}
+	if len(c.SharedAPIKey) < MinSharedAPIKeyLength {
+		return fmt.Errorf("tenant.single_tenant.shared_api_key must be at least %d characters", MinSharedAPIKeyLength)
+	}
+	return nil
+}
+
+// DefaultTenantID returns the tenant every request is resolved to
+func (c *SingleTenantConfig) DefaultTenantID() uint64 {
+	if c.TenantID == 0 {
+		return DefaultSingleTenantID
+	}
+	return c.TenantID
+}
+
+// Authentication providers validating the bearer tokens of API requests
+const (
+	// AuthProviderJWT validates the tokens issued by WeKnora at login
+	AuthProviderJWT = "jwt"
+	// AuthProviderIntrospection validates tokens with an OAuth2 token introspection endpoint (RFC 7662)
+	AuthProviderIntrospection = "introspection"
+	// AuthProviderJWKS validates JWTs signed by an external issuer with the keys it publishes
+	AuthProviderJWKS = "jwks"
+)
+
+// Types of the OpenID Connect identity providers users can log in with
+const (
+	// AuthOIDCTypeGeneric is any provider publishing an OpenID Connect discovery document at its issuer URL
+	AuthOIDCTypeGeneric = "oidc"
+	// AuthOIDCTypeGoogle is Google, with the issuer https://accounts.google.com
+	AuthOIDCTypeGoogle = "google"
+	// AuthOIDCTypeAzure is an Azure AD (Microsoft Entra ID) directory
+	AuthOIDCTypeAzure = "azure"
+)
+
+// oidcProviderNamePattern restricts the provider names, which appear in the login routes
+var oidcProviderNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// AuthConfig selects how the bearer tokens of API requests are validated. Tokens of an external provider
+// are mapped to WeKnora users through one of their claims, and act as that user and its tenant.
+type AuthConfig struct {
+	// Provider is "jwt" (default), "introspection" or "jwks"
+	Provider string `yaml:"provider"      json:"provider"`
+	// CacheTTL is how long a token validated by an external provider is trusted before it is validated
+	// again (default: 1m, negative to validate every request)
+	CacheTTL time.Duration `yaml:"cache_ttl"     json:"cache_ttl"`
+	// UserClaim is the claim of an external token identifying the user (default: "email")
+	UserClaim string `yaml:"user_claim"    json:"user_claim"`
+	// UserField is the user field the claim is matched against: "email" (default), "username" or "id"
+	UserField     string                   `yaml:"user_field"    json:"user_field"`
+	Introspection *AuthIntrospectionConfig `yaml:"introspection" json:"introspection"`
+	JWKS          *AuthJWKSConfig          `yaml:"jwks"          json:"jwks"`
+	OIDC          *AuthOIDCConfig          `yaml:"oidc"          json:"oidc"`
+}
+
+// AuthIntrospectionConfig configures the OAuth2 token introspection endpoint
+type AuthIntrospectionConfig struct {
+	// URL of the introspection endpoint
+	URL string `yaml:"url"           json:"url"`
+	// ClientID and ClientSecret authenticate WeKnora to the endpoint with HTTP basic auth, when set
+	ClientID     string `yaml:"client_id"     json:"client_id"`
+	ClientSecret string `yaml:"client_secret" json:"client_secret"`
+	// Timeout bounds a call to the endpoint (default: 5s)
+	Timeout time.Duration `yaml:"timeout"       json:"timeout"`
+}
+
+// AuthJWKSConfig configures the validation of JWTs signed by an external issuer
+type AuthJWKSConfig struct {
+	// URL of the JSON Web Key Set of the issuer
+	URL string `yaml:"url"              json:"url"`
+	// Issuer and Audience, when set, must match the iss and aud claims of the tokens
+	Issuer   string `yaml:"issuer"           json:"issuer"`
+	Audience string `yaml:"audience"         json:"audience"`
+	// RefreshInterval is how often the keys are fetched again (default: 1h); unknown key IDs also
+	// trigger a fetch, at most once a minute
+	RefreshInterval time.Duration `yaml:"refresh_interval" json:"refresh_interval"`
+}
+
+// AuthOIDCConfig configures the login with OpenID Connect identity providers, alongside the login with a
+// password. The login issues the same tokens as the password login.
+type AuthOIDCConfig struct {
+	Providers []*AuthOIDCProviderConfig `yaml:"providers" json:"providers"`
+}
+
+// AuthOIDCProviderConfig configures an OpenID Connect identity provider
+type AuthOIDCProviderConfig struct {
+	// Name identifies the provider in the login routes, e.g. "google"
+	Name string `yaml:"name"            json:"name"`
+	// DisplayName is shown on the login page (default: the name)
+	DisplayName string `yaml:"display_name"    json:"display_name"`
+	// Type is "oidc" (default), "google" or "azure"
+	Type string `yaml:"type"            json:"type"`
+	// Issuer is the URL of the provider, which publishes its discovery document under it. Required by the
+	// oidc type; google and azure derive it
+	Issuer string `yaml:"issuer"          json:"issuer"`
+	// Tenant is the directory of the azure type, its ID or domain
+	Tenant       string `yaml:"tenant"          json:"tenant"`
+	ClientID     string `yaml:"client_id"       json:"client_id"`
+	ClientSecret string `yaml:"client_secret"   json:"client_secret"`
+	// RedirectURL is the frontend page the provider sends users back to; it must be registered with the provider
+	RedirectURL string `yaml:"redirect_url"    json:"redirect_url"`
+	// Scopes are requested in addition to openid (default: email and profile)
+	Scopes []string `yaml:"scopes"          json:"scopes"`
+	// AllowedDomains restricts the login to users with an email in these domains; empty allows any domain
+	AllowedDomains []string `yaml:"allowed_domains" json:"allowed_domains"`
+	// TrustEmail accepts the emails the provider does not mark as verified, which Azure AD never does,
+	// for new users only: identities are never linked to existing users without a verified email.
+	// Only set it for providers that control the emails of their users.
+	TrustEmail bool `yaml:"trust_email"     json:"trust_email"`
+}
+
+// IssuerURL returns the issuer of the provider, derived from its type when not set
+func (c *AuthOIDCProviderConfig) IssuerURL() string {
+	if c.Issuer != "" {
+		return strings.TrimSuffix(c.Issuer, "/")
+	}
+	switch c.Type {
+	case AuthOIDCTypeGoogle:
+		return "https://accounts.google.com"
+	case AuthOIDCTypeAzure:
+		return "https://login.microsoftonline.com/" + c.Tenant + "/v2.0"
+	}
+	return ""
+}
+
+// Validate checks that the providers are complete and their names unique
+func (c *AuthOIDCConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	names := make(map[string]bool, len(c.Providers))
+	for _, p := range c.Providers {
+		if p == nil {
+			continue
+		}
+		if !oidcProviderNamePattern.MatchString(p.Name) {
+			return fmt.Errorf("auth.oidc provider name %q must be lowercase letters, digits, _ and -", p.Name)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("auth.oidc provider %q is configured twice", p.Name)
+		}
+		names[p.Name] = true
+		switch p.Type {
+		case "", AuthOIDCTypeGeneric:
+			if p.Issuer == "" {
+				return fmt.Errorf("auth.oidc provider %q requires an issuer", p.Name)
+			}
+		case AuthOIDCTypeGoogle:
+		case AuthOIDCTypeAzure:
+			if p.Tenant == "" && p.Issuer == "" {
+				return fmt.Errorf("auth.oidc provider %q requires the tenant of its Azure AD directory", p.Name)
+			}
+		default:
+			return fmt.Errorf("auth.oidc provider %q has unknown type %q", p.Name, p.Type)
+		}
+		if p.ClientID == "" || p.RedirectURL == "" {
+			return fmt.Errorf("auth.oidc provider %q requires client_id and redirect_url", p.Name)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the selected provider is known and configured
+func (c *AuthConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.Provider {
+	case "", AuthProviderJWT:
+	case AuthProviderIntrospection:
+		if c.Introspection == nil || c.Introspection.URL == "" {
+			return fmt.Errorf("auth.introspection.url is required by the introspection provider")
+		}
+	case AuthProviderJWKS:
+		if c.JWKS == nil || c.JWKS.URL == "" {
+			return fmt.Errorf("auth.jwks.url is required by the jwks provider")
+		}
+	default:
+		return fmt.Errorf("unknown auth.provider %q", c.Provider)
+	}
+	switch c.UserField {
+	case "", "email", "username", "id":
+	default:
+		return fmt.Errorf("auth.user_field must be email, username or id")
+	}
+	if c.OIDC != nil && len(c.OIDC.Providers) > 0 && c.Provider != "" && c.Provider != AuthProviderJWT {
+		return fmt.Errorf("auth.oidc requires the jwt provider, which validates the tokens issued at login")
+	}
+	return c.OIDC.Validate()
+}
+
+// SingleTenantMode returns the single-tenant configuration when the mode is enabled, nil otherwise
+func (c *Config) SingleTenantMode() *SingleTenantConfig {
+	if c == nil || c.Tenant == nil || c.Tenant.SingleTenant == nil || !c.Tenant.SingleTenant.Enabled {
+		return nil
+	}
+	return c.Tenant.SingleTenant
+}
+
+// ProviderLogConfig controls capture of raw model provider request/response bodies for debugging.
+// SENSITIVE: captured payloads contain user content; keep disabled unless actively debugging a provider.
+type ProviderLogConfig struct {
+	// Enabled turns provider call logging on (default: false)
+	Enabled bool `yaml:"enabled"        json:"enabled"`
+	// TenantIDs limits logging to these tenants; empty means all tenants
+	TenantIDs []uint64 `yaml:"tenant_ids"     json:"tenant_ids"`
+	// RedactContent replaces prompt/completion text with its length
+	RedactContent bool `yaml:"redact_content" json:"redact_content"`
+	// TTL is how long captured calls are kept (default: 1h)
+	TTL time.Duration `yaml:"ttl"            json:"ttl"`
+	// MaxBodyBytes truncates captured bodies (default: 64KB)
+	MaxBodyBytes int `yaml:"max_body_bytes" json:"max_body_bytes"`
+}
+
+// SlowRequestLogConfig controls the detailed log emitted for requests exceeding a latency threshold.
+// The query text is user content; it is only logged when IncludeQuery is set.
+type SlowRequestLogConfig struct {
+	// Enabled turns slow request logging on (default: false)
+	Enabled bool `yaml:"enabled"       json:"enabled"`
+	// Threshold is the latency from which a request is logged as slow (default: 5s)
+	Threshold time.Duration `yaml:"threshold"     json:"threshold"`
+	// IncludeQuery logs the query text instead of only its length
+	IncludeQuery bool `yaml:"include_query" json:"include_query"`
+}
+
+// AnalyticsConfig configures the structured events streamed to an analytics sink.
+// The query text is user content; it is only sent when QueryMode is redacted or full.
+type AnalyticsConfig struct {
+	// Enabled turns the analytics events on (default: false)
+	Enabled bool `yaml:"enabled"        json:"enabled"`
+	// Sink receives the events: "stdout" (default), "webhook" or "kafka"
+	Sink string `yaml:"sink"           json:"sink"`
+	// Events lists the events emitted: query_received, retrieval_done, answer_generated, feedback_given
+	// (default: all)
+	Events []string `yaml:"events"         json:"events"`
+	// QueryMode is "none" to send only the query length (default), "redacted" or "full"
+	QueryMode string `yaml:"query_mode"     json:"query_mode"`
+	// BufferSize is the number of events queued before new ones are dropped (default: 10000)
+	BufferSize int `yaml:"buffer_size"    json:"buffer_size"`
+	// BatchSize is the largest number of events published at once (default: 100)
+	BatchSize int `yaml:"batch_size"     json:"batch_size"`
+	// FlushInterval is the longest time an event is held before it is published (default: 2s)
+	FlushInterval time.Duration `yaml:"flush_interval" json:"flush_interval"`
+	// Webhook posts the events as signed JSON arrays
+	Webhook *AnalyticsWebhookConfig `yaml:"webhook"        json:"webhook"`
+	// Kafka produces the events through a Kafka REST Proxy
+	Kafka *AnalyticsKafkaConfig `yaml:"kafka"          json:"kafka"`
+}
+
+// AnalyticsWebhookConfig configures the webhook analytics sink
+type AnalyticsWebhookConfig struct {
+	URL string `yaml:"url"     json:"url"`
+	// Secret signs the batches; receivers verify X-WeKnora-Signature with it
+	Secret string `yaml:"secret"  json:"-"`
+	// Timeout bounds each delivery attempt (default: 5s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+}
+
+// AnalyticsKafkaConfig configures the Kafka analytics sink
+type AnalyticsKafkaConfig struct {
+	// RESTProxyURL is the base URL of the Kafka REST Proxy, e.g. http://kafka-rest:8082
+	RESTProxyURL string `yaml:"rest_proxy_url" json:"rest_proxy_url"`
+	Topic        string `yaml:"topic"          json:"topic"`
+	// Timeout bounds each produce request (default: 5s)
+	Timeout time.Duration `yaml:"timeout"        json:"timeout"`
+}
+
+// RateLimitConfig controls the per-tenant request rate limit of the API
+type RateLimitConfig struct {
+	// Enabled turns rate limiting on (default: false)
+	Enabled bool `yaml:"enabled"             json:"enabled"`
+	// RequestsPerSecond is the sustained request rate of a tenant (default: 20)
+	RequestsPerSecond float64 `yaml:"requests_per_second" json:"requests_per_second"`
+	// Burst is the number of requests a tenant may send at once (default: 40)
+	Burst int `yaml:"burst"               json:"burst"`
+	// Store keeps the token buckets: "memory" for a single node (default) or "redis" to share them across nodes
+	Store string `yaml:"store"               json:"store"`
+}
+
+// PayloadLimitConfig sets the default payload size limits of a tenant, which tenants can be given
+// other limits through their payload limit override. 0 means no limit.
+type PayloadLimitConfig struct {
+	// MaxRequestBytes is the largest request body a tenant may send
+	MaxRequestBytes int64 `yaml:"max_request_bytes"  json:"max_request_bytes"`
+	// MaxResponseBytes is the largest response body a tenant may receive
+	MaxResponseBytes int64 `yaml:"max_response_bytes" json:"max_response_bytes"`
+}
+
+// BodyLimitConfig caps the request bodies of every route, whatever the tenant. A negative limit
+// disables it.
+type BodyLimitConfig struct {
+	// MaxBytes is the largest request body of a route without its own limit (default: 10MiB)
+	MaxBytes int64 `yaml:"max_bytes"        json:"max_bytes"`
+	// UploadMaxBytes is the largest request body of the file upload routes (default: MAX_FILE_SIZE_MB plus 1MiB)
+	UploadMaxBytes int64 `yaml:"upload_max_bytes" json:"upload_max_bytes"`
+	// Routes sets the limit of single routes, keyed on method and route, e.g. "POST /api/v1/knowledge-bases/:id/faq/entries"
+	Routes map[string]int64 `yaml:"routes"           json:"routes"`
+}
+
+// IdempotencyConfig controls how the responses of requests sent with an Idempotency-Key header are kept
+type IdempotencyConfig struct {
+	// TTL is how long a key is remembered after its first request (default: 24h)
+	TTL time.Duration `yaml:"ttl"   json:"ttl"`
+	// Store keeps the responses: "memory" for a single node (default) or "redis" to share them across nodes
+	Store string `yaml:"store" json:"store"`
+}
+
+// AuditConfig controls the audit log of the mutating requests of the API
+type AuditConfig struct {
+	// Enabled turns the audit log on (default: false)
+	Enabled bool `yaml:"enabled"        json:"enabled"`
+	// BufferSize is the number of entries queued before new ones are dropped (default: 10000)
+	BufferSize int `yaml:"buffer_size"    json:"buffer_size"`
+	// BatchSize is the largest number of entries written at once (default: 100)
+	BatchSize int `yaml:"batch_size"     json:"batch_size"`
+	// FlushInterval is the longest time an entry is held before it is written (default: 2s)
+	FlushInterval time.Duration `yaml:"flush_interval" json:"flush_interval"`
+}
+
+// ModelQueueConfig controls the shared priority queue in front of chat and embedding model calls
+type ModelQueueConfig struct {
+	// MaxConcurrency is the number of model calls allowed in flight at once; 0 disables queueing
+	MaxConcurrency int `yaml:"max_concurrency" json:"max_concurrency"`
+	// Weights is the relative share of slots per priority ("interactive", "bulk") when both are waiting
+	Weights map[string]int `yaml:"weights"         json:"weights"`
+}
+
+// EvaluationConfig limits how hard evaluation runs call the model backend. The values are both the
+// defaults and the maximums of the limits set on an evaluation request; 0 means no limit.
+type EvaluationConfig struct {
+	// MaxConcurrency is the number of QA pairs evaluated in parallel (0 = number of CPUs - 1)
+	MaxConcurrency int `yaml:"max_concurrency"     json:"max_concurrency"`
+	// RequestsPerMinute is the number of QA pairs started per minute
+	RequestsPerMinute int `yaml:"requests_per_minute" json:"requests_per_minute"`
+}
+
+// OpenAICompatConfig configures the OpenAI-compatible API independently of the main API, so that tools
+// built for OpenAI can reach WeKnora without loosening the main API's policies
+type OpenAICompatConfig struct {
+	// Enabled registers the OpenAI-compatible routes under /openai/v1
+	Enabled bool `yaml:"enabled"         json:"enabled"`
+	// BearerAPIKeys accepts tenant API keys as "Authorization: Bearer sk-..." tokens, the way OpenAI clients send them
+	BearerAPIKeys bool `yaml:"bearer_api_keys" json:"bearer_api_keys"`
+	// CORS is the cross-origin policy of the OpenAI-compatible routes
+	CORS *CORSConfig `yaml:"cors"            json:"cors"`
+}
+
+// AsyncChatConfig configures knowledge chat requests answered in the background (async=true)
+type AsyncChatConfig struct {
+	// Timeout bounds the generation of a background answer (default 10m)
+	Timeout time.Duration `yaml:"timeout"           json:"timeout"`
+	// CallbackSecret signs the completion callbacks; requests with a callback URL are refused without it
+	CallbackSecret string `yaml:"callback_secret"   json:"callback_secret"`
+	// CallbackTimeout bounds each callback delivery attempt (default 10s)
+	CallbackTimeout time.Duration `yaml:"callback_timeout"  json:"callback_timeout"`
+	// CallbackAttempts is the number of deliveries tried before the callback is dropped (default 3)
+	CallbackAttempts int `yaml:"callback_attempts" json:"callback_attempts"`
+}
+
+// TaskWebhookConfig configures the webhooks notified when asynchronous tasks finish
+type TaskWebhookConfig struct {
+	// Timeout bounds each delivery attempt (default 10s)
+	Timeout time.Duration `yaml:"timeout"  json:"timeout"`
+	// Attempts is the number of deliveries tried before the webhook is given up (default 5)
+	Attempts int `yaml:"attempts" json:"attempts"`
+}
+
+// HealthConfig configures the readiness probe
+type HealthConfig struct {
+	// CheckTimeout bounds each dependency check of the readiness probe (default: 2s)
+	CheckTimeout time.Duration `yaml:"check_timeout" json:"check_timeout"`
+}
+
+// MCPConfig configures the MCP service integration
+type MCPConfig struct {
+	// HealthCheckInterval is how often the tools advertised by the enabled MCP services are validated (0 = never)
+	HealthCheckInterval time.Duration `yaml:"health_check_interval" json:"health_check_interval"`
+}
+
+// CORSConfig is a cross-origin resource sharing policy
+type CORSConfig struct {
+	// AllowOrigins lists the allowed origins, "*" allows any and "https://*.example.com" matches subdomains
+	AllowOrigins []string `yaml:"allow_origins"     json:"allow_origins"`
+	// AllowMethods lists the allowed methods, replacing the defaults when set
+	AllowMethods []string `yaml:"allow_methods"     json:"allow_methods"`
+	// AllowHeaders lists request headers allowed in addition to the standard ones
+	AllowHeaders []string `yaml:"allow_headers"     json:"allow_headers"`
+	// AllowCredentials lets browsers send cookies and authorization headers; not allowed with origin "*"
+	AllowCredentials bool `yaml:"allow_credentials" json:"allow_credentials"`
 }
 
 // PromptTemplate 提示词模板
@@ -214,7 +714,17 @@ func LoadConfig() (*Config, error) {
 	}); err != nil {
 		return nil, fmt.Errorf("unable to decode config into struct: %w", err)
 	}
+	if cfg.Tenant != nil {
+		if err := cfg.Tenant.SingleTenant.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.Auth.Validate(); err != nil {
+		return nil, err
+	}
 	fmt.Printf("Using configuration file: %s\n", viper.ConfigFileUsed())
+	cfg.configFile = viper.ConfigFileUsed()
+	cfg.rawContent = configFileContent
 
 	// 加载提示词模板（从目录或配置文件）
 	configDir := filepath.Dir(viper.ConfigFileUsed())
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSingleTenantMode(t *testing.T) {
+	longKey := strings.Repeat("k", MinSharedAPIKeyLength)
+
+	tests := []struct {
+		name         string
+		cfg          *Config
+		wantEnabled  bool
+		wantTenantID uint64
+		wantErr      bool
+	}{
+		{name: "no config", cfg: &Config{}},
+		{name: "disabled", cfg: &Config{Tenant: &TenantConfig{SingleTenant: &SingleTenantConfig{TenantID: 3}}}},
+		{
+			name:         "default tenant",
+			cfg:          &Config{Tenant: &TenantConfig{SingleTenant: &SingleTenantConfig{Enabled: true}}},
+			wantEnabled:  true,
+			wantTenantID: DefaultSingleTenantID,
+		},
+		{
+			name: "configured tenant and shared key",
+			cfg: &Config{Tenant: &TenantConfig{SingleTenant: &SingleTenantConfig{
+				Enabled: true, TenantID: 3, SharedAPIKey: longKey,
+			}}},
+			wantEnabled:  true,
+			wantTenantID: 3,
+		},
+		{
+			name: "short shared key",
+			cfg: &Config{Tenant: &TenantConfig{SingleTenant: &SingleTenantConfig{
+				Enabled: true, SharedAPIKey: longKey[1:],
+			}}},
+			wantEnabled:  true,
+			wantTenantID: DefaultSingleTenantID,
+			wantErr:      true,
+		},
+		{
+			name: "unresolved environment variable",
+			cfg: &Config{Tenant: &TenantConfig{SingleTenant: &SingleTenantConfig{
+				Enabled: true, SharedAPIKey: "${SINGLE_TENANT_SHARED_API_KEY_WITH_A_LONG_NAME}",
+			}}},
+			wantEnabled:  true,
+			wantTenantID: DefaultSingleTenantID,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode := tt.cfg.SingleTenantMode()
+			if (mode != nil) != tt.wantEnabled {
+				t.Fatalf("SingleTenantMode() = %+v, want enabled %v", mode, tt.wantEnabled)
+			}
+			if mode == nil {
+				return
+			}
+			if got := mode.DefaultTenantID(); got != tt.wantTenantID {
+				t.Errorf("DefaultTenantID() = %d, want %d", got, tt.wantTenantID)
+			}
+			if err := mode.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthOIDCValidate(t *testing.T) {
+	google := func() *AuthOIDCProviderConfig {
+		return &AuthOIDCProviderConfig{Name: "google", Type: AuthOIDCTypeGoogle, ClientID: "id",
+			RedirectURL: "https://weknora.example.com/login/oidc/google"}
+	}
+
+	tests := []struct {
+		name    string
+		cfg     *AuthConfig
+		wantErr bool
+	}{
+		{name: "no providers", cfg: &AuthConfig{OIDC: &AuthOIDCConfig{}}},
+		{name: "google", cfg: &AuthConfig{OIDC: &AuthOIDCConfig{Providers: []*AuthOIDCProviderConfig{google()}}}},
+		{
+			name: "external token provider",
+			cfg: &AuthConfig{Provider: AuthProviderJWKS, JWKS: &AuthJWKSConfig{URL: "https://idp.example.com/jwks"},
+				OIDC: &AuthOIDCConfig{Providers: []*AuthOIDCProviderConfig{google()}}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate name",
+			cfg:     &AuthConfig{OIDC: &AuthOIDCConfig{Providers: []*AuthOIDCProviderConfig{google(), google()}}},
+			wantErr: true,
+		},
+		{
+			name: "generic without issuer",
+			cfg: &AuthConfig{OIDC: &AuthOIDCConfig{Providers: []*AuthOIDCProviderConfig{{Name: "sso", ClientID: "id",
+				RedirectURL: "https://weknora.example.com/login/oidc/sso"}}}},
+			wantErr: true,
+		},
+		{
+			name: "azure without tenant",
+			cfg: &AuthConfig{OIDC: &AuthOIDCConfig{Providers: []*AuthOIDCProviderConfig{{Name: "azure",
+				Type: AuthOIDCTypeAzure, ClientID: "id", RedirectURL: "https://weknora.example.com/login/oidc/azure"}}}},
+			wantErr: true,
+		},
+		{
+			name: "invalid name",
+			cfg: &AuthConfig{OIDC: &AuthOIDCConfig{Providers: []*AuthOIDCProviderConfig{{Name: "My IdP",
+				Type: AuthOIDCTypeGoogle, ClientID: "id", RedirectURL: "https://weknora.example.com/login"}}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	azure := &AuthOIDCProviderConfig{Type: AuthOIDCTypeAzure, Tenant: "contoso.onmicrosoft.com"}
+	if got := azure.IssuerURL(); got != "https://login.microsoftonline.com/contoso.onmicrosoft.com/v2.0" {
+		t.Errorf("azure IssuerURL() = %s", got)
+	}
+}
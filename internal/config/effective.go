@@ -0,0 +1,238 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of effective configuration values
+const (
+	// ConfigSourceFile marks values set in the configuration file
+	ConfigSourceFile = "file"
+	// ConfigSourceEnv marks values taken from environment variables
+	ConfigSourceEnv = "env"
+	// ConfigSourceDefault marks values not set anywhere, for which the built-in default applies
+	ConfigSourceDefault = "default"
+)
+
+// redactedValue replaces secret values in the effective configuration
+const redactedValue = "******"
+
+// maxEffectiveValueLength truncates long values such as prompts in the effective configuration
+const maxEffectiveValueLength = 256
+
+// secretKeyMarkers identify configuration keys and environment variables holding credentials
+var secretKeyMarkers = []string{
+	"password", "secret", "api_key", "apikey", "token", "access_key", "private_key", "aes_key", "credential",
+}
+
+// envReferencePattern matches ${ENV_VAR} references in the configuration file
+var envReferencePattern = regexp.MustCompile(`\${([^}]+)}`)
+
+// runtimeEnvVars are environment variables read directly by the process rather than through the configuration file
+var runtimeEnvVars = []string{
+	"GIN_MODE", "AUTO_MIGRATE", "AUTO_RECOVER_DIRTY", "DISABLE_REGISTRATION", "JWT_SECRET", "TENANT_AES_KEY",
+	"DB_DRIVER", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME",
+	"DB_REPLICA_HOSTS", "DB_REPLICA_MAX_LAG_SECONDS", "DB_REPLICA_CHECK_INTERVAL_SECONDS",
+	"REDIS_ADDR", "REDIS_PASSWORD", "REDIS_DB", "REDIS_PREFIX", "STREAM_MANAGER_TYPE",
+	"RETRIEVE_DRIVER", "ELASTICSEARCH_ADDR", "ELASTICSEARCH_USERNAME", "ELASTICSEARCH_PASSWORD", "ELASTICSEARCH_INDEX",
+	"QDRANT_HOST", "QDRANT_PORT", "QDRANT_API_KEY", "QDRANT_USE_TLS",
+	"NEO4J_ENABLE", "NEO4J_URI", "NEO4J_USERNAME", "NEO4J_PASSWORD",
+	"STORAGE_TYPE", "LOCAL_STORAGE_BASE_DIR", "MAX_FILE_SIZE_MB",
+	"MINIO_ENDPOINT", "MINIO_ACCESS_KEY_ID", "MINIO_SECRET_ACCESS_KEY", "MINIO_BUCKET_NAME", "MINIO_USE_SSL",
+	"COS_SECRET_ID", "COS_SECRET_KEY", "COS_REGION", "COS_BUCKET_NAME", "COS_PATH_PREFIX",
+	"COS_TEMP_REGION", "COS_TEMP_BUCKET_NAME",
+	"DOCREADER_ADDR", "OLLAMA_BASE_URL", "OLLAMA_OPTIONAL", "CONCURRENCY_POOL_SIZE", "BATCH_EMBED_SIZE",
+	"OTEL_EXPORTER_OTLP_ENDPOINT",
+}
+
+// EffectiveValue is a configuration value the process is running with
+type EffectiveValue struct {
+	// Key is the dotted configuration key, or the environment variable name
+	Key string `json:"key"`
+	// Value is the effective value; secrets are redacted and long strings truncated
+	Value any `json:"value"`
+	// Source is where the value came from: file, env or default
+	Source string `json:"source"`
+	// EnvVars names the environment variables the value was taken from
+	EnvVars []string `json:"env_vars,omitempty"`
+	// Redacted reports that the value is a credential and was hidden
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// EffectiveConfig is the non-secret configuration the process is running with
+type EffectiveConfig struct {
+	// ConfigFile is the path of the loaded configuration file
+	ConfigFile string `json:"config_file"`
+	// Values are the configuration file settings after environment substitution, by dotted key
+	Values []EffectiveValue `json:"values"`
+	// Environment are the environment variables read directly by the process
+	Environment []EffectiveValue `json:"environment"`
+}
+
+// Effective returns the effective configuration with the source of every value and credentials redacted.
+// Values whose source is default were not set; the zero value shown means the built-in default applies.
+func (c *Config) Effective() (*EffectiveConfig, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("marshal effective config: %w", err)
+	}
+	var effective any
+	if err := yaml.Unmarshal(data, &effective); err != nil {
+		return nil, fmt.Errorf("decode effective config: %w", err)
+	}
+	var raw any
+	if len(c.rawContent) > 0 {
+		if err := yaml.Unmarshal(c.rawContent, &raw); err != nil {
+			return nil, fmt.Errorf("decode config file: %w", err)
+		}
+	}
+
+	result := &EffectiveConfig{ConfigFile: c.configFile}
+	walkConfig(effective, nil, func(path []string, value any) {
+		result.Values = append(result.Values, effectiveValue(path, value, raw))
+	})
+	sort.Slice(result.Values, func(i, j int) bool { return result.Values[i].Key < result.Values[j].Key })
+
+	for _, name := range runtimeEnvVars {
+		v := EffectiveValue{Key: name, Source: ConfigSourceDefault}
+		if value, ok := os.LookupEnv(name); ok {
+			v.Source = ConfigSourceEnv
+			v.Value, v.Redacted = redactConfigValue(name, value)
+		}
+		result.Environment = append(result.Environment, v)
+	}
+	return result, nil
+}
+
+// walkConfig calls fn for every leaf of a decoded YAML tree; empty maps and lists are leaves
+func walkConfig(node any, path []string, fn func(path []string, value any)) {
+	switch n := node.(type) {
+	case map[string]any:
+		if len(n) == 0 {
+			fn(path, n)
+		}
+		for k, v := range n {
+			walkConfig(v, append(slices.Clone(path), k), fn)
+		}
+	case []any:
+		if len(n) == 0 {
+			fn(path, n)
+		}
+		for i, v := range n {
+			walkConfig(v, append(slices.Clone(path), strconv.Itoa(i)), fn)
+		}
+	default:
+		if len(path) > 0 {
+			fn(path, n)
+		}
+	}
+}
+
+// effectiveValue determines the source of a configuration value by comparing it with the raw configuration file
+func effectiveValue(path []string, value any, raw any) EffectiveValue {
+	key := strings.Join(path, ".")
+	v := EffectiveValue{Key: key, Source: ConfigSourceDefault}
+	v.Value, v.Redacted = redactConfigValue(path[len(path)-1], value)
+
+	rawValue, inFile := lookupConfigPath(raw, path)
+	if !inFile {
+		return v
+	}
+	v.Source = ConfigSourceFile
+
+	// ${ENV_VAR} references in the file are replaced when they are set
+	if s, ok := rawValue.(string); ok {
+		for _, m := range envReferencePattern.FindAllStringSubmatch(s, -1) {
+			if os.Getenv(m[1]) != "" {
+				v.Source = ConfigSourceEnv
+				v.EnvVars = append(v.EnvVars, m[1])
+			}
+		}
+	}
+	// Keys in the file are overridden by environment variables named after the key, e.g. CONVERSATION_MAX_ROUNDS
+	if !hasIndexSegment(path) {
+		envName := strings.ToUpper(strings.Join(path, "_"))
+		if _, ok := os.LookupEnv(envName); ok {
+			v.Source = ConfigSourceEnv
+			v.EnvVars = append(v.EnvVars, envName)
+		}
+	}
+	return v
+}
+
+// lookupConfigPath finds the value at path in a decoded YAML tree
+func lookupConfigPath(node any, path []string) (any, bool) {
+	for _, segment := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			v, ok := n[segment]
+			if !ok {
+				return nil, false
+			}
+			node = v
+		case []any:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, false
+			}
+			node = n[i]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// hasIndexSegment reports whether a path goes through a list element, which environment variables can't override
+func hasIndexSegment(path []string) bool {
+	for _, segment := range path {
+		if _, err := strconv.Atoi(segment); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// redactConfigValue hides credentials, strips passwords from URLs and truncates long strings
+func redactConfigValue(key string, value any) (any, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return value, false
+	}
+	if s != "" && isSecretKey(key) {
+		return redactedValue, true
+	}
+	if strings.Contains(s, "://") {
+		if u, err := url.Parse(s); err == nil && u.User != nil {
+			if _, hasPassword := u.User.Password(); hasPassword {
+				// The escaped username contains no "@", so the first one ends the userinfo
+				u.User = url.User(u.User.Username())
+				return strings.Replace(u.String(), "@", ":"+redactedValue+"@", 1), true
+			}
+		}
+	}
+	if len(s) > maxEffectiveValueLength {
+		return fmt.Sprintf("%s... (%d bytes)", strings.ToValidUTF8(s[:maxEffectiveValueLength], ""), len(s)), false
+	}
+	return s, false
+}
+
+// isSecretKey reports whether a configuration key or environment variable holds a credential
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, marker := range secretKeyMarkers {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -26,6 +26,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/Tencent/WeKnora/docreader/client"
+	"github.com/Tencent/WeKnora/internal/analytics"
 	"github.com/Tencent/WeKnora/internal/application/repository"
 	elasticsearchRepoV7 "github.com/Tencent/WeKnora/internal/application/repository/retriever/elasticsearch/v7"
 	elasticsearchRepoV8 "github.com/Tencent/WeKnora/internal/application/repository/retriever/elasticsearch/v8"
@@ -33,21 +34,29 @@ import (
 	postgresRepo "github.com/Tencent/WeKnora/internal/application/repository/retriever/postgres"
 	qdrantRepo "github.com/Tencent/WeKnora/internal/application/repository/retriever/qdrant"
 	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/application/service/auth"
 	chatpipline "github.com/Tencent/WeKnora/internal/application/service/chat_pipline"
 	"github.com/Tencent/WeKnora/internal/application/service/file"
 	"github.com/Tencent/WeKnora/internal/application/service/llmcontext"
 	"github.com/Tencent/WeKnora/internal/application/service/retriever"
 	"github.com/Tencent/WeKnora/internal/application/service/web_search"
+	"github.com/Tencent/WeKnora/internal/clock"
 	"github.com/Tencent/WeKnora/internal/config"
 	"github.com/Tencent/WeKnora/internal/database"
 	"github.com/Tencent/WeKnora/internal/event"
 	"github.com/Tencent/WeKnora/internal/handler"
 	"github.com/Tencent/WeKnora/internal/handler/session"
+	"github.com/Tencent/WeKnora/internal/health"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/mcp"
+	"github.com/Tencent/WeKnora/internal/metrics"
+	"github.com/Tencent/WeKnora/internal/middleware"
+	"github.com/Tencent/WeKnora/internal/models/calllog"
 	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/models/scheduler"
 	"github.com/Tencent/WeKnora/internal/models/utils/ollama"
 	"github.com/Tencent/WeKnora/internal/router"
+	"github.com/Tencent/WeKnora/internal/runtime"
 	"github.com/Tencent/WeKnora/internal/stream"
 	"github.com/Tencent/WeKnora/internal/tracing"
 	"github.com/Tencent/WeKnora/internal/types"
@@ -72,15 +81,28 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	// Core infrastructure configuration
 	logger.Debugf(ctx, "[Container] Registering core infrastructure...")
 	must(container.Provide(config.LoadConfig))
+	// Real system clock; tests can replace it with clock.Fake via container.Decorate
+	must(container.Provide(clock.New))
 	must(container.Provide(initTracer))
 	must(container.Provide(initDatabase))
+	must(container.Provide(initReplicaPool))
 	must(container.Provide(initFileService))
 	must(container.Provide(initRedisClient))
 	must(container.Provide(initAntsPool))
 	must(container.Provide(initContextStorage))
+	must(container.Provide(initProviderCallLog))
+	must(container.Provide(initRateLimitStore))
+	must(container.Provide(initIdempotencyStore))
+	must(container.Provide(initAnalyticsEmitter))
+	// Prometheus registry; tests can replace it via container.Decorate to assert on the metrics
+	must(container.Provide(metrics.NewRegistry))
+	must(container.Provide(metrics.New))
+	must(container.Invoke(initModelScheduler))
 
 	// Register goroutine pool cleanup handler
 	must(container.Invoke(registerPoolCleanup))
+	// Register read replica cleanup handler, which also installs replica routing on startup
+	must(container.Invoke(registerReplicaCleanup))
 
 	// Initialize retrieval engine registry for search capabilities
 	logger.Debugf(ctx, "[Container] Registering retrieval engine registry...")
@@ -108,10 +130,18 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	must(container.Provide(repository.NewModelRepository))
 	must(container.Provide(repository.NewUserRepository))
 	must(container.Provide(repository.NewAuthTokenRepository))
+	must(container.Provide(repository.NewUserIdentityRepository))
 	must(container.Provide(neo4jRepo.NewNeo4jRepository))
 	must(container.Provide(repository.NewMCPServiceRepository))
 	must(container.Provide(repository.NewCustomAgentRepository))
+	must(container.Provide(repository.NewAgentRunRepository))
+	must(container.Provide(repository.NewAuditLogRepository))
+	must(container.Provide(repository.NewRoleRepository))
+	must(container.Provide(repository.NewAPIKeyRepository))
+	must(container.Provide(initAuditRecorder))
 	must(container.Provide(service.NewWebSearchStateService))
+	must(container.Provide(service.NewAnswerCacheService))
+	must(container.Provide(service.NewTaskWebhookService))
 
 	// MCP manager for managing MCP client connections
 	logger.Debugf(ctx, "[Container] Registering MCP manager...")
@@ -120,6 +150,7 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	// Business service layer
 	logger.Debugf(ctx, "[Container] Registering business services...")
 	must(container.Provide(service.NewTenantService))
+	must(container.Provide(service.NewTenantDataService))
 	must(container.Provide(service.NewKnowledgeBaseService))
 	must(container.Provide(service.NewKnowledgeService))
 	must(container.Provide(service.NewChunkService))
@@ -129,6 +160,8 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	must(container.Provide(service.NewDatasetService))
 	must(container.Provide(service.NewEvaluationService))
 	must(container.Provide(service.NewUserService))
+	must(container.Provide(auth.NewAuthenticator))
+	must(container.Provide(service.NewOIDCService))
 
 	// Extract services - register individual extracters with names
 	must(container.Provide(service.NewChunkExtractService, dig.Name("chunkExtracter")))
@@ -136,6 +169,7 @@ func BuildContainer(container *dig.Container) *dig.Container {
 
 	must(container.Provide(service.NewMessageService))
 	must(container.Provide(service.NewMCPServiceService))
+	must(container.Invoke(service.StartMCPHealthChecks))
 	must(container.Provide(service.NewCustomAgentService))
 
 	// Web search service (needed by AgentService)
@@ -154,10 +188,16 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	// SessionService is created after AgentService and passes itself to AgentService.CreateAgentEngine when needed
 	logger.Debugf(ctx, "[Container] Registering session service...")
 	must(container.Provide(service.NewSessionService))
+	must(container.Provide(service.NewAgentRunService))
+	must(container.Provide(service.NewAuditLogService))
+	must(container.Provide(service.NewRoleService))
+	must(container.Provide(service.NewAPIKeyService))
 
 	logger.Debugf(ctx, "[Container] Registering asynq client and server...")
 	must(container.Provide(router.NewAsyncqClient))
 	must(container.Provide(router.NewAsynqServer))
+	must(container.Provide(router.NewAsynqInspector))
+	must(container.Invoke(metrics.RegisterTaskQueueCollector))
 
 	// Chat pipeline components for processing chat requests
 	logger.Debugf(ctx, "[Container] Registering chat pipeline plugins...")
@@ -177,8 +217,18 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	must(container.Invoke(chatpipline.NewPluginExtractEntity))
 	must(container.Invoke(chatpipline.NewPluginSearchEntity))
 	must(container.Invoke(chatpipline.NewPluginSearchParallel))
+	must(container.Invoke(chatpipline.NewPluginConfidenceGate))
+	must(container.Invoke(chatpipline.NewPluginGrounding))
+	must(container.Invoke(chatpipline.NewPluginCitation))
+	must(container.Invoke(chatpipline.NewPluginAttachment))
 	logger.Debugf(ctx, "[Container] Chat pipeline plugins registered")
 
+	// Readiness probe checkers; subsystems opt in by providing a checker in the group
+	logger.Debugf(ctx, "[Container] Registering health checkers...")
+	must(container.Provide(health.NewDatabaseChecker, dig.Group(health.CheckerGroup)))
+	must(container.Provide(health.NewStorageCheckers))
+	must(container.Provide(health.NewModelProviderChecker, dig.Group(health.CheckerGroup)))
+
 	// HTTP handlers layer
 	logger.Debugf(ctx, "[Container] Registering HTTP handlers...")
 	must(container.Provide(handler.NewTenantHandler))
@@ -197,6 +247,13 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	must(container.Provide(handler.NewMCPServiceHandler))
 	must(container.Provide(handler.NewWebSearchHandler))
 	must(container.Provide(handler.NewCustomAgentHandler))
+	must(container.Provide(handler.NewAgentRunHandler))
+	must(container.Provide(handler.NewProviderLogHandler))
+	must(container.Provide(handler.NewAuditHandler))
+	must(container.Provide(handler.NewRoleHandler))
+	must(container.Provide(handler.NewAPIKeyHandler))
+	must(container.Provide(handler.NewOpenAICompatHandler))
+	must(container.Provide(handler.NewHealthHandler))
 	logger.Debugf(ctx, "[Container] HTTP handlers registered")
 
 	// Router configuration
@@ -248,6 +305,9 @@ func initRedisClient() (*redis.Client, error) {
 		return nil, fmt.Errorf("连接Redis失败: %w", err)
 	}
 
+	runtime.OnShutdown("Redis", func(context.Context) error {
+		return client.Close()
+	})
 	return client, nil
 }
 
@@ -259,6 +319,122 @@ func initContextStorage(redisClient *redis.Client) (llmcontext.ContextStorage, e
 	return storage, nil
 }
 
+// initProviderCallLog creates the provider call log store and installs it as the
+// recorder for model HTTP clients when logging is enabled in the configuration
+func initProviderCallLog(cfg *config.Config, redisClient *redis.Client) *calllog.RedisStore {
+	opts := calllog.Options{}
+	if pl := cfg.ProviderLog; pl != nil {
+		opts = calllog.Options{
+			Enabled:       pl.Enabled,
+			TenantIDs:     pl.TenantIDs,
+			RedactContent: pl.RedactContent,
+			TTL:           pl.TTL,
+			MaxBodyBytes:  pl.MaxBodyBytes,
+		}
+	}
+	store := calllog.NewRedisStore(redisClient, opts)
+	if store.IsEnabled() {
+		logger.Warnf(context.Background(),
+			"[ProviderLog] Provider call logging is ENABLED; model request and response bodies are being stored")
+		calllog.SetRecorder(store)
+	}
+	return store
+}
+
+// initAnalyticsEmitter creates the emitter of the analytics events; it is nil when analytics are disabled.
+// The queued events are published on shutdown.
+func initAnalyticsEmitter(cfg *config.Config) (*analytics.Emitter, error) {
+	ac := cfg.Analytics
+	if ac == nil || !ac.Enabled {
+		return nil, nil
+	}
+	opts := analytics.Options{
+		Enabled:       true,
+		Sink:          ac.Sink,
+		Events:        ac.Events,
+		QueryMode:     ac.QueryMode,
+		BufferSize:    ac.BufferSize,
+		BatchSize:     ac.BatchSize,
+		FlushInterval: ac.FlushInterval,
+	}
+	if opts.Sink == "" {
+		opts.Sink = analytics.SinkStdout
+	}
+	if ac.Webhook != nil {
+		opts.WebhookURL = ac.Webhook.URL
+		opts.WebhookSecret = ac.Webhook.Secret
+		if opts.Sink == analytics.SinkWebhook {
+			opts.Timeout = ac.Webhook.Timeout
+		}
+	}
+	if ac.Kafka != nil {
+		opts.KafkaRESTProxyURL = ac.Kafka.RESTProxyURL
+		opts.KafkaTopic = ac.Kafka.Topic
+		if opts.Sink == analytics.SinkKafka {
+			opts.Timeout = ac.Kafka.Timeout
+		}
+	}
+	emitter, err := analytics.New(opts)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof(context.Background(), "[Analytics] Streaming events to the %s sink (query mode: %s)",
+		opts.Sink, emitter.QueryMode())
+	runtime.OnShutdown("Analytics", emitter.Close)
+	return emitter, nil
+}
+
+// initRateLimitStore creates the token bucket store of the rate limiter. Redis is used when
+// configured, so that nodes share the buckets; otherwise buckets are kept in memory
+func initRateLimitStore(cfg *config.Config, redisClient *redis.Client) middleware.RateLimitStore {
+	if cfg.RateLimit != nil && cfg.RateLimit.Store == "redis" {
+		return middleware.NewRedisRateLimitStore(redisClient)
+	}
+	return middleware.NewMemoryRateLimitStore()
+}
+
+// initIdempotencyStore creates the store of the responses kept for idempotency keys. Redis is used when
+// configured, so that a retry reaching another node gets the same response; otherwise they are kept in memory
+func initIdempotencyStore(cfg *config.Config, redisClient *redis.Client) middleware.IdempotencyStore {
+	if cfg.Idempotency != nil && cfg.Idempotency.Store == "redis" {
+		return middleware.NewRedisIdempotencyStore(redisClient)
+	}
+	return middleware.NewMemoryIdempotencyStore()
+}
+
+// initAuditRecorder creates the recorder of the audit log, writing to the database; it is nil when the audit
+// log is disabled. The queued entries are written on shutdown.
+func initAuditRecorder(cfg *config.Config, repo interfaces.AuditLogRepository) *middleware.AuditRecorder {
+	if cfg.Audit == nil || !cfg.Audit.Enabled {
+		return nil
+	}
+	recorder := middleware.NewAuditRecorder(cfg.Audit, repo)
+	runtime.OnShutdown("Audit", recorder.Close)
+	return recorder
+}
+
+// initModelScheduler installs the shared priority queue used by chat and embedding clients
+func initModelScheduler(cfg *config.Config) {
+	mq := cfg.ModelQueue
+	if mq == nil || mq.MaxConcurrency <= 0 {
+		return
+	}
+	weights := make(map[scheduler.Priority]int, len(mq.Weights))
+	for name, weight := range mq.Weights {
+		p, ok := scheduler.ParsePriority(name)
+		if !ok {
+			logger.Warnf(context.Background(), "[ModelQueue] Ignoring weight for unknown priority %q", name)
+			continue
+		}
+		weights[p] = weight
+	}
+	scheduler.SetDefault(scheduler.New(scheduler.Options{
+		MaxConcurrency: mq.MaxConcurrency,
+		Weights:        weights,
+	}))
+	logger.Infof(context.Background(), "[ModelQueue] Model call queue enabled, max concurrency: %d", mq.MaxConcurrency)
+}
+
 // initDatabase initializes database connection
 // Creates and configures database connection based on environment configuration
 // Supports multiple database backends (PostgreSQL)
@@ -358,9 +534,77 @@ func initDatabase(cfg *config.Config) (*gorm.DB, error) {
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetConnMaxLifetime(time.Duration(10) * time.Minute)
 
+	runtime.OnShutdown("Database", func(context.Context) error {
+		return sqlDB.Close()
+	})
 	return db, nil
 }
 
+// initReplicaPool connects the read replicas listed in DB_REPLICA_HOSTS (comma separated host:port) and
+// installs read routing on the database. Replicas share the primary's user, password and database name.
+// Without replicas every query keeps using the primary.
+// Parameters:
+//   - db: Primary database connection
+//
+// Returns:
+//   - Replica pool, empty when no replica is configured
+//   - Error if a replica cannot be opened
+func initReplicaPool(db *gorm.DB) (*database.ReplicaPool, error) {
+	var replicas []database.Replica
+	for _, hostPort := range strings.Split(os.Getenv("DB_REPLICA_HOSTS"), ",") {
+		hostPort = strings.TrimSpace(hostPort)
+		if hostPort == "" {
+			continue
+		}
+		host, port, found := strings.Cut(hostPort, ":")
+		if !found {
+			port = os.Getenv("DB_PORT")
+		}
+		replicaDSN := fmt.Sprintf(
+			"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+			host,
+			port,
+			os.Getenv("DB_USER"),
+			os.Getenv("DB_PASSWORD"),
+			os.Getenv("DB_NAME"),
+			"disable",
+		)
+		replicaDB, err := gorm.Open(postgres.Open(replicaDSN), &gorm.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open read replica %s: %w", hostPort, err)
+		}
+		sqlDB, err := replicaDB.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxIdleConns(10)
+		sqlDB.SetConnMaxLifetime(time.Duration(10) * time.Minute)
+		replicas = append(replicas, database.Replica{Name: host + ":" + port, DB: sqlDB})
+	}
+
+	maxLag, _ := strconv.Atoi(os.Getenv("DB_REPLICA_MAX_LAG_SECONDS"))
+	checkInterval, _ := strconv.Atoi(os.Getenv("DB_REPLICA_CHECK_INTERVAL_SECONDS"))
+	pool := database.NewReplicaPool(replicas,
+		time.Duration(maxLag)*time.Second, time.Duration(checkInterval)*time.Second)
+	if !pool.Enabled() {
+		return pool, nil
+	}
+	if err := db.Use(pool); err != nil {
+		return nil, err
+	}
+	pool.Start()
+	logger.Infof(context.Background(), "Read replica routing enabled with %d replicas", len(replicas))
+	return pool, nil
+}
+
+// registerReplicaCleanup closes the read replica connections on shutdown
+// Parameters:
+//   - pool: Read replica pool
+//   - cleaner: Resource cleaner
+func registerReplicaCleanup(pool *database.ReplicaPool, cleaner interfaces.ResourceCleaner) {
+	cleaner.RegisterWithName("ReplicaPool", pool.Close)
+}
+
 // initFileService initializes file storage service
 // Creates the appropriate file storage service based on configuration
 // Supports multiple storage backends (MinIO, COS, local filesystem)
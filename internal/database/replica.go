@@ -0,0 +1,265 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+)
+
+const (
+	replicaPluginName  = "weknora:replica"
+	replicaInstanceKey = "weknora:replica_route"
+
+	// DefaultReplicaMaxLag is the replication lag above which a replica stops serving reads
+	DefaultReplicaMaxLag = 10 * time.Second
+	// DefaultReplicaCheckInterval is how often replica health and lag are checked
+	DefaultReplicaCheckInterval = 5 * time.Second
+)
+
+// replicaLagQuery returns the replication lag in seconds; a replica that has replayed everything it received
+// reports no lag even when the primary has been idle
+const replicaLagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`
+
+type replicaReadsKey struct{}
+
+// WithReplicaReads marks a context whose reads may be served by a read replica.
+// Only use it for reads that tolerate replication lag, never right after a write that must be visible.
+func WithReplicaReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaReadsKey{}, true)
+}
+
+// replicaReadsAllowed reports whether the context was marked with WithReplicaReads
+func replicaReadsAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(replicaReadsKey{}).(bool)
+	return allowed
+}
+
+// Replica is a read replica connection
+type Replica struct {
+	// Name identifies the replica in diagnostics, e.g. host:port
+	Name string
+	DB   *sql.DB
+}
+
+// ReplicaStatus is the health of a read replica
+type ReplicaStatus struct {
+	Name       string    `json:"name"`
+	Healthy    bool      `json:"healthy"`
+	LagSeconds float64   `json:"lag_seconds"`
+	LastError  string    `json:"last_error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+type replicaState struct {
+	Replica
+	mu     sync.RWMutex
+	status ReplicaStatus
+}
+
+func (r *replicaState) healthy() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.status.Healthy
+}
+
+func (r *replicaState) setStatus(status ReplicaStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+// markFailed takes the replica out of rotation until the next successful health check
+func (r *replicaState) markFailed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.Healthy = false
+	r.status.LastError = err.Error()
+}
+
+// ReplicaPool routes reads of contexts marked with WithReplicaReads to healthy read replicas.
+// Writes, transactions, locking reads and unmarked reads always use the primary. A read that fails on a
+// replica is retried on the primary, and the replica is skipped until its next health check succeeds.
+type ReplicaPool struct {
+	replicas      []*replicaState
+	maxLag        time.Duration
+	checkInterval time.Duration
+	next          atomic.Uint64
+	stop          chan struct{}
+	stopOnce      sync.Once
+}
+
+// NewReplicaPool creates a pool over the replicas and checks their health once.
+// A pool without replicas is valid and routes everything to the primary.
+func NewReplicaPool(replicas []Replica, maxLag, checkInterval time.Duration) *ReplicaPool {
+	if maxLag <= 0 {
+		maxLag = DefaultReplicaMaxLag
+	}
+	if checkInterval <= 0 {
+		checkInterval = DefaultReplicaCheckInterval
+	}
+	pool := &ReplicaPool{
+		maxLag:        maxLag,
+		checkInterval: checkInterval,
+		stop:          make(chan struct{}),
+	}
+	for _, replica := range replicas {
+		state := &replicaState{Replica: replica}
+		state.status.Name = replica.Name
+		pool.replicas = append(pool.replicas, state)
+	}
+	pool.checkAll(context.Background())
+	return pool
+}
+
+// Name implements gorm.Plugin
+func (p *ReplicaPool) Name() string {
+	return replicaPluginName
+}
+
+// Initialize implements gorm.Plugin, registering the routing callbacks around queries
+func (p *ReplicaPool) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register(replicaPluginName+":route", p.route); err != nil {
+		return err
+	}
+	return db.Callback().Query().After("gorm:query").Register(replicaPluginName+":fallback", p.fallback)
+}
+
+// replicaRoute records the connection replaced by a replica for the fallback
+type replicaRoute struct {
+	replica  *replicaState
+	original gorm.ConnPool
+}
+
+func (p *ReplicaPool) route(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Context == nil || !replicaReadsAllowed(db.Statement.Context) {
+		return
+	}
+	if _, inTransaction := db.Statement.ConnPool.(gorm.TxCommitter); inTransaction {
+		return
+	}
+	if _, locking := db.Statement.Clauses["FOR"]; locking {
+		return
+	}
+	replica := p.pick()
+	if replica == nil {
+		return
+	}
+	db.InstanceSet(replicaInstanceKey, &replicaRoute{replica: replica, original: db.Statement.ConnPool})
+	db.Statement.ConnPool = replica.DB
+}
+
+func (p *ReplicaPool) fallback(db *gorm.DB) {
+	value, _ := db.InstanceGet(replicaInstanceKey)
+	route, _ := value.(*replicaRoute)
+	if route == nil {
+		return
+	}
+	db.Statement.ConnPool = route.original
+	db.InstanceSet(replicaInstanceKey, (*replicaRoute)(nil))
+
+	if db.Error == nil || errors.Is(db.Error, gorm.ErrRecordNotFound) || db.Statement.Context.Err() != nil {
+		return
+	}
+	logger.Warnf(db.Statement.Context, "Read replica %s failed, retrying on primary: %v", route.replica.Name, db.Error)
+	route.replica.markFailed(db.Error)
+	db.Error = nil
+	callbacks.Query(db)
+}
+
+// pick returns the next healthy replica in round-robin order, nil when none is healthy
+func (p *ReplicaPool) pick() *replicaState {
+	n := len(p.replicas)
+	if n == 0 {
+		return nil
+	}
+	start := p.next.Add(1)
+	for i := 0; i < n; i++ {
+		replica := p.replicas[(start+uint64(i))%uint64(n)]
+		if replica.healthy() {
+			return replica
+		}
+	}
+	return nil
+}
+
+// Start checks replica health periodically until Close
+func (p *ReplicaPool) Start() {
+	if len(p.replicas) == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(p.checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.checkAll(context.Background())
+			}
+		}
+	}()
+}
+
+// checkAll pings every replica and measures its replication lag
+func (p *ReplicaPool) checkAll(ctx context.Context) {
+	for _, replica := range p.replicas {
+		status := ReplicaStatus{Name: replica.Name, CheckedAt: time.Now()}
+		checkCtx, cancel := context.WithTimeout(ctx, p.checkInterval)
+		var lag float64
+		err := replica.DB.QueryRowContext(checkCtx, replicaLagQuery).Scan(&lag)
+		cancel()
+		switch {
+		case err != nil:
+			status.LastError = err.Error()
+		case time.Duration(lag*float64(time.Second)) > p.maxLag:
+			status.LagSeconds = lag
+			status.LastError = "replication lag exceeds " + p.maxLag.String()
+		default:
+			status.LagSeconds = lag
+			status.Healthy = true
+		}
+		if replica.healthy() != status.Healthy {
+			logger.Infof(ctx, "Read replica %s healthy: %v %s", replica.Name, status.Healthy, status.LastError)
+		}
+		replica.setStatus(status)
+	}
+}
+
+// Statuses returns the health of every replica
+func (p *ReplicaPool) Statuses() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, 0, len(p.replicas))
+	for _, replica := range p.replicas {
+		replica.mu.RLock()
+		statuses = append(statuses, replica.status)
+		replica.mu.RUnlock()
+	}
+	return statuses
+}
+
+// Enabled reports whether any replica is configured
+func (p *ReplicaPool) Enabled() bool {
+	return len(p.replicas) > 0
+}
+
+// Close stops the health checks and closes the replica connections
+func (p *ReplicaPool) Close() error {
+	var errs []error
+	p.stopOnce.Do(func() {
+		close(p.stop)
+		for _, replica := range p.replicas {
+			errs = append(errs, replica.DB.Close())
+		}
+	})
+	return errors.Join(errs...)
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// fakeReplica is a database/sql driver answering the lag query with a fixed lag or error
+type fakeReplica struct {
+	lag float64
+	err error
+}
+
+func (f *fakeReplica) Connect(context.Context) (driver.Conn, error) { return f, nil }
+func (f *fakeReplica) Driver() driver.Driver                        { return f }
+func (f *fakeReplica) Open(string) (driver.Conn, error)             { return f, nil }
+func (f *fakeReplica) Prepare(string) (driver.Stmt, error)          { return nil, errors.New("not supported") }
+func (f *fakeReplica) Close() error                                 { return nil }
+func (f *fakeReplica) Begin() (driver.Tx, error)                    { return nil, errors.New("not supported") }
+
+func (f *fakeReplica) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &lagRows{lag: f.lag}, nil
+}
+
+// lagRows is a single row holding the replication lag
+type lagRows struct {
+	lag  float64
+	read bool
+}
+
+func (r *lagRows) Columns() []string { return []string{"lag"} }
+func (r *lagRows) Close() error      { return nil }
+
+func (r *lagRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	dest[0] = r.lag
+	return nil
+}
+
+func newTestReplicaPool(t *testing.T, replicas map[string]*fakeReplica, names ...string) *ReplicaPool {
+	t.Helper()
+	var list []Replica
+	for _, name := range names {
+		db := sql.OpenDB(replicas[name])
+		t.Cleanup(func() { db.Close() })
+		list = append(list, Replica{Name: name, DB: db})
+	}
+	return NewReplicaPool(list, 10*time.Second, time.Second)
+}
+
+func TestReplicaPoolHealth(t *testing.T) {
+	tests := []struct {
+		name        string
+		replica     *fakeReplica
+		wantHealthy bool
+		wantLag     float64
+	}{
+		{name: "caught up", replica: &fakeReplica{}, wantHealthy: true},
+		{name: "lag within the limit", replica: &fakeReplica{lag: 9.5}, wantHealthy: true, wantLag: 9.5},
+		{name: "lag above the limit", replica: &fakeReplica{lag: 30}, wantLag: 30},
+		{name: "unreachable", replica: &fakeReplica{err: errors.New("connection refused")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newTestReplicaPool(t, map[string]*fakeReplica{"replica-1": tt.replica}, "replica-1")
+			status := pool.Statuses()[0]
+			if status.Healthy != tt.wantHealthy || status.LagSeconds != tt.wantLag {
+				t.Errorf("status = %+v, want healthy %v with lag %v", status, tt.wantHealthy, tt.wantLag)
+			}
+			if !status.Healthy && status.LastError == "" {
+				t.Error("an unhealthy replica must report why")
+			}
+			// Lagging or failed replicas fall back to the primary
+			if picked := pool.pick(); (picked != nil) != tt.wantHealthy {
+				t.Errorf("picked %v, want a replica only when healthy", picked)
+			}
+		})
+	}
+}
+
+func TestReplicaPoolPick(t *testing.T) {
+	replicas := map[string]*fakeReplica{
+		"replica-1": {lag: 1},
+		"replica-2": {lag: 60},
+		"replica-3": {},
+	}
+	pool := newTestReplicaPool(t, replicas, "replica-1", "replica-2", "replica-3")
+
+	picked := map[string]int{}
+	for i := 0; i < 6; i++ {
+		picked[pool.pick().Name]++
+	}
+	if picked["replica-1"] == 0 || picked["replica-3"] == 0 || picked["replica-2"] != 0 {
+		t.Errorf("picked %v, want reads spread over the caught-up replicas only", picked)
+	}
+
+	// A failed read takes the replica out of rotation until the next successful check
+	pool.replicas[0].markFailed(errors.New("read failed"))
+	for i := 0; i < 3; i++ {
+		if name := pool.pick().Name; name != "replica-3" {
+			t.Fatalf("picked %s after replica-1 failed", name)
+		}
+	}
+	replicas["replica-2"].lag = 2
+	pool.checkAll(context.Background())
+	picked = map[string]int{}
+	for i := 0; i < 6; i++ {
+		picked[pool.pick().Name]++
+	}
+	if len(picked) != 3 {
+		t.Errorf("picked %v after recovery, want all replicas", picked)
+	}
+
+	for _, replica := range replicas {
+		replica.err = errors.New("connection refused")
+	}
+	pool.checkAll(context.Background())
+	if replica := pool.pick(); replica != nil {
+		t.Errorf("picked %s while every replica is down, want the primary", replica.Name)
+	}
+}
+
+func TestReplicaPoolWithoutReplicas(t *testing.T) {
+	pool := NewReplicaPool(nil, 0, 0)
+	if pool.Enabled() || pool.pick() != nil || len(pool.Statuses()) != 0 {
+		t.Error("a pool without replicas must route everything to the primary")
+	}
+	if pool.maxLag != DefaultReplicaMaxLag || pool.checkInterval != DefaultReplicaCheckInterval {
+		t.Errorf("defaults = %v, %v", pool.maxLag, pool.checkInterval)
+	}
+	if !replicaReadsAllowed(WithReplicaReads(context.Background())) || replicaReadsAllowed(context.Background()) {
+		t.Error("only contexts marked with WithReplicaReads may read from replicas")
+	}
+}
@@ -0,0 +1,186 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Stable machine-readable error codes, returned as "code" in error responses.
+// Codes are never renamed once published; see docs/api/errors.md for the full list.
+const (
+	// Generic codes, used when no domain-specific code applies
+	CodeBadRequest         = "request.invalid"
+	CodeValidation         = "request.validation_failed"
+	CodeUnauthorized       = "auth.unauthorized"
+	CodeForbidden          = "auth.forbidden"
+	CodeNotFound           = "resource.not_found"
+	CodeMethodNotAllowed   = "request.method_not_allowed"
+	CodeConflict           = "resource.conflict"
+	CodeTooManyRequests    = "rate_limit.exceeded"
+	CodeInternal           = "internal.error"
+	CodeServiceUnavailable = "service.unavailable"
+	CodeTimeout            = "request.timeout"
+
+	// Authentication
+	CodeRegistrationDisabled = "auth.registration_disabled"
+	CodeInsufficientScope    = "auth.insufficient_scope"
+	CodeInsufficientRole     = "auth.insufficient_role"
+	CodeOIDCProviderNotFound = "auth.oidc_provider_not_found"
+	CodeOIDCLoginFailed      = "auth.oidc_login_failed"
+	CodeTwoFactorInvalidCode = "auth.two_factor_invalid_code"
+	CodeTwoFactorEnabled     = "auth.two_factor_already_enabled"
+	CodeTwoFactorNotEnabled  = "auth.two_factor_not_enabled"
+	CodeTwoFactorLocked      = "auth.two_factor_locked"
+	CodeInvalidPassword      = "auth.invalid_password"
+
+	// Tenant
+	CodeTenantNotFound      = "tenant.not_found"
+	CodeTenantAlreadyExists = "tenant.already_exists"
+	CodeTenantInactive      = "tenant.inactive"
+	CodeTenantNameRequired  = "tenant.name_required"
+	CodeTenantInvalidStatus = "tenant.invalid_status"
+	CodeQuotaExceeded       = "quota.exceeded"
+	CodeFeatureNotEnabled   = "feature.not_enabled"
+	CodeRequestTooLarge     = "payload.request_too_large"
+	CodeResponseTooLarge    = "payload.response_too_large"
+
+	// Idempotency keys
+	CodeIdempotencyKeyReused  = "idempotency.key_reused"
+	CodeIdempotencyInProgress = "idempotency.in_progress"
+
+	// Knowledge bases and knowledge
+	CodeKnowledgeBaseNotFound        = "knowledge_base.not_found"
+	CodeKnowledgeBaseInUse           = "knowledge_base.in_use"
+	CodeKnowledgeBaseEmbeddingLocked = "knowledge_base.embedding_locked"
+	CodeKnowledgeNotFound            = "knowledge.not_found"
+	CodeKnowledgeDuplicate           = "knowledge.duplicate"
+	CodeKnowledgeUnsupportedFileType = "knowledge.unsupported_file_type"
+	CodeChunkNotFound                = "chunk.not_found"
+	CodeTagNotFound                  = "tag.not_found"
+	CodeTagDuplicate                 = "tag.duplicate"
+	CodeTagInUse                     = "tag.in_use"
+	CodeFAQEntryNotFound             = "faq_entry.not_found"
+	CodeTaskNotFound                 = "task.not_found"
+
+	// Models
+	CodeModelNotFound = "model.not_found"
+	CodeModelInUse    = "model.in_use"
+
+	// Sessions
+	CodeSessionNotFound = "session.not_found"
+	CodeMessageNotFound = "message.not_found"
+
+	// Agents and MCP services
+	CodeAgentNotFound             = "agent.not_found"
+	CodeAgentBuiltinReadOnly      = "agent.builtin_read_only"
+	CodeAgentRunNotFound          = "agent_run.not_found"
+	CodeAgentMissingThinkingModel = "agent.missing_thinking_model"
+	CodeAgentMissingAllowedTools  = "agent.missing_allowed_tools"
+	CodeAgentInvalidMaxIterations = "agent.invalid_max_iterations"
+	CodeAgentInvalidTemperature   = "agent.invalid_temperature"
+	CodeAgentInvalidRetrievalMode = "agent.invalid_retrieval_mode"
+	CodeMCPServiceNotFound        = "mcp_service.not_found"
+	CodeMCPServiceInvalidConfig   = "mcp_service.invalid_advanced_config"
+	CodeMCPServiceUnavailable     = "mcp_service.unavailable"
+)
+
+// legacyCodes maps HTTP statuses to the numeric codes used before string codes were introduced
+var legacyCodes = map[int]ErrorCode{
+	http.StatusBadRequest:          ErrBadRequest,
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusForbidden:           ErrForbidden,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusMethodNotAllowed:    ErrMethodNotAllowed,
+	http.StatusConflict:            ErrConflict,
+	http.StatusTooManyRequests:     ErrTooManyRequests,
+	http.StatusServiceUnavailable:  ErrServiceUnavailable,
+	http.StatusGatewayTimeout:      ErrTimeout,
+	http.StatusInternalServerError: ErrInternalServer,
+}
+
+// genericCodes maps legacy numeric codes to their generic string code
+var genericCodes = map[ErrorCode]string{
+	ErrBadRequest:                CodeBadRequest,
+	ErrUnauthorized:              CodeUnauthorized,
+	ErrForbidden:                 CodeForbidden,
+	ErrNotFound:                  CodeNotFound,
+	ErrMethodNotAllowed:          CodeMethodNotAllowed,
+	ErrConflict:                  CodeConflict,
+	ErrTooManyRequests:           CodeTooManyRequests,
+	ErrInternalServer:            CodeInternal,
+	ErrServiceUnavailable:        CodeServiceUnavailable,
+	ErrTimeout:                   CodeTimeout,
+	ErrValidation:                CodeValidation,
+	ErrTenantNotFound:            CodeTenantNotFound,
+	ErrTenantAlreadyExists:       CodeTenantAlreadyExists,
+	ErrTenantInactive:            CodeTenantInactive,
+	ErrTenantNameRequired:        CodeTenantNameRequired,
+	ErrTenantInvalidStatus:       CodeTenantInvalidStatus,
+	ErrAgentMissingThinkingModel: CodeAgentMissingThinkingModel,
+	ErrAgentMissingAllowedTools:  CodeAgentMissingAllowedTools,
+	ErrAgentInvalidMaxIterations: CodeAgentInvalidMaxIterations,
+	ErrAgentInvalidTemperature:   CodeAgentInvalidTemperature,
+}
+
+// Converter is implemented by domain errors that know their API representation
+type Converter interface {
+	AppError() *AppError
+}
+
+// sentinelError is a comparable error value carrying a stable code, for use with errors.Is
+type sentinelError struct {
+	code    string
+	status  int
+	message string
+}
+
+// Error implements the error interface
+func (e *sentinelError) Error() string {
+	return e.message
+}
+
+// AppError implements Converter
+func (e *sentinelError) AppError() *AppError {
+	return newAppError(e.code, e.status, e.message)
+}
+
+// NewSentinel creates a sentinel error that handlers report with the given code and HTTP status
+func NewSentinel(code string, status int, message string) error {
+	return &sentinelError{code: code, status: status, message: message}
+}
+
+// FromError converts any error into an AppError. AppErrors and Converters anywhere in the
+// wrap chain keep their code; context timeouts become request.timeout; everything else is an
+// internal error carrying the original message.
+func FromError(err error) *AppError {
+	if err == nil {
+		return nil
+	}
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	var converter Converter
+	if errors.As(err, &converter) {
+		return converter.AppError()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return newAppError(CodeTimeout, http.StatusGatewayTimeout, err.Error())
+	}
+	return NewInternalServerError(err.Error())
+}
+
+// newAppError builds an AppError from a string code and HTTP status
+func newAppError(code string, status int, message string) *AppError {
+	legacy, ok := legacyCodes[status]
+	if !ok {
+		legacy = ErrInternalServer
+	}
+	return &AppError{
+		Code:     legacy,
+		Reason:   code,
+		Message:  message,
+		HTTPCode: status,
+	}
+}
@@ -0,0 +1,102 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+var errTestQuota = NewSentinel(CodeQuotaExceeded, http.StatusForbidden, "storage quota exceeded")
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   string
+		wantStatus int
+		wantLegacy ErrorCode
+	}{
+		{
+			name:       "app error",
+			err:        NewNotFoundError("knowledge base not found").WithCode(CodeKnowledgeBaseNotFound),
+			wantCode:   CodeKnowledgeBaseNotFound,
+			wantStatus: http.StatusNotFound,
+			wantLegacy: ErrNotFound,
+		},
+		{
+			name:       "wrapped app error",
+			err:        fmt.Errorf("load agent: %w", NewForbiddenError("read only").WithCode(CodeAgentBuiltinReadOnly)),
+			wantCode:   CodeAgentBuiltinReadOnly,
+			wantStatus: http.StatusForbidden,
+			wantLegacy: ErrForbidden,
+		},
+		{
+			name:       "wrapped sentinel",
+			err:        fmt.Errorf("upload: %w", errTestQuota),
+			wantCode:   CodeQuotaExceeded,
+			wantStatus: http.StatusForbidden,
+			wantLegacy: ErrForbidden,
+		},
+		{
+			name:       "deadline",
+			err:        fmt.Errorf("search: %w", context.DeadlineExceeded),
+			wantCode:   CodeTimeout,
+			wantStatus: http.StatusGatewayTimeout,
+			wantLegacy: ErrTimeout,
+		},
+		{
+			name:       "plain error",
+			err:        fmt.Errorf("connection refused"),
+			wantCode:   CodeInternal,
+			wantStatus: http.StatusInternalServerError,
+			wantLegacy: ErrInternalServer,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appErr := FromError(tt.err)
+			if appErr.MachineCode() != tt.wantCode || appErr.HTTPCode != tt.wantStatus || appErr.Code != tt.wantLegacy {
+				t.Errorf("FromError() = (%s, %d, %d), want (%s, %d, %d)",
+					appErr.MachineCode(), appErr.HTTPCode, appErr.Code, tt.wantCode, tt.wantStatus, tt.wantLegacy)
+			}
+		})
+	}
+	if FromError(nil) != nil {
+		t.Error("FromError(nil) must be nil")
+	}
+}
+
+func TestMachineCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *AppError
+		want string
+	}{
+		{name: "domain code", err: NewConflictError("tag exists").WithCode(CodeTagDuplicate), want: CodeTagDuplicate},
+		{name: "generic code of the legacy code", err: NewValidationError("name is required"), want: CodeValidation},
+		{name: "tenant legacy code", err: &AppError{Code: ErrTenantInactive, HTTPCode: http.StatusForbidden}, want: CodeTenantInactive},
+		{name: "generic code of the status", err: &AppError{Code: 9999, HTTPCode: http.StatusTooManyRequests}, want: CodeTooManyRequests},
+		{name: "unknown", err: &AppError{Code: 9999, HTTPCode: http.StatusTeapot}, want: CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.MachineCode(); got != tt.want {
+				t.Errorf("MachineCode() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppErrorBody(t *testing.T) {
+	body := NewNotFoundError("session not found").WithCode(CodeSessionNotFound).Body()
+	if body["code"] != CodeSessionNotFound || body["status"] != http.StatusNotFound || body["legacy_code"] != ErrNotFound {
+		t.Errorf("Body() = %v", body)
+	}
+	if _, ok := body["details"]; ok {
+		t.Error("details must be omitted when empty")
+	}
+	if details := NewBadRequestError("bad").WithDetails("page_size").Body()["details"]; details != "page_size" {
+		t.Errorf("details = %v, want page_size", details)
+	}
+}
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -41,10 +42,13 @@ const (
 
 // AppError defines the application error structure
 type AppError struct {
-	Code     ErrorCode `json:"code"`
-	Message  string    `json:"message"`
-	Details  any       `json:"details,omitempty"`
-	HTTPCode int       `json:"-"`
+	// Code is the legacy numeric error code
+	Code ErrorCode `json:"legacy_code"`
+	// Reason is the stable machine-readable code (e.g. knowledge_base.not_found)
+	Reason   string `json:"code"`
+	Message  string `json:"message"`
+	Details  any    `json:"details,omitempty"`
+	HTTPCode int    `json:"status"`
 }
 
 // Error implements the error interface
@@ -58,6 +62,41 @@ func (e *AppError) WithDetails(details any) *AppError {
 	return e
 }
 
+// WithCode sets a domain-specific machine-readable code
+func (e *AppError) WithCode(code string) *AppError {
+	e.Reason = code
+	return e
+}
+
+// MachineCode returns the stable code, falling back to the generic code of the legacy code or HTTP status
+func (e *AppError) MachineCode() string {
+	if e.Reason != "" {
+		return e.Reason
+	}
+	if code, ok := genericCodes[e.Code]; ok {
+		return code
+	}
+	if code, ok := genericCodes[legacyCodes[e.HTTPCode]]; ok {
+		return code
+	}
+	return CodeInternal
+}
+
+// Body returns the error object of an error response: the stable code, HTTP status, message,
+// optional details and the legacy numeric code for older clients
+func (e *AppError) Body() map[string]any {
+	body := map[string]any{
+		"code":        e.MachineCode(),
+		"status":      e.HTTPCode,
+		"message":     e.Message,
+		"legacy_code": e.Code,
+	}
+	if e.Details != nil {
+		body["details"] = e.Details
+	}
+	return body
+}
+
 // NewBadRequestError creates a bad request error
 func NewBadRequestError(message string) *AppError {
 	return &AppError{
@@ -115,6 +154,24 @@ func NewInternalServerError(message string) *AppError {
 	}
 }
 
+// NewTooManyRequestsError creates a too many requests error
+func NewTooManyRequestsError(message string) *AppError {
+	return &AppError{
+		Code:     ErrTooManyRequests,
+		Message:  message,
+		HTTPCode: http.StatusTooManyRequests,
+	}
+}
+
+// NewServiceUnavailableError creates a service unavailable error
+func NewServiceUnavailableError(message string) *AppError {
+	return &AppError{
+		Code:     ErrServiceUnavailable,
+		Message:  message,
+		HTTPCode: http.StatusServiceUnavailable,
+	}
+}
+
 // NewValidationError creates a validation error
 func NewValidationError(message string) *AppError {
 	return &AppError{
@@ -184,8 +241,26 @@ func NewAgentInvalidTemperatureError() *AppError {
 	}
 }
 
-// IsAppError checks if the error is an AppError type
+// NewFeatureNotEnabledError creates an error for a feature that is disabled for the tenant
+func NewFeatureNotEnabledError(feature string) *AppError {
+	return NewForbiddenError(fmt.Sprintf("Feature %s is not enabled for this tenant", feature)).
+		WithCode(CodeFeatureNotEnabled).
+		WithDetails(map[string]string{"feature": feature})
+}
+
+// NewPayloadTooLargeError creates an error for a request or response over the payload size limit of the tenant
+func NewPayloadTooLargeError(code, message string) *AppError {
+	return &AppError{
+		Code:     ErrBadRequest,
+		Reason:   code,
+		Message:  message,
+		HTTPCode: http.StatusRequestEntityTooLarge,
+	}
+}
+
+// IsAppError checks if the error, or any error it wraps, is an AppError
 func IsAppError(err error) (*AppError, bool) {
-	appErr, ok := err.(*AppError)
+	var appErr *AppError
+	ok := errors.As(err, &appErr)
 	return appErr, ok
 }
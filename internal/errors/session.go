@@ -1,10 +1,13 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+)
 
 var (
 	// ErrSessionNotFound session not found error
-	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionNotFound = NewSentinel(CodeSessionNotFound, http.StatusNotFound, "session not found")
 	// ErrSessionExpired session expired error
 	ErrSessionExpired = errors.New("session expired")
 	// ErrSessionLimitExceeded session limit exceeded error
@@ -47,12 +47,18 @@ const (
 	EventAgentComplete EventType = "agent.complete" // Agent 完成
 
 	// Agent streaming events (for real-time feedback)
-	EventAgentThought     EventType = "thought"      // Agent 思考过程
-	EventAgentToolCall    EventType = "tool_call"    // 工具调用通知
-	EventAgentToolResult  EventType = "tool_result"  // 工具结果
-	EventAgentReflection  EventType = "reflection"   // Agent 反思
-	EventAgentReferences  EventType = "references"   // 知识引用
-	EventAgentFinalAnswer EventType = "final_answer" // 最终答案
+	EventAgentThought      EventType = "thought"            // Agent 思考过程
+	EventAgentToolCall     EventType = "tool_call"          // 工具调用通知
+	EventAgentToolResult   EventType = "tool_result"        // 工具结果
+	EventAgentToolStatus   EventType = "tool_status"        // 工具执行中的状态与部分结果
+	EventAgentReflection   EventType = "reflection"         // Agent 反思
+	EventAgentReferences   EventType = "references"         // 知识引用
+	EventAgentFinalAnswer  EventType = "final_answer"       // 最终答案
+	EventConfidence        EventType = "confidence"         // 回答置信度评估结果
+	EventContextBudget     EventType = "context_budget"     // 提示词上下文预算分布
+	EventGrounding         EventType = "grounding"          // 回答事实依据校验结果
+	EventCitation          EventType = "citation"           // 回答引用标注校验结果
+	EventRetrievalDegraded EventType = "retrieval_degraded" // 检索在软超时截止，回答基于部分检索结果
 
 	// Error events
 	EventError EventType = "error" // 错误事件
@@ -1,5 +1,7 @@
 package event
 
+import "github.com/Tencent/WeKnora/internal/types"
+
 // EventData contains common event data structures for different stages
 
 // QueryData represents query-related event data
@@ -142,6 +144,9 @@ type AgentCompleteData struct {
 	TotalDurationMs int64                  `json:"total_duration_ms"`
 	MessageID       string                 `json:"message_id,omitempty"` // Assistant message ID
 	RequestID       string                 `json:"request_id,omitempty"`
+	StopReason      string                 `json:"stop_reason,omitempty"` // Why the agent loop ended
+	Truncated       bool                   `json:"truncated"`             // Whether the agent was cut short by a limit
+	TokensUsed      int                    `json:"tokens_used,omitempty"` // Estimated tokens consumed by the loop
 	Extra           map[string]interface{} `json:"extra,omitempty"`
 }
 
@@ -175,6 +180,16 @@ type AgentToolResultData struct {
 	Data       map[string]interface{} `json:"data,omitempty"` // Structured data from tool result (e.g., display_type, formatted results)
 }
 
+// AgentToolStatusData represents an intermediate update from a running tool
+type AgentToolStatusData struct {
+	ToolCallID string `json:"tool_call_id"`
+	ToolName   string `json:"tool_name"`
+	Status     string `json:"status"`            // Human-readable status, e.g. "Querying database..."
+	Partial    string `json:"partial,omitempty"` // Partial output produced so far
+	Seq        int    `json:"seq"`               // Position of this update within the tool call
+	Iteration  int    `json:"iteration"`
+}
+
 // AgentReferencesData represents knowledge references data
 type AgentReferencesData struct {
 	References interface{} `json:"references"` // []*types.SearchResult
@@ -185,6 +200,8 @@ type AgentReferencesData struct {
 type AgentFinalAnswerData struct {
 	Content string `json:"content"`
 	Done    bool   `json:"done"`
+	// Usage is the token usage of the answer, set on the last chunk when the model reports it
+	Usage *types.TokenUsage `json:"usage,omitempty"`
 }
 
 // AgentReflectionData represents agent reflection data
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AgentRunHandler exposes agent execution traces and replays for debugging
+// All endpoints are restricted to administrators
+type AgentRunHandler struct {
+	service     interfaces.AgentRunService
+	userService interfaces.UserService
+}
+
+// NewAgentRunHandler creates a new agent run handler instance
+func NewAgentRunHandler(service interfaces.AgentRunService, userService interfaces.UserService) *AgentRunHandler {
+	return &AgentRunHandler{
+		service:     service,
+		userService: userService,
+	}
+}
+
+// ReplayAgentRunRequest defines the request body for replaying an agent run
+type ReplayAgentRunRequest struct {
+	// Agent version to replay against; 0 reuses the recorded configuration
+	AgentVersion int `json:"agent_version"`
+}
+
+// GetAgentRun godoc
+// @Summary      Get agent run trace
+// @Description  Get the full execution trace of the agent run that produced a message, followed by its replays (admin only)
+// @Tags         Agent
+// @Accept       json
+// @Produce      json
+// @Param        message_id  path      string  true  "Assistant message ID"
+// @Success      200         {object}  map[string]interface{}  "Agent runs"
+// @Failure      403         {object}  errors.AppError         "Insufficient permissions"
+// @Failure      404         {object}  errors.AppError         "Agent run not found"
+// @Security     Bearer
+// @Router       /agents/runs/{message_id} [get]
+func (h *AgentRunHandler) GetAgentRun(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	messageID := secutils.SanitizeForLog(c.Param("message_id"))
+	runs, err := h.service.ListRuns(ctx, messageID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"message_id": messageID,
+		})
+		if err == service.ErrAgentRunNotFound {
+			c.Error(errors.NewNotFoundError("Agent run not found").WithCode(errors.CodeAgentRunNotFound))
+			return
+		}
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    runs,
+	})
+}
+
+// ReplayAgentRun godoc
+// @Summary      Replay agent run
+// @Description  Re-execute the inputs of a recorded agent run, optionally against another agent version (admin only)
+// @Tags         Agent
+// @Accept       json
+// @Produce      json
+// @Param        message_id  path      string                 true   "Assistant message ID"
+// @Param        request     body      ReplayAgentRunRequest  false  "Replay options"
+// @Success      200         {object}  map[string]interface{}  "Replayed run"
+// @Failure      403         {object}  errors.AppError         "Insufficient permissions"
+// @Failure      404         {object}  errors.AppError         "Agent run or version not found"
+// @Security     Bearer
+// @Router       /agents/runs/{message_id}/replay [post]
+func (h *AgentRunHandler) ReplayAgentRun(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	messageID := secutils.SanitizeForLog(c.Param("message_id"))
+	var req ReplayAgentRunRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.Error(ctx, "Failed to parse request parameters", err)
+			c.Error(errors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
+			return
+		}
+	}
+
+	logger.Infof(ctx, "Replaying agent run, message ID: %s, agent version: %d", messageID, req.AgentVersion)
+
+	run, err := h.service.ReplayRun(ctx, messageID, req.AgentVersion)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"message_id": messageID,
+		})
+		switch err {
+		case service.ErrAgentRunNotFound:
+			c.Error(errors.NewNotFoundError("Agent run not found").WithCode(errors.CodeAgentRunNotFound))
+		case service.ErrAgentNotFound, service.ErrAgentVersionNotFound:
+			c.Error(errors.NewNotFoundError(err.Error()))
+		case service.ErrBuiltinAgentNotVersioned:
+			c.Error(errors.NewBadRequestError(err.Error()))
+		default:
+			c.Error(errors.FromError(err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    run,
+	})
+}
+
+// requireAdmin aborts the request unless the current user is an administrator
+func (h *AgentRunHandler) requireAdmin(c *gin.Context) bool {
+	ctx := c.Request.Context()
+
+	user, err := h.userService.GetCurrentUser(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get current user: %v", err)
+		c.Error(errors.NewForbiddenError("Agent run traces require an administrator account"))
+		return false
+	}
+	if !user.CanAccessAllTenants {
+		logger.Warnf(ctx, "User %s attempted to access agent run traces without permission", user.ID)
+		c.Error(errors.NewForbiddenError("Insufficient permissions to access agent run traces"))
+		return false
+	}
+	return true
+}
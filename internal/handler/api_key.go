@@ -0,0 +1,120 @@
+package handler
+
+import (
+	goerrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler manages the API keys minted for the current tenant
+type APIKeyHandler struct {
+	service interfaces.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new API key handler instance
+func NewAPIKeyHandler(service interfaces.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// CreateAPIKey godoc
+// @Summary      Create API key
+// @Description  Mint an API key for the current tenant with its own scopes and optional expiration.
+// @Description  The key is only returned in this response; only its hash is stored.
+// @Tags         API Keys
+// @Accept       json
+// @Produce      json
+// @Param        request  body      types.CreateAPIKeyRequest  true  "API key"
+// @Success      201      {object}  map[string]interface{}     "Created key"
+// @Failure      400      {object}  errors.AppError            "Invalid name, scopes or expiration"
+// @Failure      403      {object}  errors.AppError            "Too many active keys"
+// @Security     Bearer
+// @Router       /api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req types.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to bind create API key payload", err)
+		c.Error(errors.NewBadRequestError("invalid request parameters").WithDetails(err.Error()))
+		return
+	}
+
+	key, err := h.service.CreateKey(ctx, &req)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(apiKeyError(err))
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    key,
+	})
+}
+
+// ListAPIKeys godoc
+// @Summary      List API keys
+// @Description  List the API keys minted for the current tenant, newest first, including expired and revoked keys
+// @Tags         API Keys
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "API keys"
+// @Security     Bearer
+// @Router       /api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	keys, err := h.service.ListKeys(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(apiKeyError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    keys,
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoke API key
+// @Description  Revoke an API key of the current tenant; requests with the key are rejected from then on
+// @Tags         API Keys
+// @Produce      json
+// @Param        id   path      string                  true  "API key ID"
+// @Success      200  {object}  map[string]interface{}  "Revoked"
+// @Failure      404  {object}  errors.AppError         "API key not found"
+// @Security     Bearer
+// @Router       /api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	if err := h.service.RevokeKey(ctx, id); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"api_key_id": id})
+		c.Error(apiKeyError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// apiKeyError maps the errors of the API key service to API errors
+func apiKeyError(err error) *errors.AppError {
+	switch {
+	case goerrors.Is(err, service.ErrInvalidAPIKeyRequest):
+		return errors.NewValidationError("Invalid API key").WithDetails(err.Error())
+	case goerrors.Is(err, service.ErrTooManyAPIKeys):
+		return errors.NewForbiddenError("Too many API keys").WithCode(errors.CodeQuotaExceeded).WithDetails(err.Error())
+	case goerrors.Is(err, service.ErrAPIKeyNotFound):
+		return errors.NewNotFoundError("API key not found")
+	default:
+		return errors.FromError(err)
+	}
+}
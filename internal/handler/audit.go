@@ -0,0 +1,107 @@
+package handler
+
+import (
+	goerrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler exposes the audit log of the mutating requests of the current tenant
+type AuditHandler struct {
+	service interfaces.AuditLogService
+}
+
+// NewAuditHandler creates a new audit handler instance
+func NewAuditHandler(service interfaces.AuditLogService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// ListAuditLogs godoc
+// @Summary      List audit log
+// @Description  List the non-GET requests of the current tenant, newest first, with passwords and secrets redacted
+// @Tags         System
+// @Accept       json
+// @Produce      json
+// @Param        resource_type  query     string  false  "Resource type, e.g. knowledge-bases"
+// @Param        user_id        query     string  false  "User who sent the requests"
+// @Param        start_time     query     string  false  "Start of the time range (RFC 3339)"
+// @Param        end_time       query     string  false  "End of the time range, exclusive (RFC 3339)"
+// @Param        page           query     int     false  "Page number"
+// @Param        page_size      query     int     false  "Page size"
+// @Success      200            {object}  map[string]interface{}  "Audit log entries"
+// @Failure      400            {object}  errors.AppError         "Invalid query parameters"
+// @Security     Bearer
+// @Router       /audit-logs [get]
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var filter types.AuditLogFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		logger.Error(ctx, "Failed to parse audit log filter", err)
+		c.Error(errors.NewBadRequestError("Invalid query parameters").WithDetails(err.Error()))
+		return
+	}
+	if filter.StartTime != nil && filter.EndTime != nil && !filter.EndTime.After(*filter.StartTime) {
+		c.Error(errors.NewBadRequestError("end_time must be after start_time"))
+		return
+	}
+	var page types.Pagination
+	if err := c.ShouldBindQuery(&page); err != nil {
+		logger.Error(ctx, "Failed to parse pagination parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid query parameters").WithDetails(err.Error()))
+		return
+	}
+
+	result, err := h.service.ListLogs(ctx, &filter, &page)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      result.Data,
+		"total":     result.Total,
+		"page":      result.Page,
+		"page_size": result.PageSize,
+	})
+}
+
+// GetAuditLog godoc
+// @Summary      Get audit log entry
+// @Description  Get an entry of the audit log of the current tenant
+// @Tags         System
+// @Produce      json
+// @Param        id   path      string  true  "Audit log entry ID"
+// @Success      200  {object}  map[string]interface{}  "Audit log entry"
+// @Failure      404  {object}  errors.AppError         "Entry not found"
+// @Security     Bearer
+// @Router       /audit-logs/{id} [get]
+func (h *AuditHandler) GetAuditLog(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := secutils.SanitizeForLog(c.Param("id"))
+
+	log, err := h.service.GetLog(ctx, id)
+	if err != nil {
+		if goerrors.Is(err, service.ErrAuditLogNotFound) {
+			c.Error(errors.NewNotFoundError("Audit log entry not found"))
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    log,
+	})
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/middleware"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeAuditLogRepository keeps the audit log of every tenant in memory
+type fakeAuditLogRepository struct {
+	interfaces.AuditLogRepository
+	logs []*types.AuditLog
+}
+
+func (f *fakeAuditLogRepository) GetLog(_ context.Context, tenantID uint64, id string) (*types.AuditLog, error) {
+	for _, log := range f.logs {
+		if log.TenantID == tenantID && log.ID == id {
+			return log, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestGetAuditLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &fakeAuditLogRepository{logs: []*types.AuditLog{
+		{ID: "log-1", TenantID: 1, Method: http.MethodDelete, Route: "/api/v1/knowledge-bases/:id"},
+		{ID: "log-2", TenantID: 2, Method: http.MethodPost, Route: "/api/v1/knowledge-bases"},
+	}}
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(), func(c *gin.Context) {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), types.TenantIDContextKey, uint64(1)))
+	})
+	router.GET("/audit-logs/:id", NewAuditHandler(service.NewAuditLogService(repo)).GetAuditLog)
+
+	tests := []struct {
+		name       string
+		id         string
+		wantStatus int
+	}{
+		{"entry of the tenant", "log-1", http.StatusOK},
+		{"unknown entry", "log-3", http.StatusNotFound},
+		// Entries of other tenants are not found rather than forbidden, so IDs do not leak
+		{"entry of another tenant", "log-2", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/audit-logs/"+tt.id, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data types.AuditLog `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Data.ID != tt.id || resp.Data.TenantID != 1 {
+				t.Errorf("entry = %+v, want %s of tenant 1", resp.Data, tt.id)
+			}
+		})
+	}
+}
@@ -21,6 +21,8 @@ import (
 type AuthHandler struct {
 	userService   interfaces.UserService
 	tenantService interfaces.TenantService
+	authenticator interfaces.Authenticator
+	oidcService   interfaces.OIDCService
 	configInfo    *config.Config
 }
 
@@ -28,14 +30,20 @@ type AuthHandler struct {
 // Parameters:
 //   - userService: An implementation of the UserService interface for business logic
 //   - tenantService: An implementation of the TenantService interface for tenant management
+//   - authenticator: The configured validator of bearer tokens
+//   - oidcService: The login with OpenID Connect identity providers
 //
 // Returns a pointer to the newly created AuthHandler
 func NewAuthHandler(configInfo *config.Config,
-	userService interfaces.UserService, tenantService interfaces.TenantService) *AuthHandler {
+	userService interfaces.UserService, tenantService interfaces.TenantService,
+	authenticator interfaces.Authenticator, oidcService interfaces.OIDCService,
+) *AuthHandler {
 	return &AuthHandler{
 		configInfo:    configInfo,
 		userService:   userService,
 		tenantService: tenantService,
+		authenticator: authenticator,
+		oidcService:   oidcService,
 	}
 }
 
@@ -58,7 +66,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// 通过环境变量 DISABLE_REGISTRATION=true 禁止注册
 	if os.Getenv("DISABLE_REGISTRATION") == "true" {
 		logger.Warn(ctx, "Registration is disabled by DISABLE_REGISTRATION env")
-		appErr := errors.NewForbiddenError("Registration is disabled")
+		appErr := errors.NewForbiddenError("Registration is disabled").WithCode(errors.CodeRegistrationDisabled)
 		c.Error(appErr)
 		return
 	}
@@ -275,16 +283,23 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	}
 
 	// Get tenant information
+	// In single-tenant mode every user works in the default tenant
+	tenantID := user.TenantID
+	singleTenant := h.configInfo.SingleTenantMode()
+	if singleTenant != nil {
+		tenantID = singleTenant.DefaultTenantID()
+	}
 	var tenant *types.Tenant
-	if user.TenantID > 0 {
-		tenant, err = h.tenantService.GetTenantByID(ctx, user.TenantID)
+	if tenantID > 0 {
+		tenant, err = h.tenantService.GetTenantByID(ctx, tenantID)
 		if err != nil {
-			logger.Warnf(ctx, "Failed to get tenant info for user %s, tenant ID %d: %v", user.Email, user.TenantID, err)
+			logger.Warnf(ctx, "Failed to get tenant info for user %s, tenant ID %d: %v", user.Email, tenantID, err)
 			// Don't fail the request if tenant info is not available
 		}
 	}
 	userInfo := user.ToUserInfo()
-	userInfo.CanAccessAllTenants = user.CanAccessAllTenants && h.configInfo.Tenant.EnableCrossTenantAccess
+	userInfo.CanAccessAllTenants = user.CanAccessAllTenants && h.configInfo.Tenant.EnableCrossTenantAccess &&
+		singleTenant == nil
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
@@ -382,8 +397,8 @@ func (h *AuthHandler) ValidateToken(c *gin.Context) {
 
 	token := tokenParts[1]
 
-	// Validate token
-	user, err := h.userService.ValidateToken(ctx, token)
+	// Validate token with the configured provider
+	user, err := h.authenticator.Authenticate(ctx, token)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to validate token: %v", err)
 		appErr := errors.NewUnauthorizedError("Token validation failed").WithDetails(err.Error())
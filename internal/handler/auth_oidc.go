@@ -0,0 +1,139 @@
+package handler
+
+import (
+	goerrors "errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+)
+
+// ListOIDCProviders godoc
+// @Summary      List identity providers
+// @Description  List the OpenID Connect identity providers users can log in with
+// @Tags         认证
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Identity providers"
+// @Router       /auth/oidc/providers [get]
+func (h *AuthHandler) ListOIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.oidcService.Providers(),
+	})
+}
+
+// AuthorizeOIDC godoc
+// @Summary      Start identity provider login
+// @Description  Return the login page of an identity provider and the state to send back with the authorization code
+// @Tags         认证
+// @Produce      json
+// @Param        provider  path      string  true  "Identity provider name"
+// @Success      200       {object}  map[string]interface{}  "Authorization URL and state"
+// @Failure      404       {object}  errors.AppError         "Identity provider not found"
+// @Router       /auth/oidc/{provider}/authorize [get]
+func (h *AuthHandler) AuthorizeOIDC(c *gin.Context) {
+	ctx := c.Request.Context()
+	provider := secutils.SanitizeForLog(c.Param("provider"))
+
+	authorization, err := h.oidcService.Authorize(ctx, provider)
+	if err != nil {
+		c.Error(oidcError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    authorization,
+	})
+}
+
+// OIDCCallback godoc
+// @Summary      Complete identity provider login
+// @Description  Exchange the authorization code the identity provider sent the user back with for tokens; the first login links the identity to the user with its verified email or creates a user
+// @Tags         认证
+// @Accept       json
+// @Produce      json
+// @Param        provider  path      string                     true  "Identity provider name"
+// @Param        request   body      types.OIDCCallbackRequest  true  "Authorization code and state"
+// @Success      200       {object}  types.LoginResponse
+// @Failure      401       {object}  errors.AppError  "Login refused"
+// @Failure      403       {object}  errors.AppError  "Registration disabled"
+// @Failure      404       {object}  errors.AppError  "Identity provider not found"
+// @Router       /auth/oidc/{provider}/callback [post]
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	ctx := c.Request.Context()
+	provider := secutils.SanitizeForLog(c.Param("provider"))
+
+	var req types.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse OIDC callback parameters", err)
+		c.Error(errors.NewValidationError("Invalid login parameters").WithDetails(err.Error()))
+		return
+	}
+
+	response, err := h.oidcService.Login(ctx, provider, &req)
+	if err != nil {
+		c.Error(oidcError(err))
+		return
+	}
+	if !response.Success {
+		logger.Warnf(ctx, "OIDC login failed: %s", response.Message)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// OIDCTwoFactor godoc
+// @Summary      Complete identity provider login with two-factor authentication
+// @Description  Complete a login with an identity provider of a user with two-factor authentication with the token returned by the callback and a TOTP or recovery code
+// @Tags         认证
+// @Accept       json
+// @Produce      json
+// @Param        request  body      types.OIDCTwoFactorRequest  true  "Two-factor token and code"
+// @Success      200      {object}  types.LoginResponse
+// @Failure      401      {object}  errors.AppError  "Login refused"
+// @Router       /auth/oidc/two-factor [post]
+func (h *AuthHandler) OIDCTwoFactor(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req types.OIDCTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse OIDC two-factor parameters", err)
+		c.Error(errors.NewValidationError("Invalid login parameters").WithDetails(err.Error()))
+		return
+	}
+
+	response, err := h.oidcService.CompleteTwoFactor(ctx, &req)
+	if err != nil {
+		c.Error(oidcError(err))
+		return
+	}
+	if !response.Success {
+		logger.Warnf(ctx, "OIDC two-factor login failed: %s", response.Message)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// oidcError maps the errors of the identity provider login to API errors
+func oidcError(err error) *errors.AppError {
+	switch {
+	case goerrors.Is(err, service.ErrOIDCProviderNotFound):
+		return errors.NewNotFoundError("Identity provider not found").WithCode(errors.CodeOIDCProviderNotFound)
+	case goerrors.Is(err, service.ErrOIDCLoginFailed):
+		return errors.NewUnauthorizedError("Login failed").WithCode(errors.CodeOIDCLoginFailed).WithDetails(err.Error())
+	case goerrors.Is(err, service.ErrOIDCRegistrationDisabled):
+		return errors.NewForbiddenError("Registration is disabled").WithCode(errors.CodeRegistrationDisabled)
+	default:
+		return errors.NewInternalServerError("Login failed").WithDetails(err.Error())
+	}
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	goerrors "errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// SetupTwoFactor godoc
+// @Summary      Set up two-factor authentication
+// @Description  Generate a TOTP secret for an authenticator app; two-factor authentication is enabled once a code of the app is verified
+// @Tags         认证
+// @Produce      json
+// @Success      200  {object}  types.TwoFactorSetup
+// @Failure      409  {object}  errors.AppError  "Two-factor authentication already enabled"
+// @Security     Bearer
+// @Router       /auth/2fa/setup [post]
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	user, err := h.userService.GetCurrentUser(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get current user: %v", err)
+		c.Error(errors.NewUnauthorizedError("Failed to get user information").WithDetails(err.Error()))
+		return
+	}
+
+	setup, err := h.userService.SetupTwoFactor(ctx, user.ID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to set up two-factor authentication: %v", err)
+		c.Error(twoFactorError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    setup,
+	})
+}
+
+// VerifyTwoFactor godoc
+// @Summary      Enable two-factor authentication
+// @Description  Verify a code of the authenticator app set up with /auth/2fa/setup to enable two-factor authentication; the recovery codes are only returned here
+// @Tags         认证
+// @Accept       json
+// @Produce      json
+// @Param        request  body      types.TwoFactorVerifyRequest  true  "TOTP code"
+// @Success      200      {object}  types.TwoFactorRecoveryCodes
+// @Failure      400      {object}  errors.AppError  "Invalid code or no setup"
+// @Failure      409      {object}  errors.AppError  "Two-factor authentication already enabled"
+// @Security     Bearer
+// @Router       /auth/2fa/verify [post]
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req types.TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse two-factor verification request", err)
+		c.Error(errors.NewValidationError("Invalid two-factor verification request").WithDetails(err.Error()))
+		return
+	}
+
+	user, err := h.userService.GetCurrentUser(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get current user: %v", err)
+		c.Error(errors.NewUnauthorizedError("Failed to get user information").WithDetails(err.Error()))
+		return
+	}
+
+	codes, err := h.userService.EnableTwoFactor(ctx, user.ID, req.TOTPCode)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to enable two-factor authentication: %v", err)
+		c.Error(twoFactorError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    codes,
+	})
+}
+
+// DisableTwoFactor godoc
+// @Summary      Disable two-factor authentication
+// @Description  Disable two-factor authentication with the password and a TOTP or recovery code
+// @Tags         认证
+// @Accept       json
+// @Produce      json
+// @Param        request  body      types.TwoFactorDisableRequest  true  "Password and code"
+// @Success      200      {object}  map[string]interface{}  "Two-factor authentication disabled"
+// @Failure      400      {object}  errors.AppError         "Invalid password or code"
+// @Security     Bearer
+// @Router       /auth/2fa/disable [post]
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req types.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse two-factor disable request", err)
+		c.Error(errors.NewValidationError("Invalid two-factor disable request").WithDetails(err.Error()))
+		return
+	}
+
+	user, err := h.userService.GetCurrentUser(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get current user: %v", err)
+		c.Error(errors.NewUnauthorizedError("Failed to get user information").WithDetails(err.Error()))
+		return
+	}
+
+	if err := h.userService.DisableTwoFactor(ctx, user.ID, &req); err != nil {
+		logger.Warnf(ctx, "Failed to disable two-factor authentication: %v", err)
+		c.Error(twoFactorError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Regenerate recovery codes
+// @Description  Replace the recovery codes of the current user after verifying a TOTP code
+// @Tags         认证
+// @Accept       json
+// @Produce      json
+// @Param        request  body      types.TwoFactorVerifyRequest  true  "TOTP code"
+// @Success      200      {object}  types.TwoFactorRecoveryCodes
+// @Failure      400      {object}  errors.AppError  "Invalid code or two-factor authentication not enabled"
+// @Security     Bearer
+// @Router       /auth/2fa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req types.TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse recovery code request", err)
+		c.Error(errors.NewValidationError("Invalid recovery code request").WithDetails(err.Error()))
+		return
+	}
+
+	user, err := h.userService.GetCurrentUser(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get current user: %v", err)
+		c.Error(errors.NewUnauthorizedError("Failed to get user information").WithDetails(err.Error()))
+		return
+	}
+
+	codes, err := h.userService.RegenerateRecoveryCodes(ctx, user.ID, req.TOTPCode)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to regenerate recovery codes: %v", err)
+		c.Error(twoFactorError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    codes,
+	})
+}
+
+// twoFactorError maps the errors of the two-factor authentication to API errors
+func twoFactorError(err error) *errors.AppError {
+	switch {
+	case goerrors.Is(err, service.ErrInvalidTwoFactorCode):
+		return errors.NewBadRequestError("Invalid two-factor authentication code").
+			WithCode(errors.CodeTwoFactorInvalidCode)
+	case goerrors.Is(err, service.ErrTwoFactorLocked):
+		return errors.NewTooManyRequestsError("Too many failed two-factor authentication attempts, try again later").
+			WithCode(errors.CodeTwoFactorLocked)
+	case goerrors.Is(err, service.ErrInvalidPassword):
+		return errors.NewBadRequestError("Invalid password").WithCode(errors.CodeInvalidPassword)
+	case goerrors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		return errors.NewConflictError("Two-factor authentication is already enabled").
+			WithCode(errors.CodeTwoFactorEnabled)
+	case goerrors.Is(err, service.ErrTwoFactorNotEnabled), goerrors.Is(err, service.ErrTwoFactorNotSetUp):
+		return errors.NewBadRequestError(err.Error()).WithCode(errors.CodeTwoFactorNotEnabled)
+	case goerrors.Is(err, service.ErrTwoFactorNotConfigured):
+		return errors.NewServiceUnavailableError("Two-factor authentication is not configured")
+	default:
+		return errors.FromError(err)
+	}
+}
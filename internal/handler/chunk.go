@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/Tencent/WeKnora/internal/application/service"
 	"github.com/Tencent/WeKnora/internal/errors"
@@ -14,12 +15,13 @@ import (
 
 // ChunkHandler defines HTTP handlers for chunk operations
 type ChunkHandler struct {
-	service interfaces.ChunkService
+	service   interfaces.ChunkService
+	kbService interfaces.KnowledgeBaseService
 }
 
 // NewChunkHandler creates a new chunk handler
-func NewChunkHandler(service interfaces.ChunkService) *ChunkHandler {
-	return &ChunkHandler{service: service}
+func NewChunkHandler(service interfaces.ChunkService, kbService interfaces.KnowledgeBaseService) *ChunkHandler {
+	return &ChunkHandler{service: service, kbService: kbService}
 }
 
 // GetChunkByIDOnly godoc
@@ -61,11 +63,11 @@ func (h *ChunkHandler) GetChunkByIDOnly(c *gin.Context) {
 	if err != nil {
 		if err == service.ErrChunkNotFound {
 			logger.Warnf(ctx, "Chunk not found, chunk ID: %s", chunkID)
-			c.Error(errors.NewNotFoundError("Chunk not found"))
+			c.Error(errors.NewNotFoundError("Chunk not found").WithCode(errors.CodeChunkNotFound))
 			return
 		}
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -91,6 +93,83 @@ func (h *ChunkHandler) GetChunkByIDOnly(c *gin.Context) {
 	})
 }
 
+// GetSimilarChunks godoc
+// @Summary      查找相似分块
+// @Description  按分块的向量检索同一知识库中最相似的分块（不含该分块本身）
+// @Tags         分块管理
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string  true   "分块ID"
+// @Param        count      query     int     false  "返回数量"  default(10)
+// @Param        scope      query     string  false  "检索范围：knowledge_base、document 或 other_documents"
+// @Param        threshold  query     number  false  "最低相似度"
+// @Success      200        {object}  map[string]interface{}  "相似分块列表"
+// @Failure      400        {object}  errors.AppError         "请求参数错误"
+// @Failure      404        {object}  errors.AppError         "分块不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /chunks/by-id/{id}/similar [get]
+func (h *ChunkHandler) GetSimilarChunks(c *gin.Context) {
+	ctx := c.Request.Context()
+	logger.Info(ctx, "Start retrieving similar chunks")
+
+	chunkID := secutils.SanitizeForLog(c.Param("id"))
+	if chunkID == "" {
+		logger.Error(ctx, "Chunk ID is empty")
+		c.Error(errors.NewBadRequestError("Chunk ID cannot be empty"))
+		return
+	}
+
+	var params types.SimilarChunkParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		logger.Error(ctx, "Failed to parse query parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid query parameters").WithDetails(err.Error()))
+		return
+	}
+	if err := params.Validate(); err != nil {
+		logger.Error(ctx, "Invalid similar chunk parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid query parameters").WithDetails(err.Error()))
+		return
+	}
+
+	tenantID, exists := c.Get(types.TenantIDContextKey.String())
+	if !exists {
+		logger.Error(ctx, "Failed to get tenant ID")
+		c.Error(errors.NewUnauthorizedError("Unauthorized"))
+		return
+	}
+
+	chunk, err := h.service.GetChunkByID(ctx, chunkID)
+	if err != nil {
+		if err == service.ErrChunkNotFound {
+			logger.Warnf(ctx, "Chunk not found, chunk ID: %s", chunkID)
+			c.Error(errors.NewNotFoundError("Chunk not found").WithCode(errors.CodeChunkNotFound))
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+	if chunk.TenantID != tenantID.(uint64) {
+		logger.Warnf(ctx, "Tenant has no permission to access chunk, chunk ID: %s", chunkID)
+		c.Error(errors.NewForbiddenError("No permission to access this chunk"))
+		return
+	}
+
+	results, err := h.kbService.SimilarChunks(ctx, chunk, params)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	logger.Infof(ctx, "Similar chunks retrieved, chunk ID: %s, result count: %d", chunkID, len(results))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    results,
+	})
+}
+
 // ListKnowledgeChunks godoc
 // @Summary      获取知识分块列表
 // @Description  获取指定知识下的所有分块列表，支持分页
@@ -100,6 +179,8 @@ func (h *ChunkHandler) GetChunkByIDOnly(c *gin.Context) {
 // @Param        knowledge_id  path      string  true   "知识ID"
 // @Param        page          query     int     false  "页码"  default(1)
 // @Param        page_size     query     int     false  "每页数量"  default(10)
+// @Param        chunk_type    query     string  false  "分块类型：text（默认）或 image"
+// @Param        keyword       query     string  false  "按分块内容子串过滤"
 // @Success      200           {object}  map[string]interface{}  "分块列表"
 // @Failure      400           {object}  errors.AppError         "请求参数错误"
 // @Security     Bearer
@@ -133,29 +214,46 @@ func (h *ChunkHandler) ListKnowledgeChunks(c *gin.Context) {
 		pagination.PageSize = 100
 	}
 
-	chunkType := []types.ChunkType{types.ChunkTypeText}
+	// Filter by chunk type: text chunks by default, or the OCR and caption chunks of images
+	var chunkType []types.ChunkType
+	switch c.Query("chunk_type") {
+	case "", "text":
+		chunkType = []types.ChunkType{types.ChunkTypeText}
+	case "image":
+		chunkType = []types.ChunkType{types.ChunkTypeImageOCR, types.ChunkTypeImageCaption}
+	default:
+		c.Error(errors.NewBadRequestError("chunk_type must be text or image"))
+		return
+	}
+	keyword := strings.TrimSpace(c.Query("keyword"))
 
 	// Use pagination for query
-	result, err := h.service.ListPagedChunksByKnowledgeID(ctx, knowledgeID, &pagination, chunkType)
+	result, err := h.service.ListPagedChunksByKnowledgeID(ctx, knowledgeID, &pagination, chunkType, keyword)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
 	// 对 chunk 内容进行安全清理
-	for _, chunk := range result.Data.([]*types.Chunk) {
+	chunks, _ := result.Data.([]*types.Chunk)
+	for _, chunk := range chunks {
 		if chunk.Content != "" {
 			chunk.Content = secutils.SanitizeForDisplay(chunk.Content)
 		}
 	}
+	// Pages past the last one are empty, not null
+	if chunks == nil {
+		chunks = []*types.Chunk{}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":   true,
-		"data":      result.Data,
+		"data":      chunks,
 		"total":     result.Total,
 		"page":      result.Page,
 		"page_size": result.PageSize,
+		"has_more":  result.HasMore(),
 	})
 }
 
@@ -203,10 +301,10 @@ func (h *ChunkHandler) validateAndGetChunk(c *gin.Context) (*types.Chunk, string
 	if err != nil {
 		if err == service.ErrChunkNotFound {
 			logger.Warnf(ctx, "Chunk not found, knowledge ID: %s, chunk ID: %s", knowledgeID, id)
-			return nil, knowledgeID, errors.NewNotFoundError("Chunk not found")
+			return nil, knowledgeID, errors.NewNotFoundError("Chunk not found").WithCode(errors.CodeChunkNotFound)
 		}
 		logger.ErrorWithFields(ctx, err, nil)
-		return nil, knowledgeID, errors.NewInternalServerError(err.Error())
+		return nil, knowledgeID, errors.FromError(err)
 	}
 
 	// Validate tenant ID
@@ -266,7 +364,7 @@ func (h *ChunkHandler) UpdateChunk(c *gin.Context) {
 
 	if err := h.service.UpdateChunk(ctx, chunk); err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -305,7 +403,7 @@ func (h *ChunkHandler) DeleteChunk(c *gin.Context) {
 
 	if err := h.service.DeleteChunk(ctx, chunk.ID); err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -342,7 +440,7 @@ func (h *ChunkHandler) DeleteChunksByKnowledgeID(c *gin.Context) {
 	err := h.service.DeleteChunksByKnowledgeID(ctx, knowledgeID)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -399,11 +497,11 @@ func (h *ChunkHandler) DeleteGeneratedQuestion(c *gin.Context) {
 	if err != nil {
 		if err == service.ErrChunkNotFound {
 			logger.Warnf(ctx, "Chunk not found, chunk ID: %s", chunkID)
-			c.Error(errors.NewNotFoundError("Chunk not found"))
+			c.Error(errors.NewNotFoundError("Chunk not found").WithCode(errors.CodeChunkNotFound))
 			return
 		}
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/middleware"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeChunkService pages through the chunks of a single knowledge
+type fakeChunkService struct {
+	interfaces.ChunkService
+	chunks []*types.Chunk
+}
+
+func (f *fakeChunkService) ListPagedChunksByKnowledgeID(_ context.Context,
+	_ string, page *types.Pagination, chunkType []types.ChunkType, keyword string,
+) (*types.PageResult, error) {
+	var matched []*types.Chunk
+	for _, chunk := range f.chunks {
+		if slices.Contains(chunkType, chunk.ChunkType) && strings.Contains(chunk.Content, keyword) {
+			matched = append(matched, chunk)
+		}
+	}
+	var data []*types.Chunk
+	if offset := (page.GetPage() - 1) * page.GetPageSize(); offset < len(matched) {
+		data = matched[offset:min(offset+page.GetPageSize(), len(matched))]
+	}
+	return types.NewPageResult(int64(len(matched)), page, data), nil
+}
+
+func TestListKnowledgeChunks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	chunks := make([]*types.Chunk, 0, 27)
+	for i := range 25 {
+		chunks = append(chunks, &types.Chunk{ID: fmt.Sprintf("text-%d", i), ChunkType: types.ChunkTypeText,
+			Content: fmt.Sprintf("passage %d about comets", i)})
+	}
+	chunks = append(chunks,
+		&types.Chunk{ID: "ocr", ChunkType: types.ChunkTypeImageOCR, Content: "text read in the image"},
+		&types.Chunk{ID: "caption", ChunkType: types.ChunkTypeImageCaption, Content: "a comet tail"},
+	)
+
+	tests := []struct {
+		name        string
+		chunks      []*types.Chunk
+		query       string
+		wantStatus  int
+		wantIDs     []string
+		wantTotal   int64
+		wantHasMore bool
+	}{
+		{
+			name:       "empty knowledge",
+			wantStatus: http.StatusOK,
+			wantIDs:    []string{},
+		},
+		{
+			name:        "default page",
+			chunks:      chunks,
+			wantStatus:  http.StatusOK,
+			wantIDs:     []string{"text-0", "text-1", "text-2", "text-3", "text-4", "text-5", "text-6", "text-7", "text-8", "text-9"},
+			wantTotal:   25,
+			wantHasMore: true,
+		},
+		{
+			name:       "last page",
+			chunks:     chunks,
+			query:      "page=3&page_size=10",
+			wantStatus: http.StatusOK,
+			wantIDs:    []string{"text-20", "text-21", "text-22", "text-23", "text-24"},
+			wantTotal:  25,
+		},
+		{
+			name:       "page out of range",
+			chunks:     chunks,
+			query:      "page=9&page_size=10",
+			wantStatus: http.StatusOK,
+			wantIDs:    []string{},
+			wantTotal:  25,
+		},
+		{
+			name:       "image chunks",
+			chunks:     chunks,
+			query:      "chunk_type=image",
+			wantStatus: http.StatusOK,
+			wantIDs:    []string{"ocr", "caption"},
+			wantTotal:  2,
+		},
+		{
+			name:       "content keyword",
+			chunks:     chunks,
+			query:      "keyword=passage+7+about",
+			wantStatus: http.StatusOK,
+			wantIDs:    []string{"text-7"},
+			wantTotal:  1,
+		},
+		{
+			name:       "unknown chunk type",
+			chunks:     chunks,
+			query:      "chunk_type=table",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "page size over the limit",
+			chunks:     chunks,
+			query:      "page_size=500",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewChunkHandler(&fakeChunkService{chunks: tt.chunks}, &fakeKnowledgeBaseService{})
+			router := gin.New()
+			router.Use(middleware.ErrorHandler())
+			router.GET("/chunks/:knowledge_id", h.ListKnowledgeChunks)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/chunks/k-1?"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data    []*types.Chunk `json:"data"`
+				Total   int64          `json:"total"`
+				HasMore bool           `json:"has_more"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Data == nil {
+				t.Fatalf("data is null, want a list: %s", rec.Body.String())
+			}
+			ids := make([]string, 0, len(resp.Data))
+			for _, chunk := range resp.Data {
+				ids = append(ids, chunk.ID)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("chunks = %v, want %v", ids, tt.wantIDs)
+			}
+			if resp.Total != tt.wantTotal || resp.HasMore != tt.wantHasMore {
+				t.Errorf("total = %d, has_more = %v, want %d, %v", resp.Total, resp.HasMore, tt.wantTotal, tt.wantHasMore)
+			}
+		})
+	}
+}
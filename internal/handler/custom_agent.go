@@ -1,7 +1,9 @@
 package handler
 
 import (
+	goerrors "errors"
 	"net/http"
+	"strconv"
 
 	"github.com/Tencent/WeKnora/internal/application/service"
 	"github.com/Tencent/WeKnora/internal/errors"
@@ -14,13 +16,18 @@ import (
 
 // CustomAgentHandler defines the HTTP handler for custom agent operations
 type CustomAgentHandler struct {
-	service interfaces.CustomAgentService
+	service           interfaces.CustomAgentService
+	mcpServiceService interfaces.MCPServiceService
 }
 
 // NewCustomAgentHandler creates a new custom agent handler instance
-func NewCustomAgentHandler(service interfaces.CustomAgentService) *CustomAgentHandler {
+func NewCustomAgentHandler(
+	service interfaces.CustomAgentService,
+	mcpServiceService interfaces.MCPServiceService,
+) *CustomAgentHandler {
 	return &CustomAgentHandler{
-		service: service,
+		service:           service,
+		mcpServiceService: mcpServiceService,
 	}
 }
 
@@ -84,7 +91,12 @@ func (h *CustomAgentHandler) CreateAgent(c *gin.Context) {
 			c.Error(errors.NewBadRequestError(err.Error()))
 			return
 		}
-		c.Error(errors.NewInternalServerError(err.Error()))
+		var templateErr *types.PromptTemplateError
+		if goerrors.As(err, &templateErr) {
+			c.Error(errors.NewValidationError(templateErr.Error()).WithDetails(templateErr))
+			return
+		}
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -126,10 +138,10 @@ func (h *CustomAgentHandler) GetAgent(c *gin.Context) {
 			"agent_id": id,
 		})
 		if err == service.ErrAgentNotFound {
-			c.Error(errors.NewNotFoundError("Agent not found"))
+			c.Error(errors.NewNotFoundError("Agent not found").WithCode(errors.CodeAgentNotFound))
 			return
 		}
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -157,7 +169,7 @@ func (h *CustomAgentHandler) ListAgents(c *gin.Context) {
 	agents, err := h.service.ListAgents(ctx)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -222,13 +234,18 @@ func (h *CustomAgentHandler) UpdateAgent(c *gin.Context) {
 		})
 		switch err {
 		case service.ErrAgentNotFound:
-			c.Error(errors.NewNotFoundError("Agent not found"))
+			c.Error(errors.NewNotFoundError("Agent not found").WithCode(errors.CodeAgentNotFound))
 		case service.ErrCannotModifyBuiltin:
-			c.Error(errors.NewForbiddenError("Cannot modify built-in agent"))
+			c.Error(errors.NewForbiddenError("Cannot modify built-in agent").WithCode(errors.CodeAgentBuiltinReadOnly))
 		case service.ErrAgentNameRequired:
 			c.Error(errors.NewBadRequestError(err.Error()))
 		default:
-			c.Error(errors.NewInternalServerError(err.Error()))
+			var templateErr *types.PromptTemplateError
+			if goerrors.As(err, &templateErr) {
+				c.Error(errors.NewValidationError(templateErr.Error()).WithDetails(templateErr))
+				return
+			}
+			c.Error(errors.FromError(err))
 		}
 		return
 	}
@@ -277,11 +294,11 @@ func (h *CustomAgentHandler) DeleteAgent(c *gin.Context) {
 		})
 		switch err {
 		case service.ErrAgentNotFound:
-			c.Error(errors.NewNotFoundError("Agent not found"))
+			c.Error(errors.NewNotFoundError("Agent not found").WithCode(errors.CodeAgentNotFound))
 		case service.ErrCannotDeleteBuiltin:
-			c.Error(errors.NewForbiddenError("Cannot delete built-in agent"))
+			c.Error(errors.NewForbiddenError("Cannot delete built-in agent").WithCode(errors.CodeAgentBuiltinReadOnly))
 		default:
-			c.Error(errors.NewInternalServerError(err.Error()))
+			c.Error(errors.FromError(err))
 		}
 		return
 	}
@@ -329,9 +346,9 @@ func (h *CustomAgentHandler) CopyAgent(c *gin.Context) {
 		})
 		switch err {
 		case service.ErrAgentNotFound:
-			c.Error(errors.NewNotFoundError("Agent not found"))
+			c.Error(errors.NewNotFoundError("Agent not found").WithCode(errors.CodeAgentNotFound))
 		default:
-			c.Error(errors.NewInternalServerError(err.Error()))
+			c.Error(errors.FromError(err))
 		}
 		return
 	}
@@ -344,6 +361,269 @@ func (h *CustomAgentHandler) CopyAgent(c *gin.Context) {
 	})
 }
 
+// RollbackAgentRequest defines the request body for rolling back an agent
+type RollbackAgentRequest struct {
+	// Version to restore; 0 restores the version published before the current one
+	Version int `json:"version"`
+}
+
+// ListAgentVersions godoc
+// @Summary      List agent versions
+// @Description  List all versions of a custom agent, newest first
+// @Tags         Agent
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Agent ID"
+// @Success      200  {object}  map[string]interface{}  "Version list"
+// @Failure      400  {object}  errors.AppError         "Built-in agents do not support versioning"
+// @Failure      404  {object}  errors.AppError         "Agent not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/versions [get]
+func (h *CustomAgentHandler) ListAgentVersions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Agent ID is empty")
+		c.Error(errors.NewBadRequestError("Agent ID cannot be empty"))
+		return
+	}
+
+	versions, err := h.service.ListVersions(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"agent_id": id,
+		})
+		h.handleVersionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    versions,
+	})
+}
+
+// DiffAgentVersions godoc
+// @Summary      Diff agent versions
+// @Description  Compare two versions of a custom agent field by field
+// @Tags         Agent
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string  true  "Agent ID"
+// @Param        from  query     int     true  "Base version"
+// @Param        to    query     int     true  "Target version"
+// @Success      200   {object}  map[string]interface{}  "Version diff"
+// @Failure      400   {object}  errors.AppError         "Invalid request parameters"
+// @Failure      404   {object}  errors.AppError         "Agent or version not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/versions/diff [get]
+func (h *CustomAgentHandler) DiffAgentVersions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Agent ID is empty")
+		c.Error(errors.NewBadRequestError("Agent ID cannot be empty"))
+		return
+	}
+
+	fromVersion, err := strconv.Atoi(c.Query("from"))
+	if err != nil || fromVersion <= 0 {
+		c.Error(errors.NewBadRequestError("Invalid 'from' version"))
+		return
+	}
+	toVersion, err := strconv.Atoi(c.Query("to"))
+	if err != nil || toVersion <= 0 {
+		c.Error(errors.NewBadRequestError("Invalid 'to' version"))
+		return
+	}
+
+	diff, err := h.service.DiffVersions(ctx, id, fromVersion, toVersion)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"agent_id": id,
+		})
+		h.handleVersionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    diff,
+	})
+}
+
+// PublishAgentVersion godoc
+// @Summary      Publish agent version
+// @Description  Promote a version of a custom agent to serve live traffic
+// @Tags         Agent
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "Agent ID"
+// @Param        version  path      int     true  "Version number"
+// @Success      200      {object}  map[string]interface{}  "Published agent"
+// @Failure      400      {object}  errors.AppError         "Invalid request parameters"
+// @Failure      404      {object}  errors.AppError         "Agent or version not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/versions/{version}/publish [post]
+func (h *CustomAgentHandler) PublishAgentVersion(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Agent ID is empty")
+		c.Error(errors.NewBadRequestError("Agent ID cannot be empty"))
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version <= 0 {
+		c.Error(errors.NewBadRequestError("Invalid version"))
+		return
+	}
+
+	logger.Infof(ctx, "Publishing agent version, ID: %s, version: %d", id, version)
+
+	agent, err := h.service.PublishVersion(ctx, id, version)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"agent_id": id,
+			"version":  version,
+		})
+		h.handleVersionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    agent,
+	})
+}
+
+// RollbackAgent godoc
+// @Summary      Roll back agent
+// @Description  Revert a custom agent to a previously published version
+// @Tags         Agent
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                true   "Agent ID"
+// @Param        request  body      RollbackAgentRequest  false  "Target version (defaults to the previously published version)"
+// @Success      200      {object}  map[string]interface{}  "Restored agent"
+// @Failure      400      {object}  errors.AppError         "Invalid request parameters"
+// @Failure      404      {object}  errors.AppError         "Agent or version not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/rollback [post]
+func (h *CustomAgentHandler) RollbackAgent(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Agent ID is empty")
+		c.Error(errors.NewBadRequestError("Agent ID cannot be empty"))
+		return
+	}
+
+	// The body is optional; an empty body rolls back to the previous published version
+	var req RollbackAgentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.Error(ctx, "Failed to parse request parameters", err)
+			c.Error(errors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
+			return
+		}
+	}
+	if req.Version < 0 {
+		c.Error(errors.NewBadRequestError("Invalid version"))
+		return
+	}
+
+	agent, err := h.service.RollbackAgent(ctx, id, req.Version)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"agent_id": id,
+			"version":  req.Version,
+		})
+		h.handleVersionError(c, err)
+		return
+	}
+
+	logger.Infof(ctx, "Agent rolled back successfully, ID: %s, version: %d", id, agent.PublishedVersion)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    agent,
+	})
+}
+
+// GetAgentMCPStatus godoc
+// @Summary      Get agent MCP status
+// @Description  Report the problems of the MCP services used by the agent, from their last health check: services
+// @Description  deleted, disabled or unreachable, tools no longer advertised, with an invalid schema, or whose schema
+// @Description  changed since the agent was last saved
+// @Tags         Agent
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Agent ID"
+// @Success      200  {object}  map[string]interface{}  "Agent MCP status"
+// @Failure      404  {object}  errors.AppError         "Agent not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/mcp-status [get]
+func (h *CustomAgentHandler) GetAgentMCPStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Agent ID is empty")
+		c.Error(errors.NewBadRequestError("Agent ID cannot be empty"))
+		return
+	}
+
+	agent, err := h.service.GetAgentByID(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"agent_id": id,
+		})
+		if err == service.ErrAgentNotFound {
+			c.Error(errors.NewNotFoundError("Agent not found").WithCode(errors.CodeAgentNotFound))
+			return
+		}
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	status, err := h.mcpServiceService.GetAgentMCPStatus(ctx, c.GetUint64(types.TenantIDContextKey.String()), agent)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"agent_id": id,
+		})
+		c.Error(errors.NewInternalServerError("Failed to get agent MCP status: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// handleVersionError maps agent versioning errors to HTTP errors
+func (h *CustomAgentHandler) handleVersionError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrAgentNotFound:
+		c.Error(errors.NewNotFoundError("Agent not found").WithCode(errors.CodeAgentNotFound))
+	case service.ErrAgentVersionNotFound:
+		c.Error(errors.NewNotFoundError("Agent version not found").WithCode(errors.CodeAgentNotFound))
+	case service.ErrBuiltinAgentNotVersioned, service.ErrAgentVersionNotPublished, service.ErrNoRollbackTarget:
+		c.Error(errors.NewBadRequestError(err.Error()))
+	default:
+		c.Error(errors.FromError(err))
+	}
+}
+
 // GetPlaceholders godoc
 // @Summary      Get placeholder definitions
 // @Description  Get all available prompt placeholder definitions, grouped by field type
@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
@@ -27,6 +31,9 @@ type EvaluationRequest struct {
 	KnowledgeBaseID string `json:"knowledge_base_id"` // ID of knowledge base to use
 	ChatModelID     string `json:"chat_id"`           // ID of chat model to use
 	RerankModelID   string `json:"rerank_id"`         // ID of rerank model to use
+	// Optional concurrency and rate limits, capped by the server configuration
+	MaxConcurrency    int `json:"max_concurrency"`
+	RequestsPerMinute int `json:"requests_per_minute"`
 }
 
 // Evaluation godoc
@@ -73,10 +80,14 @@ func (e *EvaluationHandler) Evaluation(c *gin.Context) {
 		secutils.SanitizeForLog(request.KnowledgeBaseID),
 		secutils.SanitizeForLog(request.ChatModelID),
 		secutils.SanitizeForLog(request.RerankModelID),
+		types.EvaluationLimits{
+			MaxConcurrency:    request.MaxConcurrency,
+			RequestsPerMinute: request.RequestsPerMinute,
+		},
 	)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -119,7 +130,7 @@ func (e *EvaluationHandler) GetEvaluationResult(c *gin.Context) {
 	result, err := e.evaluationService.EvaluationResult(ctx, secutils.SanitizeForLog(request.TaskID))
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -129,3 +140,158 @@ func (e *EvaluationHandler) GetEvaluationResult(c *gin.Context) {
 		"data":    result,
 	})
 }
+
+// PauseEvaluation godoc
+// @Summary      暂停评估
+// @Description  暂停正在运行的评估任务，已开始的用例会继续完成，不再启动新的用例
+// @Tags         评估
+// @Produce      json
+// @Param        task_id  path      string  true  "评估任务ID"
+// @Success      200      {object}  map[string]interface{}  "评估任务"
+// @Failure      400      {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /evaluation/{task_id}/pause [post]
+func (e *EvaluationHandler) PauseEvaluation(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+	logger.Infof(ctx, "Pausing evaluation task: %s", taskID)
+
+	detail, err := e.evaluationService.PauseEvaluation(ctx, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    detail,
+	})
+}
+
+// ResumeEvaluation godoc
+// @Summary      恢复评估
+// @Description  恢复已暂停的评估任务
+// @Tags         评估
+// @Produce      json
+// @Param        task_id  path      string  true  "评估任务ID"
+// @Success      200      {object}  map[string]interface{}  "评估任务"
+// @Failure      400      {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /evaluation/{task_id}/resume [post]
+func (e *EvaluationHandler) ResumeEvaluation(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+	logger.Infof(ctx, "Resuming evaluation task: %s", taskID)
+
+	detail, err := e.evaluationService.ResumeEvaluation(ctx, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    detail,
+	})
+}
+
+// evaluationStreamHeartbeat is how often a comment is written to an idle evaluation stream
+const evaluationStreamHeartbeat = 15 * time.Second
+
+// StreamEvaluationResult godoc
+// @Summary      流式获取评估结果
+// @Description  以SSE流的形式推送每个评估用例的结果和累计指标，最后推送汇总事件。支持通过Last-Event-ID断点续传
+// @Tags         评估
+// @Produce      text/event-stream
+// @Param        task_id        path      string  true   "评估任务ID"
+// @Param        Last-Event-ID  header    int     false  "最后收到的事件序号"
+// @Param        last_event_id  query     int     false  "最后收到的事件序号（无法设置请求头时使用）"
+// @Success      200            {object}  types.EvaluationEvent  "评估事件流"
+// @Failure      400            {object}  errors.AppError        "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /evaluation/stream/{task_id} [get]
+func (e *EvaluationHandler) StreamEvaluationResult(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	afterSeq := 0
+	if lastEventID != "" {
+		seq, err := strconv.Atoi(lastEventID)
+		if err != nil || seq < 0 {
+			c.Error(errors.NewBadRequestError("Invalid last event ID").WithDetails(lastEventID))
+			return
+		}
+		afterSeq = seq
+	}
+	logger.Infof(ctx, "Start streaming evaluation result, task ID: %s, after event: %d", taskID, afterSeq)
+
+	// Check the task before switching to an event stream so errors are returned as JSON
+	events, wait, err := e.evaluationService.EvaluationEvents(ctx, taskID, afterSeq)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache, no-transform")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(evaluationStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				logger.Errorf(ctx, "Failed to marshal evaluation event: %v", err)
+				return
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data); err != nil {
+				logger.Warnf(ctx, "Evaluation stream closed by client, task ID: %s", taskID)
+				return
+			}
+			afterSeq = event.Seq
+		}
+		events = nil
+		c.Writer.Flush()
+
+		// The summary was delivered, the stream is complete
+		if wait == nil {
+			logger.Infof(ctx, "Evaluation stream completed, task ID: %s", taskID)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Infof(ctx, "Evaluation stream disconnected, task ID: %s, last event: %d", taskID, afterSeq)
+			return
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+			continue
+		case <-wait:
+		}
+
+		events, wait, err = e.evaluationService.EvaluationEvents(ctx, taskID, afterSeq)
+		if err != nil {
+			logger.ErrorWithFields(ctx, err, nil)
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/middleware"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeEvaluationStream serves the events of one evaluation task
+type fakeEvaluationStream struct {
+	interfaces.EvaluationService
+
+	mu     sync.Mutex
+	events []*types.EvaluationEvent
+	notify chan struct{}
+	// afterSeqs records the sequence numbers the handler resumed from
+	afterSeqs []int
+	// connected is closed when the handler first reads the stream
+	connected chan struct{}
+}
+
+func (f *fakeEvaluationStream) EvaluationEvents(
+	_ context.Context, _ string, afterSeq int,
+) ([]*types.EvaluationEvent, <-chan struct{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.afterSeqs) == 0 {
+		close(f.connected)
+	}
+	f.afterSeqs = append(f.afterSeqs, afterSeq)
+	var wait <-chan struct{}
+	if f.notify != nil {
+		wait = f.notify
+	}
+	return slices.Clone(f.events[min(afterSeq, len(f.events)):]), wait, nil
+}
+
+// publish appends an event and wakes up the stream, the summary ends it
+func (f *fakeEvaluationStream) publish(eventType string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, &types.EvaluationEvent{Seq: len(f.events) + 1, Type: eventType})
+	close(f.notify)
+	f.notify = nil
+	if eventType != types.EvaluationEventSummary {
+		f.notify = make(chan struct{})
+	}
+}
+
+var sseEventID = regexp.MustCompile(`(?m)^id: (\d+)\nevent: (\w+)$`)
+
+func TestStreamEvaluationResult(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		header      string
+		query       string
+		live        bool
+		wantStatus  int
+		wantIDs     []string
+		wantResumed int
+	}{
+		{name: "finished task", wantStatus: http.StatusOK, wantIDs: []string{"1", "2", "3"}},
+		{name: "resume from Last-Event-ID", header: "2", wantStatus: http.StatusOK, wantIDs: []string{"3"}, wantResumed: 2},
+		{name: "resume from query", query: "?last_event_id=1", wantStatus: http.StatusOK, wantIDs: []string{"2", "3"}, wantResumed: 1},
+		{name: "header wins over query", header: "3", query: "?last_event_id=1", wantStatus: http.StatusOK, wantResumed: 3},
+		{name: "live task", live: true, wantStatus: http.StatusOK, wantIDs: []string{"1", "2", "3"}},
+		{name: "invalid event ID", header: "abc", wantStatus: http.StatusBadRequest},
+		{name: "negative event ID", query: "?last_event_id=-1", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeEvaluationStream{notify: make(chan struct{}), connected: make(chan struct{})}
+			service.publish(types.EvaluationEventCase)
+			if tt.live {
+				// The rest of the run is published while the client is connected
+				go func() {
+					<-service.connected
+					service.publish(types.EvaluationEventCase)
+					service.publish(types.EvaluationEventSummary)
+				}()
+			} else {
+				service.publish(types.EvaluationEventCase)
+				service.publish(types.EvaluationEventSummary)
+			}
+
+			router := gin.New()
+			router.Use(middleware.ErrorHandler())
+			router.GET("/evaluation/stream/:task_id", NewEvaluationHandler(service).StreamEvaluationResult)
+			req := httptest.NewRequest(http.MethodGet, "/evaluation/stream/task-1"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Last-Event-ID", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var ids []string
+			for _, match := range sseEventID.FindAllStringSubmatch(rec.Body.String(), -1) {
+				ids = append(ids, match[1])
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("event IDs = %v, want %v:\n%s", ids, tt.wantIDs, rec.Body.String())
+			}
+			if resumed := service.afterSeqsSnapshot()[0]; resumed != tt.wantResumed {
+				t.Errorf("resumed after %d, want %d", resumed, tt.wantResumed)
+			}
+		})
+	}
+}
+
+func (f *fakeEvaluationStream) afterSeqsSnapshot() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.afterSeqs)
+}
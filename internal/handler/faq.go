@@ -16,11 +16,14 @@ import (
 // FAQHandler handles FAQ knowledge base operations.
 type FAQHandler struct {
 	knowledgeService interfaces.KnowledgeService
+	taskWebhooks     interfaces.TaskWebhookService
 }
 
 // NewFAQHandler creates a new FAQ handler
-func NewFAQHandler(knowledgeService interfaces.KnowledgeService) *FAQHandler {
-	return &FAQHandler{knowledgeService: knowledgeService}
+func NewFAQHandler(knowledgeService interfaces.KnowledgeService,
+	taskWebhooks interfaces.TaskWebhookService,
+) *FAQHandler {
+	return &FAQHandler{knowledgeService: knowledgeService, taskWebhooks: taskWebhooks}
 }
 
 // ListEntries godoc
@@ -121,14 +124,16 @@ func (h *FAQHandler) UpsertEntries(c *gin.Context) {
 
 // CreateEntry godoc
 // @Summary      创建单个FAQ条目
-// @Description  同步创建单个FAQ条目
+// @Description  同步创建单个FAQ条目，与已有条目重复时按 duplicate_policy 处理
 // @Tags         FAQ管理
 // @Accept       json
 // @Produce      json
-// @Param        id       path      string                true  "知识库ID"
-// @Param        request  body      types.FAQEntryPayload true  "FAQ条目"
-// @Success      200      {object}  map[string]interface{}  "创建的FAQ条目"
-// @Failure      400      {object}  errors.AppError         "请求参数错误"
+// @Param        id                   path      string                true   "知识库ID"
+// @Param        duplicate_policy     query     string                false  "重复处理策略：error（默认）、merge_into_existing、allow"
+// @Param        duplicate_threshold  query     number                false  "相似重复检测阈值(0-1)，0 仅检测完全重复"
+// @Param        request              body      types.FAQEntryPayload true   "FAQ条目"
+// @Success      200                  {object}  map[string]interface{}  "创建（或合并到）的FAQ条目及重复判定"
+// @Failure      400                  {object}  errors.AppError         "请求参数错误"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /knowledge-bases/{id}/faq/entry [post]
@@ -141,7 +146,23 @@ func (h *FAQHandler) CreateEntry(c *gin.Context) {
 		return
 	}
 
-	entry, err := h.knowledgeService.CreateFAQEntry(ctx, secutils.SanitizeForLog(c.Param("id")), &req)
+	options := types.FAQDuplicateOptions{
+		DuplicatePolicy: types.FAQDuplicatePolicy(c.Query("duplicate_policy")),
+	}
+	if thresholdStr := c.Query("duplicate_threshold"); thresholdStr != "" {
+		threshold, err := strconv.ParseFloat(thresholdStr, 64)
+		if err != nil {
+			c.Error(errors.NewBadRequestError("duplicate_threshold 必须是数字"))
+			return
+		}
+		options.DuplicateThreshold = threshold
+	}
+	if err := options.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	entry, decision, err := h.knowledgeService.CreateFAQEntry(ctx, secutils.SanitizeForLog(c.Param("id")), &req, options)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
 		c.Error(err)
@@ -149,8 +170,9 @@ func (h *FAQHandler) CreateEntry(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    entry,
+		"success":   true,
+		"data":      entry,
+		"duplicate": decision,
 	})
 }
 
@@ -355,13 +377,17 @@ func (h *FAQHandler) SearchFAQ(c *gin.Context) {
 
 // ExportEntries godoc
 // @Summary      导出FAQ条目
-// @Description  将所有FAQ条目导出为CSV文件
+// @Description  以流式方式将FAQ条目导出为CSV或JSON Lines文件，可按标签和启用状态过滤
 // @Tags         FAQ管理
 // @Accept       json
 // @Produce      text/csv
-// @Param        id   path      string  true  "知识库ID"
-// @Success      200  {file}    file    "CSV文件"
-// @Failure      400  {object}  errors.AppError  "请求参数错误"
+// @Produce      application/x-ndjson
+// @Param        id       path      string  true   "知识库ID"
+// @Param        format   query     string  false  "导出格式：csv（默认）或 jsonl"
+// @Param        tag_id   query     int     false  "标签ID（seq_id）"
+// @Param        enabled  query     bool    false  "按启用状态过滤"
+// @Success      200      {file}    file    "导出文件"
+// @Failure      400      {object}  errors.AppError  "请求参数错误"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /knowledge-bases/{id}/faq/entries/export [get]
@@ -369,19 +395,61 @@ func (h *FAQHandler) ExportEntries(c *gin.Context) {
 	ctx := c.Request.Context()
 	kbID := secutils.SanitizeForLog(c.Param("id"))
 
-	csvData, err := h.knowledgeService.ExportFAQEntries(ctx, kbID)
-	if err != nil {
+	var req types.FAQExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		logger.Error(ctx, "Failed to bind export query", err)
+		c.Error(errors.NewBadRequestError("导出参数不合法").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	w := &faqExportWriter{c: c, format: req.EffectiveFormat()}
+	if err := h.knowledgeService.ExportFAQEntries(ctx, kbID, &req, w); err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(err)
+		if !w.started {
+			c.Error(err)
+			return
+		}
+		// The download has started; end it so the client sees a truncated file
+		c.Abort()
 		return
 	}
+	w.start()
+}
+
+// faqExportWriter writes an FAQ export to the response, sending the download headers with the first
+// write so that errors raised before any entry is written are still reported as JSON
+type faqExportWriter struct {
+	c       *gin.Context
+	format  types.FAQExportFormat
+	started bool
+}
 
-	// Set response headers for CSV download
-	c.Header("Content-Type", "text/csv; charset=utf-8")
-	c.Header("Content-Disposition", "attachment; filename=faq_export.csv")
-	// Add BOM for Excel compatibility with UTF-8
-	bom := []byte{0xEF, 0xBB, 0xBF}
-	c.Data(http.StatusOK, "text/csv; charset=utf-8", append(bom, csvData...))
+// start sends the status and download headers
+func (w *faqExportWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	contentType, filename := "text/csv; charset=utf-8", "faq_export.csv"
+	if w.format == types.FAQExportFormatJSONL {
+		contentType, filename = "application/x-ndjson; charset=utf-8", "faq_export.jsonl"
+	}
+	w.c.Header("Content-Type", contentType)
+	w.c.Header("Content-Disposition", "attachment; filename="+filename)
+	w.c.Status(http.StatusOK)
+	w.c.Writer.WriteHeaderNow()
+}
+
+// Write writes export data to the client and flushes it
+func (w *faqExportWriter) Write(p []byte) (int, error) {
+	w.start()
+	n, err := w.c.Writer.Write(p)
+	w.c.Writer.Flush()
+	return n, err
 }
 
 // GetEntry godoc
@@ -442,6 +510,9 @@ func (h *FAQHandler) GetImportProgress(c *gin.Context) {
 		c.Error(err)
 		return
 	}
+	if progress.Webhook, err = h.taskWebhooks.GetTaskWebhookDelivery(ctx, taskID); err != nil {
+		logger.Warnf(ctx, "Failed to get FAQ import webhook delivery: %v", err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -534,3 +605,116 @@ func (h *FAQHandler) AddSimilarQuestions(c *gin.Context) {
 		"data":    entry,
 	})
 }
+
+// GenerateSimilarQuestions godoc
+// @Summary      批量生成相似问
+// @Description  使用摘要模型为选定的FAQ条目（未指定时为全部条目）生成相似问，异步执行。默认仅生成建议供预览，apply=true 时直接添加到条目
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                             true  "知识库ID"
+// @Param        request  body      types.FAQSimilarGenerationRequest  true  "生成请求"
+// @Success      200      {object}  map[string]interface{}             "任务进度"
+// @Failure      400      {object}  errors.AppError                    "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/faq/entries/generate-similar [post]
+func (h *FAQHandler) GenerateSimilarQuestions(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+
+	var req types.FAQSimilarGenerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to bind similar question generation payload", err)
+		c.Error(errors.NewBadRequestError("请求参数不合法").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError("请求参数不合法").WithDetails(err.Error()))
+		return
+	}
+
+	progress, err := h.knowledgeService.GenerateFAQSimilarQuestions(ctx, kbID, &req)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// GetSimilarQuestionsProgress godoc
+// @Summary      获取相似问生成进度
+// @Description  获取相似问生成任务的进度以及各条目生成的相似问建议
+// @Tags         FAQ管理
+// @Produce      json
+// @Param        id       path      string                  true  "知识库ID"
+// @Param        task_id  path      string                  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "任务进度"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/faq/entries/generate-similar/{task_id} [get]
+func (h *FAQHandler) GetSimilarQuestionsProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+
+	progress, err := h.knowledgeService.GetFAQSimilarGenerationProgress(ctx, kbID, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// ApplySimilarQuestions godoc
+// @Summary      应用相似问建议
+// @Description  将已完成任务的相似问建议添加到对应条目，可指定条目并修改相似问；未指定条目时应用全部建议
+// @Tags         FAQ管理
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                        true  "知识库ID"
+// @Param        task_id  path      string                        true  "任务ID"
+// @Param        request  body      types.FAQSimilarApplyRequest  false  "要应用的建议"
+// @Success      200      {object}  map[string]interface{}        "任务进度"
+// @Failure      400      {object}  errors.AppError               "请求参数错误"
+// @Failure      404      {object}  errors.AppError               "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/faq/entries/generate-similar/{task_id}/apply [post]
+func (h *FAQHandler) ApplySimilarQuestions(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Param("id"))
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+
+	var req types.FAQSimilarApplyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.Error(ctx, "Failed to bind similar question apply payload", err)
+			c.Error(errors.NewBadRequestError("请求参数不合法").WithDetails(err.Error()))
+			return
+		}
+	}
+
+	progress, err := h.knowledgeService.ApplyFAQSimilarQuestions(ctx, kbID, taskID, &req)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// requireFeature returns a feature not enabled error when the feature is disabled for the tenant in ctx
+func requireFeature(ctx context.Context, cfg *config.Config, name string) *errors.AppError {
+	tenant, _ := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	var defaults types.FeatureFlags
+	if cfg != nil {
+		defaults = cfg.Features
+	}
+	if tenant.FeatureEnabled(name, defaults) {
+		return nil
+	}
+	logger.Warnf(ctx, "Feature %s is not enabled for tenant", name)
+	return errors.NewFeatureNotEnabledError(name)
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/dig"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/health"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// defaultHealthCheckTimeout is used when health.check_timeout is not set
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	checkers []interfaces.HealthChecker
+	timeout  time.Duration
+}
+
+// HealthHandlerParams are the dependencies of the health handler. Subsystems opt in to the readiness
+// probe by providing a checker in the health checker group.
+type HealthHandlerParams struct {
+	dig.In
+
+	Config   *config.Config
+	Checkers []interfaces.HealthChecker `group:"health_checkers"`
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(params HealthHandlerParams) *HealthHandler {
+	timeout := defaultHealthCheckTimeout
+	if params.Config != nil && params.Config.Health != nil && params.Config.Health.CheckTimeout > 0 {
+		timeout = params.Config.Health.CheckTimeout
+	}
+	return &HealthHandler{checkers: params.Checkers, timeout: timeout}
+}
+
+// Live godoc
+// @Summary      存活检查
+// @Description  进程存活即返回 ok，不检查依赖服务
+// @Tags         系统
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "存活"
+// @Router       /health [get]
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready godoc
+// @Summary      就绪检查
+// @Description  并行检查数据库、对象存储和模型服务等依赖，任一不可用时返回 503 并列出降级的子系统
+// @Tags         系统
+// @Produce      json
+// @Success      200  {object}  types.ReadinessReport  "就绪"
+// @Failure      503  {object}  types.ReadinessReport  "依赖不可用"
+// @Router       /health/ready [get]
+func (h *HealthHandler) Ready(c *gin.Context) {
+	ctx := c.Request.Context()
+	report := health.Run(ctx, h.checkers, h.timeout)
+	if report.Status != types.HealthStatusOK {
+		logger.Warnf(ctx, "[Health] Readiness check failed, degraded: %v", report.Degraded)
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -40,6 +40,10 @@ type DownloadTask struct {
 	Message   string     `json:"message"`
 	StartTime time.Time  `json:"startTime"`
 	EndTime   *time.Time `json:"endTime,omitempty"`
+	// Webhook 完成通知 webhook 的最近一次投递，查询进度时填充
+	Webhook *types.TaskWebhookDelivery `json:"webhook,omitempty"`
+
+	tenantID uint64
 }
 
 // 全局下载任务管理器
@@ -56,6 +60,7 @@ type InitializationHandler struct {
 	kbService        interfaces.KnowledgeBaseService
 	kbRepository     interfaces.KnowledgeBaseRepository
 	knowledgeService interfaces.KnowledgeService
+	taskWebhooks     interfaces.TaskWebhookService
 	ollamaService    *ollama.OllamaService
 	docReaderClient  *client.Client
 	pooler           embedding.EmbedderPooler
@@ -69,6 +74,7 @@ func NewInitializationHandler(
 	kbService interfaces.KnowledgeBaseService,
 	kbRepository interfaces.KnowledgeBaseRepository,
 	knowledgeService interfaces.KnowledgeService,
+	taskWebhooks interfaces.TaskWebhookService,
 	ollamaService *ollama.OllamaService,
 	docReaderClient *client.Client,
 	pooler embedding.EmbedderPooler,
@@ -80,6 +86,7 @@ func NewInitializationHandler(
 		kbService:        kbService,
 		kbRepository:     kbRepository,
 		knowledgeService: knowledgeService,
+		taskWebhooks:     taskWebhooks,
 		ollamaService:    ollamaService,
 		docReaderClient:  docReaderClient,
 		pooler:           pooler,
@@ -87,20 +94,23 @@ func NewInitializationHandler(
 }
 
 // KBModelConfigRequest 知识库模型配置请求（简化版，只传模型ID）
+// 各项均可省略，省略的项保持不变，例如只传 llmModelId 即可单独更换对话模型而不影响向量索引
 type KBModelConfigRequest struct {
-	LLMModelID       string           `json:"llmModelId"       binding:"required"`
-	EmbeddingModelID string           `json:"embeddingModelId" binding:"required"`
-	VLMConfig        *types.VLMConfig `json:"vlm_config"`
+	LLMModelID       string `json:"llmModelId"`
+	EmbeddingModelID string `json:"embeddingModelId"`
+	// RerankModelID 为空字符串时清除知识库的Rerank模型
+	RerankModelID *string          `json:"rerankModelId"`
+	VLMConfig     *types.VLMConfig `json:"vlm_config"`
 
 	// 文档分块配置
-	DocumentSplitting struct {
+	DocumentSplitting *struct {
 		ChunkSize    int      `json:"chunkSize"`
 		ChunkOverlap int      `json:"chunkOverlap"`
 		Separators   []string `json:"separators"`
 	} `json:"documentSplitting"`
 
 	// 多模态配置
-	Multimodal struct {
+	Multimodal *struct {
 		Enabled     bool   `json:"enabled"`
 		StorageType string `json:"storageType"` // "cos" or "minio"
 		COS         *struct {
@@ -119,7 +129,7 @@ type KBModelConfigRequest struct {
 	} `json:"multimodal"`
 
 	// 知识图谱配置
-	NodeExtract struct {
+	NodeExtract *struct {
 		Enabled   bool                  `json:"enabled"`
 		Text      string                `json:"text"`
 		Tags      []string              `json:"tags"`
@@ -128,7 +138,7 @@ type KBModelConfigRequest struct {
 	} `json:"nodeExtract"`
 
 	// 问题生成配置
-	QuestionGeneration struct {
+	QuestionGeneration *struct {
 		Enabled       bool `json:"enabled"`
 		QuestionCount int  `json:"questionCount"`
 	} `json:"questionGeneration"`
@@ -181,11 +191,15 @@ type InitializationRequest struct {
 		} `json:"minio,omitempty"`
 	} `json:"multimodal"`
 
+	// SmartDefaults picks the document splitting omitted from the request from the content of the
+	// knowledge base and the embedding model
+	SmartDefaults bool `json:"smartDefaults"`
+
 	DocumentSplitting struct {
-		ChunkSize    int      `json:"chunkSize" binding:"required,min=100,max=10000"`
+		ChunkSize    int      `json:"chunkSize" binding:"omitempty,min=100,max=10000"`
 		ChunkOverlap int      `json:"chunkOverlap" binding:"min=0"`
-		Separators   []string `json:"separators" binding:"required,min=1"`
-	} `json:"documentSplitting" binding:"required"`
+		Separators   []string `json:"separators" binding:"omitempty,min=1"`
+	} `json:"documentSplitting"`
 
 	NodeExtract struct {
 		Enabled bool     `json:"enabled"`
@@ -210,7 +224,7 @@ type InitializationRequest struct {
 
 // UpdateKBConfig godoc
 // @Summary      更新知识库配置
-// @Description  根据知识库ID更新模型和分块配置
+// @Description  根据知识库ID更新模型和分块配置，省略的配置项保持不变。已有文件时不能更换Embedding模型
 // @Tags         初始化
 // @Accept       json
 // @Produce      json
@@ -219,6 +233,7 @@ type InitializationRequest struct {
 // @Success      200      {object}  map[string]interface{}  "更新成功"
 // @Failure      400      {object}  errors.AppError         "请求参数错误"
 // @Failure      404      {object}  errors.AppError         "知识库不存在"
+// @Failure      409      {object}  errors.AppError         "已有文件，无法更换Embedding模型"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /initialization/kb/{kbId}/config [put]
@@ -237,144 +252,166 @@ func (h *InitializationHandler) UpdateKBConfig(c *gin.Context) {
 	kb, err := h.kbService.GetKnowledgeBaseByID(ctx, kbIdStr)
 	if err != nil || kb == nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{"kbId": utils.SanitizeForLog(kbIdStr)})
-		c.Error(errors.NewNotFoundError("知识库不存在"))
+		c.Error(errors.NewNotFoundError("知识库不存在").WithCode(errors.CodeKnowledgeBaseNotFound))
 		return
 	}
 
-	// 检查Embedding模型是否可以修改
-	if kb.EmbeddingModelID != "" && kb.EmbeddingModelID != req.EmbeddingModelID {
-		// 检查是否已有文件
-		knowledgeList, err := h.knowledgeService.ListPagedKnowledgeByKnowledgeBaseID(ctx,
-			kbIdStr, &types.Pagination{
-				Page:     1,
-				PageSize: 1,
-			}, "", "", "")
-		if err == nil && knowledgeList != nil && knowledgeList.Total > 0 {
-			logger.Error(ctx, "Cannot change embedding model when files exist")
-			c.Error(errors.NewBadRequestError("知识库中已有文件，无法修改Embedding模型"))
+	// 更新对话模型，不影响向量索引
+	if req.LLMModelID != "" {
+		if _, err := h.getKBModel(ctx, req.LLMModelID, types.ModelTypeKnowledgeQA, "LLM"); err != nil {
+			c.Error(err)
 			return
 		}
+		kb.SummaryModelID = req.LLMModelID
 	}
 
-	// 从数据库获取模型详情并验证
-	llmModel, err := h.modelService.GetModelByID(ctx, req.LLMModelID)
-	if err != nil || llmModel == nil {
-		logger.Error(ctx, "LLM model not found")
-		c.Error(errors.NewBadRequestError("LLM模型不存在"))
-		return
+	// 更换Embedding模型需要重新向量化，已有文件时禁止修改
+	if req.EmbeddingModelID != "" && req.EmbeddingModelID != kb.EmbeddingModelID {
+		if _, err := h.getKBModel(ctx, req.EmbeddingModelID, types.ModelTypeEmbedding, "Embedding"); err != nil {
+			c.Error(err)
+			return
+		}
+		if kb.EmbeddingModelID != "" {
+			knowledgeList, err := h.knowledgeService.ListPagedKnowledgeByKnowledgeBaseID(ctx,
+				kbIdStr, &types.Pagination{
+					Page:     1,
+					PageSize: 1,
+				}, "", "", "", nil)
+			if err != nil {
+				logger.Error(ctx, "Failed to check knowledge base files", err)
+				c.Error(errors.NewInternalServerError("检查知识库文件失败: " + err.Error()))
+				return
+			}
+			if knowledgeList != nil && knowledgeList.Total > 0 {
+				logger.Error(ctx, "Cannot change embedding model when files exist")
+				c.Error(errors.NewConflictError(
+					"知识库中已有文件，无法修改Embedding模型，可在向量空间配置中添加新的Embedding模型",
+				).WithCode(errors.CodeKnowledgeBaseEmbeddingLocked))
+				return
+			}
+		}
+		kb.EmbeddingModelID = req.EmbeddingModelID
 	}
 
-	embeddingModel, err := h.modelService.GetModelByID(ctx, req.EmbeddingModelID)
-	if err != nil || embeddingModel == nil {
-		logger.Error(ctx, "Embedding model not found")
-		c.Error(errors.NewBadRequestError("Embedding模型不存在"))
-		return
+	// 更新Rerank模型，重排序无需重新向量化
+	if req.RerankModelID != nil {
+		if *req.RerankModelID != "" {
+			if _, err := h.getKBModel(ctx, *req.RerankModelID, types.ModelTypeRerank, "Rerank"); err != nil {
+				c.Error(err)
+				return
+			}
+		}
+		kb.RerankModelID = *req.RerankModelID
 	}
 
-	// 更新知识库的模型ID
-	kb.SummaryModelID = req.LLMModelID
-	kb.EmbeddingModelID = req.EmbeddingModelID
-
-	// 处理多模态模型配置
-	kb.VLMConfig = types.VLMConfig{}
-	if req.VLMConfig != nil && req.Multimodal.Enabled && req.VLMConfig.ModelID != "" {
-		vllmModel, err := h.modelService.GetModelByID(ctx, req.VLMConfig.ModelID)
-		if err != nil || vllmModel == nil {
-			logger.Warn(ctx, "VLM model not found")
-		} else {
-			kb.VLMConfig.Enabled = req.VLMConfig.Enabled
-			kb.VLMConfig.ModelID = req.VLMConfig.ModelID
+	// 处理多模态模型配置，传入VLM配置或关闭多模态时更新
+	if req.VLMConfig != nil || (req.Multimodal != nil && !req.Multimodal.Enabled) {
+		vlmConfig := types.VLMConfig{}
+		multimodalEnabled := req.Multimodal == nil || req.Multimodal.Enabled
+		if req.VLMConfig != nil && multimodalEnabled && req.VLMConfig.Enabled && req.VLMConfig.ModelID != "" {
+			if _, err := h.getKBModel(ctx, req.VLMConfig.ModelID, types.ModelTypeVLLM, "VLM"); err != nil {
+				c.Error(err)
+				return
+			}
+			vlmConfig.Enabled = true
+			vlmConfig.ModelID = req.VLMConfig.ModelID
 		}
-	}
-	if !kb.VLMConfig.Enabled {
-		kb.VLMConfig.ModelID = ""
+		kb.VLMConfig = vlmConfig
 	}
 
 	// 更新文档分块配置
-	if req.DocumentSplitting.ChunkSize > 0 {
-		kb.ChunkingConfig.ChunkSize = req.DocumentSplitting.ChunkSize
-	}
-	if req.DocumentSplitting.ChunkOverlap >= 0 {
-		kb.ChunkingConfig.ChunkOverlap = req.DocumentSplitting.ChunkOverlap
-	}
-	if len(req.DocumentSplitting.Separators) > 0 {
-		kb.ChunkingConfig.Separators = req.DocumentSplitting.Separators
+	if req.DocumentSplitting != nil {
+		if req.DocumentSplitting.ChunkSize > 0 {
+			kb.ChunkingConfig.ChunkSize = req.DocumentSplitting.ChunkSize
+		}
+		if req.DocumentSplitting.ChunkOverlap >= 0 {
+			kb.ChunkingConfig.ChunkOverlap = req.DocumentSplitting.ChunkOverlap
+		}
+		if len(req.DocumentSplitting.Separators) > 0 {
+			kb.ChunkingConfig.Separators = req.DocumentSplitting.Separators
+		}
 	}
 
 	// 更新多模态配置
-	if req.Multimodal.Enabled {
-		switch strings.ToLower(req.Multimodal.StorageType) {
-		case "cos":
-			if req.Multimodal.COS != nil {
-				kb.StorageConfig = types.StorageConfig{
-					SecretID:   req.Multimodal.COS.SecretID,
-					SecretKey:  req.Multimodal.COS.SecretKey,
-					Region:     req.Multimodal.COS.Region,
-					BucketName: req.Multimodal.COS.BucketName,
-					AppID:      req.Multimodal.COS.AppID,
-					PathPrefix: req.Multimodal.COS.PathPrefix,
-					Provider:   "cos",
+	if req.Multimodal != nil {
+		if req.Multimodal.Enabled {
+			switch strings.ToLower(req.Multimodal.StorageType) {
+			case "cos":
+				if req.Multimodal.COS != nil {
+					kb.StorageConfig = types.StorageConfig{
+						SecretID:   req.Multimodal.COS.SecretID,
+						SecretKey:  req.Multimodal.COS.SecretKey,
+						Region:     req.Multimodal.COS.Region,
+						BucketName: req.Multimodal.COS.BucketName,
+						AppID:      req.Multimodal.COS.AppID,
+						PathPrefix: req.Multimodal.COS.PathPrefix,
+						Provider:   "cos",
+					}
 				}
-			}
-		case "minio":
-			if req.Multimodal.Minio != nil {
-				kb.StorageConfig = types.StorageConfig{
-					BucketName: req.Multimodal.Minio.BucketName,
-					PathPrefix: req.Multimodal.Minio.PathPrefix,
-					Provider:   "minio",
-					SecretID:   os.Getenv("MINIO_ACCESS_KEY_ID"),
-					SecretKey:  os.Getenv("MINIO_SECRET_ACCESS_KEY"),
+			case "minio":
+				if req.Multimodal.Minio != nil {
+					kb.StorageConfig = types.StorageConfig{
+						BucketName: req.Multimodal.Minio.BucketName,
+						PathPrefix: req.Multimodal.Minio.PathPrefix,
+						Provider:   "minio",
+						SecretID:   os.Getenv("MINIO_ACCESS_KEY_ID"),
+						SecretKey:  os.Getenv("MINIO_SECRET_ACCESS_KEY"),
+					}
 				}
 			}
+		} else {
+			// 多模态未启用时，清空存储配置
+			kb.StorageConfig = types.StorageConfig{}
 		}
-	} else {
-		// 多模态未启用时，清空存储配置
-		kb.StorageConfig = types.StorageConfig{}
 	}
 
 	// 更新知识图谱配置
-	if req.NodeExtract.Enabled {
-		// 转换 Nodes 和 Relations 为指针类型
-		nodes := make([]*types.GraphNode, len(req.NodeExtract.Nodes))
-		for i := range req.NodeExtract.Nodes {
-			nodes[i] = &req.NodeExtract.Nodes[i]
-		}
-		relations := make([]*types.GraphRelation, len(req.NodeExtract.Relations))
-		for i := range req.NodeExtract.Relations {
-			relations[i] = &req.NodeExtract.Relations[i]
-		}
+	if req.NodeExtract != nil {
+		if req.NodeExtract.Enabled {
+			// 转换 Nodes 和 Relations 为指针类型
+			nodes := make([]*types.GraphNode, len(req.NodeExtract.Nodes))
+			for i := range req.NodeExtract.Nodes {
+				nodes[i] = &req.NodeExtract.Nodes[i]
+			}
+			relations := make([]*types.GraphRelation, len(req.NodeExtract.Relations))
+			for i := range req.NodeExtract.Relations {
+				relations[i] = &req.NodeExtract.Relations[i]
+			}
 
-		kb.ExtractConfig = &types.ExtractConfig{
-			Enabled:   req.NodeExtract.Enabled,
-			Text:      req.NodeExtract.Text,
-			Tags:      req.NodeExtract.Tags,
-			Nodes:     nodes,
-			Relations: relations,
+			kb.ExtractConfig = &types.ExtractConfig{
+				Enabled:   req.NodeExtract.Enabled,
+				Text:      req.NodeExtract.Text,
+				Tags:      req.NodeExtract.Tags,
+				Nodes:     nodes,
+				Relations: relations,
+			}
+		} else {
+			kb.ExtractConfig = &types.ExtractConfig{Enabled: false}
+		}
+		if err := validateExtractConfig(kb.ExtractConfig); err != nil {
+			logger.Error(ctx, "Invalid extract configuration", err)
+			c.Error(err)
+			return
 		}
-	} else {
-		kb.ExtractConfig = &types.ExtractConfig{Enabled: false}
-	}
-	if err := validateExtractConfig(kb.ExtractConfig); err != nil {
-		logger.Error(ctx, "Invalid extract configuration", err)
-		c.Error(err)
-		return
 	}
 
 	// 更新问题生成配置
-	if req.QuestionGeneration.Enabled {
-		questionCount := req.QuestionGeneration.QuestionCount
-		if questionCount <= 0 {
-			questionCount = 3
-		}
-		if questionCount > 10 {
-			questionCount = 10
-		}
-		kb.QuestionGenerationConfig = &types.QuestionGenerationConfig{
-			Enabled:       true,
-			QuestionCount: questionCount,
+	if req.QuestionGeneration != nil {
+		if req.QuestionGeneration.Enabled {
+			questionCount := req.QuestionGeneration.QuestionCount
+			if questionCount <= 0 {
+				questionCount = 3
+			}
+			if questionCount > 10 {
+				questionCount = 10
+			}
+			kb.QuestionGenerationConfig = &types.QuestionGenerationConfig{
+				Enabled:       true,
+				QuestionCount: questionCount,
+			}
+		} else {
+			kb.QuestionGenerationConfig = &types.QuestionGenerationConfig{Enabled: false}
 		}
-	} else {
-		kb.QuestionGenerationConfig = &types.QuestionGenerationConfig{Enabled: false}
 	}
 
 	// 保存更新后的知识库
@@ -387,9 +424,31 @@ func (h *InitializationHandler) UpdateKBConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "配置更新成功",
+		"data": gin.H{
+			"llmModelId":       kb.SummaryModelID,
+			"embeddingModelId": kb.EmbeddingModelID,
+			"rerankModelId":    kb.RerankModelID,
+			"vlmModelId":       kb.VLMConfig.ModelID,
+		},
 	})
 }
 
+// getKBModel 获取知识库配置引用的模型并校验模型类型
+func (h *InitializationHandler) getKBModel(ctx context.Context,
+	modelID string, modelType types.ModelType, role string,
+) (*types.Model, error) {
+	model, err := h.modelService.GetModelByID(ctx, modelID)
+	if err != nil || model == nil {
+		logger.Errorf(ctx, "%s model not found: %s", role, utils.SanitizeForLog(modelID))
+		return nil, errors.NewBadRequestError(role + "模型不存在")
+	}
+	if model.Type != modelType {
+		logger.Errorf(ctx, "Model %s is not a %s model", utils.SanitizeForLog(modelID), role)
+		return nil, errors.NewBadRequestError(fmt.Sprintf("模型 %s 不是%s模型", model.Name, role))
+	}
+	return model, nil
+}
+
 // InitializeByKB godoc
 // @Summary      初始化知识库配置
 // @Description  根据知识库ID执行完整配置更新
@@ -431,6 +490,15 @@ func (h *InitializationHandler) InitializeByKB(c *gin.Context) {
 		return
 	}
 
+	var smartDefaults *types.KBDefaults
+	if req.SmartDefaults {
+		smartDefaults, err = h.applySmartDefaults(ctx, kbIdStr, req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
 	processedModels, err := h.processInitializationModels(ctx, kb, kbIdStr, req)
 	if err != nil {
 		c.Error(err)
@@ -438,6 +506,12 @@ func (h *InitializationHandler) InitializeByKB(c *gin.Context) {
 	}
 
 	h.applyKnowledgeBaseInitialization(kb, req, processedModels)
+	if smartDefaults != nil {
+		kb.ChunkingConfig.Strategy = smartDefaults.ChunkingConfig.Strategy
+		if kb.ParentChildConfig == nil {
+			kb.ParentChildConfig = smartDefaults.ParentChildConfig
+		}
+	}
 
 	if err := h.kbRepository.UpdateKnowledgeBase(ctx, kb); err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{"kbId": utils.SanitizeForLog(kbIdStr)})
@@ -445,16 +519,74 @@ func (h *InitializationHandler) InitializeByKB(c *gin.Context) {
 		return
 	}
 
+	data := gin.H{
+		"models":         processedModels,
+		"knowledge_base": kb,
+	}
+	if smartDefaults != nil {
+		data["smart_defaults"] = smartDefaults
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "知识库配置更新成功",
-		"data": gin.H{
-			"models":         processedModels,
-			"knowledge_base": kb,
-		},
+		"data":    data,
 	})
 }
 
+// applySmartDefaults picks the document splitting of the knowledge base from the types of its first files
+// and the embedding model, and fills the values omitted from the request with it
+func (h *InitializationHandler) applySmartDefaults(ctx context.Context,
+	kbIdStr string, req *InitializationRequest,
+) (*types.KBDefaults, error) {
+	knowledgeList, err := h.knowledgeService.ListPagedKnowledgeByKnowledgeBaseID(ctx,
+		kbIdStr, &types.Pagination{
+			Page:     1,
+			PageSize: types.KBDefaultsSampleSize,
+		}, "", "", "", nil)
+	if err != nil {
+		logger.Error(ctx, "Failed to list knowledge base files", err)
+		return nil, errors.NewInternalServerError("检查知识库文件失败: " + err.Error())
+	}
+	var fileTypes []string
+	if knowledgeList != nil {
+		if knowledges, ok := knowledgeList.Data.([]*types.Knowledge); ok {
+			for _, knowledge := range knowledges {
+				fileTypes = append(fileTypes, knowledge.FileType)
+			}
+		}
+	}
+
+	base := types.ChunkingConfig{ChunkSize: 512, ChunkOverlap: 50, Separators: []string{"\n\n", "\n", "。"}}
+	if h.config != nil && h.config.KnowledgeBase != nil {
+		if h.config.KnowledgeBase.ChunkSize > 0 {
+			base.ChunkSize = h.config.KnowledgeBase.ChunkSize
+			base.ChunkOverlap = h.config.KnowledgeBase.ChunkOverlap
+		}
+		if len(h.config.KnowledgeBase.SplitMarkers) > 0 {
+			base.Separators = h.config.KnowledgeBase.SplitMarkers
+		}
+	}
+	defaults := types.SuggestKBDefaults(base, types.DetectKBContentProfile(fileTypes), req.Embedding.ModelName)
+
+	// 请求中指定的值优先于推荐值
+	if req.DocumentSplitting.ChunkSize == 0 {
+		req.DocumentSplitting.ChunkSize = defaults.ChunkingConfig.ChunkSize
+	}
+	if req.DocumentSplitting.ChunkOverlap == 0 {
+		req.DocumentSplitting.ChunkOverlap = defaults.ChunkingConfig.ChunkOverlap
+	}
+	if len(req.DocumentSplitting.Separators) == 0 {
+		req.DocumentSplitting.Separators = defaults.ChunkingConfig.Separators
+	}
+	if req.DocumentSplitting.ChunkOverlap >= req.DocumentSplitting.ChunkSize {
+		req.DocumentSplitting.ChunkOverlap = req.DocumentSplitting.ChunkSize / 5
+	}
+
+	logger.Infof(ctx, "Smart defaults for knowledge base %s: profile %s, chunk size %d, overlap %d",
+		kbIdStr, defaults.ContentProfile, req.DocumentSplitting.ChunkSize, req.DocumentSplitting.ChunkOverlap)
+	return defaults, nil
+}
+
 func (h *InitializationHandler) bindInitializationRequest(ctx context.Context, c *gin.Context) (*InitializationRequest, error) {
 	var req InitializationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -472,12 +604,16 @@ func (h *InitializationHandler) getKnowledgeBaseForInitialization(ctx context.Co
 	}
 	if kb == nil {
 		logger.Error(ctx, "Knowledge base not found")
-		return nil, errors.NewNotFoundError("知识库不存在")
+		return nil, errors.NewNotFoundError("知识库不存在").WithCode(errors.CodeKnowledgeBaseNotFound)
 	}
 	return kb, nil
 }
 
 func (h *InitializationHandler) validateInitializationConfigs(ctx context.Context, req *InitializationRequest) error {
+	if !req.SmartDefaults && (req.DocumentSplitting.ChunkSize == 0 || len(req.DocumentSplitting.Separators) == 0) {
+		logger.Error(ctx, "Document splitting configuration incomplete")
+		return errors.NewBadRequestError("文档分块配置不完整")
+	}
 	if err := h.validateMultimodalConfig(ctx, req); err != nil {
 		return err
 	}
@@ -695,6 +831,8 @@ func (h *InitializationHandler) findExistingModelID(kb *types.KnowledgeBase, mod
 		return kb.EmbeddingModelID
 	case types.ModelTypeKnowledgeQA:
 		return kb.SummaryModelID
+	case types.ModelTypeRerank:
+		return kb.RerankModelID
 	case types.ModelTypeVLLM:
 		return kb.VLMConfig.ModelID
 	default:
@@ -707,10 +845,11 @@ func (h *InitializationHandler) applyKnowledgeBaseInitialization(
 	req *InitializationRequest,
 	processedModels []*types.Model,
 ) {
-	embeddingModelID, llmModelID, vlmModelID := extractModelIDs(processedModels)
+	embeddingModelID, llmModelID, rerankModelID, vlmModelID := extractModelIDs(processedModels)
 
 	kb.SummaryModelID = llmModelID
 	kb.EmbeddingModelID = embeddingModelID
+	kb.RerankModelID = rerankModelID
 
 	kb.ChunkingConfig = types.ChunkingConfig{
 		ChunkSize:    req.DocumentSplitting.ChunkSize,
@@ -776,7 +915,7 @@ func (h *InitializationHandler) applyKnowledgeBaseInitialization(
 	}
 }
 
-func extractModelIDs(processedModels []*types.Model) (embeddingModelID, llmModelID, vlmModelID string) {
+func extractModelIDs(processedModels []*types.Model) (embeddingModelID, llmModelID, rerankModelID, vlmModelID string) {
 	for _, model := range processedModels {
 		if model == nil {
 			continue
@@ -786,6 +925,8 @@ func extractModelIDs(processedModels []*types.Model) (embeddingModelID, llmModel
 			embeddingModelID = model.ID
 		case types.ModelTypeKnowledgeQA:
 			llmModelID = model.ID
+		case types.ModelTypeRerank:
+			rerankModelID = model.ID
 		case types.ModelTypeVLLM:
 			vlmModelID = model.ID
 		}
@@ -912,7 +1053,7 @@ func (h *InitializationHandler) CheckOllamaModels(c *gin.Context) {
 // @Tags         初始化
 // @Accept       json
 // @Produce      json
-// @Param        request  body      object{modelName=string}  true  "模型名称"
+// @Param        request  body      object{modelName=string,webhookUrl=string}  true  "模型名称及可选的完成通知 webhook"
 // @Success      200      {object}  map[string]interface{}    "下载任务信息"
 // @Failure      400      {object}  errors.AppError           "请求参数错误"
 // @Security     Bearer
@@ -925,6 +1066,8 @@ func (h *InitializationHandler) DownloadOllamaModel(c *gin.Context) {
 
 	var req struct {
 		ModelName string `json:"modelName" binding:"required"`
+		// WebhookURL 下载结束时通知的地址，替代租户 webhook（可选）
+		WebhookURL string `json:"webhookUrl"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -985,6 +1128,14 @@ func (h *InitializationHandler) DownloadOllamaModel(c *gin.Context) {
 
 	// 创建下载任务
 	taskID := uuid.New().String()
+	if req.WebhookURL != "" {
+		if err := h.taskWebhooks.RegisterTaskWebhook(ctx, taskID, req.WebhookURL); err != nil {
+			logger.Warnf(ctx, "Failed to register model download webhook: %v", err)
+			c.Error(err)
+			return
+		}
+	}
+	tenantID, _ := ctx.Value(types.TenantIDContextKey).(uint64)
 	task := &DownloadTask{
 		ID:        taskID,
 		ModelName: req.ModelName,
@@ -992,6 +1143,7 @@ func (h *InitializationHandler) DownloadOllamaModel(c *gin.Context) {
 		Progress:  0.0,
 		Message:   "准备下载",
 		StartTime: time.Now(),
+		tenantID:  tenantID,
 	}
 
 	tasksMutex.Lock()
@@ -1039,14 +1191,22 @@ func (h *InitializationHandler) GetDownloadProgress(c *gin.Context) {
 	}
 
 	tasksMutex.RLock()
-	task, exists := downloadTasks[taskID]
+	var task DownloadTask
+	current, exists := downloadTasks[taskID]
+	if exists {
+		task = *current
+	}
 	tasksMutex.RUnlock()
 
 	if !exists {
-		c.Error(errors.NewNotFoundError("下载任务不存在"))
+		c.Error(errors.NewNotFoundError("下载任务不存在").WithCode(errors.CodeTaskNotFound))
 		return
 	}
 
+	var err error
+	if task.Webhook, err = h.taskWebhooks.GetTaskWebhookDelivery(c.Request.Context(), taskID); err != nil {
+		logger.Warnf(c.Request.Context(), "Failed to get model download webhook delivery: %v", err)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    task,
@@ -1134,12 +1294,43 @@ func (h *InitializationHandler) downloadModelAsync(ctx context.Context,
 	if err != nil {
 		logger.Error(ctx, "Failed to download model", err)
 		h.updateTaskStatus(taskID, "failed", 0.0, fmt.Sprintf("下载失败: %v", err))
+		h.notifyDownloadFinished(ctx, taskID, err)
 		return
 	}
 
 	// 下载成功
 	logger.Infof(ctx, "Model downloaded successfully, task: %s", taskID)
 	h.updateTaskStatus(taskID, "completed", 100.0, "下载完成")
+	h.notifyDownloadFinished(ctx, taskID, nil)
+}
+
+// notifyDownloadFinished 通知下载任务的 webhook，err 非空表示下载失败
+func (h *InitializationHandler) notifyDownloadFinished(ctx context.Context, taskID string, err error) {
+	tasksMutex.RLock()
+	task, exists := downloadTasks[taskID]
+	var event *types.TaskWebhookEvent
+	if exists && task.tenantID != 0 {
+		event = &types.TaskWebhookEvent{
+			TaskID:   taskID,
+			TaskType: types.TaskTypeModelDownload,
+			TenantID: task.tenantID,
+			Status:   types.TaskWebhookStatusCompleted,
+			Summary: map[string]interface{}{
+				"model_name": task.ModelName,
+				"message":    task.Message,
+			},
+			FinishedAt: time.Now().UTC(),
+		}
+	}
+	tasksMutex.RUnlock()
+	if event == nil {
+		return
+	}
+	if err != nil {
+		event.Status = types.TaskWebhookStatusFailed
+		event.Error = err.Error()
+	}
+	h.taskWebhooks.NotifyTaskFinished(ctx, event)
 }
 
 // pullModelWithProgress 下载模型并提供进度回调
@@ -1243,7 +1434,7 @@ func (h *InitializationHandler) GetCurrentConfigByKB(c *gin.Context) {
 
 	if kb == nil {
 		logger.Error(ctx, "Knowledge base not found")
-		c.Error(errors.NewNotFoundError("知识库不存在"))
+		c.Error(errors.NewNotFoundError("知识库不存在").WithCode(errors.CodeKnowledgeBaseNotFound))
 		return
 	}
 
@@ -1252,6 +1443,7 @@ func (h *InitializationHandler) GetCurrentConfigByKB(c *gin.Context) {
 	modelIDs := []string{
 		kb.EmbeddingModelID,
 		kb.SummaryModelID,
+		kb.RerankModelID,
 		kb.VLMConfig.ModelID,
 	}
 
@@ -1274,7 +1466,7 @@ func (h *InitializationHandler) GetCurrentConfigByKB(c *gin.Context) {
 		kbIdStr, &types.Pagination{
 			Page:     1,
 			PageSize: 1,
-		}, "", "", "")
+		}, "", "", "", nil)
 	hasFiles := err == nil && knowledgeList != nil && knowledgeList.Total > 0
 
 	// 构建配置响应
@@ -1311,6 +1503,7 @@ func (h *InitializationHandler) buildConfigResponse(ctx context.Context, models
 		switch model.Type {
 		case types.ModelTypeKnowledgeQA:
 			config["llm"] = map[string]interface{}{
+				"modelId":   model.ID,
 				"source":    string(model.Source),
 				"modelName": model.Name,
 				"baseUrl":   baseURL,
@@ -1318,6 +1511,7 @@ func (h *InitializationHandler) buildConfigResponse(ctx context.Context, models
 			}
 		case types.ModelTypeEmbedding:
 			config["embedding"] = map[string]interface{}{
+				"modelId":   model.ID,
 				"source":    string(model.Source),
 				"modelName": model.Name,
 				"baseUrl":   baseURL,
@@ -1327,6 +1521,8 @@ func (h *InitializationHandler) buildConfigResponse(ctx context.Context, models
 		case types.ModelTypeRerank:
 			config["rerank"] = map[string]interface{}{
 				"enabled":   true,
+				"modelId":   model.ID,
+				"source":    string(model.Source),
 				"modelName": model.Name,
 				"baseUrl":   baseURL,
 				"apiKey":    apiKey,
@@ -1510,6 +1706,7 @@ func (h *InitializationHandler) TestEmbeddingModel(c *gin.Context) {
 		APIKey    string `json:"apiKey"`
 		Dimension int    `json:"dimension"`
 		Provider  string `json:"provider"`
+		Normalize bool   `json:"normalize"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -1545,6 +1742,7 @@ func (h *InitializationHandler) TestEmbeddingModel(c *gin.Context) {
 		Dimensions:           req.Dimension,
 		ModelID:              "",
 		Provider:             req.Provider,
+		Normalize:            req.Normalize,
 	}
 
 	emb, err := embedding.NewEmbedder(cfg, h.pooler, h.ollamaService)
@@ -1569,10 +1767,25 @@ func (h *InitializationHandler) TestEmbeddingModel(c *gin.Context) {
 		return
 	}
 
+	// 检测模型是否返回未归一化的向量，未归一化的向量会破坏余弦相似度的假设
+	norm := embedding.L2Norm(vec)
+	data := gin.H{
+		`available`:  true,
+		`message`:    fmt.Sprintf("测试成功，向量维度=%d", len(vec)),
+		`dimension`:  len(vec),
+		`norm`:       norm,
+		`normalized`: embedding.IsNormalized(vec),
+	}
+	if !embedding.IsNormalized(vec) {
+		logger.Warnf(ctx, "Embedding model %s returns unnormalized vectors, norm: %f",
+			utils.SanitizeForLog(req.ModelName), norm)
+		data[`warning`] = fmt.Sprintf("模型返回的向量未归一化（L2范数=%.4f），建议开启 normalize 选项", norm)
+	}
+
 	logger.Infof(ctx, "Embedding test succeeded, dimension: %d", len(vec))
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    gin.H{`available`: true, `message`: fmt.Sprintf("测试成功，向量维度=%d", len(vec)), `dimension`: len(vec)},
+		"data":    data,
 	})
 }
 
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/Tencent/WeKnora/internal/config"
 	"github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
@@ -20,14 +21,16 @@ import (
 type KnowledgeHandler struct {
 	kgService interfaces.KnowledgeService
 	kbService interfaces.KnowledgeBaseService
+	config    *config.Config
 }
 
 // NewKnowledgeHandler creates a new knowledge handler instance
 func NewKnowledgeHandler(
 	kgService interfaces.KnowledgeService,
 	kbService interfaces.KnowledgeBaseService,
+	config *config.Config,
 ) *KnowledgeHandler {
-	return &KnowledgeHandler{kgService: kgService, kbService: kbService}
+	return &KnowledgeHandler{kgService: kgService, kbService: kbService, config: config}
 }
 
 // validateKnowledgeBaseAccess validates access permissions to a knowledge base
@@ -46,7 +49,7 @@ func (h *KnowledgeHandler) validateKnowledgeBaseAccess(c *gin.Context) (*types.K
 	kb, err := h.kbService.GetKnowledgeBaseByID(ctx, kbID)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		return nil, kbID, errors.NewInternalServerError(err.Error())
+		return nil, kbID, errors.FromError(err)
 	}
 
 	// Verify tenant permissions
@@ -77,12 +80,42 @@ func (h *KnowledgeHandler) handleDuplicateKnowledgeError(c *gin.Context,
 			"message": dupErr.Error(),
 			"data":    knowledge, // knowledge contains the existing document
 			"code":    fmt.Sprintf("duplicate_%s", duplicateType),
+			"error":   errors.FromError(dupErr).Body(),
 		})
 		return true
 	}
 	return false
 }
 
+// maxUploadFormFieldsSize bounds the form fields sent with an uploaded file
+const maxUploadFormFieldsSize = 1 << 20
+
+// uploadSizeLimitReader fails once more than the allowed number of bytes has been read,
+// so that oversized uploads are rejected without reading them to the end
+type uploadSizeLimitReader struct {
+	r         io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func (l *uploadSizeLimitReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		l.exceeded = true
+		return 0, fmt.Errorf("file size limit exceeded")
+	}
+	// Read one byte past the limit to tell a file of exactly the limit from a larger one
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		l.exceeded = true
+		return n, fmt.Errorf("file size limit exceeded")
+	}
+	return n, err
+}
+
 // CreateKnowledgeFromFile godoc
 // @Summary      从文件创建知识
 // @Description  上传文件并创建知识条目
@@ -97,6 +130,7 @@ func (h *KnowledgeHandler) handleDuplicateKnowledgeError(c *gin.Context,
 // @Success      200               {object}  map[string]interface{}  "创建的知识"
 // @Failure      400               {object}  errors.AppError         "请求参数错误"
 // @Failure      409               {object}  map[string]interface{}  "文件重复"
+// @Failure      415               {object}  errors.AppError         "不支持的文件类型"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /knowledge-bases/{id}/knowledge/file [post]
@@ -111,40 +145,106 @@ func (h *KnowledgeHandler) CreateKnowledgeFromFile(c *gin.Context) {
 		return
 	}
 
-	// Get the uploaded file
-	file, err := c.FormFile("file")
+	// Stream the multipart body instead of parsing it into memory: the file part is staged to a temporary
+	// file as it arrives, while the other form fields may come before or after it
+	maxSize := secutils.GetMaxFileSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+maxUploadFormFieldsSize)
+	reader, err := c.Request.MultipartReader()
 	if err != nil {
 		logger.Error(ctx, "File upload failed", err)
 		c.Error(errors.NewBadRequestError("File upload failed").WithDetails(err.Error()))
 		return
 	}
 
-	// Validate file size (configurable via MAX_FILE_SIZE_MB)
-	maxSize := secutils.GetMaxFileSize()
-	if file.Size > maxSize {
-		logger.Error(ctx, "File size too large")
-		c.Error(errors.NewBadRequestError(fmt.Sprintf("file size cannot exceed %dMB", secutils.GetMaxFileSizeMB())))
+	form := make(map[string]string)
+	var staged *types.StagedKnowledgeFile
+	// Discard the staged file on any failure before the knowledge is created
+	discard := func() {
+		if staged != nil {
+			h.kgService.DiscardStagedKnowledgeFile(ctx, staged)
+		}
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			discard()
+			logger.Error(ctx, "File upload failed", err)
+			c.Error(errors.NewBadRequestError("File upload failed").WithDetails(err.Error()))
+			return
+		}
+
+		if part.FormName() != "file" {
+			value, err := io.ReadAll(io.LimitReader(part, maxUploadFormFieldsSize))
+			part.Close()
+			if err != nil {
+				discard()
+				logger.Error(ctx, "File upload failed", err)
+				c.Error(errors.NewBadRequestError("File upload failed").WithDetails(err.Error()))
+				return
+			}
+			form[part.FormName()] = string(value)
+			continue
+		}
+		if staged != nil {
+			part.Close()
+			discard()
+			c.Error(errors.NewBadRequestError("Only one file can be uploaded per request"))
+			return
+		}
+
+		fileName := part.FileName()
+		if customFileName := form["fileName"]; customFileName != "" {
+			fileName = customFileName
+		}
+		logger.Infof(ctx, "Receiving file: %s", secutils.SanitizeForLog(fileName))
+
+		// Validate file size (configurable via MAX_FILE_SIZE_MB) while the file streams
+		content := &uploadSizeLimitReader{r: part, remaining: maxSize}
+		staged, err = h.kgService.StageKnowledgeFile(ctx, kbID, fileName, content)
+		part.Close()
+		if content.exceeded {
+			discard()
+			logger.Error(ctx, "File size too large")
+			c.Error(errors.NewBadRequestError(fmt.Sprintf("file size cannot exceed %dMB", secutils.GetMaxFileSizeMB())))
+			return
+		}
+		if err != nil {
+			if appErr, ok := errors.IsAppError(err); ok {
+				c.Error(appErr)
+				return
+			}
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.FromError(err))
+			return
+		}
+	}
+	if staged == nil {
+		logger.Error(ctx, "File upload failed: no file part")
+		c.Error(errors.NewBadRequestError("File upload failed").WithDetails("missing form file \"file\""))
 		return
 	}
 
 	// Get custom filename if provided (for folder uploads with path)
-	customFileName := c.PostForm("fileName")
+	customFileName := form["fileName"]
 	customFileName = secutils.SanitizeForLog(customFileName)
-	displayFileName := file.Filename
-	displayFileName = secutils.SanitizeForLog(displayFileName)
+	displayFileName := secutils.SanitizeForLog(staged.FileName)
 	if customFileName != "" {
 		displayFileName = customFileName
 		logger.Infof(ctx, "Using custom filename: %s (original: %s)", customFileName, displayFileName)
 	}
 
-	logger.Infof(ctx, "File upload successful, filename: %s, size: %.2f KB", displayFileName, float64(file.Size)/1024)
+	logger.Infof(ctx, "File upload successful, filename: %s, size: %.2f KB", displayFileName, float64(staged.FileSize)/1024)
 	logger.Infof(ctx, "Creating knowledge, knowledge base ID: %s, filename: %s", kbID, displayFileName)
 
 	// Parse metadata if provided
 	var metadata map[string]string
-	metadataStr := c.PostForm("metadata")
+	metadataStr := form["metadata"]
 	if metadataStr != "" {
 		if err := json.Unmarshal([]byte(metadataStr), &metadata); err != nil {
+			discard()
 			logger.Error(ctx, "Failed to parse metadata", err)
 			c.Error(errors.NewBadRequestError("Invalid metadata format").WithDetails(err.Error()))
 			return
@@ -152,27 +252,37 @@ func (h *KnowledgeHandler) CreateKnowledgeFromFile(c *gin.Context) {
 		logger.Infof(ctx, "Received file metadata: %s", secutils.SanitizeForLog(fmt.Sprintf("%v", metadata)))
 	}
 
-	enableMultimodelForm := c.PostForm("enable_multimodel")
+	enableMultimodelForm := form["enable_multimodel"]
 	var enableMultimodel *bool
 	if enableMultimodelForm != "" {
 		parseBool, err := strconv.ParseBool(enableMultimodelForm)
 		if err != nil {
+			discard()
 			logger.Error(ctx, "Failed to parse enable_multimodel", err)
 			c.Error(errors.NewBadRequestError("Invalid enable_multimodel format").WithDetails(err.Error()))
 			return
 		}
 		enableMultimodel = &parseBool
 	}
+	if enableMultimodel != nil && *enableMultimodel {
+		if appErr := requireFeature(ctx, h.config, types.FeatureMultimodal); appErr != nil {
+			discard()
+			c.Error(appErr)
+			return
+		}
+	}
 
 	// 获取分类ID（如果提供），用于知识分类管理
-	tagID := c.PostForm("tag_id")
+	tagID := form["tag_id"]
 	// 过滤特殊值，空字符串或 "__untagged__" 表示未分类
 	if tagID == "__untagged__" || tagID == "" {
 		tagID = ""
 	}
 
-	// Create knowledge entry from the file
-	knowledge, err := h.kgService.CreateKnowledgeFromFile(ctx, kbID, file, metadata, enableMultimodel, customFileName, tagID)
+	// Create knowledge entry from the staged file
+	knowledge, err := h.kgService.CreateKnowledgeFromStagedFile(
+		ctx, kbID, staged, metadata, enableMultimodel, customFileName, tagID,
+	)
 	// Check for duplicate knowledge error
 	if err != nil {
 		if h.handleDuplicateKnowledgeError(c, err, knowledge, "file") {
@@ -183,7 +293,7 @@ func (h *KnowledgeHandler) CreateKnowledgeFromFile(c *gin.Context) {
 			return
 		}
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -245,6 +355,13 @@ func (h *KnowledgeHandler) CreateKnowledgeFromURL(c *gin.Context) {
 		secutils.SanitizeForLog(req.URL),
 	)
 
+	if req.EnableMultimodel != nil && *req.EnableMultimodel {
+		if appErr := requireFeature(ctx, h.config, types.FeatureMultimodal); appErr != nil {
+			c.Error(appErr)
+			return
+		}
+	}
+
 	// Create knowledge entry from the URL
 	knowledge, err := h.kgService.CreateKnowledgeFromURL(ctx, kbID, req.URL, req.EnableMultimodel, req.Title, req.TagID)
 	// Check for duplicate knowledge error
@@ -253,7 +370,7 @@ func (h *KnowledgeHandler) CreateKnowledgeFromURL(c *gin.Context) {
 			return
 		}
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -308,7 +425,7 @@ func (h *KnowledgeHandler) CreateManualKnowledge(c *gin.Context) {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{
 			"kb_id": kbID,
 		})
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -350,7 +467,7 @@ func (h *KnowledgeHandler) GetKnowledge(c *gin.Context) {
 	knowledge, err := h.kgService.GetKnowledgeByID(ctx, id)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -378,6 +495,7 @@ func (h *KnowledgeHandler) GetKnowledge(c *gin.Context) {
 // @Param        tag_id     query     string  false  "标签ID筛选"
 // @Param        keyword    query     string  false  "关键词搜索"
 // @Param        file_type  query     string  false  "文件类型筛选"
+// @Param        is_enabled query     bool    false  "按是否参与检索筛选"
 // @Success      200        {object}  map[string]interface{}  "知识列表"
 // @Failure      400        {object}  errors.AppError         "请求参数错误"
 // @Security     Bearer
@@ -407,6 +525,15 @@ func (h *KnowledgeHandler) ListKnowledge(c *gin.Context) {
 	tagID := c.Query("tag_id")
 	keyword := c.Query("keyword")
 	fileType := c.Query("file_type")
+	var isEnabled *bool
+	if raw := c.Query("is_enabled"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.Error(errors.NewBadRequestError("is_enabled 参数不合法").WithDetails(err.Error()))
+			return
+		}
+		isEnabled = &parsed
+	}
 
 	logger.Infof(
 		ctx,
@@ -420,10 +547,10 @@ func (h *KnowledgeHandler) ListKnowledge(c *gin.Context) {
 	)
 
 	// Retrieve paginated knowledge entries
-	result, err := h.kgService.ListPagedKnowledgeByKnowledgeBaseID(ctx, kbID, &pagination, tagID, keyword, fileType)
+	result, err := h.kgService.ListPagedKnowledgeByKnowledgeBaseID(ctx, kbID, &pagination, tagID, keyword, fileType, isEnabled)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -471,7 +598,7 @@ func (h *KnowledgeHandler) DeleteKnowledge(c *gin.Context) {
 	err := h.kgService.DeleteKnowledge(ctx, id)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -640,7 +767,7 @@ func (h *KnowledgeHandler) UpdateKnowledge(c *gin.Context) {
 
 	if err := h.kgService.UpdateKnowledge(ctx, &knowledge); err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -691,7 +818,7 @@ func (h *KnowledgeHandler) UpdateManualKnowledge(c *gin.Context) {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{
 			"knowledge_id": id,
 		})
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -736,6 +863,40 @@ func (h *KnowledgeHandler) UpdateKnowledgeTagBatch(c *gin.Context) {
 	})
 }
 
+type knowledgeEnabledBatchRequest struct {
+	Updates map[string]bool `json:"updates" binding:"required,min=1"`
+}
+
+// UpdateKnowledgeEnabledBatch godoc
+// @Summary      批量启用/停用知识
+// @Description  批量设置知识是否参与检索，停用的知识仍可查看和下载
+// @Tags         知识管理
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "启用状态更新请求"
+// @Success      200      {object}  map[string]interface{}  "更新成功"
+// @Failure      400      {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/enabled [put]
+func (h *KnowledgeHandler) UpdateKnowledgeEnabledBatch(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req knowledgeEnabledBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse knowledge enabled batch request", err)
+		c.Error(errors.NewBadRequestError("请求参数不合法").WithDetails(err.Error()))
+		return
+	}
+	if err := h.kgService.UpdateKnowledgeEnabledBatch(ctx, req.Updates); err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
 // UpdateImageInfo godoc
 // @Summary      更新图像信息
 // @Description  更新知识分块的图像信息
@@ -783,7 +944,7 @@ func (h *KnowledgeHandler) UpdateImageInfo(c *gin.Context) {
 	err := h.kgService.UpdateImageInfo(ctx, id, chunkID, secutils.SanitizeForLog(request.ImageInfo))
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -840,3 +1001,37 @@ func (h *KnowledgeHandler) SearchKnowledge(c *gin.Context) {
 		"has_more": hasMore,
 	})
 }
+
+// GetSupportedFormats godoc
+// @Summary      List supported file formats
+// @Description  List the file formats that can be uploaded as knowledge, with per-format notes and availability
+// @Tags         Knowledge
+// @Accept       json
+// @Produce      json
+// @Param        knowledge_base_id  query     string  false  "Evaluate availability against this knowledge base's multimodal configuration"
+// @Success      200                {object}  map[string]interface{}  "Supported file formats"
+// @Failure      404                {object}  errors.AppError         "Knowledge base not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge/supported-formats [get]
+func (h *KnowledgeHandler) GetSupportedFormats(c *gin.Context) {
+	ctx := c.Request.Context()
+	kbID := secutils.SanitizeForLog(c.Query("knowledge_base_id"))
+
+	formats, err := h.kgService.GetSupportedFileFormats(ctx, kbID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"knowledge_base_id": kbID,
+		})
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"formats":         formats,
+			"supported_types": types.AvailableFileExtensions(formats),
+		},
+	})
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/middleware"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeKnowledgeBaseService serves a single knowledge base of tenant 1
+type fakeKnowledgeBaseService struct {
+	interfaces.KnowledgeBaseService
+}
+
+func (f *fakeKnowledgeBaseService) GetKnowledgeBaseByID(context.Context, string) (*types.KnowledgeBase, error) {
+	return &types.KnowledgeBase{ID: "kb-1", TenantID: 1}, nil
+}
+
+// fakeKnowledgeService records the staging calls of a file upload
+type fakeKnowledgeService struct {
+	interfaces.KnowledgeService
+	// keepOnReadError stages the file even when reading it failed
+	keepOnReadError bool
+	// duplicate makes the knowledge creation report an existing knowledge
+	duplicate *types.Knowledge
+
+	staged    int
+	created   int
+	discarded int
+}
+
+func (f *fakeKnowledgeService) StageKnowledgeFile(
+	_ context.Context, _ string, fileName string, content io.Reader,
+) (*types.StagedKnowledgeFile, error) {
+	n, err := io.Copy(io.Discard, content)
+	if err != nil && !f.keepOnReadError {
+		return nil, err
+	}
+	f.staged++
+	return &types.StagedKnowledgeFile{KnowledgeID: "k-new", FileName: fileName, FileSize: n}, nil
+}
+
+func (f *fakeKnowledgeService) CreateKnowledgeFromStagedFile(context.Context,
+	string, *types.StagedKnowledgeFile, map[string]string, *bool, string, string,
+) (*types.Knowledge, error) {
+	f.created++
+	if f.duplicate != nil {
+		return f.duplicate, types.NewDuplicateFileError(f.duplicate)
+	}
+	return &types.Knowledge{ID: "k-new"}, nil
+}
+
+func (f *fakeKnowledgeService) DiscardStagedKnowledgeFile(context.Context, *types.StagedKnowledgeFile) {
+	f.discarded++
+}
+
+func TestCreateKnowledgeFromFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("MAX_FILE_SIZE_MB", "1")
+	const limit = 1 << 20
+
+	tests := []struct {
+		name          string
+		fileSize      int
+		service       *fakeKnowledgeService
+		wantStatus    int
+		wantCode      string
+		wantCreated   int
+		wantDiscarded int
+	}{
+		{
+			name:        "created",
+			fileSize:    limit,
+			service:     &fakeKnowledgeService{},
+			wantStatus:  http.StatusOK,
+			wantCreated: 1,
+		},
+		{
+			name:       "oversize rejected while streaming",
+			fileSize:   limit + 1,
+			service:    &fakeKnowledgeService{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:          "oversize staged file is discarded",
+			fileSize:      limit + 1,
+			service:       &fakeKnowledgeService{keepOnReadError: true},
+			wantStatus:    http.StatusBadRequest,
+			wantDiscarded: 1,
+		},
+		{
+			name:     "duplicate",
+			fileSize: 16,
+			service: &fakeKnowledgeService{
+				duplicate: &types.Knowledge{ID: "k-old", FileName: "doc.txt"},
+			},
+			wantStatus:  http.StatusConflict,
+			wantCode:    "duplicate_file",
+			wantCreated: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewKnowledgeHandler(tt.service, &fakeKnowledgeBaseService{}, nil)
+			router := gin.New()
+			router.Use(middleware.ErrorHandler(), func(c *gin.Context) {
+				c.Set(types.TenantIDContextKey.String(), uint64(1))
+			})
+			router.POST("/knowledge-bases/:id/knowledge/file", h.CreateKnowledgeFromFile)
+
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			part, _ := form.CreateFormFile("file", "doc.txt")
+			part.Write(bytes.Repeat([]byte("a"), tt.fileSize))
+			form.WriteField("tag_id", "__untagged__")
+			form.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/knowledge-bases/kb-1/knowledge/file", &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantCode != "" {
+				var resp struct {
+					Code string `json:"code"`
+				}
+				json.Unmarshal(rec.Body.Bytes(), &resp)
+				if resp.Code != tt.wantCode {
+					t.Errorf("code = %q, want %q", resp.Code, tt.wantCode)
+				}
+			}
+			if tt.service.created != tt.wantCreated {
+				t.Errorf("created %d times, want %d", tt.service.created, tt.wantCreated)
+			}
+			if tt.service.discarded != tt.wantDiscarded {
+				t.Errorf("discarded %d times, want %d", tt.service.discarded, tt.wantDiscarded)
+			}
+		})
+	}
+}
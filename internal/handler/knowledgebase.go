@@ -2,10 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/database"
 	"github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
@@ -21,6 +24,9 @@ type KnowledgeBaseHandler struct {
 	service          interfaces.KnowledgeBaseService
 	knowledgeService interfaces.KnowledgeService
 	asynqClient      *asynq.Client
+	answerCache      interfaces.AnswerCacheService
+	taskWebhooks     interfaces.TaskWebhookService
+	config           *config.Config
 }
 
 // NewKnowledgeBaseHandler creates a new knowledge base handler instance
@@ -28,11 +34,17 @@ func NewKnowledgeBaseHandler(
 	service interfaces.KnowledgeBaseService,
 	knowledgeService interfaces.KnowledgeService,
 	asynqClient *asynq.Client,
+	answerCache interfaces.AnswerCacheService,
+	taskWebhooks interfaces.TaskWebhookService,
+	config *config.Config,
 ) *KnowledgeBaseHandler {
 	return &KnowledgeBaseHandler{
 		service:          service,
 		knowledgeService: knowledgeService,
 		asynqClient:      asynqClient,
+		answerCache:      answerCache,
+		taskWebhooks:     taskWebhooks,
+		config:           config,
 	}
 }
 
@@ -69,23 +81,49 @@ func (h *KnowledgeBaseHandler) HybridSearch(c *gin.Context) {
 		c.Error(errors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
 		return
 	}
+	if err := req.VectorSearch.Validate(); err != nil {
+		logger.Error(ctx, "Invalid vector search parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid vector search parameters").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Dedup.Validate(); err != nil {
+		logger.Error(ctx, "Invalid dedup parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid dedup parameters").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Fusion.Validate(); err != nil {
+		logger.Error(ctx, "Invalid fusion parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid fusion parameters").WithDetails(err.Error()))
+		return
+	}
+	if err := req.SourceQuota.Validate(); err != nil {
+		logger.Error(ctx, "Invalid source quota parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid source quota parameters").WithDetails(err.Error()))
+		return
+	}
+	if err := types.ValidateRetrievalMode(req.RetrievalMode); err != nil {
+		logger.Error(ctx, "Invalid retrieval mode", err)
+		c.Error(errors.NewBadRequestError("Invalid retrieval mode").WithDetails(err.Error()))
+		return
+	}
 
 	logger.Infof(ctx, "Executing hybrid search, knowledge base ID: %s, query: %s",
 		secutils.SanitizeForLog(id), secutils.SanitizeForLog(req.QueryText))
 
-	// Execute hybrid search with default search parameters
-	results, err := h.service.HybridSearch(ctx, id, req)
+	// Execute hybrid search with default search parameters; its reads tolerate replication lag
+	results, err := h.service.HybridSearch(database.WithReplicaReads(ctx), id, req)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
 	logger.Infof(ctx, "Hybrid search completed, knowledge base ID: %s, result count: %d",
 		secutils.SanitizeForLog(id), len(results))
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    results,
+		"success":         true,
+		"data":            results,
+		"collapsed_count": types.CountCollapsed(results),
 	})
 }
 
@@ -118,13 +156,99 @@ func (h *KnowledgeBaseHandler) CreateKnowledgeBase(c *gin.Context) {
 		c.Error(err)
 		return
 	}
+	if err := req.ChunkingConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid chunking configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid chunking configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.VectorSearchConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid vector search configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid vector search configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.DedupConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid dedup configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid dedup configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.SourceQuotaConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid source quota configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid source quota configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.ConfidenceGateConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid confidence gate configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid confidence gate configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.FusionConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid fusion configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid fusion configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.VectorSpaceConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid vector space configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid vector space configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.MetadataSchemaConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid metadata schema configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid metadata schema configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.AnswerCacheConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid answer cache configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid answer cache configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.DocumentSummaryConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid document summary configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid document summary configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.ParentChildConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid parent-child configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid parent-child configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.RerankFallbackConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid rerank fallback configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid rerank fallback configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.GroundingConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid grounding configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid grounding configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.CitationConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid citation configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid citation configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.PipelineConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid pipeline configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid pipeline configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := types.ValidateHistoryDepth(req.HistoryDepth, false); err != nil {
+		logger.Error(ctx, "Invalid history depth", err)
+		c.Error(errors.NewBadRequestError("Invalid history depth").WithDetails(err.Error()))
+		return
+	}
+	if req.IsMultimodalEnabled() {
+		if appErr := requireFeature(ctx, h.config, types.FeatureMultimodal); appErr != nil {
+			c.Error(appErr)
+			return
+		}
+	}
 
 	logger.Infof(ctx, "Creating knowledge base, name: %s", secutils.SanitizeForLog(req.Name))
 	// Create knowledge base using the service
 	kb, err := h.service.CreateKnowledgeBase(ctx, &req)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -159,7 +283,7 @@ func (h *KnowledgeBaseHandler) validateAndGetKnowledgeBase(c *gin.Context) (*typ
 	kb, err := h.service.GetKnowledgeBaseByID(ctx, id)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		return nil, id, errors.NewInternalServerError(err.Error())
+		return nil, id, errors.FromError(err)
 	}
 
 	// Verify tenant ownership
@@ -220,7 +344,7 @@ func (h *KnowledgeBaseHandler) ListKnowledgeBases(c *gin.Context) {
 	kbs, err := h.service.ListKnowledgeBases(ctx)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -269,6 +393,93 @@ func (h *KnowledgeBaseHandler) UpdateKnowledgeBase(c *gin.Context) {
 		return
 	}
 
+	if err := req.Config.ChunkingConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid chunking configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid chunking configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.VectorSearchConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid vector search configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid vector search configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.DedupConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid dedup configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid dedup configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.SourceQuotaConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid source quota configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid source quota configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.ConfidenceGateConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid confidence gate configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid confidence gate configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.FusionConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid fusion configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid fusion configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.VectorSpaceConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid vector space configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid vector space configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.MetadataSchemaConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid metadata schema configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid metadata schema configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.AnswerCacheConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid answer cache configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid answer cache configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.DocumentSummaryConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid document summary configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid document summary configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.ParentChildConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid parent-child configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid parent-child configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.RerankFallbackConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid rerank fallback configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid rerank fallback configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.GroundingConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid grounding configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid grounding configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.CitationConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid citation configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid citation configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := req.Config.PipelineConfig.Validate(); err != nil {
+		logger.Error(ctx, "Invalid pipeline configuration", err)
+		c.Error(errors.NewBadRequestError("Invalid pipeline configuration").WithDetails(err.Error()))
+		return
+	}
+	if err := types.ValidateHistoryDepth(req.Config.HistoryDepth, true); err != nil {
+		logger.Error(ctx, "Invalid history depth", err)
+		c.Error(errors.NewBadRequestError("Invalid history depth").WithDetails(err.Error()))
+		return
+	}
+	if req.Config.ChunkingConfig.EnableMultimodal {
+		if appErr := requireFeature(ctx, h.config, types.FeatureMultimodal); appErr != nil {
+			c.Error(appErr)
+			return
+		}
+	}
+
 	logger.Infof(ctx, "Updating knowledge base, ID: %s, name: %s",
 		secutils.SanitizeForLog(id), secutils.SanitizeForLog(req.Name))
 
@@ -276,7 +487,7 @@ func (h *KnowledgeBaseHandler) UpdateKnowledgeBase(c *gin.Context) {
 	kb, err := h.service.UpdateKnowledgeBase(ctx, id, req.Name, req.Description, req.Config)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -290,13 +501,15 @@ func (h *KnowledgeBaseHandler) UpdateKnowledgeBase(c *gin.Context) {
 
 // DeleteKnowledgeBase godoc
 // @Summary      删除知识库
-// @Description  删除指定的知识库及其所有内容
+// @Description  删除指定的知识库及其所有内容，被智能体引用时返回409及引用列表，force=true时先从智能体中移除再删除
 // @Tags         知识库
 // @Accept       json
 // @Produce      json
-// @Param        id   path      string  true  "知识库ID"
-// @Success      200  {object}  map[string]interface{}  "删除成功"
-// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Param        id     path      string  true   "知识库ID"
+// @Param        force  query     bool    false  "移除引用后强制删除"
+// @Success      200    {object}  map[string]interface{}  "删除成功"
+// @Failure      400    {object}  errors.AppError         "请求参数错误"
+// @Failure      409    {object}  errors.AppError         "知识库仍被引用"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /knowledge-bases/{id} [delete]
@@ -315,9 +528,9 @@ func (h *KnowledgeBaseHandler) DeleteKnowledgeBase(c *gin.Context) {
 		secutils.SanitizeForLog(id), secutils.SanitizeForLog(kb.Name))
 
 	// Delete the knowledge base
-	if err := h.service.DeleteKnowledgeBase(ctx, id); err != nil {
+	if err := h.service.DeleteKnowledgeBase(ctx, id, c.Query("force") == "true"); err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -333,6 +546,8 @@ type CopyKnowledgeBaseRequest struct {
 	TaskID   string `json:"task_id"`
 	SourceID string `json:"source_id" binding:"required"`
 	TargetID string `json:"target_id"`
+	// WebhookURL is notified when the copy finishes instead of the tenant webhook (optional)
+	WebhookURL string `json:"webhook_url"`
 }
 
 // CopyKnowledgeBaseResponse defines the response for copy knowledge base
@@ -378,6 +593,15 @@ func (h *KnowledgeBaseHandler) CopyKnowledgeBase(c *gin.Context) {
 		taskID = utils.GenerateTaskID("kb_clone", tenantID.(uint64), req.SourceID)
 	}
 
+	// Register the webhook before enqueueing, the task may finish right away
+	if req.WebhookURL != "" {
+		if err := h.taskWebhooks.RegisterTaskWebhook(ctx, taskID, req.WebhookURL); err != nil {
+			logger.Warnf(ctx, "Failed to register KB clone webhook: %v", err)
+			c.Error(err)
+			return
+		}
+	}
+
 	// Create KB clone payload
 	payload := types.KBClonePayload{
 		TenantID: tenantID.(uint64),
@@ -461,6 +685,250 @@ func (h *KnowledgeBaseHandler) GetKBCloneProgress(c *gin.Context) {
 		c.Error(err)
 		return
 	}
+	if progress.Webhook, err = h.taskWebhooks.GetTaskWebhookDelivery(ctx, taskID); err != nil {
+		logger.Warnf(ctx, "Failed to get KB clone webhook delivery: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// ExportKnowledgeBase godoc
+// @Summary      导出知识库
+// @Description  将知识库的元数据、标签、知识、分块、FAQ和原始文件导出为可移植的归档（tar.gz）
+// @Tags         知识库
+// @Produce      application/gzip
+// @Param        id   path      string  true  "知识库ID"
+// @Success      200  {file}    file    "知识库归档"
+// @Failure      404  {object}  errors.AppError  "知识库不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/export [get]
+func (h *KnowledgeBaseHandler) ExportKnowledgeBase(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, id, err := h.validateAndGetKnowledgeBase(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	logger.Infof(ctx, "Exporting knowledge base %s", secutils.SanitizeForLog(id))
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=knowledge_base_%s.tar.gz", id))
+	c.Status(http.StatusOK)
+	// The archive is streamed as it is written, so errors after the first bytes can only be logged
+	if err := h.knowledgeService.ExportKnowledgeBase(ctx, id, c.Writer); err != nil {
+		logger.Errorf(ctx, "Failed to export knowledge base %s: %v", secutils.SanitizeForLog(id), err)
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			c.Error(errors.FromError(err))
+		}
+	}
+}
+
+// ImportKnowledgeBase godoc
+// @Summary      导入知识库
+// @Description  从导出的归档创建新的知识库（异步任务），进度通过知识库复制进度接口查询
+// @Tags         知识库
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file                formData  file    true   "知识库归档"
+// @Param        name                formData  string  false  "新知识库名称"
+// @Param        embedding_model_id  formData  string  false  "嵌入模型ID，默认使用归档中的模型"
+// @Param        summary_model_id    formData  string  false  "摘要模型ID，默认使用归档中的模型"
+// @Success      200  {object}  map[string]interface{}  "任务进度"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/import [post]
+func (h *KnowledgeBaseHandler) ImportKnowledgeBase(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req types.KBImportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		logger.Error(ctx, "Failed to get knowledge base archive", err)
+		c.Error(errors.NewBadRequestError("Knowledge base archive is required").WithDetails(err.Error()))
+		return
+	}
+	archive, err := header.Open()
+	if err != nil {
+		logger.Error(ctx, "Failed to open knowledge base archive", err)
+		c.Error(errors.NewInternalServerError("Failed to read knowledge base archive"))
+		return
+	}
+	defer archive.Close()
+
+	progress, err := h.knowledgeService.ImportKnowledgeBase(ctx, archive, &req)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// MergeKnowledgeBases godoc
+// @Summary      合并知识库
+// @Description  将源知识库的知识、分块、标签和FAQ移动到目标知识库（异步任务），按内容哈希去重，可选删除源知识库
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        request  body      types.KBMergeRequest    true  "合并请求"
+// @Success      200      {object}  map[string]interface{}  "任务进度"
+// @Failure      400      {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/merge [post]
+func (h *KnowledgeBaseHandler) MergeKnowledgeBases(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req types.KBMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Merging knowledge base %s into %s",
+		secutils.SanitizeForLog(req.SourceID), secutils.SanitizeForLog(req.TargetID))
+	progress, err := h.knowledgeService.MergeKnowledgeBases(ctx, &req)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// GetKBMergeProgress godoc
+// @Summary      获取知识库合并进度
+// @Description  获取知识库合并任务的进度
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        task_id  path      string  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "进度信息"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/merge/progress/{task_id} [get]
+func (h *KnowledgeBaseHandler) GetKBMergeProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	taskID := c.Param("task_id")
+	if taskID == "" {
+		logger.Error(ctx, "Task ID is empty")
+		c.Error(errors.NewBadRequestError("Task ID cannot be empty"))
+		return
+	}
+
+	progress, err := h.knowledgeService.GetKBMergeProgress(ctx, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// ReprocessFailedKnowledge godoc
+// @Summary      批量重新处理失败的知识
+// @Description  将知识库中所有处理失败的知识重新入队，作为一个任务跟踪每条知识的结果；可按错误信息或时间过滤
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                        true   "知识库ID"
+// @Param        request  body      types.KnowledgeFailureFilter  false  "过滤条件"
+// @Success      200      {object}  map[string]interface{}        "任务进度"
+// @Failure      400      {object}  errors.AppError               "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/reprocess-failed [post]
+func (h *KnowledgeBaseHandler) ReprocessFailedKnowledge(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	_, id, err := h.validateAndGetKnowledgeBase(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var filter types.KnowledgeFailureFilter
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&filter); err != nil {
+			logger.Error(ctx, "Failed to parse request parameters", err)
+			c.Error(errors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
+			return
+		}
+	}
+	if filter.FailedAfter != nil && filter.FailedBefore != nil && !filter.FailedAfter.Before(*filter.FailedBefore) {
+		c.Error(errors.NewBadRequestError("failed_after must be earlier than failed_before"))
+		return
+	}
+
+	logger.Infof(ctx, "Reprocessing failed knowledge, knowledge base ID: %s", secutils.SanitizeForLog(id))
+	progress, err := h.knowledgeService.ReprocessFailedKnowledge(ctx, id, &filter)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// GetKnowledgeReprocessProgress godoc
+// @Summary      获取批量重新处理进度
+// @Description  获取失败知识批量重新处理任务的进度及每条知识的结果
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        task_id  path      string  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "进度信息"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/reprocess/progress/{task_id} [get]
+func (h *KnowledgeBaseHandler) GetKnowledgeReprocessProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	taskID := c.Param("task_id")
+	if taskID == "" {
+		logger.Error(ctx, "Task ID is empty")
+		c.Error(errors.NewBadRequestError("Task ID cannot be empty"))
+		return
+	}
+
+	progress, err := h.knowledgeService.GetKnowledgeReprocessProgress(ctx, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -468,6 +936,218 @@ func (h *KnowledgeBaseHandler) GetKBCloneProgress(c *gin.Context) {
 	})
 }
 
+// InvalidateAnswerCache godoc
+// @Summary      清除答案缓存
+// @Description  清除使用该知识库生成的所有缓存答案
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识库ID"
+// @Success      200  {object}  map[string]interface{}  "清除成功"
+// @Failure      404  {object}  errors.AppError         "知识库不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/cache/invalidate [post]
+func (h *KnowledgeBaseHandler) InvalidateAnswerCache(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	_, id, err := h.validateAndGetKnowledgeBase(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.answerCache.Invalidate(ctx, id); err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Answer cache invalidated",
+	})
+}
+
+// pinnedSourceRequest is the body of pinned source rule create and update requests
+type pinnedSourceRequest struct {
+	Name               string   `json:"name"`
+	MatchType          string   `json:"match_type"`
+	Patterns           []string `json:"patterns"`
+	Threshold          float64  `json:"threshold"`
+	KnowledgeIDs       []string `json:"knowledge_ids"`
+	ChunksPerKnowledge int      `json:"chunks_per_knowledge"`
+	// Enabled defaults to true
+	Enabled *bool `json:"enabled"`
+}
+
+// toRule converts the request into a rule
+func (r *pinnedSourceRequest) toRule() *types.PinnedSourceRule {
+	return &types.PinnedSourceRule{
+		Name:               r.Name,
+		MatchType:          r.MatchType,
+		Patterns:           r.Patterns,
+		Threshold:          r.Threshold,
+		KnowledgeIDs:       r.KnowledgeIDs,
+		ChunksPerKnowledge: r.ChunksPerKnowledge,
+		Enabled:            r.Enabled == nil || *r.Enabled,
+	}
+}
+
+// ListPinnedSources godoc
+// @Summary      获取置顶来源规则
+// @Description  获取知识库的置顶来源规则列表
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "知识库ID"
+// @Success      200  {object}  map[string]interface{}  "规则列表"
+// @Failure      404  {object}  errors.AppError         "知识库不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/pinned-sources [get]
+func (h *KnowledgeBaseHandler) ListPinnedSources(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	_, id, err := h.validateAndGetKnowledgeBase(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	rules, err := h.service.ListPinnedSources(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rules,
+	})
+}
+
+// CreatePinnedSource godoc
+// @Summary      创建置顶来源规则
+// @Description  匹配规则的查询会将指定知识置顶于检索结果
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string               true  "知识库ID"
+// @Param        request  body      pinnedSourceRequest  true  "规则"
+// @Success      201      {object}  map[string]interface{}  "创建的规则"
+// @Failure      400      {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/pinned-sources [post]
+func (h *KnowledgeBaseHandler) CreatePinnedSource(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	_, id, err := h.validateAndGetKnowledgeBase(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req pinnedSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
+		return
+	}
+
+	rule, err := h.service.CreatePinnedSource(ctx, id, req.toRule())
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    rule,
+	})
+}
+
+// UpdatePinnedSource godoc
+// @Summary      更新置顶来源规则
+// @Description  替换知识库的置顶来源规则
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string               true  "知识库ID"
+// @Param        rule_id  path      string               true  "规则ID"
+// @Param        request  body      pinnedSourceRequest  true  "规则"
+// @Success      200      {object}  map[string]interface{}  "更新的规则"
+// @Failure      400      {object}  errors.AppError         "请求参数错误"
+// @Failure      404      {object}  errors.AppError         "规则不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/pinned-sources/{rule_id} [put]
+func (h *KnowledgeBaseHandler) UpdatePinnedSource(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	_, id, err := h.validateAndGetKnowledgeBase(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req pinnedSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
+		return
+	}
+
+	rule, err := h.service.UpdatePinnedSource(ctx, id, secutils.SanitizeForLog(c.Param("rule_id")), req.toRule())
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rule,
+	})
+}
+
+// DeletePinnedSource godoc
+// @Summary      删除置顶来源规则
+// @Description  删除知识库的置顶来源规则
+// @Tags         知识库
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "知识库ID"
+// @Param        rule_id  path      string  true  "规则ID"
+// @Success      200      {object}  map[string]interface{}  "删除成功"
+// @Failure      404      {object}  errors.AppError         "规则不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-bases/{id}/pinned-sources/{rule_id} [delete]
+func (h *KnowledgeBaseHandler) DeletePinnedSource(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	_, id, err := h.validateAndGetKnowledgeBase(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.service.DeletePinnedSource(ctx, id, secutils.SanitizeForLog(c.Param("rule_id"))); err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Pinned source rule deleted",
+	})
+}
+
 // validateExtractConfig validates the graph configuration parameters
 func validateExtractConfig(config *types.ExtractConfig) error {
 	if config == nil {
@@ -1,10 +1,12 @@
 package handler
 
 import (
+	stderrors "errors"
 	"net/http"
 
 	"github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/mcp"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	secutils "github.com/Tencent/WeKnora/internal/utils"
@@ -53,6 +55,11 @@ func (h *MCPServiceHandler) CreateMCPService(c *gin.Context) {
 	}
 	service.TenantID = tenantID
 
+	if err := service.AdvancedConfig.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()).WithCode(errors.CodeMCPServiceInvalidConfig))
+		return
+	}
+
 	if err := h.mcpServiceService.CreateMCPService(ctx, &service); err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_name": secutils.SanitizeForLog(service.Name)})
 		c.Error(errors.NewInternalServerError("Failed to create MCP service: " + err.Error()))
@@ -125,7 +132,7 @@ func (h *MCPServiceHandler) GetMCPService(c *gin.Context) {
 	service, err := h.mcpServiceService.GetMCPServiceByID(ctx, tenantID, serviceID)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": secutils.SanitizeForLog(serviceID)})
-		c.Error(errors.NewNotFoundError("MCP service not found"))
+		c.Error(errors.NewNotFoundError("MCP service not found").WithCode(errors.CodeMCPServiceNotFound))
 		return
 	}
 
@@ -252,6 +259,16 @@ func (h *MCPServiceHandler) UpdateMCPService(c *gin.Context) {
 		if retryDelay, ok := advancedConfig["retry_delay"].(float64); ok {
 			service.AdvancedConfig.RetryDelay = int(retryDelay)
 		}
+		if threshold, ok := advancedConfig["breaker_threshold"].(float64); ok {
+			service.AdvancedConfig.BreakerThreshold = int(threshold)
+		}
+		if cooldown, ok := advancedConfig["breaker_cooldown"].(float64); ok {
+			service.AdvancedConfig.BreakerCooldown = int(cooldown)
+		}
+		if err := service.AdvancedConfig.Validate(); err != nil {
+			c.Error(errors.NewBadRequestError(err.Error()).WithCode(errors.CodeMCPServiceInvalidConfig))
+			return
+		}
 	}
 
 	if err := h.mcpServiceService.UpdateMCPService(ctx, &service); err != nil {
@@ -357,6 +374,7 @@ func (h *MCPServiceHandler) TestMCPService(c *gin.Context) {
 // @Param        id   path      string  true  "MCP服务ID"
 // @Success      200  {object}  map[string]interface{}  "工具列表"
 // @Failure      500  {object}  errors.AppError         "服务器错误"
+// @Failure      503  {object}  errors.AppError         "服务熔断中，暂时不可用"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /mcp-services/{id}/tools [get]
@@ -374,7 +392,7 @@ func (h *MCPServiceHandler) GetMCPServiceTools(c *gin.Context) {
 	tools, err := h.mcpServiceService.GetMCPServiceTools(ctx, tenantID, serviceID)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": secutils.SanitizeForLog(serviceID)})
-		c.Error(errors.NewInternalServerError("Failed to get MCP service tools: " + err.Error()))
+		c.Error(mcpCallError("Failed to get MCP service tools", err))
 		return
 	}
 
@@ -384,6 +402,53 @@ func (h *MCPServiceHandler) GetMCPServiceTools(c *gin.Context) {
 	})
 }
 
+// GetMCPServiceHealth godoc
+// @Summary      获取MCP服务健康报告
+// @Description  获取MCP服务工具的最近一次校验结果（可达性、工具Schema是否有效、变更或缺失），refresh=true时立即重新校验
+// @Tags         MCP服务
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true   "MCP服务ID"
+// @Param        refresh  query     bool    false  "立即重新校验"
+// @Success      200      {object}  map[string]interface{}  "健康报告，尚未校验时为null"
+// @Failure      404      {object}  errors.AppError         "MCP服务不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /mcp-services/{id}/health [get]
+func (h *MCPServiceHandler) GetMCPServiceHealth(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	service, err := h.mcpServiceService.GetMCPServiceByID(ctx, tenantID, serviceID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": secutils.SanitizeForLog(serviceID)})
+		c.Error(errors.NewNotFoundError("MCP service not found"))
+		return
+	}
+
+	health := service.Health
+	if c.Query("refresh") == "true" {
+		health, err = h.mcpServiceService.CheckMCPServiceHealth(ctx, tenantID, serviceID)
+		if err != nil {
+			logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": secutils.SanitizeForLog(serviceID)})
+			c.Error(errors.NewInternalServerError("Failed to check MCP service health: " + err.Error()))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    health,
+	})
+}
+
 // GetMCPServiceResources godoc
 // @Summary      获取MCP服务资源列表
 // @Description  获取MCP服务提供的资源列表
@@ -393,6 +458,7 @@ func (h *MCPServiceHandler) GetMCPServiceTools(c *gin.Context) {
 // @Param        id   path      string  true  "MCP服务ID"
 // @Success      200  {object}  map[string]interface{}  "资源列表"
 // @Failure      500  {object}  errors.AppError         "服务器错误"
+// @Failure      503  {object}  errors.AppError         "服务熔断中，暂时不可用"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /mcp-services/{id}/resources [get]
@@ -410,7 +476,7 @@ func (h *MCPServiceHandler) GetMCPServiceResources(c *gin.Context) {
 	resources, err := h.mcpServiceService.GetMCPServiceResources(ctx, tenantID, serviceID)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": secutils.SanitizeForLog(serviceID)})
-		c.Error(errors.NewInternalServerError("Failed to get MCP service resources: " + err.Error()))
+		c.Error(mcpCallError("Failed to get MCP service resources", err))
 		return
 	}
 
@@ -419,3 +485,13 @@ func (h *MCPServiceHandler) GetMCPServiceResources(c *gin.Context) {
 		"data":    resources,
 	})
 }
+
+// mcpCallError maps a failed call to an MCP service to a 503 when its circuit breaker is open, so that
+// clients can tell a service known to be failing from other errors
+func mcpCallError(message string, err error) error {
+	if stderrors.Is(err, mcp.ErrCircuitOpen) {
+		return errors.NewServiceUnavailableError(message + ": service temporarily unavailable").
+			WithCode(errors.CodeMCPServiceUnavailable)
+	}
+	return errors.NewInternalServerError(message + ": " + err.Error())
+}
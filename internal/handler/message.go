@@ -7,8 +7,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/Tencent/WeKnora/internal/analytics"
 	"github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	secutils "github.com/Tencent/WeKnora/internal/utils"
 )
@@ -17,27 +19,39 @@ import (
 // It provides endpoints for loading and managing message history
 type MessageHandler struct {
 	MessageService interfaces.MessageService // Service that implements message business logic
+	analytics      *analytics.Emitter        // Receives the answer feedback, nil when analytics are disabled
 }
 
 // NewMessageHandler creates a new message handler instance with the required service
 // Parameters:
 //   - messageService: Service that implements message business logic
+//   - analyticsEmitter: Emitter of the analytics events, nil when analytics are disabled
 //
 // Returns a pointer to a new MessageHandler
-func NewMessageHandler(messageService interfaces.MessageService) *MessageHandler {
+func NewMessageHandler(messageService interfaces.MessageService,
+	analyticsEmitter *analytics.Emitter,
+) *MessageHandler {
 	return &MessageHandler{
 		MessageService: messageService,
+		analytics:      analyticsEmitter,
 	}
 }
 
+// MessageFeedbackRequest is the rating of an assistant answer
+type MessageFeedbackRequest struct {
+	// Rating is "up" or "down"
+	Rating string `json:"rating" binding:"required,oneof=up down"`
+}
+
 // LoadMessages godoc
 // @Summary      加载消息历史
-// @Description  加载会话的消息历史，支持分页和时间筛选
+// @Description  加载会话的消息历史，支持游标分页和时间筛选
 // @Tags         消息
 // @Accept       json
 // @Produce      json
 // @Param        session_id   path      string  true   "会话ID"
 // @Param        limit        query     int     false  "返回数量"  default(20)
+// @Param        before       query     string  false  "上一页返回的next_cursor，返回该消息之前的消息（按时间倒序）"
 // @Param        before_time  query     string  false  "在此时间之前的消息（RFC3339Nano格式）"
 // @Success      200          {object}  map[string]interface{}  "消息列表"
 // @Failure      400          {object}  errors.AppError         "请求参数错误"
@@ -52,6 +66,7 @@ func (h *MessageHandler) LoadMessages(c *gin.Context) {
 	// Get path parameters and query parameters
 	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
 	limit := secutils.SanitizeForLog(c.DefaultQuery("limit", "20"))
+	before := c.Query("before")
 	beforeTimeStr := secutils.SanitizeForLog(c.DefaultQuery("before_time", ""))
 
 	logger.Infof(ctx, "Loading messages params, session ID: %s, limit: %s, before time: %s",
@@ -59,29 +74,60 @@ func (h *MessageHandler) LoadMessages(c *gin.Context) {
 
 	// Parse limit parameter with fallback to default
 	limitInt, err := strconv.Atoi(limit)
-	if err != nil {
+	if err != nil || limitInt <= 0 {
 		logger.Warnf(ctx, "Invalid limit value, using default value 20, input: %s", limit)
 		limitInt = 20
 	}
 
+	// A cursor returns the messages strictly before it, newest first
+	if before != "" {
+		cursor, err := types.ParseMessageCursor(before)
+		if err != nil {
+			logger.Warnf(ctx, "Invalid message cursor: %s", secutils.SanitizeForLog(before))
+			c.Error(errors.NewBadRequestError("Invalid before cursor"))
+			return
+		}
+		// One more message than the limit tells whether older messages remain
+		messages, err := h.MessageService.GetMessagesBySessionBeforeCursor(ctx, sessionID, cursor, limitInt+1)
+		if err != nil {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.FromError(err))
+			return
+		}
+		page := types.NewMessagePage(messages, limitInt)
+		logger.Infof(ctx, "Successfully retrieved messages before cursor, session ID: %s, message count: %d",
+			sessionID, len(page.Messages))
+		c.JSON(http.StatusOK, gin.H{
+			"success":     true,
+			"data":        page.Messages,
+			"next_cursor": page.NextCursor,
+			"has_more":    page.HasMore,
+		})
+		return
+	}
+
 	// If no beforeTime is provided, retrieve the most recent messages
 	if beforeTimeStr == "" {
 		logger.Infof(ctx, "Getting recent messages for session, session ID: %s, limit: %d", sessionID, limitInt)
-		messages, err := h.MessageService.GetRecentMessagesBySession(ctx, sessionID, limitInt)
+		messages, err := h.MessageService.GetRecentMessagesBySession(ctx, sessionID, limitInt+1)
 		if err != nil {
 			logger.ErrorWithFields(ctx, err, nil)
-			c.Error(errors.NewInternalServerError(err.Error()))
+			c.Error(errors.FromError(err))
 			return
 		}
+		page := types.NewMessagePage(messages, limitInt)
 
 		logger.Infof(
 			ctx,
 			"Successfully retrieved recent messages, session ID: %s, message count: %d",
-			sessionID, len(messages),
+			sessionID, len(page.Messages),
 		)
+		// Recent messages keep the chronological order; next_cursor continues with older messages
 		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"data":    messages,
+			"success":     true,
+			"data":        page.Messages,
+			"next_cursor": page.NextCursor,
+			"has_more":    page.HasMore,
 		})
 		return
 	}
@@ -104,7 +150,7 @@ func (h *MessageHandler) LoadMessages(c *gin.Context) {
 	messages, err := h.MessageService.GetMessagesBySessionBeforeTime(ctx, sessionID, beforeTime, limitInt)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -119,6 +165,39 @@ func (h *MessageHandler) LoadMessages(c *gin.Context) {
 	})
 }
 
+// GetMessage godoc
+// @Summary      获取消息
+// @Description  获取会话中的指定消息，可用于查询后台生成（async）的答案
+// @Tags         消息
+// @Accept       json
+// @Produce      json
+// @Param        session_id  path      string  true  "会话ID"
+// @Param        id          path      string  true  "消息ID"
+// @Success      200         {object}  map[string]interface{}  "消息"
+// @Failure      404         {object}  errors.AppError         "消息不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /messages/{session_id}/{id} [get]
+func (h *MessageHandler) GetMessage(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+	messageID := secutils.SanitizeForLog(c.Param("id"))
+	logger.Infof(ctx, "Getting message, session ID: %s, message ID: %s", sessionID, messageID)
+
+	message, err := h.MessageService.GetMessage(ctx, sessionID, messageID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewNotFoundError("Message not found").WithCode(errors.CodeMessageNotFound))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    message,
+	})
+}
+
 // DeleteMessage godoc
 // @Summary      删除消息
 // @Description  从会话中删除指定消息
@@ -146,7 +225,7 @@ func (h *MessageHandler) DeleteMessage(c *gin.Context) {
 	// Delete the message using the message service
 	if err := h.MessageService.DeleteMessage(ctx, sessionID, messageID); err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -156,3 +235,73 @@ func (h *MessageHandler) DeleteMessage(c *gin.Context) {
 		"message": "Message deleted successfully",
 	})
 }
+
+// GiveFeedback godoc
+// @Summary      评价回答
+// @Description  对助手回答点赞或点踩；评价作为 feedback_given 事件发送到分析接收端，不会保存
+// @Tags         消息
+// @Accept       json
+// @Produce      json
+// @Param        session_id  path      string                  true  "会话ID"
+// @Param        id          path      string                  true  "消息ID"
+// @Param        request     body      MessageFeedbackRequest  true  "评价"
+// @Success      200         {object}  map[string]interface{}  "评价已接收"
+// @Failure      400         {object}  errors.AppError         "请求参数错误"
+// @Failure      404         {object}  errors.AppError         "消息不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /messages/{session_id}/{id}/feedback [post]
+func (h *MessageHandler) GiveFeedback(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+	messageID := secutils.SanitizeForLog(c.Param("id"))
+
+	var req MessageFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.NewBadRequestError("rating must be up or down").WithDetails(err.Error()))
+		return
+	}
+
+	message, err := h.MessageService.GetMessage(ctx, sessionID, messageID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewNotFoundError("Message not found").WithCode(errors.CodeMessageNotFound))
+		return
+	}
+	if message.Role != "assistant" {
+		c.Error(errors.NewBadRequestError("Only assistant answers can be rated"))
+		return
+	}
+
+	logger.Infof(ctx, "Received %s feedback, session ID: %s, message ID: %s", req.Rating, sessionID, messageID)
+	h.analytics.Emit(ctx, &analytics.Event{
+		Type:      analytics.EventFeedbackGiven,
+		SessionID: message.SessionID,
+		MessageID: message.ID,
+		Attributes: map[string]interface{}{
+			"rating":            req.Rating,
+			"answer_request_id": message.RequestID,
+			"reference_count":   len(message.KnowledgeReferences),
+			"knowledge_ids":     referencedKnowledgeIDs(message.KnowledgeReferences),
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// referencedKnowledgeIDs returns the IDs of the knowledge items referenced by an answer
+func referencedKnowledgeIDs(references types.References) []string {
+	seen := make(map[string]bool)
+	ids := make([]string, 0, len(references))
+	for _, ref := range references {
+		if ref == nil || ref.KnowledgeID == "" || seen[ref.KnowledgeID] {
+			continue
+		}
+		seen[ref.KnowledgeID] = true
+		ids = append(ids, ref.KnowledgeID)
+	}
+	return ids
+}
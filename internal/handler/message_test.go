@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/middleware"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeMessageService keeps the messages of a single session in memory
+type fakeMessageService struct {
+	interfaces.MessageService
+	messages []*types.Message
+}
+
+func (f *fakeMessageService) add(id string, createdAt time.Time) {
+	f.messages = append(f.messages, &types.Message{ID: id, SessionID: "s-1", CreatedAt: createdAt})
+}
+
+func (f *fakeMessageService) GetRecentMessagesBySession(ctx context.Context,
+	sessionID string, limit int,
+) ([]*types.Message, error) {
+	messages, _ := f.GetMessagesBySessionBeforeCursor(ctx, sessionID, nil, limit)
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+func (f *fakeMessageService) GetMessagesBySessionBeforeCursor(_ context.Context,
+	_ string, cursor *types.MessageCursor, limit int,
+) ([]*types.Message, error) {
+	var messages []*types.Message
+	for _, message := range f.messages {
+		if cursor == nil || cursor.Precedes(message) {
+			messages = append(messages, message)
+		}
+	}
+	slices.SortFunc(messages, func(a, b *types.Message) int {
+		if cmp := b.CreatedAt.Compare(a.CreatedAt); cmp != 0 {
+			return cmp
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+	return messages[:min(limit, len(messages))], nil
+}
+
+type loadMessagesResponse struct {
+	Data       []*types.Message `json:"data"`
+	NextCursor string           `json:"next_cursor"`
+	HasMore    bool             `json:"has_more"`
+}
+
+func loadMessages(t *testing.T, router *gin.Engine, query url.Values) (int, loadMessagesResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/messages/s-1/load?"+query.Encode(), nil))
+	var resp loadMessagesResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestLoadMessagesCursorPagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &fakeMessageService{}
+	start := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	// Question and answer pairs share their creation time
+	for i := range 5 {
+		service.add(fmt.Sprintf("m-%d-a", i), start.Add(time.Duration(i)*time.Minute))
+		service.add(fmt.Sprintf("m-%d-b", i), start.Add(time.Duration(i)*time.Minute))
+	}
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.GET("/messages/:session_id/load", NewMessageHandler(service, nil).LoadMessages)
+
+	code, first := loadMessages(t, router, url.Values{"limit": {"3"}})
+	if code != http.StatusOK || !first.HasMore || first.NextCursor == "" {
+		t.Fatalf("first page = %d %+v, want more messages", code, first)
+	}
+	var seen []string
+	for _, message := range first.Data {
+		seen = append(seen, message.ID)
+	}
+	if !slices.Equal(seen, []string{"m-3-b", "m-4-a", "m-4-b"}) {
+		t.Fatalf("recent messages = %v", seen)
+	}
+
+	cursor := first.NextCursor
+	for page := 0; ; page++ {
+		// New messages arrive while scrolling up, some created at the same time as the loaded ones
+		service.add(fmt.Sprintf("new-%d", page), start.Add(time.Hour))
+		service.add(fmt.Sprintf("m-4-%d", page), start.Add(4*time.Minute))
+
+		code, resp := loadMessages(t, router, url.Values{"limit": {"3"}, "before": {cursor}})
+		if code != http.StatusOK {
+			t.Fatalf("page %d status = %d", page, code)
+		}
+		for i, message := range resp.Data {
+			if i > 0 && message.CreatedAt.After(resp.Data[i-1].CreatedAt) {
+				t.Errorf("page %d is not in descending order", page)
+			}
+			seen = append(seen, message.ID)
+		}
+		if !resp.HasMore {
+			if resp.NextCursor != "" {
+				t.Errorf("last page next_cursor = %q, want none", resp.NextCursor)
+			}
+			break
+		}
+		cursor = resp.NextCursor
+		if page > 5 {
+			t.Fatal("pagination does not end")
+		}
+	}
+
+	want := []string{"m-3-b", "m-4-a", "m-4-b", "m-3-a", "m-2-b", "m-2-a", "m-1-b", "m-1-a", "m-0-b", "m-0-a"}
+	if !slices.Equal(seen, want) {
+		t.Errorf("loaded messages = %v, want %v", seen, want)
+	}
+
+	// Past the first message, the history is empty
+	oldest := types.NewMessageCursor(&types.Message{ID: "m-0-a", CreatedAt: start}).Encode()
+	if code, resp := loadMessages(t, router, url.Values{"before": {oldest}}); code != http.StatusOK ||
+		len(resp.Data) != 0 || resp.HasMore {
+		t.Errorf("page before the first message = %d %+v", code, resp)
+	}
+}
+
+func TestLoadMessagesInvalidCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.GET("/messages/:session_id/load", NewMessageHandler(&fakeMessageService{}, nil).LoadMessages)
+
+	if code, _ := loadMessages(t, router, url.Values{"before": {"2026-10-16"}}); code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", code, http.StatusBadRequest)
+	}
+}
@@ -113,7 +113,7 @@ func (h *ModelHandler) CreateModel(c *gin.Context) {
 
 	if err := h.service.CreateModel(ctx, model); err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -162,11 +162,11 @@ func (h *ModelHandler) GetModel(c *gin.Context) {
 	if err != nil {
 		if err == service.ErrModelNotFound {
 			logger.Warnf(ctx, "Model not found, ID: %s", id)
-			c.Error(errors.NewNotFoundError("Model not found"))
+			c.Error(errors.NewNotFoundError("Model not found").WithCode(errors.CodeModelNotFound))
 			return
 		}
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -210,7 +210,7 @@ func (h *ModelHandler) ListModels(c *gin.Context) {
 	models, err := h.service.ListModels(ctx)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -278,11 +278,11 @@ func (h *ModelHandler) UpdateModel(c *gin.Context) {
 	if err != nil {
 		if err == service.ErrModelNotFound {
 			logger.Warnf(ctx, "Model not found, ID: %s", id)
-			c.Error(errors.NewNotFoundError("Model not found"))
+			c.Error(errors.NewNotFoundError("Model not found").WithCode(errors.CodeModelNotFound))
 			return
 		}
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -301,7 +301,7 @@ func (h *ModelHandler) UpdateModel(c *gin.Context) {
 	logger.Infof(ctx, "Updating model, ID: %s, Name: %s", id, model.Name)
 	if err := h.service.UpdateModel(ctx, model); err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -318,13 +318,15 @@ func (h *ModelHandler) UpdateModel(c *gin.Context) {
 
 // DeleteModel godoc
 // @Summary      删除模型
-// @Description  删除指定的模型
+// @Description  删除指定的模型，被知识库或智能体引用时返回409及引用列表，force=true时先移除引用再删除
 // @Tags         模型管理
 // @Accept       json
 // @Produce      json
-// @Param        id   path      string  true  "模型ID"
-// @Success      200  {object}  map[string]interface{}  "删除成功"
-// @Failure      404  {object}  errors.AppError         "模型不存在"
+// @Param        id     path      string  true   "模型ID"
+// @Param        force  query     bool    false  "移除引用后强制删除"
+// @Success      200    {object}  map[string]interface{}  "删除成功"
+// @Failure      404    {object}  errors.AppError         "模型不存在"
+// @Failure      409    {object}  errors.AppError         "模型仍被引用"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /models/{id} [delete]
@@ -340,15 +342,17 @@ func (h *ModelHandler) DeleteModel(c *gin.Context) {
 		return
 	}
 
+	force := c.Query("force") == "true"
+
 	logger.Infof(ctx, "Deleting model, ID: %s", id)
-	if err := h.service.DeleteModel(ctx, id); err != nil {
+	if err := h.service.DeleteModel(ctx, id, force); err != nil {
 		if err == service.ErrModelNotFound {
 			logger.Warnf(ctx, "Model not found, ID: %s", id)
-			c.Error(errors.NewNotFoundError("Model not found"))
+			c.Error(errors.NewNotFoundError("Model not found").WithCode(errors.CodeModelNotFound))
 			return
 		}
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -359,6 +363,57 @@ func (h *ModelHandler) DeleteModel(c *gin.Context) {
 	})
 }
 
+// BatchDeleteModelsRequest defines the request of a batch model deletion
+type BatchDeleteModelsRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=100"`
+}
+
+// BatchDeleteModels godoc
+// @Summary      批量删除模型
+// @Description  批量删除模型，仍被知识库或智能体使用的模型不会被删除，并返回引用它们的资源
+// @Tags         模型管理
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BatchDeleteModelsRequest  true  "模型ID列表"
+// @Success      200      {object}  map[string]interface{}    "每个模型的删除结果"
+// @Failure      400      {object}  errors.AppError           "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /models/batch-delete [post]
+func (h *ModelHandler) BatchDeleteModels(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req BatchDeleteModelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	results, err := h.service.DeleteModels(ctx, secutils.SanitizeForLogArray(req.IDs))
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	deleted := 0
+	for _, result := range results {
+		if result.Success {
+			deleted++
+		}
+	}
+	logger.Infof(ctx, "Batch model deletion finished, deleted: %d, failed: %d", deleted, len(results)-deleted)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"results": results,
+			"deleted": deleted,
+			"failed":  len(results) - deleted,
+		},
+	})
+}
+
 // ModelProviderDTO 模型厂商信息 DTO
 type ModelProviderDTO struct {
 	Value       string            `json:"value"`       // provider 标识符
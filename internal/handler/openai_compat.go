@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAICompatHandler serves the OpenAI-compatible API, which maps OpenAI concepts onto WeKnora:
+// an OpenAI model is a knowledge base of the tenant the API key belongs to
+type OpenAICompatHandler struct {
+	kbService interfaces.KnowledgeBaseService
+}
+
+// NewOpenAICompatHandler creates a new OpenAI-compatible API handler instance
+func NewOpenAICompatHandler(kbService interfaces.KnowledgeBaseService) *OpenAICompatHandler {
+	return &OpenAICompatHandler{kbService: kbService}
+}
+
+// OpenAIModel is a model in the OpenAI model list format
+type OpenAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+	// Name is the knowledge base name, an extension to the OpenAI format
+	Name string `json:"name"`
+}
+
+// OpenAIModelList is a list in the OpenAI list format
+type OpenAIModelList struct {
+	Object string        `json:"object"`
+	Data   []OpenAIModel `json:"data"`
+}
+
+// ListModels godoc
+// @Summary      List models (OpenAI-compatible)
+// @Description  List the knowledge bases of the tenant as OpenAI models; the model ID is the knowledge base ID
+// @Tags         OpenAI
+// @Produce      json
+// @Success      200  {object}  OpenAIModelList
+// @Failure      401  {object}  errors.AppError  "Invalid API key"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /openai/v1/models [get]
+func (h *OpenAICompatHandler) ListModels(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	kbs, err := h.kbService.ListKnowledgeBases(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	list := OpenAIModelList{Object: "list", Data: make([]OpenAIModel, 0, len(kbs))}
+	for _, kb := range kbs {
+		if kb.IsTemporary {
+			continue
+		}
+		list.Data = append(list.Data, OpenAIModel{
+			ID:      kb.ID,
+			Object:  "model",
+			Created: kb.CreatedAt.Unix(),
+			OwnedBy: "weknora",
+			Name:    kb.Name,
+		})
+	}
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/calllog"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ProviderLogHandler exposes captured model provider calls for debugging
+// Captured payloads are sensitive, so all endpoints are restricted to administrators
+type ProviderLogHandler struct {
+	store       *calllog.RedisStore
+	userService interfaces.UserService
+}
+
+// NewProviderLogHandler creates a new provider log handler instance
+func NewProviderLogHandler(store *calllog.RedisStore, userService interfaces.UserService) *ProviderLogHandler {
+	return &ProviderLogHandler{
+		store:       store,
+		userService: userService,
+	}
+}
+
+// GetProviderLogs godoc
+// @Summary      Get provider calls
+// @Description  Get the redacted model provider requests and responses captured for a request ID (admin only, SENSITIVE)
+// @Tags         System
+// @Accept       json
+// @Produce      json
+// @Param        request_id  path      string  true  "Request ID"
+// @Success      200         {object}  map[string]interface{}  "Captured provider calls"
+// @Failure      403         {object}  errors.AppError         "Insufficient permissions"
+// @Failure      404         {object}  errors.AppError         "No calls captured"
+// @Security     Bearer
+// @Router       /system/provider-logs/{request_id} [get]
+func (h *ProviderLogHandler) GetProviderLogs(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	user, err := h.userService.GetCurrentUser(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get current user: %v", err)
+		c.Error(errors.NewForbiddenError("Provider logs require an administrator account"))
+		return
+	}
+	if !user.CanAccessAllTenants {
+		logger.Warnf(ctx, "User %s attempted to access provider logs without permission", user.ID)
+		c.Error(errors.NewForbiddenError("Insufficient permissions to access provider logs"))
+		return
+	}
+
+	if !h.store.IsEnabled() {
+		c.Error(errors.NewBadRequestError("Provider call logging is disabled"))
+		return
+	}
+
+	requestID := secutils.SanitizeForLog(c.Param("request_id"))
+	logger.Infof(ctx, "User %s reading provider logs for request %s", user.ID, requestID)
+
+	entries, err := h.store.Get(ctx, requestID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"request_id": requestID,
+		})
+		c.Error(errors.FromError(err))
+		return
+	}
+	if len(entries) == 0 {
+		c.Error(errors.NewNotFoundError("No provider calls captured for this request"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    entries,
+	})
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	goerrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RoleHandler manages the roles of the users of the current tenant
+type RoleHandler struct {
+	service interfaces.RoleService
+}
+
+// NewRoleHandler creates a new role handler instance
+func NewRoleHandler(service interfaces.RoleService) *RoleHandler {
+	return &RoleHandler{service: service}
+}
+
+// ListRoles godoc
+// @Summary      List roles
+// @Description  List the user roles and the scopes each of them grants
+// @Tags         Roles
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Roles"
+// @Security     Bearer
+// @Router       /roles [get]
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	roles := make([]*types.RoleDefinition, 0, len(types.AllRoles))
+	for _, role := range types.AllRoles {
+		roles = append(roles, &types.RoleDefinition{Name: role, Scopes: types.RoleScopes(role)})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    roles,
+	})
+}
+
+// GetCurrentRole godoc
+// @Summary      Get current role
+// @Description  Get the role of the logged-in user in the current tenant and the scopes of the request;
+// @Description  requests authenticated with an API key have no role and the scopes of the key
+// @Tags         Roles
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Current role"
+// @Security     Bearer
+// @Router       /roles/me [get]
+func (h *RoleHandler) GetCurrentRole(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	current := &types.CurrentRole{Scopes: types.AllAPIKeyScopes}
+	if user, ok := ctx.Value(types.UserContextKey).(*types.User); ok {
+		current.UserID = user.ID
+	}
+	if role, ok := ctx.Value(types.UserRoleContextKey).(string); ok {
+		current.Role = role
+		current.Scopes = types.RoleScopes(role)
+	}
+	if scopes, ok := ctx.Value(types.APIKeyScopesContextKey).(types.StringArray); ok {
+		current.Scopes = scopes
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    current,
+	})
+}
+
+// ListRoleAssignments godoc
+// @Summary      List role assignments
+// @Description  List the roles assigned to the users of the current tenant; other users have the default role
+// @Tags         Roles
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Role assignments"
+// @Failure      403  {object}  errors.AppError         "Not an admin"
+// @Security     Bearer
+// @Router       /roles/assignments [get]
+func (h *RoleHandler) ListRoleAssignments(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	assignments, err := h.service.ListAssignments(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(roleError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    assignments,
+	})
+}
+
+// AssignRole godoc
+// @Summary      Assign role
+// @Description  Assign a role (admin, editor or viewer) to a user of the current tenant, replacing its role
+// @Tags         Roles
+// @Accept       json
+// @Produce      json
+// @Param        user_id  path      string                   true  "User ID"
+// @Param        request  body      types.AssignRoleRequest  true  "Role"
+// @Success      200      {object}  map[string]interface{}   "Role assignment"
+// @Failure      400      {object}  errors.AppError          "Invalid role, or own role"
+// @Failure      404      {object}  errors.AppError          "User not found"
+// @Security     Bearer
+// @Router       /roles/assignments/{user_id} [put]
+func (h *RoleHandler) AssignRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := secutils.SanitizeForLog(c.Param("user_id"))
+
+	var req types.AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to bind assign role payload", err)
+		c.Error(errors.NewBadRequestError("invalid request parameters").WithDetails(err.Error()))
+		return
+	}
+
+	assignment, err := h.service.AssignRole(ctx, userID, req.Role)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"user_id": userID})
+		c.Error(roleError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    assignment,
+	})
+}
+
+// RemoveRole godoc
+// @Summary      Remove role assignment
+// @Description  Remove the role assigned to a user of the current tenant, who gets the default role again
+// @Tags         Roles
+// @Produce      json
+// @Param        user_id  path      string                  true  "User ID"
+// @Success      200      {object}  map[string]interface{}  "Removed"
+// @Failure      400      {object}  errors.AppError         "Own role"
+// @Failure      404      {object}  errors.AppError         "User not found"
+// @Security     Bearer
+// @Router       /roles/assignments/{user_id} [delete]
+func (h *RoleHandler) RemoveRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := secutils.SanitizeForLog(c.Param("user_id"))
+
+	if err := h.service.RemoveRole(ctx, userID); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"user_id": userID})
+		c.Error(roleError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// roleError maps the errors of the role service to API errors
+func roleError(err error) *errors.AppError {
+	switch {
+	case goerrors.Is(err, service.ErrInvalidRole):
+		return errors.NewValidationError("Invalid role").WithDetails(err.Error())
+	case goerrors.Is(err, service.ErrOwnRoleChange):
+		return errors.NewBadRequestError("Users cannot change their own role")
+	case goerrors.Is(err, service.ErrRoleUserNotFound):
+		return errors.NewNotFoundError("User not found in tenant")
+	default:
+		return errors.FromError(err)
+	}
+}
@@ -59,8 +59,14 @@ func (h *AgentStreamHandler) Subscribe() {
 	// Subscribe to all agent streaming events on the dedicated EventBus
 	h.eventBus.On(event.EventAgentThought, h.handleThought)
 	h.eventBus.On(event.EventAgentToolCall, h.handleToolCall)
+	h.eventBus.On(event.EventAgentToolStatus, h.handleToolStatus)
 	h.eventBus.On(event.EventAgentToolResult, h.handleToolResult)
 	h.eventBus.On(event.EventAgentReferences, h.handleReferences)
+	h.eventBus.On(event.EventConfidence, h.handleConfidence)
+	h.eventBus.On(event.EventContextBudget, h.handleContextBudget)
+	h.eventBus.On(event.EventGrounding, h.handleGrounding)
+	h.eventBus.On(event.EventCitation, h.handleCitation)
+	h.eventBus.On(event.EventRetrievalDegraded, h.handleRetrievalDegraded)
 	h.eventBus.On(event.EventAgentFinalAnswer, h.handleFinalAnswer)
 	h.eventBus.On(event.EventAgentReflection, h.handleReflection)
 	h.eventBus.On(event.EventError, h.handleError)
@@ -149,6 +155,44 @@ func (h *AgentStreamHandler) handleToolCall(ctx context.Context, evt event.Event
 	return nil
 }
 
+// handleToolStatus handles intermediate status and partial output of a running tool
+func (h *AgentStreamHandler) handleToolStatus(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.AgentToolStatusData)
+	if !ok {
+		return nil
+	}
+
+	metadata := map[string]interface{}{
+		"tool_name":    data.ToolName,
+		"tool_call_id": data.ToolCallID,
+		"status":       data.Status,
+		"seq":          data.Seq,
+	}
+	if data.Partial != "" {
+		metadata["partial"] = data.Partial
+	}
+
+	h.mu.Lock()
+	if startTime, exists := h.eventStartTimes[data.ToolCallID]; exists {
+		metadata["elapsed_ms"] = time.Since(startTime).Milliseconds()
+	}
+	h.mu.Unlock()
+
+	// Append event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeToolStatus,
+		Content:   data.Status,
+		Done:      false,
+		Timestamp: time.Now(),
+		Data:      metadata,
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append tool status event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
 // handleToolResult handles tool result events
 func (h *AgentStreamHandler) handleToolResult(ctx context.Context, evt event.Event) error {
 	data, ok := evt.Data.(event.AgentToolResultData)
@@ -275,6 +319,135 @@ func (h *AgentStreamHandler) handleReferences(ctx context.Context, evt event.Eve
 	return nil
 }
 
+// handleConfidence handles confidence events, from the gate and then for the generated answer
+func (h *AgentStreamHandler) handleConfidence(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(*types.ConfidenceResult)
+	if !ok || data == nil {
+		return nil
+	}
+
+	// The answer confidence arrives before the last answer chunk and replaces the gate one
+	h.mu.Lock()
+	h.assistantMessage.Confidence = data
+	h.mu.Unlock()
+
+	// Append confidence event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeConfidence,
+		Content:   "",
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"confidence": data,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append confidence event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// handleGrounding handles answer grounding events
+func (h *AgentStreamHandler) handleGrounding(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(*types.GroundingResult)
+	if !ok || data == nil {
+		return nil
+	}
+
+	// Append grounding event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeGrounding,
+		Content:   "",
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"grounding": data,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append grounding event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// handleCitation handles answer citation events
+func (h *AgentStreamHandler) handleCitation(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(*types.CitationResult)
+	if !ok || data == nil {
+		return nil
+	}
+
+	// Append citation event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeCitation,
+		Content:   "",
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"citation": data,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append citation event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// handleRetrievalDegraded handles events of retrieval cut short at its soft deadline
+func (h *AgentStreamHandler) handleRetrievalDegraded(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(*types.RetrievalDegradation)
+	if !ok || data == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	h.assistantMessage.RetrievalDegradation = data
+	h.mu.Unlock()
+
+	// Append retrieval degraded event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeRetrievalDegraded,
+		Content:   "",
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"retrieval_degradation": data,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append retrieval degraded event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// handleContextBudget handles context budget report events
+func (h *AgentStreamHandler) handleContextBudget(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(*types.ContextBudgetReport)
+	if !ok || data == nil {
+		return nil
+	}
+
+	// Append context budget event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeContextBudget,
+		Content:   "",
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"context_budget": data,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append context budget event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
 // handleFinalAnswer handles final answer events
 func (h *AgentStreamHandler) handleFinalAnswer(ctx context.Context, evt event.Event) error {
 	data, ok := evt.Data.(event.AgentFinalAnswerData)
@@ -445,6 +618,9 @@ func (h *AgentStreamHandler) handleComplete(ctx context.Context, evt event.Event
 		Data: map[string]interface{}{
 			"total_steps":       data.TotalSteps,
 			"total_duration_ms": data.TotalDurationMs,
+			"stop_reason":       data.StopReason,
+			"truncated":         data.Truncated,
+			"tokens_used":       data.TokensUsed,
 		},
 	}); err != nil {
 		logger.GetLogger(h.ctx).Errorf("Append complete event to stream failed: %v", err)
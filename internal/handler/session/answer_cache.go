@@ -0,0 +1,112 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// answerCacheHeader reports whether the answer was served from the answer cache
+const answerCacheHeader = "X-Answer-Cache"
+
+// answerCacheLookup holds the answer cache state of a knowledge Q&A request
+type answerCacheLookup struct {
+	key    types.AnswerCacheKey
+	ttl    time.Duration
+	status string
+	answer *types.CachedAnswer
+}
+
+// lookupCachedAnswer checks the answer cache for a normal mode request. Requests whose answer depends on
+// more than the knowledge bases and the query (attachments, web search, mentions, retrieval parameters)
+// bypass the cache, as do requests targeting a knowledge base without an answer cache TTL.
+func (h *Handler) lookupCachedAnswer(reqCtx *qaRequestContext) *answerCacheLookup {
+	lookup := &answerCacheLookup{status: types.AnswerCacheBypass}
+	if len(reqCtx.knowledgeBaseIDs) == 0 || len(reqCtx.attachments) > 0 ||
+		reqCtx.webSearchEnabled || len(reqCtx.mentionedItems) > 0 || reqCtx.retrieval != nil {
+		return lookup
+	}
+	lookup.ttl = h.answerCache.TTL(reqCtx.ctx, reqCtx.knowledgeBaseIDs)
+	if lookup.ttl <= 0 {
+		return lookup
+	}
+
+	lookup.key = types.AnswerCacheKey{
+		TenantID:         reqCtx.session.TenantID,
+		KnowledgeBaseIDs: reqCtx.knowledgeBaseIDs,
+		KnowledgeIDs:     reqCtx.knowledgeIDs,
+		Query:            reqCtx.query,
+		SummaryModelID:   reqCtx.summaryModelID,
+		HistoryDepth:     reqCtx.historyDepth,
+	}
+	if reqCtx.customAgent != nil {
+		lookup.key.AgentID = reqCtx.customAgent.ID
+		lookup.key.AgentVersion = reqCtx.customAgent.UpdatedAt.Unix()
+	}
+
+	lookup.status = types.AnswerCacheMiss
+	answer, err := h.answerCache.Get(reqCtx.ctx, lookup.key)
+	if err != nil {
+		logger.Warnf(reqCtx.ctx, "Failed to read answer cache: %v", err)
+		return lookup
+	}
+	if answer != nil {
+		lookup.status = types.AnswerCacheHit
+		lookup.answer = answer
+	}
+	return lookup
+}
+
+// storeCachedAnswer caches the completed answer of a cache miss; answers of stopped requests and answers
+// generated from partial retrieval results are not cached
+func (h *Handler) storeCachedAnswer(ctx context.Context, lookup *answerCacheLookup, message *types.Message) {
+	if lookup.status != types.AnswerCacheMiss || message.Content == "" || ctx.Err() != nil ||
+		message.RetrievalDegradation != nil {
+		return
+	}
+	answer := &types.CachedAnswer{
+		Content:    message.Content,
+		References: message.KnowledgeReferences,
+		Confidence: message.Confidence,
+		CreatedAt:  time.Now(),
+	}
+	if err := h.answerCache.Set(ctx, lookup.key, answer, lookup.ttl); err != nil {
+		logger.Warnf(ctx, "Failed to cache answer: %v", err)
+	}
+}
+
+// replayCachedAnswer streams a cached answer through the event bus as if it had just been generated
+func (h *Handler) replayCachedAnswer(streamCtx *sseStreamContext, sessionID string, answer *types.CachedAnswer) {
+	ctx := streamCtx.asyncCtx
+	messageID := streamCtx.assistantMessage.ID
+	logger.Infof(ctx, "Serving cached answer for session: %s, cached at: %s",
+		sessionID, answer.CreatedAt.Format(time.RFC3339))
+
+	if len(answer.References) > 0 {
+		streamCtx.eventBus.Emit(ctx, event.Event{
+			ID:        "references-" + messageID,
+			Type:      event.EventAgentReferences,
+			SessionID: sessionID,
+			Data: event.AgentReferencesData{
+				References: []*types.SearchResult(answer.References),
+			},
+		})
+	}
+	if answer.Confidence != nil {
+		streamCtx.eventBus.Emit(ctx, event.Event{
+			ID:        "confidence-" + messageID,
+			Type:      event.EventConfidence,
+			SessionID: sessionID,
+			Data:      answer.Confidence,
+		})
+	}
+	streamCtx.eventBus.Emit(ctx, event.Event{
+		ID:        "answer-" + messageID,
+		Type:      event.EventAgentFinalAnswer,
+		SessionID: sessionID,
+		Data:      event.AgentFinalAnswerData{Content: answer.Content, Done: true},
+	})
+}
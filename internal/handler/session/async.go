@@ -0,0 +1,221 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Async chat defaults used when async_chat is not configured
+const (
+	defaultAsyncChatTimeout          = 10 * time.Minute
+	defaultAsyncChatCallbackTimeout  = 10 * time.Second
+	defaultAsyncChatCallbackAttempts = 3
+)
+
+// asyncChatConfig returns the async chat configuration with defaults applied
+func (h *Handler) asyncChatConfig() config.AsyncChatConfig {
+	var cfg config.AsyncChatConfig
+	if h.config != nil && h.config.AsyncChat != nil {
+		cfg = *h.config.AsyncChat
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultAsyncChatTimeout
+	}
+	if cfg.CallbackTimeout <= 0 {
+		cfg.CallbackTimeout = defaultAsyncChatCallbackTimeout
+	}
+	if cfg.CallbackAttempts <= 0 {
+		cfg.CallbackAttempts = defaultAsyncChatCallbackAttempts
+	}
+	return cfg
+}
+
+// validateAsyncCallback checks the callback URL of an async request; callbacks can only be sent signed
+func validateAsyncCallback(callbackURL string, cfg config.AsyncChatConfig) error {
+	if callbackURL == "" {
+		return nil
+	}
+	if cfg.CallbackSecret == "" {
+		return errors.NewBadRequestError("Async chat callbacks are not configured").
+			WithDetails("async_chat.callback_secret must be set to sign callbacks")
+	}
+	if safe, reason := secutils.IsSSRFSafeURL(callbackURL); !safe {
+		return errors.NewBadRequestError("Invalid callback URL").WithDetails(reason)
+	}
+	return nil
+}
+
+// executeAsyncQA answers a normal mode request in the background and responds right away with the job ID,
+// the ID of the assistant message the answer is stored on. Once the answer completes or fails, it is
+// posted to the request's callback URL, if any. The answer is also streamed to the stream manager so that
+// continue-stream can follow it.
+func (h *Handler) executeAsyncQA(reqCtx *qaRequestContext, callbackURL string, generateTitle bool) {
+	ctx := reqCtx.ctx
+	sessionID := reqCtx.sessionID
+	cfg := h.asyncChatConfig()
+	if err := validateAsyncCallback(callbackURL, cfg); err != nil {
+		reqCtx.c.Error(err)
+		return
+	}
+
+	// Create user message
+	if err := h.createUserMessage(ctx, sessionID, reqCtx.query, reqCtx.requestID, reqCtx.mentionedItems); err != nil {
+		reqCtx.c.Error(errors.FromError(err))
+		return
+	}
+
+	// Create assistant message
+	if _, err := h.createAssistantMessage(ctx, reqCtx.assistantMessage); err != nil {
+		reqCtx.c.Error(errors.FromError(err))
+		return
+	}
+	assistantMessage := reqCtx.assistantMessage
+
+	// The answer outlives the request, only bounded by the async timeout
+	eventBus := event.NewEventBus()
+	runCtx, cancel := context.WithTimeout(context.WithoutCancel(logger.CloneContext(ctx)), cfg.Timeout)
+	streamCtx := &sseStreamContext{
+		eventBus:         eventBus,
+		asyncCtx:         runCtx,
+		cancel:           cancel,
+		assistantMessage: assistantMessage,
+	}
+	h.writeAgentQueryEvent(ctx, sessionID, assistantMessage.ID)
+	h.setupStopEventHandler(eventBus, sessionID, assistantMessage, cancel)
+	h.setupStreamHandler(runCtx, sessionID, assistantMessage.ID, reqCtx.requestID, assistantMessage, eventBus)
+	h.handleNormalModeCompletion(streamCtx, sessionID)
+	if generateTitle && reqCtx.session.Title == "" {
+		h.sessionService.GenerateTitleAsync(runCtx, reqCtx.session, reqCtx.query, "", eventBus)
+	}
+
+	// The first of completion and failure is the outcome of the job
+	outcome := make(chan error, 1)
+	report := func(err error) {
+		select {
+		case outcome <- err:
+		default:
+		}
+	}
+	eventBus.On(event.EventAgentComplete, func(ctx context.Context, evt event.Event) error {
+		report(nil)
+		return nil
+	})
+	eventBus.On(event.EventError, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.ErrorData); ok {
+			report(fmt.Errorf("%s: %s", data.Stage, data.Error))
+		}
+		return nil
+	})
+
+	go func() {
+		defer cancel()
+		defer func() {
+			if r := recover(); r != nil {
+				buf := make([]byte, 10240)
+				runtime.Stack(buf, true)
+				logger.ErrorWithFields(runCtx,
+					errors.NewInternalServerError(fmt.Sprintf("Async knowledge QA panicked: %v\n%s", r, string(buf))), nil)
+			}
+		}()
+
+		err := h.sessionService.KnowledgeQA(
+			runCtx,
+			reqCtx.session,
+			reqCtx.query,
+			reqCtx.knowledgeBaseIDs,
+			reqCtx.knowledgeIDs,
+			assistantMessage.ID,
+			reqCtx.summaryModelID,
+			reqCtx.webSearchEnabled,
+			eventBus,
+			reqCtx.customAgent,
+			reqCtx.attachments,
+			reqCtx.historyDepth,
+			reqCtx.retrieval,
+		)
+		if err != nil {
+			logger.ErrorWithFields(runCtx, err, nil)
+			report(err)
+		}
+
+		select {
+		case err = <-outcome:
+		case <-runCtx.Done():
+			err = runCtx.Err()
+		}
+		if err != nil {
+			logger.Warnf(runCtx, "Async knowledge QA failed for session %s: %v", sessionID, err)
+			h.completeAssistantMessage(context.WithoutCancel(runCtx), assistantMessage)
+		}
+		h.deliverAsyncCallback(context.WithoutCancel(runCtx), cfg, callbackURL, reqCtx.requestID, assistantMessage, err)
+	}()
+
+	logger.Infof(ctx, "Async knowledge QA started for session %s, job ID: %s", sessionID, assistantMessage.ID)
+	reqCtx.c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data": gin.H{
+			"job_id":     assistantMessage.ID,
+			"session_id": sessionID,
+			"message_id": assistantMessage.ID,
+			"request_id": reqCtx.requestID,
+			"status":     AsyncChatStatusRunning,
+		},
+	})
+}
+
+// newAsyncChatCallback builds the callback body of a finished async request, failed when err is set
+func newAsyncChatCallback(requestID string, message *types.Message, err error) *AsyncChatCallback {
+	callback := &AsyncChatCallback{
+		JobID:       message.ID,
+		SessionID:   message.SessionID,
+		MessageID:   message.ID,
+		RequestID:   requestID,
+		Status:      AsyncChatStatusCompleted,
+		Answer:      message.Content,
+		References:  message.KnowledgeReferences,
+		CompletedAt: time.Now(),
+	}
+	if err != nil {
+		callback.Status = AsyncChatStatusFailed
+		callback.Error = err.Error()
+	}
+	return callback
+}
+
+// deliverAsyncCallback posts the outcome of an async request to its callback URL; failed deliveries are
+// only logged since the answer stays available through the message API
+func (h *Handler) deliverAsyncCallback(ctx context.Context, cfg config.AsyncChatConfig,
+	callbackURL, requestID string, message *types.Message, err error,
+) {
+	if callbackURL == "" {
+		return
+	}
+	body, marshalErr := json.Marshal(newAsyncChatCallback(requestID, message, err))
+	if marshalErr != nil {
+		logger.Errorf(ctx, "Failed to marshal async chat callback: %v", marshalErr)
+		return
+	}
+	client := secutils.NewSSRFSafeHTTPClient(secutils.SSRFSafeHTTPClientConfig{
+		Timeout:      cfg.CallbackTimeout,
+		MaxRedirects: 3,
+	})
+	if deliverErr := secutils.DeliverWebhook(ctx, client, callbackURL, cfg.CallbackSecret, body,
+		cfg.CallbackAttempts); deliverErr != nil {
+		logger.Warnf(ctx, "Failed to deliver async chat callback of message %s: %v",
+			message.ID, deliverErr)
+		return
+	}
+	logger.Infof(ctx, "Delivered async chat callback of message %s", message.ID)
+}
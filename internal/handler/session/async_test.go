@@ -0,0 +1,67 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestValidateAsyncCallback(t *testing.T) {
+	signed := config.AsyncChatConfig{CallbackSecret: "secret"}
+
+	tests := []struct {
+		name        string
+		callbackURL string
+		cfg         config.AsyncChatConfig
+		wantErr     bool
+	}{
+		{name: "no callback", cfg: config.AsyncChatConfig{}},
+		{name: "callbacks not configured", callbackURL: "https://hooks.example.com/weknora", wantErr: true},
+		{name: "loopback callback", callbackURL: "http://localhost:8080/hook", cfg: signed, wantErr: true},
+		{name: "metadata endpoint", callbackURL: "http://169.254.169.254/latest", cfg: signed, wantErr: true},
+		{name: "not http", callbackURL: "ftp://hooks.example.com/weknora", cfg: signed, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateAsyncCallback(tt.callbackURL, tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("validateAsyncCallback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewAsyncChatCallback(t *testing.T) {
+	message := &types.Message{
+		ID:                  "msg-1",
+		SessionID:           "session-1",
+		Content:             "Comet tails point away from the Sun.",
+		KnowledgeReferences: types.References{{ID: "chunk-1"}},
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus string
+		wantError  string
+	}{
+		{name: "completed", wantStatus: AsyncChatStatusCompleted},
+		{name: "failed", err: errors.New("model_call: timeout"), wantStatus: AsyncChatStatusFailed, wantError: "model_call: timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callback := newAsyncChatCallback("req-1", message, tt.err)
+			if callback.Status != tt.wantStatus || callback.Error != tt.wantError {
+				t.Errorf("callback status = %s (%q), want %s (%q)", callback.Status, callback.Error, tt.wantStatus, tt.wantError)
+			}
+			if callback.JobID != "msg-1" || callback.MessageID != "msg-1" || callback.SessionID != "session-1" ||
+				callback.RequestID != "req-1" {
+				t.Errorf("callback ids = %+v", callback)
+			}
+			if callback.Answer != message.Content || len(callback.References) != 1 {
+				t.Errorf("callback answer = %q with %d references", callback.Answer, len(callback.References))
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/Tencent/WeKnora/internal/config"
 	"github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/metrics"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	secutils "github.com/Tencent/WeKnora/internal/utils"
@@ -20,6 +21,8 @@ type Handler struct {
 	config               *config.Config                  // Application configuration
 	knowledgebaseService interfaces.KnowledgeBaseService // Service for managing knowledge bases
 	customAgentService   interfaces.CustomAgentService   // Service for managing custom agents
+	answerCache          interfaces.AnswerCacheService   // Cache of knowledge Q&A answers
+	metrics              *metrics.Metrics                // Metrics of the open answer streams
 }
 
 // NewHandler creates a new instance of Handler with all necessary dependencies
@@ -30,6 +33,8 @@ func NewHandler(
 	config *config.Config,
 	knowledgebaseService interfaces.KnowledgeBaseService,
 	customAgentService interfaces.CustomAgentService,
+	answerCache interfaces.AnswerCacheService,
+	metrics *metrics.Metrics,
 ) *Handler {
 	return &Handler{
 		sessionService:       sessionService,
@@ -38,6 +43,8 @@ func NewHandler(
 		config:               config,
 		knowledgebaseService: knowledgebaseService,
 		customAgentService:   customAgentService,
+		answerCache:          answerCache,
+		metrics:              metrics,
 	}
 }
 
@@ -62,6 +69,11 @@ func (h *Handler) CreateSession(c *gin.Context) {
 		c.Error(errors.NewBadRequestError(err.Error()))
 		return
 	}
+	if err := request.RetrievalParams.Validate(); err != nil {
+		logger.Errorf(ctx, "Invalid retrieval params: %v", err)
+		c.Error(errors.NewBadRequestError("Invalid retrieval params").WithDetails(err.Error()))
+		return
+	}
 
 	// Get tenant ID from context
 	tenantID, exists := c.Get(types.TenantIDContextKey.String())
@@ -73,7 +85,7 @@ func (h *Handler) CreateSession(c *gin.Context) {
 
 	// Sessions are now knowledge-base-independent:
 	// - All configuration comes from custom agent at query time
-	// - Session only stores basic info (tenant ID, title, description)
+	// - Session only stores basic info (tenant ID, title, description) and default retrieval parameters
 	logger.Infof(
 		ctx,
 		"Processing session creation request, tenant ID: %d",
@@ -82,9 +94,10 @@ func (h *Handler) CreateSession(c *gin.Context) {
 
 	// Create session object with base properties
 	createdSession := &types.Session{
-		TenantID:    tenantID.(uint64),
-		Title:       request.Title,
-		Description: request.Description,
+		TenantID:        tenantID.(uint64),
+		Title:           request.Title,
+		Description:     request.Description,
+		RetrievalParams: request.RetrievalParams,
 	}
 
 	// Call service to create session
@@ -92,7 +105,7 @@ func (h *Handler) CreateSession(c *gin.Context) {
 	createdSession, err := h.sessionService.CreateSession(ctx, createdSession)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -135,11 +148,11 @@ func (h *Handler) GetSession(c *gin.Context) {
 	if err != nil {
 		if err == errors.ErrSessionNotFound {
 			logger.Warnf(ctx, "Session not found, ID: %s", id)
-			c.Error(errors.NewNotFoundError(err.Error()))
+			c.Error(errors.FromError(err))
 			return
 		}
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -179,7 +192,7 @@ func (h *Handler) GetSessionsByTenant(c *gin.Context) {
 	result, err := h.sessionService.GetPagedSessionsByTenant(ctx, &pagination)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -232,6 +245,11 @@ func (h *Handler) UpdateSession(c *gin.Context) {
 		c.Error(errors.NewBadRequestError(err.Error()))
 		return
 	}
+	if err := session.RetrievalParams.Validate(); err != nil {
+		logger.Errorf(ctx, "Invalid retrieval params: %v", err)
+		c.Error(errors.NewBadRequestError("Invalid retrieval params").WithDetails(err.Error()))
+		return
+	}
 
 	session.ID = id
 	session.TenantID = tenantID.(uint64)
@@ -240,11 +258,11 @@ func (h *Handler) UpdateSession(c *gin.Context) {
 	if err := h.sessionService.UpdateSession(ctx, &session); err != nil {
 		if err == errors.ErrSessionNotFound {
 			logger.Warnf(ctx, "Session not found, ID: %s", id)
-			c.Error(errors.NewNotFoundError(err.Error()))
+			c.Error(errors.FromError(err))
 			return
 		}
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -283,11 +301,11 @@ func (h *Handler) DeleteSession(c *gin.Context) {
 	if err := h.sessionService.DeleteSession(ctx, id); err != nil {
 		if err == errors.ErrSessionNotFound {
 			logger.Warnf(ctx, "Session not found, ID: %s", id)
-			c.Error(errors.NewNotFoundError(err.Error()))
+			c.Error(errors.FromError(err))
 			return
 		}
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
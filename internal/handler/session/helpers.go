@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/i18n"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
@@ -32,9 +33,11 @@ func convertMentionedItems(items []MentionedItemRequest) types.MentionedItems {
 // setSSEHeaders sets the standard Server-Sent Events headers
 func setSSEHeaders(c *gin.Context) {
 	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
+	// no-transform keeps intermediaries from compressing or otherwise buffering the stream
+	c.Header("Cache-Control", "no-cache, no-transform")
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no")
+	c.Header("Content-Encoding", "identity")
 }
 
 // buildStreamResponse constructs a StreamResponse from a StreamEvent
@@ -106,6 +109,38 @@ func sendCompletionEvent(c *gin.Context, requestID string) {
 	// which is already sent before this function is called
 }
 
+// buildStreamResetResponse tells a resuming client that the events it missed are no longer buffered, so it
+// has to reload the message instead of continuing the stream
+func buildStreamResetResponse(message *types.Message, firstOffset int) *types.StreamResponse {
+	return &types.StreamResponse{
+		ID:           message.RequestID,
+		ResponseType: types.ResponseTypeStreamReset,
+		Done:         true,
+		Data: map[string]interface{}{
+			"message_id":   message.ID,
+			"first_offset": firstOffset,
+		},
+	}
+}
+
+// buildStreamFinalResponse tells a resuming client that the stream already finished, with the final
+// answer once the message is completed
+func buildStreamFinalResponse(message *types.Message) *types.StreamResponse {
+	data := map[string]interface{}{
+		"message_id": message.ID,
+		"final":      true,
+	}
+	if message.IsCompleted {
+		data["content"] = message.Content
+	}
+	return &types.StreamResponse{
+		ID:           message.RequestID,
+		ResponseType: types.ResponseTypeComplete,
+		Done:         true,
+		Data:         data,
+	}
+}
+
 // createAgentQueryEvent creates a standard agent query event
 func createAgentQueryEvent(sessionID, assistantMessageID string) interfaces.StreamEvent {
 	return interfaces.StreamEvent{
@@ -166,7 +201,7 @@ func (h *Handler) setupStopEventHandler(
 	eventBus.On(event.EventStop, func(ctx context.Context, evt event.Event) error {
 		logger.Infof(ctx, "Received stop event, cancelling async operations for session: %s", sessionID)
 		cancel()
-		assistantMessage.Content = "User stopped this conversation"
+		assistantMessage.Content = i18n.Localize(ctx, "chat.stopped_by_user")
 		h.completeAssistantMessage(ctx, assistantMessage)
 		return nil
 	})
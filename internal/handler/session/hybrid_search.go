@@ -0,0 +1,124 @@
+package session
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/database"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+)
+
+const (
+	// defaultMultiSearchMaxKnowledgeBases is used when knowledge_base.multi_search.max_knowledge_bases is not set
+	defaultMultiSearchMaxKnowledgeBases = 20
+	// defaultMultiSearchConcurrency is used when knowledge_base.multi_search.max_concurrency is not set
+	defaultMultiSearchConcurrency = 4
+)
+
+// MultiHybridSearch godoc
+// @Summary      多知识库混合搜索
+// @Description  在多个知识库中并发执行混合搜索，合并为统一排序的结果，每条结果标注其来源知识库
+// @Tags         问答
+// @Accept       json
+// @Produce      json
+// @Param        request  body      MultiHybridSearchRequest  true  "搜索请求"
+// @Success      200      {object}  map[string]interface{}    "搜索结果"
+// @Failure      400      {object}  errors.AppError           "请求参数错误"
+// @Failure      404      {object}  errors.AppError           "知识库不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-search/hybrid [post]
+func (h *Handler) MultiHybridSearch(c *gin.Context) {
+	ctx := logger.CloneContext(c.Request.Context())
+	logger.Info(ctx, "Start processing multi knowledge base hybrid search request")
+
+	var request MultiHybridSearchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error(ctx, "Failed to parse request data", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+	if request.QueryText == "" {
+		c.Error(errors.NewBadRequestError("Query content cannot be empty"))
+		return
+	}
+
+	maxKnowledgeBases, concurrency := h.multiSearchLimits()
+	ids := make([]string, 0, len(request.KnowledgeBaseIDs))
+	seen := make(map[string]bool, len(request.KnowledgeBaseIDs))
+	for _, id := range request.KnowledgeBaseIDs {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		c.Error(errors.NewBadRequestError("At least one knowledge base ID must be provided"))
+		return
+	}
+	if len(ids) > maxKnowledgeBases {
+		c.Error(errors.NewBadRequestError(
+			fmt.Sprintf("At most %d knowledge bases can be searched at once", maxKnowledgeBases)).
+			WithDetails(map[string]interface{}{"max_knowledge_bases": maxKnowledgeBases}))
+		return
+	}
+
+	params := request.SearchParams
+	if err := params.VectorSearch.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError("Invalid vector search parameters").WithDetails(err.Error()))
+		return
+	}
+	if err := params.Dedup.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError("Invalid dedup parameters").WithDetails(err.Error()))
+		return
+	}
+	if err := params.Fusion.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError("Invalid fusion parameters").WithDetails(err.Error()))
+		return
+	}
+	if err := params.SourceQuota.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError("Invalid source quota parameters").WithDetails(err.Error()))
+		return
+	}
+	if err := types.ValidateRetrievalMode(params.RetrievalMode); err != nil {
+		c.Error(errors.NewBadRequestError("Invalid retrieval mode").WithDetails(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Executing hybrid search, knowledge base IDs: %v, query: %s",
+		secutils.SanitizeForLogArray(ids), secutils.SanitizeForLog(params.QueryText))
+
+	// Its reads tolerate replication lag, like the hybrid search of a single knowledge base
+	results, err := h.knowledgebaseService.MultiHybridSearch(database.WithReplicaReads(ctx), ids, params, concurrency)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"data":            results,
+		"collapsed_count": types.CountCollapsed(results),
+	})
+}
+
+// multiSearchLimits returns the number of knowledge bases a search may span and how many are searched at once
+func (h *Handler) multiSearchLimits() (int, int) {
+	maxKnowledgeBases, concurrency := defaultMultiSearchMaxKnowledgeBases, defaultMultiSearchConcurrency
+	if h.config != nil && h.config.KnowledgeBase != nil && h.config.KnowledgeBase.MultiSearch != nil {
+		limits := h.config.KnowledgeBase.MultiSearch
+		if limits.MaxKnowledgeBases > 0 {
+			maxKnowledgeBases = limits.MaxKnowledgeBases
+		}
+		if limits.MaxConcurrency > 0 {
+			concurrency = limits.MaxConcurrency
+		}
+	}
+	return maxKnowledgeBases, concurrency
+}
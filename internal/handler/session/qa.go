@@ -31,6 +31,9 @@ type qaRequestContext struct {
 	summaryModelID   string
 	webSearchEnabled bool
 	mentionedItems   types.MentionedItems
+	attachments      []*types.ChatAttachment
+	historyDepth     *int
+	retrieval        *types.RetrievalParams
 }
 
 // parseQARequest parses and validates a QA request, returns the request context
@@ -52,35 +55,78 @@ func (h *Handler) parseQARequest(c *gin.Context, logPrefix string) (*qaRequestCo
 		return nil, nil, errors.NewBadRequestError(err.Error())
 	}
 
+	reqCtx, err := h.newQARequestContext(ctx, c, sessionID, &request, logPrefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reqCtx, &request, nil
+}
+
+// newQARequestContext validates a parsed QA request and builds its request context
+func (h *Handler) newQARequestContext(ctx context.Context, c *gin.Context, sessionID string,
+	request *CreateKnowledgeQARequest, logPrefix string,
+) (*qaRequestContext, error) {
 	// Validate query content
 	if request.Query == "" {
 		logger.Error(ctx, "Query content is empty")
-		return nil, nil, errors.NewBadRequestError("Query content cannot be empty")
+		return nil, errors.NewBadRequestError("Query content cannot be empty")
+	}
+
+	// Validate attachments
+	if err := types.ValidateChatAttachments(request.Attachments); err != nil {
+		logger.Errorf(ctx, "Invalid attachments: %v", err)
+		return nil, errors.NewBadRequestError("Invalid attachments").WithDetails(err.Error())
+	}
+
+	// Validate history depth
+	if err := types.ValidateHistoryDepth(request.HistoryDepth, false); err != nil {
+		logger.Errorf(ctx, "Invalid history depth: %v", err)
+		return nil, errors.NewBadRequestError("Invalid history depth").WithDetails(err.Error())
 	}
 
-	// Log request details
-	if requestJSON, err := json.Marshal(request); err == nil {
-		logger.Infof(ctx, "[%s] Request: session_id=%s, request=%s",
-			logPrefix, sessionID, secutils.SanitizeForLog(string(requestJSON)))
+	// Validate retrieval overrides
+	if err := request.Retrieval.Validate(); err != nil {
+		logger.Errorf(ctx, "Invalid retrieval params: %v", err)
+		return nil, errors.NewBadRequestError("Invalid retrieval params").WithDetails(err.Error())
+	}
+
+	// Log request details (attachment content is left out)
+	loggedRequest := *request
+	loggedRequest.Attachments = nil
+	if requestJSON, err := json.Marshal(loggedRequest); err == nil {
+		logger.Infof(ctx, "[%s] Request: session_id=%s, attachments=%d, request=%s",
+			logPrefix, sessionID, len(request.Attachments), secutils.SanitizeForLog(string(requestJSON)))
+	}
+
+	if request.WebSearchEnabled && !h.featureEnabled(ctx, types.FeatureWebSearch) {
+		logger.Warnf(ctx, "[%s] Web search requested but not enabled for tenant", logPrefix)
+		return nil, errors.NewFeatureNotEnabledError(types.FeatureWebSearch)
 	}
 
 	// Get session
 	session, err := h.sessionService.GetSession(ctx, sessionID)
 	if err != nil {
 		logger.Errorf(ctx, "Failed to get session, session ID: %s, error: %v", sessionID, err)
-		return nil, nil, errors.NewNotFoundError("Session not found")
+		return nil, errors.NewNotFoundError("Session not found").WithCode(errors.CodeSessionNotFound)
 	}
 
 	// Get custom agent if agent_id is provided
 	var customAgent *types.CustomAgent
 	if request.AgentID != "" {
 		logger.Infof(ctx, "Fetching custom agent, agent ID: %s", secutils.SanitizeForLog(request.AgentID))
-		agent, err := h.customAgentService.GetAgentByID(ctx, request.AgentID)
+		agent, err := h.customAgentService.GetPublishedAgent(ctx, request.AgentID)
 		if err != nil {
 			logger.Warnf(ctx, "Failed to get custom agent, agent ID: %s, error: %v, using default config",
 				secutils.SanitizeForLog(request.AgentID), err)
 		} else {
 			customAgent = agent
+			if customAgent.Config.WebSearchEnabled && !h.featureEnabled(ctx, types.FeatureWebSearch) {
+				// Agent defaults may enable web search, turn it off on a copy instead of failing the request
+				agentCopy := *customAgent
+				agentCopy.Config.WebSearchEnabled = false
+				customAgent = &agentCopy
+				logger.Infof(ctx, "Web search not enabled for tenant, disabled for agent %s", customAgent.ID)
+			}
 			logger.Infof(ctx, "Using custom agent: ID=%s, Name=%s, IsBuiltin=%v, AgentMode=%s",
 				customAgent.ID, customAgent.Name, customAgent.IsBuiltin, customAgent.Config.AgentMode)
 		}
@@ -106,9 +152,31 @@ func (h *Handler) parseQARequest(c *gin.Context, logPrefix string) (*qaRequestCo
 		summaryModelID:   secutils.SanitizeForLog(request.SummaryModelID),
 		webSearchEnabled: request.WebSearchEnabled,
 		mentionedItems:   convertMentionedItems(request.MentionedItems),
+		attachments:      request.Attachments,
+		historyDepth:     request.HistoryDepth,
 	}
 
-	return reqCtx, &request, nil
+	// Inherit the session's default retrieval parameters unless the request overrides them, the default
+	// search targets only apply when the request selects none
+	reqCtx.retrieval = session.RetrievalParams.Merge(request.Retrieval)
+	if reqCtx.retrieval.IsEmpty() {
+		reqCtx.retrieval = nil
+	} else if len(reqCtx.knowledgeBaseIDs) == 0 && len(reqCtx.knowledgeIDs) == 0 {
+		reqCtx.knowledgeBaseIDs = secutils.SanitizeForLogArray(reqCtx.retrieval.KnowledgeBaseIDs)
+		reqCtx.knowledgeIDs = secutils.SanitizeForLogArray(reqCtx.retrieval.KnowledgeIDs)
+	}
+
+	return reqCtx, nil
+}
+
+// featureEnabled reports whether the feature is enabled for the tenant in ctx
+func (h *Handler) featureEnabled(ctx context.Context, name string) bool {
+	tenant, _ := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	var defaults types.FeatureFlags
+	if h.config != nil {
+		defaults = h.config.Features
+	}
+	return tenant.FeatureEnabled(name, defaults)
 }
 
 // sseStreamContext holds the context for SSE streaming
@@ -117,13 +185,20 @@ type sseStreamContext struct {
 	asyncCtx         context.Context
 	cancel           context.CancelFunc
 	assistantMessage *types.Message
+	// onComplete runs after the answer of a normal mode request is completed
+	onComplete func(ctx context.Context, message *types.Message)
 }
 
 // setupSSEStream sets up the SSE streaming context
 func (h *Handler) setupSSEStream(reqCtx *qaRequestContext, generateTitle bool) *sseStreamContext {
 	// Set SSE headers
 	setSSEHeaders(reqCtx.c)
+	return h.setupAnswerStream(reqCtx, generateTitle)
+}
 
+// setupAnswerStream starts streaming the answer of a request to the stream manager, whatever transport
+// then delivers it to the client
+func (h *Handler) setupAnswerStream(reqCtx *qaRequestContext, generateTitle bool) *sseStreamContext {
 	// Write initial agent_query event
 	h.writeAgentQueryEvent(reqCtx.ctx, reqCtx.sessionID, reqCtx.assistantMessage.ID)
 
@@ -220,30 +295,108 @@ func (h *Handler) SearchKnowledge(c *gin.Context) {
 		secutils.SanitizeForLog(request.Query),
 	)
 
+	// Knowledge bases without content return an empty result set with an explicit status
+	if message, empty := h.sessionService.EmptyKnowledgeBaseMessage(ctx, knowledgeBaseIDs, request.KnowledgeIDs); empty {
+		logger.Info(ctx, "Knowledge search targets have no content")
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    []*types.SearchResult{},
+			"status":  "empty_knowledge_base",
+			"message": message,
+		})
+		return
+	}
+
 	// Directly call knowledge retrieval service without LLM summarization
 	searchResults, err := h.sessionService.SearchKnowledge(ctx, knowledgeBaseIDs, request.KnowledgeIDs, request.Query)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
 	logger.Infof(ctx, "Knowledge search completed, found %d results", len(searchResults))
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"success": true,
 		"data":    searchResults,
+	}
+
+	// Explanation is opt-in since it costs a generation call; failures do not fail the search
+	if request.Explain || c.Query("explain") == "true" {
+		explanation, err := h.sessionService.ExplainSearchResults(
+			ctx, knowledgeBaseIDs, request.KnowledgeIDs, request.Query, searchResults,
+		)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to explain search results: %v", err)
+		} else {
+			response["explanation"] = explanation
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// EvaluateConfidenceGate godoc
+// @Summary      评估置信度门控
+// @Description  使用带标注的问题评估知识库的置信度门控，并给出不同阈值下的表现
+// @Tags         问答
+// @Accept       json
+// @Produce      json
+// @Param        request  body      EvaluateConfidenceGateRequest  true  "评估请求"
+// @Success      200      {object}  map[string]interface{}         "评估报告"
+// @Failure      400      {object}  errors.AppError                "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-search/confidence-gate/evaluate [post]
+func (h *Handler) EvaluateConfidenceGate(c *gin.Context) {
+	ctx := logger.CloneContext(c.Request.Context())
+	logger.Info(ctx, "Start processing confidence gate evaluation request")
+
+	var request EvaluateConfidenceGateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error(ctx, "Failed to parse request data", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+	if len(request.Cases) == 0 {
+		c.Error(errors.NewBadRequestError("At least one labeled case must be provided"))
+		return
+	}
+	for _, evalCase := range request.Cases {
+		if evalCase.Query == "" {
+			c.Error(errors.NewBadRequestError("Query content cannot be empty"))
+			return
+		}
+	}
+	if err := request.Config.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError("Invalid confidence gate config").WithDetails(err.Error()))
+		return
+	}
+
+	report, err := h.sessionService.EvaluateConfidenceGate(ctx, request.KnowledgeBaseID, request.Cases, request.Config)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
 	})
 }
 
 // KnowledgeQA godoc
 // @Summary      知识问答
-// @Description  基于知识库的问答（使用LLM总结），支持SSE流式响应
+// @Description  基于知识库的问答（使用LLM总结），支持SSE流式响应；async=true时后台生成并返回任务ID
 // @Tags         问答
 // @Accept       json
 // @Produce      text/event-stream
 // @Param        session_id  path      string                   true  "会话ID"
+// @Param        async       query     bool                     false "后台生成答案"
 // @Param        request     body      CreateKnowledgeQARequest true  "问答请求"
 // @Success      200         {object}  map[string]interface{}   "问答结果（SSE流）"
+// @Success      202         {object}  map[string]interface{}   "后台任务（async=true）"
 // @Failure      400         {object}  errors.AppError          "请求参数错误"
 // @Security     Bearer
 // @Security     ApiKeyAuth
@@ -256,6 +409,12 @@ func (h *Handler) KnowledgeQA(c *gin.Context) {
 		return
 	}
 
+	// Answer in the background when requested, generate title unless disabled
+	if request.Async || reqCtx.c.Query("async") == "true" {
+		h.executeAsyncQA(reqCtx, request.CallbackURL, !request.DisableTitle)
+		return
+	}
+
 	// Execute normal mode QA, generate title unless disabled
 	h.executeNormalModeQA(reqCtx, !request.DisableTitle)
 }
@@ -292,6 +451,15 @@ func (h *Handler) AgentQA(c *gin.Context) {
 
 	// Route to appropriate handler based on agent mode
 	if agentModeEnabled {
+		if !h.featureEnabled(reqCtx.ctx, types.FeatureAgent) {
+			logger.Warnf(reqCtx.ctx, "Agent mode requested but not enabled for tenant, session: %s", reqCtx.sessionID)
+			c.Error(errors.NewFeatureNotEnabledError(types.FeatureAgent))
+			return
+		}
+		if len(reqCtx.attachments) > 0 {
+			c.Error(errors.NewBadRequestError("Attachments are not supported in agent mode"))
+			return
+		}
 		h.executeAgentModeQA(reqCtx)
 	} else {
 		logger.Infof(reqCtx.ctx, "Agent mode disabled, delegating to normal mode for session: %s", reqCtx.sessionID)
@@ -307,22 +475,90 @@ func (h *Handler) executeNormalModeQA(reqCtx *qaRequestContext, generateTitle bo
 
 	// Create user message
 	if err := h.createUserMessage(ctx, sessionID, reqCtx.query, reqCtx.requestID, reqCtx.mentionedItems); err != nil {
-		reqCtx.c.Error(errors.NewInternalServerError(err.Error()))
+		reqCtx.c.Error(errors.FromError(err))
 		return
 	}
 
 	// Create assistant message
 	if _, err := h.createAssistantMessage(ctx, reqCtx.assistantMessage); err != nil {
-		reqCtx.c.Error(errors.NewInternalServerError(err.Error()))
+		reqCtx.c.Error(errors.FromError(err))
 		return
 	}
 
 	logger.Infof(ctx, "Using knowledge bases: %v", reqCtx.knowledgeBaseIDs)
 
+	// Check the answer cache before the SSE headers are written
+	cacheLookup := h.lookupCachedAnswer(reqCtx)
+	reqCtx.c.Header(answerCacheHeader, cacheLookup.status)
+
 	// Setup SSE stream
 	streamCtx := h.setupSSEStream(reqCtx, generateTitle)
+	streamCtx.onComplete = func(ctx context.Context, message *types.Message) {
+		h.storeCachedAnswer(ctx, cacheLookup, message)
+	}
 
 	// Setup completion handler for normal mode
+	h.handleNormalModeCompletion(streamCtx, sessionID)
+
+	// Execute KnowledgeQA asynchronously
+	go h.runKnowledgeQA(reqCtx, streamCtx, cacheLookup)
+
+	// Handle SSE events (blocking)
+	shouldWaitForTitle := generateTitle && reqCtx.session.Title == ""
+	h.handleAgentEventsForSSE(ctx, reqCtx.c, sessionID, reqCtx.assistantMessage.ID,
+		reqCtx.requestID, streamCtx.eventBus, shouldWaitForTitle)
+}
+
+// runKnowledgeQA answers a normal mode request, from the answer cache when it holds one, and reports
+// failures on the event bus of the stream
+func (h *Handler) runKnowledgeQA(reqCtx *qaRequestContext, streamCtx *sseStreamContext, cacheLookup *answerCacheLookup) {
+	sessionID := reqCtx.sessionID
+	defer func() {
+		if r := recover(); r != nil {
+			buf := make([]byte, 10240)
+			runtime.Stack(buf, true)
+			logger.ErrorWithFields(streamCtx.asyncCtx,
+				errors.NewInternalServerError(fmt.Sprintf("Knowledge QA service panicked: %v\n%s", r, string(buf))), nil)
+		}
+	}()
+
+	if cacheLookup.answer != nil {
+		h.replayCachedAnswer(streamCtx, sessionID, cacheLookup.answer)
+		return
+	}
+
+	err := h.sessionService.KnowledgeQA(
+		streamCtx.asyncCtx,
+		reqCtx.session,
+		reqCtx.query,
+		reqCtx.knowledgeBaseIDs,
+		reqCtx.knowledgeIDs,
+		reqCtx.assistantMessage.ID,
+		reqCtx.summaryModelID,
+		reqCtx.webSearchEnabled,
+		streamCtx.eventBus,
+		reqCtx.customAgent,
+		reqCtx.attachments,
+		reqCtx.historyDepth,
+		reqCtx.retrieval,
+	)
+	if err != nil {
+		logger.ErrorWithFields(streamCtx.asyncCtx, err, nil)
+		streamCtx.eventBus.Emit(streamCtx.asyncCtx, event.Event{
+			Type:      event.EventError,
+			SessionID: sessionID,
+			Data: event.ErrorData{
+				Error:     err.Error(),
+				Stage:     "knowledge_qa_execution",
+				SessionID: sessionID,
+			},
+		})
+	}
+}
+
+// handleNormalModeCompletion collects the streamed answer into the assistant message and completes it
+// once the final answer chunk arrives
+func (h *Handler) handleNormalModeCompletion(streamCtx *sseStreamContext, sessionID string) {
 	// Note: Thinking content is now embedded in answer stream with <think> tags
 	// by chat_completion_stream.go, so we don't need separate thinking event handling
 	var completionHandled bool // Prevent duplicate completion handling
@@ -343,6 +579,9 @@ func (h *Handler) executeNormalModeQA(reqCtx *qaRequestContext, generateTitle bo
 			logger.Infof(streamCtx.asyncCtx, "Knowledge QA service completed for session: %s", sessionID)
 			// Content already contains <think>...</think> tags from chat_completion_stream.go
 			h.completeAssistantMessage(streamCtx.asyncCtx, streamCtx.assistantMessage)
+			if streamCtx.onComplete != nil {
+				streamCtx.onComplete(streamCtx.asyncCtx, streamCtx.assistantMessage)
+			}
 			// Emit EventAgentComplete - this will trigger handleComplete which sends the SSE complete event
 			// Note: Don't cancel context here, let the SSE handler close naturally after receiving the complete event
 			streamCtx.eventBus.Emit(streamCtx.asyncCtx, event.Event{
@@ -353,48 +592,6 @@ func (h *Handler) executeNormalModeQA(reqCtx *qaRequestContext, generateTitle bo
 		}
 		return nil
 	})
-
-	// Execute KnowledgeQA asynchronously
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				buf := make([]byte, 10240)
-				runtime.Stack(buf, true)
-				logger.ErrorWithFields(streamCtx.asyncCtx,
-					errors.NewInternalServerError(fmt.Sprintf("Knowledge QA service panicked: %v\n%s", r, string(buf))), nil)
-			}
-		}()
-
-		err := h.sessionService.KnowledgeQA(
-			streamCtx.asyncCtx,
-			reqCtx.session,
-			reqCtx.query,
-			reqCtx.knowledgeBaseIDs,
-			reqCtx.knowledgeIDs,
-			reqCtx.assistantMessage.ID,
-			reqCtx.summaryModelID,
-			reqCtx.webSearchEnabled,
-			streamCtx.eventBus,
-			reqCtx.customAgent,
-		)
-		if err != nil {
-			logger.ErrorWithFields(streamCtx.asyncCtx, err, nil)
-			streamCtx.eventBus.Emit(streamCtx.asyncCtx, event.Event{
-				Type:      event.EventError,
-				SessionID: sessionID,
-				Data: event.ErrorData{
-					Error:     err.Error(),
-					Stage:     "knowledge_qa_execution",
-					SessionID: sessionID,
-				},
-			})
-		}
-	}()
-
-	// Handle SSE events (blocking)
-	shouldWaitForTitle := generateTitle && reqCtx.session.Title == ""
-	h.handleAgentEventsForSSE(ctx, reqCtx.c, sessionID, reqCtx.assistantMessage.ID,
-		reqCtx.requestID, streamCtx.eventBus, shouldWaitForTitle)
 }
 
 // executeAgentModeQA executes the agent mode
@@ -419,14 +616,14 @@ func (h *Handler) executeAgentModeQA(reqCtx *qaRequestContext) {
 
 	// Create user message
 	if err := h.createUserMessage(ctx, sessionID, reqCtx.query, reqCtx.requestID, reqCtx.mentionedItems); err != nil {
-		reqCtx.c.Error(errors.NewInternalServerError(err.Error()))
+		reqCtx.c.Error(errors.FromError(err))
 		return
 	}
 
 	// Create assistant message
 	assistantMessagePtr, err := h.createAssistantMessage(ctx, reqCtx.assistantMessage)
 	if err != nil {
-		reqCtx.c.Error(errors.NewInternalServerError(err.Error()))
+		reqCtx.c.Error(errors.FromError(err))
 		return
 	}
 	reqCtx.assistantMessage = assistantMessagePtr
@@ -460,6 +657,7 @@ func (h *Handler) executeAgentModeQA(reqCtx *qaRequestContext) {
 			reqCtx.customAgent,
 			reqCtx.knowledgeBaseIDs,
 			reqCtx.knowledgeIDs,
+			reqCtx.historyDepth,
 		)
 		if err != nil {
 			logger.ErrorWithFields(streamCtx.asyncCtx, err, nil)
@@ -0,0 +1,150 @@
+package session
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxRegenerateTemperature is the highest temperature accepted when regenerating an answer
+const maxRegenerateTemperature = 2.0
+
+// RegenerateAnswer godoc
+// @Summary      重新生成回答
+// @Description  复用原回答的引用重新生成回答，不重新检索；原回答保留在历史中
+// @Tags         消息
+// @Accept       json
+// @Produce      text/event-stream
+// @Param        session_id  path      string                   true   "会话ID"
+// @Param        id          path      string                   true   "原回答消息ID"
+// @Param        request     body      RegenerateAnswerRequest  false  "重新生成参数"
+// @Success      200         {object}  map[string]interface{}   "新回答（SSE流）"
+// @Failure      400         {object}  errors.AppError          "请求参数错误"
+// @Failure      404         {object}  errors.AppError          "消息不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /messages/{session_id}/{id}/regenerate [post]
+func (h *Handler) RegenerateAnswer(c *gin.Context) {
+	ctx := logger.CloneContext(c.Request.Context())
+	logger.Info(ctx, "[RegenerateAnswer] Start processing request")
+
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+	messageID := secutils.SanitizeForLog(c.Param("id"))
+	if sessionID == "" {
+		c.Error(errors.NewBadRequestError(errors.ErrInvalidSessionID.Error()))
+		return
+	}
+
+	// The request body is optional
+	var request RegenerateAnswerRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			logger.Error(ctx, "Failed to parse request data", err)
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+	}
+	if request.Temperature != nil && (*request.Temperature < 0 || *request.Temperature > maxRegenerateTemperature) {
+		c.Error(errors.NewBadRequestError(
+			fmt.Sprintf("Temperature must be between 0 and %.1f", maxRegenerateTemperature)))
+		return
+	}
+
+	session, err := h.sessionService.GetSession(ctx, sessionID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get session, session ID: %s, error: %v", sessionID, err)
+		c.Error(errors.NewNotFoundError("Session not found").WithCode(errors.CodeSessionNotFound))
+		return
+	}
+
+	// Only completed answers can be regenerated
+	sourceMessage, err := h.messageService.GetMessage(ctx, sessionID, messageID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get message, message ID: %s, error: %v", messageID, err)
+		c.Error(errors.NewNotFoundError("Message not found"))
+		return
+	}
+	if sourceMessage.Role != "assistant" {
+		c.Error(errors.NewBadRequestError("Only assistant messages can be regenerated"))
+		return
+	}
+	if !sourceMessage.IsCompleted {
+		c.Error(errors.NewBadRequestError("Message generation is not completed yet"))
+		return
+	}
+
+	userMessage, err := h.messageService.GetUserMessageByRequestID(ctx, sessionID, sourceMessage.RequestID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get question of message %s: %v", messageID, err)
+		c.Error(errors.NewNotFoundError("Question of the message not found"))
+		return
+	}
+
+	// The new answer shares the request ID of the original turn, so the latest version is used as history
+	reqCtx := &qaRequestContext{
+		ctx:       ctx,
+		c:         c,
+		sessionID: sessionID,
+		requestID: secutils.SanitizeForLog(getRequestID(c)),
+		query:     secutils.SanitizeForLog(userMessage.Content),
+		session:   session,
+		assistantMessage: &types.Message{
+			SessionID:       sessionID,
+			Role:            "assistant",
+			RequestID:       sourceMessage.RequestID,
+			RegeneratedFrom: sourceMessage.ID,
+			IsCompleted:     false,
+		},
+	}
+	if _, err := h.createAssistantMessage(ctx, reqCtx.assistantMessage); err != nil {
+		c.Error(errors.FromError(err))
+		return
+	}
+
+	streamCtx := h.setupSSEStream(reqCtx, false)
+	h.handleNormalModeCompletion(streamCtx, sessionID)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				buf := make([]byte, 10240)
+				runtime.Stack(buf, true)
+				logger.ErrorWithFields(streamCtx.asyncCtx,
+					errors.NewInternalServerError(fmt.Sprintf("Answer regeneration panicked: %v\n%s", r, string(buf))), nil)
+			}
+		}()
+
+		err := h.sessionService.RegenerateAnswer(
+			streamCtx.asyncCtx,
+			session,
+			userMessage,
+			sourceMessage,
+			reqCtx.assistantMessage.ID,
+			secutils.SanitizeForLog(request.SummaryModelID),
+			request.Temperature,
+			streamCtx.eventBus,
+		)
+		if err != nil {
+			logger.ErrorWithFields(streamCtx.asyncCtx, err, nil)
+			streamCtx.eventBus.Emit(streamCtx.asyncCtx, event.Event{
+				Type:      event.EventError,
+				SessionID: sessionID,
+				Data: event.ErrorData{
+					Error:     err.Error(),
+					Stage:     "answer_regeneration",
+					SessionID: sessionID,
+				},
+			})
+		}
+	}()
+
+	// Handle SSE events (blocking)
+	h.handleAgentEventsForSSE(ctx, c, sessionID, reqCtx.assistantMessage.ID,
+		reqCtx.requestID, streamCtx.eventBus, false)
+}
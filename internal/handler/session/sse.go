@@ -0,0 +1,92 @@
+package session
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultSSEPaddingBytes is the size of padding comments when not configured
+const defaultSSEPaddingBytes = 2048
+
+// sseHeartbeat is the comment written to keep an idle stream open
+const sseHeartbeat = ": heartbeat\n\n"
+
+// sseStream writes Server-Sent Events frames and flushes every frame so that it reaches the client
+// immediately. With a padding interval, padding comments are written while the stream is idle to push
+// frames through proxies that buffer until a size threshold is reached. With a heartbeat interval, short
+// comments are written while the stream is idle so that proxies do not close the connection.
+type sseStream struct {
+	c                 *gin.Context
+	paddingInterval   time.Duration
+	padding           string
+	heartbeatInterval time.Duration
+	lastWrite         time.Time
+}
+
+// newSSEStream creates a stream writer for c and flushes the response headers
+func newSSEStream(c *gin.Context, cfg *config.SSEConfig) *sseStream {
+	s := &sseStream{c: c, lastWrite: time.Now()}
+	if cfg != nil && cfg.PaddingInterval > 0 {
+		size := cfg.PaddingBytes
+		if size <= 0 {
+			size = defaultSSEPaddingBytes
+		}
+		s.paddingInterval = cfg.PaddingInterval
+		s.padding = ":" + strings.Repeat(" ", size) + "\n\n"
+	}
+	if cfg != nil {
+		s.heartbeatInterval = cfg.HeartbeatInterval
+	}
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+	return s
+}
+
+// sseStream returns the stream writer for c configured for this server
+func (h *Handler) sseStream(c *gin.Context) *sseStream {
+	var cfg *config.SSEConfig
+	if h.config != nil && h.config.Server != nil {
+		cfg = h.config.Server.SSE
+	}
+	return newSSEStream(c, cfg)
+}
+
+// send writes a message frame and flushes it
+func (s *sseStream) send(data any) {
+	s.c.SSEvent("message", data)
+	s.c.Writer.Flush()
+	s.lastWrite = time.Now()
+}
+
+// sendEvent writes a message frame with the stream offset after the event as its ID, which clients send
+// back in the Last-Event-ID header to resume the stream after it
+func (s *sseStream) sendEvent(offset int, data any) {
+	if _, err := s.c.Writer.WriteString("id: " + strconv.Itoa(offset) + "\n"); err != nil {
+		return
+	}
+	s.send(data)
+}
+
+// keepAlive writes a padding comment when padding is enabled and nothing was written for the padding interval,
+// or else a heartbeat comment when nothing was written for the heartbeat interval
+func (s *sseStream) keepAlive() {
+	idle := time.Since(s.lastWrite)
+	comment := ""
+	switch {
+	case s.paddingInterval > 0 && idle >= s.paddingInterval:
+		comment = s.padding
+	case s.heartbeatInterval > 0 && idle >= s.heartbeatInterval:
+		comment = sseHeartbeat
+	default:
+		return
+	}
+	if _, err := s.c.Writer.WriteString(comment); err != nil {
+		return
+	}
+	s.c.Writer.Flush()
+	s.lastWrite = time.Now()
+}
@@ -0,0 +1,147 @@
+package session
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// readFrame reads lines until an SSE frame or comment ends, failing the test after timeout
+func readFrame(t *testing.T, reader *bufio.Reader, timeout time.Duration) string {
+	t.Helper()
+	result := make(chan string, 1)
+	go func() {
+		var frame strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				result <- frame.String()
+				return
+			}
+			if line == "\n" {
+				result <- frame.String()
+				return
+			}
+			frame.WriteString(line)
+		}
+	}()
+	select {
+	case frame := <-result:
+		return frame
+	case <-time.After(timeout):
+		t.Fatal("frame was not delivered in time, the stream is buffered")
+		return ""
+	}
+}
+
+func TestSSEStreamDeliversFramesIncrementally(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	received := make(chan struct{})
+	router := gin.New()
+	router.GET("/stream", func(c *gin.Context) {
+		setSSEHeaders(c)
+		stream := newSSEStream(c, nil)
+		stream.send(map[string]string{"content": "first"})
+		// The second frame is only written once the client has received the first one
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			return
+		}
+		stream.send(map[string]string{"content": "second"})
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/event-stream") {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := resp.Header.Get("X-Accel-Buffering"); got != "no" {
+		t.Errorf("X-Accel-Buffering = %q", got)
+	}
+	if got := resp.Header.Get("Content-Encoding"); got != "identity" {
+		t.Errorf("Content-Encoding = %q", got)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	if frame := readFrame(t, reader, 2*time.Second); !strings.Contains(frame, "first") {
+		t.Fatalf("unexpected first frame %q", frame)
+	}
+	close(received)
+	if frame := readFrame(t, reader, 2*time.Second); !strings.Contains(frame, "second") {
+		t.Fatalf("unexpected second frame %q", frame)
+	}
+}
+
+func TestSSEStreamPadding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/stream", func(c *gin.Context) {
+		setSSEHeaders(c)
+		stream := newSSEStream(c, &config.SSEConfig{PaddingInterval: 10 * time.Millisecond, PaddingBytes: 16})
+		// Not idle yet, nothing is written
+		stream.keepAlive()
+		time.Sleep(20 * time.Millisecond)
+		stream.keepAlive()
+		stream.send("done")
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	if frame := readFrame(t, reader, 2*time.Second); frame != ":"+strings.Repeat(" ", 16)+"\n" {
+		t.Fatalf("expected a padding comment, got %q", frame)
+	}
+	if frame := readFrame(t, reader, 2*time.Second); !strings.Contains(frame, "done") {
+		t.Fatalf("unexpected frame %q", frame)
+	}
+}
+
+func TestSSEStreamHeartbeat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/stream", func(c *gin.Context) {
+		setSSEHeaders(c)
+		stream := newSSEStream(c, &config.SSEConfig{HeartbeatInterval: 10 * time.Millisecond})
+		// Not idle yet, nothing is written
+		stream.keepAlive()
+		time.Sleep(20 * time.Millisecond)
+		stream.keepAlive()
+		stream.sendEvent(7, "done")
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	if frame := readFrame(t, reader, 2*time.Second); frame != ": heartbeat\n" {
+		t.Fatalf("expected a heartbeat comment, got %q", frame)
+	}
+	if frame := readFrame(t, reader, 2*time.Second); !strings.HasPrefix(frame, "id: 7\n") ||
+		!strings.Contains(frame, "done") {
+		t.Fatalf("unexpected frame %q", frame)
+	}
+}
@@ -2,12 +2,15 @@ package session
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/i18n"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
@@ -17,14 +20,17 @@ import (
 
 // ContinueStream godoc
 // @Summary      继续流式响应
-// @Description  继续获取正在进行的流式响应
+// @Description  继续获取正在进行的流式响应。支持通过Last-Event-ID或from_offset只重放客户端尚未收到的事件
 // @Tags         问答
 // @Accept       json
 // @Produce      text/event-stream
-// @Param        session_id  path      string  true  "会话ID"
-// @Param        message_id  query     string  true  "消息ID"
-// @Success      200         {object}  map[string]interface{}  "流式响应"
-// @Failure      404         {object}  errors.AppError         "会话或消息不存在"
+// @Param        session_id     path      string  true   "会话ID"
+// @Param        message_id     query     string  true   "消息ID"
+// @Param        Last-Event-ID  header    int     false  "最后收到的事件ID"
+// @Param        from_offset    query     int     false  "已收到的事件数（无法设置请求头时使用）"
+// @Success      200            {object}  map[string]interface{}  "流式响应"
+// @Failure      400            {object}  errors.AppError         "请求参数错误"
+// @Failure      404            {object}  errors.AppError         "会话或消息不存在"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /sessions/{session_id}/continue [get]
@@ -49,17 +55,33 @@ func (h *Handler) ContinueStream(c *gin.Context) {
 		return
 	}
 
-	logger.Infof(ctx, "Continuing stream, session ID: %s, message ID: %s", sessionID, messageID)
+	// Event IDs are stream offsets, so the last received ID is the offset to resume from
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("from_offset")
+	}
+	fromOffset := 0
+	if lastEventID != "" {
+		offset, err := strconv.Atoi(lastEventID)
+		if err != nil || offset < 0 {
+			c.Error(errors.NewBadRequestError("Invalid last event ID").WithDetails(lastEventID))
+			return
+		}
+		fromOffset = offset
+	}
+
+	logger.Infof(ctx, "Continuing stream, session ID: %s, message ID: %s, from offset: %d",
+		sessionID, messageID, fromOffset)
 
 	// Verify that the session exists and belongs to this tenant
 	_, err := h.sessionService.GetSession(ctx, sessionID)
 	if err != nil {
 		if err == errors.ErrSessionNotFound {
 			logger.Warnf(ctx, "Session not found, ID: %s", sessionID)
-			c.Error(errors.NewNotFoundError(err.Error()))
+			c.Error(errors.FromError(err))
 		} else {
 			logger.ErrorWithFields(ctx, err, nil)
-			c.Error(errors.NewInternalServerError(err.Error()))
+			c.Error(errors.FromError(err))
 		}
 		return
 	}
@@ -68,7 +90,7 @@ func (h *Handler) ContinueStream(c *gin.Context) {
 	message, err := h.messageService.GetMessage(ctx, sessionID, messageID)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -81,21 +103,48 @@ func (h *Handler) ContinueStream(c *gin.Context) {
 		return
 	}
 
-	// Get initial events from stream (offset 0)
-	events, currentOffset, err := h.streamManager.GetEvents(ctx, sessionID, messageID, 0)
+	// Read from the last received event as well, which tells whether the stream already finished
+	readOffset := max(fromOffset-1, 0)
+	events, currentOffset, err := h.streamManager.GetEvents(ctx, sessionID, messageID, readOffset)
+	if goerrors.Is(err, interfaces.ErrStreamEventsExpired) && currentOffset == fromOffset {
+		// Only the last received event was dropped
+		readOffset = fromOffset
+		events, currentOffset, err = h.streamManager.GetEvents(ctx, sessionID, messageID, readOffset)
+	}
+	if goerrors.Is(err, interfaces.ErrStreamEventsExpired) {
+		logger.Infof(ctx, "Events from offset %d are no longer buffered, session ID: %s, message ID: %s",
+			fromOffset, sessionID, messageID)
+		setSSEHeaders(c)
+		if message.IsCompleted {
+			h.sseStream(c).send(buildStreamFinalResponse(message))
+		} else {
+			h.sseStream(c).send(buildStreamResetResponse(message, currentOffset))
+		}
+		return
+	}
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
 		c.Error(errors.NewInternalServerError(fmt.Sprintf("Failed to get stream data: %s", err.Error())))
 		return
 	}
+	streamCompleted := false
+	if readOffset < fromOffset && len(events) > 0 {
+		streamCompleted = events[0].Type == types.ResponseTypeComplete
+		events = events[1:]
+		readOffset++
+	}
 
-	if len(events) == 0 {
-		logger.Warnf(ctx, "No events found in stream, session ID: %s, message ID: %s", sessionID, messageID)
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"error":   "No stream events found",
-		})
-		return
+	if len(events) == 0 && !streamCompleted && !message.IsCompleted {
+		if fromOffset == 0 {
+			logger.Warnf(ctx, "No events found in stream, session ID: %s, message ID: %s", sessionID, messageID)
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   "No stream events found",
+			})
+			return
+		}
+		// The client is up to date, wait for the next events
+		currentOffset = fromOffset
 	}
 
 	logger.Infof(
@@ -105,22 +154,26 @@ func (h *Handler) ContinueStream(c *gin.Context) {
 
 	// Set headers for SSE
 	setSSEHeaders(c)
+	stream := h.sseStream(c)
+	h.metrics.ActiveStreams.Inc()
+	defer h.metrics.ActiveStreams.Dec()
 
-	// Check if stream is already completed
-	streamCompleted := false
-	for _, evt := range events {
-		if evt.Type == "complete" {
+	// Replay the events the client has not received
+	logger.Debugf(ctx, "Replaying %d existing events", len(events))
+	for i, evt := range events {
+		if evt.Type == types.ResponseTypeComplete {
 			streamCompleted = true
-			break
 		}
+		response := buildStreamResponse(evt, message.RequestID)
+		stream.sendEvent(readOffset+i+1, response)
 	}
 
-	// Replay existing events
-	logger.Debugf(ctx, "Replaying %d existing events", len(events))
-	for _, evt := range events {
-		response := buildStreamResponse(evt, message.RequestID)
-		c.SSEvent("message", response)
-		c.Writer.Flush()
+	// The stream finished before the client reconnected: with nothing left to replay, the final state is
+	// sent so that the client can settle the message
+	if len(events) == 0 && (streamCompleted || message.IsCompleted) {
+		logger.Infof(ctx, "Stream already delivered, session ID: %s, message ID: %s", sessionID, messageID)
+		stream.send(buildStreamFinalResponse(message))
+		return
 	}
 
 	// If stream is already completed, send final event and return
@@ -144,6 +197,11 @@ func (h *Handler) ContinueStream(c *gin.Context) {
 		case <-ticker.C:
 			// Get new events from current offset
 			newEvents, newOffset, err := h.streamManager.GetEvents(ctx, sessionID, messageID, currentOffset)
+			if goerrors.Is(err, interfaces.ErrStreamEventsExpired) {
+				logger.Warnf(ctx, "Stream events were dropped before they were sent, session ID: %s", sessionID)
+				stream.send(buildStreamResetResponse(message, newOffset))
+				return
+			}
 			if err != nil {
 				logger.Errorf(ctx, "Failed to get new events: %v", err)
 				return
@@ -151,19 +209,19 @@ func (h *Handler) ContinueStream(c *gin.Context) {
 
 			// Send new events
 			streamCompletedNow := false
-			for _, evt := range newEvents {
+			for i, evt := range newEvents {
 				// Check for completion event
-				if evt.Type == "complete" {
+				if evt.Type == types.ResponseTypeComplete {
 					streamCompletedNow = true
 				}
 
 				response := buildStreamResponse(evt, message.RequestID)
-				c.SSEvent("message", response)
-				c.Writer.Flush()
+				stream.sendEvent(currentOffset+i+1, response)
 			}
 
 			// Update offset
 			currentOffset = newOffset
+			stream.keepAlive()
 
 			// If stream completed, send final event and exit
 			if streamCompletedNow {
@@ -217,8 +275,38 @@ func (h *Handler) StopSession(c *gin.Context) {
 		c.JSON(401, gin.H{"error": "Unauthorized"})
 		return
 	}
-	tenantIDUint := tenantID.(uint64)
 
+	completed, stopErr := h.stopGeneration(ctx, tenantID.(uint64), sessionID, assistantMessageID)
+	if stopErr != nil {
+		c.JSON(stopErr.status, gin.H{"error": stopErr.message})
+		return
+	}
+	if completed {
+		c.JSON(200, gin.H{
+			"success": true,
+			"message": "Message already completed",
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": i18n.Localize(ctx, "chat.generation_stopped"),
+	})
+}
+
+// stopError is a rejected stop request, with the HTTP status it is answered with
+type stopError struct {
+	status  int
+	message string
+}
+
+// stopGeneration writes a stop event for an assistant message of the tenant's session, which the
+// instance streaming the answer picks up to cancel the generation. It reports whether the message was
+// already completed, in which case there is nothing to stop.
+func (h *Handler) stopGeneration(ctx context.Context, tenantID uint64,
+	sessionID, assistantMessageID string,
+) (bool, *stopError) {
 	// Verify message ownership and status
 	message, err := h.messageService.GetMessage(ctx, sessionID, assistantMessageID)
 	if err != nil {
@@ -226,15 +314,13 @@ func (h *Handler) StopSession(c *gin.Context) {
 			"session_id": sessionID,
 			"message_id": assistantMessageID,
 		})
-		c.JSON(404, gin.H{"error": "Message not found"})
-		return
+		return false, &stopError{status: 404, message: "Message not found"}
 	}
 
 	// Verify message belongs to this session (double check)
 	if message.SessionID != sessionID {
 		logger.Warnf(ctx, "Message %s does not belong to session %s", assistantMessageID, sessionID)
-		c.JSON(403, gin.H{"error": "Message does not belong to this session"})
-		return
+		return false, &stopError{status: 403, message: "Message does not belong to this session"}
 	}
 
 	// Verify message belongs to the current tenant
@@ -243,24 +329,18 @@ func (h *Handler) StopSession(c *gin.Context) {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{
 			"session_id": sessionID,
 		})
-		c.JSON(404, gin.H{"error": "Session not found"})
-		return
+		return false, &stopError{status: 404, message: "Session not found"}
 	}
 
-	if session.TenantID != tenantIDUint {
-		logger.Warnf(ctx, "Session %s does not belong to tenant %d", sessionID, tenantIDUint)
-		c.JSON(403, gin.H{"error": "Access denied"})
-		return
+	if session.TenantID != tenantID {
+		logger.Warnf(ctx, "Session %s does not belong to tenant %d", sessionID, tenantID)
+		return false, &stopError{status: 403, message: "Access denied"}
 	}
 
 	// Check if message is already completed (stopped)
 	if message.IsCompleted {
 		logger.Infof(ctx, "Message %s is already completed, no need to stop", assistantMessageID)
-		c.JSON(200, gin.H{
-			"success": true,
-			"message": "Message already completed",
-		})
-		return
+		return true, nil
 	}
 
 	// Write stop event to StreamManager for distributed support
@@ -282,15 +362,11 @@ func (h *Handler) StopSession(c *gin.Context) {
 			"session_id": sessionID,
 			"message_id": assistantMessageID,
 		})
-		c.JSON(500, gin.H{"error": "Failed to write stop event"})
-		return
+		return false, &stopError{status: 500, message: "Failed to write stop event"}
 	}
 
 	logger.Infof(ctx, "Stop event written successfully for session: %s, message: %s", sessionID, assistantMessageID)
-	c.JSON(200, gin.H{
-		"success": true,
-		"message": "Generation stopped",
-	})
+	return false, nil
 }
 
 // handleAgentEventsForSSE handles agent events for SSE streaming using an existing handler
@@ -307,6 +383,9 @@ func (h *Handler) handleAgentEventsForSSE(
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 
+	stream := h.sseStream(c)
+	h.metrics.ActiveStreams.Inc()
+	defer h.metrics.ActiveStreams.Dec()
 	lastOffset := 0
 	log := logger.GetLogger(ctx)
 
@@ -326,6 +405,12 @@ func (h *Handler) handleAgentEventsForSSE(
 		case <-ticker.C:
 			// Get new events from StreamManager using offset
 			events, newOffset, err := h.streamManager.GetEvents(ctx, sessionID, assistantMessageID, lastOffset)
+			if goerrors.Is(err, interfaces.ErrStreamEventsExpired) {
+				// Only when this reader fell behind by a whole buffer, the client can reload the message
+				log.Warnf("Stream events were dropped before they were sent, session=%s", sessionID)
+				lastOffset = newOffset
+				continue
+			}
 			if err != nil {
 				log.Warnf("Failed to get events from stream: %v", err)
 				continue
@@ -334,7 +419,7 @@ func (h *Handler) handleAgentEventsForSSE(
 			// Send any new events
 			streamCompleted := false
 			titleReceived := false
-			for _, evt := range events {
+			for i, evt := range events {
 				// Check for stop event
 				if evt.Type == types.ResponseType(event.EventStop) {
 					log.Infof("Detected stop event, triggering stop via EventBus for session=%s", sessionID)
@@ -353,13 +438,12 @@ func (h *Handler) handleAgentEventsForSSE(
 					}
 
 					// Send stop notification to frontend
-					c.SSEvent("message", &types.StreamResponse{
+					stream.send(&types.StreamResponse{
 						ID:           requestID,
 						ResponseType: "stop",
-						Content:      "Generation stopped by user",
+						Content:      i18n.Localize(c.Request.Context(), "chat.generation_stopped_by_user"),
 						Done:         true,
 					})
-					c.Writer.Flush()
 					return
 				}
 
@@ -382,12 +466,12 @@ func (h *Handler) handleAgentEventsForSSE(
 					return
 				}
 
-				c.SSEvent("message", response)
-				c.Writer.Flush()
+				stream.sendEvent(lastOffset+i+1, response)
 			}
 
 			// Update offset
 			lastOffset = newOffset
+			stream.keepAlive()
 
 			// Check if stream is completed - wait for title event only if needed and not already received
 			if streamCompleted {
@@ -412,10 +496,9 @@ func (h *Handler) handleAgentEventsForSSE(
 								break titleWaitLoop
 							}
 							if len(events) > 0 {
-								for _, evt := range events {
+								for i, evt := range events {
 									response := buildStreamResponse(evt, requestID)
-									c.SSEvent("message", response)
-									c.Writer.Flush()
+									stream.sendEvent(lastOffset+i+1, response)
 									// If we got the title, we can exit
 									if evt.Type == types.ResponseTypeSessionTitle {
 										log.Infof("Title event received: %s", evt.Content)
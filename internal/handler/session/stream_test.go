@@ -0,0 +1,184 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/metrics"
+	"github.com/Tencent/WeKnora/internal/middleware"
+	"github.com/Tencent/WeKnora/internal/stream"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+type fakeStreamSessionService struct {
+	interfaces.SessionService
+}
+
+func (f *fakeStreamSessionService) GetSession(_ context.Context, id string) (*types.Session, error) {
+	return &types.Session{ID: id}, nil
+}
+
+type fakeStreamMessageService struct {
+	interfaces.MessageService
+	message *types.Message
+}
+
+func (f *fakeStreamMessageService) GetMessage(_ context.Context, _, _ string) (*types.Message, error) {
+	return f.message, nil
+}
+
+// sseFrame is a frame of the continued stream
+type sseFrame struct {
+	id       string
+	response types.StreamResponse
+}
+
+// parseSSEFrames parses the message frames of a stream, skipping comments
+func parseSSEFrames(t *testing.T, body string) []sseFrame {
+	t.Helper()
+	var frames []sseFrame
+	for _, block := range strings.Split(body, "\n\n") {
+		var frame sseFrame
+		data := ""
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				frame.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data:"):
+				data = strings.TrimPrefix(line, "data:")
+			}
+		}
+		if data == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(data), &frame.response); err != nil {
+			t.Fatalf("invalid frame data %q: %v", data, err)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func TestContinueStreamResume(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		maxEvents   int
+		completed   bool
+		header      string
+		query       string
+		wantStatus  int
+		wantIDs     []string
+		wantTypes   []types.ResponseType
+		wantContent string
+	}{
+		{
+			name:      "replay all",
+			wantIDs:   []string{"1", "2", "3", "4"},
+			wantTypes: []types.ResponseType{"answer", "answer", "answer", "complete"},
+		},
+		{
+			name:      "resume from Last-Event-ID",
+			header:    "2",
+			wantIDs:   []string{"3", "4"},
+			wantTypes: []types.ResponseType{"answer", "complete"},
+		},
+		{
+			name:      "resume from from_offset",
+			query:     "3",
+			wantIDs:   []string{"4"},
+			wantTypes: []types.ResponseType{"complete"},
+		},
+		{
+			name:        "reconnect after completion",
+			completed:   true,
+			header:      "4",
+			wantIDs:     []string{""},
+			wantTypes:   []types.ResponseType{"complete"},
+			wantContent: "Comet tails",
+		},
+		{
+			name:      "offset no longer buffered",
+			maxEvents: 2,
+			header:    "1",
+			wantIDs:   []string{""},
+			wantTypes: []types.ResponseType{types.ResponseTypeStreamReset},
+		},
+		{
+			name:      "resume at the first buffered event",
+			maxEvents: 2,
+			header:    "2",
+			wantIDs:   []string{"3", "4"},
+			wantTypes: []types.ResponseType{"answer", "complete"},
+		},
+		{name: "invalid Last-Event-ID", header: "-1", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := stream.NewMemoryStreamManager(tt.maxEvents)
+			for _, content := range []string{"Comet", " tails", "."} {
+				_ = manager.AppendEvent(ctx, "session-1", "msg-1",
+					interfaces.StreamEvent{Type: types.ResponseTypeAnswer, Content: content})
+			}
+			_ = manager.AppendEvent(ctx, "session-1", "msg-1",
+				interfaces.StreamEvent{Type: types.ResponseTypeComplete, Done: true})
+
+			message := &types.Message{ID: "msg-1", RequestID: "req-1", IsCompleted: tt.completed}
+			if tt.completed {
+				message.Content = "Comet tails."
+			}
+			h := &Handler{
+				sessionService: &fakeStreamSessionService{},
+				messageService: &fakeStreamMessageService{message: message},
+				streamManager:  manager,
+				metrics:        metrics.New(metrics.NewRegistry()),
+			}
+			router := gin.New()
+			router.Use(middleware.ErrorHandler())
+			router.GET("/sessions/continue-stream/:session_id", h.ContinueStream)
+
+			url := "/sessions/continue-stream/session-1?message_id=msg-1"
+			if tt.query != "" {
+				url += "&from_offset=" + tt.query
+			}
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			if tt.header != "" {
+				req.Header.Set("Last-Event-ID", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			wantStatus := tt.wantStatus
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			if rec.Code != wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, wantStatus, rec.Body.String())
+			}
+			if wantStatus != http.StatusOK {
+				return
+			}
+			frames := parseSSEFrames(t, rec.Body.String())
+			if len(frames) != len(tt.wantIDs) {
+				t.Fatalf("got %d frames, want %d: %s", len(frames), len(tt.wantIDs), rec.Body.String())
+			}
+			for i, frame := range frames {
+				if frame.id != tt.wantIDs[i] || frame.response.ResponseType != tt.wantTypes[i] {
+					t.Errorf("frame %d = id %q type %s, want id %q type %s",
+						i, frame.id, frame.response.ResponseType, tt.wantIDs[i], tt.wantTypes[i])
+				}
+			}
+			if tt.wantContent != "" && !strings.Contains(frames[0].response.Data["content"].(string), tt.wantContent) {
+				t.Errorf("final frame data = %v, want content %q", frames[0].response.Data, tt.wantContent)
+			}
+		})
+	}
+}
@@ -46,7 +46,7 @@ func (h *Handler) GenerateTitle(c *gin.Context) {
 	session, err := h.sessionService.GetSession(ctx, sessionID)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -55,7 +55,7 @@ func (h *Handler) GenerateTitle(c *gin.Context) {
 	title, err := h.sessionService.GenerateTitle(ctx, session, request.Messages, "")
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		c.Error(errors.NewInternalServerError(err.Error()))
+		c.Error(errors.FromError(err))
 		return
 	}
 
@@ -1,6 +1,8 @@
 package session
 
 import (
+	"time"
+
 	"github.com/Tencent/WeKnora/internal/types"
 )
 
@@ -12,6 +14,8 @@ type CreateSessionRequest struct {
 	Title string `json:"title"`
 	// Description for the session (optional)
 	Description string `json:"description"`
+	// Default retrieval parameters inherited by knowledge QA requests (optional)
+	RetrievalParams *types.RetrievalParams `json:"retrieval_params"`
 }
 
 // GenerateTitleRequest defines the request structure for generating a session title
@@ -38,6 +42,38 @@ type CreateKnowledgeQARequest struct {
 	SummaryModelID   string                 `json:"summary_model_id"`                      // Optional summary model ID for this request (overrides session default)
 	MentionedItems   []MentionedItemRequest `json:"mentioned_items"`                       // @mentioned knowledge bases and files
 	DisableTitle     bool                   `json:"disable_title"`                         // Whether to disable auto title generation
+	// Attachments are used as context for this message only and are not added to any knowledge base
+	Attachments []*types.ChatAttachment `json:"attachments"`
+	// HistoryDepth caps the prior turns included in the prompt for this request (0 = no history),
+	// overriding the knowledge base setting
+	HistoryDepth *int `json:"history_depth"`
+	// Retrieval overrides the session's default retrieval parameters for this request
+	Retrieval *types.RetrievalParams `json:"retrieval"`
+	// Async answers in the background: the request returns a job ID right away instead of a stream
+	Async bool `json:"async"`
+	// CallbackURL receives the signed answer of an async request once it completes (optional)
+	CallbackURL string `json:"callback_url"`
+}
+
+// Statuses of a knowledge chat request answered in the background
+const (
+	AsyncChatStatusRunning   = "running"
+	AsyncChatStatusCompleted = "completed"
+	AsyncChatStatusFailed    = "failed"
+)
+
+// AsyncChatCallback is the body posted to the callback URL of an async knowledge chat request.
+// The job ID is the ID of the assistant message holding the answer.
+type AsyncChatCallback struct {
+	JobID       string           `json:"job_id"`
+	SessionID   string           `json:"session_id"`
+	MessageID   string           `json:"message_id"`
+	RequestID   string           `json:"request_id"`
+	Status      string           `json:"status"`
+	Answer      string           `json:"answer"`
+	References  types.References `json:"references"`
+	Error       string           `json:"error,omitempty"`
+	CompletedAt time.Time        `json:"completed_at"`
 }
 
 // SearchKnowledgeRequest defines the request structure for searching knowledge without LLM summarization
@@ -46,6 +82,27 @@ type SearchKnowledgeRequest struct {
 	KnowledgeBaseID  string   `json:"knowledge_base_id"`                     // Single knowledge base ID (for backward compatibility)
 	KnowledgeBaseIDs []string `json:"knowledge_base_ids"`                    // IDs of knowledge bases to search (multi-KB support)
 	KnowledgeIDs     []string `json:"knowledge_ids"`                         // IDs of specific knowledge (files) to search
+	Explain          bool     `json:"explain"`                               // Whether to add an LLM explanation of the top results
+}
+
+// MultiHybridSearchRequest defines the request structure for a hybrid search across knowledge bases.
+// The search parameters are those of the knowledge base hybrid search, applied to every knowledge base.
+type MultiHybridSearchRequest struct {
+	KnowledgeBaseIDs []string `json:"knowledge_base_ids" binding:"required"` // Knowledge bases to search
+	types.SearchParams
+}
+
+// EvaluateConfidenceGateRequest defines the request structure for evaluating a confidence gate
+type EvaluateConfidenceGateRequest struct {
+	KnowledgeBaseID string                      `json:"knowledge_base_id" binding:"required"` // Knowledge base to evaluate
+	Cases           []types.ConfidenceEvalCase  `json:"cases"             binding:"required"` // Labeled queries
+	Config          *types.ConfidenceGateConfig `json:"config"`                               // Optional policy overriding the knowledge base one
+}
+
+// RegenerateAnswerRequest defines the optional overrides for regenerating an answer
+type RegenerateAnswerRequest struct {
+	SummaryModelID string   `json:"summary_model_id"` // Optional model ID (overrides session default)
+	Temperature    *float64 `json:"temperature"`      // Optional sampling temperature (0-2)
 }
 
 // StopSessionRequest represents the stop session request
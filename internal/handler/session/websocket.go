@@ -0,0 +1,350 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/i18n"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+)
+
+// wsPingInterval is how often the server pings an idle or busy knowledge chat socket
+const wsPingInterval = 30 * time.Second
+
+// Frame types of the knowledge chat WebSocket. Server frames carrying a stream event are typed by the
+// event's response type instead (answer, references, complete, ...).
+const (
+	wsFrameQuestion = "question" // Client: ask a question
+	wsFrameStop     = "stop"     // Client: stop an answer; server: the answer was stopped
+	wsFrameStarted  = "started"  // Server: the messages of a question were created and the answer started
+	wsFrameError    = "error"    // Server: a frame was rejected or an answer failed
+)
+
+// wsClientFrame is a frame sent by the client over the knowledge chat WebSocket
+type wsClientFrame struct {
+	Type string `json:"type"`
+	// ID correlates the frames of a question; a stop frame can name the question by it
+	ID string `json:"id,omitempty"`
+	// MessageID names the assistant message of the answer to stop
+	MessageID string                    `json:"message_id,omitempty"`
+	Request   *CreateKnowledgeQARequest `json:"request,omitempty"`
+}
+
+// wsServerFrame is a frame sent by the server over the knowledge chat WebSocket
+type wsServerFrame struct {
+	Type      string                `json:"type"`
+	ID        string                `json:"id,omitempty"`
+	MessageID string                `json:"message_id,omitempty"`
+	RequestID string                `json:"request_id,omitempty"`
+	Event     *types.StreamResponse `json:"event,omitempty"`
+	Error     string                `json:"error,omitempty"`
+}
+
+// newWSEventFrame wraps a stream response of the answer to question id in a frame typed by its response type
+func newWSEventFrame(id, messageID string, response *types.StreamResponse) *wsServerFrame {
+	return &wsServerFrame{
+		Type:      string(response.ResponseType),
+		ID:        id,
+		MessageID: messageID,
+		RequestID: response.ID,
+		Event:     response,
+	}
+}
+
+// wsPing sends an empty ping frame; the client answers with a pong the connection discards
+var wsPing = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		return nil, websocket.PingFrame, nil
+	},
+}
+
+// KnowledgeChatWebSocket godoc
+// @Summary      知识问答（WebSocket）
+// @Description  通过WebSocket进行知识问答，同一连接可并发多个问题并可随时停止回答
+// @Tags         问答
+// @Param        session_id  path      string  true  "会话ID"
+// @Success      101         {string}  string  "切换到WebSocket协议"
+// @Failure      400         {object}  errors.AppError  "请求参数错误"
+// @Failure      404         {object}  errors.AppError  "会话不存在"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /knowledge-chat/ws/{session_id} [get]
+func (h *Handler) KnowledgeChatWebSocket(c *gin.Context) {
+	ctx := logger.CloneContext(c.Request.Context())
+
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+	if sessionID == "" {
+		logger.Error(ctx, "Session ID is empty")
+		c.Error(errors.NewBadRequestError(errors.ErrInvalidSessionID.Error()))
+		return
+	}
+
+	// Reject unknown sessions before upgrading, while errors can still be answered over HTTP
+	if _, err := h.sessionService.GetSession(ctx, sessionID); err != nil {
+		logger.Errorf(ctx, "Failed to get session, session ID: %s, error: %v", sessionID, err)
+		c.Error(errors.NewNotFoundError("Session not found").WithCode(errors.CodeSessionNotFound))
+		return
+	}
+
+	// Requests are authenticated by header, so any origin is accepted
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		h.serveKnowledgeChat(ctx, c, ws, sessionID)
+	}}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// chatSocket is a knowledge chat WebSocket connection, answering any number of questions concurrently
+type chatSocket struct {
+	h         *Handler
+	c         *gin.Context
+	ws        *websocket.Conn
+	ctx       context.Context // Cancelled once the client disconnects
+	sessionID string
+	tenantID  uint64
+
+	mu      sync.Mutex
+	answers map[string]string // Assistant message ID by question ID
+	running sync.WaitGroup
+}
+
+// serveKnowledgeChat reads the client frames of a connection until it closes, then stops its answers
+func (h *Handler) serveKnowledgeChat(ctx context.Context, c *gin.Context, ws *websocket.Conn, sessionID string) {
+	connCtx, cancel := context.WithCancel(ctx)
+	s := &chatSocket{
+		h:         h,
+		c:         c,
+		ws:        ws,
+		ctx:       connCtx,
+		sessionID: sessionID,
+		tenantID:  c.GetUint64(types.TenantIDContextKey.String()),
+		answers:   make(map[string]string),
+	}
+	defer func() {
+		cancel()
+		s.running.Wait()
+		ws.Close()
+	}()
+	go s.ping()
+
+	logger.Infof(ctx, "Knowledge chat WebSocket opened for session: %s", sessionID)
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			if err != io.EOF && connCtx.Err() == nil {
+				logger.Warnf(ctx, "Knowledge chat WebSocket read failed for session %s: %v", sessionID, err)
+			}
+			logger.Infof(ctx, "Knowledge chat WebSocket closed for session: %s", sessionID)
+			return
+		}
+
+		var frame wsClientFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			s.send(&wsServerFrame{Type: wsFrameError, Error: "Invalid frame: " + err.Error()})
+			continue
+		}
+		switch frame.Type {
+		case wsFrameQuestion:
+			s.ask(&frame)
+		case wsFrameStop:
+			s.stop(&frame)
+		default:
+			s.send(&wsServerFrame{Type: wsFrameError, ID: frame.ID, Error: "Unknown frame type: " + frame.Type})
+		}
+	}
+}
+
+// send writes a frame; frames written concurrently by the answers never interleave
+func (s *chatSocket) send(frame *wsServerFrame) {
+	if err := websocket.JSON.Send(s.ws, frame); err != nil && s.ctx.Err() == nil {
+		logger.Warnf(s.ctx, "Failed to write knowledge chat WebSocket frame: %v", err)
+	}
+}
+
+// ping pings the client until the connection closes, closing it when a ping cannot be written
+func (s *chatSocket) ping() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := wsPing.Send(s.ws, nil); err != nil {
+				logger.Warnf(s.ctx, "Failed to ping knowledge chat WebSocket, closing it: %v", err)
+				s.ws.Close()
+				return
+			}
+		}
+	}
+}
+
+// ask starts answering a question frame the way the knowledge chat endpoint does, so that the session
+// history is the same whichever transport asked the question
+func (s *chatSocket) ask(frame *wsClientFrame) {
+	h := s.h
+	if frame.Request == nil {
+		s.send(&wsServerFrame{Type: wsFrameError, ID: frame.ID, Error: "Question frame has no request"})
+		return
+	}
+
+	reqCtx, err := h.newQARequestContext(s.ctx, s.c, s.sessionID, frame.Request, "KnowledgeChatWebSocket")
+	if err != nil {
+		s.send(&wsServerFrame{Type: wsFrameError, ID: frame.ID, Error: errors.FromError(err).Message})
+		return
+	}
+	// Questions share the upgrade request, each answer gets its own request ID
+	reqCtx.requestID = uuid.New().String()
+	reqCtx.assistantMessage.RequestID = reqCtx.requestID
+	ctx := reqCtx.ctx
+
+	if err := h.createUserMessage(ctx, s.sessionID, reqCtx.query, reqCtx.requestID, reqCtx.mentionedItems); err != nil {
+		s.send(&wsServerFrame{Type: wsFrameError, ID: frame.ID, Error: errors.FromError(err).Message})
+		return
+	}
+	if _, err := h.createAssistantMessage(ctx, reqCtx.assistantMessage); err != nil {
+		s.send(&wsServerFrame{Type: wsFrameError, ID: frame.ID, Error: errors.FromError(err).Message})
+		return
+	}
+	messageID := reqCtx.assistantMessage.ID
+	if frame.ID != "" {
+		s.mu.Lock()
+		s.answers[frame.ID] = messageID
+		s.mu.Unlock()
+	}
+
+	generateTitle := !frame.Request.DisableTitle
+	cacheLookup := h.lookupCachedAnswer(reqCtx)
+	streamCtx := h.setupAnswerStream(reqCtx, generateTitle)
+	streamCtx.onComplete = func(ctx context.Context, message *types.Message) {
+		h.storeCachedAnswer(ctx, cacheLookup, message)
+	}
+	h.handleNormalModeCompletion(streamCtx, s.sessionID)
+
+	s.send(&wsServerFrame{Type: wsFrameStarted, ID: frame.ID, MessageID: messageID, RequestID: reqCtx.requestID})
+	go h.runKnowledgeQA(reqCtx, streamCtx, cacheLookup)
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.forward(frame.ID, reqCtx, streamCtx.eventBus, generateTitle && reqCtx.session.Title == "")
+	}()
+}
+
+// stop stops the answer named by a stop frame, through the stream manager like the stop endpoint does
+func (s *chatSocket) stop(frame *wsClientFrame) {
+	messageID := secutils.SanitizeForLog(frame.MessageID)
+	if messageID == "" && frame.ID != "" {
+		s.mu.Lock()
+		messageID = s.answers[frame.ID]
+		s.mu.Unlock()
+	}
+	if messageID == "" {
+		s.send(&wsServerFrame{Type: wsFrameError, ID: frame.ID, Error: "message_id is required"})
+		return
+	}
+
+	logger.Infof(s.ctx, "Stop generation request for session: %s, message: %s", s.sessionID, messageID)
+	if _, stopErr := s.h.stopGeneration(s.ctx, s.tenantID, s.sessionID, messageID); stopErr != nil {
+		s.send(&wsServerFrame{Type: wsFrameError, ID: frame.ID, MessageID: messageID, Error: stopErr.message})
+	}
+}
+
+// forward polls the stream manager for the events of an answer and writes them to the socket until the
+// answer completes, fails or is stopped. When the client disconnects, the answer is stopped.
+func (s *chatSocket) forward(id string, reqCtx *qaRequestContext, eventBus *event.EventBus, waitForTitle bool) {
+	h := s.h
+	sessionID, messageID, requestID := s.sessionID, reqCtx.assistantMessage.ID, reqCtx.requestID
+	h.metrics.ActiveStreams.Inc()
+	defer h.metrics.ActiveStreams.Dec()
+	defer func() {
+		if id != "" {
+			s.mu.Lock()
+			delete(s.answers, id)
+			s.mu.Unlock()
+		}
+	}()
+
+	emitStop := func(reason string) {
+		eventBus.Emit(context.WithoutCancel(s.ctx), event.Event{
+			Type:      event.EventStop,
+			SessionID: sessionID,
+			Data: event.StopData{
+				SessionID: sessionID,
+				MessageID: messageID,
+				Reason:    reason,
+			},
+		})
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	lastOffset := 0
+	// Once the answer completes, the session title is still awaited for a while
+	var titleDeadline time.Time
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			logger.Infof(reqCtx.ctx, "Client disconnected, stopping answer for session=%s, message=%s",
+				sessionID, messageID)
+			if titleDeadline.IsZero() {
+				emitStop("client_disconnected")
+			}
+			return
+
+		case <-ticker.C:
+			events, newOffset, err := h.streamManager.GetEvents(s.ctx, sessionID, messageID, lastOffset)
+			if goerrors.Is(err, interfaces.ErrStreamEventsExpired) {
+				logger.Warnf(reqCtx.ctx, "Stream events were dropped before they were sent, session=%s", sessionID)
+				lastOffset = newOffset
+				continue
+			}
+			if err != nil {
+				logger.Warnf(reqCtx.ctx, "Failed to get events from stream: %v", err)
+				continue
+			}
+			lastOffset = newOffset
+
+			for _, evt := range events {
+				if evt.Type == types.ResponseType(event.EventStop) {
+					emitStop("user_requested")
+					s.send(newWSEventFrame(id, messageID, &types.StreamResponse{
+						ID:           requestID,
+						ResponseType: wsFrameStop,
+						Content:      i18n.Localize(reqCtx.ctx, "chat.generation_stopped_by_user"),
+						Done:         true,
+					}))
+					return
+				}
+
+				s.send(newWSEventFrame(id, messageID, buildStreamResponse(evt, requestID)))
+				switch evt.Type {
+				case types.ResponseTypeError:
+					return
+				case types.ResponseTypeSessionTitle:
+					waitForTitle = false
+				case "complete":
+					titleDeadline = time.Now().Add(3 * time.Second)
+				}
+			}
+
+			if !titleDeadline.IsZero() && (!waitForTitle || time.Now().After(titleDeadline)) {
+				logger.Infof(reqCtx.ctx, "Answer streamed over WebSocket for session=%s, message=%s",
+					sessionID, messageID)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"golang.org/x/net/websocket"
+)
+
+func TestNewWSEventFrame(t *testing.T) {
+	frame := newWSEventFrame("q-1", "msg-1", &types.StreamResponse{
+		ID:           "req-1",
+		ResponseType: types.ResponseTypeAnswer,
+		Content:      "Comet",
+	})
+	if frame.Type != string(types.ResponseTypeAnswer) || frame.ID != "q-1" || frame.MessageID != "msg-1" ||
+		frame.RequestID != "req-1" || frame.Event.Content != "Comet" {
+		t.Errorf("frame = %+v, want an answer frame of question q-1", frame)
+	}
+}
+
+func TestWSPingBetweenFrames(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		if err := wsPing.Send(ws, nil); err != nil {
+			t.Errorf("ping: %v", err)
+			return
+		}
+		_ = websocket.JSON.Send(ws, &wsServerFrame{Type: wsFrameStarted, ID: "q-1", MessageID: "msg-1"})
+	}))
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer ws.Close()
+
+	// The client answers the ping and receives the frame written after it
+	var frame wsServerFrame
+	if err := websocket.JSON.Receive(ws, &frame); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if frame.Type != wsFrameStarted || frame.MessageID != "msg-1" {
+		t.Errorf("frame = %+v, want the started frame", frame)
+	}
+}
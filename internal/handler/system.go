@@ -7,8 +7,12 @@ import (
 	"strings"
 
 	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/database"
+	"github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/scheduler"
 	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	"github.com/gin-gonic/gin"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -19,13 +23,22 @@ import (
 type SystemHandler struct {
 	cfg         *config.Config
 	neo4jDriver neo4j.Driver
+	replicaPool *database.ReplicaPool
+	userService interfaces.UserService
 }
 
 // NewSystemHandler creates a new system handler
-func NewSystemHandler(cfg *config.Config, neo4jDriver neo4j.Driver) *SystemHandler {
+func NewSystemHandler(
+	cfg *config.Config,
+	neo4jDriver neo4j.Driver,
+	replicaPool *database.ReplicaPool,
+	userService interfaces.UserService,
+) *SystemHandler {
 	return &SystemHandler{
 		cfg:         cfg,
 		neo4jDriver: neo4jDriver,
+		replicaPool: replicaPool,
+		userService: userService,
 	}
 }
 
@@ -91,6 +104,81 @@ func (h *SystemHandler) GetSystemInfo(c *gin.Context) {
 	})
 }
 
+// GetModelQueueStats godoc
+// @Summary      获取模型调用队列状态
+// @Description  获取交互与批量优先级下模型调用的排队深度和运行数
+// @Tags         系统
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  scheduler.Stats  "队列状态"
+// @Router       /system/model-queue [get]
+func (h *SystemHandler) GetModelQueueStats(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": scheduler.Default().Stats(),
+	})
+}
+
+// GetDatabaseReplicas godoc
+// @Summary      获取数据库只读副本状态
+// @Description  获取检索读请求使用的只读副本的健康状态与复制延迟
+// @Tags         系统
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "副本状态"
+// @Router       /system/database/replicas [get]
+func (h *SystemHandler) GetDatabaseReplicas(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"enabled":  h.replicaPool.Enabled(),
+			"replicas": h.replicaPool.Statuses(),
+		},
+	})
+}
+
+// GetEffectiveConfig godoc
+// @Summary      获取生效配置
+// @Description  获取进程实际运行的配置（凭据已脱敏），并标注每个值来自配置文件、环境变量还是默认值（仅管理员）
+// @Tags         系统
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  config.EffectiveConfig  "生效配置"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Security     Bearer
+// @Router       /system/config [get]
+func (h *SystemHandler) GetEffectiveConfig(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	user, err := h.userService.GetCurrentUser(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get current user: %v", err)
+		c.Error(errors.NewForbiddenError("The effective configuration requires an administrator account"))
+		return
+	}
+	if !user.CanAccessAllTenants {
+		logger.Warnf(ctx, "User %s attempted to access the effective configuration without permission", user.ID)
+		c.Error(errors.NewForbiddenError("Insufficient permissions to access the effective configuration"))
+		return
+	}
+
+	effective, err := h.cfg.Effective()
+	if err != nil {
+		logger.Errorf(ctx, "Failed to build effective configuration: %v", err)
+		c.Error(errors.NewInternalServerError("Failed to build effective configuration").WithDetails(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Effective configuration retrieved by user %s", user.ID)
+	c.JSON(200, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": effective,
+	})
+}
+
 // getKeywordIndexEngine returns the keyword index engine name
 func (h *SystemHandler) getKeywordIndexEngine() string {
 	retrieveDriver := os.Getenv("RETRIEVE_DRIVER")
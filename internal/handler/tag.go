@@ -43,7 +43,7 @@ func (h *TagHandler) resolveTagID(c *gin.Context) (string, error) {
 		tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 		tag, err := h.tagRepo.GetBySeqID(ctx, tenantID, seqID)
 		if err != nil {
-			return "", errors.NewNotFoundError("tag not found")
+			return "", errors.NewNotFoundError("tag not found").WithCode(errors.CodeTagNotFound)
 		}
 		return tag.ID, nil
 	}
@@ -208,6 +208,7 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 // @Param        body          body      DeleteTagRequest    false  "删除选项"
 // @Success      200           {object}  map[string]interface{}  "删除成功"
 // @Failure      400           {object}  errors.AppError         "请求参数错误"
+// @Failure      409           {object}  errors.AppError         "标签仍被引用"
 // @Security     Bearer
 // @Security     ApiKeyAuth
 // @Router       /knowledge-bases/{id}/tags/{tag_id} [delete]
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/Tencent/WeKnora/internal/config"
 	"github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/metrics"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	secutils "github.com/Tencent/WeKnora/internal/utils"
@@ -21,22 +23,33 @@ import (
 // through the REST API endpoints
 type TenantHandler struct {
 	service     interfaces.TenantService
+	dataService interfaces.TenantDataService
 	userService interfaces.UserService
 	config      *config.Config
+	metrics     *metrics.Metrics
 }
 
 // NewTenantHandler creates a new tenant handler instance with the provided service
 // Parameters:
 //   - service: An implementation of the TenantService interface for business logic
+//   - dataService: An implementation of the TenantDataService interface for data export and purge
 //   - userService: An implementation of the UserService interface for user operations
 //   - config: Application configuration
+//   - metrics: Service metrics, holding the payload sizes of the tenants
 //
 // Returns a pointer to the newly created TenantHandler
-func NewTenantHandler(service interfaces.TenantService, userService interfaces.UserService, config *config.Config) *TenantHandler {
+func NewTenantHandler(service interfaces.TenantService,
+	dataService interfaces.TenantDataService,
+	userService interfaces.UserService,
+	config *config.Config,
+	metrics *metrics.Metrics,
+) *TenantHandler {
 	return &TenantHandler{
 		service:     service,
+		dataService: dataService,
 		userService: userService,
 		config:      config,
+		metrics:     metrics,
 	}
 }
 
@@ -63,6 +76,11 @@ func (h *TenantHandler) CreateTenant(c *gin.Context) {
 		c.Error(appErr)
 		return
 	}
+	if err := types.ValidateAPIKeyScopes(tenantData.APIKeyScopes); err != nil {
+		logger.Error(ctx, "Invalid API key scopes", err)
+		c.Error(errors.NewValidationError("Invalid API key scopes").WithDetails(err.Error()))
+		return
+	}
 
 	logger.Infof(ctx, "Creating tenant, name: %s", secutils.SanitizeForLog(tenantData.Name))
 
@@ -163,10 +181,19 @@ func (h *TenantHandler) UpdateTenant(c *gin.Context) {
 		c.Error(errors.NewValidationError("Invalid request data").WithDetails(err.Error()))
 		return
 	}
+	// Omitted scopes are kept, an empty list revokes every scope of the API key
+	if err := types.ValidateAPIKeyScopes(tenantData.APIKeyScopes); err != nil {
+		logger.Error(ctx, "Invalid API key scopes", err)
+		c.Error(errors.NewValidationError("Invalid API key scopes").WithDetails(err.Error()))
+		return
+	}
 
 	logger.Infof(ctx, "Updating tenant, ID: %d, Name: %s", id, secutils.SanitizeForLog(tenantData.Name))
 
 	tenantData.ID = id
+	// The rate limit and payload limit overrides are managed by operators through the tenant KV store only
+	tenantData.RateLimitConfig = nil
+	tenantData.PayloadLimitConfig = nil
 	updatedTenant, err := h.service.UpdateTenant(ctx, &tenantData)
 	if err != nil {
 		// Check if this is an application-specific error
@@ -194,46 +221,21 @@ func (h *TenantHandler) UpdateTenant(c *gin.Context) {
 
 // DeleteTenant godoc
 // @Summary      删除租户
-// @Description  删除指定的租户
+// @Description  异步永久删除租户及其全部数据（知识库、知识、分块、向量、FAQ、会话、消息、智能体、模型和存储的文件），需要跨租户访问权限和确认令牌，不能删除当前租户
 // @Tags         租户管理
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "租户ID"
-// @Success      200  {object}  map[string]interface{}  "删除成功"
-// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Param        id                    path    int     true   "租户ID"
+// @Param        X-Confirmation-Token  header  string  false  "确认令牌"
+// @Param        confirmation_token    query   string  false  "确认令牌"
+// @Success      200  {object}  map[string]interface{}  "删除任务进度"
+// @Failure      400  {object}  errors.AppError         "请求参数错误或确认令牌无效"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Failure      404  {object}  errors.AppError         "租户不存在"
 // @Security     Bearer
 // @Router       /tenants/{id} [delete]
 func (h *TenantHandler) DeleteTenant(c *gin.Context) {
-	ctx := c.Request.Context()
-
-	logger.Info(ctx, "Start deleting tenant")
-
-	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
-	if err != nil {
-		logger.Errorf(ctx, "Invalid tenant ID: %s", secutils.SanitizeForLog(c.Param("id")))
-		c.Error(errors.NewBadRequestError("Invalid tenant ID"))
-		return
-	}
-
-	logger.Infof(ctx, "Deleting tenant, ID: %d", id)
-
-	if err := h.service.DeleteTenant(ctx, id); err != nil {
-		// Check if this is an application-specific error
-		if appErr, ok := errors.IsAppError(err); ok {
-			logger.Error(ctx, "Failed to delete tenant: application error", appErr)
-			c.Error(appErr)
-		} else {
-			logger.ErrorWithFields(ctx, err, nil)
-			c.Error(errors.NewInternalServerError("Failed to delete tenant").WithDetails(err.Error()))
-		}
-		return
-	}
-
-	logger.Infof(ctx, "Tenant deleted successfully, ID: %d", id)
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Tenant deleted successfully",
-	})
+	h.purgeTenant(c)
 }
 
 // ListTenants godoc
@@ -572,7 +574,7 @@ func (h *TenantHandler) updateTenantAgentConfigInternal(c *gin.Context) {
 
 // GetTenantKV godoc
 // @Summary      获取租户KV配置
-// @Description  获取租户级别的KV配置（支持agent-config、web-search-config、conversation-config）
+// @Description  获取租户级别的KV配置（支持agent-config、web-search-config、conversation-config、prompt-injection-config、rate-limit-config、payload-limit-config、webhook-config）
 // @Tags         租户管理
 // @Accept       json
 // @Produce      json
@@ -596,6 +598,18 @@ func (h *TenantHandler) GetTenantKV(c *gin.Context) {
 	case "conversation-config":
 		h.GetTenantConversationConfig(c)
 		return
+	case "prompt-injection-config":
+		h.GetTenantPromptInjectionConfig(c)
+		return
+	case "rate-limit-config":
+		h.GetTenantRateLimitConfig(c)
+		return
+	case "payload-limit-config":
+		h.GetTenantPayloadLimitConfig(c)
+		return
+	case "webhook-config":
+		h.GetTenantWebhookConfig(c)
+		return
 	case "prompt-templates":
 		h.GetPromptTemplates(c)
 		return
@@ -608,7 +622,7 @@ func (h *TenantHandler) GetTenantKV(c *gin.Context) {
 
 // UpdateTenantKV godoc
 // @Summary      更新租户KV配置
-// @Description  更新租户级别的KV配置（支持agent-config、web-search-config、conversation-config）
+// @Description  更新租户级别的KV配置（支持agent-config、web-search-config、conversation-config、prompt-injection-config、rate-limit-config、payload-limit-config、webhook-config）
 // @Tags         租户管理
 // @Accept       json
 // @Produce      json
@@ -633,6 +647,18 @@ func (h *TenantHandler) UpdateTenantKV(c *gin.Context) {
 	case "conversation-config":
 		h.updateTenantConversationInternal(c)
 		return
+	case "prompt-injection-config":
+		h.updateTenantPromptInjectionConfigInternal(c)
+		return
+	case "rate-limit-config":
+		h.updateTenantRateLimitConfigInternal(c)
+		return
+	case "payload-limit-config":
+		h.updateTenantPayloadLimitConfigInternal(c)
+		return
+	case "webhook-config":
+		h.updateTenantWebhookConfigInternal(c)
+		return
 	default:
 		logger.Info(ctx, "KV key not supported", "key", key)
 		c.Error(errors.NewBadRequestError("unsupported key"))
@@ -713,6 +739,514 @@ func (h *TenantHandler) GetTenantWebSearchConfig(c *gin.Context) {
 	})
 }
 
+// updateTenantPromptInjectionConfigInternal updates tenant's prompt injection defense config
+func (h *TenantHandler) updateTenantPromptInjectionConfigInternal(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var cfg types.PromptInjectionConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewValidationError("Invalid request data").WithDetails(err.Error()))
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	tenant.PromptInjectionConfig = &cfg
+	updatedTenant, err := h.service.UpdateTenant(ctx, tenant)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			logger.Error(ctx, "Failed to update tenant: application error", appErr)
+			c.Error(appErr)
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to update tenant prompt injection config").WithDetails(err.Error()))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updatedTenant.PromptInjectionConfig,
+		"message": "Prompt injection configuration updated successfully",
+	})
+}
+
+// GetTenantPromptInjectionConfig godoc
+// @Summary      获取租户提示注入防护配置
+// @Description  获取租户对检索内容的提示注入防护配置
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "提示注入防护配置"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/kv/prompt-injection-config [get]
+func (h *TenantHandler) GetTenantPromptInjectionConfig(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	config := tenant.PromptInjectionConfig
+	if config == nil {
+		config = &types.PromptInjectionConfig{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    config,
+	})
+}
+
+// updateTenantRateLimitConfigInternal updates tenant's rate limit override.
+// Only operators with cross-tenant access may change it, so tenants cannot raise their own limits.
+func (h *TenantHandler) updateTenantRateLimitConfigInternal(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if appErr := h.checkCrossTenantAccess(ctx); appErr != nil {
+		c.Error(appErr)
+		return
+	}
+
+	var cfg types.RateLimitConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewValidationError("Invalid request data").WithDetails(err.Error()))
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	tenant.RateLimitConfig = &cfg
+	updatedTenant, err := h.service.UpdateTenant(ctx, tenant)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			logger.Error(ctx, "Failed to update tenant: application error", appErr)
+			c.Error(appErr)
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to update tenant rate limit config").WithDetails(err.Error()))
+		}
+		return
+	}
+	logger.Infof(ctx, "Tenant rate limit config updated, Tenant ID: %d, rps: %v, burst: %d, disabled: %v",
+		tenant.ID, cfg.RequestsPerSecond, cfg.Burst, cfg.Disabled)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updatedTenant.RateLimitConfig,
+		"message": "Rate limit configuration updated successfully",
+	})
+}
+
+// GetTenantRateLimitConfig godoc
+// @Summary      获取租户限流配置
+// @Description  获取租户的请求限流覆盖配置，未设置的字段使用全局默认值
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "限流配置"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/kv/rate-limit-config [get]
+func (h *TenantHandler) GetTenantRateLimitConfig(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	config := tenant.RateLimitConfig
+	if config == nil {
+		config = &types.RateLimitConfig{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    config,
+	})
+}
+
+// updateTenantPayloadLimitConfigInternal updates tenant's payload size limit override.
+// Only operators with cross-tenant access may change it, so tenants cannot raise their own limits.
+func (h *TenantHandler) updateTenantPayloadLimitConfigInternal(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if appErr := h.checkCrossTenantAccess(ctx); appErr != nil {
+		c.Error(appErr)
+		return
+	}
+
+	var cfg types.PayloadLimitConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewValidationError("Invalid request data").WithDetails(err.Error()))
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	tenant.PayloadLimitConfig = &cfg
+	updatedTenant, err := h.service.UpdateTenant(ctx, tenant)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			logger.Error(ctx, "Failed to update tenant: application error", appErr)
+			c.Error(appErr)
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to update tenant payload limit config").WithDetails(err.Error()))
+		}
+		return
+	}
+	logger.Infof(ctx, "Tenant payload limit config updated, Tenant ID: %d, max request: %d, max response: %d, disabled: %v",
+		tenant.ID, cfg.MaxRequestBytes, cfg.MaxResponseBytes, cfg.Disabled)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updatedTenant.PayloadLimitConfig,
+		"message": "Payload limit configuration updated successfully",
+	})
+}
+
+// GetTenantPayloadLimitConfig godoc
+// @Summary      获取租户请求体大小限制配置
+// @Description  获取租户的请求/响应体大小限制覆盖配置，未设置的字段使用全局默认值
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "请求体大小限制配置"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/kv/payload-limit-config [get]
+func (h *TenantHandler) GetTenantPayloadLimitConfig(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	config := tenant.PayloadLimitConfig
+	if config == nil {
+		config = &types.PayloadLimitConfig{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    config,
+	})
+}
+
+// updateTenantWebhookConfigInternal updates the webhook notified when the tenant's asynchronous tasks finish.
+// An empty secret keeps the current one, so that the URL can be changed without resending the secret.
+func (h *TenantHandler) updateTenantWebhookConfigInternal(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var cfg types.WebhookConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewValidationError("Invalid request data").WithDetails(err.Error()))
+		return
+	}
+
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	if cfg.Secret == "" && tenant.WebhookConfig != nil {
+		cfg.Secret = tenant.WebhookConfig.Secret
+	}
+	if err := cfg.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+	if cfg.URL != "" {
+		if safe, reason := secutils.IsSSRFSafeURL(cfg.URL); !safe {
+			c.Error(errors.NewBadRequestError("Invalid webhook URL").WithDetails(reason))
+			return
+		}
+	}
+
+	tenant.WebhookConfig = &cfg
+	updatedTenant, err := h.service.UpdateTenant(ctx, tenant)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			logger.Error(ctx, "Failed to update tenant: application error", appErr)
+			c.Error(appErr)
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to update tenant webhook config").WithDetails(err.Error()))
+		}
+		return
+	}
+	logger.Infof(ctx, "Tenant webhook config updated, Tenant ID: %d, url set: %v", tenant.ID, cfg.URL != "")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updatedTenant.WebhookConfig.Masked(),
+		"message": "Webhook configuration updated successfully",
+	})
+}
+
+// GetTenantWebhookConfig godoc
+// @Summary      获取租户任务 webhook 配置
+// @Description  获取异步任务（知识库复制、FAQ导入、Ollama模型下载）完成时通知的 webhook 配置，密钥以掩码返回
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "webhook 配置"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/kv/webhook-config [get]
+func (h *TenantHandler) GetTenantWebhookConfig(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	config := tenant.WebhookConfig
+	if config == nil {
+		config = &types.WebhookConfig{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    config.Masked(),
+	})
+}
+
+// GetTenantUsage godoc
+// @Summary      获取租户用量
+// @Description  获取当前租户的存储用量与配额，以及本节点统计的请求/响应体大小与生效的限制
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "租户用量"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/usage [get]
+func (h *TenantHandler) GetTenantUsage(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenant, _ := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	var defaults config.PayloadLimitConfig
+	if h.config != nil && h.config.PayloadLimit != nil {
+		defaults = *h.config.PayloadLimit
+	}
+	maxRequest, maxResponse := tenant.PayloadLimits(defaults.MaxRequestBytes, defaults.MaxResponseBytes)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"tenant_id": tenant.ID,
+			"storage": gin.H{
+				"used":  tenant.StorageUsed,
+				"quota": tenant.StorageQuota,
+			},
+			"payload": gin.H{
+				"max_request_bytes":  maxRequest,
+				"max_response_bytes": maxResponse,
+				"usage":              h.metrics.Payloads.Stats(tenant.ID),
+			},
+		},
+	})
+}
+
+// checkCrossTenantAccess returns an error unless cross-tenant access is enabled and the current user may access all tenants
+func (h *TenantHandler) checkCrossTenantAccess(ctx context.Context) *errors.AppError {
+	user, err := h.userService.GetCurrentUser(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get current user: %v", err)
+		return errors.NewUnauthorizedError("Failed to get user information").WithDetails(err.Error())
+	}
+	if h.config == nil || h.config.Tenant == nil || !h.config.Tenant.EnableCrossTenantAccess {
+		logger.Warnf(ctx, "Cross-tenant access is disabled, user: %s", user.ID)
+		return errors.NewForbiddenError("Cross-tenant access is disabled")
+	}
+	if !user.CanAccessAllTenants {
+		logger.Warnf(ctx, "User %s attempted to access tenant features without permission", user.ID)
+		return errors.NewForbiddenError("Insufficient permissions to access all tenants")
+	}
+	return nil
+}
+
+// GetTenantFeatures godoc
+// @Summary      获取租户功能开关
+// @Description  获取租户生效的功能开关、租户覆盖值及全局默认值
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "租户ID"
+// @Success      200  {object}  map[string]interface{}  "功能开关"
+// @Failure      400  {object}  errors.AppError         "请求参数错误"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/{id}/features [get]
+func (h *TenantHandler) GetTenantFeatures(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Errorf(ctx, "Invalid tenant ID: %s", secutils.SanitizeForLog(c.Param("id")))
+		c.Error(errors.NewBadRequestError("Invalid tenant ID"))
+		return
+	}
+
+	// Other tenants' features are only visible to users with cross-tenant access
+	if id != c.GetUint64(types.TenantIDContextKey.String()) {
+		if appErr := h.checkCrossTenantAccess(ctx); appErr != nil {
+			c.Error(appErr)
+			return
+		}
+	}
+
+	tenant, err := h.service.GetTenantByID(ctx, id)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			logger.Error(ctx, "Failed to retrieve tenant: application error", appErr)
+			c.Error(appErr)
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to retrieve tenant").WithDetails(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.tenantFeatures(tenant),
+	})
+}
+
+// UpdateTenantFeatures godoc
+// @Summary      更新租户功能开关
+// @Description  设置租户的功能开关覆盖值，未设置的功能使用全局默认值（需要跨租户访问权限）
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                 true  "租户ID"
+// @Param        request  body      types.FeatureFlags  true  "功能开关覆盖值"
+// @Success      200      {object}  map[string]interface{}  "更新后的功能开关"
+// @Failure      400      {object}  errors.AppError         "请求参数错误"
+// @Failure      403      {object}  errors.AppError         "权限不足"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/{id}/features [put]
+func (h *TenantHandler) UpdateTenantFeatures(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Errorf(ctx, "Invalid tenant ID: %s", secutils.SanitizeForLog(c.Param("id")))
+		c.Error(errors.NewBadRequestError("Invalid tenant ID"))
+		return
+	}
+
+	if appErr := h.checkCrossTenantAccess(ctx); appErr != nil {
+		c.Error(appErr)
+		return
+	}
+
+	var flags types.FeatureFlags
+	if err := c.ShouldBindJSON(&flags); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewValidationError("Invalid request data").WithDetails(err.Error()))
+		return
+	}
+	if err := flags.Validate(); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	tenant, err := h.service.GetTenantByID(ctx, id)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			logger.Error(ctx, "Failed to retrieve tenant: application error", appErr)
+			c.Error(appErr)
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to retrieve tenant").WithDetails(err.Error()))
+		}
+		return
+	}
+
+	tenant.FeatureFlags = flags
+	updatedTenant, err := h.service.UpdateTenant(ctx, tenant)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			logger.Error(ctx, "Failed to update tenant: application error", appErr)
+			c.Error(appErr)
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to update tenant features").WithDetails(err.Error()))
+		}
+		return
+	}
+
+	logger.Infof(ctx, "Tenant features updated successfully, ID: %d", id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.tenantFeatures(updatedTenant),
+		"message": "Tenant features updated successfully",
+	})
+}
+
+// tenantFeatures describes the effective features of a tenant
+func (h *TenantHandler) tenantFeatures(tenant *types.Tenant) gin.H {
+	var defaults types.FeatureFlags
+	if h.config != nil {
+		defaults = h.config.Features
+	}
+	return gin.H{
+		"tenant_id": tenant.ID,
+		"features":  types.ResolveFeatures(defaults, tenant.FeatureFlags),
+		"overrides": tenant.FeatureFlags,
+		"defaults":  types.ResolveFeatures(defaults, nil),
+	}
+}
+
 func (h *TenantHandler) buildDefaultConversationConfig() *types.ConversationConfig {
 	return &types.ConversationConfig{
 		Prompt:               h.config.Conversation.Summary.Prompt,
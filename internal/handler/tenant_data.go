@@ -0,0 +1,367 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+)
+
+// tenantDataTarget parses the tenant ID of a tenant data request and checks that the current user may
+// export or purge other tenants. Returns false after reporting the error.
+func (h *TenantHandler) tenantDataTarget(c *gin.Context) (uint64, bool) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Errorf(ctx, "Invalid tenant ID: %s", secutils.SanitizeForLog(c.Param("id")))
+		c.Error(errors.NewBadRequestError("Invalid tenant ID"))
+		return 0, false
+	}
+	if appErr := h.checkCrossTenantAccess(ctx); appErr != nil {
+		c.Error(appErr)
+		return 0, false
+	}
+	return id, true
+}
+
+// ExportTenantData godoc
+// @Summary      导出租户数据
+// @Description  异步导出租户的全部数据（知识库、文件、分块、FAQ、会话、消息、智能体、模型配置），密钥已脱敏（需要跨租户访问权限）
+// @Tags         租户管理
+// @Produce      json
+// @Param        id   path      int                     true  "租户ID"
+// @Success      200  {object}  map[string]interface{}  "导出任务进度"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Failure      404  {object}  errors.AppError         "租户不存在"
+// @Security     Bearer
+// @Router       /tenants/{id}/export [post]
+func (h *TenantHandler) ExportTenantData(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := h.tenantDataTarget(c)
+	if !ok {
+		return
+	}
+
+	logger.Infof(ctx, "Exporting tenant data, ID: %d", id)
+	progress, err := h.dataService.ExportTenantData(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// GetTenantExportProgress godoc
+// @Summary      获取租户导出进度
+// @Description  获取租户导出任务的进度，完成后包含清单（各部分的路径、大小、SHA-256 和记录数）
+// @Tags         租户管理
+// @Produce      json
+// @Param        id       path      int                     true  "租户ID"
+// @Param        task_id  path      string                  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "导出任务进度"
+// @Failure      403      {object}  errors.AppError         "权限不足"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Router       /tenants/{id}/export/{task_id} [get]
+func (h *TenantHandler) GetTenantExportProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := h.tenantDataTarget(c)
+	if !ok {
+		return
+	}
+
+	progress, err := h.dataService.GetTenantExportProgress(ctx, id, secutils.SanitizeForLog(c.Param("task_id")))
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// ResumeTenantExport godoc
+// @Summary      继续租户导出
+// @Description  重新执行失败或中断的租户导出任务，已完成的部分不会重新导出
+// @Tags         租户管理
+// @Produce      json
+// @Param        id       path      int                     true  "租户ID"
+// @Param        task_id  path      string                  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "导出任务进度"
+// @Failure      400      {object}  errors.AppError         "任务已完成"
+// @Failure      409      {object}  errors.AppError         "任务仍在执行"
+// @Security     Bearer
+// @Router       /tenants/{id}/export/{task_id}/resume [post]
+func (h *TenantHandler) ResumeTenantExport(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := h.tenantDataTarget(c)
+	if !ok {
+		return
+	}
+
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+	logger.Infof(ctx, "Resuming tenant export, ID: %d, task: %s", id, taskID)
+	progress, err := h.dataService.ResumeTenantExport(ctx, id, taskID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// DownloadTenantExportPart godoc
+// @Summary      下载租户导出部分
+// @Description  以流式下载已完成的导出部分（tar.gz），支持 Range: bytes=N- 断点续传
+// @Tags         租户管理
+// @Produce      application/gzip
+// @Param        id       path      int     true   "租户ID"
+// @Param        task_id  path      string  true   "任务ID"
+// @Param        part     path      string  true   "部分名称"
+// @Param        Range    header    string  false  "断点续传的起始位置，如 bytes=1048576-"
+// @Success      200      {file}    file    "导出部分"
+// @Success      206      {file}    file    "导出部分的剩余内容"
+// @Failure      404      {object}  errors.AppError  "部分不存在"
+// @Failure      416      {object}  errors.AppError  "范围无效"
+// @Security     Bearer
+// @Router       /tenants/{id}/export/{task_id}/parts/{part} [get]
+func (h *TenantHandler) DownloadTenantExportPart(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := h.tenantDataTarget(c)
+	if !ok {
+		return
+	}
+
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+	name := secutils.SanitizeForLog(c.Param("part"))
+	part, reader, err := h.dataService.GetTenantExportPart(ctx, id, taskID, name)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+	defer reader.Close()
+
+	start, ok := parseRangeStart(c.GetHeader("Range"), part.Size)
+	if !ok {
+		c.Header("Content-Range", fmt.Sprintf("bytes */%d", part.Size))
+		c.Error(&errors.AppError{
+			Code:     errors.ErrBadRequest,
+			Message:  "Requested range not satisfiable",
+			HTTPCode: http.StatusRequestedRangeNotSatisfiable,
+		})
+		return
+	}
+	status := http.StatusOK
+	if start > 0 {
+		// Storage backends only stream from the beginning, so the downloaded bytes are skipped
+		if _, err := io.CopyN(io.Discard, reader, start); err != nil {
+			logger.Errorf(ctx, "Failed to skip to offset %d of export part %s: %v", start, name, err)
+			c.Error(errors.NewInternalServerError("Failed to read export part").WithDetails(err.Error()))
+			return
+		}
+		status = http.StatusPartialContent
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, part.Size-1, part.Size))
+	}
+
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_%s.tar.gz", taskID, name))
+	c.Header("X-Checksum-SHA256", part.SHA256)
+	c.DataFromReader(status, part.Size-start, "application/gzip", reader, nil)
+}
+
+// parseRangeStart returns the offset of a "bytes=N-" range header, or 0 without one. Other range forms are
+// ignored and answered with the whole content. Returns false for offsets beyond the content.
+func parseRangeStart(header string, size int64) (int64, bool) {
+	spec, found := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !found {
+		return 0, true
+	}
+	startText, endText, found := strings.Cut(spec, "-")
+	if !found || endText != "" || strings.Contains(spec, ",") {
+		return 0, true
+	}
+	start, err := strconv.ParseInt(startText, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, false
+	}
+	return start, true
+}
+
+// ConfirmTenantPurge godoc
+// @Summary      获取租户删除确认令牌
+// @Description  签发删除或清除租户所需的确认令牌，有效期 10 分钟（需要跨租户访问权限）
+// @Tags         租户管理
+// @Produce      json
+// @Param        id   path      int                     true  "租户ID"
+// @Success      200  {object}  map[string]interface{}  "确认令牌和过期时间"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Failure      404  {object}  errors.AppError         "租户不存在"
+// @Security     Bearer
+// @Router       /tenants/{id}/purge/confirmation [post]
+func (h *TenantHandler) ConfirmTenantPurge(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := h.tenantDataTarget(c)
+	if !ok {
+		return
+	}
+
+	confirmation, err := h.dataService.ConfirmTenantPurge(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    confirmation,
+	})
+}
+
+// PurgeTenantData godoc
+// @Summary      清除租户数据
+// @Description  异步永久删除租户及其全部数据，包括知识库索引、存储的文件和导出文件（需要跨租户访问权限和确认令牌，不能清除当前租户）
+// @Tags         租户管理
+// @Produce      json
+// @Param        id                    path    int     true   "租户ID"
+// @Param        X-Confirmation-Token  header  string  false  "确认令牌"
+// @Param        confirmation_token    query   string  false  "确认令牌"
+// @Success      200  {object}  map[string]interface{}  "清除任务进度"
+// @Failure      400  {object}  errors.AppError         "请求参数错误或确认令牌无效"
+// @Failure      403  {object}  errors.AppError         "权限不足"
+// @Failure      404  {object}  errors.AppError         "租户不存在"
+// @Security     Bearer
+// @Router       /tenants/{id}/purge [post]
+func (h *TenantHandler) PurgeTenantData(c *gin.Context) {
+	h.purgeTenant(c)
+}
+
+// purgeTenant starts the purge of a tenant for DeleteTenant and PurgeTenantData
+func (h *TenantHandler) purgeTenant(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := h.tenantDataTarget(c)
+	if !ok {
+		return
+	}
+	if id == c.GetUint64(types.TenantIDContextKey.String()) {
+		c.Error(errors.NewBadRequestError("Cannot purge the current tenant"))
+		return
+	}
+
+	token := c.GetHeader("X-Confirmation-Token")
+	if token == "" {
+		token = c.Query("confirmation_token")
+	}
+	logger.Infof(ctx, "Purging tenant data, ID: %d", id)
+	progress, err := h.dataService.PurgeTenantData(ctx, id, token)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// RetryTenantPurge godoc
+// @Summary      重试租户清除
+// @Description  重新执行失败或中断的租户清除任务，已清除的知识库会跳过；force 为 true 时即使仍有数据无法删除也会删除记录，失败项保留在报告中
+// @Tags         租户管理
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                     true   "租户ID"
+// @Param        task_id  path      string                  true   "任务ID"
+// @Param        request  body      object                  false  "重试选项"
+// @Success      200      {object}  map[string]interface{}  "清除任务进度"
+// @Failure      400      {object}  errors.AppError         "任务已完成"
+// @Failure      409      {object}  errors.AppError         "任务仍在执行"
+// @Security     Bearer
+// @Router       /tenants/{id}/purge/{task_id}/retry [post]
+func (h *TenantHandler) RetryTenantPurge(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := h.tenantDataTarget(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Force bool `json:"force"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(errors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
+			return
+		}
+	}
+
+	taskID := secutils.SanitizeForLog(c.Param("task_id"))
+	logger.Infof(ctx, "Retrying tenant purge, ID: %d, task: %s, force: %v", id, taskID, req.Force)
+	progress, err := h.dataService.RetryTenantPurge(ctx, id, taskID, req.Force)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
+
+// GetTenantPurgeProgress godoc
+// @Summary      获取租户清除进度
+// @Description  获取租户清除任务的进度，结束后即为清除报告（各类已删除数据、失败项和无法删除的文件）
+// @Tags         租户管理
+// @Produce      json
+// @Param        id       path      int                     true  "租户ID"
+// @Param        task_id  path      string                  true  "任务ID"
+// @Success      200      {object}  map[string]interface{}  "清除任务进度"
+// @Failure      403      {object}  errors.AppError         "权限不足"
+// @Failure      404      {object}  errors.AppError         "任务不存在"
+// @Security     Bearer
+// @Router       /tenants/{id}/purge/{task_id} [get]
+func (h *TenantHandler) GetTenantPurgeProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := h.tenantDataTarget(c)
+	if !ok {
+		return
+	}
+
+	progress, err := h.dataService.GetTenantPurgeProgress(ctx, id, secutils.SanitizeForLog(c.Param("task_id")))
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    progress,
+	})
+}
@@ -0,0 +1,29 @@
+package handler
+
+import "testing"
+
+func TestParseRangeStart(t *testing.T) {
+	tests := []struct {
+		header    string
+		wantStart int64
+		wantOK    bool
+	}{
+		{"", 0, true},
+		{"bytes=0-", 0, true},
+		{"bytes=512-", 512, true},
+		{" bytes=999- ", 999, true},
+		{"bytes=1000-", 0, false},
+		{"bytes=abc-", 0, false},
+		// Closed, suffix and multiple ranges are answered with the whole content
+		{"bytes=-100", 0, true},
+		{"bytes=0-99", 0, true},
+		{"bytes=0-,100-", 0, true},
+		{"items=5-", 0, true},
+	}
+	for _, tt := range tests {
+		start, ok := parseRangeStart(tt.header, 1000)
+		if start != tt.wantStart || ok != tt.wantOK {
+			t.Errorf("parseRangeStart(%q) = %d, %v, want %d, %v", tt.header, start, ok, tt.wantStart, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// databaseChecker pings the primary database
+type databaseChecker struct {
+	db *gorm.DB
+}
+
+// NewDatabaseChecker creates a checker for the primary database connection
+func NewDatabaseChecker(db *gorm.DB) interfaces.HealthChecker {
+	return &databaseChecker{db: db}
+}
+
+// Name returns the subsystem name
+func (c *databaseChecker) Name() string {
+	return "database"
+}
+
+// Check pings the database
+func (c *databaseChecker) Check(ctx context.Context) error {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	return sqlDB.PingContext(ctx)
+}
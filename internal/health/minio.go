@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.uber.org/dig"
+
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// minioChecker checks that the MinIO bucket of the file service is reachable
+type minioChecker struct {
+	client *minio.Client
+	bucket string
+}
+
+// StorageCheckers are the checkers of the object storage, provided to the checker group one by one
+type StorageCheckers struct {
+	dig.Out
+
+	Checkers []interfaces.HealthChecker `group:"health_checkers,flatten"`
+}
+
+// NewStorageCheckers creates the checkers of the object storage configured for files.
+// MinIO is checked when it is the storage type; other storage types are not checked.
+func NewStorageCheckers() (StorageCheckers, error) {
+	if os.Getenv("STORAGE_TYPE") != "minio" {
+		return StorageCheckers{}, nil
+	}
+	client, err := minio.New(os.Getenv("MINIO_ENDPOINT"), &minio.Options{
+		Creds: credentials.NewStaticV4(
+			os.Getenv("MINIO_ACCESS_KEY_ID"), os.Getenv("MINIO_SECRET_ACCESS_KEY"), "",
+		),
+		Secure: strings.EqualFold(os.Getenv("MINIO_USE_SSL"), "true"),
+	})
+	if err != nil {
+		return StorageCheckers{}, fmt.Errorf("failed to create MinIO client: %w", err)
+	}
+	return StorageCheckers{Checkers: []interfaces.HealthChecker{
+		&minioChecker{client: client, bucket: os.Getenv("MINIO_BUCKET_NAME")},
+	}}, nil
+}
+
+// Name returns the subsystem name
+func (c *minioChecker) Name() string {
+	return "minio"
+}
+
+// Check checks that the bucket exists
+func (c *minioChecker) Check(ctx context.Context) error {
+	exists, err := c.client.BucketExists(ctx, c.bucket)
+	if err != nil {
+		return fmt.Errorf("failed to reach MinIO: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", c.bucket)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/utils/ollama"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// modelProviderCheckLimit bounds the embedding models whose providers are tried
+const modelProviderCheckLimit = 20
+
+// modelProviderChecker checks that at least one provider serving the embedding models is reachable
+type modelProviderChecker struct {
+	repo   interfaces.ModelRepository
+	ollama *ollama.OllamaService
+	client *http.Client
+}
+
+// NewModelProviderChecker creates a checker for the providers of the configured embedding models
+func NewModelProviderChecker(
+	repo interfaces.ModelRepository, ollamaService *ollama.OllamaService,
+) interfaces.HealthChecker {
+	return &modelProviderChecker{repo: repo, ollama: ollamaService, client: &http.Client{}}
+}
+
+// Name returns the subsystem name
+func (c *modelProviderChecker) Name() string {
+	return "model_provider"
+}
+
+// Check tries the providers of the most recently updated embedding models until one answers.
+// Provider addresses are configured by tenants, so failures are only detailed in the logs.
+func (c *modelProviderChecker) Check(ctx context.Context) error {
+	models, err := c.repo.ListActiveByType(ctx, types.ModelTypeEmbedding, modelProviderCheckLimit)
+	if err != nil {
+		return fmt.Errorf("failed to list embedding models: %w", err)
+	}
+	// Nothing to check before the first embedding model is configured
+	if len(models) == 0 {
+		return nil
+	}
+
+	checked := make(map[string]bool)
+	for _, model := range models {
+		endpoint := string(model.Source) + " " + model.Parameters.BaseURL
+		if checked[endpoint] {
+			continue
+		}
+		checked[endpoint] = true
+		err := c.checkProvider(ctx, model)
+		if err == nil {
+			return nil
+		}
+		logger.Warnf(ctx, "[Health] Embedding model provider unreachable, model: %s, source: %s, error: %v",
+			model.ID, model.Source, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return fmt.Errorf("none of the %d embedding model providers checked is reachable", len(checked))
+}
+
+// checkProvider checks the provider of a model: Ollama for local models, the models endpoint of the
+// OpenAI-compatible API otherwise
+func (c *modelProviderChecker) checkProvider(ctx context.Context, model *types.Model) error {
+	if model.Source == types.ModelSourceLocal {
+		if c.ollama == nil {
+			return fmt.Errorf("ollama is not configured")
+		}
+		version, err := c.ollama.GetVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if version == "unavailable" {
+			return fmt.Errorf("ollama is unavailable")
+		}
+		return nil
+	}
+
+	baseURL := strings.TrimRight(model.Parameters.BaseURL, "/")
+	if baseURL == "" {
+		return fmt.Errorf("no base URL")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	if model.Parameters.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+model.Parameters.APIKey)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	// Any answer shows the provider is up, unless it rejects the key or fails
+	if resp.StatusCode >= http.StatusInternalServerError ||
+		resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("provider returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
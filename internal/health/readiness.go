@@ -0,0 +1,77 @@
+// Package health checks the dependencies the service needs to serve traffic, for the readiness probe.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// CheckerGroup is the container group health checkers are provided in
+const CheckerGroup = "health_checkers"
+
+// Run runs the checkers in parallel, each bounded by timeout, and reports the degraded subsystems.
+// A checker that ignores its context is reported as timed out without delaying the report.
+func Run(ctx context.Context, checkers []interfaces.HealthChecker, timeout time.Duration) *types.ReadinessReport {
+	results := make([]types.HealthCheckResult, len(checkers))
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func(i int, checker interfaces.HealthChecker) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, checker, timeout)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	report := &types.ReadinessReport{Status: types.HealthStatusOK, Checks: results}
+	for _, result := range results {
+		if result.Status != types.HealthStatusOK {
+			report.Status = types.HealthStatusDegraded
+			report.Degraded = append(report.Degraded, result.Name)
+		}
+	}
+	return report
+}
+
+// runCheck runs a checker with a timeout
+func runCheck(ctx context.Context,
+	checker interfaces.HealthChecker, timeout time.Duration,
+) types.HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out after %s", timeout)
+	}
+	result := types.HealthCheckResult{
+		Name:      checker.Name(),
+		Status:    types.HealthStatusOK,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = types.HealthStatusDegraded
+		result.Error = err.Error()
+	}
+	return result
+}
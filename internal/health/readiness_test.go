@@ -0,0 +1,78 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeChecker waits for delay, or ignores its context when stuck, and returns err
+type fakeChecker struct {
+	name  string
+	delay time.Duration
+	stuck bool
+	err   error
+}
+
+func (c *fakeChecker) Name() string { return c.name }
+
+func (c *fakeChecker) Check(ctx context.Context) error {
+	if c.stuck {
+		time.Sleep(time.Second)
+		return nil
+	}
+	select {
+	case <-time.After(c.delay):
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestRun(t *testing.T) {
+	checkers := []interfaces.HealthChecker{
+		&fakeChecker{name: "minio", delay: 50 * time.Millisecond},
+		&fakeChecker{name: "database", delay: 50 * time.Millisecond},
+		&fakeChecker{name: "model_provider", delay: 50 * time.Millisecond},
+	}
+	start := time.Now()
+	report := Run(context.Background(), checkers, time.Second)
+	if elapsed := time.Since(start); elapsed > 140*time.Millisecond {
+		t.Errorf("checks took %v, want them to run in parallel", elapsed)
+	}
+	if report.Status != types.HealthStatusOK || len(report.Degraded) != 0 {
+		t.Fatalf("report = %+v, want ok", report)
+	}
+	names := []string{report.Checks[0].Name, report.Checks[1].Name, report.Checks[2].Name}
+	if !slices.Equal(names, []string{"database", "minio", "model_provider"}) {
+		t.Errorf("checks = %v, want them sorted by name", names)
+	}
+}
+
+func TestRunDegraded(t *testing.T) {
+	checkers := []interfaces.HealthChecker{
+		&fakeChecker{name: "database"},
+		&fakeChecker{name: "minio", err: errors.New("connection refused")},
+		&fakeChecker{name: "model_provider", delay: time.Second},
+		&fakeChecker{name: "stuck", stuck: true},
+	}
+	start := time.Now()
+	report := Run(context.Background(), checkers, 50*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("checks took %v, want them bounded by the timeout", elapsed)
+	}
+	if report.Status != types.HealthStatusDegraded {
+		t.Errorf("status = %s, want degraded", report.Status)
+	}
+	if want := []string{"minio", "model_provider", "stuck"}; !slices.Equal(report.Degraded, want) {
+		t.Errorf("degraded = %v, want %v", report.Degraded, want)
+	}
+	if report.Checks[1].Error != "connection refused" {
+		t.Errorf("minio error = %q", report.Checks[1].Error)
+	}
+}
@@ -0,0 +1,148 @@
+// Package i18n renders user-facing messages (errors, chat fallbacks, system notices) in the
+// language requested by the client. Messages are looked up by key in JSON catalogs under
+// locales/, one file per language named after its tag (en.json, zh.json); adding a catalog
+// file is all it takes to support a new language.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// DefaultLanguage is used when the client does not ask for a supported language,
+// and as the fallback for keys missing from another catalog
+const DefaultLanguage = "en"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// catalogs maps a language tag to its messages
+var catalogs = mustLoadCatalogs()
+
+func mustLoadCatalogs() map[string]map[string]string {
+	files, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: read locales: %v", err))
+	}
+	result := make(map[string]map[string]string, len(files))
+	for _, file := range files {
+		data, err := localeFS.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: read %s: %v", file.Name(), err))
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: parse %s: %v", file.Name(), err))
+		}
+		result[strings.ToLower(strings.TrimSuffix(file.Name(), path.Ext(file.Name())))] = messages
+	}
+	return result
+}
+
+// Languages returns the tags of all available catalogs, sorted
+func Languages() []string {
+	languages := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Match returns the supported language closest to tag: an exact match (zh-tw), then the
+// base language (zh), otherwise an empty string
+func Match(tag string) string {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if tag == "" {
+		return ""
+	}
+	if _, ok := catalogs[tag]; ok {
+		return tag
+	}
+	if base, _, found := strings.Cut(tag, "-"); found {
+		if _, ok := catalogs[base]; ok {
+			return base
+		}
+	}
+	return ""
+}
+
+// ParseAcceptLanguage returns the supported language the client prefers most according to an
+// Accept-Language header, or DefaultLanguage when none of the requested languages is supported
+func ParseAcceptLanguage(header string) string {
+	best, bestQ := DefaultLanguage, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if lang := Match(tag); lang != "" && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// WithLanguage returns a context carrying the language of user-facing messages
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, types.LanguageContextKey, lang)
+}
+
+// LanguageFromContext returns the language stored in ctx, or DefaultLanguage
+func LanguageFromContext(ctx context.Context) string {
+	if lang, ok := ctx.Value(types.LanguageContextKey).(string); ok && lang != "" {
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// Lookup returns the message for key in lang, falling back to DefaultLanguage
+func Lookup(lang, key string) (string, bool) {
+	if message, ok := catalogs[lang][key]; ok {
+		return message, true
+	}
+	message, ok := catalogs[DefaultLanguage][key]
+	return message, ok
+}
+
+// T renders the message for key in lang, formatting args with fmt verbs in the message.
+// The key itself is returned when no catalog defines it.
+func T(lang, key string, args ...any) string {
+	message, ok := Lookup(lang, key)
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Localize renders the message for key in the language of ctx
+func Localize(ctx context.Context, key string, args ...any) string {
+	return T(LanguageFromContext(ctx), key, args...)
+}
+
+// LocalizeDefault renders the message for key in the language of ctx when value is empty or
+// still the built-in default text; values customized by operators are returned unchanged
+func LocalizeDefault(ctx context.Context, key, value string) string {
+	if value != "" {
+		if defaultText, ok := catalogs[DefaultLanguage][key]; !ok || value != defaultText {
+			return value
+		}
+	}
+	return Localize(ctx, key)
+}
@@ -0,0 +1,52 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":                        DefaultLanguage,
+		"zh-CN,zh;q=0.9,en;q=0.8": "zh",
+		"en-US,en;q=0.9,zh;q=0.8": "en",
+		"fr-FR,fr;q=0.9,zh;q=0.5": "zh",
+		"fr,de":                   DefaultLanguage,
+		"en;q=0.3, zh_TW;q=0.7":   "zh",
+		"zh;q=invalid, en":        "en",
+		"*":                       DefaultLanguage,
+	}
+	for header, want := range cases {
+		if got := ParseAcceptLanguage(header); got != want {
+			t.Errorf("ParseAcceptLanguage(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCatalogsDefineSameKeys(t *testing.T) {
+	for lang, messages := range catalogs {
+		for key := range messages {
+			if _, ok := catalogs[DefaultLanguage][key]; !ok {
+				t.Errorf("key %q in %s is missing from the default catalog", key, lang)
+			}
+		}
+	}
+}
+
+func TestLocalizeDefault(t *testing.T) {
+	zh := WithLanguage(context.Background(), "zh")
+	defaultText := T(DefaultLanguage, "chat.fallback_response")
+
+	if got := LocalizeDefault(zh, "chat.fallback_response", defaultText); got != T("zh", "chat.fallback_response") {
+		t.Errorf("built-in default not localized: %q", got)
+	}
+	if got := LocalizeDefault(zh, "chat.fallback_response", ""); got != T("zh", "chat.fallback_response") {
+		t.Errorf("empty value not localized: %q", got)
+	}
+	if got := LocalizeDefault(zh, "chat.fallback_response", "custom"); got != "custom" {
+		t.Errorf("customized value changed: %q", got)
+	}
+	if got := Localize(context.Background(), "missing.key"); got != "missing.key" {
+		t.Errorf("missing key = %q", got)
+	}
+}
@@ -270,6 +270,7 @@ func CloneContext(ctx context.Context) context.Context {
 		types.TenantIDContextKey,
 		types.RequestIDContextKey,
 		types.TenantInfoContextKey,
+		types.StageTimingsContextKey,
 	} {
 		if v := ctx.Value(k); v != nil {
 			newCtx = context.WithValue(newCtx, k, v)
@@ -29,4 +29,7 @@ var (
 
 	// ErrConnectionClosed is returned when connection is closed unexpectedly
 	ErrConnectionClosed = errors.New("connection closed")
+
+	// ErrCircuitOpen is returned when the circuit breaker of a service rejects a call after repeated failures
+	ErrCircuitOpen = errors.New("service temporarily unavailable: circuit breaker is open")
 )
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Tencent/WeKnora/internal/clock"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
 )
@@ -16,16 +17,25 @@ type MCPManager struct {
 	clientsMu sync.RWMutex
 	ctx       context.Context
 	cancel    context.CancelFunc
+	clock     clock.Clock
+
+	breakers   map[string]*circuitBreaker // serviceID -> circuit breaker
+	breakersMu sync.Mutex
+	// sleep waits between retries; replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error
 }
 
 // NewMCPManager creates a new MCP manager
-func NewMCPManager() *MCPManager {
+func NewMCPManager(clk clock.Clock) *MCPManager {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	manager := &MCPManager{
-		clients: make(map[string]MCPClient),
-		ctx:     ctx,
-		cancel:  cancel,
+		clients:  make(map[string]MCPClient),
+		ctx:      ctx,
+		cancel:   cancel,
+		clock:    clk,
+		breakers: make(map[string]*circuitBreaker),
+		sleep:    sleepContext,
 	}
 
 	// Start cleanup goroutine
@@ -38,14 +48,8 @@ func NewMCPManager() *MCPManager {
 // Caches and reuses existing connections for SSE/HTTP Streamable
 // Note: Stdio transport is disabled for security reasons
 func (m *MCPManager) GetOrCreateClient(service *types.MCPService) (MCPClient, error) {
-	// Check if service is enabled
-	if !service.Enabled {
-		return nil, fmt.Errorf("MCP service %s is not enabled", service.Name)
-	}
-
-	// Stdio transport is disabled for security reasons
-	if service.TransportType == types.MCPTransportStdio {
-		return nil, fmt.Errorf("stdio transport is disabled for security reasons; please use SSE or HTTP Streamable transport instead")
+	if err := checkServiceCallable(service); err != nil {
+		return nil, err
 	}
 
 	// For SSE/HTTP Streamable, check if client already exists and reuse
@@ -95,6 +99,18 @@ func (m *MCPManager) GetOrCreateClient(service *types.MCPService) (MCPClient, er
 	return client, nil
 }
 
+// checkServiceCallable rejects services that must not be called: disabled ones and those using the
+// stdio transport, which is disabled for security reasons
+func checkServiceCallable(service *types.MCPService) error {
+	if !service.Enabled {
+		return fmt.Errorf("MCP service %s is not enabled", service.Name)
+	}
+	if service.TransportType == types.MCPTransportStdio {
+		return fmt.Errorf("stdio transport is disabled for security reasons; please use SSE or HTTP Streamable transport instead")
+	}
+	return nil
+}
+
 // initializeClient handles the shared initialization flow with timeout enforcement.
 func (m *MCPManager) initializeClient(service *types.MCPService, client MCPClient, errPrefix string) error {
 	initTimeout := 30 * time.Second
@@ -170,14 +186,14 @@ func (m *MCPManager) Shutdown() {
 
 // cleanupIdleConnections periodically cleans up disconnected clients
 func (m *MCPManager) cleanupIdleConnections() {
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := m.clock.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-m.ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			m.removeDisconnectedClients()
 		}
 	}
@@ -0,0 +1,266 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// Resilience defaults for services whose advanced configuration leaves a parameter unset
+const (
+	defaultCallTimeout      = 30 * time.Second
+	defaultRetryDelay       = time.Second
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+	// maxBackoff caps the exponential backoff between retries
+	maxBackoff = 30 * time.Second
+)
+
+// resiliencePolicy holds the retry and circuit breaker parameters of an MCP service
+type resiliencePolicy struct {
+	timeout    time.Duration
+	retries    int
+	retryDelay time.Duration
+	threshold  int
+	cooldown   time.Duration
+}
+
+// newResiliencePolicy reads the parameters from the advanced configuration of the service
+func newResiliencePolicy(service *types.MCPService) resiliencePolicy {
+	cfg := service.AdvancedConfig
+	if cfg == nil {
+		cfg = types.GetDefaultAdvancedConfig()
+	}
+	policy := resiliencePolicy{
+		timeout:    defaultCallTimeout,
+		retries:    max(cfg.RetryCount, 0),
+		retryDelay: defaultRetryDelay,
+		threshold:  defaultBreakerThreshold,
+		cooldown:   defaultBreakerCooldown,
+	}
+	if cfg.Timeout > 0 {
+		policy.timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	if cfg.RetryDelay > 0 {
+		policy.retryDelay = time.Duration(cfg.RetryDelay) * time.Second
+	}
+	if cfg.BreakerThreshold > 0 {
+		policy.threshold = cfg.BreakerThreshold
+	}
+	if cfg.BreakerCooldown > 0 {
+		policy.cooldown = time.Duration(cfg.BreakerCooldown) * time.Second
+	}
+	return policy
+}
+
+// backoff returns the delay before the given retry, counted from 0: the retry delay doubled for each
+// previous retry, capped, with up to half of it random so that callers do not retry in lockstep
+func (p resiliencePolicy) backoff(retry int) time.Duration {
+	delay := maxBackoff
+	if retry < 16 {
+		delay = min(p.retryDelay<<retry, maxBackoff)
+	}
+	half := delay / 2
+	return delay - half + rand.N(half+1)
+}
+
+// circuitBreaker stops calls to a failing service: it opens after threshold consecutive failed attempts,
+// rejects calls during the cooldown, then lets a single trial call through, whose outcome closes the
+// breaker or opens it again
+type circuitBreaker struct {
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	// trial is set while the trial call of a half-open breaker is in flight
+	trial bool
+}
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{state: types.MCPBreakerClosed}
+}
+
+// allow reports whether a call may proceed, and whether it is the trial call of a half-open breaker
+func (b *circuitBreaker) allow(now time.Time, cooldown time.Duration) (allowed bool, trial bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case types.MCPBreakerOpen:
+		if now.Sub(b.openedAt) < cooldown {
+			return false, false
+		}
+		b.state = types.MCPBreakerHalfOpen
+		fallthrough
+	case types.MCPBreakerHalfOpen:
+		if b.trial {
+			return false, false
+		}
+		b.trial = true
+		return true, true
+	}
+	return true, false
+}
+
+// record records the outcome of an attempt and reports whether a failure opened the breaker
+func (b *circuitBreaker) record(now time.Time, threshold int, failed bool) (opened bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if !failed {
+		b.state = types.MCPBreakerClosed
+		b.failures = 0
+		return false
+	}
+	b.failures++
+	if b.state == types.MCPBreakerHalfOpen || (b.state == types.MCPBreakerClosed && b.failures >= threshold) {
+		b.state = types.MCPBreakerOpen
+		b.openedAt = now
+		return true
+	}
+	return false
+}
+
+// release ends an attempt abandoned by its caller, which says nothing about the service
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// currentState returns the state of the breaker; an open breaker whose cooldown elapsed is half-open,
+// as the next call is a trial
+func (b *circuitBreaker) currentState(now time.Time, cooldown time.Duration) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == types.MCPBreakerOpen && now.Sub(b.openedAt) >= cooldown {
+		return types.MCPBreakerHalfOpen
+	}
+	return b.state
+}
+
+// CallFunc is an operation on the client of an MCP service
+type CallFunc func(ctx context.Context, client MCPClient) error
+
+// Call runs an idempotent operation, such as listing tools, on the client of a service. Each attempt runs
+// within the service timeout, and failed attempts are retried with exponential backoff and jitter. Failed
+// attempts count towards the circuit breaker of the service: while it is open, calls fail fast with
+// ErrCircuitOpen
+func (m *MCPManager) Call(ctx context.Context, service *types.MCPService, op CallFunc) error {
+	return m.call(ctx, service, op, true)
+}
+
+// CallOnce runs an operation that must not be repeated, such as a tool call, through the circuit breaker of
+// the service without retrying it
+func (m *MCPManager) CallOnce(ctx context.Context, service *types.MCPService, op CallFunc) error {
+	return m.call(ctx, service, op, false)
+}
+
+func (m *MCPManager) call(ctx context.Context, service *types.MCPService, op CallFunc, retry bool) error {
+	// Configuration errors say nothing about the health of the service
+	if err := checkServiceCallable(service); err != nil {
+		return err
+	}
+
+	policy := newResiliencePolicy(service)
+	breaker := m.breaker(service.ID)
+	for attempt := 0; ; attempt++ {
+		allowed, trial := breaker.allow(m.clock.Now(), policy.cooldown)
+		if !allowed {
+			return fmt.Errorf("MCP service %s: %w", service.Name, ErrCircuitOpen)
+		}
+
+		err := m.attempt(ctx, service, policy.timeout, op)
+		if err == nil {
+			breaker.record(m.clock.Now(), policy.threshold, false)
+			return nil
+		}
+		// A caller giving up says nothing about the service, unlike a caller deadline running out
+		if errors.Is(ctx.Err(), context.Canceled) {
+			breaker.release()
+			return err
+		}
+		if breaker.record(m.clock.Now(), policy.threshold, true) {
+			logger.Warnf(ctx, "Circuit breaker of MCP service %s opened for %v: %v", service.Name, policy.cooldown, err)
+		}
+		if !retry || trial || attempt >= policy.retries || ctx.Err() != nil {
+			return err
+		}
+
+		delay := policy.backoff(attempt)
+		logger.Warnf(ctx, "MCP service %s call failed (attempt %d/%d), retrying in %v: %v",
+			service.Name, attempt+1, policy.retries+1, delay, err)
+		if err := m.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// attempt runs op once within timeout; on failure the client is closed so that the next attempt reconnects
+func (m *MCPManager) attempt(ctx context.Context,
+	service *types.MCPService, timeout time.Duration, op CallFunc,
+) error {
+	client, err := m.GetOrCreateClient(service)
+	if err != nil {
+		return err
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := op(attemptCtx, client); err != nil {
+		m.CloseClient(service.ID)
+		return err
+	}
+	return nil
+}
+
+// BreakerState returns the circuit breaker state of a service
+func (m *MCPManager) BreakerState(service *types.MCPService) string {
+	m.breakersMu.Lock()
+	breaker, exists := m.breakers[service.ID]
+	m.breakersMu.Unlock()
+	if !exists {
+		return types.MCPBreakerClosed
+	}
+	return breaker.currentState(m.clock.Now(), newResiliencePolicy(service).cooldown)
+}
+
+// ResetBreaker forgets the failures of a service, whose configuration changed or which was deleted
+func (m *MCPManager) ResetBreaker(serviceID string) {
+	m.breakersMu.Lock()
+	defer m.breakersMu.Unlock()
+	delete(m.breakers, serviceID)
+}
+
+// breaker returns the circuit breaker of a service, creating it on first use
+func (m *MCPManager) breaker(serviceID string) *circuitBreaker {
+	m.breakersMu.Lock()
+	defer m.breakersMu.Unlock()
+
+	breaker, exists := m.breakers[serviceID]
+	if !exists {
+		breaker = newCircuitBreaker()
+		m.breakers[serviceID] = breaker
+	}
+	return breaker
+}
+
+// sleepContext waits for d, or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
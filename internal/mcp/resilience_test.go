@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const threshold = 3
+	const cooldown = 30 * time.Second
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := newCircuitBreaker()
+
+	// A success resets the count of consecutive failures
+	for i := 0; i < threshold-1; i++ {
+		b.record(now, threshold, true)
+	}
+	b.record(now, threshold, false)
+	for i := 0; i < threshold-1; i++ {
+		if b.record(now, threshold, true) {
+			t.Fatalf("breaker opened after %d failures", i+1)
+		}
+	}
+	if !b.record(now, threshold, true) {
+		t.Fatalf("breaker did not open after %d consecutive failures", threshold)
+	}
+
+	if allowed, _ := b.allow(now.Add(cooldown-time.Second), cooldown); allowed {
+		t.Error("open breaker let a call through during the cooldown")
+	}
+	if got := b.currentState(now.Add(cooldown-time.Second), cooldown); got != types.MCPBreakerOpen {
+		t.Errorf("state during the cooldown = %s, want %s", got, types.MCPBreakerOpen)
+	}
+	if got := b.currentState(now.Add(cooldown), cooldown); got != types.MCPBreakerHalfOpen {
+		t.Errorf("state after the cooldown = %s, want %s", got, types.MCPBreakerHalfOpen)
+	}
+
+	// After the cooldown a single trial call goes through, and its failure opens the breaker again
+	now = now.Add(cooldown)
+	if allowed, trial := b.allow(now, cooldown); !allowed || !trial {
+		t.Fatalf("allow after the cooldown = %v, %v, want a trial call", allowed, trial)
+	}
+	if allowed, _ := b.allow(now, cooldown); allowed {
+		t.Error("half-open breaker let a second call through during the trial")
+	}
+	if !b.record(now, threshold, true) {
+		t.Fatal("failed trial call did not open the breaker again")
+	}
+
+	// A trial call abandoned by its caller leaves the breaker half-open, a successful one closes it
+	now = now.Add(cooldown)
+	b.allow(now, cooldown)
+	b.release()
+	if allowed, trial := b.allow(now, cooldown); !allowed || !trial {
+		t.Fatalf("allow after an abandoned trial = %v, %v, want a trial call", allowed, trial)
+	}
+	b.record(now, threshold, false)
+	if got := b.currentState(now, cooldown); got != types.MCPBreakerClosed {
+		t.Errorf("state after a successful trial = %s, want %s", got, types.MCPBreakerClosed)
+	}
+	if allowed, trial := b.allow(now, cooldown); !allowed || trial {
+		t.Errorf("allow on a closed breaker = %v, %v, want a regular call", allowed, trial)
+	}
+}
+
+func TestResiliencePolicy(t *testing.T) {
+	policy := newResiliencePolicy(&types.MCPService{AdvancedConfig: &types.MCPAdvancedConfig{RetryCount: 2}})
+	want := resiliencePolicy{
+		timeout:    defaultCallTimeout,
+		retries:    2,
+		retryDelay: defaultRetryDelay,
+		threshold:  defaultBreakerThreshold,
+		cooldown:   defaultBreakerCooldown,
+	}
+	if policy != want {
+		t.Errorf("policy = %+v, want %+v", policy, want)
+	}
+
+	policy = resiliencePolicy{retryDelay: 2 * time.Second}
+	for retry, base := range []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, maxBackoff, maxBackoff} {
+		for i := 0; i < 20; i++ {
+			if got := policy.backoff(retry); got < base/2 || got > base {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", retry, got, base/2, base)
+			}
+		}
+	}
+	if got := policy.backoff(100); got < maxBackoff/2 || got > maxBackoff {
+		t.Errorf("backoff(100) = %v, want at most %v", got, maxBackoff)
+	}
+}
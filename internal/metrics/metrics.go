@@ -0,0 +1,98 @@
+// Package metrics defines the Prometheus metrics of the service, exposed on /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// namespace prefixes the names of the metrics
+const namespace = "weknora"
+
+// payloadSizeBuckets are the buckets of the payload size histograms, from 100B to 100MB
+var payloadSizeBuckets = prometheus.ExponentialBuckets(100, 10, 7)
+
+// Metrics are the collectors updated while serving requests. They are registered on the registry
+// provided by the container, which tests can replace to assert on the emitted metrics.
+type Metrics struct {
+	// Requests counts the handled requests by route template, method, tenant and status code
+	Requests *prometheus.CounterVec
+	// RequestDuration observes the latency of the handled requests, with the same labels
+	RequestDuration *prometheus.HistogramVec
+	// InFlight is the number of requests being handled by route template and method
+	InFlight *prometheus.GaugeVec
+	// ActiveStreams is the number of answer streams open on this node
+	ActiveStreams prometheus.Gauge
+	// RequestSize observes the request body sizes by tenant
+	RequestSize *prometheus.HistogramVec
+	// ResponseSize observes the response body sizes by tenant
+	ResponseSize *prometheus.HistogramVec
+	// PayloadRejections counts the payloads rejected over the tenant's size limit by tenant and direction
+	PayloadRejections *prometheus.CounterVec
+	// Payloads keeps the payload sizes of every tenant seen by this node, for the tenant usage endpoint
+	Payloads *PayloadUsage
+}
+
+// NewRegistry creates the registry of the service, with the Go runtime and process collectors
+func NewRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+}
+
+// New creates the metrics and registers them on the registry
+func New(registry *prometheus.Registry) *Metrics {
+	m := &Metrics{
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Handled HTTP requests by route template, method, tenant and status code.",
+		}, []string{"route", "method", "tenant", "status"}),
+		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of handled HTTP requests by route template, method, tenant and status code.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"route", "method", "tenant", "status"}),
+		InFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "HTTP requests being handled by route template and method.",
+		}, []string{"route", "method"}),
+		ActiveStreams: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active_streams",
+			Help:      "Answer streams (SSE) currently open on this node.",
+		}),
+		RequestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_size_bytes",
+			Help:      "Size of request bodies by tenant.",
+			Buckets:   payloadSizeBuckets,
+		}, []string{"tenant"}),
+		ResponseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "response_size_bytes",
+			Help:      "Size of response bodies by tenant.",
+			Buckets:   payloadSizeBuckets,
+		}, []string{"tenant"}),
+		PayloadRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "payload_rejections_total",
+			Help:      "Requests and responses rejected over the payload size limit of the tenant, by tenant and direction.",
+		}, []string{"tenant", "direction"}),
+		Payloads: NewPayloadUsage(),
+	}
+	registry.MustRegister(m.Requests, m.RequestDuration, m.InFlight, m.ActiveStreams,
+		m.RequestSize, m.ResponseSize, m.PayloadRejections)
+	return m
+}
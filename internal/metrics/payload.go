@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// PayloadStats are the payload sizes of the requests of a tenant handled by a node
+type PayloadStats struct {
+	// Requests is the number of requests handled
+	Requests int64 `json:"requests"`
+	// RequestBytes and ResponseBytes are the total sizes of the request and response bodies
+	RequestBytes  int64 `json:"request_bytes"`
+	ResponseBytes int64 `json:"response_bytes"`
+	// LargestRequestBytes and LargestResponseBytes are the sizes of the largest bodies
+	LargestRequestBytes  int64 `json:"largest_request_bytes"`
+	LargestResponseBytes int64 `json:"largest_response_bytes"`
+	// RejectedRequests and RejectedResponses count the bodies over the tenant's size limits
+	RejectedRequests  int64 `json:"rejected_requests"`
+	RejectedResponses int64 `json:"rejected_responses"`
+	// Since is when the node started counting
+	Since time.Time `json:"since"`
+}
+
+// PayloadUsage keeps the payload stats of every tenant in memory, since the node started
+type PayloadUsage struct {
+	mu      sync.Mutex
+	since   time.Time
+	tenants map[uint64]*PayloadStats
+}
+
+// NewPayloadUsage creates empty payload stats
+func NewPayloadUsage() *PayloadUsage {
+	return &PayloadUsage{since: time.Now(), tenants: make(map[uint64]*PayloadStats)}
+}
+
+// Observe adds a handled request of the tenant to its stats
+func (u *PayloadUsage) Observe(tenantID uint64, requestBytes, responseBytes int64,
+	requestRejected, responseRejected bool,
+) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	stats, ok := u.tenants[tenantID]
+	if !ok {
+		stats = &PayloadStats{Since: u.since}
+		u.tenants[tenantID] = stats
+	}
+	stats.Requests++
+	stats.RequestBytes += requestBytes
+	stats.ResponseBytes += responseBytes
+	stats.LargestRequestBytes = max(stats.LargestRequestBytes, requestBytes)
+	stats.LargestResponseBytes = max(stats.LargestResponseBytes, responseBytes)
+	if requestRejected {
+		stats.RejectedRequests++
+	}
+	if responseRejected {
+		stats.RejectedResponses++
+	}
+}
+
+// Stats returns a copy of the stats of the tenant
+func (u *PayloadUsage) Stats(tenantID uint64) PayloadStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if stats, ok := u.tenants[tenantID]; ok {
+		return *stats
+	}
+	return PayloadStats{Since: u.since}
+}
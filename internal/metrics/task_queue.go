@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// Queue depth is counted over at most taskQueueMaxPages pages of taskQueuePageSize tasks per state
+const (
+	taskQueuePageSize = 500
+	taskQueueMaxPages = 20
+)
+
+// TaskQueueTypes are the task types whose queue depth is reported
+var TaskQueueTypes = []string{types.TypeKBClone, types.TypeFAQImport}
+
+// TaskLister lists the tasks of the async queues; implemented by *asynq.Inspector
+type TaskLister interface {
+	Queues() ([]string, error)
+	ListPendingTasks(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error)
+	ListActiveTasks(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error)
+	ListRetryTasks(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error)
+}
+
+// taskQueueCollector reports the number of queued tasks of some types, by type and state. The queues
+// are read on every scrape, since they are shared by all nodes.
+type taskQueueCollector struct {
+	lister    TaskLister
+	taskTypes []string
+	depth     *prometheus.Desc
+}
+
+// RegisterTaskQueueCollector registers the queue depth of TaskQueueTypes on the registry
+func RegisterTaskQueueCollector(registry *prometheus.Registry, inspector *asynq.Inspector) {
+	registry.MustRegister(NewTaskQueueCollector(inspector, TaskQueueTypes))
+}
+
+// NewTaskQueueCollector creates a collector of the queue depth of the task types
+func NewTaskQueueCollector(lister TaskLister, taskTypes []string) prometheus.Collector {
+	return &taskQueueCollector{
+		lister:    lister,
+		taskTypes: taskTypes,
+		depth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "task_queue", "depth"),
+			"Async tasks waiting or running, by task type and state (pending, active, retry).",
+			[]string{"task_type", "state"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *taskQueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.depth
+}
+
+// Collect implements prometheus.Collector
+func (c *taskQueueCollector) Collect(ch chan<- prometheus.Metric) {
+	queues, err := c.lister.Queues()
+	if err != nil {
+		logger.Warnf(context.Background(), "[Metrics] Failed to list task queues: %v", err)
+		return
+	}
+	states := []struct {
+		name string
+		list func(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error)
+	}{
+		{"pending", c.lister.ListPendingTasks},
+		{"active", c.lister.ListActiveTasks},
+		{"retry", c.lister.ListRetryTasks},
+	}
+
+	for _, state := range states {
+		counts := make(map[string]int, len(c.taskTypes))
+		for _, queue := range queues {
+			if err := countTasks(queue, state.list, counts); err != nil {
+				logger.Warnf(context.Background(), "[Metrics] Failed to list %s tasks of queue %s: %v",
+					state.name, queue, err)
+				return
+			}
+		}
+		for _, taskType := range c.taskTypes {
+			ch <- prometheus.MustNewConstMetric(c.depth, prometheus.GaugeValue,
+				float64(counts[taskType]), taskType, state.name)
+		}
+	}
+}
+
+// countTasks adds the tasks of the queue to the counts by type
+func countTasks(queue string,
+	list func(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error), counts map[string]int,
+) error {
+	for page := 1; page <= taskQueueMaxPages; page++ {
+		tasks, err := list(queue, asynq.PageSize(taskQueuePageSize), asynq.Page(page))
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			counts[task.Type]++
+		}
+		if len(tasks) < taskQueuePageSize {
+			return nil
+		}
+	}
+	return nil
+}
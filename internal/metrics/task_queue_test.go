@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// fakeTaskLister serves the tasks of each queue by state
+type fakeTaskLister struct {
+	pending map[string][]string
+	active  map[string][]string
+}
+
+func (l *fakeTaskLister) Queues() ([]string, error) {
+	return []string{"critical", "default", "low"}, nil
+}
+
+func (l *fakeTaskLister) ListPendingTasks(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error) {
+	return taskInfos(l.pending[queue]), nil
+}
+
+func (l *fakeTaskLister) ListActiveTasks(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error) {
+	return taskInfos(l.active[queue]), nil
+}
+
+func (l *fakeTaskLister) ListRetryTasks(string, ...asynq.ListOption) ([]*asynq.TaskInfo, error) {
+	return nil, nil
+}
+
+func taskInfos(taskTypes []string) []*asynq.TaskInfo {
+	infos := make([]*asynq.TaskInfo, len(taskTypes))
+	for i, taskType := range taskTypes {
+		infos[i] = &asynq.TaskInfo{Type: taskType}
+	}
+	return infos
+}
+
+func TestTaskQueueCollector(t *testing.T) {
+	lister := &fakeTaskLister{
+		pending: map[string][]string{
+			"default": {types.TypeKBClone, types.TypeFAQImport, types.TypeFAQImport, types.TypeDocumentProcess},
+		},
+		active: map[string][]string{"default": {types.TypeFAQImport}},
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewTaskQueueCollector(lister, TaskQueueTypes))
+
+	expected := `
+# HELP weknora_task_queue_depth Async tasks waiting or running, by task type and state (pending, active, retry).
+# TYPE weknora_task_queue_depth gauge
+weknora_task_queue_depth{state="active",task_type="faq:import"} 1
+weknora_task_queue_depth{state="active",task_type="kb:clone"} 0
+weknora_task_queue_depth{state="pending",task_type="faq:import"} 2
+weknora_task_queue_depth{state="pending",task_type="kb:clone"} 1
+weknora_task_queue_depth{state="retry",task_type="faq:import"} 0
+weknora_task_queue_depth{state="retry",task_type="kb:clone"} 0
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,327 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+const (
+	// defaultAuditBufferSize is used when audit.buffer_size is not set
+	defaultAuditBufferSize = 10000
+	// defaultAuditBatchSize is used when audit.batch_size is not set
+	defaultAuditBatchSize = 100
+	// defaultAuditFlushInterval is used when audit.flush_interval is not set
+	defaultAuditFlushInterval = 2 * time.Second
+	// auditWriteTimeout bounds each write of a batch to the sink
+	auditWriteTimeout = 10 * time.Second
+	// maxAuditBodyBytes bounds the request bodies kept in the audit log; larger ones are not kept
+	maxAuditBodyBytes = 16 << 10
+	// auditRoutePrefix is the route prefix of the audited API
+	auditRoutePrefix = "/api/v1/"
+	// auditRedacted replaces the values of sensitive fields in the request bodies
+	auditRedacted = "***"
+)
+
+// AuditSink stores the entries of the audit log
+type AuditSink interface {
+	// CreateLogs stores a batch of entries
+	CreateLogs(ctx context.Context, logs []*types.AuditLog) error
+}
+
+// AuditRecorder queues the entries of the audit log and writes them to the sink in the background,
+// so that a slow sink never delays the responses. Entries are dropped when the queue is full.
+type AuditRecorder struct {
+	sink          AuditSink
+	batchSize     int
+	flushInterval time.Duration
+
+	queue     chan *types.AuditLog
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closed    atomic.Bool
+	dropped   atomic.Int64
+}
+
+// NewAuditRecorder creates a recorder writing to the sink and starts its worker
+func NewAuditRecorder(cfg *config.AuditConfig, sink AuditSink) *AuditRecorder {
+	r := &AuditRecorder{
+		sink:          sink,
+		batchSize:     defaultAuditBatchSize,
+		flushInterval: defaultAuditFlushInterval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	bufferSize := defaultAuditBufferSize
+	if cfg != nil {
+		if cfg.BufferSize > 0 {
+			bufferSize = cfg.BufferSize
+		}
+		if cfg.BatchSize > 0 {
+			r.batchSize = cfg.BatchSize
+		}
+		if cfg.FlushInterval > 0 {
+			r.flushInterval = cfg.FlushInterval
+		}
+	}
+	r.queue = make(chan *types.AuditLog, bufferSize)
+
+	go r.run()
+	return r
+}
+
+// Record queues the entry without blocking; it is dropped when the queue is full
+func (r *AuditRecorder) Record(entry *types.AuditLog) {
+	if r == nil || entry == nil || r.closed.Load() {
+		return
+	}
+	select {
+	case r.queue <- entry:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of entries dropped because the queue was full or the sink failed
+func (r *AuditRecorder) Dropped() int64 {
+	if r == nil {
+		return 0
+	}
+	return r.dropped.Load()
+}
+
+// Close writes the queued entries and stops the worker
+func (r *AuditRecorder) Close(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	r.closeOnce.Do(func() {
+		r.closed.Store(true)
+		close(r.stop)
+	})
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run batches the queued entries until the recorder is closed
+func (r *AuditRecorder) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*types.AuditLog, 0, r.batchSize)
+	var reported int64
+	flush := func() {
+		if len(batch) > 0 {
+			r.write(batch)
+			batch = make([]*types.AuditLog, 0, r.batchSize)
+		}
+		if dropped := r.dropped.Load(); dropped > reported {
+			logger.Warnf(context.Background(), "[Audit] %d audit log entries dropped so far", dropped)
+			reported = dropped
+		}
+	}
+
+	for {
+		select {
+		case entry := <-r.queue:
+			batch = append(batch, entry)
+			if len(batch) >= r.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-r.stop:
+			for {
+				select {
+				case entry := <-r.queue:
+					batch = append(batch, entry)
+					if len(batch) >= r.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// write sends a batch to the sink; a failed batch is dropped
+func (r *AuditRecorder) write(batch []*types.AuditLog) {
+	ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+	defer cancel()
+	defer func() {
+		if rec := recover(); rec != nil {
+			r.dropped.Add(int64(len(batch)))
+			logger.Errorf(ctx, "[Audit] Sink panicked: %v", rec)
+		}
+	}()
+	if err := r.sink.CreateLogs(ctx, batch); err != nil {
+		r.dropped.Add(int64(len(batch)))
+		logger.Warnf(ctx, "[Audit] Failed to write %d audit log entries: %v", len(batch), err)
+	}
+}
+
+// Audit middleware records the non-GET requests of the API in the audit log once they are processed:
+// the acting tenant and user, the route template, the target resource, the response status and the
+// request ID. JSON request bodies are kept with passwords, tokens and secrets redacted. Entries are
+// handed to the recorder without blocking; without a recorder requests are not audited.
+func Audit(recorder *AuditRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if recorder == nil || !auditedMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		body := readAuditBody(c.Request)
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		entry := &types.AuditLog{
+			ID:           uuid.New().String(),
+			TenantID:     c.GetUint64(types.TenantIDContextKey.String()),
+			RequestID:    c.GetString(types.RequestIDContextKey.String()),
+			Method:       c.Request.Method,
+			Route:        route,
+			ResourceType: auditResourceType(route),
+			ResourceID:   auditResourceID(c.Params),
+			Status:       c.Writer.Status(),
+			ClientIP:     c.ClientIP(),
+			RequestBody:  body,
+			DurationMs:   time.Since(start).Milliseconds(),
+			CreatedAt:    start,
+		}
+		if user, ok := c.Get(types.UserContextKey.String()); ok {
+			if u, ok := user.(*types.User); ok && u != nil {
+				entry.UserID = u.ID
+			}
+		}
+		recorder.Record(entry)
+	}
+}
+
+// auditedMethod reports whether requests with the method can change state
+func auditedMethod(method string) bool {
+	return method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions
+}
+
+// auditResourceType returns the first segment of the route under /api/v1, e.g. knowledge-bases
+func auditResourceType(route string) string {
+	rest := strings.TrimPrefix(route, auditRoutePrefix)
+	if rest == route {
+		return ""
+	}
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		rest = rest[:i]
+	}
+	return rest
+}
+
+// auditResourceID returns the id path parameter, or else the last path parameter of the route
+func auditResourceID(params gin.Params) string {
+	if id := params.ByName("id"); id != "" {
+		return id
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return params[len(params)-1].Value
+}
+
+// readAuditBody returns the redacted JSON body of the request, leaving the body readable by the handlers.
+// Other bodies, bodies too large to keep and malformed JSON give an empty string.
+func readAuditBody(req *http.Request) string {
+	if req.Body == nil || req.Body == http.NoBody {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return ""
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(req.Body, maxAuditBodyBytes+1))
+	req.Body = &replayBody{Reader: io.MultiReader(bytes.NewReader(buf), req.Body), Closer: req.Body}
+	if err != nil || len(buf) > maxAuditBodyBytes {
+		return ""
+	}
+	return redactAuditBody(buf)
+}
+
+// replayBody serves the bytes already read from a request body before the rest of it
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// redactAuditBody replaces the values of the sensitive fields of a JSON document, at any depth.
+// An empty string is returned when the document cannot be parsed, so that nothing unredacted is kept.
+func redactAuditBody(body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return ""
+	}
+	redacted, err := json.Marshal(redactAuditValue(doc))
+	if err != nil {
+		return ""
+	}
+	return string(redacted)
+}
+
+// redactAuditValue redacts the sensitive fields of the objects in a decoded JSON value
+func redactAuditValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if sensitiveAuditField(key) {
+				v[key] = auditRedacted
+			} else {
+				v[key] = redactAuditValue(field)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactAuditValue(item)
+		}
+	}
+	return value
+}
+
+// sensitiveAuditField reports whether a JSON field holds a credential, such as password, new_password,
+// access_token, api_key, totp_code or recovery_code, or the authorization code and state of an
+// identity provider login
+func sensitiveAuditField(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"password", "secret", "api_key", "apikey", "credential", "totp", "recovery_code"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return strings.HasSuffix(key, "token") || key == "authorization" || key == "code" || key == "state"
+}
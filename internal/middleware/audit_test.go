@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// memoryAuditSink keeps the written entries; block delays every write until it is closed
+type memoryAuditSink struct {
+	mu    sync.Mutex
+	logs  []*types.AuditLog
+	block chan struct{}
+}
+
+func (s *memoryAuditSink) CreateLogs(ctx context.Context, logs []*types.AuditLog) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs = append(s.logs, logs...)
+	return nil
+}
+
+func (s *memoryAuditSink) entries() []*types.AuditLog {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*types.AuditLog(nil), s.logs...)
+}
+
+func TestAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sink := &memoryAuditSink{}
+	recorder := NewAuditRecorder(&config.AuditConfig{FlushInterval: time.Hour}, sink)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(types.RequestIDContextKey.String(), "req-1")
+		c.Set(types.TenantIDContextKey.String(), uint64(7))
+		c.Set(types.UserContextKey.String(), &types.User{ID: "user-1"})
+	})
+	v1 := router.Group("/api/v1", Audit(recorder))
+	var handlerBody string
+	v1.POST("/auth/change-password", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		handlerBody = string(body)
+		c.Status(http.StatusOK)
+	})
+	v1.DELETE("/chunks/:knowledge_id/:id", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+	v1.PUT("/sessions/:session_id/title", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	v1.GET("/knowledge-bases/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	send := func(method, path, body string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	password := `{"old_password":"hunter2","new_password":"correct horse","profile":{"api_key":"sk-1"},"name":"a"}`
+	send(http.MethodPost, "/api/v1/auth/change-password", password)
+	send(http.MethodDelete, "/api/v1/chunks/kn-1/ch-1", "")
+	send(http.MethodPut, "/api/v1/sessions/s-1/title", "")
+	send(http.MethodGet, "/api/v1/knowledge-bases/kb-1", "")
+	send(http.MethodPost, "/api/v1/unknown", "")
+
+	if handlerBody != password {
+		t.Errorf("handler body = %q, want the original body", handlerBody)
+	}
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	logs := sink.entries()
+	if len(logs) != 3 {
+		t.Fatalf("got %d entries, want the 3 non-GET requests of known routes", len(logs))
+	}
+	first := logs[0]
+	if first.TenantID != 7 || first.UserID != "user-1" || first.RequestID != "req-1" ||
+		first.Route != "/api/v1/auth/change-password" || first.ResourceType != "auth" || first.Status != http.StatusOK {
+		t.Errorf("unexpected entry: %+v", first)
+	}
+	if strings.Contains(first.RequestBody, "hunter2") || strings.Contains(first.RequestBody, "correct horse") ||
+		strings.Contains(first.RequestBody, "sk-1") {
+		t.Errorf("request body %s keeps a secret", first.RequestBody)
+	}
+	var body map[string]any
+	if err := json.Unmarshal([]byte(first.RequestBody), &body); err != nil || body["name"] != "a" ||
+		body["old_password"] != auditRedacted {
+		t.Errorf("request body = %s, want the redacted document", first.RequestBody)
+	}
+
+	if logs[1].ResourceType != "chunks" || logs[1].ResourceID != "ch-1" || logs[1].Status != http.StatusNotFound {
+		t.Errorf("unexpected entry: %+v", logs[1])
+	}
+	if logs[2].ResourceType != "sessions" || logs[2].ResourceID != "s-1" {
+		t.Errorf("unexpected entry: %+v", logs[2])
+	}
+}
+
+func TestAuditRecorderDropsWhenFull(t *testing.T) {
+	sink := &memoryAuditSink{block: make(chan struct{})}
+	recorder := NewAuditRecorder(&config.AuditConfig{BufferSize: 2, BatchSize: 1, FlushInterval: time.Hour}, sink)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			recorder.Record(&types.AuditLog{ID: "entry"})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record blocked on a slow sink")
+	}
+	if recorder.Dropped() == 0 {
+		t.Error("Dropped = 0, want the entries beyond the buffer to be dropped")
+	}
+
+	close(sink.block)
+	if err := recorder.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := int64(len(sink.entries())) + recorder.Dropped(); got != 10 {
+		t.Errorf("written + dropped = %d, want 10", got)
+	}
+}
+
+func TestRedactAuditBody(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"username":"a","password":"p"}`, `{"password":"***","username":"a"}`},
+		{`[{"access_token":"t","max_tokens":5}]`, `[{"access_token":"***","max_tokens":5}]`},
+		{`{"totp_code":"123456","recovery_code":"abcde-fghij"}`, `{"recovery_code":"***","totp_code":"***"}`},
+		{`{"code":"c","state":"s"}`, `{"code":"***","state":"***"}`},
+		{`{"id":12345678901234567890}`, `{"id":12345678901234567890}`},
+		{`{"password":`, ``},
+	}
+	for _, tt := range tests {
+		if got := redactAuditBody([]byte(tt.body)); got != tt.want {
+			t.Errorf("redactAuditBody(%s) = %s, want %s", tt.body, got, tt.want)
+		}
+	}
+}
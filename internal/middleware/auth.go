@@ -2,14 +2,15 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"log"
-	"net/http"
 	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/Tencent/WeKnora/internal/config"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	"github.com/gin-gonic/gin"
@@ -18,9 +19,12 @@ import (
 // 无需认证的API列表
 var noAuthAPI = map[string][]string{
 	"/health":               {"GET"},
+	"/health/ready":         {"GET"},
+	"/metrics":              {"GET"},
 	"/api/v1/auth/register": {"POST"},
 	"/api/v1/auth/login":    {"POST"},
 	"/api/v1/auth/refresh":  {"POST"},
+	"/api/v1/auth/oidc/*":   {"GET", "POST"},
 }
 
 // 检查请求是否在无需认证的API列表中
@@ -56,10 +60,12 @@ func canAccessTenant(user *types.User, targetTenantID uint64, cfg *config.Config
 	return true
 }
 
-// Auth 认证中间件
+// Auth 认证中间件；Bearer令牌由authenticator校验（内置JWT或外部认证服务），其余流程与提供方无关
 func Auth(
 	tenantService interfaces.TenantService,
-	userService interfaces.UserService,
+	authenticator interfaces.Authenticator,
+	roleService interfaces.RoleService,
+	apiKeyService interfaces.APIKeyService,
 	cfg *config.Config,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -75,17 +81,26 @@ func Auth(
 			return
 		}
 
-		// 尝试JWT Token认证
+		// 单租户模式：共享API Key直接认证为默认租户
+		singleTenant := cfg.SingleTenantMode()
+		if singleTenant != nil && isSharedAPIKey(c, singleTenant.SharedAPIKey) {
+			useDefaultTenant(c, tenantService, singleTenant.DefaultTenantID())
+			return
+		}
+
+		// 尝试Bearer Token认证
 		authHeader := c.GetHeader("Authorization")
 		if authHeader != "" && strings.HasPrefix(authHeader, "Bearer ") {
 			token := strings.TrimPrefix(authHeader, "Bearer ")
-			user, err := userService.ValidateToken(c.Request.Context(), token)
+			user, err := authenticator.Authenticate(c.Request.Context(), token)
 			if err == nil && user != nil {
-				// JWT Token认证成功
-				// 检查是否有跨租户访问请求
+				// Token认证成功
+				// 检查是否有跨租户访问请求；单租户模式下所有请求使用默认租户，忽略X-Tenant-ID
 				targetTenantID := user.TenantID
 				tenantHeader := c.GetHeader("X-Tenant-ID")
-				if tenantHeader != "" {
+				if singleTenant != nil {
+					targetTenantID = singleTenant.DefaultTenantID()
+				} else if tenantHeader != "" {
 					// 解析目标租户ID
 					parsedTenantID, err := strconv.ParseUint(tenantHeader, 10, 64)
 					if err == nil {
@@ -98,19 +113,13 @@ func Auth(
 								log.Printf("User %s switching to tenant %d", user.ID, targetTenantID)
 							} else {
 								log.Printf("Error getting target tenant by ID: %v, tenantID: %d", err, parsedTenantID)
-								c.JSON(http.StatusBadRequest, gin.H{
-									"error": "Invalid target tenant ID",
-								})
-								c.Abort()
+								abortWithError(c, werrors.NewBadRequestError("Invalid target tenant ID"))
 								return
 							}
 						} else {
 							// 用户没有权限访问目标租户
 							log.Printf("User %s attempted to access tenant %d without permission", user.ID, parsedTenantID)
-							c.JSON(http.StatusForbidden, gin.H{
-								"error": "Forbidden: insufficient permissions to access target tenant",
-							})
-							c.Abort()
+							abortWithError(c, werrors.NewForbiddenError("Forbidden: insufficient permissions to access target tenant"))
 							return
 						}
 					}
@@ -120,10 +129,7 @@ func Auth(
 				tenant, err := tenantService.GetTenantByID(c.Request.Context(), targetTenantID)
 				if err != nil {
 					log.Printf("Error getting tenant by ID: %v, tenantID: %d, userID: %s", err, targetTenantID, user.ID)
-					c.JSON(http.StatusUnauthorized, gin.H{
-						"error": "Unauthorized: invalid tenant",
-					})
-					c.Abort()
+					abortWithError(c, werrors.NewUnauthorizedError("Unauthorized: invalid tenant"))
 					return
 				}
 
@@ -140,6 +146,16 @@ func Auth(
 						types.UserContextKey, user,
 					),
 				)
+				// Routes check the scopes of the role of the user in the tenant with RequireScope
+				if roleService != nil {
+					role, err := roleService.ResolveRole(c.Request.Context(), targetTenantID, user)
+					if err != nil {
+						log.Printf("Error resolving role: %v, tenantID: %d, userID: %s", err, targetTenantID, user.ID)
+						abortWithError(c, werrors.NewInternalServerError("Failed to resolve user role"))
+						return
+					}
+					withUserRole(c, role)
+				}
 				c.Next()
 				return
 			}
@@ -147,33 +163,55 @@ func Auth(
 
 		// 尝试X-API-Key认证（兼容模式）
 		apiKey := c.GetHeader("X-API-Key")
+		if apiKey == "" {
+			// OpenAI兼容接口按OpenAI客户端的方式以Bearer令牌传递API Key
+			apiKey = openAICompatBearerAPIKey(c, cfg)
+		}
 		if apiKey != "" {
-			// Get tenant information
-			tenantID, err := tenantService.ExtractTenantIDFromAPIKey(apiKey)
-			if err != nil {
-				c.JSON(http.StatusUnauthorized, gin.H{
-					"error": "Unauthorized: invalid API key format",
-				})
-				c.Abort()
-				return
-			}
+			var tenantID uint64
+			var t *types.Tenant
+			var scopes types.StringArray
+			if strings.HasPrefix(apiKey, types.ManagedAPIKeyPrefix) {
+				// API key minted with the API key management API, with its own scopes and expiration
+				key, err := authenticateManagedAPIKey(c.Request.Context(), apiKeyService, apiKey)
+				if err != nil {
+					abortWithError(c, werrors.NewUnauthorizedError("Unauthorized: invalid, expired or revoked API key"))
+					return
+				}
+				tenantID, scopes = key.TenantID, key.Scopes
+				t, err = tenantService.GetTenantByID(c.Request.Context(), tenantID)
+				if err != nil || t == nil {
+					log.Printf("Error getting tenant by ID: %v, tenantID: %d", err, tenantID)
+					abortWithError(c, werrors.NewUnauthorizedError("Unauthorized: invalid API key"))
+					return
+				}
+			} else {
+				// Get tenant information
+				var err error
+				tenantID, err = tenantService.ExtractTenantIDFromAPIKey(apiKey)
+				if err != nil {
+					abortWithError(c, werrors.NewUnauthorizedError("Unauthorized: invalid API key format"))
+					return
+				}
 
-			// Verify API key validity (matches the one in database)
-			t, err := tenantService.GetTenantByID(c.Request.Context(), tenantID)
-			if err != nil {
-				log.Printf("Error getting tenant by ID: %v, tenantID: %d", err, tenantID)
-				c.JSON(http.StatusUnauthorized, gin.H{
-					"error": "Unauthorized: invalid API key",
-				})
-				c.Abort()
-				return
-			}
+				// Verify API key validity (matches the one in database)
+				t, err = tenantService.GetTenantByID(c.Request.Context(), tenantID)
+				if err != nil {
+					log.Printf("Error getting tenant by ID: %v, tenantID: %d", err, tenantID)
+					abortWithError(c, werrors.NewUnauthorizedError("Unauthorized: invalid API key"))
+					return
+				}
 
-			if t == nil || t.APIKey != apiKey {
-				c.JSON(http.StatusUnauthorized, gin.H{
-					"error": "Unauthorized: invalid API key",
-				})
-				c.Abort()
+				if t == nil || t.APIKey != apiKey {
+					abortWithError(c, werrors.NewUnauthorizedError("Unauthorized: invalid API key"))
+					return
+				}
+				scopes = t.EffectiveAPIKeyScopes()
+			}
+			// Routes check the scopes of the key with RequireScope
+			withAPIKeyScopes(c, scopes)
+			if singleTenant != nil {
+				useDefaultTenant(c, tenantService, singleTenant.DefaultTenantID())
 				return
 			}
 
@@ -191,9 +229,55 @@ func Auth(
 		}
 
 		// 没有提供任何认证信息
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: missing authentication"})
-		c.Abort()
+		abortWithError(c, werrors.NewUnauthorizedError("Unauthorized: missing authentication"))
+	}
+}
+
+// authenticateManagedAPIKey returns the active minted API key matching the key of a request
+func authenticateManagedAPIKey(
+	ctx context.Context, apiKeyService interfaces.APIKeyService, apiKey string,
+) (*types.APIKey, error) {
+	if apiKeyService == nil {
+		return nil, errors.New("API key management is not available")
+	}
+	key, err := apiKeyService.Authenticate(ctx, apiKey)
+	if err != nil {
+		log.Printf("Rejected minted API key: %v", err)
+		return nil, err
+	}
+	return key, nil
+}
+
+// isSharedAPIKey reports whether the request authenticates with the shared API key of single-tenant mode,
+// sent as X-API-Key or Bearer token
+func isSharedAPIKey(c *gin.Context, sharedAPIKey string) bool {
+	if sharedAPIKey == "" {
+		return false
+	}
+	key := c.GetHeader("X-API-Key")
+	if key == "" {
+		key = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(key), []byte(sharedAPIKey)) == 1
+}
+
+// useDefaultTenant continues the request as the default tenant of single-tenant mode
+func useDefaultTenant(c *gin.Context, tenantService interfaces.TenantService, tenantID uint64) {
+	tenant, err := tenantService.GetTenantByID(c.Request.Context(), tenantID)
+	if err != nil || tenant == nil {
+		log.Printf("Error getting default tenant by ID: %v, tenantID: %d", err, tenantID)
+		abortWithError(c, werrors.NewUnauthorizedError("Unauthorized: invalid default tenant"))
+		return
 	}
+	c.Set(types.TenantIDContextKey.String(), tenantID)
+	c.Set(types.TenantInfoContextKey.String(), tenant)
+	c.Request = c.Request.WithContext(
+		context.WithValue(
+			context.WithValue(c.Request.Context(), types.TenantIDContextKey, tenantID),
+			types.TenantInfoContextKey, tenant,
+		),
+	)
+	c.Next()
 }
 
 // GetTenantIDFromContext helper function to get tenant ID from context
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeAuthTenantService knows tenants 1 and 7, whose API keys are "key-<id>"
+type fakeAuthTenantService struct {
+	interfaces.TenantService
+}
+
+func (fakeAuthTenantService) GetTenantByID(ctx context.Context, id uint64) (*types.Tenant, error) {
+	if id != 1 && id != 7 {
+		return nil, errors.New("tenant not found")
+	}
+	return &types.Tenant{ID: id, APIKey: fmt.Sprintf("key-%d", id)}, nil
+}
+
+func (fakeAuthTenantService) ExtractTenantIDFromAPIKey(apiKey string) (uint64, error) {
+	if !strings.HasPrefix(apiKey, "key-") {
+		return 0, errors.New("invalid api key")
+	}
+	return strconv.ParseUint(strings.TrimPrefix(apiKey, "key-"), 10, 64)
+}
+
+// fakeAuthenticator accepts the token "user-token" of a user of tenant 7
+type fakeAuthenticator struct{}
+
+func (fakeAuthenticator) Name() string { return "fake" }
+
+func (fakeAuthenticator) Authenticate(ctx context.Context, token string) (*types.User, error) {
+	if token != "user-token" {
+		return nil, errors.New("invalid token")
+	}
+	return &types.User{ID: "user-1", TenantID: 7, CanAccessAllTenants: true}, nil
+}
+
+func TestAuthSingleTenantMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const sharedKey = "shared-key-0123456789abcdefghijklmnop"
+	singleTenant := &config.Config{Tenant: &config.TenantConfig{
+		EnableCrossTenantAccess: true,
+		SingleTenant:            &config.SingleTenantConfig{Enabled: true, SharedAPIKey: sharedKey},
+	}}
+	multiTenant := &config.Config{Tenant: &config.TenantConfig{EnableCrossTenantAccess: true}}
+
+	tests := []struct {
+		name       string
+		cfg        *config.Config
+		headers    map[string]string
+		wantStatus int
+		wantTenant uint64
+	}{
+		{
+			name:       "shared key as api key",
+			cfg:        singleTenant,
+			headers:    map[string]string{"X-API-Key": sharedKey},
+			wantStatus: http.StatusOK,
+			wantTenant: 1,
+		},
+		{
+			name:       "shared key as bearer token",
+			cfg:        singleTenant,
+			headers:    map[string]string{"Authorization": "Bearer " + sharedKey},
+			wantStatus: http.StatusOK,
+			wantTenant: 1,
+		},
+		{
+			name:       "tenant api key resolves to the default tenant",
+			cfg:        singleTenant,
+			headers:    map[string]string{"X-API-Key": "key-7"},
+			wantStatus: http.StatusOK,
+			wantTenant: 1,
+		},
+		{
+			name:       "user login ignores tenant switching",
+			cfg:        singleTenant,
+			headers:    map[string]string{"Authorization": "Bearer user-token", "X-Tenant-ID": "7"},
+			wantStatus: http.StatusOK,
+			wantTenant: 1,
+		},
+		{
+			name:       "wrong key",
+			cfg:        singleTenant,
+			headers:    map[string]string{"X-API-Key": sharedKey + "x"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing credentials",
+			cfg:        singleTenant,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "shared key is unknown in multi-tenant mode",
+			cfg:        multiTenant,
+			headers:    map[string]string{"X-API-Key": sharedKey},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "tenant api key keeps its tenant in multi-tenant mode",
+			cfg:        multiTenant,
+			headers:    map[string]string{"X-API-Key": "key-7"},
+			wantStatus: http.StatusOK,
+			wantTenant: 7,
+		},
+		{
+			name:       "tenant switching in multi-tenant mode",
+			cfg:        multiTenant,
+			headers:    map[string]string{"Authorization": "Bearer user-token", "X-Tenant-ID": "1"},
+			wantStatus: http.StatusOK,
+			wantTenant: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTenant uint64
+			r := gin.New()
+			r.Use(Auth(fakeAuthTenantService{}, fakeAuthenticator{}, nil, nil, tt.cfg))
+			r.GET("/api/v1/sessions", func(c *gin.Context) {
+				gotTenant = c.GetUint64(types.TenantIDContextKey.String())
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/sessions", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if gotTenant != tt.wantTenant {
+				t.Errorf("tenant = %d, want %d", gotTenant, tt.wantTenant)
+			}
+		})
+	}
+}
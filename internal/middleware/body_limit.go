@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+)
+
+// defaultBodyLimitBytes is used when body_limit.max_bytes is not set
+const defaultBodyLimitBytes = 10 << 20
+
+// BodyLimit middleware caps the request body of every route. A route takes the limit configured in
+// body_limit.routes, else the one given in routeLimits, else body_limit.max_bytes; routes are keyed on
+// their method and pattern, e.g. "POST /api/v1/knowledge-bases/:id/knowledge/file". A request declaring
+// a larger body is answered with 413 before the handler runs. The body is read through a limited reader,
+// so a larger body sent without its length fails the read crossing the limit, and the request is answered
+// with 413 instead of the handler's error.
+func BodyLimit(cfg *config.Config, routeLimits map[string]int64) gin.HandlerFunc {
+	maxBytes := int64(defaultBodyLimitBytes)
+	limits := maps.Clone(routeLimits)
+	if limits == nil {
+		limits = make(map[string]int64)
+	}
+	if cfg != nil && cfg.BodyLimit != nil {
+		if cfg.BodyLimit.MaxBytes != 0 {
+			maxBytes = cfg.BodyLimit.MaxBytes
+		}
+		maps.Copy(limits, cfg.BodyLimit.Routes)
+	}
+
+	return func(c *gin.Context) {
+		limit := maxBytes
+		if routeLimit, ok := limits[c.Request.Method+" "+c.FullPath()]; ok {
+			limit = routeLimit
+		}
+		if limit < 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			logger.Warnf(c.Request.Context(), "Request body too large, path: %s, size: %d, limit: %d",
+				c.FullPath(), c.Request.ContentLength, limit)
+			abortWithError(c, bodyTooLargeError(c.Request.ContentLength, limit))
+			return
+		}
+
+		body := &bodyLimitReader{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, limit)}
+		c.Request.Body = body
+
+		c.Next()
+
+		if body.exceeded {
+			logger.Warnf(c.Request.Context(), "Request body too large, path: %s, limit: %d", c.FullPath(), limit)
+			// The handler failed to read the body, report why instead of its error
+			if !c.Writer.Written() {
+				c.Errors = c.Errors[:0]
+				_ = c.Error(bodyTooLargeError(0, limit))
+			}
+		}
+	}
+}
+
+// bodyTooLargeError reports a request body over the limit of its route; size is 0 when the body was
+// cut while streaming, before its size was known
+func bodyTooLargeError(size, limit int64) *errors.AppError {
+	details := map[string]interface{}{"limit": limit}
+	if size > 0 {
+		details["size"] = size
+	}
+	return errors.NewPayloadTooLargeError(errors.CodeRequestTooLarge,
+		fmt.Sprintf("Request body exceeds the limit of %d bytes", limit)).WithDetails(details)
+}
+
+// bodyLimitReader records whether a request body was cut at its limit
+type bodyLimitReader struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+// Read implements io.Reader
+func (r *bodyLimitReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if err != nil && stderrors.As(err, &maxBytesErr) {
+		r.exceeded = true
+	}
+	return n, err
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/errors"
+)
+
+const uploadRoute = "/api/v1/knowledge-bases/:id/knowledge/file"
+
+// multipartUpload builds a multipart upload of a file of size bytes
+func multipartUpload(t *testing.T, size int) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "manual.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write(bytes.Repeat([]byte("x"), size)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &body, writer.FormDataContentType()
+}
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{BodyLimit: &config.BodyLimitConfig{
+		MaxBytes: 64,
+		Routes:   map[string]int64{"POST /unlimited": -1},
+	}}
+
+	uploads := 0
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.Use(BodyLimit(cfg, map[string]int64{"POST " + uploadRoute: 4096}))
+	// Stands in for CreateKnowledgeFromFile, which streams the file part of the multipart body
+	router.POST(uploadRoute, func(c *gin.Context) {
+		uploads++
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+		part, err := reader.NextPart()
+		if err != nil {
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+		n, err := io.Copy(io.Discard, part)
+		if err != nil {
+			c.Error(errors.NewBadRequestError("File upload failed").WithDetails(err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"size": n})
+	})
+	echo := func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"size": len(body)})
+	}
+	router.POST("/json", echo)
+	router.POST("/unlimited", echo)
+
+	send := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	wantTooLarge := func(t *testing.T, w *httptest.ResponseRecorder) {
+		t.Helper()
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("status = %d, want 413, body: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error.Code != errors.CodeRequestTooLarge {
+			t.Errorf("error code = %q (%v), want %q", resp.Error.Code, err, errors.CodeRequestTooLarge)
+		}
+	}
+
+	t.Run("upload within the route limit reaches the handler", func(t *testing.T) {
+		body, contentType := multipartUpload(t, 3000)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/knowledge-bases/kb-1/knowledge/file", body)
+		req.Header.Set("Content-Type", contentType)
+		if w := send(req); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"size":3000`) {
+			t.Fatalf("status = %d, body: %s, want the whole file uploaded", w.Code, w.Body.String())
+		}
+		if uploads != 1 {
+			t.Errorf("handler ran %d times, want 1", uploads)
+		}
+	})
+
+	t.Run("upload slightly over the limit is rejected before the handler", func(t *testing.T) {
+		uploads = 0
+		body, contentType := multipartUpload(t, 4000)
+		if body.Len() <= 4096 || body.Len() > 4300 {
+			t.Fatalf("test body of %d bytes is not slightly over the limit", body.Len())
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/knowledge-bases/kb-1/knowledge/file", body)
+		req.Header.Set("Content-Type", contentType)
+		wantTooLarge(t, send(req))
+		if uploads != 0 {
+			t.Errorf("handler ran %d times, want 0", uploads)
+		}
+	})
+
+	t.Run("streamed upload over the limit fails while reading", func(t *testing.T) {
+		body, contentType := multipartUpload(t, 8000)
+		source := &countingReader{ReadCloser: io.NopCloser(body)}
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/knowledge-bases/kb-1/knowledge/file", source)
+		req.Header.Set("Content-Type", contentType)
+		req.ContentLength = -1
+		wantTooLarge(t, send(req))
+		if source.n > 4096+32*1024 {
+			t.Errorf("read %d bytes of the body, want reading to stop near the limit", source.n)
+		}
+	})
+
+	t.Run("json route uses the global limit", func(t *testing.T) {
+		if w := send(httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(`{"a":1}`))); w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", w.Code)
+		}
+		wantTooLarge(t, send(httptest.NewRequest(http.MethodPost, "/json", strings.NewReader(strings.Repeat("x", 65)))))
+	})
+
+	t.Run("negative route limit disables the limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/unlimited", strings.NewReader(strings.Repeat("x", 1000)))
+		if w := send(req); w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", w.Code)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/logger"
+)
+
+// corsMethods are the methods allowed on the API unless the policy lists its own
+var corsMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// corsHeaders are the request headers always allowed on the API, including those of idempotent
+// requests, confirmed tenant purges and resumed event streams
+var corsHeaders = []string{
+	"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Request-ID", "Accept-Language",
+	IdempotencyKeyHeader, "X-Confirmation-Token", "Last-Event-ID",
+}
+
+// corsExposeHeaders are the response headers readable by browser clients
+var corsExposeHeaders = []string{
+	"Content-Length", "Access-Control-Allow-Origin", "X-Request-ID", "X-Answer-Cache",
+	"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", idempotentReplayedHeader,
+}
+
+// CORS applies the cross-origin policy of the cors section of the configuration to the API.
+// Without an origin list any origin is allowed, which suits local development; with one, only the
+// listed origins are allowed and the matched origin is echoed back.
+func CORS(cfg *config.Config) gin.HandlerFunc {
+	var policy *config.CORSConfig
+	if cfg != nil {
+		policy = cfg.CORS
+	}
+	corsConfig := corsPolicyConfig(policy, corsMethods, corsHeaders, corsExposeHeaders)
+	if policy != nil && policy.AllowCredentials && !corsConfig.AllowCredentials {
+		logger.Warnf(context.Background(),
+			"[CORS] allow_credentials is ignored because any origin is allowed; "+
+				"list the frontend origins in cors.allow_origins to allow credentials")
+	}
+	return cors.New(corsConfig)
+}
+
+// corsPolicyConfig builds a CORS policy from a cors section of the configuration on top of the
+// default methods and headers of the API it applies to, allowing any origin by default
+func corsPolicyConfig(policy *config.CORSConfig, methods, headers, exposeHeaders []string) cors.Config {
+	corsConfig := cors.Config{
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  slices.Clone(methods),
+		AllowHeaders:  slices.Clone(headers),
+		ExposeHeaders: slices.Clone(exposeHeaders),
+		AllowWildcard: true,
+		MaxAge:        12 * time.Hour,
+	}
+	if policy == nil {
+		return corsConfig
+	}
+	if len(policy.AllowOrigins) > 0 {
+		corsConfig.AllowOrigins = policy.AllowOrigins
+	}
+	if len(policy.AllowMethods) > 0 {
+		corsConfig.AllowMethods = policy.AllowMethods
+	}
+	corsConfig.AllowHeaders = append(corsConfig.AllowHeaders, policy.AllowHeaders...)
+	// Browsers refuse credentials for any origin, so they are only allowed with an origin list
+	corsConfig.AllowCredentials = policy.AllowCredentials && !slices.Contains(corsConfig.AllowOrigins, "*")
+	return corsConfig
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	allowlist := &config.CORSConfig{
+		AllowOrigins:     []string{"https://weknora.example.com", "https://*.example.org"},
+		AllowCredentials: true,
+	}
+
+	tests := []struct {
+		name            string
+		cfg             *config.Config
+		origin          string
+		wantStatus      int
+		wantAllowOrigin string
+		wantCredentials bool
+	}{
+		{
+			name:            "any origin without an allowlist",
+			cfg:             &config.Config{},
+			origin:          "http://localhost:5173",
+			wantStatus:      http.StatusOK,
+			wantAllowOrigin: "*",
+		},
+		{
+			name:            "no credentials for any origin",
+			cfg:             &config.Config{CORS: &config.CORSConfig{AllowCredentials: true}},
+			origin:          "http://localhost:5173",
+			wantStatus:      http.StatusOK,
+			wantAllowOrigin: "*",
+		},
+		{
+			name:            "allowlisted origin is echoed back",
+			cfg:             &config.Config{CORS: allowlist},
+			origin:          "https://weknora.example.com",
+			wantStatus:      http.StatusOK,
+			wantAllowOrigin: "https://weknora.example.com",
+			wantCredentials: true,
+		},
+		{
+			name:            "allowlisted subdomain",
+			cfg:             &config.Config{CORS: allowlist},
+			origin:          "https://app.example.org",
+			wantStatus:      http.StatusOK,
+			wantAllowOrigin: "https://app.example.org",
+			wantCredentials: true,
+		},
+		{
+			name:       "origin outside the allowlist",
+			cfg:        &config.Config{CORS: allowlist},
+			origin:     "https://evil.example.com",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(CORS(tt.cfg))
+			router.GET("/api/v1/sessions", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/sessions", nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllowOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCredentials {
+				t.Errorf("credentials allowed = %v, want %v", got, tt.wantCredentials)
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{CORS: &config.CORSConfig{
+		AllowOrigins: []string{"https://weknora.example.com"},
+		AllowMethods: []string{"GET", "POST"},
+		AllowHeaders: []string{"X-Custom-Header"},
+	}}
+	router := gin.New()
+	router.Use(CORS(cfg))
+	router.POST("/api/v1/sessions", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/sessions", nil)
+	req.Header.Set("Origin", "https://weknora.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "X-Custom-Header, Idempotency-Key, Last-Event-ID")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://weknora.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET,POST" {
+		t.Errorf("Access-Control-Allow-Methods = %q, want GET,POST", got)
+	}
+	if got := strings.ToLower(rec.Header().Get("Access-Control-Allow-Headers")); !strings.Contains(got, "x-custom-header") ||
+		!strings.Contains(got, "x-api-key") || !strings.Contains(got, "idempotency-key") ||
+		!strings.Contains(got, "x-confirmation-token") || !strings.Contains(got, "last-event-id") {
+		t.Errorf("Access-Control-Allow-Headers = %q", got)
+	}
+}
@@ -1,11 +1,10 @@
 package middleware
 
 import (
-	"net/http"
-
 	"github.com/gin-gonic/gin"
 
 	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/i18n"
 )
 
 // ErrorHandler 是一个处理应用错误的中间件
@@ -19,28 +18,37 @@ func ErrorHandler() gin.HandlerFunc {
 			// 获取最后一个错误
 			err := c.Errors.Last().Err
 
-			// 检查是否为应用错误
-			if appErr, ok := errors.IsAppError(err); ok {
-				// 返回应用错误
-				c.JSON(appErr.HTTPCode, gin.H{
-					"success": false,
-					"error": gin.H{
-						"code":    appErr.Code,
-						"message": appErr.Message,
-						"details": appErr.Details,
-					},
-				})
-				return
+			// 检查是否为应用错误；其他错误中已知的领域错误保留其 code，
+			// 其余按内部错误处理且不暴露原始信息
+			appErr, ok := errors.IsAppError(err)
+			if !ok {
+				appErr = errors.FromError(err)
+				if appErr.MachineCode() == errors.CodeInternal {
+					appErr.Message = "Internal server error"
+				}
 			}
-
-			// 处理其他类型的错误
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(appErr.HTTPCode, gin.H{
 				"success": false,
-				"error": gin.H{
-					"code":    errors.ErrInternalServer,
-					"message": "Internal server error",
-				},
+				"error":   localizedBody(c, appErr),
 			})
 		}
 	}
 }
+
+// abortWithError aborts the request with the standard error response
+func abortWithError(c *gin.Context, appErr *errors.AppError) {
+	c.AbortWithStatusJSON(appErr.HTTPCode, gin.H{
+		"success": false,
+		"error":   localizedBody(c, appErr),
+	})
+}
+
+// localizedBody 返回错误响应体，若消息目录中有该错误 code 的文案则按请求语言渲染 message；
+// code 本身与语言无关，保持不变
+func localizedBody(c *gin.Context, appErr *errors.AppError) map[string]any {
+	body := appErr.Body()
+	if message, ok := i18n.Lookup(i18n.LanguageFromContext(c.Request.Context()), "error."+appErr.MachineCode()); ok {
+		body["message"] = message
+	}
+	return body
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		hideMessage bool
+	}{
+		{
+			name:       "app error",
+			err:        errors.NewNotFoundError("Agent not found").WithCode(errors.CodeAgentNotFound),
+			wantStatus: http.StatusNotFound,
+			wantCode:   errors.CodeAgentNotFound,
+		},
+		{
+			name: "wrapped domain error",
+			err: fmt.Errorf("save file: %w",
+				errors.NewSentinel(errors.CodeQuotaExceeded, http.StatusForbidden, "storage quota exceeded")),
+			wantStatus: http.StatusForbidden,
+			wantCode:   errors.CodeQuotaExceeded,
+		},
+		{
+			name:        "internal error hides the original message",
+			err:         fmt.Errorf("dial tcp 10.0.0.3:5432: connection refused"),
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    errors.CodeInternal,
+			hideMessage: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/", func(c *gin.Context) { c.Error(tt.err) })
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var resp struct {
+				Success bool `json:"success"`
+				Error   struct {
+					Code    string `json:"code"`
+					Status  int    `json:"status"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+			}
+			if rec.Code != tt.wantStatus || resp.Success || resp.Error.Status != tt.wantStatus {
+				t.Errorf("status = %d (body %d), want %d", rec.Code, resp.Error.Status, tt.wantStatus)
+			}
+			if resp.Error.Code != tt.wantCode {
+				t.Errorf("code = %s, want %s", resp.Error.Code, tt.wantCode)
+			}
+			// The message may be localized, but an internal error never leaks the original one
+			if resp.Error.Message == "" || (tt.hideMessage && resp.Error.Message == tt.err.Error()) {
+				t.Errorf("message = %q", resp.Error.Message)
+			}
+		})
+	}
+}
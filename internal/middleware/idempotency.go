@@ -0,0 +1,341 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+const (
+	// IdempotencyKeyHeader carries the key a client picks to make a request safe to retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader marks a response replayed from an earlier request with the same key
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	// defaultIdempotencyTTL is used when idempotency.ttl is not set
+	defaultIdempotencyTTL = 24 * time.Hour
+	// maxIdempotencyKeyLength bounds the length of a key
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentResponseBytes bounds the responses kept for replay; larger ones are not kept
+	maxIdempotentResponseBytes = 1 << 20
+	// idempotencySweepInterval is how often the in-memory store drops expired keys
+	idempotencySweepInterval = time.Minute
+)
+
+// IdempotencyRecord is what is kept for a key: a reservation while its first request is processed,
+// then the response of that request
+type IdempotencyRecord struct {
+	// Completed is false while the first request is processed
+	Completed bool `json:"completed"`
+	// Fingerprint identifies the method, path and body of the first request
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// IdempotencyStore keeps the records of idempotency keys until they expire
+type IdempotencyStore interface {
+	// Reserve claims the key for a request about to be processed.
+	// Returns false when the key is already reserved or completed.
+	Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Get returns the record of the key, nil when there is none
+	Get(ctx context.Context, key string) (*IdempotencyRecord, error)
+	// Complete replaces the reservation of the key with the response of its request
+	Complete(ctx context.Context, key string, record *IdempotencyRecord, ttl time.Duration) error
+	// Release drops the reservation of the key, so that the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// Idempotency middleware makes the requests sent with an Idempotency-Key header safe to retry. The first
+// request with a key is processed and its response is kept; later requests of the same tenant with the key
+// get that response again without being processed, until the key expires. Reusing a key for a different
+// request, or while its first request is still processed, is answered with 409. Only successful responses
+// are kept, so that a failed request can be retried with its key. When the store fails the request is
+// processed as if it had no key.
+func Idempotency(cfg *config.Config, store IdempotencyStore) gin.HandlerFunc {
+	ttl := defaultIdempotencyTTL
+	if cfg != nil && cfg.Idempotency != nil && cfg.Idempotency.TTL > 0 {
+		ttl = cfg.Idempotency.TTL
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		tenantID := c.GetUint64(types.TenantIDContextKey.String())
+		if key == "" || tenantID == 0 || store == nil {
+			c.Next()
+			return
+		}
+		if !validIdempotencyKey(key) {
+			abortWithError(c, errors.NewValidationError(fmt.Sprintf(
+				"%s must be 1 to %d printable ASCII characters", IdempotencyKeyHeader, maxIdempotencyKeyLength)))
+			return
+		}
+
+		ctx := c.Request.Context()
+		storeKey := "tenant:" + strconv.FormatUint(tenantID, 10) + ":" + key
+		reserved, err := store.Reserve(ctx, storeKey, ttl)
+		if err != nil {
+			logger.Warnf(ctx, "Idempotency store failed, processing the request: %v", err)
+			c.Next()
+			return
+		}
+		if !reserved {
+			replayIdempotent(c, store, storeKey)
+			return
+		}
+
+		body := newFingerprintReader(c.Request)
+		c.Request.Body = body
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		status := writer.Status()
+		if !writer.Written() || status < http.StatusOK || status >= http.StatusMultipleChoices || writer.overflow {
+			body.stop()
+			if err := store.Release(ctx, storeKey); err != nil {
+				logger.Warnf(ctx, "Failed to release idempotency key: %v", err)
+			}
+			return
+		}
+		record := &IdempotencyRecord{
+			Completed:   true,
+			Fingerprint: body.Sum(),
+			Status:      status,
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+		}
+		if err := store.Complete(ctx, storeKey, record, ttl); err != nil {
+			logger.Warnf(ctx, "Failed to keep idempotent response: %v", err)
+		}
+	}
+}
+
+// replayIdempotent answers a request whose key is already taken with the response of the first request
+func replayIdempotent(c *gin.Context, store IdempotencyStore, storeKey string) {
+	ctx := c.Request.Context()
+	record, err := store.Get(ctx, storeKey)
+	if err != nil {
+		logger.Warnf(ctx, "Idempotency store failed, processing the request: %v", err)
+		c.Next()
+		return
+	}
+	// A record gone in between belongs to a first request that failed, the client can retry as well
+	if record == nil || !record.Completed {
+		abortWithError(c, errors.NewConflictError("A request with the same idempotency key is in progress").
+			WithCode(errors.CodeIdempotencyInProgress))
+		return
+	}
+	body := newFingerprintReader(c.Request)
+	c.Request.Body = body
+	if body.Sum() != record.Fingerprint {
+		logger.Warnf(ctx, "Idempotency key reused for a different request: %s", c.Request.URL.Path)
+		abortWithError(c, errors.NewConflictError("The idempotency key was already used for a different request").
+			WithCode(errors.CodeIdempotencyKeyReused))
+		return
+	}
+
+	c.Header(idempotentReplayedHeader, "true")
+	c.Data(record.Status, record.ContentType, record.Body)
+	c.Abort()
+}
+
+// validIdempotencyKey checks that a key is short printable ASCII
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// recordingWriter keeps a copy of the response body for replay
+type recordingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool // The body is larger than the responses kept
+}
+
+// Write implements io.Writer
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	if !w.overflow {
+		if w.body.Len()+n > maxIdempotentResponseBytes {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(data[:n])
+		}
+	}
+	return n, err
+}
+
+// WriteString implements io.StringWriter
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// fingerprintReader hashes a request body as the handler reads it. Multipart bodies are hashed part by
+// part, leaving out the boundary that clients pick anew for every request.
+type fingerprintReader struct {
+	io.ReadCloser
+	hash hash.Hash
+	sink io.Writer
+	// pipe feeds the multipart parser, which hashes the parts in the parsed goroutine
+	pipe   *io.PipeWriter
+	parsed chan struct{}
+	once   sync.Once
+	sum    string
+}
+
+// newFingerprintReader wraps the body of a request, starting the hash with its method and path
+func newFingerprintReader(req *http.Request) *fingerprintReader {
+	r := &fingerprintReader{ReadCloser: req.Body, hash: sha256.New()}
+	fmt.Fprintf(r.hash, "%s %s\n", req.Method, req.URL.Path)
+	r.sink = r.hash
+
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return r
+	}
+	pr, pw := io.Pipe()
+	r.sink, r.pipe, r.parsed = pw, pw, make(chan struct{})
+	go func() {
+		defer close(r.parsed)
+		parts := multipart.NewReader(pr, params["boundary"])
+		for {
+			part, err := parts.NextPart()
+			if err != nil {
+				break
+			}
+			fmt.Fprintf(r.hash, "%s\n", part.Header.Get("Content-Disposition"))
+			_, _ = io.Copy(r.hash, part)
+			r.hash.Write([]byte{0})
+		}
+		// What follows a malformed body is not hashed, but is drained so that reads never block
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	return r
+}
+
+// Read implements io.Reader
+func (r *fingerprintReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		_, _ = r.sink.Write(p[:n])
+	}
+	return n, err
+}
+
+// stop ends the hash of a body that is not needed
+func (r *fingerprintReader) stop() {
+	if r.pipe != nil {
+		_ = r.pipe.Close()
+		<-r.parsed
+	}
+}
+
+// Sum reads what the handler left of the body and returns the fingerprint of the request
+func (r *fingerprintReader) Sum() string {
+	r.once.Do(func() {
+		_, _ = io.Copy(io.Discard, r)
+		r.stop()
+		r.sum = hex.EncodeToString(r.hash.Sum(nil))
+	})
+	return r.sum
+}
+
+// memoryIdempotencyStore keeps the records in memory, for single-node deployments
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]*memoryIdempotencyRecord
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// memoryIdempotencyRecord is a record with its expiry
+type memoryIdempotencyRecord struct {
+	record  IdempotencyRecord
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore creates an idempotency store that keeps the records in memory
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{
+		records: make(map[string]*memoryIdempotencyRecord),
+		now:     time.Now,
+	}
+}
+
+// Reserve claims the key when it has no live record
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= idempotencySweepInterval {
+		for k, entry := range s.records {
+			if now.After(entry.expires) {
+				delete(s.records, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	if entry, ok := s.records[key]; ok && !now.After(entry.expires) {
+		return false, nil
+	}
+	s.records[key] = &memoryIdempotencyRecord{expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Get returns a copy of the live record of the key
+func (s *memoryIdempotencyStore) Get(_ context.Context, key string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.records[key]
+	if !ok || s.now().After(entry.expires) {
+		return nil, nil
+	}
+	record := entry.record
+	return &record, nil
+}
+
+// Complete stores the response of the key
+func (s *memoryIdempotencyStore) Complete(_ context.Context, key string, record *IdempotencyRecord,
+	ttl time.Duration,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = &memoryIdempotencyRecord{record: *record, expires: s.now().Add(ttl)}
+	return nil
+}
+
+// Release drops the record of the key
+func (s *memoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
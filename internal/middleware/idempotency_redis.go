@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// idempotencyKeyPrefix prefixes the Redis keys of the idempotency records
+const idempotencyKeyPrefix = "idempotency:"
+
+// redisIdempotencyStore keeps the idempotency records in Redis, shared by all nodes
+type redisIdempotencyStore struct {
+	client *redis.Client
+}
+
+// NewRedisIdempotencyStore creates an idempotency store that keeps the records in Redis
+func NewRedisIdempotencyStore(client *redis.Client) IdempotencyStore {
+	return &redisIdempotencyStore{client: client}
+}
+
+// Reserve claims the key when it has no record
+func (s *redisIdempotencyStore) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	value, err := json.Marshal(&IdempotencyRecord{})
+	if err != nil {
+		return false, err
+	}
+	reserved, err := s.client.SetNX(ctx, idempotencyKeyPrefix+key, value, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	return reserved, nil
+}
+
+// Get returns the record of the key
+func (s *redisIdempotencyStore) Get(ctx context.Context, key string) (*IdempotencyRecord, error) {
+	value, err := s.client.Get(ctx, idempotencyKeyPrefix+key).Bytes()
+	if stderrors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+	var record IdempotencyRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency record: %w", err)
+	}
+	return &record, nil
+}
+
+// Complete stores the response of the key
+func (s *redisIdempotencyStore) Complete(ctx context.Context, key string, record *IdempotencyRecord,
+	ttl time.Duration,
+) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Set(ctx, idempotencyKeyPrefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store idempotency record: %w", err)
+	}
+	return nil
+}
+
+// Release drops the record of the key
+func (s *redisIdempotencyStore) Release(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, idempotencyKeyPrefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := NewMemoryIdempotencyStore()
+	created := 0
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.Use(func(c *gin.Context) {
+		tenantID, _ := strconv.ParseUint(c.GetHeader("X-Tenant-ID"), 10, 64)
+		c.Set(types.TenantIDContextKey.String(), tenantID)
+	})
+	router.POST("/knowledge", Idempotency(&config.Config{}, store), func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil || len(body) == 0 {
+			c.Error(errors.NewBadRequestError("empty body"))
+			return
+		}
+		created++
+		c.JSON(http.StatusCreated, gin.H{"id": created})
+	})
+
+	send := func(tenant, key, contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/knowledge", bytes.NewReader(body))
+		req.Header.Set("X-Tenant-ID", tenant)
+		req.Header.Set("Content-Type", contentType)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	sendJSON := func(tenant, key, body string) *httptest.ResponseRecorder {
+		return send(tenant, key, "application/json", []byte(body))
+	}
+
+	first := sendJSON("1", "key-1", `{"url":"https://example.com"}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, body: %s", first.Code, first.Body.String())
+	}
+	replayed := sendJSON("1", "key-1", `{"url":"https://example.com"}`)
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != first.Body.String() ||
+		replayed.Header().Get(idempotentReplayedHeader) != "true" {
+		t.Errorf("replay = %d %s, want the first response", replayed.Code, replayed.Body.String())
+	}
+	if created != 1 {
+		t.Errorf("created = %d, want the replay not to be processed", created)
+	}
+
+	if w := sendJSON("1", "key-1", `{"url":"https://example.org"}`); w.Code != http.StatusConflict ||
+		errorCode(t, w) != errors.CodeIdempotencyKeyReused {
+		t.Errorf("reused key = %d %s, want %s", w.Code, w.Body.String(), errors.CodeIdempotencyKeyReused)
+	}
+	if w := sendJSON("2", "key-1", `{"url":"https://example.com"}`); w.Code != http.StatusCreated || created != 2 {
+		t.Errorf("other tenant = %d, created = %d, want the key to be scoped per tenant", w.Code, created)
+	}
+	if w := sendJSON("1", "", `{"url":"https://example.com"}`); w.Code != http.StatusCreated || created != 3 {
+		t.Errorf("no key = %d, created = %d, want the request to be processed", w.Code, created)
+	}
+
+	// Failed requests are not kept and can be retried with their key
+	if w := sendJSON("1", "key-2", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("failed request = %d, want 400", w.Code)
+	}
+	if w := sendJSON("1", "key-2", `{"url":"https://example.com"}`); w.Code != http.StatusCreated || created != 4 {
+		t.Errorf("retried request = %d, created = %d, want it to be processed", w.Code, created)
+	}
+
+	// A multipart body sent again with another boundary is the same request
+	upload := func(boundary string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		_ = writer.SetBoundary(boundary)
+		part, _ := writer.CreateFormFile("file", "report.md")
+		_, _ = part.Write([]byte("# Report"))
+		_ = writer.Close()
+		return send("1", "key-3", writer.FormDataContentType(), body.Bytes())
+	}
+	if w := upload("first-boundary"); w.Code != http.StatusCreated {
+		t.Fatalf("upload = %d, body: %s", w.Code, w.Body.String())
+	}
+	if w := upload("second-boundary"); w.Code != http.StatusCreated || created != 5 {
+		t.Errorf("upload retry = %d, created = %d, want the upload to be replayed", w.Code, created)
+	}
+
+	// A key whose first request is still processed
+	if _, err := store.Reserve(context.Background(), "tenant:1:key-4", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if w := sendJSON("1", "key-4", `{}`); w.Code != http.StatusConflict ||
+		errorCode(t, w) != errors.CodeIdempotencyInProgress {
+		t.Errorf("in progress = %d %s, want %s", w.Code, w.Body.String(), errors.CodeIdempotencyInProgress)
+	}
+
+	if w := sendJSON("1", strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("long key = %d, want 400", w.Code)
+	}
+}
+
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryIdempotencyStore().(*memoryIdempotencyStore)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if ok, _ := store.Reserve(ctx, "k", time.Hour); !ok {
+		t.Fatal("first reserve failed")
+	}
+	_ = store.Complete(ctx, "k", &IdempotencyRecord{Completed: true, Status: http.StatusOK}, time.Hour)
+	if ok, _ := store.Reserve(ctx, "k", time.Hour); ok {
+		t.Error("reserved a completed key")
+	}
+	now = now.Add(time.Hour + time.Second)
+	if record, _ := store.Get(ctx, "k"); record != nil {
+		t.Errorf("record = %+v, want the key to have expired", record)
+	}
+	if ok, _ := store.Reserve(ctx, "k", time.Hour); !ok {
+		t.Error("could not reserve an expired key")
+	}
+}
+
+// errorCode returns the code of an error response
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var resp struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("body is not an error response: %v, body: %s", err, w.Body.String())
+	}
+	return resp.Error.Code
+}
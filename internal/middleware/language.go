@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/i18n"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// Language 根据 Accept-Language 请求头选择用户可见消息的语言，存入上下文供错误处理和对话兜底使用
+func Language() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+		c.Header("Content-Language", lang)
+		c.Set(types.LanguageContextKey.String(), lang)
+		c.Request = c.Request.WithContext(i18n.WithLanguage(c.Request.Context(), lang))
+		c.Next()
+	}
+}
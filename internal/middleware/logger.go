@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Tencent/WeKnora/internal/config"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
 	secutils "github.com/Tencent/WeKnora/internal/utils"
@@ -97,13 +98,50 @@ func readRequestBody(c *gin.Context) string {
 	return sanitizeBody(bodyStr)
 }
 
-// RequestID middleware adds a unique request ID to the context
-func RequestID() gin.HandlerFunc {
+const (
+	// RequestIDFormatUUID generates request IDs as canonical UUIDs
+	RequestIDFormatUUID = "uuid"
+	// RequestIDFormatShort generates request IDs as 16 hex characters
+	RequestIDFormatShort = "short"
+
+	// maxRequestIDLength caps the length of client-provided request IDs
+	maxRequestIDLength = 128
+)
+
+// requestIDPattern restricts client-provided request IDs to characters that are safe in logs and headers
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]*$`)
+
+// validRequestID reports whether a client-provided request ID can be used as is
+func validRequestID(requestID string) bool {
+	return len(requestID) <= maxRequestIDLength && requestIDPattern.MatchString(requestID)
+}
+
+// newRequestID generates a request ID in the given format; unknown formats fall back to UUID
+func newRequestID(format string) string {
+	id := uuid.New().String()
+	if format == RequestIDFormatShort {
+		return strings.ReplaceAll(id, "-", "")[:16]
+	}
+	return id
+}
+
+// RequestID middleware adds a unique request ID to the context.
+// A well-formed X-Request-ID from the client is kept, otherwise a new ID is generated
+// in the configured format. The effective ID is always echoed in the response header.
+func RequestID(cfg *config.Config) gin.HandlerFunc {
+	format := RequestIDFormatUUID
+	if cfg != nil && cfg.Server != nil && cfg.Server.RequestIDFormat != "" {
+		format = cfg.Server.RequestIDFormat
+	}
 	return func(c *gin.Context) {
 		// Get request ID from header or generate a new one
 		requestID := c.GetHeader("X-Request-ID")
+		if requestID != "" && !validRequestID(requestID) {
+			logger.Warnf(c, "Rejected malformed X-Request-ID header, generating a new one")
+			requestID = ""
+		}
 		if requestID == "" {
-			requestID = uuid.New().String()
+			requestID = newRequestID(format)
 		}
 		safeRequestID := secutils.SanitizeForLog(requestID)
 		// Set request ID in header
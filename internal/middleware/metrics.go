@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/metrics"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// unmatchedRoute labels requests that match no route, so that arbitrary paths do not become labels
+const unmatchedRoute = "unmatched"
+
+// Metrics middleware records the count, latency and in-flight number of requests. Requests are
+// labeled by route template (e.g. /api/v1/knowledge-bases/:id) rather than path, to keep the label
+// space small, and by the tenant resolved by Auth; unauthenticated requests have an empty tenant.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+		inFlight := m.InFlight.WithLabelValues(route, method)
+		inFlight.Inc()
+		start := time.Now()
+
+		c.Next()
+
+		inFlight.Dec()
+		var tenant string
+		if tenantID := c.GetUint64(types.TenantIDContextKey.String()); tenantID != 0 {
+			tenant = strconv.FormatUint(tenantID, 10)
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.Requests.WithLabelValues(route, method, tenant, status).Inc()
+		m.RequestDuration.WithLabelValues(route, method, tenant, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/Tencent/WeKnora/internal/metrics"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := metrics.New(prometheus.NewRegistry())
+	router := gin.New()
+	router.Use(Metrics(m))
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader("X-API-Key") != "" {
+			c.Set(types.TenantIDContextKey.String(), uint64(10001))
+		}
+	})
+	router.GET("/api/v1/knowledge-bases/:id", func(c *gin.Context) {
+		if got := testutil.ToFloat64(m.InFlight.WithLabelValues("/api/v1/knowledge-bases/:id", "GET")); got != 1 {
+			t.Errorf("in flight = %v, want 1", got)
+		}
+		c.Status(http.StatusOK)
+	})
+
+	for _, id := range []string{"kb-1", "kb-2"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/knowledge-bases/"+id, nil)
+		req.Header.Set("X-API-Key", "sk-test")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/unknown/kb-1", nil))
+
+	// Requests are labeled by route template, not by path
+	if got := testutil.ToFloat64(m.Requests.WithLabelValues("/api/v1/knowledge-bases/:id", "GET", "10001", "200")); got != 2 {
+		t.Errorf("requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.Requests.WithLabelValues(unmatchedRoute, "GET", "", "404")); got != 1 {
+		t.Errorf("unmatched requests = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.Requests); got != 2 {
+		t.Errorf("request series = %d, want 2", got)
+	}
+	if got := testutil.CollectAndCount(m.RequestDuration); got != 2 {
+		t.Errorf("duration series = %d, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.InFlight.WithLabelValues("/api/v1/knowledge-bases/:id", "GET")); got != 0 {
+		t.Errorf("in flight after the requests = %v, want 0", got)
+	}
+}
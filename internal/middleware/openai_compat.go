@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAICompatPathPrefix is the path prefix of the OpenAI-compatible API
+const OpenAICompatPathPrefix = "/openai/v1"
+
+// openAICompatHeaders are the request headers allowed on the OpenAI-compatible API, including those
+// sent by the official OpenAI SDKs
+var openAICompatHeaders = []string{
+	"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Request-ID", "Accept-Language",
+	"OpenAI-Organization", "OpenAI-Project", "OpenAI-Beta",
+	"X-Stainless-Lang", "X-Stainless-Package-Version", "X-Stainless-OS", "X-Stainless-Arch",
+	"X-Stainless-Runtime", "X-Stainless-Runtime-Version", "X-Stainless-Retry-Count", "X-Stainless-Timeout",
+}
+
+// openAICompatMethods are the methods allowed on the OpenAI-compatible API unless its policy lists its own
+var openAICompatMethods = []string{"GET", "POST", "OPTIONS"}
+
+// openAICompatExposeHeaders are the response headers of the OpenAI-compatible API readable by browser clients
+var openAICompatExposeHeaders = []string{"Content-Length", "X-Request-ID"}
+
+// openAICompatEnabled reports whether the OpenAI-compatible API is enabled
+func openAICompatEnabled(cfg *config.Config) bool {
+	return cfg != nil && cfg.OpenAICompat != nil && cfg.OpenAICompat.Enabled
+}
+
+// IsOpenAICompatPath reports whether a request path belongs to the OpenAI-compatible API
+func IsOpenAICompatPath(path string) bool {
+	return path == OpenAICompatPathPrefix || strings.HasPrefix(path, OpenAICompatPathPrefix+"/")
+}
+
+// WithOpenAICompatCORS applies the OpenAI-compatible API's own CORS policy to its routes and the main
+// policy to every other route
+func WithOpenAICompatCORS(cfg *config.Config, mainCORS gin.HandlerFunc) gin.HandlerFunc {
+	if !openAICompatEnabled(cfg) {
+		return mainCORS
+	}
+	openAICORS := cors.New(corsPolicyConfig(cfg.OpenAICompat.CORS,
+		openAICompatMethods, openAICompatHeaders, openAICompatExposeHeaders))
+	return func(c *gin.Context) {
+		if IsOpenAICompatPath(c.Request.URL.Path) {
+			openAICORS(c)
+			return
+		}
+		mainCORS(c)
+	}
+}
+
+// openAICompatBearerAPIKey returns the tenant API key sent as "Authorization: Bearer sk-..." to the
+// OpenAI-compatible API, or an empty string when the request doesn't carry one or mapping is disabled
+func openAICompatBearerAPIKey(c *gin.Context, cfg *config.Config) string {
+	if !openAICompatEnabled(cfg) || !cfg.OpenAICompat.BearerAPIKeys || !IsOpenAICompatPath(c.Request.URL.Path) {
+		return ""
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+)
+
+func TestOpenAICompatBearerAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	enabled := &config.Config{OpenAICompat: &config.OpenAICompatConfig{Enabled: true, BearerAPIKeys: true}}
+
+	tests := []struct {
+		name   string
+		cfg    *config.Config
+		path   string
+		header string
+		want   string
+	}{
+		{name: "bearer key", cfg: enabled, path: "/openai/v1/chat/completions", header: "Bearer sk-123", want: "sk-123"},
+		{name: "prefix itself", cfg: enabled, path: "/openai/v1", header: "Bearer sk-123 ", want: "sk-123"},
+		{name: "main api", cfg: enabled, path: "/api/v1/sessions", header: "Bearer sk-123"},
+		{name: "path sharing the prefix", cfg: enabled, path: "/openai/v10/models", header: "Bearer sk-123"},
+		{name: "not a bearer token", cfg: enabled, path: "/openai/v1/models", header: "Basic c2stMTIz"},
+		{name: "no header", cfg: enabled, path: "/openai/v1/models"},
+		{
+			name:   "bearer keys disabled",
+			cfg:    &config.Config{OpenAICompat: &config.OpenAICompatConfig{Enabled: true}},
+			path:   "/openai/v1/models",
+			header: "Bearer sk-123",
+		},
+		{name: "not configured", cfg: &config.Config{}, path: "/openai/v1/models", header: "Bearer sk-123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				c.Request.Header.Set("Authorization", tt.header)
+			}
+			if got := openAICompatBearerAPIKey(c, tt.cfg); got != tt.want {
+				t.Errorf("api key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithOpenAICompatCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// mainCORS marks the requests handled by the main policy
+	mainCORS := func(c *gin.Context) {
+		c.Header("X-Main-CORS", "1")
+		c.Next()
+	}
+
+	tests := []struct {
+		name            string
+		cfg             *config.Config
+		path            string
+		origin          string
+		wantMain        bool
+		wantAllowOrigin string
+		wantCredentials bool
+	}{
+		{
+			name:     "disabled uses the main policy",
+			cfg:      &config.Config{},
+			path:     "/openai/v1/models",
+			origin:   "https://app.example.com",
+			wantMain: true,
+		},
+		{
+			name:     "main api uses the main policy",
+			cfg:      &config.Config{OpenAICompat: &config.OpenAICompatConfig{Enabled: true}},
+			path:     "/api/v1/sessions",
+			origin:   "https://app.example.com",
+			wantMain: true,
+		},
+		{
+			name:            "any origin by default",
+			cfg:             &config.Config{OpenAICompat: &config.OpenAICompatConfig{Enabled: true}},
+			path:            "/openai/v1/models",
+			origin:          "https://app.example.com",
+			wantAllowOrigin: "*",
+		},
+		{
+			name: "credentials with an origin list",
+			cfg: &config.Config{OpenAICompat: &config.OpenAICompatConfig{Enabled: true, CORS: &config.CORSConfig{
+				AllowOrigins: []string{"https://*.example.com"}, AllowCredentials: true,
+			}}},
+			path:            "/openai/v1/models",
+			origin:          "https://app.example.com",
+			wantAllowOrigin: "https://app.example.com",
+			wantCredentials: true,
+		},
+		{
+			name: "no credentials for any origin",
+			cfg: &config.Config{OpenAICompat: &config.OpenAICompatConfig{Enabled: true, CORS: &config.CORSConfig{
+				AllowOrigins: []string{"*"}, AllowCredentials: true,
+			}}},
+			path:            "/openai/v1/models",
+			origin:          "https://app.example.com",
+			wantAllowOrigin: "*",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(WithOpenAICompatCORS(tt.cfg, mainCORS))
+			router.GET(tt.path, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("X-Main-CORS") == "1"; got != tt.wantMain {
+				t.Errorf("main policy applied = %v, want %v", got, tt.wantMain)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllowOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCredentials {
+				t.Errorf("credentials allowed = %v, want %v", got, tt.wantCredentials)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/metrics"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// errResponseTooLarge is returned to handlers writing past the response size limit of the tenant
+var errResponseTooLarge = stderrors.New("response exceeds the payload size limit of the tenant")
+
+// PayloadLimit middleware records the request and response body sizes of every tenant resolved by Auth,
+// and enforces the tenant's payload size limits. Requests declaring a larger body are answered with 413
+// before the handler runs, and so are requests whose body turns out larger while the handler reads it. A
+// response over the limit is replaced with a 413 when nothing was sent yet, and cut otherwise.
+func PayloadLimit(cfg *config.Config, m *metrics.Metrics) gin.HandlerFunc {
+	var defaults config.PayloadLimitConfig
+	if cfg != nil && cfg.PayloadLimit != nil {
+		defaults = *cfg.PayloadLimit
+	}
+
+	return func(c *gin.Context) {
+		tenantID := c.GetUint64(types.TenantIDContextKey.String())
+		if tenantID == 0 {
+			c.Next()
+			return
+		}
+		value, _ := c.Get(types.TenantInfoContextKey.String())
+		tenant, _ := value.(*types.Tenant)
+		maxRequest, maxResponse := tenant.PayloadLimits(defaults.MaxRequestBytes, defaults.MaxResponseBytes)
+		tenantLabel := strconv.FormatUint(tenantID, 10)
+
+		if maxRequest > 0 && c.Request.ContentLength > maxRequest {
+			logger.Warnf(c.Request.Context(), "Request payload too large, tenant: %d, size: %d, limit: %d",
+				tenantID, c.Request.ContentLength, maxRequest)
+			m.PayloadRejections.WithLabelValues(tenantLabel, "request").Inc()
+			m.RequestSize.WithLabelValues(tenantLabel).Observe(float64(c.Request.ContentLength))
+			m.Payloads.Observe(tenantID, c.Request.ContentLength, 0, true, false)
+			abortWithError(c, requestTooLargeError(tenantID, c.Request.ContentLength, maxRequest))
+			return
+		}
+
+		body := &countingReader{ReadCloser: c.Request.Body}
+		c.Request.Body = body
+		if maxRequest > 0 {
+			c.Request.Body = http.MaxBytesReader(c.Writer, body, maxRequest)
+		}
+		writer := &payloadLimitWriter{ResponseWriter: c.Writer, c: c, tenantID: tenantID, limit: maxResponse}
+		c.Writer = writer
+
+		c.Next()
+
+		// Error responses written by the outer middleware are not limited
+		c.Writer = writer.ResponseWriter
+		requestBytes := c.Request.ContentLength
+		if requestBytes < 0 {
+			requestBytes = body.n
+		}
+		requestRejected := maxRequest > 0 && body.n > maxRequest
+		if requestRejected {
+			logger.Warnf(c.Request.Context(), "Request payload too large, tenant: %d, limit: %d", tenantID, maxRequest)
+			m.PayloadRejections.WithLabelValues(tenantLabel, "request").Inc()
+			// The handler failed to read the body, report why instead of its error
+			if len(c.Errors) > 0 && !c.Writer.Written() {
+				c.Errors = c.Errors[:0]
+				_ = c.Error(requestTooLargeError(tenantID, body.n, maxRequest))
+			}
+		}
+		if writer.exceeded {
+			logger.Warnf(c.Request.Context(), "Response payload too large, tenant: %d, limit: %d", tenantID, maxResponse)
+			m.PayloadRejections.WithLabelValues(tenantLabel, "response").Inc()
+			// The response was already replaced or cut, nothing else may be written
+			c.Errors = c.Errors[:0]
+		}
+		m.RequestSize.WithLabelValues(tenantLabel).Observe(float64(requestBytes))
+		m.ResponseSize.WithLabelValues(tenantLabel).Observe(float64(writer.size))
+		m.Payloads.Observe(tenantID, requestBytes, writer.size, requestRejected, writer.exceeded)
+	}
+}
+
+// requestTooLargeError reports a request body over the limit of the tenant
+func requestTooLargeError(tenantID uint64, size, limit int64) *errors.AppError {
+	return errors.NewPayloadTooLargeError(errors.CodeRequestTooLarge,
+		fmt.Sprintf("Request payload of %d bytes exceeds the limit of %d bytes of tenant %d", size, limit, tenantID)).
+		WithDetails(map[string]interface{}{"size": size, "limit": limit})
+}
+
+// responseTooLargeError reports a response body over the limit of the tenant
+func responseTooLargeError(tenantID uint64, size, limit int64) *errors.AppError {
+	return errors.NewPayloadTooLargeError(errors.CodeResponseTooLarge,
+		fmt.Sprintf("Response payload of at least %d bytes exceeds the limit of %d bytes of tenant %d", size, limit, tenantID)).
+		WithDetails(map[string]interface{}{"size": size, "limit": limit})
+}
+
+// countingReader counts the bytes read from a request body, up to 1 byte past a MaxBytesReader limit
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+// Read implements io.Reader
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// payloadLimitWriter counts the bytes of a response body and refuses writes past the limit of the tenant
+type payloadLimitWriter struct {
+	gin.ResponseWriter
+	c        *gin.Context
+	tenantID uint64
+	limit    int64 // 0 means no limit
+	size     int64 // Bytes of the body written by the handler
+	exceeded bool
+}
+
+// Write implements io.Writer
+func (w *payloadLimitWriter) Write(data []byte) (int, error) {
+	if w.exceeded {
+		return 0, errResponseTooLarge
+	}
+	if w.limit > 0 && w.size+int64(len(data)) > w.limit {
+		w.exceeded = true
+		w.size += int64(len(data))
+		if !w.ResponseWriter.Written() {
+			w.writeTooLarge()
+		}
+		return 0, errResponseTooLarge
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.size += int64(n)
+	return n, err
+}
+
+// WriteString implements io.StringWriter
+func (w *payloadLimitWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// writeTooLarge replaces a response that was not sent yet with the standard 413 error response
+func (w *payloadLimitWriter) writeTooLarge() {
+	body, err := json.Marshal(gin.H{
+		"success": false,
+		"error":   localizedBody(w.c, responseTooLargeError(w.tenantID, w.size, w.limit)),
+	})
+	if err != nil {
+		return
+	}
+	header := w.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(http.StatusRequestEntityTooLarge)
+	_, _ = w.ResponseWriter.Write(body)
+}
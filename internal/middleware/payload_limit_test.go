@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/metrics"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestPayloadLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{PayloadLimit: &config.PayloadLimitConfig{MaxRequestBytes: 16, MaxResponseBytes: 64}}
+	tenants := map[string]*types.Tenant{
+		"10001": {ID: 10001},
+		"10002": {ID: 10002, PayloadLimitConfig: &types.PayloadLimitConfig{MaxRequestBytes: 1024}},
+		"10003": {ID: 10003, PayloadLimitConfig: &types.PayloadLimitConfig{Disabled: true}},
+	}
+
+	m := metrics.New(prometheus.NewRegistry())
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.Use(func(c *gin.Context) {
+		tenant := tenants[c.GetHeader("X-Tenant-ID")]
+		c.Set(types.TenantIDContextKey.String(), tenant.ID)
+		c.Set(types.TenantInfoContextKey.String(), tenant)
+	})
+	router.Use(PayloadLimit(cfg, m))
+	router.POST("/echo", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"echo": string(body)})
+	})
+	router.GET("/large", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": strings.Repeat("x", 100)})
+	})
+
+	tests := []struct {
+		name     string
+		tenant   string
+		request  *http.Request
+		wantCode int
+		wantErr  string
+	}{
+		{name: "within limits", tenant: "10001",
+			request: httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("hello")), wantCode: http.StatusOK},
+		{name: "declared request too large", tenant: "10001",
+			request:  httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(strings.Repeat("a", 32))),
+			wantCode: http.StatusRequestEntityTooLarge, wantErr: errors.CodeRequestTooLarge},
+		{name: "undeclared request too large", tenant: "10001",
+			request:  undeclaredLength(httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(strings.Repeat("a", 32)))),
+			wantCode: http.StatusRequestEntityTooLarge, wantErr: errors.CodeRequestTooLarge},
+		{name: "response too large", tenant: "10001",
+			request: httptest.NewRequest(http.MethodGet, "/large", nil), wantCode: http.StatusRequestEntityTooLarge,
+			wantErr: errors.CodeResponseTooLarge},
+		{name: "raised request limit", tenant: "10002",
+			request: httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(strings.Repeat("a", 32))), wantCode: http.StatusOK},
+		{name: "exempted tenant", tenant: "10003",
+			request: httptest.NewRequest(http.MethodGet, "/large", nil), wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.Header.Set("X-Tenant-ID", tt.tenant)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, tt.request)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d, body: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantErr == "" {
+				return
+			}
+			var resp struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("body is not a single error response: %v, body: %s", err, w.Body.String())
+			}
+			if resp.Error.Code != tt.wantErr {
+				t.Errorf("code = %q, want %q", resp.Error.Code, tt.wantErr)
+			}
+		})
+	}
+
+	if got := testutil.ToFloat64(m.PayloadRejections.WithLabelValues("10001", "request")); got != 2 {
+		t.Errorf("rejected requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.PayloadRejections.WithLabelValues("10001", "response")); got != 1 {
+		t.Errorf("rejected responses = %v, want 1", got)
+	}
+	stats := m.Payloads.Stats(10001)
+	if stats.Requests != 4 || stats.RejectedRequests != 2 || stats.RejectedResponses != 1 || stats.LargestRequestBytes != 32 {
+		t.Errorf("stats = %+v, want 4 requests with 2 rejected requests and 1 rejected response", stats)
+	}
+}
+
+// undeclaredLength makes the body size of a request unknown, as for chunked requests
+func undeclaredLength(req *http.Request) *http.Request {
+	req.ContentLength = -1
+	return req
+}
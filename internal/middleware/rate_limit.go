@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+const (
+	// defaultRateLimitRequestsPerSecond is used when rate_limit.requests_per_second is not set
+	defaultRateLimitRequestsPerSecond = 20
+	// defaultRateLimitBurst is used when rate_limit.burst is not set
+	defaultRateLimitBurst = 40
+	// rateLimitSweepInterval is how often the in-memory store drops buckets that have refilled
+	rateLimitSweepInterval = time.Minute
+)
+
+// RateLimitRule is a token bucket: RequestsPerSecond tokens are added per second up to Burst,
+// and every request takes one
+type RateLimitRule struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// RateLimitResult is the outcome of taking a token
+type RateLimitResult struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	// RetryAfter is the time until the next token, set when the request is not allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the token buckets of the rate limiter by key
+type RateLimitStore interface {
+	// Take takes a token from the bucket of the key, creating a full bucket when there is none
+	Take(ctx context.Context, key string, limit RateLimitRule) (RateLimitResult, error)
+}
+
+// RateLimit middleware limits the request rate of every tenant with a token bucket keyed on the tenant
+// resolved by Auth. Tenants can be given other limits, or be exempted, through their rate limit override.
+// Requests over the limit are answered with 429 and a Retry-After header. When the store fails the
+// request is let through.
+func RateLimit(cfg *config.Config, store RateLimitStore) gin.HandlerFunc {
+	if cfg == nil || cfg.RateLimit == nil || !cfg.RateLimit.Enabled || store == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	defaults := RateLimitRule{
+		RequestsPerSecond: cfg.RateLimit.RequestsPerSecond,
+		Burst:             cfg.RateLimit.Burst,
+	}
+	if defaults.RequestsPerSecond <= 0 {
+		defaults.RequestsPerSecond = defaultRateLimitRequestsPerSecond
+	}
+	if defaults.Burst <= 0 {
+		defaults.Burst = defaultRateLimitBurst
+	}
+
+	return func(c *gin.Context) {
+		tenantID := c.GetUint64(types.TenantIDContextKey.String())
+		if tenantID == 0 {
+			c.Next()
+			return
+		}
+		value, _ := c.Get(types.TenantInfoContextKey.String())
+		tenant, _ := value.(*types.Tenant)
+		limit, limited := tenantRateLimit(defaults, tenant)
+		if !limited {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		result, err := store.Take(ctx, "tenant:"+strconv.FormatUint(tenantID, 10), limit)
+		if err != nil {
+			logger.Warnf(ctx, "Rate limit store failed, letting the request through: %v", err)
+			c.Next()
+			return
+		}
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if result.Allowed {
+			c.Next()
+			return
+		}
+
+		retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		logger.Warnf(ctx, "Rate limit exceeded, tenant: %d, retry after: %ds", tenantID, retryAfter)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.Error(errors.NewTooManyRequestsError("Rate limit exceeded").WithDetails(map[string]interface{}{
+			"requests_per_second": limit.RequestsPerSecond,
+			"burst":               limit.Burst,
+			"retry_after":         retryAfter,
+		}))
+		c.Abort()
+	}
+}
+
+// tenantRateLimit applies the override of the tenant to the default limit.
+// Returns false when the tenant is exempted.
+func tenantRateLimit(defaults RateLimitRule, tenant *types.Tenant) (RateLimitRule, bool) {
+	if tenant == nil || tenant.RateLimitConfig == nil {
+		return defaults, true
+	}
+	override := tenant.RateLimitConfig
+	if override.Disabled {
+		return defaults, false
+	}
+	limit := defaults
+	if override.RequestsPerSecond > 0 {
+		limit.RequestsPerSecond = override.RequestsPerSecond
+	}
+	if override.Burst > 0 {
+		limit.Burst = override.Burst
+	}
+	return limit, true
+}
+
+// memoryRateLimitStore keeps the token buckets in memory, for single-node deployments
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// tokenBucket is the state of a bucket at its last update
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will have refilled, after which it can be dropped
+	full time.Time
+}
+
+// NewMemoryRateLimitStore creates a rate limit store that keeps the buckets in memory
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Take takes a token from the bucket of the key
+func (s *memoryRateLimitStore) Take(_ context.Context, key string, limit RateLimitRule) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= rateLimitSweepInterval {
+		for k, bucket := range s.buckets {
+			if now.After(bucket.full) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = bucket
+	}
+	tokens, result := takeToken(bucket.tokens, now.Sub(bucket.updated), limit)
+	bucket.tokens = tokens
+	bucket.updated = now
+	bucket.full = now.Add(secondsToDuration((float64(limit.Burst) - tokens) / limit.RequestsPerSecond))
+	return result, nil
+}
+
+// takeToken refills a bucket holding tokens for the elapsed time and takes a token.
+// Returns the tokens left and the result.
+func takeToken(tokens float64, elapsed time.Duration, limit RateLimitRule) (float64, RateLimitResult) {
+	if elapsed > 0 {
+		tokens += elapsed.Seconds() * limit.RequestsPerSecond
+	}
+	tokens = math.Min(tokens, float64(limit.Burst))
+	if tokens >= 1 {
+		tokens--
+		return tokens, RateLimitResult{Allowed: true, Remaining: int(tokens)}
+	}
+	return tokens, RateLimitResult{RetryAfter: secondsToDuration((1 - tokens) / limit.RequestsPerSecond)}
+}
+
+// secondsToDuration converts fractional seconds to a duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// rateLimitKeyPrefix prefixes the Redis keys of the token buckets
+const rateLimitKeyPrefix = "rate_limit:"
+
+// rateLimitScript refills and takes a token from a bucket atomically. The Redis clock is used, so that
+// the nodes sharing the buckets need not agree on the time. Buckets expire once they have refilled.
+// Returns whether the request is allowed and the tokens left.
+var rateLimitScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+	tokens = burst
+	updated = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - updated) / 1000 * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// redisRateLimitStore keeps the token buckets in Redis, shared by all nodes
+type redisRateLimitStore struct {
+	client *redis.Client
+}
+
+// NewRedisRateLimitStore creates a rate limit store that keeps the buckets in Redis
+func NewRedisRateLimitStore(client *redis.Client) RateLimitStore {
+	return &redisRateLimitStore{client: client}
+}
+
+// Take takes a token from the bucket of the key
+func (s *redisRateLimitStore) Take(ctx context.Context, key string, limit RateLimitRule) (RateLimitResult, error) {
+	reply, err := rateLimitScript.Run(ctx, s.client,
+		[]string{rateLimitKeyPrefix + key}, limit.RequestsPerSecond, limit.Burst).Slice()
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	if len(reply) != 2 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+	allowed, _ := reply[0].(int64)
+	text, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit tokens %q: %w", text, err)
+	}
+
+	if allowed == 1 {
+		return RateLimitResult{Allowed: true, Remaining: int(tokens)}, nil
+	}
+	return RateLimitResult{
+		RetryAfter: time.Duration((1 - tokens) / limit.RequestsPerSecond * float64(time.Second)),
+	}, nil
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryRateLimitStore().(*memoryRateLimitStore)
+	store.now = func() time.Time { return now }
+	limit := RateLimitRule{RequestsPerSecond: 2, Burst: 3}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		result, _ := store.Take(ctx, "a", limit)
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("burst request: %+v, want allowed with %d remaining", result, i)
+		}
+	}
+	result, _ := store.Take(ctx, "a", limit)
+	if result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("over the burst: %+v, want denied with retry after 500ms", result)
+	}
+	// Buckets are independent per key
+	if result, _ := store.Take(ctx, "b", limit); !result.Allowed {
+		t.Fatalf("other key: %+v, want allowed", result)
+	}
+
+	now = now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		if result, _ := store.Take(ctx, "a", limit); !result.Allowed {
+			t.Fatalf("refilled request %d: %+v, want allowed", i, result)
+		}
+	}
+	if result, _ := store.Take(ctx, "a", limit); result.Allowed {
+		t.Fatalf("refill exhausted: %+v, want denied", result)
+	}
+
+	// Buckets that have refilled are dropped
+	now = now.Add(time.Hour)
+	store.Take(ctx, "c", limit)
+	if _, ok := store.buckets["a"]; ok {
+		t.Errorf("refilled bucket was not dropped")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{RateLimit: &config.RateLimitConfig{Enabled: true, RequestsPerSecond: 1, Burst: 2}}
+
+	tests := []struct {
+		name       string
+		override   *types.RateLimitConfig
+		requests   int
+		wantStatus int
+	}{
+		{name: "within the burst", requests: 2, wantStatus: http.StatusOK},
+		{name: "over the burst", requests: 3, wantStatus: http.StatusTooManyRequests},
+		{name: "tenant override", override: &types.RateLimitConfig{Burst: 5}, requests: 5, wantStatus: http.StatusOK},
+		{name: "exempted tenant", override: &types.RateLimitConfig{Disabled: true}, requests: 10, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := &types.Tenant{ID: 10001, RateLimitConfig: tt.override}
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.Use(func(c *gin.Context) {
+				c.Set(types.TenantIDContextKey.String(), tenant.ID)
+				c.Set(types.TenantInfoContextKey.String(), tenant)
+			})
+			router.Use(RateLimit(cfg, NewMemoryRateLimitStore()))
+			router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			var rec *httptest.ResponseRecorder
+			for i := 0; i < tt.requests; i++ {
+				rec = httptest.NewRecorder()
+				router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusTooManyRequests {
+				return
+			}
+
+			if got := rec.Header().Get("Retry-After"); got != "1" {
+				t.Errorf("Retry-After = %q, want 1", got)
+			}
+			var resp struct {
+				Error struct {
+					Code    string `json:"code"`
+					Details struct {
+						RequestsPerSecond float64 `json:"requests_per_second"`
+						Burst             int     `json:"burst"`
+						RetryAfter        int     `json:"retry_after"`
+					} `json:"details"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+			}
+			if resp.Error.Code != errors.CodeTooManyRequests {
+				t.Errorf("code = %s, want %s", resp.Error.Code, errors.CodeTooManyRequests)
+			}
+			if d := resp.Error.Details; d.RequestsPerSecond != 1 || d.Burst != 2 || d.RetryAfter != 1 {
+				t.Errorf("details = %+v", d)
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"runtime/debug"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/errors"
 )
 
 // Recovery is a middleware that recovers from panics
@@ -22,10 +24,7 @@ func Recovery() gin.HandlerFunc {
 				log.Printf("[PANIC] %s | %v | %s", requestID, err, stacktrace)
 
 				// 返回500错误
-				c.AbortWithStatusJSON(500, gin.H{
-					"error":   "Internal Server Error",
-					"message": fmt.Sprintf("%v", err),
-				})
+				abortWithError(c, errors.NewInternalServerError(fmt.Sprintf("%v", err)))
 			}
 		}()
 
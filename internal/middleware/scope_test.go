@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeScopedTenantService knows tenant 1 with a read-only API key, tenant 2 with a write-only one
+// and tenant 3 with a key predating scopes; the key of tenant 7, whose user logs in with a token, has no
+// scope. Their API keys are "key-<id>"
+type fakeScopedTenantService struct {
+	fakeAuthTenantService
+}
+
+func (fakeScopedTenantService) GetTenantByID(ctx context.Context, id uint64) (*types.Tenant, error) {
+	scopes := map[uint64]types.StringArray{
+		1: {types.APIKeyScopeKnowledgeRead},
+		2: {types.APIKeyScopeKnowledgeWrite},
+		3: nil,
+		7: {},
+	}
+	tenantScopes, ok := scopes[id]
+	if !ok {
+		return nil, errors.New("tenant not found")
+	}
+	return &types.Tenant{ID: id, APIKey: fmt.Sprintf("key-%d", id), APIKeyScopes: tenantScopes}, nil
+}
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Tenant: &config.TenantConfig{}}
+
+	router := gin.New()
+	router.Use(Auth(fakeScopedTenantService{}, fakeAuthenticator{}, nil, nil, cfg))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/knowledge-bases", RequireScope(types.APIKeyScopeKnowledgeRead), ok)
+	router.POST("/knowledge-bases", RequireScope(types.APIKeyScopeKnowledgeWrite), ok)
+
+	tests := []struct {
+		name       string
+		method     string
+		headers    map[string]string
+		wantStatus int
+		wantScope  string
+	}{
+		{"read-only key reads", http.MethodGet, map[string]string{"X-API-Key": "key-1"}, http.StatusOK, ""},
+		{"read-only key writes", http.MethodPost, map[string]string{"X-API-Key": "key-1"},
+			http.StatusForbidden, types.APIKeyScopeKnowledgeWrite},
+		{"write-only key writes", http.MethodPost, map[string]string{"X-API-Key": "key-2"}, http.StatusOK, ""},
+		{"write-only key reads", http.MethodGet, map[string]string{"X-API-Key": "key-2"},
+			http.StatusForbidden, types.APIKeyScopeKnowledgeRead},
+		{"key predating scopes writes", http.MethodPost, map[string]string{"X-API-Key": "key-3"}, http.StatusOK, ""},
+		{"key without scopes reads", http.MethodGet, map[string]string{"X-API-Key": "key-7"},
+			http.StatusForbidden, types.APIKeyScopeKnowledgeRead},
+		{"user token is not scoped", http.MethodPost, map[string]string{"Authorization": "Bearer user-token"},
+			http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/knowledge-bases", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantScope == "" {
+				return
+			}
+			var resp struct {
+				Error struct {
+					Code    string `json:"code"`
+					Details struct {
+						RequiredScope string `json:"required_scope"`
+					} `json:"details"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error.Code != werrors.CodeInsufficientScope || resp.Error.Details.RequiredScope != tt.wantScope {
+				t.Errorf("error = %+v, want %s requiring %s", resp.Error, werrors.CodeInsufficientScope, tt.wantScope)
+			}
+		})
+	}
+}
+
+// fakeRoleService gives every user the same role
+type fakeRoleService struct {
+	interfaces.RoleService
+	role string
+}
+
+func (s fakeRoleService) ResolveRole(ctx context.Context, tenantID uint64, user *types.User) (string, error) {
+	return s.role, nil
+}
+
+func TestRequireScopeUserRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Tenant: &config.TenantConfig{}}
+
+	tests := []struct {
+		role       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{types.RoleViewer, http.MethodGet, "/knowledge-bases", http.StatusOK},
+		{types.RoleViewer, http.MethodPost, "/knowledge-bases", http.StatusForbidden},
+		{types.RoleEditor, http.MethodPost, "/knowledge-bases", http.StatusOK},
+		{types.RoleEditor, http.MethodPut, "/models", http.StatusForbidden},
+		{types.RoleAdmin, http.MethodPut, "/models", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			router := gin.New()
+			router.Use(Auth(fakeScopedTenantService{}, fakeAuthenticator{}, fakeRoleService{role: tt.role}, nil, cfg))
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+			router.GET("/knowledge-bases", RequireScope(types.APIKeyScopeKnowledgeRead), ok)
+			router.POST("/knowledge-bases", RequireScope(types.APIKeyScopeKnowledgeWrite), ok)
+			router.PUT("/models", RequireScope(types.APIKeyScopeAdmin), ok)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer user-token")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusForbidden {
+				return
+			}
+			var resp struct {
+				Error struct {
+					Code    string `json:"code"`
+					Details struct {
+						Role string `json:"role"`
+					} `json:"details"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error.Code != werrors.CodeInsufficientRole || resp.Error.Details.Role != tt.role {
+				t.Errorf("error = %+v, want %s for role %s", resp.Error, werrors.CodeInsufficientRole, tt.role)
+			}
+		})
+	}
+
+	// API keys have no role and keep being checked against their scopes only
+	router := gin.New()
+	router.Use(Auth(fakeScopedTenantService{}, fakeAuthenticator{}, fakeRoleService{role: types.RoleViewer}, nil, cfg))
+	router.POST("/knowledge-bases", RequireScope(types.APIKeyScopeKnowledgeWrite),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+	req := httptest.NewRequest(http.MethodPost, "/knowledge-bases", nil)
+	req.Header.Set("X-API-Key", "key-2")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("write-only key status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+// fakeAPIKeyService knows the minted keys "wk-search" and "wk-ingest" of tenant 1
+type fakeAPIKeyService struct {
+	interfaces.APIKeyService
+}
+
+func (fakeAPIKeyService) Authenticate(ctx context.Context, key string) (*types.APIKey, error) {
+	scopes := map[string]types.StringArray{
+		"wk-search": {types.APIKeyScopeKnowledgeSearch},
+		"wk-ingest": {types.APIKeyScopeKnowledgeIngest},
+	}
+	keyScopes, ok := scopes[key]
+	if !ok {
+		return nil, errors.New("invalid, expired or revoked API key")
+	}
+	return &types.APIKey{ID: key, TenantID: 1, Scopes: keyScopes}, nil
+}
+
+func TestRequireScopeMintedAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Tenant: &config.TenantConfig{}}
+
+	router := gin.New()
+	router.Use(Auth(fakeScopedTenantService{}, fakeAuthenticator{}, nil, fakeAPIKeyService{}, cfg))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/knowledge-bases", RequireScope(types.APIKeyScopeKnowledgeRead), ok)
+	router.GET("/knowledge-search",
+		RequireScope(types.APIKeyScopeKnowledgeRead, types.APIKeyScopeKnowledgeSearch), ok)
+	router.POST("/knowledge",
+		RequireScope(types.APIKeyScopeKnowledgeWrite, types.APIKeyScopeKnowledgeIngest), ok)
+
+	tests := []struct {
+		key        string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"wk-search", http.MethodGet, "/knowledge-search", http.StatusOK},
+		{"wk-search", http.MethodGet, "/knowledge-bases", http.StatusForbidden},
+		{"wk-search", http.MethodPost, "/knowledge", http.StatusForbidden},
+		{"wk-ingest", http.MethodPost, "/knowledge", http.StatusOK},
+		{"wk-ingest", http.MethodGet, "/knowledge-search", http.StatusForbidden},
+		{"wk-revoked", http.MethodGet, "/knowledge-search", http.StatusUnauthorized},
+		{"key-1", http.MethodGet, "/knowledge-search", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("X-API-Key", tt.key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	// A route accepting narrower scopes names them in the error
+	req := httptest.NewRequest(http.MethodGet, "/knowledge-search", nil)
+	req.Header.Set("X-API-Key", "wk-ingest")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp struct {
+		Error struct {
+			Details struct {
+				RequiredScope  string   `json:"required_scope"`
+				AcceptedScopes []string `json:"accepted_scopes"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error.Details.RequiredScope != types.APIKeyScopeKnowledgeRead || len(resp.Error.Details.AcceptedScopes) != 2 {
+		t.Errorf("details = %+v, want knowledge:read required and the search scope accepted", resp.Error.Details)
+	}
+}
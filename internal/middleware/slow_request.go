@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultSlowRequestThreshold is used when slow_request_log.threshold is not set
+	defaultSlowRequestThreshold = 5 * time.Second
+	// maxSlowRequestQueryLength caps the query text logged for a slow request, in characters
+	maxSlowRequestQueryLength = 1000
+)
+
+// SlowRequestLog middleware logs the route, tenant, stage timings and query of requests slower than
+// the configured threshold. The query text is redacted to its length unless include_query is set.
+func SlowRequestLog(cfg *config.Config) gin.HandlerFunc {
+	if cfg == nil || cfg.SlowRequestLog == nil || !cfg.SlowRequestLog.Enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+	threshold := cfg.SlowRequestLog.Threshold
+	if threshold <= 0 {
+		threshold = defaultSlowRequestThreshold
+	}
+	includeQuery := cfg.SlowRequestLog.IncludeQuery
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		ctx, timings := types.WithStageTimings(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		latency := time.Since(start)
+		if latency < threshold {
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		fields := map[string]interface{}{
+			"method":      c.Request.Method,
+			"route":       secutils.SanitizeForLog(route),
+			"status_code": c.Writer.Status(),
+			"latency":     latency.String(),
+			"threshold":   threshold.String(),
+			"tenant_id":   c.GetUint64(types.TenantIDContextKey.String()),
+		}
+		if stages := timings.String(); stages != "" {
+			fields["stages"] = stages
+		}
+		if query := timings.Query(); query != "" {
+			fields["query_length"] = utf8.RuneCountInString(query)
+			if includeQuery {
+				fields["query"] = secutils.SanitizeForLog(truncateQuery(query))
+			} else {
+				fields["query"] = "[redacted]"
+			}
+		}
+		logger.GetLogger(c).WithFields(fields).Warn("Slow request")
+	}
+}
+
+// truncateQuery cuts a query to the logged length
+func truncateQuery(query string) string {
+	runes := []rune(query)
+	if len(runes) <= maxSlowRequestQueryLength {
+		return query
+	}
+	return string(runes[:maxSlowRequestQueryLength]) + "... [truncated]"
+}
@@ -0,0 +1,359 @@
+// Package calllog captures raw model provider HTTP calls for debugging.
+//
+// Capturing is off unless a Recorder is installed with SetRecorder. Credentials
+// are always redacted; prompt and completion text is redacted when configured.
+package calllog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+)
+
+// DefaultMaxBodyBytes is the body capture limit used when none is configured
+const DefaultMaxBodyBytes = 64 * 1024
+
+// Entry is a single captured provider call
+type Entry struct {
+	RequestID       string            `json:"request_id"`
+	TenantID        uint64            `json:"tenant_id"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     string            `json:"request_body"`
+	StatusCode      int               `json:"status_code"`
+	ResponseHeaders map[string]string `json:"response_headers"`
+	ResponseBody    string            `json:"response_body"`
+	Truncated       bool              `json:"truncated"`
+	Error           string            `json:"error,omitempty"`
+	DurationMs      int64             `json:"duration_ms"`
+	Timestamp       time.Time         `json:"timestamp"`
+}
+
+// Recorder decides which calls are captured and persists them
+type Recorder interface {
+	// Enabled reports whether calls made on behalf of the tenant should be captured
+	Enabled(tenantID uint64) bool
+	// RedactContent reports whether prompt and completion text should be redacted
+	RedactContent() bool
+	// MaxBodyBytes returns the maximum number of body bytes captured per direction
+	MaxBodyBytes() int
+	// Record persists a captured call
+	Record(ctx context.Context, entry *Entry)
+}
+
+type recorderHolder struct {
+	recorder Recorder
+}
+
+var current atomic.Value
+
+// SetRecorder installs the recorder used by all wrapped clients; nil disables capturing
+func SetRecorder(r Recorder) {
+	current.Store(recorderHolder{recorder: r})
+}
+
+func getRecorder() Recorder {
+	h, _ := current.Load().(recorderHolder)
+	return h.recorder
+}
+
+// NewHTTPClient returns an HTTP client whose calls are captured when logging is enabled
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: &Transport{}}
+}
+
+// Transport is an http.RoundTripper that captures requests and responses
+type Transport struct {
+	// Base is the underlying transport; http.DefaultTransport is used when nil
+	Base http.RoundTripper
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := getRecorder()
+	if rec == nil {
+		return t.base().RoundTrip(req)
+	}
+	ctx := req.Context()
+	tenantID, _ := ctx.Value(types.TenantIDContextKey).(uint64)
+	requestID, _ := ctx.Value(types.RequestIDContextKey).(string)
+	if requestID == "" || !rec.Enabled(tenantID) {
+		return t.base().RoundTrip(req)
+	}
+
+	maxBytes := rec.MaxBodyBytes()
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	entry := &Entry{
+		RequestID:      requestID,
+		TenantID:       tenantID,
+		Method:         req.Method,
+		URL:            redactURL(req.URL),
+		RequestHeaders: redactHeaders(req.Header),
+		Timestamp:      time.Now(),
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		var truncated bool
+		entry.RequestBody, truncated = redactBody(body, maxBytes, rec.RedactContent())
+		entry.Truncated = entry.Truncated || truncated
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		entry.Error = err.Error()
+		entry.DurationMs = time.Since(entry.Timestamp).Milliseconds()
+		rec.Record(context.WithoutCancel(ctx), entry)
+		return nil, err
+	}
+	entry.StatusCode = resp.StatusCode
+	entry.ResponseHeaders = redactHeaders(resp.Header)
+	// Streamed responses are captured as the caller consumes them and recorded on Close
+	resp.Body = &captureBody{
+		ReadCloser: resp.Body,
+		ctx:        context.WithoutCancel(ctx),
+		rec:        rec,
+		entry:      entry,
+		maxBytes:   maxBytes,
+	}
+	return resp, nil
+}
+
+// captureBody tees a response body into the entry and records it once closed
+type captureBody struct {
+	io.ReadCloser
+	ctx      context.Context
+	rec      Recorder
+	entry    *Entry
+	maxBytes int
+	buf      bytes.Buffer
+	overflow bool
+	done     atomic.Bool
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		room := b.maxBytes - b.buf.Len()
+		if n > room {
+			b.overflow = true
+		}
+		if room > 0 {
+			b.buf.Write(p[:min(n, room)])
+		}
+	}
+	return n, err
+}
+
+func (b *captureBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.done.CompareAndSwap(false, true) {
+		body, truncated := redactBody(b.buf.Bytes(), b.maxBytes, b.rec.RedactContent())
+		b.entry.ResponseBody = body
+		b.entry.Truncated = b.entry.Truncated || truncated || b.overflow
+		b.entry.DurationMs = time.Since(b.entry.Timestamp).Milliseconds()
+		b.rec.Record(b.ctx, b.entry)
+	}
+	return err
+}
+
+// sensitiveHeaders are always redacted regardless of configuration
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"api-key":             true,
+	"x-api-key":           true,
+	"x-goog-api-key":      true,
+	"cookie":              true,
+	"set-cookie":          true,
+}
+
+func redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		if sensitiveHeaders[strings.ToLower(k)] || secutils.IsSensitiveKey(k) {
+			out[k] = secutils.RedactedValue
+			continue
+		}
+		out[k] = strings.Join(v, ", ")
+	}
+	return out
+}
+
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	clone := *u
+	clone.User = nil
+	query := clone.Query()
+	for k := range query {
+		if secutils.IsSensitiveKey(k) || strings.EqualFold(k, "key") {
+			query.Set(k, secutils.RedactedValue)
+		}
+	}
+	clone.RawQuery = query.Encode()
+	return clone.String()
+}
+
+// contentKeys hold prompt and completion text in provider payloads
+var contentKeys = map[string]bool{
+	"content":   true,
+	"text":      true,
+	"input":     true,
+	"query":     true,
+	"prompt":    true,
+	"documents": true,
+	"arguments": true,
+}
+
+// redactBody redacts credentials (and optionally content) from a captured body and truncates the result.
+// The body is redacted before it is truncated so a cut can never expose a half-redacted value.
+func redactBody(body []byte, maxBytes int, redactContent bool) (string, bool) {
+	redacted := redactPayload(body, redactContent)
+	if len(redacted) <= maxBytes {
+		return redacted, false
+	}
+	return strings.ToValidUTF8(redacted[:maxBytes], ""), true
+}
+
+// redactPayload redacts JSON bodies field by field and SSE streams line by line.
+// Anything that can't be parsed is masked rather than passed through.
+func redactPayload(body []byte, redactContent bool) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ""
+	}
+	if redacted, ok := redactJSON(body, redactContent); ok {
+		return redacted
+	}
+	if !isEventStream(body) {
+		return maskBody(len(body))
+	}
+	lines := strings.Split(string(body), "\n")
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		payload, found := strings.CutPrefix(line, "data:")
+		if !found {
+			if !isEventStreamField(line) {
+				lines[i] = maskBody(len(line))
+			}
+			continue
+		}
+		payload = strings.TrimSpace(payload)
+		if payload == "[DONE]" {
+			continue
+		}
+		if redacted, ok := redactJSON([]byte(payload), redactContent); ok {
+			lines[i] = "data: " + redacted
+		} else {
+			lines[i] = "data: " + maskBody(len(payload))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func maskBody(n int) string {
+	return fmt.Sprintf("[REDACTED %d bytes]", n)
+}
+
+// isEventStream reports whether a body looks like a server-sent event stream
+func isEventStream(body []byte) bool {
+	trimmed := bytes.TrimLeft(body, " \r\n")
+	return bytes.HasPrefix(trimmed, []byte("data:")) || bytes.HasPrefix(trimmed, []byte("event:")) ||
+		bytes.Contains(body, []byte("\ndata:"))
+}
+
+// isEventStreamField reports whether a non-data SSE line carries no payload worth redacting
+func isEventStreamField(line string) bool {
+	if line == "" || strings.HasPrefix(line, ":") {
+		return true
+	}
+	for _, prefix := range []string{"event:", "id:", "retry:"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactJSON(body []byte, redactContent bool) (string, bool) {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "", false
+	}
+	v = redactValue(v, redactContent)
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(out), true
+}
+
+func redactValue(v any, redactContent bool) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			switch {
+			case secutils.IsSensitiveKey(k):
+				out[k] = secutils.RedactedValue
+			case redactContent && contentKeys[strings.ToLower(k)]:
+				out[k] = redactContentValue(item)
+			default:
+				out[k] = redactValue(item, redactContent)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = redactValue(item, redactContent)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// redactContentValue replaces text with its length, keeping structure for nested parts
+func redactContentValue(v any) any {
+	switch val := v.(type) {
+	case string:
+		return fmt.Sprintf("[REDACTED %d chars]", len([]rune(val)))
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = redactContentValue(item)
+		}
+		return out
+	case map[string]any:
+		return redactValue(val, true)
+	default:
+		return v
+	}
+}
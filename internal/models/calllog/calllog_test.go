@@ -0,0 +1,181 @@
+package calllog
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+	body := `{"model":"m","api_key":"sk-123","messages":[{"role":"user","content":"hello world"}]}`
+	stream := "event: message\ndata: {\"api_key\":\"sk-123\",\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n"
+
+	tests := []struct {
+		name          string
+		body          string
+		maxBytes      int
+		redactContent bool
+		contains      []string
+		excludes      []string
+		truncated     bool
+	}{
+		{
+			name:     "json keeps content",
+			body:     body,
+			maxBytes: DefaultMaxBodyBytes,
+			contains: []string{"hello world", `"role":"user"`},
+			excludes: []string{"sk-123"},
+		},
+		{
+			name:          "json redacts content",
+			body:          body,
+			maxBytes:      DefaultMaxBodyBytes,
+			redactContent: true,
+			contains:      []string{"[REDACTED 11 chars]", `"role":"user"`},
+			excludes:      []string{"sk-123", "hello world"},
+		},
+		{
+			name:          "sse redacted line by line",
+			body:          stream,
+			maxBytes:      DefaultMaxBodyBytes,
+			redactContent: true,
+			contains:      []string{"event: message", "data: [DONE]", "[REDACTED 2 chars]"},
+			excludes:      []string{"sk-123", `"hi"`},
+		},
+		{
+			name:     "sse with unparsable data masked",
+			body:     "data: {\"api_key\":\"sk-123\n\ndata: plain secret\n",
+			maxBytes: DefaultMaxBodyBytes,
+			contains: []string{"data: [REDACTED"},
+			excludes: []string{"sk-123", "plain secret"},
+		},
+		{
+			name:      "truncated json is redacted before the cut",
+			body:      `{"api_key":"sk-123","padding":"` + strings.Repeat("x", 100) + `"}`,
+			maxBytes:  30,
+			excludes:  []string{"sk-123", "sk-1"},
+			truncated: true,
+		},
+		{
+			name:     "partial json is masked",
+			body:     `{"model":"m","api_key":"sk-123","messa`,
+			maxBytes: DefaultMaxBodyBytes,
+			contains: []string{"[REDACTED 38 bytes]"},
+			excludes: []string{"sk-123"},
+		},
+		{
+			name:     "plain text is masked",
+			body:     "upstream error: token sk-123 rejected",
+			maxBytes: DefaultMaxBodyBytes,
+			contains: []string{"[REDACTED"},
+			excludes: []string{"sk-123"},
+		},
+		{
+			name:     "empty body",
+			body:     "  \n",
+			maxBytes: DefaultMaxBodyBytes,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := redactBody([]byte(tt.body), tt.maxBytes, tt.redactContent)
+			if truncated != tt.truncated {
+				t.Errorf("truncated = %v, want %v", truncated, tt.truncated)
+			}
+			if len(got) > tt.maxBytes {
+				t.Errorf("len = %d, exceeds max %d", len(got), tt.maxBytes)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("%q missing from %s", s, got)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(got, s) {
+					t.Errorf("%q leaked in %s", s, got)
+				}
+			}
+		})
+	}
+}
+
+type stubRecorder struct {
+	entries []*Entry
+}
+
+func (r *stubRecorder) Enabled(uint64) bool                    { return true }
+func (r *stubRecorder) RedactContent() bool                    { return false }
+func (r *stubRecorder) MaxBodyBytes() int                      { return 0 }
+func (r *stubRecorder) Record(_ context.Context, entry *Entry) { r.entries = append(r.entries, entry) }
+
+func TestCaptureBodyOverflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		maxBytes int
+		overflow bool
+	}{
+		{name: "fits", body: `{"a":"b"}`, maxBytes: 64},
+		{name: "exactly full", body: `{"a":"b"}`, maxBytes: 9},
+		{name: "one byte over", body: `{"a":"bc"}`, maxBytes: 9, overflow: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &stubRecorder{}
+			b := &captureBody{
+				ReadCloser: io.NopCloser(strings.NewReader(tt.body)),
+				ctx:        context.Background(),
+				rec:        rec,
+				entry:      &Entry{},
+				maxBytes:   tt.maxBytes,
+			}
+			data, err := io.ReadAll(b)
+			if err != nil || string(data) != tt.body {
+				t.Fatalf("caller should see the full body, got %q, %v", data, err)
+			}
+			b.Close()
+			b.Close()
+			if b.overflow != tt.overflow {
+				t.Errorf("overflow = %v, want %v", b.overflow, tt.overflow)
+			}
+			if len(rec.entries) != 1 {
+				t.Fatalf("recorded %d entries, want 1", len(rec.entries))
+			}
+			if rec.entries[0].Truncated != tt.overflow {
+				t.Errorf("truncated = %v, want %v", rec.entries[0].Truncated, tt.overflow)
+			}
+		})
+	}
+}
+
+func TestRedactHeadersAndURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		kept   bool
+	}{
+		{name: "authorization", header: "Authorization", value: "Bearer sk-123"},
+		{name: "api key", header: "X-Api-Key", value: "sk-123"},
+		{name: "cookie", header: "Cookie", value: "session=abc"},
+		{name: "content type", header: "Content-Type", value: "application/json", kept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set(tt.header, tt.value)
+			got := redactHeaders(h)[tt.header]
+			if (got == tt.value) != tt.kept {
+				t.Errorf("header %s = %q, kept want %v", tt.header, got, tt.kept)
+			}
+		})
+	}
+
+	u, _ := url.Parse("https://example.com/v1/embed?key=sk-123&model=m")
+	if s := redactURL(u); strings.Contains(s, "sk-123") || !strings.Contains(s, "model=m") {
+		t.Errorf("unexpected url: %s", s)
+	}
+}
@@ -0,0 +1,110 @@
+package calllog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// Options configures which calls are captured and how long they are kept
+type Options struct {
+	Enabled       bool
+	TenantIDs     []uint64
+	RedactContent bool
+	TTL           time.Duration
+	MaxBodyBytes  int
+}
+
+// RedisStore records captured calls in Redis lists keyed by request ID
+type RedisStore struct {
+	client  *redis.Client
+	opts    Options
+	tenants map[uint64]bool
+	prefix  string
+}
+
+// NewRedisStore creates a Redis-backed recorder
+func NewRedisStore(client *redis.Client, opts Options) *RedisStore {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Hour // Default TTL 1 hour
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	tenants := make(map[uint64]bool, len(opts.TenantIDs))
+	for _, id := range opts.TenantIDs {
+		tenants[id] = true
+	}
+	return &RedisStore{
+		client:  client,
+		opts:    opts,
+		tenants: tenants,
+		prefix:  "provider_log:",
+	}
+}
+
+// IsEnabled reports whether provider call logging is turned on
+func (s *RedisStore) IsEnabled() bool {
+	return s.opts.Enabled
+}
+
+// Enabled implements Recorder
+func (s *RedisStore) Enabled(tenantID uint64) bool {
+	if !s.opts.Enabled {
+		return false
+	}
+	return len(s.tenants) == 0 || s.tenants[tenantID]
+}
+
+// RedactContent implements Recorder
+func (s *RedisStore) RedactContent() bool {
+	return s.opts.RedactContent
+}
+
+// MaxBodyBytes implements Recorder
+func (s *RedisStore) MaxBodyBytes() int {
+	return s.opts.MaxBodyBytes
+}
+
+// buildKey builds the Redis key for a request
+func (s *RedisStore) buildKey(requestID string) string {
+	return fmt.Sprintf("%s%s", s.prefix, requestID)
+}
+
+// Record implements Recorder
+func (s *RedisStore) Record(ctx context.Context, entry *Entry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		logger.Warnf(ctx, "[ProviderLog] Failed to marshal call entry: %v", err)
+		return
+	}
+	key := s.buildKey(entry.RequestID)
+	pipe := s.client.TxPipeline()
+	pipe.RPush(ctx, key, data)
+	pipe.Expire(ctx, key, s.opts.TTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Warnf(ctx, "[ProviderLog] Failed to store call entry: %v", err)
+	}
+}
+
+// Get returns the calls captured for a request, oldest first
+func (s *RedisStore) Get(ctx context.Context, requestID string) ([]*Entry, error) {
+	items, err := s.client.LRange(ctx, s.buildKey(requestID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load provider calls: %w", err)
+	}
+	entries := make([]*Entry, 0, len(items))
+	for _, item := range items {
+		var entry Entry
+		if err := json.Unmarshal([]byte(item), &entry); err != nil {
+			logger.Warnf(ctx, "[ProviderLog] Skipping malformed call entry: %v", err)
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
@@ -86,8 +86,16 @@ type ChatConfig struct {
 	Extra     map[string]any
 }
 
-// NewChat 创建聊天实例
+// NewChat 创建聊天实例，相同的并发非流式请求会被合并，调用会经过共享的模型调度队列
 func NewChat(config *ChatConfig, ollamaService *ollama.OllamaService) (Chat, error) {
+	instance, err := newChat(config, ollamaService)
+	if err != nil {
+		return nil, err
+	}
+	return &coalescedChat{model: &scheduledChat{model: instance}}, nil
+}
+
+func newChat(config *ChatConfig, ollamaService *ollama.OllamaService) (Chat, error) {
 	switch strings.ToLower(string(config.Source)) {
 	case string(types.ModelSourceLocal):
 		return NewOllamaChat(config, ollamaService)
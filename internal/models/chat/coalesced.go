@@ -0,0 +1,77 @@
+package chat
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"golang.org/x/sync/singleflight"
+)
+
+// chatFlight 合并所有模型实例上相同的并发非流式请求
+var chatFlight singleflight.Group
+
+// coalescedChat 将同一租户、同一模型、相同消息与选项的并发非流式请求合并为一次调用，
+// 避免缓存失效时的请求风暴重复打到模型服务。流式请求按会话独立输出，不做合并。
+type coalescedChat struct {
+	model Chat
+}
+
+// Chat 进行非流式聊天，相同的并发请求共享同一次调用结果
+func (c *coalescedChat) Chat(ctx context.Context, messages []Message, opts *ChatOptions) (*types.ChatResponse, error) {
+	key, err := coalesceKey(ctx, c.model.GetModelID(), messages, opts)
+	if err != nil {
+		return c.model.Chat(ctx, messages, opts)
+	}
+
+	// 共享调用不随首个请求方取消而中断，否则会连带其他等待方失败
+	sharedCtx := context.WithoutCancel(ctx)
+	ch := chatFlight.DoChan(key, func() (interface{}, error) {
+		return c.model.Chat(sharedCtx, messages, opts)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		// 每个请求方拿到独立副本，避免并发修改
+		resp := *res.Val.(*types.ChatResponse)
+		return &resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ChatStream 进行流式聊天，不做合并
+func (c *coalescedChat) ChatStream(
+	ctx context.Context, messages []Message, opts *ChatOptions,
+) (<-chan types.StreamResponse, error) {
+	return c.model.ChatStream(ctx, messages, opts)
+}
+
+// GetModelName 获取模型名称
+func (c *coalescedChat) GetModelName() string {
+	return c.model.GetModelName()
+}
+
+// GetModelID 获取模型ID
+func (c *coalescedChat) GetModelID() string {
+	return c.model.GetModelID()
+}
+
+// coalesceKey 由租户、模型、消息与选项生成合并键，保证不同租户的请求不会共享结果
+func coalesceKey(ctx context.Context, modelID string, messages []Message, opts *ChatOptions) (string, error) {
+	tenantID, _ := ctx.Value(types.TenantIDContextKey).(uint64)
+	payload, err := json.Marshal(struct {
+		Messages []Message    `json:"messages"`
+		Options  *ChatOptions `json:"options"`
+	}{messages, opts})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return fmt.Sprintf("%d:%s:%s", tenantID, modelID, hex.EncodeToString(sum[:])), nil
+}
@@ -0,0 +1,128 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// blockingChat answers every call once released and counts the calls
+type blockingChat struct {
+	modelID string
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (c *blockingChat) Chat(ctx context.Context, _ []Message, _ *ChatOptions) (*types.ChatResponse, error) {
+	c.calls.Add(1)
+	select {
+	case <-c.release:
+		return &types.ChatResponse{Content: "answer"}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *blockingChat) ChatStream(
+	ctx context.Context, _ []Message, _ *ChatOptions,
+) (<-chan types.StreamResponse, error) {
+	return nil, errors.New("streaming not supported")
+}
+
+func (c *blockingChat) GetModelName() string {
+	return c.modelID
+}
+
+func (c *blockingChat) GetModelID() string {
+	return c.modelID
+}
+
+func TestCoalescedChat(t *testing.T) {
+	tests := []struct {
+		name      string
+		tenants   []uint64
+		messages  []string
+		wantCalls int32
+	}{
+		{name: "identical requests share one call", tenants: []uint64{1, 1, 1}, messages: []string{"hi", "hi", "hi"}, wantCalls: 1},
+		{name: "tenants never share", tenants: []uint64{1, 2}, messages: []string{"hi", "hi"}, wantCalls: 2},
+		{name: "different messages", tenants: []uint64{1, 1}, messages: []string{"hi", "hello"}, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := &blockingChat{modelID: "model-" + tt.name, release: make(chan struct{})}
+			chat := &coalescedChat{model: model}
+
+			responses := make([]*types.ChatResponse, len(tt.tenants))
+			var wg sync.WaitGroup
+			for i, tenantID := range tt.tenants {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ctx := context.WithValue(context.Background(), types.TenantIDContextKey, tenantID)
+					resp, err := chat.Chat(ctx, []Message{{Role: "user", Content: tt.messages[i]}}, &ChatOptions{})
+					if err != nil {
+						t.Errorf("Chat() error = %v", err)
+					}
+					responses[i] = resp
+				}()
+			}
+			// Let every caller join before the model answers
+			time.Sleep(50 * time.Millisecond)
+			close(model.release)
+			wg.Wait()
+
+			if calls := model.calls.Load(); calls != tt.wantCalls {
+				t.Errorf("model called %d times, want %d", calls, tt.wantCalls)
+			}
+			// Every caller owns its response
+			responses[0].Content = "modified"
+			for i, resp := range responses[1:] {
+				if resp.Content != "answer" {
+					t.Errorf("response %d = %q, want it unaffected by another caller", i+1, resp.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestCoalescedChatCancelledCaller(t *testing.T) {
+	model := &blockingChat{modelID: "model-cancel", release: make(chan struct{})}
+	chat := &coalescedChat{model: model}
+	messages := []Message{{Role: "user", Content: "hi"}}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := chat.Chat(cancelled, messages, &ChatOptions{})
+		firstErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	second := make(chan *types.ChatResponse, 1)
+	go func() {
+		resp, err := chat.Chat(context.Background(), messages, &ChatOptions{})
+		if err != nil {
+			t.Errorf("waiting caller failed: %v", err)
+		}
+		second <- resp
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller error = %v, want context.Canceled", err)
+	}
+	close(model.release)
+	if resp := <-second; resp == nil || resp.Content != "answer" {
+		t.Errorf("waiting caller response = %+v, want the shared answer", resp)
+	}
+	if calls := model.calls.Load(); calls != 1 {
+		t.Errorf("model called %d times, want 1", calls)
+	}
+}
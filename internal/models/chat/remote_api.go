@@ -9,6 +9,7 @@ import (
 	"net/http"
 
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/calllog"
 	"github.com/Tencent/WeKnora/internal/models/provider"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/sashabaranov/go-openai"
@@ -33,6 +34,7 @@ type RemoteAPIChat struct {
 func NewRemoteAPIChat(chatConfig *ChatConfig) (*RemoteAPIChat, error) {
 	apiKey := chatConfig.APIKey
 	config := openai.DefaultConfig(apiKey)
+	config.HTTPClient = calllog.NewHTTPClient(0)
 	if baseURL := chatConfig.BaseURL; baseURL != "" {
 		config.BaseURL = baseURL
 	}
@@ -101,6 +103,10 @@ func (c *RemoteAPIChat) BuildChatCompletionRequest(messages []Message, opts *Cha
 		Messages: c.ConvertMessages(messages),
 		Stream:   isStream,
 	}
+	if isStream {
+		// Ask for token usage in the final chunk so callers can track budgets
+		req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	}
 
 	if opts != nil {
 		if opts.Temperature > 0 {
@@ -214,7 +220,7 @@ func (c *RemoteAPIChat) chatWithRawHTTP(ctx context.Context, customReq any) (*ty
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
 
-	client := &http.Client{}
+	client := calllog.NewHTTPClient(0)
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("send request: %w", err)
@@ -318,7 +324,7 @@ func (c *RemoteAPIChat) chatStreamWithRawHTTP(ctx context.Context, customReq any
 	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
 	httpReq.Header.Set("Accept", "text/event-stream")
 
-	client := &http.Client{}
+	client := calllog.NewHTTPClient(0)
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("send request: %w", err)
@@ -353,6 +359,7 @@ func (c *RemoteAPIChat) processStream(ctx context.Context, stream *openai.ChatCo
 					Content:      "",
 					Done:         true,
 					ToolCalls:    state.buildOrderedToolCalls(),
+					Usage:        state.usage,
 				}
 			} else {
 				streamChan <- types.StreamResponse{
@@ -364,6 +371,7 @@ func (c *RemoteAPIChat) processStream(ctx context.Context, stream *openai.ChatCo
 			return
 		}
 
+		state.recordUsage(response.Usage)
 		if len(response.Choices) > 0 {
 			c.processStreamDelta(ctx, &response.Choices[0], state, streamChan)
 		}
@@ -402,6 +410,7 @@ func (c *RemoteAPIChat) processRawHTTPStream(ctx context.Context, resp *http.Res
 				Content:      "",
 				Done:         true,
 				ToolCalls:    state.buildOrderedToolCalls(),
+				Usage:        state.usage,
 			}
 			return
 		}
@@ -416,6 +425,7 @@ func (c *RemoteAPIChat) processRawHTTPStream(ctx context.Context, resp *http.Res
 			continue
 		}
 
+		state.recordUsage(streamResp.Usage)
 		if len(streamResp.Choices) > 0 {
 			c.processStreamDelta(ctx, &streamResp.Choices[0], state, streamChan)
 		}
@@ -428,6 +438,19 @@ type streamState struct {
 	lastFunctionName map[int]string
 	nameNotified     map[int]bool
 	hasThinking      bool
+	usage            *types.TokenUsage
+}
+
+// recordUsage keeps the token usage sent with a stream chunk
+func (s *streamState) recordUsage(usage *openai.Usage) {
+	if usage == nil {
+		return
+	}
+	s.usage = &types.TokenUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
 }
 
 func newStreamState() *streamState {
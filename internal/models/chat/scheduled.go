@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/models/scheduler"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// scheduledChat 通过共享的模型调度器对聊天调用排队
+type scheduledChat struct {
+	model Chat
+}
+
+// Chat 在获取调度槽位后进行非流式聊天
+func (c *scheduledChat) Chat(ctx context.Context, messages []Message, opts *ChatOptions) (*types.ChatResponse, error) {
+	release, err := scheduler.Default().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return c.model.Chat(ctx, messages, opts)
+}
+
+// ChatStream 在获取调度槽位后进行流式聊天，槽位在流结束时释放
+func (c *scheduledChat) ChatStream(
+	ctx context.Context, messages []Message, opts *ChatOptions,
+) (<-chan types.StreamResponse, error) {
+	s := scheduler.Default()
+	if s == nil {
+		return c.model.ChatStream(ctx, messages, opts)
+	}
+	release, err := s.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := c.model.ChatStream(ctx, messages, opts)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	out := make(chan types.StreamResponse)
+	go func() {
+		defer close(out)
+		defer release()
+		for resp := range stream {
+			select {
+			case out <- resp:
+			case <-ctx.Done():
+				// 消费方已放弃，排空上游避免其阻塞
+				go func() {
+					for range stream {
+					}
+				}()
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// GetModelName 获取模型名称
+func (c *scheduledChat) GetModelName() string {
+	return c.model.GetModelName()
+}
+
+// GetModelID 获取模型ID
+func (c *scheduledChat) GetModelID() string {
+	return c.model.GetModelID()
+}
@@ -47,10 +47,24 @@ type Config struct {
 	Dimensions           int               `json:"dimensions"`
 	ModelID              string            `json:"model_id"`
 	Provider             string            `json:"provider"`
+	// Normalize L2-normalizes the returned vectors
+	Normalize bool `json:"normalize"`
 }
 
-// NewEmbedder creates an embedder based on the configuration
+// NewEmbedder creates an embedder based on the configuration.
+// Calls made through the returned embedder are queued by the shared model scheduler.
 func NewEmbedder(config Config, pooler EmbedderPooler, ollamaService *ollama.OllamaService) (Embedder, error) {
+	embedder, err := newEmbedder(config, pooler, ollamaService)
+	if err != nil {
+		return nil, err
+	}
+	if config.Normalize {
+		embedder = &normalizedEmbedder{Embedder: embedder}
+	}
+	return &scheduledEmbedder{Embedder: embedder}, nil
+}
+
+func newEmbedder(config Config, pooler EmbedderPooler, ollamaService *ollama.OllamaService) (Embedder, error) {
 	var embedder Embedder
 	var err error
 	switch strings.ToLower(string(config.Source)) {
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/calllog"
 )
 
 // JinaEmbedder implements text vectorization functionality using Jina AI API
@@ -58,9 +59,7 @@ func NewJinaEmbedder(apiKey, baseURL, modelName string,
 	timeout := 60 * time.Second
 
 	// Create HTTP client
-	client := &http.Client{
-		Timeout: timeout,
-	}
+	client := calllog.NewHTTPClient(timeout)
 
 	return &JinaEmbedder{
 		apiKey:         apiKey,
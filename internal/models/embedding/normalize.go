@@ -0,0 +1,62 @@
+package embedding
+
+import (
+	"context"
+	"math"
+)
+
+// normalizedTolerance is the allowed deviation of the L2 norm from 1 for a vector to count as normalized
+const normalizedTolerance = 0.01
+
+// normalizedEmbedder L2-normalizes the vectors returned by the provider, so that cosine similarity and
+// inner product agree for models that return unnormalized vectors
+type normalizedEmbedder struct {
+	Embedder
+}
+
+// Embed converts text to a unit length vector
+func (e *normalizedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vec, err := e.Embedder.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	return NormalizeL2(vec), nil
+}
+
+// BatchEmbed converts multiple texts to unit length vectors
+func (e *normalizedEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	vecs, err := e.Embedder.BatchEmbed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range vecs {
+		vecs[i] = NormalizeL2(vecs[i])
+	}
+	return vecs, nil
+}
+
+// L2Norm returns the Euclidean length of the vector
+func L2Norm(vec []float32) float64 {
+	var sum float64
+	for _, v := range vec {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum)
+}
+
+// IsNormalized reports whether the vector has unit length
+func IsNormalized(vec []float32) bool {
+	return math.Abs(L2Norm(vec)-1) <= normalizedTolerance
+}
+
+// NormalizeL2 scales the vector in place to unit length and returns it; zero vectors are returned unchanged
+func NormalizeL2(vec []float32) []float32 {
+	norm := L2Norm(vec)
+	if norm == 0 {
+		return vec
+	}
+	for i, v := range vec {
+		vec[i] = float32(float64(v) / norm)
+	}
+	return vec
+}
@@ -0,0 +1,57 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+type fixedEmbedder struct {
+	Embedder
+	vecs [][]float32
+}
+
+func (e *fixedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return append([]float32(nil), e.vecs[0]...), nil
+}
+
+func (e *fixedEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	vecs := make([][]float32, len(e.vecs))
+	for i, vec := range e.vecs {
+		vecs[i] = append([]float32(nil), vec...)
+	}
+	return vecs, nil
+}
+
+func TestNormalizedEmbedderReturnsUnitVectors(t *testing.T) {
+	inner := &fixedEmbedder{vecs: [][]float32{{3, 4}, {1, 2, 2}, {0.1, 0, 0}}}
+	embedder := &normalizedEmbedder{Embedder: inner}
+
+	vec, err := embedder.Embed(context.Background(), "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(L2Norm(vec)-1) > 1e-6 || math.Abs(float64(vec[0])-0.6) > 1e-6 {
+		t.Errorf("unexpected normalized vector %v", vec)
+	}
+
+	vecs, err := embedder.BatchEmbed(context.Background(), []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, vec := range vecs {
+		if !IsNormalized(vec) || math.Abs(L2Norm(vec)-1) > 1e-6 {
+			t.Errorf("vector %d has length %f", i, L2Norm(vec))
+		}
+	}
+}
+
+func TestNormalizeL2(t *testing.T) {
+	if IsNormalized([]float32{3, 4}) {
+		t.Error("vector of length 5 is not normalized")
+	}
+	zero := NormalizeL2([]float32{0, 0})
+	if zero[0] != 0 || zero[1] != 0 {
+		t.Errorf("zero vector should stay unchanged, got %v", zero)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/calllog"
 )
 
 // OpenAIEmbedder implements text vectorization functionality using OpenAI API
@@ -61,9 +62,7 @@ func NewOpenAIEmbedder(apiKey, baseURL, modelName string,
 	timeout := 60 * time.Second
 
 	// Create HTTP client
-	client := &http.Client{
-		Timeout: timeout,
-	}
+	client := calllog.NewHTTPClient(timeout)
 
 	return &OpenAIEmbedder{
 		apiKey:               apiKey,
@@ -0,0 +1,32 @@
+package embedding
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/models/scheduler"
+)
+
+// scheduledEmbedder queues embedding calls through the shared model scheduler
+type scheduledEmbedder struct {
+	Embedder
+}
+
+// Embed converts text to vector once a scheduler slot is available
+func (e *scheduledEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	release, err := scheduler.Default().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return e.Embedder.Embed(ctx, text)
+}
+
+// BatchEmbed converts multiple texts to vectors once a scheduler slot is available
+func (e *scheduledEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	release, err := scheduler.Default().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return e.Embedder.BatchEmbed(ctx, texts)
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/calllog"
 )
 
 const (
@@ -105,9 +106,7 @@ func NewVolcengineEmbedder(apiKey, baseURL, modelName string,
 
 	timeout := 60 * time.Second
 
-	client := &http.Client{
-		Timeout: timeout,
-	}
+	client := calllog.NewHTTPClient(timeout)
 
 	return &VolcengineEmbedder{
 		apiKey:               apiKey,
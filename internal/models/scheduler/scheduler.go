@@ -0,0 +1,261 @@
+// Package scheduler queues model provider calls by priority.
+//
+// Chat and embedding clients acquire a slot from the default Scheduler before
+// calling a provider. When all slots are busy, waiting calls are dispatched by
+// smooth weighted round-robin across priorities, so interactive requests jump
+// ahead of bulk ingestion work while bulk work still gets a share of slots.
+package scheduler
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Priority is the scheduling class of a model call
+type Priority int
+
+const (
+	// PriorityInteractive is used for user-facing requests such as chat
+	PriorityInteractive Priority = iota
+	// PriorityBulk is used for background work such as document ingestion
+	PriorityBulk
+
+	numPriorities = int(PriorityBulk) + 1
+)
+
+// Default weights used when none are configured
+const (
+	DefaultInteractiveWeight = 4
+	DefaultBulkWeight        = 1
+)
+
+// String returns the configuration name of the priority
+func (p Priority) String() string {
+	switch p {
+	case PriorityBulk:
+		return "bulk"
+	default:
+		return "interactive"
+	}
+}
+
+// ParsePriority converts a configuration name to a priority
+func ParsePriority(name string) (Priority, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "interactive":
+		return PriorityInteractive, true
+	case "bulk":
+		return PriorityBulk, true
+	default:
+		return PriorityInteractive, false
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose model calls are scheduled with the given priority
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority stored in ctx, defaulting to interactive
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && int(p) >= 0 && int(p) < numPriorities {
+		return p
+	}
+	return PriorityInteractive
+}
+
+// Options configures a Scheduler
+type Options struct {
+	// MaxConcurrency is the number of model calls allowed in flight at once
+	MaxConcurrency int
+	// Weights is the relative share of dispatch slots per priority when several are waiting
+	Weights map[Priority]int
+}
+
+// QueueStats describes the state of a single priority queue
+type QueueStats struct {
+	Priority string `json:"priority"`
+	Weight   int    `json:"weight"`
+	Queued   int    `json:"queued"`
+	Running  int    `json:"running"`
+}
+
+// Stats is a snapshot of the scheduler state
+type Stats struct {
+	Enabled        bool         `json:"enabled"`
+	MaxConcurrency int          `json:"max_concurrency"`
+	Running        int          `json:"running"`
+	Queues         []QueueStats `json:"queues"`
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// Scheduler limits concurrent model calls and dispatches waiting calls by priority
+type Scheduler struct {
+	mu             sync.Mutex
+	maxConcurrency int
+	running        int
+	weights        [numPriorities]int
+	current        [numPriorities]int
+	queues         [numPriorities]*list.List
+	inflight       [numPriorities]int
+}
+
+// New creates a scheduler; it returns nil when MaxConcurrency is not positive
+func New(opts Options) *Scheduler {
+	if opts.MaxConcurrency <= 0 {
+		return nil
+	}
+	s := &Scheduler{maxConcurrency: opts.MaxConcurrency}
+	s.weights[PriorityInteractive] = DefaultInteractiveWeight
+	s.weights[PriorityBulk] = DefaultBulkWeight
+	for p, w := range opts.Weights {
+		if int(p) >= 0 && int(p) < numPriorities && w > 0 {
+			s.weights[p] = w
+		}
+	}
+	for i := range s.queues {
+		s.queues[i] = list.New()
+	}
+	return s
+}
+
+// Acquire blocks until a slot is available for the priority in ctx.
+// The returned release function must be called once the call has finished.
+// A nil scheduler never blocks.
+func (s *Scheduler) Acquire(ctx context.Context) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+	p := PriorityFromContext(ctx)
+
+	s.mu.Lock()
+	if s.running < s.maxConcurrency && s.queuedLocked() == 0 {
+		s.running++
+		s.inflight[p]++
+		s.mu.Unlock()
+		return s.releaseFunc(p), nil
+	}
+	w := &waiter{ready: make(chan struct{})}
+	elem := s.queues[p].PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return s.releaseFunc(p), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		if w.granted {
+			// The slot was handed over while we were giving up; pass it on
+			s.mu.Unlock()
+			s.release(p)
+			return nil, ctx.Err()
+		}
+		s.queues[p].Remove(elem)
+		s.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of queue depth and running calls per priority
+func (s *Scheduler) Stats() Stats {
+	if s == nil {
+		return Stats{Queues: []QueueStats{}}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := Stats{
+		Enabled:        true,
+		MaxConcurrency: s.maxConcurrency,
+		Running:        s.running,
+		Queues:         make([]QueueStats, 0, numPriorities),
+	}
+	for i := 0; i < numPriorities; i++ {
+		stats.Queues = append(stats.Queues, QueueStats{
+			Priority: Priority(i).String(),
+			Weight:   s.weights[i],
+			Queued:   s.queues[i].Len(),
+			Running:  s.inflight[i],
+		})
+	}
+	return stats
+}
+
+func (s *Scheduler) releaseFunc(p Priority) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { s.release(p) })
+	}
+}
+
+func (s *Scheduler) release(p Priority) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	s.inflight[p]--
+	for s.running < s.maxConcurrency {
+		next, ok := s.nextLocked()
+		if !ok {
+			break
+		}
+		w := s.queues[next].Remove(s.queues[next].Front()).(*waiter)
+		w.granted = true
+		s.running++
+		s.inflight[next]++
+		close(w.ready)
+	}
+}
+
+// nextLocked picks the next priority to dispatch using smooth weighted round-robin
+// over the non-empty queues
+func (s *Scheduler) nextLocked() (Priority, bool) {
+	best, total := -1, 0
+	for i := 0; i < numPriorities; i++ {
+		if s.queues[i].Len() == 0 {
+			continue
+		}
+		s.current[i] += s.weights[i]
+		total += s.weights[i]
+		if best < 0 || s.current[i] > s.current[best] {
+			best = i
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	s.current[best] -= total
+	return Priority(best), true
+}
+
+func (s *Scheduler) queuedLocked() int {
+	n := 0
+	for _, q := range s.queues {
+		n += q.Len()
+	}
+	return n
+}
+
+type schedulerHolder struct {
+	scheduler *Scheduler
+}
+
+var defaultScheduler atomic.Value
+
+// SetDefault installs the scheduler shared by all model clients; nil disables queueing
+func SetDefault(s *Scheduler) {
+	defaultScheduler.Store(schedulerHolder{scheduler: s})
+}
+
+// Default returns the shared scheduler, or nil when queueing is disabled
+func Default() *Scheduler {
+	h, _ := defaultScheduler.Load().(schedulerHolder)
+	return h.scheduler
+}
@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNilSchedulerNeverBlocks(t *testing.T) {
+	var s *Scheduler
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release()
+	if s.Stats().Enabled {
+		t.Errorf("nil scheduler should report disabled")
+	}
+}
+
+func TestWeightedDispatch(t *testing.T) {
+	s := New(Options{MaxConcurrency: 1, Weights: map[Priority]int{PriorityInteractive: 2, PriorityBulk: 1}})
+	hold, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	order := make(chan Priority, 6)
+	enqueue := func(p Priority) {
+		ctx := WithPriority(context.Background(), p)
+		go func() {
+			release, err := s.Acquire(ctx)
+			if err != nil {
+				t.Errorf("acquire: %v", err)
+				return
+			}
+			order <- p
+			release()
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		enqueue(PriorityBulk)
+	}
+	for i := 0; i < 3; i++ {
+		enqueue(PriorityInteractive)
+	}
+	waitQueued(t, s, 6)
+	hold()
+
+	var got []Priority
+	for i := 0; i < 6; i++ {
+		got = append(got, <-order)
+	}
+	// With weights 2:1 bulk must be served before all interactive calls are done
+	firstBulk := -1
+	for i, p := range got {
+		if p == PriorityBulk {
+			firstBulk = i
+			break
+		}
+	}
+	if got[0] != PriorityInteractive || firstBulk < 0 || firstBulk > 2 {
+		t.Errorf("unexpected dispatch order: %v", got)
+	}
+}
+
+func TestAcquireCancelled(t *testing.T) {
+	s := New(Options{MaxConcurrency: 1})
+	hold, _ := s.Acquire(context.Background())
+	defer hold()
+
+	ctx, cancel := context.WithTimeout(WithPriority(context.Background(), PriorityBulk), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx); err == nil {
+		t.Fatalf("expected context error")
+	}
+	if q := s.Stats().Queues[PriorityBulk].Queued; q != 0 {
+		t.Errorf("cancelled waiter left in queue: %d", q)
+	}
+}
+
+func waitQueued(t *testing.T, s *Scheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		total := 0
+		for _, q := range s.Stats().Queues {
+			total += q.Queued
+		}
+		if total == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d queued calls", n)
+}
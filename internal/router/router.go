@@ -1,10 +1,9 @@
 package router
 
 import (
-	"time"
-
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/dig"
@@ -12,8 +11,11 @@ import (
 	"github.com/Tencent/WeKnora/internal/config"
 	"github.com/Tencent/WeKnora/internal/handler"
 	"github.com/Tencent/WeKnora/internal/handler/session"
+	"github.com/Tencent/WeKnora/internal/metrics"
 	"github.com/Tencent/WeKnora/internal/middleware"
+	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
 
 	_ "github.com/Tencent/WeKnora/docs" // swagger docs
 )
@@ -23,7 +25,6 @@ type RouterParams struct {
 	dig.In
 
 	Config                *config.Config
-	UserService           interfaces.UserService
 	KBService             interfaces.KnowledgeBaseService
 	KnowledgeService      interfaces.KnowledgeService
 	ChunkService          interfaces.ChunkService
@@ -48,32 +49,63 @@ type RouterParams struct {
 	FAQHandler            *handler.FAQHandler
 	TagHandler            *handler.TagHandler
 	CustomAgentHandler    *handler.CustomAgentHandler
+	AgentRunHandler       *handler.AgentRunHandler
+	ProviderLogHandler    *handler.ProviderLogHandler
+	AuditHandler          *handler.AuditHandler
+	RoleHandler           *handler.RoleHandler
+	APIKeyHandler         *handler.APIKeyHandler
+	OpenAICompatHandler   *handler.OpenAICompatHandler
+	Authenticator         interfaces.Authenticator
+	RoleService           interfaces.RoleService
+	APIKeyService         interfaces.APIKeyService
+	RateLimitStore        middleware.RateLimitStore
+	IdempotencyStore      middleware.IdempotencyStore
+	AuditRecorder         *middleware.AuditRecorder
+	HealthHandler         *handler.HealthHandler
+	Metrics               *metrics.Metrics
+	MetricsRegistry       *prometheus.Registry
 }
 
+// Scope checks of the routes, against the scopes of the tenant API key or of the role of the logged-in user
+var (
+	requireKnowledgeRead  = middleware.RequireScope(types.APIKeyScopeKnowledgeRead)
+	requireKnowledgeWrite = middleware.RequireScope(types.APIKeyScopeKnowledgeWrite)
+	requireChat           = middleware.RequireScope(types.APIKeyScopeChat)
+	requireAdmin          = middleware.RequireScope(types.APIKeyScopeAdmin)
+	// Search and knowledge creation routes also accept the narrower scopes of search-only and ingest-only keys
+	requireKnowledgeSearch = middleware.RequireScope(types.APIKeyScopeKnowledgeRead, types.APIKeyScopeKnowledgeSearch)
+	requireKnowledgeIngest = middleware.RequireScope(types.APIKeyScopeKnowledgeWrite, types.APIKeyScopeKnowledgeIngest)
+	// Ingest-only keys may also list the supported upload formats
+	requireKnowledgeReadOrIngest = middleware.RequireScope(types.APIKeyScopeKnowledgeRead,
+		types.APIKeyScopeKnowledgeIngest)
+)
+
 // NewRouter creates a new router
 func NewRouter(params RouterParams) *gin.Engine {
 	r := gin.New()
 
-	// CORS middleware should be placed first
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Request-ID"},
-		ExposeHeaders:    []string{"Content-Length", "Access-Control-Allow-Origin"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
+	// CORS middleware should be placed first; the OpenAI-compatible API has its own policy
+	r.Use(middleware.WithOpenAICompatCORS(params.Config, middleware.CORS(params.Config)))
 
 	// Basic middleware (no authentication required)
-	r.Use(middleware.RequestID())
+	r.Use(middleware.RequestID(params.Config))
+	r.Use(middleware.SlowRequestLog(params.Config))
+	r.Use(middleware.Metrics(params.Metrics))
+	r.Use(middleware.Language())
 	r.Use(middleware.Logger())
 	r.Use(middleware.Recovery())
 	r.Use(middleware.ErrorHandler())
 
-	// Health check (no authentication required)
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// Request body size limits, enforced while the body streams; the upload routes allow larger bodies
+	r.Use(middleware.BodyLimit(params.Config, uploadBodyLimits(params.Config)))
+
+	// Health checks (no authentication required): liveness, and readiness checking the dependencies
+	r.GET("/health", params.HealthHandler.Live)
+	r.GET("/health/ready", params.HealthHandler.Ready)
+
+	// Prometheus metrics (no authentication required, restrict access at the network level)
+	r.GET("/metrics", gin.WrapH(promhttp.HandlerFor(params.MetricsRegistry,
+		promhttp.HandlerOpts{Registry: params.MetricsRegistry})))
 
 	// Swagger API documentation (only enabled in non-production environments)
 	// Determined by GIN_MODE environment variable: disabled in release mode
@@ -87,19 +119,27 @@ func NewRouter(params RouterParams) *gin.Engine {
 	}
 
 	// Authentication middleware
-	r.Use(middleware.Auth(params.TenantService, params.UserService, params.Config))
+	r.Use(middleware.Auth(params.TenantService, params.Authenticator, params.RoleService, params.APIKeyService,
+		params.Config))
+
+	// Per-tenant rate limiting, keyed on the tenant resolved by Auth
+	r.Use(middleware.RateLimit(params.Config, params.RateLimitStore))
+
+	// Per-tenant payload size metrics and limits
+	r.Use(middleware.PayloadLimit(params.Config, params.Metrics))
 
 	// Add OpenTelemetry tracing middleware
 	r.Use(middleware.TracingMiddleware())
 
-	// API routes requiring authentication
-	v1 := r.Group("/api/v1")
+	// API routes requiring authentication; their non-GET requests are recorded in the audit log
+	v1 := r.Group("/api/v1", middleware.Audit(params.AuditRecorder))
 	{
 		RegisterAuthRoutes(v1, params.AuthHandler)
-		RegisterTenantRoutes(v1, params.TenantHandler)
+		RegisterTenantRoutes(v1, params.TenantHandler, params.Config.SingleTenantMode() != nil)
 		RegisterKnowledgeBaseRoutes(v1, params.KBHandler)
 		RegisterKnowledgeTagRoutes(v1, params.TagHandler)
-		RegisterKnowledgeRoutes(v1, params.KnowledgeHandler)
+		RegisterKnowledgeRoutes(v1, params.KnowledgeHandler,
+			middleware.Idempotency(params.Config, params.IdempotencyStore))
 		RegisterFAQRoutes(v1, params.FAQHandler)
 		RegisterChunkRoutes(v1, params.ChunkHandler)
 		RegisterSessionRoutes(v1, params.SessionHandler)
@@ -112,67 +152,100 @@ func NewRouter(params RouterParams) *gin.Engine {
 		RegisterMCPServiceRoutes(v1, params.MCPServiceHandler)
 		RegisterWebSearchRoutes(v1, params.WebSearchHandler)
 		RegisterCustomAgentRoutes(v1, params.CustomAgentHandler)
+		RegisterAgentRunRoutes(v1, params.AgentRunHandler)
+		RegisterProviderLogRoutes(v1, params.ProviderLogHandler)
+		RegisterAuditRoutes(v1, params.AuditHandler)
+		RegisterRoleRoutes(v1, params.RoleHandler)
+		RegisterAPIKeyRoutes(v1, params.APIKeyHandler)
+	}
+
+	// OpenAI-compatible API, enabled by configuration
+	if params.Config.OpenAICompat != nil && params.Config.OpenAICompat.Enabled {
+		RegisterOpenAICompatRoutes(r.Group(middleware.OpenAICompatPathPrefix), params.OpenAICompatHandler)
 	}
 
 	return r
 }
 
+// uploadFormFieldsBytes is the room left for the form fields sent with an uploaded file
+const uploadFormFieldsBytes = 1 << 20
+
+// uploadBodyLimits returns the body size limits of the file upload routes, which take larger bodies than
+// the JSON routes
+func uploadBodyLimits(cfg *config.Config) map[string]int64 {
+	limit := secutils.GetMaxFileSize() + uploadFormFieldsBytes
+	if cfg.BodyLimit != nil && cfg.BodyLimit.UploadMaxBytes != 0 {
+		limit = cfg.BodyLimit.UploadMaxBytes
+	}
+	return map[string]int64{
+		"POST /api/v1/knowledge-bases/:id/knowledge/file": limit,
+		"POST /api/v1/initialization/multimodal/test":     limit,
+	}
+}
+
 // RegisterChunkRoutes registers chunk-related routes
 func RegisterChunkRoutes(r *gin.RouterGroup, handler *handler.ChunkHandler) {
 	// Chunk route group
 	chunks := r.Group("/chunks")
 	{
 		// Get chunk list
-		chunks.GET("/:knowledge_id", handler.ListKnowledgeChunks)
+		chunks.GET("/:knowledge_id", requireKnowledgeRead, handler.ListKnowledgeChunks)
 		// Get single chunk by chunk_id (knowledge_id not required)
-		chunks.GET("/by-id/:id", handler.GetChunkByIDOnly)
+		chunks.GET("/by-id/:id", requireKnowledgeRead, handler.GetChunkByIDOnly)
+		// Find the chunks most similar to a chunk
+		chunks.GET("/by-id/:id/similar", requireKnowledgeRead, handler.GetSimilarChunks)
 		// Delete chunk
-		chunks.DELETE("/:knowledge_id/:id", handler.DeleteChunk)
+		chunks.DELETE("/:knowledge_id/:id", requireKnowledgeWrite, handler.DeleteChunk)
 		// Delete all chunks under knowledge
-		chunks.DELETE("/:knowledge_id", handler.DeleteChunksByKnowledgeID)
+		chunks.DELETE("/:knowledge_id", requireKnowledgeWrite, handler.DeleteChunksByKnowledgeID)
 		// Update chunk info
-		chunks.PUT("/:knowledge_id/:id", handler.UpdateChunk)
+		chunks.PUT("/:knowledge_id/:id", requireKnowledgeWrite, handler.UpdateChunk)
 		// Delete single generated question (by question ID)
-		chunks.DELETE("/by-id/:id/questions", handler.DeleteGeneratedQuestion)
+		chunks.DELETE("/by-id/:id/questions", requireKnowledgeWrite, handler.DeleteGeneratedQuestion)
 	}
 }
 
-// RegisterKnowledgeRoutes registers knowledge-related routes
-func RegisterKnowledgeRoutes(r *gin.RouterGroup, handler *handler.KnowledgeHandler) {
+// RegisterKnowledgeRoutes registers knowledge-related routes.
+// The creation routes honor the Idempotency-Key header through the idempotency middleware.
+func RegisterKnowledgeRoutes(r *gin.RouterGroup, handler *handler.KnowledgeHandler, idempotency gin.HandlerFunc) {
 	// Knowledge routes under knowledge base
 	kb := r.Group("/knowledge-bases/:id/knowledge")
 	{
 		// Create knowledge from file
-		kb.POST("/file", handler.CreateKnowledgeFromFile)
+		kb.POST("/file", requireKnowledgeIngest, idempotency, handler.CreateKnowledgeFromFile)
 		// Create knowledge from URL
-		kb.POST("/url", handler.CreateKnowledgeFromURL)
+		kb.POST("/url", requireKnowledgeIngest, idempotency, handler.CreateKnowledgeFromURL)
 		// Manual Markdown entry
-		kb.POST("/manual", handler.CreateManualKnowledge)
+		kb.POST("/manual", requireKnowledgeIngest, idempotency, handler.CreateManualKnowledge)
 		// Get knowledge list under knowledge base
-		kb.GET("", handler.ListKnowledge)
+		kb.GET("", requireKnowledgeRead, handler.ListKnowledge)
 	}
 
 	// Knowledge route group
 	k := r.Group("/knowledge")
 	{
 		// Batch get knowledge
-		k.GET("/batch", handler.GetKnowledgeBatch)
+		k.GET("/batch", requireKnowledgeRead, handler.GetKnowledgeBatch)
 		// Get knowledge details
-		k.GET("/:id", handler.GetKnowledge)
+		k.GET("/:id", requireKnowledgeRead, handler.GetKnowledge)
 		// Delete knowledge
-		k.DELETE("/:id", handler.DeleteKnowledge)
+		k.DELETE("/:id", requireKnowledgeWrite, handler.DeleteKnowledge)
 		// Update knowledge
-		k.PUT("/:id", handler.UpdateKnowledge)
+		k.PUT("/:id", requireKnowledgeWrite, handler.UpdateKnowledge)
 		// Update manual Markdown knowledge
-		k.PUT("/manual/:id", handler.UpdateManualKnowledge)
+		k.PUT("/manual/:id", requireKnowledgeWrite, handler.UpdateManualKnowledge)
 		// Get knowledge file
-		k.GET("/:id/download", handler.DownloadKnowledgeFile)
+		k.GET("/:id/download", requireKnowledgeRead, handler.DownloadKnowledgeFile)
 		// Update image chunk info
-		k.PUT("/image/:id/:chunk_id", handler.UpdateImageInfo)
+		k.PUT("/image/:id/:chunk_id", requireKnowledgeWrite, handler.UpdateImageInfo)
 		// Batch update knowledge tags
-		k.PUT("/tags", handler.UpdateKnowledgeTagBatch)
+		k.PUT("/tags", requireKnowledgeWrite, handler.UpdateKnowledgeTagBatch)
+		// Batch enable/disable knowledge for retrieval
+		k.PUT("/enabled", requireKnowledgeWrite, handler.UpdateKnowledgeEnabledBatch)
 		// Search knowledge
-		k.GET("/search", handler.SearchKnowledge)
+		k.GET("/search", requireKnowledgeSearch, handler.SearchKnowledge)
+		// List supported upload formats
+		k.GET("/supported-formats", requireKnowledgeReadOrIngest, handler.GetSupportedFormats)
 	}
 }
 
@@ -183,25 +256,28 @@ func RegisterFAQRoutes(r *gin.RouterGroup, handler *handler.FAQHandler) {
 	}
 	faq := r.Group("/knowledge-bases/:id/faq")
 	{
-		faq.GET("/entries", handler.ListEntries)
-		faq.GET("/entries/export", handler.ExportEntries)
-		faq.GET("/entries/:entry_id", handler.GetEntry)
-		faq.POST("/entries", handler.UpsertEntries)
-		faq.POST("/entry", handler.CreateEntry)
-		faq.PUT("/entries/:entry_id", handler.UpdateEntry)
-		faq.POST("/entries/:entry_id/similar-questions", handler.AddSimilarQuestions)
+		faq.GET("/entries", requireKnowledgeRead, handler.ListEntries)
+		faq.GET("/entries/export", requireKnowledgeRead, handler.ExportEntries)
+		faq.GET("/entries/:entry_id", requireKnowledgeRead, handler.GetEntry)
+		faq.POST("/entries", requireKnowledgeWrite, handler.UpsertEntries)
+		faq.POST("/entry", requireKnowledgeWrite, handler.CreateEntry)
+		faq.PUT("/entries/:entry_id", requireKnowledgeWrite, handler.UpdateEntry)
+		faq.POST("/entries/:entry_id/similar-questions", requireKnowledgeWrite, handler.AddSimilarQuestions)
+		faq.POST("/entries/generate-similar", requireKnowledgeWrite, handler.GenerateSimilarQuestions)
+		faq.GET("/entries/generate-similar/:task_id", requireKnowledgeRead, handler.GetSimilarQuestionsProgress)
+		faq.POST("/entries/generate-similar/:task_id/apply", requireKnowledgeWrite, handler.ApplySimilarQuestions)
 		// Unified batch update API - supports is_enabled, is_recommended, tag_id
-		faq.PUT("/entries/fields", handler.UpdateEntryFieldsBatch)
-		faq.PUT("/entries/tags", handler.UpdateEntryTagBatch)
-		faq.DELETE("/entries", handler.DeleteEntries)
-		faq.POST("/search", handler.SearchFAQ)
+		faq.PUT("/entries/fields", requireKnowledgeWrite, handler.UpdateEntryFieldsBatch)
+		faq.PUT("/entries/tags", requireKnowledgeWrite, handler.UpdateEntryTagBatch)
+		faq.DELETE("/entries", requireKnowledgeWrite, handler.DeleteEntries)
+		faq.POST("/search", requireKnowledgeSearch, handler.SearchFAQ)
 		// FAQ import result display status
-		faq.PUT("/import/last-result/display", handler.UpdateLastImportResultDisplayStatus)
+		faq.PUT("/import/last-result/display", requireKnowledgeWrite, handler.UpdateLastImportResultDisplayStatus)
 	}
 	// FAQ import progress route (outside of knowledge-base scope)
 	faqImport := r.Group("/faq/import")
 	{
-		faqImport.GET("/progress/:task_id", handler.GetImportProgress)
+		faqImport.GET("/progress/:task_id", requireKnowledgeRead, handler.GetImportProgress)
 	}
 }
 
@@ -211,21 +287,40 @@ func RegisterKnowledgeBaseRoutes(r *gin.RouterGroup, handler *handler.KnowledgeB
 	kb := r.Group("/knowledge-bases")
 	{
 		// Create knowledge base
-		kb.POST("", handler.CreateKnowledgeBase)
+		kb.POST("", requireKnowledgeWrite, handler.CreateKnowledgeBase)
 		// Get knowledge base list
-		kb.GET("", handler.ListKnowledgeBases)
+		kb.GET("", requireKnowledgeRead, handler.ListKnowledgeBases)
 		// Get knowledge base details
-		kb.GET("/:id", handler.GetKnowledgeBase)
+		kb.GET("/:id", requireKnowledgeRead, handler.GetKnowledgeBase)
 		// Update knowledge base
-		kb.PUT("/:id", handler.UpdateKnowledgeBase)
+		kb.PUT("/:id", requireKnowledgeWrite, handler.UpdateKnowledgeBase)
 		// Delete knowledge base
-		kb.DELETE("/:id", handler.DeleteKnowledgeBase)
+		kb.DELETE("/:id", requireKnowledgeWrite, handler.DeleteKnowledgeBase)
 		// Hybrid search
-		kb.GET("/:id/hybrid-search", handler.HybridSearch)
+		kb.GET("/:id/hybrid-search", requireKnowledgeSearch, handler.HybridSearch)
+		// Invalidate cached answers
+		kb.POST("/:id/cache/invalidate", requireKnowledgeWrite, handler.InvalidateAnswerCache)
+		// Pinned source rules
+		kb.GET("/:id/pinned-sources", requireKnowledgeRead, handler.ListPinnedSources)
+		kb.POST("/:id/pinned-sources", requireKnowledgeWrite, handler.CreatePinnedSource)
+		kb.PUT("/:id/pinned-sources/:rule_id", requireKnowledgeWrite, handler.UpdatePinnedSource)
+		kb.DELETE("/:id/pinned-sources/:rule_id", requireKnowledgeWrite, handler.DeletePinnedSource)
 		// Copy knowledge base
-		kb.POST("/copy", handler.CopyKnowledgeBase)
+		kb.POST("/copy", requireKnowledgeWrite, handler.CopyKnowledgeBase)
 		// Get knowledge base copy progress
-		kb.GET("/copy/progress/:task_id", handler.GetKBCloneProgress)
+		kb.GET("/copy/progress/:task_id", requireKnowledgeRead, handler.GetKBCloneProgress)
+		// Export knowledge base as a portable archive
+		kb.GET("/:id/export", requireKnowledgeRead, handler.ExportKnowledgeBase)
+		// Import knowledge base archive, progress is read like a copy
+		kb.POST("/import", requireKnowledgeWrite, handler.ImportKnowledgeBase)
+		// Merge knowledge base into another
+		kb.POST("/merge", requireKnowledgeWrite, handler.MergeKnowledgeBases)
+		// Get knowledge base merge progress
+		kb.GET("/merge/progress/:task_id", requireKnowledgeRead, handler.GetKBMergeProgress)
+		// Reprocess failed knowledge
+		kb.POST("/:id/reprocess-failed", requireKnowledgeWrite, handler.ReprocessFailedKnowledge)
+		// Get reprocess progress
+		kb.GET("/reprocess/progress/:task_id", requireKnowledgeRead, handler.GetKnowledgeReprocessProgress)
 	}
 }
 
@@ -236,28 +331,32 @@ func RegisterKnowledgeTagRoutes(r *gin.RouterGroup, tagHandler *handler.TagHandl
 	}
 	kbTags := r.Group("/knowledge-bases/:id/tags")
 	{
-		kbTags.GET("", tagHandler.ListTags)
-		kbTags.POST("", tagHandler.CreateTag)
-		kbTags.PUT("/:tag_id", tagHandler.UpdateTag)
-		kbTags.DELETE("/:tag_id", tagHandler.DeleteTag)
+		kbTags.GET("", requireKnowledgeRead, tagHandler.ListTags)
+		kbTags.POST("", requireKnowledgeWrite, tagHandler.CreateTag)
+		kbTags.PUT("/:tag_id", requireKnowledgeWrite, tagHandler.UpdateTag)
+		kbTags.DELETE("/:tag_id", requireKnowledgeWrite, tagHandler.DeleteTag)
 	}
 }
 
 // RegisterMessageRoutes registers message-related routes
 func RegisterMessageRoutes(r *gin.RouterGroup, handler *handler.MessageHandler) {
 	// Message route group
-	messages := r.Group("/messages")
+	messages := r.Group("/messages", requireChat)
 	{
 		// Load earlier messages for scroll-up loading
 		messages.GET("/:session_id/load", handler.LoadMessages)
+		// Get a message, e.g. the answer of an async knowledge chat request
+		messages.GET("/:session_id/:id", handler.GetMessage)
 		// Delete message
 		messages.DELETE("/:session_id/:id", handler.DeleteMessage)
+		// Rate an answer; the rating is sent to the analytics sink
+		messages.POST("/:session_id/:id/feedback", handler.GiveFeedback)
 	}
 }
 
 // RegisterSessionRoutes registers routes
 func RegisterSessionRoutes(r *gin.RouterGroup, handler *session.Handler) {
-	sessions := r.Group("/sessions")
+	sessions := r.Group("/sessions", requireChat)
 	{
 		sessions.POST("", handler.CreateSession)
 		sessions.GET("/:id", handler.GetSession)
@@ -269,54 +368,84 @@ func RegisterSessionRoutes(r *gin.RouterGroup, handler *session.Handler) {
 		// Continue receiving active stream
 		sessions.GET("/continue-stream/:session_id", handler.ContinueStream)
 	}
+
+	// Regenerate an answer from its stored references
+	r.POST("/messages/:session_id/:id/regenerate", requireChat, handler.RegenerateAnswer)
 }
 
 // RegisterChatRoutes registers routes
 func RegisterChatRoutes(r *gin.RouterGroup, handler *session.Handler) {
-	knowledgeChat := r.Group("/knowledge-chat")
+	knowledgeChat := r.Group("/knowledge-chat", requireChat)
 	{
 		knowledgeChat.POST("/:session_id", handler.KnowledgeQA)
+		// Same answers over a WebSocket, several at a time
+		knowledgeChat.GET("/ws/:session_id", handler.KnowledgeChatWebSocket)
 	}
 
 	// Agent-based chat
-	agentChat := r.Group("/agent-chat")
+	agentChat := r.Group("/agent-chat", requireChat)
 	{
 		agentChat.POST("/:session_id", handler.AgentQA)
 	}
 
 	// New knowledge retrieval interface, does not require session_id
-	knowledgeSearch := r.Group("/knowledge-search")
+	knowledgeSearch := r.Group("/knowledge-search", requireKnowledgeSearch)
 	{
 		knowledgeSearch.POST("", handler.SearchKnowledge)
+		// Hybrid search across several knowledge bases, merged into a single ranking
+		knowledgeSearch.POST("/hybrid", handler.MultiHybridSearch)
+		knowledgeSearch.POST("/confidence-gate/evaluate", handler.EvaluateConfidenceGate)
 	}
 }
 
 // RegisterTenantRoutes registers tenant-related routes
-func RegisterTenantRoutes(r *gin.RouterGroup, handler *handler.TenantHandler) {
-	// Add route to get all tenants (requires cross-tenant permission)
-	r.GET("/tenants/all", handler.ListAllTenants)
-	// Add route to search tenants (requires cross-tenant permission, supports pagination and search)
-	r.GET("/tenants/search", handler.SearchTenants)
+// In single-tenant mode the routes creating, listing, deleting or switching tenants are not registered.
+func RegisterTenantRoutes(r *gin.RouterGroup, handler *handler.TenantHandler, singleTenant bool) {
+	if !singleTenant {
+		// Add route to get all tenants (requires cross-tenant permission)
+		r.GET("/tenants/all", requireAdmin, handler.ListAllTenants)
+		// Add route to search tenants (requires cross-tenant permission, supports pagination and search)
+		r.GET("/tenants/search", requireAdmin, handler.SearchTenants)
+	}
 	// Tenant route group
-	tenantRoutes := r.Group("/tenants")
+	tenantRoutes := r.Group("/tenants", requireAdmin)
 	{
-		tenantRoutes.POST("", handler.CreateTenant)
+		if !singleTenant {
+			tenantRoutes.POST("", handler.CreateTenant)
+			tenantRoutes.DELETE("/:id", handler.DeleteTenant)
+			tenantRoutes.GET("", handler.ListTenants)
+
+			// Data export and purge, require cross-tenant permission
+			tenantRoutes.POST("/:id/export", handler.ExportTenantData)
+			tenantRoutes.GET("/:id/export/:task_id", handler.GetTenantExportProgress)
+			tenantRoutes.POST("/:id/export/:task_id/resume", handler.ResumeTenantExport)
+			tenantRoutes.GET("/:id/export/:task_id/parts/:part", handler.DownloadTenantExportPart)
+			tenantRoutes.POST("/:id/purge/confirmation", handler.ConfirmTenantPurge)
+			tenantRoutes.POST("/:id/purge", handler.PurgeTenantData)
+			tenantRoutes.GET("/:id/purge/:task_id", handler.GetTenantPurgeProgress)
+			tenantRoutes.POST("/:id/purge/:task_id/retry", handler.RetryTenantPurge)
+		}
 		tenantRoutes.GET("/:id", handler.GetTenant)
 		tenantRoutes.PUT("/:id", handler.UpdateTenant)
-		tenantRoutes.DELETE("/:id", handler.DeleteTenant)
-		tenantRoutes.GET("", handler.ListTenants)
+
+		// Feature flags, updates require cross-tenant permission
+		tenantRoutes.GET("/:id/features", handler.GetTenantFeatures)
+		tenantRoutes.PUT("/:id/features", handler.UpdateTenantFeatures)
 
 		// Generic KV configuration management (tenant-level)
 		// Tenant ID is obtained from authentication context
 		tenantRoutes.GET("/kv/:key", handler.GetTenantKV)
 		tenantRoutes.PUT("/kv/:key", handler.UpdateTenantKV)
+
+		// Usage of the current tenant against its quota and limits
+		tenantRoutes.GET("/usage", handler.GetTenantUsage)
 	}
 }
 
 // RegisterModelRoutes registers model-related routes
 func RegisterModelRoutes(r *gin.RouterGroup, handler *handler.ModelHandler) {
 	// Model route group
-	models := r.Group("/models")
+	models := r.Group("/models", requireAdmin)
 	{
 		// Get model provider list
 		models.GET("/providers", handler.ListModelProviders)
@@ -324,6 +453,8 @@ func RegisterModelRoutes(r *gin.RouterGroup, handler *handler.ModelHandler) {
 		models.POST("", handler.CreateModel)
 		// Get model list
 		models.GET("", handler.ListModels)
+		// Delete models in batch
+		models.POST("/batch-delete", handler.BatchDeleteModels)
 		// Get single model
 		models.GET("/:id", handler.GetModel)
 		// Update model
@@ -334,10 +465,13 @@ func RegisterModelRoutes(r *gin.RouterGroup, handler *handler.ModelHandler) {
 }
 
 func RegisterEvaluationRoutes(r *gin.RouterGroup, handler *handler.EvaluationHandler) {
-	evaluationRoutes := r.Group("/evaluation")
+	evaluationRoutes := r.Group("/evaluation", requireAdmin)
 	{
 		evaluationRoutes.POST("/", handler.Evaluation)
 		evaluationRoutes.GET("/", handler.GetEvaluationResult)
+		evaluationRoutes.GET("/stream/:task_id", handler.StreamEvaluationResult)
+		evaluationRoutes.POST("/:task_id/pause", handler.PauseEvaluation)
+		evaluationRoutes.POST("/:task_id/resume", handler.ResumeEvaluation)
 	}
 }
 
@@ -350,9 +484,22 @@ func RegisterAuthRoutes(r *gin.RouterGroup, handler *handler.AuthHandler) {
 	r.POST("/auth/logout", handler.Logout)
 	r.GET("/auth/me", handler.GetCurrentUser)
 	r.POST("/auth/change-password", handler.ChangePassword)
+	// Login with OpenID Connect identity providers
+	r.GET("/auth/oidc/providers", handler.ListOIDCProviders)
+	r.GET("/auth/oidc/:provider/authorize", handler.AuthorizeOIDC)
+	r.POST("/auth/oidc/:provider/callback", handler.OIDCCallback)
+	r.POST("/auth/oidc/two-factor", handler.OIDCTwoFactor)
+	// Two-factor authentication of the current user
+	r.POST("/auth/2fa/setup", handler.SetupTwoFactor)
+	r.POST("/auth/2fa/verify", handler.VerifyTwoFactor)
+	r.POST("/auth/2fa/disable", handler.DisableTwoFactor)
+	r.POST("/auth/2fa/recovery-codes", handler.RegenerateRecoveryCodes)
 }
 
 func RegisterInitializationRoutes(r *gin.RouterGroup, handler *handler.InitializationHandler) {
+	// Initialization configures models for the whole tenant
+	r = r.Group("", requireAdmin)
+
 	// Initialization interface
 	r.GET("/initialization/config/:kbId", handler.GetCurrentConfigByKB)
 	r.POST("/initialization/initialize/:kbId", handler.InitializeByKB)
@@ -379,16 +526,63 @@ func RegisterInitializationRoutes(r *gin.RouterGroup, handler *handler.Initializ
 
 // RegisterSystemRoutes registers system information routes
 func RegisterSystemRoutes(r *gin.RouterGroup, handler *handler.SystemHandler) {
-	systemRoutes := r.Group("/system")
+	systemRoutes := r.Group("/system", requireAdmin)
 	{
 		systemRoutes.GET("/info", handler.GetSystemInfo)
 		systemRoutes.GET("/minio/buckets", handler.ListMinioBuckets)
+		systemRoutes.GET("/model-queue", handler.GetModelQueueStats)
+		systemRoutes.GET("/database/replicas", handler.GetDatabaseReplicas)
+		// Effective configuration (admin only)
+		systemRoutes.GET("/config", handler.GetEffectiveConfig)
 	}
 }
 
+// RegisterProviderLogRoutes registers captured model provider call routes (admin only)
+func RegisterProviderLogRoutes(r *gin.RouterGroup, handler *handler.ProviderLogHandler) {
+	r.GET("/system/provider-logs/:request_id", requireAdmin, handler.GetProviderLogs)
+}
+
+// RegisterAuditRoutes registers the audit log routes
+func RegisterAuditRoutes(r *gin.RouterGroup, handler *handler.AuditHandler) {
+	auditLogs := r.Group("/audit-logs", requireAdmin)
+	{
+		auditLogs.GET("", handler.ListAuditLogs)
+		auditLogs.GET("/:id", handler.GetAuditLog)
+	}
+	// Deprecated: the first path of the audit log, kept for existing clients
+	r.GET("/audit", requireAdmin, handler.ListAuditLogs)
+}
+
+// RegisterRoleRoutes registers the routes of the user roles; every user may read the roles and their own
+func RegisterRoleRoutes(r *gin.RouterGroup, handler *handler.RoleHandler) {
+	roles := r.Group("/roles")
+	{
+		roles.GET("", handler.ListRoles)
+		roles.GET("/me", handler.GetCurrentRole)
+		roles.GET("/assignments", requireAdmin, handler.ListRoleAssignments)
+		roles.PUT("/assignments/:user_id", requireAdmin, handler.AssignRole)
+		roles.DELETE("/assignments/:user_id", requireAdmin, handler.RemoveRole)
+	}
+}
+
+// RegisterAPIKeyRoutes registers the routes managing the API keys of the tenant (admin only)
+func RegisterAPIKeyRoutes(r *gin.RouterGroup, handler *handler.APIKeyHandler) {
+	apiKeys := r.Group("/api-keys", requireAdmin)
+	{
+		apiKeys.POST("", handler.CreateAPIKey)
+		apiKeys.GET("", handler.ListAPIKeys)
+		apiKeys.DELETE("/:id", handler.RevokeAPIKey)
+	}
+}
+
+// RegisterOpenAICompatRoutes registers the OpenAI-compatible API routes
+func RegisterOpenAICompatRoutes(r *gin.RouterGroup, handler *handler.OpenAICompatHandler) {
+	r.GET("/models", requireChat, handler.ListModels)
+}
+
 // RegisterMCPServiceRoutes registers MCP service routes
 func RegisterMCPServiceRoutes(r *gin.RouterGroup, handler *handler.MCPServiceHandler) {
-	mcpServices := r.Group("/mcp-services")
+	mcpServices := r.Group("/mcp-services", requireAdmin)
 	{
 		// Create MCP service
 		mcpServices.POST("", handler.CreateMCPService)
@@ -406,13 +600,15 @@ func RegisterMCPServiceRoutes(r *gin.RouterGroup, handler *handler.MCPServiceHan
 		mcpServices.GET("/:id/tools", handler.GetMCPServiceTools)
 		// Get MCP service resources
 		mcpServices.GET("/:id/resources", handler.GetMCPServiceResources)
+		// Get MCP service tool health report
+		mcpServices.GET("/:id/health", handler.GetMCPServiceHealth)
 	}
 }
 
 // RegisterWebSearchRoutes registers web search routes
 func RegisterWebSearchRoutes(r *gin.RouterGroup, webSearchHandler *handler.WebSearchHandler) {
 	// Web search providers
-	webSearch := r.Group("/web-search")
+	webSearch := r.Group("/web-search", requireChat)
 	{
 		// Get available providers
 		webSearch.GET("/providers", webSearchHandler.GetProviders)
@@ -424,18 +620,36 @@ func RegisterCustomAgentRoutes(r *gin.RouterGroup, agentHandler *handler.CustomA
 	agents := r.Group("/agents")
 	{
 		// Get placeholder definitions (must be before /:id to avoid conflict)
-		agents.GET("/placeholders", agentHandler.GetPlaceholders)
+		agents.GET("/placeholders", requireChat, agentHandler.GetPlaceholders)
 		// Create custom agent
-		agents.POST("", agentHandler.CreateAgent)
+		agents.POST("", requireAdmin, agentHandler.CreateAgent)
 		// List all agents (including built-in)
-		agents.GET("", agentHandler.ListAgents)
+		agents.GET("", requireChat, agentHandler.ListAgents)
 		// Get agent by ID
-		agents.GET("/:id", agentHandler.GetAgent)
+		agents.GET("/:id", requireChat, agentHandler.GetAgent)
 		// Update agent
-		agents.PUT("/:id", agentHandler.UpdateAgent)
+		agents.PUT("/:id", requireAdmin, agentHandler.UpdateAgent)
 		// Delete agent
-		agents.DELETE("/:id", agentHandler.DeleteAgent)
+		agents.DELETE("/:id", requireAdmin, agentHandler.DeleteAgent)
 		// Copy agent
-		agents.POST("/:id/copy", agentHandler.CopyAgent)
+		agents.POST("/:id/copy", requireAdmin, agentHandler.CopyAgent)
+		// Version management: list, diff, publish and roll back
+		agents.GET("/:id/versions", requireChat, agentHandler.ListAgentVersions)
+		agents.GET("/:id/versions/diff", requireChat, agentHandler.DiffAgentVersions)
+		agents.POST("/:id/versions/:version/publish", requireAdmin, agentHandler.PublishAgentVersion)
+		agents.POST("/:id/rollback", requireAdmin, agentHandler.RollbackAgent)
+		// Problems of the MCP services used by the agent
+		agents.GET("/:id/mcp-status", requireChat, agentHandler.GetAgentMCPStatus)
+	}
+}
+
+// RegisterAgentRunRoutes registers agent execution trace routes (admin only)
+func RegisterAgentRunRoutes(r *gin.RouterGroup, runHandler *handler.AgentRunHandler) {
+	runs := r.Group("/agents/runs", requireAdmin)
+	{
+		// Get the execution trace of the run that produced a message
+		runs.GET("/:message_id", runHandler.GetAgentRun)
+		// Re-execute a recorded run for debugging
+		runs.POST("/:message_id/replay", runHandler.ReplayAgentRun)
 	}
 }
@@ -1,11 +1,14 @@
 package router
 
 import (
+	"context"
 	"log"
 	"os"
 	"strconv"
 	"time"
 
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/models/scheduler"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	"github.com/hibiken/asynq"
@@ -18,6 +21,7 @@ type AsynqTaskParams struct {
 	Server               *asynq.Server
 	KnowledgeService     interfaces.KnowledgeService
 	KnowledgeBaseService interfaces.KnowledgeBaseService
+	TenantDataService    interfaces.TenantDataService
 	TagService           interfaces.KnowledgeTagService
 	ChunkExtracter       interfaces.TaskHandler `name:"chunkExtracter"`
 	DataTableSummary     interfaces.TaskHandler `name:"dataTableSummary"`
@@ -50,8 +54,17 @@ func NewAsyncqClient() (*asynq.Client, error) {
 	return client, nil
 }
 
-func NewAsynqServer() *asynq.Server {
+// NewAsynqInspector creates an inspector of the task queues, used to report their depth
+func NewAsynqInspector() *asynq.Inspector {
+	return asynq.NewInspector(getAsynqRedisClientOpt())
+}
+
+func NewAsynqServer(cfg *config.Config) *asynq.Server {
 	opt := getAsynqRedisClientOpt()
+	var retryConfig *config.IngestionRetryConfig
+	if cfg != nil && cfg.KnowledgeBase != nil {
+		retryConfig = cfg.KnowledgeBase.IngestionRetry
+	}
 	srv := asynq.NewServer(
 		opt,
 		asynq.Config{
@@ -60,15 +73,47 @@ func NewAsynqServer() *asynq.Server {
 				"default":  3, // Default priority queue
 				"low":      1, // Lowest priority queue
 			},
+			RetryDelayFunc: func(n int, err error, t *asynq.Task) time.Duration {
+				if t.Type() == types.TypeDocumentProcess {
+					return ingestionRetryDelay(retryConfig, n)
+				}
+				return asynq.DefaultRetryDelayFunc(n, err, t)
+			},
 		},
 	)
 	return srv
 }
 
+// ingestionRetryDelay returns the exponential backoff before retry n (counted from 0) of a document processing task
+func ingestionRetryDelay(cfg *config.IngestionRetryConfig, n int) time.Duration {
+	baseDelay, maxDelay := 10*time.Second, 5*time.Minute
+	if cfg != nil && cfg.BaseDelay > 0 {
+		baseDelay = cfg.BaseDelay
+	}
+	if cfg != nil && cfg.MaxDelay > 0 {
+		maxDelay = cfg.MaxDelay
+	}
+	delay := baseDelay
+	for i := 0; i < n && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// bulkModelPriority marks model calls made by task handlers as bulk work
+func bulkModelPriority(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		return next.ProcessTask(scheduler.WithPriority(ctx, scheduler.PriorityBulk), t)
+	})
+}
+
 func RunAsynqServer(params AsynqTaskParams) *asynq.ServeMux {
 	// Create a new mux and register all handlers
 	mux := asynq.NewServeMux()
 
+	// Background tasks run model calls at bulk priority so interactive requests go first
+	mux.Use(bulkModelPriority)
+
 	// Register extract handlers - router will dispatch to appropriate handler
 	mux.HandleFunc(types.TypeChunkExtract, params.ChunkExtracter.Handle)
 	mux.HandleFunc(types.TypeDataTableSummary, params.DataTableSummary.Handle)
@@ -79,6 +124,9 @@ func RunAsynqServer(params AsynqTaskParams) *asynq.ServeMux {
 	// Register FAQ import handler (includes dry run mode)
 	mux.HandleFunc(types.TypeFAQImport, params.KnowledgeService.ProcessFAQImport)
 
+	// Register FAQ similar question generation handler
+	mux.HandleFunc(types.TypeFAQSimilarQuestion, params.KnowledgeService.ProcessFAQSimilarGeneration)
+
 	// Register question generation handler
 	mux.HandleFunc(types.TypeQuestionGeneration, params.KnowledgeService.ProcessQuestionGeneration)
 
@@ -88,6 +136,11 @@ func RunAsynqServer(params AsynqTaskParams) *asynq.ServeMux {
 	// Register KB clone handler
 	mux.HandleFunc(types.TypeKBClone, params.KnowledgeService.ProcessKBClone)
 
+	// Register failed knowledge reprocess handler
+	mux.HandleFunc(types.TypeKnowledgeReprocess, params.KnowledgeService.ProcessKnowledgeReprocess)
+	mux.HandleFunc(types.TypeKBMerge, params.KnowledgeService.ProcessKBMerge)
+	mux.HandleFunc(types.TypeKBImport, params.KnowledgeService.ProcessKBImport)
+
 	// Register knowledge list delete handler
 	mux.HandleFunc(types.TypeKnowledgeListDelete, params.KnowledgeService.ProcessKnowledgeListDelete)
 
@@ -97,6 +150,10 @@ func RunAsynqServer(params AsynqTaskParams) *asynq.ServeMux {
 	// Register KB delete handler
 	mux.HandleFunc(types.TypeKBDelete, params.KnowledgeBaseService.ProcessKBDelete)
 
+	// Register tenant data export and purge handlers
+	mux.HandleFunc(types.TypeTenantExport, params.TenantDataService.ProcessTenantExport)
+	mux.HandleFunc(types.TypeTenantPurge, params.TenantDataService.ProcessTenantPurge)
+
 	go func() {
 		// Start the server
 		if err := params.Server.Run(mux); err != nil {
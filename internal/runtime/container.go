@@ -3,21 +3,48 @@
 package runtime
 
 import (
+	"context"
+	"sync/atomic"
+
 	"go.uber.org/dig"
 )
 
 // container is the application's global dependency injection container
 // All services and components are registered and resolved through it
-var container *dig.Container
+var container atomic.Pointer[dig.Container]
+
+// containerContextKey is the context key of the container set by WithContainer
+type containerContextKey struct{}
 
 // init initializes the dependency injection container
 // Called automatically when the program starts
 func init() {
-	container = dig.New()
+	container.Store(dig.New())
 }
 
 // GetContainer returns a reference to the global dependency injection container
 // Used by other packages to register or retrieve services
 func GetContainer() *dig.Container {
-	return container
+	return container.Load()
+}
+
+// NewScope returns a new container isolated from the global one and from other scopes
+// Tests register their fakes in a scope of their own, which is safe with t.Parallel()
+func NewScope() *dig.Container {
+	return dig.New()
+}
+
+// WithContainer runs fn with a context carrying c, for code that resolves the container with
+// ContainerFromContext. The scope only exists on that context, so parallel tests each see their own.
+func WithContainer(ctx context.Context, c *dig.Container, fn func(ctx context.Context)) {
+	fn(context.WithValue(ctx, containerContextKey{}, c))
+}
+
+// ContainerFromContext returns the container set on the context by WithContainer, or else the
+// global container
+func ContainerFromContext(ctx context.Context) *dig.Container {
+	if c, ok := ctx.Value(containerContextKey{}).(*dig.Container); ok && c != nil {
+		return c
+	}
+	return GetContainer()
 }
@@ -0,0 +1,77 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/dig"
+)
+
+// resolve returns the string provided by c
+func resolve(t *testing.T, c *dig.Container) string {
+	t.Helper()
+	var got string
+	if err := c.Invoke(func(s string) { got = s }); err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	return got
+}
+
+func TestNewScopeIsolation(t *testing.T) {
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c := NewScope()
+			// Both scopes provide a string, which would conflict in a shared container
+			if err := c.Provide(func() string { return name }); err != nil {
+				t.Fatalf("Provide() error = %v", err)
+			}
+			if got := resolve(t, c); got != name {
+				t.Errorf("resolved %q, want %q", got, name)
+			}
+			if c == GetContainer() {
+				t.Error("scope is the global container")
+			}
+		})
+	}
+}
+
+func TestWithContainer(t *testing.T) {
+	global := GetContainer()
+	for _, name := range []string{"first", "second", "third"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c := NewScope()
+			if err := c.Provide(func() string { return name }); err != nil {
+				t.Fatalf("Provide() error = %v", err)
+			}
+			WithContainer(context.Background(), c, func(ctx context.Context) {
+				if ContainerFromContext(ctx) != c {
+					t.Fatalf("ContainerFromContext() in %s is not its scope", name)
+				}
+				if got := resolve(t, ContainerFromContext(ctx)); got != name {
+					t.Errorf("resolved %q, want %q", got, name)
+				}
+				if GetContainer() != global {
+					t.Error("WithContainer changed the global container")
+				}
+			})
+		})
+	}
+
+	if ContainerFromContext(context.Background()) != global {
+		t.Error("ContainerFromContext() without a scope is not the global container")
+	}
+}
+
+func TestReset(t *testing.T) {
+	if err := GetContainer().Provide(func() int { return 1 }); err != nil {
+		t.Fatalf("Provide() error = %v", err)
+	}
+	Reset()
+	// The provider was dropped with the previous container, so it can be registered again
+	if err := GetContainer().Provide(func() int { return 2 }); err != nil {
+		t.Fatalf("Provide() after Reset() error = %v", err)
+	}
+	Reset()
+}
@@ -0,0 +1,9 @@
+package runtime
+
+import "go.uber.org/dig"
+
+// Reset replaces the global container with a new one, dropping everything registered in it
+// It is only compiled into the test binary of this package
+func Reset() {
+	container.Store(dig.New())
+}
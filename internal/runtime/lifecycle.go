@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+)
+
+// ShutdownHook stops a service or releases a resource when the application shuts down
+// It should return once done or when ctx is done
+type ShutdownHook func(ctx context.Context) error
+
+// namedHook is a shutdown hook with the name used in logs and errors
+type namedHook struct {
+	name string
+	hook ShutdownHook
+}
+
+// Lifecycle runs the shutdown hooks registered by the services of the application
+// Services register their hooks while they are constructed, after the services they depend on,
+// so running the hooks in reverse registration order stops every service before its dependencies
+type Lifecycle struct {
+	mu    sync.Mutex
+	hooks []namedHook
+	once  sync.Once
+	err   error
+}
+
+// lifecycle is the application's global lifecycle, shared by the services of the global container
+var lifecycle = &Lifecycle{}
+
+// OnShutdown registers a shutdown hook on the global lifecycle
+func OnShutdown(name string, hook ShutdownHook) {
+	lifecycle.OnShutdown(name, hook)
+}
+
+// Shutdown runs the shutdown hooks of the global lifecycle
+func Shutdown(ctx context.Context) error {
+	return lifecycle.Shutdown(ctx)
+}
+
+// OnShutdown registers a shutdown hook
+// Note: the hooks are run in reverse order (the last registered is run first)
+func (l *Lifecycle) OnShutdown(name string, hook ShutdownHook) {
+	if hook == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hooks = append(l.hooks, namedHook{name: name, hook: hook})
+}
+
+// Shutdown runs the registered hooks in reverse order and returns their errors joined
+// Every hook is run, even when a previous one fails or panics. Once ctx is done, Shutdown no longer
+// waits for a hook to return and records the context error for it instead.
+// Only the first call runs the hooks; later calls wait for it and return the same error.
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.once.Do(func() {
+		l.mu.Lock()
+		hooks := slices.Clone(l.hooks)
+		l.mu.Unlock()
+
+		var errs []error
+		for i := len(hooks) - 1; i >= 0; i-- {
+			log.Printf("Shutting down: %s", hooks[i].name)
+			if err := runHook(ctx, hooks[i].hook); err != nil {
+				log.Printf("Error shutting down %s: %v", hooks[i].name, err)
+				errs = append(errs, fmt.Errorf("shutdown %s: %w", hooks[i].name, err))
+			}
+		}
+		l.err = errors.Join(errs...)
+	})
+	return l.err
+}
+
+// runHook runs a hook, turning a panic into an error, and waits for it until ctx is done
+func runHook(ctx context.Context, hook ShutdownHook) error {
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		result <- hook(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycleShutdown(t *testing.T) {
+	var order []string
+	record := func(name string, err error) ShutdownHook {
+		return func(context.Context) error {
+			order = append(order, name)
+			return err
+		}
+	}
+	errPool := errors.New("pool busy")
+
+	l := &Lifecycle{}
+	l.OnShutdown("database", record("database", nil))
+	l.OnShutdown("pool", record("pool", errPool))
+	l.OnShutdown("cache", func(context.Context) error {
+		order = append(order, "cache")
+		panic("cache gone")
+	})
+	l.OnShutdown("server", record("server", nil))
+	l.OnShutdown("nil", nil)
+
+	err := l.Shutdown(context.Background())
+	if want := []string{"server", "cache", "pool", "database"}; !slices.Equal(order, want) {
+		t.Errorf("hooks ran in order %v, want %v", order, want)
+	}
+	if !errors.Is(err, errPool) || !strings.Contains(err.Error(), "shutdown cache: panic: cache gone") {
+		t.Errorf("Shutdown() = %v, want the pool error and the cache panic", err)
+	}
+
+	if again := l.Shutdown(context.Background()); again != err || len(order) != 4 {
+		t.Errorf("second Shutdown() = %v and ran hooks %v, want the first error without running hooks", again, order)
+	}
+}
+
+func TestLifecycleShutdownDeadline(t *testing.T) {
+	released := make(chan struct{})
+	l := &Lifecycle{}
+	l.OnShutdown("database", func(context.Context) error {
+		close(released)
+		return nil
+	})
+	l.OnShutdown("server", func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := l.Shutdown(ctx)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Shutdown() took %v, want it to stop waiting at the deadline", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() = %v, want a deadline error", err)
+	}
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Error("hook after the deadline was not run")
+	}
+}
@@ -14,8 +14,16 @@ const (
 	TypeRedis  = "redis"
 )
 
+// defaultMaxEvents is the number of events buffered per stream for reconnecting clients
+const defaultMaxEvents = 10000
+
 // NewStreamManager creates a stream manager
 func NewStreamManager() (interfaces.StreamManager, error) {
+	// STREAM_MAX_EVENTS caps the buffered events per stream, 0 keeps all events until the stream expires
+	maxEvents, err := strconv.Atoi(os.Getenv("STREAM_MAX_EVENTS"))
+	if err != nil || maxEvents < 0 {
+		maxEvents = defaultMaxEvents
+	}
 	switch os.Getenv("STREAM_MANAGER_TYPE") {
 	case TypeRedis:
 		db, err := strconv.Atoi(os.Getenv("REDIS_DB"))
@@ -29,8 +37,9 @@ func NewStreamManager() (interfaces.StreamManager, error) {
 			db,
 			os.Getenv("REDIS_PREFIX"),
 			ttl,
+			maxEvents,
 		)
 	default:
-		return NewMemoryStreamManager(), nil
+		return NewMemoryStreamManager(maxEvents), nil
 	}
 }
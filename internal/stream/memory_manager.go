@@ -10,7 +10,9 @@ import (
 
 // memoryStreamData holds stream events in memory
 type memoryStreamData struct {
-	events      []interfaces.StreamEvent
+	events []interfaces.StreamEvent
+	// dropped counts the events removed from the start of the buffer, the offset of events[0]
+	dropped     int
 	lastUpdated time.Time
 	mu          sync.RWMutex
 }
@@ -20,12 +22,16 @@ type MemoryStreamManager struct {
 	// Map: sessionID -> messageID -> stream data
 	streams map[string]map[string]*memoryStreamData
 	mu      sync.RWMutex
+	// maxEvents caps the events buffered per stream, 0 keeps all events
+	maxEvents int
 }
 
-// NewMemoryStreamManager creates a new in-memory stream manager
-func NewMemoryStreamManager() *MemoryStreamManager {
+// NewMemoryStreamManager creates a new in-memory stream manager that buffers at most maxEvents events per
+// stream (0 for no limit)
+func NewMemoryStreamManager(maxEvents int) *MemoryStreamManager {
 	return &MemoryStreamManager{
-		streams: make(map[string]map[string]*memoryStreamData),
+		streams:   make(map[string]map[string]*memoryStreamData),
+		maxEvents: maxEvents,
 	}
 }
 
@@ -75,8 +81,14 @@ func (m *MemoryStreamManager) AppendEvent(
 		event.Timestamp = time.Now()
 	}
 
-	// Append event
+	// Append event, dropping the oldest events beyond the buffer size
 	stream.events = append(stream.events, event)
+	if m.maxEvents > 0 && len(stream.events) > m.maxEvents {
+		excess := len(stream.events) - m.maxEvents
+		// Growing the slice later copies only the kept events
+		stream.events = stream.events[excess:]
+		stream.dropped += excess
+	}
 	stream.lastUpdated = time.Now()
 
 	return nil
@@ -98,14 +110,18 @@ func (m *MemoryStreamManager) GetEvents(
 	stream.mu.RLock()
 	defer stream.mu.RUnlock()
 
+	if fromOffset < stream.dropped {
+		return nil, stream.dropped, interfaces.ErrStreamEventsExpired
+	}
+
 	// Check if offset is beyond current events
-	if fromOffset >= len(stream.events) {
+	nextOffset := stream.dropped + len(stream.events)
+	if fromOffset >= nextOffset {
 		return []interfaces.StreamEvent{}, fromOffset, nil
 	}
 
 	// Get events from offset to end
-	events := stream.events[fromOffset:]
-	nextOffset := len(stream.events)
+	events := stream.events[fromOffset-stream.dropped:]
 
 	// Return copy of events to avoid race conditions
 	eventsCopy := make([]interfaces.StreamEvent, len(events))
@@ -0,0 +1,35 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+func TestMemoryStreamManagerBuffer(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStreamManager(3)
+	for _, content := range []string{"a", "b", "c", "d", "e"} {
+		if err := m.AppendEvent(ctx, "session-1", "msg-1", interfaces.StreamEvent{Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Offsets keep counting the dropped events
+	events, next, err := m.GetEvents(ctx, "session-1", "msg-1", 3)
+	if err != nil || next != 5 || len(events) != 2 || events[0].Content != "d" {
+		t.Fatalf("GetEvents(3) = %v, %d, %v; want d, e and offset 5", events, next, err)
+	}
+	events, next, err = m.GetEvents(ctx, "session-1", "msg-1", 5)
+	if err != nil || next != 5 || len(events) != 0 {
+		t.Fatalf("GetEvents(5) = %v, %d, %v; want no events", events, next, err)
+	}
+
+	// Events before the buffer report the first buffered offset
+	_, next, err = m.GetEvents(ctx, "session-1", "msg-1", 1)
+	if !errors.Is(err, interfaces.ErrStreamEventsExpired) || next != 2 {
+		t.Fatalf("GetEvents(1) = %d, %v; want ErrStreamEventsExpired at offset 2", next, err)
+	}
+}
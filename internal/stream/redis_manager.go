@@ -10,16 +10,43 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// appendEventScript appends an event and drops the oldest events beyond the buffer size, counting them in
+// the second key so that offsets stay positions in the whole stream
+var appendEventScript = redis.NewScript(`
+local length = redis.call('RPUSH', KEYS[1], ARGV[1])
+local maxEvents = tonumber(ARGV[2])
+if maxEvents > 0 and length > maxEvents then
+	redis.call('LTRIM', KEYS[1], length - maxEvents, -1)
+	redis.call('INCRBY', KEYS[2], length - maxEvents)
+end
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	redis.call('EXPIRE', KEYS[2], ARGV[3])
+end
+return length
+`)
+
+// getEventsScript returns the number of dropped events and the buffered events from an offset
+var getEventsScript = redis.NewScript(`
+local dropped = tonumber(redis.call('GET', KEYS[2]) or '0')
+local from = tonumber(ARGV[1])
+if from < dropped then
+	return {dropped, {}}
+end
+return {dropped, redis.call('LRANGE', KEYS[1], from - dropped, -1)}
+`)
+
 // RedisStreamManager implements StreamManager using Redis Lists for append-only event streaming
 type RedisStreamManager struct {
-	client *redis.Client
-	ttl    time.Duration // TTL for stream data in Redis
-	prefix string        // Redis key prefix
+	client    *redis.Client
+	ttl       time.Duration // TTL for stream data in Redis
+	prefix    string        // Redis key prefix
+	maxEvents int           // Events buffered per stream, 0 keeps all events
 }
 
 // NewRedisStreamManager creates a new Redis-based stream manager
 func NewRedisStreamManager(redisAddr, redisPassword string,
-	redisDB int, prefix string, ttl time.Duration,
+	redisDB int, prefix string, ttl time.Duration, maxEvents int,
 ) (*RedisStreamManager, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     redisAddr,
@@ -42,9 +69,10 @@ func NewRedisStreamManager(redisAddr, redisPassword string,
 	}
 
 	return &RedisStreamManager{
-		client: client,
-		ttl:    ttl,
-		prefix: prefix,
+		client:    client,
+		ttl:       ttl,
+		prefix:    prefix,
+		maxEvents: maxEvents,
 	}, nil
 }
 
@@ -53,14 +81,18 @@ func (r *RedisStreamManager) buildKey(sessionID, messageID string) string {
 	return fmt.Sprintf("%s:%s:%s", r.prefix, sessionID, messageID)
 }
 
+// buildKeys builds the Redis keys for the event list and its count of dropped events
+func (r *RedisStreamManager) buildKeys(sessionID, messageID string) []string {
+	key := r.buildKey(sessionID, messageID)
+	return []string{key, key + ":dropped"}
+}
+
 // AppendEvent appends a single event to the stream using Redis RPush
 func (r *RedisStreamManager) AppendEvent(
 	ctx context.Context,
 	sessionID, messageID string,
 	event interfaces.StreamEvent,
 ) error {
-	key := r.buildKey(sessionID, messageID)
-
 	// Set timestamp if not already set
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
@@ -72,16 +104,12 @@ func (r *RedisStreamManager) AppendEvent(
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	// Append to Redis list with RPush (O(1) operation)
-	if err := r.client.RPush(ctx, key, eventJSON).Err(); err != nil {
+	// Append to Redis list with RPush (O(1) operation), trim the buffer and refresh the TTL atomically
+	if err := appendEventScript.Run(ctx, r.client, r.buildKeys(sessionID, messageID),
+		eventJSON, r.maxEvents, int64(r.ttl/time.Second)).Err(); err != nil {
 		return fmt.Errorf("failed to append event to Redis: %w", err)
 	}
 
-	// Set/refresh TTL on the key
-	if err := r.client.Expire(ctx, key, r.ttl).Err(); err != nil {
-		return fmt.Errorf("failed to set TTL: %w", err)
-	}
-
 	return nil
 }
 
@@ -92,18 +120,20 @@ func (r *RedisStreamManager) GetEvents(
 	sessionID, messageID string,
 	fromOffset int,
 ) ([]interfaces.StreamEvent, int, error) {
-	key := r.buildKey(sessionID, messageID)
-
-	// Get all events from offset to end using LRange
+	// Get all events from offset to end using LRange, after the dropped events
 	// LRange is inclusive, so fromOffset to -1 gets all remaining elements
-	results, err := r.client.LRange(ctx, key, int64(fromOffset), -1).Result()
+	reply, err := getEventsScript.Run(ctx, r.client, r.buildKeys(sessionID, messageID), fromOffset).Slice()
 	if err != nil {
-		if err == redis.Nil {
-			// Key doesn't exist - return empty slice
-			return []interfaces.StreamEvent{}, fromOffset, nil
-		}
 		return nil, fromOffset, fmt.Errorf("failed to get events from Redis: %w", err)
 	}
+	if len(reply) != 2 {
+		return nil, fromOffset, fmt.Errorf("unexpected stream events reply: %v", reply)
+	}
+	dropped, _ := reply[0].(int64)
+	if int64(fromOffset) < dropped {
+		return nil, int(dropped), interfaces.ErrStreamEventsExpired
+	}
+	results, _ := reply[1].([]interface{})
 
 	// No new events
 	if len(results) == 0 {
@@ -113,8 +143,9 @@ func (r *RedisStreamManager) GetEvents(
 	// Unmarshal events
 	events := make([]interfaces.StreamEvent, 0, len(results))
 	for _, result := range results {
+		text, _ := result.(string)
 		var event interfaces.StreamEvent
-		if err := json.Unmarshal([]byte(result), &event); err != nil {
+		if err := json.Unmarshal([]byte(text), &event); err != nil {
 			// Log error but continue with other events
 			continue
 		}
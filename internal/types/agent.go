@@ -4,19 +4,22 @@ import (
 	"context"
 	"database/sql/driver"
 	"encoding/json"
+	"slices"
 	"time"
 )
 
 // AgentConfig represents the full agent configuration (used at tenant level and runtime)
 // This includes all configuration parameters for agent execution
 type AgentConfig struct {
-	MaxIterations     int      `json:"max_iterations"`          // Maximum number of ReAct iterations
-	ReflectionEnabled bool     `json:"reflection_enabled"`      // Whether to enable reflection
-	AllowedTools      []string `json:"allowed_tools"`           // List of allowed tool names
-	Temperature       float64  `json:"temperature"`             // LLM temperature for agent
-	KnowledgeBases    []string `json:"knowledge_bases"`         // Accessible knowledge base IDs
-	KnowledgeIDs      []string `json:"knowledge_ids"`           // Accessible knowledge IDs (individual documents)
-	SystemPrompt      string   `json:"system_prompt,omitempty"` // Unified system prompt (uses {{web_search_status}} placeholder for dynamic behavior)
+	MaxIterations       int      `json:"max_iterations"`          // Maximum number of ReAct iterations
+	MaxTotalTokens      int      `json:"max_total_tokens"`        // Maximum tokens for the whole loop, as reported by the provider or estimated (0 = unlimited)
+	MaxExecutionSeconds int      `json:"max_execution_seconds"`   // Wall-clock budget for the whole loop in seconds (0 = unlimited)
+	ReflectionEnabled   bool     `json:"reflection_enabled"`      // Whether to enable reflection
+	AllowedTools        []string `json:"allowed_tools"`           // List of allowed tool names
+	Temperature         float64  `json:"temperature"`             // LLM temperature for agent
+	KnowledgeBases      []string `json:"knowledge_bases"`         // Accessible knowledge base IDs
+	KnowledgeIDs        []string `json:"knowledge_ids"`           // Accessible knowledge IDs (individual documents)
+	SystemPrompt        string   `json:"system_prompt,omitempty"` // Unified system prompt (uses {{web_search_status}} placeholder for dynamic behavior)
 	// Deprecated: Use SystemPrompt instead. Kept for backward compatibility during migration.
 	SystemPromptWebEnabled  string        `json:"system_prompt_web_enabled,omitempty"`  // Deprecated: Custom prompt when web search is enabled
 	SystemPromptWebDisabled string        `json:"system_prompt_web_disabled,omitempty"` // Deprecated: Custom prompt when web search is disabled
@@ -33,6 +36,23 @@ type AgentConfig struct {
 	Thinking *bool `json:"thinking"`
 	// Whether to retrieve knowledge base only when explicitly mentioned with @ (default: false)
 	RetrieveKBOnlyWhenMentioned bool `json:"retrieve_kb_only_when_mentioned"`
+	// Retrieval mode resolved from the custom agent: "always", "tool_only" or "never"
+	RetrievalMode string `json:"retrieval_mode,omitempty"`
+}
+
+// knowledgeSearchToolName is the agent tool used to honor retrieval mode "always"
+const knowledgeSearchToolName = "knowledge_search"
+
+// ToolChoiceForRound returns the tool the model must call in the given round, or "" to let it choose
+// Retrieval mode "always" forces knowledge_search in the first round when the tool is registered
+func (c *AgentConfig) ToolChoiceForRound(round int, availableTools []string) string {
+	if c.RetrievalMode != RetrievalModeAlways || round > 0 {
+		return ""
+	}
+	if !slices.Contains(availableTools, knowledgeSearchToolName) {
+		return ""
+	}
+	return knowledgeSearchToolName
 }
 
 // SessionAgentConfig represents session-level agent configuration
@@ -160,6 +180,18 @@ func (s *AgentStep) GetObservations() []string {
 	return observations
 }
 
+// Agent stop reasons reported when the ReAct loop ends
+const (
+	// AgentStopReasonCompleted means the agent produced its final answer on its own
+	AgentStopReasonCompleted = "completed"
+	// AgentStopReasonMaxIterations means the iteration limit was reached
+	AgentStopReasonMaxIterations = "max_iterations"
+	// AgentStopReasonMaxTokens means the token budget was exhausted
+	AgentStopReasonMaxTokens = "max_tokens"
+	// AgentStopReasonTimeBudget means the wall-clock budget was exhausted
+	AgentStopReasonTimeBudget = "time_budget"
+)
+
 // AgentState tracks the execution state of an agent across iterations
 type AgentState struct {
 	CurrentRound  int             `json:"current_round"`  // Current round number
@@ -167,6 +199,13 @@ type AgentState struct {
 	IsComplete    bool            `json:"is_complete"`    // Whether agent has finished
 	FinalAnswer   string          `json:"final_answer"`   // The final answer to the query
 	KnowledgeRefs []*SearchResult `json:"knowledge_refs"` // Collected knowledge references
+	TokensUsed    int             `json:"tokens_used"`    // Estimated tokens consumed by LLM calls so far
+	StopReason    string          `json:"stop_reason"`    // Why the loop ended (see AgentStopReason*)
+}
+
+// IsTruncated reports whether the agent was cut short by a configured limit
+func (s *AgentState) IsTruncated() bool {
+	return s.StopReason != "" && s.StopReason != AgentStopReasonCompleted
 }
 
 // FunctionDefinition represents a function definition for LLM function calling
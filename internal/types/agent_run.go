@@ -0,0 +1,93 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// Agent run status constants
+const (
+	// AgentRunStatusSucceeded means the agent loop finished without error
+	AgentRunStatusSucceeded = "succeeded"
+	// AgentRunStatusFailed means the agent loop aborted with an error
+	AgentRunStatusFailed = "failed"
+)
+
+// AgentRunMessage is a history message that was fed to the agent as LLM context
+type AgentRunMessage struct {
+	Role       string `json:"role"`
+	Content    string `json:"content"`
+	Name       string `json:"name,omitempty"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// AgentRunInput captures everything needed to re-execute an agent run
+type AgentRunInput struct {
+	// User query
+	Query string `json:"query"`
+	// Chat model used for the run
+	ModelID string `json:"model_id"`
+	// Rerank model used for the run (empty when no knowledge bases were searched)
+	RerankModelID string `json:"rerank_model_id,omitempty"`
+	// Knowledge bases and files @mentioned in the request
+	MentionedKnowledgeBaseIDs []string `json:"mentioned_knowledge_base_ids,omitempty"`
+	MentionedKnowledgeIDs     []string `json:"mentioned_knowledge_ids,omitempty"`
+	// Effective runtime configuration
+	Config AgentConfig `json:"config"`
+	// Conversation history passed to the LLM
+	History []AgentRunMessage `json:"history"`
+}
+
+// Value implements the driver.Valuer interface for database serialization
+func (i AgentRunInput) Value() (driver.Value, error) {
+	return json.Marshal(i)
+}
+
+// Scan implements the sql.Scanner interface for database deserialization
+func (i *AgentRunInput) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, i)
+}
+
+// AgentRun is the full execution trace of one agent run, keyed by the assistant message ID
+type AgentRun struct {
+	// Unique identifier of the run
+	ID string `json:"id" gorm:"type:varchar(36);primaryKey"`
+	// Tenant ID for isolation
+	TenantID uint64 `json:"tenant_id" gorm:"index"`
+	// Session the run belongs to
+	SessionID string `json:"session_id" gorm:"type:varchar(36)"`
+	// Assistant message produced by the run
+	MessageID string `json:"message_id" gorm:"type:varchar(36);index"`
+	// Agent used for the run and its published version (0 for built-in or unversioned agents)
+	AgentID      string `json:"agent_id" gorm:"type:varchar(36)"`
+	AgentVersion int    `json:"agent_version"`
+	// ID of the original run when this run is a replay
+	ReplayOf string `json:"replay_of,omitempty" gorm:"type:varchar(36)"`
+	// Inputs of the run (secrets redacted)
+	Input AgentRunInput `json:"input" gorm:"type:jsonb"`
+	// Each LLM turn with its tool calls and results (tool inputs redacted)
+	Steps AgentSteps `json:"steps" gorm:"type:jsonb"`
+	// Final answer returned to the user
+	FinalAnswer string `json:"final_answer" gorm:"type:text"`
+	// Outcome of the run
+	Status     string `json:"status" gorm:"type:varchar(16)"`
+	StopReason string `json:"stop_reason" gorm:"type:varchar(32)"`
+	Error      string `json:"error,omitempty" gorm:"type:text"`
+	TokensUsed int    `json:"tokens_used"`
+	DurationMs int64  `json:"duration_ms"`
+	// When the run started
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for AgentRun
+func (AgentRun) TableName() string {
+	return "agent_runs"
+}
@@ -0,0 +1,106 @@
+package types
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxAnswerCacheTTLSeconds is the longest time an answer can be cached (7 days)
+const MaxAnswerCacheTTLSeconds = 7 * 24 * 3600
+
+// Values of the X-Answer-Cache response header
+const (
+	AnswerCacheHit    = "HIT"
+	AnswerCacheMiss   = "MISS"
+	AnswerCacheBypass = "BYPASS"
+)
+
+// AnswerCacheConfig enables exact-match caching of knowledge Q&A answers for a knowledge base
+type AnswerCacheConfig struct {
+	// TTLSeconds is how long an answer is served from the cache; 0 disables the cache
+	TTLSeconds int `yaml:"ttl_seconds" json:"ttl_seconds"`
+}
+
+// Validate checks the TTL
+func (c *AnswerCacheConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.TTLSeconds < 0 || c.TTLSeconds > MaxAnswerCacheTTLSeconds {
+		return fmt.Errorf("ttl_seconds must be between 0 and %d", MaxAnswerCacheTTLSeconds)
+	}
+	return nil
+}
+
+// TTL returns the cache TTL, 0 when caching is disabled
+func (c *AnswerCacheConfig) TTL() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return time.Duration(c.TTLSeconds) * time.Second
+}
+
+// Value implements driver.Valuer
+func (c AnswerCacheConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *AnswerCacheConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// AnswerCacheKey holds everything a cached answer depends on besides the knowledge base content
+type AnswerCacheKey struct {
+	TenantID         uint64   `json:"tenant_id"`
+	KnowledgeBaseIDs []string `json:"knowledge_base_ids"`
+	KnowledgeIDs     []string `json:"knowledge_ids,omitempty"`
+	Query            string   `json:"query"`
+	SummaryModelID   string   `json:"summary_model_id,omitempty"`
+	AgentID          string   `json:"agent_id,omitempty"`
+	// AgentVersion changes whenever the agent is updated
+	AgentVersion int64 `json:"agent_version,omitempty"`
+	HistoryDepth *int  `json:"history_depth,omitempty"`
+}
+
+// NormalizeAnswerCacheQuery folds case and whitespace so that trivially different spellings of a question
+// share a cache entry
+func NormalizeAnswerCacheQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// Normalized returns a copy with the query normalized and IDs sorted
+func (k AnswerCacheKey) Normalized() AnswerCacheKey {
+	k.Query = NormalizeAnswerCacheQuery(k.Query)
+	k.KnowledgeBaseIDs = slices.Sorted(slices.Values(k.KnowledgeBaseIDs))
+	k.KnowledgeIDs = slices.Sorted(slices.Values(k.KnowledgeIDs))
+	return k
+}
+
+// Hash returns a stable digest of the normalized key
+func (k AnswerCacheKey) Hash() string {
+	payload, _ := json.Marshal(k.Normalized())
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// CachedAnswer is an answer served for exact repeats of a question
+type CachedAnswer struct {
+	Content    string            `json:"content"`
+	References References        `json:"references,omitempty"`
+	Confidence *ConfidenceResult `json:"confidence,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
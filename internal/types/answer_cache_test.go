@@ -0,0 +1,44 @@
+package types
+
+import "testing"
+
+func TestAnswerCacheKeyHash(t *testing.T) {
+	base := AnswerCacheKey{
+		TenantID:         1,
+		KnowledgeBaseIDs: []string{"kb-1", "kb-2"},
+		Query:            "How do I reset my password?",
+	}
+
+	same := base
+	same.KnowledgeBaseIDs = []string{"kb-2", "kb-1"}
+	same.Query = "  how do I   RESET my password? "
+	if base.Hash() != same.Hash() {
+		t.Error("expected case, whitespace and ID order to be ignored")
+	}
+
+	depth := 0
+	variants := []AnswerCacheKey{
+		{TenantID: 2, KnowledgeBaseIDs: base.KnowledgeBaseIDs, Query: base.Query},
+		{TenantID: 1, KnowledgeBaseIDs: []string{"kb-1"}, Query: base.Query},
+		{TenantID: 1, KnowledgeBaseIDs: base.KnowledgeBaseIDs, Query: "How do I reset my password"},
+		{TenantID: 1, KnowledgeBaseIDs: base.KnowledgeBaseIDs, Query: base.Query, SummaryModelID: "m1"},
+		{TenantID: 1, KnowledgeBaseIDs: base.KnowledgeBaseIDs, Query: base.Query, HistoryDepth: &depth},
+	}
+	for _, variant := range variants {
+		if variant.Hash() == base.Hash() {
+			t.Errorf("expected %+v to hash differently", variant)
+		}
+	}
+}
+
+func TestAnswerCacheConfigValidate(t *testing.T) {
+	var none *AnswerCacheConfig
+	if err := none.Validate(); err != nil || none.TTL() != 0 {
+		t.Errorf("nil config: %v, ttl %v", err, none.TTL())
+	}
+	for _, ttl := range []int{-1, MaxAnswerCacheTTLSeconds + 1} {
+		if err := (&AnswerCacheConfig{TTLSeconds: ttl}).Validate(); err == nil {
+			t.Errorf("ttl %d: expected error", ttl)
+		}
+	}
+}
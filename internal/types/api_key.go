@@ -0,0 +1,62 @@
+package types
+
+import "time"
+
+// ManagedAPIKeyPrefix starts the API keys minted with the API key management API, telling them apart from
+// the tenant API key, which starts with sk-
+const ManagedAPIKeyPrefix = "wk-"
+
+// APIKey is an API key minted for a tenant, with its own scopes, expiration and revocation. Only the
+// SHA-256 hash of the key is stored; the key itself is returned once, when it is created.
+type APIKey struct {
+	// Unique identifier of the key
+	ID string `json:"id"           gorm:"type:varchar(36);primaryKey"`
+	// Tenant the key authenticates as
+	TenantID uint64 `json:"tenant_id"    gorm:"index"`
+	// Name describing what the key is used for
+	Name string `json:"name"         gorm:"type:varchar(100);not null"`
+	// First characters of the key, to recognize it in listings
+	Prefix string `json:"prefix"       gorm:"type:varchar(16)"`
+	// SHA-256 hash of the key, hex encoded
+	KeyHash string `json:"-"            gorm:"type:varchar(64);uniqueIndex;not null"`
+	// Scopes of the key, see AllAPIKeyScopes
+	Scopes StringArray `json:"scopes"       gorm:"type:jsonb"`
+	// Time the key stops being accepted; nil for a key that does not expire
+	ExpiresAt *time.Time `json:"expires_at"`
+	// Time the key was revoked; nil for a key that was not revoked
+	RevokedAt *time.Time `json:"revoked_at"`
+	// Time the key was last used, updated at most once per minute
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// User who created the key; empty when it was created with an API key
+	CreatedBy string `json:"created_by"   gorm:"type:varchar(36)"`
+	// Creation time of the key
+	CreatedAt time.Time `json:"created_at"`
+	// Last updated time of the key
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name of the API keys
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// Active reports whether the key is accepted at the given time: not revoked and not expired
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// CreateAPIKeyRequest mints an API key for the current tenant
+type CreateAPIKeyRequest struct {
+	// Name describing what the key is used for
+	Name string `json:"name"       binding:"required"`
+	// Scopes of the key, at least one
+	Scopes StringArray `json:"scopes"     binding:"required"`
+	// Optional expiration time, RFC 3339; the key does not expire when omitted
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedAPIKey is a newly minted API key, the only time the key itself is returned
+type CreatedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
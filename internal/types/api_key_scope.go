@@ -0,0 +1,51 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+)
+
+// API key scopes, the operations a tenant API key may perform
+const (
+	// APIKeyScopeKnowledgeRead reads knowledge bases, knowledge and chunks, and searches them
+	APIKeyScopeKnowledgeRead = "knowledge:read"
+	// APIKeyScopeKnowledgeWrite creates, updates and deletes knowledge bases, knowledge, chunks, tags and FAQ entries
+	APIKeyScopeKnowledgeWrite = "knowledge:write"
+	// APIKeyScopeChat manages sessions and messages and asks questions
+	APIKeyScopeChat = "chat"
+	// APIKeyScopeAdmin manages the tenant, its models, agents, MCP services and system settings
+	APIKeyScopeAdmin = "admin"
+	// APIKeyScopeKnowledgeSearch only searches knowledge bases, a subset of knowledge:read
+	APIKeyScopeKnowledgeSearch = "knowledge:search"
+	// APIKeyScopeKnowledgeIngest only adds knowledge from files, URLs and manual entries, a subset of knowledge:write
+	APIKeyScopeKnowledgeIngest = "knowledge:ingest"
+)
+
+// AllAPIKeyScopes are the scopes of a full-access API key
+var AllAPIKeyScopes = StringArray{
+	APIKeyScopeKnowledgeRead,
+	APIKeyScopeKnowledgeWrite,
+	APIKeyScopeChat,
+	APIKeyScopeAdmin,
+	APIKeyScopeKnowledgeSearch,
+	APIKeyScopeKnowledgeIngest,
+}
+
+// ValidateAPIKeyScopes rejects unknown scopes
+func ValidateAPIKeyScopes(scopes StringArray) error {
+	for _, scope := range scopes {
+		if !slices.Contains(AllAPIKeyScopes, scope) {
+			return fmt.Errorf("unknown API key scope %q, expected one of %v", scope, AllAPIKeyScopes)
+		}
+	}
+	return nil
+}
+
+// EffectiveAPIKeyScopes returns the scopes of the tenant API key; a key without scopes predates them
+// and keeps full access, while an empty list grants nothing
+func (t *Tenant) EffectiveAPIKeyScopes() StringArray {
+	if t.APIKeyScopes == nil {
+		return AllAPIKeyScopes
+	}
+	return t.APIKeyScopes
+}
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Limits applied to attachments sent with a single chat message
+const (
+	// MaxChatAttachments is the maximum number of attachments per message
+	MaxChatAttachments = 5
+	// MaxChatAttachmentSize is the maximum decoded size of one attachment in bytes
+	MaxChatAttachmentSize = 10 * 1024 * 1024
+	// MaxChatAttachmentContextChars is the maximum number of extracted characters added to the context
+	MaxChatAttachmentContextChars = 30000
+)
+
+// ChatAttachmentType is the kind of content attached to a chat message
+type ChatAttachmentType string
+
+const (
+	// ChatAttachmentTypeText is inline text
+	ChatAttachmentTypeText ChatAttachmentType = "text"
+	// ChatAttachmentTypeFile is a document parsed like an uploaded knowledge file
+	ChatAttachmentTypeFile ChatAttachmentType = "file"
+	// ChatAttachmentTypeImage is an image described by the multimodal model
+	ChatAttachmentTypeImage ChatAttachmentType = "image"
+)
+
+// ChatAttachment is content attached to a single chat message. It is extracted and chunked in memory,
+// used as context for that turn only and never stored in a knowledge base.
+type ChatAttachment struct {
+	// Name is the file name, its extension determines how a file or image is parsed
+	Name string `json:"name"`
+	// Type is text, file or image
+	Type ChatAttachmentType `json:"type"`
+	// Text is the inline content of a text attachment
+	Text string `json:"text,omitempty"`
+	// Data is the base64 encoded content of a file or image attachment
+	Data string `json:"data,omitempty"`
+}
+
+// FileType returns the lower-case file extension of the attachment name
+func (a *ChatAttachment) FileType() string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(a.Name)), ".")
+}
+
+// Decode returns the raw content of the attachment
+func (a *ChatAttachment) Decode() ([]byte, error) {
+	if a.Type == ChatAttachmentTypeText {
+		return []byte(a.Text), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(a.Data)
+	if err != nil {
+		return nil, fmt.Errorf("attachment %q: invalid base64 data", a.Name)
+	}
+	return data, nil
+}
+
+// ValidateChatAttachments checks attachment count, types and sizes
+func ValidateChatAttachments(attachments []*ChatAttachment) error {
+	if len(attachments) > MaxChatAttachments {
+		return fmt.Errorf("at most %d attachments are allowed per message", MaxChatAttachments)
+	}
+	for _, a := range attachments {
+		if a == nil {
+			return fmt.Errorf("attachment must not be empty")
+		}
+		switch a.Type {
+		case ChatAttachmentTypeText:
+			if strings.TrimSpace(a.Text) == "" {
+				return fmt.Errorf("attachment %q: text is empty", a.Name)
+			}
+			if len(a.Text) > MaxChatAttachmentSize {
+				return fmt.Errorf("attachment %q exceeds %d MB", a.Name, MaxChatAttachmentSize>>20)
+			}
+		case ChatAttachmentTypeFile, ChatAttachmentTypeImage:
+			if a.FileType() == "" {
+				return fmt.Errorf("attachment %q: name must have a file extension", a.Name)
+			}
+			if a.Data == "" {
+				return fmt.Errorf("attachment %q: data is empty", a.Name)
+			}
+			if base64.StdEncoding.DecodedLen(len(a.Data)) > MaxChatAttachmentSize+2 {
+				return fmt.Errorf("attachment %q exceeds %d MB", a.Name, MaxChatAttachmentSize>>20)
+			}
+		default:
+			return fmt.Errorf("attachment %q: unsupported type %q", a.Name, a.Type)
+		}
+	}
+	return nil
+}
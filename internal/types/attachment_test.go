@@ -0,0 +1,100 @@
+package types
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestValidateChatAttachments(t *testing.T) {
+	pdf := base64.StdEncoding.EncodeToString([]byte("%PDF-1.4"))
+	tooLarge := base64.StdEncoding.EncodeToString(make([]byte, MaxChatAttachmentSize+4))
+
+	tests := []struct {
+		name        string
+		attachments []*ChatAttachment
+		wantErr     string
+	}{
+		{name: "none"},
+		{
+			name: "text and file",
+			attachments: []*ChatAttachment{
+				{Name: "notes", Type: ChatAttachmentTypeText, Text: "meeting notes"},
+				{Name: "report.PDF", Type: ChatAttachmentTypeFile, Data: pdf},
+			},
+		},
+		{
+			name:        "too many",
+			attachments: []*ChatAttachment{{}, {}, {}, {}, {}, {}},
+			wantErr:     "at most 5 attachments",
+		},
+		{name: "nil attachment", attachments: []*ChatAttachment{nil}, wantErr: "must not be empty"},
+		{
+			name:        "blank text",
+			attachments: []*ChatAttachment{{Name: "notes", Type: ChatAttachmentTypeText, Text: "  "}},
+			wantErr:     "text is empty",
+		},
+		{
+			name:        "file without extension",
+			attachments: []*ChatAttachment{{Name: "report", Type: ChatAttachmentTypeFile, Data: pdf}},
+			wantErr:     "file extension",
+		},
+		{
+			name:        "image without data",
+			attachments: []*ChatAttachment{{Name: "chart.png", Type: ChatAttachmentTypeImage}},
+			wantErr:     "data is empty",
+		},
+		{
+			name:        "oversized file",
+			attachments: []*ChatAttachment{{Name: "big.pdf", Type: ChatAttachmentTypeFile, Data: tooLarge}},
+			wantErr:     "exceeds 10 MB",
+		},
+		{
+			name:        "unknown type",
+			attachments: []*ChatAttachment{{Name: "clip.mp4", Type: "video", Data: pdf}},
+			wantErr:     "unsupported type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateChatAttachments(tt.attachments)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChatAttachmentDecode(t *testing.T) {
+	tests := []struct {
+		name       string
+		attachment ChatAttachment
+		want       string
+		wantErr    bool
+	}{
+		{name: "text", attachment: ChatAttachment{Type: ChatAttachmentTypeText, Text: "hello"}, want: "hello"},
+		{
+			name:       "file",
+			attachment: ChatAttachment{Type: ChatAttachmentTypeFile, Data: base64.StdEncoding.EncodeToString([]byte("body"))},
+			want:       "body",
+		},
+		{name: "invalid base64", attachment: ChatAttachment{Type: ChatAttachmentTypeFile, Data: "not base64!"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.attachment.Decode()
+			if (err != nil) != tt.wantErr || string(got) != tt.want {
+				t.Errorf("Decode() = %q, %v, want %q, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+	if got := (&ChatAttachment{Name: "Report.Final.PDF"}).FileType(); got != "pdf" {
+		t.Errorf("FileType() = %q, want pdf", got)
+	}
+}
@@ -0,0 +1,48 @@
+package types
+
+import "time"
+
+// AuditLog records a mutating request of the API: who sent it, what it targeted and how it ended
+type AuditLog struct {
+	// Unique identifier of the entry
+	ID string `json:"id" gorm:"type:varchar(36);primaryKey"`
+	// Tenant the request acted on (0 for unauthenticated requests such as registration)
+	TenantID uint64 `json:"tenant_id" gorm:"index"`
+	// User who sent the request; empty when it authenticated with a tenant API key
+	UserID string `json:"user_id" gorm:"type:varchar(36)"`
+	// Request ID set by the RequestID middleware, to correlate with the request logs
+	RequestID string `json:"request_id" gorm:"type:varchar(128)"`
+	// HTTP method and route template of the request, e.g. DELETE /api/v1/knowledge-bases/:id
+	Method string `json:"method" gorm:"type:varchar(16)"`
+	Route  string `json:"route" gorm:"type:varchar(255)"`
+	// Resource type is the first segment of the route under /api/v1, e.g. knowledge-bases
+	ResourceType string `json:"resource_type" gorm:"type:varchar(64)"`
+	// ID of the target resource taken from the path parameters
+	ResourceID string `json:"resource_id" gorm:"type:varchar(255)"`
+	// HTTP status of the response
+	Status int `json:"status"`
+	// Client IP of the request
+	ClientIP string `json:"client_ip" gorm:"type:varchar(64)"`
+	// JSON request body with passwords, tokens and secrets redacted; empty for other bodies
+	RequestBody string `json:"request_body,omitempty" gorm:"type:text"`
+	// Time taken to process the request
+	DurationMs int64 `json:"duration_ms"`
+	// Time the request was received
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName returns the table name of the audit log
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditLogFilter narrows the audit log of a tenant; empty fields match all entries
+type AuditLogFilter struct {
+	// Resource type, e.g. knowledge-bases
+	ResourceType string `form:"resource_type"`
+	// User who sent the requests
+	UserID string `form:"user_id"`
+	// Time range of the requests, RFC 3339; the end is exclusive
+	StartTime *time.Time `form:"start_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTime   *time.Time `form:"end_time"   time_format:"2006-01-02T15:04:05Z07:00"`
+}
@@ -50,6 +50,18 @@ const (
 	ResponseTypeToolCall ResponseType = "tool_call"
 	// Tool result response type (for agent tool results)
 	ResponseTypeToolResult ResponseType = "tool_result"
+	// Tool status response type (status and partial output while a tool is running)
+	ResponseTypeToolStatus ResponseType = "tool_status"
+	// Confidence response type (answer confidence computed by the confidence gate)
+	ResponseTypeConfidence ResponseType = "confidence"
+	// Context budget response type (token budget breakdown of the prompt)
+	ResponseTypeContextBudget ResponseType = "context_budget"
+	// Grounding response type (grounding verdict of the answer)
+	ResponseTypeGrounding ResponseType = "grounding"
+	// Citation response type (citation verdict of the answer)
+	ResponseTypeCitation ResponseType = "citation"
+	// Retrieval degraded response type (the answer is generated from partial retrieval results)
+	ResponseTypeRetrievalDegraded ResponseType = "retrieval_degraded"
 	// Error response type
 	ResponseTypeError ResponseType = "error"
 	// Reflection response type (for agent reflection)
@@ -60,6 +72,8 @@ const (
 	ResponseTypeAgentQuery ResponseType = "agent_query"
 	// Complete response type (agent complete)
 	ResponseTypeComplete ResponseType = "complete"
+	// Stream reset response type (the events to resume from are no longer buffered, reload the message)
+	ResponseTypeStreamReset ResponseType = "stream_reset"
 )
 
 // StreamResponse stream response
@@ -82,6 +96,15 @@ type StreamResponse struct {
 	ToolCalls []LLMToolCall `json:"tool_calls,omitempty"`
 	// Additional metadata for enhanced display
 	Data map[string]interface{} `json:"data,omitempty"`
+	// Token usage reported by the provider, set on the final chunk when available
+	Usage *TokenUsage `json:"usage,omitempty"`
+}
+
+// TokenUsage is the token usage of a streamed model call
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // References references
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // ChatManage represents the configuration and state for a chat session
 // including query processing, search parameters, and model configurations
 type ChatManage struct {
@@ -21,8 +23,20 @@ type ChatManage struct {
 	RerankModelID   string  `json:"rerank_model_id"`  // Model ID for reranking search results
 	RerankTopK      int     `json:"rerank_top_k"`     // Number of top results after reranking
 	RerankThreshold float64 `json:"rerank_threshold"` // Minimum score threshold for reranked results
+	// SummaryFirst overrides the summary-first retrieval of the searched knowledge bases when set
+	SummaryFirst *bool `json:"summary_first,omitempty"`
+	// RetrievalMode overrides the retrieval mode ("chunk" or "parent") of the searched knowledge bases when set
+	RetrievalMode string `json:"retrieval_mode,omitempty"`
+	// SourceQuota overrides the cap of chunks per knowledge item of the searched knowledge bases when set
+	SourceQuota *SourceQuotaConfig `json:"source_quota,omitempty"`
 
 	MaxRounds int `json:"max_rounds"` // Maximum history rounds used for rewrite/context
+	// HistoryDepth caps the prior turns included in the prompt and overrides MaxRounds when set;
+	// 0 answers without conversational history
+	HistoryDepth *int `json:"history_depth,omitempty"`
+	// HistoryBefore limits the loaded history to turns created before this time, e.g. when
+	// regenerating an earlier answer; zero loads the most recent turns
+	HistoryBefore time.Time `json:"-"`
 
 	ChatModelID      string           `json:"chat_model_id"`     // ID of the chat model to use
 	SummaryConfig    SummaryConfig    `json:"summary_config"`    // Configuration for summary generation
@@ -39,16 +53,26 @@ type ChatManage struct {
 	SearchResult    []*SearchResult   `json:"-"` // Results from search phase
 	RerankResult    []*SearchResult   `json:"-"` // Results after reranking
 	MergeResult     []*SearchResult   `json:"-"` // Final merged results after all processing
+	Attachments     []*SearchResult   `json:"-"` // Transient chunks extracted from the message attachments
 	Entity          []string          `json:"-"` // List of identified entities
 	EntityKBIDs     []string          `json:"-"` // Knowledge base IDs with ExtractConfig enabled
 	EntityKnowledge map[string]string `json:"-"` // KnowledgeID -> KnowledgeBaseID mapping for graph-enabled files
 	GraphResult     *GraphData        `json:"-"` // Graph data from search phase
 	UserContent     string            `json:"-"` // Processed user content
 	ChatResponse    *ChatResponse     `json:"-"` // Final response from chat model
+	Confidence      *ConfidenceResult `json:"-"` // Confidence computed by the gate, then of the generated answer
+	Grounding       *GroundingResult  `json:"-"` // Grounding verdict of the generated answer
+	Citation        *CitationResult   `json:"-"` // Citation verdict of the generated answer
+	// RetrievalDegradation is set when retrieval was cut short at its soft deadline
+	RetrievalDegradation *RetrievalDegradation `json:"-"`
+	// ContextBudget is the token budget breakdown of the final prompt
+	ContextBudget *ContextBudgetReport `json:"-"`
 
 	// Event system for streaming responses
 	EventBus  EventBusInterface `json:"-"` // EventBus for emitting streaming events
 	MessageID string            `json:"-"` // Assistant message ID for event emission
+	// ReceivedAt is the time the question was accepted, from which the answer latency is measured
+	ReceivedAt time.Time `json:"-"`
 
 	// Web search configuration (internal use)
 	TenantID         uint64 `json:"-"` // Tenant ID for retrieving web search config
@@ -95,10 +119,14 @@ func (c *ChatManage) Clone() *ChatManage {
 		KeywordThreshold: c.KeywordThreshold,
 		EmbeddingTopK:    c.EmbeddingTopK,
 		MaxRounds:        c.MaxRounds,
+		HistoryDepth:     c.HistoryDepth,
 		VectorDatabase:   c.VectorDatabase,
 		RerankModelID:    c.RerankModelID,
 		RerankTopK:       c.RerankTopK,
 		RerankThreshold:  c.RerankThreshold,
+		SummaryFirst:     c.SummaryFirst,
+		RetrievalMode:    c.RetrievalMode,
+		SourceQuota:      c.SourceQuota,
 		ChatModelID:      c.ChatModelID,
 		SummaryConfig: SummaryConfig{
 			MaxTokens:           c.SummaryConfig.MaxTokens,
@@ -113,7 +141,10 @@ func (c *ChatManage) Clone() *ChatManage {
 			Temperature:         c.SummaryConfig.Temperature,
 			Seed:                c.SummaryConfig.Seed,
 			MaxCompletionTokens: c.SummaryConfig.MaxCompletionTokens,
+			ContextWindow:       c.SummaryConfig.ContextWindow,
 			Thinking:            c.SummaryConfig.Thinking,
+
+			OversizedChunkStrategy: c.SummaryConfig.OversizedChunkStrategy,
 		},
 		FallbackStrategy:     c.FallbackStrategy,
 		FallbackResponse:     c.FallbackResponse,
@@ -147,6 +178,11 @@ const (
 	CHAT_COMPLETION_STREAM EventType = "chat_completion_stream" // Stream chat completion
 	STREAM_FILTER          EventType = "stream_filter"          // Filter streaming output
 	FILTER_TOP_K           EventType = "filter_top_k"           // Keep only top K results
+	CONFIDENCE_GATE        EventType = "confidence_gate"        // Refuse to answer when confidence is low
+	ATTACHMENT_MERGE       EventType = "attachment_merge"       // Add message attachments to the context
+	GROUNDING_CHECK        EventType = "grounding_check"        // Verify that the answer is supported by the context
+	CITATION_CHECK         EventType = "citation_check"         // Remove or flag answer sentences without a citation
+	ANSWER_CONFIDENCE      EventType = "answer_confidence"      // Score the confidence of the generated answer
 )
 
 // Pipline defines the sequence of events for different chat modes
@@ -167,8 +203,13 @@ var Pipline = map[string][]EventType{
 		CHUNK_SEARCH,
 		CHUNK_RERANK,
 		CHUNK_MERGE,
+		ATTACHMENT_MERGE,
+		CONFIDENCE_GATE,
 		INTO_CHAT_MESSAGE,
 		CHAT_COMPLETION,
+		CITATION_CHECK,
+		GROUNDING_CHECK,
+		ANSWER_CONFIDENCE,
 	},
 	"rag_stream": { // Streaming Retrieval Augmented Generation
 		REWRITE_QUERY,
@@ -176,8 +217,26 @@ var Pipline = map[string][]EventType{
 		CHUNK_RERANK,
 		CHUNK_MERGE,
 		FILTER_TOP_K,
+		ATTACHMENT_MERGE,
+		CONFIDENCE_GATE,
 		DATA_ANALYSIS,
 		INTO_CHAT_MESSAGE,
+		ANSWER_CONFIDENCE, // Installed before streaming and grounding: scores the answer once it is complete
+		GROUNDING_CHECK,   // Installed before streaming: holds back the answer until it is checked
+		CITATION_CHECK,    // Installed last: filters the complete answer before grounding checks it
+		CHAT_COMPLETION_STREAM,
+		STREAM_FILTER,
+	},
+	"attachment_stream": { // Streaming chat over message attachments without retrieval
+		LOAD_HISTORY,
+		ATTACHMENT_MERGE,
+		INTO_CHAT_MESSAGE,
+		CHAT_COMPLETION_STREAM,
+		STREAM_FILTER,
+	},
+	"regenerate_stream": { // Streaming generation over the references of an earlier answer, without retrieval
+		LOAD_HISTORY,
+		INTO_CHAT_MESSAGE,
 		CHAT_COMPLETION_STREAM,
 		STREAM_FILTER,
 	},
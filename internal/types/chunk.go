@@ -32,6 +32,10 @@ const (
 	ChunkTypeTableSummary ChunkType = "table_summary"
 	// ChunkTypeTableColumn represents a data table column description Chunk
 	ChunkTypeTableColumn ChunkType = "table_column"
+	// ChunkTypeAttachment represents a transient Chunk extracted from a chat message attachment
+	ChunkTypeAttachment ChunkType = "attachment"
+	// ChunkTypeParent represents a parent Chunk grouping consecutive text Chunks, stored but not indexed
+	ChunkTypeParent ChunkType = "parent"
 )
 
 // ChunkStatus defines different states of Chunk
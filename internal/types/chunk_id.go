@@ -0,0 +1,50 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// chunkIDNamespace is the UUID namespace of deterministic chunk IDs
+var chunkIDNamespace = uuid.MustParse("5b8f2a1e-7c4d-4e3b-9a6f-0d1c2e3f4a5b")
+
+// ChunkIDGenerator derives deterministic chunk IDs for one knowledge, so that re-chunking identical
+// content yields the same IDs. IDs are name-based UUIDs (version 5) of the knowledge ID, chunk type,
+// position, parent chunk and content. IDs are unique among all IDs generated or reserved by the
+// generator: on a collision a counter is appended to the name until the ID is free.
+type ChunkIDGenerator struct {
+	knowledgeID string
+	used        map[string]bool
+}
+
+// NewChunkIDGenerator creates a chunk ID generator for a knowledge
+func NewChunkIDGenerator(knowledgeID string) *ChunkIDGenerator {
+	return &ChunkIDGenerator{knowledgeID: knowledgeID, used: make(map[string]bool)}
+}
+
+// ID returns the deterministic ID of a chunk from its type, index, parent and content
+func (g *ChunkIDGenerator) ID(chunk *Chunk) string {
+	name := strings.Join([]string{
+		g.knowledgeID,
+		string(chunk.ChunkType),
+		strconv.Itoa(chunk.ChunkIndex),
+		chunk.ParentChunkID,
+		chunk.Content,
+	}, "\x00")
+
+	id := uuid.NewSHA1(chunkIDNamespace, []byte(name)).String()
+	for attempt := 1; g.used[id]; attempt++ {
+		id = uuid.NewSHA1(chunkIDNamespace, []byte(name+"\x00"+strconv.Itoa(attempt))).String()
+	}
+	g.used[id] = true
+	return id
+}
+
+// Reserve marks IDs as taken, e.g. by existing chunks, so that later IDs avoid them
+func (g *ChunkIDGenerator) Reserve(ids ...string) {
+	for _, id := range ids {
+		g.used[id] = true
+	}
+}
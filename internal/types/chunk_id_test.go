@@ -0,0 +1,40 @@
+package types
+
+import "testing"
+
+func TestChunkIDGeneratorIsDeterministic(t *testing.T) {
+	chunk := &Chunk{ChunkType: ChunkTypeText, ChunkIndex: 3, Content: "hello"}
+
+	first := NewChunkIDGenerator("knowledge-1").ID(chunk)
+	if second := NewChunkIDGenerator("knowledge-1").ID(chunk); second != first {
+		t.Fatalf("same input produced %s and %s", first, second)
+	}
+	if other := NewChunkIDGenerator("knowledge-2").ID(chunk); other == first {
+		t.Fatalf("different knowledge produced the same ID %s", other)
+	}
+	moved := &Chunk{ChunkType: ChunkTypeText, ChunkIndex: 4, Content: "hello"}
+	if id := NewChunkIDGenerator("knowledge-1").ID(moved); id == first {
+		t.Fatalf("different position produced the same ID %s", id)
+	}
+	if len(first) != 36 {
+		t.Fatalf("ID %q is not a UUID", first)
+	}
+}
+
+func TestChunkIDGeneratorAvoidsCollisions(t *testing.T) {
+	chunk := &Chunk{ChunkType: ChunkTypeImageOCR, ChunkIndex: 101, Content: "same text"}
+
+	generator := NewChunkIDGenerator("knowledge-1")
+	first := generator.ID(chunk)
+	second := generator.ID(chunk)
+	if first == second {
+		t.Fatalf("duplicate chunk got the same ID %s", first)
+	}
+
+	// IDs taken by existing chunks are skipped, and the result is still deterministic
+	reserved := NewChunkIDGenerator("knowledge-1")
+	reserved.Reserve(first)
+	if id := reserved.ID(chunk); id != second {
+		t.Fatalf("reserved ID not skipped deterministically: got %s, want %s", id, second)
+	}
+}
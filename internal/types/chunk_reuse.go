@@ -0,0 +1,144 @@
+package types
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// ChunkReuseStats reports how an incremental update of a document reused the chunks of its previous version.
+// Parent and summary chunks are not counted, as they are not embedded with the document
+type ChunkReuseStats struct {
+	// Reused chunks kept their ID, embeddings and generated questions
+	Reused int `json:"reused"`
+	// Recomputed chunks are new or changed and were embedded again
+	Recomputed int `json:"recomputed"`
+	// Removed chunks of the previous version no longer appear in the document
+	Removed int `json:"removed"`
+}
+
+// Value implements driver.Valuer
+func (s ChunkReuseStats) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner
+func (s *ChunkReuseStats) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, s)
+}
+
+// ChunkReuse is the outcome of matching the chunks of a new version of a document with the previous version
+type ChunkReuse struct {
+	// Reused holds the IDs of the new chunks taken over from the previous version
+	Reused map[string]bool
+	// Stale are the chunks of the previous version without a counterpart in the new one
+	Stale []*Chunk
+	Stats ChunkReuseStats
+}
+
+// ChunkContentHash hashes the type and content of a chunk, which identify it across versions of a document
+func ChunkContentHash(chunk *Chunk) string {
+	sum := sha256.Sum256([]byte(chunk.ChunkType + "\x00" + chunk.Content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ReuseChunks matches the chunks of a new version of a document with the chunks of its previous version by
+// content hash, so that an edit only recomputes the chunks it touched. A matched chunk takes over the ID,
+// creation time and generated questions of its previous counterpart, preferring the previous chunk with
+// the same ID, and parent references within chunks follow the new IDs
+func ReuseChunks(previous, chunks []*Chunk) *ChunkReuse {
+	byID := make(map[string]*Chunk, len(previous))
+	byHash := make(map[string][]*Chunk, len(previous))
+	hashes := make(map[*Chunk]string, len(previous)+len(chunks))
+	for _, chunk := range previous {
+		hashes[chunk] = ChunkContentHash(chunk)
+		byID[chunk.ID] = chunk
+		byHash[hashes[chunk]] = append(byHash[hashes[chunk]], chunk)
+	}
+
+	// Unchanged chunks at unchanged positions keep their deterministic ID, the others are matched by content
+	matches := make(map[*Chunk]*Chunk, len(chunks))
+	taken := make(map[*Chunk]bool, len(previous))
+	for _, chunk := range chunks {
+		hashes[chunk] = ChunkContentHash(chunk)
+		if prev, ok := byID[chunk.ID]; ok && hashes[prev] == hashes[chunk] {
+			matches[chunk] = prev
+			taken[prev] = true
+		}
+	}
+	for _, chunk := range chunks {
+		if matches[chunk] != nil {
+			continue
+		}
+		for _, prev := range byHash[hashes[chunk]] {
+			if !taken[prev] {
+				matches[chunk] = prev
+				taken[prev] = true
+				break
+			}
+		}
+	}
+
+	reuse := &ChunkReuse{Reused: make(map[string]bool, len(matches))}
+	renamed := make(map[string]string, len(matches))
+	for _, chunk := range chunks {
+		prev := matches[chunk]
+		if prev == nil {
+			if isEmbeddedDocumentChunk(chunk) {
+				reuse.Stats.Recomputed++
+			}
+			continue
+		}
+		renamed[chunk.ID] = prev.ID
+		chunk.ID = prev.ID
+		chunk.CreatedAt = prev.CreatedAt
+		keepGeneratedQuestions(chunk, prev)
+		reuse.Reused[chunk.ID] = true
+		if isEmbeddedDocumentChunk(chunk) {
+			reuse.Stats.Reused++
+		}
+	}
+	for _, chunk := range chunks {
+		if id, ok := renamed[chunk.ParentChunkID]; ok {
+			chunk.ParentChunkID = id
+		}
+	}
+	for _, prev := range previous {
+		if taken[prev] {
+			continue
+		}
+		reuse.Stale = append(reuse.Stale, prev)
+		if isEmbeddedDocumentChunk(prev) {
+			reuse.Stats.Removed++
+		}
+	}
+	return reuse
+}
+
+// isEmbeddedDocumentChunk reports whether the chunk is embedded as part of the document content
+func isEmbeddedDocumentChunk(chunk *Chunk) bool {
+	return chunk.ChunkType != ChunkTypeParent && chunk.ChunkType != ChunkTypeSummary
+}
+
+// keepGeneratedQuestions carries the generated questions of the previous version of a chunk over, as their
+// index entries are kept with the chunk
+func keepGeneratedQuestions(chunk, prev *Chunk) {
+	prevMeta, err := prev.DocumentMetadata()
+	if err != nil || prevMeta == nil || len(prevMeta.GeneratedQuestions) == 0 {
+		return
+	}
+	meta, err := chunk.DocumentMetadata()
+	if err != nil || meta == nil {
+		meta = &DocumentChunkMetadata{}
+	}
+	meta.GeneratedQuestions = prevMeta.GeneratedQuestions
+	_ = chunk.SetDocumentMetadata(meta)
+}
@@ -0,0 +1,70 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReuseChunks(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	withQuestions := &Chunk{ID: "p-b", Content: "b", ChunkType: ChunkTypeText, CreatedAt: created}
+	if err := withQuestions.SetDocumentMetadata(&DocumentChunkMetadata{
+		GeneratedQuestions: []GeneratedQuestion{{ID: "q1", Question: "what is b?"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	previous := []*Chunk{
+		{ID: "p-a", Content: "a", ChunkType: ChunkTypeText},
+		withQuestions,
+		{ID: "p-c", Content: "c", ChunkType: ChunkTypeText},
+		{ID: "p-ocr", Content: "text in image", ChunkType: ChunkTypeImageOCR, ParentChunkID: "p-c"},
+		{ID: "p-summary", Content: "summary", ChunkType: ChunkTypeSummary},
+	}
+	moved := &Chunk{ID: "n-b", Content: "b", ChunkType: ChunkTypeText}
+	if err := moved.SetDocumentMetadata(&DocumentChunkMetadata{SectionPath: []string{"Intro"}}); err != nil {
+		t.Fatal(err)
+	}
+	// "a" is unchanged in place, a paragraph is inserted before "b", "c" is edited and keeps its image
+	chunks := []*Chunk{
+		{ID: "p-a", Content: "a", ChunkType: ChunkTypeText},
+		{ID: "n-new", Content: "new", ChunkType: ChunkTypeText},
+		moved,
+		{ID: "n-c", Content: "c!", ChunkType: ChunkTypeText},
+		{ID: "n-ocr", Content: "text in image", ChunkType: ChunkTypeImageOCR, ParentChunkID: "n-c"},
+	}
+
+	reuse := ReuseChunks(previous, chunks)
+
+	ids := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		ids = append(ids, chunk.ID)
+	}
+	if want := []string{"p-a", "n-new", "p-b", "n-c", "p-ocr"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("IDs = %v, want %v", ids, want)
+	}
+	if want := map[string]bool{"p-a": true, "p-b": true, "p-ocr": true}; !reflect.DeepEqual(reuse.Reused, want) {
+		t.Errorf("reused = %v, want %v", reuse.Reused, want)
+	}
+	if chunks[4].ParentChunkID != "n-c" {
+		t.Errorf("OCR chunk parent = %q, want the recomputed text chunk", chunks[4].ParentChunkID)
+	}
+	if !chunks[2].CreatedAt.Equal(created) {
+		t.Errorf("reused chunk created at %v, want %v", chunks[2].CreatedAt, created)
+	}
+	meta, err := chunks[2].DocumentMetadata()
+	if err != nil || meta == nil || len(meta.GeneratedQuestions) != 1 || !reflect.DeepEqual(meta.SectionPath, []string{"Intro"}) {
+		t.Errorf("reused chunk metadata = %+v (%v), want the previous questions and the new section path", meta, err)
+	}
+
+	stale := make([]string, 0, len(reuse.Stale))
+	for _, chunk := range reuse.Stale {
+		stale = append(stale, chunk.ID)
+	}
+	if want := []string{"p-c", "p-summary"}; !reflect.DeepEqual(stale, want) {
+		t.Errorf("stale = %v, want %v", stale, want)
+	}
+	if want := (ChunkReuseStats{Reused: 3, Recomputed: 2, Removed: 1}); reuse.Stats != want {
+		t.Errorf("stats = %+v, want %+v", reuse.Stats, want)
+	}
+}
@@ -0,0 +1,144 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Chunk size defaults, in characters
+const (
+	// DefaultMinChunkSize is the size below which a parsed fragment is merged into a neighbor
+	DefaultMinChunkSize = 50
+	// DefaultMaxChunkSize is the size above which a chunk is split when the chunk size is not set either
+	DefaultMaxChunkSize = 2048
+)
+
+// Chunking strategies
+const (
+	// ChunkingStrategyFixed keeps the chunks of the document parser, limited to the chunk sizes
+	ChunkingStrategyFixed = "fixed"
+	// ChunkingStrategySection chunks documents by their Markdown sections, keeping tables and code
+	// blocks whole and recording the heading path of each chunk
+	ChunkingStrategySection = "section"
+)
+
+// chunkSizeBucketBounds are the lower bounds of the buckets of a chunk size distribution
+var chunkSizeBucketBounds = []int{0, 64, 128, 256, 512, 1024, 2048, 4096}
+
+// Validate checks that the chunk size limits are positive and that the minimum is below the maximum
+func (c *ChunkingConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.ChunkSize < 0 || c.ChunkOverlap < 0 {
+		return fmt.Errorf("chunk_size and chunk_overlap must not be negative")
+	}
+	if c.MinChunkSize < 0 || c.MaxChunkSize < 0 {
+		return fmt.Errorf("min_chunk_size and max_chunk_size must not be negative")
+	}
+	if minSize, maxSize := c.EffectiveMinChunkSize(), c.EffectiveMaxChunkSize(); minSize >= maxSize {
+		return fmt.Errorf("min_chunk_size (%d) must be less than max_chunk_size (%d)", minSize, maxSize)
+	}
+	switch c.Strategy {
+	case "", ChunkingStrategyFixed, ChunkingStrategySection:
+	default:
+		return fmt.Errorf("strategy must be %q or %q", ChunkingStrategyFixed, ChunkingStrategySection)
+	}
+	return nil
+}
+
+// UsesSections reports whether documents are chunked by their sections
+func (c *ChunkingConfig) UsesSections() bool {
+	return c != nil && c.Strategy == ChunkingStrategySection
+}
+
+// EffectiveMinChunkSize returns the size below which a chunk is merged into a neighbor
+func (c *ChunkingConfig) EffectiveMinChunkSize() int {
+	if c == nil || c.MinChunkSize <= 0 {
+		return DefaultMinChunkSize
+	}
+	return c.MinChunkSize
+}
+
+// EffectiveMaxChunkSize returns the size above which a chunk is split, twice the chunk size by default
+func (c *ChunkingConfig) EffectiveMaxChunkSize() int {
+	switch {
+	case c != nil && c.MaxChunkSize > 0:
+		return c.MaxChunkSize
+	case c != nil && c.ChunkSize > 0:
+		return 2 * c.ChunkSize
+	default:
+		return DefaultMaxChunkSize
+	}
+}
+
+// ChunkSizeStats is the chunk size distribution of a processed knowledge, in characters
+type ChunkSizeStats struct {
+	// Count is the number of text chunks
+	Count int `json:"count"`
+	// MinSize, MaxSize and AvgSize describe the chunk sizes
+	MinSize int `json:"min_size"`
+	MaxSize int `json:"max_size"`
+	AvgSize int `json:"avg_size"`
+	// Merged is the number of parsed fragments merged into a neighbor for being below the minimum size
+	Merged int `json:"merged"`
+	// Split is the number of parsed chunks split for being above the maximum size
+	Split int `json:"split"`
+	// Buckets counts the chunks per size range
+	Buckets []ChunkSizeBucket `json:"buckets"`
+}
+
+// ChunkSizeBucket counts the chunks of size in [Min, Max), Max is 0 for the last, unbounded bucket
+type ChunkSizeBucket struct {
+	Min   int `json:"min"`
+	Max   int `json:"max,omitempty"`
+	Count int `json:"count"`
+}
+
+// NewChunkSizeStats builds the distribution of the given chunk sizes
+func NewChunkSizeStats(sizes []int, merged, split int) *ChunkSizeStats {
+	stats := &ChunkSizeStats{Count: len(sizes), Merged: merged, Split: split}
+	for i, bound := range chunkSizeBucketBounds {
+		bucket := ChunkSizeBucket{Min: bound}
+		if i+1 < len(chunkSizeBucketBounds) {
+			bucket.Max = chunkSizeBucketBounds[i+1]
+		}
+		stats.Buckets = append(stats.Buckets, bucket)
+	}
+	total := 0
+	for i, size := range sizes {
+		if i == 0 || size < stats.MinSize {
+			stats.MinSize = size
+		}
+		stats.MaxSize = max(stats.MaxSize, size)
+		total += size
+		for j := len(stats.Buckets) - 1; j >= 0; j-- {
+			if size >= stats.Buckets[j].Min {
+				stats.Buckets[j].Count++
+				break
+			}
+		}
+	}
+	if len(sizes) > 0 {
+		stats.AvgSize = total / len(sizes)
+	}
+	return stats
+}
+
+// Value implements driver.Valuer
+func (s ChunkSizeStats) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner
+func (s *ChunkSizeStats) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, s)
+}
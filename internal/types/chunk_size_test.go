@@ -0,0 +1,53 @@
+package types
+
+import "testing"
+
+func TestChunkingConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ChunkingConfig
+		wantMin int
+		wantMax int
+		wantErr bool
+	}{
+		{name: "defaults", wantMin: DefaultMinChunkSize, wantMax: DefaultMaxChunkSize},
+		{name: "maximum follows the chunk size", cfg: ChunkingConfig{ChunkSize: 512}, wantMin: 50, wantMax: 1024},
+		{name: "explicit limits", cfg: ChunkingConfig{MinChunkSize: 20, MaxChunkSize: 800}, wantMin: 20, wantMax: 800},
+		{name: "minimum equal to maximum", cfg: ChunkingConfig{MinChunkSize: 100, MaxChunkSize: 100}, wantErr: true},
+		{name: "maximum below the default minimum", cfg: ChunkingConfig{MaxChunkSize: 40}, wantErr: true},
+		{name: "negative minimum", cfg: ChunkingConfig{MinChunkSize: -1}, wantErr: true},
+		{name: "section strategy", cfg: ChunkingConfig{Strategy: ChunkingStrategySection}, wantMin: 50, wantMax: 2048},
+		{name: "unknown strategy", cfg: ChunkingConfig{Strategy: "semantic"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tt.cfg.EffectiveMinChunkSize(); got != tt.wantMin {
+				t.Errorf("EffectiveMinChunkSize() = %d, want %d", got, tt.wantMin)
+			}
+			if got := tt.cfg.EffectiveMaxChunkSize(); got != tt.wantMax {
+				t.Errorf("EffectiveMaxChunkSize() = %d, want %d", got, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestNewChunkSizeStats(t *testing.T) {
+	stats := NewChunkSizeStats([]int{10, 100, 5000}, 2, 1)
+	if stats.Count != 3 || stats.MinSize != 10 || stats.MaxSize != 5000 || stats.AvgSize != 1703 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	counts := map[int]int{}
+	for _, b := range stats.Buckets {
+		counts[b.Min] = b.Count
+	}
+	if counts[0] != 1 || counts[64] != 1 || counts[4096] != 1 {
+		t.Errorf("buckets = %+v", stats.Buckets)
+	}
+}
@@ -0,0 +1,340 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Citation strictness levels
+const (
+	// CitationStrictnessStrict requires every sentence to carry its own citation
+	CitationStrictnessStrict = "strict"
+	// CitationStrictnessLenient accepts a sentence when another sentence of the same line cites a passage,
+	// so that a citation may close a paragraph
+	CitationStrictnessLenient = "lenient"
+)
+
+// Actions taken on sentences without a valid citation
+const (
+	// CitationActionDrop removes the uncited sentences from the answer
+	CitationActionDrop = "drop"
+	// CitationActionFlag marks the uncited sentences of the answer
+	CitationActionFlag = "flag"
+)
+
+// CitationMissingMarker is appended to the flagged sentences
+const CitationMissingMarker = " [citation needed]"
+
+// DefaultCitationDropThreshold is the share of uncited sentences above which the answer is refused
+const DefaultCitationDropThreshold = 0.5
+
+// citationMarkerPattern matches citation markers such as [1], [2, 3] or [FAQ-1]
+var citationMarkerPattern = regexp.MustCompile(`\[\s*((?:(?:FAQ|DOC)-)?\d+(?:\s*,\s*(?:(?:FAQ|DOC)-)?\d+)*)\s*\]`)
+
+// citationLeadingMarkersPattern matches the citation markers opening a sentence
+var citationLeadingMarkersPattern = regexp.MustCompile(`^(?:\s*\[\s*(?:(?:FAQ|DOC)-)?\d+(?:\s*,\s*(?:(?:FAQ|DOC)-)?\d+)*\s*\])+`)
+
+// CitationConfig is the per knowledge base policy that asks the model to cite the passages inline and
+// removes or flags the answer sentences without a valid citation, so that every statement is traceable
+type CitationConfig struct {
+	// Required enables inline citations and the check of the answers generated from the knowledge base
+	Required bool `yaml:"required"          json:"required"`
+	// Strictness is strict (default), each sentence cites a passage, or lenient, a citation covers its line
+	Strictness string `yaml:"strictness"        json:"strictness,omitempty"`
+	// Action is applied to uncited sentences: drop (default) or flag
+	Action string `yaml:"action"            json:"action,omitempty"`
+	// DropThreshold is the share of uncited sentences above which the answer is replaced with the
+	// fallback response (0-1, 0 uses the default of 0.5)
+	DropThreshold float64 `yaml:"drop_threshold"    json:"drop_threshold,omitempty"`
+	// FallbackResponse replaces refused answers; empty uses the conversation fallback response
+	FallbackResponse string `yaml:"fallback_response" json:"fallback_response,omitempty"`
+}
+
+// Validate checks the strictness, action and drop threshold
+func (c *CitationConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.Strictness {
+	case "", CitationStrictnessStrict, CitationStrictnessLenient:
+	default:
+		return fmt.Errorf("strictness must be %q or %q", CitationStrictnessStrict, CitationStrictnessLenient)
+	}
+	switch c.Action {
+	case "", CitationActionDrop, CitationActionFlag:
+	default:
+		return fmt.Errorf("action must be %q or %q", CitationActionDrop, CitationActionFlag)
+	}
+	if c.DropThreshold < 0 || c.DropThreshold > 1 {
+		return fmt.Errorf("drop_threshold must be between 0 and 1")
+	}
+	return nil
+}
+
+// EffectiveStrictness returns the configured strictness or the default
+func (c *CitationConfig) EffectiveStrictness() string {
+	if c == nil || c.Strictness == "" {
+		return CitationStrictnessStrict
+	}
+	return c.Strictness
+}
+
+// EffectiveAction returns the configured action or the default
+func (c *CitationConfig) EffectiveAction() string {
+	if c == nil || c.Action == "" {
+		return CitationActionDrop
+	}
+	return c.Action
+}
+
+// EffectiveDropThreshold returns the configured drop threshold or the default
+func (c *CitationConfig) EffectiveDropThreshold() float64 {
+	if c == nil || c.DropThreshold <= 0 {
+		return DefaultCitationDropThreshold
+	}
+	return c.DropThreshold
+}
+
+// Stricter combines the policies of two knowledge bases that require citations: strict strictness,
+// dropping and the lower threshold win. Returns a copy when both require citations.
+func (c *CitationConfig) Stricter(other *CitationConfig) *CitationConfig {
+	if c == nil || !c.Required {
+		return other
+	}
+	if other == nil || !other.Required {
+		return c
+	}
+	merged := *c
+	if other.EffectiveStrictness() == CitationStrictnessStrict {
+		merged.Strictness = CitationStrictnessStrict
+	}
+	if other.EffectiveAction() == CitationActionDrop {
+		merged.Action = CitationActionDrop
+	}
+	if other.EffectiveDropThreshold() < c.EffectiveDropThreshold() {
+		merged.DropThreshold = other.EffectiveDropThreshold()
+	}
+	if merged.FallbackResponse == "" {
+		merged.FallbackResponse = other.FallbackResponse
+	}
+	return &merged
+}
+
+// Value implements driver.Valuer
+func (c CitationConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *CitationConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// CitationResult is the citation verdict of an answer
+type CitationResult struct {
+	Strictness string `json:"strictness"`
+	Action     string `json:"action"`
+	// Sentences is the number of answer sentences that need a citation
+	Sentences int `json:"sentences"`
+	// UncitedRatio is the share of these sentences without a valid citation (0-1)
+	UncitedRatio float64 `json:"uncited_ratio"`
+	// DropThreshold is the share of uncited sentences above which the answer is refused
+	DropThreshold float64 `json:"drop_threshold"`
+	// Dropped reports whether uncited sentences were removed from the answer
+	Dropped bool `json:"dropped"`
+	// Flagged reports whether uncited sentences were marked in the answer
+	Flagged bool `json:"flagged"`
+	// Refused reports whether the answer was replaced with the fallback response
+	Refused bool `json:"refused"`
+	// Uncited lists the sentences without a valid citation
+	Uncited []string `json:"uncited,omitempty"`
+}
+
+// citedSentence is an answer sentence with the citation markers that belong to it
+type citedSentence struct {
+	*GroundingSentence
+	// body is the offset of the sentence after the markers that close the previous sentence
+	body  int
+	line  int
+	cited bool
+}
+
+// ApplyCitations checks that the sentences of the answer cite a passage and applies the policy. valid
+// reports whether the label of a marker, such as "1" or "FAQ-2", names a passage of the context. Markers
+// opening a sentence close the previous sentence of the line, as models often cite after the period.
+// Markdown headings and sentences introducing a list with a colon need no citation. A refused answer is
+// returned unchanged; the caller replaces it with the fallback response.
+func (c *CitationConfig) ApplyCitations(answer string, valid func(label string) bool) (string, *CitationResult) {
+	result := &CitationResult{
+		Strictness:    c.EffectiveStrictness(),
+		Action:        c.EffectiveAction(),
+		DropThreshold: c.EffectiveDropThreshold(),
+	}
+
+	sentences := citedSentences(answer, valid)
+	if result.Strictness == CitationStrictnessLenient {
+		citedLines := make(map[int]bool)
+		for _, sentence := range sentences {
+			if sentence.cited {
+				citedLines[sentence.line] = true
+			}
+		}
+		for _, sentence := range sentences {
+			sentence.cited = sentence.cited || citedLines[sentence.line]
+		}
+	}
+
+	uncited := 0
+	for _, sentence := range sentences {
+		if needsCitation(answer, sentence) {
+			result.Sentences++
+			if !sentence.cited {
+				uncited++
+				result.Uncited = append(result.Uncited, sentence.Text)
+			}
+		} else {
+			sentence.cited = true
+		}
+	}
+	if uncited == 0 {
+		return answer, result
+	}
+	result.UncitedRatio = float64(uncited) / float64(result.Sentences)
+	// A small tolerance keeps e.g. 1 of 2 sentences at the 0.5 threshold
+	if result.UncitedRatio > result.DropThreshold+1e-9 ||
+		(result.Action == CitationActionDrop && uncited == result.Sentences) {
+		result.Refused = true
+		return answer, result
+	}
+	if result.Action == CitationActionFlag {
+		result.Flagged = true
+		return flagUncited(answer, sentences), result
+	}
+	result.Dropped = true
+	return dropUncited(answer, sentences), result
+}
+
+// citedSentences splits the answer into sentences and records which ones carry a valid citation, in
+// their text or in the markers that follow them on their line
+func citedSentences(answer string, valid func(label string) bool) []*citedSentence {
+	var sentences []*citedSentence
+	for _, sentence := range SplitGroundingSentences(answer) {
+		// Fragments made of markers only, such as "[FAQ-1]" after a period, belong to the previous sentence
+		if !strings.ContainsFunc(citationMarkerPattern.ReplaceAllString(sentence.Text, ""), unicode.IsLetter) {
+			continue
+		}
+		sentences = append(sentences, &citedSentence{
+			GroundingSentence: sentence,
+			body:              sentence.Start,
+			line:              strings.Count(answer[:sentence.Start], "\n"),
+		})
+	}
+
+	for i, sentence := range sentences {
+		end := lineEnd(answer, sentence.End)
+		if i+1 < len(sentences) && sentences[i+1].line == sentence.line {
+			next := sentences[i+1]
+			next.body = next.Start + len(citationLeadingMarkersPattern.FindString(next.Text))
+			end = next.body
+		}
+		sentence.cited = hasValidCitation(answer[sentence.body:end], valid)
+	}
+	return sentences
+}
+
+// hasValidCitation reports whether the text holds a marker citing a passage of the context
+func hasValidCitation(text string, valid func(label string) bool) bool {
+	for _, match := range citationMarkerPattern.FindAllStringSubmatch(text, -1) {
+		for _, label := range strings.Split(match[1], ",") {
+			if valid(strings.TrimSpace(label)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lineEnd returns the offset of the end of the line holding the offset
+func lineEnd(text string, offset int) int {
+	if i := strings.IndexByte(text[offset:], '\n'); i >= 0 {
+		return offset + i
+	}
+	return len(text)
+}
+
+// needsCitation reports whether a sentence states something: headings and list introductions do not
+func needsCitation(answer string, sentence *citedSentence) bool {
+	lineStart := strings.LastIndexByte(answer[:sentence.Start], '\n') + 1
+	if strings.HasPrefix(strings.TrimSpace(answer[lineStart:sentence.End]), "#") {
+		return false
+	}
+	text := strings.TrimRightFunc(sentence.Text, unicode.IsSpace)
+	return !strings.HasSuffix(text, ":") && !strings.HasSuffix(text, "：")
+}
+
+// flagUncited appends CitationMissingMarker to the uncited sentences of the answer
+func flagUncited(answer string, sentences []*citedSentence) string {
+	var sb strings.Builder
+	last := 0
+	for _, sentence := range sentences {
+		if sentence.cited || sentence.End < last {
+			continue
+		}
+		sb.WriteString(answer[last:sentence.End])
+		sb.WriteString(CitationMissingMarker)
+		last = sentence.End
+	}
+	sb.WriteString(answer[last:])
+	return sb.String()
+}
+
+// dropUncited removes the uncited sentences of the answer, with the text up to the next sentence of their
+// line. Lines left without a letter, such as a bare list bullet, are removed entirely.
+func dropUncited(answer string, sentences []*citedSentence) string {
+	var sb strings.Builder
+	lineStart := 0
+	for lineStart < len(answer) {
+		end := lineEnd(answer, lineStart)
+		next := min(end+1, len(answer))
+
+		var line strings.Builder
+		last, dropped, trailingDrop := lineStart, false, false
+		for i, sentence := range sentences {
+			if sentence.Start < lineStart || sentence.Start >= end || sentence.cited {
+				continue
+			}
+			cut := end
+			if i+1 < len(sentences) && sentences[i+1].Start < end {
+				cut = sentences[i+1].body
+			}
+			line.WriteString(answer[last:sentence.body])
+			last, dropped, trailingDrop = cut, true, cut == end
+		}
+		line.WriteString(answer[last:end])
+
+		kept := line.String()
+		if trailingDrop {
+			kept = strings.TrimRight(kept, " \t")
+		}
+		switch {
+		case !dropped:
+			sb.WriteString(answer[lineStart:next])
+		case strings.ContainsFunc(citationMarkerPattern.ReplaceAllString(kept, ""), unicode.IsLetter):
+			sb.WriteString(kept)
+			sb.WriteString(answer[end:next])
+		}
+		lineStart = next
+	}
+	return sb.String()
+}
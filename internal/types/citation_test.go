@@ -0,0 +1,103 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyCitations(t *testing.T) {
+	// The context holds passages [1], [2] and [FAQ-1]
+	valid := func(label string) bool { return label == "1" || label == "2" || label == "FAQ-1" }
+	answer := "## Password policy\nPasswords expire after 90 days [1]. They are stored hashed. [2]\n" +
+		"The rules:\n- Reset links last one hour [3].\n- Accounts lock after five attempts [1, 3]."
+
+	tests := []struct {
+		name        string
+		config      *CitationConfig
+		answer      string
+		wantAnswer  string
+		wantUncited []string
+		wantResult  CitationResult
+	}{
+		{
+			name:   "drop",
+			config: &CitationConfig{Required: true},
+			answer: answer,
+			wantAnswer: "## Password policy\nPasswords expire after 90 days [1]. They are stored hashed. [2]\n" +
+				"The rules:\n- Accounts lock after five attempts [1, 3].",
+			wantUncited: []string{"- Reset links last one hour [3]."},
+			wantResult:  CitationResult{Sentences: 4, UncitedRatio: 0.25, Dropped: true},
+		},
+		{
+			name:   "flag",
+			config: &CitationConfig{Required: true, Action: CitationActionFlag},
+			answer: answer,
+			wantAnswer: "## Password policy\nPasswords expire after 90 days [1]. They are stored hashed. [2]\n" +
+				"The rules:\n- Reset links last one hour [3]." + CitationMissingMarker +
+				"\n- Accounts lock after five attempts [1, 3].",
+			wantUncited: []string{"- Reset links last one hour [3]."},
+			wantResult:  CitationResult{Sentences: 4, UncitedRatio: 0.25, Flagged: true},
+		},
+		{
+			name:        "strict drops a sentence cited only by its neighbor",
+			config:      &CitationConfig{Required: true, DropThreshold: 0.7},
+			answer:      "Passwords expire after 90 days. They are stored hashed [1]. Links expire.",
+			wantAnswer:  "They are stored hashed [1].",
+			wantUncited: []string{"Passwords expire after 90 days.", "Links expire."},
+			wantResult:  CitationResult{Sentences: 3, UncitedRatio: 2.0 / 3, Dropped: true},
+		},
+		{
+			name:        "lenient accepts a citation closing the line",
+			config:      &CitationConfig{Required: true, Strictness: CitationStrictnessLenient, DropThreshold: 0.7},
+			answer:      "Passwords expire after 90 days. They are stored hashed [1].\nLinks expire.",
+			wantAnswer:  "Passwords expire after 90 days. They are stored hashed [1].\n",
+			wantUncited: []string{"Links expire."},
+			wantResult:  CitationResult{Sentences: 3, UncitedRatio: 1.0 / 3, Dropped: true},
+		},
+		{
+			name:        "too many uncited sentences",
+			config:      &CitationConfig{Required: true, DropThreshold: 0.3},
+			answer:      "Passwords expire after 90 days [1]. They are stored hashed.",
+			wantAnswer:  "Passwords expire after 90 days [1]. They are stored hashed.",
+			wantUncited: []string{"They are stored hashed."},
+			wantResult:  CitationResult{Sentences: 2, UncitedRatio: 0.5, Refused: true},
+		},
+		{
+			name:       "fully cited",
+			config:     &CitationConfig{Required: true},
+			answer:     "<think>Passage 1 answers.</think>Passwords expire after 90 days.[FAQ-1]",
+			wantAnswer: "<think>Passage 1 answers.</think>Passwords expire after 90 days.[FAQ-1]",
+			wantResult: CitationResult{Sentences: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, result := tt.config.ApplyCitations(tt.answer, valid)
+			if got != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", got, tt.wantAnswer)
+			}
+			tt.wantResult.Uncited = tt.wantUncited
+			tt.wantResult.Strictness = tt.config.EffectiveStrictness()
+			tt.wantResult.Action = tt.config.EffectiveAction()
+			tt.wantResult.DropThreshold = tt.config.EffectiveDropThreshold()
+			if !reflect.DeepEqual(*result, tt.wantResult) {
+				t.Errorf("result = %+v, want %+v", *result, tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestCitationConfigStricter(t *testing.T) {
+	lenient := &CitationConfig{Required: true, Strictness: CitationStrictnessLenient, Action: CitationActionFlag,
+		DropThreshold: 0.8}
+	strict := &CitationConfig{Required: true, DropThreshold: 0.6, FallbackResponse: "Not documented."}
+
+	if got := lenient.Stricter(&CitationConfig{Strictness: CitationStrictnessStrict}); got != lenient {
+		t.Errorf("Stricter(not required) = %+v, want the required policy", got)
+	}
+	want := CitationConfig{Required: true, Strictness: CitationStrictnessStrict, Action: CitationActionDrop,
+		DropThreshold: 0.6, FallbackResponse: "Not documented."}
+	if got := lenient.Stricter(strict); *got != want {
+		t.Errorf("Stricter() = %+v, want %+v", *got, want)
+	}
+}
@@ -0,0 +1,407 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Default confidence gate policy values
+const (
+	DefaultConfidenceThreshold            = 0.5
+	DefaultConfidenceRetrievalWeight      = 0.4
+	DefaultConfidenceRerankWeight         = 0.6
+	DefaultConfidenceSelfAssessmentWeight = 0.3
+	DefaultConfidenceCoverageWeight       = 0.5
+)
+
+// coverageMinOverlap is the share of its terms a sentence must share with the sources to be covered
+const coverageMinOverlap = 0.5
+
+// ConfidenceGateConfig is the per knowledge base policy that decides whether to answer a question
+// or refuse with the fallback response. Confidence is the weighted average of the available
+// signals: the best retrieval score, the best rerank score and, optionally, an LLM self-assessment.
+// Once the answer is generated, its coverage by the sources is added to score the answer itself.
+type ConfidenceGateConfig struct {
+	// Enabled refuses to answer when confidence is below Threshold; when disabled confidence is
+	// still computed and reported so the threshold can be calibrated first
+	Enabled bool `yaml:"enabled"                json:"enabled"`
+	// Threshold is the minimum confidence required to answer (0-1, 0 = default 0.5)
+	Threshold float64 `yaml:"threshold"              json:"threshold,omitempty"`
+	// RetrievalWeight weighs the best retrieval (vector/keyword) score
+	RetrievalWeight float64 `yaml:"retrieval_weight"       json:"retrieval_weight,omitempty"`
+	// RerankWeight weighs the best rerank model score
+	RerankWeight float64 `yaml:"rerank_weight"          json:"rerank_weight,omitempty"`
+	// SelfAssessment asks the chat model to rate whether the retrieved passages answer the question
+	SelfAssessment bool `yaml:"self_assessment"        json:"self_assessment"`
+	// SelfAssessmentWeight weighs the LLM self-assessment score
+	SelfAssessmentWeight float64 `yaml:"self_assessment_weight" json:"self_assessment_weight,omitempty"`
+	// CoverageWeight weighs the share of answer sentences covered by the sources
+	CoverageWeight float64 `yaml:"coverage_weight"        json:"coverage_weight,omitempty"`
+}
+
+// Validate checks the threshold and weights
+func (c *ConfidenceGateConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Threshold < 0 || c.Threshold > 1 {
+		return fmt.Errorf("threshold must be between 0 and 1")
+	}
+	if c.RetrievalWeight < 0 || c.RerankWeight < 0 || c.SelfAssessmentWeight < 0 || c.CoverageWeight < 0 {
+		return fmt.Errorf("weights must not be negative")
+	}
+	return nil
+}
+
+// EffectiveThreshold returns the configured threshold or the default
+func (c *ConfidenceGateConfig) EffectiveThreshold() float64 {
+	if c == nil || c.Threshold <= 0 {
+		return DefaultConfidenceThreshold
+	}
+	return c.Threshold
+}
+
+// Stricter returns whichever enabled policy has the higher threshold
+func (c *ConfidenceGateConfig) Stricter(other *ConfidenceGateConfig) *ConfidenceGateConfig {
+	if c == nil || !c.Enabled {
+		return other
+	}
+	if other == nil || !other.Enabled {
+		return c
+	}
+	if other.EffectiveThreshold() > c.EffectiveThreshold() {
+		return other
+	}
+	return c
+}
+
+func (c *ConfidenceGateConfig) weights() (retrieval, rerank, selfAssessment, coverage float64) {
+	retrieval, rerank, selfAssessment, coverage = DefaultConfidenceRetrievalWeight, DefaultConfidenceRerankWeight,
+		DefaultConfidenceSelfAssessmentWeight, DefaultConfidenceCoverageWeight
+	if c == nil {
+		return
+	}
+	if c.RetrievalWeight > 0 || c.RerankWeight > 0 || c.SelfAssessmentWeight > 0 || c.CoverageWeight > 0 {
+		retrieval, rerank, selfAssessment, coverage = c.RetrievalWeight, c.RerankWeight, c.SelfAssessmentWeight,
+			c.CoverageWeight
+	}
+	return
+}
+
+// ConfidenceSignals are the inputs of the confidence gate; nil means the signal is unavailable
+type ConfidenceSignals struct {
+	Retrieval      *float64
+	Rerank         *float64
+	SelfAssessment *float64
+	// Coverage is only known once the answer is generated
+	Coverage *float64
+}
+
+// ConfidenceResult is the outcome of the confidence gate for a single question, or the confidence
+// of the generated answer when Final is set
+type ConfidenceResult struct {
+	// Confidence is the weighted average of the available signals (0-1)
+	Confidence float64 `json:"confidence"`
+	// Threshold is the minimum confidence required to answer
+	Threshold float64 `json:"threshold"`
+	// Answer reports whether confidence reached the threshold
+	Answer bool `json:"answer"`
+	// Enforced reports whether the gate refuses low-confidence questions
+	Enforced bool `json:"enforced"`
+	// Final reports whether the score is the confidence of the generated answer, including its coverage
+	Final bool `json:"final"`
+
+	RetrievalScore      *float64 `json:"retrieval_score,omitempty"`
+	RerankScore         *float64 `json:"rerank_score,omitempty"`
+	SelfAssessmentScore *float64 `json:"self_assessment_score,omitempty"`
+	CoverageScore       *float64 `json:"coverage_score,omitempty"`
+}
+
+// Refused reports whether the gate decided not to answer
+func (r *ConfidenceResult) Refused() bool {
+	return r != nil && !r.Final && r.Enforced && !r.Answer
+}
+
+// Signals returns the signals a result was computed from
+func (r *ConfidenceResult) Signals() ConfidenceSignals {
+	if r == nil {
+		return ConfidenceSignals{}
+	}
+	return ConfidenceSignals{
+		Retrieval:      r.RetrievalScore,
+		Rerank:         r.RerankScore,
+		SelfAssessment: r.SelfAssessmentScore,
+		Coverage:       r.CoverageScore,
+	}
+}
+
+// Value implements driver.Valuer
+func (r ConfidenceResult) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+// Scan implements sql.Scanner
+func (r *ConfidenceResult) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, r)
+}
+
+// Evaluate combines the available signals into a confidence score. Weights of missing
+// signals are redistributed over the present ones; without any signal confidence is 0.
+func (c *ConfidenceGateConfig) Evaluate(signals ConfidenceSignals) *ConfidenceResult {
+	retrievalWeight, rerankWeight, selfWeight, coverageWeight := c.weights()
+	var sum, totalWeight float64
+	add := func(score *float64, weight float64) {
+		if score == nil || weight <= 0 {
+			return
+		}
+		sum += clamp01(*score) * weight
+		totalWeight += weight
+	}
+	add(signals.Retrieval, retrievalWeight)
+	add(signals.Rerank, rerankWeight)
+	add(signals.SelfAssessment, selfWeight)
+	add(signals.Coverage, coverageWeight)
+
+	confidence := 0.0
+	if totalWeight > 0 {
+		confidence = sum / totalWeight
+	}
+	threshold := c.EffectiveThreshold()
+	return &ConfidenceResult{
+		Confidence:          confidence,
+		Threshold:           threshold,
+		Answer:              confidence >= threshold,
+		Enforced:            c != nil && c.Enabled,
+		RetrievalScore:      signals.Retrieval,
+		RerankScore:         signals.Rerank,
+		SelfAssessmentScore: signals.SelfAssessment,
+		CoverageScore:       signals.Coverage,
+	}
+}
+
+// EvaluateAnswer scores a generated answer: the signals of the gate plus the coverage of the answer
+func (c *ConfidenceGateConfig) EvaluateAnswer(signals ConfidenceSignals, coverage *float64) *ConfidenceResult {
+	signals.Coverage = coverage
+	result := c.Evaluate(signals)
+	result.Final = true
+	return result
+}
+
+// AnswerCoverage estimates the share of answer sentences covered by the sources: a sentence is
+// covered when at least half of its terms (words, or pairs of adjacent CJK characters) occur in
+// the sources. Returns false when the answer has no sentence with terms.
+func AnswerCoverage(answer string, results []*SearchResult) (float64, bool) {
+	sourceTerms := make(map[string]struct{})
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		for term := range coverageTerms(result.Content) {
+			sourceTerms[term] = struct{}{}
+		}
+	}
+
+	var sentences, covered int
+	for _, sentence := range SplitGroundingSentences(answer) {
+		terms := coverageTerms(sentence.Text)
+		if len(terms) == 0 {
+			continue
+		}
+		shared := 0
+		for term := range terms {
+			if _, ok := sourceTerms[term]; ok {
+				shared++
+			}
+		}
+		sentences++
+		if float64(shared) >= coverageMinOverlap*float64(len(terms)) {
+			covered++
+		}
+	}
+	if sentences == 0 {
+		return 0, false
+	}
+	return float64(covered) / float64(sentences), true
+}
+
+// coverageTerms returns the lowercased words of at least two characters of the text. CJK text has
+// no spaces, so it contributes the pairs of adjacent characters instead.
+func coverageTerms(text string) map[string]struct{} {
+	terms := make(map[string]struct{})
+	var word strings.Builder
+	flush := func() {
+		if utf8.RuneCountInString(word.String()) > 1 {
+			terms[word.String()] = struct{}{}
+		}
+		word.Reset()
+	}
+	var prevCJK rune
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			flush()
+			if prevCJK != 0 {
+				terms[string([]rune{prevCJK, r})] = struct{}{}
+			}
+			prevCJK = r
+			continue
+		}
+		prevCJK = 0
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		flush()
+	}
+	flush()
+	return terms
+}
+
+// ConfidenceSignalsFromResults extracts the best retrieval and rerank scores from search results.
+// The rerank stage keeps the pre-rerank score in metadata "base_score" and the model score in
+// "rerank_score"; without reranking the result score is the retrieval score.
+func ConfidenceSignalsFromResults(results []*SearchResult) ConfidenceSignals {
+	var signals ConfidenceSignals
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		retrieval := result.Score
+		if base, ok := parseScoreMetadata(result.Metadata, "base_score"); ok {
+			retrieval = base
+		}
+		if signals.Retrieval == nil || retrieval > *signals.Retrieval {
+			v := retrieval
+			signals.Retrieval = &v
+		}
+		if rerank, ok := parseScoreMetadata(result.Metadata, "rerank_score"); ok {
+			if signals.Rerank == nil || rerank > *signals.Rerank {
+				v := rerank
+				signals.Rerank = &v
+			}
+		}
+	}
+	return signals
+}
+
+func parseScoreMetadata(metadata map[string]string, key string) (float64, bool) {
+	raw, ok := metadata[key]
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	return v, err == nil
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// Value implements driver.Valuer
+func (c ConfidenceGateConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *ConfidenceGateConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// ConfidenceEvalCase is a labeled query used to evaluate the confidence gate
+type ConfidenceEvalCase struct {
+	Query string `json:"query"`
+	// Answerable is the label: whether the knowledge base contains the answer
+	Answerable bool `json:"answerable"`
+}
+
+// ConfidenceEvalItem is the gate outcome for one labeled query
+type ConfidenceEvalItem struct {
+	Query      string            `json:"query"`
+	Answerable bool              `json:"answerable"`
+	Result     *ConfidenceResult `json:"result,omitempty"`
+	Correct    bool              `json:"correct"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// ConfidenceThresholdStat summarizes gate quality at one threshold
+type ConfidenceThresholdStat struct {
+	Threshold float64 `json:"threshold"`
+	Accuracy  float64 `json:"accuracy"`
+	// RefusalPrecision is the share of refusals that were unanswerable queries
+	RefusalPrecision float64 `json:"refusal_precision"`
+	// RefusalRecall is the share of unanswerable queries that were refused
+	RefusalRecall float64 `json:"refusal_recall"`
+	// AnswerRate is the share of queries that would be answered
+	AnswerRate float64 `json:"answer_rate"`
+}
+
+// ConfidenceEvalReport is the result of evaluating the confidence gate against labeled queries
+type ConfidenceEvalReport struct {
+	Config        *ConfidenceGateConfig     `json:"config"`
+	Items         []*ConfidenceEvalItem     `json:"items"`
+	Summary       ConfidenceThresholdStat   `json:"summary"`
+	Sweep         []ConfidenceThresholdStat `json:"sweep"`
+	BestThreshold float64                   `json:"best_threshold"`
+}
+
+// ThresholdStat computes gate quality over the evaluated items at the given threshold
+func (r *ConfidenceEvalReport) ThresholdStat(threshold float64) ConfidenceThresholdStat {
+	stat := ConfidenceThresholdStat{Threshold: threshold}
+	var total, correct, refused, refusedUnanswerable, unanswerable, answered int
+	for _, item := range r.Items {
+		if item.Result == nil {
+			continue
+		}
+		total++
+		answer := item.Result.Confidence >= threshold
+		if answer == item.Answerable {
+			correct++
+		}
+		if !item.Answerable {
+			unanswerable++
+		}
+		if answer {
+			answered++
+		} else {
+			refused++
+			if !item.Answerable {
+				refusedUnanswerable++
+			}
+		}
+	}
+	if total > 0 {
+		stat.Accuracy = float64(correct) / float64(total)
+		stat.AnswerRate = float64(answered) / float64(total)
+	}
+	if refused > 0 {
+		stat.RefusalPrecision = float64(refusedUnanswerable) / float64(refused)
+	}
+	if unanswerable > 0 {
+		stat.RefusalRecall = float64(refusedUnanswerable) / float64(unanswerable)
+	}
+	return stat
+}
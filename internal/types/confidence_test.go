@@ -0,0 +1,130 @@
+package types
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConfidenceGateEvaluate(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		config  *ConfidenceGateConfig
+		signals ConfidenceSignals
+		want    float64
+		refused bool
+	}{
+		{
+			name:    "default weights",
+			config:  &ConfidenceGateConfig{Enabled: true},
+			signals: ConfidenceSignals{Retrieval: f(0.5), Rerank: f(1.0)},
+			want:    0.8,
+		},
+		{
+			name:    "missing rerank redistributes weight",
+			config:  &ConfidenceGateConfig{Enabled: true, Threshold: 0.6},
+			signals: ConfidenceSignals{Retrieval: f(0.5)},
+			want:    0.5,
+			refused: true,
+		},
+		{
+			name:    "self assessment ignored when weight zero",
+			config:  &ConfidenceGateConfig{Enabled: true, RetrievalWeight: 1},
+			signals: ConfidenceSignals{Retrieval: f(0.7), SelfAssessment: f(0)},
+			want:    0.7,
+		},
+		{
+			name:    "disabled gate never refuses",
+			config:  nil,
+			signals: ConfidenceSignals{},
+			want:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.Evaluate(tt.signals)
+			if math.Abs(got.Confidence-tt.want) > 1e-9 {
+				t.Errorf("confidence = %v, want %v", got.Confidence, tt.want)
+			}
+			if got.Refused() != tt.refused {
+				t.Errorf("refused = %v, want %v", got.Refused(), tt.refused)
+			}
+		})
+	}
+}
+
+func TestConfidenceSignalsFromResults(t *testing.T) {
+	results := []*SearchResult{
+		{Score: 0.9, Metadata: map[string]string{"base_score": "0.4000", "rerank_score": "0.9500"}},
+		{Score: 0.3, Metadata: map[string]string{"base_score": "0.6000"}},
+	}
+	signals := ConfidenceSignalsFromResults(results)
+	if signals.Retrieval == nil || *signals.Retrieval != 0.6 {
+		t.Errorf("retrieval = %v, want 0.6", signals.Retrieval)
+	}
+	if signals.Rerank == nil || *signals.Rerank != 0.95 {
+		t.Errorf("rerank = %v, want 0.95", signals.Rerank)
+	}
+}
+
+func TestConfidenceGateEvaluateAnswer(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	config := &ConfidenceGateConfig{Enabled: true, Threshold: 0.6}
+
+	// Default weights: 0.4 * 0.5 + 0.6 * 1.0 + 0.5 * 0.2 over 1.5
+	got := config.EvaluateAnswer(ConfidenceSignals{Retrieval: f(0.5), Rerank: f(1.0)}, f(0.2))
+	if math.Abs(got.Confidence-0.6) > 1e-9 {
+		t.Errorf("confidence = %v, want 0.6", got.Confidence)
+	}
+	if !got.Final || got.CoverageScore == nil || *got.CoverageScore != 0.2 {
+		t.Errorf("result = %+v, want a final result with the coverage", got)
+	}
+
+	// A low answer confidence is reported, never refused
+	got = config.EvaluateAnswer(ConfidenceSignals{Retrieval: f(0.5)}, f(0))
+	if got.Answer || got.Refused() {
+		t.Errorf("answer = %v, refused = %v, want low confidence without refusal", got.Answer, got.Refused())
+	}
+}
+
+func TestAnswerCoverage(t *testing.T) {
+	results := []*SearchResult{
+		{Content: "Passwords expire after 90 days and must then be reset by an administrator."},
+		{Content: "密码每九十天过期一次。"},
+	}
+	tests := []struct {
+		name   string
+		answer string
+		want   float64
+		ok     bool
+	}{
+		{name: "covered", answer: "Passwords expire after 90 days.", want: 1, ok: true},
+		{name: "partly covered", answer: "Passwords expire after 90 days. Accounts are locked on Sundays.", want: 0.5, ok: true},
+		{name: "cjk", answer: "密码九十天过期。账户会被永久删除。", want: 0.5, ok: true},
+		{name: "thinking is ignored", answer: "<think>Accounts are locked.</think>An administrator must reset passwords.", want: 1, ok: true},
+		{name: "no terms", answer: "1. 2.", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := AnswerCoverage(tt.answer, results)
+			if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("AnswerCoverage() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestConfidenceEvalThresholdStat(t *testing.T) {
+	report := &ConfidenceEvalReport{Items: []*ConfidenceEvalItem{
+		{Answerable: true, Result: &ConfidenceResult{Confidence: 0.9}},
+		{Answerable: true, Result: &ConfidenceResult{Confidence: 0.4}},
+		{Answerable: false, Result: &ConfidenceResult{Confidence: 0.3}},
+		{Answerable: false, Result: &ConfidenceResult{Confidence: 0.6}},
+	}}
+	stat := report.ThresholdStat(0.5)
+	if stat.Accuracy != 0.5 || stat.RefusalPrecision != 0.5 || stat.RefusalRecall != 0.5 || stat.AnswerRate != 0.5 {
+		t.Errorf("unexpected stat: %+v", stat)
+	}
+}
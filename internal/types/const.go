@@ -14,6 +14,14 @@ const (
 	LoggerContextKey ContextKey = "Logger"
 	// UserContextKey is the context key for user information
 	UserContextKey ContextKey = "User"
+	// LanguageContextKey is the context key for the language of user-facing messages
+	LanguageContextKey ContextKey = "Language"
+	// StageTimingsContextKey is the context key for the stage timings of a request
+	StageTimingsContextKey ContextKey = "StageTimings"
+	// APIKeyScopesContextKey is the context key for the scopes of the API key a request authenticated with
+	APIKeyScopesContextKey ContextKey = "APIKeyScopes"
+	// UserRoleContextKey is the context key for the role of the logged-in user in the tenant of a request
+	UserRoleContextKey ContextKey = "UserRole"
 )
 
 // String returns the string representation of the context key
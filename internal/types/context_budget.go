@@ -0,0 +1,119 @@
+package types
+
+import "fmt"
+
+// Context budget defaults
+const (
+	// DefaultContextWindow is the assumed model context window in tokens when none is configured
+	DefaultContextWindow = 32 * 1024
+	// ContextBudgetWarningRatio is the share of the context window above which the prompt is reported as near the limit
+	ContextBudgetWarningRatio = 0.9
+)
+
+// ContextBudgetReport breaks down how the model's context window is spent by a knowledge QA prompt.
+// Token counts are estimates (about 4 characters per token), not the model tokenizer's exact counts.
+type ContextBudgetReport struct {
+	// ContextWindow is the model context window the prompt is measured against
+	ContextWindow int `json:"context_window"`
+	// SystemPromptTokens is the rendered system prompt
+	SystemPromptTokens int `json:"system_prompt_tokens"`
+	// HistoryTokens and HistoryRounds cover the prior turns included in the prompt
+	HistoryTokens int `json:"history_tokens"`
+	HistoryRounds int `json:"history_rounds"`
+	// RetrievedContextTokens and RetrievedChunks cover the retrieved passages inserted into the prompt
+	RetrievedContextTokens int `json:"retrieved_context_tokens"`
+	RetrievedChunks        int `json:"retrieved_chunks"`
+	// QueryTokens is the rest of the user message: the query and the context template around it
+	QueryTokens int `json:"query_tokens"`
+	// PromptTokens is the total of the above
+	PromptTokens int `json:"prompt_tokens"`
+	// MaxCompletionTokens is the configured generation limit (0 = model default)
+	MaxCompletionTokens int `json:"max_completion_tokens"`
+	// RemainingTokens is what the context window leaves for generation after the prompt
+	RemainingTokens int `json:"remaining_tokens"`
+	// UsageRatio is PromptTokens / ContextWindow
+	UsageRatio float64 `json:"usage_ratio"`
+	// Warning is set when the prompt is near or over the context window
+	Warning string `json:"warning,omitempty"`
+	// OversizedChunks lists the retrieved chunks reduced to fit the context budget
+	OversizedChunks []*OversizedChunkAction `json:"oversized_chunks,omitempty"`
+}
+
+// Strategies for retrieved chunks that alone exceed the prompt's context budget
+const (
+	// OversizedChunkTruncate keeps the beginning of the chunk
+	OversizedChunkTruncate = "truncate"
+	// OversizedChunkSummarize keeps the chunk's sentences sharing the most terms with the query
+	OversizedChunkSummarize = "summarize"
+)
+
+// OversizedChunkAction records how a retrieved chunk larger than the context budget was fitted into the prompt
+type OversizedChunkAction struct {
+	ChunkID string `json:"chunk_id"`
+	// Action is the applied strategy, "truncate" or "summarize"
+	Action string `json:"action"`
+	// OriginalTokens and Tokens are the estimated sizes of the chunk before and after fitting
+	OriginalTokens int `json:"original_tokens"`
+	Tokens         int `json:"tokens"`
+}
+
+// ResolveOversizedChunkStrategy returns a valid oversized chunk strategy, truncate by default
+func ResolveOversizedChunkStrategy(strategy string) string {
+	if strategy == OversizedChunkSummarize {
+		return OversizedChunkSummarize
+	}
+	return OversizedChunkTruncate
+}
+
+// EstimateTextTokens estimates the token count of a text (rough approximation: 4 characters ≈ 1 token)
+func EstimateTextTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// NewContextBudgetReport builds the budget report of a prompt made of a system prompt, prior turns and
+// a user message containing the retrieved contexts. contextWindow <= 0 uses DefaultContextWindow.
+func NewContextBudgetReport(
+	contextWindow, maxCompletionTokens int,
+	systemPrompt string,
+	history []*History,
+	userContent, contexts string,
+	retrievedChunks int,
+) *ContextBudgetReport {
+	if contextWindow <= 0 {
+		contextWindow = DefaultContextWindow
+	}
+	report := &ContextBudgetReport{
+		ContextWindow:       contextWindow,
+		SystemPromptTokens:  EstimateTextTokens(systemPrompt),
+		HistoryRounds:       len(history),
+		RetrievedChunks:     retrievedChunks,
+		MaxCompletionTokens: maxCompletionTokens,
+	}
+	for _, h := range history {
+		report.HistoryTokens += EstimateTextTokens(h.Query) + EstimateTextTokens(h.Answer)
+	}
+	userTokens := EstimateTextTokens(userContent)
+	report.RetrievedContextTokens = min(EstimateTextTokens(contexts), userTokens)
+	report.QueryTokens = userTokens - report.RetrievedContextTokens
+	report.PromptTokens = report.SystemPromptTokens + report.HistoryTokens + userTokens
+	report.RemainingTokens = max(contextWindow-report.PromptTokens, 0)
+	report.UsageRatio = float64(report.PromptTokens) / float64(contextWindow)
+
+	switch {
+	case report.PromptTokens >= contextWindow:
+		report.Warning = fmt.Sprintf("prompt (~%d tokens) exceeds the %d token context window; "+
+			"reduce history_depth or the number of retrieved chunks", report.PromptTokens, contextWindow)
+	case maxCompletionTokens > 0 && report.RemainingTokens < maxCompletionTokens:
+		report.Warning = fmt.Sprintf("only ~%d tokens remain for generation, less than max_completion_tokens (%d); "+
+			"the answer may be truncated", report.RemainingTokens, maxCompletionTokens)
+	case report.UsageRatio >= ContextBudgetWarningRatio:
+		report.Warning = fmt.Sprintf("prompt uses %.0f%% of the %d token context window",
+			report.UsageRatio*100, contextWindow)
+	}
+	return report
+}
+
+// NearLimit reports whether the prompt is near or over the context window
+func (r *ContextBudgetReport) NearLimit() bool {
+	return r != nil && r.Warning != ""
+}
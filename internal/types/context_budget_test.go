@@ -0,0 +1,71 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewContextBudgetReport(t *testing.T) {
+	contexts := strings.Repeat("c", 400)
+	userContent := "Question: what is a comet?\n" + contexts
+	history := []*History{{Query: strings.Repeat("q", 40), Answer: strings.Repeat("a", 80)}}
+
+	report := NewContextBudgetReport(0, 512, strings.Repeat("s", 200), history, userContent, contexts, 3)
+	if report.ContextWindow != DefaultContextWindow {
+		t.Errorf("ContextWindow = %d, want default %d", report.ContextWindow, DefaultContextWindow)
+	}
+	if report.SystemPromptTokens != 50 {
+		t.Errorf("SystemPromptTokens = %d, want 50", report.SystemPromptTokens)
+	}
+	if report.HistoryTokens != 30 || report.HistoryRounds != 1 {
+		t.Errorf("history = %d tokens / %d rounds, want 30 / 1", report.HistoryTokens, report.HistoryRounds)
+	}
+	if report.RetrievedContextTokens != 100 || report.RetrievedChunks != 3 {
+		t.Errorf("retrieved = %d tokens / %d chunks, want 100 / 3", report.RetrievedContextTokens, report.RetrievedChunks)
+	}
+	if want := EstimateTextTokens(userContent) - 100; report.QueryTokens != want {
+		t.Errorf("QueryTokens = %d, want %d", report.QueryTokens, want)
+	}
+	wantPrompt := report.SystemPromptTokens + report.HistoryTokens + report.RetrievedContextTokens + report.QueryTokens
+	if report.PromptTokens != wantPrompt {
+		t.Errorf("PromptTokens = %d, want %d", report.PromptTokens, wantPrompt)
+	}
+	if report.RemainingTokens != DefaultContextWindow-wantPrompt {
+		t.Errorf("RemainingTokens = %d, want %d", report.RemainingTokens, DefaultContextWindow-wantPrompt)
+	}
+	if report.NearLimit() {
+		t.Errorf("small prompt reported near limit: %s", report.Warning)
+	}
+}
+
+func TestContextBudgetReportWarnings(t *testing.T) {
+	contexts := strings.Repeat("c", 3600) // 900 tokens
+
+	tests := []struct {
+		name          string
+		window        int
+		maxCompletion int
+		wantWarning   string
+		wantRemaining int
+	}{
+		{"fits", 4000, 0, "", 3100},
+		{"near window", 950, 0, "of the 950 token context window", 50},
+		{"no room for completion", 1200, 512, "less than max_completion_tokens", 300},
+		{"over window", 800, 0, "exceeds the 800 token context window", 0},
+	}
+	for _, tt := range tests {
+		report := NewContextBudgetReport(tt.window, tt.maxCompletion, "", nil, contexts, contexts, 5)
+		if report.RemainingTokens != tt.wantRemaining {
+			t.Errorf("%s: RemainingTokens = %d, want %d", tt.name, report.RemainingTokens, tt.wantRemaining)
+		}
+		if tt.wantWarning == "" {
+			if report.NearLimit() {
+				t.Errorf("%s: unexpected warning %q", tt.name, report.Warning)
+			}
+			continue
+		}
+		if !strings.Contains(report.Warning, tt.wantWarning) {
+			t.Errorf("%s: warning = %q, want it to contain %q", tt.name, report.Warning, tt.wantWarning)
+		}
+	}
+}
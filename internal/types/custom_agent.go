@@ -3,8 +3,11 @@ package types
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
+	"slices"
 	"time"
 
+	werrors "github.com/Tencent/WeKnora/internal/errors"
 	"gorm.io/gorm"
 )
 
@@ -32,6 +35,16 @@ const (
 	AgentModeSmartReasoning = "smart-reasoning"
 )
 
+// RetrievalMode constants declaring when an agent retrieves from its knowledge bases
+const (
+	// RetrievalModeAlways retrieves before every answer (default for quick-answer agents)
+	RetrievalModeAlways = "always"
+	// RetrievalModeToolOnly lets the agent decide when to call knowledge tools (default for smart-reasoning agents)
+	RetrievalModeToolOnly = "tool_only"
+	// RetrievalModeNever answers without knowledge base retrieval, even when knowledge is @ mentioned
+	RetrievalModeNever = "never"
+)
+
 // CustomAgent represents a configurable AI agent (similar to GPTs)
 type CustomAgent struct {
 	// Unique identifier of the agent (composite primary key with TenantID)
@@ -53,6 +66,8 @@ type CustomAgent struct {
 
 	// Agent configuration
 	Config CustomAgentConfig `yaml:"config" json:"config" gorm:"type:json"`
+	// Version currently serving live traffic (0 = unversioned, the stored config is live)
+	PublishedVersion int `yaml:"published_version" json:"published_version" gorm:"default:0"`
 
 	// Timestamps
 	CreatedAt time.Time      `yaml:"created_at" json:"created_at"`
@@ -85,6 +100,10 @@ type CustomAgentConfig struct {
 	// ===== Agent Mode Settings =====
 	// Maximum iterations for ReAct loop (only for agent type)
 	MaxIterations int `yaml:"max_iterations" json:"max_iterations"`
+	// Maximum total tokens (prompt + completion, provider-reported or estimated) the ReAct loop may consume, 0 means unlimited (only for agent type)
+	MaxTotalTokens int `yaml:"max_total_tokens" json:"max_total_tokens"`
+	// Wall-clock budget in seconds for the ReAct loop, 0 means unlimited (only for agent type)
+	MaxExecutionSeconds int `yaml:"max_execution_seconds" json:"max_execution_seconds"`
 	// Allowed tools (only for agent type)
 	AllowedTools []string `yaml:"allowed_tools" json:"allowed_tools"`
 	// Whether reflection is enabled (only for agent type)
@@ -103,6 +122,9 @@ type CustomAgentConfig struct {
 	// When true, knowledge base retrieval only happens if user explicitly mentions KB/files with @
 	// When false, knowledge base retrieval happens according to KBSelectionMode
 	RetrieveKBOnlyWhenMentioned bool `yaml:"retrieve_kb_only_when_mentioned" json:"retrieve_kb_only_when_mentioned"`
+	// Retrieval mode: "always", "tool_only" or "never" (empty = default for the agent mode)
+	// "tool_only" requires smart-reasoning mode; "always" in smart-reasoning mode forces a knowledge_search first
+	RetrievalMode string `yaml:"retrieval_mode" json:"retrieval_mode"`
 
 	// ===== File Type Restriction Settings =====
 	// Supported file types for this agent (e.g., ["csv", "xlsx", "xls"])
@@ -227,6 +249,67 @@ func (a *CustomAgent) EnsureDefaults() {
 	}
 }
 
+// ValidatePromptTemplates validates every prompt template of the agent against the known placeholder set
+// The system prompt is checked against agent-mode or normal-mode placeholders depending on AgentMode
+func (c *CustomAgentConfig) ValidatePromptTemplates() error {
+	systemPromptField := PromptFieldSystemPrompt
+	if c.AgentMode == AgentModeSmartReasoning {
+		systemPromptField = PromptFieldAgentSystemPrompt
+	}
+	templates := []struct {
+		field    PromptFieldType
+		template string
+	}{
+		{systemPromptField, c.SystemPrompt},
+		{PromptFieldContextTemplate, c.ContextTemplate},
+		{PromptFieldRewriteSystemPrompt, c.RewritePromptSystem},
+		{PromptFieldRewritePrompt, c.RewritePromptUser},
+		{PromptFieldFallbackPrompt, c.FallbackPrompt},
+	}
+	for _, t := range templates {
+		if err := ValidatePromptTemplate(t.field, t.template); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EffectiveRetrievalMode returns the configured retrieval mode, falling back to the agent mode default:
+// quick-answer agents always retrieve, smart-reasoning agents retrieve through knowledge tools
+func (c *CustomAgentConfig) EffectiveRetrievalMode() string {
+	if c.RetrievalMode != "" {
+		return c.RetrievalMode
+	}
+	if c.AgentMode == AgentModeSmartReasoning {
+		return RetrievalModeToolOnly
+	}
+	return RetrievalModeAlways
+}
+
+// ValidateRetrievalMode checks that the retrieval mode is known and can be honored by the agent mode
+func (c *CustomAgentConfig) ValidateRetrievalMode() error {
+	switch c.RetrievalMode {
+	case "", RetrievalModeNever:
+		return nil
+	case RetrievalModeAlways:
+		if c.AgentMode == AgentModeSmartReasoning && len(c.AllowedTools) > 0 &&
+			!slices.Contains(c.AllowedTools, knowledgeSearchToolName) {
+			return werrors.NewValidationError("retrieval_mode \"always\" requires the knowledge_search tool").
+				WithCode(werrors.CodeAgentInvalidRetrievalMode)
+		}
+		return nil
+	case RetrievalModeToolOnly:
+		if c.AgentMode != AgentModeSmartReasoning {
+			return werrors.NewValidationError("retrieval_mode \"tool_only\" requires smart-reasoning agent mode").
+				WithCode(werrors.CodeAgentInvalidRetrievalMode)
+		}
+		return nil
+	default:
+		return werrors.NewValidationError(fmt.Sprintf("unknown retrieval_mode %q, expected always, tool_only or never", c.RetrievalMode)).
+			WithCode(werrors.CodeAgentInvalidRetrievalMode)
+	}
+}
+
 // IsAgentMode returns true if this agent uses ReAct agent mode
 func (a *CustomAgent) IsAgentMode() bool {
 	return a.Config.AgentMode == AgentModeSmartReasoning
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"testing"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+)
+
+func TestEffectiveRetrievalMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		config CustomAgentConfig
+		want   string
+	}{
+		{"quick-answer default", CustomAgentConfig{AgentMode: AgentModeQuickAnswer}, RetrievalModeAlways},
+		{"empty agent mode default", CustomAgentConfig{}, RetrievalModeAlways},
+		{"smart-reasoning default", CustomAgentConfig{AgentMode: AgentModeSmartReasoning}, RetrievalModeToolOnly},
+		{"explicit never", CustomAgentConfig{AgentMode: AgentModeQuickAnswer, RetrievalMode: RetrievalModeNever}, RetrievalModeNever},
+		{"explicit always in agent mode", CustomAgentConfig{AgentMode: AgentModeSmartReasoning, RetrievalMode: RetrievalModeAlways}, RetrievalModeAlways},
+	}
+	for _, tt := range tests {
+		if got := tt.config.EffectiveRetrievalMode(); got != tt.want {
+			t.Errorf("%s: EffectiveRetrievalMode() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	for _, id := range []string{BuiltinQuickAnswerID, BuiltinSmartReasoningID} {
+		agent := GetBuiltinAgent(id, 1)
+		if err := agent.Config.ValidateRetrievalMode(); err != nil {
+			t.Errorf("built-in agent %s has invalid retrieval mode: %v", id, err)
+		}
+	}
+}
+
+func TestValidateRetrievalMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  CustomAgentConfig
+		wantErr bool
+	}{
+		{"unset", CustomAgentConfig{AgentMode: AgentModeQuickAnswer}, false},
+		{"never in quick-answer", CustomAgentConfig{AgentMode: AgentModeQuickAnswer, RetrievalMode: RetrievalModeNever}, false},
+		{"never in smart-reasoning", CustomAgentConfig{AgentMode: AgentModeSmartReasoning, RetrievalMode: RetrievalModeNever}, false},
+		{"always with default tools", CustomAgentConfig{AgentMode: AgentModeSmartReasoning, RetrievalMode: RetrievalModeAlways}, false},
+		{
+			"always with knowledge_search",
+			CustomAgentConfig{AgentMode: AgentModeSmartReasoning, RetrievalMode: RetrievalModeAlways, AllowedTools: []string{"thinking", "knowledge_search"}},
+			false,
+		},
+		{
+			"always without knowledge_search",
+			CustomAgentConfig{AgentMode: AgentModeSmartReasoning, RetrievalMode: RetrievalModeAlways, AllowedTools: []string{"web_search"}},
+			true,
+		},
+		{"tool_only in smart-reasoning", CustomAgentConfig{AgentMode: AgentModeSmartReasoning, RetrievalMode: RetrievalModeToolOnly}, false},
+		{"tool_only in quick-answer", CustomAgentConfig{AgentMode: AgentModeQuickAnswer, RetrievalMode: RetrievalModeToolOnly}, true},
+		{"unknown mode", CustomAgentConfig{AgentMode: AgentModeQuickAnswer, RetrievalMode: "sometimes"}, true},
+	}
+	for _, tt := range tests {
+		err := tt.config.ValidateRetrievalMode()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateRetrievalMode() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			if code := werrors.FromError(err).MachineCode(); code != werrors.CodeAgentInvalidRetrievalMode {
+				t.Errorf("%s: code = %q, want %q", tt.name, code, werrors.CodeAgentInvalidRetrievalMode)
+			}
+		}
+	}
+}
+
+func TestToolChoiceForRound(t *testing.T) {
+	available := []string{"thinking", "knowledge_search", "grep_chunks"}
+
+	always := &AgentConfig{RetrievalMode: RetrievalModeAlways}
+	if got := always.ToolChoiceForRound(0, available); got != "knowledge_search" {
+		t.Errorf("always, round 0: tool choice = %q, want knowledge_search", got)
+	}
+	if got := always.ToolChoiceForRound(1, available); got != "" {
+		t.Errorf("always, round 1: tool choice = %q, want model's choice", got)
+	}
+	// Without knowledge the tool is not registered, so nothing can be forced
+	if got := always.ToolChoiceForRound(0, []string{"thinking"}); got != "" {
+		t.Errorf("always without knowledge_search: tool choice = %q, want model's choice", got)
+	}
+
+	for _, mode := range []string{RetrievalModeToolOnly, RetrievalModeNever, ""} {
+		config := &AgentConfig{RetrievalMode: mode}
+		if got := config.ToolChoiceForRound(0, available); got != "" {
+			t.Errorf("mode %q, round 0: tool choice = %q, want model's choice", mode, got)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Agent version status constants
+const (
+	// AgentVersionStatusDraft is an edited version that has not been published yet
+	AgentVersionStatusDraft = "draft"
+	// AgentVersionStatusPublished is the version currently serving live traffic
+	AgentVersionStatusPublished = "published"
+	// AgentVersionStatusRetired is a previously published version that has been replaced
+	AgentVersionStatusRetired = "retired"
+)
+
+// CustomAgentVersion is an immutable snapshot of a custom agent's editable fields
+// Every edit creates a new draft version; publishing promotes one version to live traffic
+type CustomAgentVersion struct {
+	// Unique identifier of the version record
+	ID string `json:"id" gorm:"type:varchar(36);primaryKey"`
+	// Agent this version belongs to
+	AgentID string `json:"agent_id" gorm:"type:varchar(36);index"`
+	// Tenant ID for isolation
+	TenantID uint64 `json:"tenant_id" gorm:"index"`
+	// Monotonic version number per agent, starting at 1
+	Version int `json:"version"`
+	// Version status: draft, published or retired
+	Status string `json:"status" gorm:"type:varchar(16)"`
+	// Snapshot of the agent's basic information
+	Name        string `json:"name" gorm:"type:varchar(255)"`
+	Description string `json:"description" gorm:"type:text"`
+	Avatar      string `json:"avatar" gorm:"type:varchar(64)"`
+	// Snapshot of the agent configuration
+	Config CustomAgentConfig `json:"config" gorm:"type:json"`
+	// User who created this version
+	CreatedBy string `json:"created_by" gorm:"type:varchar(36)"`
+	// When the version was created
+	CreatedAt time.Time `json:"created_at"`
+	// When the version was last published (nil if never published)
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+// TableName returns the table name for CustomAgentVersion
+func (CustomAgentVersion) TableName() string {
+	return "custom_agent_versions"
+}
+
+// NewCustomAgentVersion snapshots the editable fields of an agent into a new version
+func NewCustomAgentVersion(agent *CustomAgent, version int, status string) *CustomAgentVersion {
+	return &CustomAgentVersion{
+		AgentID:     agent.ID,
+		TenantID:    agent.TenantID,
+		Version:     version,
+		Status:      status,
+		Name:        agent.Name,
+		Description: agent.Description,
+		Avatar:      agent.Avatar,
+		Config:      agent.Config,
+		CreatedBy:   agent.CreatedBy,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// ApplyTo overwrites the agent's editable fields with this version's snapshot
+func (v *CustomAgentVersion) ApplyTo(agent *CustomAgent) {
+	agent.Name = v.Name
+	agent.Description = v.Description
+	agent.Avatar = v.Avatar
+	agent.Config = v.Config
+}
+
+// AgentFieldChange describes a single field difference between two agent versions
+type AgentFieldChange struct {
+	// Field path, e.g. "name" or "config.temperature"
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// CustomAgentVersionDiff lists the field changes between two agent versions
+type CustomAgentVersionDiff struct {
+	AgentID     string             `json:"agent_id"`
+	FromVersion int                `json:"from_version"`
+	ToVersion   int                `json:"to_version"`
+	Changes     []AgentFieldChange `json:"changes"`
+}
+
+// DiffAgentVersions compares two versions field by field, sorted by field path
+func DiffAgentVersions(from, to *CustomAgentVersion) *CustomAgentVersionDiff {
+	diff := &CustomAgentVersionDiff{
+		AgentID:     to.AgentID,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Changes:     []AgentFieldChange{},
+	}
+
+	basic := []struct {
+		field    string
+		from, to string
+	}{
+		{"name", from.Name, to.Name},
+		{"description", from.Description, to.Description},
+		{"avatar", from.Avatar, to.Avatar},
+	}
+	for _, b := range basic {
+		if b.from != b.to {
+			diff.Changes = append(diff.Changes, AgentFieldChange{Field: b.field, From: b.from, To: b.to})
+		}
+	}
+
+	fromConfig := configAsMap(from.Config)
+	toConfig := configAsMap(to.Config)
+	keys := make(map[string]struct{}, len(fromConfig)+len(toConfig))
+	for k := range fromConfig {
+		keys[k] = struct{}{}
+	}
+	for k := range toConfig {
+		keys[k] = struct{}{}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for k := range keys {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, k := range sortedKeys {
+		if !reflect.DeepEqual(fromConfig[k], toConfig[k]) {
+			diff.Changes = append(diff.Changes, AgentFieldChange{
+				Field: "config." + k,
+				From:  fromConfig[k],
+				To:    toConfig[k],
+			})
+		}
+	}
+	return diff
+}
+
+// configAsMap converts an agent config into a generic map keyed by JSON field name
+func configAsMap(config CustomAgentConfig) map[string]any {
+	result := make(map[string]any)
+	data, err := json.Marshal(config)
+	if err != nil {
+		return result
+	}
+	_ = json.Unmarshal(data, &result)
+	return result
+}
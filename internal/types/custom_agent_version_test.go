@@ -0,0 +1,33 @@
+package types
+
+import "testing"
+
+func TestDiffAgentVersions(t *testing.T) {
+	from := &CustomAgentVersion{
+		AgentID: "agent-1",
+		Version: 1,
+		Name:    "Support Bot",
+		Config:  CustomAgentConfig{Temperature: 0.7, SystemPrompt: "Be helpful"},
+	}
+	to := &CustomAgentVersion{
+		AgentID: "agent-1",
+		Version: 2,
+		Name:    "Support Bot",
+		Config:  CustomAgentConfig{Temperature: 0.2, SystemPrompt: "Be helpful"},
+	}
+
+	diff := DiffAgentVersions(from, to)
+	if diff.FromVersion != 1 || diff.ToVersion != 2 {
+		t.Fatalf("versions = %d -> %d, want 1 -> 2", diff.FromVersion, diff.ToVersion)
+	}
+	if len(diff.Changes) != 1 {
+		t.Fatalf("got %d changes, want 1: %+v", len(diff.Changes), diff.Changes)
+	}
+	if diff.Changes[0].Field != "config.temperature" {
+		t.Errorf("Field = %q, want config.temperature", diff.Changes[0].Field)
+	}
+
+	if same := DiffAgentVersions(from, from); len(same.Changes) != 0 {
+		t.Errorf("identical versions produced changes: %+v", same.Changes)
+	}
+}
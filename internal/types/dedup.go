@@ -0,0 +1,127 @@
+package types
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// dedupShingleSize is the number of characters per shingle when comparing chunk contents.
+// Characters rather than words keep the comparison meaningful for languages written without spaces.
+const dedupShingleSize = 5
+
+// contentShingles returns the set of character shingles of a text, ignoring case, punctuation and whitespace
+func contentShingles(content string) map[string]struct{} {
+	runes := make([]rune, 0, len(content))
+	for _, r := range strings.ToLower(content) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			runes = append(runes, r)
+		}
+	}
+	shingles := make(map[string]struct{})
+	if len(runes) == 0 {
+		return shingles
+	}
+	if len(runes) <= dedupShingleSize {
+		shingles[string(runes)] = struct{}{}
+		return shingles
+	}
+	for i := 0; i+dedupShingleSize <= len(runes); i++ {
+		shingles[string(runes[i:i+dedupShingleSize])] = struct{}{}
+	}
+	return shingles
+}
+
+// shingleSimilarity returns the Jaccard similarity of two shingle sets, 0 when both are empty
+func shingleSimilarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	shared := 0
+	for shingle := range a {
+		if _, ok := b[shingle]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// ContentSimilarity returns how similar two chunk contents are, from 0 (nothing shared) to 1 (identical
+// apart from case, punctuation and whitespace)
+func ContentSimilarity(a, b string) float64 {
+	return shingleSimilarity(contentShingles(a), contentShingles(b))
+}
+
+// CollapseNearDuplicates greedily groups results in descending score order. Each result is compared
+// with the representatives kept so far; it is collapsed into the first one from a different knowledge
+// item whose content similarity reaches threshold and listed among its alternates. The relative order
+// of kept results is preserved. It returns the kept results and the number of collapsed ones.
+func CollapseNearDuplicates(results []*SearchResult, threshold float64) ([]*SearchResult, int) {
+	if len(results) < 2 {
+		return results, 0
+	}
+	shingles := make([]map[string]struct{}, len(results))
+	for i, result := range results {
+		shingles[i] = contentShingles(result.Content)
+	}
+
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return results[order[a]].Score > results[order[b]].Score
+	})
+
+	kept := make([]int, 0, len(results))
+	dropped := make(map[int]bool)
+	for _, idx := range order {
+		candidate := results[idx]
+		for _, repIdx := range kept {
+			rep := results[repIdx]
+			if rep.KnowledgeID == candidate.KnowledgeID {
+				continue
+			}
+			similarity := shingleSimilarity(shingles[repIdx], shingles[idx])
+			if similarity < threshold {
+				continue
+			}
+			rep.Alternates = append(rep.Alternates, &ChunkAlternate{
+				ChunkID:           candidate.ID,
+				KnowledgeID:       candidate.KnowledgeID,
+				KnowledgeTitle:    candidate.KnowledgeTitle,
+				KnowledgeFilename: candidate.KnowledgeFilename,
+				Score:             candidate.Score,
+				Similarity:        similarity,
+			})
+			dropped[idx] = true
+			break
+		}
+		if !dropped[idx] {
+			kept = append(kept, idx)
+		}
+	}
+
+	if len(dropped) == 0 {
+		return results, 0
+	}
+	collapsed := make([]*SearchResult, 0, len(kept))
+	for i, result := range results {
+		if !dropped[i] {
+			collapsed = append(collapsed, result)
+		}
+	}
+	return collapsed, len(dropped)
+}
+
+// CountCollapsed returns the number of near-duplicates collapsed into the given results
+func CountCollapsed(results []*SearchResult) int {
+	count := 0
+	for _, result := range results {
+		count += len(result.Alternates)
+	}
+	return count
+}
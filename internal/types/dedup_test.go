@@ -0,0 +1,73 @@
+package types
+
+import "testing"
+
+func TestContentSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		min, max float64
+	}{
+		{name: "identical", a: "Refunds are issued within 30 days.", b: "Refunds are issued within 30 days.", min: 1, max: 1},
+		{name: "case, punctuation and whitespace", a: "Refunds are issued within 30 days.", b: "refunds  are issued\nwithin 30 days", min: 1, max: 1},
+		{name: "small edit", a: "Refunds are issued within 30 days of the purchase date.", b: "Refunds are issued within 30 days of the purchase.", min: 0.8, max: 0.99},
+		{name: "unrelated", a: "Refunds are issued within 30 days.", b: "The office is closed on public holidays.", min: 0, max: 0.1},
+		{name: "cjk", a: "退款将在三十天内处理完毕", b: "退款将在三十天内处理完毕。", min: 1, max: 1},
+		{name: "short texts", a: "yes", b: "yes", min: 1, max: 1},
+		{name: "empty", a: "", b: "anything", min: 0, max: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ContentSimilarity(tt.a, tt.b)
+			if got < tt.min || got > tt.max {
+				t.Errorf("ContentSimilarity = %.3f, want between %.2f and %.2f", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestCollapseNearDuplicates(t *testing.T) {
+	appendix := "All employees must complete the security training before accessing production systems."
+	newResults := func() []*SearchResult {
+		return []*SearchResult{
+			{ID: "a1", KnowledgeID: "a", Content: appendix, Score: 0.7},
+			{ID: "b1", KnowledgeID: "b", Content: appendix + " ", Score: 0.9},
+			{ID: "c1", KnowledgeID: "c", Content: "Expense reports are due by the fifth of each month.", Score: 0.8},
+			{ID: "b2", KnowledgeID: "b", Content: appendix, Score: 0.6},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		wantIDs   []string
+		collapsed int
+	}{
+		{name: "collapses across knowledge only", threshold: 0.8, wantIDs: []string{"b1", "c1", "b2"}, collapsed: 1},
+		{name: "threshold above similarity keeps all", threshold: 1.01, wantIDs: []string{"a1", "b1", "c1", "b2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := newResults()
+			kept, collapsed := CollapseNearDuplicates(results, tt.threshold)
+			if collapsed != tt.collapsed || CountCollapsed(kept) != tt.collapsed {
+				t.Errorf("collapsed = %d, counted %d, want %d", collapsed, CountCollapsed(kept), tt.collapsed)
+			}
+			if len(kept) != len(tt.wantIDs) {
+				t.Fatalf("kept %d results, want %d", len(kept), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if kept[i].ID != id {
+					t.Errorf("kept[%d] = %s, want %s", i, kept[i].ID, id)
+				}
+			}
+		})
+	}
+
+	// The highest-scored copy represents the group and lists the others as alternates
+	kept, _ := CollapseNearDuplicates(newResults(), 0.8)
+	if alternates := kept[0].Alternates; len(alternates) != 1 || alternates[0].ChunkID != "a1" ||
+		alternates[0].Similarity != 1 {
+		t.Errorf("unexpected alternates of %s: %+v", kept[0].ID, alternates)
+	}
+}
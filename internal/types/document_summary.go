@@ -0,0 +1,108 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Document summary defaults
+const (
+	// DefaultSummaryExpandDocuments is how many documents matched by their summary are searched for chunks
+	DefaultSummaryExpandDocuments = 3
+	// MaxSummaryExpandDocuments caps expand_documents
+	MaxSummaryExpandDocuments = 20
+	// summaryCandidateFactor widens the summary search, since summaries compete with the chunks they summarize
+	summaryCandidateFactor = 10
+)
+
+// DocumentSummaryConfig configures the document-level summaries of a knowledge base. A summary is generated
+// for each document after ingestion, embedded and indexed as a summary chunk, and regenerated whenever the
+// document is chunked again. With summary-first retrieval, a search matches the summaries first and then
+// searches the chunks of the best matching documents only (parent-document retrieval).
+type DocumentSummaryConfig struct {
+	// Disabled skips summary generation on ingestion; summaries are generated by default
+	Disabled bool `yaml:"disabled"         json:"disabled"`
+	// SummaryFirst makes searches go through the document summaries by default
+	SummaryFirst bool `yaml:"summary_first"    json:"summary_first"`
+	// ExpandDocuments is how many documents matched by their summary are searched for chunks (0 = default)
+	ExpandDocuments int `yaml:"expand_documents" json:"expand_documents,omitempty"`
+}
+
+// Validate checks the expansion range and that summary-first retrieval has summaries to search
+func (c *DocumentSummaryConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.ExpandDocuments < 0 || c.ExpandDocuments > MaxSummaryExpandDocuments {
+		return fmt.Errorf("expand_documents must be between 0 and %d", MaxSummaryExpandDocuments)
+	}
+	if c.Disabled && c.SummaryFirst {
+		return fmt.Errorf("summary_first requires document summaries to be generated")
+	}
+	return nil
+}
+
+// GenerationEnabled reports whether summaries are generated on ingestion
+func (c *DocumentSummaryConfig) GenerationEnabled() bool {
+	return c == nil || !c.Disabled
+}
+
+// UseSummaryFirst reports whether a search goes through the document summaries, override taking
+// precedence over the knowledge base default when set
+func (c *DocumentSummaryConfig) UseSummaryFirst(override *bool) bool {
+	if !c.GenerationEnabled() {
+		return false
+	}
+	if override != nil {
+		return *override
+	}
+	return c != nil && c.SummaryFirst
+}
+
+// ExpandCount returns the effective number of documents searched for chunks
+func (c *DocumentSummaryConfig) ExpandCount() int {
+	if c == nil || c.ExpandDocuments <= 0 {
+		return DefaultSummaryExpandDocuments
+	}
+	return c.ExpandDocuments
+}
+
+// SummaryCandidateCount returns how many results the summary search of a summary-first search retrieves
+func (c *DocumentSummaryConfig) SummaryCandidateCount() int {
+	return c.ExpandCount() * summaryCandidateFactor
+}
+
+// Value implements driver.Valuer
+func (c DocumentSummaryConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *DocumentSummaryConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// SummaryDocumentIDs returns the knowledge IDs of the summary chunks among results, best first, up to limit
+func SummaryDocumentIDs(results []*SearchResult, limit int) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if len(ids) >= limit {
+			break
+		}
+		if result.ChunkType != ChunkTypeSummary || seen[result.KnowledgeID] {
+			continue
+		}
+		seen[result.KnowledgeID] = true
+		ids = append(ids, result.KnowledgeID)
+	}
+	return ids
+}
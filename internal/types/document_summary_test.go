@@ -0,0 +1,59 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDocumentSummaryConfig(t *testing.T) {
+	enabled, disabled := true, false
+	var none *DocumentSummaryConfig
+	if !none.GenerationEnabled() || none.UseSummaryFirst(nil) || !none.UseSummaryFirst(&enabled) {
+		t.Error("without a config, summaries are generated and summary-first is off unless requested")
+	}
+	if none.ExpandCount() != DefaultSummaryExpandDocuments {
+		t.Errorf("ExpandCount() = %d, want the default", none.ExpandCount())
+	}
+
+	cfg := &DocumentSummaryConfig{SummaryFirst: true, ExpandDocuments: 5}
+	if !cfg.UseSummaryFirst(nil) || cfg.UseSummaryFirst(&disabled) {
+		t.Error("the request override must take precedence over the knowledge base default")
+	}
+	if cfg.ExpandCount() != 5 {
+		t.Errorf("ExpandCount() = %d, want 5", cfg.ExpandCount())
+	}
+
+	off := &DocumentSummaryConfig{Disabled: true}
+	if off.GenerationEnabled() || off.UseSummaryFirst(&enabled) {
+		t.Error("summary-first needs generated summaries")
+	}
+
+	for _, invalid := range []*DocumentSummaryConfig{
+		{ExpandDocuments: -1},
+		{ExpandDocuments: MaxSummaryExpandDocuments + 1},
+		{Disabled: true, SummaryFirst: true},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want an error", invalid)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestSummaryDocumentIDs(t *testing.T) {
+	results := []*SearchResult{
+		{KnowledgeID: "k1", ChunkType: ChunkTypeText},
+		{KnowledgeID: "k2", ChunkType: ChunkTypeSummary},
+		{KnowledgeID: "k2", ChunkType: ChunkTypeSummary},
+		{KnowledgeID: "k3", ChunkType: ChunkTypeSummary},
+		{KnowledgeID: "k4", ChunkType: ChunkTypeSummary},
+	}
+	if got, want := SummaryDocumentIDs(results, 2), []string{"k2", "k3"}; !slices.Equal(got, want) {
+		t.Errorf("SummaryDocumentIDs() = %v, want %v", got, want)
+	}
+	if got := SummaryDocumentIDs(results[:1], 3); len(got) != 0 {
+		t.Errorf("SummaryDocumentIDs() = %v, want none without summaries", got)
+	}
+}
@@ -23,6 +23,7 @@ const (
 	MatchTypeWebSearch    // Web search match type
 	MatchTypeDirectLoad   // Direct load match type
 	MatchTypeDataAnalysis // Data analysis match type
+	MatchTypePinned       // Chunk injected by a pinned source rule
 )
 
 // IndexInfo contains information about indexed content
@@ -1,6 +1,11 @@
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+)
 
 // StorageQuotaExceededError represents the storage quota exceeded error
 type StorageQuotaExceededError struct {
@@ -12,6 +17,16 @@ func (e *StorageQuotaExceededError) Error() string {
 	return e.Message
 }
 
+// AppError reports the error as quota.exceeded
+func (e *StorageQuotaExceededError) AppError() *werrors.AppError {
+	return &werrors.AppError{
+		Code:     werrors.ErrForbidden,
+		Reason:   werrors.CodeQuotaExceeded,
+		Message:  e.Message,
+		HTTPCode: http.StatusForbidden,
+	}
+}
+
 // NewStorageQuotaExceededError creates a storage quota exceeded error
 func NewStorageQuotaExceededError() *StorageQuotaExceededError {
 	return &StorageQuotaExceededError{
@@ -29,6 +44,11 @@ func (e *DuplicateKnowledgeError) Error() string {
 	return e.Message
 }
 
+// AppError reports the error as knowledge.duplicate
+func (e *DuplicateKnowledgeError) AppError() *werrors.AppError {
+	return werrors.NewConflictError(e.Message).WithCode(werrors.CodeKnowledgeDuplicate)
+}
+
 // NewDuplicateFileError creates a duplicate file error
 func NewDuplicateFileError(knowledge *Knowledge) *DuplicateKnowledgeError {
 	return &DuplicateKnowledgeError{
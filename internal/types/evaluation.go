@@ -18,8 +18,33 @@ const (
 	EvaluationStatueRunning                         // Task is in progress
 	EvaluationStatueSuccess                         // Task completed successfully
 	EvaluationStatueFailed                          // Task failed
+	EvaluationStatuePaused                          // Task is paused, no new QA pairs are started
 )
 
+// EvaluationLimits throttles the model calls of an evaluation task
+type EvaluationLimits struct {
+	MaxConcurrency    int `json:"max_concurrency,omitempty"`     // QA pairs evaluated in parallel (0 = default)
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"` // QA pairs started per minute (0 = unlimited)
+}
+
+// Clamp applies the server-wide limits to l: unset values take the defaults, and values above a
+// configured maximum are lowered to it (a maximum of 0 means no maximum)
+func (l EvaluationLimits) Clamp(maxLimits EvaluationLimits, defaultConcurrency int) EvaluationLimits {
+	clamp := func(v, limit, def int) int {
+		if v <= 0 {
+			v = def
+		}
+		if limit > 0 && (v <= 0 || v > limit) {
+			v = limit
+		}
+		return v
+	}
+	return EvaluationLimits{
+		MaxConcurrency:    clamp(l.MaxConcurrency, maxLimits.MaxConcurrency, defaultConcurrency),
+		RequestsPerMinute: clamp(l.RequestsPerMinute, maxLimits.RequestsPerMinute, 0),
+	}
+}
+
 // EvaluationTask contains information about an evaluation task
 type EvaluationTask struct {
 	ID        string `json:"id"`         // Unique task ID
@@ -32,6 +57,10 @@ type EvaluationTask struct {
 
 	Total    int `json:"total,omitempty"`    // Total items to evaluate
 	Finished int `json:"finished,omitempty"` // Completed items count
+
+	Limits           EvaluationLimits `json:"limits"`                      // Effective concurrency and rate limits
+	Throttled        int              `json:"throttled,omitempty"`         // QA pairs delayed by the rate limit
+	ThrottledSeconds float64          `json:"throttled_seconds,omitempty"` // Total time QA pairs waited for the rate limit
 }
 
 // EvaluationDetail contains detailed evaluation information
@@ -41,6 +70,32 @@ type EvaluationDetail struct {
 	Metric *MetricResult   `json:"metric,omitempty"` // Evaluation metrics
 }
 
+// Evaluation stream event types
+const (
+	EvaluationEventCase    = "case"    // A QA pair finished evaluating
+	EvaluationEventSummary = "summary" // Terminal event with the final task state
+	EvaluationEventPaused  = "paused"  // The task was paused
+	EvaluationEventResumed = "resumed" // The task was resumed
+)
+
+// EvaluationCaseResult contains the outcome of a single evaluated QA pair
+type EvaluationCaseResult struct {
+	Index    int           `json:"index"`    // Position of the QA pair in the dataset
+	Question string        `json:"question"` // Question asked
+	Expected string        `json:"expected"` // Reference answer from the dataset
+	Answer   string        `json:"answer"`   // Generated answer
+	Metric   *MetricResult `json:"metric"`   // Metrics of this QA pair
+}
+
+// EvaluationEvent is an event of an evaluation result stream
+type EvaluationEvent struct {
+	Seq    int                   `json:"seq"`              // 1-based delivery order, used to resume the stream
+	Type   string                `json:"type"`             // Event type: case, paused, resumed or summary
+	Case   *EvaluationCaseResult `json:"case,omitempty"`   // Finished QA pair (case events only)
+	Task   EvaluationTask        `json:"task"`             // Task progress when the event was emitted
+	Metric *MetricResult         `json:"metric,omitempty"` // Aggregate metrics over the finished QA pairs
+}
+
 // String returns JSON representation of EvaluationTask
 func (e *EvaluationTask) String() string {
 	b, _ := json.Marshal(e)
@@ -0,0 +1,27 @@
+package types
+
+import "testing"
+
+func TestEvaluationLimitsClamp(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested EvaluationLimits
+		maxLimits EvaluationLimits
+		want      EvaluationLimits
+	}{
+		{"defaults without maximums", EvaluationLimits{}, EvaluationLimits{}, EvaluationLimits{MaxConcurrency: 7}},
+		{"maximums are the defaults", EvaluationLimits{},
+			EvaluationLimits{MaxConcurrency: 4, RequestsPerMinute: 60}, EvaluationLimits{MaxConcurrency: 4, RequestsPerMinute: 60}},
+		{"lower requested limits are kept", EvaluationLimits{MaxConcurrency: 2, RequestsPerMinute: 10},
+			EvaluationLimits{MaxConcurrency: 4, RequestsPerMinute: 60}, EvaluationLimits{MaxConcurrency: 2, RequestsPerMinute: 10}},
+		{"higher requested limits are capped", EvaluationLimits{MaxConcurrency: 32, RequestsPerMinute: 600},
+			EvaluationLimits{MaxConcurrency: 4, RequestsPerMinute: 60}, EvaluationLimits{MaxConcurrency: 4, RequestsPerMinute: 60}},
+		{"requested limits without maximums", EvaluationLimits{MaxConcurrency: 32, RequestsPerMinute: 600},
+			EvaluationLimits{}, EvaluationLimits{MaxConcurrency: 32, RequestsPerMinute: 600}},
+	}
+	for _, tt := range tests {
+		if got := tt.requested.Clamp(tt.maxLimits, 7); got != tt.want {
+			t.Errorf("%s: Clamp() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
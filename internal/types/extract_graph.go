@@ -1,5 +1,10 @@
 package types
 
+import (
+	"fmt"
+	"time"
+)
+
 const (
 	TypeChunkExtract        = "chunk:extract"
 	TypeDocumentProcess     = "document:process"      // Document processing task
@@ -11,6 +16,12 @@ const (
 	TypeKBDelete            = "kb:delete"             // Knowledge base deletion task
 	TypeKnowledgeListDelete = "knowledge:list_delete" // Batch knowledge deletion task
 	TypeDataTableSummary    = "datatable:summary"     // Data table summary task
+	TypeKnowledgeReprocess  = "knowledge:reprocess"   // Batch reprocessing of failed knowledge
+	TypeKBMerge             = "kb:merge"              // Knowledge base merge task
+	TypeFAQSimilarQuestion  = "faq:similar_question"  // FAQ similar question generation task
+	TypeTenantExport        = "tenant:export"         // Tenant data export task
+	TypeTenantPurge         = "tenant:purge"          // Tenant data purge task
+	TypeKBImport            = "kb:import"             // Knowledge base archive import task
 )
 
 // ExtractChunkPayload represents the extract chunk task payload
@@ -48,6 +59,8 @@ type FAQImportPayload struct {
 	Mode        string            `json:"mode"`
 	DryRun      bool              `json:"dry_run"`     // Dry run mode only validates, does not import
 	EnqueuedAt  int64             `json:"enqueued_at"` // Task enqueue timestamp, used to distinguish different submissions with same TaskID
+	// Duplicate handling against existing entries (append mode only)
+	FAQDuplicateOptions
 }
 
 // QuestionGenerationPayload represents the question generation task payload
@@ -73,6 +86,17 @@ type KBClonePayload struct {
 	TargetID string `json:"target_id"`
 }
 
+// KBMergePayload represents the knowledge base merge task payload
+type KBMergePayload struct {
+	TenantID     uint64 `json:"tenant_id"`
+	TaskID       string `json:"task_id"`
+	SourceID     string `json:"source_id"`
+	TargetID     string `json:"target_id"`
+	DeleteSource bool   `json:"delete_source"`
+	TagConflict  string `json:"tag_conflict"`
+	RequestID    string `json:"request_id"`
+}
+
 // IndexDeletePayload represents the index delete task payload
 type IndexDeletePayload struct {
 	TenantID         uint64                  `json:"tenant_id"`
@@ -88,6 +112,8 @@ type KBDeletePayload struct {
 	TenantID         uint64                  `json:"tenant_id"`
 	KnowledgeBaseID  string                  `json:"knowledge_base_id"`
 	EffectiveEngines []RetrieverEngineParams `json:"effective_engines"`
+	// VectorSpaceConfig of the deleted knowledge base, whose additional spaces are cleaned up as well
+	VectorSpaceConfig *VectorSpaceConfig `json:"vector_space_config,omitempty"`
 }
 
 // KnowledgeListDeletePayload represents the batch knowledge delete task payload
@@ -96,6 +122,90 @@ type KnowledgeListDeletePayload struct {
 	KnowledgeIDs []string `json:"knowledge_ids"`
 }
 
+// KnowledgeReprocessPayload represents the batch reprocess task payload
+type KnowledgeReprocessPayload struct {
+	TenantID        uint64 `json:"tenant_id"`
+	TaskID          string `json:"task_id"`
+	KnowledgeBaseID string `json:"knowledge_base_id"`
+	RequestID       string `json:"request_id"`
+}
+
+// KnowledgeFailureFilter narrows which failed knowledge is selected for reprocessing
+type KnowledgeFailureFilter struct {
+	// ErrorContains matches knowledge whose error message contains this text (case-insensitive)
+	ErrorContains string `json:"error_contains"`
+	// FailedAfter matches knowledge that last changed at or after this time
+	FailedAfter *time.Time `json:"failed_after"`
+	// FailedBefore matches knowledge that last changed before this time
+	FailedBefore *time.Time `json:"failed_before"`
+}
+
+// KnowledgeReprocessStatus represents the status of a batch reprocess task
+type KnowledgeReprocessStatus string
+
+const (
+	KnowledgeReprocessStatusPending    KnowledgeReprocessStatus = "pending"
+	KnowledgeReprocessStatusProcessing KnowledgeReprocessStatus = "processing"
+	KnowledgeReprocessStatusCompleted  KnowledgeReprocessStatus = "completed"
+)
+
+// Per-item results of a batch reprocess task
+const (
+	ReprocessItemPending    = "pending"
+	ReprocessItemInProgress = "in_progress" // Handed off, its processing continues asynchronously
+	ReprocessItemSucceeded  = "succeeded"
+	ReprocessItemFailed     = "failed"
+	ReprocessItemSkipped    = "skipped"
+)
+
+// ReprocessItemResult is the outcome of reprocessing a single knowledge item
+type ReprocessItemResult struct {
+	KnowledgeID   string `json:"knowledge_id"`
+	Title         string `json:"title"`
+	Status        string `json:"status"`
+	PreviousError string `json:"previous_error,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// KnowledgeReprocessProgress represents the progress of a batch reprocess task
+type KnowledgeReprocessProgress struct {
+	TaskID          string                   `json:"task_id"`
+	KnowledgeBaseID string                   `json:"knowledge_base_id"`
+	Status          KnowledgeReprocessStatus `json:"status"`
+	Total           int                      `json:"total"`
+	Processed       int                      `json:"processed"`
+	Succeeded       int                      `json:"succeeded"`
+	Failed          int                      `json:"failed"`
+	Skipped         int                      `json:"skipped"`
+	InProgress      int                      `json:"in_progress"`
+	Items           []*ReprocessItemResult   `json:"items"`
+	Message         string                   `json:"message"`
+	CreatedAt       int64                    `json:"created_at"`
+	UpdatedAt       int64                    `json:"updated_at"`
+}
+
+// CountItem adds a finished item to the counter of its status
+func (p *KnowledgeReprocessProgress) CountItem(status string) {
+	switch status {
+	case ReprocessItemSucceeded:
+		p.Succeeded++
+	case ReprocessItemFailed:
+		p.Failed++
+	default:
+		p.Skipped++
+	}
+}
+
+// Summary describes the outcome of the task
+func (p *KnowledgeReprocessProgress) Summary() string {
+	summary := fmt.Sprintf("Reprocessed %d knowledge: %d succeeded, %d failed, %d skipped",
+		p.Total, p.Succeeded, p.Failed, p.Skipped)
+	if p.InProgress > 0 {
+		summary += fmt.Sprintf(", %d still processing", p.InProgress)
+	}
+	return summary
+}
+
 // KBCloneTaskStatus represents the status of a knowledge base clone task
 type KBCloneTaskStatus string
 
@@ -119,6 +229,49 @@ type KBCloneProgress struct {
 	Error     string            `json:"error"`      // Error message
 	CreatedAt int64             `json:"created_at"` // Task creation time
 	UpdatedAt int64             `json:"updated_at"` // Last update time
+	// Webhook is the last delivery of the completion webhook, set when the progress is queried
+	Webhook *TaskWebhookDelivery `json:"webhook,omitempty"`
+}
+
+// Handling of source tags whose name already exists in the target of a merge
+const (
+	// KBMergeTagConflictMerge files the content under the existing target tag (default)
+	KBMergeTagConflictMerge = "merge"
+	// KBMergeTagConflictRename creates a separate tag named after the source knowledge base
+	KBMergeTagConflictRename = "rename"
+)
+
+// KBMergeRequest is the request to merge one knowledge base into another
+type KBMergeRequest struct {
+	SourceID string `json:"source_id"     binding:"required"`
+	TargetID string `json:"target_id"     binding:"required"`
+	// DeleteSource deletes the source knowledge base once everything was merged without failures
+	DeleteSource bool `json:"delete_source"`
+	// TagConflict is merge (default) or rename
+	TagConflict string `json:"tag_conflict"`
+}
+
+// KBMergeProgress represents the progress of a knowledge base merge task
+type KBMergeProgress struct {
+	TaskID        string            `json:"task_id"`
+	SourceID      string            `json:"source_id"`
+	TargetID      string            `json:"target_id"`
+	Status        KBCloneTaskStatus `json:"status"`
+	Progress      int               `json:"progress"`       // 0-100
+	Total         int               `json:"total"`          // Number of knowledge items (FAQ entries for FAQ knowledge bases) in the source
+	Processed     int               `json:"processed"`      // Number processed
+	Merged        int               `json:"merged"`         // Number moved into the target
+	Skipped       int               `json:"skipped"`        // Duplicates already in the target or items not ready to be merged
+	Failed        int               `json:"failed"`         // Number that could not be merged
+	TagsCreated   int               `json:"tags_created"`   // Source tags created in the target
+	TagsMerged    int               `json:"tags_merged"`    // Source tags merged into a target tag of the same name
+	TagsRenamed   int               `json:"tags_renamed"`   // Source tags created under a new name because of a collision
+	Reembedded    bool              `json:"reembedded"`     // Whether content was re-embedded with the target embedding model
+	SourceDeleted bool              `json:"source_deleted"` // Whether the source knowledge base was deleted
+	Message       string            `json:"message"`        // Status message
+	Error         string            `json:"error"`          // Error message
+	CreatedAt     int64             `json:"created_at"`     // Task creation time
+	UpdatedAt     int64             `json:"updated_at"`     // Last update time
 }
 
 // ChunkContext represents chunk content with surrounding context
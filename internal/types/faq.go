@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"sort"
 	"strings"
 	"time"
@@ -32,6 +33,8 @@ type DocumentChunkMetadata struct {
 	// GeneratedQuestions stores AI-generated related questions for this chunk
 	// These questions are independently indexed to improve recall rate
 	GeneratedQuestions []GeneratedQuestion `json:"generated_questions,omitempty"`
+	// SectionPath is the heading path of the section the chunk belongs to, from the top-level heading
+	SectionPath []string `json:"section_path,omitempty"`
 }
 
 // GetQuestionStrings returns a list of question content strings (for backward compatibility)
@@ -58,6 +61,18 @@ func (c *Chunk) DocumentMetadata() (*DocumentChunkMetadata, error) {
 	return &meta, nil
 }
 
+// SectionPath returns the heading path recorded for a document chunk, nil when there is none
+func (c *Chunk) SectionPath() []string {
+	if c == nil || (c.ChunkType != ChunkTypeText && c.ChunkType != ChunkTypeParent) {
+		return nil
+	}
+	meta, err := c.DocumentMetadata()
+	if err != nil || meta == nil {
+		return nil
+	}
+	return meta.SectionPath
+}
+
 // SetDocumentMetadata sets document metadata for Chunk
 func (c *Chunk) SetDocumentMetadata(meta *DocumentChunkMetadata) error {
 	if c == nil {
@@ -226,6 +241,84 @@ type FAQBatchUpsertPayload struct {
 	KnowledgeID string            `json:"knowledge_id"`
 	TaskID      string            `json:"task_id"` // Optional, auto-generates UUID if not provided
 	DryRun      bool              `json:"dry_run"` // Only validate, do not actually import
+	// WebhookURL is notified when the import finishes instead of the tenant webhook (optional)
+	WebhookURL string `json:"webhook_url"`
+	// Duplicate handling against existing entries (append mode only)
+	FAQDuplicateOptions
+}
+
+// FAQDuplicatePolicy controls how a new FAQ entry duplicating an existing entry is handled
+type FAQDuplicatePolicy string
+
+const (
+	// FAQDuplicatePolicyError rejects the new entry (default)
+	FAQDuplicatePolicyError FAQDuplicatePolicy = "error"
+	// FAQDuplicatePolicyMerge folds the questions of the new entry into the similar questions of the existing one
+	FAQDuplicatePolicyMerge FAQDuplicatePolicy = "merge_into_existing"
+	// FAQDuplicatePolicyAllow creates the new entry anyway
+	FAQDuplicatePolicyAllow FAQDuplicatePolicy = "allow"
+)
+
+// FAQ duplicate match types
+const (
+	// FAQDuplicateMatchExact means a question is identical to a question of the existing entry
+	FAQDuplicateMatchExact = "exact"
+	// FAQDuplicateMatchNear means the standard question is close to the existing entry by embedding similarity
+	FAQDuplicateMatchNear = "near"
+)
+
+// FAQ duplicate decisions
+const (
+	FAQDuplicateDecisionCreated  = "created"
+	FAQDuplicateDecisionMerged   = "merged"
+	FAQDuplicateDecisionRejected = "rejected"
+)
+
+// FAQDuplicateOptions configures duplicate detection when creating FAQ entries
+type FAQDuplicateOptions struct {
+	// DuplicatePolicy is applied when a duplicate is found, defaults to "error"
+	DuplicatePolicy FAQDuplicatePolicy `json:"duplicate_policy,omitempty"`
+	// DuplicateThreshold enables near-duplicate detection: an existing entry whose vector similarity to the
+	// standard question reaches the threshold counts as a duplicate. 0 only detects exact duplicates.
+	DuplicateThreshold float64 `json:"duplicate_threshold,omitempty"`
+}
+
+// Validate checks the policy and threshold
+func (o FAQDuplicateOptions) Validate() error {
+	switch o.DuplicatePolicy {
+	case "", FAQDuplicatePolicyError, FAQDuplicatePolicyMerge, FAQDuplicatePolicyAllow:
+	default:
+		return fmt.Errorf("duplicate_policy must be one of %q, %q or %q",
+			FAQDuplicatePolicyError, FAQDuplicatePolicyMerge, FAQDuplicatePolicyAllow)
+	}
+	if o.DuplicateThreshold < 0 || o.DuplicateThreshold > 1 {
+		return fmt.Errorf("duplicate_threshold must be between 0 and 1")
+	}
+	return nil
+}
+
+// Policy returns the effective duplicate policy
+func (o FAQDuplicateOptions) Policy() FAQDuplicatePolicy {
+	if o.DuplicatePolicy == "" {
+		return FAQDuplicatePolicyError
+	}
+	return o.DuplicatePolicy
+}
+
+// FAQDuplicateDecision reports how a new entry that duplicates an existing entry was handled
+type FAQDuplicateDecision struct {
+	Index            int    `json:"index"`             // Entry index in batch (0-based), 0 for single entries
+	StandardQuestion string `json:"standard_question"` // Standard question of the new entry
+	// Decision is "created", "merged" or "rejected"
+	Decision string `json:"decision"`
+	// MatchType is "exact" or "near"
+	MatchType string `json:"match_type,omitempty"`
+	// DuplicateOf is the ID (seq_id) of the existing entry
+	DuplicateOf int64 `json:"duplicate_of,omitempty"`
+	// DuplicateChunkID is the chunk ID of the existing entry
+	DuplicateChunkID string `json:"duplicate_chunk_id,omitempty"`
+	// Score is the vector similarity of near duplicates
+	Score float64 `json:"score,omitempty"`
 }
 
 // FAQFailedEntry represents an entry that failed to import/validate
@@ -269,6 +362,54 @@ type FAQSearchRequest struct {
 	OnlyRecommended      bool    `json:"only_recommended"`        // Whether to return only recommended entries
 }
 
+// FAQExportFormat is the file format of an FAQ export
+type FAQExportFormat string
+
+const (
+	// FAQExportFormatCSV exports the import template columns, list fields joined with ## (default)
+	FAQExportFormatCSV FAQExportFormat = "csv"
+	// FAQExportFormatJSONL exports one FAQ entry object per line
+	FAQExportFormatJSONL FAQExportFormat = "jsonl"
+)
+
+// FAQExportRequest selects the format and the entries of an FAQ export
+type FAQExportRequest struct {
+	Format FAQExportFormat `form:"format"`
+	// TagID limits the export to a tag (seq_id)
+	TagID int64 `form:"tag_id"`
+	// Enabled limits the export to enabled or disabled entries
+	Enabled *bool `form:"enabled"`
+}
+
+// Validate checks the format and tag
+func (r *FAQExportRequest) Validate() error {
+	switch r.Format {
+	case "", FAQExportFormatCSV, FAQExportFormatJSONL:
+	default:
+		return fmt.Errorf("format must be %q or %q", FAQExportFormatCSV, FAQExportFormatJSONL)
+	}
+	if r.TagID < 0 {
+		return fmt.Errorf("tag_id must be positive")
+	}
+	return nil
+}
+
+// EffectiveFormat returns the format, CSV by default
+func (r *FAQExportRequest) EffectiveFormat() FAQExportFormat {
+	if r.Format == "" {
+		return FAQExportFormatCSV
+	}
+	return r.Format
+}
+
+// FAQExportFilter filters the chunks listed for an FAQ export
+type FAQExportFilter struct {
+	// TagID is the tag (UUID) of the chunks, empty for any tag
+	TagID string
+	// Enabled is the enabled status of the chunks, nil for any status
+	Enabled *bool
+}
+
 // UntaggedTagName is the default tag name for entries without a tag
 const UntaggedTagName = "Untagged"
 
@@ -330,11 +471,17 @@ type FAQImportProgress struct {
 	UpdatedAt         int64               `json:"updated_at"`                    // Last update timestamp
 	DryRun            bool                `json:"dry_run,omitempty"`             // Whether it's dry run mode
 
+	// DuplicateDecisions reports the entries that duplicate existing entries and how they were handled
+	DuplicateDecisions []FAQDuplicateDecision `json:"duplicate_decisions,omitempty"`
+
 	// Result fields (populated when Status == "completed")
 	ImportMode     string    `json:"import_mode,omitempty"`     // Import mode: append or replace
 	ImportedAt     time.Time `json:"imported_at,omitempty"`     // Import completion time
 	DisplayStatus  string    `json:"display_status,omitempty"`  // Display status: open or close
 	ProcessingTime int64     `json:"processing_time,omitempty"` // Processing time (milliseconds)
+
+	// Webhook is the last delivery of the completion webhook, set when the progress is queried
+	Webhook *TaskWebhookDelivery `json:"webhook,omitempty"`
 }
 
 // FAQImportMetadata stores FAQ import task information in Knowledge.Metadata
@@ -0,0 +1,105 @@
+package types
+
+import "fmt"
+
+// Similar question generation limits
+const (
+	// DefaultFAQSimilarQuestionCount is the number of similar questions generated per entry by default
+	DefaultFAQSimilarQuestionCount = 3
+	// MaxFAQSimilarQuestionCount is the maximum number of similar questions generated per entry
+	MaxFAQSimilarQuestionCount = 10
+	// MaxFAQSimilarGenerationEntries is the maximum number of entries selected by ID in one request
+	MaxFAQSimilarGenerationEntries = 1000
+)
+
+// FAQSimilarGenerationRequest selects the FAQ entries to generate similar questions for
+type FAQSimilarGenerationRequest struct {
+	// EntryIDs are the seq IDs of the selected entries; empty selects every entry of the knowledge base
+	EntryIDs []int64 `json:"entry_ids"`
+	// Count is the number of similar questions generated per entry (0 = default 3)
+	Count int `json:"count"`
+	// Apply adds the generated questions to the entries right away instead of returning them for preview
+	Apply bool `json:"apply"`
+}
+
+// Validate checks the entry selection and the question count
+func (r *FAQSimilarGenerationRequest) Validate() error {
+	if r.Count < 0 || r.Count > MaxFAQSimilarQuestionCount {
+		return fmt.Errorf("count must be between 1 and %d", MaxFAQSimilarQuestionCount)
+	}
+	if len(r.EntryIDs) > MaxFAQSimilarGenerationEntries {
+		return fmt.Errorf("at most %d entry_ids can be selected", MaxFAQSimilarGenerationEntries)
+	}
+	for _, id := range r.EntryIDs {
+		if id <= 0 {
+			return fmt.Errorf("invalid entry id %d", id)
+		}
+	}
+	return nil
+}
+
+// EffectiveCount returns the number of similar questions to generate per entry
+func (r *FAQSimilarGenerationRequest) EffectiveCount() int {
+	if r.Count <= 0 {
+		return DefaultFAQSimilarQuestionCount
+	}
+	return r.Count
+}
+
+// FAQSimilarGenerationPayload represents the similar question generation task payload
+type FAQSimilarGenerationPayload struct {
+	TenantID  uint64 `json:"tenant_id"`
+	TaskID    string `json:"task_id"`
+	KBID      string `json:"kb_id"`
+	RequestID string `json:"request_id"`
+}
+
+// FAQSimilarGenerationStatus represents the status of a similar question generation task
+type FAQSimilarGenerationStatus string
+
+const (
+	FAQSimilarGenerationPending    FAQSimilarGenerationStatus = "pending"
+	FAQSimilarGenerationProcessing FAQSimilarGenerationStatus = "processing"
+	FAQSimilarGenerationCompleted  FAQSimilarGenerationStatus = "completed"
+	FAQSimilarGenerationFailed     FAQSimilarGenerationStatus = "failed"
+)
+
+// FAQSimilarSuggestion holds the similar questions generated for one FAQ entry
+type FAQSimilarSuggestion struct {
+	EntryID          int64    `json:"entry_id"`
+	StandardQuestion string   `json:"standard_question,omitempty"`
+	Questions        []string `json:"questions"`
+	Applied          bool     `json:"applied"`
+	Error            string   `json:"error,omitempty"`
+}
+
+// FAQSimilarGenerationProgress represents the progress of a similar question generation task.
+// Suggestions are filled in as entries are processed.
+type FAQSimilarGenerationProgress struct {
+	TaskID      string                     `json:"task_id"`
+	KBID        string                     `json:"kb_id"`
+	Status      FAQSimilarGenerationStatus `json:"status"`
+	Count       int                        `json:"count"`
+	Apply       bool                       `json:"apply"`
+	Progress    int                        `json:"progress"` // 0-100 percentage
+	Total       int                        `json:"total"`
+	Processed   int                        `json:"processed"`
+	Generated   int                        `json:"generated"` // Number of new similar questions generated
+	Failed      int                        `json:"failed"`
+	Suggestions []*FAQSimilarSuggestion    `json:"suggestions"`
+	Message     string                     `json:"message"`
+	CreatedAt   int64                      `json:"created_at"`
+	UpdatedAt   int64                      `json:"updated_at"`
+}
+
+// FAQSimilarApplyRequest selects the previewed suggestions to apply
+type FAQSimilarApplyRequest struct {
+	// Entries are the suggestions to apply, optionally with edited questions; empty applies every suggestion
+	Entries []FAQSimilarApplyEntry `json:"entries"`
+}
+
+// FAQSimilarApplyEntry selects the suggestion of one entry. Questions replace the generated ones when set.
+type FAQSimilarApplyEntry struct {
+	EntryID   int64    `json:"entry_id"`
+	Questions []string `json:"questions"`
+}
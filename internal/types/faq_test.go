@@ -0,0 +1,57 @@
+package types
+
+import "testing"
+
+func TestFAQDuplicateOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    FAQDuplicateOptions
+		wantPolicy FAQDuplicatePolicy
+		wantErr    bool
+	}{
+		{name: "defaults to error", wantPolicy: FAQDuplicatePolicyError},
+		{
+			name:       "merge with threshold",
+			options:    FAQDuplicateOptions{DuplicatePolicy: FAQDuplicatePolicyMerge, DuplicateThreshold: 0.9},
+			wantPolicy: FAQDuplicatePolicyMerge,
+		},
+		{name: "allow", options: FAQDuplicateOptions{DuplicatePolicy: FAQDuplicatePolicyAllow}, wantPolicy: FAQDuplicatePolicyAllow},
+		{name: "unknown policy", options: FAQDuplicateOptions{DuplicatePolicy: "overwrite"}, wantPolicy: "overwrite", wantErr: true},
+		{name: "negative threshold", options: FAQDuplicateOptions{DuplicateThreshold: -0.1}, wantPolicy: FAQDuplicatePolicyError, wantErr: true},
+		{name: "threshold above one", options: FAQDuplicateOptions{DuplicateThreshold: 1.5}, wantPolicy: FAQDuplicatePolicyError, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.options.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := tt.options.Policy(); got != tt.wantPolicy {
+				t.Errorf("Policy() = %s, want %s", got, tt.wantPolicy)
+			}
+		})
+	}
+}
+
+func TestFAQExportRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        FAQExportRequest
+		wantFormat FAQExportFormat
+		wantErr    bool
+	}{
+		{name: "defaults to csv", wantFormat: FAQExportFormatCSV},
+		{name: "jsonl", req: FAQExportRequest{Format: FAQExportFormatJSONL, TagID: 3}, wantFormat: FAQExportFormatJSONL},
+		{name: "unknown format", req: FAQExportRequest{Format: "xlsx"}, wantFormat: "xlsx", wantErr: true},
+		{name: "negative tag", req: FAQExportRequest{TagID: -1}, wantFormat: FAQExportFormatCSV, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := tt.req.EffectiveFormat(); got != tt.wantFormat {
+				t.Errorf("EffectiveFormat() = %s, want %s", got, tt.wantFormat)
+			}
+		})
+	}
+}
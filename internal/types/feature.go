@@ -0,0 +1,90 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Features that can be enabled or disabled per tenant
+const (
+	// FeatureWebSearch allows web search in knowledge QA and agent sessions
+	FeatureWebSearch = "web_search"
+	// FeatureAgent allows agent mode in sessions
+	FeatureAgent = "agent"
+	// FeatureMultimodal allows multimodal (image) processing of documents
+	FeatureMultimodal = "multimodal"
+)
+
+// KnownFeatures lists the features that can be configured
+var KnownFeatures = []string{FeatureWebSearch, FeatureAgent, FeatureMultimodal}
+
+// FeatureFlags maps feature names to their enabled state
+type FeatureFlags map[string]bool
+
+// Validate checks that only known features are set
+func (f FeatureFlags) Validate() error {
+	for name := range f {
+		if !isKnownFeature(name) {
+			return fmt.Errorf("unknown feature %q, expected one of %v", name, KnownFeatures)
+		}
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface, used to convert FeatureFlags to database value
+func (f FeatureFlags) Value() (driver.Value, error) {
+	if f == nil {
+		return nil, nil
+	}
+	return json.Marshal(f)
+}
+
+// Scan implements the sql.Scanner interface, used to convert database value to FeatureFlags
+func (f *FeatureFlags) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, f)
+}
+
+// ResolveFeatures returns the state of every known feature. A tenant override takes precedence over
+// the global default, features without either are enabled.
+func ResolveFeatures(defaults, overrides FeatureFlags) FeatureFlags {
+	resolved := make(FeatureFlags, len(KnownFeatures))
+	for _, name := range KnownFeatures {
+		resolved[name] = featureEnabled(name, defaults, overrides)
+	}
+	return resolved
+}
+
+// FeatureEnabled reports whether the feature is enabled for the tenant, falling back to the global defaults
+func (t *Tenant) FeatureEnabled(name string, defaults FeatureFlags) bool {
+	if t == nil {
+		return featureEnabled(name, defaults, nil)
+	}
+	return featureEnabled(name, defaults, t.FeatureFlags)
+}
+
+func featureEnabled(name string, defaults, overrides FeatureFlags) bool {
+	if enabled, ok := overrides[name]; ok {
+		return enabled
+	}
+	if enabled, ok := defaults[name]; ok {
+		return enabled
+	}
+	return true
+}
+
+func isKnownFeature(name string) bool {
+	for _, known := range KnownFeatures {
+		if known == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package types
+
+import "testing"
+
+func TestResolveFeatures(t *testing.T) {
+	defaults := FeatureFlags{FeatureAgent: false, FeatureWebSearch: true}
+	overrides := FeatureFlags{FeatureAgent: true, FeatureMultimodal: false}
+
+	resolved := ResolveFeatures(defaults, overrides)
+	if !resolved[FeatureAgent] {
+		t.Error("tenant override should enable agent")
+	}
+	if !resolved[FeatureWebSearch] {
+		t.Error("global default should enable web search")
+	}
+	if resolved[FeatureMultimodal] {
+		t.Error("tenant override should disable multimodal")
+	}
+
+	if ResolveFeatures(nil, nil)[FeatureAgent] != true {
+		t.Error("features without configuration should be enabled")
+	}
+	var tenant *Tenant
+	if tenant.FeatureEnabled(FeatureAgent, defaults) {
+		t.Error("global default should apply without a tenant")
+	}
+}
+
+func TestFeatureFlagsValidate(t *testing.T) {
+	if err := (FeatureFlags{FeatureWebSearch: false}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (FeatureFlags{"unknown": true}).Validate(); err == nil {
+		t.Error("expected an error for an unknown feature")
+	}
+}
@@ -0,0 +1,133 @@
+package types
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+)
+
+// File format categories
+const (
+	FileFormatCategoryDocument    = "document"
+	FileFormatCategoryText        = "text"
+	FileFormatCategorySpreadsheet = "spreadsheet"
+	FileFormatCategoryImage       = "image"
+)
+
+// FileFormatRequirementVLM marks formats that can only be extracted with a VLM (multimodal) model
+const FileFormatRequirementVLM = "vlm_model"
+
+// FileFormat describes a file format accepted for knowledge upload
+type FileFormat struct {
+	// Extension is the lowercase file extension without the dot
+	Extension string `json:"extension"`
+	// Category groups formats by how they are parsed: document, text, spreadsheet or image
+	Category string `json:"category"`
+	// Available reports whether the format can be processed with the current configuration
+	Available bool `json:"available"`
+	// Requires names the configuration the format depends on, e.g. vlm_model
+	Requires string `json:"requires,omitempty"`
+	// Notes explains format-specific processing
+	Notes string `json:"notes,omitempty"`
+}
+
+// fileFormats is the catalog of formats the document reader can extract
+var fileFormats = []FileFormat{
+	{Extension: "pdf", Category: FileFormatCategoryDocument,
+		Notes: "Embedded images are only described when multimodal processing is enabled"},
+	{Extension: "docx", Category: FileFormatCategoryDocument,
+		Notes: "Embedded images are only described when multimodal processing is enabled"},
+	{Extension: "doc", Category: FileFormatCategoryDocument},
+	{Extension: "txt", Category: FileFormatCategoryText},
+	{Extension: "md", Category: FileFormatCategoryText},
+	{Extension: "markdown", Category: FileFormatCategoryText},
+	{Extension: "csv", Category: FileFormatCategorySpreadsheet,
+		Notes: "Can also be queried with the data analysis tools of smart-reasoning agents"},
+	{Extension: "xlsx", Category: FileFormatCategorySpreadsheet,
+		Notes: "Can also be queried with the data analysis tools of smart-reasoning agents"},
+	{Extension: "xls", Category: FileFormatCategorySpreadsheet},
+	{Extension: "png", Category: FileFormatCategoryImage, Requires: FileFormatRequirementVLM,
+		Notes: "Text is extracted with OCR and the image is captioned by a VLM model; requires object storage"},
+	{Extension: "jpg", Category: FileFormatCategoryImage, Requires: FileFormatRequirementVLM,
+		Notes: "Text is extracted with OCR and the image is captioned by a VLM model; requires object storage"},
+	{Extension: "jpeg", Category: FileFormatCategoryImage, Requires: FileFormatRequirementVLM,
+		Notes: "Text is extracted with OCR and the image is captioned by a VLM model; requires object storage"},
+	{Extension: "gif", Category: FileFormatCategoryImage, Requires: FileFormatRequirementVLM,
+		Notes: "Text is extracted with OCR and the image is captioned by a VLM model; requires object storage"},
+}
+
+// SupportedFileFormats returns the upload format catalog, marking VLM-dependent formats
+// unavailable when no VLM model is configured
+func SupportedFileFormats(vlmAvailable bool) []FileFormat {
+	formats := make([]FileFormat, len(fileFormats))
+	for i, f := range fileFormats {
+		f.Available = f.Requires == "" || (f.Requires == FileFormatRequirementVLM && vlmAvailable)
+		formats[i] = f
+	}
+	return formats
+}
+
+// LookupFileFormat finds the format of a file extension (case-insensitive, with or without the dot)
+func LookupFileFormat(formats []FileFormat, extension string) (FileFormat, bool) {
+	extension = strings.ToLower(strings.TrimPrefix(extension, "."))
+	for _, f := range formats {
+		if f.Extension == extension {
+			return f, true
+		}
+	}
+	return FileFormat{}, false
+}
+
+// AvailableFileExtensions lists the extensions of the available formats
+func AvailableFileExtensions(formats []FileFormat) []string {
+	extensions := make([]string, 0, len(formats))
+	for _, f := range formats {
+		if f.Available {
+			extensions = append(extensions, f.Extension)
+		}
+	}
+	return extensions
+}
+
+// UnsupportedFileTypeError is returned when an uploaded file cannot be processed
+type UnsupportedFileTypeError struct {
+	// FileType is the rejected extension
+	FileType string `json:"file_type"`
+	// Reason explains why a known format is unavailable (empty for unknown formats)
+	Reason string `json:"reason,omitempty"`
+	// SupportedTypes lists the extensions that can be uploaded
+	SupportedTypes []string `json:"supported_types"`
+}
+
+// NewUnsupportedFileTypeError creates the error for a file extension, explaining why a known format is unavailable
+func NewUnsupportedFileTypeError(formats []FileFormat, extension string) *UnsupportedFileTypeError {
+	e := &UnsupportedFileTypeError{
+		FileType:       strings.ToLower(strings.TrimPrefix(extension, ".")),
+		SupportedTypes: AvailableFileExtensions(formats),
+	}
+	if f, ok := LookupFileFormat(formats, extension); ok && f.Requires == FileFormatRequirementVLM {
+		e.Reason = "a VLM model must be configured for multimodal processing"
+	}
+	return e
+}
+
+// Error implements the error interface
+func (e *UnsupportedFileTypeError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("unsupported file type %q: %s", e.FileType, e.Reason)
+	}
+	return fmt.Sprintf("unsupported file type %q, supported types: %s", e.FileType, strings.Join(e.SupportedTypes, ", "))
+}
+
+// AppError reports the error as knowledge.unsupported_file_type with HTTP 415
+func (e *UnsupportedFileTypeError) AppError() *werrors.AppError {
+	return &werrors.AppError{
+		Code:     werrors.ErrBadRequest,
+		Reason:   werrors.CodeKnowledgeUnsupportedFileType,
+		Message:  e.Error(),
+		Details:  e,
+		HTTPCode: http.StatusUnsupportedMediaType,
+	}
+}
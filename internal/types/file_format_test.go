@@ -0,0 +1,60 @@
+package types
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+)
+
+func TestSupportedFileFormats(t *testing.T) {
+	withoutVLM := SupportedFileFormats(false)
+	withVLM := SupportedFileFormats(true)
+
+	for _, ext := range []string{"pdf", "docx", "md", "csv", "xlsx"} {
+		if f, ok := LookupFileFormat(withoutVLM, ext); !ok || !f.Available {
+			t.Errorf("%s should be available without a VLM model", ext)
+		}
+	}
+	if f, ok := LookupFileFormat(withoutVLM, "PNG"); !ok || f.Available {
+		t.Errorf("png should be known but unavailable without a VLM model, got %+v (found %v)", f, ok)
+	}
+	if f, ok := LookupFileFormat(withVLM, ".png"); !ok || !f.Available {
+		t.Errorf("png should be available with a VLM model, got %+v (found %v)", f, ok)
+	}
+	if _, ok := LookupFileFormat(withVLM, "exe"); ok {
+		t.Error("exe should not be a known format")
+	}
+
+	extensions := AvailableFileExtensions(withoutVLM)
+	if slices.Contains(extensions, "jpg") || !slices.Contains(extensions, "txt") {
+		t.Errorf("available extensions without VLM = %v", extensions)
+	}
+	if len(AvailableFileExtensions(withVLM)) != len(withVLM) {
+		t.Errorf("all formats should be available with a VLM model")
+	}
+}
+
+func TestUnsupportedFileTypeError(t *testing.T) {
+	formats := SupportedFileFormats(false)
+
+	err := NewUnsupportedFileTypeError(formats, "EXE")
+	if err.FileType != "exe" || err.Reason != "" {
+		t.Errorf("unknown format error = %+v", err)
+	}
+	if !slices.Equal(err.SupportedTypes, AvailableFileExtensions(formats)) {
+		t.Errorf("SupportedTypes = %v", err.SupportedTypes)
+	}
+	appErr := werrors.FromError(err)
+	if appErr.HTTPCode != http.StatusUnsupportedMediaType {
+		t.Errorf("status = %d, want 415", appErr.HTTPCode)
+	}
+	if appErr.MachineCode() != werrors.CodeKnowledgeUnsupportedFileType {
+		t.Errorf("code = %q, want %q", appErr.MachineCode(), werrors.CodeKnowledgeUnsupportedFileType)
+	}
+
+	if err := NewUnsupportedFileTypeError(formats, "jpg"); err.Reason == "" {
+		t.Error("unavailable image format should explain the missing VLM model")
+	}
+}
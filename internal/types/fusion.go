@@ -0,0 +1,219 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Fusion algorithms merging vector and keyword results in hybrid search
+const (
+	// FusionAlgorithmRRF ranks by reciprocal rank fusion: sum(1 / (k + rank)) over retrievers
+	FusionAlgorithmRRF = "rrf"
+	// FusionAlgorithmWeightedSum ranks by the weighted sum of min-max normalized retriever scores
+	FusionAlgorithmWeightedSum = "weighted_sum"
+	// FusionAlgorithmMax ranks by the best min-max normalized score of any retriever
+	FusionAlgorithmMax = "max"
+	// FusionAlgorithmNone is reported when only one retriever returned results and no fusion was applied
+	FusionAlgorithmNone = "none"
+)
+
+// Default fusion parameters
+const (
+	DefaultFusionRRFK          = 60
+	DefaultFusionVectorWeight  = 0.7
+	DefaultFusionKeywordWeight = 0.3
+)
+
+// FusionConfig selects how hybrid search merges vector and keyword results
+type FusionConfig struct {
+	// Algorithm is one of rrf (default), weighted_sum or max
+	Algorithm string `yaml:"algorithm"      json:"algorithm,omitempty"`
+	// RRFK is the rank constant of rrf (0 = default 60); larger values flatten rank differences
+	RRFK int `yaml:"rrf_k"          json:"rrf_k,omitempty"`
+	// VectorWeight weighs vector scores in weighted_sum (default 0.7)
+	VectorWeight float64 `yaml:"vector_weight"  json:"vector_weight,omitempty"`
+	// KeywordWeight weighs keyword scores in weighted_sum (default 0.3)
+	KeywordWeight float64 `yaml:"keyword_weight" json:"keyword_weight,omitempty"`
+}
+
+// Validate checks the algorithm and its parameters
+func (c *FusionConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.Algorithm {
+	case "", FusionAlgorithmRRF, FusionAlgorithmWeightedSum, FusionAlgorithmMax:
+	default:
+		return fmt.Errorf("unsupported fusion algorithm %q, expected rrf, weighted_sum or max", c.Algorithm)
+	}
+	if c.RRFK < 0 {
+		return fmt.Errorf("rrf_k must not be negative")
+	}
+	if c.VectorWeight < 0 || c.KeywordWeight < 0 {
+		return fmt.Errorf("weights must not be negative")
+	}
+	return nil
+}
+
+// Resolved returns a copy with defaults filled in, holding only the parameters of the selected algorithm
+func (c *FusionConfig) Resolved() *FusionConfig {
+	resolved := &FusionConfig{Algorithm: FusionAlgorithmRRF}
+	if c != nil && c.Algorithm != "" {
+		resolved.Algorithm = c.Algorithm
+	}
+	switch resolved.Algorithm {
+	case FusionAlgorithmRRF:
+		resolved.RRFK = DefaultFusionRRFK
+		if c != nil && c.RRFK > 0 {
+			resolved.RRFK = c.RRFK
+		}
+	case FusionAlgorithmWeightedSum:
+		resolved.VectorWeight, resolved.KeywordWeight = DefaultFusionVectorWeight, DefaultFusionKeywordWeight
+		if c != nil && (c.VectorWeight > 0 || c.KeywordWeight > 0) {
+			resolved.VectorWeight, resolved.KeywordWeight = c.VectorWeight, c.KeywordWeight
+		}
+	}
+	return resolved
+}
+
+// FusionTrace explains how a hybrid search result was ranked; returned in debug mode
+type FusionTrace struct {
+	// Algorithm and parameters that produced the ranking
+	Algorithm     string  `json:"algorithm"`
+	RRFK          int     `json:"rrf_k,omitempty"`
+	VectorWeight  float64 `json:"vector_weight,omitempty"`
+	KeywordWeight float64 `json:"keyword_weight,omitempty"`
+	// Rank (1-based) and raw score in each retriever; rank 0 means the retriever did not return the chunk
+	VectorRank   int     `json:"vector_rank,omitempty"`
+	VectorScore  float64 `json:"vector_score,omitempty"`
+	KeywordRank  int     `json:"keyword_rank,omitempty"`
+	KeywordScore float64 `json:"keyword_score,omitempty"`
+	// FusedScore is the score the result was ranked by
+	FusedScore float64 `json:"fused_score"`
+}
+
+// retrieverRanking holds the best rank and score of each chunk in one retriever's results
+type retrieverRanking struct {
+	ranks          map[string]int
+	scores         map[string]float64
+	minScore, span float64
+}
+
+func newRetrieverRanking(results []*IndexWithScore) *retrieverRanking {
+	r := &retrieverRanking{ranks: make(map[string]int), scores: make(map[string]float64)}
+	maxScore := 0.0
+	for i, result := range results {
+		if _, exists := r.ranks[result.ChunkID]; !exists {
+			r.ranks[result.ChunkID] = i + 1
+		}
+		if score, exists := r.scores[result.ChunkID]; !exists || result.Score > score {
+			r.scores[result.ChunkID] = result.Score
+		}
+		if i == 0 || result.Score < r.minScore {
+			r.minScore = result.Score
+		}
+		if i == 0 || result.Score > maxScore {
+			maxScore = result.Score
+		}
+	}
+	r.span = maxScore - r.minScore
+	return r
+}
+
+// normalized returns the min-max normalized score of a chunk, 0 when the retriever did not return it
+func (r *retrieverRanking) normalized(chunkID string) float64 {
+	score, ok := r.scores[chunkID]
+	if !ok {
+		return 0
+	}
+	if r.span <= 0 {
+		return 1
+	}
+	return (score - r.minScore) / r.span
+}
+
+// Fuse merges vector and keyword results (each sorted by score, best first) into one list sorted
+// by fused score. Each chunk appears once and carries the fused score; the returned traces are
+// keyed by chunk ID.
+func (c *FusionConfig) Fuse(vectorResults, keywordResults []*IndexWithScore) ([]*IndexWithScore, map[string]*FusionTrace) {
+	config := c.Resolved()
+	vector, keyword := newRetrieverRanking(vectorResults), newRetrieverRanking(keywordResults)
+
+	// Keep the highest-scored vector hit per chunk, then add keyword-only chunks
+	chunks := make(map[string]*IndexWithScore)
+	for _, r := range vectorResults {
+		if existing, exists := chunks[r.ChunkID]; !exists || r.Score > existing.Score {
+			chunks[r.ChunkID] = r
+		}
+	}
+	for _, r := range keywordResults {
+		if _, exists := chunks[r.ChunkID]; !exists {
+			chunks[r.ChunkID] = r
+		}
+	}
+
+	fused := make([]*IndexWithScore, 0, len(chunks))
+	traces := make(map[string]*FusionTrace, len(chunks))
+	for chunkID, info := range chunks {
+		trace := &FusionTrace{
+			Algorithm:     config.Algorithm,
+			RRFK:          config.RRFK,
+			VectorWeight:  config.VectorWeight,
+			KeywordWeight: config.KeywordWeight,
+			VectorRank:    vector.ranks[chunkID],
+			VectorScore:   vector.scores[chunkID],
+			KeywordRank:   keyword.ranks[chunkID],
+			KeywordScore:  keyword.scores[chunkID],
+		}
+		switch config.Algorithm {
+		case FusionAlgorithmWeightedSum:
+			total := config.VectorWeight + config.KeywordWeight
+			if total > 0 {
+				trace.FusedScore = (config.VectorWeight*vector.normalized(chunkID) +
+					config.KeywordWeight*keyword.normalized(chunkID)) / total
+			}
+		case FusionAlgorithmMax:
+			trace.FusedScore = max(vector.normalized(chunkID), keyword.normalized(chunkID))
+		default:
+			if trace.VectorRank > 0 {
+				trace.FusedScore += 1.0 / float64(config.RRFK+trace.VectorRank)
+			}
+			if trace.KeywordRank > 0 {
+				trace.FusedScore += 1.0 / float64(config.RRFK+trace.KeywordRank)
+			}
+		}
+		info.Score = trace.FusedScore
+		fused = append(fused, info)
+		traces[chunkID] = trace
+	}
+
+	slices.SortFunc(fused, func(a, b *IndexWithScore) int {
+		if a.Score > b.Score {
+			return -1
+		} else if a.Score < b.Score {
+			return 1
+		}
+		return strings.Compare(a.ChunkID, b.ChunkID)
+	})
+	return fused, traces
+}
+
+// Value implements driver.Valuer
+func (c FusionConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *FusionConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
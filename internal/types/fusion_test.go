@@ -0,0 +1,77 @@
+package types
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFusionConfigFuse(t *testing.T) {
+	newResults := func() ([]*IndexWithScore, []*IndexWithScore) {
+		vector := []*IndexWithScore{
+			{ChunkID: "a", Score: 0.9},
+			{ChunkID: "b", Score: 0.8},
+			{ChunkID: "c", Score: 0.5},
+		}
+		keyword := []*IndexWithScore{
+			{ChunkID: "c", Score: 12},
+			{ChunkID: "d", Score: 4},
+		}
+		return vector, keyword
+	}
+
+	tests := []struct {
+		name   string
+		config *FusionConfig
+		order  []string
+		top    float64
+	}{
+		{
+			name:   "default rrf",
+			config: nil,
+			order:  []string{"c", "a", "b", "d"},
+			top:    1.0/63 + 1.0/61,
+		},
+		{
+			name:   "weighted sum favours vector",
+			config: &FusionConfig{Algorithm: FusionAlgorithmWeightedSum, VectorWeight: 1, KeywordWeight: 0},
+			order:  []string{"a", "b", "c", "d"},
+			top:    1,
+		},
+		{
+			name:   "max",
+			config: &FusionConfig{Algorithm: FusionAlgorithmMax},
+			order:  []string{"a", "c", "b", "d"},
+			top:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vector, keyword := newResults()
+			fused, traces := tt.config.Fuse(vector, keyword)
+			if len(fused) != len(tt.order) {
+				t.Fatalf("got %d results, want %d", len(fused), len(tt.order))
+			}
+			for i, id := range tt.order {
+				if fused[i].ChunkID != id {
+					t.Errorf("position %d = %s, want %s", i, fused[i].ChunkID, id)
+				}
+			}
+			if math.Abs(fused[0].Score-tt.top) > 1e-9 {
+				t.Errorf("top score = %v, want %v", fused[0].Score, tt.top)
+			}
+			if traces[fused[0].ChunkID].FusedScore != fused[0].Score {
+				t.Errorf("trace score does not match result score")
+			}
+		})
+	}
+}
+
+func TestFusionConfigValidate(t *testing.T) {
+	if err := (&FusionConfig{Algorithm: "borda"}).Validate(); err == nil {
+		t.Error("expected error for unsupported algorithm")
+	}
+	if err := (&FusionConfig{Algorithm: FusionAlgorithmRRF, RRFK: 10}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Grounding strictness levels
+const (
+	// GroundingStrictnessLenient requires half of the answer sentences to be supported
+	GroundingStrictnessLenient = "lenient"
+	// GroundingStrictnessModerate requires 80% of the answer sentences to be supported
+	GroundingStrictnessModerate = "moderate"
+	// GroundingStrictnessStrict requires every answer sentence to be supported and treats a failed
+	// check as insufficient grounding
+	GroundingStrictnessStrict = "strict"
+)
+
+// Actions taken on answers with insufficient grounding
+const (
+	// GroundingActionAnnotate marks the unsupported sentences of the answer
+	GroundingActionAnnotate = "annotate"
+	// GroundingActionRefuse replaces the answer with the fallback response
+	GroundingActionRefuse = "refuse"
+)
+
+// GroundingUnsupportedMarker is appended to the sentences the retrieved content does not support
+const GroundingUnsupportedMarker = " [unverified]"
+
+var groundingStrictness = map[string]float64{
+	GroundingStrictnessLenient:  0.5,
+	GroundingStrictnessModerate: 0.8,
+	GroundingStrictnessStrict:   1,
+}
+
+// GroundingConfig is the per knowledge base policy that verifies after generation that every
+// sentence of an answer is supported by the retrieved chunks
+type GroundingConfig struct {
+	// Enabled checks the answers generated from the knowledge base
+	Enabled bool `yaml:"enabled"           json:"enabled"`
+	// Strictness is the share of supported sentences required: lenient, moderate (default) or strict
+	Strictness string `yaml:"strictness"        json:"strictness,omitempty"`
+	// Action is applied when grounding is insufficient: annotate (default) or refuse
+	Action string `yaml:"action"            json:"action,omitempty"`
+	// FallbackResponse replaces refused answers; empty uses the conversation fallback response
+	FallbackResponse string `yaml:"fallback_response" json:"fallback_response,omitempty"`
+}
+
+// Validate checks the strictness and action
+func (c *GroundingConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if _, ok := groundingStrictness[c.Strictness]; c.Strictness != "" && !ok {
+		return fmt.Errorf("strictness must be %q, %q or %q",
+			GroundingStrictnessLenient, GroundingStrictnessModerate, GroundingStrictnessStrict)
+	}
+	switch c.Action {
+	case "", GroundingActionAnnotate, GroundingActionRefuse:
+	default:
+		return fmt.Errorf("action must be %q or %q", GroundingActionAnnotate, GroundingActionRefuse)
+	}
+	return nil
+}
+
+// EffectiveStrictness returns the configured strictness or the default
+func (c *GroundingConfig) EffectiveStrictness() string {
+	if c == nil || c.Strictness == "" {
+		return GroundingStrictnessModerate
+	}
+	return c.Strictness
+}
+
+// EffectiveAction returns the configured action or the default
+func (c *GroundingConfig) EffectiveAction() string {
+	if c == nil || c.Action == "" {
+		return GroundingActionAnnotate
+	}
+	return c.Action
+}
+
+// MinSupportedRatio returns the share of supported sentences an answer needs
+func (c *GroundingConfig) MinSupportedRatio() float64 {
+	return groundingStrictness[c.EffectiveStrictness()]
+}
+
+// Stricter combines the enabled policies of two knowledge bases: the higher strictness and
+// refusal win. Returns a copy when both are enabled.
+func (c *GroundingConfig) Stricter(other *GroundingConfig) *GroundingConfig {
+	if c == nil || !c.Enabled {
+		return other
+	}
+	if other == nil || !other.Enabled {
+		return c
+	}
+	merged := *c
+	if other.MinSupportedRatio() > c.MinSupportedRatio() {
+		merged.Strictness = other.EffectiveStrictness()
+	}
+	if other.EffectiveAction() == GroundingActionRefuse {
+		merged.Action = GroundingActionRefuse
+	}
+	if merged.FallbackResponse == "" {
+		merged.FallbackResponse = other.FallbackResponse
+	}
+	return &merged
+}
+
+// Value implements driver.Valuer
+func (c GroundingConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *GroundingConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// GroundingSentence is a sentence of the answer, located by its byte offsets in the answer
+type GroundingSentence struct {
+	Text      string `json:"text"`
+	Start     int    `json:"-"`
+	End       int    `json:"-"`
+	Supported bool   `json:"supported"`
+}
+
+// GroundingResult is the grounding verdict of an answer
+type GroundingResult struct {
+	// SupportedRatio is the share of answer sentences supported by the retrieved chunks (0-1)
+	SupportedRatio float64 `json:"supported_ratio"`
+	// Threshold is the share of supported sentences required by the strictness
+	Threshold  float64 `json:"threshold"`
+	Strictness string  `json:"strictness"`
+	Action     string  `json:"action"`
+	// Grounded reports whether the answer reached the threshold
+	Grounded bool `json:"grounded"`
+	// Annotated reports whether unsupported sentences were marked in the answer
+	Annotated bool `json:"annotated"`
+	// Refused reports whether the answer was replaced with the fallback response
+	Refused bool `json:"refused"`
+	// Unsupported lists the sentences the retrieved chunks do not support
+	Unsupported []string `json:"unsupported,omitempty"`
+	// Error is set when the check could not be performed
+	Error string `json:"error,omitempty"`
+}
+
+var groundingThinkPattern = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// SplitGroundingSentences splits an answer into the sentences to verify. Sentences end at line breaks,
+// at sentence punctuation and at periods followed by a space, so that numbers such as 1.5 stay whole.
+// Thinking content and fragments without a letter, such as list numbers, are skipped.
+func SplitGroundingSentences(answer string) []*GroundingSentence {
+	// Thinking content is blanked out with line breaks, which keeps the offsets of the answer
+	text := groundingThinkPattern.ReplaceAllStringFunc(answer, func(think string) string {
+		return strings.Repeat("\n", len(think))
+	})
+	var sentences []*GroundingSentence
+	add := func(start, end int) {
+		raw := text[start:end]
+		sentence := strings.TrimSpace(raw)
+		if !strings.ContainsFunc(sentence, unicode.IsLetter) {
+			return
+		}
+		offset := start + strings.Index(raw, sentence)
+		sentences = append(sentences, &GroundingSentence{Text: sentence, Start: offset, End: offset + len(sentence)})
+	}
+
+	start := 0
+	for i, r := range text {
+		if i < start {
+			continue
+		}
+		end := -1
+		switch r {
+		case '\n':
+			end = i + 1
+		case '!', '?', ';', '。', '！', '？', '；':
+			end = i + utf8.RuneLen(r)
+		case '.':
+			next := i + 1
+			if next >= len(text) || text[next] == ' ' || text[next] == '\n' || text[next] == '\t' {
+				end = next
+			}
+		}
+		if end < 0 {
+			continue
+		}
+		// Keep runs of punctuation such as "?!" with their sentence
+		for end < len(text) && strings.IndexByte(".!?;", text[end]) >= 0 {
+			end++
+		}
+		add(start, end)
+		start = end
+	}
+	if start < len(text) {
+		add(start, len(text))
+	}
+	return sentences
+}
+
+// Evaluate builds the verdict for the checked sentences. A nil error with no sentence is
+// considered grounded; a failed check is grounded only below the strict level.
+func (c *GroundingConfig) Evaluate(sentences []*GroundingSentence, checkErr error) *GroundingResult {
+	result := &GroundingResult{
+		Threshold:  c.MinSupportedRatio(),
+		Strictness: c.EffectiveStrictness(),
+		Action:     c.EffectiveAction(),
+	}
+	if checkErr != nil {
+		result.Error = checkErr.Error()
+		result.Grounded = result.Strictness != GroundingStrictnessStrict
+		return result
+	}
+	if len(sentences) == 0 {
+		result.SupportedRatio = 1
+		result.Grounded = true
+		return result
+	}
+	supported := 0
+	for _, sentence := range sentences {
+		if sentence.Supported {
+			supported++
+		} else {
+			result.Unsupported = append(result.Unsupported, sentence.Text)
+		}
+	}
+	result.SupportedRatio = float64(supported) / float64(len(sentences))
+	// A small tolerance keeps e.g. 4 of 5 sentences at the 0.8 threshold
+	result.Grounded = result.SupportedRatio+1e-9 >= result.Threshold
+	return result
+}
+
+// AnnotateUnsupported appends GroundingUnsupportedMarker to the unsupported sentences of the answer
+func AnnotateUnsupported(answer string, sentences []*GroundingSentence) string {
+	var sb strings.Builder
+	last := 0
+	for _, sentence := range sentences {
+		if sentence.Supported || sentence.End < last || sentence.End > len(answer) {
+			continue
+		}
+		sb.WriteString(answer[last:sentence.End])
+		sb.WriteString(GroundingUnsupportedMarker)
+		last = sentence.End
+	}
+	sb.WriteString(answer[last:])
+	return sb.String()
+}
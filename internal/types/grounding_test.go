@@ -0,0 +1,138 @@
+package types
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSplitGroundingSentences(t *testing.T) {
+	answer := "<think>The user asks about limits. Check the docs.</think>The limit is 1.5 GB per file. " +
+		"Uploads over it fail!\n\n1. Compress the file\n2. Split it?\n- 部分文件无法上传。请联系管理员"
+	var got []string
+	for _, sentence := range SplitGroundingSentences(answer) {
+		if answer[sentence.Start:sentence.End] != sentence.Text {
+			t.Errorf("sentence %q is not at its offsets", sentence.Text)
+		}
+		got = append(got, sentence.Text)
+	}
+	want := []string{
+		"The limit is 1.5 GB per file.",
+		"Uploads over it fail!",
+		"Compress the file",
+		"Split it?",
+		"- 部分文件无法上传。",
+		"请联系管理员",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("SplitGroundingSentences() = %q, want %q", got, want)
+	}
+}
+
+func TestGroundingEvaluate(t *testing.T) {
+	sentences := func(supported ...bool) []*GroundingSentence {
+		var s []*GroundingSentence
+		for i, ok := range supported {
+			s = append(s, &GroundingSentence{Text: string(rune('a' + i)), Supported: ok})
+		}
+		return s
+	}
+	tests := []struct {
+		name         string
+		config       *GroundingConfig
+		sentences    []*GroundingSentence
+		checkErr     error
+		wantGrounded bool
+		wantRatio    float64
+	}{
+		{"moderate accepts 4 of 5", &GroundingConfig{Enabled: true}, sentences(true, true, false, true, true), nil, true, 0.8},
+		{"moderate rejects 3 of 5", &GroundingConfig{Enabled: true}, sentences(true, false, false, true, true), nil, false, 0.6},
+		{
+			"lenient accepts half",
+			&GroundingConfig{Enabled: true, Strictness: GroundingStrictnessLenient},
+			sentences(true, false), nil, true, 0.5,
+		},
+		{
+			"strict rejects one unsupported",
+			&GroundingConfig{Enabled: true, Strictness: GroundingStrictnessStrict},
+			sentences(true, true, true, false), nil, false, 0.75,
+		},
+		{"no sentence", &GroundingConfig{Enabled: true}, nil, nil, true, 1},
+		{"failed check passes", &GroundingConfig{Enabled: true}, nil, errors.New("timeout"), true, 0},
+		{
+			"failed check fails strict",
+			&GroundingConfig{Enabled: true, Strictness: GroundingStrictnessStrict},
+			nil, errors.New("timeout"), false, 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.config.Evaluate(tt.sentences, tt.checkErr)
+			if result.Grounded != tt.wantGrounded {
+				t.Errorf("Grounded = %v, want %v", result.Grounded, tt.wantGrounded)
+			}
+			if result.SupportedRatio != tt.wantRatio {
+				t.Errorf("SupportedRatio = %v, want %v", result.SupportedRatio, tt.wantRatio)
+			}
+			if (tt.checkErr != nil) != (result.Error != "") {
+				t.Errorf("Error = %q", result.Error)
+			}
+		})
+	}
+}
+
+func TestAnnotateUnsupported(t *testing.T) {
+	answer := "Resets take effect at once. They also notify your manager. Contact support otherwise."
+	sentences := SplitGroundingSentences(answer)
+	sentences[0].Supported = true
+	sentences[2].Supported = true
+
+	want := "Resets take effect at once. They also notify your manager." + GroundingUnsupportedMarker +
+		" Contact support otherwise."
+	if got := AnnotateUnsupported(answer, sentences); got != want {
+		t.Errorf("AnnotateUnsupported() = %q, want %q", got, want)
+	}
+}
+
+func TestGroundingConfigStricter(t *testing.T) {
+	lenient := &GroundingConfig{Enabled: true, Strictness: GroundingStrictnessLenient, Action: GroundingActionRefuse}
+	strict := &GroundingConfig{Enabled: true, Strictness: GroundingStrictnessStrict, FallbackResponse: "No."}
+	disabled := &GroundingConfig{Strictness: GroundingStrictnessStrict, Action: GroundingActionRefuse}
+
+	merged := lenient.Stricter(strict)
+	if merged.EffectiveStrictness() != GroundingStrictnessStrict || merged.EffectiveAction() != GroundingActionRefuse ||
+		merged.FallbackResponse != "No." {
+		t.Errorf("Stricter() = %+v, want strict refusal with the fallback response", merged)
+	}
+	if lenient.Strictness != GroundingStrictnessLenient {
+		t.Error("Stricter() modified the receiver")
+	}
+	if got := disabled.Stricter(lenient); got != lenient {
+		t.Errorf("disabled policy was not ignored: %+v", got)
+	}
+	if got := (*GroundingConfig)(nil).Stricter(nil); got != nil {
+		t.Errorf("Stricter(nil) = %+v", got)
+	}
+}
+
+func TestGroundingConfigValidate(t *testing.T) {
+	valid := []*GroundingConfig{
+		nil,
+		{Enabled: true},
+		{Enabled: true, Strictness: GroundingStrictnessStrict, Action: GroundingActionRefuse},
+	}
+	for _, config := range valid {
+		if err := config.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", config, err)
+		}
+	}
+	invalid := []*GroundingConfig{
+		{Strictness: "paranoid"},
+		{Action: "delete"},
+	}
+	for _, config := range invalid {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted an invalid configuration", config)
+		}
+	}
+}
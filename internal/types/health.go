@@ -0,0 +1,28 @@
+package types
+
+// HealthStatus is the state of the service or one of its subsystems
+type HealthStatus string
+
+const (
+	// HealthStatusOK means the subsystem works
+	HealthStatusOK HealthStatus = "ok"
+	// HealthStatusDegraded means the subsystem is unreachable or failed its check
+	HealthStatusDegraded HealthStatus = "degraded"
+)
+
+// HealthCheckResult is the outcome of a subsystem check
+type HealthCheckResult struct {
+	Name   string       `json:"name"`
+	Status HealthStatus `json:"status"`
+	// Error explains why the subsystem is degraded
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// ReadinessReport is the outcome of the readiness probe
+type ReadinessReport struct {
+	Status HealthStatus `json:"status"`
+	// Degraded lists the subsystems that failed their check
+	Degraded []string            `json:"degraded,omitempty"`
+	Checks   []HealthCheckResult `json:"checks"`
+}
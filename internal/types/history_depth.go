@@ -0,0 +1,33 @@
+package types
+
+import "fmt"
+
+// MaxHistoryDepth is the largest number of prior turns that can be included in the prompt
+const MaxHistoryDepth = 100
+
+// HistoryDepthUnset clears the history depth of a knowledge base in an update request
+const HistoryDepthUnset = -1
+
+// ValidateHistoryDepth checks a history depth: nil (not set) or 0..MaxHistoryDepth.
+// allowUnset additionally accepts HistoryDepthUnset, used by updates to clear the setting.
+func ValidateHistoryDepth(depth *int, allowUnset bool) error {
+	if depth == nil || (allowUnset && *depth == HistoryDepthUnset) {
+		return nil
+	}
+	if *depth < 0 || *depth > MaxHistoryDepth {
+		return fmt.Errorf("history_depth must be between 0 and %d", MaxHistoryDepth)
+	}
+	return nil
+}
+
+// HistoryRounds returns how many prior turns to include: the history depth when set,
+// otherwise MaxRounds, otherwise defaultRounds
+func (c *ChatManage) HistoryRounds(defaultRounds int) int {
+	if c.HistoryDepth != nil {
+		return *c.HistoryDepth
+	}
+	if c.MaxRounds > 0 {
+		return c.MaxRounds
+	}
+	return defaultRounds
+}
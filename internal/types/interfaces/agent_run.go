@@ -0,0 +1,24 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// AgentRunService defines the agent execution trace service interface
+type AgentRunService interface {
+	// ListRuns returns the original run of a message followed by its replays
+	ListRuns(ctx context.Context, messageID string) ([]*types.AgentRun, error)
+	// ReplayRun re-executes the inputs of a message's original run
+	// agentVersion is optional - if > 0, the given agent version is used instead of the recorded config
+	ReplayRun(ctx context.Context, messageID string, agentVersion int) (*types.AgentRun, error)
+}
+
+// AgentRunRepository defines the agent execution trace repository interface
+type AgentRunRepository interface {
+	// CreateRun stores an agent execution trace
+	CreateRun(ctx context.Context, run *types.AgentRun) error
+	// ListRunsByMessageID lists all runs of a message, oldest first
+	ListRunsByMessageID(ctx context.Context, tenantID uint64, messageID string) ([]*types.AgentRun, error)
+}
@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// AnswerCacheService caches knowledge Q&A answers for exact repeats of a question
+type AnswerCacheService interface {
+	// TTL returns how long answers from the knowledge bases may be cached, 0 when any of them disables caching
+	TTL(ctx context.Context, knowledgeBaseIDs []string) time.Duration
+	// Get returns the cached answer for the key, nil on a miss
+	Get(ctx context.Context, key types.AnswerCacheKey) (*types.CachedAnswer, error)
+	// Set caches an answer for the key
+	Set(ctx context.Context, key types.AnswerCacheKey, answer *types.CachedAnswer, ttl time.Duration) error
+	// Invalidate drops every cached answer that used the knowledge base
+	Invalidate(ctx context.Context, knowledgeBaseID string) error
+}
@@ -0,0 +1,38 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// APIKeyService defines the service of the API keys minted for tenants
+type APIKeyService interface {
+	// CreateKey mints an API key for the current tenant; the key is only returned here
+	CreateKey(ctx context.Context, req *types.CreateAPIKeyRequest) (*types.CreatedAPIKey, error)
+	// ListKeys lists the API keys of the current tenant, newest first
+	ListKeys(ctx context.Context) ([]*types.APIKey, error)
+	// RevokeKey revokes an API key of the current tenant
+	RevokeKey(ctx context.Context, id string) error
+	// Authenticate returns the active API key matching a key sent with a request
+	Authenticate(ctx context.Context, key string) (*types.APIKey, error)
+}
+
+// APIKeyRepository defines the repository of the API keys
+type APIKeyRepository interface {
+	// CreateKey stores an API key
+	CreateKey(ctx context.Context, key *types.APIKey) error
+	// GetKeyByHash gets the API key with the hash, nil when there is none
+	GetKeyByHash(ctx context.Context, keyHash string) (*types.APIKey, error)
+	// GetKeyByID gets an API key of a tenant, nil when there is none
+	GetKeyByID(ctx context.Context, tenantID uint64, id string) (*types.APIKey, error)
+	// ListKeys lists the API keys of a tenant, newest first
+	ListKeys(ctx context.Context, tenantID uint64) ([]*types.APIKey, error)
+	// CountActiveKeys counts the API keys of a tenant that are neither revoked nor expired
+	CountActiveKeys(ctx context.Context, tenantID uint64, now time.Time) (int64, error)
+	// RevokeKey marks an API key of a tenant as revoked
+	RevokeKey(ctx context.Context, tenantID uint64, id string, revokedAt time.Time) error
+	// TouchKey records the last use of an API key
+	TouchKey(ctx context.Context, id string, usedAt time.Time) error
+}
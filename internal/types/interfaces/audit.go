@@ -0,0 +1,26 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// AuditLogService defines the audit log service interface
+type AuditLogService interface {
+	// ListLogs lists the audit log entries of the current tenant matching the filter, newest first
+	ListLogs(ctx context.Context, filter *types.AuditLogFilter, page *types.Pagination) (*types.PageResult, error)
+	// GetLog gets an audit log entry of the current tenant
+	GetLog(ctx context.Context, id string) (*types.AuditLog, error)
+}
+
+// AuditLogRepository defines the audit log repository interface
+type AuditLogRepository interface {
+	// CreateLogs stores a batch of audit log entries
+	CreateLogs(ctx context.Context, logs []*types.AuditLog) error
+	// ListLogs lists the entries of a tenant matching the filter, newest first, with their total count
+	ListLogs(ctx context.Context, tenantID uint64, filter *types.AuditLogFilter, page *types.Pagination,
+	) ([]*types.AuditLog, int64, error)
+	// GetLog gets an entry of a tenant, nil when there is none
+	GetLog(ctx context.Context, tenantID uint64, id string) (*types.AuditLog, error)
+}
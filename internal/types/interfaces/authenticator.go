@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// Authenticator validates the bearer tokens of API requests. The Auth middleware resolves the tenant
+// of the request from the returned user, whichever provider validated the token.
+type Authenticator interface {
+	// Name identifies the provider in logs
+	Name() string
+	// Authenticate validates the token and returns the user it belongs to.
+	// Returns an error when the token is invalid, expired or revoked.
+	Authenticate(ctx context.Context, token string) (*types.User, error)
+}
@@ -57,6 +57,8 @@ type ChunkRepository interface {
 	DeleteChunksByTagID(ctx context.Context, tenantID uint64, kbID string, tagID string, excludeIDs []string) ([]string, error)
 	// CountChunksByKnowledgeBaseID counts the number of chunks in a knowledge base.
 	CountChunksByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) (int64, error)
+	// CountEnabledChunks counts the enabled chunks of a knowledge base, optionally limited to some knowledge
+	CountEnabledChunks(ctx context.Context, tenantID uint64, kbID string, knowledgeIDs []string) (int64, error)
 	// DeleteUnindexedChunks deletes unindexed chunks by knowledge id and chunk index range
 	DeleteUnindexedChunks(ctx context.Context, tenantID uint64, knowledgeID string) ([]*types.Chunk, error)
 	// ListAllFAQChunksByKnowledgeID lists all FAQ chunks for a knowledge ID
@@ -67,6 +69,10 @@ type ChunkRepository interface {
 	ListAllFAQChunksWithMetadataByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) ([]*types.Chunk, error)
 	// ListAllFAQChunksForExport lists all FAQ chunks for export with full metadata, tag_id, is_enabled, and flags
 	ListAllFAQChunksForExport(ctx context.Context, tenantID uint64, knowledgeID string) ([]*types.Chunk, error)
+	// ListFAQChunksForExportAfter lists up to limit FAQ chunks for export with seq_id greater than afterSeqID,
+	// ordered by seq_id, optionally filtered by tag and enabled status
+	ListFAQChunksForExportAfter(ctx context.Context, tenantID uint64, knowledgeID string,
+		filter *types.FAQExportFilter, afterSeqID int64, limit int) ([]*types.Chunk, error)
 	// UpdateChunkFlagsBatch updates flags for multiple chunks in batch using a single SQL statement.
 	// setFlags: map of chunk ID to flags to set (OR operation)
 	// clearFlags: map of chunk ID to flags to clear (AND NOT operation)
@@ -75,6 +81,9 @@ type ChunkRepository interface {
 	// Supports updating is_enabled, flags, and tag_id fields.
 	// newTagID: if not nil, updates tag_id to this value (empty string means uncategorized)
 	UpdateChunkFieldsByTagID(ctx context.Context, tenantID uint64, kbID string, tagID string, isEnabled *bool, setFlags types.ChunkFlags, clearFlags types.ChunkFlags, newTagID *string, excludeIDs []string) ([]string, error)
+	// UpdateChunkEnabledByKnowledgeIDs sets is_enabled on all chunks of the given knowledge items.
+	// Returns the IDs of chunks whose state changed.
+	UpdateChunkEnabledByKnowledgeIDs(ctx context.Context, tenantID uint64, knowledgeIDs []string, isEnabled bool) ([]string, error)
 	// FAQChunkDiff compares FAQ chunks between two knowledge bases and returns the differences.
 	// Returns: chunksToAdd (content_hash in src but not in dst), chunksToDelete (content_hash in dst but not in src)
 	FAQChunkDiff(ctx context.Context, srcTenantID uint64, srcKBID string, dstTenantID uint64, dstKBID string) (chunksToAdd []string, chunksToDelete []string, err error)
@@ -88,12 +97,14 @@ type ChunkService interface {
 	GetChunkByID(ctx context.Context, id string) (*types.Chunk, error)
 	// ListChunksByKnowledgeID lists chunks by knowledge id
 	ListChunksByKnowledgeID(ctx context.Context, knowledgeID string) ([]*types.Chunk, error)
-	// ListPagedChunksByKnowledgeID lists paged chunks by knowledge id
+	// ListPagedChunksByKnowledgeID lists paged chunks by knowledge id.
+	// When keyword is non-empty, only chunks whose content contains it are listed.
 	ListPagedChunksByKnowledgeID(
 		ctx context.Context,
 		knowledgeID string,
 		page *types.Pagination,
 		chunkType []types.ChunkType,
+		keyword string,
 	) (*types.PageResult, error)
 	// UpdateChunk updates a chunk
 	UpdateChunk(ctx context.Context, chunk *types.Chunk) error
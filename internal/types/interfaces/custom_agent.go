@@ -37,11 +37,12 @@ type CustomAgentService interface {
 	ListAgents(ctx context.Context) ([]*types.CustomAgent, error)
 
 	// UpdateAgent updates agent information
+	// Edits to custom agents are saved as a draft version and reach live traffic once published
 	// Parameters:
 	//   - ctx: Context information
 	//   - agent: Agent object containing update information
 	// Returns:
-	//   - Updated agent object
+	//   - Agent object with the edits applied
 	//   - Possible errors such as not existing, insufficient permissions, cannot modify built-in, etc.
 	UpdateAgent(ctx context.Context, agent *types.CustomAgent) (*types.CustomAgent, error)
 
@@ -61,6 +62,65 @@ type CustomAgentService interface {
 	//   - The newly created agent copy
 	//   - Possible errors such as not existing, insufficient permissions, etc.
 	CopyAgent(ctx context.Context, id string) (*types.CustomAgent, error)
+
+	// GetPublishedAgent retrieves the agent as it should serve live traffic
+	// Parameters:
+	//   - ctx: Context information
+	//   - id: Unique identifier of the agent
+	// Returns:
+	//   - Agent object with the published version applied (built-in and unversioned agents are returned as stored)
+	//   - Possible errors such as not existing, insufficient permissions, etc.
+	GetPublishedAgent(ctx context.Context, id string) (*types.CustomAgent, error)
+
+	// GetAgentAtVersion retrieves a custom agent with a specific version applied
+	// Parameters:
+	//   - ctx: Context information
+	//   - id: Unique identifier of the agent
+	//   - version: Version number to apply
+	// Returns:
+	//   - Agent object as it looks in the given version
+	//   - Possible errors such as version not existing, built-in agent, etc.
+	GetAgentAtVersion(ctx context.Context, id string, version int) (*types.CustomAgent, error)
+
+	// ListVersions lists all versions of a custom agent
+	// Parameters:
+	//   - ctx: Context information
+	//   - id: Unique identifier of the agent
+	// Returns:
+	//   - List of versions, newest first
+	//   - Possible errors such as not existing, built-in agent, etc.
+	ListVersions(ctx context.Context, id string) ([]*types.CustomAgentVersion, error)
+
+	// DiffVersions compares two versions of a custom agent
+	// Parameters:
+	//   - ctx: Context information
+	//   - id: Unique identifier of the agent
+	//   - fromVersion: Base version number
+	//   - toVersion: Target version number
+	// Returns:
+	//   - Field-level differences between the two versions
+	//   - Possible errors such as version not existing, etc.
+	DiffVersions(ctx context.Context, id string, fromVersion, toVersion int) (*types.CustomAgentVersionDiff, error)
+
+	// PublishVersion promotes a version to serve live traffic
+	// Parameters:
+	//   - ctx: Context information
+	//   - id: Unique identifier of the agent
+	//   - version: Version number to publish
+	// Returns:
+	//   - Agent object with the published version applied
+	//   - Possible errors such as version not existing, built-in agent, etc.
+	PublishVersion(ctx context.Context, id string, version int) (*types.CustomAgent, error)
+
+	// RollbackAgent reverts live traffic to a previously published version
+	// Parameters:
+	//   - ctx: Context information
+	//   - id: Unique identifier of the agent
+	//   - version: Previously published version to restore (0 = the one published before the current version)
+	// Returns:
+	//   - Agent object with the restored version applied
+	//   - Possible errors such as no earlier published version, etc.
+	RollbackAgent(ctx context.Context, id string, version int) (*types.CustomAgent, error)
 }
 
 // CustomAgentRepository defines the custom agent repository interface
@@ -109,4 +169,54 @@ type CustomAgentRepository interface {
 	// Returns:
 	//   - Possible errors such as record not existing, database errors, etc.
 	DeleteAgent(ctx context.Context, id string, tenantID uint64) error
+
+	// CreateVersion creates an agent version record
+	// Parameters:
+	//   - ctx: Context information
+	//   - version: Version snapshot
+	// Returns:
+	//   - Possible errors such as unique constraint conflicts, database errors, etc.
+	CreateVersion(ctx context.Context, version *types.CustomAgentVersion) error
+
+	// GetVersion queries a specific version of an agent
+	// Parameters:
+	//   - ctx: Context information
+	//   - agentID: Agent ID
+	//   - tenantID: Tenant ID for isolation
+	//   - version: Version number
+	// Returns:
+	//   - Version object, if found
+	//   - Possible errors such as record not existing, database errors, etc.
+	GetVersion(ctx context.Context, agentID string, tenantID uint64, version int) (*types.CustomAgentVersion, error)
+
+	// ListVersions lists all versions of an agent, newest first
+	// Parameters:
+	//   - ctx: Context information
+	//   - agentID: Agent ID
+	//   - tenantID: Tenant ID for isolation
+	// Returns:
+	//   - List of version objects
+	//   - Possible errors such as database errors, etc.
+	ListVersions(ctx context.Context, agentID string, tenantID uint64) ([]*types.CustomAgentVersion, error)
+
+	// GetLatestVersionNumber returns the highest version number of an agent
+	// Parameters:
+	//   - ctx: Context information
+	//   - agentID: Agent ID
+	//   - tenantID: Tenant ID for isolation
+	// Returns:
+	//   - Latest version number (0 if the agent has no versions)
+	//   - Possible errors such as database errors, etc.
+	GetLatestVersionNumber(ctx context.Context, agentID string, tenantID uint64) (int, error)
+
+	// PublishVersion marks a version as published, retires the previous one and copies the version's
+	// snapshot into the agent record, which is the only way edits reach the live agent
+	// Parameters:
+	//   - ctx: Context information
+	//   - agentID: Agent ID
+	//   - tenantID: Tenant ID for isolation
+	//   - version: Version number to publish
+	// Returns:
+	//   - Possible errors such as record not existing, database errors, etc.
+	PublishVersion(ctx context.Context, agentID string, tenantID uint64, version int) error
 }
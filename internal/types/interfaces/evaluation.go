@@ -10,10 +10,17 @@ import (
 type EvaluationService interface {
 	// Evaluation starts a new evaluation task
 	Evaluation(ctx context.Context, datasetID string, knowledgeBaseID string,
-		chatModelID string, rerankModelID string,
+		chatModelID string, rerankModelID string, limits types.EvaluationLimits,
 	) (*types.EvaluationDetail, error)
 	// EvaluationResult retrieves evaluation result by task ID
 	EvaluationResult(ctx context.Context, taskID string) (*types.EvaluationDetail, error)
+	// EvaluationEvents returns the stream events of a task after the given sequence number,
+	// and a channel closed when more events are available (nil once the summary was emitted)
+	EvaluationEvents(ctx context.Context, taskID string, afterSeq int) ([]*types.EvaluationEvent, <-chan struct{}, error)
+	// PauseEvaluation stops a task from starting new QA pairs
+	PauseEvaluation(ctx context.Context, taskID string) (*types.EvaluationDetail, error)
+	// ResumeEvaluation continues a paused task
+	ResumeEvaluation(ctx context.Context, taskID string) (*types.EvaluationDetail, error)
 }
 
 // Metrics defines interface for computing evaluation metrics
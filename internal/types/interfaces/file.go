@@ -11,6 +11,9 @@ import (
 type FileService interface {
 	// SaveFile saves a file.
 	SaveFile(ctx context.Context, file *multipart.FileHeader, tenantID uint64, knowledgeID string) (string, error)
+	// SaveStream saves a file read from content as it arrives, without buffering it in memory.
+	// fileName is only used for the extension of the stored file. Nothing is left in storage when it fails.
+	SaveStream(ctx context.Context, content io.Reader, tenantID uint64, knowledgeID string, fileName string) (string, error)
 	// SaveBytes saves bytes data to a file and returns the file path.
 	// If temp is true, the file will be saved to a temporary storage that may auto-expire.
 	SaveBytes(ctx context.Context, data []byte, tenantID uint64, fileName string, temp bool) (string, error)
@@ -20,4 +23,7 @@ type FileService interface {
 	GetFileURL(ctx context.Context, filePath string) (string, error)
 	// DeleteFile deletes a file.
 	DeleteFile(ctx context.Context, filePath string) error
+	// DeleteTenantFiles deletes every file stored for the tenant and returns the number of deleted files.
+	// Files that are already gone are skipped, so it can be retried after a failure.
+	DeleteTenantFiles(ctx context.Context, tenantID uint64) (int, error)
 }
@@ -0,0 +1,12 @@
+package interfaces
+
+import "context"
+
+// HealthChecker checks a dependency the service needs to serve traffic. Checkers are provided to the
+// container in the "health_checkers" group and run by the readiness probe.
+type HealthChecker interface {
+	// Name identifies the subsystem in the readiness report
+	Name() string
+	// Check returns an error when the subsystem is unreachable or unusable
+	Check(ctx context.Context) error
+}
@@ -22,6 +22,27 @@ type KnowledgeService interface {
 		customFileName string,
 		tagID string,
 	) (*types.Knowledge, error)
+	// StageKnowledgeFile spools an uploaded file to a temporary file for a new knowledge of kbID,
+	// computing its hash on the fly. The file must then be passed to CreateKnowledgeFromStagedFile
+	// or DiscardStagedKnowledgeFile.
+	StageKnowledgeFile(ctx context.Context, kbID string, fileName string, content io.Reader) (*types.StagedKnowledgeFile, error)
+	// CreateKnowledgeFromStagedFile checks a staged file for duplicates, saves it to storage, creates its
+	// knowledge and enqueues its processing. Nothing is left in storage when the knowledge is not created.
+	CreateKnowledgeFromStagedFile(
+		ctx context.Context,
+		kbID string,
+		file *types.StagedKnowledgeFile,
+		metadata map[string]string,
+		enableMultimodel *bool,
+		customFileName string,
+		tagID string,
+	) (*types.Knowledge, error)
+	// DiscardStagedKnowledgeFile removes a staged file whose knowledge will not be created.
+	DiscardStagedKnowledgeFile(ctx context.Context, file *types.StagedKnowledgeFile)
+	// GetSupportedFileFormats lists the file formats that can be uploaded.
+	// When kbID is non-empty, availability reflects that knowledge base's multimodal configuration,
+	// otherwise whether the tenant has any VLM model.
+	GetSupportedFileFormats(ctx context.Context, kbID string) ([]types.FileFormat, error)
 	// CreateKnowledgeFromURL creates knowledge from a URL.
 	// tagID is optional - when provided, the knowledge will be assigned to the specified tag/category.
 	CreateKnowledgeFromURL(
@@ -52,6 +73,7 @@ type KnowledgeService interface {
 	// When tagID is non-empty, results are filtered by tag_id.
 	// When keyword is non-empty, results are filtered by file_name.
 	// When fileType is non-empty, results are filtered by file_type or type.
+	// When isEnabled is non-nil, results are filtered by the retrieval enable flag.
 	ListPagedKnowledgeByKnowledgeBaseID(
 		ctx context.Context,
 		kbID string,
@@ -59,6 +81,7 @@ type KnowledgeService interface {
 		tagID string,
 		keyword string,
 		fileType string,
+		isEnabled *bool,
 	) (*types.PageResult, error)
 	// DeleteKnowledge deletes knowledge by ID.
 	DeleteKnowledge(ctx context.Context, id string) error
@@ -96,13 +119,23 @@ type KnowledgeService interface {
 	// Returns task ID (Knowledge ID) for tracking import progress.
 	UpsertFAQEntries(ctx context.Context, kbID string, payload *types.FAQBatchUpsertPayload) (string, error)
 	// CreateFAQEntry creates a single FAQ entry synchronously.
-	CreateFAQEntry(ctx context.Context, kbID string, payload *types.FAQEntryPayload) (*types.FAQEntry, error)
+	// Duplicates of existing entries are handled according to options and the decision is returned.
+	CreateFAQEntry(ctx context.Context, kbID string, payload *types.FAQEntryPayload,
+		options types.FAQDuplicateOptions) (*types.FAQEntry, *types.FAQDuplicateDecision, error)
 	// GetFAQEntry retrieves a single FAQ entry by seq_id.
 	GetFAQEntry(ctx context.Context, kbID string, entrySeqID int64) (*types.FAQEntry, error)
 	// UpdateFAQEntry updates a single FAQ entry.
 	UpdateFAQEntry(ctx context.Context, kbID string, entrySeqID int64, payload *types.FAQEntryPayload) (*types.FAQEntry, error)
 	// AddSimilarQuestions adds similar questions to a FAQ entry.
 	AddSimilarQuestions(ctx context.Context, kbID string, entrySeqID int64, questions []string) (*types.FAQEntry, error)
+	// GenerateFAQSimilarQuestions queues the generation of similar questions for FAQ entries as a tracked task.
+	GenerateFAQSimilarQuestions(ctx context.Context, kbID string,
+		req *types.FAQSimilarGenerationRequest) (*types.FAQSimilarGenerationProgress, error)
+	// GetFAQSimilarGenerationProgress retrieves the progress and suggestions of a similar question generation task.
+	GetFAQSimilarGenerationProgress(ctx context.Context, kbID, taskID string) (*types.FAQSimilarGenerationProgress, error)
+	// ApplyFAQSimilarQuestions adds the previewed suggestions of a completed generation task to their entries.
+	ApplyFAQSimilarQuestions(ctx context.Context, kbID, taskID string,
+		req *types.FAQSimilarApplyRequest) (*types.FAQSimilarGenerationProgress, error)
 	// UpdateFAQEntryFieldsBatch updates multiple fields for FAQ entries in batch.
 	// Supports updating is_enabled, is_recommended, tag_id, and other fields in a single call.
 	UpdateFAQEntryFieldsBatch(ctx context.Context, kbID string, req *types.FAQEntryFieldsBatchUpdate) error
@@ -110,10 +143,14 @@ type KnowledgeService interface {
 	DeleteFAQEntries(ctx context.Context, kbID string, entrySeqIDs []int64) error
 	// SearchFAQEntries searches FAQ entries using hybrid search.
 	SearchFAQEntries(ctx context.Context, kbID string, req *types.FAQSearchRequest) ([]*types.FAQEntry, error)
-	// ExportFAQEntries exports all FAQ entries for a knowledge base as CSV data.
-	ExportFAQEntries(ctx context.Context, kbID string) ([]byte, error)
+	// ExportFAQEntries streams the FAQ entries of a knowledge base to w as CSV or JSON Lines,
+	// optionally filtered by tag and enabled status. Nothing is written when the request is invalid.
+	ExportFAQEntries(ctx context.Context, kbID string, req *types.FAQExportRequest, w io.Writer) error
 	// UpdateKnowledgeTagBatch updates tag for document knowledge items in batch.
 	UpdateKnowledgeTagBatch(ctx context.Context, updates map[string]*string) error
+	// UpdateKnowledgeEnabledBatch enables or disables document knowledge items for retrieval in batch.
+	// Key: knowledge ID, Value: whether the knowledge is retrievable
+	UpdateKnowledgeEnabledBatch(ctx context.Context, updates map[string]bool) error
 	// UpdateFAQEntryTagBatch updates tag for FAQ entries in batch.
 	// Key: entry seq_id, Value: tag seq_id (nil to remove tag)
 	UpdateFAQEntryTagBatch(ctx context.Context, kbID string, updates map[int64]*int64) error
@@ -135,6 +172,30 @@ type KnowledgeService interface {
 	GetKBCloneProgress(ctx context.Context, taskID string) (*types.KBCloneProgress, error)
 	// SaveKBCloneProgress saves the progress of a knowledge base clone task
 	SaveKBCloneProgress(ctx context.Context, progress *types.KBCloneProgress) error
+	// ExtractChatAttachments parses the attachments of a chat message into transient chunks
+	// without storing them in any knowledge base
+	ExtractChatAttachments(ctx context.Context, knowledgeBaseIDs []string,
+		attachments []*types.ChatAttachment) ([]*types.SearchResult, error)
+	// ReprocessFailedKnowledge re-queues all failed knowledge in a knowledge base as one tracked task
+	ReprocessFailedKnowledge(ctx context.Context, kbID string, filter *types.KnowledgeFailureFilter) (*types.KnowledgeReprocessProgress, error)
+	// ProcessKnowledgeReprocess handles Asynq batch reprocess tasks
+	ProcessKnowledgeReprocess(ctx context.Context, t *asynq.Task) error
+	// GetKnowledgeReprocessProgress retrieves the progress of a batch reprocess task
+	GetKnowledgeReprocessProgress(ctx context.Context, taskID string) (*types.KnowledgeReprocessProgress, error)
+	// MergeKnowledgeBases queues the merge of a knowledge base into another as one tracked task
+	MergeKnowledgeBases(ctx context.Context, req *types.KBMergeRequest) (*types.KBMergeProgress, error)
+	// ProcessKBMerge handles Asynq knowledge base merge tasks
+	ProcessKBMerge(ctx context.Context, t *asynq.Task) error
+	// ExportKnowledgeBase streams a portable archive of the knowledge base to w
+	ExportKnowledgeBase(ctx context.Context, kbID string, w io.Writer) error
+	// ImportKnowledgeBase queues the import of a knowledge base archive into a new knowledge base
+	ImportKnowledgeBase(ctx context.Context, archive io.ReadSeeker, req *types.KBImportRequest) (*types.KBCloneProgress, error)
+	// ProcessKBImport handles Asynq knowledge base import tasks
+	ProcessKBImport(ctx context.Context, t *asynq.Task) error
+	// ProcessFAQSimilarGeneration handles Asynq FAQ similar question generation tasks
+	ProcessFAQSimilarGeneration(ctx context.Context, t *asynq.Task) error
+	// GetKBMergeProgress retrieves the progress of a knowledge base merge task
+	GetKBMergeProgress(ctx context.Context, taskID string) (*types.KBMergeProgress, error)
 	// GetFAQImportProgress retrieves the progress of an FAQ import task
 	GetFAQImportProgress(ctx context.Context, taskID string) (*types.FAQImportProgress, error)
 	// UpdateLastFAQImportResultDisplayStatus updates the display status of FAQ import result
@@ -149,16 +210,22 @@ type KnowledgeRepository interface {
 	CreateKnowledge(ctx context.Context, knowledge *types.Knowledge) error
 	GetKnowledgeByID(ctx context.Context, tenantID uint64, id string) (*types.Knowledge, error)
 	ListKnowledgeByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) ([]*types.Knowledge, error)
+	// ListAllKnowledgeByKnowledgeBaseID lists all knowledge in a knowledge base, including soft-deleted knowledge
+	ListAllKnowledgeByKnowledgeBaseID(ctx context.Context, tenantID uint64, kbID string) ([]*types.Knowledge, error)
 	// ListPagedKnowledgeByKnowledgeBaseID lists all knowledge in a knowledge base with pagination.
 	// When tagID is non-empty, results are filtered by tag_id.
 	// When keyword is non-empty, results are filtered by file_name.
 	// When fileType is non-empty, results are filtered by file_type or type.
+	// When isEnabled is non-nil, results are filtered by is_enabled.
 	ListPagedKnowledgeByKnowledgeBaseID(ctx context.Context,
 		tenantID uint64, kbID string, page *types.Pagination, tagID string, keyword string, fileType string,
+		isEnabled *bool,
 	) ([]*types.Knowledge, int64, error)
 	UpdateKnowledge(ctx context.Context, knowledge *types.Knowledge) error
 	// UpdateKnowledgeBatch updates knowledge items in batch
 	UpdateKnowledgeBatch(ctx context.Context, knowledgeList []*types.Knowledge) error
+	// UpdateKnowledgeEnabled sets the retrieval enable flag of knowledge items
+	UpdateKnowledgeEnabled(ctx context.Context, tenantID uint64, ids []string, isEnabled bool) error
 	DeleteKnowledge(ctx context.Context, tenantID uint64, id string) error
 	DeleteKnowledgeList(ctx context.Context, tenantID uint64, ids []string) error
 	GetKnowledgeBatch(ctx context.Context, tenantID uint64, ids []string) ([]*types.Knowledge, error)
@@ -184,4 +251,8 @@ type KnowledgeRepository interface {
 	SearchKnowledge(ctx context.Context, tenantID uint64, keyword string, offset, limit int, fileTypes []string) ([]*types.Knowledge, bool, error)
 	// ListIDsByTagID returns all knowledge IDs that have the specified tag ID.
 	ListIDsByTagID(ctx context.Context, tenantID uint64, kbID, tagID string) ([]string, error)
+	// ListIDsByMetadata returns all knowledge IDs whose metadata has all the given values.
+	ListIDsByMetadata(ctx context.Context, tenantID uint64, kbID string, filter map[string]string) ([]string, error)
+	// ListFailedKnowledge lists failed knowledge in a knowledge base matching the filter.
+	ListFailedKnowledge(ctx context.Context, tenantID uint64, kbID string, filter *types.KnowledgeFailureFilter) ([]*types.Knowledge, error)
 }
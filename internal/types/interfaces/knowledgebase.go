@@ -58,9 +58,10 @@ type KnowledgeBaseService interface {
 	// Parameters:
 	//   - ctx: Context information
 	//   - id: Unique identifier of the knowledge base
+	//   - force: Remove the knowledge base from the agents that use it instead of failing
 	// Returns:
-	//   - Possible errors such as not existing, insufficient permissions, etc.
-	DeleteKnowledgeBase(ctx context.Context, id string) error
+	//   - Possible errors such as not existing, insufficient permissions, still in use, etc.
+	DeleteKnowledgeBase(ctx context.Context, id string, force bool) error
 
 	// HybridSearch performs hybrid search (vector + keywords) in the knowledge base
 	// Parameters:
@@ -72,6 +73,41 @@ type KnowledgeBaseService interface {
 	//   - Possible errors such as not existing, insufficient permissions, search engine errors, etc.
 	HybridSearch(ctx context.Context, id string, params types.SearchParams) ([]*types.SearchResult, error)
 
+	// MultiHybridSearch performs a hybrid search in each of several knowledge bases of the tenant, at most
+	// concurrency at once, and merges the results into a single ranking
+	// Parameters:
+	//   - ctx: Context information, containing tenant information
+	//   - ids: Knowledge bases to search, which must all belong to the tenant
+	//   - params: Search parameters applied to every knowledge base; MatchCount bounds the merged results
+	//   - concurrency: Number of knowledge bases searched at once
+	// Returns:
+	//   - Merged results keeping the score given by their knowledge base, annotated with it
+	//   - Possible errors such as a knowledge base not found in the tenant, search engine errors, etc.
+	MultiHybridSearch(ctx context.Context,
+		ids []string, params types.SearchParams, concurrency int,
+	) ([]*types.SearchResult, error)
+
+	// SimilarChunks returns the chunks of the source chunk's knowledge base most similar to it by embedding,
+	// excluding the source chunk itself
+	SimilarChunks(ctx context.Context, source *types.Chunk, params types.SimilarChunkParams) ([]*types.SearchResult, error)
+
+	// ListPinnedSources lists the pinned source rules of a knowledge base
+	ListPinnedSources(ctx context.Context, kbID string) ([]*types.PinnedSourceRule, error)
+
+	// CreatePinnedSource adds a pinned source rule to a knowledge base
+	// Returns:
+	//   - Created rule with its generated ID
+	//   - Possible errors such as invalid patterns or knowledge outside the knowledge base
+	CreatePinnedSource(ctx context.Context, kbID string, rule *types.PinnedSourceRule) (*types.PinnedSourceRule, error)
+
+	// UpdatePinnedSource replaces a pinned source rule of a knowledge base
+	UpdatePinnedSource(ctx context.Context,
+		kbID string, ruleID string, rule *types.PinnedSourceRule,
+	) (*types.PinnedSourceRule, error)
+
+	// DeletePinnedSource removes a pinned source rule from a knowledge base
+	DeletePinnedSource(ctx context.Context, kbID string, ruleID string) error
+
 	// CopyKnowledgeBase copies a knowledge base
 	// Parameters:
 	//   - ctx: Context information
@@ -146,6 +182,16 @@ type KnowledgeBaseRepository interface {
 	//   - Possible errors such as database errors, etc.
 	ListKnowledgeBasesByTenantID(ctx context.Context, tenantID uint64) ([]*types.KnowledgeBase, error)
 
+	// ListAllKnowledgeBasesByTenantID lists all knowledge bases of a tenant, including temporary and
+	// soft-deleted ones
+	// Parameters:
+	//   - ctx: Context information
+	//   - tenantID: Tenant ID
+	// Returns:
+	//   - List of knowledge base objects
+	//   - Possible errors such as database errors, etc.
+	ListAllKnowledgeBasesByTenantID(ctx context.Context, tenantID uint64) ([]*types.KnowledgeBase, error)
+
 	// UpdateKnowledgeBase updates a knowledge base record
 	// Parameters:
 	//   - ctx: Context information
@@ -20,12 +20,18 @@ type MCPServiceRepository interface {
 	// ListEnabled retrieves all enabled MCP services for a tenant
 	ListEnabled(ctx context.Context, tenantID uint64) ([]*types.MCPService, error)
 
+	// ListAllEnabled retrieves the enabled MCP services of every tenant
+	ListAllEnabled(ctx context.Context) ([]*types.MCPService, error)
+
 	// ListByIDs retrieves MCP services by multiple IDs for a tenant
 	ListByIDs(ctx context.Context, tenantID uint64, ids []string) ([]*types.MCPService, error)
 
 	// Update updates an MCP service
 	Update(ctx context.Context, service *types.MCPService) error
 
+	// UpdateHealth stores the result of the last validation of the tools of an MCP service
+	UpdateHealth(ctx context.Context, tenantID uint64, id string, health *types.MCPServiceHealth) error
+
 	// Delete deletes an MCP service (soft delete)
 	Delete(ctx context.Context, tenantID uint64, id string) error
 }
@@ -58,4 +64,13 @@ type MCPServiceService interface {
 
 	// GetMCPServiceResources retrieves the list of resources from an MCP service
 	GetMCPServiceResources(ctx context.Context, tenantID uint64, id string) ([]*types.MCPResource, error)
+
+	// CheckMCPServiceHealth validates the tools advertised by an MCP service and stores the result
+	CheckMCPServiceHealth(ctx context.Context, tenantID uint64, id string) (*types.MCPServiceHealth, error)
+
+	// CheckAllMCPServices validates the tools of the enabled MCP services of every tenant
+	CheckAllMCPServices(ctx context.Context)
+
+	// GetAgentMCPStatus reports the problems of the MCP services used by an agent
+	GetAgentMCPStatus(ctx context.Context, tenantID uint64, agent *types.CustomAgent) (*types.AgentMCPStatus, error)
 }
@@ -26,6 +26,15 @@ type MessageService interface {
 		ctx context.Context, sessionID string, beforeTime time.Time, limit int,
 	) ([]*types.Message, error)
 
+	// GetMessagesBySessionBeforeCursor gets the messages of a session strictly before the cursor, newest first.
+	// A nil cursor starts from the latest message.
+	GetMessagesBySessionBeforeCursor(
+		ctx context.Context, sessionID string, cursor *types.MessageCursor, limit int,
+	) ([]*types.Message, error)
+
+	// GetUserMessageByRequestID gets the user message of a conversation turn
+	GetUserMessageByRequestID(ctx context.Context, sessionID string, requestID string) (*types.Message, error)
+
 	// UpdateMessage updates a message
 	UpdateMessage(ctx context.Context, message *types.Message) error
 
@@ -19,8 +19,12 @@ type ModelService interface {
 	ListModels(ctx context.Context) ([]*types.Model, error)
 	// UpdateModel updates a model
 	UpdateModel(ctx context.Context, model *types.Model) error
-	// DeleteModel deletes a model
-	DeleteModel(ctx context.Context, id string) error
+	// DeleteModel deletes a model, force removes its references from knowledge bases and agents
+	DeleteModel(ctx context.Context, id string, force bool) error
+	// DeleteModels deletes models in batch, keeping the models that are still in use
+	DeleteModels(ctx context.Context, ids []string) ([]*types.ModelBatchResult, error)
+	// GetModelReferences returns the knowledge bases and agents that use each model
+	GetModelReferences(ctx context.Context, ids []string) (map[string][]types.ResourceReference, error)
 	// GetEmbeddingModel gets an embedding model
 	GetEmbeddingModel(ctx context.Context, modelId string) (embedding.Embedder, error)
 	// GetRerankModel gets a rerank model
@@ -49,4 +53,6 @@ type ModelRepository interface {
 	// ClearDefaultByType clears the default flag for all models of a specific type
 	// optionally excluding a specific model ID.
 	ClearDefaultByType(ctx context.Context, tenantID uint, modelType types.ModelType, excludeID string) error
+	// ListActiveByType lists the most recently updated active models of a type across all tenants
+	ListActiveByType(ctx context.Context, modelType types.ModelType, limit int) ([]*types.Model, error)
 }
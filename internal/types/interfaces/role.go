@@ -0,0 +1,31 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// RoleService defines the service of the user roles of tenants
+type RoleService interface {
+	// ResolveRole returns the role of a logged-in user in a tenant
+	ResolveRole(ctx context.Context, tenantID uint64, user *types.User) (string, error)
+	// ListAssignments lists the role assignments of the current tenant
+	ListAssignments(ctx context.Context) ([]*types.UserRole, error)
+	// AssignRole assigns a role to a user of the current tenant
+	AssignRole(ctx context.Context, userID string, role string) (*types.UserRole, error)
+	// RemoveRole removes the role assignment of a user of the current tenant, who gets the default role again
+	RemoveRole(ctx context.Context, userID string) error
+}
+
+// RoleRepository defines the repository of the role assignments
+type RoleRepository interface {
+	// GetAssignment gets the role assignment of a user in a tenant, nil when the user has none
+	GetAssignment(ctx context.Context, tenantID uint64, userID string) (*types.UserRole, error)
+	// ListAssignments lists the role assignments of a tenant
+	ListAssignments(ctx context.Context, tenantID uint64) ([]*types.UserRole, error)
+	// UpsertAssignment creates or replaces the role assignment of a user in a tenant
+	UpsertAssignment(ctx context.Context, assignment *types.UserRole) error
+	// DeleteAssignment deletes the role assignment of a user in a tenant
+	DeleteAssignment(ctx context.Context, tenantID uint64, userID string) error
+}
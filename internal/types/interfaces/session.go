@@ -34,11 +34,24 @@ type SessionService interface {
 	// summaryModelID: optional summary model ID override (if empty, uses session/KB default)
 	// webSearchEnabled: whether to enable web search to supplement knowledge base results
 	// customAgent: optional custom agent for config override (multiTurnEnabled, historyTurns)
+	// attachments: optional files/text/images used as context for this turn only, never stored in a KB
+	// historyDepth: optional cap on prior turns in the prompt (0 = no history), overrides the KB setting
+	// retrieval: optional retrieval parameters (session defaults merged with request overrides),
+	// taking precedence over the agent's retrieval settings
 	// Events are emitted through eventBus (references, answer chunks, completion)
 	KnowledgeQA(ctx context.Context,
 		session *types.Session, query string, knowledgeBaseIDs []string, knowledgeIDs []string,
 		assistantMessageID string, summaryModelID string, webSearchEnabled bool, eventBus *event.EventBus,
-		customAgent *types.CustomAgent,
+		customAgent *types.CustomAgent, attachments []*types.ChatAttachment, historyDepth *int,
+		retrieval *types.RetrievalParams,
+	) error
+	// RegenerateAnswer generates a new answer for an earlier turn from the references stored with
+	// sourceMessage, without running retrieval again
+	// summaryModelID and temperature optionally override the model and its temperature
+	// Events are emitted through eventBus (references, answer chunks, completion)
+	RegenerateAnswer(ctx context.Context,
+		session *types.Session, userMessage *types.Message, sourceMessage *types.Message,
+		assistantMessageID string, summaryModelID string, temperature *float64, eventBus *event.EventBus,
 	) error
 	// KnowledgeQAByEvent performs knowledge-based question answering by event
 	KnowledgeQAByEvent(ctx context.Context, chatManage *types.ChatManage, eventList []types.EventType) error
@@ -46,10 +59,22 @@ type SessionService interface {
 	// knowledgeBaseIDs: list of knowledge base IDs to search (supports multi-KB)
 	// knowledgeIDs: list of specific knowledge (file) IDs to search
 	SearchKnowledge(ctx context.Context, knowledgeBaseIDs []string, knowledgeIDs []string, query string) ([]*types.SearchResult, error)
+	// EmptyKnowledgeBaseMessage reports whether none of the given knowledge bases or knowledge has enabled
+	// chunks, and if so returns the message to answer with
+	EmptyKnowledgeBaseMessage(ctx context.Context, knowledgeBaseIDs []string, knowledgeIDs []string) (string, bool)
+	// ExplainSearchResults generates a short natural-language explanation of why the top search results
+	// are relevant to the query, using only the given results
+	ExplainSearchResults(ctx context.Context, knowledgeBaseIDs []string, knowledgeIDs []string,
+		query string, results []*types.SearchResult) (string, error)
+	// EvaluateConfidenceGate evaluates the confidence gate of a knowledge base against labeled queries
+	// override is optional - if provided, replaces the knowledge base policy
+	EvaluateConfidenceGate(ctx context.Context, knowledgeBaseID string, cases []types.ConfidenceEvalCase,
+		override *types.ConfidenceGateConfig) (*types.ConfidenceEvalReport, error)
 	// AgentQA performs agent-based question answering with conversation history and streaming support
 	// eventBus is optional - if nil, uses service's default EventBus
 	// customAgent is optional - if provided, uses custom agent configuration instead of tenant defaults
 	// summaryModelID is optional - if provided, overrides the model from customAgent config
+	// historyDepth is optional - 0 answers without history; positive depths are left to context compression
 	AgentQA(
 		ctx context.Context,
 		session *types.Session,
@@ -60,7 +85,11 @@ type SessionService interface {
 		customAgent *types.CustomAgent,
 		knowledgeBaseIDs []string,
 		knowledgeIDs []string,
+		historyDepth *int,
 	) error
+	// ReplayAgentRun re-executes a recorded agent run without streaming or writing session history
+	// customAgent is optional - if provided, its config is used instead of the recorded config
+	ReplayAgentRun(ctx context.Context, original *types.AgentRun, customAgent *types.CustomAgent) (*types.AgentRun, error)
 	// ClearContext clears the LLM context for a session
 	ClearContext(ctx context.Context, sessionID string) error
 }
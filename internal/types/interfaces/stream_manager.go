@@ -2,11 +2,15 @@ package interfaces
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/Tencent/WeKnora/internal/types"
 )
 
+// ErrStreamEventsExpired is returned when events before the requested offset are no longer buffered
+var ErrStreamEventsExpired = errors.New("stream events are no longer buffered")
+
 // StreamEvent represents a single event in the stream
 type StreamEvent struct {
 	ID        string                 `json:"id"`             // Unique event ID
@@ -26,7 +30,9 @@ type StreamManager interface {
 	AppendEvent(ctx context.Context, sessionID, messageID string, event StreamEvent) error
 
 	// GetEvents gets events starting from offset
+	// Offsets are positions in the whole stream and keep increasing when old events are dropped from the buffer
 	// Uses Redis LRange for incremental reads
 	// Returns: events slice, next offset for subsequent reads, error
+	// When the offset precedes the buffered events, returns ErrStreamEventsExpired and the first buffered offset
 	GetEvents(ctx context.Context, sessionID, messageID string, fromOffset int) ([]StreamEvent, int, error)
 }
@@ -0,0 +1,21 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// TaskWebhookService notifies webhooks when asynchronous tasks finish
+type TaskWebhookService interface {
+	// RegisterTaskWebhook sets the URL notified when the task finishes, instead of the webhook of the tenant
+	// in the context. Fails when the URL is unsafe or the tenant has no webhook secret to sign with.
+	RegisterTaskWebhook(ctx context.Context, taskID, url string) error
+
+	// NotifyTaskFinished posts the event to the webhook of the task or of its tenant without blocking.
+	// Only the first notification of a task is delivered.
+	NotifyTaskFinished(ctx context.Context, event *types.TaskWebhookEvent)
+
+	// GetTaskWebhookDelivery returns the last webhook delivery of the task, nil when there was none
+	GetTaskWebhookDelivery(ctx context.Context, taskID string) (*types.TaskWebhookDelivery, error)
+}
@@ -2,8 +2,10 @@ package interfaces
 
 import (
 	"context"
+	"io"
 
 	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/hibiken/asynq"
 )
 
 // TenantService defines the tenant service interface
@@ -30,6 +32,34 @@ type TenantService interface {
 	GetTenantByIDForUser(ctx context.Context, tenantID uint64, userID string) (*types.Tenant, error)
 }
 
+// TenantDataService exports and purges all data of a tenant
+type TenantDataService interface {
+	// ExportTenantData queues an export of all data of the tenant
+	ExportTenantData(ctx context.Context, tenantID uint64) (*types.TenantExportProgress, error)
+	// ResumeTenantExport queues a failed or interrupted export again, keeping its completed parts
+	ResumeTenantExport(ctx context.Context, tenantID uint64, taskID string) (*types.TenantExportProgress, error)
+	// GetTenantExportProgress gets the progress of an export, with its manifest once completed
+	GetTenantExportProgress(ctx context.Context, tenantID uint64, taskID string) (*types.TenantExportProgress, error)
+	// GetTenantExportPart opens the archive of a completed part of an export
+	GetTenantExportPart(ctx context.Context,
+		tenantID uint64, taskID string, name string) (*types.TenantExportPart, io.ReadCloser, error)
+	// ConfirmTenantPurge issues the short-lived confirmation token that a purge of the tenant requires
+	ConfirmTenantPurge(ctx context.Context, tenantID uint64) (*types.TenantPurgeConfirmation, error)
+	// PurgeTenantData queues the permanent deletion of the tenant and all of its data, after checking the
+	// confirmation token
+	PurgeTenantData(ctx context.Context, tenantID uint64, confirmationToken string) (*types.TenantPurgeProgress, error)
+	// RetryTenantPurge queues a failed or interrupted purge again; with force the records are removed
+	// even if some data cannot be deleted
+	RetryTenantPurge(ctx context.Context,
+		tenantID uint64, taskID string, force bool) (*types.TenantPurgeProgress, error)
+	// GetTenantPurgeProgress gets the progress of a purge
+	GetTenantPurgeProgress(ctx context.Context, tenantID uint64, taskID string) (*types.TenantPurgeProgress, error)
+	// ProcessTenantExport handles tenant export tasks
+	ProcessTenantExport(ctx context.Context, t *asynq.Task) error
+	// ProcessTenantPurge handles tenant purge tasks
+	ProcessTenantPurge(ctx context.Context, t *asynq.Task) error
+}
+
 // TenantRepository defines the tenant repository interface
 type TenantRepository interface {
 	// CreateTenant creates a tenant
@@ -44,6 +74,8 @@ type TenantRepository interface {
 	UpdateTenant(ctx context.Context, tenant *types.Tenant) error
 	// DeleteTenant deletes a tenant
 	DeleteTenant(ctx context.Context, id uint64) error
+	// PurgeTenant permanently deletes the tenant and all of its records, returning the deleted count by table
+	PurgeTenant(ctx context.Context, id uint64) (map[string]int64, error)
 	// AdjustStorageUsed adjusts the storage used for a tenant
 	AdjustStorageUsed(ctx context.Context, tenantID uint64, delta int64) error
 }
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
 	"github.com/Tencent/WeKnora/internal/types"
 )
@@ -36,6 +37,17 @@ type UserService interface {
 	RevokeToken(ctx context.Context, token string) error
 	// GetCurrentUser gets current user from context
 	GetCurrentUser(ctx context.Context) (*types.User, error)
+	// SetupTwoFactor generates the TOTP secret of a user for an authenticator app
+	SetupTwoFactor(ctx context.Context, userID string) (*types.TwoFactorSetup, error)
+	// EnableTwoFactor enables two-factor authentication after verifying a TOTP code and returns the recovery codes
+	EnableTwoFactor(ctx context.Context, userID string, totpCode string) (*types.TwoFactorRecoveryCodes, error)
+	// DisableTwoFactor disables two-factor authentication after verifying the password and a code
+	DisableTwoFactor(ctx context.Context, userID string, req *types.TwoFactorDisableRequest) error
+	// RegenerateRecoveryCodes replaces the recovery codes of a user after verifying a TOTP code
+	RegenerateRecoveryCodes(ctx context.Context, userID string, totpCode string) (*types.TwoFactorRecoveryCodes, error)
+	// CheckTwoFactorLogin checks the TOTP or recovery code of a login of a user with two-factor authentication,
+	// returning the failed login response, or nil when the code was accepted
+	CheckTwoFactorLogin(ctx context.Context, user *types.User, totpCode, recoveryCode string) *types.LoginResponse
 }
 
 // UserRepository defines the user repository interface
@@ -54,6 +66,15 @@ type UserRepository interface {
 	DeleteUser(ctx context.Context, id string) error
 	// ListUsers lists users with pagination
 	ListUsers(ctx context.Context, offset, limit int) ([]*types.User, error)
+	// UseTOTPStep records the time step of a TOTP code accepted for a user and clears its failed two-factor
+	// attempts, unless a code of the same or a later step was accepted meanwhile, in which case it returns false
+	UseTOTPStep(ctx context.Context, id string, step int64) (bool, error)
+	// UseRecoveryCode replaces the recovery codes of a user with the remaining ones after a code was accepted
+	// and clears its failed two-factor attempts, unless the codes changed meanwhile, in which case it returns false
+	UseRecoveryCode(ctx context.Context, id string, codes, remaining types.StringArray) (bool, error)
+	// RecordTwoFactorFailure counts a wrong two-factor code of a user. The attempt reaching maxAttempts locks
+	// the two-factor authentication of the user until lockedUntil and starts a new count.
+	RecordTwoFactorFailure(ctx context.Context, id string, maxAttempts int, lockedUntil time.Time) error
 }
 
 // AuthTokenRepository defines the auth token repository interface
@@ -73,3 +94,26 @@ type AuthTokenRepository interface {
 	// RevokeTokensByUserID revokes all tokens for a user
 	RevokeTokensByUserID(ctx context.Context, userID string) error
 }
+
+// OIDCService defines the login with OpenID Connect identity providers
+type OIDCService interface {
+	// Providers lists the identity providers users can log in with
+	Providers() []*types.OIDCProviderInfo
+	// Authorize starts a login with a provider and returns the login page of the provider
+	Authorize(ctx context.Context, provider string) (*types.OIDCAuthorization, error)
+	// Login completes a login with the authorization code the provider sent the user back with, and
+	// returns tokens for the user of the identity, linking or creating the user on its first login
+	Login(ctx context.Context, provider string, req *types.OIDCCallbackRequest) (*types.LoginResponse, error)
+	// CompleteTwoFactor completes a login of a user with two-factor authentication with its TOTP or recovery code
+	CompleteTwoFactor(ctx context.Context, req *types.OIDCTwoFactorRequest) (*types.LoginResponse, error)
+}
+
+// UserIdentityRepository defines the repository of the links between users and identity provider accounts
+type UserIdentityRepository interface {
+	// GetIdentity gets the link of an account of a provider, nil when the account is not linked
+	GetIdentity(ctx context.Context, provider, subject string) (*types.UserIdentity, error)
+	// CreateIdentity links an account of a provider to a user
+	CreateIdentity(ctx context.Context, identity *types.UserIdentity) error
+	// DeleteIdentity unlinks an account of a provider
+	DeleteIdentity(ctx context.Context, id string) error
+}
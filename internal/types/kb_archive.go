@@ -0,0 +1,119 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// KBArchiveKind identifies knowledge base archives in their manifest
+const KBArchiveKind = "weknora.knowledge_base"
+
+// Schema versions of knowledge base archives. Archives with a version between the minimum and the current
+// one can be imported; older archives have to be migrated to the current layout while they are read.
+const (
+	KBArchiveSchemaVersion    = 1
+	KBArchiveMinSchemaVersion = 1
+)
+
+// Entries of a knowledge base archive, a gzip-compressed tar archive. The manifest comes first and the
+// knowledge base second, so that an archive can be checked without reading it all. Tags and knowledge
+// precede the chunks and original file of every knowledge, which are stored under the knowledge ID.
+const (
+	KBArchiveEntryManifest      = "manifest.json"
+	KBArchiveEntryKnowledgeBase = "knowledge_base.json"
+	KBArchiveEntryTags          = "tags.jsonl"
+	KBArchiveEntryKnowledge     = "knowledge.jsonl"
+	KBArchiveEntryFAQ           = "faq.jsonl"
+	KBArchiveDirChunks          = "chunks"
+	KBArchiveDirFiles           = "files"
+)
+
+// KBArchiveManifest describes a knowledge base archive
+type KBArchiveManifest struct {
+	Kind          string `json:"kind"`
+	SchemaVersion int    `json:"schema_version"`
+	// KnowledgeBaseID is the ID of the knowledge base in the exporting instance
+	KnowledgeBaseID string `json:"knowledge_base_id"`
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	ExportedAt      int64  `json:"exported_at"`
+	// Records counts the exported records by kind: knowledge, chunks and tags
+	Records map[string]int `json:"records"`
+}
+
+// NewKBArchiveManifest returns the manifest of an archive of the knowledge base
+func NewKBArchiveManifest(kb *KnowledgeBase, exportedAt int64) *KBArchiveManifest {
+	return &KBArchiveManifest{
+		Kind:            KBArchiveKind,
+		SchemaVersion:   KBArchiveSchemaVersion,
+		KnowledgeBaseID: kb.ID,
+		Name:            kb.Name,
+		Type:            kb.Type,
+		ExportedAt:      exportedAt,
+		Records:         make(map[string]int),
+	}
+}
+
+// Validate checks that the archive is a knowledge base archive this version can import
+func (m *KBArchiveManifest) Validate() error {
+	switch {
+	case m.Kind != KBArchiveKind:
+		return fmt.Errorf("not a knowledge base archive")
+	case m.SchemaVersion > KBArchiveSchemaVersion:
+		return fmt.Errorf("archive schema version %d is newer than the supported version %d",
+			m.SchemaVersion, KBArchiveSchemaVersion)
+	case m.SchemaVersion < KBArchiveMinSchemaVersion:
+		return fmt.Errorf("archive schema version %d is no longer supported", m.SchemaVersion)
+	}
+	return nil
+}
+
+// KBImportRequest holds the options of a knowledge base import. Models left empty are taken from the
+// archive and must exist in the importing instance.
+type KBImportRequest struct {
+	// Name replaces the name of the archived knowledge base
+	Name             string `form:"name"`
+	EmbeddingModelID string `form:"embedding_model_id"`
+	SummaryModelID   string `form:"summary_model_id"`
+}
+
+// KBImportPayload represents the knowledge base import task payload
+type KBImportPayload struct {
+	TenantID  uint64 `json:"tenant_id"`
+	TaskID    string `json:"task_id"`
+	RequestID string `json:"request_id"`
+	// ArchivePath is the storage path of the uploaded archive, removed once the import finished
+	ArchivePath string `json:"archive_path"`
+	// KnowledgeBase is the archived knowledge base with the models resolved for this instance
+	KnowledgeBase *KnowledgeBase `json:"knowledge_base"`
+}
+
+// KBImportID maps the ID of an archived record to the ID of its copy. IDs are derived from the import task,
+// so that references between records resolve without keeping a table of the records read so far.
+func KBImportID(taskID, archivedID string) string {
+	if archivedID == "" {
+		return ""
+	}
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(taskID+"\x00"+archivedID)).String()
+}
+
+// RedactSecrets hides the storage and VLM credentials of the knowledge base for export
+func (kb *KnowledgeBase) RedactSecrets() {
+	if kb.StorageConfig.SecretKey != "" {
+		kb.StorageConfig.SecretKey = RedactedSecret
+	}
+	if kb.VLMConfig.APIKey != "" {
+		kb.VLMConfig.APIKey = RedactedSecret
+	}
+}
+
+// ClearRedactedSecrets removes the credentials redacted by an export, which have to be configured again
+func (kb *KnowledgeBase) ClearRedactedSecrets() {
+	if kb.StorageConfig.SecretKey == RedactedSecret {
+		kb.StorageConfig.SecretKey = ""
+	}
+	if kb.VLMConfig.APIKey == RedactedSecret {
+		kb.VLMConfig.APIKey = ""
+	}
+}
@@ -0,0 +1,53 @@
+package types
+
+import "testing"
+
+func TestKBArchiveManifestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		version int
+		wantErr bool
+	}{
+		{name: "current", kind: KBArchiveKind, version: KBArchiveSchemaVersion},
+		{name: "other kind", kind: "weknora.tenant", version: KBArchiveSchemaVersion, wantErr: true},
+		{name: "newer", kind: KBArchiveKind, version: KBArchiveSchemaVersion + 1, wantErr: true},
+		{name: "older", kind: KBArchiveKind, version: KBArchiveMinSchemaVersion - 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &KBArchiveManifest{Kind: tt.kind, SchemaVersion: tt.version}
+			if err := m.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKBImportID(t *testing.T) {
+	id := KBImportID("task-1", "chunk-1")
+	if id == "" || id != KBImportID("task-1", "chunk-1") {
+		t.Fatalf("KBImportID() = %q, want a stable ID", id)
+	}
+	if id == KBImportID("task-2", "chunk-1") {
+		t.Error("imports of the same archive share IDs")
+	}
+	if KBImportID("task-1", "") != "" {
+		t.Error("empty references are not kept empty")
+	}
+}
+
+func TestKnowledgeBaseRedactSecrets(t *testing.T) {
+	kb := &KnowledgeBase{
+		StorageConfig: StorageConfig{SecretKey: "secret"},
+		VLMConfig:     VLMConfig{APIKey: "key"},
+	}
+	kb.RedactSecrets()
+	if kb.StorageConfig.SecretKey != RedactedSecret || kb.VLMConfig.APIKey != RedactedSecret {
+		t.Fatalf("RedactSecrets() left %+v, %+v", kb.StorageConfig, kb.VLMConfig)
+	}
+	kb.ClearRedactedSecrets()
+	if kb.StorageConfig.SecretKey != "" || kb.VLMConfig.APIKey != "" {
+		t.Errorf("ClearRedactedSecrets() left %+v, %+v", kb.StorageConfig, kb.VLMConfig)
+	}
+}
@@ -0,0 +1,132 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Content profiles of a knowledge base, detected from the types of its first files
+const (
+	// KBContentGeneral is plain text, web pages, a mix of file types or no file yet
+	KBContentGeneral = "general"
+	// KBContentMarkdown is Markdown documents
+	KBContentMarkdown = "markdown"
+	// KBContentDocument is long paginated documents: PDF, Word and slides
+	KBContentDocument = "document"
+	// KBContentTabular is spreadsheets and CSV files
+	KBContentTabular = "tabular"
+)
+
+// KBDefaultsSampleSize is the number of files whose type is used to detect the content profile
+const KBDefaultsSampleSize = 10
+
+// Smart default values, in characters
+const (
+	// documentParentChunkSize is the parent chunk size suggested for long documents
+	documentParentChunkSize = 4096
+	// shortInputChunkSize is the largest chunk size suggested for embedding models limited to 512 tokens
+	shortInputChunkSize = 512
+)
+
+// kbContentFileTypes maps file types to the content profile they suggest
+var kbContentFileTypes = map[string]string{
+	"md":       KBContentMarkdown,
+	"markdown": KBContentMarkdown,
+	"pdf":      KBContentDocument,
+	"doc":      KBContentDocument,
+	"docx":     KBContentDocument,
+	"ppt":      KBContentDocument,
+	"pptx":     KBContentDocument,
+	"csv":      KBContentTabular,
+	"xls":      KBContentTabular,
+	"xlsx":     KBContentTabular,
+}
+
+// shortInputEmbeddingModels are name fragments of embedding models that read at most 512 tokens
+var shortInputEmbeddingModels = []string{
+	"bge-small", "bge-base", "bge-large", "m3e", "text2vec", "all-minilm", "e5-small", "e5-base", "e5-large",
+}
+
+// KBDefaults are the configuration values picked for a knowledge base in smart defaults mode, returned
+// with the reason of each choice so that they can be reviewed
+type KBDefaults struct {
+	// ContentProfile is the content profile detected from the first files of the knowledge base
+	ContentProfile string `json:"content_profile"`
+	// ChunkingConfig is the suggested chunking
+	ChunkingConfig ChunkingConfig `json:"chunking_config"`
+	// ParentChildConfig is the suggested parent-child retrieval, nil keeps the current one
+	ParentChildConfig *ParentChildConfig `json:"parent_child_config,omitempty"`
+	// Reasons explain the values that differ from the configured knowledge base defaults
+	Reasons []string `json:"reasons"`
+}
+
+// DetectKBContentProfile returns the content profile suggested by more than half of the file types,
+// or the general profile
+func DetectKBContentProfile(fileTypes []string) string {
+	counts := make(map[string]int)
+	for _, fileType := range fileTypes {
+		if profile, ok := kbContentFileTypes[strings.ToLower(strings.TrimPrefix(fileType, "."))]; ok {
+			counts[profile]++
+		}
+	}
+	for profile, count := range counts {
+		if count*2 > len(fileTypes) {
+			return profile
+		}
+	}
+	return KBContentGeneral
+}
+
+// SuggestKBDefaults picks the chunking and retrieval of a knowledge base for its content profile, starting
+// from the configured knowledge base defaults and bounded by the input size of the embedding model
+func SuggestKBDefaults(base ChunkingConfig, profile string, embeddingModel string) *KBDefaults {
+	defaults := &KBDefaults{
+		ContentProfile: profile,
+		ChunkingConfig: ChunkingConfig{
+			ChunkSize:    base.ChunkSize,
+			ChunkOverlap: base.ChunkOverlap,
+			Separators:   base.Separators,
+		},
+		Reasons: []string{},
+	}
+	chunking := &defaults.ChunkingConfig
+
+	switch profile {
+	case KBContentMarkdown:
+		chunking.Strategy = ChunkingStrategySection
+		defaults.Reasons = append(defaults.Reasons,
+			"Markdown documents are chunked by section, keeping tables and code blocks whole")
+	case KBContentDocument:
+		defaults.ParentChildConfig = &ParentChildConfig{
+			ParentChunkSize: documentParentChunkSize,
+			RetrievalMode:   RetrievalModeParent,
+		}
+		defaults.Reasons = append(defaults.Reasons, fmt.Sprintf(
+			"long documents are searched by small chunks and answered with their %d-character parent chunk",
+			documentParentChunkSize))
+	case KBContentTabular:
+		chunking.Separators = []string{"\n"}
+		chunking.ChunkOverlap = 0
+		defaults.Reasons = append(defaults.Reasons, "spreadsheet rows are split on line breaks, without overlap")
+	}
+
+	if isShortInputEmbeddingModel(embeddingModel) && chunking.ChunkSize > shortInputChunkSize {
+		chunking.ChunkSize = shortInputChunkSize
+		chunking.ChunkOverlap = min(chunking.ChunkOverlap, shortInputChunkSize/5)
+		defaults.Reasons = append(defaults.Reasons, fmt.Sprintf(
+			"embedding model %s reads at most 512 tokens, chunks are limited to %d characters",
+			embeddingModel, shortInputChunkSize))
+	}
+	return defaults
+}
+
+// isShortInputEmbeddingModel reports whether the embedding model reads at most 512 tokens
+func isShortInputEmbeddingModel(name string) bool {
+	name = strings.ToLower(name)
+	for _, fragment := range shortInputEmbeddingModels {
+		if strings.Contains(name, fragment) {
+			return true
+		}
+	}
+	return false
+}
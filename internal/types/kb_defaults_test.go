@@ -0,0 +1,59 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDetectKBContentProfile(t *testing.T) {
+	tests := []struct {
+		fileTypes []string
+		want      string
+	}{
+		{nil, KBContentGeneral},
+		{[]string{"md", "md", "pdf"}, KBContentMarkdown},
+		{[]string{"PDF", "docx", "txt"}, KBContentDocument},
+		{[]string{"xlsx", "csv"}, KBContentTabular},
+		{[]string{"md", "pdf", "txt", "xlsx"}, KBContentGeneral},
+		{[]string{"txt", "url", "manual"}, KBContentGeneral},
+	}
+	for _, tt := range tests {
+		if got := DetectKBContentProfile(tt.fileTypes); got != tt.want {
+			t.Errorf("DetectKBContentProfile(%v) = %q, want %q", tt.fileTypes, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestKBDefaults(t *testing.T) {
+	base := ChunkingConfig{ChunkSize: 1000, ChunkOverlap: 200, Separators: []string{"\n\n", "\n"}}
+
+	general := SuggestKBDefaults(base, KBContentGeneral, "nomic-embed-text")
+	if general.ChunkingConfig.ChunkSize != 1000 || general.ChunkingConfig.ChunkOverlap != 200 ||
+		general.ParentChildConfig != nil || len(general.Reasons) != 0 {
+		t.Errorf("general defaults = %+v, want the base config", general)
+	}
+
+	markdown := SuggestKBDefaults(base, KBContentMarkdown, "")
+	if markdown.ChunkingConfig.Strategy != ChunkingStrategySection {
+		t.Errorf("markdown strategy = %q, want section", markdown.ChunkingConfig.Strategy)
+	}
+
+	document := SuggestKBDefaults(base, KBContentDocument, "")
+	if document.ParentChildConfig == nil || document.ParentChildConfig.Validate() != nil ||
+		document.ParentChildConfig.RetrievalMode != RetrievalModeParent {
+		t.Errorf("document parent-child config = %+v, want parent retrieval", document.ParentChildConfig)
+	}
+
+	tabular := SuggestKBDefaults(base, KBContentTabular, "")
+	if !slices.Equal(tabular.ChunkingConfig.Separators, []string{"\n"}) || tabular.ChunkingConfig.ChunkOverlap != 0 {
+		t.Errorf("tabular chunking = %+v, want line breaks without overlap", tabular.ChunkingConfig)
+	}
+
+	short := SuggestKBDefaults(base, KBContentGeneral, "BAAI/bge-large-zh-v1.5")
+	if short.ChunkingConfig.ChunkSize != 512 || short.ChunkingConfig.ChunkOverlap != 102 || len(short.Reasons) != 1 {
+		t.Errorf("short input model chunking = %+v, reasons %v, want 512/102", short.ChunkingConfig, short.Reasons)
+	}
+	if !slices.Equal(base.Separators, []string{"\n\n", "\n"}) {
+		t.Errorf("base separators modified: %v", base.Separators)
+	}
+}
@@ -30,6 +30,22 @@ const (
 	ParseStatusDeleting = "deleting"
 )
 
+// Processing modes of uploaded knowledge
+const (
+	// KnowledgeProcessingSync means the knowledge was processed within the upload request
+	KnowledgeProcessingSync = "sync"
+	// KnowledgeProcessingAsync means the knowledge is processed by a background task
+	KnowledgeProcessingAsync = "async"
+)
+
+// Failure kinds of knowledge processing
+const (
+	// FailureKindPermanent indicates an error that retrying cannot fix, such as an unparsable file
+	FailureKindPermanent = "permanent"
+	// FailureKindRetriesExhausted indicates a transient error that persisted through every attempt
+	FailureKindRetriesExhausted = "retries_exhausted"
+)
+
 // Summary status constants for async summary generation
 const (
 	// SummaryStatusNone indicates no summary task is needed
@@ -77,6 +93,8 @@ type Knowledge struct {
 	SummaryStatus string `json:"summary_status"     gorm:"type:varchar(32);default:none"`
 	// Enable status of the knowledge
 	EnableStatus string `json:"enable_status"`
+	// Whether the knowledge takes part in retrieval; disabled knowledge stays listed and downloadable
+	IsEnabled bool `json:"is_enabled"         gorm:"column:is_enabled;default:true"`
 	// ID of the embedding model
 	EmbeddingModelID string `json:"embedding_model_id"`
 	// File name of the knowledge
@@ -103,10 +121,26 @@ type Knowledge struct {
 	ProcessedAt *time.Time `json:"processed_at"`
 	// Error message of the knowledge
 	ErrorMessage string `json:"error_message"`
+	// Failure kind of the last failed processing, empty unless the parse status is failed
+	FailureKind string `json:"failure_kind"       gorm:"type:varchar(32)"`
+	// Number of processing attempts of the current (re)processing run
+	ProcessAttempts int `json:"process_attempts"   gorm:"default:0"`
+	// Chunk size distribution of the last processing of a document
+	ChunkSizeStats *ChunkSizeStats `json:"chunk_size_stats,omitempty" gorm:"type:json"`
+	// Ingestion stages skipped by the last processing, as configured by the knowledge base pipeline
+	SkippedStages StringArray `json:"skipped_stages"     gorm:"type:json"`
+	// Chunks reused and recomputed by the last incremental update of a document, nil after a full processing
+	ChunkReuseStats *ChunkReuseStats `json:"chunk_reuse_stats,omitempty" gorm:"type:json"`
 	// Deletion time of the knowledge
 	DeletedAt gorm.DeletedAt `json:"deleted_at"         gorm:"index"`
 	// Knowledge base name (not stored in database, populated on query)
 	KnowledgeBaseName string `json:"knowledge_base_name" gorm:"-"`
+	// Processing mode of an uploaded file, sync or async (not stored in database, set on upload)
+	ProcessingMode string `json:"processing_mode,omitempty"    gorm:"-"`
+	// ID of the background task processing an uploaded file (not stored in database, set on async upload)
+	ProcessingTaskID string `json:"processing_task_id,omitempty" gorm:"-"`
+	// Chunks of a file processed within the upload request (not stored in database, set on sync upload)
+	Chunks []*Chunk `json:"chunks,omitempty"             gorm:"-"`
 }
 
 // GetMetadata returns the metadata as a map[string]string.
@@ -130,6 +164,36 @@ func (k *Knowledge) BeforeCreate(tx *gorm.DB) (err error) {
 	return nil
 }
 
+// StartProcessAttempt marks the knowledge as processing in the given attempt, counted from 1
+func (k *Knowledge) StartProcessAttempt(attempt int) {
+	k.ParseStatus = ParseStatusProcessing
+	k.ProcessAttempts = attempt
+	k.FailureKind = ""
+	k.UpdatedAt = time.Now()
+}
+
+// MarkProcessRetrying records a transient failure of the current attempt, the knowledge waits for the next attempt
+func (k *Knowledge) MarkProcessRetrying(err error) {
+	k.ParseStatus = ParseStatusPending
+	k.ErrorMessage = fmt.Sprintf("attempt %d failed, retrying: %v", k.ProcessAttempts, err)
+	k.FailureKind = ""
+	k.UpdatedAt = time.Now()
+}
+
+// MarkProcessFailed marks the knowledge as failed. A transient error is recorded as failed after the
+// attempts made so far, any other error as permanent.
+func (k *Knowledge) MarkProcessFailed(err error, transient bool) {
+	k.ParseStatus = ParseStatusFailed
+	if transient {
+		k.FailureKind = FailureKindRetriesExhausted
+		k.ErrorMessage = fmt.Sprintf("failed after %d attempts: %v", max(k.ProcessAttempts, 1), err)
+	} else {
+		k.FailureKind = FailureKindPermanent
+		k.ErrorMessage = err.Error()
+	}
+	k.UpdatedAt = time.Now()
+}
+
 // ManualKnowledgeMetadata stores metadata for manual Markdown knowledge content.
 type ManualKnowledgeMetadata struct {
 	Content   string `json:"content"`
@@ -269,6 +333,22 @@ func (p ManualKnowledgePayload) IsDraft() bool {
 	return p.Status == "" || p.Status == ManualKnowledgeStatusDraft
 }
 
+// StagedKnowledgeFile is an uploaded file spooled to a temporary file, waiting for its knowledge record.
+type StagedKnowledgeFile struct {
+	// KnowledgeID is the ID reserved for the knowledge, part of the storage path
+	KnowledgeID string
+	// FileName is the uploaded file name
+	FileName string
+	// TempPath is the local temporary file holding the content
+	TempPath string
+	// FilePath is the storage path of the saved file, empty until it is saved
+	FilePath string
+	// FileSize is the number of bytes received
+	FileSize int64
+	// FileHash is the MD5 hash of the content, computed while it was received
+	FileHash string
+}
+
 // KnowledgeCheckParams defines parameters used to check if knowledge already exists.
 type KnowledgeCheckParams struct {
 	// File parameters
@@ -0,0 +1,35 @@
+package types
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKnowledgeProcessAttempts(t *testing.T) {
+	k := &Knowledge{}
+	k.StartProcessAttempt(1)
+	k.MarkProcessRetrying(errors.New("unavailable"))
+	if k.ParseStatus != ParseStatusPending || !strings.HasPrefix(k.ErrorMessage, "attempt 1 failed, retrying") {
+		t.Fatalf("unexpected retry state %q: %q", k.ParseStatus, k.ErrorMessage)
+	}
+
+	k.StartProcessAttempt(3)
+	if k.ParseStatus != ParseStatusProcessing || k.ProcessAttempts != 3 {
+		t.Fatalf("unexpected attempt state %q: %d", k.ParseStatus, k.ProcessAttempts)
+	}
+	k.MarkProcessFailed(errors.New("unavailable"), true)
+	if k.FailureKind != FailureKindRetriesExhausted || k.ErrorMessage != "failed after 3 attempts: unavailable" {
+		t.Errorf("unexpected exhausted failure %q: %q", k.FailureKind, k.ErrorMessage)
+	}
+
+	k.StartProcessAttempt(1)
+	if k.FailureKind != "" {
+		t.Errorf("failure kind should be reset, got %q", k.FailureKind)
+	}
+	k.MarkProcessFailed(errors.New("unsupported format"), false)
+	if k.ParseStatus != ParseStatusFailed || k.FailureKind != FailureKindPermanent ||
+		k.ErrorMessage != "unsupported format" {
+		t.Errorf("unexpected permanent failure %q: %q", k.FailureKind, k.ErrorMessage)
+	}
+}
@@ -3,6 +3,7 @@ package types
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -57,6 +58,8 @@ type KnowledgeBase struct {
 	EmbeddingModelID string `yaml:"embedding_model_id"      json:"embedding_model_id"`
 	// Summary model ID
 	SummaryModelID string `yaml:"summary_model_id"        json:"summary_model_id"`
+	// RerankModelID reranks results retrieved from this knowledge base unless the agent selects a rerank model
+	RerankModelID string `yaml:"rerank_model_id"         json:"rerank_model_id"         gorm:"column:rerank_model_id"`
 	// VLM config
 	VLMConfig VLMConfig `yaml:"vlm_config"              json:"vlm_config"              gorm:"type:json"`
 	// Storage config
@@ -67,6 +70,40 @@ type KnowledgeBase struct {
 	FAQConfig *FAQConfig `yaml:"faq_config"              json:"faq_config"              gorm:"column:faq_config;type:json"`
 	// QuestionGenerationConfig stores question generation configuration for document knowledge bases
 	QuestionGenerationConfig *QuestionGenerationConfig `yaml:"question_generation_config" json:"question_generation_config" gorm:"column:question_generation_config;type:json"`
+	// VectorSearchConfig stores search-time accuracy parameters for the vector index
+	VectorSearchConfig *VectorSearchConfig `yaml:"vector_search_config"    json:"vector_search_config"    gorm:"column:vector_search_config;type:json"`
+	// DedupConfig controls collapsing of near-duplicate chunks from different knowledge items
+	DedupConfig *DedupConfig `yaml:"dedup_config"            json:"dedup_config"            gorm:"column:dedup_config;type:json"`
+	// SourceQuotaConfig caps the chunks of the same knowledge item in the search results
+	SourceQuotaConfig *SourceQuotaConfig `yaml:"source_quota_config"     json:"source_quota_config"     gorm:"column:source_quota_config;type:json"`
+	// ConfidenceGateConfig decides whether to answer or refuse based on retrieval confidence
+	ConfidenceGateConfig *ConfidenceGateConfig `yaml:"confidence_gate_config"  json:"confidence_gate_config"  gorm:"column:confidence_gate_config;type:json"`
+	// FusionConfig selects how hybrid search merges vector and keyword results
+	FusionConfig *FusionConfig `yaml:"fusion_config"           json:"fusion_config"           gorm:"column:fusion_config;type:json"`
+	// HistoryDepth caps the prior conversation turns included in the prompt (nil = system default, 0 = none)
+	HistoryDepth *int `yaml:"history_depth"           json:"history_depth"           gorm:"column:history_depth"`
+	// EmptyMessage answers questions while the knowledge base has no enabled chunks (empty = localized default)
+	EmptyMessage string `yaml:"empty_message"           json:"empty_message"           gorm:"column:empty_message;type:text"`
+	// VectorSpaceConfig adds vector spaces embedded with other models and selects the searched ones
+	VectorSpaceConfig *VectorSpaceConfig `yaml:"vector_space_config"     json:"vector_space_config"     gorm:"column:vector_space_config;type:json"`
+	// MetadataSchemaConfig declares the knowledge metadata fields and their types; nil accepts any metadata
+	MetadataSchemaConfig *MetadataSchemaConfig `yaml:"metadata_schema_config"  json:"metadata_schema_config"  gorm:"column:metadata_schema_config;type:json"`
+	// AnswerCacheConfig enables exact-match caching of knowledge Q&A answers
+	AnswerCacheConfig *AnswerCacheConfig `yaml:"answer_cache_config"     json:"answer_cache_config"     gorm:"column:answer_cache_config;type:json"`
+	// DocumentSummaryConfig controls document summary generation and summary-first retrieval
+	DocumentSummaryConfig *DocumentSummaryConfig `yaml:"document_summary_config" json:"document_summary_config" gorm:"column:document_summary_config;type:json"`
+	// ParentChildConfig groups chunks into parent chunks and selects the retrieval mode
+	ParentChildConfig *ParentChildConfig `yaml:"parent_child_config"     json:"parent_child_config"     gorm:"column:parent_child_config;type:json"`
+	// RerankFallbackConfig decides whether retrieval degrades or fails when the rerank model is unavailable
+	RerankFallbackConfig *RerankFallbackConfig `yaml:"rerank_fallback_config"  json:"rerank_fallback_config"  gorm:"column:rerank_fallback_config;type:json"`
+	// GroundingConfig verifies that generated answers are supported by the retrieved chunks
+	GroundingConfig *GroundingConfig `yaml:"grounding_config"        json:"grounding_config"        gorm:"column:grounding_config;type:json"`
+	// CitationConfig requires inline citations and removes or flags the uncited sentences of answers
+	CitationConfig *CitationConfig `yaml:"citation_config"         json:"citation_config"         gorm:"column:citation_config;type:json"`
+	// PipelineConfig enables or disables the ingestion stages of the documents
+	PipelineConfig *PipelineConfig `yaml:"pipeline_config"         json:"pipeline_config"         gorm:"column:pipeline_config;type:json"`
+	// PinnedSources force curated knowledge to the top of the retrieval results of matching queries
+	PinnedSources PinnedSourceRules `yaml:"pinned_sources"          json:"pinned_sources"          gorm:"column:pinned_sources;type:json"`
 	// Creation time of the knowledge base
 	CreatedAt time.Time `yaml:"created_at"              json:"created_at"`
 	// Last updated time of the knowledge base
@@ -91,6 +128,38 @@ type KnowledgeBaseConfig struct {
 	ImageProcessingConfig ImageProcessingConfig `yaml:"image_processing_config" json:"image_processing_config"`
 	// FAQ configuration (only for FAQ type knowledge bases)
 	FAQConfig *FAQConfig `yaml:"faq_config"              json:"faq_config"`
+	// Vector search configuration
+	VectorSearchConfig *VectorSearchConfig `yaml:"vector_search_config"    json:"vector_search_config"`
+	// Near-duplicate collapsing configuration
+	DedupConfig *DedupConfig `yaml:"dedup_config"            json:"dedup_config"`
+	// Source quota configuration; a cap of 0 disables the quota
+	SourceQuotaConfig *SourceQuotaConfig `yaml:"source_quota_config"     json:"source_quota_config"`
+	// Confidence gate configuration
+	ConfidenceGateConfig *ConfidenceGateConfig `yaml:"confidence_gate_config"  json:"confidence_gate_config"`
+	// Hybrid search fusion configuration
+	FusionConfig *FusionConfig `yaml:"fusion_config"           json:"fusion_config"`
+	// Maximum prior conversation turns in the prompt; -1 restores the system default
+	HistoryDepth *int `yaml:"history_depth"           json:"history_depth"`
+	// Message answered while the knowledge base has no content; an empty string restores the default
+	EmptyMessage *string `yaml:"empty_message"           json:"empty_message"`
+	// Additional vector spaces and the spaces searched; an empty config keeps only the primary space
+	VectorSpaceConfig *VectorSpaceConfig `yaml:"vector_space_config"     json:"vector_space_config"`
+	// Knowledge metadata schema; an empty schema accepts any metadata again
+	MetadataSchemaConfig *MetadataSchemaConfig `yaml:"metadata_schema_config"  json:"metadata_schema_config"`
+	// Answer cache configuration; a TTL of 0 disables the cache
+	AnswerCacheConfig *AnswerCacheConfig `yaml:"answer_cache_config"     json:"answer_cache_config"`
+	// Document summary configuration
+	DocumentSummaryConfig *DocumentSummaryConfig `yaml:"document_summary_config" json:"document_summary_config"`
+	// Parent-child chunking configuration; a parent chunk size of 0 disables parent chunks
+	ParentChildConfig *ParentChildConfig `yaml:"parent_child_config"     json:"parent_child_config"`
+	// Rerank fallback configuration
+	RerankFallbackConfig *RerankFallbackConfig `yaml:"rerank_fallback_config"  json:"rerank_fallback_config"`
+	// Answer grounding configuration
+	GroundingConfig *GroundingConfig `yaml:"grounding_config"        json:"grounding_config"`
+	// Citation requirement configuration
+	CitationConfig *CitationConfig `yaml:"citation_config"         json:"citation_config"`
+	// Pipeline stages configuration
+	PipelineConfig *PipelineConfig `yaml:"pipeline_config"         json:"pipeline_config"`
 }
 
 // ChunkingConfig represents the document splitting configuration
@@ -103,6 +172,12 @@ type ChunkingConfig struct {
 	Separators []string `yaml:"separators"    json:"separators"`
 	// EnableMultimodal (deprecated, kept for backward compatibility with old data)
 	EnableMultimodal bool `yaml:"enable_multimodal,omitempty" json:"enable_multimodal,omitempty"`
+	// MinChunkSize is the size in characters below which a chunk is merged into a neighbor (0 = default)
+	MinChunkSize int `yaml:"min_chunk_size,omitempty" json:"min_chunk_size,omitempty"`
+	// MaxChunkSize is the size in characters above which a chunk is split (0 = default)
+	MaxChunkSize int `yaml:"max_chunk_size,omitempty" json:"max_chunk_size,omitempty"`
+	// Strategy is "fixed" (default) to keep the parsed chunks, or "section" to chunk Markdown by headings
+	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
 }
 
 // COSConfig represents the COS configuration
@@ -301,6 +376,157 @@ func (f *FAQConfig) Scan(value interface{}) error {
 	return json.Unmarshal(b, f)
 }
 
+const (
+	// MaxVectorEfSearch is the largest HNSW ef_search accepted
+	MaxVectorEfSearch = 1000
+	// MaxVectorProbes is the largest IVF probe count accepted
+	MaxVectorProbes = 1000
+)
+
+// Vector search modes reported with search results
+const (
+	VectorSearchModeApproximate = "approximate"
+	VectorSearchModeExact       = "exact"
+)
+
+// VectorSearchConfig holds search-time accuracy parameters for approximate vector indexes.
+// Larger values scan more candidates, improving recall at the cost of query latency.
+// Zero values keep the index defaults (pgvector: ef_search=40, probes=1).
+type VectorSearchConfig struct {
+	// EfSearch is the size of the HNSW candidate list (pgvector hnsw.ef_search, Qdrant hnsw_ef)
+	EfSearch int `yaml:"ef_search"       json:"ef_search,omitempty"`
+	// Probes is the number of IVF lists scanned (pgvector ivfflat.probes)
+	Probes int `yaml:"probes"          json:"probes,omitempty"`
+	// Exact bypasses the ANN index and scores every vector, guaranteeing perfect recall
+	Exact bool `yaml:"exact"           json:"exact,omitempty"`
+	// ExactThreshold switches to exact search while the knowledge base has fewer chunks
+	// than this; 0 disables the automatic switch. Only read from knowledge base config.
+	ExactThreshold int `yaml:"exact_threshold" json:"exact_threshold,omitempty"`
+}
+
+// Validate checks the parameters against the ranges supported by the vector stores
+func (c *VectorSearchConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.EfSearch < 0 || c.EfSearch > MaxVectorEfSearch {
+		return fmt.Errorf("ef_search must be between 1 and %d, or 0 for the index default", MaxVectorEfSearch)
+	}
+	if c.Probes < 0 || c.Probes > MaxVectorProbes {
+		return fmt.Errorf("probes must be between 1 and %d, or 0 for the index default", MaxVectorProbes)
+	}
+	if c.ExactThreshold < 0 {
+		return fmt.Errorf("exact_threshold must not be negative")
+	}
+	return nil
+}
+
+// IsZero reports whether no search-time parameter is set
+func (c *VectorSearchConfig) IsZero() bool {
+	return c == nil || (c.EfSearch == 0 && c.Probes == 0 && !c.Exact)
+}
+
+// Mode returns the vector search mode these parameters select
+func (c *VectorSearchConfig) Mode() string {
+	if c != nil && c.Exact {
+		return VectorSearchModeExact
+	}
+	return VectorSearchModeApproximate
+}
+
+// Merge returns the effective parameters, with non-zero override values taking precedence
+func (c *VectorSearchConfig) Merge(override *VectorSearchConfig) *VectorSearchConfig {
+	merged := VectorSearchConfig{}
+	if c != nil {
+		merged = *c
+	}
+	if override != nil {
+		if override.EfSearch > 0 {
+			merged.EfSearch = override.EfSearch
+		}
+		if override.Probes > 0 {
+			merged.Probes = override.Probes
+		}
+		merged.Exact = merged.Exact || override.Exact
+	}
+	merged.ExactThreshold = 0
+	if merged.IsZero() {
+		return nil
+	}
+	return &merged
+}
+
+// Value implements driver.Valuer
+func (c VectorSearchConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *VectorSearchConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// DefaultDedupSimilarityThreshold is the content similarity above which chunks are considered duplicates
+const DefaultDedupSimilarityThreshold = 0.8
+
+// DedupConfig controls collapsing of near-duplicate chunks retrieved from different knowledge items.
+// The highest-scored chunk is kept and the others are listed as its alternates.
+type DedupConfig struct {
+	// Enabled turns near-duplicate collapsing on
+	Enabled bool `yaml:"enabled"              json:"enabled"`
+	// SimilarityThreshold is the content similarity (see ContentSimilarity) at or above which chunks
+	// are collapsed (0 = default)
+	SimilarityThreshold float64 `yaml:"similarity_threshold" json:"similarity_threshold,omitempty"`
+}
+
+// Validate checks the similarity threshold range
+func (c *DedupConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.SimilarityThreshold < 0 || c.SimilarityThreshold > 1 {
+		return fmt.Errorf("similarity_threshold must be between 0 and 1")
+	}
+	return nil
+}
+
+// IsEnabled reports whether collapsing is turned on
+func (c *DedupConfig) IsEnabled() bool {
+	return c != nil && c.Enabled
+}
+
+// Threshold returns the effective similarity threshold
+func (c *DedupConfig) Threshold() float64 {
+	if c == nil || c.SimilarityThreshold <= 0 {
+		return DefaultDedupSimilarityThreshold
+	}
+	return c.SimilarityThreshold
+}
+
+// Value implements driver.Valuer
+func (c DedupConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *DedupConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
 // EnsureDefaults ensures types and configurations have default values
 func (kb *KnowledgeBase) EnsureDefaults() {
 	if kb == nil {
@@ -0,0 +1,70 @@
+package types
+
+import "testing"
+
+func TestVectorSearchConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *VectorSearchConfig
+		wantErr bool
+	}{
+		{name: "nil"},
+		{name: "index defaults", config: &VectorSearchConfig{}},
+		{name: "in range", config: &VectorSearchConfig{EfSearch: MaxVectorEfSearch, Probes: MaxVectorProbes}},
+		{name: "negative ef_search", config: &VectorSearchConfig{EfSearch: -1}, wantErr: true},
+		{name: "ef_search too large", config: &VectorSearchConfig{EfSearch: MaxVectorEfSearch + 1}, wantErr: true},
+		{name: "probes too large", config: &VectorSearchConfig{Probes: MaxVectorProbes + 1}, wantErr: true},
+		{name: "negative exact threshold", config: &VectorSearchConfig{ExactThreshold: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVectorSearchConfigMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     *VectorSearchConfig
+		override *VectorSearchConfig
+		want     *VectorSearchConfig
+	}{
+		{name: "both empty"},
+		{name: "zero values keep the index defaults", base: &VectorSearchConfig{}, override: &VectorSearchConfig{}},
+		{
+			name: "knowledge base only",
+			base: &VectorSearchConfig{EfSearch: 100},
+			want: &VectorSearchConfig{EfSearch: 100},
+		},
+		{
+			name:     "request overrides non-zero values",
+			base:     &VectorSearchConfig{EfSearch: 100, Probes: 10},
+			override: &VectorSearchConfig{EfSearch: 200},
+			want:     &VectorSearchConfig{EfSearch: 200, Probes: 10},
+		},
+		{
+			name:     "exact from either side",
+			base:     &VectorSearchConfig{EfSearch: 100},
+			override: &VectorSearchConfig{Exact: true},
+			want:     &VectorSearchConfig{EfSearch: 100, Exact: true},
+		},
+		{
+			name: "threshold alone is not a search parameter",
+			base: &VectorSearchConfig{ExactThreshold: 1000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.base.Merge(tt.override)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("Merge() = %+v, want %+v", got, tt.want)
+			}
+			if got.Mode() != tt.want.Mode() {
+				t.Errorf("Mode() = %s, want %s", got.Mode(), tt.want.Mode())
+			}
+		})
+	}
+}
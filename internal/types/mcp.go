@@ -3,6 +3,7 @@ package types
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -32,6 +33,7 @@ type MCPService struct {
 	AdvancedConfig *MCPAdvancedConfig `json:"advanced_config"        gorm:"type:json"`
 	StdioConfig    *MCPStdioConfig    `json:"stdio_config,omitempty" gorm:"type:json"` // Required for stdio transport
 	EnvVars        MCPEnvVars         `json:"env_vars,omitempty"     gorm:"type:json"` // Environment variables for stdio
+	Health         *MCPServiceHealth  `json:"health,omitempty"       gorm:"type:json"` // Last validation of the advertised tools
 	CreatedAt      time.Time          `json:"created_at"`
 	UpdatedAt      time.Time          `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt     `json:"deleted_at"             gorm:"index"`
@@ -51,9 +53,28 @@ type MCPAuthConfig struct {
 type MCPAdvancedConfig struct {
 	Timeout    int `json:"timeout"`     // Timeout in seconds, default: 30
 	RetryCount int `json:"retry_count"` // Number of retries, default: 3
-	RetryDelay int `json:"retry_delay"` // Delay between retries in seconds, default: 1
+	RetryDelay int `json:"retry_delay"` // Delay before the first retry in seconds, doubled for each retry, default: 1
+	// Consecutive failed attempts, retries included, that open the circuit breaker, default: 5
+	BreakerThreshold int `json:"breaker_threshold"`
+	// Seconds an open circuit breaker rejects calls before letting a trial call through, default: 30
+	BreakerCooldown int `json:"breaker_cooldown"`
 }
 
+// Limits of the advanced configuration of MCP services
+const (
+	MaxMCPRetryCount       = 10
+	MaxMCPRetryDelay       = 60
+	MaxMCPBreakerThreshold = 100
+	MaxMCPBreakerCooldown  = 3600
+)
+
+// Circuit breaker states of an MCP service
+const (
+	MCPBreakerClosed   = "closed"    // Calls go through
+	MCPBreakerOpen     = "open"      // Calls are rejected until the cooldown elapses
+	MCPBreakerHalfOpen = "half-open" // A trial call decides whether the breaker closes or opens again
+)
+
 // MCPStdioConfig represents stdio transport configuration
 type MCPStdioConfig struct {
 	Command string   `json:"command"` // Command: "uvx" or "npx"
@@ -84,6 +105,8 @@ type MCPTestResult struct {
 	Message   string         `json:"message,omitempty"`
 	Tools     []*MCPTool     `json:"tools,omitempty"`
 	Resources []*MCPResource `json:"resources,omitempty"`
+	// State of the circuit breaker guarding calls to the service, which the test itself bypasses
+	BreakerState string `json:"breaker_state,omitempty"`
 }
 
 // BeforeCreate is a GORM hook that runs before creating a new MCP service
@@ -199,12 +222,38 @@ func (e *MCPEnvVars) Scan(value interface{}) error {
 // GetDefaultAdvancedConfig returns default advanced configuration
 func GetDefaultAdvancedConfig() *MCPAdvancedConfig {
 	return &MCPAdvancedConfig{
-		Timeout:    30,
-		RetryCount: 3,
-		RetryDelay: 1,
+		Timeout:          30,
+		RetryCount:       3,
+		RetryDelay:       1,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30,
 	}
 }
 
+// Validate checks that the retry and circuit breaker parameters are within their limits;
+// a zero retry delay, breaker threshold or breaker cooldown falls back to the default
+func (c *MCPAdvancedConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if c.RetryCount < 0 || c.RetryCount > MaxMCPRetryCount {
+		return fmt.Errorf("retry_count must be between 0 and %d", MaxMCPRetryCount)
+	}
+	if c.RetryDelay < 0 || c.RetryDelay > MaxMCPRetryDelay {
+		return fmt.Errorf("retry_delay must be between 0 and %d seconds", MaxMCPRetryDelay)
+	}
+	if c.BreakerThreshold < 0 || c.BreakerThreshold > MaxMCPBreakerThreshold {
+		return fmt.Errorf("breaker_threshold must be between 0 and %d", MaxMCPBreakerThreshold)
+	}
+	if c.BreakerCooldown < 0 || c.BreakerCooldown > MaxMCPBreakerCooldown {
+		return fmt.Errorf("breaker_cooldown must be between 0 and %d seconds", MaxMCPBreakerCooldown)
+	}
+	return nil
+}
+
 // MaskSensitiveData masks sensitive information in the MCP service for display
 func (m *MCPService) MaskSensitiveData() {
 	if m.AuthConfig != nil {
@@ -0,0 +1,246 @@
+package types
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MCP tool health statuses
+const (
+	MCPToolStatusOK            = "ok"             // Advertised with a valid input schema
+	MCPToolStatusInvalidSchema = "invalid_schema" // Advertised with a missing or malformed input schema
+	MCPToolStatusMissing       = "missing"        // No longer advertised by the service
+)
+
+// Issues of the MCP services referenced by an agent
+const (
+	MCPAgentIssueServiceMissing     = "service_missing"     // The referenced service was deleted
+	MCPAgentIssueServiceDisabled    = "service_disabled"    // The referenced service is disabled
+	MCPAgentIssueServiceUnreachable = "service_unreachable" // The last check could not list the tools
+	MCPAgentIssueToolMissing        = "tool_missing"        // A tool is no longer advertised
+	MCPAgentIssueToolSchemaChanged  = "tool_schema_changed" // A tool schema changed since the agent was saved
+	MCPAgentIssueToolInvalidSchema  = "tool_invalid_schema" // A tool schema is missing or malformed
+)
+
+// MCPToolHealth is the result of the last validation of a tool advertised by an MCP service
+type MCPToolHealth struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// SchemaHash identifies the input schema, independently of key order and whitespace
+	SchemaHash string `json:"schema_hash"`
+	// SchemaChangedAt is when a check found the schema changed, nil while unchanged since first seen
+	SchemaChangedAt *time.Time `json:"schema_changed_at,omitempty"`
+	// MissingSince is when a check first found the tool no longer advertised
+	MissingSince *time.Time `json:"missing_since,omitempty"`
+}
+
+// MCPServiceHealth is the result of the last validation of the tools advertised by an MCP service
+type MCPServiceHealth struct {
+	Reachable bool             `json:"reachable"`
+	Error     string           `json:"error,omitempty"`
+	CheckedAt time.Time        `json:"checked_at"`
+	Tools     []*MCPToolHealth `json:"tools"`
+}
+
+// MCPAgentIssue is a problem of an MCP service referenced by an agent
+type MCPAgentIssue struct {
+	ServiceID   string `json:"service_id"`
+	ServiceName string `json:"service_name,omitempty"`
+	Tool        string `json:"tool,omitempty"`
+	Issue       string `json:"issue"`
+	Detail      string `json:"detail,omitempty"`
+}
+
+// AgentMCPStatus reports the problems of the MCP services an agent uses
+type AgentMCPStatus struct {
+	AgentID string           `json:"agent_id"`
+	Healthy bool             `json:"healthy"`
+	Issues  []*MCPAgentIssue `json:"issues"`
+}
+
+// NewMCPServiceHealth validates the tools listed by a reachable service against the previous check:
+// tools whose schema hash differs are marked changed, and tools no longer listed are kept as missing
+func NewMCPServiceHealth(previous *MCPServiceHealth, tools []*MCPTool, now time.Time) *MCPServiceHealth {
+	known := make(map[string]*MCPToolHealth)
+	if previous != nil {
+		for _, tool := range previous.Tools {
+			known[tool.Name] = tool
+		}
+	}
+
+	health := &MCPServiceHealth{Reachable: true, CheckedAt: now, Tools: make([]*MCPToolHealth, 0, len(tools))}
+	listed := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		if tool == nil || listed[tool.Name] {
+			continue
+		}
+		listed[tool.Name] = true
+
+		toolHealth := &MCPToolHealth{Name: tool.Name, Status: MCPToolStatusOK, SchemaHash: mcpSchemaHash(tool.InputSchema)}
+		if err := validateMCPToolSchema(tool); err != nil {
+			toolHealth.Status = MCPToolStatusInvalidSchema
+			toolHealth.Error = err.Error()
+		}
+		if prev, ok := known[tool.Name]; ok {
+			toolHealth.SchemaChangedAt = prev.SchemaChangedAt
+			if prev.SchemaHash != toolHealth.SchemaHash {
+				changedAt := now
+				toolHealth.SchemaChangedAt = &changedAt
+			}
+		}
+		health.Tools = append(health.Tools, toolHealth)
+	}
+
+	for name, prev := range known {
+		if listed[name] {
+			continue
+		}
+		missing := *prev
+		missing.Status = MCPToolStatusMissing
+		missing.Error = ""
+		if missing.MissingSince == nil {
+			missingSince := now
+			missing.MissingSince = &missingSince
+		}
+		health.Tools = append(health.Tools, &missing)
+	}
+
+	sort.Slice(health.Tools, func(i, j int) bool { return health.Tools[i].Name < health.Tools[j].Name })
+	return health
+}
+
+// UnreachableMCPServiceHealth records a check that could not list the tools of a service,
+// keeping the tools of the previous check to compare the next ones against
+func UnreachableMCPServiceHealth(previous *MCPServiceHealth, err error, now time.Time) *MCPServiceHealth {
+	health := &MCPServiceHealth{Error: err.Error(), CheckedAt: now, Tools: []*MCPToolHealth{}}
+	if previous != nil {
+		health.Tools = previous.Tools
+	}
+	return health
+}
+
+// AgentIssues returns the problems of the service for an agent saved at referencedAt
+// A service that has not been checked yet has no issue
+func (m *MCPService) AgentIssues(referencedAt time.Time) []*MCPAgentIssue {
+	issue := func(kind, tool, detail string) *MCPAgentIssue {
+		return &MCPAgentIssue{ServiceID: m.ID, ServiceName: m.Name, Tool: tool, Issue: kind, Detail: detail}
+	}
+	if !m.Enabled {
+		return []*MCPAgentIssue{issue(MCPAgentIssueServiceDisabled, "", "")}
+	}
+	if m.Health == nil {
+		return nil
+	}
+	if !m.Health.Reachable {
+		return []*MCPAgentIssue{issue(MCPAgentIssueServiceUnreachable, "", m.Health.Error)}
+	}
+
+	var issues []*MCPAgentIssue
+	for _, tool := range m.Health.Tools {
+		switch {
+		case tool.Status == MCPToolStatusMissing:
+			issues = append(issues, issue(MCPAgentIssueToolMissing, tool.Name, ""))
+		case tool.Status == MCPToolStatusInvalidSchema:
+			issues = append(issues, issue(MCPAgentIssueToolInvalidSchema, tool.Name, tool.Error))
+		case tool.SchemaChangedAt != nil && tool.SchemaChangedAt.After(referencedAt):
+			issues = append(issues, issue(MCPAgentIssueToolSchemaChanged, tool.Name,
+				"changed at "+tool.SchemaChangedAt.Format(time.RFC3339)))
+		}
+	}
+	return issues
+}
+
+// NewAgentMCPStatus reports the problems of the MCP services used by an agent, following its MCP
+// selection mode: the selected services, or every enabled service of the tenant
+func NewAgentMCPStatus(agent *CustomAgent, services []*MCPService) *AgentMCPStatus {
+	status := &AgentMCPStatus{AgentID: agent.ID, Issues: []*MCPAgentIssue{}}
+	config := agent.Config
+
+	switch {
+	case config.MCPSelectionMode == "none":
+	case config.MCPSelectionMode == "selected" && len(config.MCPServices) > 0:
+		byID := make(map[string]*MCPService, len(services))
+		for _, service := range services {
+			byID[service.ID] = service
+		}
+		for _, id := range config.MCPServices {
+			service, ok := byID[id]
+			if !ok {
+				status.Issues = append(status.Issues, &MCPAgentIssue{ServiceID: id, Issue: MCPAgentIssueServiceMissing})
+				continue
+			}
+			status.Issues = append(status.Issues, service.AgentIssues(agent.UpdatedAt)...)
+		}
+	default:
+		for _, service := range services {
+			if service.Enabled {
+				status.Issues = append(status.Issues, service.AgentIssues(agent.UpdatedAt)...)
+			}
+		}
+	}
+
+	status.Healthy = len(status.Issues) == 0
+	return status
+}
+
+// mcpSchemaHash hashes the canonical form of a JSON schema, or its raw bytes when it is not valid JSON
+func mcpSchemaHash(schema json.RawMessage) string {
+	canonical := []byte(schema)
+	var value interface{}
+	if err := json.Unmarshal(schema, &value); err == nil {
+		if b, err := json.Marshal(value); err == nil {
+			canonical = b
+		}
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// validateMCPToolSchema checks that a tool has a name and an object input schema
+func validateMCPToolSchema(tool *MCPTool) error {
+	if tool.Name == "" {
+		return fmt.Errorf("tool has no name")
+	}
+	if len(tool.InputSchema) == 0 {
+		return fmt.Errorf("input schema is missing")
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(tool.InputSchema, &schema); err != nil || schema == nil {
+		return fmt.Errorf("input schema is not a JSON object")
+	}
+	if schemaType, ok := schema["type"]; ok && schemaType != "object" {
+		return fmt.Errorf("input schema type is %v, want object", schemaType)
+	}
+	if properties, ok := schema["properties"]; ok {
+		if _, isObject := properties.(map[string]interface{}); !isObject {
+			return fmt.Errorf("input schema properties is not an object")
+		}
+	}
+	return nil
+}
+
+// Value implements driver.Valuer interface for MCPServiceHealth
+func (h *MCPServiceHealth) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	return json.Marshal(h)
+}
+
+// Scan implements sql.Scanner interface for MCPServiceHealth
+func (h *MCPServiceHealth) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, h)
+}
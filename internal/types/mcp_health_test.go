@@ -0,0 +1,125 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewMCPServiceHealth(t *testing.T) {
+	first := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	second := first.Add(10 * time.Minute)
+
+	health := NewMCPServiceHealth(nil, []*MCPTool{
+		{Name: "search", InputSchema: json.RawMessage(`{"type":"object","properties":{"q":{"type":"string"}}}`)},
+		{Name: "fetch", InputSchema: json.RawMessage(`{"type":"object"}`)},
+		{Name: "broken", InputSchema: json.RawMessage(`{"type":"string"}`)},
+	}, first)
+	statuses := toolStatuses(health)
+	if statuses["search"] != MCPToolStatusOK || statuses["fetch"] != MCPToolStatusOK ||
+		statuses["broken"] != MCPToolStatusInvalidSchema {
+		t.Fatalf("first check statuses = %v", statuses)
+	}
+	for _, tool := range health.Tools {
+		if tool.SchemaChangedAt != nil {
+			t.Errorf("tool %s first seen is marked changed", tool.Name)
+		}
+	}
+
+	// search keeps its schema with another key order, fetch gains a parameter and broken disappears
+	health = NewMCPServiceHealth(health, []*MCPTool{
+		{Name: "search", InputSchema: json.RawMessage(`{ "properties": {"q": {"type": "string"}}, "type": "object" }`)},
+		{Name: "fetch", InputSchema: json.RawMessage(`{"type":"object","properties":{"url":{"type":"string"}}}`)},
+	}, second)
+	tools := make(map[string]*MCPToolHealth)
+	for _, tool := range health.Tools {
+		tools[tool.Name] = tool
+	}
+	if tools["search"].SchemaChangedAt != nil {
+		t.Errorf("reordered schema is marked changed at %v", tools["search"].SchemaChangedAt)
+	}
+	if tools["fetch"].SchemaChangedAt == nil || !tools["fetch"].SchemaChangedAt.Equal(second) {
+		t.Errorf("changed schema changed at = %v, want %v", tools["fetch"].SchemaChangedAt, second)
+	}
+	if tools["broken"] == nil || tools["broken"].Status != MCPToolStatusMissing || !tools["broken"].MissingSince.Equal(second) {
+		t.Errorf("removed tool = %+v, want missing since %v", tools["broken"], second)
+	}
+
+	unreachable := UnreachableMCPServiceHealth(health, errors.New("connection refused"), second.Add(time.Minute))
+	if unreachable.Reachable || unreachable.Error != "connection refused" || len(unreachable.Tools) != 3 {
+		t.Errorf("unreachable health = %+v, want the previous tools kept", unreachable)
+	}
+}
+
+func TestNewAgentMCPStatus(t *testing.T) {
+	saved := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	before, after := saved.Add(-time.Hour), saved.Add(time.Hour)
+	missingSince := after
+
+	services := []*MCPService{
+		{ID: "search", Name: "Search", Enabled: true, Health: &MCPServiceHealth{Reachable: true, Tools: []*MCPToolHealth{
+			{Name: "query", Status: MCPToolStatusOK, SchemaChangedAt: &before},
+			{Name: "suggest", Status: MCPToolStatusOK, SchemaChangedAt: &after},
+			{Name: "legacy", Status: MCPToolStatusMissing, MissingSince: &missingSince},
+		}}},
+		{ID: "crm", Name: "CRM", Enabled: true, Health: &MCPServiceHealth{Error: "timeout"}},
+		{ID: "wiki", Name: "Wiki", Enabled: false},
+		{ID: "new", Name: "New", Enabled: true},
+	}
+
+	tests := []struct {
+		name   string
+		config CustomAgentConfig
+		want   []string
+	}{
+		{name: "no mcp", config: CustomAgentConfig{MCPSelectionMode: "none"}},
+		{
+			name:   "selected services",
+			config: CustomAgentConfig{MCPSelectionMode: "selected", MCPServices: []string{"search", "wiki", "gone"}},
+			want: []string{
+				"search/suggest:" + MCPAgentIssueToolSchemaChanged,
+				"search/legacy:" + MCPAgentIssueToolMissing,
+				"wiki/:" + MCPAgentIssueServiceDisabled,
+				"gone/:" + MCPAgentIssueServiceMissing,
+			},
+		},
+		{
+			name:   "all enabled services",
+			config: CustomAgentConfig{MCPSelectionMode: "all"},
+			want: []string{
+				"search/suggest:" + MCPAgentIssueToolSchemaChanged,
+				"search/legacy:" + MCPAgentIssueToolMissing,
+				"crm/:" + MCPAgentIssueServiceUnreachable,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := NewAgentMCPStatus(&CustomAgent{ID: "agent-1", UpdatedAt: saved, Config: tt.config}, services)
+			var got []string
+			for _, issue := range status.Issues {
+				got = append(got, issue.ServiceID+"/"+issue.Tool+":"+issue.Issue)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("issues = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("issue %d = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+			if status.Healthy != (len(tt.want) == 0) {
+				t.Errorf("healthy = %v with issues %v", status.Healthy, got)
+			}
+		})
+	}
+}
+
+func toolStatuses(health *MCPServiceHealth) map[string]string {
+	statuses := make(map[string]string, len(health.Tools))
+	for _, tool := range health.Tools {
+		statuses[tool.Name] = tool.Status
+	}
+	return statuses
+}
@@ -76,6 +76,13 @@ type Message struct {
 	// Mentioned knowledge bases and files (for user messages)
 	// Stores the @mentioned items when user sends a message
 	MentionedItems MentionedItems `json:"mentioned_items,omitempty" gorm:"type:jsonb,column:mentioned_items"`
+	// ID of the answer this answer was regenerated from (for regenerated assistant messages)
+	// Regenerated answers share the request ID of the original turn and the latest one is used as history
+	RegeneratedFrom string `json:"regenerated_from,omitempty" gorm:"type:varchar(36)"`
+	// Confidence of the answer (for assistant messages generated from knowledge bases)
+	Confidence *ConfidenceResult `json:"confidence,omitempty" gorm:"type:jsonb,column:confidence"`
+	// Set when the answer was generated from partial retrieval results cut short at the soft deadline
+	RetrievalDegradation *RetrievalDegradation `json:"retrieval_degradation,omitempty" gorm:"type:jsonb,column:retrieval_degradation"`
 	// Whether message generation is complete
 	IsCompleted bool `json:"is_completed"`
 	// Message creation timestamp
@@ -0,0 +1,97 @@
+package types
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidMessageCursor is returned when a message cursor cannot be decoded
+var ErrInvalidMessageCursor = errors.New("invalid message cursor")
+
+// MessageCursor is the position of a message in the history of a session. Messages are ordered by
+// creation time, then by ID for messages created at the same time, so every message has a distinct
+// position and pages read backward from a cursor never overlap or skip messages.
+type MessageCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// NewMessageCursor returns the position of the message
+func NewMessageCursor(message *Message) *MessageCursor {
+	return &MessageCursor{CreatedAt: message.CreatedAt, ID: message.ID}
+}
+
+// ParseMessageCursor decodes a cursor returned by MessageCursor.Encode
+func ParseMessageCursor(s string) (*MessageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidMessageCursor
+	}
+	nanos, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, ErrInvalidMessageCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidMessageCursor
+	}
+	return &MessageCursor{CreatedAt: time.Unix(0, n).UTC(), ID: id}, nil
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c *MessageCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString(
+		[]byte(strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + "|" + c.ID))
+}
+
+// Precedes reports whether the message comes before the cursor in the history
+func (c *MessageCursor) Precedes(message *Message) bool {
+	if message.CreatedAt.Equal(c.CreatedAt) {
+		return message.ID < c.ID
+	}
+	return message.CreatedAt.Before(c.CreatedAt)
+}
+
+// MessagePage is a page of the message history of a session
+type MessagePage struct {
+	Messages []*Message
+	// NextCursor loads the messages before this page; empty when the page reaches the first message
+	NextCursor string
+	// HasMore is false once the beginning of the conversation is reached
+	HasMore bool
+}
+
+// NewMessagePage builds a page from messages fetched with one more than the limit, in any order.
+// When there are more messages than the limit, the oldest one only tells that more messages
+// exist: it is left out and the next cursor points at the oldest message of the page.
+func NewMessagePage(messages []*Message, limit int) *MessagePage {
+	if len(messages) <= limit {
+		return &MessagePage{Messages: messages}
+	}
+	oldest := oldestMessage(messages)
+	page := make([]*Message, 0, len(messages)-1)
+	for i, message := range messages {
+		if i != oldest {
+			page = append(page, message)
+		}
+	}
+	return &MessagePage{
+		Messages:   page,
+		NextCursor: NewMessageCursor(page[oldestMessage(page)]).Encode(),
+		HasMore:    true,
+	}
+}
+
+// oldestMessage returns the index of the first message of the history among messages
+func oldestMessage(messages []*Message) int {
+	oldest := 0
+	for i := 1; i < len(messages); i++ {
+		if NewMessageCursor(messages[oldest]).Precedes(messages[i]) {
+			oldest = i
+		}
+	}
+	return oldest
+}
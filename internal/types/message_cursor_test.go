@@ -0,0 +1,72 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMessageCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 10, 16, 8, 30, 2, 123456789, time.UTC)
+	cursor := NewMessageCursor(&Message{ID: "9bcafbcf-a758-40af-a9a3-c4d8e0f49439", CreatedAt: createdAt})
+
+	parsed, err := ParseMessageCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("ParseMessageCursor() error = %v", err)
+	}
+	if !parsed.CreatedAt.Equal(createdAt) || parsed.ID != cursor.ID {
+		t.Errorf("parsed cursor = %+v, want %+v", parsed, cursor)
+	}
+
+	for _, s := range []string{"", "not base64!", "MTIz", "YWJjfGlk", "MTIzfA"} {
+		if _, err := ParseMessageCursor(s); err != ErrInvalidMessageCursor {
+			t.Errorf("ParseMessageCursor(%q) error = %v, want ErrInvalidMessageCursor", s, err)
+		}
+	}
+}
+
+func TestMessageCursorPrecedes(t *testing.T) {
+	now := time.Now()
+	cursor := &MessageCursor{CreatedAt: now, ID: "m-5"}
+	tests := []struct {
+		message *Message
+		want    bool
+	}{
+		{message: &Message{ID: "m-9", CreatedAt: now.Add(-time.Millisecond)}, want: true},
+		{message: &Message{ID: "m-4", CreatedAt: now}, want: true},
+		{message: &Message{ID: "m-5", CreatedAt: now}, want: false},
+		{message: &Message{ID: "m-6", CreatedAt: now}, want: false},
+		{message: &Message{ID: "m-1", CreatedAt: now.Add(time.Millisecond)}, want: false},
+	}
+	for _, tt := range tests {
+		if got := cursor.Precedes(tt.message); got != tt.want {
+			t.Errorf("Precedes(%s at %v) = %v, want %v", tt.message.ID, tt.message.CreatedAt.Sub(now), got, tt.want)
+		}
+	}
+}
+
+func TestNewMessagePage(t *testing.T) {
+	now := time.Now()
+	messages := []*Message{
+		{ID: "b", CreatedAt: now},
+		{ID: "c", CreatedAt: now.Add(time.Second)},
+		{ID: "a", CreatedAt: now},
+	}
+
+	page := NewMessagePage(messages, 3)
+	if len(page.Messages) != 3 || page.HasMore || page.NextCursor != "" {
+		t.Errorf("page within the limit = %+v, want all messages and no more", page)
+	}
+
+	// The extra message is the oldest one, whatever the order of the messages
+	page = NewMessagePage(messages, 2)
+	if !page.HasMore || len(page.Messages) != 2 || page.Messages[0].ID != "b" || page.Messages[1].ID != "c" {
+		t.Fatalf("page over the limit = %+v, want b and c with more", page)
+	}
+	cursor, err := ParseMessageCursor(page.NextCursor)
+	if err != nil {
+		t.Fatalf("ParseMessageCursor() error = %v", err)
+	}
+	if cursor.ID != "b" || !cursor.Precedes(messages[2]) {
+		t.Errorf("next cursor = %+v, want the position of b", cursor)
+	}
+}
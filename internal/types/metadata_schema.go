@@ -0,0 +1,219 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata field types
+const (
+	MetadataFieldTypeString  = "string"
+	MetadataFieldTypeNumber  = "number"
+	MetadataFieldTypeBoolean = "boolean"
+	// MetadataFieldTypeDate holds a calendar date, stored as YYYY-MM-DD
+	MetadataFieldTypeDate = "date"
+)
+
+// Handling of metadata that does not match the schema
+const (
+	// MetadataMismatchReject rejects the whole metadata (default)
+	MetadataMismatchReject = "reject"
+	// MetadataMismatchCoerce converts values leniently and drops unknown fields and values that cannot be converted
+	MetadataMismatchCoerce = "coerce"
+)
+
+// metadataFieldNamePattern restricts field names to keys that are safe in JSON path expressions
+var metadataFieldNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// MetadataField declares a metadata key and the type of its value
+type MetadataField struct {
+	Name string `yaml:"name"     json:"name"`
+	// Type is string, number, boolean or date
+	Type string `yaml:"type"     json:"type"`
+	// Required rejects metadata without the field
+	Required bool `yaml:"required" json:"required,omitempty"`
+}
+
+// MetadataSchemaConfig declares the metadata accepted for knowledge in a knowledge base.
+// Knowledge metadata is validated against it on ingestion and update, and only its fields can be
+// filtered on in search. Without a schema any metadata is accepted.
+type MetadataSchemaConfig struct {
+	Fields []MetadataField `yaml:"fields"      json:"fields"`
+	// OnMismatch is reject (default) or coerce
+	OnMismatch string `yaml:"on_mismatch" json:"on_mismatch,omitempty"`
+}
+
+// Validate checks field names, types and the mismatch handling
+func (c *MetadataSchemaConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.OnMismatch {
+	case "", MetadataMismatchReject, MetadataMismatchCoerce:
+	default:
+		return fmt.Errorf("unsupported on_mismatch %q, expected reject or coerce", c.OnMismatch)
+	}
+	seen := make(map[string]bool, len(c.Fields))
+	for _, field := range c.Fields {
+		if !metadataFieldNamePattern.MatchString(field.Name) {
+			return fmt.Errorf("invalid metadata field name %q", field.Name)
+		}
+		if seen[field.Name] {
+			return fmt.Errorf("duplicate metadata field %q", field.Name)
+		}
+		seen[field.Name] = true
+		switch field.Type {
+		case MetadataFieldTypeString, MetadataFieldTypeNumber, MetadataFieldTypeBoolean, MetadataFieldTypeDate:
+		default:
+			return fmt.Errorf("unsupported type %q of metadata field %q", field.Type, field.Name)
+		}
+	}
+	return nil
+}
+
+// IsZero reports whether no schema is defined
+func (c *MetadataSchemaConfig) IsZero() bool {
+	return c == nil || len(c.Fields) == 0
+}
+
+// FilterableFields returns the names of the fields that can be filtered on in search
+func (c *MetadataSchemaConfig) FilterableFields() []string {
+	if c.IsZero() {
+		return nil
+	}
+	names := make([]string, 0, len(c.Fields))
+	for _, field := range c.Fields {
+		names = append(names, field.Name)
+	}
+	return names
+}
+
+func (c *MetadataSchemaConfig) field(name string) (MetadataField, bool) {
+	for _, field := range c.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return MetadataField{}, false
+}
+
+// Apply validates metadata against the schema and returns it with values in their canonical form.
+// Without a schema the metadata is returned unchanged.
+func (c *MetadataSchemaConfig) Apply(metadata map[string]string) (map[string]string, error) {
+	if c.IsZero() {
+		return metadata, nil
+	}
+	coerce := c.OnMismatch == MetadataMismatchCoerce
+	result := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		field, ok := c.field(key)
+		if !ok {
+			if coerce {
+				continue
+			}
+			return nil, fmt.Errorf("metadata field %q is not defined in the schema", key)
+		}
+		normalized, ok := normalizeMetadataValue(field.Type, value, coerce)
+		if !ok {
+			if coerce {
+				continue
+			}
+			return nil, fmt.Errorf("value %q of metadata field %q is not a valid %s", value, key, field.Type)
+		}
+		result[key] = normalized
+	}
+	for _, field := range c.Fields {
+		if _, ok := result[field.Name]; field.Required && !ok {
+			return nil, fmt.Errorf("required metadata field %q is missing or invalid", field.Name)
+		}
+	}
+	return result, nil
+}
+
+// NormalizeFilter checks that the filtered fields are defined in the schema and converts the values to the
+// canonical form stored with the metadata. Without a schema the filter is returned unchanged.
+func (c *MetadataSchemaConfig) NormalizeFilter(filter map[string]string) (map[string]string, error) {
+	if c.IsZero() {
+		return filter, nil
+	}
+	result := make(map[string]string, len(filter))
+	for key, value := range filter {
+		field, ok := c.field(key)
+		if !ok {
+			return nil, fmt.Errorf("metadata field %q is not filterable, filterable fields: %s",
+				key, strings.Join(c.FilterableFields(), ", "))
+		}
+		normalized, ok := normalizeMetadataValue(field.Type, value, true)
+		if !ok {
+			return nil, fmt.Errorf("value %q of metadata field %q is not a valid %s", value, key, field.Type)
+		}
+		result[key] = normalized
+	}
+	return result, nil
+}
+
+// normalizeMetadataValue converts a value to the canonical form of the type; lenient also accepts
+// common alternative spellings such as thousands separators or yes/no
+func normalizeMetadataValue(fieldType, value string, lenient bool) (string, bool) {
+	value = strings.TrimSpace(value)
+	switch fieldType {
+	case MetadataFieldTypeNumber:
+		if lenient {
+			value = strings.ReplaceAll(value, ",", "")
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", false
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), true
+	case MetadataFieldTypeBoolean:
+		if lenient {
+			switch strings.ToLower(value) {
+			case "yes", "y", "on":
+				return "true", true
+			case "no", "n", "off":
+				return "false", true
+			}
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", false
+		}
+		return strconv.FormatBool(b), true
+	case MetadataFieldTypeDate:
+		layouts := []string{time.DateOnly, time.RFC3339}
+		if lenient {
+			layouts = append(layouts, "2006/01/02", time.DateTime, "2006/1/2", "2006-1-2")
+		}
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.Format(time.DateOnly), true
+			}
+		}
+		return "", false
+	default:
+		return value, true
+	}
+}
+
+// Value implements driver.Valuer
+func (c MetadataSchemaConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *MetadataSchemaConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
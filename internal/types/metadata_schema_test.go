@@ -0,0 +1,101 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetadataSchemaConfigApply(t *testing.T) {
+	fields := []MetadataField{
+		{Name: "department", Type: MetadataFieldTypeString, Required: true},
+		{Name: "year", Type: MetadataFieldTypeNumber},
+		{Name: "public", Type: MetadataFieldTypeBoolean},
+		{Name: "published", Type: MetadataFieldTypeDate},
+	}
+
+	tests := []struct {
+		name     string
+		config   *MetadataSchemaConfig
+		metadata map[string]string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "no schema",
+			config:   nil,
+			metadata: map[string]string{"anything": "goes"},
+			want:     map[string]string{"anything": "goes"},
+		},
+		{
+			name:     "canonical values",
+			config:   &MetadataSchemaConfig{Fields: fields},
+			metadata: map[string]string{"department": " hr ", "year": "2024.0", "public": "TRUE", "published": "2024-03-01T10:00:00Z"},
+			want:     map[string]string{"department": "hr", "year": "2024", "public": "true", "published": "2024-03-01"},
+		},
+		{
+			name:     "reject unknown field",
+			config:   &MetadataSchemaConfig{Fields: fields},
+			metadata: map[string]string{"department": "hr", "owner": "bob"},
+			wantErr:  true,
+		},
+		{
+			name:     "reject invalid value",
+			config:   &MetadataSchemaConfig{Fields: fields},
+			metadata: map[string]string{"department": "hr", "year": "1,024"},
+			wantErr:  true,
+		},
+		{
+			name:     "coerce",
+			config:   &MetadataSchemaConfig{Fields: fields, OnMismatch: MetadataMismatchCoerce},
+			metadata: map[string]string{"department": "hr", "year": "1,024", "public": "yes", "published": "soon", "owner": "bob"},
+			want:     map[string]string{"department": "hr", "year": "1024", "public": "true"},
+		},
+		{
+			name:     "missing required field",
+			config:   &MetadataSchemaConfig{Fields: fields, OnMismatch: MetadataMismatchCoerce},
+			metadata: map[string]string{"year": "2024"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.Apply(tt.metadata)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadataSchemaConfigNormalizeFilter(t *testing.T) {
+	config := &MetadataSchemaConfig{Fields: []MetadataField{{Name: "year", Type: MetadataFieldTypeNumber}}}
+
+	got, err := config.NormalizeFilter(map[string]string{"year": "2024.00"})
+	if err != nil || got["year"] != "2024" {
+		t.Fatalf("NormalizeFilter() = %v, %v", got, err)
+	}
+	if _, err := config.NormalizeFilter(map[string]string{"owner": "bob"}); err == nil {
+		t.Error("expected error for a field outside the schema")
+	}
+}
+
+func TestMetadataSchemaConfigValidate(t *testing.T) {
+	invalid := []*MetadataSchemaConfig{
+		{Fields: []MetadataField{{Name: "a-b", Type: MetadataFieldTypeString}}},
+		{Fields: []MetadataField{{Name: "a", Type: "int"}}},
+		{Fields: []MetadataField{{Name: "a", Type: "string"}, {Name: "a", Type: "number"}}},
+		{OnMismatch: "ignore"},
+	}
+	for _, config := range invalid {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected error", config)
+		}
+	}
+	var none *MetadataSchemaConfig
+	if err := none.Validate(); err != nil {
+		t.Errorf("nil config: %v", err)
+	}
+}
@@ -52,6 +52,8 @@ const (
 type EmbeddingParameters struct {
 	Dimension            int `yaml:"dimension"              json:"dimension"`
 	TruncatePromptTokens int `yaml:"truncate_prompt_tokens" json:"truncate_prompt_tokens"`
+	// Normalize L2-normalizes the vectors returned by the provider, at ingestion and query time alike
+	Normalize bool `yaml:"normalize"              json:"normalize"`
 }
 
 type ModelParameters struct {
@@ -122,3 +124,12 @@ func (m *Model) BeforeCreate(tx *gorm.DB) (err error) {
 	m.ID = uuid.New().String()
 	return nil
 }
+
+// ModelBatchResult is the outcome of a batch operation for one model
+type ModelBatchResult struct {
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// References are the resources that prevented the operation
+	References []ResourceReference `json:"references,omitempty"`
+}
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestKnowledgeBaseModelFields(t *testing.T) {
+	kb := &KnowledgeBase{
+		EmbeddingModelID:      "embed",
+		SummaryModelID:        "chat",
+		VLMConfig:             VLMConfig{ModelID: "vision"},
+		ImageProcessingConfig: ImageProcessingConfig{ModelID: "vision"},
+		VectorSpaceConfig:     &VectorSpaceConfig{Spaces: []VectorSpace{{Name: "multilingual", EmbeddingModelID: "embed-m3"}}},
+	}
+	tests := []struct {
+		name    string
+		modelID string
+		want    []string
+	}{
+		{name: "embedding model", modelID: "embed", want: []string{"embedding_model_id"}},
+		{name: "summary model", modelID: "chat", want: []string{"summary_model_id"}},
+		{name: "vision model in two fields", modelID: "vision", want: []string{"vlm_config.model_id", "image_processing_config.model_id"}},
+		{name: "vector space model", modelID: "embed-m3", want: []string{"vector_space_config.spaces.multilingual"}},
+		{name: "unused model", modelID: "rerank"},
+		{name: "empty model ID", modelID: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KnowledgeBaseModelFields(kb, tt.modelID); !slices.Equal(got, tt.want) {
+				t.Errorf("KnowledgeBaseModelFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgentModelFields(t *testing.T) {
+	agent := &CustomAgent{}
+	agent.Config.ModelID = "chat"
+	agent.Config.RerankModelID = "rerank"
+
+	tests := []struct {
+		modelID string
+		want    []string
+	}{
+		{modelID: "chat", want: []string{"model_id"}},
+		{modelID: "rerank", want: []string{"rerank_model_id"}},
+		{modelID: "embed"},
+		{modelID: ""},
+	}
+	for _, tt := range tests {
+		if got := AgentModelFields(agent, tt.modelID); !slices.Equal(got, tt.want) {
+			t.Errorf("AgentModelFields(%q) = %v, want %v", tt.modelID, got, tt.want)
+		}
+	}
+}
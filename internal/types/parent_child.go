@@ -0,0 +1,146 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Retrieval modes of knowledge base searches
+const (
+	// RetrievalModeChunk returns the matched chunks themselves
+	RetrievalModeChunk = "chunk"
+	// RetrievalModeParent returns the parent chunk of each matched child chunk instead (small-to-big)
+	RetrievalModeParent = "parent"
+)
+
+// Parent chunk size limits, in characters
+const (
+	MinParentChunkSize = 256
+	MaxParentChunkSize = 16384
+)
+
+// ParentChildConfig configures parent-child (small-to-big) chunking. Consecutive chunks of a document are
+// grouped into larger parent chunks on ingestion: the small child chunks are embedded and retrieved for
+// precision, while the parent they belong to gives the model enough context. Parent chunks are stored
+// but not indexed.
+type ParentChildConfig struct {
+	// ParentChunkSize is the size in characters up to which consecutive chunks are grouped into a
+	// parent chunk; 0 disables parent chunks
+	ParentChunkSize int `yaml:"parent_chunk_size" json:"parent_chunk_size"`
+	// RetrievalMode is the default retrieval mode of searches, "parent" (default) or "chunk"
+	RetrievalMode string `yaml:"retrieval_mode"    json:"retrieval_mode,omitempty"`
+}
+
+// Validate checks the parent chunk size range and the retrieval mode
+func (c *ParentChildConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.ParentChunkSize != 0 &&
+		(c.ParentChunkSize < MinParentChunkSize || c.ParentChunkSize > MaxParentChunkSize) {
+		return fmt.Errorf("parent_chunk_size must be 0 or between %d and %d", MinParentChunkSize, MaxParentChunkSize)
+	}
+	return ValidateRetrievalMode(c.RetrievalMode)
+}
+
+// IsEnabled reports whether documents are grouped into parent chunks on ingestion
+func (c *ParentChildConfig) IsEnabled() bool {
+	return c != nil && c.ParentChunkSize > 0
+}
+
+// ResolveRetrievalMode returns the retrieval mode of a search, override taking precedence over the
+// knowledge base default when set. Without a configuration, chunks are returned as matched.
+func (c *ParentChildConfig) ResolveRetrievalMode(override string) string {
+	switch {
+	case override != "":
+		return override
+	case c == nil:
+		return RetrievalModeChunk
+	case c.RetrievalMode != "":
+		return c.RetrievalMode
+	case c.IsEnabled():
+		return RetrievalModeParent
+	default:
+		return RetrievalModeChunk
+	}
+}
+
+// Value implements driver.Valuer
+func (c ParentChildConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *ParentChildConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// ValidateRetrievalMode checks a retrieval mode; an empty mode is the default
+func ValidateRetrievalMode(mode string) error {
+	switch mode {
+	case "", RetrievalModeChunk, RetrievalModeParent:
+		return nil
+	default:
+		return fmt.Errorf("retrieval_mode must be %q or %q", RetrievalModeChunk, RetrievalModeParent)
+	}
+}
+
+// ExpandToParents replaces the results whose parent chunk is among the results with that parent, in the
+// rank of its best child, and drops the other parent chunks. The parent keeps the best child's score,
+// match type and matched content, so that it stays clear why the parent was retrieved.
+func ExpandToParents(results []*SearchResult) []*SearchResult {
+	parents := make(map[string]*SearchResult)
+	for _, result := range results {
+		if result.ChunkType == ChunkTypeParent {
+			parents[result.ID] = result
+		}
+	}
+	if len(parents) == 0 {
+		return results
+	}
+
+	expanded := make([]*SearchResult, 0, len(results))
+	added := make(map[string]bool)
+	for _, result := range results {
+		if result.ChunkType == ChunkTypeParent {
+			continue
+		}
+		parent, ok := parents[result.ParentChunkID]
+		if !ok {
+			expanded = append(expanded, result)
+			continue
+		}
+		if added[parent.ID] {
+			continue
+		}
+		added[parent.ID] = true
+		p := *parent
+		p.Score = result.Score
+		p.MatchType = result.MatchType
+		p.MatchedContent = result.Content
+		if len(p.SectionPath) == 0 {
+			p.SectionPath = result.SectionPath
+		}
+		expanded = append(expanded, &p)
+	}
+	return expanded
+}
+
+// DropParents removes parent chunks added to results as context of their children
+func DropParents(results []*SearchResult) []*SearchResult {
+	kept := results[:0:0]
+	for _, result := range results {
+		if result.ChunkType != ChunkTypeParent {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
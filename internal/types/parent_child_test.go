@@ -0,0 +1,58 @@
+package types
+
+import "testing"
+
+func TestParentChildConfigRetrievalMode(t *testing.T) {
+	var none *ParentChildConfig
+	if got := none.ResolveRetrievalMode(""); got != RetrievalModeChunk {
+		t.Errorf("without a config the mode is %q, want chunk", got)
+	}
+	enabled := &ParentChildConfig{ParentChunkSize: 2000}
+	if got := enabled.ResolveRetrievalMode(""); got != RetrievalModeParent {
+		t.Errorf("with parent chunks the mode is %q, want parent", got)
+	}
+	if got := enabled.ResolveRetrievalMode(RetrievalModeChunk); got != RetrievalModeChunk {
+		t.Errorf("the request override is %q, want chunk", got)
+	}
+	if got := (&ParentChildConfig{ParentChunkSize: 2000, RetrievalMode: RetrievalModeChunk}).ResolveRetrievalMode(""); got != RetrievalModeChunk {
+		t.Errorf("the configured mode is %q, want chunk", got)
+	}
+
+	for _, invalid := range []*ParentChildConfig{
+		{ParentChunkSize: MinParentChunkSize - 1},
+		{ParentChunkSize: MaxParentChunkSize + 1},
+		{RetrievalMode: "document"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want an error", invalid)
+		}
+	}
+}
+
+func TestExpandToParents(t *testing.T) {
+	results := []*SearchResult{
+		{ID: "c2", ParentChunkID: "p1", Score: 0.9, Content: "child 2"},
+		{ID: "c9", Score: 0.8},
+		{ID: "c1", ParentChunkID: "p1", Score: 0.7},
+		{ID: "p1", ChunkType: ChunkTypeParent, Score: 0.7, Content: "parent"},
+		{ID: "c5", ParentChunkID: "p2", Score: 0.6},
+	}
+	expanded := ExpandToParents(results)
+	var ids []string
+	for _, r := range expanded {
+		ids = append(ids, r.ID)
+	}
+	if len(ids) != 3 || ids[0] != "p1" || ids[1] != "c9" || ids[2] != "c5" {
+		t.Fatalf("ExpandToParents() = %v, want [p1 c9 c5]", ids)
+	}
+	if expanded[0].Score != 0.9 || expanded[0].MatchedContent != "child 2" || expanded[0].Content != "parent" {
+		t.Errorf("parent should carry the best child's score and content, got %+v", expanded[0])
+	}
+	if results[3].Score != 0.7 {
+		t.Error("ExpandToParents must not modify the input results")
+	}
+
+	if dropped := DropParents(results); len(dropped) != 4 {
+		t.Errorf("DropParents() kept %d results, want 4", len(dropped))
+	}
+}
@@ -0,0 +1,67 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// maxPayloadLimitBytes bounds the payload size limits of a tenant override
+const maxPayloadLimitBytes = 1 << 30
+
+// PayloadLimitConfig overrides the payload size limits of a tenant. Zero values use the global defaults.
+type PayloadLimitConfig struct {
+	// MaxRequestBytes is the largest request body the tenant may send
+	MaxRequestBytes int64 `json:"max_request_bytes"`
+	// MaxResponseBytes is the largest response body the tenant may receive
+	MaxResponseBytes int64 `json:"max_response_bytes"`
+	// Disabled exempts the tenant from payload size limits
+	Disabled bool `json:"disabled"`
+}
+
+// Validate checks that the override is within bounds
+func (c *PayloadLimitConfig) Validate() error {
+	if c.MaxRequestBytes < 0 || c.MaxRequestBytes > maxPayloadLimitBytes {
+		return fmt.Errorf("max_request_bytes must be between 0 and %d", maxPayloadLimitBytes)
+	}
+	if c.MaxResponseBytes < 0 || c.MaxResponseBytes > maxPayloadLimitBytes {
+		return fmt.Errorf("max_response_bytes must be between 0 and %d", maxPayloadLimitBytes)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (c PayloadLimitConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *PayloadLimitConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// PayloadLimits applies the override of the tenant to the default payload size limits.
+// A zero limit means no limit; an exempted tenant has none.
+func (t *Tenant) PayloadLimits(maxRequestBytes, maxResponseBytes int64) (int64, int64) {
+	if t == nil || t.PayloadLimitConfig == nil {
+		return maxRequestBytes, maxResponseBytes
+	}
+	override := t.PayloadLimitConfig
+	if override.Disabled {
+		return 0, 0
+	}
+	if override.MaxRequestBytes > 0 {
+		maxRequestBytes = override.MaxRequestBytes
+	}
+	if override.MaxResponseBytes > 0 {
+		maxResponseBytes = override.MaxResponseBytes
+	}
+	return maxRequestBytes, maxResponseBytes
+}
@@ -0,0 +1,235 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Match types of pinned source rules
+const (
+	// PinnedMatchKeyword matches queries containing one of the rule's patterns, ignoring case
+	PinnedMatchKeyword = "keyword"
+	// PinnedMatchSemantic matches queries whose embedding is similar enough to one of the rule's patterns
+	PinnedMatchSemantic = "semantic"
+)
+
+// Retrieval origins reported in debug mode
+const (
+	// RetrievalOriginOrganic marks a result found by the search itself
+	RetrievalOriginOrganic = "organic"
+	// RetrievalOriginPinned marks a result forced to the top by a pinned source rule
+	RetrievalOriginPinned = "pinned"
+)
+
+// Pinned source rule limits
+const (
+	MaxPinnedSourceRules            = 50
+	MaxPinnedSourcePatterns         = 20
+	MaxPinnedSourceKnowledge        = 10
+	MaxPinnedChunksPerKnowledge     = 5
+	DefaultPinnedChunksPerKnowledge = 1
+	DefaultPinnedSemanticThreshold  = 0.8
+)
+
+// PinnedSourceRule forces knowledge items to the top of the retrieval results of matching queries
+type PinnedSourceRule struct {
+	// ID of the rule, unique within the knowledge base
+	ID string `json:"id"`
+	// Name describes the rule for the people curating it
+	Name string `json:"name"`
+	// MatchType is keyword (default) or semantic
+	MatchType string `json:"match_type"`
+	// Patterns the query is matched against; the rule matches when any pattern does
+	Patterns []string `json:"patterns"`
+	// Threshold is the minimum query-pattern similarity of semantic rules (default 0.8)
+	Threshold float64 `json:"threshold,omitempty"`
+	// KnowledgeIDs are the pinned knowledge items, in the order they are placed at the top
+	KnowledgeIDs []string `json:"knowledge_ids"`
+	// ChunksPerKnowledge is the number of leading chunks injected for a pinned knowledge item
+	// the search didn't find (default 1)
+	ChunksPerKnowledge int `json:"chunks_per_knowledge,omitempty"`
+	// Enabled rules are applied to searches
+	Enabled bool `json:"enabled"`
+	// Creation time of the rule
+	CreatedAt time.Time `json:"created_at"`
+	// Last updated time of the rule
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Normalize trims patterns, removes empty and duplicate entries and fills in the match type
+func (r *PinnedSourceRule) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.MatchType == "" {
+		r.MatchType = PinnedMatchKeyword
+	}
+	r.Patterns = compactStrings(r.Patterns)
+	r.KnowledgeIDs = compactStrings(r.KnowledgeIDs)
+}
+
+// compactStrings trims values and drops empty and repeated ones, keeping the first occurrence
+func compactStrings(values []string) []string {
+	compacted := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" && !slices.Contains(compacted, value) {
+			compacted = append(compacted, value)
+		}
+	}
+	return compacted
+}
+
+// Validate checks the match type, patterns, pinned knowledge and limits of a normalized rule
+func (r *PinnedSourceRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch r.MatchType {
+	case PinnedMatchKeyword, PinnedMatchSemantic:
+	default:
+		return fmt.Errorf("unsupported match_type %q, expected keyword or semantic", r.MatchType)
+	}
+	if len(r.Patterns) == 0 || len(r.Patterns) > MaxPinnedSourcePatterns {
+		return fmt.Errorf("patterns must contain between 1 and %d entries", MaxPinnedSourcePatterns)
+	}
+	if len(r.KnowledgeIDs) == 0 || len(r.KnowledgeIDs) > MaxPinnedSourceKnowledge {
+		return fmt.Errorf("knowledge_ids must contain between 1 and %d entries", MaxPinnedSourceKnowledge)
+	}
+	if r.Threshold < 0 || r.Threshold > 1 {
+		return fmt.Errorf("threshold must be between 0 and 1")
+	}
+	if r.ChunksPerKnowledge < 0 || r.ChunksPerKnowledge > MaxPinnedChunksPerKnowledge {
+		return fmt.Errorf("chunks_per_knowledge must be between 0 and %d", MaxPinnedChunksPerKnowledge)
+	}
+	return nil
+}
+
+// SemanticThreshold returns the minimum similarity of a semantic match
+func (r *PinnedSourceRule) SemanticThreshold() float64 {
+	if r.Threshold > 0 {
+		return r.Threshold
+	}
+	return DefaultPinnedSemanticThreshold
+}
+
+// InjectedChunks returns the number of chunks injected for a pinned knowledge item missing from the results
+func (r *PinnedSourceRule) InjectedChunks() int {
+	if r.ChunksPerKnowledge > 0 {
+		return r.ChunksPerKnowledge
+	}
+	return DefaultPinnedChunksPerKnowledge
+}
+
+// MatchKeyword returns the first pattern contained in the query, ignoring case and repeated whitespace
+func (r *PinnedSourceRule) MatchKeyword(query string) (string, bool) {
+	normalizedQuery := NormalizeAnswerCacheQuery(query)
+	for _, pattern := range r.Patterns {
+		if strings.Contains(normalizedQuery, NormalizeAnswerCacheQuery(pattern)) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// PinnedSourceRules is the list of pinned source rules of a knowledge base
+type PinnedSourceRules []*PinnedSourceRule
+
+// Find returns the rule with the given ID, nil if there is none
+func (r PinnedSourceRules) Find(id string) *PinnedSourceRule {
+	for _, rule := range r {
+		if rule.ID == id {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Enabled returns the enabled rules of the given match type
+func (r PinnedSourceRules) Enabled(matchType string) PinnedSourceRules {
+	var enabled PinnedSourceRules
+	for _, rule := range r {
+		if rule.Enabled && rule.MatchType == matchType {
+			enabled = append(enabled, rule)
+		}
+	}
+	return enabled
+}
+
+// Value implements driver.Valuer
+func (r PinnedSourceRules) Value() (driver.Value, error) {
+	if r == nil {
+		return json.Marshal([]*PinnedSourceRule{})
+	}
+	return json.Marshal([]*PinnedSourceRule(r))
+}
+
+// Scan implements sql.Scanner
+func (r *PinnedSourceRules) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, r)
+}
+
+// PinnedTrace explains which rule pinned a search result
+type PinnedTrace struct {
+	RuleID    string `json:"rule_id"`
+	RuleName  string `json:"rule_name"`
+	MatchType string `json:"match_type"`
+	// Pattern is the pattern the query matched
+	Pattern string `json:"pattern"`
+	// Similarity between the query and the pattern of a semantic match
+	Similarity float64 `json:"similarity,omitempty"`
+	// Injected is set when the search didn't find the knowledge and its leading chunks were added
+	Injected bool `json:"injected"`
+}
+
+// PinnedKnowledge is a knowledge item pinned for a query by a matching rule
+type PinnedKnowledge struct {
+	KnowledgeID string
+	// Chunks is the number of chunks injected when the search didn't find the knowledge
+	Chunks int
+	Trace  PinnedTrace
+}
+
+// PinSearchResults moves the results of pinned knowledge to the top, in pinned order and keeping the
+// relative order of each knowledge's results. It returns the reordered results and the pinned knowledge
+// without any result, whose chunks have to be injected.
+func PinSearchResults(results []*SearchResult,
+	pinned []*PinnedKnowledge,
+) ([]*SearchResult, []*PinnedKnowledge) {
+	reordered := make([]*SearchResult, 0, len(results))
+	taken := make(map[*SearchResult]bool)
+	var missing []*PinnedKnowledge
+	for _, pin := range pinned {
+		found := false
+		for _, result := range results {
+			if result.KnowledgeID != pin.KnowledgeID || taken[result] {
+				continue
+			}
+			if result.Pinned == nil {
+				trace := pin.Trace
+				result.Pinned = &trace
+			}
+			reordered = append(reordered, result)
+			taken[result] = true
+			found = true
+		}
+		if !found {
+			missing = append(missing, pin)
+		}
+	}
+	for _, result := range results {
+		if !taken[result] {
+			reordered = append(reordered, result)
+		}
+	}
+	return reordered, missing
+}
@@ -0,0 +1,73 @@
+package types
+
+import "testing"
+
+func TestPinnedSourceRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    PinnedSourceRule
+		wantErr bool
+	}{
+		{"keyword default", PinnedSourceRule{Name: "r", Patterns: []string{"refund"}, KnowledgeIDs: []string{"k1"}}, false},
+		{"semantic", PinnedSourceRule{Name: "r", MatchType: PinnedMatchSemantic, Patterns: []string{"refund"},
+			KnowledgeIDs: []string{"k1"}, Threshold: 0.7}, false},
+		{"no name", PinnedSourceRule{Patterns: []string{"refund"}, KnowledgeIDs: []string{"k1"}}, true},
+		{"blank patterns", PinnedSourceRule{Name: "r", Patterns: []string{" ", ""}, KnowledgeIDs: []string{"k1"}}, true},
+		{"no knowledge", PinnedSourceRule{Name: "r", Patterns: []string{"refund"}}, true},
+		{"unknown match type", PinnedSourceRule{Name: "r", MatchType: "regex", Patterns: []string{"refund"},
+			KnowledgeIDs: []string{"k1"}}, true},
+		{"threshold above 1", PinnedSourceRule{Name: "r", Patterns: []string{"refund"}, KnowledgeIDs: []string{"k1"},
+			Threshold: 1.5}, true},
+		{"too many chunks", PinnedSourceRule{Name: "r", Patterns: []string{"refund"}, KnowledgeIDs: []string{"k1"},
+			ChunksPerKnowledge: MaxPinnedChunksPerKnowledge + 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := tt.rule
+			rule.Normalize()
+			if err := rule.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPinnedSourceRuleMatchKeyword(t *testing.T) {
+	rule := &PinnedSourceRule{Patterns: []string{"Money  Back", "refund"}}
+	if pattern, ok := rule.MatchKeyword("How do I get my money back?"); !ok || pattern != "Money  Back" {
+		t.Errorf("expected a case and whitespace insensitive match, got %q %v", pattern, ok)
+	}
+	if _, ok := rule.MatchKeyword("How do I reset my password?"); ok {
+		t.Error("expected no match")
+	}
+}
+
+func TestPinSearchResults(t *testing.T) {
+	a1 := &SearchResult{ID: "a1", KnowledgeID: "a"}
+	b1 := &SearchResult{ID: "b1", KnowledgeID: "b"}
+	a2 := &SearchResult{ID: "a2", KnowledgeID: "a"}
+	c1 := &SearchResult{ID: "c1", KnowledgeID: "c"}
+	pinned := []*PinnedKnowledge{
+		{KnowledgeID: "c", Trace: PinnedTrace{RuleID: "r1"}},
+		{KnowledgeID: "missing", Trace: PinnedTrace{RuleID: "r1"}},
+		{KnowledgeID: "a", Trace: PinnedTrace{RuleID: "r2"}},
+	}
+
+	results, missing := PinSearchResults([]*SearchResult{a1, b1, a2, c1}, pinned)
+
+	want := []string{"c1", "a1", "a2", "b1"}
+	for i, id := range want {
+		if results[i].ID != id {
+			t.Fatalf("result %d = %s, want %s", i, results[i].ID, id)
+		}
+	}
+	if len(missing) != 1 || missing[0].KnowledgeID != "missing" {
+		t.Errorf("expected the knowledge without results to be reported missing, got %+v", missing)
+	}
+	if c1.Pinned == nil || c1.Pinned.RuleID != "r1" || a2.Pinned == nil || a2.Pinned.RuleID != "r2" {
+		t.Error("expected pinned results to carry the trace of their rule")
+	}
+	if b1.Pinned != nil {
+		t.Error("expected organic results not to be marked pinned")
+	}
+}
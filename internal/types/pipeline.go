@@ -0,0 +1,146 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Ingestion pipeline stages, in the order they run
+const (
+	// PipelineStageExtract parses the document into text; without it the file is only stored
+	PipelineStageExtract = "extract"
+	// PipelineStageChunk splits the text into chunks; without it each parsed document is kept whole
+	PipelineStageChunk = "chunk"
+	// PipelineStageEmbed embeds the chunks for vector retrieval; without it they are indexed for keyword retrieval only
+	PipelineStageEmbed = "embed"
+	// PipelineStageGenerateQuestions generates questions for the chunks to improve their recall
+	PipelineStageGenerateQuestions = "generate_questions"
+	// PipelineStageExtractImages extracts the images of documents with OCR and captions
+	PipelineStageExtractImages = "extract_images"
+	// PipelineStageSummarize generates a summary of each document
+	PipelineStageSummarize = "summarize"
+)
+
+// PipelineConfig enables or disables the ingestion stages of a knowledge base. A stage left unset
+// follows the option it replaces: questions follow question_generation_config, images follow the
+// multimodal setting and summaries follow document_summary_config; the other stages run.
+type PipelineConfig struct {
+	Extract           *bool `yaml:"extract"            json:"extract,omitempty"`
+	Chunk             *bool `yaml:"chunk"              json:"chunk,omitempty"`
+	Embed             *bool `yaml:"embed"              json:"embed,omitempty"`
+	GenerateQuestions *bool `yaml:"generate_questions" json:"generate_questions,omitempty"`
+	ExtractImages     *bool `yaml:"extract_images"     json:"extract_images,omitempty"`
+	Summarize         *bool `yaml:"summarize"          json:"summarize,omitempty"`
+}
+
+// PipelineStages are the stages that run for a document
+type PipelineStages struct {
+	Extract           bool
+	Chunk             bool
+	Embed             bool
+	GenerateQuestions bool
+	ExtractImages     bool
+	Summarize         bool
+}
+
+// Validate rejects stages enabled while a stage they depend on is disabled
+func (c *PipelineConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if isFalse(c.Extract) {
+		for stage, enabled := range map[string]*bool{
+			PipelineStageChunk:             c.Chunk,
+			PipelineStageEmbed:             c.Embed,
+			PipelineStageGenerateQuestions: c.GenerateQuestions,
+			PipelineStageExtractImages:     c.ExtractImages,
+			PipelineStageSummarize:         c.Summarize,
+		} {
+			if isTrue(enabled) {
+				return fmt.Errorf("%s requires the %s stage", stage, PipelineStageExtract)
+			}
+		}
+	}
+	// Generated questions are indexed for vector retrieval
+	if isFalse(c.Embed) && isTrue(c.GenerateQuestions) {
+		return fmt.Errorf("%s requires the %s stage", PipelineStageGenerateQuestions, PipelineStageEmbed)
+	}
+	return nil
+}
+
+// Resolve applies the configured stages over legacy, the stages the knowledge base options select.
+// Stages depending on a disabled stage are disabled as well.
+func (c *PipelineConfig) Resolve(legacy PipelineStages) PipelineStages {
+	stages := legacy
+	if c != nil {
+		stages = PipelineStages{
+			Extract:           boolOr(c.Extract, legacy.Extract),
+			Chunk:             boolOr(c.Chunk, legacy.Chunk),
+			Embed:             boolOr(c.Embed, legacy.Embed),
+			GenerateQuestions: boolOr(c.GenerateQuestions, legacy.GenerateQuestions),
+			ExtractImages:     boolOr(c.ExtractImages, legacy.ExtractImages),
+			Summarize:         boolOr(c.Summarize, legacy.Summarize),
+		}
+	}
+	if !stages.Extract {
+		return PipelineStages{}
+	}
+	if !stages.Embed {
+		stages.GenerateQuestions = false
+	}
+	return stages
+}
+
+// Skipped returns the names of the stages that do not run, in pipeline order
+func (s PipelineStages) Skipped() StringArray {
+	skipped := StringArray{}
+	for _, stage := range []struct {
+		name    string
+		enabled bool
+	}{
+		{PipelineStageExtract, s.Extract},
+		{PipelineStageChunk, s.Chunk},
+		{PipelineStageEmbed, s.Embed},
+		{PipelineStageGenerateQuestions, s.GenerateQuestions},
+		{PipelineStageExtractImages, s.ExtractImages},
+		{PipelineStageSummarize, s.Summarize},
+	} {
+		if !stage.enabled {
+			skipped = append(skipped, stage.name)
+		}
+	}
+	return skipped
+}
+
+// Value implements driver.Valuer
+func (c PipelineConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *PipelineConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+func boolOr(value *bool, fallback bool) bool {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
+func isTrue(value *bool) bool {
+	return value != nil && *value
+}
+
+func isFalse(value *bool) bool {
+	return value != nil && !*value
+}
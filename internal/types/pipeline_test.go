@@ -0,0 +1,58 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPipelineConfigResolve(t *testing.T) {
+	enabled, disabled := true, false
+	legacy := PipelineStages{Extract: true, Chunk: true, Embed: true, GenerateQuestions: true, Summarize: true}
+
+	var none *PipelineConfig
+	if got := none.Resolve(legacy); got != legacy {
+		t.Errorf("without a config the stages are %+v, want the legacy ones", got)
+	}
+
+	tests := []struct {
+		name    string
+		config  *PipelineConfig
+		skipped StringArray
+	}{
+		{
+			name:    "unset stages follow the legacy options",
+			config:  &PipelineConfig{ExtractImages: &enabled, Summarize: &disabled},
+			skipped: StringArray{PipelineStageSummarize},
+		},
+		{
+			name:   "store only",
+			config: &PipelineConfig{Extract: &disabled},
+			skipped: StringArray{PipelineStageExtract, PipelineStageChunk, PipelineStageEmbed,
+				PipelineStageGenerateQuestions, PipelineStageExtractImages, PipelineStageSummarize},
+		},
+		{
+			name:    "keyword only skips the questions",
+			config:  &PipelineConfig{Embed: &disabled},
+			skipped: StringArray{PipelineStageEmbed, PipelineStageGenerateQuestions, PipelineStageExtractImages},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.Resolve(legacy).Skipped(); !reflect.DeepEqual(got, tt.skipped) {
+				t.Errorf("skipped stages = %v, want %v", got, tt.skipped)
+			}
+		})
+	}
+
+	for _, invalid := range []*PipelineConfig{
+		{Extract: &disabled, Chunk: &enabled},
+		{Embed: &disabled, GenerateQuestions: &enabled},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want an error", invalid)
+		}
+	}
+	if err := (&PipelineConfig{Extract: &disabled, Summarize: &disabled}).Validate(); err != nil {
+		t.Errorf("Validate() = %v, want disabling stages to be accepted", err)
+	}
+}
@@ -1,5 +1,11 @@
 package types
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
 // PromptPlaceholder represents a placeholder that can be used in prompt templates
 type PromptPlaceholder struct {
 	// Name is the placeholder name (without braces), e.g., "query"
@@ -163,3 +169,112 @@ func PlaceholderMap() map[PromptFieldType][]PromptPlaceholder {
 		PromptFieldFallbackPrompt:      PlaceholdersByField(PromptFieldFallbackPrompt),
 	}
 }
+
+// RequiredPlaceholdersByField returns the placeholders that must appear in a non-empty template of the given field type
+func RequiredPlaceholdersByField(fieldType PromptFieldType) []PromptPlaceholder {
+	switch fieldType {
+	case PromptFieldContextTemplate:
+		// Without {{contexts}} the retrieved content never reaches the model
+		return []PromptPlaceholder{PlaceholderContexts}
+	case PromptFieldRewritePrompt:
+		return []PromptPlaceholder{PlaceholderQuery}
+	default:
+		return []PromptPlaceholder{}
+	}
+}
+
+// PromptTemplateError describes a placeholder problem found in a prompt template
+type PromptTemplateError struct {
+	// Field is the prompt field that failed validation
+	Field PromptFieldType `json:"field"`
+	// Token is the offending token as it appears in the template (empty for missing placeholders)
+	Token string `json:"token,omitempty"`
+	// Offset is the byte offset of the token in the template (-1 for missing placeholders)
+	Offset int `json:"offset"`
+	// Line and Column locate the token in the template (1-based, 0 for missing placeholders)
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Reason explains why the template was rejected
+	Reason string `json:"reason"`
+}
+
+// Error implements the error interface
+func (e *PromptTemplateError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+	}
+	return fmt.Sprintf("%s: %s at line %d, column %d: %q", e.Field, e.Reason, e.Line, e.Column, e.Token)
+}
+
+// placeholderNamePattern matches a well-formed placeholder name
+var placeholderNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ValidatePromptTemplate checks that a template only uses well-formed placeholders known for the field type
+// and that all required placeholders are present. Empty templates are valid (defaults are used instead).
+func ValidatePromptTemplate(fieldType PromptFieldType, template string) error {
+	if strings.TrimSpace(template) == "" {
+		return nil
+	}
+
+	allowed := make(map[string]bool)
+	for _, p := range PlaceholdersByField(fieldType) {
+		allowed[p.Name] = true
+	}
+	newError := func(offset int, token, reason string) *PromptTemplateError {
+		line, column := 1, 1
+		for _, r := range template[:offset] {
+			if r == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+		}
+		return &PromptTemplateError{
+			Field: fieldType, Token: token, Offset: offset, Line: line, Column: column, Reason: reason,
+		}
+	}
+
+	used := make(map[string]bool)
+	pos := 0
+	for {
+		openIdx := strings.Index(template[pos:], "{{")
+		if openIdx < 0 {
+			break
+		}
+		start := pos + openIdx
+		closeIdx := strings.Index(template[start+2:], "}}")
+		if closeIdx < 0 {
+			token := template[start:]
+			if nl := strings.IndexByte(token, '\n'); nl >= 0 {
+				token = token[:nl]
+			}
+			return newError(start, token, "unclosed placeholder")
+		}
+		end := start + 2 + closeIdx
+		token := template[start : end+2]
+		name := template[start+2 : end]
+		if strings.Contains(name, "{{") {
+			return newError(start, token, "nested placeholder braces")
+		}
+		if !placeholderNamePattern.MatchString(name) {
+			return newError(start, token, "malformed placeholder (expected {{name}} without spaces)")
+		}
+		if !allowed[name] {
+			return newError(start, token, "unknown placeholder for this field")
+		}
+		used[name] = true
+		pos = end + 2
+	}
+
+	for _, p := range RequiredPlaceholdersByField(fieldType) {
+		if !used[p.Name] {
+			return &PromptTemplateError{
+				Field:  fieldType,
+				Offset: -1,
+				Reason: fmt.Sprintf("missing required placeholder {{%s}}", p.Name),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestValidatePromptTemplate(t *testing.T) {
+	tests := []struct {
+		name       string
+		field      PromptFieldType
+		template   string
+		wantErr    bool
+		wantToken  string
+		wantLine   int
+		wantColumn int
+	}{
+		{
+			name:     "empty template",
+			field:    PromptFieldContextTemplate,
+			template: "",
+		},
+		{
+			name:     "valid context template",
+			field:    PromptFieldContextTemplate,
+			template: "Reference:\n{{contexts}}\nQuestion: {{query}}",
+		},
+		{
+			name:       "unknown placeholder",
+			field:      PromptFieldContextTemplate,
+			template:   "{{contexts}}\nHello {{user_name}}",
+			wantErr:    true,
+			wantToken:  "{{user_name}}",
+			wantLine:   2,
+			wantColumn: 7,
+		},
+		{
+			name:       "placeholder not allowed for field",
+			field:      PromptFieldAgentSystemPrompt,
+			template:   "Answer {{query}}",
+			wantErr:    true,
+			wantToken:  "{{query}}",
+			wantLine:   1,
+			wantColumn: 8,
+		},
+		{
+			name:       "spaces inside braces",
+			field:      PromptFieldContextTemplate,
+			template:   "{{ contexts }}",
+			wantErr:    true,
+			wantToken:  "{{ contexts }}",
+			wantLine:   1,
+			wantColumn: 1,
+		},
+		{
+			name:       "unclosed placeholder",
+			field:      PromptFieldContextTemplate,
+			template:   "{{contexts}} {{query\nmore text",
+			wantErr:    true,
+			wantToken:  "{{query",
+			wantLine:   1,
+			wantColumn: 14,
+		},
+		{
+			name:     "missing required placeholder",
+			field:    PromptFieldContextTemplate,
+			template: "Question: {{query}}",
+			wantErr:  true,
+		},
+		{
+			name:     "json braces are not placeholders",
+			field:    PromptFieldAgentSystemPrompt,
+			template: `Reply as {"answer": {"text": "..."}} at {{current_time}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePromptTemplate(tt.field, tt.template)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidatePromptTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil || tt.wantToken == "" {
+				return
+			}
+			templateErr, ok := err.(*PromptTemplateError)
+			if !ok {
+				t.Fatalf("expected *PromptTemplateError, got %T", err)
+			}
+			if templateErr.Token != tt.wantToken {
+				t.Errorf("Token = %q, want %q", templateErr.Token, tt.wantToken)
+			}
+			if templateErr.Line != tt.wantLine || templateErr.Column != tt.wantColumn {
+				t.Errorf("position = %d:%d, want %d:%d",
+					templateErr.Line, templateErr.Column, tt.wantLine, tt.wantColumn)
+			}
+		})
+	}
+}
+
+func TestBuiltinAgentPromptTemplatesAreValid(t *testing.T) {
+	for _, id := range GetBuiltinAgentIDs() {
+		agent := GetBuiltinAgent(id, 1)
+		if agent == nil {
+			continue
+		}
+		if err := agent.Config.ValidatePromptTemplates(); err != nil {
+			t.Errorf("built-in agent %s has invalid prompt templates: %v", id, err)
+		}
+	}
+}
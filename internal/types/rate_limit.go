@@ -0,0 +1,51 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Bounds of tenant rate limit overrides
+const (
+	maxRateLimitRequestsPerSecond = 10000
+	maxRateLimitBurst             = 100000
+)
+
+// RateLimitConfig overrides the request rate limit of a tenant. Zero values use the global defaults.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained request rate of the tenant
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	// Burst is the number of requests the tenant may send at once
+	Burst int `json:"burst"`
+	// Disabled exempts the tenant from rate limiting
+	Disabled bool `json:"disabled"`
+}
+
+// Validate checks that the override is within bounds
+func (c *RateLimitConfig) Validate() error {
+	if c.RequestsPerSecond < 0 || c.RequestsPerSecond > maxRateLimitRequestsPerSecond {
+		return fmt.Errorf("requests_per_second must be between 0 and %d", maxRateLimitRequestsPerSecond)
+	}
+	if c.Burst < 0 || c.Burst > maxRateLimitBurst {
+		return fmt.Errorf("burst must be between 0 and %d", maxRateLimitBurst)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (c RateLimitConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *RateLimitConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
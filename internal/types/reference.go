@@ -0,0 +1,185 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+)
+
+// Types of resources that reference other resources
+const (
+	ReferenceTypeKnowledgeBase = "knowledge_base"
+	ReferenceTypeAgent         = "agent"
+	ReferenceTypeKnowledge     = "knowledge"
+)
+
+// MaxListedReferences limits the references listed in a resource in use error
+const MaxListedReferences = 20
+
+// ResourceReference is a resource that references another resource
+type ResourceReference struct {
+	// Type is knowledge_base, agent or knowledge
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Fields are the configuration fields that hold the reference
+	Fields []string `json:"fields,omitempty"`
+}
+
+// ResourceInUseError is returned when a resource that is still referenced is deleted without force
+type ResourceInUseError struct {
+	// Code is the error code, e.g. model.in_use
+	Code    string
+	Message string
+	// References lists up to MaxListedReferences referencing resources
+	References []ResourceReference
+	// Total is the number of references, it can exceed the listed ones
+	Total int64
+}
+
+// NewResourceInUseError creates a resource in use error for the references
+func NewResourceInUseError(code, resource string, references []ResourceReference, total int64) *ResourceInUseError {
+	if int64(len(references)) > total {
+		total = int64(len(references))
+	}
+	if len(references) > MaxListedReferences {
+		references = references[:MaxListedReferences]
+	}
+	return &ResourceInUseError{
+		Code:       code,
+		Message:    fmt.Sprintf("%s is referenced by %d resources, delete with force=true to remove the references", resource, total),
+		References: references,
+		Total:      total,
+	}
+}
+
+// Error implements the error interface
+func (e *ResourceInUseError) Error() string {
+	return e.Message
+}
+
+// AppError reports the error as a conflict listing the references
+func (e *ResourceInUseError) AppError() *werrors.AppError {
+	return werrors.NewConflictError(e.Message).WithCode(e.Code).WithDetails(map[string]any{
+		"references": e.References,
+		"total":      e.Total,
+	})
+}
+
+// KnowledgeBaseModelFields returns the configuration fields of the knowledge base that use the model
+func KnowledgeBaseModelFields(kb *KnowledgeBase, modelID string) []string {
+	if modelID == "" {
+		return nil
+	}
+	var fields []string
+	if kb.EmbeddingModelID == modelID {
+		fields = append(fields, "embedding_model_id")
+	}
+	if kb.SummaryModelID == modelID {
+		fields = append(fields, "summary_model_id")
+	}
+	if kb.RerankModelID == modelID {
+		fields = append(fields, "rerank_model_id")
+	}
+	if kb.VLMConfig.ModelID == modelID {
+		fields = append(fields, "vlm_config.model_id")
+	}
+	if kb.ImageProcessingConfig.ModelID == modelID {
+		fields = append(fields, "image_processing_config.model_id")
+	}
+	if kb.VectorSpaceConfig != nil {
+		for _, space := range kb.VectorSpaceConfig.Spaces {
+			if space.EmbeddingModelID == modelID {
+				fields = append(fields, "vector_space_config.spaces."+space.Name)
+			}
+		}
+	}
+	return fields
+}
+
+// ClearKnowledgeBaseModel removes the optional references of the knowledge base to the model. Embedding
+// models are kept, the indexed data depends on them; it reports whether the knowledge base was changed.
+func ClearKnowledgeBaseModel(kb *KnowledgeBase, modelID string) bool {
+	if modelID == "" {
+		return false
+	}
+	changed := false
+	if kb.SummaryModelID == modelID {
+		kb.SummaryModelID = ""
+		changed = true
+	}
+	if kb.RerankModelID == modelID {
+		kb.RerankModelID = ""
+		changed = true
+	}
+	if kb.VLMConfig.ModelID == modelID {
+		kb.VLMConfig.ModelID = ""
+		kb.VLMConfig.Enabled = false
+		changed = true
+	}
+	if kb.ImageProcessingConfig.ModelID == modelID {
+		kb.ImageProcessingConfig.ModelID = ""
+		changed = true
+	}
+	return changed
+}
+
+// KnowledgeBaseEmbedsWithModel reports whether the knowledge base embeds its chunks with the model,
+// as primary embedding model or in a vector space
+func KnowledgeBaseEmbedsWithModel(kb *KnowledgeBase, modelID string) bool {
+	for _, field := range KnowledgeBaseModelFields(kb, modelID) {
+		if field == "embedding_model_id" || strings.HasPrefix(field, "vector_space_config.") {
+			return true
+		}
+	}
+	return false
+}
+
+// AgentModelFields returns the configuration fields of the agent that use the model
+func AgentModelFields(agent *CustomAgent, modelID string) []string {
+	if modelID == "" {
+		return nil
+	}
+	var fields []string
+	if agent.Config.ModelID == modelID {
+		fields = append(fields, "model_id")
+	}
+	if agent.Config.RerankModelID == modelID {
+		fields = append(fields, "rerank_model_id")
+	}
+	return fields
+}
+
+// ClearAgentModel removes the references of the agent to the model and reports whether it was changed
+func ClearAgentModel(agent *CustomAgent, modelID string) bool {
+	if modelID == "" {
+		return false
+	}
+	changed := false
+	if agent.Config.ModelID == modelID {
+		agent.Config.ModelID = ""
+		changed = true
+	}
+	if agent.Config.RerankModelID == modelID {
+		agent.Config.RerankModelID = ""
+		changed = true
+	}
+	return changed
+}
+
+// AgentUsesKnowledgeBase reports whether the agent is configured with the knowledge base
+func AgentUsesKnowledgeBase(agent *CustomAgent, kbID string) bool {
+	return kbID != "" && slices.Contains(agent.Config.KnowledgeBases, kbID)
+}
+
+// RemoveAgentKnowledgeBase removes the knowledge base from the agent and reports whether it was changed
+func RemoveAgentKnowledgeBase(agent *CustomAgent, kbID string) bool {
+	if !AgentUsesKnowledgeBase(agent, kbID) {
+		return false
+	}
+	agent.Config.KnowledgeBases = slices.DeleteFunc(slices.Clone(agent.Config.KnowledgeBases),
+		func(id string) bool { return id == kbID })
+	return true
+}
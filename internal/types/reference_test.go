@@ -0,0 +1,97 @@
+package types
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	werrors "github.com/Tencent/WeKnora/internal/errors"
+)
+
+func TestKnowledgeBaseModelReferences(t *testing.T) {
+	kb := &KnowledgeBase{
+		EmbeddingModelID: "embed",
+		SummaryModelID:   "chat",
+		RerankModelID:    "rerank",
+		VLMConfig:        VLMConfig{Enabled: true, ModelID: "vlm"},
+		VectorSpaceConfig: &VectorSpaceConfig{Spaces: []VectorSpace{
+			{Name: "small", EmbeddingModelID: "embed-small"},
+		}},
+	}
+
+	if got := KnowledgeBaseModelFields(kb, "chat"); !slices.Equal(got, []string{"summary_model_id"}) {
+		t.Errorf("summary model fields = %v", got)
+	}
+	if got := KnowledgeBaseModelFields(kb, "rerank"); !slices.Equal(got, []string{"rerank_model_id"}) {
+		t.Errorf("rerank model fields = %v", got)
+	}
+	if got := KnowledgeBaseModelFields(kb, "embed-small"); !slices.Equal(got, []string{"vector_space_config.spaces.small"}) {
+		t.Errorf("vector space fields = %v", got)
+	}
+	if got := KnowledgeBaseModelFields(kb, ""); got != nil {
+		t.Errorf("empty model id should not match, got %v", got)
+	}
+
+	if !KnowledgeBaseEmbedsWithModel(kb, "embed") || !KnowledgeBaseEmbedsWithModel(kb, "embed-small") {
+		t.Error("embedding models should be reported")
+	}
+	if KnowledgeBaseEmbedsWithModel(kb, "chat") {
+		t.Error("summary model is not an embedding model")
+	}
+
+	if !ClearKnowledgeBaseModel(kb, "vlm") || kb.VLMConfig.ModelID != "" || kb.VLMConfig.Enabled {
+		t.Errorf("vlm model should be cleared and disabled, got %+v", kb.VLMConfig)
+	}
+	if !ClearKnowledgeBaseModel(kb, "rerank") || kb.RerankModelID != "" {
+		t.Error("rerank model should be cleared")
+	}
+	if ClearKnowledgeBaseModel(kb, "embed") || kb.EmbeddingModelID != "embed" {
+		t.Error("embedding model must be kept")
+	}
+}
+
+func TestAgentReferences(t *testing.T) {
+	agent := &CustomAgent{Config: CustomAgentConfig{
+		ModelID:        "chat",
+		RerankModelID:  "rerank",
+		KnowledgeBases: []string{"kb1", "kb2"},
+	}}
+
+	if got := AgentModelFields(agent, "rerank"); !slices.Equal(got, []string{"rerank_model_id"}) {
+		t.Errorf("rerank fields = %v", got)
+	}
+	if !ClearAgentModel(agent, "chat") || agent.Config.ModelID != "" {
+		t.Error("chat model should be cleared")
+	}
+
+	if !AgentUsesKnowledgeBase(agent, "kb1") || AgentUsesKnowledgeBase(agent, "kb3") {
+		t.Error("unexpected knowledge base usage")
+	}
+	if !RemoveAgentKnowledgeBase(agent, "kb1") || !slices.Equal(agent.Config.KnowledgeBases, []string{"kb2"}) {
+		t.Errorf("knowledge bases = %v", agent.Config.KnowledgeBases)
+	}
+	if RemoveAgentKnowledgeBase(agent, "kb1") {
+		t.Error("removing a missing knowledge base should not change the agent")
+	}
+}
+
+func TestResourceInUseError(t *testing.T) {
+	refs := make([]ResourceReference, MaxListedReferences+5)
+	for i := range refs {
+		refs[i] = ResourceReference{Type: ReferenceTypeKnowledge, ID: "k"}
+	}
+
+	err := NewResourceInUseError(werrors.CodeTagInUse, "tag", refs, 100)
+	appErr := werrors.FromError(err)
+	if appErr.HTTPCode != http.StatusConflict || appErr.Reason != werrors.CodeTagInUse {
+		t.Fatalf("unexpected app error %+v", appErr)
+	}
+	if len(err.References) != MaxListedReferences || err.Total != 100 {
+		t.Errorf("references = %d, total = %d", len(err.References), err.Total)
+	}
+
+	// The total is never smaller than the listed references
+	if err := NewResourceInUseError(werrors.CodeModelInUse, "model", refs[:2], 0); err.Total != 2 {
+		t.Errorf("total = %d", err.Total)
+	}
+}
@@ -0,0 +1,92 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Behaviors of retrieval when the rerank model is unavailable
+const (
+	// RerankFailureDegrade keeps the results in their retrieval (vector/keyword) order
+	RerankFailureDegrade = "degrade"
+	// RerankFailureFail fails the request
+	RerankFailureFail = "fail"
+)
+
+// MaxRerankTimeoutSeconds is the maximum rerank call timeout
+const MaxRerankTimeoutSeconds = 300
+
+// RerankFallbackConfig decides what retrieval does when the rerank model cannot be loaded, errors or
+// times out. By default the results fall back to their retrieval order so the question is still answered.
+type RerankFallbackConfig struct {
+	// OnFailure is "degrade" (default) or "fail"
+	OnFailure string `yaml:"on_failure"      json:"on_failure,omitempty"`
+	// TimeoutSeconds bounds the rerank call; 0 keeps the model client timeout
+	TimeoutSeconds int `yaml:"timeout_seconds" json:"timeout_seconds,omitempty"`
+}
+
+// Validate checks the failure behavior and the timeout range
+func (c *RerankFallbackConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.OnFailure {
+	case "", RerankFailureDegrade, RerankFailureFail:
+	default:
+		return fmt.Errorf("on_failure must be %q or %q", RerankFailureDegrade, RerankFailureFail)
+	}
+	if c.TimeoutSeconds < 0 || c.TimeoutSeconds > MaxRerankTimeoutSeconds {
+		return fmt.Errorf("timeout_seconds must be between 0 and %d", MaxRerankTimeoutSeconds)
+	}
+	return nil
+}
+
+// FailOnError reports whether a rerank failure fails the request
+func (c *RerankFallbackConfig) FailOnError() bool {
+	return c != nil && c.OnFailure == RerankFailureFail
+}
+
+// Timeout returns the rerank call timeout, 0 when not bounded
+func (c *RerankFallbackConfig) Timeout() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// Stricter combines two policies: the request fails if either policy fails it and the shorter timeout wins
+func (c *RerankFallbackConfig) Stricter(other *RerankFallbackConfig) *RerankFallbackConfig {
+	if c == nil {
+		return other
+	}
+	if other == nil {
+		return c
+	}
+	res := *c
+	if other.FailOnError() {
+		res.OnFailure = RerankFailureFail
+	}
+	if other.TimeoutSeconds > 0 && (res.TimeoutSeconds == 0 || other.TimeoutSeconds < res.TimeoutSeconds) {
+		res.TimeoutSeconds = other.TimeoutSeconds
+	}
+	return &res
+}
+
+// Value implements driver.Valuer
+func (c RerankFallbackConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *RerankFallbackConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
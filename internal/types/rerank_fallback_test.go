@@ -0,0 +1,43 @@
+package types
+
+import "testing"
+
+func TestRerankFallbackConfigValidate(t *testing.T) {
+	valid := []*RerankFallbackConfig{
+		nil,
+		{},
+		{OnFailure: RerankFailureDegrade, TimeoutSeconds: 5},
+		{OnFailure: RerankFailureFail, TimeoutSeconds: MaxRerankTimeoutSeconds},
+	}
+	for _, c := range valid {
+		if err := c.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", c, err)
+		}
+	}
+	invalid := []*RerankFallbackConfig{
+		{OnFailure: "retry"},
+		{TimeoutSeconds: -1},
+		{TimeoutSeconds: MaxRerankTimeoutSeconds + 1},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted an invalid config", c)
+		}
+	}
+}
+
+func TestRerankFallbackConfigStricter(t *testing.T) {
+	degrade := &RerankFallbackConfig{TimeoutSeconds: 10}
+	fail := &RerankFallbackConfig{OnFailure: RerankFailureFail, TimeoutSeconds: 30}
+	got := degrade.Stricter(fail)
+	if !got.FailOnError() || got.TimeoutSeconds != 10 {
+		t.Errorf("Stricter() = %+v, want fail with a 10s timeout", got)
+	}
+	if degrade.FailOnError() || degrade.TimeoutSeconds != 10 {
+		t.Errorf("Stricter() modified its receiver: %+v", degrade)
+	}
+	var none *RerankFallbackConfig
+	if none.Stricter(nil).FailOnError() {
+		t.Error("without a policy a rerank failure fails the request")
+	}
+}
@@ -0,0 +1,42 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// DefaultRetrievalMinChunks is the number of chunks needed to stop retrieval at its soft deadline when none is configured
+const DefaultRetrievalMinChunks = 1
+
+// RetrievalDegradationSoftDeadline is the reason of a retrieval stopped at its soft deadline
+const RetrievalDegradationSoftDeadline = "soft_deadline"
+
+// RetrievalDegradation notes that the answer was generated from partial retrieval results: the
+// searches still running at the soft deadline were cancelled and their results left out.
+type RetrievalDegradation struct {
+	// Reason is why retrieval was cut short ("soft_deadline")
+	Reason string `json:"reason"`
+	// SoftDeadlineMs is the configured soft deadline
+	SoftDeadlineMs int64 `json:"soft_deadline_ms"`
+	// ElapsedMs is the time retrieval ran before it was cut short
+	ElapsedMs int64 `json:"elapsed_ms"`
+	// RetrievedChunks is the number of chunks retrieved when retrieval was cut short
+	RetrievedChunks int `json:"retrieved_chunks"`
+}
+
+// Value implements driver.Valuer
+func (d RetrievalDegradation) Value() (driver.Value, error) {
+	return json.Marshal(d)
+}
+
+// Scan implements sql.Scanner
+func (d *RetrievalDegradation) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, d)
+}
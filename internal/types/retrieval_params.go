@@ -0,0 +1,177 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MaxRetrievalTopK is the largest top-k accepted in retrieval parameters
+const MaxRetrievalTopK = 100
+
+// RetrievalParams holds retrieval parameters of a knowledge QA request. A session stores them as defaults
+// that later requests inherit; zero values are not set and keep the agent or global configuration.
+type RetrievalParams struct {
+	// Number of results recalled by vector and keyword search
+	EmbeddingTopK int `json:"embedding_top_k,omitempty"`
+	// Minimum score of vector search results (0-1)
+	VectorThreshold float64 `json:"vector_threshold,omitempty"`
+	// Minimum score of keyword search results (0-1)
+	KeywordThreshold float64 `json:"keyword_threshold,omitempty"`
+	// Rerank model ID
+	RerankModelID string `json:"rerank_model_id,omitempty"`
+	// Number of results kept after reranking
+	RerankTopK int `json:"rerank_top_k,omitempty"`
+	// Minimum score of reranked results (0-1)
+	RerankThreshold float64 `json:"rerank_threshold,omitempty"`
+	// Knowledge bases searched when the request selects none
+	KnowledgeBaseIDs []string `json:"knowledge_base_ids,omitempty"`
+	// Knowledge (files) searched when the request selects none
+	KnowledgeIDs []string `json:"knowledge_ids,omitempty"`
+	// Match document summaries first and search the chunks of the matched documents; unset follows
+	// the knowledge base configuration
+	SummaryFirst *bool `json:"summary_first,omitempty"`
+	// Return matched chunks ("chunk") or their parent chunks ("parent"); unset follows the knowledge base
+	// configuration
+	RetrievalMode string `json:"retrieval_mode,omitempty"`
+	// Most chunks of one knowledge item among the results; 0 disables the quota and unset follows the
+	// knowledge base configuration
+	MaxChunksPerKnowledge *int `json:"max_chunks_per_knowledge,omitempty"`
+}
+
+// Validate checks the ranges of the parameters
+func (p *RetrievalParams) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.EmbeddingTopK < 0 || p.EmbeddingTopK > MaxRetrievalTopK {
+		return fmt.Errorf("embedding_top_k must be between 0 and %d", MaxRetrievalTopK)
+	}
+	if p.RerankTopK < 0 || p.RerankTopK > MaxRetrievalTopK {
+		return fmt.Errorf("rerank_top_k must be between 0 and %d", MaxRetrievalTopK)
+	}
+	for name, threshold := range map[string]float64{
+		"vector_threshold":  p.VectorThreshold,
+		"keyword_threshold": p.KeywordThreshold,
+		"rerank_threshold":  p.RerankThreshold,
+	} {
+		if threshold < 0 || threshold > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	if p.MaxChunksPerKnowledge != nil {
+		if err := (&SourceQuotaConfig{MaxChunksPerKnowledge: *p.MaxChunksPerKnowledge}).Validate(); err != nil {
+			return err
+		}
+	}
+	return ValidateRetrievalMode(p.RetrievalMode)
+}
+
+// IsEmpty reports whether no parameter is set
+func (p *RetrievalParams) IsEmpty() bool {
+	return p == nil || (p.EmbeddingTopK == 0 && p.VectorThreshold == 0 && p.KeywordThreshold == 0 &&
+		p.RerankModelID == "" && p.RerankTopK == 0 && p.RerankThreshold == 0 &&
+		len(p.KnowledgeBaseIDs) == 0 && len(p.KnowledgeIDs) == 0 && p.SummaryFirst == nil &&
+		p.RetrievalMode == "" && p.MaxChunksPerKnowledge == nil)
+}
+
+// Merge returns the parameters with the set fields of override taking precedence. The search targets are
+// overridden together, so a request selecting only knowledge IDs does not inherit the knowledge bases.
+func (p *RetrievalParams) Merge(override *RetrievalParams) *RetrievalParams {
+	if p == nil && override == nil {
+		return nil
+	}
+	merged := RetrievalParams{}
+	if p != nil {
+		merged = *p
+	}
+	if override == nil {
+		return &merged
+	}
+	if override.EmbeddingTopK > 0 {
+		merged.EmbeddingTopK = override.EmbeddingTopK
+	}
+	if override.VectorThreshold > 0 {
+		merged.VectorThreshold = override.VectorThreshold
+	}
+	if override.KeywordThreshold > 0 {
+		merged.KeywordThreshold = override.KeywordThreshold
+	}
+	if override.RerankModelID != "" {
+		merged.RerankModelID = override.RerankModelID
+	}
+	if override.RerankTopK > 0 {
+		merged.RerankTopK = override.RerankTopK
+	}
+	if override.RerankThreshold > 0 {
+		merged.RerankThreshold = override.RerankThreshold
+	}
+	if override.SummaryFirst != nil {
+		merged.SummaryFirst = override.SummaryFirst
+	}
+	if override.RetrievalMode != "" {
+		merged.RetrievalMode = override.RetrievalMode
+	}
+	if override.MaxChunksPerKnowledge != nil {
+		merged.MaxChunksPerKnowledge = override.MaxChunksPerKnowledge
+	}
+	if len(override.KnowledgeBaseIDs) > 0 || len(override.KnowledgeIDs) > 0 {
+		merged.KnowledgeBaseIDs = override.KnowledgeBaseIDs
+		merged.KnowledgeIDs = override.KnowledgeIDs
+	}
+	return &merged
+}
+
+// Apply overrides the retrieval settings of the chat with the set parameters
+func (p *RetrievalParams) Apply(chatManage *ChatManage) {
+	if p == nil {
+		return
+	}
+	if p.EmbeddingTopK > 0 {
+		chatManage.EmbeddingTopK = p.EmbeddingTopK
+	}
+	if p.VectorThreshold > 0 {
+		chatManage.VectorThreshold = p.VectorThreshold
+	}
+	if p.KeywordThreshold > 0 {
+		chatManage.KeywordThreshold = p.KeywordThreshold
+	}
+	if p.RerankModelID != "" {
+		chatManage.RerankModelID = p.RerankModelID
+	}
+	if p.RerankTopK > 0 {
+		chatManage.RerankTopK = p.RerankTopK
+	}
+	if p.RerankThreshold > 0 {
+		chatManage.RerankThreshold = p.RerankThreshold
+	}
+	if p.SummaryFirst != nil {
+		chatManage.SummaryFirst = p.SummaryFirst
+	}
+	if p.RetrievalMode != "" {
+		chatManage.RetrievalMode = p.RetrievalMode
+	}
+	if p.MaxChunksPerKnowledge != nil {
+		chatManage.SourceQuota = &SourceQuotaConfig{MaxChunksPerKnowledge: *p.MaxChunksPerKnowledge}
+	}
+}
+
+// Value implements the driver.Valuer interface, used to convert RetrievalParams to database value
+func (p *RetrievalParams) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// Scan implements the sql.Scanner interface, used to convert database value to RetrievalParams
+func (p *RetrievalParams) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, p)
+}
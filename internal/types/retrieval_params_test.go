@@ -0,0 +1,48 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRetrievalParamsValidate(t *testing.T) {
+	valid := &RetrievalParams{EmbeddingTopK: 20, VectorThreshold: 0.4, RerankThreshold: 1}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, params := range []*RetrievalParams{
+		{EmbeddingTopK: MaxRetrievalTopK + 1},
+		{RerankTopK: -1},
+		{KeywordThreshold: 1.5},
+	} {
+		if err := params.Validate(); err == nil {
+			t.Errorf("expected error for %+v", params)
+		}
+	}
+}
+
+func TestRetrievalParamsMerge(t *testing.T) {
+	session := &RetrievalParams{EmbeddingTopK: 20, RerankTopK: 5, KnowledgeBaseIDs: []string{"kb1"}}
+
+	merged := session.Merge(&RetrievalParams{RerankTopK: 8, KnowledgeIDs: []string{"k1"}})
+	if merged.EmbeddingTopK != 20 || merged.RerankTopK != 8 {
+		t.Errorf("unexpected merged params %+v", merged)
+	}
+	if len(merged.KnowledgeBaseIDs) != 0 || !slices.Equal(merged.KnowledgeIDs, []string{"k1"}) {
+		t.Errorf("search targets should be overridden together, got %+v", merged)
+	}
+
+	inherited := session.Merge(nil)
+	if inherited == session || !slices.Equal(inherited.KnowledgeBaseIDs, []string{"kb1"}) {
+		t.Errorf("unexpected inherited params %+v", inherited)
+	}
+	if (*RetrievalParams)(nil).Merge(nil) != nil {
+		t.Error("merging nothing should return nil")
+	}
+
+	chatManage := &ChatManage{EmbeddingTopK: 10, RerankTopK: 10, VectorThreshold: 0.5}
+	merged.Apply(chatManage)
+	if chatManage.EmbeddingTopK != 20 || chatManage.RerankTopK != 8 || chatManage.VectorThreshold != 0.5 {
+		t.Errorf("unexpected chat settings %+v", chatManage)
+	}
+}
@@ -48,6 +48,8 @@ type RetrieveParams struct {
 	AdditionalParams map[string]interface{}
 	// Retriever type
 	RetrieverType RetrieverType // Retriever type
+	// Search-time accuracy parameters for vector retrieval; nil uses index defaults
+	VectorSearch *VectorSearchConfig
 }
 
 // RetrieverEngineParams represents the parameters for retriever engine
@@ -0,0 +1,77 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// User roles, the operations a logged-in user may perform in a tenant
+const (
+	// RoleAdmin may do everything, including managing the tenant, its settings and the roles of its users
+	RoleAdmin = "admin"
+	// RoleEditor reads and changes knowledge and chats, but does not manage the tenant
+	RoleEditor = "editor"
+	// RoleViewer reads knowledge and chats, but does not change knowledge
+	RoleViewer = "viewer"
+)
+
+// AllRoles are the roles, from the most to the least privileged
+var AllRoles = []string{RoleAdmin, RoleEditor, RoleViewer}
+
+// roleScopes are the scopes granted by each role; routes check them with the API key scopes
+var roleScopes = map[string]StringArray{
+	RoleAdmin:  AllAPIKeyScopes,
+	RoleEditor: {APIKeyScopeKnowledgeRead, APIKeyScopeKnowledgeWrite, APIKeyScopeChat},
+	RoleViewer: {APIKeyScopeKnowledgeRead, APIKeyScopeChat},
+}
+
+// ValidateRole rejects unknown roles
+func ValidateRole(role string) error {
+	if !slices.Contains(AllRoles, role) {
+		return fmt.Errorf("unknown role %q, expected one of %v", role, AllRoles)
+	}
+	return nil
+}
+
+// RoleScopes returns the scopes granted by a role, none for an unknown role
+func RoleScopes(role string) StringArray {
+	return roleScopes[role]
+}
+
+// RoleDefinition describes a role and the scopes it grants
+type RoleDefinition struct {
+	Name   string      `json:"name"`
+	Scopes StringArray `json:"scopes"`
+}
+
+// UserRole assigns a role to a user in a tenant; users without an assignment get the default role
+type UserRole struct {
+	// Tenant the role applies to
+	TenantID uint64 `json:"tenant_id"  gorm:"primaryKey"`
+	// User the role is assigned to
+	UserID string `json:"user_id"    gorm:"type:varchar(36);primaryKey"`
+	// Role of the user: admin, editor or viewer
+	Role string `json:"role"       gorm:"type:varchar(32);not null"`
+	// Creation time of the assignment
+	CreatedAt time.Time `json:"created_at"`
+	// Last updated time of the assignment
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name of the role assignments
+func (UserRole) TableName() string {
+	return "user_roles"
+}
+
+// AssignRoleRequest assigns a role to a user of the current tenant
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// CurrentRole is the role of the logged-in user in the current tenant
+type CurrentRole struct {
+	UserID string      `json:"user_id"`
+	Role   string      `json:"role"`
+	Scopes StringArray `json:"scopes"`
+}
@@ -88,9 +88,45 @@ type SearchResult struct {
 	// ChunkMetadata stores chunk-level metadata (e.g., generated questions)
 	ChunkMetadata JSON `json:"chunk_metadata,omitempty"`
 
+	// SectionPath is the heading path (breadcrumb) of the section the chunk belongs to
+	SectionPath []string `json:"section_path,omitempty"`
+
 	// MatchedContent is the actual content that was matched in vector search
 	// For FAQ: this is the matched question text (standard or similar question)
 	MatchedContent string `json:"matched_content,omitempty"`
+
+	// VectorSearchMode reports whether vector retrieval used the ANN index ("approximate") or exact search
+	VectorSearchMode string `json:"vector_search_mode,omitempty"`
+
+	// Alternates lists near-duplicate chunks from other knowledge items collapsed into this one
+	Alternates []*ChunkAlternate `json:"alternates,omitempty"`
+
+	// Fusion explains how hybrid search ranked this result; only set in debug mode
+	Fusion *FusionTrace `json:"fusion,omitempty"`
+
+	// SourceQuota lists the chunks of the same knowledge item dropped by the source quota; only set in
+	// debug mode, on the highest-ranked result of the knowledge item
+	SourceQuota *SourceQuotaTrace `json:"source_quota,omitempty"`
+
+	// Pinned explains which pinned source rule forced this result to the top
+	Pinned *PinnedTrace `json:"pinned,omitempty"`
+	// Origin tells pinned from organically retrieved results; only set in debug mode
+	Origin string `json:"origin,omitempty"`
+
+	// KnowledgeBaseID and KnowledgeBaseName tell the source of a result of a search across knowledge bases
+	KnowledgeBaseID   string `json:"knowledge_base_id,omitempty"`
+	KnowledgeBaseName string `json:"knowledge_base_name,omitempty"`
+}
+
+// ChunkAlternate is another source of content collapsed into a search result as a near-duplicate
+type ChunkAlternate struct {
+	ChunkID           string  `json:"chunk_id"`
+	KnowledgeID       string  `json:"knowledge_id"`
+	KnowledgeTitle    string  `json:"knowledge_title"`
+	KnowledgeFilename string  `json:"knowledge_filename,omitempty"`
+	Score             float64 `json:"score"`
+	// Similarity is the content similarity with the result it was collapsed into
+	Similarity float64 `json:"similarity"`
 }
 
 // SearchParams represents the search parameters
@@ -104,6 +140,26 @@ type SearchParams struct {
 	KnowledgeIDs         []string `json:"knowledge_ids"`
 	TagIDs               []string `json:"tag_ids"` // Tag IDs for filtering (used for FAQ priority filtering)
 	OnlyRecommended      bool     `json:"only_recommended"`
+	// VectorSearch overrides the knowledge base's vector search parameters for this request
+	VectorSearch *VectorSearchConfig `json:"vector_search,omitempty"`
+	// Exact forces exact (brute-force) vector search for this request
+	Exact bool `json:"exact"`
+	// Dedup overrides the knowledge base's near-duplicate collapsing for this request
+	Dedup *DedupConfig `json:"dedup,omitempty"`
+	// Fusion overrides the knowledge base's hybrid search fusion algorithm for this request
+	Fusion *FusionConfig `json:"fusion,omitempty"`
+	// SourceQuota overrides the knowledge base's cap of chunks per knowledge item for this request
+	SourceQuota *SourceQuotaConfig `json:"source_quota,omitempty"`
+	// Debug reports the fusion algorithm, parameters and per-retriever ranks with each result, and the
+	// chunks dropped by the source quota
+	Debug bool `json:"debug"`
+	// MetadataFilter restricts results to knowledge whose metadata has all the given values. With a metadata
+	// schema only its fields can be filtered on
+	MetadataFilter map[string]string `json:"metadata_filter,omitempty"`
+	// SummaryFirst overrides the knowledge base's summary-first retrieval for this request
+	SummaryFirst *bool `json:"summary_first,omitempty"`
+	// RetrievalMode overrides the knowledge base's retrieval mode for this request, "chunk" or "parent"
+	RetrievalMode string `json:"retrieval_mode,omitempty"`
 }
 
 // Value implements the driver.Valuer interface, used to convert SearchResult to database value
@@ -168,6 +224,11 @@ type PageResult struct {
 	Data     interface{} `json:"data"`      // Data
 }
 
+// HasMore reports whether pages follow the current one
+func (r *PageResult) HasMore() bool {
+	return int64(r.Page)*int64(r.PageSize) < r.Total
+}
+
 // NewPageResult creates a new pagination result
 func NewPageResult(total int64, page *Pagination, data interface{}) *PageResult {
 	return &PageResult{
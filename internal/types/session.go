@@ -43,6 +43,11 @@ type SummaryConfig struct {
 	Seed int `json:"seed"`
 	// Max completion tokens
 	MaxCompletionTokens int `json:"max_completion_tokens"`
+	// Context window of the chat model in tokens, used for the context budget report (0 = default)
+	ContextWindow int `json:"context_window"`
+	// OversizedChunkStrategy is how a retrieved chunk larger than the prompt's context budget is fitted:
+	// "truncate" (default) or "summarize"
+	OversizedChunkStrategy string `json:"oversized_chunk_strategy"`
 	// Thinking - whether to enable thinking mode
 	Thinking *bool `json:"thinking"`
 }
@@ -99,6 +104,9 @@ type Session struct {
 	// AgentConfig       *SessionAgentConfig `json:"agent_config"       gorm:"type:jsonb"` // Agent configuration (session level, only stores enabled and knowledge_bases)
 	// ContextConfig     *ContextConfig      `json:"context_config"     gorm:"type:jsonb"` // Context management configuration (optional)
 
+	// Default retrieval parameters inherited by knowledge QA requests of the session
+	RetrievalParams *RetrievalParams `json:"retrieval_params" gorm:"type:jsonb"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Scopes of a similar chunk search
+const (
+	// SimilarChunkScopeKnowledgeBase searches the whole knowledge base of the source chunk
+	SimilarChunkScopeKnowledgeBase = "knowledge_base"
+	// SimilarChunkScopeDocument only searches the knowledge the source chunk belongs to
+	SimilarChunkScopeDocument = "document"
+	// SimilarChunkScopeOtherDocuments searches the knowledge base except the knowledge of the source chunk
+	SimilarChunkScopeOtherDocuments = "other_documents"
+)
+
+// Number of similar chunks returned by default and at most
+const (
+	DefaultSimilarChunkCount = 10
+	MaxSimilarChunkCount     = 50
+)
+
+// SimilarChunkParams are the parameters of a search for the chunks most similar to a given chunk
+type SimilarChunkParams struct {
+	// Count is the number of chunks returned (0 = default)
+	Count int `form:"count"`
+	// Scope restricts the searched knowledge, see SimilarChunkScope* (empty = knowledge_base)
+	Scope string `form:"scope"`
+	// Threshold is the minimum vector similarity of a returned chunk
+	Threshold float64 `form:"threshold"`
+}
+
+// Validate checks the count, scope and threshold ranges
+func (p *SimilarChunkParams) Validate() error {
+	if p.Count < 0 || p.Count > MaxSimilarChunkCount {
+		return fmt.Errorf("count must be between 1 and %d", MaxSimilarChunkCount)
+	}
+	scopes := []string{SimilarChunkScopeKnowledgeBase, SimilarChunkScopeDocument, SimilarChunkScopeOtherDocuments}
+	if p.Scope != "" && !slices.Contains(scopes, p.Scope) {
+		return fmt.Errorf("scope must be one of %v", scopes)
+	}
+	if p.Threshold < 0 || p.Threshold > 1 {
+		return fmt.Errorf("threshold must be between 0 and 1")
+	}
+	return nil
+}
+
+// EffectiveCount returns the number of chunks to return
+func (p *SimilarChunkParams) EffectiveCount() int {
+	if p.Count <= 0 {
+		return DefaultSimilarChunkCount
+	}
+	return p.Count
+}
+
+// EffectiveScope returns the scope of the search
+func (p *SimilarChunkParams) EffectiveScope() string {
+	if p.Scope == "" {
+		return SimilarChunkScopeKnowledgeBase
+	}
+	return p.Scope
+}
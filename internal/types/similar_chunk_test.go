@@ -0,0 +1,42 @@
+package types
+
+import "testing"
+
+func TestSimilarChunkParamsValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    SimilarChunkParams
+		wantErr   bool
+		wantCount int
+		wantScope string
+	}{
+		{name: "defaults", wantCount: DefaultSimilarChunkCount, wantScope: SimilarChunkScopeKnowledgeBase},
+		{
+			name:      "explicit",
+			params:    SimilarChunkParams{Count: 5, Scope: SimilarChunkScopeOtherDocuments, Threshold: 0.5},
+			wantCount: 5,
+			wantScope: SimilarChunkScopeOtherDocuments,
+		},
+		{name: "count too large", params: SimilarChunkParams{Count: MaxSimilarChunkCount + 1}, wantErr: true},
+		{name: "negative count", params: SimilarChunkParams{Count: -1}, wantErr: true},
+		{name: "unknown scope", params: SimilarChunkParams{Scope: "tenant"}, wantErr: true},
+		{name: "threshold out of range", params: SimilarChunkParams{Threshold: 1.5}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tt.params.EffectiveCount(); got != tt.wantCount {
+				t.Errorf("EffectiveCount() = %d, want %d", got, tt.wantCount)
+			}
+			if got := tt.params.EffectiveScope(); got != tt.wantScope {
+				t.Errorf("EffectiveScope() = %s, want %s", got, tt.wantScope)
+			}
+		})
+	}
+}
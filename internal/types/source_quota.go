@@ -0,0 +1,92 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// SourceQuotaConfig caps how many chunks of the same knowledge item a search returns, so that a large
+// document does not crowd the other relevant sources out of the results
+type SourceQuotaConfig struct {
+	// MaxChunksPerKnowledge is the most chunks of one knowledge item kept in the results (0 = no quota)
+	MaxChunksPerKnowledge int `yaml:"max_chunks_per_knowledge" json:"max_chunks_per_knowledge"`
+}
+
+// Validate checks the cap range
+func (c *SourceQuotaConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.MaxChunksPerKnowledge < 0 || c.MaxChunksPerKnowledge > MaxRetrievalTopK {
+		return fmt.Errorf("max_chunks_per_knowledge must be between 0 and %d", MaxRetrievalTopK)
+	}
+	return nil
+}
+
+// IsEnabled reports whether the quota caps the results
+func (c *SourceQuotaConfig) IsEnabled() bool {
+	return c != nil && c.MaxChunksPerKnowledge > 0
+}
+
+// Value implements driver.Valuer
+func (c SourceQuotaConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *SourceQuotaConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// SourceQuotaTrace lists the chunks of a knowledge item dropped by the source quota; only set in debug mode
+type SourceQuotaTrace struct {
+	MaxChunksPerKnowledge int `json:"max_chunks_per_knowledge"`
+	// Dropped are the chunks over the quota that ranked within the top-k, in rank order
+	Dropped []*QuotaDroppedChunk `json:"dropped"`
+}
+
+// QuotaDroppedChunk is a chunk left out of the results by the source quota
+type QuotaDroppedChunk struct {
+	ChunkID string  `json:"chunk_id"`
+	Score   float64 `json:"score"`
+	// Rank (1-based) of the chunk in the ranking before the quota was applied
+	Rank int `json:"rank"`
+}
+
+// Apply walks the ranked chunks and keeps at most MaxChunksPerKnowledge chunks of each knowledge item,
+// until limit chunks are kept. The chunks skipped on the way are returned per knowledge item; the chunks
+// ranked after the last kept one are not considered dropped.
+func (c *SourceQuotaConfig) Apply(chunks []*IndexWithScore,
+	limit int,
+) ([]*IndexWithScore, map[string][]*QuotaDroppedChunk) {
+	if !c.IsEnabled() {
+		return chunks, nil
+	}
+	kept := make([]*IndexWithScore, 0, min(len(chunks), limit))
+	dropped := make(map[string][]*QuotaDroppedChunk)
+	perKnowledge := make(map[string]int)
+	for i, chunk := range chunks {
+		if len(kept) >= limit {
+			break
+		}
+		if perKnowledge[chunk.KnowledgeID] >= c.MaxChunksPerKnowledge {
+			dropped[chunk.KnowledgeID] = append(dropped[chunk.KnowledgeID], &QuotaDroppedChunk{
+				ChunkID: chunk.ChunkID,
+				Score:   chunk.Score,
+				Rank:    i + 1,
+			})
+			continue
+		}
+		perKnowledge[chunk.KnowledgeID]++
+		kept = append(kept, chunk)
+	}
+	return kept, dropped
+}
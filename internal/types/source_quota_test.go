@@ -0,0 +1,55 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSourceQuotaApply(t *testing.T) {
+	// Ranked chunks: the large document "a" holds the four best
+	ranked := []*IndexWithScore{
+		{ChunkID: "a1", KnowledgeID: "a", Score: 0.9},
+		{ChunkID: "a2", KnowledgeID: "a", Score: 0.8},
+		{ChunkID: "a3", KnowledgeID: "a", Score: 0.7},
+		{ChunkID: "a4", KnowledgeID: "a", Score: 0.6},
+		{ChunkID: "b1", KnowledgeID: "b", Score: 0.5},
+		{ChunkID: "c1", KnowledgeID: "c", Score: 0.4},
+		{ChunkID: "a5", KnowledgeID: "a", Score: 0.3},
+	}
+	chunkIDs := func(chunks []*IndexWithScore) []string {
+		ids := make([]string, 0, len(chunks))
+		for _, chunk := range chunks {
+			ids = append(ids, chunk.ChunkID)
+		}
+		return ids
+	}
+
+	for _, disabled := range []*SourceQuotaConfig{nil, {}} {
+		if kept, dropped := disabled.Apply(ranked, 3); len(kept) != len(ranked) || dropped != nil {
+			t.Errorf("Apply(%+v) kept %d chunks and dropped %v, want the ranking unchanged", disabled, len(kept), dropped)
+		}
+	}
+
+	kept, dropped := (&SourceQuotaConfig{MaxChunksPerKnowledge: 2}).Apply(ranked, 4)
+	if got, want := chunkIDs(kept), []string{"a1", "a2", "b1", "c1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("kept = %v, want %v", got, want)
+	}
+	// a5 ranks after the last kept chunk and is not reported
+	want := map[string][]*QuotaDroppedChunk{"a": {
+		{ChunkID: "a3", Score: 0.7, Rank: 3},
+		{ChunkID: "a4", Score: 0.6, Rank: 4},
+	}}
+	if !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropped = %v, want %v", dropped, want)
+	}
+
+	if kept, _ := (&SourceQuotaConfig{MaxChunksPerKnowledge: 1}).Apply(ranked, 5); len(kept) != 3 {
+		t.Errorf("kept %d chunks, want one per knowledge item when the candidates run out", len(kept))
+	}
+
+	for _, invalid := range []int{-1, MaxRetrievalTopK + 1} {
+		if err := (&SourceQuotaConfig{MaxChunksPerKnowledge: invalid}).Validate(); err == nil {
+			t.Errorf("Validate(%d) = nil, want an error", invalid)
+		}
+	}
+}
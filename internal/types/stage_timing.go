@@ -0,0 +1,82 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StageTimings collects the time a request spends in each stage of its processing, and the query it
+// answers, for the slow request log. It is safe for concurrent use.
+type StageTimings struct {
+	mu     sync.Mutex
+	stages []StageTiming
+	query  string
+}
+
+// StageTiming is the total time spent in a stage
+type StageTiming struct {
+	Stage    string
+	Duration time.Duration
+}
+
+// WithStageTimings returns a context collecting stage timings
+func WithStageTimings(ctx context.Context) (context.Context, *StageTimings) {
+	timings := &StageTimings{}
+	return context.WithValue(ctx, StageTimingsContextKey, timings), timings
+}
+
+// RecordStage adds the time spent in a stage to the timings of the request, if collected
+func RecordStage(ctx context.Context, stage string, duration time.Duration) {
+	timings, ok := ctx.Value(StageTimingsContextKey).(*StageTimings)
+	if !ok {
+		return
+	}
+	timings.mu.Lock()
+	defer timings.mu.Unlock()
+	for i := range timings.stages {
+		if timings.stages[i].Stage == stage {
+			timings.stages[i].Duration += duration
+			return
+		}
+	}
+	timings.stages = append(timings.stages, StageTiming{Stage: stage, Duration: duration})
+}
+
+// RecordQuery sets the query the request answers, if stage timings are collected. The first query wins.
+func RecordQuery(ctx context.Context, query string) {
+	timings, ok := ctx.Value(StageTimingsContextKey).(*StageTimings)
+	if !ok || query == "" {
+		return
+	}
+	timings.mu.Lock()
+	defer timings.mu.Unlock()
+	if timings.query == "" {
+		timings.query = query
+	}
+}
+
+// Stages returns the stages in the order they were first recorded
+func (t *StageTimings) Stages() []StageTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]StageTiming(nil), t.stages...)
+}
+
+// Query returns the recorded query
+func (t *StageTimings) Query() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.query
+}
+
+// String formats the stages as "stage=duration" pairs, e.g. "chunk_search=120ms chunk_rerank=1.2s"
+func (t *StageTimings) String() string {
+	var parts []string
+	for _, stage := range t.Stages() {
+		parts = append(parts, fmt.Sprintf("%s=%s", stage.Stage, stage.Duration.Round(time.Millisecond)))
+	}
+	return strings.Join(parts, " ")
+}
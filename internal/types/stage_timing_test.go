@@ -0,0 +1,32 @@
+package types
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStageTimings(t *testing.T) {
+	// Without collection, recording is a no-op
+	RecordStage(context.Background(), "chunk_search", time.Second)
+	RecordQuery(context.Background(), "ignored")
+
+	ctx, timings := WithStageTimings(context.Background())
+	RecordQuery(ctx, "")
+	RecordQuery(ctx, "what is weknora")
+	RecordQuery(ctx, "rewritten query")
+	RecordStage(ctx, "chunk_search", 120*time.Millisecond)
+	RecordStage(ctx, "chunk_rerank", 1200*time.Millisecond)
+	RecordStage(ctx, "chunk_search", 30*time.Millisecond)
+
+	if got := timings.Query(); got != "what is weknora" {
+		t.Errorf("Query() = %q, want the first query", got)
+	}
+	stages := timings.Stages()
+	if len(stages) != 2 || stages[0].Stage != "chunk_search" || stages[0].Duration != 150*time.Millisecond {
+		t.Fatalf("Stages() = %+v, want chunk_search summed first", stages)
+	}
+	if got, want := timings.String(), "chunk_search=150ms chunk_rerank=1.2s"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Types of the asynchronous tasks that notify webhooks when they finish
+const (
+	TaskTypeKBClone       = "kb_clone"
+	TaskTypeFAQImport     = "faq_import"
+	TaskTypeModelDownload = "model_download"
+)
+
+// Final statuses reported to task webhooks
+const (
+	TaskWebhookStatusCompleted = "completed"
+	TaskWebhookStatusFailed    = "failed"
+)
+
+// Statuses of a task webhook delivery
+const (
+	// WebhookDeliveryPending means the delivery is in progress
+	WebhookDeliveryPending = "pending"
+	// WebhookDeliveryDelivered means the webhook answered with a 2xx status
+	WebhookDeliveryDelivered = "delivered"
+	// WebhookDeliveryFailed means every delivery attempt failed
+	WebhookDeliveryFailed = "failed"
+)
+
+// minWebhookSecretLength is the shortest accepted webhook signing secret
+const minWebhookSecretLength = 16
+
+// WebhookConfig is the tenant webhook notified when an asynchronous task finishes. Tasks created with
+// their own webhook URL notify that URL instead; both are signed with the tenant secret.
+type WebhookConfig struct {
+	// URL receives the completion of every task of the tenant (optional)
+	URL string `yaml:"url"    json:"url"`
+	// Secret signs the webhook payloads, see utils.SignWebhookPayload
+	Secret string `yaml:"secret" json:"secret"`
+}
+
+// Validate checks that webhooks can be signed
+func (c *WebhookConfig) Validate() error {
+	if c.Secret != "" && len(c.Secret) < minWebhookSecretLength {
+		return fmt.Errorf("secret must be at least %d characters", minWebhookSecretLength)
+	}
+	if c.URL != "" && c.Secret == "" {
+		return fmt.Errorf("secret is required to sign the webhooks")
+	}
+	return nil
+}
+
+// Masked returns a copy of the config safe for display
+func (c *WebhookConfig) Masked() *WebhookConfig {
+	masked := *c
+	if masked.Secret != "" {
+		masked.Secret = maskString(masked.Secret)
+	}
+	return &masked
+}
+
+// Value implements driver.Valuer
+func (c WebhookConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *WebhookConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
+
+// TaskWebhookEvent is the payload posted to the webhook when an asynchronous task reaches a final status
+type TaskWebhookEvent struct {
+	TaskID   string `json:"task_id"`
+	TaskType string `json:"task_type"`
+	TenantID uint64 `json:"tenant_id"`
+	// Status is completed or failed
+	Status string `json:"status"`
+	// Summary holds the outcome of the task, such as the number of imported FAQ entries
+	Summary map[string]interface{} `json:"summary,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+	// FinishedAt is the time the task reached its final status
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// TaskWebhookDelivery is the last delivery of the webhook of a task
+type TaskWebhookDelivery struct {
+	URL string `json:"url"`
+	// Status is pending, delivered or failed
+	Status string `json:"status"`
+	// Attempts is the number of deliveries tried
+	Attempts int `json:"attempts"`
+	// LastAttemptAt is the time the last attempt finished
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	// Error is the failure of the last attempt
+	Error string `json:"error,omitempty"`
+}
+
+// NewKBCloneWebhookEvent returns the webhook event of a finished knowledge base copy, nil while it runs
+func NewKBCloneWebhookEvent(tenantID uint64, progress *KBCloneProgress) *TaskWebhookEvent {
+	var status string
+	switch progress.Status {
+	case KBCloneStatusCompleted:
+		status = TaskWebhookStatusCompleted
+	case KBCloneStatusFailed:
+		status = TaskWebhookStatusFailed
+	default:
+		return nil
+	}
+	return &TaskWebhookEvent{
+		TaskID:   progress.TaskID,
+		TaskType: TaskTypeKBClone,
+		TenantID: tenantID,
+		Status:   status,
+		Summary: map[string]interface{}{
+			"source_id": progress.SourceID,
+			"target_id": progress.TargetID,
+			"total":     progress.Total,
+			"processed": progress.Processed,
+			"message":   progress.Message,
+		},
+		Error:      progress.Error,
+		FinishedAt: time.Unix(progress.UpdatedAt, 0).UTC(),
+	}
+}
+
+// NewFAQImportWebhookEvent returns the webhook event of a finished FAQ import, nil while it runs
+func NewFAQImportWebhookEvent(tenantID uint64, progress *FAQImportProgress) *TaskWebhookEvent {
+	var status string
+	switch progress.Status {
+	case FAQImportStatusCompleted:
+		status = TaskWebhookStatusCompleted
+	case FAQImportStatusFailed:
+		status = TaskWebhookStatusFailed
+	default:
+		return nil
+	}
+	summary := map[string]interface{}{
+		"kb_id":         progress.KBID,
+		"knowledge_id":  progress.KnowledgeID,
+		"dry_run":       progress.DryRun,
+		"total":         progress.Total,
+		"success_count": progress.SuccessCount,
+		"failed_count":  progress.FailedCount,
+		"skipped_count": progress.SkippedCount,
+	}
+	if progress.FailedEntriesURL != "" {
+		summary["failed_entries_url"] = progress.FailedEntriesURL
+	}
+	return &TaskWebhookEvent{
+		TaskID:     progress.TaskID,
+		TaskType:   TaskTypeFAQImport,
+		TenantID:   tenantID,
+		Status:     status,
+		Summary:    summary,
+		Error:      progress.Error,
+		FinishedAt: time.Unix(progress.UpdatedAt, 0).UTC(),
+	}
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebhookConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  WebhookConfig
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "secret only", config: WebhookConfig{Secret: "whsec-7f3c2a9e41b84d06"}},
+		{name: "url and secret", config: WebhookConfig{URL: "https://hooks.example.com/tasks", Secret: "whsec-7f3c2a9e41b84d06"}},
+		{name: "url without secret", config: WebhookConfig{URL: "https://hooks.example.com/tasks"}, wantErr: true},
+		{name: "short secret", config: WebhookConfig{Secret: "short"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	config := &WebhookConfig{URL: "https://hooks.example.com/tasks", Secret: "whsec-7f3c2a9e41b84d06"}
+	if got := config.Masked().Secret; got != "whse****4d06" {
+		t.Errorf("Masked().Secret = %q, want %q", got, "whse****4d06")
+	}
+	if config.Secret != "whsec-7f3c2a9e41b84d06" {
+		t.Error("Masked() changed the config")
+	}
+}
+
+func TestTaskWebhookEvents(t *testing.T) {
+	finished := time.Date(2026, 10, 16, 8, 30, 0, 0, time.UTC)
+
+	if event := NewFAQImportWebhookEvent(10002, &FAQImportProgress{Status: FAQImportStatusProcessing}); event != nil {
+		t.Errorf("event of a running import = %+v, want none", event)
+	}
+	event := NewFAQImportWebhookEvent(10002, &FAQImportProgress{
+		TaskID:       "task-1",
+		KBID:         "kb-1",
+		Status:       FAQImportStatusCompleted,
+		Total:        120,
+		SuccessCount: 117,
+		FailedCount:  2,
+		SkippedCount: 1,
+		UpdatedAt:    finished.Unix(),
+	})
+	if event == nil || event.Status != TaskWebhookStatusCompleted || event.TaskType != TaskTypeFAQImport ||
+		event.TenantID != 10002 || !event.FinishedAt.Equal(finished) {
+		t.Fatalf("FAQ import event = %+v", event)
+	}
+	if event.Summary["success_count"] != 117 || event.Summary["failed_count"] != 2 || event.Summary["skipped_count"] != 1 {
+		t.Errorf("FAQ import summary = %v", event.Summary)
+	}
+
+	if event := NewKBCloneWebhookEvent(10002, &KBCloneProgress{Status: KBCloneStatusPending}); event != nil {
+		t.Errorf("event of a pending copy = %+v, want none", event)
+	}
+	event = NewKBCloneWebhookEvent(10002, &KBCloneProgress{
+		TaskID:    "task-2",
+		SourceID:  "kb-1",
+		Status:    KBCloneStatusFailed,
+		Error:     "source not found",
+		UpdatedAt: finished.Unix(),
+	})
+	if event == nil || event.Status != TaskWebhookStatusFailed || event.TaskType != TaskTypeKBClone ||
+		event.Error != "source not found" || event.Summary["source_id"] != "kb-1" {
+		t.Errorf("KB clone event = %+v", event)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -65,6 +66,8 @@ type Tenant struct {
 	Description string `yaml:"description"         json:"description"`
 	// API key
 	APIKey string `yaml:"api_key"             json:"api_key"`
+	// Scopes of the API key, see AllAPIKeyScopes
+	APIKeyScopes StringArray `yaml:"api_key_scopes"      json:"api_key_scopes"      gorm:"type:jsonb"`
 	// Status
 	Status string `yaml:"status"              json:"status"              gorm:"default:'active'"`
 	// Retriever engines
@@ -85,6 +88,16 @@ type Tenant struct {
 	// Deprecated: ConversationConfig is deprecated, use CustomAgent (builtin-quick-answer) config instead.
 	// This field is kept for backward compatibility and will be removed in future versions.
 	ConversationConfig *ConversationConfig `yaml:"conversation_config" json:"conversation_config" gorm:"type:jsonb"`
+	// Prompt injection defense for retrieved content
+	PromptInjectionConfig *PromptInjectionConfig `yaml:"prompt_injection_config" json:"prompt_injection_config" gorm:"type:jsonb"`
+	// Feature flag overrides, features not listed use the global defaults
+	FeatureFlags FeatureFlags `yaml:"feature_flags" json:"feature_flags" gorm:"type:jsonb"`
+	// Request rate limit override, set by operators through the tenant KV store
+	RateLimitConfig *RateLimitConfig `yaml:"rate_limit_config" json:"rate_limit_config" gorm:"type:jsonb"`
+	// Payload size limit override, set by operators through the tenant KV store
+	PayloadLimitConfig *PayloadLimitConfig `yaml:"payload_limit_config" json:"payload_limit_config" gorm:"type:jsonb"`
+	// Webhook notified when asynchronous tasks finish, with the secret signing the payloads
+	WebhookConfig *WebhookConfig `yaml:"webhook_config" json:"-" gorm:"type:jsonb"`
 	// Creation time
 	CreatedAt time.Time `yaml:"created_at"          json:"created_at"`
 	// Last updated time
@@ -111,6 +124,9 @@ func (t *Tenant) BeforeCreate(tx *gorm.DB) error {
 	if t.RetrieverEngines.Engines == nil {
 		t.RetrieverEngines.Engines = []RetrieverEngineParams{}
 	}
+	if t.APIKeyScopes == nil {
+		t.APIKeyScopes = slices.Clone(AllAPIKeyScopes)
+	}
 	return nil
 }
 
@@ -0,0 +1,255 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TenantExportManifestVersion is the version of the tenant export manifest format
+const TenantExportManifestVersion = 1
+
+// RedactedSecret replaces credentials in tenant exports
+const RedactedSecret = "[redacted]"
+
+// Tenant export part names. Every knowledge base is exported as its own part named
+// TenantExportPartKnowledgeBasePrefix followed by the knowledge base ID.
+const (
+	TenantExportPartTenant              = "tenant"
+	TenantExportPartSessions            = "sessions"
+	TenantExportPartKnowledgeBasePrefix = "knowledge_base_"
+)
+
+// Tenant export part statuses
+const (
+	TenantExportPartPending   = "pending"
+	TenantExportPartCompleted = "completed"
+)
+
+// TenantExportPayload represents the tenant export task payload
+type TenantExportPayload struct {
+	TenantID  uint64 `json:"tenant_id"`
+	TaskID    string `json:"task_id"`
+	RequestID string `json:"request_id"`
+}
+
+// TenantExportPart is one archive of a tenant export. Parts are gzip-compressed tar archives that are
+// written one after another, so that an interrupted export resumes with the first incomplete part.
+type TenantExportPart struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Path is the storage path of the archive
+	Path string `json:"path,omitempty"`
+	// Size is the size of the archive in bytes
+	Size int64 `json:"size,omitempty"`
+	// SHA256 is the hex encoded SHA-256 checksum of the archive
+	SHA256 string `json:"sha256,omitempty"`
+	// Records counts the exported records by kind, e.g. chunks or messages
+	Records map[string]int `json:"records,omitempty"`
+}
+
+// TenantExportManifest describes a completed tenant export
+type TenantExportManifest struct {
+	Version     int                 `json:"version"`
+	TenantID    uint64              `json:"tenant_id"`
+	TaskID      string              `json:"task_id"`
+	CreatedAt   int64               `json:"created_at"`
+	CompletedAt int64               `json:"completed_at"`
+	Parts       []*TenantExportPart `json:"parts"`
+	// Records counts the exported records of all parts by kind
+	Records map[string]int `json:"records"`
+	// Size is the total size of the archives in bytes
+	Size int64 `json:"size"`
+}
+
+// TenantExportProgress represents the progress of a tenant export task
+type TenantExportProgress struct {
+	TaskID    string              `json:"task_id"`
+	TenantID  uint64              `json:"tenant_id"`
+	Status    KBCloneTaskStatus   `json:"status"`
+	Progress  int                 `json:"progress"`  // 0-100
+	Total     int                 `json:"total"`     // Number of parts
+	Processed int                 `json:"processed"` // Number of completed parts
+	Parts     []*TenantExportPart `json:"parts"`
+	// Manifest is set once every part is completed
+	Manifest     *TenantExportManifest `json:"manifest,omitempty"`
+	ManifestPath string                `json:"manifest_path,omitempty"`
+	Message      string                `json:"message"`    // Status message
+	Error        string                `json:"error"`      // Error message
+	CreatedAt    int64                 `json:"created_at"` // Task creation time
+	UpdatedAt    int64                 `json:"updated_at"` // Last update time
+}
+
+// TenantExportKnowledgeBasePart returns the part name of a knowledge base
+func TenantExportKnowledgeBasePart(kbID string) string {
+	return TenantExportPartKnowledgeBasePrefix + kbID
+}
+
+// Part returns the part with the name, or nil
+func (p *TenantExportProgress) Part(name string) *TenantExportPart {
+	for _, part := range p.Parts {
+		if part.Name == name {
+			return part
+		}
+	}
+	return nil
+}
+
+// BuildManifest builds the manifest of the export from its completed parts
+func (p *TenantExportProgress) BuildManifest(completedAt int64) (*TenantExportManifest, error) {
+	manifest := &TenantExportManifest{
+		Version:     TenantExportManifestVersion,
+		TenantID:    p.TenantID,
+		TaskID:      p.TaskID,
+		CreatedAt:   p.CreatedAt,
+		CompletedAt: completedAt,
+		Parts:       p.Parts,
+		Records:     make(map[string]int),
+	}
+	for _, part := range p.Parts {
+		if part.Status != TenantExportPartCompleted {
+			return nil, fmt.Errorf("export part %s is not completed", part.Name)
+		}
+		for kind, n := range part.Records {
+			manifest.Records[kind] += n
+		}
+		manifest.Size += part.Size
+	}
+	return manifest, nil
+}
+
+// TenantPurgeConfirmationTTL is how long a tenant purge confirmation token stays valid
+const TenantPurgeConfirmationTTL = 10 * time.Minute
+
+// TenantPurgeConfirmation confirms the permanent deletion of a tenant. The token must be passed to the
+// deletion request before it expires.
+type TenantPurgeConfirmation struct {
+	TenantID  uint64 `json:"tenant_id"`
+	Token     string `json:"confirmation_token"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// Resources of a tenant that a purge deletes, as reported in purge failures
+const (
+	TenantPurgeResourceKnowledgeBase = "knowledge_base"
+	TenantPurgeResourceVectors       = "vectors"
+	TenantPurgeResourceGraph         = "graph"
+	TenantPurgeResourceStorage       = "storage"
+	TenantPurgeResourceRecords       = "records"
+)
+
+// TenantPurgeMaxRetainedFiles caps the retained files listed in a purge report
+const TenantPurgeMaxRetainedFiles = 100
+
+// TenantPurgePayload represents the tenant purge task payload
+type TenantPurgePayload struct {
+	TenantID  uint64 `json:"tenant_id"`
+	TaskID    string `json:"task_id"`
+	RequestID string `json:"request_id"`
+	// Force removes the records even if data could not be deleted; the failures stay in the report
+	Force bool `json:"force"`
+}
+
+// TenantPurgeFailure is data of a tenant that a purge failed to delete
+type TenantPurgeFailure struct {
+	Resource string `json:"resource"`
+	// ID identifies the failed item, e.g. the knowledge base or the embedding model
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// TenantPurgeProgress represents the progress of a tenant purge task. Once the task has finished it is
+// the report of the purge.
+//
+// The records of the tenant are only removed when all of its data was deleted. Otherwise the task fails
+// with the failures listed, and a retry continues with the knowledge bases that are not purged yet.
+type TenantPurgeProgress struct {
+	TaskID    string            `json:"task_id"`
+	TenantID  uint64            `json:"tenant_id"`
+	Status    KBCloneTaskStatus `json:"status"`
+	Progress  int               `json:"progress"`  // 0-100
+	Total     int               `json:"total"`     // Number of knowledge bases, plus storage and records
+	Processed int               `json:"processed"` // Number processed
+	Attempts  int               `json:"attempts"`  // Number of runs, including retries
+	// PurgedKnowledgeBases lists the knowledge bases whose indices, chunks and graph data are deleted
+	PurgedKnowledgeBases []string `json:"purged_knowledge_bases"`
+	// Deleted counts the deleted data: files removed from the tenant storage, chunk images, and the records
+	// removed in the final step by table. Index entries are deleted by knowledge and not counted.
+	Deleted map[string]int64 `json:"deleted"`
+	// Failures lists the data the last run failed to delete
+	Failures []*TenantPurgeFailure `json:"failures,omitempty"`
+	// RetainedFiles lists chunk images stored outside of the tenant storage that could not be deleted,
+	// at most TenantPurgeMaxRetainedFiles of RetainedFileCount
+	RetainedFiles     []string `json:"retained_files,omitempty"`
+	RetainedFileCount int      `json:"retained_file_count"`
+	Message           string   `json:"message"`      // Status message
+	Error             string   `json:"error"`        // Error message
+	CreatedAt         int64    `json:"created_at"`   // Task creation time
+	UpdatedAt         int64    `json:"updated_at"`   // Last update time
+	CompletedAt       int64    `json:"completed_at"` // Completion time
+}
+
+// AddFailure records data that could not be deleted
+func (p *TenantPurgeProgress) AddFailure(resource, id string, err error) {
+	p.Failures = append(p.Failures, &TenantPurgeFailure{Resource: resource, ID: id, Error: err.Error()})
+}
+
+// AddRetainedFile records a file that could not be deleted
+func (p *TenantPurgeProgress) AddRetainedFile(filePath string) {
+	p.RetainedFileCount++
+	if len(p.RetainedFiles) < TenantPurgeMaxRetainedFiles {
+		p.RetainedFiles = append(p.RetainedFiles, filePath)
+	}
+}
+
+// KnowledgeBasePurged reports whether an earlier run purged the knowledge base
+func (p *TenantPurgeProgress) KnowledgeBasePurged(kbID string) bool {
+	for _, id := range p.PurgedKnowledgeBases {
+		if id == kbID {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactSecrets hides the credentials of the tenant for export
+func (t *Tenant) RedactSecrets() {
+	if t.APIKey != "" {
+		t.APIKey = RedactedSecret
+	}
+	if t.WebSearchConfig != nil && t.WebSearchConfig.APIKey != "" {
+		config := *t.WebSearchConfig
+		config.APIKey = RedactedSecret
+		t.WebSearchConfig = &config
+	}
+}
+
+// RedactSecrets hides the credentials of the model for export: the API key and the provider
+// specific settings whose name suggests a credential
+func (m *Model) RedactSecrets() {
+	if m.Parameters.APIKey != "" {
+		m.Parameters.APIKey = RedactedSecret
+	}
+	if len(m.Parameters.ExtraConfig) == 0 {
+		return
+	}
+	extra := make(map[string]string, len(m.Parameters.ExtraConfig))
+	for key, value := range m.Parameters.ExtraConfig {
+		if value != "" && isSecretKey(key) {
+			value = RedactedSecret
+		}
+		extra[key] = value
+	}
+	m.Parameters.ExtraConfig = extra
+}
+
+// isSecretKey reports whether a configuration key names a credential
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, marker := range []string{"key", "secret", "token", "password", "credential"} {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package types
+
+import "testing"
+
+func TestTenantExportProgressBuildManifest(t *testing.T) {
+	progress := &TenantExportProgress{
+		TaskID:    "tenant_export_1",
+		TenantID:  1,
+		CreatedAt: 100,
+		Parts: []*TenantExportPart{
+			{Name: TenantExportPartTenant, Status: TenantExportPartCompleted, Size: 10, Records: map[string]int{"models": 2}},
+			{
+				Name:    TenantExportKnowledgeBasePart("kb-1"),
+				Status:  TenantExportPartCompleted,
+				Size:    30,
+				Records: map[string]int{"knowledge_bases": 1, "chunks": 5},
+			},
+			{
+				Name:    TenantExportKnowledgeBasePart("kb-2"),
+				Status:  TenantExportPartCompleted,
+				Size:    20,
+				Records: map[string]int{"knowledge_bases": 1, "chunks": 3},
+			},
+		},
+	}
+	manifest, err := progress.BuildManifest(200)
+	if err != nil {
+		t.Fatalf("BuildManifest() = %v", err)
+	}
+	if manifest.Size != 60 || manifest.Records["chunks"] != 8 || manifest.Records["knowledge_bases"] != 2 ||
+		manifest.Records["models"] != 2 {
+		t.Errorf("manifest = %+v, records = %v", manifest, manifest.Records)
+	}
+	if manifest.Version != TenantExportManifestVersion || manifest.CompletedAt != 200 || len(manifest.Parts) != 3 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+	if progress.Part(TenantExportKnowledgeBasePart("kb-2")) != progress.Parts[2] || progress.Part("kb-3") != nil {
+		t.Error("Part() did not find the part by name")
+	}
+
+	progress.Parts = append(progress.Parts, &TenantExportPart{Name: TenantExportPartSessions, Status: TenantExportPartPending})
+	if _, err := progress.BuildManifest(200); err == nil {
+		t.Error("BuildManifest() accepted an incomplete export")
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	model := &Model{Parameters: ModelParameters{
+		BaseURL: "https://api.example.com",
+		APIKey:  "sk-123",
+		ExtraConfig: map[string]string{
+			"region": "us-east-1", "secret_key": "abc", "access_token": "def", "api_version": "",
+		},
+	}}
+	original := model.Parameters.ExtraConfig
+	model.RedactSecrets()
+	if model.Parameters.APIKey != RedactedSecret || model.Parameters.BaseURL != "https://api.example.com" {
+		t.Errorf("parameters = %+v", model.Parameters)
+	}
+	want := map[string]string{
+		"region": "us-east-1", "secret_key": RedactedSecret, "access_token": RedactedSecret, "api_version": "",
+	}
+	for key, value := range want {
+		if model.Parameters.ExtraConfig[key] != value {
+			t.Errorf("extra_config[%s] = %q, want %q", key, model.Parameters.ExtraConfig[key], value)
+		}
+	}
+	if original["secret_key"] != "abc" {
+		t.Error("RedactSecrets() modified the original extra config")
+	}
+
+	webSearch := &WebSearchConfig{Provider: "bing", APIKey: "key"}
+	tenant := &Tenant{APIKey: "sk-tenant", WebSearchConfig: webSearch}
+	tenant.RedactSecrets()
+	if tenant.APIKey != RedactedSecret || tenant.WebSearchConfig.APIKey != RedactedSecret {
+		t.Errorf("tenant = %+v", tenant)
+	}
+	if webSearch.APIKey != "key" {
+		t.Error("RedactSecrets() modified the shared web search configuration")
+	}
+}
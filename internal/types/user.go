@@ -24,6 +24,18 @@ type User struct {
 	IsActive bool `json:"is_active"  gorm:"default:true"`
 	// Whether the user can access all tenants (cross-tenant access)
 	CanAccessAllTenants bool `json:"can_access_all_tenants" gorm:"default:false"`
+	// Whether logging in with a password also requires a TOTP code or a recovery code
+	TwoFactorEnabled bool `json:"two_factor_enabled" gorm:"default:false"`
+	// TOTP secret encrypted with AES-GCM; set by the two-factor setup and used once it is enabled
+	TOTPSecret string `json:"-"          gorm:"type:varchar(255)"`
+	// Time step of the last TOTP code accepted, so that each code is accepted once
+	TOTPLastStep int64 `json:"-"          gorm:"default:0"`
+	// SHA-256 hashes of the unused recovery codes
+	RecoveryCodes StringArray `json:"-"          gorm:"type:jsonb"`
+	// Wrong TOTP or recovery codes in a row since the last accepted one
+	TwoFactorFailedAttempts int `json:"-"          gorm:"default:0"`
+	// Time until which two-factor codes are refused after too many wrong ones
+	TwoFactorLockedUntil *time.Time `json:"-"`
 	// Creation time of the user
 	CreatedAt time.Time `json:"created_at"`
 	// Last updated time of the user
@@ -58,10 +70,61 @@ type AuthToken struct {
 	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
+// UserIdentity links a user to its account at an OpenID Connect identity provider
+type UserIdentity struct {
+	// Unique identifier of the link
+	ID string `json:"id"         gorm:"type:varchar(36);primaryKey"`
+	// User the account is linked to
+	UserID string `json:"user_id"    gorm:"type:varchar(36);index;not null"`
+	// Name of the identity provider in the configuration
+	Provider string `json:"provider"   gorm:"type:varchar(64);not null"`
+	// Subject identifying the account at the provider
+	Subject string `json:"subject"    gorm:"type:varchar(255);not null"`
+	// Email of the account when it was linked
+	Email string `json:"email"      gorm:"type:varchar(255)"`
+	// Creation time of the link
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OIDCProviderInfo describes an identity provider users can log in with
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+}
+
+// OIDCAuthorization starts a login with an identity provider
+type OIDCAuthorization struct {
+	// AuthorizationURL is the login page of the provider the user is sent to
+	AuthorizationURL string `json:"authorization_url"`
+	// State comes back with the authorization code and must be sent with it
+	State string `json:"state"`
+}
+
+// OIDCCallbackRequest completes a login with an identity provider
+type OIDCCallbackRequest struct {
+	Code  string `json:"code"  binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// OIDCTwoFactorRequest completes a login with an identity provider of a user with two-factor authentication
+type OIDCTwoFactorRequest struct {
+	// Token of the login returned by the callback
+	TwoFactorToken string `json:"two_factor_token" binding:"required"`
+	// TOTP code of the authenticator app
+	TOTPCode string `json:"totp_code"`
+	// Recovery code used instead of a TOTP code
+	RecoveryCode string `json:"recovery_code"`
+}
+
 // LoginRequest represents a login request
 type LoginRequest struct {
 	Email    string `json:"email"    binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+	// TOTP code of the authenticator app, required when two-factor authentication is enabled
+	TOTPCode string `json:"totp_code"`
+	// Recovery code used instead of a TOTP code; each recovery code is accepted once
+	RecoveryCode string `json:"recovery_code"`
 }
 
 // RegisterRequest represents a registration request
@@ -79,6 +142,10 @@ type LoginResponse struct {
 	Tenant       *Tenant `json:"tenant,omitempty"`
 	Token        string  `json:"token,omitempty"`
 	RefreshToken string  `json:"refresh_token,omitempty"`
+	// TwoFactorRequired is set when the login needs a TOTP code or a recovery code
+	TwoFactorRequired bool `json:"two_factor_required,omitempty"`
+	// TwoFactorToken is sent back with the code to complete a login with an identity provider
+	TwoFactorToken string `json:"two_factor_token,omitempty"`
 }
 
 // RegisterResponse represents a registration response
@@ -98,6 +165,7 @@ type UserInfo struct {
 	TenantID            uint64    `json:"tenant_id"`
 	IsActive            bool      `json:"is_active"`
 	CanAccessAllTenants bool      `json:"can_access_all_tenants"`
+	TwoFactorEnabled    bool      `json:"two_factor_enabled"`
 	CreatedAt           time.Time `json:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at"`
 }
@@ -112,7 +180,33 @@ func (u *User) ToUserInfo() *UserInfo {
 		TenantID:            u.TenantID,
 		IsActive:            u.IsActive,
 		CanAccessAllTenants: u.CanAccessAllTenants,
+		TwoFactorEnabled:    u.TwoFactorEnabled,
 		CreatedAt:           u.CreatedAt,
 		UpdatedAt:           u.UpdatedAt,
 	}
 }
+
+// TwoFactorSetup is a TOTP secret to add to an authenticator app, which is enabled once a code is verified
+type TwoFactorSetup struct {
+	// Secret is the base32 secret for apps where it is typed in
+	Secret string `json:"secret"`
+	// OTPAuthURL is the otpauth URL of the secret, to show as a QR code
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// TwoFactorVerifyRequest enables two-factor authentication with a code of the authenticator app
+type TwoFactorVerifyRequest struct {
+	TOTPCode string `json:"totp_code" binding:"required"`
+}
+
+// TwoFactorDisableRequest disables two-factor authentication with the password and a TOTP or recovery code
+type TwoFactorDisableRequest struct {
+	Password     string `json:"password"      binding:"required"`
+	TOTPCode     string `json:"totp_code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// TwoFactorRecoveryCodes are the recovery codes of a user, returned once when they are generated
+type TwoFactorRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
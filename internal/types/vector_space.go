@@ -0,0 +1,164 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// PrimaryVectorSpace names the vector space of the knowledge base's own embedding model
+	PrimaryVectorSpace = "default"
+	// VectorSpaceQueryFuse searches every vector space and merges the results with reciprocal rank fusion
+	VectorSpaceQueryFuse = "fuse"
+	// MaxVectorSpaces caps the additional vector spaces of a knowledge base
+	MaxVectorSpaces = 4
+)
+
+// vectorSpaceSourceNamespace is the UUID namespace of index source IDs in additional vector spaces
+var vectorSpaceSourceNamespace = uuid.MustParse("8e0c6d2b-41f7-4a35-b9d8-3c7a1f5e9b24")
+
+// VectorSpaceSourceID derives the index source ID of an entry embedded into the vector space of an
+// embedding model. Index stores key entries by source ID, so every space needs IDs of its own.
+func VectorSpaceSourceID(sourceID, embeddingModelID string) string {
+	return uuid.NewSHA1(vectorSpaceSourceNamespace, []byte(embeddingModelID+"\x00"+sourceID)).String()
+}
+
+// VectorSpace is an additional named vector space of a knowledge base, embedded with its own model
+type VectorSpace struct {
+	// Name identifies the space in the query setting
+	Name string `yaml:"name"               json:"name"`
+	// EmbeddingModelID is the embedding model of the space
+	EmbeddingModelID string `yaml:"embedding_model_id" json:"embedding_model_id"`
+}
+
+// VectorSpaceConfig lets a knowledge base keep several vector spaces, e.g. one per embedding model.
+// Chunks are embedded into the primary space and every additional space at ingestion. Spaces are
+// kept apart by embedding dimension, so each model must have a dimension of its own.
+type VectorSpaceConfig struct {
+	// Spaces are the additional vector spaces next to the primary one
+	Spaces []VectorSpace `yaml:"spaces" json:"spaces"`
+	// Query selects the searched space by name (default "default"), or "fuse" to search all spaces
+	Query string `yaml:"query"  json:"query,omitempty"`
+}
+
+// Validate checks the space names, models and query setting
+func (c *VectorSpaceConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if len(c.Spaces) > MaxVectorSpaces {
+		return fmt.Errorf("at most %d additional vector spaces are allowed", MaxVectorSpaces)
+	}
+	names := make(map[string]bool, len(c.Spaces))
+	models := make(map[string]bool, len(c.Spaces))
+	for _, space := range c.Spaces {
+		name := strings.TrimSpace(space.Name)
+		switch {
+		case name == "":
+			return fmt.Errorf("vector space name is required")
+		case name == PrimaryVectorSpace || name == VectorSpaceQueryFuse:
+			return fmt.Errorf("vector space name %q is reserved", name)
+		case names[name]:
+			return fmt.Errorf("duplicate vector space name %q", name)
+		case space.EmbeddingModelID == "":
+			return fmt.Errorf("vector space %q requires an embedding model", name)
+		case models[space.EmbeddingModelID]:
+			return fmt.Errorf("embedding model %s is used by more than one vector space", space.EmbeddingModelID)
+		}
+		names[name] = true
+		models[space.EmbeddingModelID] = true
+	}
+	if c.Query != "" && c.Query != PrimaryVectorSpace && c.Query != VectorSpaceQueryFuse && !names[c.Query] {
+		return fmt.Errorf("unknown vector space %q in query", c.Query)
+	}
+	return nil
+}
+
+// QuerySpaces returns the names of the vector spaces searched at query time
+func (c *VectorSpaceConfig) QuerySpaces() []string {
+	if c == nil || c.Query == "" || c.Query == PrimaryVectorSpace {
+		return []string{PrimaryVectorSpace}
+	}
+	if c.Query != VectorSpaceQueryFuse {
+		return []string{c.Query}
+	}
+	spaces := []string{PrimaryVectorSpace}
+	for _, space := range c.Spaces {
+		spaces = append(spaces, space.Name)
+	}
+	return spaces
+}
+
+// SpaceModelID returns the embedding model of a vector space; the primary space uses primaryModelID
+func (c *VectorSpaceConfig) SpaceModelID(space, primaryModelID string) string {
+	if c != nil {
+		for _, s := range c.Spaces {
+			if s.Name == space {
+				return s.EmbeddingModelID
+			}
+		}
+	}
+	return primaryModelID
+}
+
+// FuseVectorSpaces merges the vector results of several spaces (each sorted by score, best first)
+// with reciprocal rank fusion, since scores of different embedding models are not comparable.
+// Each chunk appears once and carries its fused score.
+func FuseVectorSpaces(resultLists [][]*IndexWithScore, rrfK int) []*IndexWithScore {
+	if rrfK <= 0 {
+		rrfK = DefaultFusionRRFK
+	}
+	scores := make(map[string]float64)
+	chunks := make(map[string]*IndexWithScore)
+	for _, results := range resultLists {
+		seen := make(map[string]bool, len(results))
+		for rank, result := range results {
+			if seen[result.ChunkID] {
+				continue
+			}
+			seen[result.ChunkID] = true
+			scores[result.ChunkID] += 1.0 / float64(rrfK+rank+1)
+			if _, exists := chunks[result.ChunkID]; !exists {
+				chunks[result.ChunkID] = result
+			}
+		}
+	}
+
+	fused := make([]*IndexWithScore, 0, len(chunks))
+	for chunkID, result := range chunks {
+		merged := *result
+		merged.Score = scores[chunkID]
+		fused = append(fused, &merged)
+	}
+	slices.SortFunc(fused, func(a, b *IndexWithScore) int {
+		if a.Score > b.Score {
+			return -1
+		} else if a.Score < b.Score {
+			return 1
+		}
+		return strings.Compare(a.ChunkID, b.ChunkID)
+	})
+	return fused
+}
+
+// Value implements driver.Valuer
+func (c VectorSpaceConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *VectorSpaceConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
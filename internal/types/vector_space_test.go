@@ -0,0 +1,53 @@
+package types
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVectorSpaceConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *VectorSpaceConfig
+		wantErr bool
+	}{
+		{name: "nil", config: nil},
+		{name: "fuse", config: &VectorSpaceConfig{Spaces: []VectorSpace{{Name: "faq", EmbeddingModelID: "m1"}}, Query: "fuse"}},
+		{name: "named query", config: &VectorSpaceConfig{Spaces: []VectorSpace{{Name: "faq", EmbeddingModelID: "m1"}}, Query: "faq"}},
+		{name: "reserved name", config: &VectorSpaceConfig{Spaces: []VectorSpace{{Name: "default", EmbeddingModelID: "m1"}}}, wantErr: true},
+		{name: "duplicate name", config: &VectorSpaceConfig{Spaces: []VectorSpace{
+			{Name: "a", EmbeddingModelID: "m1"}, {Name: "a", EmbeddingModelID: "m2"},
+		}}, wantErr: true},
+		{name: "missing model", config: &VectorSpaceConfig{Spaces: []VectorSpace{{Name: "a"}}}, wantErr: true},
+		{name: "unknown query", config: &VectorSpaceConfig{Query: "code"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFuseVectorSpaces(t *testing.T) {
+	primary := []*IndexWithScore{{ChunkID: "a", Score: 0.9}, {ChunkID: "b", Score: 0.8}, {ChunkID: "b", Score: 0.7}}
+	faq := []*IndexWithScore{{ChunkID: "b", Score: 12}, {ChunkID: "c", Score: 3}}
+
+	fused := FuseVectorSpaces([][]*IndexWithScore{primary, faq}, 0)
+	order := []string{"b", "a", "c"}
+	if len(fused) != len(order) {
+		t.Fatalf("got %d results, want %d", len(fused), len(order))
+	}
+	for i, id := range order {
+		if fused[i].ChunkID != id {
+			t.Fatalf("rank %d: got %s, want %s", i, fused[i].ChunkID, id)
+		}
+	}
+	if want := 1.0/62 + 1.0/61; math.Abs(fused[0].Score-want) > 1e-12 {
+		t.Fatalf("fused score = %v, want %v", fused[0].Score, want)
+	}
+	if primary[1].Score != 0.8 {
+		t.Fatal("input results must not be modified")
+	}
+}
@@ -497,6 +497,53 @@ func SanitizeForLogArray(input []string) []string {
 	return sanitized
 }
 
+// RedactedValue replaces sensitive values in logged data
+const RedactedValue = "[REDACTED]"
+
+// sensitiveKeyPattern matches map keys whose values must never be logged
+// Anchored at the end so that e.g. "max_tokens" is not mistaken for a token
+var sensitiveKeyPattern = regexp.MustCompile(
+	`(?i)(api[_-]?key|secret|password|passwd|token|authorization|credentials?|private[_-]?key|access[_-]?key|cookie)$`,
+)
+
+// IsSensitiveKey reports whether a field name looks like it holds a secret
+func IsSensitiveKey(key string) bool {
+	return sensitiveKeyPattern.MatchString(key)
+}
+
+// RedactSensitiveFields returns a copy of the map with the values of sensitive keys replaced
+// Nested maps and slices are redacted recursively; the input map is not modified
+func RedactSensitiveFields(input map[string]interface{}) map[string]interface{} {
+	if input == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		if IsSensitiveKey(k) {
+			redacted[k] = RedactedValue
+			continue
+		}
+		redacted[k] = redactValue(v)
+	}
+	return redacted
+}
+
+// redactValue redacts sensitive fields inside nested structures
+func redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return RedactSensitiveFields(val)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = redactValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
 // AllowedStdioCommands defines the whitelist of allowed commands for MCP stdio transport
 // These are the standard MCP server launchers that are considered safe
 var AllowedStdioCommands = map[string]bool{
@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestRedactSensitiveFields(t *testing.T) {
+	input := map[string]interface{}{
+		"query":      "weather in Paris",
+		"api_key":    "sk-123",
+		"max_tokens": 100,
+		"headers": map[string]interface{}{
+			"Authorization": "Bearer abc",
+			"Accept":        "application/json",
+		},
+		"items": []interface{}{
+			map[string]interface{}{"password": "hunter2", "name": "db"},
+		},
+	}
+
+	redacted := RedactSensitiveFields(input)
+
+	if redacted["query"] != "weather in Paris" {
+		t.Errorf("query was modified: %v", redacted["query"])
+	}
+	if redacted["max_tokens"] != 100 {
+		t.Errorf("max_tokens should not be redacted: %v", redacted["max_tokens"])
+	}
+	if redacted["api_key"] != RedactedValue {
+		t.Errorf("api_key not redacted: %v", redacted["api_key"])
+	}
+	headers := redacted["headers"].(map[string]interface{})
+	if headers["Authorization"] != RedactedValue || headers["Accept"] != "application/json" {
+		t.Errorf("headers redacted incorrectly: %v", headers)
+	}
+	item := redacted["items"].([]interface{})[0].(map[string]interface{})
+	if item["password"] != RedactedValue || item["name"] != "db" {
+		t.Errorf("nested item redacted incorrectly: %v", item)
+	}
+	if input["api_key"] != "sk-123" {
+		t.Error("input map must not be modified")
+	}
+}
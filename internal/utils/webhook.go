@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers of the callbacks posted by WeKnora. The signature is "sha256=" followed by the hex HMAC-SHA256
+// of "<timestamp>.<body>" keyed with the shared secret; receivers should also reject stale timestamps.
+const (
+	WebhookSignatureHeader = "X-WeKnora-Signature"
+	WebhookTimestampHeader = "X-WeKnora-Timestamp"
+)
+
+// webhookRetryDelay is the delay before the second delivery attempt, doubled on each further attempt
+var webhookRetryDelay = time.Second
+
+// SignWebhookPayload returns the signature of a callback body sent at timestamp (Unix seconds)
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether signature is the signature of a callback body sent at timestamp
+func VerifyWebhookSignature(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhookPayload(secret, timestamp, body)), []byte(signature))
+}
+
+// DeliverWebhook posts a signed JSON body to url, retrying failed deliveries with exponential backoff
+// up to attempts times. Any 2xx response is a successful delivery.
+func DeliverWebhook(ctx context.Context, client *http.Client, url, secret string, body []byte, attempts int) error {
+	_, err := DeliverWebhookCounted(ctx, client, url, secret, body, attempts)
+	return err
+}
+
+// DeliverWebhookCounted is DeliverWebhook that also returns the number of delivery attempts made
+func DeliverWebhookCounted(ctx context.Context, client *http.Client, url, secret string, body []byte,
+	attempts int,
+) (int, error) {
+	var err error
+	delay := webhookRetryDelay
+	attempt := 1
+	for ; attempt <= max(attempts, 1); attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return attempt - 1, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		if err = postWebhook(ctx, client, url, secret, body); err == nil {
+			return attempt, nil
+		}
+	}
+	return attempt - 1, err
+}
+
+// postWebhook makes a single signed delivery attempt
+func postWebhook(ctx context.Context, client *http.Client, url, secret string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create webhook request: %w", err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
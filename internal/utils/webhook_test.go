@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDeliverWebhook(t *testing.T) {
+	webhookRetryDelay = time.Millisecond
+	const secret = "callback-secret"
+	body := []byte(`{"job_id":"msg-1","status":"completed"}`)
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		attempts     int
+		wantErr      bool
+		wantRequests int
+	}{
+		{name: "delivered", statuses: []int{http.StatusOK}, attempts: 3, wantRequests: 1},
+		{name: "retried until delivered", statuses: []int{http.StatusBadGateway, http.StatusNoContent}, attempts: 3, wantRequests: 2},
+		{
+			name:         "gives up after the attempts",
+			statuses:     []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK},
+			attempts:     2,
+			wantErr:      true,
+			wantRequests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received, _ := io.ReadAll(r.Body)
+				timestamp, err := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+				if err != nil || !VerifyWebhookSignature(secret, timestamp, received, r.Header.Get(WebhookSignatureHeader)) {
+					t.Errorf("invalid signature %q at %q", r.Header.Get(WebhookSignatureHeader), r.Header.Get(WebhookTimestampHeader))
+				}
+				w.WriteHeader(tt.statuses[requests])
+				requests++
+			}))
+			defer server.Close()
+
+			made, err := DeliverWebhookCounted(context.Background(), server.Client(), server.URL, secret, body, tt.attempts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DeliverWebhookCounted() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if requests != tt.wantRequests || made != tt.wantRequests {
+				t.Errorf("delivered %d times, reported %d attempts, want %d", requests, made, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"status":"completed"}`)
+	signature := SignWebhookPayload("secret", 1700000000, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      string
+		want      bool
+	}{
+		{name: "valid", secret: "secret", timestamp: 1700000000, body: string(body), want: true},
+		{name: "other secret", secret: "other", timestamp: 1700000000, body: string(body)},
+		{name: "other timestamp", secret: "secret", timestamp: 1700000001, body: string(body)},
+		{name: "tampered body", secret: "secret", timestamp: 1700000000, body: `{"status":"failed"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyWebhookSignature(tt.secret, tt.timestamp, []byte(tt.body), signature); got != tt.want {
+				t.Errorf("VerifyWebhookSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+-- Migration: 000012_custom_agent_versions (rollback)
+-- Description: Remove agent versioning
+DO $$ BEGIN RAISE NOTICE '[Migration 000012 DOWN] Starting custom agent versions rollback...'; END $$;
+
+ALTER TABLE custom_agents DROP COLUMN IF EXISTS published_version;
+
+DROP INDEX IF EXISTS idx_custom_agent_versions_agent_version;
+DROP INDEX IF EXISTS idx_custom_agent_versions_agent_id;
+DROP TABLE IF EXISTS custom_agent_versions;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000012 DOWN] Custom agent versions rollback completed!'; END $$;
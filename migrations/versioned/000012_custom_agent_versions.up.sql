@@ -0,0 +1,28 @@
+-- Migration: 000012_custom_agent_versions
+-- Description: Add agent versioning so edits create drafts and only published versions serve live traffic
+DO $$ BEGIN RAISE NOTICE '[Migration 000012] Starting custom agent versions setup...'; END $$;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000012] Creating table: custom_agent_versions'; END $$;
+CREATE TABLE IF NOT EXISTS custom_agent_versions (
+    id VARCHAR(36) PRIMARY KEY DEFAULT uuid_generate_v4(),
+    agent_id VARCHAR(36) NOT NULL,
+    tenant_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'draft',
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    avatar VARCHAR(64),
+    config JSONB NOT NULL DEFAULT '{}',
+    created_by VARCHAR(36),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_custom_agent_versions_agent_version
+    ON custom_agent_versions(tenant_id, agent_id, version);
+CREATE INDEX IF NOT EXISTS idx_custom_agent_versions_agent_id ON custom_agent_versions(agent_id);
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000012] Adding published_version column to custom_agents'; END $$;
+ALTER TABLE custom_agents ADD COLUMN IF NOT EXISTS published_version INTEGER NOT NULL DEFAULT 0;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000012] Custom agent versions setup completed!'; END $$;
@@ -0,0 +1,9 @@
+-- Migration: 000013_agent_runs (rollback)
+-- Description: Remove agent execution traces
+DO $$ BEGIN RAISE NOTICE '[Migration 000013 DOWN] Starting agent runs rollback...'; END $$;
+
+DROP INDEX IF EXISTS idx_agent_runs_tenant_message;
+DROP INDEX IF EXISTS idx_agent_runs_created_at;
+DROP TABLE IF EXISTS agent_runs;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000013 DOWN] Agent runs rollback completed!'; END $$;
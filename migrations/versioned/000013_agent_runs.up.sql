@@ -0,0 +1,28 @@
+-- Migration: 000013_agent_runs
+-- Description: Store agent execution traces so bad answers can be inspected and replayed
+DO $$ BEGIN RAISE NOTICE '[Migration 000013] Starting agent runs setup...'; END $$;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000013] Creating table: agent_runs'; END $$;
+CREATE TABLE IF NOT EXISTS agent_runs (
+    id VARCHAR(36) PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id INTEGER NOT NULL,
+    session_id VARCHAR(36),
+    message_id VARCHAR(36) NOT NULL,
+    agent_id VARCHAR(36),
+    agent_version INTEGER NOT NULL DEFAULT 0,
+    replay_of VARCHAR(36),
+    input JSONB NOT NULL DEFAULT '{}',
+    steps JSONB NOT NULL DEFAULT '[]',
+    final_answer TEXT,
+    status VARCHAR(16) NOT NULL,
+    stop_reason VARCHAR(32),
+    error TEXT,
+    tokens_used INTEGER NOT NULL DEFAULT 0,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_runs_tenant_message ON agent_runs(tenant_id, message_id);
+CREATE INDEX IF NOT EXISTS idx_agent_runs_created_at ON agent_runs(created_at);
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000013] Agent runs setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000014_kb_vector_search_config (rollback)
+-- Description: Remove per knowledge base vector search parameters
+DO $$ BEGIN RAISE NOTICE '[Migration 000014 DOWN] Removing vector_search_config column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS vector_search_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000014 DOWN] Vector search config rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000014_kb_vector_search_config
+-- Description: Add per knowledge base vector search parameters (HNSW ef_search / IVF probes)
+DO $$ BEGIN RAISE NOTICE '[Migration 000014] Adding vector_search_config column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS vector_search_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000014] Vector search config setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000015_kb_dedup_config (rollback)
+-- Description: Remove per knowledge base near-duplicate chunk collapsing configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000015 DOWN] Removing dedup_config column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS dedup_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000015 DOWN] Dedup config rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000015_kb_dedup_config
+-- Description: Add per knowledge base near-duplicate chunk collapsing configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000015] Adding dedup_config column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS dedup_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000015] Dedup config setup completed!'; END $$;
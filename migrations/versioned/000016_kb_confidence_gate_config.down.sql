@@ -0,0 +1,7 @@
+-- Migration: 000016_kb_confidence_gate_config (rollback)
+-- Description: Remove per knowledge base confidence gate policy
+DO $$ BEGIN RAISE NOTICE '[Migration 000016 DOWN] Removing confidence_gate_config column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS confidence_gate_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000016 DOWN] Confidence gate config rollback completed!'; END $$;
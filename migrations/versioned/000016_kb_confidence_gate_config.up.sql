@@ -0,0 +1,7 @@
+-- Migration: 000016_kb_confidence_gate_config
+-- Description: Add per knowledge base confidence gate policy
+DO $$ BEGIN RAISE NOTICE '[Migration 000016] Adding confidence_gate_config column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS confidence_gate_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000016] Confidence gate config setup completed!'; END $$;
@@ -0,0 +1,8 @@
+-- Migration: 000017_knowledge_is_enabled (rollback)
+-- Description: Remove retrieval enable flag from knowledges
+DO $$ BEGIN RAISE NOTICE '[Migration 000017 DOWN] Removing is_enabled column from knowledges'; END $$;
+
+DROP INDEX IF EXISTS idx_knowledges_is_enabled;
+ALTER TABLE knowledges DROP COLUMN IF EXISTS is_enabled;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000017 DOWN] Knowledge is_enabled rollback completed!'; END $$;
@@ -0,0 +1,8 @@
+-- Migration: 000017_knowledge_is_enabled
+-- Description: Add retrieval enable flag to knowledges
+DO $$ BEGIN RAISE NOTICE '[Migration 000017] Adding is_enabled column to knowledges'; END $$;
+
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS is_enabled BOOLEAN NOT NULL DEFAULT TRUE;
+CREATE INDEX IF NOT EXISTS idx_knowledges_is_enabled ON knowledges(knowledge_base_id, is_enabled);
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000017] Knowledge is_enabled setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000018_kb_fusion_config (rollback)
+-- Description: Remove per knowledge base hybrid search fusion algorithm
+DO $$ BEGIN RAISE NOTICE '[Migration 000018 DOWN] Removing fusion_config column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS fusion_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000018 DOWN] Fusion config rollback completed!'; END $$;
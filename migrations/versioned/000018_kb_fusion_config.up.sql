@@ -0,0 +1,7 @@
+-- Migration: 000018_kb_fusion_config
+-- Description: Add per knowledge base hybrid search fusion algorithm
+DO $$ BEGIN RAISE NOTICE '[Migration 000018] Adding fusion_config column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS fusion_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000018] Fusion config setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000019_kb_history_depth (rollback)
+-- Description: Remove per knowledge base maximum conversation history depth
+DO $$ BEGIN RAISE NOTICE '[Migration 000019 DOWN] Removing history_depth column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS history_depth;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000019 DOWN] History depth rollback completed!'; END $$;
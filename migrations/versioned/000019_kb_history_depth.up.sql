@@ -0,0 +1,7 @@
+-- Migration: 000019_kb_history_depth
+-- Description: Add per knowledge base maximum conversation history depth
+DO $$ BEGIN RAISE NOTICE '[Migration 000019] Adding history_depth column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS history_depth INTEGER NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000019] History depth setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000020_kb_empty_message (rollback)
+-- Description: Remove per knowledge base message returned while the knowledge base has no content
+DO $$ BEGIN RAISE NOTICE '[Migration 000020 DOWN] Removing empty_message column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS empty_message;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000020 DOWN] Empty message rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000020_kb_empty_message
+-- Description: Add per knowledge base message returned while the knowledge base has no content
+DO $$ BEGIN RAISE NOTICE '[Migration 000020] Adding empty_message column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS empty_message TEXT NOT NULL DEFAULT '';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000020] Empty message setup completed!'; END $$;
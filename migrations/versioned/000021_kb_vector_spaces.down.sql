@@ -0,0 +1,7 @@
+-- Migration: 000021_kb_vector_spaces (rollback)
+-- Description: Remove per knowledge base additional vector spaces
+DO $$ BEGIN RAISE NOTICE '[Migration 000021 DOWN] Removing vector_space_config column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS vector_space_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000021 DOWN] Vector space rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000021_kb_vector_spaces
+-- Description: Add per knowledge base additional vector spaces (one per embedding model)
+DO $$ BEGIN RAISE NOTICE '[Migration 000021] Adding vector_space_config column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS vector_space_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000021] Vector space setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000022_message_regenerated_from (rollback)
+-- Description: Remove the link of regenerated answers to the answer they were regenerated from
+DO $$ BEGIN RAISE NOTICE '[Migration 000022 DOWN] Removing regenerated_from column from messages'; END $$;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS regenerated_from;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000022 DOWN] Regenerated answer rollback completed!'; END $$;
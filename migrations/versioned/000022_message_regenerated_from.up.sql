@@ -0,0 +1,7 @@
+-- Migration: 000022_message_regenerated_from
+-- Description: Link regenerated answers to the answer they were regenerated from
+DO $$ BEGIN RAISE NOTICE '[Migration 000022] Adding regenerated_from column to messages'; END $$;
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS regenerated_from VARCHAR(36) NOT NULL DEFAULT '';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000022] Regenerated answer setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000023_kb_metadata_schema (rollback)
+-- Description: Remove per knowledge base schema of knowledge metadata
+DO $$ BEGIN RAISE NOTICE '[Migration 000023 DOWN] Removing metadata_schema_config column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS metadata_schema_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000023 DOWN] Metadata schema rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000023_kb_metadata_schema
+-- Description: Add per knowledge base schema of knowledge metadata
+DO $$ BEGIN RAISE NOTICE '[Migration 000023] Adding metadata_schema_config column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS metadata_schema_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000023] Metadata schema setup completed!'; END $$;
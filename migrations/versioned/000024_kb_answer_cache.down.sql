@@ -0,0 +1,7 @@
+-- Migration: 000024_kb_answer_cache (rollback)
+-- Description: Remove per knowledge base exact-match answer cache configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000024 DOWN] Removing answer_cache_config column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS answer_cache_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000024 DOWN] Answer cache rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000024_kb_answer_cache
+-- Description: Add per knowledge base exact-match answer cache configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000024] Adding answer_cache_config column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS answer_cache_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000024] Answer cache setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000025_tenant_prompt_injection (rollback)
+-- Description: Remove per tenant prompt injection defense configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000025 DOWN] Removing prompt_injection_config column from tenants'; END $$;
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS prompt_injection_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000025 DOWN] Prompt injection defense rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000025_tenant_prompt_injection
+-- Description: Add per tenant prompt injection defense configuration for retrieved content
+DO $$ BEGIN RAISE NOTICE '[Migration 000025] Adding prompt_injection_config column to tenants'; END $$;
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS prompt_injection_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000025] Prompt injection defense setup completed!'; END $$;
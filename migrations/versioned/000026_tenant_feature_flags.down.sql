@@ -0,0 +1,7 @@
+-- Migration: 000026_tenant_feature_flags (rollback)
+-- Description: Remove per tenant feature flag overrides
+DO $$ BEGIN RAISE NOTICE '[Migration 000026 DOWN] Removing feature_flags column from tenants'; END $$;
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS feature_flags;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000026 DOWN] Tenant feature flags rollback completed!'; END $$;
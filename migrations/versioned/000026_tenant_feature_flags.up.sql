@@ -0,0 +1,7 @@
+-- Migration: 000026_tenant_feature_flags
+-- Description: Add per tenant feature flag overrides
+DO $$ BEGIN RAISE NOTICE '[Migration 000026] Adding feature_flags column to tenants'; END $$;
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS feature_flags JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000026] Tenant feature flags setup completed!'; END $$;
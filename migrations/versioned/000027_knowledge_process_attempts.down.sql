@@ -0,0 +1,8 @@
+-- Migration: 000027_knowledge_process_attempts (rollback)
+-- Description: Remove processing attempts and failure kind of knowledges
+DO $$ BEGIN RAISE NOTICE '[Migration 000027 DOWN] Removing process_attempts and failure_kind columns from knowledges'; END $$;
+
+ALTER TABLE knowledges DROP COLUMN IF EXISTS failure_kind;
+ALTER TABLE knowledges DROP COLUMN IF EXISTS process_attempts;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000027 DOWN] Knowledge process attempts rollback completed!'; END $$;
@@ -0,0 +1,8 @@
+-- Migration: 000027_knowledge_process_attempts
+-- Description: Track processing attempts and failure kind of knowledges
+DO $$ BEGIN RAISE NOTICE '[Migration 000027] Adding process_attempts and failure_kind columns to knowledges'; END $$;
+
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS process_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS failure_kind VARCHAR(32) NOT NULL DEFAULT '';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000027] Knowledge process attempts setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000028_session_retrieval_params (rollback)
+-- Description: Remove default retrieval parameters from sessions
+DO $$ BEGIN RAISE NOTICE '[Migration 000028 DOWN] Removing retrieval_params column from sessions'; END $$;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS retrieval_params;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000028 DOWN] Session retrieval params rollback completed!'; END $$;
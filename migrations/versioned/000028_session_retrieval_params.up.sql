@@ -0,0 +1,7 @@
+-- Migration: 000028_session_retrieval_params
+-- Description: Add default retrieval parameters to sessions
+DO $$ BEGIN RAISE NOTICE '[Migration 000028] Adding retrieval_params column to sessions'; END $$;
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS retrieval_params JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000028] Session retrieval params setup completed!'; END $$;
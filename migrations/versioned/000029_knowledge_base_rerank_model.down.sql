@@ -0,0 +1,7 @@
+-- Migration: 000029_knowledge_base_rerank_model (rollback)
+-- Description: Remove the per knowledge base rerank model
+DO $$ BEGIN RAISE NOTICE '[Migration 000029 DOWN] Removing rerank_model_id column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS rerank_model_id;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000029 DOWN] Knowledge base rerank model rollback completed!'; END $$;
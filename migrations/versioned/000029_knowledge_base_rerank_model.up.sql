@@ -0,0 +1,7 @@
+-- Migration: 000029_knowledge_base_rerank_model
+-- Description: Add a per knowledge base rerank model
+DO $$ BEGIN RAISE NOTICE '[Migration 000029] Adding rerank_model_id column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS rerank_model_id VARCHAR(64) NOT NULL DEFAULT '';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000029] Knowledge base rerank model setup completed!'; END $$;
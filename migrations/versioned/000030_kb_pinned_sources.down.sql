@@ -0,0 +1,7 @@
+-- Migration: 000030_kb_pinned_sources (rollback)
+-- Description: Remove per knowledge base pinned source rules
+DO $$ BEGIN RAISE NOTICE '[Migration 000030 DOWN] Removing pinned_sources column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS pinned_sources;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000030 DOWN] Pinned sources rollback completed!'; END $$;
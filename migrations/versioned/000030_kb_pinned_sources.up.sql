@@ -0,0 +1,7 @@
+-- Migration: 000030_kb_pinned_sources
+-- Description: Add per knowledge base pinned source rules forcing curated knowledge into retrieval results
+DO $$ BEGIN RAISE NOTICE '[Migration 000030] Adding pinned_sources column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS pinned_sources JSONB NOT NULL DEFAULT '[]';
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000030] Pinned sources setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000031_knowledge_chunk_size_stats (rollback)
+-- Description: Remove the chunk size distribution of knowledges
+DO $$ BEGIN RAISE NOTICE '[Migration 000031 DOWN] Removing chunk_size_stats column from knowledges'; END $$;
+
+ALTER TABLE knowledges DROP COLUMN IF EXISTS chunk_size_stats;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000031 DOWN] Chunk size stats rollback completed!'; END $$;
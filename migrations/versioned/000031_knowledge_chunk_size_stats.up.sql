@@ -0,0 +1,7 @@
+-- Migration: 000031_knowledge_chunk_size_stats
+-- Description: Record the chunk size distribution of processed knowledges
+DO $$ BEGIN RAISE NOTICE '[Migration 000031] Adding chunk_size_stats column to knowledges'; END $$;
+
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS chunk_size_stats JSONB;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000031] Chunk size stats setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000032_kb_document_summary (rollback)
+-- Description: Remove per knowledge base document summary configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000032 DOWN] Removing document_summary_config column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS document_summary_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000032 DOWN] Document summary rollback completed!'; END $$;
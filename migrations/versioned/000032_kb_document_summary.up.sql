@@ -0,0 +1,7 @@
+-- Migration: 000032_kb_document_summary
+-- Description: Add per knowledge base document summary configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000032] Adding document_summary_config column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS document_summary_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000032] Document summary setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000033_kb_parent_child (rollback)
+-- Description: Remove per knowledge base parent-child chunking configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000033 DOWN] Removing parent_child_config column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS parent_child_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000033 DOWN] Parent-child chunking rollback completed!'; END $$;
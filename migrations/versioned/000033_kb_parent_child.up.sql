@@ -0,0 +1,7 @@
+-- Migration: 000033_kb_parent_child
+-- Description: Add per knowledge base parent-child chunking configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000033] Adding parent_child_config column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS parent_child_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000033] Parent-child chunking setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000034_kb_rerank_fallback (rollback)
+-- Description: Remove per knowledge base rerank fallback configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000034 DOWN] Removing rerank_fallback_config column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS rerank_fallback_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000034 DOWN] Rerank fallback rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000034_kb_rerank_fallback
+-- Description: Add per knowledge base rerank fallback configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000034] Adding rerank_fallback_config column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS rerank_fallback_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000034] Rerank fallback setup completed!'; END $$;
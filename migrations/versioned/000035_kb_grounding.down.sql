@@ -0,0 +1,7 @@
+-- Migration: 000035_kb_grounding (rollback)
+-- Description: Remove per knowledge base answer grounding configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000035 DOWN] Removing grounding_config column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS grounding_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000035 DOWN] Answer grounding rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000035_kb_grounding
+-- Description: Add per knowledge base answer grounding configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000035] Adding grounding_config column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS grounding_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000035] Answer grounding setup completed!'; END $$;
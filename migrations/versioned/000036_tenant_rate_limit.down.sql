@@ -0,0 +1,7 @@
+-- Migration: 000036_tenant_rate_limit (rollback)
+-- Description: Remove per tenant request rate limit override
+DO $$ BEGIN RAISE NOTICE '[Migration 000036 DOWN] Removing rate_limit_config column from tenants'; END $$;
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS rate_limit_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000036 DOWN] Tenant rate limit rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000036_tenant_rate_limit
+-- Description: Add per tenant request rate limit override
+DO $$ BEGIN RAISE NOTICE '[Migration 000036] Adding rate_limit_config column to tenants'; END $$;
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS rate_limit_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000036] Tenant rate limit setup completed!'; END $$;
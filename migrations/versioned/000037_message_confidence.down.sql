@@ -0,0 +1,7 @@
+-- Migration: 000037_message_confidence (rollback)
+-- Description: Remove the confidence of answers from assistant messages
+DO $$ BEGIN RAISE NOTICE '[Migration 000037 DOWN] Removing confidence column from messages'; END $$;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS confidence;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000037 DOWN] Message confidence rollback completed!'; END $$;
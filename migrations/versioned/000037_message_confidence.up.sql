@@ -0,0 +1,7 @@
+-- Migration: 000037_message_confidence
+-- Description: Persist the confidence of answers on assistant messages
+DO $$ BEGIN RAISE NOTICE '[Migration 000037] Adding confidence column to messages'; END $$;
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS confidence JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000037] Message confidence setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000038_message_retrieval_degradation (rollback)
+-- Description: Remove the retrieval degradation note from assistant messages
+DO $$ BEGIN RAISE NOTICE '[Migration 000038 DOWN] Removing retrieval_degradation column from messages'; END $$;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS retrieval_degradation;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000038 DOWN] Message retrieval degradation rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000038_message_retrieval_degradation
+-- Description: Note on assistant messages when the answer was generated from partial retrieval results
+DO $$ BEGIN RAISE NOTICE '[Migration 000038] Adding retrieval_degradation column to messages'; END $$;
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS retrieval_degradation JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000038] Message retrieval degradation setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000039_mcp_service_health (rollback)
+-- Description: Remove the tool validation result from MCP services
+DO $$ BEGIN RAISE NOTICE '[Migration 000039 DOWN] Removing health column from mcp_services'; END $$;
+
+ALTER TABLE mcp_services DROP COLUMN IF EXISTS health;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000039 DOWN] MCP service health rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000039_mcp_service_health
+-- Description: Result of the periodic validation of the tools advertised by MCP services
+DO $$ BEGIN RAISE NOTICE '[Migration 000039] Adding health column to mcp_services'; END $$;
+
+ALTER TABLE mcp_services ADD COLUMN IF NOT EXISTS health JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000039] MCP service health setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000040_tenant_payload_limit (rollback)
+-- Description: Remove per tenant payload size limit override
+DO $$ BEGIN RAISE NOTICE '[Migration 000040 DOWN] Removing payload_limit_config column from tenants'; END $$;
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS payload_limit_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000040 DOWN] Tenant payload limit rollback completed!'; END $$;
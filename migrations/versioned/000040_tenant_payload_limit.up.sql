@@ -0,0 +1,7 @@
+-- Migration: 000040_tenant_payload_limit
+-- Description: Add per tenant payload size limit override
+DO $$ BEGIN RAISE NOTICE '[Migration 000040] Adding payload_limit_config column to tenants'; END $$;
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS payload_limit_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000040] Tenant payload limit setup completed!'; END $$;
@@ -0,0 +1,8 @@
+-- Migration: 000041_pipeline_stages (rollback)
+-- Description: Remove per knowledge base ingestion pipeline stages
+DO $$ BEGIN RAISE NOTICE '[Migration 000041 DOWN] Removing pipeline_config and skipped_stages columns'; END $$;
+
+ALTER TABLE knowledges DROP COLUMN IF EXISTS skipped_stages;
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS pipeline_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000041 DOWN] Pipeline stages rollback completed!'; END $$;
@@ -0,0 +1,8 @@
+-- Migration: 000041_pipeline_stages
+-- Description: Add per knowledge base ingestion pipeline stages and record the stages skipped by each knowledge
+DO $$ BEGIN RAISE NOTICE '[Migration 000041] Adding pipeline_config to knowledge_bases and skipped_stages to knowledges'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS pipeline_config JSONB NULL;
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS skipped_stages JSONB;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000041] Pipeline stages setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000042_source_quota (rollback)
+-- Description: Remove per knowledge base source quota
+DO $$ BEGIN RAISE NOTICE '[Migration 000042 DOWN] Removing source_quota_config column'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS source_quota_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000042 DOWN] Source quota rollback completed!'; END $$;
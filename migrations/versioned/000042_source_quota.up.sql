@@ -0,0 +1,7 @@
+-- Migration: 000042_source_quota
+-- Description: Add per knowledge base cap of chunks per knowledge item in search results
+DO $$ BEGIN RAISE NOTICE '[Migration 000042] Adding source_quota_config to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS source_quota_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000042] Source quota setup completed!'; END $$;
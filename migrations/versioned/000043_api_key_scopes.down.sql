@@ -0,0 +1,7 @@
+-- Migration: 000043_api_key_scopes (rollback)
+-- Description: Remove the scopes of tenant API keys
+DO $$ BEGIN RAISE NOTICE '[Migration 000043 DOWN] Removing api_key_scopes column'; END $$;
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS api_key_scopes;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000043 DOWN] API key scopes rollback completed!'; END $$;
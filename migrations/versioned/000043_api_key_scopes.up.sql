@@ -0,0 +1,9 @@
+-- Migration: 000043_api_key_scopes
+-- Description: Add scopes to tenant API keys; existing keys keep full access
+DO $$ BEGIN RAISE NOTICE '[Migration 000043] Adding api_key_scopes to tenants'; END $$;
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS api_key_scopes JSONB NULL;
+UPDATE tenants SET api_key_scopes = '["knowledge:read", "knowledge:write", "chat", "admin"]'::jsonb
+WHERE api_key_scopes IS NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000043] API key scopes setup completed!'; END $$;
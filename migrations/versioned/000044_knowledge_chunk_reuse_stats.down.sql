@@ -0,0 +1,7 @@
+-- Migration: 000044_knowledge_chunk_reuse_stats (rollback)
+-- Description: Remove the chunk reuse stats of knowledges
+DO $$ BEGIN RAISE NOTICE '[Migration 000044 DOWN] Removing chunk_reuse_stats column from knowledges'; END $$;
+
+ALTER TABLE knowledges DROP COLUMN IF EXISTS chunk_reuse_stats;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000044 DOWN] Chunk reuse stats rollback completed!'; END $$;
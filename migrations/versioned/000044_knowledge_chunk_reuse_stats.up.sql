@@ -0,0 +1,7 @@
+-- Migration: 000044_knowledge_chunk_reuse_stats
+-- Description: Record the chunks reused and recomputed by incremental updates of knowledges
+DO $$ BEGIN RAISE NOTICE '[Migration 000044] Adding chunk_reuse_stats column to knowledges'; END $$;
+
+ALTER TABLE knowledges ADD COLUMN IF NOT EXISTS chunk_reuse_stats JSONB;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000044] Chunk reuse stats setup completed!'; END $$;
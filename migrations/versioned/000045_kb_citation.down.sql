@@ -0,0 +1,7 @@
+-- Migration: 000045_kb_citation (rollback)
+-- Description: Remove per knowledge base citation requirement configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000045 DOWN] Removing citation_config column from knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases DROP COLUMN IF EXISTS citation_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000045 DOWN] Citation requirement rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000045_kb_citation
+-- Description: Add per knowledge base citation requirement configuration
+DO $$ BEGIN RAISE NOTICE '[Migration 000045] Adding citation_config column to knowledge_bases'; END $$;
+
+ALTER TABLE knowledge_bases ADD COLUMN IF NOT EXISTS citation_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000045] Citation requirement setup completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000046_tenant_webhook (rollback)
+-- Description: Remove per tenant webhook notified when asynchronous tasks finish
+DO $$ BEGIN RAISE NOTICE '[Migration 000046 DOWN] Removing webhook_config column from tenants'; END $$;
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS webhook_config;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000046 DOWN] Tenant webhook rollback completed!'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000046_tenant_webhook
+-- Description: Add per tenant webhook notified when asynchronous tasks finish
+DO $$ BEGIN RAISE NOTICE '[Migration 000046] Adding webhook_config column to tenants'; END $$;
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS webhook_config JSONB NULL;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000046] Tenant webhook setup completed!'; END $$;
@@ -0,0 +1,8 @@
+-- Migration: 000047_audit_logs (rollback)
+-- Description: Remove the audit log of the API requests
+DO $$ BEGIN RAISE NOTICE '[Migration 000047 DOWN] Starting audit logs rollback...'; END $$;
+
+DROP INDEX IF EXISTS idx_audit_logs_tenant_created_at;
+DROP TABLE IF EXISTS audit_logs;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000047 DOWN] Audit logs rollback completed!'; END $$;
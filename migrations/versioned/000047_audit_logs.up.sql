@@ -0,0 +1,24 @@
+-- Migration: 000047_audit_logs
+-- Description: Record the mutating API requests of each tenant for auditing
+DO $$ BEGIN RAISE NOTICE '[Migration 000047] Starting audit logs setup...'; END $$;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000047] Creating table: audit_logs'; END $$;
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id VARCHAR(36) PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id INTEGER NOT NULL DEFAULT 0,
+    user_id VARCHAR(36),
+    request_id VARCHAR(128),
+    method VARCHAR(16) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    resource_type VARCHAR(64),
+    resource_id VARCHAR(255),
+    status INTEGER NOT NULL,
+    client_ip VARCHAR(64),
+    request_body TEXT,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_created_at ON audit_logs(tenant_id, created_at);
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000047] Audit logs setup completed!'; END $$;
@@ -0,0 +1,9 @@
+-- Migration: 000048_user_identities (rollback)
+-- Description: Remove the links between users and identity provider accounts
+DO $$ BEGIN RAISE NOTICE '[Migration 000048 DOWN] Starting user identities rollback...'; END $$;
+
+DROP INDEX IF EXISTS idx_user_identities_user_id;
+DROP INDEX IF EXISTS idx_user_identities_provider_subject;
+DROP TABLE IF EXISTS user_identities;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000048 DOWN] User identities rollback completed!'; END $$;
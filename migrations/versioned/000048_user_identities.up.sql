@@ -0,0 +1,18 @@
+-- Migration: 000048_user_identities
+-- Description: Link users to their accounts at OpenID Connect identity providers
+DO $$ BEGIN RAISE NOTICE '[Migration 000048] Starting user identities setup...'; END $$;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000048] Creating table: user_identities'; END $$;
+CREATE TABLE IF NOT EXISTS user_identities (
+    id VARCHAR(36) PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id VARCHAR(36) NOT NULL,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_provider_subject ON user_identities(provider, subject);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000048] User identities setup completed!'; END $$;
@@ -0,0 +1,8 @@
+-- Migration: 000049_user_roles (rollback)
+-- Description: Remove the role assignments of the users of each tenant
+DO $$ BEGIN RAISE NOTICE '[Migration 000049 DOWN] Starting user roles rollback...'; END $$;
+
+DROP INDEX IF EXISTS idx_user_roles_user_id;
+DROP TABLE IF EXISTS user_roles;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000049 DOWN] User roles rollback completed!'; END $$;
@@ -0,0 +1,17 @@
+-- Migration: 000049_user_roles
+-- Description: Assign roles (admin, editor, viewer) to the users of each tenant
+DO $$ BEGIN RAISE NOTICE '[Migration 000049] Starting user roles setup...'; END $$;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000049] Creating table: user_roles'; END $$;
+CREATE TABLE IF NOT EXISTS user_roles (
+    tenant_id INTEGER NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    role VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_user_id ON user_roles(user_id);
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000049] User roles setup completed!'; END $$;
@@ -0,0 +1,9 @@
+-- Migration: 000050_api_keys (rollback)
+-- Description: Remove the API keys minted for tenants
+DO $$ BEGIN RAISE NOTICE '[Migration 000050 DOWN] Starting API keys rollback...'; END $$;
+
+DROP INDEX IF EXISTS idx_api_keys_tenant_id;
+DROP INDEX IF EXISTS idx_api_keys_key_hash;
+DROP TABLE IF EXISTS api_keys;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000050 DOWN] API keys rollback completed!'; END $$;
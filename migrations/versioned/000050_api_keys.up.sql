@@ -0,0 +1,24 @@
+-- Migration: 000050_api_keys
+-- Description: API keys minted for tenants with their own scopes, expiration and revocation
+DO $$ BEGIN RAISE NOTICE '[Migration 000050] Starting API keys setup...'; END $$;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000050] Creating table: api_keys'; END $$;
+CREATE TABLE IF NOT EXISTS api_keys (
+    id VARCHAR(36) PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16),
+    key_hash VARCHAR(64) NOT NULL,
+    scopes JSONB NOT NULL DEFAULT '[]'::jsonb,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_by VARCHAR(36),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant_id ON api_keys(tenant_id);
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000050] API keys setup completed!'; END $$;
@@ -0,0 +1,10 @@
+-- Migration: 000051_user_two_factor (rollback)
+-- Description: Remove the two-factor authentication of users
+DO $$ BEGIN RAISE NOTICE '[Migration 000051 DOWN] Starting two-factor authentication rollback...'; END $$;
+
+ALTER TABLE users DROP COLUMN IF EXISTS recovery_codes;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
+ALTER TABLE users DROP COLUMN IF EXISTS two_factor_enabled;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000051 DOWN] Two-factor authentication rollback completed!'; END $$;
@@ -0,0 +1,11 @@
+-- Migration: 000051_user_two_factor
+-- Description: TOTP-based two-factor authentication of users
+DO $$ BEGIN RAISE NOTICE '[Migration 000051] Starting two-factor authentication setup...'; END $$;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000051] Adding two-factor columns to table: users'; END $$;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS two_factor_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS recovery_codes JSONB;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000051] Two-factor authentication setup completed!'; END $$;
@@ -0,0 +1,3 @@
+-- Migration: 000052_tenant_admin_roles (rollback)
+-- Description: The admin roles of the backfill cannot be told apart from assigned ones, so they are kept
+DO $$ BEGIN RAISE NOTICE '[Migration 000052 DOWN] Tenant admin roles are kept; nothing to roll back'; END $$;
@@ -0,0 +1,13 @@
+-- Migration: 000052_tenant_admin_roles
+-- Description: Assign the admin role to the first user of each tenant, now that users without a role are viewers
+DO $$ BEGIN RAISE NOTICE '[Migration 000052] Starting tenant admin roles backfill...'; END $$;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000052] Assigning admin roles in table: user_roles'; END $$;
+INSERT INTO user_roles (tenant_id, user_id, role, created_at, updated_at)
+SELECT DISTINCT ON (tenant_id) tenant_id, id, 'admin', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+FROM users
+WHERE deleted_at IS NULL AND tenant_id IS NOT NULL
+ORDER BY tenant_id, created_at, id
+ON CONFLICT (tenant_id, user_id) DO NOTHING;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000052] Tenant admin roles backfill completed!'; END $$;
@@ -0,0 +1,8 @@
+-- Migration: 000053_user_two_factor_attempts (rollback)
+-- Description: Remove the two-factor attempt limit of users
+DO $$ BEGIN RAISE NOTICE '[Migration 000053 DOWN] Starting two-factor attempts rollback...'; END $$;
+
+ALTER TABLE users DROP COLUMN IF EXISTS two_factor_locked_until;
+ALTER TABLE users DROP COLUMN IF EXISTS two_factor_failed_attempts;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000053 DOWN] Two-factor attempts rollback completed!'; END $$;
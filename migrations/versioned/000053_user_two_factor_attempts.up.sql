@@ -0,0 +1,9 @@
+-- Migration: 000053_user_two_factor_attempts
+-- Description: Lock the two-factor authentication of users after too many wrong codes
+DO $$ BEGIN RAISE NOTICE '[Migration 000053] Starting two-factor attempts setup...'; END $$;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000053] Adding two-factor attempt columns to table: users'; END $$;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS two_factor_failed_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS two_factor_locked_until TIMESTAMP WITH TIME ZONE;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000053] Two-factor attempts setup completed!'; END $$;